@@ -0,0 +1,111 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package algo
+
+import (
+	"math"
+	"math/bits"
+
+	farm "github.com/dgryski/go-farm"
+)
+
+const (
+	// hllPrecision is the number of bits of the hashes picking the register of a HyperLogLog,
+	// which has 2^hllPrecision registers, for a standard error of 1.04/sqrt(2^hllPrecision).
+	hllPrecision = 14
+	// hllSparseMax is the number of distinct hashes kept exactly before the registers of a
+	// HyperLogLog are allocated, so that the small sets don't take the memory of the registers.
+	hllSparseMax = 1024
+)
+
+// HyperLogLog approximates the number of distinct items added to it, as described by Flajolet et
+// al. in HyperLogLog: the analysis of a near-optimal cardinality estimation algorithm:
+//
+// http://algo.inria.fr/flajolet/Publications/FlFuGaMe07.pdf
+//
+// It takes 16KB whatever the number of items, with a standard error of 0.81%. Until it has
+// hllSparseMax distinct items, it keeps their hashes instead, and its count is exact up to the
+// collisions of the hashes. Two HyperLogLogs can be merged, into the HyperLogLog of the union of
+// their items.
+type HyperLogLog struct {
+	sparse    map[uint64]struct{}
+	registers []uint8
+}
+
+// NewHyperLogLog returns an empty HyperLogLog.
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{sparse: make(map[uint64]struct{})}
+}
+
+// Add adds the item to the HyperLogLog.
+func (h *HyperLogLog) Add(data []byte) {
+	h.addHash(farm.Fingerprint64(data))
+}
+
+func (h *HyperLogLog) addHash(hash uint64) {
+	if h.registers == nil {
+		h.sparse[hash] = struct{}{}
+		if len(h.sparse) > hllSparseMax {
+			h.toRegisters()
+		}
+		return
+	}
+	idx := hash >> (64 - hllPrecision)
+	// The leading zeros of the remaining bits, capped by a stop bit.
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+func (h *HyperLogLog) toRegisters() {
+	h.registers = make([]uint8, 1<<hllPrecision)
+	for hash := range h.sparse {
+		h.addHash(hash)
+	}
+	h.sparse = nil
+}
+
+// Merge adds the items of other to the HyperLogLog.
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	if other.registers == nil {
+		for hash := range other.sparse {
+			h.addHash(hash)
+		}
+		return
+	}
+	if h.registers == nil {
+		h.toRegisters()
+	}
+	for i, rank := range other.registers {
+		if rank > h.registers[i] {
+			h.registers[i] = rank
+		}
+	}
+}
+
+// Count returns the approximate number of distinct items added to the HyperLogLog.
+func (h *HyperLogLog) Count() uint64 {
+	if h.registers == nil {
+		return uint64(len(h.sparse))
+	}
+	m := float64(len(h.registers))
+	var sum float64
+	var zeros int
+	for _, rank := range h.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// The small cardinalities are better estimated by linear counting. The 64 bits hashes don't
+	// need a correction of the large ones.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
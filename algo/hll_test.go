@@ -0,0 +1,50 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package algo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	h := NewHyperLogLog()
+	for i := range 500 {
+		h.Add([]byte(fmt.Sprintf("item-%d", i%100)))
+	}
+	// The small sets are counted exactly.
+	require.Equal(t, uint64(100), h.Count())
+
+	for _, n := range []int{5000, 200000} {
+		h := NewHyperLogLog()
+		for i := range 2 * n {
+			h.Add([]byte(fmt.Sprintf("item-%d", i%n)))
+		}
+		require.InEpsilon(t, n, h.Count(), 0.03, "%d items", n)
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	a, b := NewHyperLogLog(), NewHyperLogLog()
+	for i := range 30000 {
+		a.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	for i := 20000; i < 50000; i++ {
+		b.Add([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	small := NewHyperLogLog()
+	small.Add([]byte("item-0"))
+	small.Add([]byte("other"))
+
+	a.Merge(b)
+	a.Merge(small)
+	require.InEpsilon(t, 50001, a.Count(), 0.03)
+
+	small.Merge(b)
+	require.InEpsilon(t, 30002, small.Count(), 0.03)
+}
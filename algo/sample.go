@@ -0,0 +1,54 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package algo
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// UidSample is a uniform random sample of n of the uids added to it. It's a reservoir sample, so
+// that the uids can be sampled while they are iterated over, without keeping all of them.
+type UidSample struct {
+	n    int
+	seen int
+	uids []uint64
+}
+
+// NewUidSample returns an empty sample of n uids.
+func NewUidSample(n int) *UidSample {
+	return &UidSample{n: n}
+}
+
+// Add adds the uid to the sample, in place of a sampled uid once the sample is full.
+func (s *UidSample) Add(uid uint64) {
+	s.seen++
+	if len(s.uids) < s.n {
+		s.uids = append(s.uids, uid)
+		return
+	}
+	if i := rand.Intn(s.seen); i < s.n {
+		s.uids[i] = uid
+	}
+}
+
+// Uids returns the sampled uids, sorted.
+func (s *UidSample) Uids() []uint64 {
+	sort.Slice(s.uids, func(i, j int) bool { return s.uids[i] < s.uids[j] })
+	return s.uids
+}
+
+// SampleUids returns a uniform random sample of n of the sorted uids, sorted.
+func SampleUids(uids []uint64, n int) []uint64 {
+	if len(uids) <= n {
+		return uids
+	}
+	s := NewUidSample(n)
+	for _, uid := range uids {
+		s.Add(uid)
+	}
+	return s.Uids()
+}
@@ -0,0 +1,43 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package algo
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleUids(t *testing.T) {
+	uids := make([]uint64, 100)
+	for i := range uids {
+		uids[i] = uint64(i + 1)
+	}
+	require.Equal(t, []uint64{1, 2, 3}, SampleUids([]uint64{1, 2, 3}, 5))
+
+	// Each uid is sampled about as often as the others.
+	counts := make(map[uint64]int)
+	for range 1000 {
+		sample := SampleUids(uids, 10)
+		require.Len(t, sample, 10)
+		require.True(t, sort.SliceIsSorted(sample, func(i, j int) bool {
+			return sample[i] < sample[j]
+		}))
+		seen := make(map[uint64]bool)
+		for _, uid := range sample {
+			require.False(t, seen[uid])
+			seen[uid] = true
+			counts[uid]++
+		}
+	}
+	require.Len(t, counts, 100)
+	for uid, count := range counts {
+		// Each uid is expected to be sampled 100 times.
+		require.Greater(t, count, 40, "uid %d", uid)
+		require.Less(t, count, 180, "uid %d", uid)
+	}
+}
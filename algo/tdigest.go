@@ -0,0 +1,135 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package algo
+
+import (
+	"math"
+	"sort"
+)
+
+// tdigestCompression bounds the number of centroids of a TDigest, and so its accuracy.
+const tdigestCompression = 100
+
+// TDigest approximates the quantiles of the values added to it, as described by Dunning and
+// Ertl in Computing Extremely Accurate Quantiles Using t-Digests:
+//
+// https://arxiv.org/abs/1902.04023
+//
+// The values are summarized by a few hundred centroids, smaller towards the extreme quantiles,
+// which are more accurate. Two TDigests can be merged, into the TDigest of the union of their
+// values.
+type TDigest struct {
+	// centroids are merged and sorted by mean, while buffer holds the values added since.
+	centroids []centroid
+	buffer    []centroid
+	count     float64
+	min, max  float64
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// NewTDigest returns an empty TDigest.
+func NewTDigest() *TDigest {
+	return &TDigest{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// Count returns the number of values added to the TDigest.
+func (t *TDigest) Count() int {
+	return int(t.count)
+}
+
+// Add adds the value to the TDigest.
+func (t *TDigest) Add(v float64) {
+	t.add(centroid{mean: v, weight: 1})
+}
+
+func (t *TDigest) add(c centroid) {
+	t.buffer = append(t.buffer, c)
+	t.count += c.weight
+	t.min = math.Min(t.min, c.mean)
+	t.max = math.Max(t.max, c.mean)
+	if len(t.buffer) >= 5*tdigestCompression {
+		t.compress()
+	}
+}
+
+// Merge adds the values of other to the TDigest.
+func (t *TDigest) Merge(other *TDigest) {
+	for _, c := range other.centroids {
+		t.add(c)
+	}
+	for _, c := range other.buffer {
+		t.add(c)
+	}
+	// The extremes of other are kept, although they may be merged into its centroids.
+	t.min = math.Min(t.min, other.min)
+	t.max = math.Max(t.max, other.max)
+}
+
+// compress merges the buffer into the centroids. The adjacent centroids are merged as long as the
+// merged centroid isn't heavier than 4*count*q*(1-q)/compression, q being its quantile.
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	var before float64
+	for _, c := range all[1:] {
+		q := (before + (cur.weight+c.weight)/2) / t.count
+		if cur.weight+c.weight <= math.Max(1, 4*t.count*q*(1-q)/tdigestCompression) {
+			cur.mean += (c.mean - cur.mean) * c.weight / (cur.weight + c.weight)
+			cur.weight += c.weight
+			continue
+		}
+		merged = append(merged, cur)
+		before += cur.weight
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+	t.buffer = nil
+}
+
+// Quantile returns the approximate value at the quantile q, between 0 and 1, of the values added
+// to the TDigest, interpolated between the means of its centroids. It returns NaN if the TDigest
+// is empty.
+func (t *TDigest) Quantile(q float64) float64 {
+	t.compress()
+	switch {
+	case t.count == 0:
+		return math.NaN()
+	case q <= 0:
+		return t.min
+	case q >= 1:
+		return t.max
+	}
+
+	target := q * t.count
+	// prevMean is at the quantile prevRank, starting from the minimum value.
+	prevMean, prevRank := t.min, 0.0
+	var before float64
+	for _, c := range t.centroids {
+		rank := before + c.weight/2
+		if target < rank {
+			if rank == prevRank {
+				return c.mean
+			}
+			return prevMean + (c.mean-prevMean)*(target-prevRank)/(rank-prevRank)
+		}
+		prevMean, prevRank = c.mean, rank
+		before += c.weight
+	}
+	if t.count == prevRank {
+		return t.max
+	}
+	return prevMean + (t.max-prevMean)*(target-prevRank)/(t.count-prevRank)
+}
@@ -0,0 +1,49 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package algo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTDigest(t *testing.T) {
+	td := NewTDigest()
+	require.True(t, math.IsNaN(td.Quantile(0.5)))
+	for _, v := range []float64{4, 1, 3, 2} {
+		td.Add(v)
+	}
+	require.Equal(t, 2.5, td.Quantile(0.5))
+	require.Equal(t, 1.0, td.Quantile(0))
+	require.Equal(t, 4.0, td.Quantile(1))
+
+	td = NewTDigest()
+	n := 100000
+	for _, i := range rand.Perm(n) {
+		td.Add(float64(i))
+	}
+	require.Equal(t, n, td.Count())
+	require.Less(t, len(td.centroids), 1000)
+	for _, q := range []float64{0.01, 0.25, 0.5, 0.95, 0.99} {
+		require.InDelta(t, q*float64(n), td.Quantile(q), 0.005*float64(n), "quantile %v", q)
+	}
+}
+
+func TestTDigestMerge(t *testing.T) {
+	a, b := NewTDigest(), NewTDigest()
+	for i := range 10000 {
+		a.Add(float64(i))
+		b.Add(float64(10000 + i))
+	}
+	a.Merge(b)
+	require.Equal(t, 20000, a.Count())
+	require.Equal(t, 19999.0, a.Quantile(1))
+	require.InDelta(t, 10000, a.Quantile(0.5), 100)
+	require.InDelta(t, 19000, a.Quantile(0.95), 100)
+}
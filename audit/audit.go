@@ -9,9 +9,12 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
 
 	"github.com/dgraph-io/ristretto/v2/z"
 	"github.com/hypermodeinc/dgraph/v25/worker"
@@ -22,6 +25,9 @@ const (
 	defaultAuditFilenameF = "%s_audit_%d_%d.log"
 	NodeTypeAlpha         = "alpha"
 	NodeTypeZero          = "zero"
+
+	// tsFormat is the format of the timestamps of the audit entries.
+	tsFormat = "2006-01-02T15:04:05.000Z0700"
 )
 
 var auditEnabled uint32
@@ -36,6 +42,11 @@ type AuditEvent struct {
 	Req         string
 	Status      string
 	QueryParams map[string][]string
+	Op          string
+	Predicates  []string
+	Latency     time.Duration
+	ReqBytes    int
+	RespBytes   int
 }
 
 const (
@@ -50,8 +61,12 @@ const (
 
 var auditor = &auditLogger{}
 
+// auditLogger writes the audit events to the default sink, or to the sink of the namespace of
+// the event if it has a route.
 type auditLogger struct {
-	log *x.Logger
+	sync.RWMutex
+	def    *sink
+	routes map[uint64]*sink
 }
 
 func GetAuditConf(conf string) *x.LoggerConf {
@@ -66,6 +81,8 @@ func GetAuditConf(conf string) *x.LoggerConf {
 	x.AssertTruef(out != "", "out flag is not provided for the audit logs")
 	encBytes, err := readAuditEncKey(auditFlag)
 	x.Check(err)
+	routes, err := parseRoutes(auditFlag.GetString("routes"))
+	x.Check(err)
 	return &x.LoggerConf{
 		Compress:      auditFlag.GetBool("compress"),
 		Output:        out,
@@ -73,6 +90,7 @@ func GetAuditConf(conf string) *x.LoggerConf {
 		Days:          auditFlag.GetInt64("days"),
 		Size:          auditFlag.GetInt64("size"),
 		MessageKey:    "endpoint",
+		Routes:        routes,
 	}
 }
 
@@ -102,11 +120,24 @@ func InitAuditor(conf *x.LoggerConf, gId, nId uint64) error {
 	if gId == 0 {
 		ntype = NodeTypeZero
 	}
-	var err error
 	filename := fmt.Sprintf(defaultAuditFilenameF, ntype, gId, nId)
-	if auditor.log, err = x.InitLogger(conf, filename); err != nil {
+	def, err := newSink(conf, conf.Output, filename)
+	if err != nil {
 		return err
 	}
+	routes := make(map[uint64]*sink, len(conf.Routes))
+	for ns, dest := range conf.Routes {
+		s, err := newSink(conf, dest, filename)
+		if err != nil {
+			closeSinks(def, routes)
+			return errors.Wrapf(err, "while opening audit sink of namespace %d", ns)
+		}
+		routes[ns] = s
+	}
+
+	auditor.Lock()
+	auditor.def, auditor.routes = def, routes
+	auditor.Unlock()
 	atomic.StoreUint32(&auditEnabled, 1)
 	glog.Infoln("audit logs are enabled")
 	return nil
@@ -119,20 +150,55 @@ func Close() {
 	if atomic.LoadUint32(&auditEnabled) == 0 {
 		return
 	}
-	auditor.log.Sync()
-	auditor.log = nil
+	atomic.StoreUint32(&auditEnabled, 0)
+	auditor.Lock()
+	closeSinks(auditor.def, auditor.routes)
+	auditor.def, auditor.routes = nil, nil
+	auditor.Unlock()
 	glog.Infoln("audit logs are closed.")
 }
 
+func closeSinks(def *sink, routes map[uint64]*sink) {
+	sinks := []*sink{def}
+	for _, s := range routes {
+		sinks = append(sinks, s)
+	}
+	for _, s := range sinks {
+		if err := s.close(); err != nil {
+			glog.Warningf("error closing audit sink %s: %v", s.name, err)
+		}
+	}
+}
+
 func (a *auditLogger) Audit(event *AuditEvent) {
-	a.log.AuditI(event.Endpoint,
-		"level", "AUDIT",
-		"user", event.User,
-		"namespace", event.Namespace,
-		"server", event.ServerHost,
-		"client", event.ClientHost,
-		"req_type", event.ReqType,
-		"req_body", event.Req,
-		"query_param", event.QueryParams,
-		"status", event.Status)
+	a.RLock()
+	defer a.RUnlock()
+	s, ok := a.routes[event.Namespace]
+	if !ok {
+		s = a.def
+	}
+	if s == nil {
+		return
+	}
+	err := s.write(&entry{
+		Ts:          time.Now().Format(tsFormat),
+		Endpoint:    event.Endpoint,
+		Level:       "AUDIT",
+		User:        event.User,
+		Namespace:   event.Namespace,
+		Server:      event.ServerHost,
+		Client:      event.ClientHost,
+		ReqType:     event.ReqType,
+		ReqBody:     event.Req,
+		QueryParams: event.QueryParams,
+		Status:      event.Status,
+		Op:          event.Op,
+		Predicates:  event.Predicates,
+		LatencyMs:   float64(event.Latency) / float64(time.Millisecond),
+		ReqBytes:    event.ReqBytes,
+		RespBytes:   event.RespBytes,
+	})
+	if err != nil {
+		glog.Errorf("Unable to write audit event: %v", err)
+	}
 }
@@ -14,10 +14,12 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/dgraph-io/gqlparser/v2/ast"
 	"github.com/dgraph-io/gqlparser/v2/parser"
@@ -31,6 +33,7 @@ import (
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -71,8 +74,10 @@ func AuditRequestGRPC(ctx context.Context, req interface{},
 	if atomic.LoadUint32(&auditEnabled) == 0 || skip(info.FullMethod) {
 		return handler(ctx, req)
 	}
+	start := time.Now()
+	ctx, t := withTrail(ctx)
 	response, err := handler(ctx, req)
-	auditGrpc(ctx, req, info)
+	auditGrpc(ctx, req, response, info, t, time.Since(start))
 	return response, err
 }
 
@@ -99,13 +104,16 @@ func AuditRequestHttp(next http.Handler) http.Handler {
 			}
 		}
 
+		start := time.Now()
+		ctx, t := withTrail(r.Context())
+		r = r.WithContext(ctx)
 		rw := NewResponseWriter(w)
 		var buf bytes.Buffer
 		tee := io.TeeReader(r.Body, &buf)
 		r.Body = io.NopCloser(tee)
 		next.ServeHTTP(rw, r)
 		r.Body = io.NopCloser(bytes.NewReader(buf.Bytes()))
-		auditHttp(rw, r)
+		auditHttp(rw, r, t, time.Since(start))
 	})
 }
 
@@ -145,10 +153,13 @@ func AuditWebSockets(ctx context.Context, req *schema.Request) {
 		Req:         truncate(req.Query, maxReqLength),
 		Status:      http.StatusText(http.StatusOK),
 		QueryParams: nil,
+		Op:          "subscription",
+		ReqBytes:    len(req.Query),
 	})
 }
 
-func auditGrpc(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo) {
+func auditGrpc(ctx context.Context, req, resp interface{}, info *grpc.UnaryServerInfo, t *trail,
+	latency time.Duration) {
 	clientHost := ""
 	if p, ok := peer.FromContext(ctx); ok {
 		clientHost = p.Addr.String()
@@ -187,8 +198,12 @@ func auditGrpc(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo)
 		cd = serr.Code()
 	}
 
-	reqBody := checkRequestBody(Grpc, info.FullMethod[strings.LastIndex(info.FullMethod,
-		"/")+1:], fmt.Sprintf("%+v", req))
+	method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+	reqBody := checkRequestBody(Grpc, method, fmt.Sprintf("%+v", req))
+	op, preds := t.get()
+	if op == "" {
+		op = strings.ToLower(method)
+	}
 	auditor.Audit(&AuditEvent{
 		User:       user,
 		Namespace:  namespace,
@@ -198,11 +213,27 @@ func auditGrpc(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo)
 		ReqType:    Grpc,
 		Req:        truncate(reqBody, maxReqLength),
 		Status:     cd.String(),
+		Op:         op,
+		Predicates: preds,
+		Latency:    latency,
+		ReqBytes:   protoSize(req),
+		RespBytes:  protoSize(resp),
 	})
 }
 
-func auditHttp(w *ResponseWriter, r *http.Request) {
+func protoSize(m interface{}) int {
+	if msg, ok := m.(proto.Message); ok {
+		return proto.Size(msg)
+	}
+	return 0
+}
+
+func auditHttp(w *ResponseWriter, r *http.Request, t *trail, latency time.Duration) {
 	body := getRequestBody(r)
+	op, preds := t.get()
+	if op == "" {
+		op = strings.TrimPrefix(path.Base(r.URL.Path), "/")
+	}
 	var user string
 	if token := r.Header.Get("X-Dgraph-AccessToken"); token != "" {
 		user = getUser(token, false)
@@ -222,6 +253,11 @@ func auditHttp(w *ResponseWriter, r *http.Request) {
 		Req:         truncate(checkRequestBody(Http, r.URL.Path, string(body)), maxReqLength),
 		Status:      http.StatusText(w.statusCode),
 		QueryParams: r.URL.Query(),
+		Op:          op,
+		Predicates:  preds,
+		Latency:     latency,
+		ReqBytes:    len(body),
+		RespBytes:   w.bytes,
 	})
 }
 
@@ -372,12 +408,13 @@ func getUser(token string, poorman bool) string {
 type ResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int
 }
 
 func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
 	// WriteHeader(int) is not called if our response implicitly returns 200 OK, so
 	// we default to that status code.
-	return &ResponseWriter{w, http.StatusOK}
+	return &ResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 }
 
 func (rw *ResponseWriter) WriteHeader(code int) {
@@ -385,6 +422,12 @@ func (rw *ResponseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
 func truncate(s string, l int) string {
 	if len(s) > l {
 		return s[:l]
@@ -65,7 +65,49 @@ func initSubcommands() []*x.SubCommand {
 	decFlags.String("out", "audit_log_out.log",
 		"output file to which decrypted output will be dumped.")
 	decFlags.String("encryption_key_file", "", "path to encrypt files.")
-	return []*x.SubCommand{&decryptCmd}
+
+	verifyCmd.Cmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Run Dgraph Audit tool to verify the hash chain of audit files",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runVerify(); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	verFlags := verifyCmd.Cmd.Flags()
+	verFlags.StringSlice("in", nil, "comma separated list of decrypted audit files to verify, "+
+		"in the order in which they were written.")
+	return []*x.SubCommand{&decryptCmd, &verifyCmd}
+}
+
+var verifyCmd x.SubCommand
+
+func runVerify() error {
+	files := verifyCmd.Conf.GetStringSlice("in")
+	if len(files) == 0 {
+		return errors.New("no audit files provided")
+	}
+	var readers []io.Reader
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				glog.Warningf("error closing file: %v", err)
+			}
+		}()
+		readers = append(readers, f)
+	}
+	n, err := VerifyChain(io.MultiReader(readers...))
+	if err != nil {
+		return fmt.Errorf("audit log verification failed: %w", err)
+	}
+	fmt.Printf("verified the hash chain of %d audit entries\n", n)
+	return nil
 }
 
 func run() error {
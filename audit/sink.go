@@ -0,0 +1,320 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// hashField is the last field of every audit entry. It holds the hash of the entry up to
+	// it, which includes the hash of the previous entry, so that the entries form a chain.
+	hashField = `,"hash":"`
+
+	webhookBatchSize = 100
+	webhookQueueSize = 10000
+	webhookTimeout   = 10 * time.Second
+)
+
+// entry is an audit event as written to the sinks. The order of its fields is the order in
+// which they are written.
+type entry struct {
+	Ts          string              `json:"ts"`
+	Endpoint    string              `json:"endpoint"`
+	Level       string              `json:"level"`
+	User        string              `json:"user"`
+	Namespace   uint64              `json:"namespace"`
+	Server      string              `json:"server"`
+	Client      string              `json:"client"`
+	ReqType     string              `json:"req_type"`
+	ReqBody     string              `json:"req_body"`
+	QueryParams map[string][]string `json:"query_param"`
+	Status      string              `json:"status"`
+	Op          string              `json:"op,omitempty"`
+	Predicates  []string            `json:"predicates,omitempty"`
+	LatencyMs   float64             `json:"latency_ms"`
+	ReqBytes    int                 `json:"req_bytes"`
+	RespBytes   int                 `json:"resp_bytes"`
+	Seq         uint64              `json:"seq"`
+	PrevHash    string              `json:"prev_hash"`
+}
+
+// sink writes the chained audit entries to a destination. Each sink has its own chain, which
+// starts again with seq 1 and an empty prev_hash when the process restarts.
+type sink struct {
+	name string
+	w    io.WriteCloser
+
+	sync.Mutex
+	seq  uint64
+	prev string
+}
+
+func (s *sink) write(e *entry) error {
+	s.Lock()
+	defer s.Unlock()
+	e.Seq = s.seq + 1
+	e.PrevHash = s.prev
+	line, hash, err := chainEntry(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(line); err != nil {
+		return errors.Wrapf(err, "while writing audit entry to %s", s.name)
+	}
+	s.seq, s.prev = e.Seq, hash
+	return nil
+}
+
+func (s *sink) close() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.w.Close()
+}
+
+// chainEntry encodes the entry, and appends its hash to it.
+func chainEntry(e *entry) ([]byte, string, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, "", err
+	}
+	// Drop the closing brace to append the hash field.
+	data = data[:len(data)-1]
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	data = append(data, hashField...)
+	data = append(data, hash...)
+	data = append(data, "\"}\n"...)
+	return data, hash, nil
+}
+
+// VerifyChain checks that the audit entries read from r form an unbroken hash chain, and
+// returns the number of entries verified. An entry with seq 1 starts a new chain, which happens
+// whenever the node restarts. The entries of rotated files need to be given in order.
+func VerifyChain(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	var prev string
+	var seq uint64
+	n := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		n++
+		idx := bytes.LastIndex(line, []byte(hashField))
+		if idx < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+			return n, errors.Errorf("entry %d: hash not found", n)
+		}
+		hash := string(line[idx+len(hashField) : len(line)-2])
+		sum := sha256.Sum256(line[:idx])
+		if hex.EncodeToString(sum[:]) != hash {
+			return n, errors.Errorf("entry %d: hash mismatch, the entry was modified", n)
+		}
+
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return n, errors.Wrapf(err, "entry %d", n)
+		}
+		switch {
+		case e.Seq == 1 && e.PrevHash == "":
+		case e.Seq == seq+1 && e.PrevHash == prev:
+		default:
+			return n, errors.Errorf("entry %d: chain is broken at seq %d, expected seq %d "+
+				"following hash %s", n, e.Seq, seq+1, prev)
+		}
+		seq, prev = e.Seq, hash
+	}
+	return n, scanner.Err()
+}
+
+// parseRoutes parses the routes option of the audit flag, a comma separated list of
+// <namespace>:<sink> where the sink is one of
+//
+//	file:<dir>             write to a file in the directory, rotated like the default output.
+//	syslog[:<proto>://<host:port>] send to the local or a remote syslog daemon.
+//	webhook:<url>          POST batches of newline delimited entries to the url.
+func parseRoutes(routes string) (map[uint64]string, error) {
+	res := make(map[uint64]string)
+	for _, route := range strings.Split(routes, ",") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+		parts := strings.SplitN(route, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid audit route %q, expected <namespace>:<sink>", route)
+		}
+		var ns uint64
+		if _, err := fmt.Sscanf(parts[0], "%d", &ns); err != nil {
+			return nil, errors.Wrapf(err, "invalid namespace in audit route %q", route)
+		}
+		if _, ok := res[ns]; ok {
+			return nil, errors.Errorf("namespace %d has more than one audit route", ns)
+		}
+		kind := strings.SplitN(parts[1], ":", 2)[0]
+		if kind != "file" && kind != "syslog" && kind != "webhook" {
+			return nil, errors.Errorf("invalid sink %q in audit route %q, must be one of file, "+
+				"syslog or webhook", kind, route)
+		}
+		res[ns] = parts[1]
+	}
+	return res, nil
+}
+
+// newSink opens the sink for the given destination. The destination is either stdout, a
+// directory, or one of the sinks of a route.
+func newSink(conf *x.LoggerConf, dest, filename string) (*sink, error) {
+	kind, arg, _ := strings.Cut(dest, ":")
+	var w io.WriteCloser
+	var err error
+	switch kind {
+	case "stdout":
+		w = nopCloser{os.Stdout}
+	case "syslog":
+		w, err = newSyslogWriter(arg)
+	case "webhook":
+		w, err = newWebhookWriter(arg)
+	case "file":
+		w, err = newFileWriter(conf, arg, filename)
+	default:
+		w, err = newFileWriter(conf, dest, filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sink{name: dest, w: w}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func newFileWriter(conf *x.LoggerConf, dir, filename string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if conf.EncryptionKey != nil {
+		filename = filename + ".enc"
+	}
+	path, err := filepath.Abs(filepath.Join(dir, filename))
+	if err != nil {
+		return nil, err
+	}
+	w := &x.LogWriter{
+		FilePath:      path,
+		MaxSize:       conf.Size,
+		MaxAge:        conf.Days,
+		EncryptionKey: conf.EncryptionKey,
+		Compress:      conf.Compress,
+	}
+	return w.Init()
+}
+
+func newSyslogWriter(addr string) (io.WriteCloser, error) {
+	var network, raddr string
+	if addr != "" {
+		u, err := url.Parse(addr)
+		if err != nil || u.Host == "" {
+			return nil, errors.Errorf("invalid syslog address %q, expected <proto>://<host:port>",
+				addr)
+		}
+		network, raddr = u.Scheme, u.Host
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_AUTH, "dgraph-audit")
+	return w, errors.Wrapf(err, "while connecting to syslog")
+}
+
+// webhookWriter posts the entries to a url in batches, in the background so that requests
+// aren't slowed down by the webhook. Entries are dropped if the webhook can't keep up.
+type webhookWriter struct {
+	url    string
+	client *http.Client
+	queue  chan []byte
+	done   chan struct{}
+}
+
+func newWebhookWriter(addr string) (io.WriteCloser, error) {
+	if u, err := url.Parse(addr); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.Errorf("invalid webhook url %q", addr)
+	}
+	w := &webhookWriter{
+		url:    addr,
+		client: &http.Client{Timeout: webhookTimeout},
+		queue:  make(chan []byte, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *webhookWriter) Write(p []byte) (int, error) {
+	select {
+	case w.queue <- append([]byte(nil), p...):
+	default:
+		glog.Warningf("Audit webhook %s is not keeping up, dropping entry", w.url)
+	}
+	return len(p), nil
+}
+
+func (w *webhookWriter) Close() error {
+	close(w.queue)
+	<-w.done
+	return nil
+}
+
+func (w *webhookWriter) run() {
+	defer close(w.done)
+	var batch bytes.Buffer
+	for line := range w.queue {
+		batch.Write(line)
+		// Add whatever else is queued to the batch.
+		for i := 1; i < webhookBatchSize && len(w.queue) > 0; i++ {
+			batch.Write(<-w.queue)
+		}
+		if err := w.post(batch.Bytes()); err != nil {
+			glog.Errorf("Unable to send audit entries to webhook %s: %v", w.url, err)
+		}
+		batch.Reset()
+	}
+}
+
+func (w *webhookWriter) post(body []byte) error {
+	resp, err := w.client.Post(w.url, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("got status %s", resp.Status)
+	}
+	return nil
+}
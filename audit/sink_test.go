@@ -0,0 +1,142 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestVerifyChain(t *testing.T) {
+	var buf bytes.Buffer
+	s := &sink{name: "test", w: nopCloser{&buf}}
+	for _, user := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, s.write(&entry{User: user, Endpoint: "/query"}))
+	}
+	// A restart starts a new chain.
+	s = &sink{name: "test", w: nopCloser{&buf}}
+	require.NoError(t, s.write(&entry{User: "dave", Endpoint: "/query"}))
+
+	n, err := VerifyChain(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	tampered := strings.Join(lines, "")
+	tampered = strings.Replace(tampered, `"user":"bob"`, `"user":"eve"`, 1)
+	_, err = VerifyChain(strings.NewReader(tampered))
+	require.ErrorContains(t, err, "entry 2: hash mismatch")
+
+	removed := lines[0] + lines[2] + lines[3]
+	_, err = VerifyChain(strings.NewReader(removed))
+	require.ErrorContains(t, err, "entry 2: chain is broken")
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := parseRoutes("1:file:/tmp/audit, 2:syslog, 3:webhook:https://siem/audit?a=b")
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]string{
+		1: "file:/tmp/audit",
+		2: "syslog",
+		3: "webhook:https://siem/audit?a=b",
+	}, routes)
+
+	_, err = parseRoutes("1:kafka:topic")
+	require.ErrorContains(t, err, "invalid sink")
+	_, err = parseRoutes("ns:file:/tmp")
+	require.ErrorContains(t, err, "invalid namespace")
+	_, err = parseRoutes("1:file:/a,1:file:/b")
+	require.ErrorContains(t, err, "more than one audit route")
+}
+
+func TestAuditRoutes(t *testing.T) {
+	var mu sync.Mutex
+	var posted []entry
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, line := range bytes.Split(bytes.TrimSpace(body), []byte("\n")) {
+			var e entry
+			require.NoError(t, json.Unmarshal(line, &e))
+			posted = append(posted, e)
+		}
+	}))
+	defer srv.Close()
+
+	defDir, nsDir := t.TempDir(), t.TempDir()
+	conf := &x.LoggerConf{
+		Output: defDir,
+		Size:   100,
+		Days:   10,
+		Routes: map[uint64]string{1: "file:" + nsDir, 2: "webhook:" + srv.URL},
+	}
+	require.NoError(t, InitAuditor(conf, 1, 1))
+	for _, ns := range []uint64{0, 1, 1, 2} {
+		auditor.Audit(&AuditEvent{Namespace: ns, Endpoint: "/query", Op: "query",
+			Predicates: []string{"name"}})
+	}
+	Close()
+
+	readLog := func(dir string) []entry {
+		data, err := os.ReadFile(filepath.Join(dir, "alpha_audit_1_1.log"))
+		require.NoError(t, err)
+		n, err := VerifyChain(bytes.NewReader(data))
+		require.NoError(t, err)
+		var entries []entry
+		for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+			var e entry
+			require.NoError(t, json.Unmarshal(line, &e))
+			entries = append(entries, e)
+		}
+		require.Len(t, entries, n)
+		return entries
+	}
+	def := readLog(defDir)
+	require.Len(t, def, 1)
+	require.Equal(t, uint64(0), def[0].Namespace)
+	require.Equal(t, []string{"name"}, def[0].Predicates)
+	ns := readLog(nsDir)
+	require.Len(t, ns, 2)
+	require.Equal(t, uint64(2), ns[1].Seq)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, posted, 1)
+	require.Equal(t, uint64(2), posted[0].Namespace)
+}
+
+func TestTrail(t *testing.T) {
+	ctx := context.Background()
+	require.False(t, Enabled(ctx))
+	// Recording without a trail is a no-op.
+	SetOp(ctx, "query")
+	AddPredicates(ctx, "name")
+
+	ctx, tr := withTrail(ctx)
+	require.True(t, Enabled(ctx))
+	SetOp(ctx, "mutation")
+	SetOp(ctx, "query")
+	AddPredicates(ctx, "name", "age")
+	AddPredicates(ctx, "age")
+	op, preds := tr.get()
+	require.Equal(t, "mutation", op)
+	require.Equal(t, []string{"age", "name"}, preds)
+}
@@ -0,0 +1,75 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package audit
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type trailKey struct{}
+
+// trail collects what a request did while it is being served, i.e. the operation and the
+// predicates it touched, so that they can be added to its audit event.
+type trail struct {
+	sync.Mutex
+	op    string
+	preds map[string]struct{}
+}
+
+func withTrail(ctx context.Context) (context.Context, *trail) {
+	t := &trail{preds: make(map[string]struct{})}
+	return context.WithValue(ctx, trailKey{}, t), t
+}
+
+// SetOp sets the operation of the request being audited, like query, mutation or alter. Only
+// the first operation is kept, because a request can run other operations internally.
+func SetOp(ctx context.Context, op string) {
+	t, ok := ctx.Value(trailKey{}).(*trail)
+	if !ok {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	if t.op == "" {
+		t.op = op
+	}
+}
+
+// AddPredicates adds the predicates touched by the request being audited.
+func AddPredicates(ctx context.Context, preds ...string) {
+	t, ok := ctx.Value(trailKey{}).(*trail)
+	if !ok {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	for _, pred := range preds {
+		t.preds[pred] = struct{}{}
+	}
+}
+
+// Enabled returns true if the request is being audited. It can be used to avoid collecting
+// the predicates of requests which aren't.
+func Enabled(ctx context.Context) bool {
+	_, ok := ctx.Value(trailKey{}).(*trail)
+	return ok
+}
+
+func (t *trail) get() (string, []string) {
+	if t == nil {
+		return "", nil
+	}
+	t.Lock()
+	defer t.Unlock()
+	preds := make([]string, 0, len(t.preds))
+	for pred := range t.preds {
+		preds = append(preds, pred)
+	}
+	sort.Strings(preds)
+	return t.op, preds
+}
@@ -202,6 +202,12 @@ func handleBasicType(k string, v interface{}, op int, nq *api.NQuad) error {
 		}
 		i, err := v.Int64()
 		if err != nil {
+			// The integers above the int64 range are kept as their decimal string, which is
+			// converted to uint64 by the schema of the predicate.
+			if _, uerr := strconv.ParseUint(v.String(), 10, 64); uerr == nil {
+				nq.ObjectValue = &api.Value{Val: &api.Value_DefaultVal{DefaultVal: v.String()}}
+				return nil
+			}
 			return err
 		}
 		nq.ObjectValue = &api.Value{Val: &api.Value_IntVal{IntVal: i}}
@@ -214,6 +220,11 @@ func handleBasicType(k string, v interface{}, op int, nq *api.NQuad) error {
 		}
 		nq.ObjectValue = &api.Value{Val: &api.Value_IntVal{IntVal: v}}
 
+	// FastParseJSON gives the integers above the int64 range as uint64.
+	case uint64:
+		nq.ObjectValue = &api.Value{Val: &api.Value_DefaultVal{
+			DefaultVal: strconv.FormatUint(v, 10)}}
+
 	case string:
 		// Default value is considered as S P * deletion.
 		if v == "" && op == DeleteNquads {
@@ -524,8 +535,9 @@ func (buf *NQuadBuffer) mapToNquads(m map[string]interface{}, op int, parentPred
 		nq.Predicate, nq.Lang = x.PredicateLang(nq.Predicate)
 
 		switch v := v.(type) {
-		// these int64/float64 cases are needed for FastParseJSON, which doesn't use json.Number
-		case int64, float64:
+		// these int64/uint64/float64 cases are needed for FastParseJSON, which doesn't use
+		// json.Number
+		case int64, uint64, float64:
 			if err := handleBasicType(pred, v, op, &nq); err != nil {
 				return mr, err
 			}
@@ -571,7 +583,7 @@ func (buf *NQuadBuffer) mapToNquads(m map[string]interface{}, op int, parentPred
 				if idx == 0 {
 					// determine if this is a scalar list
 					switch item.(type) {
-					case string, float64, json.Number, int64:
+					case string, float64, json.Number, int64, uint64:
 						var err error
 						facetsMapSlice, err = parseMapFacets(mr.rawFacets, prefix)
 						if err != nil {
@@ -589,7 +601,7 @@ func (buf *NQuadBuffer) mapToNquads(m map[string]interface{}, op int, parentPred
 				}
 
 				switch iv := item.(type) {
-				case string, float64, json.Number, int64:
+				case string, float64, json.Number, int64, uint64:
 					if err := handleBasicType(pred, iv, op, &nq); err != nil {
 						return mr, err
 					}
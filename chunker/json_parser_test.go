@@ -405,6 +405,8 @@ func TestJsonNumberParsing(t *testing.T) {
 		{`{"uid": "1", "key": 9223372036854775299.0}`,
 			&api.Value{Val: &api.Value_DoubleVal{DoubleVal: 9223372036854775299.0}}},
 		{`{"uid": "1", "key": 27670116110564327426}`, nil},
+		{`{"uid": "1", "key": 18446744073709551615}`,
+			&api.Value{Val: &api.Value_DefaultVal{DefaultVal: "18446744073709551615"}}},
 		{`{"uid": "1", "key": "23452786"}`, &api.Value{Val: &api.Value_StrVal{StrVal: "23452786"}}},
 		{`{"uid": "1", "key": "23452786.2378"}`, &api.Value{Val: &api.Value_StrVal{StrVal: "23452786.2378"}}},
 		{`{"uid": "1", "key": -1e10}`, &api.Value{Val: &api.Value_DoubleVal{DoubleVal: -1e+10}}},
@@ -357,6 +357,8 @@ var typeMap = map[string]types.TypeID{
 	"xs:decimal":         types.BigFloatID,
 	"geo:geojson":        types.GeoID,
 	"xs:[]float32":       types.VFloatID,
+	"xs:unsignedLong":    types.Uint64ID,
+	"xs:bytes":           types.BytesID,
 	"http://www.w3.org/2001/XMLSchema#string":          types.StringID,
 	"http://www.w3.org/2001/XMLSchema#dateTime":        types.DateTimeID,
 	"http://www.w3.org/2001/XMLSchema#date":            types.DateTimeID,
@@ -368,4 +370,5 @@ var typeMap = map[string]types.TypeID{
 	"http://www.w3.org/2001/XMLSchema#float":           types.FloatID,
 	"http://www.w3.org/2001/XMLSchema#gYear":           types.DateTimeID,
 	"http://www.w3.org/2001/XMLSchema#gYearMonth":      types.DateTimeID,
+	"http://www.w3.org/2001/XMLSchema#unsignedLong":    types.Uint64ID,
 }
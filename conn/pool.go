@@ -165,6 +165,9 @@ func newPool(addr string, tlsClientConf *tls.Config) (*Pool, error) {
 			grpc.MaxCallSendMsgSize(x.GrpcMaxSize),
 			grpc.UseCompressor((snappyCompressor{}).Name())),
 		grpc.WithBackoffMaxDelay(time.Second),
+		grpc.WithChainUnaryInterceptor(x.RequestIdUnaryClientInterceptor,
+			x.ExperimentalPathsUnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(x.RequestIdStreamClientInterceptor),
 	}
 
 	if tlsClientConf != nil {
@@ -219,10 +222,18 @@ func (p *Pool) listenToHeartbeat() error {
 	ctx, cancel := context.WithCancel(p.closer.Ctx())
 	defer cancel()
 
-	s, err := c.Heartbeat(ctx, &api.Payload{})
+	s, err := c.Heartbeat(x.WithHandshake(ctx), &api.Payload{})
 	if err != nil {
 		return err
 	}
+	md, err := s.Header()
+	if err != nil {
+		return err
+	}
+	if err := x.CheckHandshake(md, p.Addr); err != nil {
+		glog.Errorf("CONN: Not sending requests to %s: %v", p.Addr, err)
+		return err
+	}
 
 	go func() {
 		for {
@@ -19,6 +19,8 @@ import (
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/raft/v3/raftpb"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 
 	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
@@ -287,6 +289,19 @@ func (w *RaftServer) Heartbeat(_ *api.Payload, stream pb.Raft_HeartbeatServer) e
 	}
 
 	ctx := stream.Context()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		var addr string
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+		if err := x.CheckHandshake(md, addr); err != nil {
+			glog.Errorf("Rejecting the heartbeats of %s: %v", addr, err)
+			return err
+		}
+	}
+	if err := stream.SendHeader(x.HandshakeMetadata()); err != nil {
+		return err
+	}
 
 	for {
 		info.Uptime = int64(time.Since(node.StartTime) / time.Second)
@@ -0,0 +1,83 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package conn
+
+import (
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+var zstdWriterPool sync.Pool
+var zstdReaderPool sync.Pool
+
+type zstdWriter struct {
+	*zstd.Encoder
+}
+
+func (w *zstdWriter) Close() error {
+	defer zstdWriterPool.Put(w)
+	return w.Encoder.Close()
+}
+
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+func (r *zstdReader) Read(p []byte) (n int, err error) {
+	n, err = r.Decoder.Read(p)
+	if err == io.EOF {
+		zstdReaderPool.Put(r)
+	}
+	return n, err
+}
+
+// zstdCompressor lets the gRPC clients ask for zstd, with grpc.UseCompressor("zstd"). The
+// responses are then compressed with zstd too, at the level set with --compression. Like for
+// snappy, the writers and readers are pooled, as the compressor is shared by all the streams.
+type zstdCompressor struct {
+}
+
+func (zstdCompressor) Name() string {
+	return "zstd"
+}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	zw, ok := zstdWriterPool.Get().(*zstdWriter)
+	if !ok {
+		enc, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1),
+			zstd.WithEncoderLevel(x.ZstdLevel()))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdWriter{enc}, nil
+	}
+	zw.Reset(w)
+	return zw, nil
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	zr, ok := zstdReaderPool.Get().(*zstdReader)
+	if !ok {
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdReader{dec}, nil
+	}
+	if err := zr.Reset(r); err != nil {
+		return nil, err
+	}
+	return zr, nil
+}
+
+func init() {
+	encoding.RegisterCompressor(zstdCompressor{})
+}
@@ -6,15 +6,21 @@
 package alpha
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
 	"github.com/hypermodeinc/dgraph/v25/graphql/admin"
 	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+	"github.com/hypermodeinc/dgraph/v25/posting"
 	"github.com/hypermodeinc/dgraph/v25/worker"
 	"github.com/hypermodeinc/dgraph/v25/x"
 )
@@ -61,6 +67,33 @@ func adminAuthHandler(next http.Handler) http.Handler {
 	})
 }
 
+// requestSigningHandler verifies the signatures of the requests to the signed endpoints, if the
+// requests are signed. The requests whose signature is invalid or that are replayed are rejected
+// with a 401 status, and the requests whose body is too large with a 413 status.
+func requestSigningHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signer := worker.Config.RequestSigner
+		if signer == nil || r.Method == http.MethodOptions || !signer.IsSigned(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := signer.Verify(w, r); err != nil {
+			status := http.StatusUnauthorized
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			x.AddCorsHeaders(w)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			x.SetStatus(w, x.ErrorUnauthorized, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func getAdminMux() *http.ServeMux {
 	adminMux := http.NewServeMux()
 	adminMux.Handle("/admin/schema", adminAuthHandler(http.HandlerFunc(adminSchemaHandler)))
@@ -75,9 +108,340 @@ func getAdminMux() *http.ServeMux {
 		http.MethodGet: true,
 		http.MethodPut: true,
 	}, adminAuthHandler(http.HandlerFunc(memoryLimitHandler))))
+	adminMux.Handle("/admin/transactions", allowedMethodsHandler(allowedMethods{
+		http.MethodGet: true,
+	}, adminAuthHandler(http.HandlerFunc(transactionsHandler))))
+	adminMux.Handle("/admin/standing", allowedMethodsHandler(allowedMethods{
+		http.MethodGet: true,
+	}, adminAuthHandler(http.HandlerFunc(standingQueryHandler))))
+	adminMux.Handle("/admin/snapshots", allowedMethodsHandler(allowedMethods{
+		http.MethodGet:    true,
+		http.MethodPost:   true,
+		http.MethodDelete: true,
+	}, adminAuthHandler(http.HandlerFunc(snapshotHandlesHandler))))
+	adminMux.Handle("/admin/report/drift", allowedMethodsHandler(allowedMethods{
+		http.MethodGet: true,
+	}, adminAuthHandler(http.HandlerFunc(driftReportHandler))))
+	adminMux.Handle("/admin/fsck", allowedMethodsHandler(allowedMethods{
+		http.MethodGet: true,
+	}, adminAuthHandler(http.HandlerFunc(fsckHandler))))
+	adminMux.Handle("/admin/tiering", allowedMethodsHandler(allowedMethods{
+		http.MethodGet:  true,
+		http.MethodPost: true,
+	}, adminAuthHandler(http.HandlerFunc(tieringHandler))))
+	adminMux.Handle("/admin/compression/dict", allowedMethodsHandler(allowedMethods{
+		http.MethodPost: true,
+	}, adminAuthHandler(http.HandlerFunc(compressionDictHandler))))
+	adminMux.Handle("/admin/disk/usage", allowedMethodsHandler(allowedMethods{
+		http.MethodGet: true,
+	}, adminAuthHandler(http.HandlerFunc(diskUsageHandler))))
+	adminMux.Handle("/admin/debug/key", allowedMethodsHandler(allowedMethods{
+		http.MethodGet: true,
+	}, adminAuthHandler(http.HandlerFunc(debugKeyHandler))))
 	return adminMux
 }
 
+// defaultDictSamples is the number of nodes whose values a zstd dictionary is trained on,
+// unless the samples parameter says otherwise.
+const defaultDictSamples = 10000
+
+// defaultDriftSample is the number of nodes checked per predicate and per type by the schema
+// drift report, unless the sample parameter says otherwise.
+const defaultDriftSample = 1000
+
+// driftReportHandler reports the drift of the data of the namespace of the request from its
+// schema, over the tablets served by the group of this Alpha. The sample parameter is the number
+// of nodes checked per predicate and per type, 0 scans the tablets fully.
+func driftReportHandler(w http.ResponseWriter, r *http.Request) {
+	sample := defaultDriftSample
+	if s := r.URL.Query().Get("sample"); s != "" {
+		var err error
+		if sample, err = strconv.Atoi(s); err != nil || sample < 0 {
+			x.SetStatus(w, x.ErrorInvalidRequest, "Invalid sample size of the drift report")
+			return
+		}
+	}
+	report, err := worker.SchemaDriftReport(r.Context(), x.ExtractNamespaceHTTP(r), sample)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	js, err := json.Marshal(report)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.Check2(w.Write(js))
+}
+
+// transactionsHandler reports the pending transactions of the group of this Alpha.
+func transactionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	js, err := json.Marshal(struct {
+		GroupId      uint32             `json:"groupId"`
+		IdleTimeout  string             `json:"idleTimeout"`
+		Transactions []worker.TxnStatus `json:"transactions"`
+	}{
+		GroupId:      worker.GroupId(),
+		IdleTimeout:  x.WorkerConfig.AbortOlderThan.String(),
+		Transactions: worker.PendingTxns(),
+	})
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.Check2(w.Write(js))
+}
+
+// snapshotHandlesHandler lists the snapshot handles of this Alpha on GET, creates a snapshot
+// handle of the namespace of the request on POST, released after the ttl parameter (1h by
+// default), and releases the snapshot handle given by the id parameter on DELETE.
+func snapshotHandlesHandler(w http.ResponseWriter, r *http.Request) {
+	var resp interface{}
+	switch r.Method {
+	case http.MethodGet:
+		resp = struct {
+			Snapshots []*worker.SnapshotHandle `json:"snapshots"`
+		}{Snapshots: worker.SnapshotHandles()}
+	case http.MethodPost:
+		ttl := time.Hour
+		if s := r.URL.Query().Get("ttl"); s != "" {
+			var err error
+			if ttl, err = time.ParseDuration(s); err != nil {
+				x.SetStatus(w, x.ErrorInvalidRequest, "Invalid ttl of the snapshot handle")
+				return
+			}
+		}
+		h, err := worker.CreateSnapshotHandle(x.ExtractNamespaceHTTP(r), ttl)
+		if err != nil {
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+		resp = h
+	case http.MethodDelete:
+		if err := worker.ReleaseSnapshotHandle(r.URL.Query().Get("id")); err != nil {
+			x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+			return
+		}
+		x.SetStatus(w, x.Success, "Released the snapshot handle")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	js, err := json.Marshal(resp)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.Check2(w.Write(js))
+}
+
+// fsckHandler verifies the index, reverse and count keys of the tablets of the namespace of the
+// request served by the group of this Alpha against their data, or only the ones of the predicate
+// parameter. The issues are only reported, dgraph debug --fsck repairs them offline.
+func fsckHandler(w http.ResponseWriter, r *http.Request) {
+	reports, err := worker.Fsck(r.Context(), x.ExtractNamespaceHTTP(r),
+		r.URL.Query().Get("predicate"))
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	js, err := json.Marshal(struct {
+		GroupId    uint32                `json:"groupId"`
+		Predicates []*posting.FsckReport `json:"predicates"`
+	}{
+		GroupId:    worker.GroupId(),
+		Predicates: reports,
+	})
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.Check2(w.Write(js))
+}
+
+// tieringHandler lists the tablets of the namespace of the request served by the group of this
+// Alpha which are copied to the object storage on GET, and offloads the tablet of the predicate
+// parameter now on POST.
+func tieringHandler(w http.ResponseWriter, r *http.Request) {
+	ns := x.ExtractNamespaceHTTP(r)
+	if r.Method == http.MethodPost {
+		pred := r.URL.Query().Get("predicate")
+		if pred == "" {
+			x.SetStatus(w, x.ErrorInvalidRequest, "The predicate to offload is missing")
+			return
+		}
+		if err := worker.OffloadTablet(r.Context(), ns, pred); err != nil {
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+	}
+	tablets, err := worker.TieredTablets(ns)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	js, err := json.Marshal(struct {
+		GroupId uint32                 `json:"groupId"`
+		Tablets []*worker.TieredTablet `json:"tablets"`
+	}{
+		GroupId: worker.GroupId(),
+		Tablets: tablets,
+	})
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.Check2(w.Write(js))
+}
+
+// compressionDictHandler trains a zstd dictionary on the values of the predicate parameter of the
+// namespace of the request, which must have @compress(zstd) and be served by the group of this
+// Alpha, and adds it to its schema. The samples parameter is the number of nodes trained on.
+func compressionDictHandler(w http.ResponseWriter, r *http.Request) {
+	pred := r.URL.Query().Get("predicate")
+	if pred == "" {
+		x.SetStatus(w, x.ErrorInvalidRequest, "The predicate to train a dictionary for is missing")
+		return
+	}
+	samples := defaultDictSamples
+	if s := r.URL.Query().Get("samples"); s != "" {
+		var err error
+		if samples, err = strconv.Atoi(s); err != nil || samples <= 0 {
+			x.SetStatus(w, x.ErrorInvalidRequest, "Invalid number of samples of the dictionary")
+			return
+		}
+	}
+	dict, err := worker.TrainCompressionDict(r.Context(), x.ExtractNamespaceHTTP(r), pred,
+		samples)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	js, err := json.Marshal(dict)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.Check2(w.Write(js))
+}
+
+// diskUsageHandler reports the on-disk size of the p directory of this Alpha by LSM level,
+// namespace and predicate. The samples parameter is the number of keys of each predicate sampled
+// in the tables shared by predicates.
+func diskUsageHandler(w http.ResponseWriter, r *http.Request) {
+	samples := worker.DefaultDiskUsageSamples
+	if s := r.URL.Query().Get("samples"); s != "" {
+		var err error
+		if samples, err = strconv.Atoi(s); err != nil || samples <= 0 {
+			x.SetStatus(w, x.ErrorInvalidRequest, "Invalid number of samples of the tables")
+			return
+		}
+	}
+	usage, err := worker.DiskUsageOf(r.Context(), x.ExtractNamespaceHTTP(r), samples)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	js, err := json.Marshal(usage)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.Check2(w.Write(js))
+}
+
+// debugKeyHandler returns the versions, splits and postings of the key given in hex by the key
+// parameter, read at the timestamp of the at parameter, or at the max assigned one. The limit
+// parameter caps the number of versions and postings. The raw keys cross the namespaces, so only
+// the guardians of the galaxy may inspect them.
+func debugKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if err := edgraph.AuthSuperAdmin(x.AttachAccessJwt(r.Context(), r)); err != nil {
+		x.SetStatus(w, x.ErrorUnauthorized, err.Error())
+		return
+	}
+	key, err := hex.DecodeString(r.URL.Query().Get("key"))
+	if err != nil || len(key) == 0 {
+		x.SetStatus(w, x.ErrorInvalidRequest, "Invalid or missing hex key")
+		return
+	}
+	var readTs uint64
+	if at := r.URL.Query().Get("at"); at != "" {
+		if readTs, err = strconv.ParseUint(at, 0, 64); err != nil {
+			x.SetStatus(w, x.ErrorInvalidRequest, "Invalid read timestamp")
+			return
+		}
+	}
+	limit := worker.DefaultInspectLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+			x.SetStatus(w, x.ErrorInvalidRequest, "Invalid limit")
+			return
+		}
+	}
+	res, err := worker.InspectKey(r.Context(), key, readTs, limit)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	js, err := json.Marshal(res)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.Check2(w.Write(js))
+}
+
+// standingQueryHandler streams the deltas of the standing query given by the id parameter, as
+// newline delimited JSON, until the client disconnects or the standing query is deleted.
+func standingQueryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 0, 64)
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, "Invalid or missing id of the standing query")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		x.SetStatus(w, x.Error, "Streaming isn't supported by the connection")
+		return
+	}
+	ctx := x.AttachAccessJwt(r.Context(), r)
+	deltas, unsubscribe, err := (&edgraph.Server{}).SubscribeStandingQuery(ctx, id)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case delta, ok := <-deltas:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(delta); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
 func schemaValidateHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sch := readRequest(w, r)
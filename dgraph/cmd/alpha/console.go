@@ -0,0 +1,43 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package alpha
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+//go:embed console
+var consoleFiles embed.FS
+
+// setupConsole serves the web console at /console/, redirecting /console to it.
+func setupConsole(baseMux *http.ServeMux) {
+	baseMux.Handle("/console/", consoleHandler())
+	baseMux.Handle("/console", http.RedirectHandler("/console/", http.StatusMovedPermanently))
+}
+
+// consoleHandler serves the built-in web console at /console/. The console runs DQL and GraphQL
+// requests against this alpha, and shows its schema and the state of the cluster. It only uses
+// the HTTP endpoints of the alpha, with the access JWT of the logged in user if ACL is enabled.
+func consoleHandler() http.Handler {
+	files, err := fs.Sub(consoleFiles, "console")
+	x.Check(err)
+	fileServer := http.StripPrefix("/console/", http.FileServer(http.FS(files)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, x.ErrorInvalidMethod, http.StatusMethodNotAllowed)
+			return
+		}
+		// The console doesn't load anything from other origins.
+		w.Header().Set("Content-Security-Policy",
+			"default-src 'self'; frame-ancestors 'none'; form-action 'none'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fileServer.ServeHTTP(w, r)
+	})
+}
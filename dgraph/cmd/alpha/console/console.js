@@ -0,0 +1,221 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+"use strict";
+
+const tokenKey = "dgraph-console-access-token";
+const output = document.getElementById("output");
+
+function show(value, isError) {
+  output.classList.toggle("error", !!isError);
+  output.textContent = typeof value === "string" ? value : JSON.stringify(value, null, 2);
+}
+
+// request sends the body to the alpha, along with the access token if logged in, and returns
+// the decoded JSON response. It throws the errors of the response.
+async function request(path, options) {
+  const headers = Object.assign({}, options.headers);
+  const token = sessionStorage.getItem(tokenKey);
+  if (token) {
+    headers["X-Dgraph-AccessToken"] = token;
+  }
+  const resp = await fetch(path, Object.assign({}, options, { headers }));
+  const text = await resp.text();
+  let body;
+  try {
+    body = JSON.parse(text);
+  } catch (e) {
+    throw new Error(text || resp.statusText);
+  }
+  if (body.errors && body.errors.length > 0 && !body.data) {
+    throw new Error(body.errors.map((e) => e.message).join("\n"));
+  }
+  if (!resp.ok) {
+    throw new Error(text);
+  }
+  return body;
+}
+
+async function run(fn) {
+  show("Running...");
+  try {
+    show(await fn());
+  } catch (e) {
+    show(e.message, true);
+  }
+}
+
+function dql(query, readOnly) {
+  return request("/query" + (readOnly ? "?ro=true" : ""), {
+    method: "POST",
+    headers: { "Content-Type": "application/dql" },
+    body: query,
+  });
+}
+
+// mutate runs the mutation, which is either JSON like {"set": [...]}, or RDF like
+// { set { ... } }.
+function mutate(mutation) {
+  let contentType = "application/json";
+  try {
+    JSON.parse(mutation);
+  } catch (e) {
+    contentType = "application/rdf";
+  }
+  return request("/mutate?commitNow=true", {
+    method: "POST",
+    headers: { "Content-Type": contentType },
+    body: mutation,
+  });
+}
+
+function graphql() {
+  const path = document.getElementById("graphql-admin").checked ? "/admin" : "/graphql";
+  const vars = document.getElementById("graphql-variables").value.trim();
+  return request(path, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({
+      query: document.getElementById("graphql-input").value,
+      variables: vars ? JSON.parse(vars) : {},
+    }),
+  });
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+async function loadSchema() {
+  const resp = await dql("schema {}", true);
+  const data = resp.data || {};
+  const preds = document.querySelector("#schema-predicates tbody");
+  preds.replaceChildren();
+  for (const p of data.schema || []) {
+    const row = preds.insertRow();
+    cell(row, p.predicate);
+    cell(row, p.list ? "[" + p.type + "]" : p.type);
+    cell(row, (p.tokenizer || []).join(", "));
+    const flags = ["reverse", "count", "upsert", "lang", "unique"].filter((f) => p[f]);
+    cell(row, flags.map((f) => "@" + f).join(" "));
+  }
+  const types = document.querySelector("#schema-types tbody");
+  types.replaceChildren();
+  for (const t of data.types || []) {
+    const row = types.insertRow();
+    cell(row, t.name);
+    cell(row, (t.fields || []).map((f) => f.name).join(", "));
+  }
+  return resp;
+}
+
+async function loadCluster() {
+  const state = await request("/state", { method: "GET" });
+  const root = document.getElementById("cluster-state");
+  root.replaceChildren();
+
+  const addGroup = (title, members, tablets) => {
+    const div = document.createElement("div");
+    div.className = "group";
+    const h = document.createElement("h2");
+    h.textContent = title;
+    div.appendChild(h);
+
+    const table = document.createElement("table");
+    const head = table.createTHead().insertRow();
+    ["Id", "Address", "Leader", "Last update"].forEach((c) => cell(head, c));
+    for (const m of Object.values(members || {})) {
+      const row = table.insertRow();
+      cell(row, m.id, m.leader ? "leader" : "");
+      cell(row, m.addr);
+      cell(row, m.leader ? "yes" : "no");
+      cell(row, m.lastUpdate ? new Date(Number(m.lastUpdate) * 1000).toISOString() : "");
+    }
+    div.appendChild(table);
+
+    if (tablets) {
+      const tt = document.createElement("table");
+      const th = tt.createTHead().insertRow();
+      ["Predicate", "On disk bytes", "Uncompressed bytes"].forEach((c) => cell(th, c));
+      const sorted = Object.values(tablets).sort((a, b) => a.predicate.localeCompare(b.predicate));
+      for (const t of sorted) {
+        const row = tt.insertRow();
+        cell(row, t.predicate);
+        cell(row, t.onDiskBytes || 0);
+        cell(row, t.uncompressedBytes || 0);
+      }
+      div.appendChild(tt);
+    }
+    root.appendChild(div);
+  };
+
+  addGroup("Zeros", state.zeros);
+  for (const [id, group] of Object.entries(state.groups || {})) {
+    addGroup("Group " + id, group.members, group.tablets);
+  }
+  return state;
+}
+
+function updateLogin() {
+  const loggedIn = !!sessionStorage.getItem(tokenKey);
+  document.getElementById("logout").hidden = !loggedIn;
+  document.getElementById("login-status").textContent = loggedIn ? "Logged in" : "";
+}
+
+async function login(event) {
+  event.preventDefault();
+  const body = {
+    userid: document.getElementById("userid").value,
+    password: document.getElementById("password").value,
+  };
+  const ns = document.getElementById("namespace").value;
+  if (ns !== "") {
+    body.namespace = Number(ns);
+  }
+  try {
+    sessionStorage.removeItem(tokenKey);
+    const resp = await request("/login", { method: "POST", body: JSON.stringify(body) });
+    sessionStorage.setItem(tokenKey, resp.data.accessJWT);
+    document.getElementById("password").value = "";
+    show("Logged in successfully");
+  } catch (e) {
+    show(e.message, true);
+  }
+  updateLogin();
+}
+
+document.querySelectorAll("nav .tab").forEach((tab) => {
+  tab.addEventListener("click", () => {
+    document.querySelectorAll(".tab, .panel").forEach((e) => e.classList.remove("active"));
+    tab.classList.add("active");
+    document.getElementById(tab.dataset.tab).classList.add("active");
+    if (tab.dataset.tab === "schema") {
+      run(loadSchema);
+    } else if (tab.dataset.tab === "cluster") {
+      run(loadCluster);
+    }
+  });
+});
+
+document.getElementById("dql-query").addEventListener("click", () => run(() => dql(
+  document.getElementById("dql-input").value,
+  document.getElementById("dql-readonly").checked)));
+document.getElementById("dql-mutate").addEventListener("click", () => run(() => mutate(
+  document.getElementById("dql-input").value)));
+document.getElementById("graphql-run").addEventListener("click", () => run(graphql));
+document.getElementById("schema-refresh").addEventListener("click", () => run(loadSchema));
+document.getElementById("cluster-refresh").addEventListener("click", () => run(loadCluster));
+document.getElementById("login").addEventListener("submit", login);
+document.getElementById("logout").addEventListener("click", () => {
+  sessionStorage.removeItem(tokenKey);
+  updateLogin();
+  show("Logged out");
+});
+updateLogin();
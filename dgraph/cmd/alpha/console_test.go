@@ -0,0 +1,69 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package alpha
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newConsoleMux returns a mux with the routes of the alpha around the console, with the console
+// if it's enabled, like setupServer.
+func newConsoleMux(enableConsole bool) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.HandlerFunc(homeHandler))
+	if enableConsole {
+		setupConsole(mux)
+	}
+	return mux
+}
+
+func TestConsole(t *testing.T) {
+	mux := newConsoleMux(true)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/console/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "default-src 'self'; frame-ancestors 'none'; form-action 'none'",
+		w.Header().Get("Content-Security-Policy"))
+	require.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	require.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	index, err := consoleFiles.ReadFile("console/index.html")
+	require.NoError(t, err)
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	require.Equal(t, string(index), string(body))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/console/console.js", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEmpty(t, w.Header().Get("Content-Security-Policy"))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/console", nil))
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+	require.Equal(t, "/console/", w.Header().Get("Location"))
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, "/console/", strings.NewReader("{}")))
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code, method)
+	}
+}
+
+func TestConsoleDisabled(t *testing.T) {
+	mux := newConsoleMux(false)
+	for _, path := range []string{"/console/", "/console", "/console/console.js"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
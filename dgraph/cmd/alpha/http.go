@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"mime"
 	"net/http"
 	"sort"
@@ -242,6 +243,8 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := context.WithValue(r.Context(), query.DebugKey, isDebugMode)
 	ctx = x.AttachAccessJwt(ctx, r)
 	ctx = x.AttachRemoteIP(ctx, r)
+	ctx, requestId := x.WithRequestId(x.AttachRequestId(ctx, r))
+	w.Header().Set(x.RequestIdHeader, requestId)
 
 	if queryTimeout != 0 {
 		var cancel context.CancelFunc
@@ -286,24 +289,73 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		req.RespFormat = api.Request_JSON
 	case "rdf":
 		req.RespFormat = api.Request_RDF
+	case query.GraphFormat:
+		// The graph output is JSON, with the nodes and edges of the result.
+		req.RespFormat = api.Request_JSON
+		ctx = context.WithValue(ctx, query.GraphFormatKey, true)
 	default:
 		x.SetStatus(w, x.ErrorInvalidRequest, fmt.Sprintf("invalid value [%v] for parameter respFormat", respFormat))
 		return
 	}
 
+	// The output options reshape the JSON results.
+	outputOpts, err := query.ParseOutputOptions(r.URL.Query().Get("output"))
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	ctx = context.WithValue(ctx, query.OutputOptionsKey, outputOpts)
+
+	// The language fallback chain is applied to the predicates with @lang asked without languages.
+	langs, err := query.ParseLangFallback(r.URL.Query().Get("lang"))
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	ctx = context.WithValue(ctx, query.LangFallbackKey, langs)
+
+	// The request can switch the experimental paths of the query engine.
+	if spec := r.URL.Query().Get(x.ExperimentalPathsHeader); spec != "" {
+		if ctx, err = x.WithExperimentalPaths(ctx, spec); err != nil {
+			x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	// With partial results, the query returns what it fetched before the timeout.
+	isPartial, err := parseBool(r, "partial")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	var partial *query.PartialResults
+	if isPartial {
+		ctx, partial = query.WithPartialResults(ctx)
+	}
+
 	// Core processing happens here.
 	resp, err := (&edgraph.Server{}).QueryNoGrpc(ctx, &req)
 	if err != nil {
-		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+		var limitErr *x.QueryLimitError
+		if errors.As(err, &limitErr) {
+			x.SetQueryLimitStatus(w, limitErr)
+			return
+		}
+		x.SetErrorStatusWithData(w, x.ErrorInvalidRequest, err)
 		return
 	}
 	// Add cost to the header.
 	w.Header().Set(x.DgraphCostHeader, fmt.Sprint(resp.Metrics.NumUids["_total"]))
 
 	e := query.Extensions{
-		Txn:     resp.Txn,
-		Latency: resp.Latency,
-		Metrics: resp.Metrics,
+		Txn:       resp.Txn,
+		Latency:   resp.Latency,
+		Metrics:   resp.Metrics,
+		RequestId: requestId,
+	}
+	if partial != nil {
+		e.Boundary = partial.Boundary()
+		e.Partial = len(e.Boundary) > 0
 	}
 	js, err := json.Marshal(e)
 	if err != nil {
@@ -342,6 +394,73 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// nl2dqlHandler answers the natural language question of the request with the DQL query generated
+// by the LLM of --nl2dql, and its results.
+func nl2dqlHandler(w http.ResponseWriter, r *http.Request) {
+	if commonHandler(w, r) {
+		return
+	}
+	body := readRequest(w, r)
+	if body == nil {
+		return
+	}
+	var params struct {
+		Question string `json:"question"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		jsonErr := convertJSONError(string(body), err)
+		x.SetStatus(w, x.ErrorInvalidRequest, jsonErr.Error())
+		return
+	}
+	if strings.TrimSpace(params.Question) == "" {
+		x.SetStatus(w, x.ErrorInvalidRequest, "The request has no question")
+		return
+	}
+
+	ctx := x.AttachAccessJwt(r.Context(), r)
+	ctx = x.AttachRemoteIP(ctx, r)
+	ctx, requestId := x.WithRequestId(x.AttachRequestId(ctx, r))
+	w.Header().Set(x.RequestIdHeader, requestId)
+
+	res, err := (&edgraph.Server{}).NL2DQL(ctx, params.Question)
+	if err != nil {
+		var limitErr *x.QueryLimitError
+		if errors.As(err, &limitErr) {
+			x.SetQueryLimitStatus(w, limitErr)
+			return
+		}
+		x.SetErrorStatusWithData(w, x.ErrorInvalidRequest, err)
+		return
+	}
+	q, err := json.Marshal(res.Query)
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+	e, err := json.Marshal(query.Extensions{
+		Txn:       res.Resp.Txn,
+		Latency:   res.Resp.Latency,
+		Metrics:   res.Resp.Metrics,
+		RequestId: requestId,
+	})
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+
+	var out bytes.Buffer
+	x.Check2(out.WriteString(`{"data":{"query":`))
+	x.Check2(out.Write(q))
+	x.Check2(out.WriteString(`,"results":`))
+	x.Check2(out.Write(res.Resp.Json))
+	x.Check2(out.WriteString(`},"extensions":`))
+	x.Check2(out.Write(e))
+	x.Check2(out.WriteRune('}'))
+	if _, err := x.WriteResponse(w, r, out.Bytes()); err != nil {
+		glog.Errorln("Unable to write response: ", err)
+	}
+}
+
 func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	if commonHandler(w, r) {
 		return
@@ -358,6 +477,11 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
 		return
 	}
+	maxRetries, err := parseUint64(r, "retries")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
 	body := readRequest(w, r)
 	if body == nil {
 		return
@@ -467,19 +591,43 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	req.Hash = hash
 	req.CommitNow = commitNow
 
-	ctx := x.AttachAccessJwt(context.Background(), r)
-	resp, err := (&edgraph.Server{}).QueryNoGrpc(ctx, req)
+	// The request context carries the audit trail, the mutation must not be cancelled with it.
+	ctx := x.AttachAccessJwt(context.WithoutCancel(r.Context()), r)
+	ctx, requestId := x.WithRequestId(x.AttachRequestId(ctx, r))
+	w.Header().Set(x.RequestIdHeader, requestId)
+	var idem *edgraph.Idempotency
+	if key := r.Header.Get(x.IdempotencyKeyHeader); key != "" {
+		if ctx, idem, err = edgraph.WithIdempotencyKey(ctx, key); err != nil {
+			x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+			return
+		}
+	}
+	maxRetries = min(maxRetries, math.MaxInt32)
+	resp, retries, err := (&edgraph.Server{}).QueryWithRetries(ctx, req, int(maxRetries))
 	if err != nil {
-		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+		var limitErr *x.QueryLimitError
+		if errors.As(err, &limitErr) {
+			x.SetQueryLimitStatus(w, limitErr)
+			return
+		}
+		x.SetErrorStatusWithData(w, x.ErrorInvalidRequest, err)
 		return
 	}
 	// Add cost to the header.
 	w.Header().Set(x.DgraphCostHeader, fmt.Sprint(resp.Metrics.NumUids["_total"]))
+	if maxRetries > 0 {
+		w.Header().Set(x.DgraphRetriesHeader, strconv.Itoa(retries))
+	}
+	if idem != nil && idem.Replayed() {
+		w.Header().Set(x.IdempotentReplayHeader, "true")
+	}
 
 	resp.Latency.ParsingNs = uint64(parseEnd.Sub(parseStart).Nanoseconds())
 	e := query.Extensions{
-		Txn:     resp.Txn,
-		Latency: resp.Latency,
+		Txn:       resp.Txn,
+		Latency:   resp.Latency,
+		Retries:   retries,
+		RequestId: requestId,
 	}
 	sort.Strings(e.Txn.Keys)
 	sort.Strings(e.Txn.Preds)
@@ -544,7 +692,7 @@ func commitHandler(w http.ResponseWriter, r *http.Request) {
 		response, err = handleCommit(ctx, startTs, hash, reqText)
 	}
 	if err != nil {
-		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		x.SetErrorStatus(w, x.ErrorInvalidRequest, err)
 		return
 	}
 
@@ -557,6 +705,41 @@ func commitHandler(w http.ResponseWriter, r *http.Request) {
 	_, _ = x.WriteResponse(w, r, js)
 }
 
+// heartbeatHandler keeps the transaction startTs alive, so that it isn't aborted for being idle.
+func heartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	if commonHandler(w, r) {
+		return
+	}
+
+	startTs, err := parseUint64(r, "startTs")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	if startTs == 0 {
+		x.SetStatus(w, x.ErrorInvalidRequest,
+			"startTs parameter is mandatory while keeping a transaction alive")
+		return
+	}
+
+	tc := &api.TxnContext{StartTs: startTs, Hash: r.URL.Query().Get("hash")}
+	ctx := x.AttachAccessJwt(context.Background(), r)
+	if _, err := (&edgraph.Server{}).KeepAlive(ctx, tc); err != nil {
+		x.SetErrorStatus(w, x.ErrorInvalidRequest, err)
+		return
+	}
+
+	js, err := json.Marshal(map[string]interface{}{
+		"code":    x.Success,
+		"message": "Done",
+	})
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+	_, _ = x.WriteResponse(w, r, js)
+}
+
 func handleAbort(ctx context.Context, startTs uint64, hash string) (map[string]interface{}, error) {
 	tc := &api.TxnContext{
 		StartTs: startTs,
@@ -634,6 +817,15 @@ func alterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The nodes of a type are dropped with {"drop_type_data": "Person"}, which isn't an operation.
+	var drop struct {
+		DropTypeData string `json:"drop_type_data"`
+	}
+	if json.Unmarshal(b, &drop) == nil && drop.DropTypeData != "" {
+		dropTypeData(w, r, drop.DropTypeData)
+		return
+	}
+
 	op := &api.Operation{}
 	if err := jsonpb.Unmarshal(b, op); err != nil {
 		op.Schema = string(b)
@@ -653,17 +845,43 @@ func alterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Pass in PoorMan's auth, ACL and IP information if present.
-	ctx := x.AttachAuthToken(context.Background(), r)
+	ctx := x.AttachAuthToken(context.WithoutCancel(r.Context()), r)
 	ctx = x.AttachAccessJwt(ctx, r)
 	ctx = x.AttachRemoteIP(ctx, r)
 	if _, err := (&edgraph.Server{}).Alter(ctx, op); err != nil {
-		x.SetStatus(w, x.Error, err.Error())
+		x.SetErrorStatus(w, x.Error, err)
 		return
 	}
 
 	writeSuccessResponse(w, r)
 }
 
+// dropTypeData starts deleting the nodes of the type, responding with the id of the bulk deletion.
+func dropTypeData(w http.ResponseWriter, r *http.Request, typ string) {
+	glog.Infof("Got drop_type_data request via HTTP from %s\n", r.RemoteAddr)
+	ctx := x.AttachAuthToken(context.WithoutCancel(r.Context()), r)
+	ctx = x.AttachAccessJwt(ctx, r)
+	ctx = x.AttachRemoteIP(ctx, r)
+	id, err := (&edgraph.Server{}).DropTypeData(ctx, typ)
+	if err != nil {
+		x.SetErrorStatus(w, x.Error, err)
+		return
+	}
+
+	js, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"code":         x.Success,
+			"message":      "Bulk deletion started. Query bulkDeletes for its progress.",
+			"bulkDeleteId": id,
+		},
+	})
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	_, _ = x.WriteResponse(w, r, js)
+}
+
 func adminSchemaHandler(w http.ResponseWriter, r *http.Request) {
 	if commonHandler(w, r) {
 		return
@@ -676,18 +894,20 @@ func adminSchemaHandler(w http.ResponseWriter, r *http.Request) {
 
 	gqlReq := &schema.Request{
 		Query: `
-		mutation updateGqlSchema($sch: String!) {
+		mutation updateGqlSchema($sch: String!, $name: String) {
 			updateGQLSchema(input: {
 				set: {
 					schema: $sch
 				}
+				name: $name
 			}) {
 				gqlSchema {
 					id
 				}
 			}
 		}`,
-		Variables: map[string]interface{}{"sch": string(b)},
+		// the name query parameter sets a named GraphQL schema instead of the default one
+		Variables: map[string]interface{}{"sch": string(b), "name": r.URL.Query().Get("name")},
 	}
 
 	response := resolveWithAdminServer(gqlReq, r, adminServer)
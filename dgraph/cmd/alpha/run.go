@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math"
@@ -36,6 +37,7 @@ import (
 	_ "google.golang.org/grpc/encoding/gzip" // grpc compression
 	"google.golang.org/grpc/health"
 	hapi "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/dgraph-io/dgo/v250/protos/api"
@@ -44,8 +46,11 @@ import (
 	"github.com/hypermodeinc/dgraph/v25/audit"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/mcp"
 	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/enc"
 	"github.com/hypermodeinc/dgraph/v25/graphql/admin"
+	"github.com/hypermodeinc/dgraph/v25/plugin"
 	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/schema"
 	"github.com/hypermodeinc/dgraph/v25/tok"
 	"github.com/hypermodeinc/dgraph/v25/worker"
@@ -98,6 +103,10 @@ they form a Raft group and provide synchronous replication.
 
 	flag.StringP("postings", "p", "p", "Directory to store posting lists.")
 	flag.String("tmp", "t", "Directory to store temporary buffers.")
+	flag.String("install-snapshot", "",
+		"Location of a p directory uploaded by the bulk loader with a remote --out, e.g. "+
+			"s3:///bucket/bulk/0, which is installed into the postings directory on startup if "+
+			"it's missing or empty.")
 
 	flag.StringP("wal", "w", "w", "Directory to store raft write-ahead logs.")
 	flag.String("export", "export", "Folder in which to store exports.")
@@ -111,8 +120,21 @@ they form a Raft group and provide synchronous replication.
 	// Custom plugins.
 	flag.String("custom_tokenizers", "",
 		"Comma separated list of tokenizer plugins for custom indices.")
+	flag.String("plugin", plugin.Defaults, z.NewSuperFlagHelp(plugin.Defaults).
+		Head("Out-of-process plugins serving custom tokenizers and query functions over gRPC").
+		Flag("addrs",
+			"Comma separated list of the gRPC addresses of the plugins.").
+		Flag("timeout",
+			"The timeout of the calls to the plugins.").
+		Flag("startup-timeout",
+			"How long to wait for the plugins to be up when the alpha starts.").
+		Flag("health-interval",
+			"The interval of the health checks of the plugins. The tokenizers and the functions "+
+				"of an unhealthy plugin fail until it's healthy again.").
+		String())
 
 	flag.Bool("mcp", false, "run MCP server along with alpha.")
+	flag.Bool("console", false, "serve the built-in web console at /console/ along with alpha.")
 
 	// By default Go GRPC traces all requests.
 	grpc.EnableTracing = false
@@ -155,6 +177,11 @@ they form a Raft group and provide synchronous replication.
 		Flag("learner",
 			`Make this Alpha a "learner" node. In learner mode, this Alpha will not participate `+
 				"in Raft elections. This can be used to achieve a read-only replica.").
+		Flag("witness",
+			`Make this Alpha a "witness" node. A witness votes in the Raft elections of its group `+
+				"but stores no data, serves no queries and never stays the leader. This allows "+
+				"groups of two data replicas and a witness. The group must be set, and must have "+
+				"a data replica already.").
 		Flag("snapshot-after-entries",
 			"Create a new Raft snapshot after N number of Raft entries. The lower this number, "+
 				"the more frequent snapshot creation will be. Snapshots are created only if both "+
@@ -164,6 +191,35 @@ they form a Raft group and provide synchronous replication.
 				"to 0 to disable duration based snapshot.").
 		Flag("pending-proposals",
 			"Number of pending mutation proposals. Useful for rate limiting.").
+		Flag("wal-compress-above-kb",
+			"Compress with zstd the Raft entries bigger than this size in KB when writing them "+
+				"to the write-ahead log. Set to 0 to disable the compression. Older versions of "+
+				"Dgraph can't read the write-ahead logs with compressed entries.").
+		Flag("snapshot-bandwidth-mb",
+			"Maximum bandwidth in MB per second used to stream a Raft snapshot to each peer "+
+				"catching up. Set to 0 for no limit.").
+		Flag("apply-queue-depth",
+			"Number of mutations of a single predicate queued to be applied, from which the "+
+				"new mutations of the predicate wait before being proposed. The mutations of each "+
+				"predicate are applied in their own queue, so that a predicate receiving heavy "+
+				"writes doesn't slow down the others. Set to 0 to apply all the mutations in "+
+				"order.").
+		Flag("proposal-batch-window",
+			"The time a proposal waits for the others made after it, to be sent together to "+
+				"Raft in one message, like a group commit. This raises the throughput of many "+
+				"small mutations, at the cost of their latency. Set to 0 to send each proposal "+
+				"on its own.").
+		Flag("proposal-batch-kb",
+			"The size in KB of the proposals of a batch from which it's sent without waiting "+
+				"for the end of its window.").
+		Flag("proposal-batch-adaptive",
+			"Adapt the window of the proposals to the load: it starts at 0, widens up to "+
+				"proposal-batch-window while the batches fill up, and narrows back while the "+
+				"proposals come one at a time.").
+		Flag("reverse-async-lag",
+			"The maximum time the reverse edges of the predicates with @reverse(async) are "+
+				"added after their mutations are committed. Traversing these predicates backwards "+
+				"can miss the edges of the mutations committed within that time.").
 		String())
 
 	flag.String("security", worker.SecurityDefaults, z.NewSuperFlagHelp(worker.SecurityDefaults).
@@ -177,6 +233,22 @@ they form a Raft group and provide synchronous replication.
 				"to whitelist for performing admin actions (i.e., --security "+
 				`"whitelist=144.142.126.254,127.0.0.1:127.0.0.3,192.168.0.0/16,host.docker.`+
 				`internal").`).
+		Flag("signing-key-file",
+			"The file that stores the key, at least 32 bytes long, used to sign the requests to "+
+				"the signed HTTP endpoints with HMAC-SHA256. The signature is passed in the "+
+				"X-Dgraph-Signature header, along with the Unix time of the request in "+
+				"X-Dgraph-Timestamp and a unique nonce in X-Dgraph-Nonce. It covers the method, "+
+				"host, URI, timestamp, nonce and SHA-256 of the body of the request, separated by "+
+				"new lines. The requests aren't signed if it isn't set.").
+		Flag("signed-endpoints",
+			"A comma separated list of the HTTP endpoints that require signed requests. The "+
+				"endpoints below them also require signed requests.").
+		Flag("signature-max-age",
+			"The maximum age of the signed requests. Their nonces are tracked by each alpha "+
+				"during that time, so that the requests can't be replayed.").
+		Flag("signed-body-max-mb",
+			"The maximum size in MB of the body of the signed requests, which is read to verify "+
+				"their signature. The larger requests are rejected.").
 		String())
 
 	flag.String("limit", worker.LimitDefaults, z.NewSuperFlagHelp(worker.LimitDefaults).
@@ -202,8 +274,57 @@ they form a Raft group and provide synchronous replication.
 		Flag("max-retries",
 			"Commits to disk will give up after these number of retries to prevent locking the "+
 				"worker in a failed state. Use -1 to retry infinitely.").
+		Flag("mutation-retries",
+			"The maximum number of times a mutation which commits immediately, without a start "+
+				"ts, is retried when it's aborted due to a conflict. Clients ask for retries with "+
+				"the Dgraph-Retries gRPC header or the retries parameter of /mutate.").
 		Flag("txn-abort-after", "Abort any pending transactions older than this duration."+
-			" The liveness of a transaction is determined by its last mutation.").
+			" The liveness of a transaction is determined by its last mutation, or heartbeat. "+
+			"Keep a transaction alive with /txn/heartbeat?startTs=, or an empty request in "+
+			"the transaction.").
+		Flag("txn-max-duration", "Abort any pending transactions running for longer than this "+
+			"duration, even if they are kept alive. The mutations and heartbeats of such "+
+			"transactions fail with a DeadlineExceeded error. If set to 0, the transactions can "+
+			"run for as long as they are kept alive.").
+		Flag("txn-max-duration-ns", "Comma separated list of <namespace>:<duration> overriding "+
+			"txn-max-duration for the transactions of some namespaces.").
+		Flag("query-depth", "The maximum nesting of the blocks of a DQL query, which is also the "+
+			"maximum depth of @recurse. If set to 0, the depth isn't limited.").
+		Flag("query-expand", "The maximum number of predicates expand() can add to a block of a "+
+			"DQL query. If set to 0, expand() isn't limited.").
+		Flag("query-root-uids", "The maximum number of uids at the root of a DQL query block, "+
+			"after its filters and pagination. If set to 0, the uids aren't limited.").
+		Flag("query-var-uids", "The maximum number of uids, or values, of a DQL query variable. "+
+			"If set to 0, the variables aren't limited.").
+		Flag("query-memory-mb", "The maximum estimated size in MB of the results of a DQL query. "+
+			"If set to 0, the size of the results is only limited to 4GB.").
+		Flag("query-limits-ns", "Comma separated list of <namespace>:<limit>=<value> overriding "+
+			"query-depth, query-expand, query-root-uids, query-var-uids or query-memory-mb for "+
+			"the DQL queries of some namespaces, like 1:query-depth=5,1:query-var-uids=10000.").
+		Flag("mutation-size-mb", "The maximum size in MB of the mutations of a request, or of "+
+			"the mutation streamed to the Mutate method of the Loader service. If set to 0, the "+
+			"size isn't limited.").
+		Flag("mutation-size-mb-ns", "Comma separated list of <namespace>:<size> overriding "+
+			"mutation-size-mb for the mutations of some namespaces.").
+		Flag("proposal-edges", "The maximum number of edges proposed at once to a group. The "+
+			"mutations with more edges are split into several proposals, applied in the same "+
+			"transaction. If set to 0, the mutations aren't split.").
+		Flag("query-spill-uids", "The number of uids and values of the results of a DQL query "+
+			"block from which they are spilled to a temporary file in --tmp, while the other "+
+			"blocks of the query run. This trades latency for memory on the queries with large "+
+			"intermediate results. If set to 0, the results aren't spilled.").
+		Flag("wasm-timeout", "The time each call to a wasm function of a query can run for, "+
+			"after which the query fails. If set to 0, the calls aren't limited.").
+		Flag("wasm-memory-mb", "The maximum memory in MB of each instance of a wasm function of "+
+			"a query, up to 4096.").
+		Flag("reverse-scan-keys", "The maximum number of keys of a tablet scanned to traverse "+
+			"~pred when pred has no @reverse, finding the nodes linking to the ones of the query. "+
+			"The traversals of larger tablets fail, asking for @reverse. If set to 0, ~pred "+
+			"needs @reverse.").
+		Flag("idempotency-window", "The duration for which the result of a mutation sent with an "+
+			"idempotency key, through the Idempotency-Key header, is kept. The retries of the "+
+			"mutation with the same key get the result back instead of applying the mutation "+
+			"again. If set to 0, the idempotency keys are rejected.").
 		Flag("shared-instance", "When set to true, it disables ACLs for non-galaxy users. "+
 			"It expects the access JWT to be constructed outside dgraph for non-galaxy users as "+
 			"login is denied to them. Additionally, this disables access to environment variables for minio, aws, etc.").
@@ -226,6 +347,113 @@ they form a Raft group and provide synchronous replication.
 			"The polling interval for GraphQL subscription.").
 		Flag("lambda-url",
 			"The URL of a lambda server that implements custom GraphQL Javascript resolvers.").
+		Flag("upload-uri",
+			"The location where the files of the Upload fields are stored, like "+
+				"s3:///bucket/folder, gs:///bucket/folder or minio://host:port/bucket/folder. The "+
+				"credentials are taken from the environment, like for the backups. File uploads "+
+				"are disabled if it's empty.").
+		Flag("upload-max-size-mb",
+			"The maximum size of the files uploaded with a GraphQL request, in MB.").
+		String())
+
+	flag.String("compression", worker.CompressionDefaults, z.NewSuperFlagHelp(
+		worker.CompressionDefaults).
+		Head("Compression of the responses. The HTTP responses are compressed with zstd or gzip "+
+			"as accepted by the client. The gRPC clients can ask for zstd or gzip too.").
+		Flag("min-size",
+			"The size in bytes from which the HTTP responses are compressed.").
+		Flag("level",
+			"The compression level of gzip and zstd: fastest, default, better or best.").
+		String())
+
+	flag.String("metrics", worker.MetricsDefaults, z.NewSuperFlagHelp(worker.MetricsDefaults).
+		Head("Per-predicate and per-namespace metrics. Only the allow-listed predicates and "+
+			"namespaces get their own latency and throughput series, to bound their number.").
+		Flag("predicates",
+			"Comma separated list of the predicates whose queries and mutations are recorded, "+
+				"in all the namespaces. The namespaces which aren't allow-listed are recorded as "+
+				"\"other\".").
+		Flag("namespaces",
+			"Comma separated list of the ids of the namespaces whose requests are recorded, or * "+
+				"for all of them.").
+		Flag("exemplars",
+			"Attach the trace ids of the sampled requests to the latencies as exemplars, exposed "+
+				"in the OpenMetrics format.").
+		String())
+
+	flag.String("capture", worker.CaptureDefaults, z.NewSuperFlagHelp(worker.CaptureDefaults).
+		Head("Query capture options. A sample of the DQL queries is written with their variables, "+
+			"latencies and the hash of their results, to be replayed against another cluster by "+
+			"dgraph replay. The captured files hold the queries and variables in clear.").
+		Flag("dir",
+			"Directory where the queries are captured. They aren't captured if it's empty.").
+		Flag("sample",
+			"Ratio of the queries captured.").
+		Flag("size-mb",
+			"Size in MB of the capture files after which they're rotated.").
+		Flag("days",
+			"Number of days the rotated capture files are kept.").
+		String())
+
+	flag.String("sandbox", worker.SandboxDefaults, z.NewSuperFlagHelp(worker.SandboxDefaults).
+		Head("Sandbox options. The DQL and GraphQL queries of the users of the sandboxed "+
+			"namespaces run with strict caps, so that the namespaces can back a query console "+
+			"open to end users. Their requests can't access the other namespaces, and the "+
+			"sandbox limits only lower the --limit ones. The namespaces are sandboxed by the "+
+			"guardians of the galaxy with the sandboxNamespace mutation of /admin, the galaxy "+
+			"namespace can't be sandboxed.").
+		Flag("timeout",
+			"The maximum time a sandboxed query runs for, whatever the timeout it asks for.").
+		Flag("query-depth",
+			"The maximum nesting of the blocks of a sandboxed query, like --limit query-depth.").
+		Flag("query-expand",
+			"The maximum number of predicates expand() can add to a block of a sandboxed query.").
+		Flag("query-root-uids",
+			"The maximum number of uids at the root of a block of a sandboxed query.").
+		Flag("query-var-uids",
+			"The maximum number of uids, or values, of a variable of a sandboxed query.").
+		Flag("query-memory-mb",
+			"The maximum estimated size in MB of the results of a sandboxed query.").
+		String())
+
+	flag.String("nl2dql", worker.NL2DQLDefaults, z.NewSuperFlagHelp(worker.NL2DQLDefaults).
+		Head("Natural language to DQL options. With a provider, /nl2dql answers the questions "+
+			"in natural language sent to it, asking an LLM to translate them into DQL queries "+
+			"given the schema of the namespace, and running the queries for the user.").
+		Flag("provider",
+			"[openai, anthropic] The provider of the LLM. The self-hosted endpoints speaking "+
+				"the chat completions API of OpenAI use openai with their url. /nl2dql is "+
+				"disabled if it's empty.").
+		Flag("url",
+			"The endpoint of the LLM, overriding the one of the provider.").
+		Flag("model",
+			"The model of the LLM.").
+		Flag("api-key-file",
+			"The file holding the API key of the provider.").
+		Flag("max-tokens",
+			"The maximum number of tokens of the answers of the LLM.").
+		Flag("timeout",
+			"The timeout of the requests to the LLM.").
+		Flag("retries",
+			"The number of times the LLM is asked to fix a generated query which doesn't parse "+
+				"or fails.").
+		String())
+
+	flag.String("embedding", worker.EmbeddingDefaults, z.NewSuperFlagHelp(worker.EmbeddingDefaults).
+		Head("Embedding options. With a url, the values set on the source predicates of the "+
+			"float32vector predicates with @embedding(source) are embedded by the endpoint in "+
+			"the mutations, setting the vectors of the predicates.").
+		Flag("url",
+			"The endpoint speaking the embeddings API of OpenAI, like "+
+				"https://api.openai.com/v1/embeddings or a self-hosted one.").
+		Flag("model",
+			"The embedding model.").
+		Flag("api-key-file",
+			"The file holding the API key of the endpoint.").
+		Flag("batch-size",
+			"The maximum number of values embedded by a request to the endpoint.").
+		Flag("timeout",
+			"The timeout of the requests to the endpoint.").
 		String())
 
 	flag.String("cdc", worker.CDCDefaults, z.NewSuperFlagHelp(worker.CDCDefaults).
@@ -248,6 +476,18 @@ they form a Raft group and provide synchronous replication.
 			"The path to client key file for TLS encryption.").
 		String())
 
+	flag.String("tiering", worker.TieringDefaults, z.NewSuperFlagHelp(worker.TieringDefaults).
+		Head("Tiering options").
+		Flag("dest",
+			"The URI of the object storage (file, s3, minio or gs, like the backups) where the "+
+				"tablets which aren't accessed are moved to. Tiering is disabled if it's empty.").
+		Flag("cold-after",
+			"The duration after which a tablet which isn't accessed is moved to the object "+
+				"storage and dropped locally. It's read back on its next access.").
+		Flag("interval",
+			"The interval at which the idle tablets are looked for.").
+		String())
+
 	flag.String("audit", worker.AuditDefaults, z.NewSuperFlagHelp(worker.AuditDefaults).
 		Head("Audit options").
 		Flag("output",
@@ -262,6 +502,11 @@ they form a Raft group and provide synchronous replication.
 			"The number of days audit logs will be preserved.").
 		Flag("size",
 			"The audit log max size in MB after which it will be rolled over.").
+		Flag("routes",
+			`Comma separated list of <namespace>:<sink> routing the audit logs of a namespace to
+			its own sink instead of output. The sink is one of file:/path/to/dir,
+			syslog[:<udp|tcp>://host:port] or webhook:<url>. Each sink chains its entries with
+			hashes, which can be checked with "dgraph audit verify".`).
 		String())
 
 	flag.String("feature-flags", worker.FeatureFlagsDefaults, z.NewSuperFlagHelp(worker.FeatureFlagsDefaults).
@@ -270,9 +515,27 @@ they form a Raft group and provide synchronous replication.
 			" 'v20': returns values with repeated key for fields with same alias (same as v20.11)."+
 			" For more details, see https://github.com/hypermodeinc/dgraph/pull/7639").
 		Flag("enable-detailed-metrics", "Enable metrics about disk reads and cache per predicate").
+		Flag("presence-bitmap", "Maintain in-memory presence bitmaps per predicate, so that has()"+
+			" at root doesn't need to iterate over the whole tablet. Stats are reported in /state.").
+		Flag("experimental-paths", "Comma separated list of <path>[@<namespace>]=<off|on|shadow>"+
+			" switching the experimental execution paths of the query engine, for all the"+
+			" namespaces or for one of them. In shadow mode, both the old and the new paths run"+
+			" and their results and latencies are compared, the result of the old path being"+
+			" returned. The requests can override the modes with the experimental-paths query"+
+			" parameter or gRPC metadata, and the cluster config with its experimental-paths"+
+			" setting. The paths are: "+experimentalPathsHelp()+".").
 		String())
 }
 
+// experimentalPathsHelp lists the experimental paths with their default mode, for the help.
+func experimentalPathsHelp() string {
+	var paths []string
+	for _, p := range x.ExperimentalPaths() {
+		paths = append(paths, fmt.Sprintf("%s (%s by default): %s", p.Name, p.Default, p.Help))
+	}
+	return strings.Join(paths, "; ")
+}
+
 func setupCustomTokenizers() {
 	customTokenizers := Alpha.Conf.GetString("custom_tokenizers")
 	if customTokenizers == "" {
@@ -283,6 +546,12 @@ func setupCustomTokenizers() {
 	}
 }
 
+func setupPlugins() {
+	err := plugin.LoadAll(z.NewSuperFlag(Alpha.Conf.GetString("plugin")).
+		MergeAndCheckDefault(plugin.Defaults))
+	x.Checkf(err, "while loading the plugins")
+}
+
 // Parses a comma-delimited list of IP addresses, IP ranges, CIDR blocks, or hostnames
 // and returns a slice of []IPRange.
 //
@@ -428,12 +697,34 @@ func stateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err = w.Write(aResp.Json); err != nil {
+	state := aResp.Json
+	if posting.EnablePresenceBitmap {
+		if state, err = addPresenceStats(state); err != nil {
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+	}
+	if _, err = w.Write(state); err != nil {
 		x.SetStatus(w, x.Error, err.Error())
 		return
 	}
 }
 
+// addPresenceStats adds the stats of the presence bitmaps held by this alpha to the
+// membership state returned by /state.
+func addPresenceStats(state []byte) ([]byte, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(state, &m); err != nil {
+		return nil, err
+	}
+	stats, err := json.Marshal(posting.MemLayerInstance.PresenceStats())
+	if err != nil {
+		return nil, err
+	}
+	m["presence"] = stats
+	return json.Marshal(m)
+}
+
 // storeStatsHandler outputs some basic stats for data store.
 func storeStatsHandler(w http.ResponseWriter, r *http.Request) {
 	x.AddCorsHeaders(w)
@@ -457,7 +748,10 @@ func serveGRPC(l net.Listener, tlsCfg *tls.Config, closer *z.Closer) {
 		grpc.MaxSendMsgSize(x.GrpcMaxSize),
 		grpc.MaxConcurrentStreams(1000),
 		grpc.StatsHandler(&ocgrpc.ServerHandler{}),
-		grpc.UnaryInterceptor(audit.AuditRequestGRPC),
+		grpc.ChainUnaryInterceptor(edgraph.ErrorCodeUnaryInterceptor,
+			edgraph.ApiKeyUnaryInterceptor, audit.AuditRequestGRPC),
+		grpc.ChainStreamInterceptor(edgraph.ErrorCodeStreamInterceptor,
+			edgraph.ApiKeyStreamInterceptor),
 	}
 	if tlsCfg != nil {
 		tlsCfg.NextProtos = []string{"h2"}
@@ -466,6 +760,7 @@ func serveGRPC(l net.Listener, tlsCfg *tls.Config, closer *z.Closer) {
 
 	s := grpc.NewServer(opt...)
 	api.RegisterDgraphServer(s, &edgraph.Server{})
+	pb.RegisterLoaderServer(s, &edgraph.Loader{})
 	hapi.RegisterHealthServer(s, health.NewServer())
 	worker.RegisterZeroProxyServer(s)
 
@@ -474,6 +769,23 @@ func serveGRPC(l net.Listener, tlsCfg *tls.Config, closer *z.Closer) {
 	s.Stop()
 }
 
+// mcpContext forwards the access token of an MCP request to the queries, mutations and schema
+// changes of its tools, sent to the alpha over gRPC, so that they run as the user of the token.
+// Without it, they run without credentials and fail when ACLs are enabled. The token replaces any
+// accessJwt already in the outgoing metadata, so that the alpha never sees more than one.
+func mcpContext(ctx context.Context, r *http.Request) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		md = metadata.New(nil)
+	}
+	if accessJwt := r.Header.Get("X-Dgraph-AccessToken"); accessJwt != "" {
+		md.Set("accessJwt", accessJwt)
+	} else {
+		md.Delete("accessJwt")
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
 func setupMcp(baseMux *http.ServeMux, connectionString, url string, readOnly bool) error {
 	s, err := mcp.NewMCPServer(connectionString, readOnly)
 	if err != nil {
@@ -483,6 +795,7 @@ func setupMcp(baseMux *http.ServeMux, connectionString, url string, readOnly boo
 
 	sse := server.NewSSEServer(s,
 		server.WithStaticBasePath(url),
+		server.WithSSEContextFunc(mcpContext),
 	)
 
 	corsHandler := func(w http.ResponseWriter, r *http.Request) {
@@ -503,14 +816,18 @@ func buildConnectionString(addr string, port int) string {
 	return fmt.Sprintf("dgraph://%s:%d", addr, port)
 }
 
-func setupServer(closer *z.Closer, enableMcp bool) {
+func setupServer(closer *z.Closer, enableMcp, enableConsole bool) {
 	go worker.RunServer(bindall) // For pb.communication.
 	laddr := "localhost"
 	if bindall {
 		laddr = "0.0.0.0"
 	}
 
-	tlsCfg, err := x.LoadServerTLSConfig(Alpha.Conf)
+	grpcTLSCfg, err := x.LoadGrpcServerTLSConfig(Alpha.Conf)
+	if err != nil {
+		log.Fatalf("Failed to setup TLS: %v\n", err)
+	}
+	httpTLSCfg, httpsWrap, err := x.LoadHttpServerTLSConfig(Alpha.Conf)
 	if err != nil {
 		log.Fatalf("Failed to setup TLS: %v\n", err)
 	}
@@ -526,7 +843,7 @@ func setupServer(closer *z.Closer, enableMcp bool) {
 	}
 
 	baseMux := http.NewServeMux()
-	http.Handle("/", audit.AuditRequestHttp(baseMux))
+	http.Handle("/", edgraph.ApiKeyHttp(audit.AuditRequestHttp(requestSigningHandler(baseMux))))
 
 	http.HandleFunc("/login", loginHandler)
 	baseMux.HandleFunc("/query", queryHandler)
@@ -534,9 +851,16 @@ func setupServer(closer *z.Closer, enableMcp bool) {
 	baseMux.HandleFunc("/mutate", mutationHandler)
 	baseMux.HandleFunc("/mutate/", mutationHandler)
 	baseMux.HandleFunc("/commit", commitHandler)
+	baseMux.HandleFunc("/txn/heartbeat", heartbeatHandler)
 	baseMux.HandleFunc("/alter", alterHandler)
+	if edgraph.NL2DQLEnabled() {
+		baseMux.HandleFunc("/nl2dql", nl2dqlHandler)
+	}
 	baseMux.HandleFunc("/health", healthCheck)
 	baseMux.HandleFunc("/state", stateHandler)
+	if worker.Config.Scim != nil {
+		baseMux.Handle("/scim/v2/", edgraph.ScimHandler())
+	}
 	baseMux.HandleFunc("/debug/jemalloc", x.JemallocHandler)
 	http.DefaultServeMux.Handle("/debug/z", zpages.NewTracezHandler(zpages.NewSpanProcessor()))
 
@@ -568,15 +892,22 @@ func setupServer(closer *z.Closer, enableMcp bool) {
 	// Do not use := notation here because adminServer is a global variable.
 	mainServer, adminServer, gqlHealthStore = admin.NewServers(introspection,
 		globalEpoch, closer)
-	baseMux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+	graphqlHandler := func(w http.ResponseWriter, r *http.Request) {
 		namespace := x.ExtractNamespaceHTTP(r)
 		r.Header.Set("resolver", strconv.FormatUint(namespace, 10))
-		if err := admin.LazyLoadSchema(namespace); err != nil {
+		// a named GraphQL schema of the namespace is served at /graphql/<name>, or with the header
+		if name := strings.TrimPrefix(r.URL.Path, "/graphql/"); name != r.URL.Path {
+			r.Header.Set(x.GraphQLSchemaHeader, name)
+		}
+		if err := admin.LazyLoadNamedSchema(namespace,
+			r.Header.Get(x.GraphQLSchemaHeader)); err != nil {
 			admin.WriteErrorResponse(w, r, err)
 			return
 		}
 		mainServer.HTTPHandler().ServeHTTP(w, r)
-	})
+	}
+	baseMux.HandleFunc("/graphql", graphqlHandler)
+	baseMux.HandleFunc("/graphql/", graphqlHandler)
 
 	baseMux.Handle("/probe/graphql", graphqlProbeHandler(gqlHealthStore, globalEpoch))
 
@@ -602,10 +933,14 @@ func setupServer(closer *z.Closer, enableMcp bool) {
 
 	baseMux.Handle("/", http.HandlerFunc(homeHandler))
 	baseMux.Handle("/ui/keywords", http.HandlerFunc(keywordHandler))
+	if enableConsole {
+		setupConsole(baseMux)
+		glog.Infof("Bringing up web console at %s/console/", addr)
+	}
 
 	// Initialize the servers.
 	x.ServerCloser.AddRunning(3)
-	go serveGRPC(grpcListener, tlsCfg, x.ServerCloser)
+	go serveGRPC(grpcListener, grpcTLSCfg, x.ServerCloser)
 
 	if enableMcp {
 		if err := setupMcp(baseMux, buildConnectionString(laddr, grpcPort()), "/mcp", false); err != nil {
@@ -616,7 +951,7 @@ func setupServer(closer *z.Closer, enableMcp bool) {
 		}
 	}
 
-	go x.StartListenHttpAndHttps(httpListener, tlsCfg, x.ServerCloser)
+	go x.StartListenHttpAndHttps(httpListener, httpTLSCfg, httpsWrap, x.ServerCloser)
 
 	go func() {
 		defer x.ServerCloser.Done()
@@ -652,6 +987,9 @@ func setupServer(closer *z.Closer, enableMcp bool) {
 }
 
 func run() {
+	defer x.DumpFlightRecorderOnPanic()
+	x.Check(x.InitFlightRecorder(Alpha.Conf.GetString("flight-recorder")))
+
 	// keeping this flag for backward compatibility
 	_ = z.NewSuperFlag(Alpha.Conf.GetString("telemetry")).
 		MergeAndCheckDefault(x.TelemetryDefaults)
@@ -682,6 +1020,7 @@ func run() {
 		worker.LimitDefaults)
 
 	enableMcp := Alpha.Conf.GetBool("mcp")
+	enableConsole := Alpha.Conf.GetBool("console")
 
 	opts := worker.Options{
 		PostingDir:      Alpha.Conf.GetString("postings"),
@@ -694,6 +1033,7 @@ func run() {
 		AuthToken:          security.GetString("token"),
 		Audit:              conf,
 		ChangeDataConf:     Alpha.Conf.GetString("cdc"),
+		TieringConf:        Alpha.Conf.GetString("tiering"),
 		TypeFilterUidLimit: x.Config.Limit.GetUint64("type-filter-uid-limit"),
 	}
 
@@ -706,10 +1046,22 @@ func run() {
 		opts.AclSecretKeyBytes = keys.AclSecretKeyBytes
 		opts.AccessJwtTtl = keys.AclAccessTtl
 		opts.RefreshJwtTtl = keys.AclRefreshTtl
+		opts.Ldap = keys.AclLdap
+		opts.Scim = keys.AclScim
 		glog.Info("ACL secret key loaded successfully.")
 	}
 
 	abortDur := x.Config.Limit.GetDuration("txn-abort-after")
+	txnMaxDur := x.Config.Limit.GetDuration("txn-max-duration")
+	if txnMaxDur < 0 {
+		glog.Errorf(`--limit "txn-max-duration=%s;" can't be negative`, txnMaxDur)
+		os.Exit(1)
+	}
+	txnMaxDurNs, err := x.ParseNsDurations(x.Config.Limit.GetString("txn-max-duration-ns"))
+	if err != nil {
+		glog.Errorf(`Invalid --limit "txn-max-duration-ns": %v`, err)
+		os.Exit(1)
+	}
 	switch strings.ToLower(x.Config.Limit.GetString("mutations")) {
 	case "allow":
 		opts.MutationsMode = worker.AllowMutations
@@ -722,6 +1074,9 @@ func run() {
 		os.Exit(1)
 	}
 
+	opts.RequestSigner, err = x.RequestSignerFromFlag(security)
+	x.Check(err)
+
 	worker.SetConfiguration(&opts)
 
 	ips, err := getIPsFromString(security.GetString("whitelist"))
@@ -742,6 +1097,8 @@ func run() {
 		StrictMutations:     opts.MutationsMode == worker.StrictMutations,
 		AclEnabled:          keys.AclSecretKey != nil,
 		AbortOlderThan:      abortDur,
+		TxnMaxDuration:      txnMaxDur,
+		TxnMaxDurationNs:    txnMaxDurNs,
 		StartTime:           startTime,
 		Security:            security,
 		TLSClientConfig:     tlsClientConf,
@@ -757,8 +1114,19 @@ func run() {
 	z.SetTmpDir(x.WorkerConfig.TmpDir)
 
 	x.WorkerConfig.EncryptionKey = keys.EncKey
+	if keys.EncKms != nil {
+		x.WorkerConfig.EncryptionKms = keys.EncKms.Name()
+	}
+	if keys.EncNsKeyDir != "" {
+		nsKeys, err := enc.LoadNamespaceKeys(keys.EncNsKeyDir, keys.EncKey, keys.EncKms)
+		x.Check(err)
+		posting.SetNamespaceKeys(nsKeys)
+		glog.Infof("Loaded encryption keys for %d namespaces.", len(nsKeys.Namespaces()))
+		go reloadNamespaceKeys(x.ServerCloser)
+	}
 
 	setupCustomTokenizers()
+	setupPlugins()
 	x.Config.PortOffset = Alpha.Conf.GetInt("port_offset")
 	x.Config.LimitMutationsNquad = int(x.Config.Limit.GetInt64("mutations-nquad"))
 	x.Config.LimitQueryEdge = x.Config.Limit.GetUint64("query-edge")
@@ -766,7 +1134,38 @@ func run() {
 	x.Config.LimitNormalizeNode = int(x.Config.Limit.GetInt64("normalize-node"))
 	x.Config.QueryTimeout = x.Config.Limit.GetDuration("query-timeout")
 	x.Config.MaxRetries = x.Config.Limit.GetInt64("max-retries")
+	x.Config.MutationRetries = int(x.Config.Limit.GetInt64("mutation-retries"))
 	x.Config.SharedInstance = x.Config.Limit.GetBool("shared-instance")
+	x.Config.QueryLimits = x.ParseQueryLimits(x.Config.Limit)
+	if x.Config.QueryLimitsNs, err = x.ParseNsQueryLimits(
+		x.Config.Limit.GetString("query-limits-ns"), x.Config.QueryLimits); err != nil {
+		glog.Errorf(`Invalid --limit "query-limits-ns": %v`, err)
+		os.Exit(1)
+	}
+	sandbox := z.NewSuperFlag(Alpha.Conf.GetString("sandbox")).MergeAndCheckDefault(
+		worker.SandboxDefaults)
+	x.Config.SandboxTimeout = sandbox.GetDuration("timeout")
+	x.Config.SandboxLimits = x.ParseQueryLimits(sandbox)
+	x.Config.LimitMutationSize = x.Config.Limit.GetUint64("mutation-size-mb") << 20
+	sizesNs, err := x.ParseNsUints(x.Config.Limit.GetString("mutation-size-mb-ns"))
+	if err != nil {
+		glog.Errorf(`Invalid --limit "mutation-size-mb-ns": %v`, err)
+		os.Exit(1)
+	}
+	x.Config.LimitMutationSizeNs = make(map[uint64]uint64, len(sizesNs))
+	for ns, size := range sizesNs {
+		x.Config.LimitMutationSizeNs[ns] = size << 20
+	}
+	x.Config.LimitProposalEdges = int(x.Config.Limit.GetInt64("proposal-edges"))
+	x.Config.QuerySpillUids = x.Config.Limit.GetUint64("query-spill-uids")
+	x.Config.LimitWasmTimeout = x.Config.Limit.GetDuration("wasm-timeout")
+	x.Config.LimitWasmMemory = x.Config.Limit.GetUint64("wasm-memory-mb") << 20
+	x.Config.LimitReverseScanKeys = x.Config.Limit.GetUint64("reverse-scan-keys")
+	x.Config.IdempotencyWindow = x.Config.Limit.GetDuration("idempotency-window")
+	if x.Config.IdempotencyWindow < 0 {
+		glog.Errorf(`--limit "idempotency-window=%s;" can't be negative`, x.Config.IdempotencyWindow)
+		os.Exit(1)
+	}
 
 	x.Config.GraphQL = z.NewSuperFlag(Alpha.Conf.GetString("graphql")).MergeAndCheckDefault(
 		worker.GraphQLDefaults)
@@ -785,17 +1184,85 @@ func run() {
 	}
 	edgraph.Init()
 
+	nl2dql := z.NewSuperFlag(Alpha.Conf.GetString("nl2dql")).MergeAndCheckDefault(
+		worker.NL2DQLDefaults)
+	if provider := nl2dql.GetString("provider"); provider != "" {
+		var apiKey x.Sensitive
+		if keyFile := nl2dql.GetPath("api-key-file"); keyFile != "" {
+			apiKey, err = os.ReadFile(keyFile)
+			x.Checkf(err, "while reading the API key of --nl2dql")
+		}
+		llm, err := x.NewLLM(x.LLMConfig{
+			Provider:  provider,
+			URL:       nl2dql.GetString("url"),
+			Model:     nl2dql.GetString("model"),
+			APIKey:    bytes.TrimSpace(apiKey),
+			MaxTokens: int(nl2dql.GetInt64("max-tokens")),
+			Timeout:   nl2dql.GetDuration("timeout"),
+		})
+		x.Checkf(err, "Invalid --nl2dql")
+		edgraph.InitNL2DQL(llm, int(nl2dql.GetInt64("retries")))
+	}
+
+	embedding := z.NewSuperFlag(Alpha.Conf.GetString("embedding")).MergeAndCheckDefault(
+		worker.EmbeddingDefaults)
+	if url := embedding.GetString("url"); url != "" {
+		var apiKey x.Sensitive
+		if keyFile := embedding.GetPath("api-key-file"); keyFile != "" {
+			apiKey, err = os.ReadFile(keyFile)
+			x.Checkf(err, "while reading the API key of --embedding")
+		}
+		e, err := x.NewEmbedder(x.EmbedderConfig{
+			URL:       url,
+			Model:     embedding.GetString("model"),
+			APIKey:    bytes.TrimSpace(apiKey),
+			BatchSize: int(embedding.GetInt64("batch-size")),
+			Timeout:   embedding.GetDuration("timeout"),
+		})
+		x.Checkf(err, "Invalid --embedding")
+		edgraph.InitEmbedding(e)
+	}
+
+	compression := z.NewSuperFlag(Alpha.Conf.GetString("compression")).MergeAndCheckDefault(
+		worker.CompressionDefaults)
+	x.Check(x.SetCompression(int(compression.GetInt64("min-size")),
+		compression.GetString("level")))
+
+	metrics := z.NewSuperFlag(Alpha.Conf.GetString("metrics")).MergeAndCheckDefault(
+		worker.MetricsDefaults)
+	x.Check(x.SetScopedMetrics(metrics.GetString("predicates"), metrics.GetString("namespaces"),
+		metrics.GetBool("exemplars")))
+
+	capture := z.NewSuperFlag(Alpha.Conf.GetString("capture")).MergeAndCheckDefault(
+		worker.CaptureDefaults)
+	if dir := capture.GetString("dir"); dir != "" {
+		x.Check(x.InitQueryCapture(dir, capture.GetFloat64("sample"),
+			int64(capture.GetUint64("size-mb")), int64(capture.GetUint64("days"))))
+	}
+
+	profiling, err := x.ParseProfilingOptions(Alpha.Conf.GetString("profiling"), "dgraph.alpha")
+	x.Check(err)
+
 	// feature flags
 	featureFlagsConf := z.NewSuperFlag(Alpha.Conf.GetString("feature-flags")).MergeAndCheckDefault(
 		worker.FeatureFlagsDefaults)
 	x.Config.NormalizeCompatibilityMode = featureFlagsConf.GetString("normalize-compatibility-mode")
 	enableDetailedMetrics := featureFlagsConf.GetBool("enable-detailed-metrics")
+	enablePresenceBitmap := featureFlagsConf.GetBool("presence-bitmap")
+	x.Checkf(x.SetExperimentalPaths(featureFlagsConf.GetString("experimental-paths")),
+		"Invalid --feature-flags experimental-paths")
 
 	x.PrintVersion()
 	glog.Infof("x.Config: %+v", x.Config)
 	glog.Infof("x.WorkerConfig: %+v", x.WorkerConfig)
 	glog.Infof("worker.Config: %+v", worker.Config)
 
+	if location := Alpha.Conf.GetString("install-snapshot"); location != "" {
+		uri, err := url.Parse(location)
+		x.Checkf(err, "Invalid --install-snapshot location %q", location)
+		x.Checkf(worker.InstallSnapshot(uri, worker.Config.PostingDir),
+			"While installing the snapshot at %s", uri.Redacted())
+	}
 	worker.InitServerState()
 	worker.InitTasks()
 
@@ -811,6 +1278,7 @@ func run() {
 	schema.Init(worker.State.Pstore)
 	posting.Init(worker.State.Pstore, postingListCacheSize, removeOnUpdate)
 	posting.SetEnabledDetailedMetrics(enableDetailedMetrics)
+	posting.SetEnablePresenceBitmap(enablePresenceBitmap)
 	defer posting.Cleanup()
 	worker.Init(worker.State.Pstore)
 
@@ -847,8 +1315,11 @@ func run() {
 		}
 	}()
 
-	updaters := z.NewCloser(2)
+	updaters := z.NewCloser(7)
+	updaters.AddRunning(1)
+	go x.MonitorFlightRecorder(updaters)
 	go func() {
+		defer x.DumpFlightRecorderOnPanic()
 		worker.StartRaftNodes(worker.State.WALstore, bindall)
 		atomic.AddUint32(&initDone, 1)
 
@@ -856,6 +1327,26 @@ func run() {
 		// and health check passes
 		edgraph.InitializeAcl(updaters)
 		edgraph.RefreshACLs(updaters.Ctx())
+		go edgraph.SyncLdap(updaters)
+		go edgraph.SubscribeForTriggerUpdates(updaters)
+		go edgraph.SubscribeForWasmUpdates(updaters)
+		go edgraph.SubscribeForFunctionUpdates(updaters)
+		go edgraph.SubscribeForSandboxUpdates(updaters)
+		go edgraph.PurgeIdempotencyKeys(updaters)
+		if profiling != nil {
+			profiling.Labels = func() map[string]string {
+				labels := map[string]string{
+					"node":  strconv.FormatUint(worker.NodeId(), 10),
+					"group": strconv.FormatUint(uint64(worker.GroupId()), 10),
+				}
+				if ns, ok := edgraph.BusiestNamespace(); ok {
+					labels["namespace"] = strconv.FormatUint(ns, 10)
+				}
+				return labels
+			}
+			updaters.AddRunning(1)
+			go x.PushProfiles(profiling, updaters)
+		}
 		edgraph.SubscribeForAclUpdates(updaters)
 	}()
 
@@ -863,7 +1354,7 @@ func run() {
 	// close alpha. This closer is for closing and waiting that subscription.
 	adminCloser := z.NewCloser(1)
 
-	setupServer(adminCloser, enableMcp)
+	setupServer(adminCloser, enableMcp, enableConsole)
 	glog.Infoln("GRPC and HTTP stopped.")
 
 	// This might not close until group is given the signal to close. So, only signal here,
@@ -877,6 +1368,8 @@ func run() {
 	glog.Infoln("adminCloser closed.")
 
 	audit.Close()
+	x.CloseQueryCapture()
+	plugin.CloseAll()
 
 	worker.State.Dispose()
 	glog.Info("worker.State disposed.")
@@ -886,3 +1379,20 @@ func run() {
 
 	glog.Infoln("Server shutdown. Bye!")
 }
+
+// reloadNamespaceKeys periodically reloads the namespace keys so that rotated or destroyed keys
+// take effect without a restart.
+func reloadNamespaceKeys(closer *z.Closer) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			if err := posting.ReloadNamespaceKeys(); err != nil {
+				glog.Errorf("Unable to reload namespace encryption keys: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package bench generates synthetic graphs with their query workloads and runs them against a
+// cluster, so that the performance of
+// Dgraph can be tracked and its regressions reproduced without production data.
+package bench
+
+import (
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// Bench is the sub-command invoked when calling "dgraph bench".
+var Bench x.SubCommand
+
+func init() {
+	Bench.Cmd = &cobra.Command{
+		Use:         "bench",
+		Short:       "Dgraph benchmark tools",
+		Annotations: map[string]string{"group": "tool"},
+	}
+	Bench.Cmd.SetHelpTemplate(x.NonRootTemplate)
+
+	initDatagen()
+	initRun()
+	for _, sc := range []*x.SubCommand{&Datagen, &Run} {
+		Bench.Cmd.AddCommand(sc.Cmd)
+		sc.Conf = viper.New()
+		if err := sc.Conf.BindPFlags(sc.Cmd.Flags()); err != nil {
+			glog.Fatalf("Unable to bind flags for command %v: %v", sc, err)
+		}
+		sc.Conf.SetEnvPrefix(sc.EnvPrefix)
+	}
+}
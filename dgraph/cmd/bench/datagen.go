@@ -0,0 +1,562 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bench
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	graphDefaults = `nodes=10000; types=3; edge-preds=2; value-preds=5; cardinality=1000; ` +
+		`vector-dim=0;`
+	degreeDefaults   = `dist=zipf; min=0; max=50; skew=1.5;`
+	workloadDefaults = `queries=1000; lookup=40; traversal=30; aggregation=20; vector=10;`
+
+	// The files written by datagen in its output directory.
+	configFile  = "bench.config.json"
+	schemaFile  = "bench.schema"
+	queriesFile = "bench.queries.jsonl"
+)
+
+// Datagen is the sub-command invoked when calling "dgraph bench datagen".
+var Datagen x.SubCommand
+
+func initDatagen() {
+	Datagen.Cmd = &cobra.Command{
+		Use:   "datagen",
+		Short: "Generate a synthetic graph with its query workload",
+		Long: `
+Generate a synthetic graph, its schema and a query workload. The same seed and options always
+generate the same files, so that a benchmark can be reproduced anywhere. The graph is loaded with
+dgraph live -f <out>/bench.rdf.gz -s <out>/bench.schema, or the bulk loader.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runDatagen(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	Datagen.EnvPrefix = "DGRAPH_BENCH_DATAGEN"
+
+	flag := Datagen.Cmd.Flags()
+	flag.Int64("seed", 1, "Seed of the random generator.")
+	flag.String("out", "bench", "Directory where the files are written.")
+	flag.String("format", "rdf", "Format of the graph, rdf or json. It's gzipped.")
+	flag.String("graph", graphDefaults, z.NewSuperFlagHelp(graphDefaults).
+		Head("Shape of the graph").
+		Flag("nodes", "Number of nodes.").
+		Flag("types", "Number of types, the nodes being spread evenly across them.").
+		Flag("edge-preds", "Number of uid predicates, each node having edges of all of them.").
+		Flag("value-preds", "Number of value predicates, each node having a value of all of "+
+			"them. Their types cycle through string, int, float, datetime and bool.").
+		Flag("cardinality", "Number of distinct values of each value predicate.").
+		Flag("vector-dim", "Dimension of the float32vector predicate bench.embedding, 0 for none.").
+		String())
+	flag.String("degree", degreeDefaults, z.NewSuperFlagHelp(degreeDefaults).
+		Head("Distribution of the number of edges of each node and uid predicate").
+		Flag("dist", "const (always max), uniform (between min and max) or zipf (between min "+
+			"and max, skewed towards min).").
+		Flag("min", "Minimum number of edges.").
+		Flag("max", "Maximum number of edges.").
+		Flag("skew", "Skew of the zipf distribution, greater than 1.").
+		String())
+	flag.String("workload", workloadDefaults, z.NewSuperFlagHelp(workloadDefaults).
+		Head("Query workload, with the relative weights of the kinds of queries").
+		Flag("queries", "Number of queries.").
+		Flag("lookup", "Point lookups of a node by id, with its values.").
+		Flag("traversal", "3-hop traversals from a node.").
+		Flag("aggregation", "Aggregations over the nodes of a type.").
+		Flag("vector", "Vector similarity searches, if vector-dim isn't 0.").
+		String())
+}
+
+// degreeConfig is the distribution of the number of edges of each node and uid predicate.
+type degreeConfig struct {
+	Dist string  `json:"dist"`
+	Min  int     `json:"min"`
+	Max  int     `json:"max"`
+	Skew float64 `json:"skew"`
+}
+
+// graphConfig is the shape of a synthetic graph. It's written next to it, so that its workloads
+// can be generated again.
+type graphConfig struct {
+	Seed        int64        `json:"seed"`
+	Nodes       int          `json:"nodes"`
+	Types       int          `json:"types"`
+	EdgePreds   int          `json:"edge_preds"`
+	ValuePreds  int          `json:"value_preds"`
+	Cardinality int          `json:"cardinality"`
+	VectorDim   int          `json:"vector_dim"`
+	Degree      degreeConfig `json:"degree"`
+}
+
+func (c *graphConfig) validate() error {
+	switch {
+	case c.Nodes <= 0 || c.Types <= 0 || c.Cardinality <= 0:
+		return errors.Errorf("the nodes, types and cardinality of the graph should be positive")
+	case c.EdgePreds < 0 || c.ValuePreds < 0 || c.VectorDim < 0:
+		return errors.Errorf("the edge-preds, value-preds and vector-dim of the graph can't be " +
+			"negative")
+	case c.Degree.Min < 0 || c.Degree.Max < c.Degree.Min:
+		return errors.Errorf("the degree should have 0 <= min <= max")
+	}
+	switch c.Degree.Dist {
+	case "const", "uniform":
+	case "zipf":
+		if c.Degree.Skew <= 1 {
+			return errors.Errorf("the skew of the zipf degree should be greater than 1")
+		}
+	default:
+		return errors.Errorf("unknown degree distribution %q", c.Degree.Dist)
+	}
+	return nil
+}
+
+// The value predicates are named bench.v<i>, their types cycling through valueKinds.
+var valueKinds = []struct {
+	typ, index, xsd string
+}{
+	{"string", "exact", ""},
+	{"int", "int", "xs:int"},
+	{"float", "float", "xs:float"},
+	{"datetime", "hour", "xs:dateTime"},
+	{"bool", "bool", "xs:boolean"},
+}
+
+// valueEpoch is the first value of the datetime predicates, which are an hour apart.
+var valueEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func typeName(i int) string  { return fmt.Sprintf("BenchType%d", i) }
+func edgePred(i int) string  { return fmt.Sprintf("bench.e%d", i) }
+func valuePred(i int) string { return fmt.Sprintf("bench.v%d", i) }
+func nodeId(i int) string    { return fmt.Sprintf("n%d", i) }
+
+// schema returns the DQL schema of the graph.
+func (c *graphConfig) schema() string {
+	var b strings.Builder
+	fields := []string{"bench.id"}
+	b.WriteString("bench.id: string @index(exact) @upsert .\n")
+	for i := 0; i < c.ValuePreds; i++ {
+		k := valueKinds[i%len(valueKinds)]
+		fmt.Fprintf(&b, "%s: %s @index(%s) .\n", valuePred(i), k.typ, k.index)
+		fields = append(fields, valuePred(i))
+	}
+	for i := 0; i < c.EdgePreds; i++ {
+		fmt.Fprintf(&b, "%s: [uid] @count @reverse .\n", edgePred(i))
+		fields = append(fields, edgePred(i))
+	}
+	if c.VectorDim > 0 {
+		b.WriteString(`bench.embedding: float32vector @index(hnsw(metric:"euclidean")) .` + "\n")
+		fields = append(fields, "bench.embedding")
+	}
+	for t := 0; t < c.Types; t++ {
+		fmt.Fprintf(&b, "\ntype %s {\n\t%s\n}\n", typeName(t), strings.Join(fields, "\n\t"))
+	}
+	return b.String()
+}
+
+// genNode is a node of the graph.
+type genNode struct {
+	idx    int
+	values []interface{}
+	vector []float32
+	// edges holds the indexes of the targets of the edges, by uid predicate.
+	edges [][]int
+}
+
+// generator generates the nodes of a graph, always the same for the same config.
+type generator struct {
+	c    *graphConfig
+	rnd  *rand.Rand
+	zipf *rand.Zipf
+}
+
+func newGenerator(c *graphConfig) *generator {
+	g := &generator{c: c, rnd: rand.New(rand.NewSource(c.Seed))}
+	if c.Degree.Dist == "zipf" && c.Degree.Max > c.Degree.Min {
+		g.zipf = rand.NewZipf(g.rnd, c.Degree.Skew, 1, uint64(c.Degree.Max-c.Degree.Min))
+	}
+	return g
+}
+
+func (g *generator) degree() int {
+	d := g.c.Degree
+	switch {
+	case d.Dist == "const":
+		return d.Max
+	case d.Max == d.Min:
+		return d.Min
+	case d.Dist == "uniform":
+		return d.Min + g.rnd.Intn(d.Max-d.Min+1)
+	}
+	return d.Min + int(g.zipf.Uint64())
+}
+
+// value returns a random value of the i-th value predicate.
+func (g *generator) value(i int) interface{} {
+	k := g.rnd.Intn(g.c.Cardinality)
+	switch valueKinds[i%len(valueKinds)].typ {
+	case "int":
+		return int64(k)
+	case "float":
+		return float64(k) / 10
+	case "datetime":
+		return valueEpoch.Add(time.Duration(k) * time.Hour)
+	case "bool":
+		return k%2 == 0
+	}
+	return fmt.Sprintf("v%d", k)
+}
+
+func (g *generator) vector() []float32 {
+	v := make([]float32, g.c.VectorDim)
+	for i := range v {
+		v[i] = float32(g.rnd.Intn(10000)) / 10000
+	}
+	return v
+}
+
+func (g *generator) node(idx int) *genNode {
+	n := &genNode{idx: idx}
+	for i := 0; i < g.c.ValuePreds; i++ {
+		n.values = append(n.values, g.value(i))
+	}
+	if g.c.VectorDim > 0 {
+		n.vector = g.vector()
+	}
+	for i := 0; i < g.c.EdgePreds; i++ {
+		targets := make([]int, g.degree())
+		for j := range targets {
+			targets[j] = g.rnd.Intn(g.c.Nodes)
+		}
+		n.edges = append(n.edges, targets)
+	}
+	return n
+}
+
+func formatVector(v []float32) string {
+	parts := make([]string, len(v))
+	for i, f := range v {
+		parts[i] = strconv.FormatFloat(float64(f), 'f', -1, 32)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// writeRDF writes the node as N-Quads.
+func writeRDF(w io.Writer, n *genNode, types int) error {
+	var b strings.Builder
+	subject := "_:" + nodeId(n.idx)
+	fmt.Fprintf(&b, "%s <dgraph.type> %q .\n", subject, typeName(n.idx%types))
+	fmt.Fprintf(&b, "%s <bench.id> %q .\n", subject, nodeId(n.idx))
+	for i, v := range n.values {
+		lit := fmt.Sprint(v)
+		if t, ok := v.(time.Time); ok {
+			lit = t.Format(time.RFC3339)
+		}
+		fmt.Fprintf(&b, "%s <%s> %q", subject, valuePred(i), lit)
+		if xsd := valueKinds[i%len(valueKinds)].xsd; xsd != "" {
+			fmt.Fprintf(&b, "^^<%s>", xsd)
+		}
+		b.WriteString(" .\n")
+	}
+	if n.vector != nil {
+		fmt.Fprintf(&b, "%s <bench.embedding> %q .\n", subject, formatVector(n.vector))
+	}
+	for i, targets := range n.edges {
+		for _, t := range targets {
+			fmt.Fprintf(&b, "%s <%s> _:%s .\n", subject, edgePred(i), nodeId(t))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// jsonNode returns the node as a JSON object.
+func jsonNode(n *genNode, types int) map[string]interface{} {
+	obj := map[string]interface{}{
+		"uid":         "_:" + nodeId(n.idx),
+		"dgraph.type": typeName(n.idx % types),
+		"bench.id":    nodeId(n.idx),
+	}
+	for i, v := range n.values {
+		obj[valuePred(i)] = v
+	}
+	if n.vector != nil {
+		obj["bench.embedding"] = formatVector(n.vector)
+	}
+	for i, targets := range n.edges {
+		uids := make([]map[string]string, len(targets))
+		for j, t := range targets {
+			uids[j] = map[string]string{"uid": "_:" + nodeId(t)}
+		}
+		obj[edgePred(i)] = uids
+	}
+	return obj
+}
+
+// writeGraph writes the nodes of the graph in the format, rdf or json.
+func writeGraph(w io.Writer, c *graphConfig, format string) error {
+	g := newGenerator(c)
+	if format == "json" {
+		if _, err := io.WriteString(w, "[\n"); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < c.Nodes; i++ {
+		n := g.node(i)
+		if format == "rdf" {
+			if err := writeRDF(w, n, c.Types); err != nil {
+				return err
+			}
+			continue
+		}
+		b, err := json.Marshal(jsonNode(n, c.Types))
+		if err != nil {
+			return err
+		}
+		if i < c.Nodes-1 {
+			b = append(b, ',')
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	if format == "json" {
+		_, err := io.WriteString(w, "]\n")
+		return err
+	}
+	return nil
+}
+
+// queryKinds are the kinds of queries of the workloads.
+var queryKinds = []string{"lookup", "traversal", "aggregation", "vector"}
+
+// benchQuery is a query of a workload.
+type benchQuery struct {
+	Kind  string            `json:"kind"`
+	Query string            `json:"query"`
+	Vars  map[string]string `json:"vars,omitempty"`
+}
+
+// workloadConfig is the number of queries of a workload, and the weights of their kinds.
+type workloadConfig struct {
+	Queries int
+	Weights map[string]int
+}
+
+// queryGenerator generates the queries of the workloads of a graph.
+type queryGenerator struct {
+	c   *graphConfig
+	rnd *rand.Rand
+}
+
+func newQueryGenerator(c *graphConfig, seed int64) *queryGenerator {
+	return &queryGenerator{c: c, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// supports returns true if the graph has the predicates needed by the kind of queries.
+func (qg *queryGenerator) supports(kind string) bool {
+	switch kind {
+	case "traversal":
+		return qg.c.EdgePreds > 0
+	case "vector":
+		return qg.c.VectorDim > 0
+	}
+	return true
+}
+
+func (qg *queryGenerator) query(kind string) *benchQuery {
+	c := qg.c
+	id := map[string]string{"$id": nodeId(qg.rnd.Intn(c.Nodes))}
+	switch kind {
+	case "lookup":
+		fields := []string{"uid", "bench.id"}
+		for i := 0; i < c.ValuePreds; i++ {
+			fields = append(fields, valuePred(i))
+		}
+		return &benchQuery{Kind: kind, Vars: id, Query: fmt.Sprintf(
+			"query q($id: string) { q(func: eq(bench.id, $id)) { %s } }",
+			strings.Join(fields, " "))}
+	case "traversal":
+		hop := func() string { return edgePred(qg.rnd.Intn(c.EdgePreds)) }
+		return &benchQuery{Kind: kind, Vars: id, Query: fmt.Sprintf(
+			"query q($id: string) { q(func: eq(bench.id, $id)) { uid %s { uid %s { uid %s { "+
+				"count(uid) } } } } }", hop(), hop(), hop())}
+	case "aggregation":
+		typ := typeName(qg.rnd.Intn(c.Types))
+		if c.ValuePreds < 2 {
+			return &benchQuery{Kind: kind, Query: fmt.Sprintf(
+				"{ q(func: type(%s)) { count(uid) } }", typ)}
+		}
+		// bench.v1 is an int.
+		return &benchQuery{Kind: kind, Query: fmt.Sprintf("{ var(func: type(%s)) { "+
+			"v as bench.v1 } q() { min: min(val(v)) max: max(val(v)) sum: sum(val(v)) } }", typ)}
+	case "vector":
+		gen := &generator{c: c, rnd: qg.rnd}
+		return &benchQuery{Kind: kind,
+			Vars: map[string]string{"$vec": formatVector(gen.vector())},
+			Query: "query q($vec: float32vector) { q(func: similar_to(bench.embedding, 10, " +
+				"$vec)) { bench.id } }"}
+	}
+	return nil
+}
+
+// workload returns the queries of the workload, their kinds picked at random according to their
+// weights among the ones supported by the graph.
+func (qg *queryGenerator) workload(w workloadConfig) ([]*benchQuery, error) {
+	var kinds []string
+	var total int
+	for _, kind := range queryKinds {
+		if weight := w.Weights[kind]; weight > 0 && qg.supports(kind) {
+			kinds = append(kinds, kind)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return nil, errors.Errorf("the workload has no kind of queries supported by the graph")
+	}
+	queries := make([]*benchQuery, 0, w.Queries)
+	for len(queries) < w.Queries {
+		pick := qg.rnd.Intn(total)
+		for _, kind := range kinds {
+			if pick -= w.Weights[kind]; pick < 0 {
+				queries = append(queries, qg.query(kind))
+				break
+			}
+		}
+	}
+	return queries, nil
+}
+
+func parseWorkload(flag string) workloadConfig {
+	sf := z.NewSuperFlag(flag).MergeAndCheckDefault(workloadDefaults)
+	w := workloadConfig{Queries: int(sf.GetUint64("queries")), Weights: make(map[string]int)}
+	for _, kind := range queryKinds {
+		w.Weights[kind] = int(sf.GetUint64(kind))
+	}
+	return w
+}
+
+// writeFile writes the file of the directory with write, gzipped if its name ends with .gz.
+func writeFile(dir, name string, write func(w io.Writer) error) error {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriterSize(f, 1<<20)
+	var w io.Writer = bw
+	var gz *gzip.Writer
+	if strings.HasSuffix(name, ".gz") {
+		gz = gzip.NewWriter(bw)
+		w = gz
+	}
+	err = write(w)
+	if gz != nil && err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return errors.Wrapf(err, "while writing %s", name)
+}
+
+// datagen writes the graph, its schema, its config and its workload to the directory.
+func datagen(dir string, c *graphConfig, format string, w workloadConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if format != "rdf" && format != "json" {
+		return errors.Errorf("unknown format %q, it should be rdf or json", format)
+	}
+	queries, err := newQueryGenerator(c, c.Seed+1).workload(w)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	err = writeFile(dir, configFile, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeFile(dir, schemaFile, func(w io.Writer) error {
+		_, err := io.WriteString(w, c.schema())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = writeFile(dir, queriesFile, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, q := range queries {
+			if err := enc.Encode(q); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(dir, "bench."+format+".gz", func(w io.Writer) error {
+		return writeGraph(w, c, format)
+	})
+}
+
+func runDatagen() error {
+	conf := Datagen.Conf
+	graph := z.NewSuperFlag(conf.GetString("graph")).MergeAndCheckDefault(graphDefaults)
+	degree := z.NewSuperFlag(conf.GetString("degree")).MergeAndCheckDefault(degreeDefaults)
+	c := &graphConfig{
+		Seed:        conf.GetInt64("seed"),
+		Nodes:       int(graph.GetInt64("nodes")),
+		Types:       int(graph.GetInt64("types")),
+		EdgePreds:   int(graph.GetInt64("edge-preds")),
+		ValuePreds:  int(graph.GetInt64("value-preds")),
+		Cardinality: int(graph.GetInt64("cardinality")),
+		VectorDim:   int(graph.GetInt64("vector-dim")),
+		Degree: degreeConfig{
+			Dist: degree.GetString("dist"),
+			Min:  int(degree.GetInt64("min")),
+			Max:  int(degree.GetInt64("max")),
+			Skew: degree.GetFloat64("skew"),
+		},
+	}
+	dir, format := conf.GetString("out"), conf.GetString("format")
+	start := time.Now()
+	if err := datagen(dir, c, format, parseWorkload(conf.GetString("workload"))); err != nil {
+		return err
+	}
+	fmt.Printf("Generated %d nodes and their workload in %s in %v\n", c.Nodes, dir,
+		time.Since(start).Round(time.Millisecond))
+	return nil
+}
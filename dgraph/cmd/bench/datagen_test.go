@@ -0,0 +1,142 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bench
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/chunker"
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+)
+
+func testGraph() *graphConfig {
+	return &graphConfig{Seed: 7, Nodes: 50, Types: 2, EdgePreds: 2, ValuePreds: 5,
+		Cardinality: 10, VectorDim: 4, Degree: degreeConfig{Dist: "zipf", Max: 5, Skew: 1.5}}
+}
+
+func readGz(t *testing.T, path string) []byte {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return b
+}
+
+func TestDatagenDeterministic(t *testing.T) {
+	w := parseWorkload("queries=20;")
+	dirs := []string{t.TempDir(), t.TempDir()}
+	for _, dir := range dirs {
+		require.NoError(t, datagen(dir, testGraph(), "rdf", w))
+	}
+	for _, name := range []string{configFile, schemaFile, queriesFile, "bench.rdf.gz"} {
+		a, err := os.ReadFile(filepath.Join(dirs[0], name))
+		require.NoError(t, err)
+		b, err := os.ReadFile(filepath.Join(dirs[1], name))
+		require.NoError(t, err)
+		require.Equal(t, a, b, name)
+	}
+
+	// Another seed generates another graph.
+	c := testGraph()
+	c.Seed = 8
+	dir := t.TempDir()
+	require.NoError(t, datagen(dir, c, "rdf", w))
+	require.NotEqual(t, readGz(t, filepath.Join(dirs[0], "bench.rdf.gz")),
+		readGz(t, filepath.Join(dir, "bench.rdf.gz")))
+}
+
+func TestDatagenOutput(t *testing.T) {
+	c := testGraph()
+	_, err := schema.Parse(c.schema())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, datagen(dir, c, "rdf", parseWorkload("queries=100;")))
+	nquads, _, err := chunker.ParseRDFs(readGz(t, filepath.Join(dir, "bench.rdf.gz")))
+	require.NoError(t, err)
+	ids := make(map[string]bool)
+	for _, nq := range nquads {
+		if nq.Predicate == "bench.id" {
+			ids[nq.Subject] = true
+		}
+	}
+	require.Len(t, ids, c.Nodes)
+
+	require.NoError(t, datagen(dir, c, "json", parseWorkload("queries=1;")))
+	nquads, _, err = chunker.ParseJSON(readGz(t, filepath.Join(dir, "bench.json.gz")),
+		chunker.SetNquads)
+	require.NoError(t, err)
+	require.NotEmpty(t, nquads)
+
+	f, err := os.Open(filepath.Join(dir, queriesFile))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+	kinds := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var q benchQuery
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &q))
+		kinds[q.Kind]++
+	}
+	require.Len(t, kinds, 1)
+}
+
+func TestWorkloadKinds(t *testing.T) {
+	c := testGraph()
+	queries, err := newQueryGenerator(c, 1).workload(parseWorkload("queries=100;"))
+	require.NoError(t, err)
+	for _, q := range queries {
+		_, err := dql.Parse(dql.Request{Str: q.Query, Variables: q.Vars})
+		require.NoError(t, err, q.Query)
+	}
+
+	c.VectorDim, c.EdgePreds = 0, 0
+	queries, err = newQueryGenerator(c, 1).workload(parseWorkload("queries=200;"))
+	require.NoError(t, err)
+	kinds := make(map[string]int)
+	for _, q := range queries {
+		kinds[q.Kind]++
+	}
+	// The graph has no edges nor vectors to query.
+	require.Len(t, queries, 200)
+	require.Zero(t, kinds["traversal"])
+	require.Zero(t, kinds["vector"])
+	require.NotZero(t, kinds["lookup"])
+	require.NotZero(t, kinds["aggregation"])
+
+	_, err = newQueryGenerator(c, 1).workload(parseWorkload(
+		"traversal=1; vector=1; lookup=0; aggregation=0;"))
+	require.Error(t, err)
+}
+
+func TestDegree(t *testing.T) {
+	c := testGraph()
+	c.Degree = degreeConfig{Dist: "uniform", Min: 2, Max: 4}
+	g := newGenerator(c)
+	for range 100 {
+		d := g.degree()
+		require.True(t, d >= 2 && d <= 4, d)
+	}
+	c.Degree = degreeConfig{Dist: "const", Max: 3}
+	require.Equal(t, 3, newGenerator(c).degree())
+
+	c.Degree = degreeConfig{Dist: "zipf", Max: 3, Skew: 1}
+	require.Error(t, c.validate())
+	c.Degree = degreeConfig{Dist: "normal", Max: 3}
+	require.Error(t, c.validate())
+}
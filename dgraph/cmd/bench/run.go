@@ -0,0 +1,341 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bench
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// Run is the sub-command invoked when calling "dgraph bench run".
+var Run x.SubCommand
+
+func initRun() {
+	Run.Cmd = &cobra.Command{
+		Use:   "run",
+		Short: "Run the workload of a synthetic graph against a cluster",
+		Long: `
+Run the query workload generated by dgraph bench datagen against a cluster holding its graph, and
+report the throughput and latencies of each kind of queries: point lookups, 3-hop traversals,
+aggregations and vector searches. The results can be saved, and compared with the ones of a
+previous run to catch the regressions from a release to the next.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBench(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	Run.EnvPrefix = "DGRAPH_BENCH_RUN"
+
+	flag := Run.Cmd.Flags()
+	// --tls SuperFlag
+	x.RegisterClientTLSFlags(flag)
+
+	flag.String("data", "bench", "Directory of the files generated by dgraph bench datagen.")
+	flag.String("alpha", "localhost:9080", "Comma separated list of the Alphas to query.")
+	flag.Int("retries", 10, "How many times to retry setting up the connection.")
+	flag.String("creds", "",
+		`Various login credentials if login is required.
+	user defines the username to login.
+	password defines the password of the user.
+	namespace defines the namespace to log into.
+	Sample flag could look like --creds user=username;password=mypass;namespace=2`)
+	flag.Int("conc", 8, "Number of queries run at once.")
+	flag.Duration("duration", 0, "How long the workload is run, looping over its queries. It's "+
+		"run once if 0.")
+	flag.Duration("warmup", 0, "How long the workload is run before measuring it, with --duration.")
+	flag.Duration("timeout", time.Minute, "Timeout of each query.")
+	flag.String("label", "", "Label of the run saved with the results, like the Dgraph version.")
+	flag.String("out", "", "File where the results are saved as JSON, to be used as a baseline.")
+	flag.String("baseline", "", "File of the results of a previous run to compare with. The "+
+		"benchmark fails if a kind of queries is slower than in the baseline by more than the "+
+		"tolerance.")
+	flag.Float64("tolerance", 0.1, "Fraction by which the latencies can be higher and the "+
+		"throughput lower than in the baseline.")
+}
+
+// readWorkload reads the queries of the workload generated by datagen in dir.
+func readWorkload(dir string) ([]*benchQuery, error) {
+	f, err := os.Open(filepath.Join(dir, queriesFile))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var queries []*benchQuery
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), x.GrpcMaxSize)
+	for scanner.Scan() {
+		q := &benchQuery{}
+		if err := json.Unmarshal(scanner.Bytes(), q); err != nil {
+			return nil, errors.Wrapf(err, "while reading the workload")
+		}
+		queries = append(queries, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "while reading the workload")
+	}
+	if len(queries) == 0 {
+		return nil, errors.Errorf("the workload in %s has no queries", dir)
+	}
+	return queries, nil
+}
+
+// runOptions are the options of a run of a workload.
+type runOptions struct {
+	conc     int
+	duration time.Duration
+	warmup   time.Duration
+	timeout  time.Duration
+}
+
+// queryFunc runs a query of the workload.
+type queryFunc func(ctx context.Context, q *benchQuery) error
+
+// kindStats are the stats of a kind of queries during a run.
+type kindStats struct {
+	sync.Mutex
+	errors  int64
+	latency *hdrhistogram.Histogram
+}
+
+// runWorkload runs the queries with conc workers, once or looping over them for the duration,
+// and returns the stats of each kind of queries with the time they were measured for. When looping,
+// the queries run during the warmup aren't measured.
+func runWorkload(ctx context.Context, queries []*benchQuery, opts runOptions,
+	query queryFunc) (map[string]*kindStats, time.Duration) {
+
+	stats := make(map[string]*kindStats)
+	for _, q := range queries {
+		if stats[q.Kind] == nil {
+			// The latencies are in microseconds.
+			stats[q.Kind] = &kindStats{latency: hdrhistogram.New(1, time.Hour.Microseconds(), 3)}
+		}
+	}
+	loop := opts.duration > 0
+	measureFrom := time.Now()
+	if loop {
+		measureFrom = measureFrom.Add(opts.warmup)
+	}
+	end := measureFrom.Add(opts.duration)
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range opts.conc {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if !loop && i >= len(queries) {
+					return
+				}
+				if loop && !time.Now().Before(end) {
+					return
+				}
+				q := queries[i%len(queries)]
+				qctx, cancel := context.WithTimeout(ctx, opts.timeout)
+				qstart := time.Now()
+				err := query(qctx, q)
+				latency := time.Since(qstart)
+				cancel()
+				if qstart.Before(measureFrom) {
+					continue
+				}
+				s := stats[q.Kind]
+				s.Lock()
+				if err != nil {
+					s.errors++
+				} else {
+					_ = s.latency.RecordValue(latency.Microseconds())
+				}
+				s.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return stats, time.Since(measureFrom)
+}
+
+// kindResult is the result of a kind of queries, the latencies being in nanoseconds.
+type kindResult struct {
+	Kind    string        `json:"kind"`
+	Queries int64         `json:"queries"`
+	Errors  int64         `json:"errors"`
+	QPS     float64       `json:"qps"`
+	P50     time.Duration `json:"p50"`
+	P95     time.Duration `json:"p95"`
+	P99     time.Duration `json:"p99"`
+	Max     time.Duration `json:"max"`
+}
+
+// benchResults are the results of a run, saved to be compared with the next runs.
+type benchResults struct {
+	Label string        `json:"label,omitempty"`
+	Time  time.Time     `json:"time"`
+	Graph *graphConfig  `json:"graph,omitempty"`
+	Kinds []*kindResult `json:"kinds"`
+}
+
+func newResults(stats map[string]*kindStats, elapsed time.Duration) []*kindResult {
+	var res []*kindResult
+	for kind, s := range stats {
+		d := func(v int64) time.Duration { return time.Duration(v) * time.Microsecond }
+		r := &kindResult{
+			Kind:    kind,
+			Queries: s.latency.TotalCount(),
+			Errors:  s.errors,
+			P50:     d(s.latency.ValueAtQuantile(50)),
+			P95:     d(s.latency.ValueAtQuantile(95)),
+			P99:     d(s.latency.ValueAtQuantile(99)),
+			Max:     d(s.latency.Max()),
+		}
+		if elapsed > 0 {
+			r.QPS = float64(r.Queries) / elapsed.Seconds()
+		}
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Kind < res[j].Kind })
+	return res
+}
+
+func (r *benchResults) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %10s %8s %10s %12s %12s %12s %12s\n", "kind", "queries", "errors",
+		"qps", "p50", "p95", "p99", "max")
+	for _, k := range r.Kinds {
+		fmt.Fprintf(&b, "%-12s %10d %8d %10.1f %12v %12v %12v %12v\n", k.Kind, k.Queries,
+			k.Errors, k.QPS, k.P50, k.P95, k.P99, k.Max)
+	}
+	return b.String()
+}
+
+// compareBaseline returns the regressions of the results from the baseline: the kinds of queries
+// whose latencies are higher, or whose throughput is lower, by more than the tolerance.
+func compareBaseline(res, baseline *benchResults, tolerance float64) []string {
+	base := make(map[string]*kindResult)
+	for _, k := range baseline.Kinds {
+		base[k.Kind] = k
+	}
+	var regressions []string
+	for _, k := range res.Kinds {
+		b, ok := base[k.Kind]
+		if !ok {
+			continue
+		}
+		for _, l := range []struct {
+			name      string
+			cur, base time.Duration
+		}{{"p50", k.P50, b.P50}, {"p95", k.P95, b.P95}, {"p99", k.P99, b.P99}} {
+			if float64(l.cur) > float64(l.base)*(1+tolerance) {
+				regressions = append(regressions, fmt.Sprintf("%s %s latency is %v, up from %v",
+					k.Kind, l.name, l.cur, l.base))
+			}
+		}
+		if k.QPS < b.QPS*(1-tolerance) {
+			regressions = append(regressions, fmt.Sprintf("%s throughput is %.1f qps, down "+
+				"from %.1f", k.Kind, k.QPS, b.QPS))
+		}
+		if k.Errors > b.Errors {
+			regressions = append(regressions, fmt.Sprintf("%s has %d errors, up from %d",
+				k.Kind, k.Errors, b.Errors))
+		}
+	}
+	return regressions
+}
+
+func readResults(name string) (*benchResults, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	res := &benchResults{}
+	if err := json.Unmarshal(b, res); err != nil {
+		return nil, errors.Wrapf(err, "while reading the results in %s", name)
+	}
+	return res, nil
+}
+
+func runBench() error {
+	conf := Run.Conf
+	dir := conf.GetString("data")
+	queries, err := readWorkload(dir)
+	if err != nil {
+		return err
+	}
+	opts := runOptions{
+		conc:     conf.GetInt("conc"),
+		duration: conf.GetDuration("duration"),
+		warmup:   conf.GetDuration("warmup"),
+		timeout:  conf.GetDuration("timeout"),
+	}
+	if opts.conc <= 0 {
+		return errors.Errorf("--conc should be positive")
+	}
+	var baseline *benchResults
+	if name := conf.GetString("baseline"); name != "" {
+		if baseline, err = readResults(name); err != nil {
+			return err
+		}
+	}
+	res := &benchResults{Label: conf.GetString("label"), Time: time.Now().UTC()}
+	if b, err := os.ReadFile(filepath.Join(dir, configFile)); err == nil {
+		res.Graph = &graphConfig{}
+		if err := json.Unmarshal(b, res.Graph); err != nil {
+			return errors.Wrapf(err, "while reading %s", configFile)
+		}
+	}
+
+	dg, closeFunc := x.GetDgraphClient(conf, true)
+	defer closeFunc()
+	query := func(ctx context.Context, q *benchQuery) error {
+		txn := dg.NewReadOnlyTxn()
+		defer func() { _ = txn.Discard(ctx) }()
+		_, err := txn.QueryWithVars(ctx, q.Query, q.Vars)
+		return err
+	}
+
+	fmt.Printf("Running %d queries with %d workers\n", len(queries), opts.conc)
+	stats, elapsed := runWorkload(context.Background(), queries, opts, query)
+	res.Kinds = newResults(stats, elapsed)
+	fmt.Print(res)
+
+	if name := conf.GetString("out"); name != "" {
+		b, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, append(b, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	if baseline == nil {
+		return nil
+	}
+	regressions := compareBaseline(res, baseline, conf.GetFloat64("tolerance"))
+	if len(regressions) == 0 {
+		fmt.Printf("No regression from the baseline %s\n", baseline.Label)
+		return nil
+	}
+	return errors.Errorf("Regressions from the baseline %s:\n%s", baseline.Label,
+		strings.Join(regressions, "\n"))
+}
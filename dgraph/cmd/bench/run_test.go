@@ -0,0 +1,89 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bench
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWorkload(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, datagen(dir, testGraph(), "rdf", parseWorkload("queries=100;")))
+	queries, err := readWorkload(dir)
+	require.NoError(t, err)
+	require.Len(t, queries, 100)
+
+	var runs atomic.Int64
+	query := func(ctx context.Context, q *benchQuery) error {
+		runs.Add(1)
+		if q.Kind == "vector" {
+			return errors.New("no vector index")
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+
+	// Without a duration, the workload runs once.
+	opts := runOptions{conc: 4, timeout: time.Second}
+	stats, elapsed := runWorkload(context.Background(), queries, opts, query)
+	require.Equal(t, int64(100), runs.Load())
+	res := newResults(stats, elapsed)
+	var total int64
+	for _, k := range res {
+		total += k.Queries + k.Errors
+		if k.Kind == "vector" {
+			require.Zero(t, k.Queries)
+			require.Equal(t, k.Errors, stats["vector"].errors)
+		} else {
+			require.Zero(t, k.Errors)
+			require.GreaterOrEqual(t, k.P50, time.Millisecond)
+			require.Positive(t, k.QPS)
+		}
+	}
+	require.Equal(t, int64(100), total)
+
+	// With a duration, it loops over the queries, the warmup not being measured.
+	runs.Store(0)
+	opts.warmup, opts.duration = 50*time.Millisecond, 100*time.Millisecond
+	stats, elapsed = runWorkload(context.Background(), queries, opts, query)
+	require.GreaterOrEqual(t, elapsed, 100*time.Millisecond)
+	total = 0
+	for _, k := range newResults(stats, elapsed) {
+		total += k.Queries + k.Errors
+	}
+	require.Greater(t, runs.Load(), int64(100))
+	require.Less(t, total, runs.Load())
+}
+
+func TestCompareBaseline(t *testing.T) {
+	baseline := &benchResults{Label: "v25.0.0", Kinds: []*kindResult{
+		{Kind: "lookup", QPS: 1000, P50: time.Millisecond, P95: 2 * time.Millisecond,
+			P99: 3 * time.Millisecond},
+		{Kind: "traversal", QPS: 100, P50: 10 * time.Millisecond, P95: 20 * time.Millisecond,
+			P99: 30 * time.Millisecond},
+	}}
+	res := &benchResults{Kinds: []*kindResult{
+		// Within the tolerance.
+		{Kind: "lookup", QPS: 950, P50: 1050 * time.Microsecond, P95: 2 * time.Millisecond,
+			P99: 3 * time.Millisecond},
+		{Kind: "traversal", QPS: 50, P50: 10 * time.Millisecond, P95: 20 * time.Millisecond,
+			P99: 40 * time.Millisecond, Errors: 1},
+		// Not in the baseline.
+		{Kind: "vector", QPS: 1, P50: time.Second},
+	}}
+	require.Equal(t, []string{
+		"traversal p99 latency is 40ms, up from 30ms",
+		"traversal throughput is 50.0 qps, down from 100.0",
+		"traversal has 1 errors, up from 0",
+	}, compareBaseline(res, baseline, 0.1))
+	require.Empty(t, compareBaseline(baseline, baseline, 0))
+}
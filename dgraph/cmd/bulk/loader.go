@@ -11,10 +11,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"hash/adler32"
 	"io"
 	"log"
 	"math"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -60,6 +60,7 @@ type BulkOptions struct {
 	HttpAddr         string
 	IgnoreErrors     bool
 	CustomTokenizers string
+	Plugin           string
 	NewUids          bool
 	ClientDir        string
 	Encrypted        bool
@@ -70,7 +71,17 @@ type BulkOptions struct {
 
 	Namespace uint64
 
+	// ReduceWorkers are the comma separated addresses of the reduce workers, which reduce the
+	// shards instead of this process if set.
+	ReduceWorkers string
+	// ServeReduce is the address this process serves the reduce of the shards at, as a reduce
+	// worker.
+	ServeReduce string
+
 	shardOutputDirs []string
+	// outLocation is the location in object storage the p directories are uploaded to, if the
+	// out directory is one.
+	outLocation *url.URL
 
 	// ........... Badger options ..........
 	// EncryptionKey is the key used for encryption.
@@ -109,15 +120,8 @@ func newLoader(opt *BulkOptions) *loader {
 	if opt.ZeroAddr != "" {
 		fmt.Printf("Connecting to zero at %s\n", opt.ZeroAddr)
 
-		tlsConf, err := x.LoadClientTLSConfigForInternalPort(Bulk.Conf)
-		x.Check(err)
-		dialOpts := []grpc.DialOption{}
-		if tlsConf != nil {
-			dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
-		} else {
-			dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-		}
-		zero, err = grpc.NewClient(opt.ZeroAddr, dialOpts...)
+		var err error
+		zero, err = grpc.NewClient(opt.ZeroAddr, internalDialOptions()...)
 		x.Checkf(err, "Unable to connect to zero, Is it running at %s?", opt.ZeroAddr)
 	}
 
@@ -153,6 +157,17 @@ func newLoader(opt *BulkOptions) *loader {
 	return ld
 }
 
+// internalDialOptions returns the dial options of the connections to the internal ports of the
+// cluster, like the zero and the reduce workers.
+func internalDialOptions() []grpc.DialOption {
+	tlsConf, err := x.LoadClientTLSConfigForInternalPort(Bulk.Conf)
+	x.Check(err)
+	if tlsConf != nil {
+		return []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))}
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+}
+
 func getWriteTimestamp(zero *grpc.ClientConn, dg *dgo.Dgraph) uint64 {
 	if zero != nil {
 		client := pb.NewZeroClient(zero)
@@ -495,26 +510,12 @@ func (ld *loader) reduceStage() {
 }
 
 func (ld *loader) writeSchema() {
-	numDBs := uint32(len(ld.dbs))
-	preds := make([][]string, numDBs)
-
 	// Get all predicates that have data in some DB.
-	m := make(map[string]struct{})
+	preds := make([][]string, len(ld.dbs))
 	for i, db := range ld.dbs {
 		preds[i] = ld.schema.getPredicates(db)
-		for _, p := range preds[i] {
-			m[p] = struct{}{}
-		}
-	}
-
-	// Find any predicates that don't have data in any DB
-	// and distribute them among all the DBs.
-	for p := range ld.schema.schemaMap {
-		if _, ok := m[p]; !ok {
-			i := adler32.Checksum([]byte(p)) % numDBs
-			preds[i] = append(preds[i], p)
-		}
 	}
+	ld.schema.addPredicatesWithoutData(preds)
 
 	// Write out each DB's final predicate list.
 	for i, db := range ld.dbs {
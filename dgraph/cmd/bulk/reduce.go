@@ -57,62 +57,66 @@ func (r *reducer) run() error {
 		}
 		go func(shardId int, db *badger.DB, tmpDb *badger.DB) {
 			defer thr.Done(nil)
+			r.reduceShard(dirs[shardId], db, tmpDb)
+		}(i, r.createBadger(i), r.createTmpBadger())
+	}
+	return thr.Finish()
+}
 
-			mapFiles := filenamesInTree(dirs[shardId])
-			var mapItrs []*mapIterator
-
-			// Dedup the partition keys.
-			partitions := make(map[string]struct{})
-			for _, mapFile := range mapFiles {
-				header, itr := newMapIterator(mapFile)
-				for _, k := range header.PartitionKeys {
-					if len(k) == 0 {
-						continue
-					}
-					partitions[string(k)] = struct{}{}
-				}
-				mapItrs = append(mapItrs, itr)
+// reduceShard reduces the map files of the reduce shard in dir into db.
+func (r *reducer) reduceShard(dir string, db *badger.DB, tmpDb *badger.DB) {
+	mapFiles := filenamesInTree(dir)
+	var mapItrs []*mapIterator
+
+	// Dedup the partition keys.
+	partitions := make(map[string]struct{})
+	for _, mapFile := range mapFiles {
+		header, itr := newMapIterator(mapFile)
+		for _, k := range header.PartitionKeys {
+			if len(k) == 0 {
+				continue
 			}
+			partitions[string(k)] = struct{}{}
+		}
+		mapItrs = append(mapItrs, itr)
+	}
 
-			writer := db.NewStreamWriter()
-			x.Check(writer.Prepare())
-			// Split lists are written to a separate DB first to avoid ordering issues.
-			splitWriter := tmpDb.NewManagedWriteBatch()
-
-			ci := &countIndexer{
-				reducer:     r,
-				writer:      writer,
-				splitWriter: splitWriter,
-				tmpDb:       tmpDb,
-				splitCh:     make(chan *bpb.KVList, 2*runtime.NumCPU()),
-				countBuf:    getBuf(r.opt.TmpDir),
-			}
+	writer := db.NewStreamWriter()
+	x.Check(writer.Prepare())
+	// Split lists are written to a separate DB first to avoid ordering issues.
+	splitWriter := tmpDb.NewManagedWriteBatch()
+
+	ci := &countIndexer{
+		reducer:     r,
+		writer:      writer,
+		splitWriter: splitWriter,
+		tmpDb:       tmpDb,
+		splitCh:     make(chan *bpb.KVList, 2*runtime.NumCPU()),
+		countBuf:    getBuf(r.opt.TmpDir),
+	}
 
-			partitionKeys := make([][]byte, 0, len(partitions))
-			for k := range partitions {
-				partitionKeys = append(partitionKeys, []byte(k))
-			}
-			sort.Slice(partitionKeys, func(i, j int) bool {
-				return bytes.Compare(partitionKeys[i], partitionKeys[j]) < 0
-			})
+	partitionKeys := make([][]byte, 0, len(partitions))
+	for k := range partitions {
+		partitionKeys = append(partitionKeys, []byte(k))
+	}
+	sort.Slice(partitionKeys, func(i, j int) bool {
+		return bytes.Compare(partitionKeys[i], partitionKeys[j]) < 0
+	})
 
-			r.reduce(partitionKeys, mapItrs, ci)
-			ci.wait()
+	r.reduce(partitionKeys, mapItrs, ci)
+	ci.wait()
 
-			fmt.Println("Writing split lists back to the main DB now")
-			// Write split lists back to the main DB.
-			r.writeSplitLists(db, tmpDb, writer)
+	fmt.Println("Writing split lists back to the main DB now")
+	// Write split lists back to the main DB.
+	r.writeSplitLists(db, tmpDb, writer)
 
-			x.Check(writer.Flush())
+	x.Check(writer.Flush())
 
-			for _, itr := range mapItrs {
-				if err := itr.Close(); err != nil {
-					fmt.Printf("Error while closing iterator: %v", err)
-				}
-			}
-		}(i, r.createBadger(i), r.createTmpBadger())
+	for _, itr := range mapItrs {
+		if err := itr.Close(); err != nil {
+			fmt.Printf("Error while closing iterator: %v", err)
+		}
 	}
-	return thr.Finish()
 }
 
 func (r *reducer) createBadgerInternal(dir string, compression bool) *badger.DB {
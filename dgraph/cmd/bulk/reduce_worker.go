@@ -0,0 +1,414 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bulk
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dgraph-io/badger/v4"
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// reduceChunkSize is the size of the chunks the map files are sent to the reduce workers in.
+const reduceChunkSize = 4 << 20
+
+// reduceOnWorkers reduces the reduce shards on the reduce workers instead of locally, the shard
+// i on the worker i modulo the number of workers. Each worker writes the p directory of its
+// shards under its own out directory, so the directories are copied from the workers to the
+// alphas of their groups.
+func (ld *loader) reduceOnWorkers() {
+	ld.prog.setPhase(reducePhase)
+
+	dirs := readShardDirs(filepath.Join(ld.opt.TmpDir, reduceShardDir))
+	x.AssertTrue(len(dirs) == ld.opt.ReduceShards)
+
+	tlsConf, err := reduceWorkerClientTLS(Bulk.Conf)
+	x.Check(err)
+	addrs := strings.Split(ld.opt.ReduceWorkers, ",")
+	clients := make([]pb.BulkLoaderClient, len(addrs))
+	for i, addr := range addrs {
+		fmt.Printf("Connecting to the reduce worker at %s\n", addr)
+		conn, err := grpc.NewClient(addr,
+			grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
+		x.Checkf(err, "Unable to connect to the reduce worker at %s", addr)
+		defer conn.Close()
+		clients[i] = pb.NewBulkLoaderClient(conn)
+	}
+
+	// The bulk meta is marshalled before the reduce, which changes the schema.
+	metas := make([][]byte, len(dirs))
+	for i := range dirs {
+		var err error
+		metas[i], err = proto.Marshal(&pb.BulkMeta{
+			EdgeCount:   ld.prog.mapEdgeCount,
+			SchemaMap:   ld.schema.schemaMap,
+			Types:       ld.schema.types,
+			WriteTs:     ld.writeTs,
+			ReduceShard: uint32(i),
+		})
+		x.Check(err)
+	}
+
+	reduced := make([]*pb.BulkMeta, len(dirs))
+	g, ctx := errgroup.WithContext(context.Background())
+	for i, dir := range dirs {
+		g.Go(func() error {
+			var err error
+			reduced[i], err = sendReduceShard(ctx, clients[i%len(clients)], metas[i], dir)
+			return errors.Wrapf(err, "while reducing the shard %d on %s", i, addrs[i%len(addrs)])
+		})
+	}
+	x.Check(g.Wait())
+
+	// The reduce sets the schemas of the predicates with multiple values for a uid as lists.
+	preds := make([][]string, len(dirs))
+	for i, meta := range reduced {
+		for pred, sch := range meta.SchemaMap {
+			ld.schema.schemaMap[pred] = sch
+			preds[i] = append(preds[i], pred)
+		}
+		atomic.AddInt64(&ld.prog.reduceEdgeCount, meta.EdgeCount)
+	}
+	ld.schema.addPredicatesWithoutData(preds)
+
+	for i := range dirs {
+		meta := &pb.BulkMeta{
+			SchemaMap:   make(map[string]*pb.SchemaUpdate, len(preds[i])),
+			Types:       ld.schema.types,
+			ReduceShard: uint32(i),
+		}
+		for _, pred := range preds[i] {
+			meta.SchemaMap[pred] = ld.schema.schemaMap[pred]
+		}
+		status, err := clients[i%len(clients)].WriteSchema(context.Background(), meta)
+		x.Checkf(err, "While writing the schema of the shard %d on %s", i, addrs[i%len(addrs)])
+		fmt.Println(status.GetMsg())
+	}
+}
+
+// reduceWorkerClientTLS returns the TLS config of the connections to the reduce workers, which
+// require the client certificate of --tls, as the map files are the data of the cluster.
+func reduceWorkerClientTLS(v *viper.Viper) (*tls.Config, error) {
+	tlsConf, err := x.LoadClientTLSConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConf == nil || len(tlsConf.Certificates) == 0 {
+		return nil, errors.Errorf(`The reduce workers require mutual TLS, please provide --tls ` +
+			`"ca-cert=...; client-cert=...; client-key=...;"`)
+	}
+	return tlsConf, nil
+}
+
+// reduceWorkerServerTLS returns the TLS config of the reduce worker, which only accepts the
+// clients with a certificate signed by the CA of --tls.
+func reduceWorkerServerTLS(v *viper.Viper) (*tls.Config, error) {
+	tlsConf, err := x.LoadServerTLSConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConf == nil || tlsConf.ClientAuth != tls.RequireAndVerifyClientCert {
+		return nil, errors.Errorf(`The reduce worker requires mutual TLS, please provide --tls ` +
+			`"server-cert=...; server-key=...; ca-cert=...; client-auth-type=REQUIREANDVERIFY;"`)
+	}
+	return tlsConf, nil
+}
+
+// sendReduceShard sends the bulk meta and the map files of the reduce shard in dir to the reduce
+// worker, and returns the schemas of the predicates of the reduced shard.
+func sendReduceShard(ctx context.Context, client pb.BulkLoaderClient, meta []byte,
+	dir string) (*pb.BulkMeta, error) {
+
+	stream, err := client.Reduce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&bpb.KV{Key: []byte(bulkMetaFilename), Value: meta}); err != nil {
+		return nil, err
+	}
+	if err := sendMapFiles(dir, stream.Send); err != nil {
+		return nil, err
+	}
+	return stream.CloseAndRecv()
+}
+
+// sendMapFiles sends the map files in dir in chunks, keyed by their paths relative to dir.
+func sendMapFiles(dir string, send func(kv *bpb.KV) error) error {
+	buf := make([]byte, reduceChunkSize)
+	for _, path := range filenamesInTree(dir) {
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if err := sendMapFile(path, name, buf, send); err != nil {
+			return errors.Wrapf(err, "while sending the map file %s", path)
+		}
+	}
+	return nil
+}
+
+func sendMapFile(path, name string, buf []byte, send func(kv *bpb.KV) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for {
+		n, err := io.ReadFull(f, buf)
+		if err == io.EOF {
+			return nil
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if err := send(&bpb.KV{Key: []byte(name), Value: buf[:n]}); err != nil {
+			return err
+		}
+		if n < len(buf) {
+			return nil
+		}
+	}
+}
+
+// receiveMapFiles writes the map files received in chunks into dir, until the end of the stream.
+func receiveMapFiles(dir string, recv func() (*bpb.KV, error)) error {
+	var f *os.File
+	var name string
+	closeFile := func() error {
+		if f == nil {
+			return nil
+		}
+		err := f.Close()
+		f = nil
+		return err
+	}
+	defer func() { _ = closeFile() }()
+
+	for {
+		kv, err := recv()
+		if err == io.EOF {
+			return closeFile()
+		} else if err != nil {
+			return err
+		}
+
+		if f == nil || string(kv.Key) != name {
+			if err := closeFile(); err != nil {
+				return err
+			}
+			name = string(kv.Key)
+			path, err := mapFilePath(dir, name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return err
+			}
+			if f, err = os.Create(path); err != nil {
+				return err
+			}
+		}
+		if _, err := f.Write(kv.Value); err != nil {
+			return err
+		}
+	}
+}
+
+// mapFilePath returns the path in dir of the map file received with the name, which must be a
+// relative path staying in dir.
+func mapFilePath(dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+	if !filepath.IsLocal(name) || !strings.HasPrefix(path, prefix) {
+		return "", errors.Errorf("Invalid path %q of a map file, out of the directory of the job",
+			name)
+	}
+	return path, nil
+}
+
+// reduceWorker serves the reduce of the shards sent by a bulk loader run with --reduce_workers.
+type reduceWorker struct {
+	pb.UnimplementedBulkLoaderServer
+	opt *BulkOptions
+	// reducers limits the shards reduced at once to the number of reducers.
+	reducers chan struct{}
+
+	sync.Mutex
+	// dbs are the DBs of the reduced shards, kept open until their schema is written.
+	dbs map[uint32]*badger.DB
+}
+
+// serveReduce runs this process as a reduce worker, serving the reduce at opt.ServeReduce.
+func serveReduce(opt *BulkOptions) {
+	stageRemoteOut(opt)
+	x.Check(os.MkdirAll(opt.TmpDir, 0700))
+	x.Check(os.MkdirAll(opt.OutDir, 0700))
+
+	tlsConf, err := reduceWorkerServerTLS(Bulk.Conf)
+	x.Check(err)
+	lis, err := net.Listen("tcp", opt.ServeReduce)
+	x.Checkf(err, "Unable to listen at %s", opt.ServeReduce)
+
+	fmt.Printf("Serving the reduce of the bulk loader at %s\n", opt.ServeReduce)
+	x.Check(newReduceServer(opt, tlsConf).Serve(lis))
+}
+
+// newReduceServer returns the gRPC server of a reduce worker, with the TLS config.
+func newReduceServer(opt *BulkOptions, tlsConf *tls.Config) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.MaxRecvMsgSize(x.GrpcMaxSize),
+		grpc.MaxSendMsgSize(x.GrpcMaxSize),
+		grpc.Creds(credentials.NewTLS(tlsConf)),
+	)
+	pb.RegisterBulkLoaderServer(s, &reduceWorker{
+		opt:      opt,
+		reducers: make(chan struct{}, opt.NumReducers),
+		dbs:      make(map[uint32]*badger.DB),
+	})
+	return s
+}
+
+// Reduce receives the map files of a reduce shard, and reduces them into the p directory of the
+// shard under the out directory.
+func (w *reduceWorker) Reduce(stream pb.BulkLoader_ReduceServer) error {
+	kv, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if string(kv.Key) != bulkMetaFilename {
+		return errors.Errorf("The stream must start with the %s of the shard", bulkMetaFilename)
+	}
+	var meta pb.BulkMeta
+	if err := proto.Unmarshal(kv.Value, &meta); err != nil {
+		return err
+	}
+
+	jobDir, err := os.MkdirTemp(w.opt.TmpDir, "reduce")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(jobDir)
+	shardDir := filepath.Join(jobDir, reduceShardDir)
+	if err := receiveMapFiles(shardDir, stream.Recv); err != nil {
+		return errors.Wrapf(err, "while receiving the map files of the shard %d", meta.ReduceShard)
+	}
+	if err := os.MkdirAll(filepath.Join(jobDir, bufferDir), 0700); err != nil {
+		return err
+	}
+
+	select {
+	case w.reducers <- struct{}{}:
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	}
+	defer func() { <-w.reducers }()
+
+	start := time.Now()
+	db, edgeCount := w.reduceShard(&meta, jobDir, shardDir)
+	fmt.Printf("Reduced %d edges of the shard %d in %s\n", edgeCount, meta.ReduceShard,
+		x.FixedDuration(time.Since(start)))
+
+	schemas := make(map[string]*pb.SchemaUpdate)
+	for _, pred := range (&schemaStore{}).getPredicates(db) {
+		if sch, ok := meta.SchemaMap[pred]; ok {
+			schemas[pred] = sch
+		}
+	}
+	return stream.SendAndClose(&pb.BulkMeta{
+		EdgeCount:   edgeCount,
+		SchemaMap:   schemas,
+		ReduceShard: meta.ReduceShard,
+	})
+}
+
+// reduceShard reduces the map files in shardDir into the p directory of the shard, replacing the
+// one of a previous reduce, and returns its DB and the number of edges reduced.
+func (w *reduceWorker) reduceShard(meta *pb.BulkMeta, jobDir, shardDir string) (*badger.DB, int64) {
+	opt := *w.opt
+	opt.TmpDir = jobDir
+	st := &state{
+		opt:        &opt,
+		prog:       newProgress(),
+		writeTs:    meta.WriteTs,
+		namespaces: &sync.Map{},
+	}
+	st.prog.setPhase(reducePhase)
+	st.schema = &schemaStore{schemaMap: meta.SchemaMap, types: meta.Types, state: st}
+	r := &reducer{
+		state:     st,
+		streamIds: make(map[string]uint32),
+	}
+
+	w.Lock()
+	if db, ok := w.dbs[meta.ReduceShard]; ok {
+		x.Check(db.Close())
+		delete(w.dbs, meta.ReduceShard)
+	}
+	w.Unlock()
+	dir := filepath.Join(w.opt.OutDir, strconv.Itoa(int(meta.ReduceShard)), "p")
+	x.Check(os.RemoveAll(dir))
+	x.Check(os.MkdirAll(dir, 0700))
+	x.Check(x.WriteGroupIdFile(dir, meta.ReduceShard+1))
+
+	db := r.createBadgerInternal(dir, true)
+	tmpDb := r.createTmpBadger()
+	r.reduceShard(shardDir, db, tmpDb)
+	x.Check(tmpDb.Close())
+
+	w.Lock()
+	w.dbs[meta.ReduceShard] = db
+	w.Unlock()
+	return db, atomic.LoadInt64(&st.prog.reduceEdgeCount)
+}
+
+// WriteSchema writes the schema of the predicates of a reduced shard, and closes its DB.
+func (w *reduceWorker) WriteSchema(ctx context.Context, meta *pb.BulkMeta) (*pb.Status, error) {
+	w.Lock()
+	db, ok := w.dbs[meta.ReduceShard]
+	delete(w.dbs, meta.ReduceShard)
+	w.Unlock()
+	if !ok {
+		return nil, errors.Errorf("The shard %d isn't reduced on this worker", meta.ReduceShard)
+	}
+
+	preds := make([]string, 0, len(meta.SchemaMap))
+	for pred := range meta.SchemaMap {
+		preds = append(preds, pred)
+	}
+	(&schemaStore{schemaMap: meta.SchemaMap, types: meta.Types}).write(db, preds)
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+
+	location := filepath.Join(w.opt.OutDir, strconv.Itoa(int(meta.ReduceShard)), "p")
+	if w.opt.outLocation != nil {
+		var err error
+		if location, err = uploadShard(w.opt, meta.ReduceShard); err != nil {
+			return nil, errors.Wrapf(err, "while uploading the shard %d", meta.ReduceShard)
+		}
+	}
+	return &pb.Status{Msg: fmt.Sprintf("Wrote the shard %d to %s", meta.ReduceShard, location)}, nil
+}
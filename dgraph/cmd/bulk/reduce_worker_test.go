@@ -0,0 +1,309 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bulk
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dgraph-io/badger/v4"
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// testCerts are the files of a CA and of the certificates of localhost signed by it.
+type testCerts struct {
+	ca                    string
+	nodeCert, nodeKey     string
+	clientCert, clientKey string
+}
+
+// serverFlag returns the --tls superflag of a reduce worker with the client auth type.
+func (c testCerts) serverFlag(clientAuth string) string {
+	return fmt.Sprintf("ca-cert=%s; server-cert=%s; server-key=%s; client-auth-type=%s;", c.ca,
+		c.nodeCert, c.nodeKey, clientAuth)
+}
+
+// clientFlag returns the --tls superflag of a bulk loader, with the client certificate or not.
+func (c testCerts) clientFlag(withCert bool) string {
+	flag := fmt.Sprintf("ca-cert=%s; server-name=localhost;", c.ca)
+	if withCert {
+		flag += fmt.Sprintf(" client-cert=%s; client-key=%s;", c.clientCert, c.clientKey)
+	}
+	return flag
+}
+
+func writeCerts(t *testing.T, dir string) testCerts {
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		return key
+	}
+	write := func(name, typ string, der []byte) string {
+		file := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}),
+			0600))
+		return file
+	}
+
+	caKey := newKey()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDer)
+	require.NoError(t, err)
+	caFile := write("ca.crt", "CERTIFICATE", caDer)
+
+	files := make(map[string][2]string)
+	for i, name := range []string{"node", "client"} {
+		key := newKey()
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 2)),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			DNSNames:     []string{"localhost"},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDer, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		files[name] = [2]string{write(name+".crt", "CERTIFICATE", der),
+			write(name+".key", "EC PRIVATE KEY", keyDer)}
+	}
+	return testCerts{ca: caFile, nodeCert: files["node"][0], nodeKey: files["node"][1],
+		clientCert: files["client"][0], clientKey: files["client"][1]}
+}
+
+// loadTLS returns the TLS config loaded with the --tls superflag.
+func loadTLS(load func(*viper.Viper) (*tls.Config, error), flag string) (*tls.Config, error) {
+	v := viper.New()
+	v.Set("tls", flag)
+	return load(v)
+}
+
+func TestReduceWorkerTLS(t *testing.T) {
+	certs := writeCerts(t, t.TempDir())
+	conf, err := loadTLS(reduceWorkerServerTLS, certs.serverFlag("REQUIREANDVERIFY"))
+	require.NoError(t, err)
+	require.Equal(t, tls.RequireAndVerifyClientCert, conf.ClientAuth)
+	_, err = loadTLS(reduceWorkerClientTLS, certs.clientFlag(true))
+	require.NoError(t, err)
+
+	// The worker requires a server certificate, and verifies the client certificates.
+	for _, flag := range []string{"", certs.serverFlag("VERIFYIFGIVEN")} {
+		_, err = loadTLS(reduceWorkerServerTLS, flag)
+		require.ErrorContains(t, err, "The reduce worker requires mutual TLS", flag)
+	}
+	// The bulk loader requires a client certificate.
+	for _, flag := range []string{"", certs.clientFlag(false)} {
+		_, err = loadTLS(reduceWorkerClientTLS, flag)
+		require.ErrorContains(t, err, "The reduce workers require mutual TLS", flag)
+	}
+}
+
+// reduceTestWorker serves a reduce worker over an in-process connection with mutual TLS.
+type reduceTestWorker struct {
+	opt    *BulkOptions
+	server *grpc.Server
+	client pb.BulkLoaderClient
+}
+
+func newReduceTestWorker(t *testing.T) *reduceTestWorker {
+	dir := t.TempDir()
+	certs := writeCerts(t, dir)
+	serverConf, err := loadTLS(reduceWorkerServerTLS, certs.serverFlag("REQUIREANDVERIFY"))
+	require.NoError(t, err)
+	clientConf, err := loadTLS(reduceWorkerClientTLS, certs.clientFlag(true))
+	require.NoError(t, err)
+
+	opt := &BulkOptions{
+		TmpDir:        filepath.Join(dir, "tmp"),
+		OutDir:        filepath.Join(dir, "out"),
+		NumReducers:   1,
+		NumGoroutines: 2,
+		Badger:        badger.DefaultOptions("").WithLogger(nil),
+	}
+	require.NoError(t, os.MkdirAll(opt.TmpDir, 0700))
+	lis := bufconn.Listen(1 << 20)
+	server := newReduceServer(opt, serverConf)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(credentials.NewTLS(clientConf)))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return &reduceTestWorker{opt: opt, server: server, client: pb.NewBulkLoaderClient(conn)}
+}
+
+// writeMapFiles writes the map files of a shard with the names of the uids, and returns their
+// directory and the bulk meta of the shard.
+func writeMapFiles(t *testing.T, names map[uint64]string) (string, []byte) {
+	dir := t.TempDir()
+	opt := &BulkOptions{TmpDir: dir, MapShards: 1, MapBufSize: 1 << 20, PartitionBufSize: 1 << 20}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, bufferDir), 0700))
+	m := newMapper(&state{opt: opt, prog: newProgress()})
+	attr := x.AttrInRootNamespace("name")
+	for uid, name := range names {
+		m.addMapEntry(x.DataKey(attr, uid), &pb.Posting{
+			Uid:         math.MaxUint64,
+			Value:       []byte(name),
+			ValType:     pb.Posting_STRING,
+			PostingType: pb.Posting_VALUE,
+		}, 0)
+	}
+	m.shards[0].mu.Lock()
+	m.writeMapEntriesToFile(m.shards[0].cbuf, 0)
+
+	meta, err := proto.Marshal(&pb.BulkMeta{
+		SchemaMap: map[string]*pb.SchemaUpdate{
+			attr: {Predicate: attr, ValueType: pb.Posting_STRING},
+		},
+		WriteTs: 1,
+	})
+	require.NoError(t, err)
+	return filepath.Join(dir, mapShardDir, "000"), meta
+}
+
+func TestReduceWorker(t *testing.T) {
+	w := newReduceTestWorker(t)
+	dir, meta := writeMapFiles(t, map[uint64]string{1: "alice", 2: "bob"})
+
+	attr := x.AttrInRootNamespace("name")
+	reduced, err := sendReduceShard(context.Background(), w.client, meta, dir)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), reduced.EdgeCount)
+	require.Contains(t, reduced.SchemaMap, attr)
+
+	status, err := w.client.WriteSchema(context.Background(), &pb.BulkMeta{
+		SchemaMap: reduced.SchemaMap,
+	})
+	require.NoError(t, err)
+	require.Contains(t, status.Msg, "Wrote the shard 0")
+	// The schema of a shard is written once.
+	_, err = w.client.WriteSchema(context.Background(), &pb.BulkMeta{})
+	require.ErrorContains(t, err, "The shard 0 isn't reduced on this worker")
+
+	db, err := badger.OpenManaged(badger.DefaultOptions(filepath.Join(w.opt.OutDir, "0", "p")).
+		WithLogger(nil).WithReadOnly(true))
+	require.NoError(t, err)
+	defer db.Close()
+	txn := db.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	for _, key := range [][]byte{x.DataKey(attr, 1), x.DataKey(attr, 2), x.SchemaKey(attr)} {
+		_, err := txn.Get(key)
+		require.NoError(t, err)
+	}
+
+	// The job directories are removed.
+	entries, err := os.ReadDir(w.opt.TmpDir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestReduceWorkerPartialStream(t *testing.T) {
+	w := newReduceTestWorker(t)
+	_, meta := writeMapFiles(t, map[uint64]string{1: "alice"})
+
+	// The stream doesn't start with the bulk meta.
+	stream, err := w.client.Reduce(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&bpb.KV{Key: []byte("000001.map.gz"), Value: []byte("x")}))
+	_, err = stream.CloseAndRecv()
+	require.ErrorContains(t, err, "The stream must start with the "+bulkMetaFilename)
+
+	// The stream is canceled in the middle of a map file.
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err = w.client.Reduce(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&bpb.KV{Key: []byte(bulkMetaFilename), Value: meta}))
+	require.NoError(t, stream.Send(&bpb.KV{Key: []byte("000001.map.gz"), Value: []byte("x")}))
+	cancel()
+	_, err = stream.CloseAndRecv()
+	require.Error(t, err)
+
+	// A map file out of the job directory is rejected.
+	stream, err = w.client.Reduce(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&bpb.KV{Key: []byte(bulkMetaFilename), Value: meta}))
+	require.NoError(t, stream.Send(&bpb.KV{Key: []byte("../../escape"), Value: []byte("x")}))
+	_, err = stream.CloseAndRecv()
+	require.ErrorContains(t, err, `Invalid path "../../escape" of a map file`)
+
+	// Nothing of the failed shards is kept.
+	_, err = w.client.WriteSchema(context.Background(), &pb.BulkMeta{})
+	require.ErrorContains(t, err, "isn't reduced on this worker")
+	require.Eventually(t, func() bool {
+		entries, err := os.ReadDir(w.opt.TmpDir)
+		return err == nil && len(entries) == 0
+	}, 10*time.Second, 10*time.Millisecond)
+	_, err = os.Stat(filepath.Join(filepath.Dir(w.opt.TmpDir), "escape"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestReduceWorkerFailure(t *testing.T) {
+	w := newReduceTestWorker(t)
+	_, meta := writeMapFiles(t, map[uint64]string{1: "alice"})
+
+	stream, err := w.client.Reduce(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&bpb.KV{Key: []byte(bulkMetaFilename), Value: meta}))
+	require.NoError(t, stream.Send(&bpb.KV{Key: []byte("000001.map.gz"), Value: []byte("x")}))
+
+	// The worker goes away in the middle of the shard.
+	w.server.Stop()
+	_, err = stream.CloseAndRecv()
+	require.Error(t, err)
+	_, err = w.client.WriteSchema(context.Background(), &pb.BulkMeta{})
+	require.Error(t, err)
+}
+
+func TestMapFilePath(t *testing.T) {
+	dir := t.TempDir()
+	path, err := mapFilePath(dir, "000/000001.map.gz")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "000", "000001.map.gz"), path)
+	for _, name := range []string{"", ".", "..", "../x", "a/../../x", "/etc/passwd"} {
+		_, err := mapFilePath(dir, name)
+		require.Error(t, err, name)
+	}
+}
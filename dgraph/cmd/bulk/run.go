@@ -24,6 +24,7 @@ import (
 	"github.com/dgraph-io/badger/v4"
 	"github.com/dgraph-io/ristretto/v2/z"
 	"github.com/hypermodeinc/dgraph/v25/filestore"
+	"github.com/hypermodeinc/dgraph/v25/plugin"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/tok"
 	"github.com/hypermodeinc/dgraph/v25/x"
@@ -36,6 +37,10 @@ var defaultOutDir = "./out"
 
 const BulkBadgerDefaults = "compression=snappy; numgoroutines=8;"
 
+// bulkMetaFilename is the name of the file the bulk meta is written to at the end of the map
+// phase, and the key it is sent with to the reduce workers.
+const bulkMetaFilename = "bulk.meta"
+
 func init() {
 	Bulk.Cmd = &cobra.Command{
 		Use:   "bulk",
@@ -64,7 +69,10 @@ func init() {
 		"Flag to indicate whether to encrypt the output. "+
 			"Must be specified with --encryption or vault option(s).")
 	flag.String("out", defaultOutDir,
-		"Location to write the final dgraph data directories.")
+		"Location to write the final dgraph data directories. If it's a location in object "+
+			"storage like s3:///bucket/bulk, minio://host/bucket/bulk or gs:///bucket/bulk, the "+
+			"directories are written into ./out first, then uploaded to it to be installed by "+
+			"the alphas with --install-snapshot.")
 	flag.Bool("replace_out", false,
 		"Replace out directory and its contents if it exists.")
 	flag.String("tmp", "tmp",
@@ -101,6 +109,17 @@ func init() {
 			"more parallelism, but increases memory usage.")
 	flag.String("custom_tokenizers", "",
 		"Comma separated list of tokenizer plugins")
+	flag.String("plugin", plugin.Defaults, z.NewSuperFlagHelp(plugin.Defaults).
+		Head("Out-of-process plugins serving custom tokenizers over gRPC").
+		Flag("addrs",
+			"Comma separated list of the gRPC addresses of the plugins.").
+		Flag("timeout",
+			"The timeout of the calls to the plugins.").
+		Flag("startup-timeout",
+			"How long to wait for the plugins to be up when the loader starts.").
+		Flag("health-interval",
+			"The interval of the health checks of the plugins.").
+		String())
 	flag.Bool("new_uids", false,
 		"Ignore UIDs in load files and assign new ones.")
 	flag.Uint64("force-namespace", math.MaxUint64,
@@ -108,6 +127,18 @@ func init() {
 			" When using this flag to load data into specific namespace, make sure that the "+
 			"load data do not have ACL data.")
 
+	flag.String("reduce_workers", "",
+		"Comma separated list of the gRPC addresses of the reduce workers, started with "+
+			"--serve_reduce. If set, the reduce shards are reduced on the workers, which write "+
+			"their p directories under their --out directory, instead of locally. The "+
+			"connections to the workers use mutual TLS, with the ca-cert, client-cert and "+
+			"client-key of --tls.")
+	flag.String("serve_reduce", "",
+		"Address to serve the reduce of the shards of a bulk loader run with --reduce_workers "+
+			"at, e.g. :7090. The p directories of the shards are written under --out. The "+
+			"worker requires mutual TLS, with the server-cert, server-key and ca-cert of --tls, "+
+			"and its client-auth-type set to REQUIREANDVERIFY.")
+
 	flag.String("badger", BulkBadgerDefaults, z.NewSuperFlagHelp(BulkBadgerDefaults).
 		Head("Badger options (Refer to badger documentation for all possible options)").
 		Flag("compression",
@@ -158,9 +189,12 @@ func run() {
 		MapShards:        Bulk.Conf.GetInt("map_shards"),
 		ReduceShards:     Bulk.Conf.GetInt("reduce_shards"),
 		CustomTokenizers: Bulk.Conf.GetString("custom_tokenizers"),
+		Plugin:           Bulk.Conf.GetString("plugin"),
 		NewUids:          Bulk.Conf.GetBool("new_uids"),
 		ClientDir:        Bulk.Conf.GetString("xidmap"),
 		Namespace:        Bulk.Conf.GetUint64("force-namespace"),
+		ReduceWorkers:    Bulk.Conf.GetString("reduce_workers"),
+		ServeReduce:      Bulk.Conf.GetString("serve_reduce"),
 		Badger:           bopts,
 	}
 
@@ -168,11 +202,16 @@ func run() {
 	if opt.Version {
 		os.Exit(0)
 	}
+	if opt.ServeReduce != "" {
+		serveReduce(&opt)
+		return
+	}
 
 	RunBulkLoader(opt)
 }
 
 func RunBulkLoader(opt BulkOptions) {
+	stageRemoteOut(&opt)
 	if len(opt.EncryptionKey) == 0 {
 		if opt.Encrypted || opt.EncryptedOut {
 			fmt.Fprint(os.Stderr, "Must use --encryption or vault option(s).\n")
@@ -241,6 +280,12 @@ func RunBulkLoader(opt BulkOptions) {
 			tok.LoadCustomTokenizer(soFile)
 		}
 	}
+	err := plugin.LoadAll(z.NewSuperFlag(opt.Plugin).MergeAndCheckDefault(plugin.Defaults))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load the plugins: %v\n", err)
+		os.Exit(1)
+	}
+	defer plugin.CloseAll()
 	if opt.MapBufSize <= 0 || opt.PartitionBufSize <= 0 {
 		fmt.Fprintf(os.Stderr, "mapoutput_mb: %d and partition_mb: %d must be greater than zero\n",
 			opt.MapBufSize, opt.PartitionBufSize)
@@ -274,9 +319,13 @@ func RunBulkLoader(opt BulkOptions) {
 		}
 	}
 
-	// Delete and recreate the output dirs to ensure they are empty.
+	// Delete and recreate the output dirs to ensure they are empty. The reduce workers write
+	// the output dirs on their machines instead.
 	x.Check(os.RemoveAll(opt.OutDir))
 	for i := range opt.ReduceShards {
+		if opt.ReduceWorkers != "" {
+			break
+		}
 		dir := filepath.Join(opt.OutDir, strconv.Itoa(i), "p")
 		x.Check(os.MkdirAll(dir, 0700))
 		opt.shardOutputDirs = append(opt.shardOutputDirs, dir)
@@ -301,7 +350,6 @@ func RunBulkLoader(opt BulkOptions) {
 
 	loader := newLoader(&opt)
 
-	const bulkMetaFilename = "bulk.meta"
 	bulkMetaPath := filepath.Join(opt.TmpDir, bulkMetaFilename)
 
 	if opt.SkipMapPhase {
@@ -340,9 +388,22 @@ func RunBulkLoader(opt BulkOptions) {
 			os.Exit(1)
 		}
 	}
-	loader.reduceStage()
-	loader.writeSchema()
+	if opt.ReduceWorkers != "" {
+		loader.reduceOnWorkers()
+	} else {
+		loader.reduceStage()
+		loader.writeSchema()
+	}
 	loader.cleanup()
+
+	// The reduce workers upload the shards they write themselves.
+	if opt.outLocation != nil && opt.ReduceWorkers == "" {
+		for i := range opt.ReduceShards {
+			location, err := uploadShard(&opt, uint32(i))
+			x.Checkf(err, "While uploading the shard %d", i)
+			fmt.Printf("Uploaded the shard %d to %s\n", i, location)
+		}
+	}
 }
 
 func maxOpenFilesWarning() {
@@ -7,6 +7,7 @@ package bulk
 
 import (
 	"fmt"
+	"hash/adler32"
 	"log"
 	"math"
 	"sync"
@@ -156,6 +157,25 @@ func (s *schemaStore) getPredicates(db *badger.DB) []string {
 	return preds
 }
 
+// addPredicatesWithoutData adds the predicates of the schema which don't have data in any DB to
+// the predicates of the DBs, distributing them among all the DBs.
+func (s *schemaStore) addPredicatesWithoutData(preds [][]string) {
+	m := make(map[string]struct{})
+	for _, dbPreds := range preds {
+		for _, p := range dbPreds {
+			m[p] = struct{}{}
+		}
+	}
+
+	numDBs := uint32(len(preds))
+	for p := range s.schemaMap {
+		if _, ok := m[p]; !ok {
+			i := adler32.Checksum([]byte(p)) % numDBs
+			preds[i] = append(preds[i], p)
+		}
+	}
+}
+
 func (s *schemaStore) write(db *badger.DB, preds []string) {
 	w := posting.NewTxnWriter(db)
 	for _, pred := range preds {
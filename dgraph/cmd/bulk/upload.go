@@ -0,0 +1,46 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bulk
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	wk "github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// stageRemoteOut makes the p directories be written into the default out directory, when the
+// out directory is a location in object storage like s3:///bucket/bulk, to be uploaded from it.
+func stageRemoteOut(opt *BulkOptions) {
+	uri, err := url.Parse(opt.OutDir)
+	x.Check(err)
+	switch uri.Scheme {
+	case "minio", "s3", "gs":
+		fmt.Printf("Writing the p directories to %s before uploading them to %s\n",
+			defaultOutDir, uri.Redacted())
+		opt.outLocation = uri
+		opt.OutDir = defaultOutDir
+	}
+}
+
+// uploadShard uploads the p directory of the reduce shard to the out location, under the number
+// of the shard, and removes it from the default out directory.
+func uploadShard(opt *BulkOptions, shard uint32) (string, error) {
+	location := *opt.outLocation
+	location.Path = path.Join(location.Path, strconv.Itoa(int(shard)))
+	fmt.Printf("Uploading the p directory of the shard %d to %s\n", shard, location.Redacted())
+
+	dir := filepath.Join(opt.OutDir, strconv.Itoa(int(shard)))
+	if err := wk.UploadSnapshot(filepath.Join(dir, "p"), &location); err != nil {
+		return "", err
+	}
+	return location.Redacted(), os.RemoveAll(dir)
+}
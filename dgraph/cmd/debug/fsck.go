@@ -0,0 +1,69 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// fsck verifies the index, reverse and count keys of the predicates against their data, and
+// repairs them if asked to. The repaired keys are written after the latest version of the DB.
+func fsck(db *badger.DB) {
+	if opt.fsckRepair && opt.readOnly {
+		log.Fatalf("--fsck_repair needs --readonly=false")
+	}
+	schema.Init(db)
+	x.Check(schema.LoadFromDb(context.Background()))
+
+	var repairTs uint64
+	if opt.fsckRepair {
+		repairTs = db.MaxVersion() + 1
+	}
+	preds := schema.State().Predicates()
+	sort.Strings(preds)
+
+	var checked, issues, repaired int
+	for _, attr := range preds {
+		if opt.predicate != "" && x.ParseAttr(attr) != opt.predicate {
+			continue
+		}
+		report, err := posting.Fsck(context.Background(), attr, opt.readTs, repairTs)
+		if err != nil {
+			fmt.Printf("Unable to check predicate %s of namespace %#x: %v\n", x.ParseAttr(attr),
+				x.ParseNamespace(attr), err)
+			continue
+		}
+		checked++
+		issues += len(report.Issues)
+		if report.Repaired {
+			repaired += len(report.Issues)
+		}
+		if len(report.Issues) == 0 {
+			continue
+		}
+
+		fmt.Printf("Predicate %s of namespace %#x: %d data keys, %d index keys, %d issues\n",
+			report.Predicate, x.ParseNamespace(attr), report.DataKeys, report.IndexKeys,
+			len(report.Issues))
+		for _, issue := range report.Issues {
+			fmt.Printf("  %-14s key %s: %d missing %#x, %d orphaned %#x\n", issue.Index,
+				issue.Key, issue.Missing, issue.MissingUids, issue.Orphaned, issue.OrphanedUids)
+		}
+	}
+	fmt.Printf("\nChecked %d predicates, found %d keys not matching the data", checked, issues)
+	if opt.fsckRepair {
+		fmt.Printf(", repaired %d of them at ts %d", repaired, repairTs)
+	}
+	fmt.Println()
+}
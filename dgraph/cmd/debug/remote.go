@@ -0,0 +1,64 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package debug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// remoteLookup looks the key of --lookup up on the live Alpha of --alpha, through its read-only
+// /admin/debug/key endpoint, instead of opening a p directory.
+func remoteLookup() {
+	addr := opt.alpha
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+		addr = "http://" + addr
+	}
+	params := url.Values{}
+	params.Set("key", opt.keyLookup)
+	if opt.readTs != math.MaxUint64 {
+		params.Set("at", strconv.FormatUint(opt.readTs, 10))
+	}
+	req, err := http.NewRequest(http.MethodGet, addr+"/admin/debug/key?"+params.Encode(), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if opt.authToken != "" {
+		req.Header.Set("X-Dgraph-AuthToken", opt.authToken)
+	}
+	if opt.accessJwt != "" {
+		req.Header.Set("X-Dgraph-AccessToken", opt.accessJwt)
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatalf("Unable to reach the Alpha at %s: %v", opt.alpha, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("Unable to look the key up on the Alpha at %s: %s %s", opt.alpha,
+			resp.Status, body)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		log.Fatalf("Invalid response of the Alpha at %s: %v", opt.alpha, err)
+	}
+	fmt.Println(out.String())
+}
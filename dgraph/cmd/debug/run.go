@@ -30,6 +30,7 @@ import (
 	bpb "github.com/dgraph-io/badger/v4/pb"
 	"github.com/dgraph-io/ristretto/v2/z"
 	"github.com/hypermodeinc/dgraph/v25/codec"
+	"github.com/hypermodeinc/dgraph/v25/enc"
 	"github.com/hypermodeinc/dgraph/v25/posting"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/raftwal"
@@ -60,6 +61,11 @@ type flagOptions struct {
 	key           x.Sensitive
 	onlySummary   bool
 	parseKey      string
+	fsck          bool
+	fsckRepair    bool
+	alpha         string
+	authToken     string
+	accessJwt     string
 
 	// Options related to the WAL.
 	wdir           string
@@ -105,6 +111,19 @@ func init() {
 		"Set snapshot term,index,readts to this. Value must be comma-separated list containing"+
 			" the value for these vars in that order.")
 	flag.StringVar(&opt.parseKey, "parse_key", "", "Parse hex key.")
+	flag.BoolVar(&opt.fsck, "fsck", false,
+		"Verify the index, reverse and count keys against the data, of all the predicates or "+
+			"of the one given by --pred.")
+	flag.BoolVar(&opt.fsckRepair, "fsck_repair", false,
+		"Rewrite the keys found by --fsck not to match the data. Needs --readonly=false, with "+
+			"the Alpha stopped.")
+	flag.StringVar(&opt.alpha, "alpha", "",
+		"HTTP address of a live Alpha to look the key of --lookup up on, read-only, instead of "+
+			"opening a p directory. The Alpha must serve the predicate of the key.")
+	flag.StringVar(&opt.authToken, "auth_token", "",
+		"The X-Dgraph-AuthToken of the Alpha of --alpha.")
+	flag.StringVar(&opt.accessJwt, "access_jwt", "",
+		"The access JWT of a guardian of the galaxy, for the Alpha of --alpha with ACL.")
 	x.RegisterEncFlag(flag)
 }
 
@@ -977,6 +996,13 @@ func run() {
 		fmt.Printf(" Key: %+v\n", pk)
 		return
 	}
+	if opt.alpha != "" {
+		if opt.keyLookup == "" {
+			log.Fatal("--alpha needs the hex key to look up given by --lookup")
+		}
+		remoteLookup()
+		return
+	}
 
 	var err error
 	dir := opt.pdir
@@ -988,6 +1014,11 @@ func run() {
 	keys, err := x.GetEncAclKeys(Debug.Conf)
 	x.Check(err)
 	opt.key = keys.EncKey
+	if keys.EncNsKeyDir != "" {
+		nsKeys, err := enc.LoadNamespaceKeys(keys.EncNsKeyDir, keys.EncKey, keys.EncKms)
+		x.Check(err)
+		posting.SetNamespaceKeys(nsKeys)
+	}
 
 	if isWal {
 		store, err := raftwal.InitEncrypted(dir, opt.key)
@@ -1036,6 +1067,8 @@ func run() {
 		fmt.Printf("Total: %d\n", total)
 	case opt.sizeHistogram:
 		sizeHistogram(db)
+	case opt.fsck:
+		fsck(db)
 	default:
 		printKeys(db)
 	}
@@ -27,6 +27,7 @@ import (
 	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/hypermodeinc/dgraph/v25/dql"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
 	"github.com/hypermodeinc/dgraph/v25/tok"
 	"github.com/hypermodeinc/dgraph/v25/types"
 	"github.com/hypermodeinc/dgraph/v25/x"
@@ -280,6 +281,15 @@ func (l *loader) conflictKeysForNQuad(nq *api.NQuad) ([]uint64, error) {
 		return keys, nil
 	}
 
+	switch pred.Conflict {
+	case schema.ConflictUid:
+		// Only the data key conflicts, whatever the value.
+		return append(keys, farm.Fingerprint64(x.DataKey(attr, sid))), nil
+	case schema.ConflictValue:
+		key := fingerprintEdge(de, pred)
+		return append(keys, farm.Fingerprint64(x.DataKey(attr, sid))^key), nil
+	}
+
 	if pred.List {
 		key := fingerprintEdge(de, pred)
 		keys = append(keys, farm.Fingerprint64(x.DataKey(attr, sid))^key)
@@ -116,6 +116,56 @@ func TestLiveLoadUpsert(t *testing.T) {
 	checkUpsertLoadedData(t)
 }
 
+func TestLiveLoadUpsertExternalId(t *testing.T) {
+	testutil.DropAll(t, dg)
+
+	load := func(file string) {
+		pipeline := [][]string{
+			{testutil.DgraphBinaryPath(), "live",
+				"--schema", testDataDir + "/xid.schema", "--files", testDataDir + "/" + file,
+				"--alpha", alphaService, "--creds", "user=groot;password=password;",
+				"--upsert-external-id", "xid"},
+		}
+		_, err := testutil.Pipeline(pipeline)
+		require.NoError(t, err, "live loading RDF file exited with error")
+	}
+	load("extid_a.rdf")
+	// The records of extid_b.rdf update the node of an existing id, and create a single node
+	// for a new id set by two records of the batch.
+	load("extid_b.rdf")
+
+	resp, err := dg.NewTxn().Query(context.Background(), `
+		{
+			q(func: has(xid), orderasc: xid) {
+				xid
+				name
+				value
+			}
+		}
+	`)
+	require.NoError(t, err)
+	testutil.CompareJSON(t, `
+		{
+			"q": [
+				{"xid": "ext.1", "name": "name 1", "value": "value 1"},
+				{"xid": "ext.2", "name": "name 2"},
+				{"xid": "ext.3", "name": "name 3", "value": "value 3"}
+			]
+		}
+	`, string(resp.GetJson()))
+}
+
+func TestLiveLoadUpsertExternalIdWithUpsertPredicate(t *testing.T) {
+	pipeline := [][]string{
+		{testutil.DgraphBinaryPath(), "live",
+			"--schema", testDataDir + "/xid.schema", "--files", testDataDir + "/extid_a.rdf",
+			"--alpha", alphaService, "--creds", "user=groot;password=password;",
+			"--upsert-external-id", "xid", "-U", "xid"},
+	}
+	_, err := testutil.Pipeline(pipeline)
+	require.Error(t, err, "--upsert-external-id and -U can't be used together")
+}
+
 func checkLoadedData(t *testing.T, newUids bool) {
 	resp, err := dg.NewTxn().Query(context.Background(), `
 		{
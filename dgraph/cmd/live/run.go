@@ -61,6 +61,9 @@ type options struct {
 	key             x.Sensitive
 	namespaceToLoad uint64
 	preserveNs      bool
+
+	// upsertExternalId is the predicate of the external ids the records are upserted on.
+	upsertExternalId string
 }
 
 type Predicate struct {
@@ -74,6 +77,7 @@ type Predicate struct {
 	Upsert     bool     `json:"upsert,omitempty"`
 	Reverse    bool     `json:"reverse,omitempty"`
 	NoConflict bool     `json:"no_conflict,omitempty"`
+	Conflict   string   `json:"conflict,omitempty"`
 	Unique     bool     `json:"unique,omitempty"`
 	ValueType  types.TypeID
 }
@@ -164,7 +168,12 @@ func init() {
 
 	flag.StringP("bufferSize", "m", "100", "Buffer for each thread")
 	flag.StringP("upsertPredicate", "U", "", "run in upsertPredicate mode. the value would "+
-		"be used to store blank nodes as an xid")
+		"be used to store blank nodes as an xid. Can't be used with --upsert-external-id.")
+	flag.String("upsert-external-id", "", "Predicate of the external ids of the records, e.g. "+
+		"externalId. A record with the external id of an existing node updates the node, "+
+		"instead of creating a new one, in batched upserts. The predicate must be indexed. "+
+		"The external ids are the values of the predicate in the data, while --upsertPredicate "+
+		"upserts on the names of the blank nodes, so the two flags are mutually exclusive.")
 	flag.String("tmp", "t", "Directory to store temporary buffers.")
 	flag.Int64("force-namespace", 0, "Namespace onto which to load the data."+
 		"Only superadmin should use this for loading data into multiple namespaces or some"+
@@ -416,6 +425,113 @@ func (l *loader) upsertUids(nqs []*api.NQuad) error {
 	return nil
 }
 
+// upsertExternalIds assigns the subjects which set an external id in the NQuads the uid of the
+// node with the external id, or of a new node with it, in an upsert of the batch. So the NQuads of
+// the subjects update their existing nodes, instead of creating duplicates.
+//
+// Example upsert of the external ids:
+//
+//	query {
+//	    u_1 as u_1(func: eq(externalId, "1234")) {uid}
+//	}
+//
+//	mutation {
+//	    set {
+//	         uid(u_1) <externalId> "1234" .
+//	    }
+//	}
+func (l *loader) upsertExternalIds(nqs []*api.NQuad) error {
+	l.upsertLock.Lock()
+	defer l.upsertLock.Unlock()
+
+	// ids maps the subjects to the variables of their external ids. The subjects with the same
+	// external id get the same node.
+	ids := make(map[string]string)
+	queried := make(map[string]struct{})
+	query := strings.Builder{}
+	query.WriteString("query {")
+	query.WriteRune('\n')
+	var mutations []*api.NQuad
+	for _, nq := range nqs {
+		if nq.Predicate != opt.upsertExternalId {
+			continue
+		}
+		id, ok := externalId(nq)
+		subject := x.NamespaceAttr(nq.Namespace, nq.Subject)
+		if !ok || l.alloc.CheckUid(subject) {
+			continue
+		}
+		if _, err := strconv.ParseUint(nq.Subject, 0, 64); err == nil && !opt.newUids {
+			// The subject is the uid of the node.
+			continue
+		}
+
+		if _, ok := ids[subject]; ok {
+			continue
+		}
+		idx := generateBlankNode(x.NamespaceAttr(nq.Namespace, id))
+		ids[subject] = idx
+		if _, ok := queried[idx]; ok {
+			continue
+		}
+		queried[idx] = struct{}{}
+		query.WriteString(generateQuery(idx, opt.upsertExternalId, id))
+		query.WriteRune('\n')
+		mutations = append(mutations, &api.NQuad{
+			Subject:     generateUidFunc(idx),
+			Predicate:   opt.upsertExternalId,
+			ObjectValue: nq.ObjectValue,
+		})
+	}
+
+	if len(mutations) == 0 {
+		return nil
+	}
+	query.WriteRune('}')
+
+	resp, err := l.dc.NewTxn().Do(l.opts.Ctx, &api.Request{
+		CommitNow: true,
+		Query:     query.String(),
+		Mutations: []*api.Mutation{{Set: mutations}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var result map[string][]struct {
+		Uid string
+	}
+	if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+		return err
+	}
+	for subject, idx := range ids {
+		val := resp.GetUids()[generateUidFunc(idx)]
+		// The external id already exists in dgraph.
+		if nodes := result[idx]; len(nodes) > 0 {
+			val = nodes[0].Uid
+		}
+		uid, err := strconv.ParseUint(val, 0, 64)
+		if err != nil {
+			return errors.Wrapf(err, "while upserting the external id of %s", x.ParseAttr(subject))
+		}
+		l.alloc.SetUid(subject, uid)
+	}
+	return nil
+}
+
+// externalId returns the external id the NQuad sets, if its value is a string or an int.
+func externalId(nq *api.NQuad) (string, bool) {
+	switch val := nq.GetObjectValue().GetVal().(type) {
+	case *api.Value_StrVal:
+		return val.StrVal, true
+	case *api.Value_DefaultVal:
+		return val.DefaultVal, true
+	case *api.Value_IntVal:
+		return strconv.FormatInt(val.IntVal, 10), true
+	}
+	return "", false
+}
+
 // allocateUids looks for the maximum uid value in the given NQuads and bumps the
 // maximum seen uid to that value.
 func (l *loader) allocateUids(nqs []*api.NQuad) {
@@ -530,6 +646,11 @@ func (l *loader) processLoadFile(ctx context.Context, rd *bufio.Reader, ck chunk
 
 			if opt.upsertPredicate == "" {
 				l.allocateUids(nqs)
+				if opt.upsertExternalId != "" {
+					if err = l.upsertExternalIds(nqs); err != nil {
+						return
+					}
+				}
 			} else {
 				// TODO(Naman): Handle this. Upserts UIDs send a single upsert block for multiple
 				// nquads. These nquads may belong to different namespaces. Hence, alpha can't
@@ -669,21 +790,22 @@ func run() error {
 
 	x.PrintVersion()
 	opt = options{
-		dataFiles:       Live.Conf.GetString("files"),
-		dataFormat:      Live.Conf.GetString("format"),
-		schemaFile:      Live.Conf.GetString("schema"),
-		concurrent:      Live.Conf.GetInt("conc"),
-		batchSize:       Live.Conf.GetInt("batch"),
-		clientDir:       Live.Conf.GetString("xidmap"),
-		authToken:       Live.Conf.GetString("auth_token"),
-		useCompression:  Live.Conf.GetBool("use_compression"),
-		newUids:         Live.Conf.GetBool("new_uids"),
-		verbose:         Live.Conf.GetBool("verbose"),
-		httpAddr:        Live.Conf.GetString("http"),
-		bufferSize:      Live.Conf.GetInt("bufferSize"),
-		upsertPredicate: Live.Conf.GetString("upsertPredicate"),
-		tmpDir:          Live.Conf.GetString("tmp"),
-		key:             keys.EncKey,
+		dataFiles:        Live.Conf.GetString("files"),
+		dataFormat:       Live.Conf.GetString("format"),
+		schemaFile:       Live.Conf.GetString("schema"),
+		concurrent:       Live.Conf.GetInt("conc"),
+		batchSize:        Live.Conf.GetInt("batch"),
+		clientDir:        Live.Conf.GetString("xidmap"),
+		authToken:        Live.Conf.GetString("auth_token"),
+		useCompression:   Live.Conf.GetBool("use_compression"),
+		newUids:          Live.Conf.GetBool("new_uids"),
+		verbose:          Live.Conf.GetBool("verbose"),
+		httpAddr:         Live.Conf.GetString("http"),
+		bufferSize:       Live.Conf.GetInt("bufferSize"),
+		upsertPredicate:  Live.Conf.GetString("upsertPredicate"),
+		upsertExternalId: Live.Conf.GetString("upsert-external-id"),
+		tmpDir:           Live.Conf.GetString("tmp"),
+		key:              keys.EncKey,
 	}
 
 	forceNs := Live.Conf.GetInt64("force-namespace")
@@ -724,11 +846,14 @@ func run() error {
 		rootNsOperation = true
 		ctx = x.AttachRootNsOperation(ctx, opt.namespaceToLoad)
 		// We don't support upsert predicate while loading data in multiple namespace.
-		if len(opt.upsertPredicate) > 0 {
+		if len(opt.upsertPredicate) > 0 || len(opt.upsertExternalId) > 0 {
 			return errors.Errorf("Upsert Predicate feature is not supported for loading" +
 				"into multiple namespaces.")
 		}
 	}
+	if len(opt.upsertPredicate) > 0 && len(opt.upsertExternalId) > 0 {
+		return errors.Errorf("--upsertPredicate and --upsert-external-id can't be used together.")
+	}
 
 	bmOpts := batchMutationOptions{
 		Size:          opt.batchSize,
@@ -776,6 +901,15 @@ func run() error {
 		return err
 	}
 
+	if len(opt.upsertExternalId) > 0 {
+		// The external ids are looked up with eq, which needs an index.
+		pred := l.schema.preds[x.NamespaceAttr(opt.namespaceToLoad, opt.upsertExternalId)]
+		if opt.preserveNs || pred == nil || !pred.Index {
+			return errors.Errorf("The predicate %s of --upsert-external-id must be indexed in "+
+				"the namespace the data is loaded into", opt.upsertExternalId)
+		}
+	}
+
 	if opt.dataFiles == "" {
 		return errors.New("RDF or JSON file(s) location must be specified")
 	}
@@ -14,8 +14,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hypermodeinc/dgraph/v25/dgraphapi"
 	"github.com/hypermodeinc/dgraph/v25/dgraphtest"
+	"github.com/hypermodeinc/dgraph/v25/x"
 	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 
 	"github.com/stretchr/testify/require"
@@ -355,3 +358,76 @@ func TestMCPSSE(t *testing.T) {
 		require.NotEmpty(t, resultText, "Should receive run get common queries result")
 	})
 }
+
+func TestMCPSSEAcl(t *testing.T) {
+	conf := dgraphtest.NewClusterConfig().WithNumAlphas(1).WithNumZeros(1).WithReplicas(1).
+		WithACL(time.Hour).WithMCP()
+	c, err := dgraphtest.NewLocalCluster(conf)
+	require.NoError(t, err)
+	defer func() { c.Cleanup(t.Failed()) }()
+	require.NoError(t, c.Start())
+
+	hc, err := c.HTTPClient()
+	require.NoError(t, err)
+	require.NoError(t, hc.LoginIntoNamespace(dgraphapi.DefaultUser,
+		dgraphapi.DefaultPassword, x.RootNamespace))
+	_, err = hc.CreateUser("alice", "simplepassword")
+	require.NoError(t, err)
+	alice, err := c.HTTPClient()
+	require.NoError(t, err)
+	require.NoError(t, alice.LoginIntoNamespace("alice", "simplepassword", x.RootNamespace))
+
+	port, err := c.GetAlphaHttpPublicPort(0)
+	require.NoError(t, err)
+	serverURL := fmt.Sprintf("http://localhost:%s/mcp/sse", port)
+
+	// alterSchema alters the schema with the MCP server, sending the token if there's one.
+	alterSchema := func(t *testing.T, accessJwt string) (*mcp.CallToolResult, error) {
+		var opts []transport.ClientOption
+		if accessJwt != "" {
+			opts = append(opts, client.WithHeaders(map[string]string{
+				"X-Dgraph-AccessToken": accessJwt,
+			}))
+		}
+		mcpClient, err := client.NewSSEMCPClient(serverURL, opts...)
+		require.NoError(t, err)
+		defer mcpClient.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		require.NoError(t, mcpClient.Start(ctx))
+		initReq := mcp.InitializeRequest{}
+		initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		initReq.Params.ClientInfo = mcp.Implementation{Name: "sse-acl-test-client", Version: "1.0.0"}
+		_, err = mcpClient.Initialize(ctx, initReq)
+		require.NoError(t, err)
+
+		toolRequest := mcp.CallToolRequest{}
+		toolRequest.Params.Name = "alter_schema"
+		toolRequest.Params.Arguments = map[string]interface{}{"schema": "n: string ."}
+		return mcpClient.CallTool(ctx, toolRequest)
+	}
+	// denied asserts that the tool call failed with the error.
+	denied := func(t *testing.T, result *mcp.CallToolResult, err error, msg string) {
+		require.NoError(t, err)
+		require.True(t, result.IsError, "the alter should be denied")
+		require.NotEmpty(t, result.Content)
+		require.Contains(t, result.Content[0].(mcp.TextContent).Text, msg)
+	}
+
+	t.Run("Guardian", func(t *testing.T) {
+		result, err := alterSchema(t, hc.AccessJwt)
+		require.NoError(t, err)
+		require.False(t, result.IsError, "the alter should succeed: %v", result.Content)
+	})
+
+	t.Run("NonGuardian", func(t *testing.T) {
+		result, err := alterSchema(t, alice.AccessJwt)
+		denied(t, result, err, "unauthorized to alter")
+	})
+
+	t.Run("NoToken", func(t *testing.T) {
+		result, err := alterSchema(t, "")
+		denied(t, result, err, "no accessJwt available")
+	})
+}
@@ -13,6 +13,8 @@ const (
 	doubleType
 	datetimeType
 	uidType // foreign key reference, which would correspond to uid type in Dgraph
+	boolType
+	geoType
 )
 
 // the typeToString map is used to generate the Dgraph schema file
@@ -30,6 +32,8 @@ func initDataTypes() {
 	typeToString[doubleType] = "double"
 	typeToString[datetimeType] = "datetime"
 	typeToString[uidType] = "uid"
+	typeToString[boolType] = "bool"
+	typeToString[geoType] = "geo"
 
 	sqlTypeToInternal = make(map[string]dataType)
 	sqlTypeToInternal["int"] = intType
@@ -6,9 +6,8 @@
 package migrate
 
 import (
-	"bufio"
-	"database/sql"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -19,13 +18,13 @@ import (
 // all the tables' generation guide,
 // the writer to output the generated RDF entries,
 // the writer to output the Dgraph schema,
-// and a sqlPool to read information from MySQL
+// and a rowReader to read the rows from MySQL or from a PostgreSQL dump
 type dumpMeta struct {
 	tableInfos   map[string]*sqlTable
 	tableGuides  map[string]*tableGuide
-	dataWriter   *bufio.Writer
-	schemaWriter *bufio.Writer
-	sqlPool      *sql.DB
+	dataWriter   flushWriter
+	schemaWriter flushWriter
+	rows         rowReader
 
 	buf strings.Builder // reusable buf for building strings, call buf.Reset before use
 }
@@ -55,7 +54,7 @@ func (m *dumpMeta) dumpSchema() error {
 	for table := range m.tableGuides {
 		tableInfo := m.tableInfos[table]
 		for _, index := range createDgraphSchema(tableInfo) {
-			_, err := m.schemaWriter.WriteString(index)
+			_, err := io.WriteString(m.schemaWriter, index)
 			if err != nil {
 				return errors.Wrapf(err, "while writing schema")
 			}
@@ -91,15 +90,6 @@ func (m *dumpMeta) dumpTable(table string) error {
 	tableGuide := m.tableGuides[table]
 	tableInfo := m.tableInfos[table]
 
-	escapedColNames := escapeColumnNames(tableInfo.columnNames)
-
-	query := fmt.Sprintf(`select %s from %s`, strings.Join(escapedColNames, ","), table)
-	rows, err := m.sqlPool.Query(query)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
 	// populate the predNames
 	for _, column := range tableInfo.columnNames {
 		tableInfo.predNames = append(tableInfo.predNames,
@@ -110,12 +100,8 @@ func (m *dumpMeta) dumpTable(table string) error {
 		tableInfo: tableInfo,
 	}
 
-	for rows.Next() {
-		// step 1: read the row's column values
-		colValues, err := getColumnValues(tableInfo.columnNames, tableInfo.columnDataTypes, rows)
-		if err != nil {
-			return err
-		}
+	// step 1: read the row's column values
+	return m.rows.readRows(tableInfo, func(colValues []interface{}) error {
 		row.values = colValues
 
 		// step 2: output the column values in RDF format
@@ -125,9 +111,8 @@ func (m *dumpMeta) dumpTable(table string) error {
 		// step 3: record mappings to the blankNodeLabel so that future tables can look up the
 		// blankNodeLabel
 		tableGuide.valuesRecorder.record(tableInfo, colValues, row.blankNodeLabel)
-	}
-
-	return nil
+		return nil
+	})
 }
 
 // dumpTableConstraints reads data from a table, and then generate RDF entries
@@ -137,33 +122,19 @@ func (m *dumpMeta) dumpTableConstraints(table string) error {
 	tableGuide := m.tableGuides[table]
 	tableInfo := m.tableInfos[table]
 
-	escapedColNames := escapeColumnNames(tableInfo.columnNames)
-
-	query := fmt.Sprintf(`select %s from %s`, strings.Join(escapedColNames, ","), table)
-	rows, err := m.sqlPool.Query(query)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
 	row := &sqlRow{
 		tableInfo: tableInfo,
 	}
-	for rows.Next() {
-		// step 1: read the row's column values
-		colValues, err := getColumnValues(tableInfo.columnNames, tableInfo.columnDataTypes, rows)
-		if err != nil {
-			return err
-		}
+	// step 1: read the row's column values
+	return m.rows.readRows(tableInfo, func(colValues []interface{}) error {
 		row.values = colValues
 
 		// step 2: output the constraints in RDF format
 		row.blankNodeLabel = tableGuide.blankNode.generate(tableInfo, colValues)
 
 		m.outputConstraints(row, tableInfo)
-	}
-
-	return nil
+		return nil
+	})
 }
 
 // outputRow takes a row with its metadata as well as the table metadata, and
@@ -220,6 +191,13 @@ func (m *dumpMeta) outputConstraints(row *sqlRow, tableInfo *sqlTable) {
 			return
 		}
 		foreignBlankNode := m.tableGuides[foreignTableName].valuesRecorder.getBlankNode(refLabel)
+		if foreignBlankNode == "" {
+			if !quiet {
+				logger.Printf("ignoring the constraint because no row of table %s "+
+					"has the ref label %s\n", foreignTableName, refLabel)
+			}
+			continue
+		}
 		m.outputPlainCell(row.blankNodeLabel,
 			getPredFromConstraint(tableInfo.tableName, separator, constraint), uidType,
 			foreignBlankNode)
@@ -0,0 +1,152 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/chunker"
+	"github.com/hypermodeinc/dgraph/v25/lex"
+)
+
+// alphaSchemaWriter buffers the schema, and alters the schema of Dgraph Alpha with it when
+// flushed.
+type alphaSchemaWriter struct {
+	dg  *dgo.Dgraph
+	buf bytes.Buffer
+}
+
+func (w *alphaSchemaWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *alphaSchemaWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	err := w.dg.Alter(context.Background(), &api.Operation{Schema: w.buf.String()})
+	w.buf.Reset()
+	return errors.Wrapf(err, "while altering the schema")
+}
+
+// alphaDataWriter parses the RDF written to it, and commits it to Dgraph Alpha in batches of
+// N-Quads. The blank nodes of the committed batches are replaced by their uids in the next ones,
+// so that the edges between them are kept.
+type alphaDataWriter struct {
+	dg        *dgo.Dgraph
+	batchSize int
+
+	lexer   lex.Lexer
+	partial []byte // the last line written, if it isn't complete yet
+	batch   []*api.NQuad
+	uids    map[string]string
+	err     error
+	nquads  int
+	batches int
+}
+
+func newAlphaDataWriter(dg *dgo.Dgraph, batchSize int) *alphaDataWriter {
+	return &alphaDataWriter{
+		dg:        dg,
+		batchSize: max(batchSize, 1),
+		uids:      make(map[string]string),
+	}
+}
+
+func (w *alphaDataWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	data := p
+	if len(w.partial) > 0 {
+		data = append(w.partial, p...)
+		w.partial = nil
+	}
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			w.partial = append(w.partial, data...)
+			break
+		}
+		if err := w.addLine(string(data[:idx])); err != nil {
+			w.err = err
+			return 0, err
+		}
+		data = data[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (w *alphaDataWriter) addLine(line string) error {
+	nq, err := chunker.ParseRDF(line, &w.lexer)
+	switch {
+	case err == chunker.ErrEmpty:
+		return nil
+	case err != nil:
+		return errors.Wrapf(err, "while parsing %q", line)
+	}
+	w.batch = append(w.batch, &nq)
+	if len(w.batch) >= w.batchSize {
+		return w.commit()
+	}
+	return nil
+}
+
+// commit commits the batch, and records the uids assigned to its blank nodes.
+func (w *alphaDataWriter) commit() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	for _, nq := range w.batch {
+		if uid, ok := w.uids[strings.TrimPrefix(nq.Subject, "_:")]; ok {
+			nq.Subject = uid
+		}
+		if uid, ok := w.uids[strings.TrimPrefix(nq.ObjectId, "_:")]; ok {
+			nq.ObjectId = uid
+		}
+	}
+
+	ctx := context.Background()
+	txn := w.dg.NewTxn()
+	defer func() { _ = txn.Discard(ctx) }()
+	resp, err := txn.Mutate(ctx, &api.Mutation{Set: w.batch, CommitNow: true})
+	if err != nil {
+		return errors.Wrapf(err, "while committing batch %d", w.batches+1)
+	}
+	for blank, uid := range resp.Uids {
+		w.uids[blank] = uid
+	}
+	w.nquads += len(w.batch)
+	w.batches++
+	w.batch = w.batch[:0]
+	return nil
+}
+
+// Flush commits the pending N-Quads, and returns the first error that happened while writing.
+func (w *alphaDataWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(bytes.TrimSpace(w.partial)) > 0 {
+		line := string(w.partial)
+		w.partial = nil
+		if err := w.addLine(line); err != nil {
+			w.err = err
+			return err
+		}
+	}
+	if err := w.commit(); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
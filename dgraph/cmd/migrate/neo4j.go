@@ -0,0 +1,448 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// neo4jRecord is a line of a JSON export of Neo4j generated by apoc.export.json, which is
+// either a node or a relationship.
+type neo4jRecord struct {
+	Type       string                     `json:"type"`
+	ID         json.RawMessage            `json:"id"`
+	Labels     []string                   `json:"labels"`
+	Label      string                     `json:"label"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Start      struct {
+		ID json.RawMessage `json:"id"`
+	} `json:"start"`
+	End struct {
+		ID json.RawMessage `json:"id"`
+	} `json:"end"`
+}
+
+// neo4jPredicate is what is known of a predicate after reading all its values.
+type neo4jPredicate struct {
+	dataType dataType
+	list     bool
+}
+
+// neo4jDump converts a JSON export of Neo4j. The nodes become nodes whose dgraph.type are their
+// labels, the properties of the nodes become predicates, and the relationships become uid
+// predicates named after their type, with their properties as facets.
+type neo4jDump struct {
+	// open opens the export, which is read twice.
+	open func() (io.ReadCloser, error)
+
+	predicates map[string]*neo4jPredicate
+	// types are the predicates of the nodes of every label.
+	types map[string]map[string]struct{}
+	// labels are the labels of the nodes, used to add the relationships to the types.
+	labels map[string][]string
+}
+
+func newNeo4jDump(open func() (io.ReadCloser, error)) *neo4jDump {
+	return &neo4jDump{
+		open:       open,
+		predicates: make(map[string]*neo4jPredicate),
+		types:      make(map[string]map[string]struct{}),
+		labels:     make(map[string][]string),
+	}
+}
+
+// migrate writes the schema inferred from the export, and then its data in RDF.
+func (d *neo4jDump) migrate(schemaWriter, dataWriter flushWriter) error {
+	if err := d.scan(d.inferSchema); err != nil {
+		return errors.Wrapf(err, "while reading the schema")
+	}
+	if err := d.dumpSchema(schemaWriter); err != nil {
+		return errors.Wrapf(err, "while writing schema")
+	}
+
+	var buf strings.Builder
+	err := d.scan(func(rec *neo4jRecord) error {
+		buf.Reset()
+		if err := d.writeRecord(&buf, rec); err != nil {
+			return err
+		}
+		_, err := io.WriteString(dataWriter, buf.String())
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "while writing data")
+	}
+	return dataWriter.Flush()
+}
+
+// scan calls fn with every record of the export.
+func (d *neo4jDump) scan(fn func(rec *neo4jRecord) error) error {
+	r, err := d.open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 256<<20)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec neo4jRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return errors.Wrapf(err, "on line %d", lineNum)
+		}
+		if err := fn(&rec); err != nil {
+			return errors.Wrapf(err, "on line %d", lineNum)
+		}
+	}
+	return scanner.Err()
+}
+
+func (d *neo4jDump) inferSchema(rec *neo4jRecord) error {
+	var predicates []string
+	var labels []string
+	switch rec.Type {
+	case "node":
+		for name, raw := range rec.Properties {
+			pred := neo4jName(name)
+			typ, list := neo4jDataType(raw)
+			if typ == unknownType {
+				// The nulls don't tell the type.
+				continue
+			}
+			d.setPredicate(pred, typ, list)
+			predicates = append(predicates, pred)
+		}
+		labels = rec.Labels
+		d.labels[neo4jID(rec.ID)] = rec.Labels
+	case "relationship":
+		pred := neo4jName(rec.Label)
+		d.setPredicate(pred, uidType, true)
+		predicates = append(predicates, pred)
+		// The nodes are exported before the relationships.
+		labels = d.labels[neo4jID(rec.Start.ID)]
+	default:
+		return errors.Errorf("unknown record type %q", rec.Type)
+	}
+
+	for _, label := range labels {
+		label = neo4jName(label)
+		fields, ok := d.types[label]
+		if !ok {
+			fields = make(map[string]struct{})
+			d.types[label] = fields
+		}
+		for _, pred := range predicates {
+			fields[pred] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// setPredicate merges the type of a value of the predicate with the types of its other values.
+// The predicates whose values have different types are stored as strings.
+func (d *neo4jDump) setPredicate(name string, typ dataType, list bool) {
+	p, ok := d.predicates[name]
+	if !ok {
+		d.predicates[name] = &neo4jPredicate{dataType: typ, list: list}
+		return
+	}
+	p.list = p.list || list
+	switch {
+	case p.dataType == typ:
+	case (p.dataType == intType && typ == floatType) ||
+		(p.dataType == floatType && typ == intType):
+		p.dataType = floatType
+	case p.dataType == uidType || typ == uidType:
+		if !quiet {
+			logger.Printf("predicate %s is used by both properties and relationships, "+
+				"keeping the relationships\n", name)
+		}
+		p.dataType = uidType
+	default:
+		p.dataType = stringType
+	}
+}
+
+func (d *neo4jDump) dumpSchema(w flushWriter) error {
+	preds := make([]string, 0, len(d.predicates))
+	for name := range d.predicates {
+		preds = append(preds, name)
+	}
+	sort.Strings(preds)
+	for _, name := range preds {
+		p := d.predicates[name]
+		typ := p.dataType.String()
+		if p.list {
+			typ = "[" + typ + "]"
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s .\n", name, typ); err != nil {
+			return err
+		}
+	}
+
+	types := make([]string, 0, len(d.types))
+	for name := range d.types {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	for _, name := range types {
+		fields := make([]string, 0, len(d.types[name]))
+		for field := range d.types[name] {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		if _, err := fmt.Fprintf(w, "type %s {\n  %s\n}\n", name,
+			strings.Join(fields, "\n  ")); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (d *neo4jDump) writeRecord(w *strings.Builder, rec *neo4jRecord) error {
+	switch rec.Type {
+	case "node":
+		subject := neo4jBlankNode(rec.ID)
+		for _, label := range rec.Labels {
+			fmt.Fprintf(w, "%s <dgraph.type> %q .\n", subject, neo4jName(label))
+		}
+		names := make([]string, 0, len(rec.Properties))
+		for name := range rec.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			pred := neo4jName(name)
+			p, ok := d.predicates[pred]
+			if !ok || p.dataType == uidType {
+				continue
+			}
+			for _, object := range neo4jObjects(rec.Properties[name], p.dataType) {
+				fmt.Fprintf(w, "%s <%s> %s .\n", subject, pred, object)
+			}
+		}
+	case "relationship":
+		fmt.Fprintf(w, "%s <%s> %s%s .\n", neo4jBlankNode(rec.Start.ID), neo4jName(rec.Label),
+			neo4jBlankNode(rec.End.ID), neo4jFacets(rec.Properties))
+	}
+	return nil
+}
+
+// neo4jDataType returns the data type of the property value, and whether it's a list.
+func neo4jDataType(raw json.RawMessage) (dataType, bool) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return stringType, false
+	}
+	if list, ok := v.([]interface{}); ok {
+		typ := unknownType
+		for _, elem := range list {
+			elemType := neo4jScalarType(elem)
+			switch {
+			case typ == unknownType || typ == elemType:
+				typ = elemType
+			case (typ == intType && elemType == floatType) ||
+				(typ == floatType && elemType == intType):
+				typ = floatType
+			default:
+				typ = stringType
+			}
+		}
+		return typ, true
+	}
+	return neo4jScalarType(v), false
+}
+
+func neo4jScalarType(v interface{}) dataType {
+	switch v := v.(type) {
+	case nil:
+		return unknownType
+	case bool:
+		return boolType
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return intType
+		}
+		return floatType
+	case string:
+		if _, ok := neo4jTime(v); ok {
+			return datetimeType
+		}
+		return stringType
+	case map[string]interface{}:
+		if _, ok := neo4jPoint(v); ok {
+			return geoType
+		}
+		return stringType
+	default:
+		return stringType
+	}
+}
+
+// neo4jObjects returns the RDF objects of the property value, one for every element of a list.
+func neo4jObjects(raw json.RawMessage, typ dataType) []string {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil || v == nil {
+		return nil
+	}
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+
+	objects := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		var s string
+		switch value := value.(type) {
+		case string:
+			s = value
+			if t, ok := neo4jTime(value); ok && typ == datetimeType {
+				s = t.Format(time.RFC3339Nano)
+			}
+		case map[string]interface{}:
+			if point, ok := neo4jPoint(value); ok && typ == geoType {
+				objects = append(objects, strconv.Quote(point)+"^^<geo:geojson>")
+				continue
+			}
+			b, _ := json.Marshal(value)
+			s = string(b)
+		default:
+			b, _ := json.Marshal(value)
+			s = string(b)
+		}
+		objects = append(objects, strconv.Quote(s))
+	}
+	return objects
+}
+
+// neo4jFacets returns the facets of the relationship for its properties. The properties that
+// are lists or maps are skipped, as the facets can only have scalar values.
+func neo4jFacets(props map[string]json.RawMessage) string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	facets := make([]string, 0, len(names))
+	for _, name := range names {
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(props[name]))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			continue
+		}
+		var value string
+		switch v := v.(type) {
+		case bool, json.Number:
+			value = fmt.Sprint(v)
+		case string:
+			value = strconv.Quote(v)
+			if t, ok := neo4jTime(v); ok {
+				value = t.Format(time.RFC3339Nano)
+			}
+		default:
+			if !quiet && v != nil {
+				logger.Printf("ignoring the property %s of a relationship, as it isn't "+
+					"a scalar\n", name)
+			}
+			continue
+		}
+		facets = append(facets, neo4jName(name)+"="+value)
+	}
+	if len(facets) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(facets, ", ") + ")"
+}
+
+// neo4jTimeLayouts are the layouts of the temporal values exported by Neo4j.
+var neo4jTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func neo4jTime(s string) (time.Time, bool) {
+	// Skip the strings that can't be dates early, as most of the strings aren't dates.
+	if len(s) < len("2006-01-02") || s[4] != '-' {
+		return time.Time{}, false
+	}
+	for _, layout := range neo4jTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// neo4jPoint returns the GeoJSON of the point if the map is a spatial point.
+func neo4jPoint(m map[string]interface{}) (string, bool) {
+	coord := func(keys ...string) (float64, bool) {
+		for _, key := range keys {
+			if n, ok := m[key].(json.Number); ok {
+				f, err := n.Float64()
+				return f, err == nil
+			}
+		}
+		return 0, false
+	}
+	lon, okLon := coord("longitude", "x")
+	lat, okLat := coord("latitude", "y")
+	if _, ok := m["crs"]; !ok || !okLon || !okLat {
+		return "", false
+	}
+	return fmt.Sprintf(`{"type":"Point","coordinates":[%v,%v]}`, lon, lat), true
+}
+
+// neo4jID returns the id of the node, which is exported either as a string or as a number.
+func neo4jID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	return string(raw)
+}
+
+func neo4jBlankNode(raw json.RawMessage) string {
+	return "_:n" + neo4jName(neo4jID(raw))
+}
+
+// neo4jName replaces the characters that can't be in a predicate, type or blank node.
+func neo4jName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r > 127:
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
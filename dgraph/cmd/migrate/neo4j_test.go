@@ -0,0 +1,88 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package migrate
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testNeo4jExport = `{"type":"node","id":"0","labels":["Person"],"properties":{"name":"Alice","age":29,"born":"1990-01-02T03:04:05Z","tags":["a","b"]}}
+{"type":"node","id":"1","labels":["Person","Admin"],"properties":{"name":"Bob","age":31.5,"home":{"crs":"wgs-84","latitude":52.5,"longitude":13.4}}}
+{"type":"node","id":"2","labels":["City"],"properties":{"name":"Berlin","extra":null}}
+{"id":"0","type":"relationship","label":"KNOWS","properties":{"since":2010,"how":"work","notes":["x"]},"start":{"id":"0","labels":["Person"]},"end":{"id":"1","labels":["Person"]}}
+{"id":"1","type":"relationship","label":"LIVES_IN","start":{"id":"1"},"end":{"id":"2"}}
+`
+
+func TestMigrateNeo4j(t *testing.T) {
+	initDataTypes()
+	quiet = true
+
+	d := newNeo4jDump(func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(testNeo4jExport)), nil
+	})
+	schema, data := newTestWriter(), newTestWriter()
+	require.NoError(t, d.migrate(schema, data))
+
+	require.Equal(t, `KNOWS: [uid] .
+LIVES_IN: [uid] .
+age: float .
+born: datetime .
+home: geo .
+name: string .
+tags: [string] .
+type Admin {
+  LIVES_IN
+  age
+  home
+  name
+}
+type City {
+  name
+}
+type Person {
+  KNOWS
+  LIVES_IN
+  age
+  born
+  home
+  name
+  tags
+}
+`, schema.buf.String())
+
+	require.Equal(t, []string{
+		`_:n0 <KNOWS> _:n1 (how="work", since=2010) .`,
+		`_:n0 <age> "29" .`,
+		`_:n0 <born> "1990-01-02T03:04:05Z" .`,
+		`_:n0 <dgraph.type> "Person" .`,
+		`_:n0 <name> "Alice" .`,
+		`_:n0 <tags> "a" .`,
+		`_:n0 <tags> "b" .`,
+		`_:n1 <LIVES_IN> _:n2 .`,
+		`_:n1 <age> "31.5" .`,
+		`_:n1 <dgraph.type> "Admin" .`,
+		`_:n1 <dgraph.type> "Person" .`,
+		`_:n1 <home> "{\"type\":\"Point\",\"coordinates\":[13.4,52.5]}"^^<geo:geojson> .`,
+		`_:n1 <name> "Bob" .`,
+		`_:n2 <dgraph.type> "City" .`,
+		`_:n2 <name> "Berlin" .`,
+	}, sortedLines(data.buf.String()))
+
+	d = newNeo4jDump(func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(`{"type":"path"}`)), nil
+	})
+	require.Error(t, d.migrate(newTestWriter(), newTestWriter()))
+}
+
+func TestNeo4jName(t *testing.T) {
+	require.Equal(t, "n4_abc_0", neo4jName("n4:abc-0"))
+	require.Equal(t, "_:n12", neo4jBlankNode([]byte(`12`)))
+	require.Equal(t, "_:n12", neo4jBlankNode([]byte(`"12"`)))
+}
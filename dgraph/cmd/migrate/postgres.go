@@ -0,0 +1,760 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package migrate
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+var (
+	pgCreateTable = regexp.MustCompile(`(?is)^CREATE\s+(?:UNLOGGED\s+)?TABLE\s+` +
+		`(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)\s*\((.*)\)`)
+	pgAlterTable = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:ONLY\s+)?([^\s(]+)\s+ADD\s+(.*)$`)
+	pgCopy       = regexp.MustCompile(`(?is)^COPY\s+([^\s(]+)\s*\(([^)]*)\)\s+FROM\s+stdin`)
+	pgInsert     = regexp.MustCompile(`(?is)^INSERT\s+INTO\s+([^\s(]+)\s*(?:\(([^)]*)\))?\s*` +
+		`VALUES\s*(.*)$`)
+	pgPrimaryKey    = regexp.MustCompile(`(?is)^PRIMARY\s+KEY\s*\(([^)]*)\)`)
+	pgUnique        = regexp.MustCompile(`(?is)^UNIQUE\s*\(([^)]*)\)`)
+	pgForeignKeyDef = regexp.MustCompile(`(?is)^FOREIGN\s+KEY\s*\(([^)]*)\)\s*REFERENCES\s+` +
+		`([^\s(]+)\s*(?:\(([^)]*)\))?`)
+	pgConstraint = regexp.MustCompile(`(?is)^CONSTRAINT\s+("[^"]*"|\S+)\s+(.*)$`)
+	// pgColumnKeywords are the keywords ending the type of a column definition.
+	pgColumnKeywords = regexp.MustCompile(`(?i)\s+(NOT\s+NULL|NULL|DEFAULT|PRIMARY\s+KEY|` +
+		`REFERENCES|UNIQUE|CONSTRAINT|CHECK|COLLATE|GENERATED)\b`)
+	pgReferences = regexp.MustCompile(`(?is)\bREFERENCES\s+([^\s(]+)\s*(?:\(([^)]*)\))?`)
+)
+
+// pgDatetimeLayouts are the layouts of the date and time values in the PostgreSQL dumps.
+var pgDatetimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999Z07",
+	"15:04:05.999999999",
+}
+
+type pgForeignKey struct {
+	name     string
+	columns  []string
+	refTable string
+	// refColumns are empty if the primary key of the referenced table is referenced.
+	refColumns []string
+}
+
+// pgTable is a table of a PostgreSQL dump, along with its rows.
+type pgTable struct {
+	name string
+	// columns are the column names in the order of the CREATE TABLE statement, and types are
+	// their data types.
+	columns     []string
+	types       map[string]dataType
+	primaryKey  []string
+	unique      [][]string
+	foreignKeys []*pgForeignKey
+	// rows are the values of the rows in the order of the columns, with nil for NULL.
+	rows [][]*string
+}
+
+// pgDump holds the tables of a plain SQL dump generated by pg_dump, and reads their rows.
+// The dump is read in memory, as the rows of every table are read twice.
+type pgDump struct {
+	tables map[string]*pgTable
+}
+
+// parsePostgresDump parses the tables, constraints and rows of a plain SQL dump generated by
+// pg_dump. The rows can be given with COPY or INSERT statements. The statements creating other
+// objects, like functions or views, are ignored.
+func parsePostgresDump(r io.Reader) (*pgDump, error) {
+	d := &pgDump{tables: make(map[string]*pgTable)}
+	reader := bufio.NewReaderSize(r, 1<<20)
+
+	var stmt strings.Builder
+	var copyTable *pgTable
+	var copyColumns []int
+	for lineNum := 1; ; lineNum++ {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+		if line == "" && readErr == io.EOF {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case copyTable != nil:
+			if line == `\.` {
+				copyTable = nil
+				break
+			}
+			if err := copyTable.addRow(parseCopyRow(line), copyColumns); err != nil {
+				return nil, errors.Wrapf(err, "on line %d", lineNum)
+			}
+		case stmt.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")):
+			// Skip the comments between the statements.
+		default:
+			stmt.WriteString(line)
+			stmt.WriteByte('\n')
+			if !pgStatementEnds(stmt.String()) {
+				break
+			}
+			var err error
+			copyTable, copyColumns, err = d.parseStatement(stmt.String())
+			if err != nil {
+				return nil, errors.Wrapf(err, "in the statement ending on line %d", lineNum)
+			}
+			stmt.Reset()
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if copyTable != nil {
+		return nil, errors.Errorf("the rows of table %s are not terminated", copyTable.name)
+	}
+	return d, nil
+}
+
+// pgStatementEnds returns true if the statement ends with a semicolon outside of a string or of
+// the body of a function.
+func pgStatementEnds(stmt string) bool {
+	if !strings.HasSuffix(strings.TrimSpace(stmt), ";") {
+		return false
+	}
+	// The quotes in the strings are escaped by doubling them, so they're always balanced.
+	return strings.Count(stmt, "'")%2 == 0 &&
+		strings.Count(stmt, "$$")%2 == 0 && strings.Count(stmt, "$_$")%2 == 0
+}
+
+// parseStatement parses the statement, and returns the table and the indices of the columns of
+// the rows that follow it if it's a COPY statement.
+func (d *pgDump) parseStatement(stmt string) (*pgTable, []int, error) {
+	stmt = strings.TrimSuffix(strings.TrimSpace(stmt), ";")
+
+	if m := pgCreateTable.FindStringSubmatch(stmt); m != nil {
+		return nil, nil, d.parseCreateTable(pgName(m[1]), m[2])
+	}
+	if m := pgAlterTable.FindStringSubmatch(stmt); m != nil {
+		table, ok := d.tables[pgName(m[1])]
+		if !ok {
+			return nil, nil, nil
+		}
+		return nil, nil, table.parseConstraint(m[2])
+	}
+	if m := pgCopy.FindStringSubmatch(stmt); m != nil {
+		table, columns, err := d.tableColumns(m[1], m[2])
+		return table, columns, err
+	}
+	if m := pgInsert.FindStringSubmatch(stmt); m != nil {
+		table, columns, err := d.tableColumns(m[1], m[2])
+		if err != nil {
+			return nil, nil, err
+		}
+		rows, err := parseInsertValues(m[3])
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, row := range rows {
+			if err := table.addRow(row, columns); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+func (d *pgDump) parseCreateTable(name, body string) error {
+	table := &pgTable{name: name, types: make(map[string]dataType)}
+	for _, def := range splitTopLevel(body, ',') {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		if isConstraint, err := table.parseTableConstraint(def); err != nil || isConstraint {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := table.parseColumn(def); err != nil {
+			return err
+		}
+	}
+	d.tables[name] = table
+	return nil
+}
+
+// parseColumn parses a column definition, along with its primary key and references.
+func (t *pgTable) parseColumn(def string) error {
+	name, rest := splitName(def)
+	if name == "" {
+		return errors.Errorf("invalid column definition %q in table %s", def, t.name)
+	}
+	typ := rest
+	if loc := pgColumnKeywords.FindStringIndex(" " + rest); loc != nil {
+		typ = rest[:max(loc[0]-1, 0)]
+	}
+	t.columns = append(t.columns, name)
+	t.types[name] = pgDataType(typ)
+
+	upper := strings.ToUpper(rest)
+	if strings.Contains(upper, "PRIMARY KEY") {
+		t.primaryKey = []string{name}
+	}
+	if m := pgReferences.FindStringSubmatch(rest); m != nil {
+		t.foreignKeys = append(t.foreignKeys, &pgForeignKey{
+			name:       fmt.Sprintf("%s_%s_fkey", t.name, name),
+			columns:    []string{name},
+			refTable:   pgName(m[1]),
+			refColumns: pgNames(m[2]),
+		})
+	}
+	return nil
+}
+
+// parseTableConstraint parses the definition if it's a table constraint, and returns true if
+// it's one.
+func (t *pgTable) parseTableConstraint(def string) (bool, error) {
+	upper := strings.ToUpper(def)
+	for _, prefix := range []string{"CONSTRAINT", "PRIMARY KEY", "UNIQUE", "FOREIGN KEY", "CHECK",
+		"EXCLUDE"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true, t.parseConstraint(def)
+		}
+	}
+	return false, nil
+}
+
+// parseConstraint parses a table constraint, given in a CREATE TABLE or ALTER TABLE statement.
+// The constraints other than the primary keys, unique keys and foreign keys are ignored.
+func (t *pgTable) parseConstraint(def string) error {
+	def = strings.TrimSpace(def)
+	name := fmt.Sprintf("%s_fkey%d", t.name, len(t.foreignKeys)+1)
+	if m := pgConstraint.FindStringSubmatch(def); m != nil {
+		name, def = pgName(m[1]), strings.TrimSpace(m[2])
+	}
+
+	switch m := pgForeignKeyDef.FindStringSubmatch(def); {
+	case pgPrimaryKey.MatchString(def):
+		t.primaryKey = pgNames(pgPrimaryKey.FindStringSubmatch(def)[1])
+	case pgUnique.MatchString(def):
+		t.unique = append(t.unique, pgNames(pgUnique.FindStringSubmatch(def)[1]))
+	case m != nil:
+		fk := &pgForeignKey{
+			name:       name,
+			columns:    pgNames(m[1]),
+			refTable:   pgName(m[2]),
+			refColumns: pgNames(m[3]),
+		}
+		if len(fk.refColumns) > 0 && len(fk.refColumns) != len(fk.columns) {
+			return errors.Errorf("the foreign key %s of table %s references %d columns "+
+				"with %d columns", name, t.name, len(fk.refColumns), len(fk.columns))
+		}
+		t.foreignKeys = append(t.foreignKeys, fk)
+	}
+	return nil
+}
+
+// tableColumns returns the table of a COPY or INSERT statement, along with the indices in the
+// table of the columns of the rows. The columns are all the columns of the table if the
+// statement doesn't list them.
+func (d *pgDump) tableColumns(name, columns string) (*pgTable, []int, error) {
+	name = pgName(name)
+	table, ok := d.tables[name]
+	if !ok {
+		return nil, nil, errors.Errorf("found the rows of table %s before its definition", name)
+	}
+	names := pgNames(columns)
+	if len(names) == 0 {
+		names = table.columns
+	}
+	indices := make([]int, 0, len(names))
+	for _, col := range names {
+		idx := -1
+		for i, c := range table.columns {
+			if c == col {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, nil, errors.Errorf("table %s doesn't have column %s", name, col)
+		}
+		indices = append(indices, idx)
+	}
+	return table, indices, nil
+}
+
+func (t *pgTable) addRow(values []*string, columns []int) error {
+	if len(values) != len(columns) {
+		return errors.Errorf("found %d values in a row of table %s with %d columns",
+			len(values), t.name, len(columns))
+	}
+	row := make([]*string, len(t.columns))
+	for i, idx := range columns {
+		row[idx] = values[i]
+	}
+	t.rows = append(t.rows, row)
+	return nil
+}
+
+// sqlTables returns the metadata of the tables in the given list, or of all the tables if it's
+// empty. The foreign keys to the other tables are dropped.
+func (d *pgDump) sqlTables(tableNames string) (map[string]*sqlTable, error) {
+	selected := make(map[string]*pgTable)
+	if tableNames == "" {
+		selected = d.tables
+	}
+	for _, name := range strings.Split(tableNames, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		table, ok := d.tables[name]
+		if !ok {
+			return nil, errors.Errorf("the dump doesn't have table %s", name)
+		}
+		selected[name] = table
+	}
+
+	tables := make(map[string]*sqlTable, len(selected))
+	for name, t := range selected {
+		info := &sqlTable{
+			tableName:             name,
+			columns:               make(map[string]*columnInfo),
+			columnNames:           append([]string{}, t.columns...),
+			isForeignKey:          make(map[string]bool),
+			predNames:             make([]string, 0),
+			dstTables:             make(map[string]interface{}),
+			foreignKeyConstraints: make(map[string]*fkConstraint),
+		}
+		// The columns are sorted alphabetically, like the ones read from MySQL.
+		sort.Strings(info.columnNames)
+		for _, col := range info.columnNames {
+			info.columns[col] = &columnInfo{name: col, dataType: t.types[col]}
+			info.columnDataTypes = append(info.columnDataTypes, t.types[col])
+		}
+		for _, cols := range t.unique {
+			for _, col := range cols {
+				if c, ok := info.columns[col]; ok {
+					c.keyType = secondary
+				}
+			}
+		}
+		for _, col := range t.primaryKey {
+			if c, ok := info.columns[col]; ok {
+				c.keyType = primary
+			}
+		}
+
+		for _, fk := range t.foreignKeys {
+			ref, ok := selected[fk.refTable]
+			if !ok {
+				if !quiet {
+					logger.Printf("ignoring the foreign key %s of table %s, as table %s "+
+						"isn't migrated\n", fk.name, name, fk.refTable)
+				}
+				continue
+			}
+			refColumns := fk.refColumns
+			if len(refColumns) == 0 {
+				refColumns = ref.primaryKey
+			}
+			if len(refColumns) != len(fk.columns) {
+				return nil, errors.Errorf("the foreign key %s of table %s doesn't match the "+
+					"primary key of table %s", fk.name, name, fk.refTable)
+			}
+			constraint := &fkConstraint{}
+			for i, col := range fk.columns {
+				constraint.parts = append(constraint.parts, &constraintPart{
+					tableName:        name,
+					columnName:       col,
+					remoteTableName:  fk.refTable,
+					remoteColumnName: refColumns[i],
+				})
+				info.isForeignKey[col] = true
+			}
+			info.foreignKeyConstraints[fk.name] = constraint
+			info.dstTables[fk.refTable] = struct{}{}
+		}
+		tables[name] = info
+	}
+	return tables, nil
+}
+
+func (d *pgDump) readRows(info *sqlTable, fn func(values []interface{}) error) error {
+	t := d.tables[info.tableName]
+	indices := make([]int, len(info.columnNames))
+	for i, col := range info.columnNames {
+		for j, c := range t.columns {
+			if c == col {
+				indices[i] = j
+			}
+		}
+	}
+	for _, row := range t.rows {
+		values := make([]interface{}, len(indices))
+		for i, idx := range indices {
+			v, err := pgValue(row[idx], info.columnDataTypes[i])
+			if err != nil {
+				return errors.Wrapf(err, "in column %s of table %s", info.columnNames[i],
+					info.tableName)
+			}
+			values[i] = v
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pgValue converts the value in the dump to the Go type of the data type returned by
+// getColumnValues.
+func pgValue(raw *string, dt dataType) (interface{}, error) {
+	if dt == stringType {
+		if raw == nil {
+			return []byte(nil), nil
+		}
+		return []byte(*raw), nil
+	}
+
+	var err error
+	switch dt {
+	case intType:
+		var v sql.NullInt64
+		if raw != nil {
+			v.Int64, err = strconv.ParseInt(*raw, 10, 64)
+			v.Valid = err == nil
+		}
+		return v, err
+	case floatType:
+		var v sql.NullFloat64
+		if raw != nil {
+			v.Float64, err = strconv.ParseFloat(*raw, 64)
+			v.Valid = err == nil
+		}
+		return v, err
+	case boolType:
+		var v sql.NullBool
+		if raw != nil {
+			switch strings.ToLower(*raw) {
+			case "t", "true", "y", "yes", "on", "1":
+				v = sql.NullBool{Bool: true, Valid: true}
+			case "f", "false", "n", "no", "off", "0":
+				v = sql.NullBool{Valid: true}
+			default:
+				err = errors.Errorf("invalid boolean %q", *raw)
+			}
+		}
+		return v, err
+	case datetimeType:
+		var v mysql.NullTime
+		if raw == nil {
+			return v, nil
+		}
+		for _, layout := range pgDatetimeLayouts {
+			if v.Time, err = time.Parse(layout, *raw); err == nil {
+				v.Valid = true
+				return v, nil
+			}
+		}
+		return v, errors.Errorf("invalid date and time %q", *raw)
+	default:
+		return nil, errors.Errorf("unsupported type %s", dt)
+	}
+}
+
+// pgDataType returns the data type of the PostgreSQL type.
+func pgDataType(typ string) dataType {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if strings.HasSuffix(typ, "]") {
+		// The arrays are migrated as their text representation.
+		return stringType
+	}
+	switch {
+	case strings.HasPrefix(typ, "interval"):
+		return stringType
+	case strings.HasPrefix(typ, "smallint"), strings.HasPrefix(typ, "integer"),
+		strings.HasPrefix(typ, "bigint"), strings.HasPrefix(typ, "int"),
+		strings.HasSuffix(typ, "serial"):
+		return intType
+	case strings.HasPrefix(typ, "numeric"), strings.HasPrefix(typ, "decimal"),
+		strings.HasPrefix(typ, "real"), strings.HasPrefix(typ, "double precision"),
+		strings.HasPrefix(typ, "float"):
+		return floatType
+	case strings.HasPrefix(typ, "bool"):
+		return boolType
+	case strings.HasPrefix(typ, "timestamp"), strings.HasPrefix(typ, "date"),
+		strings.HasPrefix(typ, "time"):
+		return datetimeType
+	default:
+		return stringType
+	}
+}
+
+// parseCopyRow parses a row of a COPY statement, whose values are separated by tabs.
+func parseCopyRow(line string) []*string {
+	fields := strings.Split(line, "\t")
+	values := make([]*string, len(fields))
+	for i, field := range fields {
+		if field == `\N` {
+			continue
+		}
+		v := unescapeCopyValue(field)
+		values[i] = &v
+	}
+	return values
+}
+
+func unescapeCopyValue(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x':
+			j := i + 1
+			for j < len(s) && j < i+3 && isHexDigit(s[j]) {
+				j++
+			}
+			if v, err := strconv.ParseUint(s[i+1:j], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i = j - 1
+			} else {
+				b.WriteByte(c)
+			}
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+				j++
+			}
+			v, _ := strconv.ParseUint(s[i:j], 8, 8)
+			b.WriteByte(byte(v))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// parseInsertValues parses the rows of the VALUES of an INSERT statement, like
+// (1, 'a', NULL), (2, 'b”s', '2020-01-01'::date).
+func parseInsertValues(s string) ([][]*string, error) {
+	var rows [][]*string
+	i := 0
+	skipSpaces := func() {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\n' || s[i] == '\t' || s[i] == '\r') {
+			i++
+		}
+	}
+	for {
+		skipSpaces()
+		if i >= len(s) {
+			return rows, nil
+		}
+		if s[i] != '(' {
+			return nil, errors.Errorf("expected ( at offset %d of the values", i)
+		}
+		i++
+		var row []*string
+		for {
+			skipSpaces()
+			value, next, err := parseInsertValue(s, i)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, value)
+			i = next
+			skipSpaces()
+			if i >= len(s) {
+				return nil, errors.New("unterminated row in the values")
+			}
+			if s[i] == ')' {
+				i++
+				break
+			}
+			if s[i] != ',' {
+				return nil, errors.Errorf("expected , or ) at offset %d of the values", i)
+			}
+			i++
+		}
+		rows = append(rows, row)
+		skipSpaces()
+		if i < len(s) && s[i] == ',' {
+			i++
+		}
+	}
+}
+
+// parseInsertValue parses the value at offset i, and returns it along with the offset after it.
+func parseInsertValue(s string, i int) (*string, int, error) {
+	var value *string
+	escapes := false
+	if i+1 < len(s) && (s[i] == 'E' || s[i] == 'e') && s[i+1] == '\'' {
+		escapes = true
+		i++
+	}
+	if i < len(s) && s[i] == '\'' {
+		var b strings.Builder
+		i++
+		for {
+			if i >= len(s) {
+				return nil, i, errors.New("unterminated string in the values")
+			}
+			c := s[i]
+			if c == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i += 2
+				continue
+			}
+			if c == '\'' {
+				i++
+				break
+			}
+			if escapes && c == '\\' && i+1 < len(s) {
+				b.WriteByte(unescapeCopyValue(s[i : i+2])[0])
+				i += 2
+				continue
+			}
+			b.WriteByte(c)
+			i++
+		}
+		v := b.String()
+		value = &v
+	}
+
+	// Read the rest of the value, like a number, a keyword or a cast.
+	start, depth := i, 0
+	for ; i < len(s); i++ {
+		if c := s[i]; c == '(' {
+			depth++
+		} else if c == ')' && depth > 0 {
+			depth--
+		} else if (c == ',' || c == ')') && depth == 0 {
+			break
+		}
+	}
+	rest := strings.TrimSpace(s[start:i])
+	if cast := strings.Index(rest, "::"); cast >= 0 {
+		rest = strings.TrimSpace(rest[:cast])
+	}
+	switch {
+	case value != nil:
+	case strings.EqualFold(rest, "NULL"):
+	default:
+		value = &rest
+	}
+	return value, i, nil
+}
+
+// splitTopLevel splits s on the separator outside of parentheses and quotes.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	inString, inIdent := false, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case inString:
+			inString = c != '\''
+		case inIdent:
+			inIdent = c != '"'
+		case c == '\'':
+			inString = true
+		case c == '"':
+			inIdent = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitName splits the definition into its first name, unquoted, and the rest.
+func splitName(def string) (string, string) {
+	def = strings.TrimSpace(def)
+	if strings.HasPrefix(def, `"`) {
+		end := 1
+		for end < len(def) {
+			if def[end] == '"' && (end+1 == len(def) || def[end+1] != '"') {
+				break
+			}
+			if def[end] == '"' {
+				end++
+			}
+			end++
+		}
+		if end >= len(def) {
+			return "", def
+		}
+		return strings.ReplaceAll(def[1:end], `""`, `"`), strings.TrimSpace(def[end+1:])
+	}
+	if i := strings.IndexAny(def, " \t\n"); i >= 0 {
+		return def[:i], strings.TrimSpace(def[i+1:])
+	}
+	return def, ""
+}
+
+// pgName returns the unquoted name of the table or column, without its schema.
+func pgName(name string) string {
+	parts := splitTopLevel(strings.TrimSpace(name), '.')
+	last := strings.TrimSpace(parts[len(parts)-1])
+	if len(last) >= 2 && strings.HasPrefix(last, `"`) && strings.HasSuffix(last, `"`) {
+		return strings.ReplaceAll(last[1:len(last)-1], `""`, `"`)
+	}
+	return last
+}
+
+// pgNames returns the names in the comma separated list.
+func pgNames(list string) []string {
+	var names []string
+	for _, name := range splitTopLevel(list, ',') {
+		if name = pgName(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,200 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPostgresDump = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+
+CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  NEW.updated = now();
+  RETURN NEW;
+END;
+$$;
+
+CREATE TABLE public.person (
+    id integer NOT NULL,
+    name character varying(50),
+    "Active" boolean DEFAULT true,
+    born date
+);
+
+CREATE TABLE public.pet (
+    pet_id bigint NOT NULL,
+    owner_id integer,
+    weight numeric(5,2),
+    CONSTRAINT pet_weight_check CHECK ((weight > (0)::numeric))
+);
+
+COPY public.person (id, name, "Active", born) FROM stdin;
+1	Alice\tA.	t	1990-01-02
+2	Bob	f	\N
+\.
+
+INSERT INTO public.pet (pet_id, owner_id, weight) VALUES (10, 1, 4.5), (11, 3, NULL);
+INSERT INTO public.pet VALUES (12, 2, '7.25'::numeric);
+
+ALTER TABLE ONLY public.person
+    ADD CONSTRAINT person_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.pet
+    ADD CONSTRAINT pet_pkey PRIMARY KEY (pet_id);
+ALTER TABLE ONLY public.pet
+    ADD CONSTRAINT pet_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES public.person(id);
+`
+
+type testWriter struct {
+	*bufio.Writer
+	buf *bytes.Buffer
+}
+
+func newTestWriter() *testWriter {
+	var buf bytes.Buffer
+	return &testWriter{Writer: bufio.NewWriter(&buf), buf: &buf}
+}
+
+func sortedLines(s string) []string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func TestParsePostgresDump(t *testing.T) {
+	d, err := parsePostgresDump(strings.NewReader(testPostgresDump))
+	require.NoError(t, err)
+	require.Len(t, d.tables, 2)
+
+	person := d.tables["person"]
+	require.Equal(t, []string{"id", "name", "Active", "born"}, person.columns)
+	require.Equal(t, map[string]dataType{"id": intType, "name": stringType,
+		"Active": boolType, "born": datetimeType}, person.types)
+	require.Equal(t, []string{"id"}, person.primaryKey)
+	require.Len(t, person.rows, 2)
+	require.Equal(t, "Alice\tA.", *person.rows[0][1])
+	require.Nil(t, person.rows[1][3])
+
+	pet := d.tables["pet"]
+	require.Equal(t, floatType, pet.types["weight"])
+	require.Len(t, pet.rows, 3)
+	require.Nil(t, pet.rows[1][2])
+	require.Equal(t, "7.25", *pet.rows[2][2])
+	require.Len(t, pet.foreignKeys, 1)
+	require.Equal(t, &pgForeignKey{name: "pet_owner_id_fkey", columns: []string{"owner_id"},
+		refTable: "person", refColumns: []string{"id"}}, pet.foreignKeys[0])
+
+	_, err = parsePostgresDump(strings.NewReader("COPY public.t (a) FROM stdin;\n1\n\\.\n"))
+	require.Error(t, err)
+	_, err = parsePostgresDump(strings.NewReader(
+		"CREATE TABLE t (a int);\nCOPY t (a) FROM stdin;\n1\n"))
+	require.Error(t, err)
+}
+
+func TestMigratePostgres(t *testing.T) {
+	initDataTypes()
+	separator = "."
+	quiet = true
+
+	d, err := parsePostgresDump(strings.NewReader(testPostgresDump))
+	require.NoError(t, err)
+	tableInfos, err := d.sqlTables("")
+	require.NoError(t, err)
+	populateReferencedByColumns(tableInfos)
+
+	schema, data := newTestWriter(), newTestWriter()
+	require.NoError(t, generateSchemaAndData(&dumpMeta{
+		tableInfos:  tableInfos,
+		tableGuides: getTableGuides(tableInfos),
+		rows:        d,
+	}, schema, data))
+
+	require.Equal(t, []string{
+		"person.Active: bool .",
+		"person.born: datetime .",
+		"person.id: int .",
+		"person.name: string .",
+		"pet.owner_id: [uid] .",
+		"pet.pet_id: int .",
+		"pet.weight: float .",
+	}, sortedLines(schema.buf.String()))
+	require.Equal(t, []string{
+		`_:person.1 <person.Active> "true" .`,
+		`_:person.1 <person.born> "1990-01-02 00:00:00 +0000 UTC" .`,
+		`_:person.1 <person.id> "1" .`,
+		`_:person.1 <person.name> "Alice\tA." .`,
+		`_:person.2 <person.Active> "false" .`,
+		`_:person.2 <person.id> "2" .`,
+		`_:person.2 <person.name> "Bob" .`,
+		`_:pet.10 <pet.owner_id> _:person.1 .`,
+		`_:pet.10 <pet.pet_id> "10" .`,
+		`_:pet.10 <pet.weight> "4.5" .`,
+		`_:pet.11 <pet.pet_id> "11" .`,
+		`_:pet.12 <pet.owner_id> _:person.2 .`,
+		`_:pet.12 <pet.pet_id> "12" .`,
+		`_:pet.12 <pet.weight> "7.25" .`,
+	}, sortedLines(data.buf.String()))
+
+	// The foreign keys to the tables that aren't migrated are dropped.
+	tableInfos, err = d.sqlTables("pet")
+	require.NoError(t, err)
+	require.Empty(t, tableInfos["pet"].foreignKeyConstraints)
+	_, err = d.sqlTables("owner")
+	require.Error(t, err)
+}
+
+func TestParseInsertValues(t *testing.T) {
+	rows, err := parseInsertValues(`(1, 'it''s', NULL, E'a\nb'), (2, now(), 'x'::text, true)`)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	values := func(row []*string) []interface{} {
+		var vs []interface{}
+		for _, v := range row {
+			if v == nil {
+				vs = append(vs, nil)
+			} else {
+				vs = append(vs, *v)
+			}
+		}
+		return vs
+	}
+	require.Equal(t, []interface{}{"1", "it's", nil, "a\nb"}, values(rows[0]))
+	require.Equal(t, []interface{}{"2", "now()", "x", "true"}, values(rows[1]))
+
+	_, err = parseInsertValues(`(1, 'a`)
+	require.Error(t, err)
+	_, err = parseInsertValues(`1, 2`)
+	require.Error(t, err)
+}
+
+func TestPgDataType(t *testing.T) {
+	for typ, expected := range map[string]dataType{
+		"integer":                     intType,
+		"bigserial":                   intType,
+		"interval":                    stringType,
+		"double precision":            floatType,
+		"numeric(10,2)":               floatType,
+		"boolean":                     boolType,
+		"timestamp without time zone": datetimeType,
+		"text":                        stringType,
+		"integer[]":                   stringType,
+		"jsonb":                       stringType,
+	} {
+		require.Equal(t, expected, pgDataType(typ), typ)
+	}
+}
@@ -8,6 +8,7 @@ package migrate
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/dgraph-io/ristretto/v2/z"
 	"github.com/hypermodeinc/dgraph/v25/x"
 )
 
@@ -28,8 +30,9 @@ var (
 
 func init() {
 	Migrate.Cmd = &cobra.Command{
-		Use:   "migrate",
-		Short: "Run the Dgraph migration tool from a MySQL database to Dgraph",
+		Use: "migrate",
+		Short: "Run the Dgraph migration tool from a MySQL database, or a PostgreSQL or Neo4j " +
+			"dump, to Dgraph",
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := run(Migrate.Conf); err != nil {
 				logger.Fatalf("%v\n", err)
@@ -41,6 +44,10 @@ func init() {
 	Migrate.Cmd.SetHelpTemplate(x.NonRootTemplate)
 
 	flag := Migrate.Cmd.Flags()
+	flag.StringP("source", "", sourceMysql, "The source to migrate, either mysql for a MySQL "+
+		"database, postgres for a plain SQL dump of pg_dump, or neo4j for a JSON export of "+
+		"apoc.export.json")
+	flag.StringP("dump", "", "", "The dump file to migrate for the postgres and neo4j sources")
 	flag.StringP("user", "", "", "The user for logging in")
 	flag.StringP("password", "", "", "The password used for logging in")
 	flag.StringP("db", "", "", "The database to import")
@@ -52,71 +59,155 @@ func init() {
 	flag.BoolP("quiet", "q", false, "Enable quiet mode to suppress the warning logs")
 	flag.StringP("host", "", "localhost", "The hostname or IP address of the database server.")
 	flag.StringP("port", "", "3306", "The port of the database server.")
+	flag.StringP("alpha", "", "", "The comma separated addresses of Dgraph Alpha to load the "+
+		"schema and the data into, instead of writing them to the output files")
+	flag.IntP("batch", "", 1000, "The number of N-Quads in each transaction when loading the "+
+		"data into Dgraph Alpha")
+	flag.IntP("retries", "", 10, "How many times to retry setting up the connection to Alpha")
+	flag.StringP("creds", "", "",
+		`Various login credentials if login is required.
+	user defines the username to login.
+	password defines the password of the user.
+	namespace defines the namespace to log into.
+	Sample flag could look like --creds user=username;password=mypass;namespace=2`)
+	// --tls SuperFlag
+	x.RegisterClientTLSFlags(flag)
 }
 
+const (
+	sourceMysql    = "mysql"
+	sourcePostgres = "postgres"
+	sourceNeo4j    = "neo4j"
+)
+
 func run(conf *viper.Viper) error {
-	user := conf.GetString("user")
-	db := conf.GetString("db")
-	password := conf.GetString("password")
+	source := conf.GetString("source")
+	dump := conf.GetString("dump")
 	tables := conf.GetString("tables")
 	schemaOutput := conf.GetString("output_schema")
 	dataOutput := conf.GetString("output_data")
-	host := conf.GetString("host")
-	port := conf.GetString("port")
+	alpha := conf.GetString("alpha")
 	quiet = conf.GetBool("quiet")
 	separator = conf.GetString("separator")
 
 	switch {
-	case len(user) == 0:
-		logger.Fatalf("The user property should not be empty.")
-	case len(db) == 0:
-		logger.Fatalf("The db property should not be empty.")
-	case len(password) == 0:
-		logger.Fatalf("The password property should not be empty.")
-	case len(schemaOutput) == 0:
+	case source != sourceMysql && source != sourcePostgres && source != sourceNeo4j:
+		logger.Fatalf("The source property should be one of mysql, postgres or neo4j.")
+	case source != sourceMysql && len(dump) == 0:
+		logger.Fatalf("Please use the --dump option to provide the %s dump file.", source)
+	case len(alpha) == 0 && len(schemaOutput) == 0:
 		logger.Fatalf("Please use the --output_schema option to " +
 			"provide the schema output file.")
-	case len(dataOutput) == 0:
+	case len(alpha) == 0 && len(dataOutput) == 0:
 		logger.Fatalf("Please use the --output_data option to provide the data output file.")
 	}
 
-	if err := checkFile(schemaOutput); err != nil {
-		return err
-	}
-	if err := checkFile(dataOutput); err != nil {
-		return err
+	if len(alpha) == 0 {
+		if err := checkFile(schemaOutput); err != nil {
+			return err
+		}
+		if err := checkFile(dataOutput); err != nil {
+			return err
+		}
 	}
 
 	initDataTypes()
 
-	pool, err := getPool(host, port, user, password, db)
-	if err != nil {
-		return err
+	var m *dumpMeta
+	switch source {
+	case sourceMysql:
+		user := conf.GetString("user")
+		db := conf.GetString("db")
+		password := conf.GetString("password")
+		switch {
+		case len(user) == 0:
+			logger.Fatalf("The user property should not be empty.")
+		case len(db) == 0:
+			logger.Fatalf("The db property should not be empty.")
+		case len(password) == 0:
+			logger.Fatalf("The password property should not be empty.")
+		}
+
+		pool, err := getPool(conf.GetString("host"), conf.GetString("port"), user, password, db)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+
+		tablesToRead, err := showTables(pool, tables)
+		if err != nil {
+			return err
+		}
+
+		tableInfos := make(map[string]*sqlTable)
+		for _, table := range tablesToRead {
+			tableInfo, err := parseTables(pool, table, db)
+			if err != nil {
+				return err
+			}
+			tableInfos[tableInfo.tableName] = tableInfo
+		}
+		m = &dumpMeta{tableInfos: tableInfos, rows: &mysqlRows{pool: pool}}
+	case sourcePostgres:
+		f, err := os.Open(dump)
+		if err != nil {
+			return err
+		}
+		pg, err := parsePostgresDump(f)
+		_ = f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "while reading %s", dump)
+		}
+		tableInfos, err := pg.sqlTables(tables)
+		if err != nil {
+			return err
+		}
+		m = &dumpMeta{tableInfos: tableInfos, rows: pg}
+	}
+	if m != nil {
+		populateReferencedByColumns(m.tableInfos)
+		m.tableGuides = getTableGuides(m.tableInfos)
 	}
-	defer pool.Close()
 
-	tablesToRead, err := showTables(pool, tables)
+	schemaWriter, dataWriter, closeFunc, err := getWriters(conf, schemaOutput, dataOutput)
 	if err != nil {
 		return err
 	}
+	defer closeFunc()
 
-	tableInfos := make(map[string]*sqlTable)
-	for _, table := range tablesToRead {
-		tableInfo, err := parseTables(pool, table, db)
-		if err != nil {
-			return err
-		}
-		tableInfos[tableInfo.tableName] = tableInfo
+	if source == sourceNeo4j {
+		return newNeo4jDump(func() (io.ReadCloser, error) {
+			return os.Open(dump)
+		}).migrate(schemaWriter, dataWriter)
 	}
-	populateReferencedByColumns(tableInfos)
+	return generateSchemaAndData(m, schemaWriter, dataWriter)
+}
 
-	tableGuides := getTableGuides(tableInfos)
+// getWriters returns the writers of the schema and of the data, which are either the output files
+// or Dgraph Alpha if the --alpha option is set.
+func getWriters(conf *viper.Viper, schemaOutput, dataOutput string) (
+	flushWriter, flushWriter, func(), error) {
+	if conf.GetString("alpha") != "" {
+		// Do a sanity check on the passed credentials.
+		_ = z.NewSuperFlag(conf.GetString("creds")).MergeAndCheckDefault(x.DefaultCreds)
+		dg, closeFunc := x.GetDgraphClient(conf, true)
+		return &alphaSchemaWriter{dg: dg}, newAlphaDataWriter(dg, conf.GetInt("batch")),
+			closeFunc, nil
+	}
 
-	return generateSchemaAndData(&dumpMeta{
-		tableInfos:  tableInfos,
-		tableGuides: tableGuides,
-		sqlPool:     pool,
-	}, schemaOutput, dataOutput)
+	schemaWriter, schemaCancelFunc, err := getFileWriter(schemaOutput)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dataWriter, dataCancelFunc, err := getFileWriter(dataOutput)
+	if err != nil {
+		schemaCancelFunc()
+		return nil, nil, nil, err
+	}
+	return schemaWriter, dataWriter, func() {
+		schemaCancelFunc()
+		dataCancelFunc()
+	}, nil
 }
 
 // checkFile checks if the program is trying to output to an existing file.
@@ -147,21 +238,9 @@ func checkFile(file string) error {
 	return nil
 }
 
-// generateSchemaAndData opens the two files schemaOutput and dataOutput,
-// then it dumps schema to the writer backed by schemaOutput, and data in RDF format
-// to the writer backed by dataOutput
-func generateSchemaAndData(dumpMeta *dumpMeta, schemaOutput string, dataOutput string) error {
-	schemaWriter, schemaCancelFunc, err := getFileWriter(schemaOutput)
-	if err != nil {
-		return err
-	}
-	defer schemaCancelFunc()
-	dataWriter, dataCancelFunc, err := getFileWriter(dataOutput)
-	if err != nil {
-		return err
-	}
-	defer dataCancelFunc()
-
+// generateSchemaAndData dumps schema to the schemaWriter, and data in RDF format
+// to the dataWriter
+func generateSchemaAndData(dumpMeta *dumpMeta, schemaWriter, dataWriter flushWriter) error {
 	dumpMeta.dataWriter = dataWriter
 	dumpMeta.schemaWriter = schemaWriter
 
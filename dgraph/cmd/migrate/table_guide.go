@@ -8,6 +8,7 @@ package migrate
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/go-sql-driver/mysql"
@@ -174,6 +175,11 @@ func getValue(dataType dataType, value interface{}) (string, error) {
 		}
 		floatVal, _ := value.(sql.NullFloat64).Value()
 		return fmt.Sprintf("%v", floatVal), nil
+	case boolType:
+		if !value.(sql.NullBool).Valid {
+			return "", errors.Errorf("found invalid nullbool")
+		}
+		return strconv.FormatBool(value.(sql.NullBool).Bool), nil
 	default:
 		return fmt.Sprintf("%v", value), nil
 	}
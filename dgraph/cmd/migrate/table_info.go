@@ -234,8 +234,17 @@ func validateAndGetReverse(constraint *fkConstraint) (string, *fkConstraint) {
 // and stores them in tables[table name].cstSources
 func populateReferencedByColumns(tables map[string]*sqlTable) {
 	for _, tableInfo := range tables {
-		for _, constraint := range tableInfo.foreignKeyConstraints {
+		for name, constraint := range tableInfo.foreignKeyConstraints {
 			reverseTable, reverseConstraint := validateAndGetReverse(constraint)
+			if _, ok := tables[reverseTable]; !ok {
+				// The referenced table isn't migrated, so there's no node to link to.
+				if !quiet {
+					logger.Printf("ignoring the constraint %s of table %s, as table %s "+
+						"isn't migrated\n", name, tableInfo.tableName, reverseTable)
+				}
+				delete(tableInfo.foreignKeyConstraints, name)
+				continue
+			}
 
 			tables[reverseTable].cstSources = append(tables[reverseTable].cstSources,
 				reverseConstraint)
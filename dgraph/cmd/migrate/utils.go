@@ -9,6 +9,7 @@ import (
 	"bufio"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"strings"
@@ -75,6 +76,46 @@ type columnIdx struct {
 	index int    // the column index
 }
 
+// flushWriter is where the schema and the data are written, either a file or Dgraph Alpha.
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// A rowReader reads the rows of the SQL tables.
+type rowReader interface {
+	// readRows calls fn with the values of every row of the table. The values are in the order of
+	// the table's columnNames, and of the Go types returned by getColumnValues.
+	readRows(info *sqlTable, fn func(values []interface{}) error) error
+}
+
+// mysqlRows reads the rows of the tables from a MySQL database.
+type mysqlRows struct {
+	pool *sql.DB
+}
+
+func (r *mysqlRows) readRows(info *sqlTable, fn func(values []interface{}) error) error {
+	escapedColNames := escapeColumnNames(info.columnNames)
+
+	query := fmt.Sprintf(`select %s from %s`, strings.Join(escapedColNames, ","), info.tableName)
+	rows, err := r.pool.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		colValues, err := getColumnValues(info.columnNames, info.columnDataTypes, rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(colValues); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func getFileWriter(filename string) (*bufio.Writer, func(), error) {
 	output, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
@@ -0,0 +1,294 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package replay replays the queries captured by the Alphas with --capture against a cluster,
+// checking that their results didn't change, for instance to validate an upgrade.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// Replay is the sub-command invoked when calling "dgraph replay".
+var Replay x.SubCommand
+
+func init() {
+	Replay.Cmd = &cobra.Command{
+		Use:   "replay",
+		Short: "Replay the queries captured by the Alphas against a cluster",
+		Long: `
+Replay the DQL queries captured by the Alphas with --capture against a cluster, at the pace at
+which they were captured or faster, and check that their results are the same. The cluster should
+hold the data the queries were captured on, for instance restored from a backup taken while they
+were captured; the queries reading data changed since then are reported as mismatches.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := run(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+		Annotations: map[string]string{"group": "tool"},
+	}
+	Replay.EnvPrefix = "DGRAPH_REPLAY"
+	Replay.Cmd.SetHelpTemplate(x.NonRootTemplate)
+
+	flag := Replay.Cmd.Flags()
+	// --tls SuperFlag
+	x.RegisterClientTLSFlags(flag)
+
+	flag.StringSlice("files", nil, "Comma separated list of the capture files to replay.")
+	flag.String("alpha", "localhost:9080", "Comma separated list of the Alphas to replay on.")
+	flag.Int("retries", 10, "How many times to retry setting up the connection.")
+	flag.String("creds", "",
+		`Various login credentials if login is required.
+	user defines the username to login.
+	password defines the password of the user.
+	namespace defines the namespace to log into. Only the queries of this namespace are replayed.
+	Sample flag could look like --creds user=username;password=mypass;namespace=2`)
+	flag.Float64("speed", 1, "Speed of the replay relative to the capture: 2 replays the "+
+		"queries twice as fast as they came. 0 replays them as fast as possible.")
+	flag.Int("conc", 16, "Maximum number of queries replayed at once.")
+	flag.Duration("timeout", time.Minute, "Timeout of each query.")
+	flag.Bool("verify", true, "Check that the results of the queries are the same as the "+
+		"captured ones. The replay fails if they aren't.")
+	flag.String("mismatches", "", "File where the queries whose results differ or which failed "+
+		"are written, as JSON lines.")
+}
+
+// readCaptured returns the queries of the namespace captured in the files, in the order they came.
+func readCaptured(files []string, ns uint64) ([]*x.CapturedQuery, error) {
+	var queries []*x.CapturedQuery
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 1<<20), x.GrpcMaxSize)
+		for line := 1; scanner.Scan(); line++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			q := &x.CapturedQuery{}
+			if err := json.Unmarshal(scanner.Bytes(), q); err != nil {
+				_ = f.Close()
+				return nil, errors.Wrapf(err, "while reading line %d of %s", line, name)
+			}
+			if q.Namespace == ns {
+				queries = append(queries, q)
+			}
+		}
+		err = scanner.Err()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading %s", name)
+		}
+	}
+	sort.SliceStable(queries, func(i, j int) bool { return queries[i].Time.Before(queries[j].Time) })
+	return queries, nil
+}
+
+// queryFunc runs a captured query, returning its JSON result and its latency on the server.
+type queryFunc func(ctx context.Context, q *x.CapturedQuery) ([]byte, time.Duration, error)
+
+// options are the options of a replay.
+type options struct {
+	speed   float64
+	conc    int
+	timeout time.Duration
+	verify  bool
+	// mismatches receives the queries whose results differ or which failed, if it isn't nil.
+	mismatches io.Writer
+}
+
+// mismatch is a query whose result differs from the captured one, or which failed.
+type mismatch struct {
+	Query        string            `json:"query"`
+	Vars         map[string]string `json:"vars,omitempty"`
+	CapturedTime time.Time         `json:"captured_time"`
+	CapturedHash string            `json:"captured_hash"`
+	ReplayedHash string            `json:"replayed_hash,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// report sums up a replay.
+type report struct {
+	sync.Mutex
+	queries    int
+	failed     int
+	mismatched int
+	// capturedLatency and replayedLatency are the latencies of the queries in microseconds, as
+	// captured and as replayed.
+	capturedLatency *hdrhistogram.Histogram
+	replayedLatency *hdrhistogram.Histogram
+}
+
+func newReport() *report {
+	return &report{
+		capturedLatency: hdrhistogram.New(1, time.Hour.Microseconds(), 3),
+		replayedLatency: hdrhistogram.New(1, time.Hour.Microseconds(), 3),
+	}
+}
+
+func (r *report) String() string {
+	percentiles := func(h *hdrhistogram.Histogram) string {
+		d := func(v int64) time.Duration { return time.Duration(v) * time.Microsecond }
+		return fmt.Sprintf("p50 %v, p95 %v, p99 %v, max %v", d(h.ValueAtQuantile(50)),
+			d(h.ValueAtQuantile(95)), d(h.ValueAtQuantile(99)), d(h.Max()))
+	}
+	return fmt.Sprintf("Replayed %d queries: %d failed, %d with a different result.\n"+
+		"Captured latency: %s\nReplayed latency: %s\n", r.queries, r.failed, r.mismatched,
+		percentiles(r.capturedLatency), percentiles(r.replayedLatency))
+}
+
+// replay runs the queries with query, at the pace at which they were captured divided by the
+// speed, at most conc at once.
+func replay(ctx context.Context, queries []*x.CapturedQuery, opts options,
+	query queryFunc) *report {
+
+	rep := newReport()
+	if len(queries) == 0 {
+		return rep
+	}
+	// record writes the mismatch of the query, under the lock of the report.
+	record := func(q *x.CapturedQuery, m *mismatch) {
+		if opts.mismatches == nil {
+			return
+		}
+		m.Query, m.Vars, m.CapturedTime, m.CapturedHash = q.Query, q.Vars, q.Time, q.ResultHash
+		b, err := json.Marshal(m)
+		x.Check(err)
+		_, err = opts.mismatches.Write(append(b, '\n'))
+		x.Check(err)
+	}
+	runOne := func(q *x.CapturedQuery) {
+		qctx, cancel := context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+		result, latency, err := query(qctx, q)
+		var hash string
+		if err == nil && len(result) > 0 {
+			hash, err = x.ResultHash(result)
+		}
+
+		rep.Lock()
+		defer rep.Unlock()
+		rep.queries++
+		_ = rep.capturedLatency.RecordValue(q.Latency.Microseconds())
+		switch {
+		case err != nil:
+			rep.failed++
+			record(q, &mismatch{Error: err.Error()})
+		case opts.verify && hash != q.ResultHash:
+			rep.mismatched++
+			record(q, &mismatch{ReplayedHash: hash})
+		}
+		if err == nil {
+			_ = rep.replayedLatency.RecordValue(latency.Microseconds())
+		}
+	}
+
+	throttle := make(chan struct{}, opts.conc)
+	var wg sync.WaitGroup
+	start, first := time.Now(), queries[0].Time
+	for _, q := range queries {
+		if opts.speed > 0 {
+			at := start.Add(time.Duration(float64(q.Time.Sub(first)) / opts.speed))
+			select {
+			case <-time.After(time.Until(at)):
+			case <-ctx.Done():
+			}
+		}
+		select {
+		case throttle <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(q *x.CapturedQuery) {
+			defer func() {
+				<-throttle
+				wg.Done()
+			}()
+			runOne(q)
+		}(q)
+	}
+	wg.Wait()
+	return rep
+}
+
+func run() error {
+	conf := Replay.Conf
+	creds := z.NewSuperFlag(conf.GetString("creds")).MergeAndCheckDefault(x.DefaultCreds)
+	queries, err := readCaptured(conf.GetStringSlice("files"), creds.GetUint64("namespace"))
+	if err != nil {
+		return err
+	}
+	opts := options{
+		speed:   conf.GetFloat64("speed"),
+		conc:    conf.GetInt("conc"),
+		timeout: conf.GetDuration("timeout"),
+		verify:  conf.GetBool("verify"),
+	}
+	if opts.speed < 0 || opts.conc <= 0 {
+		return errors.Errorf("--speed can't be negative and --conc should be positive")
+	}
+	if name := conf.GetString("mismatches"); name != "" {
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				fmt.Printf("While closing %s: %v\n", name, err)
+			}
+		}()
+		opts.mismatches = f
+	}
+
+	dg, closeFunc := x.GetDgraphClient(conf, true)
+	defer closeFunc()
+	query := func(ctx context.Context, q *x.CapturedQuery) ([]byte, time.Duration, error) {
+		txn := dg.NewReadOnlyTxn()
+		if q.BestEffort {
+			txn = txn.BestEffort()
+		}
+		defer func(txn *dgo.Txn) { _ = txn.Discard(ctx) }(txn)
+		resp, err := txn.QueryWithVars(ctx, q.Query, q.Vars)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.GetJson(), time.Duration(resp.GetLatency().GetTotalNs()), nil
+	}
+
+	fmt.Printf("Replaying %d queries at speed %v\n", len(queries), opts.speed)
+	rep := replay(context.Background(), queries, opts, query)
+	fmt.Print(rep)
+	if rep.failed > 0 || rep.mismatched > 0 {
+		return errors.Errorf("The replay found %d failed queries and %d different results",
+			rep.failed, rep.mismatched)
+	}
+	return nil
+}
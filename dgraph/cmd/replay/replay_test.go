@@ -0,0 +1,111 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestReadCaptured(t *testing.T) {
+	t0 := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	write := func(name string, queries ...*x.CapturedQuery) string {
+		var buf bytes.Buffer
+		for _, q := range queries {
+			b, err := json.Marshal(q)
+			require.NoError(t, err)
+			buf.Write(append(b, '\n'))
+		}
+		path := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+		return path
+	}
+	rotated := write("query_capture-2026-10-15T12-00-05.jsonl",
+		&x.CapturedQuery{Time: t0, Query: "{ a }"},
+		&x.CapturedQuery{Time: t0.Add(2 * time.Second), Namespace: 2, Query: "{ other }"},
+		&x.CapturedQuery{Time: t0.Add(4 * time.Second), Query: "{ c }"})
+	current := write("query_capture.jsonl",
+		&x.CapturedQuery{Time: t0.Add(3 * time.Second), Query: "{ b }"})
+
+	queries, err := readCaptured([]string{current, rotated}, 0)
+	require.NoError(t, err)
+	var got []string
+	for _, q := range queries {
+		got = append(got, q.Query)
+	}
+	require.Equal(t, []string{"{ a }", "{ b }", "{ c }"}, got)
+
+	queries, err = readCaptured([]string{rotated}, 2)
+	require.NoError(t, err)
+	require.Len(t, queries, 1)
+
+	bad := filepath.Join(t.TempDir(), "bad.jsonl")
+	require.NoError(t, os.WriteFile(bad, []byte("{\"query\":\n"), 0644))
+	_, err = readCaptured([]string{bad}, 0)
+	require.ErrorContains(t, err, "line 1")
+}
+
+func TestReplay(t *testing.T) {
+	hash := func(result string) string {
+		h, err := x.ResultHash([]byte(result))
+		require.NoError(t, err)
+		return h
+	}
+	t0 := time.Now()
+	queries := []*x.CapturedQuery{
+		{Time: t0, Query: "same", ResultHash: hash(`{"q":[1]}`), Latency: time.Millisecond},
+		{Time: t0.Add(100 * time.Millisecond), Query: "changed", ResultHash: hash(`{"q":[1]}`)},
+		{Time: t0.Add(200 * time.Millisecond), Query: "failed", ResultHash: hash(`{"q":[]}`)},
+	}
+	query := func(ctx context.Context, q *x.CapturedQuery) ([]byte, time.Duration, error) {
+		switch q.Query {
+		case "same":
+			return []byte(`{"q": [1]}`), 2 * time.Millisecond, nil
+		case "changed":
+			return []byte(`{"q":[2]}`), time.Millisecond, nil
+		}
+		return nil, 0, errors.New("boom")
+	}
+
+	var mismatches bytes.Buffer
+	start := time.Now()
+	rep := replay(context.Background(), queries, options{speed: 2, conc: 2, timeout: time.Second,
+		verify: true, mismatches: &mismatches}, query)
+	// The last query starts 100ms after the first one, at twice the speed of the capture.
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	require.Equal(t, 3, rep.queries)
+	require.Equal(t, 1, rep.failed)
+	require.Equal(t, 1, rep.mismatched)
+	require.Equal(t, int64(2), rep.replayedLatency.TotalCount())
+	require.Contains(t, rep.String(), "Replayed 3 queries: 1 failed, 1 with a different result")
+
+	lines := strings.Split(strings.TrimSpace(mismatches.String()), "\n")
+	require.Len(t, lines, 2)
+	got := make(map[string]mismatch)
+	for _, line := range lines {
+		var m mismatch
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		got[m.Query] = m
+	}
+	require.Equal(t, hash(`{"q":[2]}`), got["changed"].ReplayedHash)
+	require.Equal(t, "boom", got["failed"].Error)
+
+	// Without verifying, only the failures are reported.
+	rep = replay(context.Background(), queries, options{conc: 1, timeout: time.Second}, query)
+	require.Equal(t, 1, rep.failed)
+	require.Equal(t, 0, rep.mismatched)
+}
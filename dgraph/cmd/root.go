@@ -27,6 +27,7 @@ import (
 	"github.com/hypermodeinc/dgraph/v25/backup"
 	checkupgrade "github.com/hypermodeinc/dgraph/v25/check_upgrade"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/alpha"
+	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/bench"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/bulk"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/cert"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/conv"
@@ -38,6 +39,8 @@ import (
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/live"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/mcp"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/migrate"
+	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/replay"
+	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/shell"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/version"
 	"github.com/hypermodeinc/dgraph/v25/dgraph/cmd/zero"
 	"github.com/hypermodeinc/dgraph/v25/upgrade"
@@ -81,7 +84,7 @@ var subcommands = []*x.SubCommand{
 	&bulk.Bulk, &cert.Cert, &conv.Conv, &live.Live, &alpha.Alpha, &zero.Zero, &version.Version,
 	&debug.Debug, &migrate.Migrate, &debuginfo.DebugInfo, &upgrade.Upgrade, &decrypt.Decrypt, &increment.Increment,
 	&checkupgrade.CheckUpgrade, &backup.Restore, &backup.LsBackup, &backup.ExportBackup, &acl.CmdAcl,
-	&audit.CmdAudit, &mcp.Mcp, &dgraphimport.ImportCmd,
+	&audit.CmdAudit, &mcp.Mcp, &dgraphimport.ImportCmd, &shell.Shell, &replay.Replay, &bench.Bench,
 }
 
 func initCmds() {
@@ -0,0 +1,131 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package shell
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// keywords are the functions, directives and keywords of DQL that are autocompleted.
+var keywords = []string{
+	"@cascade", "@facets", "@filter", "@groupby", "@if", "@ignorereflex", "@motif", "@normalize",
+	"@recurse", "@valid_at", "after", "allofterms", "alloftext", "and", "anyofterms", "anyoftext", "as",
+	"avg", "between", "ceil", "cond", "contains", "count", "delete", "depth", "eq", "except",
+	"exp", "expand", "first", "floor", "func", "fuzzy", "ge", "gt", "has", "intersect",
+	"intersects", "le", "len", "levenshtein", "ln", "logbase", "loop", "lt", "match", "math",
+	"max", "min", "mutation", "near", "not", "not_exists", "offset", "or", "orderasc",
+	"orderdesc", "plugin", "pow", "query", "regexp", "schema", "set", "shortest", "similar_to",
+	"since", "sqrt", "sum", "type", "uid", "uid_in", "union", "upsert", "val", "var", "within",
+}
+
+// completer completes the words of DQL statements with the keywords, and with the predicates
+// and types of the schema.
+type completer struct {
+	words []string
+}
+
+func newCompleter() *completer {
+	c := &completer{}
+	c.setWords(nil)
+	return c
+}
+
+func (c *completer) setWords(schemaWords []string) {
+	words := make([]string, 0, len(keywords)+len(schemaWords))
+	words = append(words, keywords...)
+	words = append(words, schemaWords...)
+	sort.Strings(words)
+	// Remove the duplicates, as a predicate can have the name of a keyword.
+	c.words = words[:0]
+	for i, w := range words {
+		if i == 0 || w != words[i-1] {
+			c.words = append(c.words, w)
+		}
+	}
+}
+
+// setSchema sets the predicates and types of the schema, given as the JSON response of a schema
+// query.
+func (c *completer) setSchema(data []byte) error {
+	var s struct {
+		Schema []struct {
+			Predicate string `json:"predicate"`
+		} `json:"schema"`
+		Types []struct {
+			Name string `json:"name"`
+		} `json:"types"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	words := make([]string, 0, len(s.Schema)+len(s.Types))
+	for _, p := range s.Schema {
+		words = append(words, p.Predicate)
+	}
+	for _, t := range s.Types {
+		words = append(words, t.Name)
+	}
+	c.setWords(words)
+	return nil
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.~@", r)
+}
+
+// complete completes the word before the cursor of the line. It returns the new line and cursor,
+// along with the words matching the word if there are more than one of them.
+func (c *completer) complete(line string, pos int) (string, int, []string) {
+	start := pos
+	for start > 0 {
+		r := rune(line[start-1])
+		if r >= 0x80 || !isWordChar(r) {
+			break
+		}
+		start--
+	}
+	word := line[start:pos]
+	if word == "" {
+		return line, pos, nil
+	}
+	// Reverse edges are completed with the predicate.
+	reverse := strings.HasPrefix(word, "~")
+	prefix := strings.TrimPrefix(word, "~")
+
+	var matches []string
+	for _, w := range c.words {
+		if strings.HasPrefix(w, prefix) && !(reverse && strings.HasPrefix(w, "@")) {
+			matches = append(matches, w)
+		}
+	}
+	if len(matches) == 0 {
+		return line, pos, nil
+	}
+
+	completed := matches[0]
+	for _, m := range matches[1:] {
+		completed = commonPrefix(completed, m)
+	}
+	if reverse {
+		completed = "~" + completed
+	}
+	newLine := line[:start] + completed + line[pos:]
+	if len(matches) == 1 {
+		return newLine, start + len(completed), nil
+	}
+	return newLine, start + len(completed), matches
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
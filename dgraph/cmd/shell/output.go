@@ -0,0 +1,168 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+// maxCellLen is the length after which the values are truncated in the tables.
+const maxCellLen = 60
+
+// writeTables writes the blocks of the JSON response as tables, with a row for every node of the
+// block and a column for every predicate. The nested nodes are written as JSON. The blocks that
+// aren't lists of nodes, like the schema, are written as JSON.
+func writeTables(w io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return errors.Errorf("invalid response: %s", data)
+	}
+	// The blocks are decoded one by one to keep them in the order of the query.
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		block, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+
+		var nodes []map[string]interface{}
+		nodeDec := json.NewDecoder(bytes.NewReader(value))
+		nodeDec.UseNumber()
+		if err := nodeDec.Decode(&nodes); err != nil {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, value, "", "  "); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s: %s\n", block, indented.String()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeTable(w, block, value, nodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTable(w io.Writer, block string, value json.RawMessage,
+	nodes []map[string]interface{}) error {
+
+	columns, err := tableColumns(value)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s (%d rows)\n", block, len(nodes)); err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, strings.Join(columns, "\t")); err != nil {
+		return err
+	}
+	cells := make([]string, len(columns))
+	for _, node := range nodes {
+		for i, col := range columns {
+			cells[i] = tableCell(node[col])
+		}
+		if _, err := fmt.Fprintln(tw, strings.Join(cells, "\t")); err != nil {
+			return err
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// The empty cells at the end of the rows are padded too.
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " \n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableColumns returns the predicates of the nodes in the order they first appear, with the
+// uid first.
+func tableColumns(value json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	seen := make(map[string]bool)
+	var columns []string
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			col := tok.(string)
+			if !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+	for i, col := range columns {
+		if col == "uid" {
+			copy(columns[1:i+1], columns[:i])
+			columns[0] = "uid"
+			break
+		}
+	}
+	return columns, nil
+}
+
+func tableCell(v interface{}) string {
+	var cell string
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		cell = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		cell = string(b)
+	}
+	// The cells must stay on their line.
+	cell = strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(cell)
+	if runes := []rune(cell); len(runes) > maxCellLen {
+		cell = string(runes[:maxCellLen-3]) + "..."
+	}
+	return cell
+}
@@ -0,0 +1,354 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package shell builds an interactive shell that runs DQL queries, mutations and upserts
+// against a Dgraph Alpha, with the predicates and types of its schema autocompleted.
+package shell
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// Shell is the sub-command invoked when calling "dgraph shell".
+var Shell x.SubCommand
+
+const (
+	prompt             = "dgraph> "
+	continuationPrompt = "     -> "
+
+	formatJson  = "json"
+	formatTable = "table"
+)
+
+const helpText = `Enter DQL queries, mutations or upserts, over as many lines as needed.
+They are run once all their braces are closed. Press Tab to complete predicates, types
+and keywords.
+
+Commands:
+  .json      Print the results as JSON.
+  .table     Print the results as tables.
+  .schema    Print the schema, and reload it for autocompletion.
+  .help      Print this help.
+  .exit      Exit the shell. Ctrl-D also exits it.
+`
+
+var mutationRegex = regexp.MustCompile(`^(upsert\s*\{|\{\s*(set|delete)\s*\{)`)
+
+func init() {
+	Shell.Cmd = &cobra.Command{
+		Use:   "shell",
+		Short: "Run an interactive DQL shell against Dgraph Alpha",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := run(Shell.Conf); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+		Annotations: map[string]string{"group": "tool"},
+	}
+	Shell.EnvPrefix = "DGRAPH_SHELL"
+	Shell.Cmd.SetHelpTemplate(x.NonRootTemplate)
+
+	flag := Shell.Cmd.Flags()
+	// --tls SuperFlag
+	x.RegisterClientTLSFlags(flag)
+
+	flag.String("alpha", "localhost:9080", "Address of Dgraph Alpha.")
+	flag.Int("retries", 10, "How many times to retry setting up the connection.")
+	flag.String("creds", "",
+		`Various login credentials if login is required.
+	user defines the username to login.
+	password defines the password of the user.
+	namespace defines the namespace to log into.
+	Sample flag could look like --creds user=username;password=mypass;namespace=2`)
+	flag.String("format", formatJson, "Output format of the results, either json or table.")
+	flag.Duration("timeout", time.Minute, "Timeout of each query or mutation.")
+}
+
+// shell holds the state of an interactive session.
+type shell struct {
+	dg      *dgo.Dgraph
+	out     io.Writer
+	format  string
+	timeout time.Duration
+	comp    *completer
+}
+
+func run(conf *viper.Viper) error {
+	// Do a sanity check on the passed credentials.
+	_ = z.NewSuperFlag(conf.GetString("creds")).MergeAndCheckDefault(x.DefaultCreds)
+
+	format := conf.GetString("format")
+	if format != formatJson && format != formatTable {
+		return errors.Errorf("invalid --format %q, it must be json or table", format)
+	}
+	dg, closeFunc := x.GetDgraphClient(conf, true)
+	defer closeFunc()
+
+	s := &shell{
+		dg:      dg,
+		out:     os.Stdout,
+		format:  format,
+		timeout: conf.GetDuration("timeout"),
+		comp:    newCompleter(),
+	}
+	if err := s.loadSchema(false); err != nil {
+		fmt.Fprintf(os.Stderr, "While loading the schema for autocompletion: %v\n", err)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		// Run the statements piped to the shell.
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+		return s.loop(func() (string, error) {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			}
+			return scanner.Text(), nil
+		}, func(string) {})
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return errors.Wrapf(err, "while setting up the terminal")
+	}
+	defer func() {
+		if err := term.Restore(fd, oldState); err != nil {
+			fmt.Fprintf(os.Stderr, "While restoring the terminal: %v\n", err)
+		}
+	}()
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, prompt)
+	if width, height, err := term.GetSize(fd); err == nil {
+		_ = t.SetSize(width, height)
+	}
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		newLine, newPos, options := s.comp.complete(line, pos)
+		if len(options) > 1 {
+			_, _ = fmt.Fprintln(t, strings.Join(options, "  "))
+		}
+		return newLine, newPos, true
+	}
+	// The terminal translates the new lines to what the raw terminal expects.
+	s.out = t
+	fmt.Fprintln(s.out, "Type .help for help.")
+	return s.loop(t.ReadLine, t.SetPrompt)
+}
+
+// loop reads the lines until the end of the input, running the statements once they're complete.
+func (s *shell) loop(readLine func() (string, error), setPrompt func(string)) error {
+	var stmt strings.Builder
+	for {
+		if stmt.Len() == 0 {
+			setPrompt(prompt)
+		} else {
+			setPrompt(continuationPrompt)
+		}
+		line, err := readLine()
+		switch {
+		case err == io.EOF && stmt.Len() > 0:
+			// Drop the statement being typed.
+			stmt.Reset()
+			fmt.Fprintln(s.out)
+			continue
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if stmt.Len() == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			if strings.HasPrefix(trimmed, ".") {
+				if exit := s.command(trimmed); exit {
+					return nil
+				}
+				continue
+			}
+		}
+		stmt.WriteString(line)
+		stmt.WriteByte('\n')
+		if !isComplete(stmt.String()) {
+			continue
+		}
+		if err := s.execute(strings.TrimSpace(stmt.String())); err != nil {
+			fmt.Fprintf(s.out, "Error: %v\n", err)
+		}
+		stmt.Reset()
+	}
+}
+
+// command runs the shell command, and returns true if the shell must exit.
+func (s *shell) command(cmd string) bool {
+	switch cmd {
+	case ".exit", ".quit":
+		return true
+	case ".help":
+		fmt.Fprint(s.out, helpText)
+	case ".json":
+		s.format = formatJson
+	case ".table":
+		s.format = formatTable
+	case ".schema":
+		if err := s.loadSchema(true); err != nil {
+			fmt.Fprintf(s.out, "Error: %v\n", err)
+		}
+	default:
+		fmt.Fprintf(s.out, "Unknown command %s, type .help for help.\n", cmd)
+	}
+	return false
+}
+
+// execute runs the statement, as a mutation if it's a mutation or an upsert block, or as a
+// read-only query otherwise.
+func (s *shell) execute(stmt string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	if !mutationRegex.MatchString(stmt) {
+		txn := s.dg.NewReadOnlyTxn()
+		defer func() { _ = txn.Discard(ctx) }()
+		resp, err := txn.Query(ctx, stmt)
+		if err != nil {
+			return err
+		}
+		if err := s.print(resp.Json); err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "Took %s.\n", time.Since(start).Round(time.Millisecond))
+		return nil
+	}
+
+	req, err := dql.ParseMutation(stmt)
+	if err != nil {
+		return err
+	}
+	req.CommitNow = true
+	txn := s.dg.NewTxn()
+	defer func() { _ = txn.Discard(ctx) }()
+	resp, err := txn.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if len(resp.Json) > 0 && string(resp.Json) != "{}" {
+		if err := s.print(resp.Json); err != nil {
+			return err
+		}
+	}
+	if len(resp.Uids) > 0 {
+		uids, err := json.MarshalIndent(resp.Uids, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "Assigned uids: %s\n", uids)
+	}
+	fmt.Fprintf(s.out, "Committed in %s.\n", time.Since(start).Round(time.Millisecond))
+	// The mutation may have added new predicates.
+	if err := s.loadSchema(false); err != nil {
+		fmt.Fprintf(s.out, "While reloading the schema for autocompletion: %v\n", err)
+	}
+	return nil
+}
+
+func (s *shell) print(data []byte) error {
+	if s.format == formatTable {
+		return writeTables(s.out, data)
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(s.out, string(b))
+	return err
+}
+
+// loadSchema reads the predicates and types of the schema for autocompletion, printing the
+// schema if print is true.
+func (s *shell) loadSchema(print bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	txn := s.dg.NewReadOnlyTxn()
+	defer func() { _ = txn.Discard(ctx) }()
+	resp, err := txn.Query(ctx, "schema {}")
+	if err != nil {
+		return err
+	}
+	if err := s.comp.setSchema(resp.Json); err != nil {
+		return err
+	}
+	if print {
+		return s.print(resp.Json)
+	}
+	return nil
+}
+
+// isComplete returns true if all the braces of the statement are closed, ignoring the ones in
+// strings and comments.
+func isComplete(stmt string) bool {
+	depth := 0
+	opened := false
+	inString, escaped, inComment := false, false, false
+	for _, c := range stmt {
+		switch {
+		case inComment:
+			inComment = c != '\n'
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '#':
+			inComment = true
+		case c == '{':
+			depth++
+			opened = true
+		case c == '}':
+			depth--
+		}
+	}
+	return opened && depth <= 0 && !inString
+}
@@ -0,0 +1,124 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package shell
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsComplete(t *testing.T) {
+	require.False(t, isComplete(""))
+	require.False(t, isComplete("{\n  q(func: has(name)) {\n"))
+	require.True(t, isComplete("{ q(func: has(name)) { name } }"))
+	require.False(t, isComplete(`{ q(func: eq(name, "}}")) {`))
+	require.False(t, isComplete("{ q(func: has(name)) { # }}\n"))
+	require.True(t, isComplete(`{ set { _:a <name> "a \" }" . } }`))
+	require.False(t, isComplete(`{ set { _:a <name> "a`))
+}
+
+func TestMutationRegex(t *testing.T) {
+	require.True(t, mutationRegex.MatchString(`{ set { _:a <name> "a" . } }`))
+	require.True(t, mutationRegex.MatchString("{\n  delete {\n"))
+	require.True(t, mutationRegex.MatchString("upsert {\n"))
+	require.False(t, mutationRegex.MatchString("{ q(func: has(set)) { set } }"))
+	require.False(t, mutationRegex.MatchString("schema {}"))
+}
+
+func TestComplete(t *testing.T) {
+	c := newCompleter()
+	require.NoError(t, c.setSchema([]byte(`{
+		"schema": [{"predicate": "name"}, {"predicate": "friend"}, {"predicate": "friend_of"}],
+		"types": [{"name": "Person"}]
+	}`)))
+
+	line, pos, options := c.complete("{ q(func: has(na", 16)
+	require.Equal(t, "{ q(func: has(name", line)
+	require.Equal(t, 18, pos)
+	require.Empty(t, options)
+
+	line, pos, options = c.complete("{ q(func: type(Per)) {", 18)
+	require.Equal(t, "{ q(func: type(Person)) {", line)
+	require.Equal(t, 21, pos)
+	require.Empty(t, options)
+
+	line, pos, options = c.complete("fr", 2)
+	require.Equal(t, "friend", line)
+	require.Equal(t, 6, pos)
+	require.Equal(t, []string{"friend", "friend_of"}, options)
+
+	line, _, _ = c.complete("~na", 3)
+	require.Equal(t, "~name", line)
+	line, _, options = c.complete("@fil", 4)
+	require.Equal(t, "@filter", line)
+	require.Empty(t, options)
+
+	line, pos, options = c.complete("zz", 2)
+	require.Equal(t, "zz", line)
+	require.Equal(t, 2, pos)
+	require.Empty(t, options)
+	line, pos, _ = c.complete("a ", 2)
+	require.Equal(t, "a ", line)
+	require.Equal(t, 2, pos)
+}
+
+func TestWriteTables(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeTables(&buf, []byte(`{
+		"q": [
+			{"name": "Alice", "uid": "0x1", "age": 29},
+			{"uid": "0x2", "name": "Bob\nSmith", "friend": [{"uid": "0x1"}]}
+		],
+		"empty": [],
+		"count": {"total": 2}
+	}`)))
+	require.Equal(t, `q (2 rows)
+uid  name        age  friend
+0x1  Alice       29
+0x2  Bob\nSmith       [{"uid":"0x1"}]
+empty (0 rows)
+count: {
+  "total": 2
+}
+`, buf.String())
+
+	require.Error(t, writeTables(&buf, []byte(`[]`)))
+	require.Equal(t, strings.Repeat("a", maxCellLen-3)+"...",
+		tableCell(strings.Repeat("a", maxCellLen+1)))
+}
+
+func TestShellCommands(t *testing.T) {
+	var out bytes.Buffer
+	s := &shell{out: &out, format: formatJson, comp: newCompleter()}
+	lines := []string{".table", "", ".unknown", "{ q(func: has(name)) {", ".json", ".help"}
+	var prompts []string
+	readLine := func() (string, error) {
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, nil
+	}
+	require.NoError(t, s.loop(readLine, func(p string) { prompts = append(prompts, p) }))
+	// The commands are only run at the start of a statement, and the statement being typed is
+	// dropped at the end of the input.
+	require.Equal(t, formatTable, s.format)
+	require.Contains(t, out.String(), "Unknown command .unknown")
+	require.NotContains(t, out.String(), "Commands:")
+	require.Equal(t, []string{prompt, prompt, prompt, prompt, continuationPrompt,
+		continuationPrompt, continuationPrompt, prompt}, prompts)
+
+	lines = []string{".help", ".exit", ".table"}
+	s.format = formatJson
+	require.NoError(t, s.loop(readLine, func(string) {}))
+	require.Contains(t, out.String(), "Commands:")
+	require.Equal(t, formatJson, s.format)
+}
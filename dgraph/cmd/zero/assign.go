@@ -17,6 +17,7 @@ import (
 	attribute "go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/x"
 )
@@ -277,3 +278,46 @@ func (s *Server) AssignIds(ctx context.Context, num *pb.Num) (*pb.AssignedIds, e
 		return reply, err
 	}
 }
+
+// leaseState returns the state of the leases, which is only up to date on the leader.
+func (s *Server) leaseState() *pb.LeaseState {
+	state := &pb.LeaseState{
+		MaxUid:   s.maxLease(pb.Num_UID),
+		MaxTxnTs: s.maxLease(pb.Num_TXN_TS),
+		MaxNsId:  s.maxLease(pb.Num_NS_ID),
+	}
+	s.leaseLock.Lock()
+	state.NextUid = s.nextUint[pb.Num_UID]
+	state.NextTxnTs = s.nextUint[pb.Num_TXN_TS]
+	state.NextNsId = s.nextUint[pb.Num_NS_ID]
+	s.leaseLock.Unlock()
+
+	s.orc.RLock()
+	state.MaxAssigned = s.orc.maxAssigned
+	state.PurgedBelow = s.orc.startTxnTs
+	s.orc.RUnlock()
+	state.DoneUntil = s.orc.doneUntil.DoneUntil()
+	state.LastTs = s.orc.doneUntil.LastIndex()
+	return state
+}
+
+// Leases returns the state of the leases of the uids, timestamps and namespace ids, and the
+// watermark of the timestamps. The requests received by a follower are forwarded to the leader,
+// which is the only one handing them out.
+func (s *Server) Leases(ctx context.Context, _ *api.Payload) (*pb.LeaseState, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if s.Node.AmLeader() {
+		return s.leaseState(), nil
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("forwarded")) > 0 {
+		return nil, errors.Errorf("Invalid Zero received Leases request forward. Please retry")
+	}
+	pl := s.Leader(0)
+	if pl == nil {
+		return nil, errors.Errorf("No healthy connection found to Leader of group zero")
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "forwarded", "true")
+	return pb.NewZeroClient(pl.Get()).Leases(ctx, &api.Payload{})
+}
@@ -0,0 +1,70 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package zero
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+var errConfigChanged = errors.New("The cluster config was changed concurrently, retry")
+
+// applyClusterConfig replaces the cluster config of the state with the new one, if it's the next
+// version of the current config. Otherwise, the config was changed concurrently since the new one
+// was built, and the proposal is rejected.
+func applyClusterConfig(state *pb.MembershipState, b []byte) error {
+	cur, err := x.ParseClusterConfig(state.GetConfig())
+	if err != nil {
+		return err
+	}
+	next, err := x.ParseClusterConfig(b)
+	if err != nil {
+		return err
+	}
+	if next.Current().Version != cur.Current().Version+1 {
+		return errConfigChanged
+	}
+	state.Config = b
+	return nil
+}
+
+// clusterConfig returns the cluster config, applied by all the Alphas.
+func (s *Server) clusterConfig() (*x.ClusterConfig, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return x.ParseClusterConfig(s.state.GetConfig())
+}
+
+// updateClusterConfig proposes the next version of the cluster config, built by update from the
+// current config. The Alphas get it with the membership state.
+func (s *Server) updateClusterConfig(ctx context.Context,
+	update func(*x.ClusterConfig) (*x.ClusterConfig, error)) (*x.ClusterConfig, error) {
+
+	cur, err := s.clusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	next, err := update(cur)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(next)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Config: b}); err != nil {
+		return nil, err
+	}
+	v := next.Current()
+	glog.Infof("Updated the cluster config to version %d: %v", v.Version, v.Settings)
+	return next, nil
+}
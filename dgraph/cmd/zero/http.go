@@ -42,6 +42,9 @@ func intFromQueryParam(w http.ResponseWriter, r *http.Request, name string) (uin
 	return val, true
 }
 
+// assign leases num uids, timestamps or namespace ids. With bump=true, the lease of the uids or
+// namespace ids is bumped to num instead, so that the ids up to num are never handed out, e.g. for
+// the loaders which assign their own uids.
 func (st *state) assign(w http.ResponseWriter, r *http.Request) {
 	x.AddCorsHeaders(w)
 	w.Header().Set("Content-Type", "application/json")
@@ -59,6 +62,14 @@ func (st *state) assign(w http.ResponseWriter, r *http.Request) {
 	}
 
 	num := &pb.Num{Val: val}
+	if bump := r.URL.Query().Get("bump"); bump != "" {
+		var err error
+		if num.Bump, err = strconv.ParseBool(bump); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			x.SetStatus(w, x.ErrorInvalidRequest, "Error while parsing bump")
+			return
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -70,6 +81,11 @@ func (st *state) assign(w http.ResponseWriter, r *http.Request) {
 		num.Type = pb.Num_UID
 		ids, err = st.zero.AssignIds(ctx, num)
 	case "timestamps":
+		if num.Bump {
+			w.WriteHeader(http.StatusBadRequest)
+			x.SetStatus(w, x.ErrorInvalidRequest, "The timestamps can't be bumped")
+			return
+		}
 		num.Type = pb.Num_TXN_TS
 		if num.Val == 0 {
 			num.ReadOnly = true
@@ -101,6 +117,108 @@ func (st *state) assign(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// leases returns the state of the leases and of the watermark of the timestamps, as pb.LeaseState.
+func (st *state) leases(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	leases, err := st.zero.Leases(ctx, &api.Payload{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+
+	m := protojson.MarshalOptions{EmitUnpopulated: true}
+	buf, err := m.Marshal(leases)
+	if err != nil {
+		x.SetStatus(w, x.ErrorNoData, err.Error())
+		return
+	}
+	if _, err := w.Write(buf); err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+}
+
+// config returns the cluster config with its versions on GET, along with the settings it can
+// change. On POST, it changes the settings given as a JSON object of their values, an empty value
+// resetting the setting to the value of the flags of the Alphas, or with rollback=<version>, it
+// restores the settings of the version. Both make a new version, applied by all the Alphas.
+func (st *state) config(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var c *x.ClusterConfig
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		if err := st.node.WaitLinearizableRead(ctx); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+		c, err = st.zero.clusterConfig()
+	case http.MethodPost:
+		if !st.node.AmLeader() {
+			w.WriteHeader(http.StatusBadRequest)
+			x.SetStatus(w, x.ErrorInvalidRequest,
+				"This Zero server is not the leader. Re-run command on leader.")
+			return
+		}
+		update := func(c *x.ClusterConfig) (*x.ClusterConfig, error) {
+			var settings map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+				return nil, errors.Wrap(err, "while reading the settings")
+			}
+			return c.Update(settings)
+		}
+		if r.URL.Query().Has("rollback") {
+			version, ok := intFromQueryParam(w, r, "rollback")
+			if !ok {
+				return
+			}
+			update = func(c *x.ClusterConfig) (*x.ClusterConfig, error) {
+				return c.Rollback(version)
+			}
+		}
+		c, err = st.zero.updateClusterConfig(ctx, update)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	resp := struct {
+		*x.ClusterConfig
+		Current  uint64              `json:"current"`
+		Settings []*x.ClusterSetting `json:"settings"`
+	}{c, c.Current().Version, x.ClusterSettings()}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Warningf("Error while writing response: %+v", err)
+	}
+}
+
 // removeNode can be used to remove a node from the cluster. It takes in the RAFT id of the node
 // and the group it belongs to. It can be used to remove Dgraph alpha and Zero nodes(group=0).
 func (st *state) removeNode(w http.ResponseWriter, r *http.Request) {
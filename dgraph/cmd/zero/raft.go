@@ -465,6 +465,11 @@ func (n *node) applyProposal(e raftpb.Entry) (uint64, error) {
 			return key, err
 		}
 	}
+	if len(p.Config) > 0 {
+		if err := applyClusterConfig(state, p.Config); err != nil {
+			return key, err
+		}
+	}
 
 	switch {
 	case p.MaxUID > state.MaxUID:
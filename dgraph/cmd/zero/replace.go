@@ -0,0 +1,324 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package zero
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/conn"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	deadNodeCheckInterval = 10 * time.Second
+	// replacementRetention is how long the finished replacements are reported.
+	replacementRetention = 24 * time.Hour
+)
+
+// The statuses of a replacement, in the order they go through.
+const (
+	// The node is unreachable, but it can't be removed safely yet.
+	replacementBlocked = "blocked"
+	// The removal of the node from its group is proposed.
+	replacementRemoving = "removing"
+	// The node is removed, and the group waits for a new Alpha to join it.
+	replacementAwaiting = "awaitingReplacement"
+	// A new Alpha has joined the group in place of the node.
+	replacementDone = "replaced"
+	// The node became reachable again before it was removed.
+	replacementRecovered = "recovered"
+)
+
+// replacement reports the progress of replacing a dead Alpha.
+type replacement struct {
+	NodeId      uint64    `json:"nodeId"`
+	Addr        string    `json:"addr"`
+	GroupId     uint32    `json:"groupId"`
+	Learner     bool      `json:"learner,omitempty"`
+	Witness     bool      `json:"witness,omitempty"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Execute     string    `json:"execute,omitempty"`
+	Replacement uint64    `json:"replacementId,omitempty"`
+	LastSeen    time.Time `json:"lastSeen"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	// members are the members of the group when the node was removed.
+	members map[uint64]bool
+}
+
+func (r *replacement) finished() bool {
+	return r.Status == replacementDone || r.Status == replacementRecovered
+}
+
+func (r *replacement) update(status string, err error, now time.Time) {
+	r.Status, r.Error, r.UpdatedAt = status, "", now
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// deadNodeTracker detects the Alphas which stay unreachable for longer than the grace period,
+// and tracks their replacements. It only runs on the Zero leader, and starts over when this
+// Zero becomes the leader, so a new leader gives every Alpha the full grace period again.
+type deadNodeTracker struct {
+	sync.Mutex
+	grace        time.Duration
+	lastSeen     map[uint64]time.Time
+	replacements map[uint64]*replacement
+}
+
+func newDeadNodeTracker(grace time.Duration) *deadNodeTracker {
+	t := &deadNodeTracker{grace: grace}
+	t.reset()
+	return t
+}
+
+func (t *deadNodeTracker) reset() {
+	t.Lock()
+	defer t.Unlock()
+	t.lastSeen = make(map[uint64]time.Time)
+	t.replacements = make(map[uint64]*replacement)
+}
+
+// observe updates the replacements from the membership state, given which addresses are
+// healthy. It returns the replacements whose nodes should be removed from their groups now.
+func (t *deadNodeTracker) observe(state *pb.MembershipState, healthy func(addr string) bool,
+	now time.Time) []*replacement {
+	t.Lock()
+	defer t.Unlock()
+
+	for id, r := range t.replacements {
+		if r.finished() && now.Sub(r.UpdatedAt) > replacementRetention {
+			delete(t.replacements, id)
+		}
+	}
+
+	members := make(map[uint64]bool)
+	var remove []*replacement
+	for _, gid := range sortedGroupIds(state) {
+		group := state.Groups[gid]
+		removing := false
+		for _, r := range t.replacements {
+			if r.GroupId != gid {
+				continue
+			}
+			if r.Status == replacementAwaiting {
+				for id, m := range group.Members {
+					if !r.members[id] && m.Learner == r.Learner && m.Witness == r.Witness {
+						r.Replacement = id
+						r.Execute = ""
+						r.update(replacementDone, nil, now)
+						break
+					}
+				}
+			}
+			removing = removing || r.Status == replacementRemoving
+		}
+
+		ids := make([]uint64, 0, len(group.Members))
+		for id := range group.Members {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		for _, id := range ids {
+			m := group.Members[id]
+			members[id] = true
+			seen, ok := t.lastSeen[id]
+			if !ok || healthy(m.Addr) {
+				seen = now
+				t.lastSeen[id] = seen
+			}
+			r := t.replacements[id]
+			if now.Sub(seen) < t.grace {
+				if r != nil && r.Status == replacementBlocked {
+					r.update(replacementRecovered, nil, now)
+				}
+				continue
+			}
+			if r == nil || r.finished() {
+				r = &replacement{NodeId: id, Addr: m.Addr, GroupId: gid, Learner: m.Learner,
+					Witness: m.Witness}
+				t.replacements[id] = r
+			}
+			if r.Status == replacementRemoving {
+				continue
+			}
+			r.LastSeen = seen
+			switch err := checkRemovable(group, id, healthy); {
+			case err != nil:
+				r.update(replacementBlocked, err, now)
+			case removing:
+				r.update(replacementBlocked,
+					errors.New("Waiting for the removal of another node of the group"), now)
+			default:
+				removing = true
+				r.update(replacementRemoving, nil, now)
+				remove = append(remove, r)
+			}
+		}
+	}
+	for id := range t.lastSeen {
+		if !members[id] {
+			delete(t.lastSeen, id)
+		}
+	}
+	return remove
+}
+
+// removed records the outcome of removing the node of r from its group.
+func (t *deadNodeTracker) removed(r *replacement, state *pb.MembershipState, err error,
+	now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+
+	if err != nil {
+		r.update(replacementBlocked, errors.Wrapf(err, "while removing the node"), now)
+		return
+	}
+	r.members = make(map[uint64]bool)
+	if group, ok := state.Groups[r.GroupId]; ok {
+		for id := range group.Members {
+			r.members[id] = true
+		}
+	}
+	r.members[r.NodeId] = true
+	raft := fmt.Sprintf("group=%d", r.GroupId)
+	switch {
+	case r.Witness:
+		raft = "witness=true; " + raft
+	case r.Learner:
+		raft = "learner=true; " + raft
+	}
+	r.Execute = fmt.Sprintf("start an Alpha with --raft %q", raft)
+	r.update(replacementAwaiting, nil, now)
+}
+
+// report returns a copy of the replacements, sorted by group and node.
+func (t *deadNodeTracker) report() []replacement {
+	t.Lock()
+	defer t.Unlock()
+
+	res := make([]replacement, 0, len(t.replacements))
+	for _, r := range t.replacements {
+		res = append(res, *r)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].GroupId != res[j].GroupId {
+			return res[i].GroupId < res[j].GroupId
+		}
+		return res[i].NodeId < res[j].NodeId
+	})
+	return res
+}
+
+// checkRemovable checks that the dead node id can be removed from the group without losing
+// data, and that the group can still agree on its removal.
+func checkRemovable(group *pb.Group, id uint64, healthy func(addr string) bool) error {
+	if len(group.Members) == 1 {
+		return errors.New("It's the only member of the group. Restore the node, or the group" +
+			" from a backup")
+	}
+	if isLastDataReplica(group, id) {
+		return errors.New("It's the last data replica of the group")
+	}
+	var voters, live int
+	for _, m := range group.Members {
+		if m.Learner {
+			continue
+		}
+		voters++
+		if m.Id != id && healthy(m.Addr) {
+			live++
+		}
+	}
+	if 2*live <= voters {
+		return errors.Errorf("The group has lost its quorum, with %d of %d replicas reachable",
+			live, voters)
+	}
+	return nil
+}
+
+// replaceDeadNodes periodically removes the Alphas which stay unreachable for longer than the
+// grace period from their groups, so that new Alphas can join the groups in their place.
+func (s *Server) replaceDeadNodes() {
+	interval := deadNodeCheckInterval
+	if s.deadNodes.grace < interval {
+		interval = s.deadNodes.grace
+	}
+	healthy := func(addr string) bool {
+		_, err := conn.GetPools().Get(addr)
+		return err == nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	wasLeader := false
+	for range ticker.C {
+		if !s.Node.AmLeader() {
+			if wasLeader {
+				s.deadNodes.reset()
+			}
+			wasLeader = false
+			continue
+		}
+		wasLeader = true
+
+		for _, r := range s.deadNodes.observe(s.membershipState(), healthy, time.Now()) {
+			glog.Warningf("Removing node %d at %s from group %d, unreachable since %s",
+				r.NodeId, r.Addr, r.GroupId, r.LastSeen.Format(time.RFC3339))
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			_, err := s.RemoveNode(ctx, &pb.RemoveNodeRequest{NodeId: r.NodeId,
+				GroupId: r.GroupId})
+			cancel()
+			if err != nil {
+				glog.Errorf("While removing the dead node %d from group %d: %v", r.NodeId,
+					r.GroupId, err)
+			}
+			s.deadNodes.removed(r, s.membershipState(), err, time.Now())
+		}
+	}
+}
+
+// replacements reports the progress of replacing the dead Alphas. Only the Zero leader detects
+// the dead Alphas.
+func (st *state) replacements(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if !st.node.AmLeader() {
+		x.SetStatus(w, x.Error, "This Zero isn't the leader. Ask the leader, see /state.")
+		return
+	}
+	tracker := st.zero.deadNodes
+	writeJSON(w, struct {
+		Enabled      bool          `json:"enabled"`
+		GracePeriod  string        `json:"gracePeriod"`
+		Replacements []replacement `json:"replacements"`
+	}{
+		Enabled:      tracker.grace > 0,
+		GracePeriod:  tracker.grace.String(),
+		Replacements: tracker.report(),
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -44,6 +45,7 @@ type options struct {
 	peer              string
 	w                 string
 	rebalanceInterval time.Duration
+	replaceDeadAfter  time.Duration
 	tlsClientConfig   *tls.Config
 	audit             *x.LoggerConf
 	limiterConfig     *x.LimiterConf
@@ -84,6 +86,9 @@ instances to achieve high-availability.
 	flag.String("peer", "", "Address of another dgraphzero server.")
 	flag.StringP("wal", "w", "zw", "Directory storing WAL.")
 	flag.Duration("rebalance_interval", 8*time.Minute, "Interval for trying a predicate move.")
+	flag.Duration("replace_dead_after", 0, "Grace period after which an unreachable Alpha is"+
+		" removed from its group, so that a new Alpha can join the group in its place. The"+
+		" progress is reported at /replacements. 0 disables it.")
 	flag.String("enterprise_license", "", "(deprecated) Path to the enterprise license file.")
 	flag.String("cid", "", "Cluster ID")
 
@@ -143,7 +148,7 @@ func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
 		grpc.MaxSendMsgSize(x.GrpcMaxSize),
 		grpc.MaxConcurrentStreams(1000),
 		grpc.StatsHandler(otelgrpc.NewClientHandler()),
-		grpc.UnaryInterceptor(audit.AuditRequestGRPC),
+		grpc.ChainUnaryInterceptor(x.RequestIdUnaryServerInterceptor, audit.AuditRequestGRPC),
 	}
 
 	tlsConf, err := x.LoadServerTLSConfigForInternalPort(Zero.Conf)
@@ -199,6 +204,9 @@ func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
 }
 
 func run() {
+	defer x.DumpFlightRecorderOnPanic()
+	x.Check(x.InitFlightRecorder(Zero.Conf.GetString("flight-recorder")))
+
 	telemetry := z.NewSuperFlag(Zero.Conf.GetString("telemetry")).
 		MergeAndCheckDefault(x.TelemetryDefaults)
 
@@ -225,6 +233,7 @@ func run() {
 		peer:              Zero.Conf.GetString("peer"),
 		w:                 Zero.Conf.GetString("wal"),
 		rebalanceInterval: Zero.Conf.GetDuration("rebalance_interval"),
+		replaceDeadAfter:  Zero.Conf.GetDuration("replace_dead_after"),
 		tlsClientConfig:   tlsConf,
 		audit:             auditConf,
 		limiterConfig:     limitConf,
@@ -251,6 +260,11 @@ func run() {
 			opts.rebalanceInterval)
 	}
 
+	if opts.replaceDeadAfter < 0 {
+		log.Fatalf("ERROR: Grace period to replace dead Alphas can't be negative. Found: %s",
+			opts.replaceDeadAfter)
+	}
+
 	addr := "localhost"
 	if opts.bindall {
 		addr = "0.0.0.0"
@@ -270,6 +284,9 @@ func run() {
 		}
 	}
 
+	profiling, err := x.ParseProfilingOptions(Zero.Conf.GetString("profiling"), "dgraph.zero")
+	x.Check(err)
+
 	grpcListener, err := setupListener(addr, x.PortZeroGrpc+opts.portOffset, "grpc")
 	x.Check(err)
 	httpListener, err := setupListener(addr, x.PortZeroHTTP+opts.portOffset, "http")
@@ -285,9 +302,9 @@ func run() {
 	var st state
 	st.serveGRPC(grpcListener, store)
 
-	tlsCfg, err := x.LoadServerTLSConfig(Zero.Conf)
+	tlsCfg, httpsWrap, err := x.LoadHttpServerTLSConfig(Zero.Conf)
 	x.Check(err)
-	go x.StartListenHttpAndHttps(httpListener, tlsCfg, st.zero.closer)
+	go x.StartListenHttpAndHttps(httpListener, tlsCfg, httpsWrap, st.zero.closer)
 
 	baseMux := http.NewServeMux()
 	http.Handle("/", audit.AuditRequestHttp(baseMux))
@@ -296,9 +313,14 @@ func run() {
 	// the following endpoints are disabled only if the flag is explicitly set to true
 	if !limit.GetBool("disable-admin-http") {
 		baseMux.HandleFunc("/state", st.getState)
+		baseMux.HandleFunc("/exportState", st.exportState)
+		baseMux.HandleFunc("/simulateTopology", st.simulateTopology)
 		baseMux.HandleFunc("/removeNode", st.removeNode)
 		baseMux.HandleFunc("/moveTablet", st.moveTablet)
+		baseMux.HandleFunc("/replacements", st.replacements)
 		baseMux.HandleFunc("/assign", st.assign)
+		baseMux.HandleFunc("/leases", st.leases)
+		baseMux.HandleFunc("/config", st.config)
 	}
 	baseMux.HandleFunc("/debug/jemalloc", x.JemallocHandler)
 	http.DefaultServeMux.Handle("/debug/z", zpages.NewTracezHandler(zpages.NewSpanProcessor()))
@@ -348,9 +370,18 @@ func run() {
 		_ = grpcListener.Close()
 	}()
 
-	st.zero.closer.AddRunning(2)
+	st.zero.closer.AddRunning(3)
 	go x.MonitorMemoryMetrics(st.zero.closer)
 	go x.MonitorDiskMetrics("wal_fs", opts.w, st.zero.closer)
+	go x.MonitorFlightRecorder(st.zero.closer)
+	if profiling != nil {
+		// All the Zeros are in group zero.
+		profiling.Labels = func() map[string]string {
+			return map[string]string{"node": strconv.FormatUint(nodeId, 10), "group": "0"}
+		}
+		st.zero.closer.AddRunning(1)
+		go x.PushProfiles(profiling, st.zero.closer)
+	}
 
 	glog.Infoln("Running Dgraph Zero...")
 	st.zero.closer.Wait()
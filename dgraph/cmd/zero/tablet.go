@@ -220,11 +220,19 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 func (s *Server) chooseTablet() (predicate string, srcGroup uint32, dstGroup uint32) {
 	s.RLock()
 	defer s.RUnlock()
-	if s.state == nil {
+	if s.state == nil || !s.Node.AmLeader() {
 		return
 	}
-	numGroups := len(s.state.Groups)
-	if !s.Node.AmLeader() || numGroups <= 1 {
+	return pickTabletMove(s.state, s.hasLeader)
+}
+
+// pickTabletMove chooses the tablet to move from the biggest group to the smallest one in order to
+// balance the sizes of the groups in state. It returns an empty predicate if the groups are
+// balanced enough, or if no tablet can be moved without unbalancing them the other way.
+func pickTabletMove(state *pb.MembershipState, hasLeader func(gid uint32) bool) (
+	predicate string, srcGroup uint32, dstGroup uint32) {
+	numGroups := len(state.Groups)
+	if numGroups <= 1 {
 		return
 	}
 
@@ -234,7 +242,7 @@ func (s *Server) chooseTablet() (predicate string, srcGroup uint32, dstGroup uin
 		size int64 // in bytes
 	}
 	var groups []kv
-	for k, v := range s.state.Groups {
+	for k, v := range state.Groups {
 		space := int64(0)
 		for _, tab := range v.Tablets {
 			space += tab.OnDiskBytes
@@ -253,7 +261,7 @@ func (s *Server) chooseTablet() (predicate string, srcGroup uint32, dstGroup uin
 		glog.Infof("size_diff %v\n", sizeDiff)
 		// Don't move a node unless you receive atleast one update regarding tablet size.
 		// Tablet size would have come up with leader update.
-		if !s.hasLeader(dstGroup) {
+		if !hasLeader(dstGroup) {
 			return
 		}
 		// We move the predicate only if the difference between size of both machines is
@@ -264,7 +272,7 @@ func (s *Server) chooseTablet() (predicate string, srcGroup uint32, dstGroup uin
 
 		// Try to find a predicate which we can move.
 		size := int64(0)
-		group := s.state.Groups[srcGroup]
+		group := state.Groups[srcGroup]
 		for _, tab := range group.Tablets {
 			// Reserved predicates should always be in group 1 so do not re-balance them.
 			if x.IsReservedPredicate(tab.Predicate) {
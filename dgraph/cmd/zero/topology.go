@@ -0,0 +1,519 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package zero
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// maxRebalanceMoves bounds the number of tablet moves simulated by a rebalance change.
+const maxRebalanceMoves = 1000
+
+// The changes of the topology that can be simulated.
+const (
+	changeAddGroup   = "addGroup"
+	changeRemoveNode = "removeNode"
+	changeMoveTablet = "moveTablet"
+	changeRebalance  = "rebalance"
+)
+
+type memberSnapshot struct {
+	ID         uint64 `json:"id"`
+	Addr       string `json:"addr"`
+	Leader     bool   `json:"leader"`
+	Learner    bool   `json:"learner"`
+	Witness    bool   `json:"witness"`
+	LastUpdate uint64 `json:"lastUpdate"`
+}
+
+type tabletSnapshot struct {
+	Namespace         uint64 `json:"namespace"`
+	Predicate         string `json:"predicate"`
+	OnDiskBytes       int64  `json:"onDiskBytes"`
+	UncompressedBytes int64  `json:"uncompressedBytes"`
+	ReadOnly          bool   `json:"readOnly"`
+	MoveTs            uint64 `json:"moveTs"`
+}
+
+type groupSnapshot struct {
+	ID                uint32           `json:"id"`
+	Members           []memberSnapshot `json:"members"`
+	Tablets           []tabletSnapshot `json:"tablets"`
+	OnDiskBytes       int64            `json:"onDiskBytes"`
+	UncompressedBytes int64            `json:"uncompressedBytes"`
+	SnapshotTs        uint64           `json:"snapshotTs"`
+	CheckpointTs      uint64           `json:"checkpointTs"`
+}
+
+// clusterSnapshot is the full state of the cluster, as exported by the /exportState endpoint.
+type clusterSnapshot struct {
+	ExportedAt time.Time        `json:"exportedAt"`
+	Cid        string           `json:"cid"`
+	Counter    uint64           `json:"counter"`
+	MaxUID     uint64           `json:"maxUID"`
+	MaxTxnTs   uint64           `json:"maxTxnTs"`
+	MaxNsID    uint64           `json:"maxNsID"`
+	MaxRaftId  uint64           `json:"maxRaftId"`
+	Zeros      []memberSnapshot `json:"zeros"`
+	Groups     []groupSnapshot  `json:"groups"`
+	Removed    []memberSnapshot `json:"removed"`
+	Balance    *balanceReport   `json:"balance"`
+}
+
+type groupBalance struct {
+	ID          uint32  `json:"id"`
+	Members     int     `json:"members"`
+	Tablets     int     `json:"tablets"`
+	OnDiskBytes int64   `json:"onDiskBytes"`
+	Share       float64 `json:"share"` // fraction of the total size served by the group
+}
+
+// balanceReport tells how evenly the data is spread among the groups. Imbalance is the
+// difference between the sizes of the biggest and the smallest groups, relative to the average
+// size of a group. It's zero when all the groups have the same size.
+type balanceReport struct {
+	Groups      []groupBalance `json:"groups"`
+	OnDiskBytes int64          `json:"onDiskBytes"`
+	Imbalance   float64        `json:"imbalance"`
+}
+
+// topologyChange is a proposed change of the topology of the cluster. Which fields are used
+// depends on Op:
+//   - addGroup adds Group, or the next group if Group is zero, with Replicas new Alphas.
+//   - removeNode removes the node ID from Group, where group 0 is the group of the Zeros.
+//   - moveTablet moves the predicate Tablet of Namespace to Group.
+//   - rebalance moves the tablets the way the rebalancer of Zero would.
+type topologyChange struct {
+	Op        string `json:"op"`
+	Group     uint32 `json:"group,omitempty"`
+	ID        uint64 `json:"id,omitempty"`
+	Replicas  int    `json:"replicas,omitempty"`
+	Tablet    string `json:"tablet,omitempty"`
+	Namespace uint64 `json:"namespace,omitempty"`
+}
+
+// simulationStep is the outcome of a change. Execute tells how to make the change for real.
+type simulationStep struct {
+	Change   topologyChange `json:"change"`
+	Result   string         `json:"result,omitempty"`
+	Execute  []string       `json:"execute,omitempty"`
+	Warnings []string       `json:"warnings,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// simulationReport is the response of the /simulateTopology endpoint. Valid is false if any of
+// the changes can't be made, in which case the changes after it aren't simulated.
+type simulationReport struct {
+	Valid  bool             `json:"valid"`
+	Before *balanceReport   `json:"before"`
+	After  *balanceReport   `json:"after"`
+	Steps  []simulationStep `json:"steps"`
+}
+
+func toMemberSnapshots(members map[uint64]*pb.Member) []memberSnapshot {
+	res := make([]memberSnapshot, 0, len(members))
+	for _, m := range members {
+		res = append(res, toMemberSnapshot(m))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+func toMemberSnapshot(m *pb.Member) memberSnapshot {
+	return memberSnapshot{
+		ID:         m.Id,
+		Addr:       m.Addr,
+		Leader:     m.Leader,
+		Learner:    m.Learner,
+		Witness:    m.Witness,
+		LastUpdate: m.LastUpdate,
+	}
+}
+
+func sortedGroupIds(state *pb.MembershipState) []uint32 {
+	gids := make([]uint32, 0, len(state.Groups))
+	for gid := range state.Groups {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+	return gids
+}
+
+// snapshotCluster converts the membership state to a snapshot of the cluster.
+func snapshotCluster(state *pb.MembershipState) *clusterSnapshot {
+	snap := &clusterSnapshot{
+		ExportedAt: time.Now().UTC(),
+		Cid:        state.Cid,
+		Counter:    state.Counter,
+		MaxUID:     state.MaxUID,
+		MaxTxnTs:   state.MaxTxnTs,
+		MaxNsID:    state.MaxNsID,
+		MaxRaftId:  state.MaxRaftId,
+		Zeros:      toMemberSnapshots(state.Zeros),
+		Groups:     []groupSnapshot{},
+		Removed:    []memberSnapshot{},
+		Balance:    balanceOf(state),
+	}
+	for _, m := range state.Removed {
+		snap.Removed = append(snap.Removed, toMemberSnapshot(m))
+	}
+	for _, gid := range sortedGroupIds(state) {
+		group := state.Groups[gid]
+		gs := groupSnapshot{
+			ID:           gid,
+			Members:      toMemberSnapshots(group.Members),
+			Tablets:      []tabletSnapshot{},
+			SnapshotTs:   group.SnapshotTs,
+			CheckpointTs: group.CheckpointTs,
+		}
+		for _, tab := range group.Tablets {
+			ns, attr := x.ParseNamespaceAttr(tab.Predicate)
+			gs.Tablets = append(gs.Tablets, tabletSnapshot{
+				Namespace:         ns,
+				Predicate:         attr,
+				OnDiskBytes:       tab.OnDiskBytes,
+				UncompressedBytes: tab.UncompressedBytes,
+				ReadOnly:          tab.ReadOnly,
+				MoveTs:            tab.MoveTs,
+			})
+			gs.OnDiskBytes += tab.OnDiskBytes
+			gs.UncompressedBytes += tab.UncompressedBytes
+		}
+		sort.Slice(gs.Tablets, func(i, j int) bool {
+			if gs.Tablets[i].Namespace != gs.Tablets[j].Namespace {
+				return gs.Tablets[i].Namespace < gs.Tablets[j].Namespace
+			}
+			return gs.Tablets[i].Predicate < gs.Tablets[j].Predicate
+		})
+		snap.Groups = append(snap.Groups, gs)
+	}
+	return snap
+}
+
+func balanceOf(state *pb.MembershipState) *balanceReport {
+	report := &balanceReport{Groups: []groupBalance{}}
+	if len(state.Groups) == 0 {
+		return report
+	}
+	minSize, maxSize := int64(-1), int64(0)
+	for _, gid := range sortedGroupIds(state) {
+		group := state.Groups[gid]
+		gb := groupBalance{ID: gid, Members: len(group.Members), Tablets: len(group.Tablets)}
+		for _, tab := range group.Tablets {
+			gb.OnDiskBytes += tab.OnDiskBytes
+		}
+		report.OnDiskBytes += gb.OnDiskBytes
+		if minSize < 0 || gb.OnDiskBytes < minSize {
+			minSize = gb.OnDiskBytes
+		}
+		if gb.OnDiskBytes > maxSize {
+			maxSize = gb.OnDiskBytes
+		}
+		report.Groups = append(report.Groups, gb)
+	}
+	if report.OnDiskBytes == 0 {
+		return report
+	}
+	for i := range report.Groups {
+		report.Groups[i].Share = float64(report.Groups[i].OnDiskBytes) /
+			float64(report.OnDiskBytes)
+	}
+	avg := float64(report.OnDiskBytes) / float64(len(report.Groups))
+	report.Imbalance = float64(maxSize-minSize) / avg
+	return report
+}
+
+// simulateTopology makes the changes to state one after the other, and reports the balance of
+// the groups before and after them. It modifies state, which must be a copy of the membership
+// state of Zero.
+func simulateTopology(state *pb.MembershipState, numReplicas int,
+	changes []topologyChange) *simulationReport {
+	if state.Groups == nil {
+		state.Groups = make(map[uint32]*pb.Group)
+	}
+	report := &simulationReport{Valid: true, Before: balanceOf(state), Steps: []simulationStep{}}
+	for _, change := range changes {
+		step := simulationStep{Change: change}
+		if err := applyChange(state, numReplicas, &step); err != nil {
+			step.Error = err.Error()
+			report.Valid = false
+		}
+		report.Steps = append(report.Steps, step)
+		if !report.Valid {
+			break
+		}
+	}
+	report.After = balanceOf(state)
+	return report
+}
+
+func applyChange(state *pb.MembershipState, numReplicas int, step *simulationStep) error {
+	switch c := step.Change; c.Op {
+	case changeAddGroup:
+		return simulateAddGroup(state, numReplicas, step)
+	case changeRemoveNode:
+		return simulateRemoveNode(state, numReplicas, step)
+	case changeMoveTablet:
+		msg, err := simulateMoveTablet(state, x.NamespaceAttr(c.Namespace, c.Tablet), c.Group)
+		if err != nil {
+			return err
+		}
+		step.Result = msg
+		step.Execute = []string{moveTabletCommand(c.Namespace, c.Tablet, c.Group)}
+		return nil
+	case changeRebalance:
+		return simulateRebalance(state, step)
+	default:
+		return errors.Errorf("unknown change %q, should be one of %s, %s, %s or %s", c.Op,
+			changeAddGroup, changeRemoveNode, changeMoveTablet, changeRebalance)
+	}
+}
+
+func simulateAddGroup(state *pb.MembershipState, numReplicas int, step *simulationStep) error {
+	gid, replicas := step.Change.Group, step.Change.Replicas
+	if gid == 0 {
+		for id := range state.Groups {
+			gid = max(gid, id)
+		}
+		gid++
+	}
+	if _, ok := state.Groups[gid]; ok {
+		return errors.Errorf("group %d already exists", gid)
+	}
+	if replicas <= 0 {
+		replicas = max(numReplicas, 1)
+	}
+	if replicas%2 == 0 {
+		step.Warnings = append(step.Warnings, fmt.Sprintf("group %d would have an even number of"+
+			" replicas, which tolerates as many failures as %d replicas", gid, replicas-1))
+	}
+
+	// The new Alphas get placeholder Raft ids, as Zero only gives them an id once they connect.
+	group := &pb.Group{Members: make(map[uint64]*pb.Member), Tablets: make(map[string]*pb.Tablet)}
+	for i := 0; i < replicas; i++ {
+		state.MaxRaftId++
+		group.Members[state.MaxRaftId] = &pb.Member{Id: state.MaxRaftId, GroupId: gid,
+			Leader: i == 0}
+	}
+	state.Groups[gid] = group
+	step.Result = fmt.Sprintf("group %d would be added with %d Alphas", gid, replicas)
+	step.Execute = []string{fmt.Sprintf("start %d Alphas with --raft \"group=%d\"", replicas, gid)}
+	return nil
+}
+
+func simulateRemoveNode(state *pb.MembershipState, numReplicas int, step *simulationStep) error {
+	id, gid := step.Change.ID, step.Change.Group
+	step.Execute = []string{fmt.Sprintf("/removeNode?id=%d&group=%d", id, gid)}
+	if gid == 0 {
+		m, ok := state.Zeros[id]
+		if !ok {
+			return errors.Errorf("no Zero with id %d found", id)
+		}
+		if len(state.Zeros) == 1 {
+			return errors.Errorf("Zero %d is the only Zero of the cluster", id)
+		}
+		if m.Leader {
+			step.Warnings = append(step.Warnings,
+				fmt.Sprintf("Zero %d is the leader, the Zeros would elect a new leader", id))
+		}
+		delete(state.Zeros, id)
+		state.Removed = append(state.Removed, m)
+		step.Result = fmt.Sprintf("Zero %d would be removed, %d Zeros would remain", id,
+			len(state.Zeros))
+		return nil
+	}
+
+	// These checks are the ones of RemoveNode.
+	group, ok := state.Groups[gid]
+	if !ok {
+		return errors.Errorf("No group with groupId %d found", gid)
+	}
+	m, ok := group.Members[id]
+	if !ok {
+		return errors.Errorf("No node with nodeId %d found in group %d", id, gid)
+	}
+	if len(group.Members) == 1 && len(group.Tablets) > 0 {
+		return errors.Errorf("Move all tablets from group %d before removing the last node", gid)
+	}
+	if isLastDataReplica(group, id) {
+		return errors.Errorf("Node %d is the last data replica of group %d, which only has"+
+			" witnesses left", id, gid)
+	}
+	if m.Leader {
+		step.Warnings = append(step.Warnings, fmt.Sprintf("node %d is the leader of group %d,"+
+			" the group would elect a new leader", id, gid))
+	}
+	delete(group.Members, id)
+	state.Removed = append(state.Removed, m)
+	if len(group.Members) == 0 {
+		delete(state.Groups, gid)
+		step.Result = fmt.Sprintf("node %d would be removed, along with the empty group %d", id,
+			gid)
+		return nil
+	}
+	if len(group.Members) < numReplicas {
+		step.Warnings = append(step.Warnings, fmt.Sprintf("group %d would have %d of the %d"+
+			" replicas", gid, len(group.Members), numReplicas))
+	}
+	step.Result = fmt.Sprintf("node %d would be removed, %d nodes would remain in group %d", id,
+		len(group.Members), gid)
+	return nil
+}
+
+// simulateMoveTablet moves the tablet of the predicate, in the namespace it belongs to, to the
+// group dstGroup. It makes the same checks as MoveTablet.
+func simulateMoveTablet(state *pb.MembershipState, predicate string,
+	dstGroup uint32) (string, error) {
+	ns, attr := x.ParseNamespaceAttr(predicate)
+	dst, ok := state.Groups[dstGroup]
+	if !ok {
+		return "", errors.Errorf("group: [%d] is not a known group", dstGroup)
+	}
+	var srcGroup uint32
+	var tab *pb.Tablet
+	for gid, group := range state.Groups {
+		if t, ok := group.Tablets[predicate]; ok {
+			srcGroup, tab = gid, t
+			break
+		}
+	}
+	if tab == nil {
+		return "", errors.Errorf("namespace: %d. No tablet found for: %s", ns, attr)
+	}
+	if srcGroup == dstGroup {
+		return "", errors.Errorf("namespace: %d. Tablet: [%s] is already being served by"+
+			" group: [%d]", ns, attr, srcGroup)
+	}
+	if x.IsReservedPredicate(predicate) {
+		return "", errors.Errorf("Unable to move reserved predicate %s", attr)
+	}
+
+	delete(state.Groups[srcGroup].Tablets, predicate)
+	tab.GroupId = dstGroup
+	if dst.Tablets == nil {
+		dst.Tablets = make(map[string]*pb.Tablet)
+	}
+	dst.Tablets[predicate] = tab
+	return fmt.Sprintf("namespace: %d. Predicate: [%s] would move from group [%d] to [%d]", ns,
+		attr, srcGroup, dstGroup), nil
+}
+
+// simulateRebalance moves the tablets the way the rebalancer would, until the groups are
+// balanced. Unlike the rebalancer, it doesn't wait for the groups to have a leader.
+func simulateRebalance(state *pb.MembershipState, step *simulationStep) error {
+	hasLeader := func(uint32) bool { return true }
+	var moves int
+	for ; moves < maxRebalanceMoves; moves++ {
+		predicate, _, dstGroup := pickTabletMove(state, hasLeader)
+		if len(predicate) == 0 {
+			break
+		}
+		if _, err := simulateMoveTablet(state, predicate, dstGroup); err != nil {
+			return err
+		}
+		ns, attr := x.ParseNamespaceAttr(predicate)
+		step.Execute = append(step.Execute, moveTabletCommand(ns, attr, dstGroup))
+	}
+	step.Result = fmt.Sprintf("the rebalancer would move %d tablets", moves)
+	return nil
+}
+
+func moveTabletCommand(ns uint64, tablet string, group uint32) string {
+	return fmt.Sprintf("/moveTablet?tablet=%s&namespace=%d&group=%d", tablet, ns, group)
+}
+
+// linearizableState returns a copy of the membership state, once this Zero is up to date with
+// the Zero group.
+func (st *state) linearizableState(w http.ResponseWriter) *pb.MembershipState {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := st.node.WaitLinearizableRead(ctx); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		x.SetStatus(w, x.Error, err.Error())
+		return nil
+	}
+	mstate := st.zero.membershipState()
+	if mstate == nil {
+		x.SetStatus(w, x.ErrorNoData, "No membership state found.")
+	}
+	return mstate
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		x.SetStatus(w, x.ErrorNoData, err.Error())
+		return
+	}
+	if _, err := w.Write(buf); err != nil {
+		glog.Warningf("Error while writing response: %+v", err)
+	}
+}
+
+// exportState exports the full state of the cluster: the Zeros, the groups with their members
+// and tablets, the sizes of the tablets and how balanced the groups are.
+func (st *state) exportState(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	mstate := st.linearizableState(w)
+	if mstate == nil {
+		return
+	}
+	writeJSON(w, snapshotCluster(mstate))
+}
+
+// simulateTopology simulates the changes of the topology posted as JSON, in the form
+// {"changes": [{"op": "moveTablet", "tablet": "name", "group": 2}, ...]}, without making them.
+// It reports the balance of the groups before and after the changes, and how to make them.
+func (st *state) simulateTopology(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Changes []topologyChange `json:"changes"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidRequest, "Invalid topology changes: "+err.Error())
+		return
+	}
+
+	mstate := st.linearizableState(w)
+	if mstate == nil {
+		return
+	}
+	writeJSON(w, simulateTopology(mstate, st.zero.NumReplicas, req.Changes))
+}
@@ -0,0 +1,105 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package zero
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func testMembershipState() *pb.MembershipState {
+	tablet := func(gid uint32, attr string, size int64) *pb.Tablet {
+		return &pb.Tablet{GroupId: gid, Predicate: x.AttrInRootNamespace(attr), OnDiskBytes: size}
+	}
+	group := func(gid uint32, ids []uint64, tablets ...*pb.Tablet) *pb.Group {
+		g := &pb.Group{Members: make(map[uint64]*pb.Member), Tablets: make(map[string]*pb.Tablet)}
+		for i, id := range ids {
+			g.Members[id] = &pb.Member{Id: id, GroupId: gid, Leader: i == 0}
+		}
+		for _, tab := range tablets {
+			g.Tablets[tab.Predicate] = tab
+		}
+		return g
+	}
+	return &pb.MembershipState{
+		MaxRaftId: 4,
+		Zeros: map[uint64]*pb.Member{
+			1: {Id: 1, Addr: "zero1:5080", Leader: true},
+		},
+		Groups: map[uint32]*pb.Group{
+			1: group(1, []uint64{1, 2}, tablet(1, "dgraph.type", 100), tablet(1, "name", 500),
+				tablet(1, "age", 300)),
+			2: group(2, []uint64{3, 4}, tablet(2, "friend", 100)),
+		},
+	}
+}
+
+func TestSnapshotCluster(t *testing.T) {
+	snap := snapshotCluster(testMembershipState())
+	require.Len(t, snap.Zeros, 1)
+	require.Len(t, snap.Groups, 2)
+	require.Equal(t, uint32(1), snap.Groups[0].ID)
+	require.Equal(t, int64(900), snap.Groups[0].OnDiskBytes)
+	require.Equal(t, "age", snap.Groups[0].Tablets[0].Predicate)
+	require.Len(t, snap.Groups[0].Members, 2)
+
+	require.Equal(t, int64(1000), snap.Balance.OnDiskBytes)
+	require.InDelta(t, 0.9, snap.Balance.Groups[0].Share, 1e-9)
+	require.InDelta(t, 1.6, snap.Balance.Imbalance, 1e-9)
+}
+
+func TestSimulateTopology(t *testing.T) {
+	report := simulateTopology(testMembershipState(), 2, []topologyChange{
+		{Op: changeAddGroup},
+		{Op: changeMoveTablet, Tablet: "name", Group: 3},
+		{Op: changeRemoveNode, ID: 4, Group: 2},
+	})
+	require.True(t, report.Valid)
+	require.Len(t, report.Steps, 3)
+	require.Equal(t, []string{`start 2 Alphas with --raft "group=3"`}, report.Steps[0].Execute)
+	require.Equal(t, []string{"/moveTablet?tablet=name&namespace=0&group=3"},
+		report.Steps[1].Execute)
+	require.Len(t, report.Steps[2].Warnings, 1)
+	require.InDelta(t, 1.6, report.Before.Imbalance, 1e-9)
+	require.Len(t, report.After.Groups, 3)
+	require.Equal(t, int64(500), report.After.Groups[2].OnDiskBytes)
+	require.Equal(t, 1, report.After.Groups[1].Members)
+
+	// The changes after an invalid change aren't simulated.
+	report = simulateTopology(testMembershipState(), 2, []topologyChange{
+		{Op: changeMoveTablet, Tablet: "dgraph.type", Group: 2},
+		{Op: changeMoveTablet, Tablet: "name", Group: 2},
+	})
+	require.False(t, report.Valid)
+	require.Len(t, report.Steps, 1)
+	require.Contains(t, report.Steps[0].Error, "reserved predicate")
+	require.Equal(t, report.Before, report.After)
+
+	for _, change := range []topologyChange{
+		{Op: changeAddGroup, Group: 2},
+		{Op: changeRemoveNode, ID: 1, Group: 0},
+		{Op: changeRemoveNode, ID: 3, Group: 1},
+		{Op: changeMoveTablet, Tablet: "friend", Group: 2},
+		{Op: changeMoveTablet, Tablet: "name", Group: 5},
+		{Op: "split"},
+	} {
+		report := simulateTopology(testMembershipState(), 2, []topologyChange{change})
+		require.False(t, report.Valid, "%+v", change)
+	}
+}
+
+func TestSimulateRebalance(t *testing.T) {
+	report := simulateTopology(testMembershipState(), 2, []topologyChange{{Op: changeRebalance}})
+	require.True(t, report.Valid)
+	require.Equal(t, []string{"/moveTablet?tablet=age&namespace=0&group=2"},
+		report.Steps[0].Execute)
+	require.Equal(t, int64(600), report.After.Groups[0].OnDiskBytes)
+	require.Equal(t, int64(400), report.After.Groups[1].OnDiskBytes)
+}
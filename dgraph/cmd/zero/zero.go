@@ -19,6 +19,7 @@ import (
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/dgraph-io/dgo/v250/protos/api"
@@ -63,6 +64,7 @@ type Server struct {
 	blockCommitsOn *sync.Map
 
 	checkpointPerGroup map[uint32]uint64
+	deadNodes          *deadNodeTracker
 	// embedding the pb.UnimplementedZeroServer struct to ensure forward compatibility of the server.
 	pb.UnimplementedZeroServer
 }
@@ -89,6 +91,7 @@ func (s *Server) Init() {
 	s.blockCommitsOn = new(sync.Map)
 	s.moveOngoing = make(chan struct{}, 1)
 	s.checkpointPerGroup = make(map[uint32]uint64)
+	s.deadNodes = newDeadNodeTracker(opts.replaceDeadAfter)
 	if opts.limiterConfig.UidLeaseLimit > 0 {
 		// rate limiting is not enabled when lease limit is set to zero.
 		s.rateLimiter = x.NewRateLimiter(int64(opts.limiterConfig.UidLeaseLimit),
@@ -96,6 +99,9 @@ func (s *Server) Init() {
 	}
 
 	go s.rebalanceTablets()
+	if opts.replaceDeadAfter > 0 {
+		go s.replaceDeadNodes()
+	}
 }
 
 func (s *Server) periodicallyPostTelemetry() {
@@ -491,6 +497,10 @@ func (s *Server) RemoveNode(ctx context.Context, req *pb.RemoveNodeRequest) (*pb
 		return nil, errors.Errorf("Move all tablets from group %d before removing the last node",
 			req.GroupId)
 	}
+	if isLastDataReplica(s.state.Groups[req.GroupId], req.NodeId) {
+		return nil, errors.Errorf("Node %d is the last data replica of group %d, which only"+
+			" has witnesses left", req.NodeId, req.GroupId)
+	}
 	if err := s.Node.proposeAndWait(ctx, zp); err != nil {
 		return nil, err
 	}
@@ -498,6 +508,56 @@ func (s *Server) RemoveNode(ctx context.Context, req *pb.RemoveNodeRequest) (*pb
 	return &pb.Status{}, nil
 }
 
+// isLastDataReplica returns true if the node id is the last member of the group which isn't a
+// witness, while the group has other members.
+func isLastDataReplica(group *pb.Group, id uint64) bool {
+	if m, ok := group.Members[id]; !ok || m.Witness || len(group.Members) == 1 {
+		return false
+	}
+	for _, m := range group.Members {
+		if m.Id != id && !m.Witness {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWitness checks that the witness m can join the group it asks for. A witness must join a
+// group which already has a data replica, and the witnesses must remain a minority of the
+// group, so that the group always has a data replica to elect as the leader.
+func checkWitness(ms *pb.MembershipState, m *pb.Member, numReplicas int) error {
+	if m.Learner {
+		return errors.Errorf("INVALID_WITNESS: A witness can't be a learner: %+v", m)
+	}
+	if m.GroupId == 0 {
+		return errors.Errorf("INVALID_WITNESS: A witness must ask for a group: %+v", m)
+	}
+	group, ok := ms.Groups[m.GroupId]
+	if !ok {
+		return errors.Errorf("No group %d found for the witness %+v", m.GroupId, m)
+	}
+	if _, ok := group.Members[m.Id]; ok {
+		// The witness is already a member of the group.
+		return nil
+	}
+	var data, witnesses int
+	for _, member := range group.Members {
+		if member.Witness {
+			witnesses++
+		} else if !member.Learner {
+			data++
+		}
+	}
+	if max := (numReplicas - 1) / 2; witnesses >= max {
+		return errors.Errorf("INVALID_WITNESS: Group %d can't have more than %d witnesses with"+
+			" %d replicas: %+v", m.GroupId, max, numReplicas, m)
+	}
+	if data == 0 {
+		return errors.Errorf("Group %d has no data replica yet for the witness %+v", m.GroupId, m)
+	}
+	return nil
+}
+
 // Connect is used by Alpha nodes to connect the very first time with group zero.
 func (s *Server) Connect(ctx context.Context,
 	m *pb.Member) (resp *pb.ConnectionState, err error) {
@@ -528,6 +588,16 @@ func (s *Server) Connect(ctx context.Context,
 	if m.Addr == "" {
 		return &emptyConnectionState, errors.Errorf("NO_ADDR: No address provided: %+v", m)
 	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if err := x.CheckHandshake(md, m.Addr); err != nil {
+			return &emptyConnectionState, err
+		}
+	}
+	if m.Witness {
+		if err := checkWitness(ms, m, s.NumReplicas); err != nil {
+			return &emptyConnectionState, err
+		}
+	}
 
 	for _, member := range ms.Removed {
 		// It is not recommended to reuse RAFT ids.
@@ -0,0 +1,145 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package zero
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestCheckWitness(t *testing.T) {
+	ms := &pb.MembershipState{Groups: map[uint32]*pb.Group{
+		1: {Members: map[uint64]*pb.Member{
+			1: {Id: 1, GroupId: 1},
+			2: {Id: 2, GroupId: 1},
+		}},
+		2: {Members: map[uint64]*pb.Member{
+			3: {Id: 3, GroupId: 2, Witness: true},
+		}},
+	}}
+
+	require.NoError(t, checkWitness(ms, &pb.Member{Id: 4, GroupId: 1, Witness: true}, 3))
+	// A witness which is already a member can always connect again.
+	require.NoError(t, checkWitness(ms, &pb.Member{Id: 3, GroupId: 2, Witness: true}, 3))
+
+	err := checkWitness(ms, &pb.Member{Id: 4, Witness: true}, 3)
+	require.ErrorContains(t, err, "must ask for a group")
+	require.True(t, x.ShouldCrash(err))
+	err = checkWitness(ms, &pb.Member{Id: 4, GroupId: 1, Witness: true, Learner: true}, 3)
+	require.True(t, x.ShouldCrash(err))
+	err = checkWitness(ms, &pb.Member{Id: 4, GroupId: 1, Witness: true}, 1)
+	require.ErrorContains(t, err, "can't have more than 0 witnesses")
+	err = checkWitness(ms, &pb.Member{Id: 4, GroupId: 2, Witness: true}, 5)
+	require.ErrorContains(t, err, "no data replica")
+	require.False(t, x.ShouldCrash(err))
+	require.Error(t, checkWitness(ms, &pb.Member{Id: 4, GroupId: 3, Witness: true}, 3))
+
+	ms.Groups[1].Members[3] = &pb.Member{Id: 3, GroupId: 1, Witness: true}
+	require.False(t, isLastDataReplica(ms.Groups[1], 1))
+	delete(ms.Groups[1].Members, 2)
+	require.True(t, isLastDataReplica(ms.Groups[1], 1))
+	require.False(t, isLastDataReplica(ms.Groups[1], 3))
+}
+
+func TestDeadNodeTracker(t *testing.T) {
+	ms := &pb.MembershipState{Groups: map[uint32]*pb.Group{
+		1: {Members: map[uint64]*pb.Member{
+			1: {Id: 1, GroupId: 1, Addr: "alpha1"},
+			2: {Id: 2, GroupId: 1, Addr: "alpha2"},
+			3: {Id: 3, GroupId: 1, Addr: "alpha3"},
+		}},
+		2: {Members: map[uint64]*pb.Member{
+			4: {Id: 4, GroupId: 2, Addr: "alpha4"},
+		}},
+	}}
+	down := map[string]bool{}
+	healthy := func(addr string) bool { return !down[addr] }
+	tracker := newDeadNodeTracker(time.Minute)
+	start := time.Now()
+
+	require.Empty(t, tracker.observe(ms, healthy, start))
+	down["alpha2"], down["alpha4"] = true, true
+	require.Empty(t, tracker.observe(ms, healthy, start.Add(30*time.Second)))
+
+	// The only member of group 2 can't be removed.
+	remove := tracker.observe(ms, healthy, start.Add(2*time.Minute))
+	require.Len(t, remove, 1)
+	require.Equal(t, uint64(2), remove[0].NodeId)
+	report := tracker.report()
+	require.Len(t, report, 2)
+	require.Equal(t, replacementRemoving, report[0].Status)
+	require.Equal(t, replacementBlocked, report[1].Status)
+	require.Contains(t, report[1].Error, "only member")
+
+	delete(ms.Groups[1].Members, 2)
+	tracker.removed(remove[0], ms, nil, start.Add(2*time.Minute))
+	require.Equal(t, replacementAwaiting, tracker.report()[0].Status)
+	require.Equal(t, `start an Alpha with --raft "group=1"`, tracker.report()[0].Execute)
+
+	ms.Groups[1].Members[5] = &pb.Member{Id: 5, GroupId: 1, Addr: "alpha5"}
+	down["alpha4"] = false
+	require.Empty(t, tracker.observe(ms, healthy, start.Add(3*time.Minute)))
+	report = tracker.report()
+	require.Equal(t, replacementDone, report[0].Status)
+	require.Equal(t, uint64(5), report[0].Replacement)
+	require.Equal(t, replacementRecovered, report[1].Status)
+
+	// The removal needs a quorum of the group.
+	down["alpha1"], down["alpha3"] = true, true
+	require.Empty(t, tracker.observe(ms, healthy, start.Add(5*time.Minute)))
+	require.Contains(t, tracker.report()[0].Error, "lost its quorum")
+
+	tracker.reset()
+	require.Empty(t, tracker.report())
+}
+
+func TestLeaseState(t *testing.T) {
+	s := &Server{
+		state: &pb.MembershipState{MaxUID: 10000, MaxTxnTs: 20000, MaxNsID: 30},
+		nextUint: map[pb.NumLeaseType]uint64{
+			pb.Num_UID: 101, pb.Num_TXN_TS: 51, pb.Num_NS_ID: 3,
+		},
+		orc: &Oracle{},
+	}
+	s.orc.Init()
+	defer s.orc.close()
+	s.orc.updateStartTxnTs(20)
+	s.orc.maxAssigned = 45
+	s.orc.doneUntil.Begin(50)
+
+	state := s.leaseState()
+	require.True(t, proto.Equal(&pb.LeaseState{
+		MaxUid: 10000, MaxTxnTs: 20000, MaxNsId: 30,
+		NextUid: 101, NextTxnTs: 51, NextNsId: 3,
+		MaxAssigned: 45, DoneUntil: 0, LastTs: 50, PurgedBelow: 20,
+	}, state), "%v", state)
+}
+
+func TestApplyClusterConfig(t *testing.T) {
+	state := &pb.MembershipState{}
+	v1, err := (&x.ClusterConfig{}).Update(map[string]string{"query-timeout": "1m"})
+	require.NoError(t, err)
+	b1, err := json.Marshal(v1)
+	require.NoError(t, err)
+	require.NoError(t, applyClusterConfig(state, b1))
+	require.Equal(t, b1, state.Config)
+
+	// A config built from an older version is rejected.
+	require.ErrorIs(t, applyClusterConfig(state, b1), errConfigChanged)
+	v2, err := v1.Rollback(0)
+	require.NoError(t, err)
+	b2, err := json.Marshal(v2)
+	require.NoError(t, err)
+	require.NoError(t, applyClusterConfig(state, b2))
+	require.Equal(t, b2, state.Config)
+}
@@ -14,7 +14,16 @@ const (
 		{"predicate":"dgraph.user.group", "list":true, "reverse":true, "type":"uid"},
 		{"predicate":"dgraph.acl.rule", "type":"uid", "list":true},
 		{"predicate":"dgraph.rule.predicate", "type":"string", "index":true, "tokenizer":["exact"], "upsert":true},
-		{"predicate":"dgraph.rule.permission", "type":"int"}
+		{"predicate":"dgraph.rule.permission", "type":"int"},
+		{"predicate":"dgraph.apikey.id", "type":"string", "index":true, "tokenizer":["exact"], "upsert":true},
+		{"predicate":"dgraph.apikey.name", "type":"string"},
+		{"predicate":"dgraph.apikey.hash", "type":"string"},
+		{"predicate":"dgraph.apikey.prev_hash", "type":"string"},
+		{"predicate":"dgraph.apikey.prev_expiry", "type":"int"},
+		{"predicate":"dgraph.apikey.expiry", "type":"int"},
+		{"predicate":"dgraph.apikey.namespaces", "type":"int", "list":true},
+		{"predicate":"dgraph.apikey.permission", "type":"int"},
+		{"predicate":"dgraph.apikey.predicates", "type":"string", "list":true}
 	`
 
 	otherInternalPreds = `
@@ -23,10 +32,22 @@ const (
 		{"predicate":"dgraph.graphql.p_query", "type":"string", "index":true, "tokenizer":["sha256"]},
 		{"predicate":"dgraph.graphql.schema", "type": "string"},
 		{"predicate":"dgraph.graphql.xid", "type":"string", "index":true, "tokenizer":["exact"], "upsert":true},
+		{"predicate":"dgraph.idempotency.key", "type":"string", "index":true, "tokenizer":["exact"], "upsert":true},
+		{"predicate":"dgraph.idempotency.result", "type":"string"},
+		{"predicate":"dgraph.idempotency.expiry", "type":"datetime", "index":true, "tokenizer":["hour"]},
 		{"predicate":"dgraph.namespace.name", "type":"string", "index":true, "tokenizer":["exact"], "unique":true,
 		 "upsert":true},
 		{"predicate":"dgraph.namespace.id", "type":"int", "index":true, "tokenizer":["int"], "unique":true,
-		 "upsert":true}
+		 "upsert":true},
+		{"predicate":"dgraph.trigger.name", "type":"string", "index":true, "tokenizer":["exact"], "upsert":true},
+		{"predicate":"dgraph.trigger.namespace", "type":"int", "index":true, "tokenizer":["int"]},
+		{"predicate":"dgraph.trigger.spec", "type":"string"},
+		{"predicate":"dgraph.wasm.name", "type":"string", "index":true, "tokenizer":["exact"], "upsert":true},
+		{"predicate":"dgraph.wasm.namespace", "type":"int", "index":true, "tokenizer":["int"]},
+		{"predicate":"dgraph.wasm.code", "type":"string"},
+		{"predicate":"dgraph.function.name", "type":"string", "index":true, "tokenizer":["exact"], "upsert":true},
+		{"predicate":"dgraph.function.namespace", "type":"int", "index":true, "tokenizer":["int"]},
+		{"predicate":"dgraph.function.query", "type":"string"}
 	`
 
 	aclTypes = `
@@ -51,6 +72,20 @@ const (
 				{"name": "dgraph.rule.permission"}
 			],
 			"name": "dgraph.type.Rule"
+		},
+		{
+			"fields": [
+				{"name": "dgraph.apikey.id"},
+				{"name": "dgraph.apikey.name"},
+				{"name": "dgraph.apikey.hash"},
+				{"name": "dgraph.apikey.prev_hash"},
+				{"name": "dgraph.apikey.prev_expiry"},
+				{"name": "dgraph.apikey.expiry"},
+				{"name": "dgraph.apikey.namespaces"},
+				{"name": "dgraph.apikey.permission"},
+				{"name": "dgraph.apikey.predicates"}
+			],
+			"name": "dgraph.type.ApiKey"
 		}
 	`
 
@@ -68,12 +103,44 @@ const (
 			],
 			"name": "dgraph.graphql.persisted_query"
 		},
+		{
+			"fields": [
+				{"name": "dgraph.idempotency.key"},
+				{"name": "dgraph.idempotency.result"},
+				{"name": "dgraph.idempotency.expiry"}
+			],
+			"name": "dgraph.idempotency"
+		},
 		{
 			"fields": [
 				{"name": "dgraph.namespace.name"},
 				{"name": "dgraph.namespace.id"}
 			],
 			"name": "dgraph.namespace"
+		},
+		{
+			"fields": [
+				{"name": "dgraph.trigger.name"},
+				{"name": "dgraph.trigger.namespace"},
+				{"name": "dgraph.trigger.spec"}
+			],
+			"name": "dgraph.trigger"
+		},
+		{
+			"fields": [
+				{"name": "dgraph.wasm.name"},
+				{"name": "dgraph.wasm.namespace"},
+				{"name": "dgraph.wasm.code"}
+			],
+			"name": "dgraph.wasm"
+		},
+		{
+			"fields": [
+				{"name": "dgraph.function.name"},
+				{"name": "dgraph.function.namespace"},
+				{"name": "dgraph.function.query"}
+			],
+			"name": "dgraph.function"
 		}
 	`
 )
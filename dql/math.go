@@ -223,6 +223,33 @@ loop:
 				return nil, false, err
 			}
 			if peekIt[0].Typ == itemLeftRound {
+				switch {
+				case lval == "val":
+					// val(a) is the value variable a, like a.
+					varName, err := parseValVar(it)
+					if err != nil {
+						return nil, false, err
+					}
+					valueStack.push(&MathTree{Var: varName})
+					continue
+				case IsWasmFunc(item.Val):
+					// The arguments of the user-defined functions are math expressions.
+					fn := &MathTree{Fn: item.Val}
+					again := false
+					for {
+						var child *MathTree
+						child, again, err = parseMathFunc(gq, it, again)
+						if err != nil {
+							return nil, false, err
+						}
+						fn.Child = append(fn.Child, child)
+						if !again {
+							break
+						}
+					}
+					valueStack.push(fn)
+					continue
+				}
 				again := false
 				if !isMathFunc(item.Val) {
 					return nil, false, errors.Errorf("Unknown math function: %v", item.Val)
@@ -344,6 +371,20 @@ loop:
 	return res, false, err
 }
 
+// parseValVar parses the variable of val(a) in a math expression.
+func parseValVar(it *lex.ItemIterator) (string, error) {
+	var name string
+	for i, typ := range []lex.ItemType{itemLeftRound, itemName, itemRightRound} {
+		if !it.Next() || it.Item().Typ != typ {
+			return "", errors.Errorf("Expected a variable name in val() in math expression")
+		}
+		if i == 1 {
+			name = it.Item().Val
+		}
+	}
+	return name, nil
+}
+
 func (t *MathTree) subs(vmap varMap) error {
 	if strings.HasPrefix(t.Var, "$") {
 		va, ok := vmap[t.Var]
@@ -404,6 +445,10 @@ func (t *MathTree) stringHelper(buf *bytes.Buffer) {
 		"logbase", "pow", "dot":
 		x.Check2(buf.WriteString(t.Fn))
 	default:
+		if IsWasmFunc(t.Fn) {
+			x.Check2(buf.WriteString(t.Fn))
+			break
+		}
 		x.Fatalf("Unknown operator: %q", t.Fn)
 	}
 
@@ -0,0 +1,236 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dql
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/lex"
+)
+
+// Motif is a small subgraph pattern given by the @motif directive of a block, like a triangle
+//
+//	@motif("(a:Account)-[transfer]->(b:Account)-[transfer]->(c:Account)-[transfer]->(a)")
+//
+// The block returns every binding of the variables of the pattern to distinct nodes, such that
+// all the edges of the pattern exist between them. The first variable is bound to the uids of the
+// root function of the block.
+type Motif struct {
+	// Vars are the variables of the pattern, in the order they first appear in.
+	Vars []string
+	// Types are the types the variables are constrained to, by index in Vars. They're empty for
+	// the variables without a type.
+	Types []string
+	Edges []MotifEdge
+}
+
+// MotifEdge is an edge of a motif, going from the variable at index From to the one at index To.
+type MotifEdge struct {
+	From, To int
+	Attr     string
+}
+
+// parseMotif parses the pattern given to @motif.
+func parseMotif(it *lex.ItemIterator, gq *GraphQuery) error {
+	item := it.Item()
+	if gq.Motif != nil {
+		return item.Errorf("Only one @motif directive allowed")
+	}
+	if ok := trySkipItemTyp(it, itemLeftRound); !ok {
+		return item.Errorf("Expected a left round after motif")
+	}
+	if !it.Next() || it.Item().Typ != itemName {
+		return it.Errorf("Expected a quoted pattern inside @motif()")
+	}
+	item = it.Item()
+	if len(item.Val) < 2 || item.Val[0] != quote {
+		return item.Errorf("Expected a quoted pattern inside @motif(), got %s", item.Val)
+	}
+	pattern, err := unquoteIfQuoted(item.Val)
+	if err != nil {
+		return err
+	}
+	if gq.Motif, err = ParseMotif(pattern); err != nil {
+		return item.Errorf("%v", err)
+	}
+	if ok := trySkipItemTyp(it, itemRightRound); !ok {
+		return it.Errorf("Expected a right round after the pattern of @motif")
+	}
+	return nil
+}
+
+// ParseMotif parses a pattern made of paths separated by commas. A path goes through nodes,
+// (name) or (name:Type), linked by edges, -[predicate]-> or <-[predicate]-.
+func ParseMotif(pattern string) (*Motif, error) {
+	p := &motifParser{s: pattern, m: &Motif{}, vars: make(map[string]int)}
+	for {
+		if err := p.path(); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected a comma between the paths")
+		}
+	}
+	if len(p.m.Edges) == 0 {
+		return nil, errors.Errorf("The pattern of @motif must have at least one edge")
+	}
+	if !p.m.connected() {
+		return nil, errors.Errorf("The pattern of @motif must be connected")
+	}
+	return p.m, nil
+}
+
+type motifParser struct {
+	s    string
+	pos  int
+	m    *Motif
+	vars map[string]int
+}
+
+func (p *motifParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("Invalid pattern of @motif at offset %d: %s", p.pos,
+		errors.Errorf(format, args...))
+}
+
+func (p *motifParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *motifParser) skipSpace() {
+	for !p.done() && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips the token if the pattern is at it, after any spaces.
+func (p *motifParser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.s[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// until returns the text up to the first of the runes in stop, without any surrounding spaces.
+func (p *motifParser) until(stop string) string {
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(stop, rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return strings.TrimSpace(p.s[start:p.pos])
+}
+
+func (p *motifParser) path() error {
+	from, err := p.node()
+	if err != nil {
+		return err
+	}
+	for {
+		var reverse bool
+		switch {
+		case p.consume("-["):
+		case p.consume("<-["):
+			reverse = true
+		default:
+			return nil
+		}
+		attr := p.until("]")
+		if attr == "" || strings.ContainsAny(attr, " \t\n") {
+			return p.errorf("expected a predicate inside [], got %q", attr)
+		}
+		if reverse && !p.consume("]-") || !reverse && !p.consume("]->") {
+			return p.errorf("expected the end of the edge %s", attr)
+		}
+		to, err := p.node()
+		if err != nil {
+			return err
+		}
+		edge := MotifEdge{From: from, To: to, Attr: attr}
+		if reverse {
+			edge.From, edge.To = to, from
+		}
+		p.m.Edges = append(p.m.Edges, edge)
+		from = to
+	}
+}
+
+// node parses a node of the pattern and returns the index of its variable.
+func (p *motifParser) node() (int, error) {
+	if !p.consume("(") {
+		return 0, p.errorf("expected ( at the start of a node")
+	}
+	name := p.until(":)")
+	if !isMotifVar(name) {
+		return 0, p.errorf("invalid variable %q", name)
+	}
+	var typ string
+	if p.consume(":") {
+		if typ = p.until(")"); typ == "" {
+			return 0, p.errorf("expected a type after %s:", name)
+		}
+	}
+	if !p.consume(")") {
+		return 0, p.errorf("expected ) at the end of the node %s", name)
+	}
+
+	i, ok := p.vars[name]
+	if !ok {
+		i = len(p.m.Vars)
+		p.vars[name] = i
+		p.m.Vars = append(p.m.Vars, name)
+		p.m.Types = append(p.m.Types, typ)
+	}
+	switch {
+	case typ == "":
+	case p.m.Types[i] == "":
+		p.m.Types[i] = typ
+	case p.m.Types[i] != typ:
+		return 0, p.errorf("%s has two types, %s and %s", name, p.m.Types[i], typ)
+	}
+	return i, nil
+}
+
+func isMotifVar(name string) bool {
+	if name == "" || !isNameBegin(rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if !isNameBegin(r) && !isNumber(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// connected returns true if every variable of the motif can be reached from the first one,
+// following the edges in either direction.
+func (m *Motif) connected() bool {
+	seen := make([]bool, len(m.Vars))
+	seen[0] = true
+	for changed := true; changed; {
+		changed = false
+		for _, e := range m.Edges {
+			if seen[e.From] != seen[e.To] {
+				seen[e.From], seen[e.To] = true, true
+				changed = true
+			}
+		}
+	}
+	for _, ok := range seen {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
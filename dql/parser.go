@@ -8,6 +8,7 @@ package dql
 import (
 	"bytes"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -29,6 +30,15 @@ const (
 	countFunc   = "count"
 	uidInFunc   = "uid_in"
 	similarToFn = "similar_to"
+	joinFunc    = "join"
+	// notExistsFunc is the anti-join of uid_in, not_exists(predicate, uid(x)).
+	notExistsFunc = "not_exists"
+	// unionFunc, intersectFunc and exceptFunc combine the uids of several blocks or variables.
+	unionFunc     = "union"
+	intersectFunc = "intersect"
+	exceptFunc    = "except"
+	// distinctFunc is the aggregator of count(distinct ...).
+	distinctFunc = "distinct"
 )
 
 var (
@@ -49,6 +59,19 @@ type GraphQuery struct {
 	NeedsVar   []VarContext
 	Func       *Function
 	Expand     string // Which variable to expand with.
+	// Propagate is the aggregator combining the values of the value variable Var which reach the
+	// same uid, when the variable is used below the level it's defined at. It's sum by default.
+	Propagate string
+	// Join is the function of a join() virtual edge, eq(predicate, val(x)), which links the nodes
+	// to the ones whose predicate is equal to their value of x. With eq(val(y), val(x)), it links
+	// them to the nodes whose value of y is equal to their value of x instead. With
+	// similar_to(predicate, k, val(x)), it links them to the k nearest nodes to their vector in x.
+	Join *Function
+	// Motif is the pattern of @motif, whose bindings are returned by the block.
+	Motif *Motif
+	// ValidAt is the datetime of @valid_at, at which the uid edges traversed below this level
+	// must be valid according to their valid_from and valid_to facets.
+	ValidAt string
 
 	Args map[string]string
 	// Query can have multiple sort parameters.
@@ -57,6 +80,7 @@ type GraphQuery struct {
 	Filter           *FilterTree
 	MathExp          *MathTree
 	Normalize        bool
+	NormalizeArgs    *NormalizeArgs
 	Recurse          bool
 	RecurseArgs      RecurseArgs
 	ShortestPathArgs ShortestPathArgs
@@ -65,7 +89,9 @@ type GraphQuery struct {
 	Facets           *pb.FacetParams
 	FacetsFilter     *FilterTree
 	GroupbyAttrs     []GroupByAttr
+	GroupbyNested    bool
 	FacetVar         map[string]string
+	FacetAggVars     []FacetAggVar
 	FacetsOrder      []*FacetOrder
 
 	// Used for ACL enabled queries to curtail results to only accessible params
@@ -88,6 +114,17 @@ type RecurseArgs struct {
 	// argument in the substitution part.
 }
 
+// NormalizeArgs stores the arguments passed to the @normalize directive. They apply to the
+// subtree of the block or predicate they are given to, unless a nested @normalize gives its own.
+type NormalizeArgs struct {
+	// Dotted keeps the fields without an alias, and prefixes the keys of the flattened fields with
+	// the path to them, like friend.name.
+	Dotted bool
+	// Lists keeps the list predicates as arrays of flattened objects, instead of producing a
+	// result for every combination of their items.
+	Lists bool
+}
+
 // ShortestPathArgs stores the arguments needed to process the shortest path query.
 type ShortestPathArgs struct {
 	// From, To can have a uid or a uid function as the argument.
@@ -103,6 +140,36 @@ type GroupByAttr struct {
 	Attr  string
 	Alias string
 	Langs []string
+	// Bucket, if set, groups the values of Attr by the bucket they fall into, instead of by value.
+	Bucket *GroupByBucket
+}
+
+// GroupByBucket stores a bucketing function of the @groupby directive, like bucket(age, 10) or
+// datetrunc(created, "month").
+type GroupByBucket struct {
+	// Name is bucket, grouping the numbers by the ranges of width Arg, or datetrunc, grouping the
+	// datetimes by their truncation to the unit Arg.
+	Name string
+	Arg  string
+}
+
+// datetruncUnits are the units of the datetrunc function of @groupby.
+var datetruncUnits = map[string]struct{}{
+	"year": {}, "month": {}, "day": {}, "hour": {}, "minute": {},
+}
+
+// FacetAggVar stores a value variable of the aggregate of a facet over the edges of every node,
+// like total as sum(weight) in @facets.
+type FacetAggVar struct {
+	Var string
+	// Func is one of sum, min, max, avg or count.
+	Func string
+	Key  string
+}
+
+// facetAggregators are the functions aggregating a facet in @facets.
+var facetAggregators = map[string]struct{}{
+	"sum": {}, "min": {}, "max": {}, "avg": {}, "count": {},
 }
 
 // FacetOrder stores ordering for single facet key.
@@ -227,7 +294,7 @@ var mathOpPrecedence = map[string]int{
 
 // IsAggregator returns true if the function name is an aggregation function.
 func (f *Function) IsAggregator() bool {
-	return isAggregator(f.Name)
+	return isAggregator(f.Name) || f.Name == distinctFunc
 }
 
 // IsPasswordVerifier returns true if the function name is "checkpwd".
@@ -304,6 +371,8 @@ func convertToVarMap(variables map[string]string) (vm varMap) {
 type Request struct {
 	Str       string
 	Variables map[string]string
+	// Functions are the stored functions which the query can call, by name.
+	Functions map[string]*StoredFunction
 }
 
 func parseValue(v varInfo) (types.Val, error) {
@@ -639,6 +708,11 @@ func Parse(r Request) (Result, error) {
 // The variable name v needs to be passed through the needVars parameter. Otherwise, an error
 // is reported complaining that the variable v is defined but not used in the query block.
 func ParseWithNeedVars(r Request, needVars []string) (res Result, rerr error) {
+	return parse(r, needVars, 0)
+}
+
+// parse parses the request, depth being the number of stored functions it is expanded from.
+func parse(r Request, needVars []string, depth int) (res Result, rerr error) {
 	query := r.Str
 	vmap := convertToVarMap(r.Variables)
 
@@ -700,9 +774,7 @@ func ParseWithNeedVars(r Request, needVars []string) (res Result, rerr error) {
 	}
 
 	if len(res.Query) != 0 {
-		res.QueryVars = make([]*Vars, 0, len(res.Query))
-		for i := range res.Query {
-			qu := res.Query[i]
+		for _, qu := range res.Query {
 			// Try expanding fragments using fragment map.
 			if err := qu.expandFragments(fmap); err != nil {
 				return res, err
@@ -712,7 +784,21 @@ func ParseWithNeedVars(r Request, needVars []string) (res Result, rerr error) {
 			if err := substituteVariables(qu, vmap); err != nil {
 				return res, err
 			}
+		}
+		// The calls to the stored functions are expanded before the variables are collected.
+		if err := expandStoredFuncs(res.Query, r.Functions, depth); err != nil {
+			return res, err
+		}
+		// The set operations can name the blocks they combine instead of their variables.
+		if err := resolveSetOps(res.Query); err != nil {
+			return res, err
+		}
+		// The filters reading variables defined in their own block are compiled into var blocks.
+		res.Query = compileSubqueries(res.Query, needVars)
 
+		res.QueryVars = make([]*Vars, 0, len(res.Query))
+		for i := range res.Query {
+			qu := res.Query[i]
 			res.QueryVars = append(res.QueryVars, &Vars{})
 			// Collect vars used and defined in Result struct.
 			qu.collectVars(res.QueryVars[i])
@@ -740,6 +826,10 @@ func ParseWithNeedVars(r Request, needVars []string) (res Result, rerr error) {
 func validateResult(res *Result) error {
 	seenQueryAliases := make(map[string]bool)
 	for _, q := range res.Query {
+		if q.Motif != nil && (q.Recurse || q.IsGroupby || len(q.Order) > 0 || q.Func == nil) {
+			return errors.Errorf("@motif of %s needs a root function, and can't be used with "+
+				"@recurse, @groupby or an order", q.Alias)
+		}
 		if q.Alias == "var" || q.Alias == "shortest" {
 			continue
 		}
@@ -797,6 +887,9 @@ func (gq *GraphQuery) collectVars(v *Vars) {
 			v.Defines = append(v.Defines, va)
 		}
 	}
+	for _, va := range gq.FacetAggVars {
+		v.Defines = append(v.Defines, va.Var)
+	}
 	for _, va := range gq.NeedsVar {
 		v.Needs = append(v.Needs, va.Name)
 	}
@@ -919,6 +1012,51 @@ func parseVarName(it *lex.ItemIterator) (string, error) {
 	return val, nil
 }
 
+func parseNormalizeArgs(it *lex.ItemIterator, gq *GraphQuery) error {
+	if ok := trySkipItemTyp(it, itemLeftRound); !ok {
+		// We don't have a (, we can return.
+		return nil
+	}
+
+	gq.NormalizeArgs = &NormalizeArgs{}
+	for it.Next() {
+		item := it.Item()
+		if item.Typ != itemName {
+			return item.Errorf("Expected key inside @normalize()")
+		}
+		key := strings.ToLower(item.Val)
+
+		if ok := trySkipItemTyp(it, itemColon); !ok {
+			return it.Errorf("Expected colon(:) after %s", key)
+		}
+		if !it.Next() {
+			return it.Errorf("Expected argument")
+		}
+
+		item = it.Item()
+		val, err := strconv.ParseBool(item.Val)
+		if item.Typ != itemName || err != nil {
+			return item.Errorf("Value inside @normalize() should be a boolean for key: %s", key)
+		}
+		switch key {
+		case "dotted":
+			gq.NormalizeArgs.Dotted = val
+		case "lists":
+			gq.NormalizeArgs.Lists = val
+		default:
+			return item.Errorf("Unexpected key: [%s] inside @normalize block", key)
+		}
+
+		if _, ok := tryParseItemType(it, itemRightRound); ok {
+			return nil
+		}
+		if _, ok := tryParseItemType(it, itemComma); !ok {
+			return it.Errorf("Expected comma after value: %s inside @normalize block", item.Val)
+		}
+	}
+	return it.Errorf("Expected right round after @normalize arguments")
+}
+
 func parseRecurseArgs(it *lex.ItemIterator, gq *GraphQuery) error {
 	if ok := trySkipItemTyp(it, itemLeftRound); !ok {
 		// We don't have a (, we can return.
@@ -1040,6 +1178,9 @@ L:
 
 			case "normalize":
 				gq.Normalize = true
+				if err := parseNormalizeArgs(it, gq); err != nil {
+					return nil, err
+				}
 			case "cascade":
 				if err := parseCascade(it, gq); err != nil {
 					return nil, err
@@ -1056,6 +1197,14 @@ L:
 				if err := parseRecurseArgs(it, gq); err != nil {
 					return nil, err
 				}
+			case "motif":
+				if err := parseMotif(it, gq); err != nil {
+					return nil, err
+				}
+			case "valid_at":
+				if err := parseValidAt(it, gq); err != nil {
+					return nil, err
+				}
 			default:
 				return nil, item.Errorf("Unknown directive [%s]", item.Val)
 			}
@@ -1694,17 +1843,36 @@ func getValueArg(val string) (string, error) {
 	return unquoteIfQuoted(strings.TrimSpace(val))
 }
 
+// checkSimilarityJoin returns an error if the function of a join() isn't
+// similar_to(predicate, k, val(x)) or similar_to(predicate, k, val(x), uid(y)), which links the
+// nodes to the k nearest ones to their vector in x by the index of the predicate, among the nodes
+// of y if it's given.
+func checkSimilarityJoin(fn *Function) error {
+	if fn.Attr == "" || fn.IsValueVar || fn.IsCount || fn.IsLenVar ||
+		len(fn.Args) < 2 || len(fn.Args) > 3 || fn.Args[0].IsValueVar || fn.Args[0].IsDQLVar ||
+		!fn.Args[1].IsValueVar || (len(fn.Args) == 3 && fn.Args[2].IsValueVar) {
+		return errors.Errorf("join() expects similar_to(predicate, k, val(variable)) or " +
+			"similar_to(predicate, k, val(variable), uid(variable))")
+	}
+	if k, err := strconv.ParseUint(fn.Args[0].Value, 10, 32); err != nil || k == 0 {
+		return errors.Errorf("The number of neighbours of similar_to in join() should be a "+
+			"positive integer, got %s", fn.Args[0].Value)
+	}
+	return nil
+}
+
 func validFuncName(name string) bool {
-	if isGeoFunc(name) || IsInequalityFn(name) {
+	if isGeoFunc(name) || IsInequalityFn(name) || IsWasmFunc(name) {
 		return true
 	}
 
 	switch name {
 	case "regexp", "anyofterms", "allofterms", "alloftext", "anyoftext", "ngram",
-		"has", "uid", "uid_in", "anyof", "allof", "type", "match", "similar_to":
+		"has", "uid", "uid_in", "not_exists", "anyof", "allof", "type", "match", "fuzzy",
+		"levenshtein", "similar_to", "plugin", storedFunc:
 		return true
 	}
-	return false
+	return IsSetOpFunc(name)
 }
 
 type regexArgs struct {
@@ -1732,7 +1900,40 @@ func parseRegexArgs(val string) (regexArgs, error) {
 	return regexArgs{expr, flags}, nil
 }
 
+// ExpandRegexp returns the regular expression given to expand(), as in expand(/^has_/i), or nil
+// if expand() is given something else. The names of the predicates of the schema matching it are
+// expanded.
+func ExpandRegexp(expand string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(expand, "/") {
+		return nil, nil
+	}
+	ra, err := parseRegexArgs(expand)
+	if err != nil {
+		return nil, err
+	}
+	switch ra.flags {
+	case "":
+	case "i":
+		ra.expr = "(?i)" + ra.expr
+	default:
+		return nil, errors.Errorf("Invalid regexp modifier: %s", ra.flags)
+	}
+	re, err := regexp.Compile(ra.expr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing the regex of expand(%s)", expand)
+	}
+	return re, nil
+}
+
 func parseFunction(it *lex.ItemIterator, gq *GraphQuery) (*Function, error) {
+	return parseFunctionWithVals(it, gq, false)
+}
+
+// parseFunctionWithVals parses a function, which can compare two value variables like
+// eq(val(y), val(x)) if valArgs is true.
+func parseFunctionWithVals(it *lex.ItemIterator, gq *GraphQuery,
+	valArgs bool) (*Function, error) {
+
 	function := &Function{}
 	var expectArg, seenFuncArg, expectLang, isDollar bool
 L:
@@ -1745,6 +1946,10 @@ L:
 
 		name := collectName(it, item.Val)
 		function.Name = strings.ToLower(name)
+		if IsWasmFunc(name) {
+			// The names of the modules and of their functions are case sensitive.
+			function.Name = name
+		}
 		if _, ok := tryParseItemType(it, itemLeftRound); !ok {
 			return nil, it.Errorf("Expected ( after func name [%s]", function.Name)
 		}
@@ -1771,7 +1976,7 @@ L:
 				continue
 			case itemLeftRound:
 				// Function inside a function.
-				if seenFuncArg {
+				if seenFuncArg && !valArgs {
 					return nil, itemInFunc.Errorf("Multiple functions as arguments not allowed")
 				}
 				it.Prev()
@@ -1780,6 +1985,13 @@ L:
 				if err != nil {
 					return nil, err
 				}
+				// similar_to(predicate, k, val(x), uid(y)) takes the candidates of a
+				// similarity join after the vectors.
+				candidates := function.Name == similarToFn && nestedFunc.Name == uidFunc
+				if seenFuncArg && !candidates && (nestedFunc.Name != valueFunc ||
+					!function.IsValueVar || len(function.Args) > 0) {
+					return nil, itemInFunc.Errorf("Multiple functions as arguments not allowed")
+				}
 				seenFuncArg = true
 				switch nestedFunc.Name {
 				case valueFunc:
@@ -1797,6 +2009,7 @@ L:
 					}
 					function.NeedsVar = append(function.NeedsVar, nestedFunc.NeedsVar...)
 					function.NeedsVar[0].Typ = ValueVar
+					function.NeedsVar[len(function.NeedsVar)-1].Typ = ValueVar
 				case lenFunc:
 					if len(nestedFunc.NeedsVar) > 1 {
 						return nil,
@@ -1831,7 +2044,7 @@ L:
 							itemInFunc.Errorf("Nested uid fn expects only uid variable, got UID")
 					}
 					function.NeedsVar = append(function.NeedsVar, nestedFunc.NeedsVar...)
-					function.NeedsVar[0].Typ = UidVar
+					function.NeedsVar[len(function.NeedsVar)-1].Typ = UidVar
 					function.Args = append(function.Args, Arg{Value: nestedFunc.NeedsVar[0].Name})
 				default:
 					return nil, itemInFunc.Errorf("Only val/count/len/uid allowed as function "+
@@ -1874,7 +2087,8 @@ L:
 				case IsInequalityFn(function.Name):
 					err = parseFuncArgs(it, function)
 
-				case function.Name == "uid_in" || function.Name == "similar_to":
+				case function.Name == uidInFunc || function.Name == notExistsFunc ||
+					function.Name == "similar_to":
 					err = parseFuncArgs(it, function)
 
 				default:
@@ -1942,14 +2156,16 @@ L:
 			// Unlike other functions, uid function has no attribute, everything is args.
 			switch {
 			case len(function.Attr) == 0 && function.Name != uidFunc &&
-				function.Name != typFunc:
+				function.Name != typFunc && !IsSetOpFunc(function.Name):
 
 				if strings.ContainsRune(itemInFunc.Val, '"') {
 					return nil, itemInFunc.Errorf("Attribute in function"+
 						" must not be quoted with \": %s", itemInFunc.Val)
 				}
-				if function.Name == uidInFunc && item.Typ == itemRightRound {
-					return nil, itemInFunc.Errorf("uid_in function expects an argument, got none")
+				if (function.Name == uidInFunc || function.Name == notExistsFunc) &&
+					item.Typ == itemRightRound {
+					return nil, itemInFunc.Errorf("%s function expects an argument, got none",
+						function.Name)
 				}
 				function.Attr = val
 				attrItemsAgo = 0
@@ -1960,7 +2176,7 @@ L:
 				}
 				function.Lang = val
 				expectLang = false
-			case function.Name != uidFunc:
+			case function.Name != uidFunc && !IsSetOpFunc(function.Name):
 				// For UID function. we set g.UID
 				function.Args = append(function.Args, Arg{Value: val})
 			}
@@ -2008,10 +2224,27 @@ L:
 					Name: val,
 					Typ:  UidVar,
 				})
+			case unionFunc, intersectFunc, exceptFunc:
+				// The arguments are variables or names of blocks, union takes the minimum
+				// number of them a uid has to be in as its last argument.
+				if _, err := strconv.ParseUint(val, 0, 32); err == nil {
+					function.Args = append(function.Args, Arg{Value: val})
+					continue
+				}
+				function.NeedsVar = append(function.NeedsVar, VarContext{
+					Name: val,
+					Typ:  UidVar,
+				})
 			}
 		}
 	}
 
+	if IsSetOpFunc(function.Name) {
+		if err := validateSetOpFunc(function); err != nil {
+			return nil, it.Errorf("%v", err)
+		}
+		return function, nil
+	}
 	if function.Name != uidFunc && function.Name != typFunc && len(function.Attr) == 0 {
 		return nil, it.Errorf("Got empty attr for function: [%s]", function.Name)
 	}
@@ -2027,6 +2260,7 @@ type facetRes struct {
 	f           *pb.FacetParams
 	ft          *FilterTree
 	vmap        map[string]string
+	aggVars     []FacetAggVar
 	facetsOrder []*FacetOrder
 }
 
@@ -2045,6 +2279,7 @@ type facetItem struct {
 	name      string
 	alias     string
 	varName   string
+	aggregate string
 	ordered   bool
 	orderdesc bool
 }
@@ -2055,6 +2290,8 @@ type facetItem struct {
 func tryParseFacetItem(it *lex.ItemIterator) (res facetItem, parseOk bool, err error) {
 	// We parse this:
 	// [{orderdesc|orderasc|alias}:] [varname as] name
+	// or the aggregation of a facet:
+	// varname as {sum|min|max|avg|count}(name)
 
 	savePos := it.Save()
 	defer func() {
@@ -2101,6 +2338,24 @@ func tryParseFacetItem(it *lex.ItemIterator) (res facetItem, parseOk bool, err e
 
 	res.name = collectName(it, item.Val)
 	res.varName = name1
+	if _, ok := facetAggregators[res.name]; !ok {
+		return res, true, nil
+	}
+	if _, ok := tryParseItemType(it, itemLeftRound); !ok {
+		return res, true, nil
+	}
+	if res.alias != "" || res.ordered {
+		return res, false, item.Errorf("Can't alias or order the aggregation of a facet")
+	}
+	res.aggregate = res.name
+	item, ok = tryParseItemType(it, itemName)
+	if !ok {
+		return res, false, item.Errorf("Expected facet name in %s()", res.aggregate)
+	}
+	res.name = collectName(it, item.Val)
+	if _, ok := tryParseItemType(it, itemRightRound); !ok {
+		return res, false, item.Errorf("Expected right round after facet %s", res.name)
+	}
 	return res, true, nil
 }
 
@@ -2128,6 +2383,7 @@ func tryParseFacetList(it *lex.ItemIterator) (res facetRes, parseOk bool, err er
 	facetVar := make(map[string]string)
 	var facets pb.FacetParams
 	var facetsOrder []*FacetOrder
+	var aggVars []FacetAggVar
 
 	if _, ok := tryParseItemType(it, itemRightRound); ok {
 		// @facets() just parses to an empty set of facets.
@@ -2150,7 +2406,13 @@ func tryParseFacetList(it *lex.ItemIterator) (res facetRes, parseOk bool, err er
 
 		// Combine the facetitem with our result.
 		{
-			if facetItem.varName != "" {
+			if facetItem.aggregate != "" {
+				aggVars = append(aggVars, FacetAggVar{
+					Var:  facetItem.varName,
+					Func: facetItem.aggregate,
+					Key:  facetItem.name,
+				})
+			} else if facetItem.varName != "" {
 				if _, has := facetVar[facetItem.name]; has {
 					return res, false, facetItemIt.Errorf("Duplicate variable mappings for facet %v",
 						facetItem.name)
@@ -2189,6 +2451,7 @@ func tryParseFacetList(it *lex.ItemIterator) (res facetRes, parseOk bool, err er
 			out = append(out, facets.Param[flen-1])
 			facets.Param = out
 			res.f, res.vmap, res.facetsOrder = &facets, facetVar, facetsOrder
+			res.aggVars = aggVars
 			return res, true, nil
 		}
 		if item, ok := tryParseItemType(it, itemComma); !ok {
@@ -2268,6 +2531,47 @@ loop:
 	return nil
 }
 
+// parseGroupbyBucket parses the bucketing function name, like bucket(age, 10), of a groupby
+// attribute.
+func parseGroupbyBucket(it *lex.ItemIterator, name string) (string, *GroupByBucket, error) {
+	it.Next() // Consume the itemLeftRound
+	var args []string
+	for it.Next() {
+		item := it.Item()
+		switch {
+		case item.Typ == itemRightRound:
+			if len(args) != 2 {
+				return "", nil, item.Errorf("Expected a predicate and an argument in %s()", name)
+			}
+			bucket := &GroupByBucket{Name: name, Arg: args[1]}
+			if name == "bucket" {
+				width, err := strconv.ParseFloat(bucket.Arg, 64)
+				if err != nil || width <= 0 {
+					return "", nil, item.Errorf("The width of bucket should be a positive number,"+
+						" got: %s", bucket.Arg)
+				}
+			} else if _, ok := datetruncUnits[bucket.Arg]; !ok {
+				return "", nil, item.Errorf("The unit of datetrunc should be one of year, month,"+
+					" day, hour or minute, got: %s", bucket.Arg)
+			}
+			return args[0], bucket, nil
+		case item.Typ == itemComma && len(args) == 1:
+		case item.Typ == itemName && len(args) < 2:
+			val := collectName(it, item.Val)
+			if len(args) == 1 {
+				var err error
+				if val, err = unquoteIfQuoted(val); err != nil {
+					return "", nil, err
+				}
+			}
+			args = append(args, val)
+		default:
+			return "", nil, item.Errorf("Unexpected item %s inside %s()", item.Val, name)
+		}
+	}
+	return "", nil, it.Errorf("Expected right round after %s arguments", name)
+}
+
 // parseGroupby parses the groupby directive.
 func parseGroupby(it *lex.ItemIterator, gq *GraphQuery) error {
 	count := 0
@@ -2300,6 +2604,20 @@ loop:
 			if err != nil {
 				return err
 			}
+			if val == "nested" && alias == "" && peekIt[0].Typ == itemColon {
+				// nested: true, unless it's the alias of a predicate.
+				next, err := it.Peek(2)
+				if err != nil {
+					return err
+				}
+				if nested, err := strconv.ParseBool(next[1].Val); err == nil {
+					gq.GroupbyNested = nested
+					it.Next() // Consume the itemColon
+					it.Next() // Consume the boolean
+					expectArg = false
+					continue
+				}
+			}
 			if peekIt[0].Typ == itemColon {
 				if alias != "" {
 					return item.Errorf("Expected predicate after %s:", alias)
@@ -2312,6 +2630,13 @@ loop:
 				continue
 			}
 
+			var bucket *GroupByBucket
+			if (val == "bucket" || val == "datetrunc") && peekIt[0].Typ == itemLeftRound {
+				if val, bucket, err = parseGroupbyBucket(it, val); err != nil {
+					return err
+				}
+			}
+
 			var langs []string
 			items, err := it.Peek(1)
 			if err == nil && items[0].Typ == itemAt {
@@ -2323,9 +2648,10 @@ loop:
 				}
 			}
 			attrLang := GroupByAttr{
-				Attr:  val,
-				Alias: alias,
-				Langs: langs,
+				Attr:   val,
+				Alias:  alias,
+				Langs:  langs,
+				Bucket: bucket,
 			}
 			alias = ""
 			gq.GroupbyAttrs = append(gq.GroupbyAttrs, attrLang)
@@ -2589,6 +2915,7 @@ func parseDirective(it *lex.ItemIterator, curp *GraphQuery) error {
 		switch {
 		case res.f != nil:
 			curp.FacetVar = res.vmap
+			curp.FacetAggVars = res.aggVars
 			curp.FacetsOrder = res.facetsOrder
 			if curp.Facets != nil {
 				return item.Errorf("Only one facets allowed")
@@ -2612,6 +2939,9 @@ func parseDirective(it *lex.ItemIterator, curp *GraphQuery) error {
 		}
 	case item.Val == "normalize":
 		curp.Normalize = true
+		if err := parseNormalizeArgs(it, curp); err != nil {
+			return err
+		}
 	case peek[0].Typ == itemLeftRound:
 		// this is directive
 		switch item.Val {
@@ -2636,6 +2966,14 @@ func parseDirective(it *lex.ItemIterator, curp *GraphQuery) error {
 			if err := parseGroupby(it, curp); err != nil {
 				return err
 			}
+		case "propagate":
+			if err := parsePropagate(it, curp); err != nil {
+				return err
+			}
+		case "valid_at":
+			if err := parseValidAt(it, curp); err != nil {
+				return err
+			}
 		default:
 			return item.Errorf("Unknown directive [%s]", item.Val)
 		}
@@ -2696,6 +3034,9 @@ func validKeyAtRoot(k string) bool {
 		return true
 	case "depth":
 		return true
+	case "n":
+		// Specific to sample
+		return true
 	}
 	return false
 }
@@ -2791,6 +3132,9 @@ loop:
 		if !validKeyAtRoot(key) {
 			return nil, item.Errorf("Got invalid keyword: %s at root", key)
 		}
+		if key == "n" && gq.Alias != "sample" {
+			return nil, item.Errorf("n only allowed for sample queries")
+		}
 
 		if !it.Next() {
 			return nil, item.Errorf("Invalid query")
@@ -2923,30 +3267,31 @@ loop:
 
 			}
 
-			// TODO - Allow only order by one of variable/predicate for now.
+			// The sort keys are either all predicates or all value variables.
 			if val == "" {
 				// This should only happen in cases like: orderasc: val(c)
 				if len(gq.NeedsVar) == 0 {
 					return nil, it.Errorf("unable to get value when parsing key value pairs")
 				}
 				val = gq.NeedsVar[len(gq.NeedsVar)-1].Name
-				// Right now we only allow one sort by a variable
-				if len(gq.Order) > 0 && isSortkey(key) {
-					return nil, it.Errorf("Multiple sorting only allowed by predicates. "+
-						"Got: %+v", val)
+				if isSortkey(key) {
+					for _, order := range gq.Order {
+						if !isOrderVar(gq, order.Attr) {
+							return nil, it.Errorf("Multiple sorting only allowed by predicates. "+
+								"Got: %+v", val)
+						}
+					}
 				}
-			}
-			if isSortkey(key) {
-
+			} else if isSortkey(key) {
 				// we dont support variable + predicate sorting
 				for _, order := range gq.Order {
-					for _, needVar := range gq.NeedsVar {
-						if needVar.Name == order.Attr {
-							return nil, it.Errorf("Val() is not allowed in multiple sorting."+
-								" Got: [%v]", needVar.Name)
-						}
+					if isOrderVar(gq, order.Attr) {
+						return nil, it.Errorf("Val() is not allowed in multiple sorting."+
+							" Got: [%v]", order.Attr)
 					}
 				}
+			}
+			if isSortkey(key) {
 
 				if order[val] {
 					return nil, it.Errorf("Sorting by an attribute: [%s] can only be done once", val)
@@ -2977,6 +3322,16 @@ func isSortkey(k string) bool {
 	return k == "orderasc" || k == "orderdesc"
 }
 
+// isOrderVar tells whether the sort key attr of gq is a value variable.
+func isOrderVar(gq *GraphQuery, attr string) bool {
+	for _, needVar := range gq.NeedsVar {
+		if needVar.Name == attr && needVar.Typ == ValueVar {
+			return true
+		}
+	}
+	return false
+}
+
 type countType int
 
 const (
@@ -3002,7 +3357,14 @@ func validateEmptyBlockItem(it *lex.ItemIterator, val string) error {
 		fname = item.Val
 	}
 	ok := trySkipItemTyp(it, itemLeftRound)
-	if !ok || (!isMathBlock(fname) && !isAggregator(fname)) {
+	if ok && fname == "count" {
+		// count(distinct val(x)) is an aggregation too.
+		item, distinct := tryParseItemType(it, itemName)
+		ok = distinct && item.Val == "distinct"
+	} else if ok && !isMathBlock(fname) && !isAggregator(fname) {
+		ok = false
+	}
+	if !ok {
 		return it.Errorf("Only aggregation/math functions allowed inside empty blocks."+
 			" Got: %v", fname)
 	}
@@ -3147,6 +3509,13 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 					Name:     valLower,
 					NeedsVar: child.NeedsVar,
 				}
+				if valLower == "percentile" {
+					quantile, err := parseQuantile(it)
+					if err != nil {
+						return err
+					}
+					child.Func.Args = []Arg{{Value: quantile}}
+				}
 				it.Next() // Skip the closing ')'
 				gq.Children = append(gq.Children, child)
 				curp = nil
@@ -3170,6 +3539,9 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 					MathExp:    mathTree,
 					IsInternal: true,
 				}
+				if err := tryParsePropagate(it, child); err != nil {
+					return err
+				}
 				// TODO - See that if we can instead initialize this at the top.
 				varName, alias = "", ""
 				gq.Children = append(gq.Children, child)
@@ -3193,8 +3565,13 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 					Args:       make(map[string]string),
 					IsInternal: true,
 				}
-				switch item.Val {
-				case valueFunc:
+				switch {
+				case item.Typ == itemRegex:
+					if _, err := ExpandRegexp(item.Val); err != nil {
+						return item.Errorf("%v", err)
+					}
+					child.Expand = item.Val
+				case item.Val == valueFunc:
 					count, err := parseVarList(it, child)
 					if err != nil {
 						return err
@@ -3204,11 +3581,11 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 					}
 					child.NeedsVar[len(child.NeedsVar)-1].Typ = ListVar
 					child.Expand = child.NeedsVar[len(child.NeedsVar)-1].Name
-				case "_all_":
+				case item.Val == "_all_":
 					child.Expand = "_all_"
-				case "_forward_":
+				case item.Val == "_forward_":
 					return item.Errorf("Argument _forward_ has been deprecated")
-				case "_reverse_":
+				case item.Val == "_reverse_":
 					return item.Errorf("Argument _reverse_ has been deprecated")
 				default:
 					if err := parseTypeList(it, child); err != nil {
@@ -3241,6 +3618,48 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 				switch {
 				case peekIt[0].Typ == itemRightRound:
 					return it.Errorf("Cannot use count(), please use count(uid)")
+				case peekIt[0].Val == "distinct" && peekIt[1].Typ == itemName:
+					// count(distinct val(x)), or count(distinct predicate) inside @groupby, is
+					// the approximate number of distinct values.
+					count = notSeen
+					it.Next() // Consume distinct
+					child := &GraphQuery{
+						Attr:       valueFunc,
+						Args:       make(map[string]string),
+						Var:        varName,
+						IsInternal: true,
+						Alias:      alias,
+					}
+					varName, alias = "", ""
+					it.Next()
+					item = it.Item()
+					if gq.IsGroupby {
+						child.Attr = collectName(it, item.Val)
+						child.IsInternal = false
+					} else {
+						if item.Val != valueFunc {
+							return it.Errorf("Only variables allowed in count(distinct ...). "+
+								"Got: %v", item.Val)
+						}
+						n, err := parseVarList(it, child)
+						if err != nil {
+							return err
+						}
+						if n != 1 {
+							return it.Errorf("Expected one variable inside val() of"+
+								" count(distinct ...) but got %v", n)
+						}
+						child.NeedsVar[len(child.NeedsVar)-1].Typ = ValueVar
+					}
+					child.Func = &Function{
+						Name:     distinctFunc,
+						NeedsVar: child.NeedsVar,
+					}
+					if !it.Next() || it.Item().Typ != itemRightRound {
+						return it.Errorf("Expected ) after count(distinct ...)")
+					}
+					gq.Children = append(gq.Children, child)
+					curp = nil
 				case peekIt[0].Val == uidFunc && peekIt[1].Typ == itemRightRound:
 					if gq.IsGroupby {
 						// count(uid) case which occurs inside @groupby
@@ -3300,6 +3719,51 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 				gq.Children = append(gq.Children, child)
 				curp = nil
 				continue
+			case valLower == joinFunc:
+				peekIt, err = it.Peek(1)
+				if err != nil {
+					return err
+				}
+				if peekIt[0].Typ != itemLeftRound {
+					goto Fall
+				}
+				if count == seen {
+					return it.Errorf("Count of a join() is not allowed")
+				}
+				if alias == "" {
+					return it.Errorf("join() should have an alias, naming the virtual edge")
+				}
+				it.Next() // Consume the '('
+				fn, err := parseFunctionWithVals(it, gq, true)
+				if err != nil {
+					return err
+				}
+				switch {
+				case fn.Name == similarToFn:
+					if err := checkSimilarityJoin(fn); err != nil {
+						return it.Errorf("%v", err)
+					}
+				// eq(val(y), val(x)) hash joins the nodes of x with the nodes of y.
+				case fn.Name != "eq" || fn.Attr == "" || fn.IsCount || fn.IsLenVar ||
+					len(fn.Args) != 1 || !fn.Args[0].IsValueVar:
+					return it.Errorf("join() expects a function eq(predicate, val(variable)) or "+
+						"eq(val(variable), val(variable)), got %s", fn.Name)
+				}
+				if !it.Next() || it.Item().Typ != itemRightRound {
+					return it.Errorf("Expected ) after the function of join()")
+				}
+				child := &GraphQuery{
+					Args:     make(map[string]string),
+					Attr:     fn.Attr,
+					Alias:    alias,
+					Var:      varName,
+					Join:     fn,
+					NeedsVar: append(fn.NeedsVar[:0:0], fn.NeedsVar...),
+				}
+				gq.Children = append(gq.Children, child)
+				varName, alias = "", ""
+				curp = child
+				continue
 			case valLower == uidFunc:
 				if count == seen {
 					return it.Errorf("Count of a variable is not allowed")
@@ -3402,8 +3866,94 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 	return nil
 }
 
+// isPropagator returns true if fname is an aggregator which can propagate a value variable.
+func isPropagator(fname string) bool {
+	return (isAggregator(fname) && fname != "percentile") || fname == "first" || fname == "concat"
+}
+
+// parsePropagate parses the aggregator of the @propagate directive, the current item being the
+// name of the directive.
+// parseValidAt parses @valid_at("2020-01-01"), whose argument is a quoted datetime.
+func parseValidAt(it *lex.ItemIterator, gq *GraphQuery) error {
+	item := it.Item()
+	if gq.ValidAt != "" {
+		return item.Errorf("Only one @valid_at directive allowed")
+	}
+	if ok := trySkipItemTyp(it, itemLeftRound); !ok {
+		return item.Errorf("Expected a left round after valid_at")
+	}
+	if !it.Next() || it.Item().Typ != itemName || it.Item().Val[0] != quote {
+		return it.Errorf("Expected a quoted datetime inside @valid_at()")
+	}
+	item = it.Item()
+	ts, err := unquoteIfQuoted(item.Val)
+	if err != nil {
+		return err
+	}
+	if _, err := types.ParseTime(ts); err != nil {
+		return item.Errorf("Invalid datetime %q inside @valid_at(): %v", ts, err)
+	}
+	if ok := trySkipItemTyp(it, itemRightRound); !ok {
+		return it.Errorf("Expected a right round after @valid_at(%s", item.Val)
+	}
+	gq.ValidAt = ts
+	return nil
+}
+
+func parsePropagate(it *lex.ItemIterator, gq *GraphQuery) error {
+	item := it.Item()
+	if gq.Var == "" {
+		return item.Errorf("@propagate can only be used where a value variable is defined")
+	}
+	if gq.Propagate != "" {
+		return item.Errorf("Only one @propagate directive allowed")
+	}
+	if ok := trySkipItemTyp(it, itemLeftRound); !ok {
+		return item.Errorf("Expected a left round after propagate")
+	}
+	if !it.Next() || it.Item().Typ != itemName {
+		return it.Errorf("Expected an aggregator inside @propagate()")
+	}
+	item = it.Item()
+	name := strings.ToLower(item.Val)
+	if !isPropagator(name) {
+		return item.Errorf("Invalid aggregator %s inside @propagate(), it should be one of sum, "+
+			"min, max, avg, first or concat", item.Val)
+	}
+	if ok := trySkipItemTyp(it, itemRightRound); !ok {
+		return it.Errorf("Expected a right round after @propagate(%s", item.Val)
+	}
+	gq.Propagate = name
+	return nil
+}
+
+// tryParsePropagate parses the @propagate directive following a math block, if there is one.
+func tryParsePropagate(it *lex.ItemIterator, gq *GraphQuery) error {
+	items, err := it.Peek(2)
+	if err != nil || items[0].Typ != itemAt || items[1].Val != "propagate" {
+		return nil
+	}
+	it.Next() // Consume the '@'
+	it.Next()
+	return parsePropagate(it, gq)
+}
+
 func isAggregator(fname string) bool {
-	return fname == "min" || fname == "max" || fname == "sum" || fname == "avg"
+	return fname == "min" || fname == "max" || fname == "sum" || fname == "avg" ||
+		fname == "percentile"
+}
+
+// parseQuantile parses the quantile of percentile(val(x), quantile), between 0 and 1, the current
+// item being the end of its variable.
+func parseQuantile(it *lex.ItemIterator) (string, error) {
+	if !it.Next() || it.Item().Typ != itemComma || !it.Next() || it.Item().Typ != itemName {
+		return "", it.Errorf("Expected a quantile in percentile, like percentile(val(x), 0.95)")
+	}
+	val := it.Item().Val
+	if q, err := strconv.ParseFloat(val, 64); err != nil || q < 0 || q > 1 {
+		return "", it.Errorf("The quantile of percentile must be between 0 and 1. Got: %v", val)
+	}
+	return val, nil
 }
 
 func isExpandFunc(name string) bool {
@@ -3426,6 +3976,15 @@ func IsInequalityFn(name string) bool {
 	return false
 }
 
+// WasmFuncPrefix is the prefix of the user-defined functions compiled to WebAssembly, called as
+// wasm.<module>.<function>(...), or wasm.<module>(...) if the module exports a single function.
+const WasmFuncPrefix = "wasm."
+
+// IsWasmFunc returns true if name is the name of a user-defined function compiled to WebAssembly.
+func IsWasmFunc(name string) bool {
+	return strings.HasPrefix(name, WasmFuncPrefix) && len(name) > len(WasmFuncPrefix)
+}
+
 // Name can have dashes or alphanumeric characters. Lexer lexes them as separate items.
 // We put it back together here.
 func collectName(it *lex.ItemIterator, val string) string {
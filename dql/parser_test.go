@@ -210,6 +210,37 @@ func TestParseQueryExpandReverse(t *testing.T) {
 	require.Contains(t, err.Error(), "Argument _reverse_ has been deprecated")
 }
 
+func TestParseQueryExpandRegex(t *testing.T) {
+	query := `
+	{
+		var(func: uid( 0x0a)) {
+			friends {
+				expand( /^has_\/[a-z]+/i ) @filter(type(Person)) {
+					name
+				}
+			}
+		}
+	}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	expand := res.Query[0].Children[0].Children[0]
+	require.Equal(t, `/^has_\/[a-z]+/i`, expand.Expand)
+	require.NotNil(t, expand.Filter)
+
+	re, err := ExpandRegexp(expand.Expand)
+	require.NoError(t, err)
+	require.True(t, re.MatchString("HAS_/friend"))
+	re, err = ExpandRegexp("Person")
+	require.NoError(t, err)
+	require.Nil(t, re)
+
+	for _, expand := range []string{`/^has_/g`, `/(/`} {
+		_, err := Parse(Request{Str: `{ q(func: uid(1)) { expand(` + expand + `) } }`})
+		require.Error(t, err, expand)
+	}
+}
+
 func TestParseQueryExpandType(t *testing.T) {
 	query := `
 	{
@@ -528,6 +559,34 @@ func TestParseQueryWithVarValAggNested2(t *testing.T) {
 		res.Query[1].Children[0].Children[4].MathExp.debugString())
 }
 
+func TestParseWasmFunctions(t *testing.T) {
+	query := `
+	{
+		var(func: uid(0x0a)) {
+			a as age
+			b as count(friends)
+			s as math(wasm.scoring.Score(val(a), b * 2) + 1)
+		}
+
+		me(func: uid(s)) @filter(wasm.keep(val(s), 0.5)) {
+			name
+		}
+	}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.EqualValues(t, "(+ (wasm.scoring.Score a (* b 2)) 1)",
+		res.Query[0].Children[2].MathExp.debugString())
+	fn := res.Query[1].Filter.Func
+	require.Equal(t, "wasm.keep", fn.Name)
+	require.Equal(t, "s", fn.Attr)
+	require.True(t, fn.IsValueVar)
+	require.Equal(t, []Arg{{Value: "0.5"}}, fn.Args)
+
+	_, err = Parse(Request{Str: `{ me(func: uid(1)) { a as age  s as math(wasm.f(val())) } }`})
+	require.ErrorContains(t, err, "Expected a variable name in val()")
+}
+
 func TestParseQueryWithVarValAggNested4(t *testing.T) {
 	query := `
 	{
@@ -1354,6 +1413,24 @@ func TestParseShortestPathInvalidFnError(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestParseSample(t *testing.T) {
+	res, err := Parse(Request{Str: `{
+		sample(func: has(name), n: 1000) {
+			name
+		}
+	}`})
+	require.NoError(t, err)
+	require.Equal(t, "sample", res.Query[0].Alias)
+	require.Equal(t, "1000", res.Query[0].Args["n"])
+
+	_, err = Parse(Request{Str: `{
+		me(func: has(name), n: 1000) {
+			name
+		}
+	}`})
+	require.ErrorContains(t, err, "n only allowed for sample queries")
+}
+
 func TestParseMultipleQueries(t *testing.T) {
 	query := `
 	{
@@ -3219,6 +3296,53 @@ func TestParseNormalize(t *testing.T) {
 	require.True(t, res.Query[0].Normalize)
 }
 
+func TestParseNormalizeArgs(t *testing.T) {
+	query := `
+	query {
+		me(func: uid( 0x3)) @normalize(dotted: true, lists: true) {
+			friends @normalize(dotted: false) {
+				name
+			}
+			pets @normalize {
+				name
+			}
+			gender
+		}
+}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.NotNil(t, res.Query[0])
+	require.True(t, res.Query[0].Normalize)
+	require.Equal(t, &NormalizeArgs{Dotted: true, Lists: true}, res.Query[0].NormalizeArgs)
+	require.True(t, res.Query[0].Children[0].Normalize)
+	require.Equal(t, &NormalizeArgs{}, res.Query[0].Children[0].NormalizeArgs)
+	require.True(t, res.Query[0].Children[1].Normalize)
+	require.Nil(t, res.Query[0].Children[1].NormalizeArgs)
+
+	query = `
+	{
+		me(func: uid(0x3)) @normalize(flat: true) {
+			name
+		}
+	}
+`
+	_, err = Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Unexpected key: [flat] inside @normalize block")
+
+	query = `
+	{
+		me(func: uid(0x3)) @normalize(dotted: yes) {
+			name
+		}
+	}
+`
+	_, err = Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Value inside @normalize() should be a boolean for key: dotted")
+}
+
 func TestParseGroupbyRoot(t *testing.T) {
 	query := `
 	query {
@@ -3362,6 +3486,78 @@ func TestParseGroupbyWithAliasForError(t *testing.T) {
 	require.Contains(t, err.Error(), "Can't use keyword first as alias in groupby")
 }
 
+func TestParseGroupbyBuckets(t *testing.T) {
+	query := `
+	query {
+		me(func: uid(0x1)) {
+			friends @groupby(nested: true, AgeRange: bucket(age, 10),
+				datetrunc(dob, "month"), name) {
+				count(uid)
+			}
+		}
+	}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	friends := res.Query[0].Children[0]
+	require.True(t, friends.GroupbyNested)
+	require.Equal(t, []GroupByAttr{
+		{Attr: "age", Alias: "AgeRange", Bucket: &GroupByBucket{Name: "bucket", Arg: "10"}},
+		{Attr: "dob", Bucket: &GroupByBucket{Name: "datetrunc", Arg: "month"}},
+		{Attr: "name"},
+	}, friends.GroupbyAttrs)
+
+	// nested is the alias of a predicate if it's not given a boolean.
+	res, err = Parse(Request{Str: `{ me(func: uid(0x1)) @groupby(nested: name) { count(uid) } }`})
+	require.NoError(t, err)
+	require.False(t, res.Query[0].GroupbyNested)
+	require.Equal(t, []GroupByAttr{{Attr: "name", Alias: "nested"}}, res.Query[0].GroupbyAttrs)
+
+	for _, query := range []string{
+		`{ me(func: uid(0x1)) @groupby(bucket(age)) { count(uid) } }`,
+		`{ me(func: uid(0x1)) @groupby(bucket(age, -5)) { count(uid) } }`,
+		`{ me(func: uid(0x1)) @groupby(datetrunc(dob, "week")) { count(uid) } }`,
+		`{ me(func: uid(0x1)) @groupby(datetrunc(dob, "day", 1)) { count(uid) } }`,
+	} {
+		_, err := Parse(Request{Str: query})
+		require.Error(t, err, query)
+	}
+}
+
+func TestParseApproxAggregators(t *testing.T) {
+	res, err := Parse(Request{Str: `{
+		var(func: has(age)) { a as age }
+		me() {
+			percentile(val(a), 0.95)
+			count(distinct val(a))
+		}
+	}`})
+	require.NoError(t, err)
+	children := res.Query[1].Children
+	require.Equal(t, "percentile", children[0].Func.Name)
+	require.Equal(t, []Arg{{Value: "0.95"}}, children[0].Func.Args)
+	require.Equal(t, "distinct", children[1].Func.Name)
+	require.Equal(t, "a", children[1].NeedsVar[0].Name)
+
+	res, err = Parse(Request{Str: `{
+		me(func: has(age)) @groupby(name) {
+			count(distinct age)
+		}
+	}`})
+	require.NoError(t, err)
+	require.Equal(t, "age", res.Query[0].Children[0].Attr)
+	require.Equal(t, "distinct", res.Query[0].Children[0].Func.Name)
+
+	for _, query := range []string{
+		`{ me() { percentile(val(a)) } }`,
+		`{ me() { percentile(val(a), 1.5) } }`,
+		`{ me(func: has(age)) { count(distinct age) } }`,
+	} {
+		_, err := Parse(Request{Str: query})
+		require.Error(t, err, query)
+	}
+}
+
 func TestParseGroupbyError(t *testing.T) {
 	// predicates not allowed inside groupby.
 	query := `
@@ -3727,6 +3923,39 @@ func TestParseFacetsMultipleVar(t *testing.T) {
 	require.Equal(t, "b", res.Query[0].Children[0].Children[0].FacetVar["key3"])
 }
 
+func TestParseFacetAggregates(t *testing.T) {
+	query := `
+	query {
+		me(func: uid(0x1)) {
+			friends @facets(total as sum(weight), n as count(weight), w as weight, close)
+			total: val(total)
+			n: val(n)
+		}
+		h(func: uid(w)) {
+			uid
+		}
+	}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	friends := res.Query[0].Children[0]
+	require.Equal(t, []FacetAggVar{
+		{Var: "total", Func: "sum", Key: "weight"},
+		{Var: "n", Func: "count", Key: "weight"},
+	}, friends.FacetAggVars)
+	require.Equal(t, map[string]string{"weight": "w"}, friends.FacetVar)
+	require.Equal(t, 2, len(friends.Facets.Param))
+
+	for _, query := range []string{
+		`{ me(func: uid(0x1)) { friends @facets(t as sum(weight)) } }`,
+		`{ me(func: uid(0x1)) { friends @facets(t: t as sum(weight)) total: val(t) } }`,
+		`{ me(func: uid(0x1)) { friends @facets(t as sum(weight, close)) total: val(t) } }`,
+	} {
+		_, err := Parse(Request{Str: query})
+		require.Error(t, err, query)
+	}
+}
+
 func TestParseFacetsMultipleRepeat(t *testing.T) {
 	query := `
 	query {
@@ -4200,6 +4429,179 @@ func TestMathWithoutVarAlias(t *testing.T) {
 	require.Contains(t, err.Error(), "Function math should be used with a variable or have an alias")
 }
 
+func TestParsePropagate(t *testing.T) {
+	query := `{
+			f(func: anyofterms(name, "Rick Michonne Andrea")) {
+				ageVar as age @propagate(max)
+				score as math(ageVar * 2) @propagate(AVG)
+				friend {
+					val(score)
+				}
+			}
+		}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Equal(t, "max", res.Query[0].Children[0].Propagate)
+	require.Equal(t, "avg", res.Query[0].Children[1].Propagate)
+	require.NotNil(t, res.Query[0].Children[1].MathExp)
+	require.Equal(t, "friend", res.Query[0].Children[2].Attr)
+
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{`{f(func: uid(1)) { age @propagate(max) }}`,
+			"@propagate can only be used where a value variable is defined"},
+		{`{f(func: uid(1)) { a as age @propagate(median) }}`,
+			"Invalid aggregator median inside @propagate()"},
+		{`{f(func: uid(1)) { a as age @propagate(min) @propagate(max) }}`,
+			"Only one @propagate directive allowed"},
+		{`{f(func: uid(1)) { a as math(1) @propagate(first, max) }}`,
+			"Expected a right round after @propagate(first"},
+	}
+	for _, tc := range tests {
+		_, err := Parse(Request{Str: tc.in})
+		require.Error(t, err, tc.in)
+		require.Contains(t, err.Error(), tc.err)
+	}
+}
+
+func TestParseJoin(t *testing.T) {
+	query := `{
+			var(func: anyofterms(name, "Rick Michonne")) {
+				e as email
+			}
+			f(func: uid(e)) {
+				same_email: join(eq(email, val(e))) @filter(has(name)) (first: 2) {
+					name
+				}
+				join
+			}
+		}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	child := res.Query[1].Children[0]
+	require.Equal(t, "email", child.Attr)
+	require.Equal(t, "same_email", child.Alias)
+	require.NotNil(t, child.Join)
+	require.Equal(t, "eq", child.Join.Name)
+	require.Equal(t, []VarContext{{Name: "e", Typ: ValueVar}}, child.NeedsVar)
+	require.NotNil(t, child.Filter)
+	require.Equal(t, "2", child.Args["first"])
+	require.Equal(t, "name", child.Children[0].Attr)
+	// join is still a predicate when it isn't followed by a function.
+	require.Equal(t, "join", res.Query[1].Children[1].Attr)
+	require.Nil(t, res.Query[1].Children[1].Join)
+
+	query = `{
+			var(func: type(Order)) {
+				c as customer_id
+			}
+			var(func: type(Customer)) {
+				id as id
+			}
+			orders(func: uid(c)) {
+				customer: join(eq(val(id), val(c))) {
+					name
+				}
+			}
+		}`
+	res, err = Parse(Request{Str: query})
+	require.NoError(t, err)
+	child = res.Query[2].Children[0]
+	require.Equal(t, "id", child.Attr)
+	require.True(t, child.Join.IsValueVar)
+	require.Equal(t, []VarContext{{Name: "id", Typ: ValueVar}, {Name: "c", Typ: ValueVar}},
+		child.NeedsVar)
+
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{`{f(func: uid(1)) { join(eq(email, val(e))) }}`,
+			"join() should have an alias, naming the virtual edge"},
+		{`{f(func: uid(1)) { j: count(join(eq(email, val(e)))) }}`,
+			"Count of a join() is not allowed"},
+		{`{f(func: uid(1)) { j: join(eq(email, "a@b.com")) }}`,
+			"join() expects a function eq(predicate, val(variable)) or " +
+				"eq(val(variable), val(variable)), got eq"},
+		{`{f(func: uid(1)) { j: join(eq(val(a), 15)) }}`,
+			"join() expects a function eq(predicate, val(variable)) or"},
+		{`{f(func: uid(1)) @filter(eq(val(b), val(a))) { name }}`,
+			"Multiple functions as arguments not allowed"},
+		{`{f(func: uid(1)) { j: join(ge(age, val(a))) }}`,
+			"join() expects a function eq(predicate, val(variable)) or " +
+				"eq(val(variable), val(variable)), got ge"},
+	}
+	for _, tc := range tests {
+		_, err := Parse(Request{Str: tc.in})
+		require.Error(t, err, tc.in)
+		require.Contains(t, err.Error(), tc.err)
+	}
+}
+
+func TestParseNotExists(t *testing.T) {
+	query := `{
+			b as var(func: type(Book))
+			q(func: not_exists(wrote, uid(b))) @filter(not_exists(friend, [0x1, 0x2])) {
+				name
+			}
+		}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	fn := res.Query[1].Func
+	require.Equal(t, "not_exists", fn.Name)
+	require.Equal(t, "wrote", fn.Attr)
+	require.Equal(t, []Arg{{Value: "b"}}, fn.Args)
+	require.Equal(t, []VarContext{{Name: "b", Typ: UidVar}}, fn.NeedsVar)
+	filter := res.Query[1].Filter.Func
+	require.Equal(t, "not_exists", filter.Name)
+	require.Equal(t, []Arg{{Value: "0x1"}, {Value: "0x2"}}, filter.Args)
+
+	_, err = Parse(Request{Str: `{q(func: uid(1)) @filter(not_exists(friend)) { name }}`})
+	require.ErrorContains(t, err, "not_exists function expects an argument, got none")
+}
+
+func TestParseSimilarityJoin(t *testing.T) {
+	query := `{
+			var(func: has(taste)) {
+				v as taste
+			}
+			c as var(func: type(Product))
+			q(func: uid(v)) {
+				nearest: join(similar_to(embedding, 5, val(v), uid(c))) {
+					name
+				}
+				any: join(similar_to(embedding, 2, val(v)))
+			}
+		}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	child := res.Query[2].Children[0]
+	require.Equal(t, "embedding", child.Attr)
+	require.Equal(t, "nearest", child.Alias)
+	require.Equal(t, "similar_to", child.Join.Name)
+	require.Equal(t, []Arg{{Value: "5"}, {Value: "v", IsValueVar: true}, {Value: "c"}},
+		child.Join.Args)
+	require.Equal(t, []VarContext{{Name: "v", Typ: ValueVar}, {Name: "c", Typ: UidVar}},
+		child.NeedsVar)
+	require.Equal(t, "name", child.Children[0].Attr)
+	child = res.Query[2].Children[1]
+	require.Len(t, child.Join.Args, 2)
+	require.Equal(t, []VarContext{{Name: "v", Typ: ValueVar}}, child.NeedsVar)
+
+	for _, in := range []string{
+		`{f(func: uid(1)) { j: join(similar_to(embedding, 5, "[1, 2]")) }}`,
+		`{f(func: uid(1)) { j: join(similar_to(embedding, 0, val(v))) }}`,
+		`{f(func: uid(1)) { j: join(similar_to(embedding, val(k), val(v))) }}`,
+		`{f(func: uid(1)) { j: join(similar_to(embedding, 5, uid(c))) }}`,
+		`{f(func: uid(1)) { j: join(similar_to(embedding, 5, val(v), val(w))) }}`,
+	} {
+		_, err := Parse(Request{Str: in})
+		require.Error(t, err, in)
+	}
+}
+
 func TestMathDiv0(t *testing.T) {
 	tests := []struct {
 		in       string
@@ -4675,6 +5077,42 @@ func TestOrderByVarAndPred(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestOrderByMultipleVars(t *testing.T) {
+	query := `{
+		var(func: uid(0x0a)) {
+			friends {
+				n as name
+				a as age
+			}
+		}
+
+		q(func: uid(n), orderasc: val(n), orderdesc: val(a)) {
+			name
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Len(t, res.Query[1].Order, 2)
+	require.Equal(t, "n", res.Query[1].Order[0].Attr)
+	require.False(t, res.Query[1].Order[0].Desc)
+	require.Equal(t, "a", res.Query[1].Order[1].Attr)
+	require.True(t, res.Query[1].Order[1].Desc)
+
+	query = `{
+		var(func: uid(0x0a)) {
+			friends {
+				n as name
+			}
+		}
+
+		q(func: uid(n), orderasc: val(n), orderdesc: age) {
+			name
+		}
+	}`
+	_, err = Parse(Request{Str: query})
+	require.ErrorContains(t, err, "Val() is not allowed in multiple sorting. Got: [n]")
+}
+
 func TestInvalidValUsage(t *testing.T) {
 	query := `
 		{
@@ -5568,3 +6006,250 @@ func TestEmptyId(t *testing.T) {
 	_, err := Parse(r)
 	require.Error(t, err, "ID cannot be empty")
 }
+
+func TestParseCorrelatedFilter(t *testing.T) {
+	query := `{
+		q(func: type(Parent), first: 10) @filter(gt(val(newest), 100) and has(name)) {
+			name
+			child (orderdesc: created, first: 1) {
+				t as total
+			}
+			newest as max(val(t))
+			count(child)
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Len(t, res.Query, 2)
+
+	sub := res.Query[0]
+	require.Equal(t, "var", sub.Alias)
+	require.Equal(t, "type", sub.Func.Name)
+	require.Empty(t, sub.Args)
+	require.Nil(t, sub.Filter)
+	require.Len(t, sub.Children, 2)
+	require.Equal(t, "child", sub.Children[0].Attr)
+	require.Equal(t, "1", sub.Children[0].Args["first"])
+	require.Equal(t, "t@sub", sub.Children[0].Children[0].Var)
+	require.Equal(t, "newest@sub", sub.Children[1].Var)
+	require.Equal(t, "t@sub", sub.Children[1].NeedsVar[0].Name)
+	require.Equal(t, []string{"t@sub", "newest@sub"}, res.QueryVars[0].Defines)
+
+	q := res.Query[1]
+	require.Equal(t, "q", q.Alias)
+	require.Equal(t, "newest@sub", q.Filter.Child[0].Func.NeedsVar[0].Name)
+	require.Equal(t, "newest@sub", q.Filter.Child[0].Func.Attr)
+	// newest isn't used anymore, t still is.
+	require.Equal(t, "", q.Children[2].Var)
+	require.Equal(t, "t", q.Children[1].Children[0].Var)
+	require.Equal(t, []string{"newest@sub"}, res.QueryVars[1].Needs[len(res.QueryVars[1].Needs)-1:])
+}
+
+func TestParseFilterVarFromOtherBlock(t *testing.T) {
+	query := `{
+		var(func: type(Parent)) {
+			child { t as total }
+			newest as max(val(t))
+		}
+		q(func: type(Parent)) @filter(gt(val(newest), 100)) {
+			name
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Len(t, res.Query, 2)
+	require.Equal(t, "newest", res.Query[1].Filter.Func.NeedsVar[0].Name)
+}
+
+func TestParseSetOps(t *testing.T) {
+	query := `{
+		a as var(func: eq(name, "Alice")) {
+			friend
+		}
+		bobs(func: eq(name, "Bob")) {
+			name
+		}
+		q(func: union(a, bobs)) @filter(except(a, bobs)) {
+			friend @filter(intersect(a, bobs)) {
+				name
+			}
+		}
+		atLeastTwo(func: union(a, bobs, q, 2)) {
+			uid
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Len(t, res.Query, 4)
+	require.Equal(t, "bobs@block", res.Query[1].Var)
+	require.Equal(t, "q@block", res.Query[2].Var)
+
+	q := res.Query[2]
+	require.Equal(t, "union", q.Func.Name)
+	require.Equal(t, []VarContext{{Name: "a", Typ: UidVar}, {Name: "bobs@block", Typ: UidVar}},
+		q.Func.NeedsVar)
+	require.Equal(t, q.Func.NeedsVar, q.NeedsVar)
+	require.Equal(t, "except", q.Filter.Func.Name)
+	require.Equal(t, "bobs@block", q.Filter.Func.NeedsVar[1].Name)
+	require.Equal(t, "bobs@block", q.Children[0].Filter.Func.NeedsVar[1].Name)
+
+	require.Equal(t, []Arg{{Value: "2"}}, res.Query[3].Func.Args)
+	require.Equal(t, "q@block", res.Query[3].Func.NeedsVar[2].Name)
+}
+
+func TestParseSetOpsErrors(t *testing.T) {
+	tests := map[string]string{
+		`{ q(func: union(a)) { uid } }`:                                  "expects at least two",
+		`{ a as var(func: has(name)) q(func: intersect(a, b)) { uid } }`: "neither a variable",
+		`{ a as var(func: has(name)) q(func: union(a, q)) { uid } }`:     "its own uids",
+		`{ a as var(func: has(name)) b as var(func: has(age))
+			q(func: except(a, b, 1)) { uid } }`: "only takes blocks",
+		`{ a as var(func: has(name)) b as var(func: has(age))
+			q(func: union(a, b, 3)) { uid } }`: "between 1 and 2",
+	}
+	for query, msg := range tests {
+		_, err := Parse(Request{Str: query})
+		require.ErrorContains(t, err, msg, query)
+	}
+}
+
+func TestParseMotif(t *testing.T) {
+	query := `{
+		rings(func: type(Account), first: 10) @motif("(a:Account)-[transfer]->(b)-[transfer]->(c:Account)<-[owes]-(a)") {
+			name
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Equal(t, &Motif{
+		Vars:  []string{"a", "b", "c"},
+		Types: []string{"Account", "", "Account"},
+		Edges: []MotifEdge{
+			{From: 0, To: 1, Attr: "transfer"},
+			{From: 1, To: 2, Attr: "transfer"},
+			{From: 0, To: 2, Attr: "owes"},
+		},
+	}, res.Query[0].Motif)
+}
+
+func TestParseMotifErrors(t *testing.T) {
+	tests := map[string]string{
+		`(a)`:                            "at least one edge",
+		`(a)-[p]->(b), (c)-[p]->(d)`:     "must be connected",
+		`(a:A)-[p]->(a:B)`:               "has two types",
+		`(a)-[p]-(b)`:                    "end of the edge",
+		`(a)-[]->(b)`:                    "expected a predicate",
+		`(1a)-[p]->(b)`:                  "invalid variable",
+		`(a)-[p]->(b) (b)-[p]->(a)`:      "expected a comma",
+		`(a)-[p]->(b`:                    "expected )",
+		`a-[p]->(b)`:                     "expected (",
+		`(a)-[p q]->(b)`:                 "expected a predicate",
+		`(a:)-[p]->(b)`:                  "expected a type",
+		`(a)-[p]->(b), (b)<-[q]-(c)-[r]`: "end of the edge",
+	}
+	for pattern, msg := range tests {
+		_, err := ParseMotif(pattern)
+		require.ErrorContains(t, err, msg, pattern)
+	}
+
+	_, err := Parse(Request{Str: `{
+		q(func: has(name), orderasc: name) @motif("(a)-[p]->(b)") { name }
+	}`})
+	require.ErrorContains(t, err, "can't be used with")
+}
+
+func TestParseValidAt(t *testing.T) {
+	query := `{
+		q(func: uid(1)) @valid_at("2020-01-01") {
+			manages @valid_at("2021-06-01T10:00:00Z") {
+				name
+			}
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Equal(t, "2020-01-01", res.Query[0].ValidAt)
+	require.Equal(t, "2021-06-01T10:00:00Z", res.Query[0].Children[0].ValidAt)
+
+	_, err = Parse(Request{Str: `{ q(func: uid(1)) @valid_at("yesterday") { name } }`})
+	require.ErrorContains(t, err, "Invalid datetime")
+	_, err = Parse(Request{Str: `{ q(func: uid(1)) @valid_at(2020) { name } }`})
+	require.ErrorContains(t, err, "Expected a quoted datetime")
+}
+
+func TestParseStoredFunctions(t *testing.T) {
+	active, err := ParseStoredFunction("activeUsers", `query activeUsers($since: string, $min: int = 3) {
+		q(func: type(User)) @filter(ge(lastSeen, $since) AND ge(logins, $min))
+	}`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"$since", "$min"}, active.Params)
+	funcs := map[string]*StoredFunction{"activeUsers": active}
+	require.NoError(t, active.Validate(funcs))
+	admins, err := ParseStoredFunction("activeAdmins", `query activeAdmins($since: string) {
+		q(func: view(activeUsers, $since)) @filter(eq(role, "admin"))
+	}`)
+	require.NoError(t, err)
+	require.NoError(t, admins.Validate(funcs))
+	funcs["activeAdmins"] = admins
+
+	res, err := Parse(Request{Str: `query q($s: string = "2024-01-01") {
+		q(func: view(activeUsers, $s)) @filter(has(name)) {
+			name
+			friend @filter(view(activeUsers, "2025-01-01", 10)) { name }
+		}
+	}`, Functions: funcs})
+	require.NoError(t, err)
+	q := res.Query[0]
+	require.Equal(t, "type", q.Func.Name)
+	require.Equal(t, "and", q.Filter.Op)
+	require.Equal(t, "and", q.Filter.Child[0].Op)
+	require.Equal(t, "2024-01-01", q.Filter.Child[0].Child[0].Func.Args[0].Value)
+	require.Equal(t, "3", q.Filter.Child[0].Child[1].Func.Args[0].Value)
+	require.Equal(t, "has", q.Filter.Child[1].Func.Name)
+
+	friend := q.Children[1].Filter
+	require.Equal(t, "and", friend.Op)
+	require.Equal(t, "type", friend.Child[0].Func.Name)
+	require.Equal(t, "2025-01-01", friend.Child[1].Child[0].Func.Args[0].Value)
+	require.Equal(t, "10", friend.Child[1].Child[1].Func.Args[0].Value)
+
+	res, err = Parse(Request{Str: `{ q(func: view(activeAdmins, "2024")) { name } }`,
+		Functions: funcs})
+	require.NoError(t, err)
+	require.Equal(t, "type", res.Query[0].Func.Name)
+	require.Equal(t, "eq", res.Query[0].Filter.Child[1].Func.Name)
+}
+
+func TestParseStoredFunctionsErrors(t *testing.T) {
+	_, err := ParseStoredFunction("f", `{ q(func: has(name) { name } }`)
+	require.ErrorContains(t, err, "while parsing the stored function f")
+
+	invalid := map[string]string{
+		`{ a(func: has(name)) { name } b(func: has(age)) { age } }`: "should have a single block",
+		`{ q(func: view(g)) { name } }`:                             "Stored function g isn't defined",
+		`{ q(func: view(f)) { name } }`:                             "can be nested at most 8 times",
+		`query f($a: int) { q(func: eq(name, $b)) }`:                "Variable not defined $b",
+	}
+	for query, msg := range invalid {
+		fn, err := ParseStoredFunction("f", query)
+		require.NoError(t, err)
+		require.ErrorContains(t, fn.Validate(map[string]*StoredFunction{"f": fn}), msg, query)
+	}
+
+	f, err := ParseStoredFunction("f", `query f($a: string) { q(func: eq(name, $a)) }`)
+	require.NoError(t, err)
+	loop, err := ParseStoredFunction("loop", `{ q(func: view(loop)) }`)
+	require.NoError(t, err)
+	funcs := map[string]*StoredFunction{"f": f, "loop": loop}
+
+	tests := map[string]string{
+		`{ q(func: view(f, "a", "b")) { name } }`:          "takes at most 1 arguments, got 2",
+		`{ q(func: view(g)) { name } }`:                    "Stored function g isn't defined",
+		`{ q(func: view(loop)) { name } }`:                 "can be nested at most 8 times",
+		`{ q(func: has(name)) @filter(view(g)) { name } }`: "Stored function g isn't defined",
+	}
+	for query, msg := range tests {
+		_, err := Parse(Request{Str: query, Functions: funcs})
+		require.ErrorContains(t, err, msg, query)
+	}
+}
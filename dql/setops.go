@@ -0,0 +1,127 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dql
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// blockVarSuffix is appended to the name of a block to get the name of the variable holding its
+// uids, when a set operation names a block which doesn't define a variable itself.
+const blockVarSuffix = "@block"
+
+// IsSetOpFunc returns true if the function combines the uids of several blocks or variables:
+// union(a, b), intersect(a, b) or except(a, b).
+func IsSetOpFunc(name string) bool {
+	switch name {
+	case unionFunc, intersectFunc, exceptFunc:
+		return true
+	}
+	return false
+}
+
+// validateSetOpFunc checks the arguments of a set operation. They are at least two variables or
+// names of blocks. The only other argument is the one of union(a, b, c, 2), which keeps the uids
+// found in at least two of a, b and c, instead of one.
+func validateSetOpFunc(fn *Function) error {
+	if len(fn.NeedsVar) < 2 {
+		return errors.Errorf("%s function expects at least two blocks or variables, got %d",
+			fn.Name, len(fn.NeedsVar))
+	}
+	if len(fn.Args) == 0 {
+		return nil
+	}
+	if fn.Name != unionFunc || len(fn.Args) > 1 {
+		return errors.Errorf("%s function only takes blocks or variables, got %s", fn.Name,
+			fn.Args[0].Value)
+	}
+	n, err := strconv.ParseUint(fn.Args[0].Value, 0, 32)
+	if err != nil {
+		return errors.Wrapf(err, "while parsing the minimum count of union")
+	}
+	if n == 0 || int(n) > len(fn.NeedsVar) {
+		return errors.Errorf("union of %d blocks expects a minimum count between 1 and %d, got %d",
+			len(fn.NeedsVar), len(fn.NeedsVar), n)
+	}
+	return nil
+}
+
+// resolveSetOps resolves the arguments of the set operations which aren't variables into the
+// names of the blocks they combine. Such a block is given a variable holding its uids, unless it
+// already defines one.
+func resolveSetOps(queries []*GraphQuery) error {
+	v := &Vars{}
+	for _, gq := range queries {
+		gq.collectVars(v)
+	}
+	defined := make(map[string]bool, len(v.Defines))
+	for _, name := range v.Defines {
+		defined[name] = true
+	}
+	blocks := make(map[string]*GraphQuery)
+	for _, gq := range queries {
+		if gq.Alias != "var" && gq.Alias != "shortest" {
+			blocks[gq.Alias] = gq
+		}
+	}
+
+	for _, gq := range queries {
+		names := make(map[string]string)
+		var err error
+		resolve := func(fn *Function) {
+			if err != nil || fn == nil || !IsSetOpFunc(fn.Name) {
+				return
+			}
+			for i, va := range fn.NeedsVar {
+				if defined[va.Name] {
+					continue
+				}
+				block, ok := blocks[va.Name]
+				switch {
+				case !ok:
+					err = errors.Errorf("%s of %s is neither a variable nor the name of a block",
+						va.Name, fn.Name)
+					return
+				case block == gq:
+					err = errors.Errorf("Block %s can't combine its own uids with %s", gq.Alias,
+						fn.Name)
+					return
+				case block.Var == "":
+					block.Var = va.Name + blockVarSuffix
+					defined[block.Var] = true
+				}
+				names[va.Name] = block.Var
+				fn.NeedsVar[i].Name = block.Var
+			}
+		}
+
+		resolve(gq.Func)
+		gq.walkFilterFuncs(resolve)
+		if err != nil {
+			return err
+		}
+		if gq.Func == nil || !IsSetOpFunc(gq.Func.Name) {
+			continue
+		}
+		// The variables of the root function are needed by the block too.
+		for i, va := range gq.NeedsVar {
+			if name, ok := names[va.Name]; ok {
+				gq.NeedsVar[i].Name = name
+			}
+		}
+	}
+	return nil
+}
+
+// walkFilterFuncs calls fn for every function of the filters of the subtree of gq.
+func (gq *GraphQuery) walkFilterFuncs(fn func(*Function)) {
+	gq.Filter.walkFuncs(fn)
+	for _, child := range gq.Children {
+		child.walkFilterFuncs(fn)
+	}
+}
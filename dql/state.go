@@ -7,6 +7,8 @@
 package dql
 
 import (
+	"strings"
+
 	"github.com/hypermodeinc/dgraph/v25/lex"
 )
 
@@ -223,6 +225,16 @@ func lexInsideSchema(l *lex.Lexer) lex.StateFn {
 	}
 }
 
+// atArgStart returns true if the rune just read is the first one of the first argument, right
+// after the left round bracket.
+func atArgStart(l *lex.Lexer) bool {
+	// The rune just read is the slash, which is a single byte.
+	prev := strings.TrimRightFunc(l.Input[:l.Pos-1], func(r rune) bool {
+		return isSpace(r) || lex.IsEndOfLine(r)
+	})
+	return strings.HasSuffix(prev, string(leftRound))
+}
+
 func lexFuncOrArg(l *lex.Lexer) lex.StateFn {
 	l.Mode = lexFuncOrArg
 	var empty bool
@@ -235,7 +247,7 @@ func lexFuncOrArg(l *lex.Lexer) lex.StateFn {
 			return lexArgName
 		case r == slash:
 			// if argument starts with '/' it's a regex, otherwise it's a division
-			if empty {
+			if empty || atArgStart(l) {
 				return lexRegex(l)
 			}
 			fallthrough
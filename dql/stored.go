@@ -0,0 +1,188 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dql
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/lex"
+)
+
+const (
+	// storedFunc calls a stored function of the namespace, e.g. view(activeUsers, "2024-01-01").
+	storedFunc = "view"
+	// maxStoredFuncDepth is the maximum depth of the stored functions calling each other.
+	maxStoredFuncDepth = 8
+)
+
+// StoredFunction is a named query of a namespace, whose root function and filter are expanded
+// wherever the queries call view(<name>, <arguments>...), either as root function or in a
+// filter. It is given like
+//
+//	query activeUsers($since: string) {
+//		q(func: type(User)) @filter(ge(lastSeen, $since))
+//	}
+//
+// The arguments of the call are bound to the variables of the query in the order they are
+// declared. The variables without argument take their default value.
+type StoredFunction struct {
+	Name   string
+	Query  string
+	Params []string
+}
+
+// ParseStoredFunction parses the declaration of the variables of the stored function. Its query
+// is checked by Validate.
+func ParseStoredFunction(name, query string) (*StoredFunction, error) {
+	var lexer lex.Lexer
+	lexer.Reset(query)
+	lexer.Run(lexTopLevel)
+	if err := lexer.ValidateResult(); err != nil {
+		return nil, errors.Wrapf(err, "while parsing the stored function %s", name)
+	}
+	fn := &StoredFunction{Name: name, Query: query}
+	// The variables are declared before the first curly brace.
+	it := lexer.NewIterator()
+	for it.Next() && it.Item().Typ != itemLeftCurl {
+		if it.Item().Typ != itemDollar {
+			continue
+		}
+		if items, err := it.Peek(1); err == nil && items[0].Typ == itemName {
+			fn.Params = append(fn.Params, "$"+items[0].Val)
+		}
+	}
+	return fn, nil
+}
+
+// Validate checks the query of the stored function, which can call the stored functions funcs. It
+// should have a single block with a root function, and optionally a filter. Its other arguments
+// and children are ignored.
+func (fn *StoredFunction) Validate(funcs map[string]*StoredFunction) error {
+	// Any value of the right type works to check the query, 0 is valid for all the types.
+	args := make([]Arg, len(fn.Params))
+	for i := range args {
+		args[i].Value = "0"
+	}
+	_, err := fn.block(args, funcs, 0)
+	return errors.Wrapf(err, "while parsing the stored function %s", fn.Name)
+}
+
+// block returns the block of the query of the stored function, called with the arguments.
+func (fn *StoredFunction) block(args []Arg, funcs map[string]*StoredFunction,
+	depth int) (*GraphQuery, error) {
+
+	if len(args) > len(fn.Params) {
+		return nil, errors.Errorf("Stored function %s takes at most %d arguments, got %d",
+			fn.Name, len(fn.Params), len(args))
+	}
+	vars := make(map[string]string, len(args))
+	for i, arg := range args {
+		if arg.IsValueVar {
+			return nil, errors.Errorf("Stored function %s only takes values as arguments",
+				fn.Name)
+		}
+		vars[fn.Params[i]] = arg.Value
+	}
+	res, err := parse(Request{Str: fn.Query, Variables: vars, Functions: funcs}, nil, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Query) != 1 {
+		return nil, errors.Errorf("Stored function %s should have a single block, got %d",
+			fn.Name, len(res.Query))
+	}
+	gq := res.Query[0]
+	if gq.Func == nil && len(gq.UID) == 0 {
+		return nil, errors.Errorf("Stored function %s should have a root function", fn.Name)
+	}
+	return gq, nil
+}
+
+// expandStoredFuncs replaces the calls to the stored functions, in the root functions and
+// filters of the queries, by the root function and filter of the stored function.
+func expandStoredFuncs(queries []*GraphQuery, funcs map[string]*StoredFunction,
+	depth int) error {
+
+	var expand func(gq *GraphQuery) error
+	expand = func(gq *GraphQuery) error {
+		if gq.Func != nil && gq.Func.Name == storedFunc {
+			stored, err := callStoredFunc(gq.Func, funcs, depth)
+			if err != nil {
+				return err
+			}
+			gq.Func = stored.Func
+			gq.UID = append(gq.UID, stored.UID...)
+			switch {
+			case stored.Filter == nil:
+			case gq.Filter == nil:
+				gq.Filter = stored.Filter
+			default:
+				gq.Filter = &FilterTree{Op: "and", Child: []*FilterTree{stored.Filter, gq.Filter}}
+			}
+		}
+		if err := expandStoredFilter(gq.Filter, funcs, depth); err != nil {
+			return err
+		}
+		for _, child := range gq.Children {
+			if err := expand(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, gq := range queries {
+		if err := expand(gq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expandStoredFilter(f *FilterTree, funcs map[string]*StoredFunction, depth int) error {
+	if f == nil {
+		return nil
+	}
+	if f.Func != nil && f.Func.Name == storedFunc {
+		stored, err := callStoredFunc(f.Func, funcs, depth)
+		if err != nil {
+			return err
+		}
+		if len(stored.UID) > 0 {
+			return errors.Errorf("Stored function %s can't be used in a filter, its root "+
+				"function is a list of uids", f.Func.Attr)
+		}
+		f.Func = stored.Func
+		if stored.Filter != nil {
+			f.Op = "and"
+			f.Child = []*FilterTree{{Func: f.Func}, stored.Filter}
+			f.Func = nil
+		}
+		return nil
+	}
+	for _, child := range f.Child {
+		if err := expandStoredFilter(child, funcs, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func callStoredFunc(call *Function, funcs map[string]*StoredFunction,
+	depth int) (*GraphQuery, error) {
+
+	if depth >= maxStoredFuncDepth {
+		return nil, errors.Errorf("Stored functions can be nested at most %d times",
+			maxStoredFuncDepth)
+	}
+	if call.Attr == "" {
+		return nil, errors.Errorf("%s function expects the name of a stored function", storedFunc)
+	}
+	fn, ok := funcs[call.Attr]
+	if !ok {
+		return nil, errors.Errorf("Stored function %s isn't defined", call.Attr)
+	}
+	return fn.block(call.Args, funcs, depth)
+}
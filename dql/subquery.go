@@ -0,0 +1,268 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package dql
+
+// subquerySuffix is appended to the names of the variables defined by a correlated subquery once
+// it's compiled into a var block, so that they don't clash with the ones of the original block.
+// It can't appear in the name of a variable given by the user.
+const subquerySuffix = "@sub"
+
+// compileSubqueries compiles the correlated subqueries of the filters at the root of the query
+// blocks into variable passes. A filter is correlated when it reads a value variable defined in
+// the body of its own block, like
+//
+//	q(func: type(Parent)) @filter(gt(val(newest), 100)) {
+//	  name
+//	  child (orderdesc: created, first: 1) { t as total }
+//	  newest as max(val(t))
+//	}
+//
+// The children defining the variable are evaluated for every candidate uid of the block, before
+// the filter is applied. They are copied into a var block taking the same root function, which
+// runs first and defines the variable under another name that the filter reads instead. The
+// definitions of the original block which aren't used anymore are dropped.
+func compileSubqueries(queries []*GraphQuery, needVars []string) []*GraphQuery {
+	var out []*GraphQuery
+	var compiled []*GraphQuery
+	for _, gq := range queries {
+		if sub := gq.correlatedSubquery(); sub != nil {
+			out = append(out, sub)
+			compiled = append(compiled, gq)
+		}
+		out = append(out, gq)
+	}
+	if len(compiled) == 0 {
+		return queries
+	}
+
+	v := &Vars{Needs: needVars}
+	for _, gq := range out {
+		gq.collectVars(v)
+	}
+	needed := make(map[string]bool, len(v.Needs))
+	for _, name := range v.Needs {
+		needed[name] = true
+	}
+	for _, gq := range compiled {
+		for _, child := range gq.Children {
+			child.dropUnneededVars(needed)
+		}
+	}
+	return out
+}
+
+// correlatedSubquery returns the var block evaluating the correlated subquery of the filter of
+// the block, or nil if the filter doesn't read any value variable defined in the block.
+func (gq *GraphQuery) correlatedSubquery() *GraphQuery {
+	if gq.Filter == nil || gq.IsEmpty || gq.Recurse || gq.IsGroupby ||
+		gq.Alias == "shortest" || (gq.Func == nil && len(gq.UID) == 0) {
+		return nil
+	}
+
+	defines := make([]*Vars, len(gq.Children))
+	for i, child := range gq.Children {
+		defines[i] = &Vars{}
+		child.collectVars(defines[i])
+	}
+	definedBy := func(name string) int {
+		for i, v := range defines {
+			for _, d := range v.Defines {
+				if d == name {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+
+	// Start with the value variables read by the filter, and add the variables needed to define
+	// them until all the children they're defined in are known.
+	var pending []string
+	gq.Filter.walkFuncs(func(fn *Function) {
+		for _, va := range fn.NeedsVar {
+			if va.Typ == ValueVar {
+				pending = append(pending, va.Name)
+			}
+		}
+	})
+	lifted := make(map[int]bool)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		i := definedBy(name)
+		if i < 0 || lifted[i] {
+			continue
+		}
+		lifted[i] = true
+		pending = append(pending, defines[i].Needs...)
+	}
+	if len(lifted) == 0 {
+		return nil
+	}
+
+	names := make(map[string]string)
+	for i := range lifted {
+		for _, d := range defines[i].Defines {
+			names[d] = d + subquerySuffix
+		}
+	}
+	sub := &GraphQuery{
+		Alias:    "var",
+		UID:      gq.UID,
+		Func:     gq.Func,
+		NeedsVar: gq.NeedsVar,
+		Langs:    gq.Langs,
+	}
+	for i, child := range gq.Children {
+		if lifted[i] {
+			sub.Children = append(sub.Children, child.renameVars(names))
+		}
+	}
+	gq.Filter.walkFuncs(func(fn *Function) {
+		fn.renameVars(names)
+	})
+	return sub
+}
+
+// dropUnneededVars removes the definitions of the variables which aren't needed by the query
+// anymore, keeping the fields defining them.
+func (gq *GraphQuery) dropUnneededVars(needed map[string]bool) {
+	if gq.Var != "" && !needed[gq.Var] {
+		gq.Var = ""
+	}
+	for _, child := range gq.Children {
+		child.dropUnneededVars(needed)
+	}
+}
+
+// walkFuncs calls fn for every function of the filter tree.
+func (f *FilterTree) walkFuncs(fn func(*Function)) {
+	if f == nil {
+		return
+	}
+	if f.Func != nil {
+		fn(f.Func)
+	}
+	for _, ch := range f.Child {
+		ch.walkFuncs(fn)
+	}
+}
+
+// renameVars returns a copy of the subtree of gq, where the variables in names are renamed.
+func (gq *GraphQuery) renameVars(names map[string]string) *GraphQuery {
+	c := *gq
+	if name, ok := names[gq.Var]; ok {
+		c.Var = name
+	}
+	c.NeedsVar = renameVarContexts(gq.NeedsVar, names)
+	if gq.Func != nil {
+		fn := gq.Func.copy()
+		fn.renameVars(names)
+		c.Func = fn
+	}
+	if gq.Join != nil {
+		fn := gq.Join.copy()
+		fn.renameVars(names)
+		c.Join = fn
+	}
+	c.Filter = gq.Filter.renameVars(names)
+	c.MathExp = gq.MathExp.renameVars(names)
+	if gq.Expand != "" {
+		if name, ok := names[gq.Expand]; ok {
+			c.Expand = name
+		}
+	}
+	if gq.FacetVar != nil {
+		c.FacetVar = make(map[string]string, len(gq.FacetVar))
+		for k, va := range gq.FacetVar {
+			if name, ok := names[va]; ok {
+				va = name
+			}
+			c.FacetVar[k] = va
+		}
+	}
+	if gq.FacetAggVars != nil {
+		c.FacetAggVars = make([]FacetAggVar, len(gq.FacetAggVars))
+		for i, va := range gq.FacetAggVars {
+			if name, ok := names[va.Var]; ok {
+				va.Var = name
+			}
+			c.FacetAggVars[i] = va
+		}
+	}
+	c.Children = make([]*GraphQuery, 0, len(gq.Children))
+	for _, child := range gq.Children {
+		c.Children = append(c.Children, child.renameVars(names))
+	}
+	return &c
+}
+
+// copy returns a copy of the function, which doesn't share its arguments and variables.
+func (fn *Function) copy() *Function {
+	c := *fn
+	c.Args = append([]Arg(nil), fn.Args...)
+	c.NeedsVar = append([]VarContext(nil), fn.NeedsVar...)
+	return &c
+}
+
+// renameVars renames the variables in names read by the function, in place.
+func (fn *Function) renameVars(names map[string]string) {
+	if name, ok := names[fn.Attr]; ok && (fn.IsValueVar || fn.IsLenVar) {
+		fn.Attr = name
+	}
+	for i, arg := range fn.Args {
+		if name, ok := names[arg.Value]; ok && arg.IsValueVar {
+			fn.Args[i].Value = name
+		}
+	}
+	fn.NeedsVar = renameVarContexts(fn.NeedsVar, names)
+}
+
+// renameVars returns a copy of the filter tree, where the variables in names are renamed.
+func (f *FilterTree) renameVars(names map[string]string) *FilterTree {
+	if f == nil {
+		return nil
+	}
+	c := &FilterTree{Op: f.Op}
+	if f.Func != nil {
+		c.Func = f.Func.copy()
+		c.Func.renameVars(names)
+	}
+	for _, ch := range f.Child {
+		c.Child = append(c.Child, ch.renameVars(names))
+	}
+	return c
+}
+
+// renameVars returns a copy of the math tree, where the variables in names are renamed.
+func (f *MathTree) renameVars(names map[string]string) *MathTree {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	if name, ok := names[f.Var]; ok {
+		c.Var = name
+	}
+	c.Child = make([]*MathTree, 0, len(f.Child))
+	for _, ch := range f.Child {
+		c.Child = append(c.Child, ch.renameVars(names))
+	}
+	return &c
+}
+
+func renameVarContexts(vars []VarContext, names map[string]string) []VarContext {
+	if vars == nil {
+		return nil
+	}
+	out := make([]VarContext, len(vars))
+	for i, va := range vars {
+		if name, ok := names[va.Name]; ok {
+			va.Name = name
+		}
+		out[i] = va
+	}
+	return out
+}
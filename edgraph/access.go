@@ -140,6 +140,20 @@ func (s *Server) authenticateLogin(ctx context.Context, request *api.LoginReques
 	// to the user, so the login request should contain the namespace, which is then set to ctx.
 	ctx = x.AttachNamespace(ctx, request.Namespace)
 
+	// The users of the LDAP directory log in with their LDAP password, except groot.
+	if ldap := worker.Config.Ldap; ldap != nil && ldap.Namespace == request.Namespace &&
+		request.Userid != x.GrootId {
+		user, found, err := ldapLogin(ctx, request.Userid, request.Password)
+		if found {
+			if err != nil {
+				return nil, errors.Wrapf(err, "while authenticating user %s with LDAP",
+					request.Userid)
+			}
+			user.Namespace = request.Namespace
+			return user, nil
+		}
+	}
+
 	// authorize the user using password
 	var err error
 	user, err = authorizeUser(ctx, request.Userid, request.Password)
@@ -162,6 +176,10 @@ type userData struct {
 	namespace uint64
 	userId    string
 	groupIds  []string
+	// apiKey is the ID of the API key the jwt was given for, along with the fingerprint of its
+	// secret, if the jwt was given for one.
+	apiKey            string
+	apiKeyFingerprint string
 }
 
 // validateToken verifies the signature and expiration of the jwt, and if validation passes,
@@ -214,7 +232,15 @@ func validateToken(jwtStr string) (*userData, error) {
 			groupIds = append(groupIds, groupId)
 		}
 	}
-	return &userData{namespace: uint64(namespace), userId: userId, groupIds: groupIds}, nil
+	apiKey, _ := claims["apikey"].(string)
+	apiKeyFingerprint, _ := claims["apikey_fp"].(string)
+	return &userData{
+		namespace:         uint64(namespace),
+		userId:            userId,
+		groupIds:          groupIds,
+		apiKey:            apiKey,
+		apiKeyFingerprint: apiKeyFingerprint,
+	}, nil
 }
 
 // validateLoginRequest validates that the login request has either the refresh token or the
@@ -386,6 +412,16 @@ func SubscribeForAclUpdates(closer *z.Closer) {
 		}
 	}, 1, closer)
 
+	// The API keys are cached along with the ACLs, and dropped whenever they change.
+	closer.AddRunning(1)
+	go worker.SubscribeForUpdates(apiKeyPrefixes, x.IgnoreBytes, func(kvs *bpb.KVList) {
+		if kvs == nil || len(kvs.Kv) == 0 {
+			return
+		}
+		glog.V(3).Infof("Got API key update via subscription")
+		apiKeys.reset()
+	}, 1, closer)
+
 	<-closer.HasBeenClosed()
 }
 
@@ -620,6 +656,9 @@ type authPredResult struct {
 func authorizePreds(ctx context.Context, userData *userData, preds []string,
 	aclOp *acl.Operation) *authPredResult {
 
+	if userData.apiKey != "" {
+		return authorizeApiKeyPreds(ctx, userData, preds, aclOp)
+	}
 	if !worker.AclCachePtr.Loaded() {
 		RefreshACLs(ctx)
 	}
@@ -673,6 +712,8 @@ func authorizeAlter(ctx context.Context, op *api.Operation) error {
 		preds = []string{op.DropAttr}
 	case op.DropOp == api.Operation_ATTR && len(op.DropValue) > 0:
 		preds = []string{op.DropValue}
+	case parseRename(op.Schema) != nil:
+		preds = parseRename(op.Schema).preds()
 	default:
 		update, err := schema.Parse(op.Schema)
 		if err != nil {
@@ -737,6 +778,34 @@ func authorizeAlter(ctx context.Context, op *api.Operation) error {
 	return err
 }
 
+// authorizeDropTypeData authorizes the deletion of the nodes of a type, which deletes the given
+// predicates from the nodes, so that it needs the permission to write all of them.
+func authorizeDropTypeData(ctx context.Context, preds []string) error {
+	if worker.Config.AclSecretKey == nil {
+		// the user has not turned on the acl feature
+		return nil
+	}
+
+	userData, err := extractUserAndGroups(ctx)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if x.IsSuperAdmin(userData.groupIds) {
+		return nil
+	}
+	result := authorizePreds(ctx, userData, preds, acl.Write)
+	if len(result.blocked) > 0 {
+		var msg strings.Builder
+		for key := range result.blocked {
+			x.Check2(msg.WriteString(key))
+			x.Check2(msg.WriteString(" "))
+		}
+		return status.Errorf(codes.PermissionDenied,
+			"unauthorized to delete the data of following predicates: %s\n", msg.String())
+	}
+	return nil
+}
+
 // parsePredsFromMutation returns a union set of all the predicate names in the input nquads
 func parsePredsFromMutation(nquads []*api.NQuad) []string {
 	// use a map to dedup predicates
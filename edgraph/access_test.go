@@ -35,9 +35,9 @@ func generateJWT(namespace uint64, userId string, groupIds []string, expiry int6
 func TestValidateToken(t *testing.T) {
 	expiry := time.Now().Add(time.Minute * 30).Unix()
 	userDataList := []userData{
-		{1234567890, "user1", []string{"701", "702"}},
-		{2345678901, "user2", []string{"703", "701"}},
-		{3456789012, "user3", []string{"702", "703"}},
+		{namespace: 1234567890, userId: "user1", groupIds: []string{"701", "702"}},
+		{namespace: 2345678901, userId: "user2", groupIds: []string{"703", "701"}},
+		{namespace: 3456789012, userId: "user3", groupIds: []string{"702", "703"}},
 	}
 
 	for _, userdata := range userDataList {
@@ -74,9 +74,9 @@ func TestGetAccessJwt(t *testing.T) {
 
 	g := acl.GetGroupIDs(grpLst)
 	userDataList := []userData{
-		{1234567890, "user1", []string{"701", "702"}},
-		{2345678901, "user2", []string{"703", "701"}},
-		{3456789012, "user3", []string{"702", "703"}},
+		{namespace: 1234567890, userId: "user1", groupIds: []string{"701", "702"}},
+		{namespace: 2345678901, userId: "user2", groupIds: []string{"703", "701"}},
+		{namespace: 3456789012, userId: "user3", groupIds: []string{"702", "703"}},
 	}
 
 	for _, userdata := range userDataList {
@@ -92,9 +92,9 @@ func TestGetAccessJwt(t *testing.T) {
 
 func TestGetRefreshJwt(t *testing.T) {
 	userDataList := []userData{
-		{1234567890, "user1", []string{"701", "702"}},
-		{2345678901, "user2", []string{"703", "701"}},
-		{3456789012, "user3", []string{"702", "703"}},
+		{namespace: 1234567890, userId: "user1", groupIds: []string{"701", "702"}},
+		{namespace: 2345678901, userId: "user2", groupIds: []string{"703", "701"}},
+		{namespace: 3456789012, userId: "user3", groupIds: []string{"702", "703"}},
 	}
 
 	for _, userdata := range userDataList {
@@ -0,0 +1,653 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/acl"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// API keys let services access Dgraph without logging in as an ACL user. Each key is scoped to
+// some namespaces, an ACL permission and optionally some predicates. The requests carrying a key
+// are given an access JWT for the key, so that they are authorized like any other request, with
+// the scope of the key checked in place of the ACL rules.
+
+const (
+	// apiKeyMetadata and apiKeyHeader carry the API key of gRPC and HTTP requests.
+	apiKeyMetadata = "apikey"
+	apiKeyHeader   = "X-Dgraph-ApiKey"
+	// apiKeyNamespaceMetadata and apiKeyNamespaceHeader carry the namespace to access with the
+	// API key, which is only needed if the key can access more than one namespace.
+	apiKeyNamespaceMetadata = "apikey-namespace"
+	apiKeyNamespaceHeader   = "X-Dgraph-ApiKey-Namespace"
+
+	// API keys look like dg_<id>_<secret>, where the id is hex encoded and the secret is base64
+	// encoded. The secret may contain underscores.
+	apiKeyPrefix = "dg_"
+	apiKeyIdLen  = 16
+
+	// apiKeyJwtTtl is how long the access JWTs of the API keys are valid for.
+	apiKeyJwtTtl = 5 * time.Minute
+	// maxCachedApiKeys is the number of keys cached, including the IDs that don't exist, before
+	// the cache is cleared.
+	maxCachedApiKeys = 10000
+)
+
+var errInvalidApiKey = errors.New("invalid API key")
+
+// ApiKey is an API key along with its scope.
+type ApiKey struct {
+	Id         string
+	Name       string
+	Namespaces []uint64
+	// Permission is a combination of the ACL permissions, as in the ACL rules.
+	Permission int32
+	// Predicates are the predicates that can be accessed with the key. All the predicates apart
+	// from the reserved ones can be accessed if there are none.
+	Predicates []string
+	// ExpiresAt is zero if the key doesn't expire.
+	ExpiresAt time.Time
+}
+
+// apiKeyNode is an API key as stored in the root namespace. Only the hash of the secret is
+// stored, along with the hash of the previous secret while it's still valid after a rotation.
+type apiKeyNode struct {
+	Uid        string   `json:"uid"`
+	Id         string   `json:"dgraph.apikey.id"`
+	Name       string   `json:"dgraph.apikey.name"`
+	Hash       string   `json:"dgraph.apikey.hash"`
+	PrevHash   string   `json:"dgraph.apikey.prev_hash"`
+	PrevExpiry int64    `json:"dgraph.apikey.prev_expiry"`
+	Expiry     int64    `json:"dgraph.apikey.expiry"`
+	Namespaces []uint64 `json:"dgraph.apikey.namespaces"`
+	Permission int32    `json:"dgraph.apikey.permission"`
+	Predicates []string `json:"dgraph.apikey.predicates"`
+}
+
+const apiKeyFields = `
+			uid
+			dgraph.apikey.id
+			dgraph.apikey.name
+			dgraph.apikey.hash
+			dgraph.apikey.prev_hash
+			dgraph.apikey.prev_expiry
+			dgraph.apikey.expiry
+			dgraph.apikey.namespaces
+			dgraph.apikey.permission
+			dgraph.apikey.predicates`
+
+func (k *apiKeyNode) apiKey() *ApiKey {
+	key := &ApiKey{
+		Id:         k.Id,
+		Name:       k.Name,
+		Namespaces: k.Namespaces,
+		Permission: k.Permission,
+		Predicates: k.Predicates,
+	}
+	if k.Expiry > 0 {
+		key.ExpiresAt = time.Unix(k.Expiry, 0)
+	}
+	return key
+}
+
+// validUntil returns the time until which the secret with the hash is valid, or the zero time if
+// it's invalid. The previous secret is valid until the end of the grace period of the rotation.
+func (k *apiKeyNode) validUntil(matches func(hash string) bool, now time.Time) time.Time {
+	var until time.Time
+	switch {
+	case k.Expiry > 0 && k.Expiry <= now.Unix():
+		return time.Time{}
+	case matches(k.Hash):
+		until = now.Add(apiKeyJwtTtl)
+	case k.PrevHash != "" && now.Unix() < k.PrevExpiry && matches(k.PrevHash):
+		until = time.Unix(k.PrevExpiry, 0)
+	default:
+		return time.Time{}
+	}
+	if exp := time.Unix(k.Expiry, 0); k.Expiry > 0 && exp.Before(until) {
+		until = exp
+	}
+	return until
+}
+
+// verify returns the time until which the secret is valid, or the zero time if it's invalid.
+func (k *apiKeyNode) verify(secret string, now time.Time) time.Time {
+	hash := []byte(hashApiKeySecret(secret))
+	return k.validUntil(func(h string) bool {
+		return subtle.ConstantTimeCompare(hash, []byte(h)) == 1
+	}, now)
+}
+
+// canAccess returns true if the secret with the fingerprint is still valid, and the key has the
+// permission for the operation in the namespace.
+func (k *apiKeyNode) canAccess(ns uint64, op *acl.Operation, fingerprint string,
+	now time.Time) bool {
+
+	valid := k.validUntil(func(h string) bool {
+		return apiKeyFingerprint(h) == fingerprint
+	}, now)
+	return !valid.IsZero() && k.Permission&op.Code != 0 && slices.Contains(k.Namespaces, ns)
+}
+
+// canAccessPredicate returns true if the predicate is in the scope of the key. The reserved
+// predicates can't be accessed, apart from dgraph.type.
+func (k *apiKeyNode) canAccessPredicate(pred string) bool {
+	pred = strings.TrimPrefix(pred, "~")
+	if x.IsReservedPredicate(x.AttrInRootNamespace(pred)) {
+		return pred == "dgraph.type"
+	}
+	return len(k.Predicates) == 0 || slices.Contains(k.Predicates, pred)
+}
+
+// apiKeyCache caches the API keys by their ID, and the access JWTs given for them.
+type apiKeyCache struct {
+	sync.RWMutex
+	// keys is nil for the IDs that don't exist.
+	keys map[string]*apiKeyNode
+	// jwts are by the hash of the secret and the namespace.
+	jwts map[string]apiKeyJwt
+}
+
+type apiKeyJwt struct {
+	jwt string
+	exp time.Time
+}
+
+var apiKeys = &apiKeyCache{
+	keys: make(map[string]*apiKeyNode),
+	jwts: make(map[string]apiKeyJwt),
+}
+
+func (c *apiKeyCache) reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.keys = make(map[string]*apiKeyNode)
+	c.jwts = make(map[string]apiKeyJwt)
+}
+
+// getApiKey returns the API key with the given ID, or nil if it doesn't exist.
+func getApiKey(ctx context.Context, id string) (*apiKeyNode, error) {
+	apiKeys.RLock()
+	key, ok := apiKeys.keys[id]
+	apiKeys.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	keys, err := queryApiKeys(ctx, "eq(dgraph.apikey.id, $id)", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		key = keys[0]
+	}
+	apiKeys.Lock()
+	defer apiKeys.Unlock()
+	if len(apiKeys.keys) >= maxCachedApiKeys {
+		apiKeys.keys = make(map[string]*apiKeyNode)
+	}
+	apiKeys.keys[id] = key
+	return key, nil
+}
+
+// queryApiKeys returns the API keys matching the function, which can use the $id variable.
+func queryApiKeys(ctx context.Context, fn, id string) ([]*apiKeyNode, error) {
+	req := &Request{
+		req: &api.Request{
+			Query: fmt.Sprintf(`query apikeys($id: string) {
+		keys(func: %s) @filter(type(dgraph.type.ApiKey)) {%s
+		}
+	}`, fn, apiKeyFields),
+			Vars:     map[string]string{"$id": id},
+			ReadOnly: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(x.AttachNamespace(ctx, x.RootNamespace), req)
+	if err != nil {
+		return nil, err
+	}
+	var keysResp struct {
+		Keys []*apiKeyNode `json:"keys"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &keysResp); err != nil {
+		return nil, errors.Wrap(err, "while reading API keys")
+	}
+	return keysResp.Keys, nil
+}
+
+// newApiKeySecret returns a new API key with its ID and secret.
+func newApiKeySecret() (key, id, secret string) {
+	b := make([]byte, apiKeyIdLen/2+32)
+	_, err := rand.Read(b)
+	x.Check(err)
+	id = hex.EncodeToString(b[:apiKeyIdLen/2])
+	secret = base64.RawURLEncoding.EncodeToString(b[apiKeyIdLen/2:])
+	return apiKeyPrefix + id + "_" + secret, id, secret
+}
+
+func parseApiKey(key string) (id, secret string, err error) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok || len(rest) < apiKeyIdLen+2 || rest[apiKeyIdLen] != '_' {
+		return "", "", errInvalidApiKey
+	}
+	id, secret = rest[:apiKeyIdLen], rest[apiKeyIdLen+1:]
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", "", errInvalidApiKey
+	}
+	return id, secret, nil
+}
+
+func hashApiKeySecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// apiKeyFingerprint identifies the secret with the hash in the access JWTs, so that the JWTs
+// given for a secret can't be used after the secret has been rotated.
+func apiKeyFingerprint(hash string) string {
+	return hashApiKeySecret(hash)[:16]
+}
+
+// apiKeyAccessJwt validates the API key and returns an access JWT for it in the namespace. The
+// namespace can be empty if the key can only access one namespace.
+func apiKeyAccessJwt(ctx context.Context, apiKey, namespace string) (string, error) {
+	id, secret, err := parseApiKey(apiKey)
+	if err != nil {
+		return "", err
+	}
+	key, err := getApiKey(ctx, id)
+	if err != nil {
+		return "", errors.Wrapf(err, "while reading API key %s", id)
+	}
+	if key == nil {
+		return "", errInvalidApiKey
+	}
+	now := time.Now()
+	until := key.verify(secret, now)
+	if until.IsZero() {
+		return "", errInvalidApiKey
+	}
+
+	var ns uint64
+	switch {
+	case namespace != "":
+		if ns, err = strconv.ParseUint(namespace, 0, 64); err != nil {
+			return "", errors.Errorf("invalid namespace %q for API key", namespace)
+		}
+	case len(key.Namespaces) == 1:
+		ns = key.Namespaces[0]
+	default:
+		return "", errors.Errorf("the namespace must be given for API key %s, as it can access "+
+			"more than one namespace", id)
+	}
+	if !slices.Contains(key.Namespaces, ns) {
+		return "", errors.Errorf("API key %s can't access namespace %#x", id, ns)
+	}
+
+	// The JWTs are reused until they are about to expire, so that they aren't signed for each
+	// request.
+	cacheKey := hashApiKeySecret(secret) + "/" + strconv.FormatUint(ns, 10)
+	apiKeys.RLock()
+	cached, ok := apiKeys.jwts[cacheKey]
+	apiKeys.RUnlock()
+	if ok && cached.exp.Sub(now) > apiKeyJwtTtl/5 && !cached.exp.After(until) {
+		return cached.jwt, nil
+	}
+
+	token := jwt.NewWithClaims(worker.Config.AclJwtAlg, jwt.MapClaims{
+		"userid":    "apikey:" + id,
+		"apikey":    id,
+		"apikey_fp": apiKeyFingerprint(hashApiKeySecret(secret)),
+		"namespace": ns,
+		"exp":       until.Unix(),
+	})
+	jwtString, err := token.SignedString(x.MaybeKeyToBytes(worker.Config.AclSecretKey))
+	if err != nil {
+		return "", errors.Errorf("unable to encode jwt to string: %v", err)
+	}
+	apiKeys.Lock()
+	defer apiKeys.Unlock()
+	if len(apiKeys.jwts) >= maxCachedApiKeys {
+		apiKeys.jwts = make(map[string]apiKeyJwt)
+	}
+	apiKeys.jwts[cacheKey] = apiKeyJwt{jwt: jwtString, exp: until}
+	return jwtString, nil
+}
+
+// authorizeApiKeyPreds authorizes the predicates with the scope of the API key of the user,
+// instead of the ACL rules. The predicates are all blocked if the key has been revoked or its
+// secret has been rotated.
+func authorizeApiKeyPreds(ctx context.Context, userData *userData, preds []string,
+	aclOp *acl.Operation) *authPredResult {
+
+	key, err := getApiKey(ctx, userData.apiKey)
+	if err != nil {
+		glog.Errorf("Unable to read API key %s: %v", userData.apiKey, err)
+	}
+	valid := key != nil &&
+		key.canAccess(userData.namespace, aclOp, userData.apiKeyFingerprint, time.Now())
+
+	blockedPreds := make(map[string]struct{})
+	for _, pred := range preds {
+		if !valid || !key.canAccessPredicate(pred) {
+			blockedPreds[pred] = struct{}{}
+		}
+	}
+	if len(blockedPreds) > 0 {
+		logAccess(&accessEntry{
+			userId:    userData.userId,
+			preds:     preds,
+			operation: aclOp,
+			allowed:   false,
+		})
+	}
+	if valid && len(key.Predicates) == 0 {
+		return &authPredResult{allowed: nil, blocked: blockedPreds}
+	}
+	allowedPreds := make([]string, 0)
+	if valid {
+		for _, pred := range key.Predicates {
+			allowedPreds = append(allowedPreds, x.NamespaceAttr(userData.namespace, pred))
+		}
+	}
+	return &authPredResult{allowed: allowedPreds, blocked: blockedPreds}
+}
+
+// ApiKeyUnaryInterceptor authenticates the gRPC requests carrying an API key in their metadata,
+// by replacing the key with an access JWT for it.
+func ApiKeyUnaryInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	ctx, err := attachApiKeyJwt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// ApiKeyStreamInterceptor is the same as ApiKeyUnaryInterceptor for the streaming requests.
+func ApiKeyStreamInterceptor(srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	ctx, err := attachApiKeyJwt(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &apiKeyServerStream{ServerStream: ss, ctx: ctx})
+}
+
+type apiKeyServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *apiKeyServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func attachApiKeyJwt(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(apiKeyMetadata)) == 0 {
+		return ctx, nil
+	}
+	if worker.Config.AclSecretKey == nil {
+		return ctx, status.Error(codes.Unauthenticated, "API keys require ACL to be enabled")
+	}
+	var namespace string
+	if ns := md.Get(apiKeyNamespaceMetadata); len(ns) > 0 {
+		namespace = ns[0]
+	}
+	accessJwt, err := apiKeyAccessJwt(ctx, md.Get(apiKeyMetadata)[0], namespace)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	md = md.Copy()
+	md.Delete(apiKeyMetadata)
+	md.Set("accessJwt", accessJwt)
+	return metadata.NewIncomingContext(ctx, md), nil
+}
+
+// ApiKeyHttp authenticates the HTTP requests carrying an API key in the X-Dgraph-ApiKey header,
+// by replacing the key with an access JWT for it.
+func ApiKeyHttp(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey := r.Header.Get(apiKeyHeader)
+		if apiKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if worker.Config.AclSecretKey == nil {
+			x.SetHttpStatus(w, http.StatusUnauthorized, "API keys require ACL to be enabled")
+			return
+		}
+		accessJwt, err := apiKeyAccessJwt(r.Context(), apiKey, r.Header.Get(apiKeyNamespaceHeader))
+		if err != nil {
+			x.SetHttpStatus(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		r.Header.Del(apiKeyHeader)
+		r.Header.Set("X-Dgraph-AccessToken", accessJwt)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validateApiKeyScope validates the scope of the key, sorting and deduplicating it.
+func validateApiKeyScope(key *ApiKey) error {
+	if len(key.Namespaces) == 0 {
+		return errors.New("an API key must be able to access at least one namespace")
+	}
+	if all := acl.Read.Code | acl.Write.Code | acl.Modify.Code; key.Permission <= 0 ||
+		key.Permission&^all != 0 {
+		return errors.Errorf("invalid permission %d for API key, it must be a combination of "+
+			"read (%d), write (%d) and modify (%d)", key.Permission, acl.Read.Code,
+			acl.Write.Code, acl.Modify.Code)
+	}
+	preds := key.Predicates[:0]
+	for _, pred := range key.Predicates {
+		pred = strings.TrimSpace(pred)
+		switch {
+		case pred == "":
+			return errors.New("the predicates of an API key cannot be empty")
+		case x.IsReservedPredicate(x.AttrInRootNamespace(pred)):
+			return errors.Errorf("API keys can't access the reserved predicate %s", pred)
+		}
+		preds = append(preds, pred)
+	}
+	slices.Sort(preds)
+	key.Predicates = slices.Compact(preds)
+	slices.Sort(key.Namespaces)
+	key.Namespaces = slices.Compact(key.Namespaces)
+	return nil
+}
+
+// CreateApiKey creates an API key with the given name and scope, and returns it. The key can't
+// be retrieved later, as only the hash of its secret is stored.
+// Only superadmin is authorized to do so. Authorization is handled by middlewares.
+func (s *Server) CreateApiKey(ctx context.Context, key *ApiKey) (string, error) {
+	if worker.Config.AclSecretKey == nil {
+		return "", errors.New("API keys require ACL to be enabled")
+	}
+	if err := validateApiKeyScope(key); err != nil {
+		return "", err
+	}
+	for _, ns := range key.Namespaces {
+		if _, ok := schema.State().Namespaces()[ns]; !ok {
+			return "", errors.Errorf("error creating API key for non-existing namespace %#x", ns)
+		}
+	}
+	if !key.ExpiresAt.IsZero() && !key.ExpiresAt.After(time.Now()) {
+		return "", errors.New("the expiry of an API key must be in the future")
+	}
+
+	apiKey, id, secret := newApiKeySecret()
+	node := map[string]interface{}{
+		"uid":                      "_:key",
+		"dgraph.type":              "dgraph.type.ApiKey",
+		"dgraph.apikey.id":         id,
+		"dgraph.apikey.hash":       hashApiKeySecret(secret),
+		"dgraph.apikey.namespaces": key.Namespaces,
+		"dgraph.apikey.permission": key.Permission,
+	}
+	if key.Name != "" {
+		node["dgraph.apikey.name"] = key.Name
+	}
+	if len(key.Predicates) > 0 {
+		node["dgraph.apikey.predicates"] = key.Predicates
+	}
+	if !key.ExpiresAt.IsZero() {
+		node["dgraph.apikey.expiry"] = key.ExpiresAt.Unix()
+	}
+	setJson, err := json.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	if err := mutateApiKeys(ctx, "", "", &api.Mutation{SetJson: setJson}); err != nil {
+		return "", errors.Wrapf(err, "while creating API key")
+	}
+	key.Id = id
+	glog.Infof("Created API key %s for namespaces %v", id, key.Namespaces)
+	return apiKey, nil
+}
+
+// RotateApiKey replaces the secret of the API key with a new one, and returns the new key. The
+// previous secret stays valid for the grace period.
+// Only superadmin is authorized to do so. Authorization is handled by middlewares.
+func (s *Server) RotateApiKey(ctx context.Context, id string, grace time.Duration) (
+	string, error) {
+
+	if grace < 0 {
+		return "", errors.New("the grace period of an API key rotation cannot be negative")
+	}
+	if err := apiKeyExists(ctx, id); err != nil {
+		return "", err
+	}
+
+	apiKey, _, secret := newApiKeySecret()
+	set := fmt.Sprintf("uid(k) <dgraph.apikey.hash> %q .\n", hashApiKeySecret(secret))
+	del := ""
+	if grace > 0 {
+		set += "uid(k) <dgraph.apikey.prev_hash> val(h) .\n"
+		set += fmt.Sprintf("uid(k) <dgraph.apikey.prev_expiry> \"%d\" .\n",
+			time.Now().Add(grace).Unix())
+	} else {
+		del = "uid(k) <dgraph.apikey.prev_hash> * .\nuid(k) <dgraph.apikey.prev_expiry> * ."
+	}
+	query := apiKeyQuery
+	if grace > 0 {
+		query = apiKeyHashQuery
+	}
+	err := mutateApiKeys(ctx, query, id, &api.Mutation{
+		SetNquads: []byte(set),
+		DelNquads: []byte(del),
+		Cond:      "@if(eq(len(k), 1))",
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "while rotating API key %s", id)
+	}
+	glog.Infof("Rotated API key %s with a grace period of %s", id, grace)
+	return apiKey, nil
+}
+
+// RevokeApiKey deletes the API key, after which it can't be used anymore.
+// Only superadmin is authorized to do so. Authorization is handled by middlewares.
+func (s *Server) RevokeApiKey(ctx context.Context, id string) error {
+	if err := apiKeyExists(ctx, id); err != nil {
+		return err
+	}
+	err := mutateApiKeys(ctx, apiKeyQuery, id, &api.Mutation{
+		DelNquads: []byte("uid(k) * * ."),
+		Cond:      "@if(eq(len(k), 1))",
+	})
+	if err != nil {
+		return errors.Wrapf(err, "while revoking API key %s", id)
+	}
+	glog.Infof("Revoked API key %s", id)
+	return nil
+}
+
+// ListApiKeys returns all the API keys.
+// Only superadmin is authorized to do so. Authorization is handled by middlewares.
+func (s *Server) ListApiKeys(ctx context.Context) ([]*ApiKey, error) {
+	nodes, err := queryApiKeys(ctx, "type(dgraph.type.ApiKey)", "")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*ApiKey, 0, len(nodes))
+	for _, node := range nodes {
+		keys = append(keys, node.apiKey())
+	}
+	return keys, nil
+}
+
+func apiKeyExists(ctx context.Context, id string) error {
+	keys, err := queryApiKeys(ctx, "eq(dgraph.apikey.id, $id)", id)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.Errorf("API key %s doesn't exist", id)
+	}
+	return nil
+}
+
+const (
+	// apiKeyQuery and apiKeyHashQuery are the queries of the mutations on an API key, where k is
+	// the key and h is the hash of its secret.
+	apiKeyQuery = `query apikey($id: string) {
+		k as var(func: eq(dgraph.apikey.id, $id)) @filter(type(dgraph.type.ApiKey))
+	}`
+	apiKeyHashQuery = `query apikey($id: string) {
+		k as var(func: eq(dgraph.apikey.id, $id)) @filter(type(dgraph.type.ApiKey)) {
+			h as dgraph.apikey.hash
+		}
+	}`
+)
+
+// mutateApiKeys runs the mutation on the API keys, conditioned on the query if there is one. The
+// cached keys are dropped, the other alphas drop theirs when they are notified of the mutation.
+func mutateApiKeys(ctx context.Context, query, id string, mu *api.Mutation) error {
+	req := &api.Request{CommitNow: true, Mutations: []*api.Mutation{mu}}
+	if query != "" {
+		req.Query = query
+		req.Vars = map[string]string{"$id": id}
+	}
+	ctx = x.AttachNamespace(context.WithValue(ctx, IsGraphql, true), x.RootNamespace)
+	_, err := (&Server{}).doQuery(ctx, &Request{req: req, doAuth: NoAuthorize})
+	apiKeys.reset()
+	return err
+}
+
+var apiKeyPrefixes = [][]byte{
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.apikey.hash")),
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.apikey.id")),
+}
@@ -0,0 +1,222 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hypermodeinc/dgraph/v25/acl"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// cacheTestApiKey caches an API key for the namespaces, so that it's not read from the DB.
+func cacheTestApiKey(t *testing.T, namespaces []uint64, predicates []string) (
+	string, *apiKeyNode) {
+
+	t.Cleanup(apiKeys.reset)
+	apiKey, id, secret := newApiKeySecret()
+	node := &apiKeyNode{
+		Id:         id,
+		Hash:       hashApiKeySecret(secret),
+		Namespaces: namespaces,
+		Permission: acl.Read.Code | acl.Write.Code,
+		Predicates: predicates,
+	}
+	apiKeys.keys[id] = node
+	return apiKey, node
+}
+
+func TestParseApiKey(t *testing.T) {
+	apiKey, id, secret := newApiKeySecret()
+	gotId, gotSecret, err := parseApiKey(apiKey)
+	require.NoError(t, err)
+	require.Equal(t, id, gotId)
+	require.Equal(t, secret, gotSecret)
+	require.Len(t, id, apiKeyIdLen)
+
+	for _, key := range []string{"", "dg_", "dg_0123456789abcdef", "dg_0123456789abcdef_",
+		"xx_0123456789abcdef_secret", "dg_0123456789abcdefxsecret", "dg_0123456789abcdeg_secret"} {
+		_, _, err := parseApiKey(key)
+		require.ErrorIs(t, err, errInvalidApiKey, key)
+	}
+}
+
+func TestApiKeyVerify(t *testing.T) {
+	now := time.Now()
+	key := &apiKeyNode{
+		Hash:       hashApiKeySecret("new"),
+		PrevHash:   hashApiKeySecret("old"),
+		PrevExpiry: now.Add(time.Minute).Unix(),
+	}
+	require.Equal(t, now.Add(apiKeyJwtTtl), key.verify("new", now))
+	require.Equal(t, time.Unix(key.PrevExpiry, 0), key.verify("old", now))
+	require.True(t, key.verify("other", now).IsZero())
+	require.True(t, key.verify("old", now.Add(time.Minute)).IsZero())
+
+	key.Expiry = now.Add(time.Second).Unix()
+	require.Equal(t, time.Unix(key.Expiry, 0), key.verify("new", now))
+	require.True(t, key.verify("new", now.Add(time.Second)).IsZero())
+
+	key = &apiKeyNode{Predicates: []string{"name"}}
+	require.True(t, key.canAccessPredicate("name"))
+	require.True(t, key.canAccessPredicate("~name"))
+	require.True(t, key.canAccessPredicate("dgraph.type"))
+	require.False(t, key.canAccessPredicate("age"))
+	key.Predicates = nil
+	require.True(t, key.canAccessPredicate("age"))
+	require.False(t, key.canAccessPredicate("dgraph.password"))
+	require.False(t, key.canAccessPredicate("dgraph.apikey.hash"))
+}
+
+func TestApiKeyAccessJwt(t *testing.T) {
+	ctx := context.Background()
+	apiKey, node := cacheTestApiKey(t, []uint64{0, 2}, nil)
+
+	_, err := apiKeyAccessJwt(ctx, apiKey, "")
+	require.ErrorContains(t, err, "the namespace must be given")
+	_, err = apiKeyAccessJwt(ctx, apiKey, "1")
+	require.ErrorContains(t, err, "can't access namespace 0x1")
+	_, err = apiKeyAccessJwt(ctx, apiKey+"x", "2")
+	require.ErrorIs(t, err, errInvalidApiKey)
+	apiKeys.keys["0123456789abcdef"] = nil
+	_, err = apiKeyAccessJwt(ctx, "dg_0123456789abcdef_secret", "2")
+	require.ErrorIs(t, err, errInvalidApiKey)
+
+	accessJwt, err := apiKeyAccessJwt(ctx, apiKey, "2")
+	require.NoError(t, err)
+	ud, err := validateToken(accessJwt)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), ud.namespace)
+	require.Equal(t, node.Id, ud.apiKey)
+	require.Equal(t, apiKeyFingerprint(node.Hash), ud.apiKeyFingerprint)
+	require.Empty(t, ud.groupIds)
+
+	// The JWT is reused until it's about to expire.
+	again, err := apiKeyAccessJwt(ctx, apiKey, "2")
+	require.NoError(t, err)
+	require.Equal(t, accessJwt, again)
+
+	node.Namespaces = []uint64{2}
+	again, err = apiKeyAccessJwt(ctx, apiKey, "")
+	require.NoError(t, err)
+	require.Equal(t, accessJwt, again)
+}
+
+func TestAuthorizeApiKeyPreds(t *testing.T) {
+	ctx := context.Background()
+	_, node := cacheTestApiKey(t, []uint64{2}, []string{"name"})
+	ud := &userData{
+		namespace:         2,
+		userId:            "apikey:" + node.Id,
+		apiKey:            node.Id,
+		apiKeyFingerprint: apiKeyFingerprint(node.Hash),
+	}
+	preds := []string{"name", "age", "dgraph.type", "dgraph.xid"}
+
+	result := authorizePreds(ctx, ud, preds, acl.Read)
+	require.Equal(t, map[string]struct{}{"age": {}, "dgraph.xid": {}}, result.blocked)
+	require.Equal(t, []string{x.NamespaceAttr(2, "name")}, result.allowed)
+
+	// The key doesn't have the modify permission.
+	result = authorizePreds(ctx, ud, preds, acl.Modify)
+	require.Len(t, result.blocked, len(preds))
+	require.Empty(t, result.allowed)
+	require.NotNil(t, result.allowed)
+
+	node.Predicates = nil
+	result = authorizePreds(ctx, ud, preds, acl.Write)
+	require.Equal(t, map[string]struct{}{"dgraph.xid": {}}, result.blocked)
+	require.Nil(t, result.allowed)
+
+	// The JWTs given for a secret can't be used once the secret is rotated.
+	node.PrevHash, node.Hash = node.Hash, hashApiKeySecret("rotated")
+	result = authorizePreds(ctx, ud, preds, acl.Read)
+	require.Len(t, result.blocked, len(preds))
+	node.PrevExpiry = time.Now().Add(time.Minute).Unix()
+	result = authorizePreds(ctx, ud, preds, acl.Read)
+	require.Len(t, result.blocked, 1)
+
+	ud.namespace = 0
+	result = authorizePreds(ctx, ud, preds, acl.Read)
+	require.Len(t, result.blocked, len(preds))
+
+	// The key has been revoked.
+	ud.namespace = 2
+	apiKeys.keys[node.Id] = nil
+	result = authorizePreds(ctx, ud, preds, acl.Read)
+	require.Len(t, result.blocked, len(preds))
+}
+
+func TestApiKeyInterceptors(t *testing.T) {
+	apiKey, node := cacheTestApiKey(t, []uint64{2}, nil)
+
+	var got *http.Request
+	handler := ApiKeyHttp(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	serve := func(key string) int {
+		got = nil
+		r := httptest.NewRequest(http.MethodPost, "/query", nil)
+		if key != "" {
+			r.Header.Set(apiKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, serve(""))
+	require.Empty(t, got.Header.Get("X-Dgraph-AccessToken"))
+	require.Equal(t, http.StatusUnauthorized, serve(apiKey+"x"))
+	require.Nil(t, got)
+	require.Equal(t, http.StatusOK, serve(apiKey))
+	require.Empty(t, got.Header.Get(apiKeyHeader))
+	ud, err := validateToken(got.Header.Get("X-Dgraph-AccessToken"))
+	require.NoError(t, err)
+	require.Equal(t, node.Id, ud.apiKey)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		apiKeyMetadata, apiKey, "accessJwt", "other"))
+	ctx, err = attachApiKeyJwt(ctx)
+	require.NoError(t, err)
+	ud, err = extractUserAndGroups(ctx)
+	require.NoError(t, err)
+	require.Equal(t, node.Id, ud.apiKey)
+	md, _ := metadata.FromIncomingContext(ctx)
+	require.Empty(t, md.Get(apiKeyMetadata))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		apiKeyMetadata, apiKey, apiKeyNamespaceMetadata, "3"))
+	_, err = attachApiKeyJwt(ctx)
+	require.ErrorContains(t, err, "can't access namespace 0x3")
+}
+
+func TestValidateApiKeyScope(t *testing.T) {
+	key := &ApiKey{
+		Namespaces: []uint64{2, 0, 2},
+		Permission: acl.Read.Code,
+		Predicates: []string{" name", "age", "name"},
+	}
+	require.NoError(t, validateApiKeyScope(key))
+	require.Equal(t, []uint64{0, 2}, key.Namespaces)
+	require.Equal(t, []string{"age", "name"}, key.Predicates)
+
+	for _, key := range []*ApiKey{
+		{Permission: acl.Read.Code},
+		{Namespaces: []uint64{0}},
+		{Namespaces: []uint64{0}, Permission: 8},
+		{Namespaces: []uint64{0}, Permission: acl.Read.Code, Predicates: []string{" "}},
+		{Namespaces: []uint64{0}, Permission: acl.Read.Code, Predicates: []string{"dgraph.xid"}},
+	} {
+		require.Error(t, validateApiKeyScope(key))
+	}
+}
@@ -0,0 +1,338 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/audit"
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	defaultBulkDeleteBatch = 1000
+	// jobRetention is how long the finished bulk deletions and renames are reported.
+	jobRetention = 24 * time.Hour
+)
+
+// The statuses of the jobs run in the background, like bulk deletions and renames.
+const (
+	jobRunning = "Running"
+	jobSuccess = "Success"
+	jobFailed  = "Failed"
+)
+
+// bulkDeleteRe matches a bulk deletion, like delete { matching(func: ...) @filter(...) }, and
+// captures what follows the name of the matching block.
+var bulkDeleteRe = regexp.MustCompile(`(?s)^\s*delete\s*\{\s*matching\s*(\(.*)\}\s*$`)
+
+// BulkDeleteRequest deletes the nodes matching a query, in batches.
+type BulkDeleteRequest struct {
+	// Query is the bulk deletion, like delete { matching(func: ...) @filter(...) }.
+	Query string
+	// BatchSize is the number of nodes deleted per transaction.
+	BatchSize int
+	// MaxRate is the maximum number of nodes deleted per second. Zero doesn't limit the rate.
+	MaxRate int
+}
+
+// BulkDeleteStatus reports the progress of a bulk deletion.
+type BulkDeleteStatus struct {
+	Id        uint64
+	Namespace uint64
+	Query     string
+	Status    string
+	// Deleted is the number of nodes deleted so far, in the given number of batches.
+	Deleted   int
+	Batches   int
+	Error     string
+	StartedAt time.Time
+	UpdatedAt time.Time
+}
+
+// bulkDeleteTracker tracks the bulk deletions run by this Alpha.
+type bulkDeleteTracker struct {
+	sync.Mutex
+	lastId  uint64
+	deletes map[uint64]*BulkDeleteStatus
+}
+
+var bulkDeletes = &bulkDeleteTracker{deletes: make(map[uint64]*BulkDeleteStatus)}
+
+func (t *bulkDeleteTracker) start(ns uint64, query string) *BulkDeleteStatus {
+	t.Lock()
+	defer t.Unlock()
+	now := time.Now()
+	for id, st := range t.deletes {
+		if st.Status != jobRunning && now.Sub(st.UpdatedAt) > jobRetention {
+			delete(t.deletes, id)
+		}
+	}
+	t.lastId++
+	st := &BulkDeleteStatus{Id: t.lastId, Namespace: ns, Query: query, Status: jobRunning,
+		StartedAt: now, UpdatedAt: now}
+	t.deletes[st.Id] = st
+	return st
+}
+
+func (t *bulkDeleteTracker) update(st *BulkDeleteStatus, deleted int, err error) {
+	t.Lock()
+	defer t.Unlock()
+	st.Deleted += deleted
+	if deleted > 0 {
+		st.Batches++
+	}
+	st.UpdatedAt = time.Now()
+	if err != nil {
+		st.Status, st.Error = jobFailed, err.Error()
+	}
+}
+
+func (t *bulkDeleteTracker) finish(st *BulkDeleteStatus) {
+	t.Lock()
+	defer t.Unlock()
+	if st.Status == jobRunning {
+		st.Status, st.UpdatedAt = jobSuccess, time.Now()
+	}
+}
+
+// list returns a copy of the bulk deletions of the namespace, the latest first.
+func (t *bulkDeleteTracker) list(ns uint64) []BulkDeleteStatus {
+	t.Lock()
+	defer t.Unlock()
+	var res []BulkDeleteStatus
+	for _, st := range t.deletes {
+		if st.Namespace == ns {
+			res = append(res, *st)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id > res[j].Id })
+	return res
+}
+
+// bulkDeleteBatch returns the upsert block which deletes the next batch of at most batchSize
+// nodes matched by the bulk deletion query. The matching block becomes a var block, so that the
+// batch can be taken out of its nodes.
+func bulkDeleteBatch(query string, batchSize int) (*api.Request, error) {
+	m := bulkDeleteRe.FindStringSubmatch(query)
+	if m == nil {
+		return nil, errors.New("A bulk deletion must be of the form " +
+			"delete { matching(func: ...) @filter(...) }")
+	}
+	q := fmt.Sprintf("{\n\tmatching as var%s\n"+
+		"\tbatch(func: uid(matching), first: %d) { b as uid }\n}", m[1], batchSize)
+	res, err := dql.ParseWithNeedVars(dql.Request{Str: q}, []string{"b"})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing the bulk deletion")
+	}
+	if len(res.Query) != 2 {
+		return nil, errors.New("A bulk deletion must only have the matching block")
+	}
+	return &api.Request{
+		Query:     q,
+		Mutations: []*api.Mutation{{DelNquads: []byte(`uid(b) * * .`)}},
+		CommitNow: true,
+	}, nil
+}
+
+// BulkDelete starts deleting the nodes matching the query of the request, in the namespace of
+// the context. The nodes are deleted in batches, each one in its own transaction, until the
+// query doesn't match any node. It returns the id of the bulk deletion, whose progress is
+// reported by BulkDeletes.
+func (s *Server) BulkDelete(ctx context.Context, req *BulkDeleteRequest) (uint64, error) {
+	if err := x.HealthCheck(); err != nil {
+		return 0, err
+	}
+	if !isMutationAllowed(ctx) {
+		return 0, errors.Errorf("no mutations allowed")
+	}
+	if req.BatchSize == 0 {
+		req.BatchSize = defaultBulkDeleteBatch
+	}
+	if req.BatchSize < 0 || req.BatchSize > x.Config.LimitMutationsNquad {
+		return 0, errors.Errorf("The batch size must be between 1 and %d",
+			x.Config.LimitMutationsNquad)
+	}
+	if req.MaxRate < 0 {
+		return 0, errors.New("The maximum rate can't be negative")
+	}
+	batch, err := bulkDeleteBatch(req.Query, req.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return s.startBulkDelete(ctx, req.Query, batch, req.MaxRate)
+}
+
+// startBulkDelete runs the batch in the namespace of the context, in the background, until its
+// query doesn't match any node.
+func (s *Server) startBulkDelete(ctx context.Context, query string, batch *api.Request,
+	maxRate int) (uint64, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	st := bulkDeletes.start(ns, query)
+	glog.Infof("Bulk deletion %d: started in namespace %#x", st.Id, ns)
+	go s.runBulkDelete(x.AttachNamespace(context.Background(), ns), st, batch, maxRate)
+	return st.Id, nil
+}
+
+func (s *Server) runBulkDelete(ctx context.Context, st *BulkDeleteStatus, batch *api.Request,
+	maxRate int) {
+	defer bulkDeletes.finish(st)
+
+	deleted := 0
+	fail := func(err error) {
+		glog.Errorf("Bulk deletion %d: failed after deleting %d nodes: %v", st.Id, deleted, err)
+		bulkDeletes.update(st, 0, err)
+	}
+
+	start := time.Now()
+	var last map[string]bool
+	for {
+		// Running the upsert block sets its start ts, each batch starts from a copy of it.
+		resp, _, err := retryAborted(ctx, proto.Clone(batch).(*api.Request),
+			x.Config.MutationRetries, s.QueryNoAuth)
+		if err != nil {
+			fail(err)
+			return
+		}
+		var res struct {
+			Batch []struct {
+				Uid string `json:"uid"`
+			} `json:"batch"`
+		}
+		if err := json.Unmarshal(resp.Json, &res); err != nil {
+			fail(err)
+			return
+		}
+		if len(res.Batch) == 0 {
+			glog.Infof("Bulk deletion %d: deleted %d nodes in %s", st.Id, deleted,
+				time.Since(start).Round(time.Second))
+			return
+		}
+		// Deleting a node only deletes the predicates of its types. A node which still matches
+		// after being deleted would be matched by every following batch.
+		uids := make(map[string]bool, len(res.Batch))
+		for _, n := range res.Batch {
+			if last[n.Uid] {
+				fail(errors.Errorf("The node %s still matches the query after being deleted. "+
+					"Only the predicates of the types of a node are deleted, check that the "+
+					"matching nodes have a dgraph.type which covers the queried predicates", n.Uid))
+				return
+			}
+			uids[n.Uid] = true
+		}
+		last = uids
+		deleted += len(res.Batch)
+		bulkDeletes.update(st, len(res.Batch), nil)
+
+		// Wait for as long as it takes to delete the nodes at the maximum rate.
+		var wait time.Duration
+		if maxRate > 0 {
+			wait = time.Duration(float64(deleted)/float64(maxRate)*float64(time.Second)) -
+				time.Since(start)
+		}
+		select {
+		case <-x.ServerCloser.HasBeenClosed():
+			bulkDeletes.update(st, 0, errors.New("The Alpha is shutting down"))
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// BulkDeletes reports the bulk deletions run by this Alpha in the namespace of the context,
+// the latest first.
+func (s *Server) BulkDeletes(ctx context.Context) ([]BulkDeleteStatus, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return bulkDeletes.list(ns), nil
+}
+
+// dropTypeDataPreds returns the predicates deleted from the nodes of the type, the fields of the
+// type and dgraph.type.
+func dropTypeDataPreds(ns uint64, typ string) []string {
+	preds := []string{"dgraph.type"}
+	t, _ := schema.State().GetType(x.NamespaceAttr(ns, typ))
+	for _, field := range t.Fields {
+		if attr := x.ParseAttr(field.Predicate); attr[0] != '~' {
+			preds = append(preds, attr)
+		}
+	}
+	return preds
+}
+
+// dropTypeDataNquads returns the deletion of the fields of the type and of the type itself from
+// the nodes of the batch.
+func dropTypeDataNquads(typ string, preds []string) []byte {
+	var b strings.Builder
+	for _, pred := range preds {
+		if pred != "dgraph.type" {
+			fmt.Fprintf(&b, "uid(b) <%s> * .\n", pred)
+		}
+	}
+	fmt.Fprintf(&b, "uid(b) <dgraph.type> %s .\n", strconv.Quote(typ))
+	return []byte(b.String())
+}
+
+// DropTypeData starts deleting the nodes of the type in the namespace of the context, as a bulk
+// deletion whose progress is reported by BulkDeletes, and returns its id. Only the fields of the
+// type and the type itself are deleted from the nodes, so that the nodes with other types keep
+// the predicates of these types. The definition of the type is kept.
+func (s *Server) DropTypeData(ctx context.Context, typ string) (uint64, error) {
+	if err := x.HealthCheck(); err != nil {
+		return 0, err
+	}
+	if !isMutationAllowed(ctx) {
+		return 0, errors.Errorf("no mutations allowed")
+	}
+	if _, err := hasAdminAuth(ctx, "DropTypeData"); err != nil {
+		return 0, err
+	}
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return 0, err
+	}
+	if x.IsPreDefinedType(x.NamespaceAttr(ns, typ)) {
+		return 0, errors.Errorf("type %s is pre-defined and its data is not allowed to be"+
+			" dropped", typ)
+	}
+	if _, ok := schema.State().GetType(x.NamespaceAttr(ns, typ)); !ok {
+		return 0, errors.Errorf("Type %s doesn't exist", typ)
+	}
+	preds := dropTypeDataPreds(ns, typ)
+	if err := authorizeDropTypeData(ctx, preds); err != nil {
+		return 0, err
+	}
+	audit.AddPredicates(ctx, preds...)
+
+	query := fmt.Sprintf("delete { matching(func: type(%s)) { uid } }", typ)
+	batch, err := bulkDeleteBatch(query, defaultBulkDeleteBatch)
+	if err != nil {
+		return 0, err
+	}
+	batch.Mutations[0].DelNquads = dropTypeDataNquads(typ, preds)
+	return s.startBulkDelete(ctx, query, batch, 0)
+}
@@ -0,0 +1,68 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkDeleteBatch(t *testing.T) {
+	req, err := bulkDeleteBatch(`delete {
+		matching(func: type(Person)) @filter(lt(age, 18)) {
+			uid
+		}
+	}`, 10)
+	require.NoError(t, err)
+	require.True(t, req.CommitNow)
+	require.Contains(t, req.Query, "matching as var(func: type(Person)) @filter(lt(age, 18))")
+	require.Contains(t, req.Query, "batch(func: uid(matching), first: 10) { b as uid }")
+	require.Len(t, req.Mutations, 1)
+	require.Equal(t, "uid(b) * * .", string(req.Mutations[0].DelNquads))
+
+	for _, query := range []string{
+		`{ matching(func: type(Person)) { uid } }`,
+		`delete { nodes(func: type(Person)) }`,
+		`delete { matching(func: type(Person)) { uid } other(func: has(name)) { uid } }`,
+		`delete { matching(func: type(Person) }`,
+	} {
+		_, err := bulkDeleteBatch(query, 10)
+		require.Error(t, err, query)
+	}
+}
+
+func TestBulkDeleteTracker(t *testing.T) {
+	tracker := &bulkDeleteTracker{deletes: make(map[uint64]*BulkDeleteStatus)}
+	first := tracker.start(1, "first")
+	tracker.update(first, 10, nil)
+	tracker.update(first, 5, nil)
+	tracker.finish(first)
+
+	second := tracker.start(1, "second")
+	tracker.update(second, 0, errors.New("failed"))
+	tracker.finish(second)
+	tracker.start(2, "other")
+
+	list := tracker.list(1)
+	require.Len(t, list, 2)
+	require.Equal(t, "second", list[0].Query)
+	require.Equal(t, jobFailed, list[0].Status)
+	require.Equal(t, "failed", list[0].Error)
+	require.Equal(t, jobSuccess, list[1].Status)
+	require.Equal(t, 15, list[1].Deleted)
+	require.Equal(t, 2, list[1].Batches)
+}
+
+func TestDropTypeDataBatch(t *testing.T) {
+	batch, err := bulkDeleteBatch("delete { matching(func: type(Person)) { uid } }", 10)
+	require.NoError(t, err)
+	batch.Mutations[0].DelNquads = dropTypeDataNquads("Person", []string{"dgraph.type", "name", "pet"})
+	require.Equal(t, "uid(b) <name> * .\nuid(b) <pet> * .\nuid(b) <dgraph.type> \"Person\" .\n",
+		string(batch.Mutations[0].DelNquads))
+	require.Len(t, batch.Mutations, 1)
+}
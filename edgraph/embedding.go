@@ -0,0 +1,134 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// embedder embeds the values of the source predicates of the @embedding predicates, nil if
+// --embedding has no endpoint.
+var embedder x.Embedder
+
+// InitEmbedding sets the embedder of the @embedding predicates.
+func InitEmbedding(e x.Embedder) {
+	embedder = e
+}
+
+// checkEmbeddingSources returns an error if the source of an @embedding predicate of the updates
+// isn't a string predicate, of the updates or of the current schema of the namespace.
+func checkEmbeddingSources(ctx context.Context, ns uint64, updates []*pb.SchemaUpdate) error {
+	preds := make(map[string]*pb.SchemaUpdate, len(updates))
+	for _, update := range updates {
+		preds[update.Predicate] = update
+	}
+	for _, update := range updates {
+		if update.EmbeddingSource == "" {
+			continue
+		}
+		source := x.NamespaceAttr(ns, update.EmbeddingSource)
+		su, ok := preds[source]
+		if !ok {
+			if cur, found := schema.State().Get(ctx, source); found {
+				su, ok = &cur, true
+			}
+		}
+		if !ok || su.ValueType != pb.Posting_STRING || su.List {
+			return errors.Errorf("The source %s of the @embedding of predicate %s must be a "+
+				"string predicate, not a list", update.EmbeddingSource,
+				x.ParseAttr(update.Predicate))
+		}
+	}
+	return nil
+}
+
+// addEmbeddingEdges returns the edges with the ones setting the vectors of the @embedding
+// predicates whose source values are set by the edges, embedded by the embedder, and the ones
+// deleting the vectors whose source values are deleted. The vectors set by the edges themselves
+// are kept as they are. Like the indexes, the vectors are written without checking the ACLs of
+// the @embedding predicates.
+func addEmbeddingEdges(ctx context.Context, ns uint64, edges []*pb.DirectedEdge) (
+	[]*pb.DirectedEdge, error) {
+
+	targets := schema.State().EmbeddingTargets(ns)
+	if len(targets) == 0 {
+		return edges, nil
+	}
+
+	type vector struct {
+		entity uint64
+		attr   string
+	}
+	set := make(map[vector]bool)
+	for _, e := range edges {
+		if e.Op == pb.DirectedEdge_SET {
+			set[vector{e.Entity, e.Attr}] = true
+		}
+	}
+
+	var texts []string
+	var embedded []vector
+	var derived []*pb.DirectedEdge
+	for _, e := range edges {
+		if e.Attr == x.Star || e.Lang != "" {
+			continue
+		}
+		for _, target := range targets[x.NamespaceAttr(ns, e.Attr)] {
+			v := vector{e.Entity, x.ParseAttr(target)}
+			if set[v] {
+				continue
+			}
+			switch {
+			case e.Op == pb.DirectedEdge_SET && (e.ValueType == pb.Posting_DEFAULT ||
+				e.ValueType == pb.Posting_STRING):
+				texts = append(texts, string(e.Value))
+				embedded = append(embedded, v)
+			case e.Op == pb.DirectedEdge_DEL:
+				derived = append(derived, &pb.DirectedEdge{
+					Entity:    e.Entity,
+					Attr:      v.attr,
+					Namespace: ns,
+					Value:     []byte(x.Star),
+					ValueType: pb.Posting_DEFAULT,
+					Op:        pb.DirectedEdge_DEL,
+				})
+			}
+		}
+	}
+
+	if len(texts) > 0 {
+		if embedder == nil {
+			return nil, errors.New("Can't embed the values of the @embedding sources, " +
+				"--embedding has no url")
+		}
+		vecs, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while embedding the values of the @embedding sources")
+		}
+		if len(vecs) != len(texts) {
+			return nil, errors.Errorf("The embedder returned %d vectors for %d values",
+				len(vecs), len(texts))
+		}
+		for i, v := range embedded {
+			derived = append(derived, &pb.DirectedEdge{
+				Entity:    v.entity,
+				Attr:      v.attr,
+				Namespace: ns,
+				Value:     types.FloatArrayAsBytes(vecs[i]),
+				ValueType: pb.Posting_VFLOAT,
+				Op:        pb.DirectedEdge_SET,
+			})
+		}
+	}
+	return append(edges, derived...), nil
+}
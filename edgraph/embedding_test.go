@@ -0,0 +1,108 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// lenEmbedder embeds the texts into vectors of their lengths.
+type lenEmbedder struct {
+	calls int
+	err   error
+}
+
+func (l *lenEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	l.calls++
+	vecs := make([][]float32, 0, len(texts))
+	for _, t := range texts {
+		vecs = append(vecs, []float32{float32(len(t))})
+	}
+	return vecs, l.err
+}
+
+func TestAddEmbeddingEdges(t *testing.T) {
+	initTriggerSchema(t)
+	schema.State().Set(x.NamespaceAttr(1, "bio"), &pb.SchemaUpdate{
+		Predicate: x.NamespaceAttr(1, "bio"), ValueType: pb.Posting_STRING})
+	schema.State().Set(x.NamespaceAttr(1, "bio_vec"), &pb.SchemaUpdate{
+		Predicate: x.NamespaceAttr(1, "bio_vec"), ValueType: pb.Posting_VFLOAT,
+		EmbeddingSource: "bio"})
+	defer InitEmbedding(nil)
+	ctx := context.Background()
+
+	edges := []*pb.DirectedEdge{
+		{Entity: 1, Attr: "bio", Value: []byte("abc")},
+		{Entity: 2, Attr: "bio", Value: []byte("de"), Lang: "fr"},
+		{Entity: 3, Attr: "bio", Value: []byte("fgh")},
+		{Entity: 3, Attr: "bio_vec", Value: types.FloatArrayAsBytes([]float32{9}),
+			ValueType: pb.Posting_VFLOAT},
+		{Entity: 4, Attr: "bio", Value: []byte(x.Star), Op: pb.DirectedEdge_DEL},
+		{Entity: 5, Attr: "name", Value: []byte("ij")},
+	}
+	// The values can't be embedded without an embedder.
+	_, err := addEmbeddingEdges(ctx, 1, edges)
+	require.Error(t, err)
+
+	e := &lenEmbedder{}
+	InitEmbedding(e)
+	// Namespace 0 has no @embedding predicates.
+	got, err := addEmbeddingEdges(ctx, 0, edges)
+	require.NoError(t, err)
+	require.Equal(t, edges, got)
+	require.Zero(t, e.calls)
+
+	got, err = addEmbeddingEdges(ctx, 1, edges)
+	require.NoError(t, err)
+	require.Equal(t, 1, e.calls)
+	require.Equal(t, edges, got[:len(edges)])
+	require.Equal(t, []*pb.DirectedEdge{
+		{Entity: 4, Attr: "bio_vec", Namespace: 1, Value: []byte(x.Star),
+			Op: pb.DirectedEdge_DEL},
+		{Entity: 1, Attr: "bio_vec", Namespace: 1, Value: types.FloatArrayAsBytes([]float32{3}),
+			ValueType: pb.Posting_VFLOAT},
+	}, got[len(edges):])
+
+	e.err = errors.New("unavailable")
+	_, err = addEmbeddingEdges(ctx, 1, edges)
+	require.ErrorContains(t, err, "unavailable")
+}
+
+func TestCheckEmbeddingSources(t *testing.T) {
+	initTriggerSchema(t)
+	schema.State().Set(x.NamespaceAttr(1, "bio"), &pb.SchemaUpdate{
+		Predicate: x.NamespaceAttr(1, "bio"), ValueType: pb.Posting_STRING})
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		sch   string
+		valid bool
+	}{
+		{"vec: float32vector @embedding(bio) .", true},
+		{"vec: float32vector @embedding(title) .\ntitle: string .", true},
+		{"vec: float32vector @embedding(title) .", false},
+		{"vec: float32vector @embedding(age) .\nage: int .", false},
+		{"vec: float32vector @embedding(tags) .\ntags: [string] .", false},
+	} {
+		result, err := schema.ParseWithNamespace(tc.sch, 1)
+		require.NoError(t, err)
+		err = checkEmbeddingSources(ctx, 1, result.Preds)
+		if tc.valid {
+			require.NoError(t, err, tc.sch)
+		} else {
+			require.Error(t, err, tc.sch)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// ErrorCodeUnaryInterceptor returns the errors of the gRPC requests as statuses carrying their
+// error code in an ErrorInfo detail, so that the clients don't have to match their messages.
+func ErrorCodeUnaryInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	resp, err := handler(ctx, req)
+	return resp, x.WithErrorCode(err)
+}
+
+// ErrorCodeStreamInterceptor is the same as ErrorCodeUnaryInterceptor for the streaming requests.
+func ErrorCodeStreamInterceptor(srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+	return x.WithErrorCode(handler(srv, ss))
+}
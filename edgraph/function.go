@@ -0,0 +1,261 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+var functionPrefixes = [][]byte{
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.function.name")),
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.function.namespace")),
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.function.query")),
+}
+
+// functionStore holds the stored DQL functions of all the namespaces, by namespace and name. The
+// maps of a namespace are replaced, not modified, so that the queries can use them without lock.
+type functionStore struct {
+	sync.RWMutex
+	// reloads serializes the reloads of the functions, so that an older reload doesn't replace
+	// the functions loaded by a newer one.
+	reloads   sync.Mutex
+	functions map[uint64]map[string]*dql.StoredFunction
+}
+
+var functions = &functionStore{functions: make(map[uint64]map[string]*dql.StoredFunction)}
+
+// storedFunctions returns the stored functions of the namespace, called by the queries as
+// view(<name>, <arguments>...).
+func storedFunctions(ns uint64) map[string]*dql.StoredFunction {
+	functions.RLock()
+	defer functions.RUnlock()
+	return functions.functions[ns]
+}
+
+type functionNode struct {
+	Name      string `json:"dgraph.function.name"`
+	Namespace uint64 `json:"dgraph.function.namespace"`
+	Query     string `json:"dgraph.function.query"`
+}
+
+// loadFunctionNodes reads the stored functions of all the namespaces.
+func loadFunctionNodes(ctx context.Context) ([]functionNode, error) {
+	req := &Request{
+		req: &api.Request{
+			Query: `{
+		functions(func: type(dgraph.function)) {
+			dgraph.function.name
+			dgraph.function.namespace
+			dgraph.function.query
+		}
+	}`,
+			ReadOnly: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(x.AttachNamespace(ctx, x.RootNamespace), req)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Functions []functionNode `json:"functions"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &res); err != nil {
+		return nil, errors.Wrap(err, "while reading the stored functions")
+	}
+	return res.Functions, nil
+}
+
+// reloadFunctions parses the stored functions of all the namespaces for the queries. The
+// functions which don't parse anymore are skipped, the calls to the others are checked by the
+// queries.
+func reloadFunctions(ctx context.Context) error {
+	functions.reloads.Lock()
+	defer functions.reloads.Unlock()
+	nodes, err := loadFunctionNodes(ctx)
+	if err != nil {
+		return err
+	}
+	loaded := make(map[uint64]map[string]*dql.StoredFunction)
+	for _, node := range nodes {
+		fn, err := dql.ParseStoredFunction(node.Name, node.Query)
+		if err != nil {
+			glog.Errorf("While parsing the stored function %s of namespace %#x: %v", node.Name,
+				node.Namespace, err)
+			continue
+		}
+		if loaded[node.Namespace] == nil {
+			loaded[node.Namespace] = make(map[string]*dql.StoredFunction)
+		}
+		loaded[node.Namespace][node.Name] = fn
+	}
+
+	functions.Lock()
+	defer functions.Unlock()
+	functions.functions = loaded
+	return nil
+}
+
+// SubscribeForFunctionUpdates loads the stored functions, and reloads them whenever they change,
+// on any Alpha.
+func SubscribeForFunctionUpdates(closer *z.Closer) {
+	defer func() {
+		glog.Infoln("SubscribeForFunctionUpdates closed")
+		closer.Done()
+	}()
+
+	closer.AddRunning(1)
+	go worker.SubscribeForUpdates(functionPrefixes, x.IgnoreBytes, func(kvs *bpb.KVList) {
+		if kvs == nil || len(kvs.Kv) == 0 {
+			return
+		}
+		glog.V(3).Infof("Got stored function update via subscription")
+		if err := reloadFunctions(closer.Ctx()); err != nil {
+			glog.Errorf("While reloading the stored functions: %v", err)
+		}
+	}, 1, closer)
+
+	for {
+		err := reloadFunctions(closer.Ctx())
+		if err == nil {
+			break
+		}
+		glog.Warningf("While loading the stored functions, retrying: %v", err)
+		select {
+		case <-time.After(time.Second):
+		case <-closer.HasBeenClosed():
+			return
+		}
+	}
+	<-closer.HasBeenClosed()
+}
+
+// mutateFunctions runs the upsert of the stored functions in the root namespace, and reloads them.
+func mutateFunctions(ctx context.Context, name string, ns uint64, mu *api.Mutation) error {
+	req := &api.Request{
+		Query: `query function($name: string, $ns: int) {
+		f as var(func: eq(dgraph.function.name, $name)) @filter(eq(dgraph.function.namespace, $ns))
+	}`,
+		Vars:      map[string]string{"$name": name, "$ns": strconv.FormatUint(ns, 10)},
+		Mutations: []*api.Mutation{mu},
+		CommitNow: true,
+	}
+	ctx = context.WithValue(context.WithValue(ctx, IsGraphql, true), IsTrigger, true)
+	if _, err := (&Server{}).doQuery(x.AttachNamespace(ctx, x.RootNamespace),
+		&Request{req: req, doAuth: NoAuthorize}); err != nil {
+		return err
+	}
+	return reloadFunctions(ctx)
+}
+
+// AddFunction adds the stored function to the namespace of the context, replacing the function
+// with the same name. Its query is checked against the other functions of the namespace.
+func (s *Server) AddFunction(ctx context.Context, name, query string) error {
+	if err := x.HealthCheck(); err != nil {
+		return err
+	}
+	ctx = x.AttachJWTNamespace(ctx)
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	if !viewNameRe.MatchString(name) {
+		return errors.Errorf("Invalid function name %q, it should only have letters, digits and "+
+			"underscores, and not start with a digit", name)
+	}
+	fn, err := dql.ParseStoredFunction(name, query)
+	if err != nil {
+		return err
+	}
+	funcs := map[string]*dql.StoredFunction{name: fn}
+	for other, f := range storedFunctions(ns) {
+		if other != name {
+			funcs[other] = f
+		}
+	}
+	if err := fn.Validate(funcs); err != nil {
+		return err
+	}
+
+	str := func(s string) *api.Value { return &api.Value{Val: &api.Value_StrVal{StrVal: s}} }
+	mu := &api.Mutation{Set: []*api.NQuad{
+		{Subject: "uid(f)", Predicate: "dgraph.function.name", ObjectValue: str(name)},
+		{Subject: "uid(f)", Predicate: "dgraph.function.namespace",
+			ObjectValue: &api.Value{Val: &api.Value_IntVal{IntVal: int64(ns)}}},
+		{Subject: "uid(f)", Predicate: "dgraph.function.query", ObjectValue: str(query)},
+		{Subject: "uid(f)", Predicate: "dgraph.type", ObjectValue: str("dgraph.function")},
+	}}
+	if err := mutateFunctions(ctx, name, ns, mu); err != nil {
+		return err
+	}
+	glog.Infof("Added the stored function %s in namespace %#x", name, ns)
+	return nil
+}
+
+// DeleteFunction deletes the stored function of the namespace of the context. It returns false if
+// there isn't a function with the name. The queries calling it fail afterwards.
+func (s *Server) DeleteFunction(ctx context.Context, name string) (bool, error) {
+	if err := x.HealthCheck(); err != nil {
+		return false, err
+	}
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return false, err
+	}
+	nodes, err := loadFunctionNodes(ctx)
+	if err != nil {
+		return false, err
+	}
+	exists := false
+	for _, node := range nodes {
+		exists = exists || (node.Namespace == ns && node.Name == name)
+	}
+	if !exists {
+		return false, nil
+	}
+	mu := &api.Mutation{DelNquads: []byte(`uid(f) * * .`)}
+	return true, mutateFunctions(ctx, name, ns, mu)
+}
+
+// Functions returns the stored functions of the namespace of the context, by name.
+func (s *Server) Functions(ctx context.Context) ([]*dql.StoredFunction, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := loadFunctionNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var funcs []*dql.StoredFunction
+	for _, node := range nodes {
+		if node.Namespace != ns {
+			continue
+		}
+		fn, err := dql.ParseStoredFunction(node.Name, node.Query)
+		if err != nil {
+			fn = &dql.StoredFunction{Name: node.Name, Query: node.Query}
+		}
+		funcs = append(funcs, fn)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Name < funcs[j].Name })
+	return funcs, nil
+}
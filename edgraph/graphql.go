@@ -31,10 +31,17 @@ import (
 //     i)  If query is not provided then update gqlRes with the found query and proceed
 //     ii) If query is provided then match query retrieved, if identical do nothing else
 //     throw "query does not match persisted query"
-func ProcessPersistedQuery(ctx context.Context, gqlReq *schema.Request) error {
+//
+// If persistedOnly is set, only the persisted queries are allowed: the sha256Hash of a query
+// given without one is computed, and the queries which aren't persisted yet are rejected instead
+// of being stored. They must be registered with RegisterPersistedQueries.
+func ProcessPersistedQuery(ctx context.Context, gqlReq *schema.Request, persistedOnly bool) error {
 	query := gqlReq.Query
 	sha256Hash := gqlReq.Extensions.PersistedQuery.Sha256Hash
 
+	if sha256Hash == "" && persistedOnly {
+		sha256Hash = queryHash(query)
+	}
 	if sha256Hash == "" {
 		return nil
 	}
@@ -91,6 +98,9 @@ func ProcessPersistedQuery(ctx context.Context, gqlReq *schema.Request) error {
 		if query == "" {
 			return errors.New("PersistedQueryNotFound")
 		}
+		if persistedOnly {
+			return errors.New("only persisted queries are allowed, and this query isn't one")
+		}
 		if match, err := hashMatches(query, sha256Hash); err != nil {
 			return err
 		} else if !match {
@@ -154,3 +164,159 @@ func hashMatches(query, sha256Hash string) (bool, error) {
 	hashGenerated := hex.EncodeToString(hasher.Sum(nil))
 	return hashGenerated == sha256Hash, nil
 }
+
+func queryHash(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
+
+// persistedQueryNode is a node storing a persisted query, as its sha256 hash followed by the
+// query in dgraph.graphql.p_query.
+type persistedQueryNode struct {
+	Uid   string `json:"uid"`
+	Query string `json:"dgraph.graphql.p_query"`
+}
+
+// hash returns the sha256 hash of the persisted query.
+func (n persistedQueryNode) hash() string {
+	if len(n.Query) < 64 {
+		return n.Query
+	}
+	return n.Query[:64]
+}
+
+// persistedQueries returns the nodes of the persisted queries of the namespace of the context.
+func persistedQueries(ctx context.Context) ([]persistedQueryNode, error) {
+	req := &Request{
+		req: &api.Request{
+			Query: `{
+				queries(func: has(dgraph.graphql.p_query)) {
+					uid
+					dgraph.graphql.p_query
+				}
+			}`,
+			ReadOnly: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Queries []persistedQueryNode `json:"queries"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &res); err != nil {
+		return nil, errors.Wrap(err, "while reading the persisted queries")
+	}
+	return res.Queries, nil
+}
+
+// RegisterPersistedQueries persists the given queries in the namespace of the context, in a
+// single transaction, and returns their sha256 hashes. With replace, the other persisted queries
+// of the namespace are deleted, so that the set of allowed queries can be rotated at once.
+func (s *Server) RegisterPersistedQueries(ctx context.Context, queries []string,
+	replace bool) ([]string, error) {
+	existing, err := persistedQueries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(existing))
+	for _, n := range existing {
+		stored[n.Query] = true
+	}
+
+	var set, del []*api.NQuad
+	var added int
+	hashes := make([]string, 0, len(queries))
+	requested := make(map[string]bool, len(queries))
+	for i, query := range queries {
+		if query == "" {
+			return nil, errors.New("the persisted queries can't be empty")
+		}
+		hash := queryHash(query)
+		hashes = append(hashes, hash)
+		join := hash + query
+		if requested[join] {
+			continue
+		}
+		requested[join] = true
+		if stored[join] {
+			continue
+		}
+		added++
+		blank := fmt.Sprintf("_:q%d", i)
+		set = append(set, &api.NQuad{
+			Subject:     blank,
+			Predicate:   "dgraph.graphql.p_query",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: join}},
+		}, &api.NQuad{
+			Subject:     blank,
+			Predicate:   "dgraph.type",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: "dgraph.graphql.persisted_query"}},
+		})
+	}
+	for _, n := range existing {
+		if replace && !requested[n.Query] {
+			del = append(del, &api.NQuad{
+				Subject:     n.Uid,
+				Predicate:   x.Star,
+				ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}},
+			})
+		}
+	}
+	if len(set) == 0 && len(del) == 0 {
+		return hashes, nil
+	}
+
+	req := &Request{
+		req: &api.Request{
+			Mutations: []*api.Mutation{{Set: set, Del: del}},
+			CommitNow: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	if _, err := (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req); err != nil {
+		return nil, err
+	}
+	glog.Infof("Registered %d persisted queries, deleted %d", added, len(del))
+	return hashes, nil
+}
+
+// DeletePersistedQueries deletes the persisted queries with the given sha256 hashes from the
+// namespace of the context, and returns the number of deleted queries.
+func (s *Server) DeletePersistedQueries(ctx context.Context, hashes []string) (int, error) {
+	existing, err := persistedQueries(ctx)
+	if err != nil {
+		return 0, err
+	}
+	toDelete := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		toDelete[hash] = true
+	}
+	var del []*api.NQuad
+	for _, n := range existing {
+		if toDelete[n.hash()] {
+			del = append(del, &api.NQuad{
+				Subject:     n.Uid,
+				Predicate:   x.Star,
+				ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}},
+			})
+		}
+	}
+	if len(del) == 0 {
+		return 0, nil
+	}
+
+	req := &Request{
+		req: &api.Request{
+			Mutations: []*api.Mutation{{Del: del}},
+			CommitNow: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	if _, err := (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req); err != nil {
+		return 0, err
+	}
+	return len(del), nil
+}
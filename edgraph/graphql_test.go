@@ -0,0 +1,24 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistedQueryHash(t *testing.T) {
+	query := "query { queryUser { name } }"
+	hash := queryHash(query)
+	require.Len(t, hash, 64)
+	match, err := hashMatches(query, hash)
+	require.NoError(t, err)
+	require.True(t, match)
+
+	n := persistedQueryNode{Uid: "0x1", Query: hash + query}
+	require.Equal(t, hash, n.hash())
+}
@@ -0,0 +1,290 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// maxIdempotencyKeyLen is the maximum length of an idempotency key.
+	maxIdempotencyKeyLen = 256
+	// idempotencyPurgeInterval is the interval at which the expired idempotency keys are deleted.
+	idempotencyPurgeInterval = 10 * time.Minute
+	// idempotencyPurgeBatch is the number of expired idempotency keys deleted per transaction.
+	idempotencyPurgeBatch = 10000
+)
+
+// Idempotency is the idempotency key of a mutation which commits immediately. The result of the
+// mutation is stored with the key, in the namespace of the mutation and in its transaction, for
+// the idempotency-window. The retries of the mutation with the same key get the result back
+// instead of applying the mutation again, so that the retries of the clients don't create the
+// nodes twice. Two mutations with the same key running concurrently conflict, the one aborted
+// gets the result of the other when it's retried.
+type Idempotency struct {
+	Key      string
+	replayed atomic.Bool
+}
+
+// WithIdempotencyKey attaches the idempotency key to the context of a mutation.
+func WithIdempotencyKey(ctx context.Context, key string) (context.Context, *Idempotency, error) {
+	if x.Config.IdempotencyWindow <= 0 {
+		return ctx, nil, errors.Errorf(`The idempotency keys are disabled by --limit ` +
+			`"idempotency-window=0s;"`)
+	}
+	if key == "" || len(key) > maxIdempotencyKeyLen {
+		return ctx, nil, errors.Errorf("Invalid idempotency key, it should have between 1 and %d "+
+			"characters", maxIdempotencyKeyLen)
+	}
+	idem := &Idempotency{Key: key}
+	return context.WithValue(ctx, idempotencyCtxKey, idem), idem, nil
+}
+
+// Replayed returns true if the mutation wasn't applied because a mutation with the same key was,
+// the response holding the result of that mutation.
+func (i *Idempotency) Replayed() bool {
+	return i.replayed.Load()
+}
+
+// requestedIdempotencyKey returns the idempotency key of the gRPC request, from its
+// Idempotency-Key metadata, if any.
+func requestedIdempotencyKey(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	vals := md.Get(x.IdempotencyKeyHeader)
+	if len(vals) == 0 {
+		return "", false
+	}
+	return vals[0], true
+}
+
+// requestIdempotency returns the idempotency key of the mutation of the context, if any. The
+// mutations of the triggers run in the context of the mutation, without its key.
+func requestIdempotency(ctx context.Context) *Idempotency {
+	if isTrigger, _ := ctx.Value(IsTrigger).(bool); isTrigger {
+		return nil
+	}
+	idem, _ := ctx.Value(idempotencyCtxKey).(*Idempotency)
+	return idem
+}
+
+// idempotencyResult is the result of a mutation stored with its idempotency key.
+type idempotencyResult struct {
+	Uids    map[string]string `json:"uids"`
+	StartTs uint64            `json:"start_ts"`
+}
+
+type idempotencyRecord struct {
+	Uid    string    `json:"uid"`
+	Result string    `json:"dgraph.idempotency.result"`
+	Expiry time.Time `json:"dgraph.idempotency.expiry"`
+}
+
+// lookupIdempotencyKey returns the record of the idempotency key in the namespace, as of the
+// start ts of the mutation, or nil if there isn't any.
+func lookupIdempotencyKey(ctx context.Context, ns uint64, key string,
+	startTs uint64) (*idempotencyRecord, error) {
+
+	if _, ok := schema.State().Get(ctx, x.NamespaceAttr(ns, "dgraph.idempotency.key")); !ok {
+		return nil, errors.Errorf("The idempotency keys aren't supported in namespace %#x, which "+
+			"lacks the dgraph.idempotency.key predicate", ns)
+	}
+	req := &Request{
+		req: &api.Request{
+			Query: `query idempotency($key: string) {
+		records(func: eq(dgraph.idempotency.key, $key)) {
+			uid
+			dgraph.idempotency.result
+			dgraph.idempotency.expiry
+		}
+	}`,
+			Vars:    map[string]string{"$key": key},
+			StartTs: startTs,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(x.AttachNamespace(ctx, ns), req)
+	if err != nil {
+		return nil, errors.Wrap(err, "while reading the idempotency key")
+	}
+	var res struct {
+		Records []*idempotencyRecord `json:"records"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &res); err != nil {
+		return nil, errors.Wrap(err, "while reading the idempotency key")
+	}
+	if len(res.Records) == 0 {
+		return nil, nil
+	}
+	return res.Records[0], nil
+}
+
+// replayIdempotent sets the response of the mutation to the result of the mutation with the same
+// idempotency key, and returns true, if there's one which hasn't expired. Otherwise, it returns
+// the uid of the expired record of the key to reuse, if any.
+func replayIdempotent(ctx context.Context, qc *queryContext, idem *Idempotency,
+	resp *api.Response) (bool, uint64, error) {
+
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+	rec, err := lookupIdempotencyKey(ctx, ns, idem.Key, qc.req.StartTs)
+	if err != nil || rec == nil {
+		return false, 0, err
+	}
+	if rec.Expiry.Before(time.Now()) {
+		uid, err := strconv.ParseUint(rec.Uid, 0, 64)
+		return false, uid, errors.Wrap(err, "while reading the idempotency key")
+	}
+	var res idempotencyResult
+	if err := json.Unmarshal([]byte(rec.Result), &res); err != nil {
+		return false, 0, errors.Wrap(err, "while reading the result of the idempotency key")
+	}
+	glog.V(2).Infof("Replaying the mutation with idempotency key %q of namespace %#x, started "+
+		"at %d", idem.Key, ns, res.StartTs)
+	resp.Uids = res.Uids
+	resp.Txn = &api.TxnContext{StartTs: res.StartTs}
+	idem.replayed.Store(true)
+	return true, 0, nil
+}
+
+// idempotencyEdges returns the edges storing the result of the mutation with its idempotency key
+// on the node uid, until expiry.
+func idempotencyEdges(ns, uid uint64, key string, res *idempotencyResult,
+	expiry time.Time) ([]*pb.DirectedEdge, error) {
+
+	result, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	edge := func(attr string, val []byte) *pb.DirectedEdge {
+		return &pb.DirectedEdge{
+			Entity:    uid,
+			Attr:      attr,
+			Value:     val,
+			ValueType: pb.Posting_STRING,
+			Op:        pb.DirectedEdge_SET,
+			Namespace: ns,
+		}
+	}
+	return []*pb.DirectedEdge{
+		edge("dgraph.idempotency.key", []byte(key)),
+		edge("dgraph.idempotency.result", result),
+		edge("dgraph.idempotency.expiry", []byte(expiry.UTC().Format(time.RFC3339Nano))),
+		edge("dgraph.type", []byte("dgraph.idempotency")),
+	}, nil
+}
+
+// recordIdempotent returns the edges storing the result of the mutation with its idempotency
+// key, applied in the transaction of the mutation. The record of the key is stored on a new node,
+// unless the key has an expired one.
+func recordIdempotent(ctx context.Context, ns, uid uint64, idem *Idempotency,
+	res *idempotencyResult) ([]*pb.DirectedEdge, error) {
+
+	if uid == 0 {
+		ids, err := worker.AssignUidsOverNetwork(ctx, &pb.Num{Val: 1, Type: pb.Num_UID})
+		if err != nil {
+			return nil, errors.Wrap(err, "while storing the idempotency key")
+		}
+		uid = ids.StartId
+	}
+	return idempotencyEdges(ns, uid, idem.Key, res, time.Now().Add(x.Config.IdempotencyWindow))
+}
+
+// PurgeIdempotencyKeys periodically deletes the expired idempotency keys of all the namespaces.
+// Only the leader of group one deletes them.
+func PurgeIdempotencyKeys(closer *z.Closer) {
+	defer func() {
+		glog.Infoln("PurgeIdempotencyKeys closed")
+		closer.Done()
+	}()
+	if x.Config.IdempotencyWindow <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			if !worker.IsGroupOneLeader() {
+				continue
+			}
+			for ns := range schema.State().Namespaces() {
+				if err := purgeIdempotencyKeys(closer.Ctx(), ns, time.Now()); err != nil {
+					glog.Errorf("While purging the idempotency keys of namespace %#x: %v", ns, err)
+				}
+			}
+		}
+	}
+}
+
+// purgeIdempotencyKeys deletes the idempotency keys of the namespace which expired before now, by
+// batches.
+func purgeIdempotencyKeys(ctx context.Context, ns uint64, now time.Time) error {
+	if _, ok := schema.State().Get(ctx, x.NamespaceAttr(ns, "dgraph.idempotency.expiry")); !ok {
+		return nil
+	}
+	ctx = x.AttachNamespace(context.WithValue(ctx, IsGraphql, true), ns)
+	purged := 0
+	for {
+		req := &api.Request{
+			Query: `query purge($now: string) {
+		r as var(func: le(dgraph.idempotency.expiry, $now), first: ` +
+				strconv.Itoa(idempotencyPurgeBatch) + `)
+		expired(func: uid(r)) {
+			count(uid)
+		}
+	}`,
+			Vars:      map[string]string{"$now": now.UTC().Format(time.RFC3339Nano)},
+			Mutations: []*api.Mutation{{DelNquads: []byte(`uid(r) * * .`)}},
+			CommitNow: true,
+		}
+		resp, err := (&Server{}).doQuery(ctx, &Request{req: req, doAuth: NoAuthorize})
+		if err != nil {
+			return err
+		}
+		var res struct {
+			Expired []struct {
+				Count int `json:"count"`
+			} `json:"expired"`
+		}
+		if err := json.Unmarshal(resp.GetJson(), &res); err != nil {
+			return err
+		}
+		if len(res.Expired) == 0 || res.Expired[0].Count == 0 {
+			break
+		}
+		purged += res.Expired[0].Count
+		if res.Expired[0].Count < idempotencyPurgeBatch {
+			break
+		}
+	}
+	if purged > 0 {
+		glog.Infof("Purged %d expired idempotency keys of namespace %#x", purged, ns)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestWithIdempotencyKey(t *testing.T) {
+	defer func(window time.Duration) { x.Config.IdempotencyWindow = window }(
+		x.Config.IdempotencyWindow)
+
+	x.Config.IdempotencyWindow = 0
+	_, _, err := WithIdempotencyKey(context.Background(), "order-1")
+	require.ErrorContains(t, err, "disabled")
+
+	x.Config.IdempotencyWindow = time.Hour
+	_, _, err = WithIdempotencyKey(context.Background(), "")
+	require.Error(t, err)
+	_, _, err = WithIdempotencyKey(context.Background(), strings.Repeat("k", 257))
+	require.Error(t, err)
+
+	ctx, idem, err := WithIdempotencyKey(context.Background(), "order-1")
+	require.NoError(t, err)
+	require.Equal(t, "order-1", idem.Key)
+	require.False(t, idem.Replayed())
+	require.Equal(t, idem, requestIdempotency(ctx))
+	// The mutations of the triggers don't use the key of the mutation running them.
+	require.Nil(t, requestIdempotency(context.WithValue(ctx, IsTrigger, true)))
+
+	_, ok := requestedIdempotencyKey(context.Background())
+	require.False(t, ok)
+	ctx = metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(x.IdempotencyKeyHeader, "order-2"))
+	key, ok := requestedIdempotencyKey(ctx)
+	require.True(t, ok)
+	require.Equal(t, "order-2", key)
+}
+
+func TestIdempotencyEdges(t *testing.T) {
+	expiry := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	res := &idempotencyResult{Uids: map[string]string{"alice": "0x2a"}, StartTs: 7}
+	edges, err := idempotencyEdges(2, 0x10, "order-1", res, expiry)
+	require.NoError(t, err)
+	require.Len(t, edges, 4)
+
+	vals := make(map[string]string)
+	for _, e := range edges {
+		require.Equal(t, uint64(0x10), e.Entity)
+		require.Equal(t, uint64(2), e.Namespace)
+		require.Equal(t, pb.DirectedEdge_SET, e.Op)
+		vals[e.Attr] = string(e.Value)
+	}
+	require.Equal(t, "order-1", vals["dgraph.idempotency.key"])
+	require.Equal(t, "2026-10-15T12:00:00Z", vals["dgraph.idempotency.expiry"])
+	require.Equal(t, "dgraph.idempotency", vals["dgraph.type"])
+
+	var stored idempotencyResult
+	require.NoError(t, json.Unmarshal([]byte(vals["dgraph.idempotency.result"]), &stored))
+	require.Equal(t, *res, stored)
+}
@@ -0,0 +1,173 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/hypermodeinc/dgraph/v25/acl"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// ldapLogin authenticates the user against the LDAP directory, and syncs the user and its
+// groups before returning it. found is false if the user isn't in the directory, in which case
+// the user is authenticated with its Dgraph password instead.
+func ldapLogin(ctx context.Context, userid, password string) (user *acl.User, found bool,
+	err error) {
+
+	groups, err := worker.Config.Ldap.Authenticate(userid, password)
+	if errors.Is(err, x.ErrLdapUserNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	if err := syncLdapUser(ctx, userid, groups); err != nil {
+		return nil, true, errors.Wrapf(err, "while syncing LDAP user %s", userid)
+	}
+	user, err = authorizeUser(ctx, userid, "")
+	if err != nil {
+		return nil, true, err
+	}
+	if user == nil {
+		return nil, true, errors.Errorf("LDAP user %s not found after syncing it", userid)
+	}
+	return user, true, nil
+}
+
+// SyncLdap periodically syncs all the users of the LDAP directory and their groups, if the
+// sync interval is set. Only the leader of group one syncs them.
+func SyncLdap(closer *z.Closer) {
+	defer func() {
+		glog.Infoln("SyncLdap closed")
+		closer.Done()
+	}()
+	ldap := worker.Config.Ldap
+	if ldap == nil || ldap.SyncInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(ldap.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			if !worker.IsGroupOneLeader() {
+				continue
+			}
+			if err := syncLdapUsers(closer.Ctx()); err != nil {
+				glog.Errorf("Unable to sync the LDAP users: %v", err)
+			}
+		}
+	}
+}
+
+func syncLdapUsers(ctx context.Context) error {
+	users, err := worker.Config.Ldap.Users()
+	if err != nil {
+		return err
+	}
+	ctx = x.AttachNamespace(ctx, worker.Config.Ldap.Namespace)
+	synced := 0
+	for userid, groups := range users {
+		// groot is always a local user, so that Dgraph can be administered without LDAP.
+		if userid == x.GrootId {
+			continue
+		}
+		if err := syncLdapUser(ctx, userid, groups); err != nil {
+			glog.Warningf("Unable to sync LDAP user %s: %v", userid, err)
+			continue
+		}
+		synced++
+	}
+	glog.Infof("Synced %d of %d LDAP users", synced, len(users))
+	return nil
+}
+
+// syncLdapUser creates the user and its groups if they don't exist, and replaces the groups of
+// the user with the given ones. The users created have a random password, so that they can
+// only log in through LDAP.
+func syncLdapUser(ctx context.Context, userid string, groups []string) error {
+	vars := map[string]string{"$userid": userid}
+	params := []string{"$userid: string"}
+	blocks := []string{
+		"u as var(func: eq(dgraph.xid, $userid)) @filter(type(dgraph.type.User))",
+	}
+	for i, group := range groups {
+		vars[fmt.Sprintf("$g%d", i)] = group
+		params = append(params, fmt.Sprintf("$g%d: string", i))
+		blocks = append(blocks, fmt.Sprintf("g%d as var(func: eq(dgraph.xid, $g%d)) "+
+			"@filter(type(dgraph.type.Group))", i, i))
+	}
+	query := fmt.Sprintf("query sync(%s) {\n%s\n}", strings.Join(params, ", "),
+		strings.Join(blocks, "\n"))
+
+	// The blank nodes are shared by the mutations of a request, so each group has its own.
+	mus := []*api.Mutation{{
+		Set:  acl.CreateUserNQuads(userid, randomPassword()),
+		Cond: "@if(eq(len(u), 0))",
+	}}
+	for i, group := range groups {
+		nqs := acl.CreateGroupNQuads(group)
+		for _, nq := range nqs {
+			nq.Subject = fmt.Sprintf("_:group%d", i)
+		}
+		mus = append(mus, &api.Mutation{Set: nqs, Cond: fmt.Sprintf("@if(eq(len(g%d), 0))", i)})
+	}
+	if _, err := runUpsert(ctx, query, vars, mus...); err != nil {
+		return errors.Wrapf(err, "while creating the user and groups")
+	}
+
+	// The groups are deleted before being set again by the mutation.
+	var set strings.Builder
+	for i := range groups {
+		fmt.Fprintf(&set, "uid(u) <dgraph.user.group> uid(g%d) .\n", i)
+	}
+	_, err := runUpsert(ctx, query, vars, &api.Mutation{
+		DelNquads: []byte("uid(u) <dgraph.user.group> * ."),
+		SetNquads: []byte(set.String()),
+		Cond:      "@if(eq(len(u), 1))",
+	})
+	return errors.Wrapf(err, "while setting the groups")
+}
+
+// runUpsert runs the mutations conditioned on the query, bypassing ACL.
+func runUpsert(ctx context.Context, query string, vars map[string]string,
+	mus ...*api.Mutation) (*api.Response, error) {
+
+	req := &Request{
+		req: &api.Request{
+			CommitNow: true,
+			Query:     query,
+			Vars:      vars,
+			Mutations: mus,
+		},
+		doAuth: NoAuthorize,
+	}
+	return (&Server{}).doQuery(ctx, req)
+}
+
+// randomPassword returns a password nobody knows, for the users that don't log in with their
+// Dgraph password.
+func randomPassword() string {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	x.Check(err)
+	return hex.EncodeToString(b)
+}
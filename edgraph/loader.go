@@ -0,0 +1,341 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/hypermodeinc/dgraph/v25/chunker"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// defaultLoadBatchSize is the number of N-Quads loaded in a transaction, like the batch size
+	// of the live loader.
+	defaultLoadBatchSize = 1000
+	// maxLoadRetryWait caps the wait between the retries of an aborted batch.
+	maxLoadRetryWait = 10 * time.Second
+)
+
+// Loader serves the Loader service, which loads the N-Quads or JSON streamed by a client like the
+// live loader does, but from within the alpha.
+type Loader struct {
+	pb.UnimplementedLoaderServer
+}
+
+// LoadProgress is the progress of a load, streamed back after each transaction in the json of
+// the response. The uids of the response hold the uids assigned to the xids in the transaction.
+type LoadProgress struct {
+	NQuads  uint64 `json:"nquads"`
+	Txns    uint64 `json:"txns"`
+	Aborts  uint64 `json:"aborts"`
+	Elapsed string `json:"elapsed"`
+}
+
+// streamLoader loads the batches of N-Quads of a stream.
+type streamLoader struct {
+	batchSize int
+	// newUids sets whether the uids of the data are xids mapped to new uids, like the
+	// --new_uids flag of the live loader.
+	newUids bool
+	// uids maps the xids of the stream to their uids. The map lives as long as the stream, so
+	// a node is referred to by the same xid in all the chunks of the stream.
+	uids     map[string]uint64
+	progress LoadProgress
+	start    time.Time
+
+	// assignUids leases num uids, returning the first one.
+	assignUids func(ctx context.Context, num uint64) (uint64, error)
+	// mutate commits the mutation of a batch.
+	mutate func(ctx context.Context, req *api.Request) (*api.Response, error)
+	send   func(resp *api.Response) error
+}
+
+// Load loads the chunks of N-Quads or JSON of the stream, set in the SetNquads or SetJson of the
+// mutations, in transactions of batch-size N-Quads each (1000 by default, set in the metadata).
+// The chunks don't need to be split on the N-Quads, they are read as one stream. The xids of the
+// data, and its uids if new-uids is set in the metadata, are assigned new uids. The aborted
+// transactions are retried until they commit, and the progress is sent once each commits.
+func (l *Loader) Load(stream pb.Loader_LoadServer) error {
+	ctx, err := loadContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	ld, err := newStreamLoader(ctx)
+	if err != nil {
+		return err
+	}
+	ld.send = stream.Send
+
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	format, _ := loadChunk(first)
+	if format == chunker.UnknownFormat {
+		return status.Error(codes.InvalidArgument,
+			"The stream must set chunks of N-Quads or JSON with SetNquads or SetJson")
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		err := readLoadStream(stream, first, format, pw)
+		_ = pw.CloseWithError(err)
+	}()
+	defer func() { _ = pr.Close() }()
+	return ld.load(ctx, bufio.NewReader(pr), chunker.NewChunker(format, ld.batchSize))
+}
+
+// loadContext checks the credentials of the stream, and attaches its namespace, which the data
+// is loaded into whatever the namespaces of the N-Quads.
+func loadContext(ctx context.Context) (context.Context, error) {
+	if x.WorkerConfig.AclEnabled {
+		// Failing early avoids leasing the uids of the data for nothing.
+		if _, err := extractUserAndGroups(ctx); err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+	return x.AttachJWTNamespace(ctx), nil
+}
+
+func newStreamLoader(ctx context.Context) (*streamLoader, error) {
+	ld := &streamLoader{
+		batchSize: defaultLoadBatchSize,
+		uids:      make(map[string]uint64),
+		start:     time.Now(),
+		assignUids: func(ctx context.Context, num uint64) (uint64, error) {
+			ids, err := worker.AssignUidsOverNetwork(ctx, &pb.Num{Val: num})
+			if err != nil {
+				return 0, err
+			}
+			return ids.StartId, nil
+		},
+		mutate: (&Server{}).QueryNoGrpc,
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vals := md.Get("batch-size"); len(vals) > 0 {
+		size, err := strconv.Atoi(vals[0])
+		if err != nil || size <= 0 || size > x.Config.LimitMutationsNquad {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"The batch-size must be a number between 1 and %d, got %q",
+				x.Config.LimitMutationsNquad, vals[0])
+		}
+		ld.batchSize = size
+	}
+	if vals := md.Get("new-uids"); len(vals) > 0 {
+		newUids, err := strconv.ParseBool(vals[0])
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"The new-uids must be true or false, got %q", vals[0])
+		}
+		ld.newUids = newUids
+	}
+	return ld, nil
+}
+
+// loadChunk returns the format and the data of the chunk set in the mutation.
+func loadChunk(mu *api.Mutation) (chunker.InputFormat, []byte) {
+	switch {
+	case len(mu.SetNquads) > 0 && len(mu.SetJson) > 0:
+		return chunker.UnknownFormat, nil
+	case len(mu.SetNquads) > 0:
+		return chunker.RdfFormat, mu.SetNquads
+	case len(mu.SetJson) > 0:
+		return chunker.JsonFormat, mu.SetJson
+	}
+	return chunker.UnknownFormat, nil
+}
+
+// readLoadStream writes the chunks of the stream to w, until the end of the stream.
+func readLoadStream(stream pb.Loader_LoadServer, first *api.Mutation, format chunker.InputFormat,
+	w io.Writer) error {
+
+	for mu := first; ; {
+		chunkFormat, data := loadChunk(mu)
+		if chunkFormat != format {
+			return status.Error(codes.InvalidArgument,
+				"All the chunks of the stream must set the N-Quads or the JSON")
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+
+		var err error
+		if mu, err = stream.Recv(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// load parses the data read from rd, like the live loader does, and loads it in batches.
+func (ld *streamLoader) load(ctx context.Context, rd *bufio.Reader, ck chunker.Chunker) error {
+	nqbuf := ck.NQuads()
+	errCh := make(chan error, 1)
+	failed := make(chan struct{})
+	go func() {
+		var err error
+		for nqs := range nqbuf.Ch() {
+			// The batches are drained after a failure, to not block the parsing.
+			if err != nil || len(nqs) == 0 {
+				continue
+			}
+			if err = ld.loadBatch(ctx, nqs); err != nil {
+				close(failed)
+			}
+		}
+		errCh <- err
+	}()
+
+	var err error
+	for err == nil {
+		select {
+		case <-failed:
+			nqbuf.Flush()
+			return <-errCh
+		default:
+		}
+
+		var chunkBuf *bytes.Buffer
+		chunkBuf, err = ck.Chunk(rd)
+		if perr := ck.Parse(chunkBuf); perr != nil {
+			err = status.Errorf(codes.InvalidArgument, "While parsing the chunk: %v", perr)
+		}
+	}
+	nqbuf.Flush()
+	if lerr := <-errCh; lerr != nil {
+		return lerr
+	}
+	if err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// loadBatch assigns the uids of the xids of the batch, and commits it in a transaction,
+// retrying it while it's aborted.
+func (ld *streamLoader) loadBatch(ctx context.Context, nqs []*api.NQuad) error {
+	assigned, err := ld.assignXids(ctx, nqs)
+	if err != nil {
+		return err
+	}
+	for _, nq := range nqs {
+		nq.Subject = ld.uid(nq.Subject)
+		if len(nq.ObjectId) > 0 {
+			nq.ObjectId = ld.uid(nq.ObjectId)
+		}
+	}
+
+	req := &api.Request{
+		Mutations: []*api.Mutation{{Set: nqs}},
+		CommitNow: true,
+	}
+	for wait := 10 * time.Millisecond; ; wait = min(2*wait, maxLoadRetryWait) {
+		_, err := ld.mutate(ctx, req)
+		if err == nil {
+			break
+		}
+		if !isAborted(err) {
+			return err
+		}
+		ld.progress.Aborts++
+		glog.V(2).Infof("Retrying the aborted batch of %d N-Quads of the load", len(nqs))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	ld.progress.NQuads += uint64(len(nqs))
+	ld.progress.Txns++
+	ld.progress.Elapsed = time.Since(ld.start).Round(time.Millisecond).String()
+	js, err := json.Marshal(ld.progress)
+	if err != nil {
+		return err
+	}
+	return ld.send(&api.Response{Json: js, Uids: assigned})
+}
+
+// xid returns the xid of the node, if it's not an existing uid, which is kept as it is.
+func (ld *streamLoader) xid(node string) (string, bool) {
+	if !ld.newUids {
+		if _, err := strconv.ParseUint(node, 0, 64); err == nil {
+			return "", false
+		}
+	}
+	return node, true
+}
+
+// assignXids assigns new uids to the xids of the batch which don't have one yet, and returns
+// them keyed by the xids, stripped of the "_:" of the blank nodes like in mutation responses.
+func (ld *streamLoader) assignXids(ctx context.Context,
+	nqs []*api.NQuad) (map[string]string, error) {
+
+	var xids []string
+	add := func(node string) {
+		if xid, ok := ld.xid(node); ok {
+			if _, ok := ld.uids[xid]; !ok {
+				ld.uids[xid] = 0
+				xids = append(xids, xid)
+			}
+		}
+	}
+	for _, nq := range nqs {
+		add(nq.Subject)
+		if len(nq.ObjectId) > 0 {
+			add(nq.ObjectId)
+		}
+	}
+	if len(xids) == 0 {
+		return nil, nil
+	}
+
+	start, err := ld.assignUids(ctx, uint64(len(xids)))
+	if err != nil {
+		for _, xid := range xids {
+			delete(ld.uids, xid)
+		}
+		return nil, errors.Wrapf(err, "While assigning the uids of the xids")
+	}
+	assigned := make(map[string]string, len(xids))
+	for i, xid := range xids {
+		uid := start + uint64(i)
+		ld.uids[xid] = uid
+		assigned[strings.TrimPrefix(xid, "_:")] = fmt.Sprintf("%#x", uid)
+	}
+	return assigned, nil
+}
+
+// uid returns the uid of the node, once its xid has been assigned one.
+func (ld *streamLoader) uid(node string) string {
+	if xid, ok := ld.xid(node); ok {
+		return fmt.Sprintf("%#x", ld.uids[xid])
+	}
+	uid, _ := strconv.ParseUint(node, 0, 64)
+	return fmt.Sprintf("%#x", uid)
+}
@@ -0,0 +1,122 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hypermodeinc/dgraph/v25/chunker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestStreamLoader(t *testing.T) {
+	var mutations [][]*api.NQuad
+	var responses []*api.Response
+	next, aborts := uint64(100), 1
+	ld := &streamLoader{
+		batchSize: 2,
+		uids:      make(map[string]uint64),
+		assignUids: func(_ context.Context, num uint64) (uint64, error) {
+			start := next
+			next += num
+			return start, nil
+		},
+		mutate: func(_ context.Context, req *api.Request) (*api.Response, error) {
+			if aborts > 0 {
+				aborts--
+				return nil, dgo.ErrAborted
+			}
+			mutations = append(mutations, req.Mutations[0].Set)
+			return &api.Response{}, nil
+		},
+		send: func(resp *api.Response) error {
+			responses = append(responses, resp)
+			return nil
+		},
+	}
+
+	rdf := `_:a <name> "A" .
+_:b <friend> _:a .
+<0x5> <friend> _:b .
+<alice> <name> "Alice" .
+`
+	require.NoError(t, ld.load(context.Background(), bufio.NewReader(strings.NewReader(rdf)),
+		chunker.NewChunker(chunker.RdfFormat, ld.batchSize)))
+
+	require.Len(t, mutations, 2)
+	edge := func(nq *api.NQuad) string { return nq.Subject + " " + nq.Predicate + " " + nq.ObjectId }
+	require.Equal(t, "0x64 name ", edge(mutations[0][0]))
+	require.Equal(t, "0x65 friend 0x64", edge(mutations[0][1]))
+	// The xids keep their uids across the batches, and the uids are kept as they are.
+	require.Equal(t, "0x5 friend 0x65", edge(mutations[1][0]))
+	require.Equal(t, "0x66 name ", edge(mutations[1][1]))
+
+	require.Len(t, responses, 2)
+	require.Equal(t, map[string]string{"a": "0x64", "b": "0x65"}, responses[0].Uids)
+	require.Equal(t, map[string]string{"alice": "0x66"}, responses[1].Uids)
+	var progress LoadProgress
+	require.NoError(t, json.Unmarshal(responses[1].Json, &progress))
+	require.Equal(t, uint64(4), progress.NQuads)
+	require.Equal(t, uint64(2), progress.Txns)
+	require.Equal(t, uint64(1), progress.Aborts)
+
+	// With new uids, the uids of the data are xids too.
+	ld.newUids = true
+	mutations = nil
+	require.NoError(t, ld.load(context.Background(),
+		bufio.NewReader(strings.NewReader(`<0x5> <name> "E" .`)),
+		chunker.NewChunker(chunker.RdfFormat, ld.batchSize)))
+	require.Equal(t, "0x67 name ", edge(mutations[0][0]))
+
+	err := ld.load(context.Background(), bufio.NewReader(strings.NewReader(`_:a <name> .`)),
+		chunker.NewChunker(chunker.RdfFormat, ld.batchSize))
+	require.ErrorContains(t, err, "While parsing the chunk")
+}
+
+func TestLoadChunk(t *testing.T) {
+	format, data := loadChunk(&api.Mutation{SetNquads: []byte(`_:a <name> "A" .`)})
+	require.Equal(t, chunker.RdfFormat, format)
+	require.Equal(t, `_:a <name> "A" .`, string(data))
+	format, _ = loadChunk(&api.Mutation{SetJson: []byte(`{"name": "A"}`)})
+	require.Equal(t, chunker.JsonFormat, format)
+	format, _ = loadChunk(&api.Mutation{SetJson: []byte(`{}`), SetNquads: []byte(`_:a <n> "A" .`)})
+	require.Equal(t, chunker.UnknownFormat, format)
+	format, _ = loadChunk(&api.Mutation{})
+	require.Equal(t, chunker.UnknownFormat, format)
+}
+
+func TestMutationChunks(t *testing.T) {
+	mu := &api.Mutation{SetNquads: []byte(`_:a <name> "A`), CommitNow: true}
+	appendMutationChunk(mu, &api.Mutation{
+		SetNquads: []byte(`nn" .`),
+		Del:       []*api.NQuad{{Subject: "0x1", Predicate: "name"}},
+	})
+	require.Equal(t, `_:a <name> "Ann" .`, string(mu.SetNquads))
+	require.Len(t, mu.Del, 1)
+	require.True(t, mu.CommitNow)
+
+	defer func(size uint64, sizeNs map[uint64]uint64) {
+		x.Config.LimitMutationSize, x.Config.LimitMutationSizeNs = size, sizeNs
+	}(x.Config.LimitMutationSize, x.Config.LimitMutationSizeNs)
+	x.Config.LimitMutationSize = 10
+	x.Config.LimitMutationSizeNs = map[uint64]uint64{2: 0}
+	size := mutationsSize([]*api.Mutation{mu})
+	require.Greater(t, size, uint64(10))
+	err := checkMutationSize(1, size)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.NoError(t, checkMutationSize(1, 10))
+	require.NoError(t, checkMutationSize(2, size))
+}
@@ -6,13 +6,19 @@
 package edgraph
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
@@ -138,5 +144,459 @@ func (s *Server) DeleteNamespace(ctx context.Context, namespace uint64) error {
 	if _, ok := schema.State().Namespaces()[namespace]; !ok {
 		return errors.Errorf("error deleting non-existing namespace %#x", namespace)
 	}
-	return worker.ProcessDeleteNsRequest(ctx, namespace)
+	if err := worker.ProcessDeleteNsRequest(ctx, namespace); err != nil {
+		return err
+	}
+	if err := deleteNamespaceName(ctx, namespace); err != nil {
+		glog.Errorf("Unable to remove the name of deleted namespace %#x: %v", namespace, err)
+	}
+	return nil
+}
+
+// RenameNamespace sets the name of the given namespace. The names are stored in the root
+// namespace using the dgraph.namespace type, and they must be unique across the cluster.
+// Only superadmin is authorized to do so. Authorization is handled by middlewares.
+func (s *Server) RenameNamespace(ctx context.Context, namespace uint64, name string) error {
+	if _, ok := schema.State().Namespaces()[namespace]; !ok {
+		return errors.Errorf("error renaming non-existing namespace %#x", namespace)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("namespace name cannot be empty")
+	}
+
+	query := fmt.Sprintf(`{
+			ns as var(func: eq(dgraph.namespace.id, %d))
+		}`, namespace)
+	nquads := []*api.NQuad{
+		{
+			Subject:     "uid(ns)",
+			Predicate:   "dgraph.namespace.name",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: name}},
+		},
+		{
+			Subject:     "uid(ns)",
+			Predicate:   "dgraph.namespace.id",
+			ObjectValue: &api.Value{Val: &api.Value_IntVal{IntVal: int64(namespace)}},
+		},
+		{
+			Subject:     "uid(ns)",
+			Predicate:   "dgraph.type",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: "dgraph.namespace"}},
+		},
+	}
+	req := &Request{
+		req: &api.Request{
+			CommitNow: true,
+			Query:     query,
+			Mutations: []*api.Mutation{{Set: nquads}},
+		},
+		doAuth: NoAuthorize,
+	}
+	ctx = x.AttachNamespace(context.WithValue(ctx, IsGraphql, true), x.RootNamespace)
+	if _, err := (&Server{}).doQuery(ctx, req); err != nil {
+		return errors.Wrapf(err, "Renaming namespace %#x to %q, got error:", namespace, name)
+	}
+	glog.Infof("Renamed namespace %#x to %q", namespace, name)
+	return nil
+}
+
+func deleteNamespaceName(ctx context.Context, namespace uint64) error {
+	query := fmt.Sprintf(`{
+			ns as var(func: eq(dgraph.namespace.id, %d))
+		}`, namespace)
+	req := &Request{
+		req: &api.Request{
+			CommitNow: true,
+			Query:     query,
+			Mutations: []*api.Mutation{{
+				Del: []*api.NQuad{{
+					Subject:     "uid(ns)",
+					Predicate:   x.Star,
+					ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}},
+				}},
+				Cond: "@if(gt(len(ns), 0))",
+			}},
+		},
+		doAuth: NoAuthorize,
+	}
+	ctx = x.AttachNamespace(context.WithValue(ctx, IsGraphql, true), x.RootNamespace)
+	_, err := (&Server{}).doQuery(ctx, req)
+	return err
+}
+
+// namespaceNames returns the names of all the namespaces that have been given one.
+func namespaceNames(ctx context.Context) (map[uint64]string, error) {
+	req := &Request{
+		req: &api.Request{
+			Query: `{
+				namespaces(func: has(dgraph.namespace.id)) {
+					dgraph.namespace.id
+					dgraph.namespace.name
+				}
+			}`,
+			ReadOnly: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	ctx = x.AttachNamespace(ctx, x.RootNamespace)
+	resp, err := (&Server{}).doQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var nsResp struct {
+		Namespaces []struct {
+			Id   uint64 `json:"dgraph.namespace.id"`
+			Name string `json:"dgraph.namespace.name"`
+		} `json:"namespaces"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &nsResp); err != nil {
+		return nil, errors.Wrap(err, "while reading namespace names")
+	}
+	names := make(map[uint64]string, len(nsResp.Namespaces))
+	for _, ns := range nsResp.Namespaces {
+		names[ns.Id] = ns.Name
+	}
+	return names, nil
+}
+
+// cloneBatchSize is the number of nodes copied in one transaction while cloning a predicate.
+const cloneBatchSize = 1000
+
+// CloneNamespaceInternal creates a new namespace and copies the schema, types and data of the
+// source namespace into it. The ACL users and groups of the source namespace are not copied,
+// the new namespace gets its own guardians and groot with the given password. Values of
+// predicates of type password can't be read back and are also not copied. The data is read
+// at a single timestamp, so mutations to the source namespace that happen while the clone is
+// in progress are not reflected in the new namespace.
+// Only superadmin is authorized to do so. Authorization is handled by middlewares.
+func (s *Server) CloneNamespaceInternal(ctx context.Context, src uint64, passwd string) (
+	uint64, error) {
+
+	if _, ok := schema.State().Namespaces()[src]; !ok {
+		return 0, errors.Errorf("error cloning non-existing namespace %#x", src)
+	}
+	readTs := worker.State.GetTimestamp(true)
+
+	dst, err := s.CreateNamespaceInternal(ctx, passwd)
+	if err != nil {
+		return 0, err
+	}
+	glog.Infof("Cloning namespace %#x into %#x at readTs: %d", src, dst, readTs)
+
+	var preds []*pb.SchemaUpdate
+	m := &pb.Mutations{StartTs: worker.State.GetTimestamp(false)}
+	for _, pred := range schema.State().Predicates() {
+		ns, attr := x.ParseNamespaceAttr(pred)
+		if ns != src {
+			continue
+		}
+		su, ok := schema.State().Get(ctx, pred)
+		if !ok {
+			continue
+		}
+		if isClonedPredicate(attr) {
+			preds = append(preds, &su)
+		}
+		if x.IsPreDefinedPredicate(attr) {
+			continue
+		}
+		update := proto.Clone(&su).(*pb.SchemaUpdate)
+		update.Predicate = x.NamespaceAttr(dst, attr)
+		m.Schema = append(m.Schema, update)
+	}
+	for _, typ := range schema.State().Types() {
+		ns, name := x.ParseNamespaceAttr(typ)
+		if ns != src || x.IsPreDefinedType(name) {
+			continue
+		}
+		tu, ok := schema.State().GetType(typ)
+		if !ok {
+			continue
+		}
+		update := proto.Clone(&tu).(*pb.TypeUpdate)
+		update.TypeName = x.NamespaceAttr(dst, name)
+		for _, field := range update.Fields {
+			field.Predicate = x.NamespaceAttr(dst, x.ParseAttr(field.Predicate))
+		}
+		m.Types = append(m.Types, update)
+	}
+	if len(m.Schema) > 0 || len(m.Types) > 0 {
+		if _, err := query.ApplyMutations(x.AttachNamespace(ctx, dst), m); err != nil {
+			return dst, errors.Wrapf(err, "while cloning schema of namespace %#x", src)
+		}
+	}
+
+	sort.Slice(preds, func(i, j int) bool { return preds[i].Predicate < preds[j].Predicate })
+	for _, su := range preds {
+		if err := clonePredicate(ctx, su, src, dst, readTs); err != nil {
+			return dst, errors.Wrapf(err, "while cloning predicate %s of namespace %#x",
+				x.ParseAttr(su.Predicate), src)
+		}
+	}
+	glog.Infof("Cloned namespace %#x into %#x", src, dst)
+	return dst, nil
+}
+
+// isClonedPredicate returns true if the data of the predicate should be copied while cloning
+// a namespace. ACL data isn't copied because the new namespace gets its own guardians and groot,
+// and the API keys, triggers, wasm modules and stored functions aren't copied because they are only
+// stored in the root namespace. The idempotency keys of the source namespace aren't copied either,
+// the retries of its mutations shouldn't be replayed in the new namespace.
+func isClonedPredicate(attr string) bool {
+	switch {
+	case attr == "dgraph.drop.op" || strings.HasPrefix(attr, "dgraph.namespace.") ||
+		strings.HasPrefix(attr, "dgraph.apikey.") || strings.HasPrefix(attr, "dgraph.trigger.") ||
+		strings.HasPrefix(attr, "dgraph.wasm.") || strings.HasPrefix(attr, "dgraph.function.") ||
+		strings.HasPrefix(attr, "dgraph.idempotency."):
+		return false
+	case x.IsAclPredicate(attr):
+		return false
+	}
+	return true
+}
+
+// clonePredicateQuery returns the query that reads a batch of nodes having the predicate
+// along with its values, language tags and facets, such that the JSON response can be used
+// as a mutation.
+func clonePredicateQuery(su *pb.SchemaUpdate, after uint64) string {
+	attr := x.ParseAttr(su.Predicate)
+	var field string
+	switch {
+	case su.ValueType == pb.Posting_UID:
+		field = fmt.Sprintf("<%s> @facets { uid }", attr)
+	case su.Lang:
+		field = fmt.Sprintf("<%s>@*", attr)
+	default:
+		field = fmt.Sprintf("<%s> @facets", attr)
+	}
+	return fmt.Sprintf(`{
+		nodes(func: has(<%s>), first: %d, after: %#x) {
+			uid
+			%s
+		}
+	}`, attr, cloneBatchSize, after, field)
+}
+
+// fixClonedNodes fixes up the nodes read while cloning a predicate so that they can be
+// mutated into the new namespace.
+func fixClonedNodes(su *pb.SchemaUpdate, nodes []map[string]interface{}) error {
+	attr := x.ParseAttr(su.Predicate)
+	for _, node := range nodes {
+		val, ok := node[attr]
+		if !ok {
+			continue
+		}
+		switch {
+		case attr == "dgraph.type":
+			// The ACL types are set on the users and groups, which aren't cloned.
+			vals, _ := val.([]interface{})
+			kept := vals[:0]
+			for _, v := range vals {
+				if typ, _ := v.(string); !strings.HasPrefix(typ, "dgraph.type.") {
+					kept = append(kept, v)
+				}
+			}
+			if len(kept) == 0 {
+				delete(node, attr)
+			} else {
+				node[attr] = kept
+			}
+		case su.ValueType == pb.Posting_VFLOAT:
+			// Vectors are returned as a JSON array but need to be mutated as a string.
+			vec, err := json.Marshal(val)
+			if err != nil {
+				return err
+			}
+			node[attr] = string(vec)
+		}
+	}
+	return nil
+}
+
+func clonePredicate(ctx context.Context, su *pb.SchemaUpdate, src, dst, readTs uint64) error {
+	if su.ValueType == pb.Posting_PASSWORD {
+		glog.Warningf("Skipping data of password predicate %s while cloning namespace %#x",
+			x.ParseAttr(su.Predicate), src)
+		return nil
+	}
+
+	srcCtx := x.AttachNamespace(ctx, src)
+	dstCtx := x.AttachNamespace(context.WithValue(ctx, IsGraphql, true), dst)
+	var after uint64
+	for {
+		resp, err := (&Server{}).doQuery(srcCtx, &Request{
+			req: &api.Request{
+				Query:    clonePredicateQuery(su, after),
+				StartTs:  readTs,
+				ReadOnly: true,
+			},
+			doAuth: NoAuthorize,
+		})
+		if err != nil {
+			return err
+		}
+
+		var batch struct {
+			Nodes []map[string]interface{} `json:"nodes"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(resp.GetJson()))
+		dec.UseNumber()
+		if err := dec.Decode(&batch); err != nil {
+			return err
+		}
+		if len(batch.Nodes) == 0 {
+			return nil
+		}
+		last, _ := batch.Nodes[len(batch.Nodes)-1]["uid"].(string)
+		if after, err = strconv.ParseUint(last, 0, 64); err != nil {
+			return errors.Wrapf(err, "while parsing uid %q", last)
+		}
+
+		if err := fixClonedNodes(su, batch.Nodes); err != nil {
+			return err
+		}
+		data, err := json.Marshal(batch.Nodes)
+		if err != nil {
+			return err
+		}
+		if _, err := (&Server{}).doQuery(dstCtx, &Request{
+			req: &api.Request{
+				Mutations: []*api.Mutation{{SetJson: data}},
+				CommitNow: true,
+			},
+			doAuth: NoAuthorize,
+		}); err != nil {
+			return err
+		}
+		if len(batch.Nodes) < cloneBatchSize {
+			return nil
+		}
+	}
+}
+
+// NamespaceUsage is the resource usage of a namespace.
+type NamespaceUsage struct {
+	Id                uint64
+	Name              string
+	Predicates        int
+	Types             int
+	OnDiskBytes       int64
+	UncompressedBytes int64
+	// QPS is the rate of queries and mutations served by this alpha for the namespace,
+	// averaged over the last minute.
+	QPS float64
+}
+
+// GetNamespaceUsage returns the usage of all the namespaces, sorted by the namespace id.
+// The disk usage is computed from the tablet sizes reported to Zero, so it lags behind
+// the actual usage. Only superadmin is authorized to do so. Authorization is handled by
+// middlewares.
+func (s *Server) GetNamespaceUsage(ctx context.Context) ([]*NamespaceUsage, error) {
+	names, err := namespaceNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[uint64]*NamespaceUsage)
+	for ns := range schema.State().Namespaces() {
+		usage[ns] = &NamespaceUsage{Id: ns, Name: names[ns]}
+	}
+	for _, pred := range schema.State().Predicates() {
+		if u, ok := usage[x.ParseNamespace(pred)]; ok {
+			u.Predicates++
+		}
+	}
+	for _, typ := range schema.State().Types() {
+		if u, ok := usage[x.ParseNamespace(typ)]; ok {
+			u.Types++
+		}
+	}
+	for _, group := range worker.GetMembershipState().GetGroups() {
+		for pred, tablet := range group.GetTablets() {
+			if u, ok := usage[x.ParseNamespace(pred)]; ok {
+				u.OnDiskBytes += tablet.GetOnDiskBytes()
+				u.UncompressedBytes += tablet.GetUncompressedBytes()
+			}
+		}
+	}
+
+	now := time.Now().Unix()
+	result := make([]*NamespaceUsage, 0, len(usage))
+	for ns, u := range usage {
+		u.QPS = nsRequests.rate(ns, now)
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result, nil
+}
+
+// qpsWindow is the number of seconds over which the request rate of a namespace is averaged.
+const qpsWindow = 60
+
+// requestRing counts the requests in each of the last qpsWindow seconds.
+type requestRing struct {
+	sync.Mutex
+	secs   [qpsWindow]int64
+	counts [qpsWindow]uint64
+}
+
+func (r *requestRing) add(now int64) {
+	r.Lock()
+	defer r.Unlock()
+	i := now % qpsWindow
+	if r.secs[i] != now {
+		r.secs[i] = now
+		r.counts[i] = 0
+	}
+	r.counts[i]++
+}
+
+func (r *requestRing) rate(now int64) float64 {
+	r.Lock()
+	defer r.Unlock()
+	var total uint64
+	for i, sec := range r.secs {
+		if sec <= now && now-sec < qpsWindow {
+			total += r.counts[i]
+		}
+	}
+	return float64(total) / qpsWindow
+}
+
+type namespaceRequests struct {
+	rings sync.Map // namespace -> *requestRing
+}
+
+var nsRequests = &namespaceRequests{}
+
+// BusiestNamespace returns the namespace with the most requests over the last minute, if any.
+func BusiestNamespace() (uint64, bool) {
+	now := time.Now().Unix()
+	var busiest uint64
+	var maxRate float64
+	nsRequests.rings.Range(func(k, v any) bool {
+		if rate := v.(*requestRing).rate(now); rate > maxRate {
+			busiest, maxRate = k.(uint64), rate
+		}
+		return true
+	})
+	return busiest, maxRate > 0
+}
+
+func (n *namespaceRequests) record(ns uint64, now int64) {
+	r, ok := n.rings.Load(ns)
+	if !ok {
+		r, _ = n.rings.LoadOrStore(ns, &requestRing{})
+	}
+	r.(*requestRing).add(now)
+}
+
+func (n *namespaceRequests) rate(ns uint64, now int64) float64 {
+	r, ok := n.rings.Load(ns)
+	if !ok {
+		return 0
+	}
+	return r.(*requestRing).rate(now)
 }
@@ -0,0 +1,75 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestRequestRing(t *testing.T) {
+	r := &requestRing{}
+	for i := 0; i < 30; i++ {
+		r.add(1000)
+	}
+	for i := 0; i < 30; i++ {
+		r.add(1030)
+	}
+	require.InDelta(t, 1.0, r.rate(1030), 1e-9)
+	// The requests older than the window are forgotten.
+	require.InDelta(t, 0.5, r.rate(1060), 1e-9)
+	require.InDelta(t, 0.0, r.rate(1090), 1e-9)
+
+	// A bucket is reused once the window wraps around.
+	r.add(1000 + 2*qpsWindow)
+	require.InDelta(t, 1.0/qpsWindow, r.rate(1000+2*qpsWindow), 1e-9)
+}
+
+func TestClonePredicateQuery(t *testing.T) {
+	for _, su := range []*pb.SchemaUpdate{
+		{Predicate: x.AttrInRootNamespace("friend"), ValueType: pb.Posting_UID},
+		{Predicate: x.AttrInRootNamespace("name"), ValueType: pb.Posting_STRING, Lang: true},
+		{Predicate: x.AttrInRootNamespace("age"), ValueType: pb.Posting_INT},
+	} {
+		_, err := dql.Parse(dql.Request{Str: clonePredicateQuery(su, 0x10)})
+		require.NoError(t, err)
+	}
+}
+
+func TestIsClonedPredicate(t *testing.T) {
+	require.True(t, isClonedPredicate("name"))
+	require.True(t, isClonedPredicate("dgraph.type"))
+	require.True(t, isClonedPredicate("dgraph.graphql.schema"))
+	require.False(t, isClonedPredicate("dgraph.password"))
+	require.False(t, isClonedPredicate("dgraph.namespace.name"))
+	require.False(t, isClonedPredicate("dgraph.drop.op"))
+	require.False(t, isClonedPredicate("dgraph.apikey.hash"))
+	require.False(t, isClonedPredicate("dgraph.trigger.spec"))
+	require.False(t, isClonedPredicate("dgraph.wasm.code"))
+	require.False(t, isClonedPredicate("dgraph.function.query"))
+	require.False(t, isClonedPredicate("dgraph.idempotency.key"))
+}
+
+func TestFixClonedNodes(t *testing.T) {
+	types := []map[string]interface{}{
+		{"uid": "0x1", "dgraph.type": []interface{}{"Person", "dgraph.type.User"}},
+		{"uid": "0x2", "dgraph.type": []interface{}{"dgraph.type.Group"}},
+	}
+	su := &pb.SchemaUpdate{Predicate: x.AttrInRootNamespace("dgraph.type")}
+	require.NoError(t, fixClonedNodes(su, types))
+	require.Equal(t, []interface{}{"Person"}, types[0]["dgraph.type"])
+	require.NotContains(t, types[1], "dgraph.type")
+
+	vectors := []map[string]interface{}{{"uid": "0x1", "vec": []interface{}{1.5, 2}}}
+	su = &pb.SchemaUpdate{Predicate: x.AttrInRootNamespace("vec"), ValueType: pb.Posting_VFLOAT}
+	require.NoError(t, fixClonedNodes(su, vectors))
+	require.Equal(t, "[1.5,2]", vectors[0]["vec"])
+}
@@ -0,0 +1,97 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"io"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// Mutate runs the mutation streamed in chunks in one transaction. The N-Quads and the JSON of the
+// chunks are concatenated, while the condition and the CommitNow of the mutation are taken from
+// its first chunk. The size of the mutation is checked as its chunks are received, so that a
+// mutation beyond the mutation-size-mb limit fails before it's received whole. The mutation is
+// split into proposals of at most proposal-edges edges, like the other mutations.
+func (l *Loader) Mutate(stream pb.Loader_MutateServer) error {
+	ctx, err := loadContext(stream.Context())
+	if err != nil {
+		return err
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return err
+	}
+
+	var mu *api.Mutation
+	var size uint64
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		size += uint64(proto.Size(chunk))
+		if err := checkMutationSize(ns, size); err != nil {
+			return err
+		}
+		if mu == nil {
+			mu = chunk
+		} else {
+			appendMutationChunk(mu, chunk)
+		}
+	}
+	if mu == nil {
+		return status.Error(codes.InvalidArgument, "The stream must have at least one chunk")
+	}
+
+	resp, err := (&Server{}).QueryNoGrpc(ctx, &api.Request{
+		Mutations: []*api.Mutation{mu},
+		CommitNow: mu.CommitNow,
+	})
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+
+// appendMutationChunk appends the N-Quads and the JSON of the chunk to the mutation.
+func appendMutationChunk(mu, chunk *api.Mutation) {
+	mu.SetJson = append(mu.SetJson, chunk.SetJson...)
+	mu.DeleteJson = append(mu.DeleteJson, chunk.DeleteJson...)
+	mu.SetNquads = append(mu.SetNquads, chunk.SetNquads...)
+	mu.DelNquads = append(mu.DelNquads, chunk.DelNquads...)
+	mu.Set = append(mu.Set, chunk.Set...)
+	mu.Del = append(mu.Del, chunk.Del...)
+}
+
+// mutationsSize returns the size in bytes of the mutations.
+func mutationsSize(mus []*api.Mutation) uint64 {
+	var size uint64
+	for _, mu := range mus {
+		size += uint64(proto.Size(mu))
+	}
+	return size
+}
+
+// checkMutationSize returns an error if the size in bytes of the mutations of a request goes
+// beyond the mutation-size-mb limit of the namespace.
+func checkMutationSize(ns, size uint64) error {
+	if limit := x.Config.NsMutationSize(ns); limit > 0 && size > limit {
+		return status.Errorf(codes.ResourceExhausted,
+			"The mutations are larger than the limit of %d bytes of the namespace %#x, "+
+				"with at least %d bytes. Split them into several requests, or load them with "+
+				"the Load method of the Loader service.", limit, ns, size)
+	}
+	return nil
+}
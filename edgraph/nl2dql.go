@@ -0,0 +1,191 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// nl2dqlPrompt holds the instructions given to the LLM translating the questions into DQL.
+//
+//go:embed nl2dql_prompt.txt
+var nl2dqlPrompt string
+
+// nl2dql is the LLM translating the questions of /nl2dql into DQL, nil if /nl2dql isn't enabled.
+// retries is the number of times the LLM is asked to fix a query which is invalid or fails.
+var nl2dql struct {
+	llm     x.LLM
+	retries int
+}
+
+// InitNL2DQL enables /nl2dql, translating the questions with the LLM.
+func InitNL2DQL(llm x.LLM, retries int) {
+	nl2dql.llm, nl2dql.retries = llm, retries
+}
+
+// NL2DQLEnabled returns true if /nl2dql is enabled.
+func NL2DQLEnabled() bool {
+	return nl2dql.llm != nil
+}
+
+// NL2DQLResponse is the answer to a natural language question.
+type NL2DQLResponse struct {
+	// Query is the DQL query generated for the question, set even if it's invalid or failed.
+	Query string
+	Resp  *api.Response
+}
+
+// NL2DQL answers the natural language question: it asks the LLM to translate it into a DQL query
+// given the schema of the namespace visible to the user, and runs the query for the user as a
+// read-only query. The query is subject to the query limits of the namespace, or to its sandbox.
+// The LLM is asked to fix the queries which don't parse or fail, up to the retries of --nl2dql.
+func (s *Server) NL2DQL(ctx context.Context, question string) (*NL2DQLResponse, error) {
+	if !NL2DQLEnabled() {
+		return nil, errors.Errorf("/nl2dql isn't enabled, see --nl2dql")
+	}
+	resp, err := s.QueryNoGrpc(ctx, &api.Request{Query: "schema {}", ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading the schema")
+	}
+	sch, err := nl2dqlSchema(resp.GetJson())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &NL2DQLResponse{}
+	var prev error
+	for attempt := 0; attempt <= nl2dql.retries; attempt++ {
+		var answer string
+		answer, err = nl2dql.llm.Complete(ctx, nl2dqlPrompt, nl2dqlQuestion(sch, question,
+			res.Query, prev))
+		if err != nil {
+			return res, errors.Wrapf(err, "while asking the %s LLM", nl2dql.llm.Name())
+		}
+		res.Query = generatedQuery(answer)
+		if err = checkGeneratedQuery(res.Query); err != nil {
+			err = errors.Wrapf(err, "the generated query %q is invalid", res.Query)
+		} else if res.Resp, err = s.QueryNoGrpc(ctx, &api.Request{Query: res.Query,
+			ReadOnly: true}); err != nil {
+			err = errors.Wrapf(err, "the generated query %q failed", res.Query)
+		}
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+		glog.V(2).Infof("NL2DQL attempt %d for %q: %v", attempt+1, question, err)
+		prev = err
+	}
+	return res, err
+}
+
+// nl2dqlQuestion returns the prompt asking the LLM to translate the question, and to fix the
+// previous query if it was invalid or failed.
+func nl2dqlQuestion(sch, question, prevQuery string, prevErr error) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Schema:\n%s\nQuestion: %s\n", sch, question)
+	if prevErr != nil {
+		fmt.Fprintf(&b, "\nYour previous query was:\n%s\nIt didn't work: %v\nAnswer with a "+
+			"fixed query.\n", prevQuery, prevErr)
+	}
+	return b.String()
+}
+
+// nl2dqlSchema returns the schema in the JSON result of a schema query, in the DQL schema format.
+// The predicates and types of Dgraph are left out.
+func nl2dqlSchema(js []byte) (string, error) {
+	var res struct {
+		Schema []struct {
+			Predicate string   `json:"predicate"`
+			Type      string   `json:"type"`
+			Tokenizer []string `json:"tokenizer"`
+			Reverse   bool     `json:"reverse"`
+			Count     bool     `json:"count"`
+			List      bool     `json:"list"`
+			Lang      bool     `json:"lang"`
+		} `json:"schema"`
+		Types []struct {
+			Name   string `json:"name"`
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+		} `json:"types"`
+	}
+	if err := json.Unmarshal(js, &res); err != nil {
+		return "", errors.Wrapf(err, "while reading the schema")
+	}
+	var b strings.Builder
+	for _, p := range res.Schema {
+		if strings.HasPrefix(p.Predicate, "dgraph.") {
+			continue
+		}
+		typ := p.Type
+		if p.List {
+			typ = "[" + typ + "]"
+		}
+		fmt.Fprintf(&b, "<%s>: %s", p.Predicate, typ)
+		if len(p.Tokenizer) > 0 {
+			fmt.Fprintf(&b, " @index(%s)", strings.Join(p.Tokenizer, ", "))
+		}
+		for _, d := range []struct {
+			set  bool
+			name string
+		}{{p.Reverse, "@reverse"}, {p.Count, "@count"}, {p.Lang, "@lang"}} {
+			if d.set {
+				b.WriteString(" " + d.name)
+			}
+		}
+		b.WriteString(" .\n")
+	}
+	for _, t := range res.Types {
+		if strings.HasPrefix(t.Name, "dgraph.") {
+			continue
+		}
+		fields := make([]string, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			fields = append(fields, f.Name)
+		}
+		fmt.Fprintf(&b, "type <%s> {\n  %s\n}\n", t.Name, strings.Join(fields, "\n  "))
+	}
+	return b.String(), nil
+}
+
+// generatedQuery returns the query in the answer of the LLM, taking it out of its code block if
+// the answer has one.
+func generatedQuery(answer string) string {
+	if _, after, ok := strings.Cut(answer, "```"); ok {
+		// Skip the language of the code block, like dql.
+		if i := strings.IndexByte(after, '\n'); i >= 0 {
+			after = after[i+1:]
+		}
+		answer, _, _ = strings.Cut(after, "```")
+	}
+	return strings.TrimSpace(answer)
+}
+
+// checkGeneratedQuery returns an error if the query doesn't parse, or doesn't read data. The
+// mutations and upserts don't parse as queries.
+func checkGeneratedQuery(q string) error {
+	res, err := dql.Parse(dql.Request{Str: q})
+	switch {
+	case err != nil:
+		return err
+	case res.Schema != nil:
+		return errors.Errorf("it should read the data, not the schema")
+	case len(res.Query) == 0:
+		return errors.Errorf("it has no query block")
+	}
+	return nil
+}
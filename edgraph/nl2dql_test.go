@@ -0,0 +1,59 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNL2DQLSchema(t *testing.T) {
+	js := `{"schema": [
+		{"predicate": "dgraph.type", "type": "string", "index": true, "tokenizer": ["exact"],
+			"list": true},
+		{"predicate": "name", "type": "string", "index": true, "tokenizer": ["term", "exact"],
+			"lang": true},
+		{"predicate": "friend", "type": "uid", "reverse": true, "count": true, "list": true}
+	], "types": [
+		{"name": "Person", "fields": [{"name": "name"}, {"name": "friend"}]},
+		{"name": "dgraph.graphql", "fields": [{"name": "dgraph.graphql.schema"}]}
+	]}`
+	sch, err := nl2dqlSchema([]byte(js))
+	require.NoError(t, err)
+	require.Equal(t, "<name>: string @index(term, exact) @lang .\n"+
+		"<friend>: [uid] @reverse @count .\n"+
+		"type <Person> {\n  name\n  friend\n}\n", sch)
+
+	_, err = nl2dqlSchema([]byte(`{"schema": 1}`))
+	require.Error(t, err)
+}
+
+func TestGeneratedQuery(t *testing.T) {
+	q := "{\n  q(func: has(name)) { name }\n}"
+	require.Equal(t, q, generatedQuery(" "+q+"\n"))
+	require.Equal(t, q, generatedQuery("Here it is:\n```dql\n"+q+"\n```\nIt reads the names."))
+	require.NoError(t, checkGeneratedQuery(q))
+
+	for _, q := range []string{
+		"{ q(func: has(name) { name } }",
+		"schema {}",
+		"{ set { _:a <name> \"a\" . } }",
+		"upsert { query { q(func: has(name)) { v as uid } } mutation { delete { uid(v) * * . } } }",
+		"",
+	} {
+		require.Error(t, checkGeneratedQuery(q), q)
+	}
+}
+
+func TestNL2DQLQuestion(t *testing.T) {
+	prompt := nl2dqlQuestion("<name>: string .\n", "Who is there?", "", nil)
+	require.Equal(t, "Schema:\n<name>: string .\n\nQuestion: Who is there?\n", prompt)
+	prompt = nl2dqlQuestion("<name>: string .\n", "Who is there?", "{ q() }",
+		errors.New("invalid"))
+	require.Contains(t, prompt, "{ q() }\nIt didn't work: invalid")
+}
@@ -0,0 +1,108 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types/facets"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// The facets recorded on the values of the @provenance predicates.
+const (
+	// provenanceChangedBy is the user who set the value, recorded when ACL is enabled.
+	provenanceChangedBy = "changed_by"
+	// provenanceChangedAt is the time the value was set at.
+	provenanceChangedAt = "changed_at"
+	// provenanceTxnTs is the start ts of the transaction which set the value.
+	provenanceTxnTs = "txn_ts"
+	// provenanceRequestId is the id of the request which set the value, from the x-request-id
+	// metadata or else the trace id of the request.
+	provenanceRequestId = "request_id"
+)
+
+// provenanceFacets returns the provenance facets of the values set by the request in ctx.
+func provenanceFacets(ctx context.Context, startTs uint64) ([]*api.Facet, error) {
+	var fs []*api.Facet
+	add := func(key, val string) error {
+		f, err := facets.FacetFor(key, val)
+		if err != nil {
+			return err
+		}
+		fs = append(fs, f)
+		return nil
+	}
+
+	if x.WorkerConfig.AclEnabled {
+		userData, err := extractUserAndGroups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(provenanceChangedBy, strconv.Quote(userData.userId)); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(provenanceChangedAt, time.Now().UTC().Format(time.RFC3339Nano)); err != nil {
+		return nil, err
+	}
+	if err := add(provenanceTxnTs, strconv.FormatUint(startTs, 10)); err != nil {
+		return nil, err
+	}
+	requestId := x.ExtractRequestId(ctx)
+	if sc := trace.SpanContextFromContext(ctx); requestId == "" && sc.HasTraceID() {
+		requestId = sc.TraceID().String()
+	}
+	if requestId != "" {
+		if err := add(provenanceRequestId, strconv.Quote(requestId)); err != nil {
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+// addProvenanceFacets records the provenance facets on the values set by the edges on the
+// @provenance predicates, replacing the facets of the same keys given by the client. The
+// deleted values lose their facets with them, so only the last set of a value is recorded.
+func addProvenanceFacets(ctx context.Context, ns uint64, edges []*pb.DirectedEdge,
+	startTs uint64) error {
+
+	var provenance []*api.Facet
+	for _, e := range edges {
+		if e.Op != pb.DirectedEdge_SET || e.Attr == x.Star ||
+			!schema.State().HasProvenance(x.NamespaceAttr(ns, e.Attr)) {
+			continue
+		}
+		if provenance == nil {
+			var err error
+			if provenance, err = provenanceFacets(ctx, startTs); err != nil {
+				return err
+			}
+		}
+
+		// The edges of a nquad share its facets, so they are copied before being changed.
+		fs := make([]*api.Facet, 0, len(e.Facets)+len(provenance))
+		for _, f := range e.Facets {
+			switch f.Key {
+			case provenanceChangedBy, provenanceChangedAt, provenanceTxnTs, provenanceRequestId:
+			default:
+				fs = append(fs, f)
+			}
+		}
+		fs = append(fs, provenance...)
+		sort.Slice(fs, func(i, j int) bool { return fs[i].Key < fs[j].Key })
+		e.Facets = fs
+	}
+	return nil
+}
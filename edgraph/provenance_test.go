@@ -0,0 +1,62 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types/facets"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestAddProvenanceFacets(t *testing.T) {
+	initTriggerSchema(t)
+	schema.State().Set(x.NamespaceAttr(1, "name"), &pb.SchemaUpdate{
+		Predicate: x.NamespaceAttr(1, "name"), Provenance: true})
+
+	weight, err := facets.FacetFor("weight", "0.5")
+	require.NoError(t, err)
+	forged, err := facets.FacetFor("txn_ts", "1")
+	require.NoError(t, err)
+	shared := []*api.Facet{forged, weight}
+	edges := []*pb.DirectedEdge{
+		{Entity: 1, Attr: "name", Value: []byte("Ann"), Facets: shared},
+		{Entity: 2, Attr: "name", Value: []byte("Bob"), Facets: shared},
+		{Entity: 1, Attr: "age", Value: []byte("20"), Facets: shared},
+		{Entity: 3, Attr: "name", Value: []byte("Cid"), Op: pb.DirectedEdge_DEL},
+	}
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("x-request-id", "req-7"))
+	require.NoError(t, addProvenanceFacets(ctx, 1, edges, 42))
+
+	keys := func(fs []*api.Facet) []string {
+		var keys []string
+		for _, f := range fs {
+			keys = append(keys, f.Key)
+		}
+		return keys
+	}
+	for _, e := range edges[:2] {
+		require.Equal(t, []string{"changed_at", "request_id", "txn_ts", "weight"}, keys(e.Facets))
+		requestId, err := facets.ValFor(e.Facets[1])
+		require.NoError(t, err)
+		require.Equal(t, "req-7", requestId.Value)
+		txnTs, err := facets.ValFor(e.Facets[2])
+		require.NoError(t, err)
+		require.Equal(t, int64(42), txnTs.Value)
+	}
+	// The predicates without @provenance and the deletions are left as they are.
+	require.Equal(t, shared, edges[2].Facets)
+	require.Equal(t, []string{"txn_ts", "weight"}, keys(shared))
+	require.Empty(t, edges[3].Facets)
+}
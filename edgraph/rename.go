@@ -0,0 +1,315 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/query"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	renameKindPredicate = "predicate"
+	renameKindType      = "type"
+
+	// renameTypeBatchSize is the number of nodes moved to the new type per transaction.
+	renameTypeBatchSize = 1000
+)
+
+// renameRe matches a rename given as the schema of an alter operation, like
+// rename predicate <name> to <fullName>, or rename type Person to User.
+var renameRe = regexp.MustCompile(
+	`^\s*rename\s+(predicate|type)\s+<?([^\s<>]+)>?\s+to\s+<?([^\s<>]+)>?\s*$`)
+
+// renameOp renames the predicate or the type from to to.
+type renameOp struct {
+	kind     string
+	from, to string
+}
+
+// parseRename returns the rename given as the schema of an alter operation, or nil if the schema
+// isn't a rename.
+func parseRename(sch string) *renameOp {
+	m := renameRe.FindStringSubmatch(sch)
+	if m == nil {
+		return nil
+	}
+	return &renameOp{kind: m[1], from: m[2], to: m[3]}
+}
+
+// preds returns the predicates modified by the rename.
+func (op *renameOp) preds() []string {
+	if op.kind == renameKindType {
+		return []string{"dgraph.type"}
+	}
+	return []string{op.from, op.to}
+}
+
+// RenameStatus reports the progress of renaming a predicate or a type.
+type RenameStatus struct {
+	Id        uint64
+	Namespace uint64
+	// Kind is either predicate or type.
+	Kind   string
+	From   string
+	To     string
+	Status string
+	// Nodes is the number of nodes moved to the new type so far, when renaming a type.
+	Nodes     int
+	Error     string
+	StartedAt time.Time
+	UpdatedAt time.Time
+}
+
+// renameTracker tracks the renames run by this Alpha.
+type renameTracker struct {
+	sync.Mutex
+	lastId  uint64
+	renames map[uint64]*RenameStatus
+}
+
+var renames = &renameTracker{renames: make(map[uint64]*RenameStatus)}
+
+func (t *renameTracker) start(ns uint64, op *renameOp) *RenameStatus {
+	t.Lock()
+	defer t.Unlock()
+	now := time.Now()
+	for id, st := range t.renames {
+		if st.Status != jobRunning && now.Sub(st.UpdatedAt) > jobRetention {
+			delete(t.renames, id)
+		}
+	}
+	t.lastId++
+	st := &RenameStatus{Id: t.lastId, Namespace: ns, Kind: op.kind, From: op.from, To: op.to,
+		Status: jobRunning, StartedAt: now, UpdatedAt: now}
+	t.renames[st.Id] = st
+	return st
+}
+
+func (t *renameTracker) update(st *RenameStatus, nodes int) {
+	t.Lock()
+	defer t.Unlock()
+	st.Nodes += nodes
+	st.UpdatedAt = time.Now()
+}
+
+func (t *renameTracker) finish(st *RenameStatus, err error) {
+	t.Lock()
+	defer t.Unlock()
+	st.Status, st.UpdatedAt = jobSuccess, time.Now()
+	if err != nil {
+		st.Status, st.Error = jobFailed, err.Error()
+	}
+}
+
+// list returns a copy of the renames of the namespace, the latest first.
+func (t *renameTracker) list(ns uint64) []RenameStatus {
+	t.Lock()
+	defer t.Unlock()
+	var res []RenameStatus
+	for _, st := range t.renames {
+		if st.Namespace == ns {
+			res = append(res, *st)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id > res[j].Id })
+	return res
+}
+
+// rename starts renaming the predicate or the type of op in the namespace, in the background.
+// Unless runInBackground is set, it waits for the rename to finish. The returned payload has
+// the id of the rename, whose progress is reported by Renames.
+func (s *Server) rename(ctx context.Context, ns uint64, op *renameOp,
+	runInBackground bool) (*api.Payload, error) {
+	// Like for the other schema changes, a rename waits for the indexes being built.
+	if schema.State().IndexingInProgress() {
+		time.Sleep(time.Second)
+		return nil, errIndexingInProgress
+	}
+	if op.from == op.to {
+		return nil, errors.Errorf("Can't rename %s %s to itself", op.kind, op.from)
+	}
+
+	from, to := x.NamespaceAttr(ns, op.from), x.NamespaceAttr(ns, op.to)
+	var run func(ctx context.Context, st *RenameStatus, from, to string) error
+	switch op.kind {
+	case renameKindPredicate:
+		for _, attr := range []string{from, to} {
+			if x.IsReservedPredicate(attr) || x.IsReverseAttr(attr) {
+				return nil, errors.Errorf("Predicate %s can't be renamed", x.ParseAttr(attr))
+			}
+		}
+		run = s.renamePredicate
+	case renameKindType:
+		if x.IsReservedType(from) || x.IsReservedType(to) {
+			return nil, errors.Errorf("Reserved types can't be renamed")
+		}
+		if _, ok := schema.State().GetType(from); !ok {
+			return nil, errors.Errorf("Type %s doesn't exist", op.from)
+		}
+		if _, ok := schema.State().GetType(to); ok {
+			return nil, errors.Errorf("Type %s already exists", op.to)
+		}
+		run = s.renameType
+	}
+
+	st := renames.start(ns, op)
+	glog.Infof("Rename %d: renaming %s %s to %s in namespace %#x", st.Id, op.kind, op.from,
+		op.to, ns)
+	done := make(chan error, 1)
+	go func() {
+		err := run(x.AttachNamespace(context.Background(), ns), st, from, to)
+		if err != nil {
+			glog.Errorf("Rename %d: failed: %v", st.Id, err)
+		} else {
+			glog.Infof("Rename %d: done in %s", st.Id, time.Since(st.StartedAt).Round(time.Second))
+		}
+		renames.finish(st, err)
+		done <- err
+	}()
+
+	data, err := json.Marshal(map[string]uint64{"renameId": st.Id})
+	if err != nil {
+		return nil, err
+	}
+	payload := &api.Payload{Data: data}
+	if runInBackground {
+		return payload, nil
+	}
+	select {
+	case err := <-done:
+		return payload, err
+	case <-ctx.Done():
+		return payload, ctx.Err()
+	}
+}
+
+// renamePredicate renames the predicate from to to, with a single schema update proposed to the
+// group serving from, which moves the data of the predicate. The types with the predicate as a
+// field get the new predicate in its place.
+func (s *Server) renamePredicate(ctx context.Context, _ *RenameStatus, from, to string) error {
+	if err := worker.ServeTabletAlongside(to, from); err != nil {
+		return err
+	}
+	ns, attr := x.ParseNamespaceAttr(from)
+	fields := map[string]string{
+		from:                          to,
+		x.NamespaceAttr(ns, "~"+attr): x.NamespaceAttr(ns, "~"+x.ParseAttr(to)),
+	}
+	var types []*pb.TypeUpdate
+	for _, name := range schema.State().Types() {
+		if x.ParseNamespace(name) != ns {
+			continue
+		}
+		typ, _ := schema.State().GetType(name)
+		update := proto.Clone(&typ).(*pb.TypeUpdate)
+		renamed := false
+		for _, field := range update.Fields {
+			if fields[field.Predicate] != "" {
+				field.Predicate, renamed = fields[field.Predicate], true
+			}
+		}
+		if renamed {
+			types = append(types, update)
+		}
+	}
+
+	m := &pb.Mutations{
+		StartTs: worker.State.GetTimestamp(false),
+		Schema:  []*pb.SchemaUpdate{{Predicate: to, RenameFrom: from}},
+		Types:   types,
+	}
+	if _, err := query.ApplyMutations(ctx, m); err != nil {
+		return err
+	}
+	// Like dropping the predicate, let the restore of the incremental backups drop it.
+	return InsertDropRecord(ctx, "DROP_ATTR;"+from)
+}
+
+// renameType renames the type from to to. The new type is created with the fields of the old
+// one, then the nodes of the type are moved to the new type in batches, each one in its own
+// transaction, before the old type is dropped.
+func (s *Server) renameType(ctx context.Context, st *RenameStatus, from, to string) error {
+	typ, ok := schema.State().GetType(from)
+	if !ok {
+		return errors.Errorf("Type %s doesn't exist", x.ParseAttr(from))
+	}
+	update := proto.Clone(&typ).(*pb.TypeUpdate)
+	update.TypeName = to
+	m := &pb.Mutations{StartTs: worker.State.GetTimestamp(false),
+		Types: []*pb.TypeUpdate{update}}
+	if _, err := query.ApplyMutations(ctx, m); err != nil {
+		return err
+	}
+
+	batch := renameTypeBatch(x.ParseAttr(from), x.ParseAttr(to))
+	for {
+		resp, _, err := retryAborted(ctx, proto.Clone(batch).(*api.Request),
+			x.Config.MutationRetries, s.QueryNoAuth)
+		if err != nil {
+			return err
+		}
+		var res struct {
+			Batch []json.RawMessage `json:"batch"`
+		}
+		if err := json.Unmarshal(resp.Json, &res); err != nil {
+			return err
+		}
+		if len(res.Batch) == 0 {
+			break
+		}
+		renames.update(st, len(res.Batch))
+		select {
+		case <-x.ServerCloser.HasBeenClosed():
+			return errors.New("The Alpha is shutting down")
+		default:
+		}
+	}
+
+	m = &pb.Mutations{StartTs: worker.State.GetTimestamp(false), DropOp: pb.Mutations_TYPE,
+		DropValue: from}
+	_, err := query.ApplyMutations(ctx, m)
+	return err
+}
+
+// renameTypeBatch returns the upsert block which moves the next batch of nodes of the type from
+// to the type to.
+func renameTypeBatch(from, to string) *api.Request {
+	return &api.Request{
+		Query: fmt.Sprintf("{\n\tbatch(func: type(%s), first: %d) { b as uid }\n}", from,
+			renameTypeBatchSize),
+		Mutations: []*api.Mutation{{
+			SetNquads: []byte(fmt.Sprintf("uid(b) <dgraph.type> %q .", to)),
+			DelNquads: []byte(fmt.Sprintf("uid(b) <dgraph.type> %q .", from)),
+		}},
+		CommitNow: true,
+	}
+}
+
+// Renames reports the renames run by this Alpha in the namespace of the context, the latest
+// first.
+func (s *Server) Renames(ctx context.Context) ([]RenameStatus, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return renames.list(ns), nil
+}
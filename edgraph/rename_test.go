@@ -0,0 +1,42 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+)
+
+func TestParseRename(t *testing.T) {
+	op := parseRename("rename predicate <name> to <fullName>")
+	require.Equal(t, &renameOp{kind: renameKindPredicate, from: "name", to: "fullName"}, op)
+	require.Equal(t, []string{"name", "fullName"}, op.preds())
+
+	op = parseRename("\n  rename type Person to User\n")
+	require.Equal(t, &renameOp{kind: renameKindType, from: "Person", to: "User"}, op)
+	require.Equal(t, []string{"dgraph.type"}, op.preds())
+
+	for _, sch := range []string{
+		"name: string @index(exact) .",
+		"rename predicate name",
+		"rename index name to fullName",
+		"rename predicate name to fullName .",
+	} {
+		require.Nil(t, parseRename(sch), sch)
+	}
+}
+
+func TestRenameTypeBatch(t *testing.T) {
+	req := renameTypeBatch("Person", "User")
+	require.True(t, req.CommitNow)
+	_, err := dql.ParseWithNeedVars(dql.Request{Str: req.Query}, []string{"b"})
+	require.NoError(t, err)
+	require.Equal(t, `uid(b) <dgraph.type> "User" .`, string(req.Mutations[0].SetNquads))
+	require.Equal(t, `uid(b) <dgraph.type> "Person" .`, string(req.Mutations[0].DelNquads))
+}
@@ -0,0 +1,186 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+var sandboxPrefixes = [][]byte{
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.namespace.sandboxed")),
+}
+
+// sandboxStore holds the sandboxed namespaces. The set is replaced, not modified, on reloads.
+type sandboxStore struct {
+	sync.RWMutex
+	// reloads serializes the reloads of the sandboxed namespaces, so that an older reload doesn't
+	// replace the namespaces loaded by a newer one.
+	reloads    sync.Mutex
+	namespaces map[uint64]struct{}
+}
+
+var sandboxes = &sandboxStore{namespaces: make(map[uint64]struct{})}
+
+// isSandboxed returns true if the queries of the namespace run in the sandbox, with the caps of
+// --sandbox.
+func isSandboxed(ns uint64) bool {
+	sandboxes.RLock()
+	defer sandboxes.RUnlock()
+	_, ok := sandboxes.namespaces[ns]
+	return ok
+}
+
+// reloadSandboxes reads the sandboxed namespaces, stored in the root namespace with the other
+// settings of the namespaces.
+func reloadSandboxes(ctx context.Context) error {
+	sandboxes.reloads.Lock()
+	defer sandboxes.reloads.Unlock()
+	req := &Request{
+		req: &api.Request{
+			Query: `{
+				namespaces(func: has(dgraph.namespace.sandboxed)) {
+					dgraph.namespace.id
+					dgraph.namespace.sandboxed
+				}
+			}`,
+			ReadOnly: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(x.AttachNamespace(ctx, x.RootNamespace), req)
+	if err != nil {
+		return err
+	}
+	var res struct {
+		Namespaces []struct {
+			Id        uint64 `json:"dgraph.namespace.id"`
+			Sandboxed bool   `json:"dgraph.namespace.sandboxed"`
+		} `json:"namespaces"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &res); err != nil {
+		return errors.Wrap(err, "while reading the sandboxed namespaces")
+	}
+	loaded := make(map[uint64]struct{})
+	for _, ns := range res.Namespaces {
+		if ns.Sandboxed && ns.Id != x.RootNamespace {
+			loaded[ns.Id] = struct{}{}
+		}
+	}
+
+	sandboxes.Lock()
+	defer sandboxes.Unlock()
+	sandboxes.namespaces = loaded
+	return nil
+}
+
+// SubscribeForSandboxUpdates loads the sandboxed namespaces, and reloads them whenever a namespace
+// is sandboxed or released, on any Alpha.
+func SubscribeForSandboxUpdates(closer *z.Closer) {
+	defer func() {
+		glog.Infoln("SubscribeForSandboxUpdates closed")
+		closer.Done()
+	}()
+
+	closer.AddRunning(1)
+	go worker.SubscribeForUpdates(sandboxPrefixes, x.IgnoreBytes, func(kvs *bpb.KVList) {
+		if kvs == nil || len(kvs.Kv) == 0 {
+			return
+		}
+		glog.V(3).Infof("Got sandboxed namespaces update via subscription")
+		if err := reloadSandboxes(closer.Ctx()); err != nil {
+			glog.Errorf("While reloading the sandboxed namespaces: %v", err)
+		}
+	}, 1, closer)
+
+	for {
+		err := reloadSandboxes(closer.Ctx())
+		if err == nil {
+			break
+		}
+		glog.Warningf("While loading the sandboxed namespaces, retrying: %v", err)
+		select {
+		case <-time.After(time.Second):
+		case <-closer.HasBeenClosed():
+			return
+		}
+	}
+	<-closer.HasBeenClosed()
+}
+
+// SandboxNamespace sets whether the queries of the namespace run in the sandbox. The setting is
+// stored in the root namespace along with the name of the namespace, and is dropped with the
+// namespace. The galaxy namespace can't be sandboxed, as its guardians have access to all the
+// namespaces. Only superadmin is authorized to do so. Authorization is handled by middlewares.
+func (s *Server) SandboxNamespace(ctx context.Context, namespace uint64, sandboxed bool) error {
+	if namespace == x.RootNamespace {
+		return errors.New("The galaxy namespace can't be sandboxed")
+	}
+	if _, ok := schema.State().Namespaces()[namespace]; !ok {
+		return errors.Errorf("error sandboxing non-existing namespace %#x", namespace)
+	}
+
+	query := fmt.Sprintf(`{
+			ns as var(func: eq(dgraph.namespace.id, %d))
+		}`, namespace)
+	nquads := []*api.NQuad{
+		{
+			Subject:     "uid(ns)",
+			Predicate:   "dgraph.namespace.sandboxed",
+			ObjectValue: &api.Value{Val: &api.Value_BoolVal{BoolVal: sandboxed}},
+		},
+		{
+			Subject:     "uid(ns)",
+			Predicate:   "dgraph.namespace.id",
+			ObjectValue: &api.Value{Val: &api.Value_IntVal{IntVal: int64(namespace)}},
+		},
+		{
+			Subject:     "uid(ns)",
+			Predicate:   "dgraph.type",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: "dgraph.namespace"}},
+		},
+	}
+	req := &Request{
+		req: &api.Request{
+			CommitNow: true,
+			Query:     query,
+			Mutations: []*api.Mutation{{Set: nquads}},
+		},
+		doAuth: NoAuthorize,
+	}
+	ctx = x.AttachNamespace(context.WithValue(ctx, IsGraphql, true), x.RootNamespace)
+	if _, err := (&Server{}).doQuery(ctx, req); err != nil {
+		return errors.Wrapf(err, "Sandboxing namespace %#x, got error:", namespace)
+	}
+	glog.Infof("Set the sandbox of namespace %#x to %v", namespace, sandboxed)
+	return reloadSandboxes(ctx)
+}
+
+// sandboxQueryContext returns the context the query of the request runs with, the query block
+// of an upsert included. The queries of the sandboxed namespaces can't run for longer than the
+// sandbox timeout, whatever the deadline of the request, while their mutations aren't cut short.
+func sandboxQueryContext(ctx context.Context, req *Request) (context.Context, context.CancelFunc) {
+	if req.doAuth == NoAuthorize || x.Config.SandboxTimeout <= 0 || req.req.Query == "" {
+		return ctx, func() {}
+	}
+	if ns, err := x.ExtractNamespace(ctx); err != nil || !isSandboxed(ns) {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, x.Config.SandboxTimeout)
+}
@@ -0,0 +1,395 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	scimListSchema   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema  = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimContentType  = "application/scim+json"
+	scimMaxBody      = 1 << 20
+)
+
+type scimList struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// scimError is an error returned to the identity provider, as per section 3.12 of RFC 7644.
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimError) Error() string {
+	return e.detail
+}
+
+func scimErrorf(status int, scimType, format string, args ...interface{}) *scimError {
+	return &scimError{status: status, scimType: scimType, detail: fmt.Sprintf(format, args...)}
+}
+
+var scimServiceProviderConfig = map[string]interface{}{
+	"schemas": []string{scimConfigSchema},
+	"patch":   map[string]bool{"supported": true},
+	"bulk": map[string]interface{}{
+		"supported": false, "maxOperations": 0, "maxPayloadSize": 0,
+	},
+	"filter":         map[string]interface{}{"supported": true, "maxResults": 0},
+	"changePassword": map[string]bool{"supported": true},
+	"sort":           map[string]bool{"supported": false},
+	"etag":           map[string]bool{"supported": false},
+	"authenticationSchemes": []map[string]string{{
+		"type":        "oauthbearertoken",
+		"name":        "Bearer Token",
+		"description": "The token set with the scim-token-file option of the acl flag.",
+	}},
+}
+
+// ScimHandler returns the handler of the SCIM 2.0 endpoint (RFC 7644) at /scim/v2, which the
+// identity providers use to provision the ACL users and groups of the SCIM namespace.
+//
+// The ids of the users and groups are their uids. Dgraph has no inactive users, so deactivating
+// a user deletes it. The groups of a user can only be changed through the members of the groups.
+func ScimHandler() http.Handler {
+	return http.HandlerFunc(serveScim)
+}
+
+func serveScim(w http.ResponseWriter, r *http.Request) {
+	conf := worker.Config.Scim
+	if conf == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !scimAuthorized(r, conf.Token) {
+		writeScimError(w, scimErrorf(http.StatusUnauthorized, "", "invalid bearer token"))
+		return
+	}
+
+	ctx := x.AttachNamespace(r.Context(), conf.Namespace)
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/scim/v2"), "/")
+	kind, id, _ := strings.Cut(path, "/")
+	status, resp := http.StatusOK, interface{}(nil)
+	var err error
+	switch kind {
+	case "Users":
+		status, resp, err = serveScimUsers(ctx, r, id)
+	case "Groups":
+		status, resp, err = serveScimGroups(ctx, r, id)
+	case "ServiceProviderConfig":
+		resp = scimServiceProviderConfig
+	default:
+		err = scimErrorf(http.StatusNotFound, "", "unknown resource %q", kind)
+	}
+	if err != nil {
+		writeScimError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	if resp != nil {
+		writeScimJSON(w, resp)
+	}
+}
+
+func scimAuthorized(r *http.Request, token x.Sensitive) bool {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), token) == 1
+}
+
+func writeScimError(w http.ResponseWriter, err error) {
+	e, ok := err.(*scimError)
+	if !ok {
+		glog.Errorf("Error while serving SCIM request: %v", err)
+		e = &scimError{status: http.StatusInternalServerError, detail: err.Error()}
+	}
+	resp := map[string]interface{}{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(e.status),
+		"detail":  e.detail,
+	}
+	if e.scimType != "" {
+		resp["scimType"] = e.scimType
+	}
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(e.status)
+	writeScimJSON(w, resp)
+}
+
+func writeScimJSON(w http.ResponseWriter, resp interface{}) {
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Warningf("Unable to write SCIM response: %v", err)
+	}
+}
+
+func readScimBody(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, scimMaxBody))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return scimErrorf(http.StatusBadRequest, "invalidSyntax", "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func parseScimID(id string) (uint64, error) {
+	uid, err := strconv.ParseUint(id, 0, 64)
+	if err != nil || uid == 0 {
+		return 0, scimErrorf(http.StatusNotFound, "", "resource %s not found", id)
+	}
+	return uid, nil
+}
+
+// parseScimFilter parses a filter of the form `<attr> eq "<value>"`, the only filter supported,
+// and returns the value.
+func parseScimFilter(filter, attr string) (string, error) {
+	fields := strings.SplitN(strings.TrimSpace(filter), " ", 3)
+	if len(fields) != 3 || !strings.EqualFold(fields[0], attr) ||
+		!strings.EqualFold(fields[1], "eq") {
+		return "", scimErrorf(http.StatusBadRequest, "invalidFilter",
+			"only the filter %s eq \"<value>\" is supported", attr)
+	}
+	var value string
+	raw := strings.TrimSpace(fields[2])
+	if err := json.Unmarshal([]byte(raw), &value); err != nil || !strings.HasPrefix(raw, `"`) {
+		return "", scimErrorf(http.StatusBadRequest, "invalidFilter", "invalid value in filter %q",
+			filter)
+	}
+	return value, nil
+}
+
+// scimPage returns the page of the resources asked for by the startIndex and count parameters.
+func scimPage(r *http.Request, n int) (int, int) {
+	start, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || start < 1 {
+		start = 1
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 0 {
+		count = n
+	}
+	from := min(start-1, n)
+	return from, min(from+count, n)
+}
+
+func serveScimUsers(ctx context.Context, r *http.Request, id string) (int, interface{}, error) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		var name string
+		if filter := r.URL.Query().Get("filter"); filter != "" {
+			var err error
+			if name, err = parseScimFilter(filter, "userName"); err != nil {
+				return 0, nil, err
+			}
+		}
+		var users []*scimUser
+		// Filtering on an empty name doesn't match any user.
+		if name != "" || r.URL.Query().Get("filter") == "" {
+			nodes, err := scimNodes(ctx, false, 0, name)
+			if err != nil {
+				return 0, nil, err
+			}
+			for i := range nodes {
+				users = append(users, toScimUser(&nodes[i]))
+			}
+		}
+		from, to := scimPage(r, len(users))
+		return http.StatusOK, &scimList{
+			Schemas:      []string{scimListSchema},
+			TotalResults: len(users),
+			StartIndex:   from + 1,
+			ItemsPerPage: to - from,
+			Resources:    append([]*scimUser{}, users[from:to]...),
+		}, nil
+
+	case id == "" && r.Method == http.MethodPost:
+		var user scimUser
+		if err := readScimBody(r, &user); err != nil {
+			return 0, nil, err
+		}
+		uid, err := createScimUser(ctx, &user)
+		if err != nil {
+			return 0, nil, err
+		}
+		_, n, err := scimNode1(ctx, false, fmt.Sprintf("%#x", uid))
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, toScimUser(n), nil
+
+	case id == "":
+		return 0, nil, scimErrorf(http.StatusMethodNotAllowed, "", "method %s not allowed",
+			r.Method)
+	}
+
+	uid, n, err := scimNode1(ctx, false, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	var patch *scimUserPatch
+	switch r.Method {
+	case http.MethodGet:
+		return http.StatusOK, toScimUser(n), nil
+	case http.MethodDelete:
+		return http.StatusNoContent, nil, deleteScimUser(ctx, uid, n)
+	case http.MethodPut:
+		var user scimUser
+		if err := readScimBody(r, &user); err != nil {
+			return 0, nil, err
+		}
+		patch = &scimUserPatch{userName: &user.UserName, active: user.Active}
+		if user.Password != "" {
+			patch.password = &user.Password
+		}
+	case http.MethodPatch:
+		var p scimPatch
+		if err := readScimBody(r, &p); err != nil {
+			return 0, nil, err
+		}
+		if patch, err = parseScimUserPatch(&p); err != nil {
+			return 0, nil, err
+		}
+	default:
+		return 0, nil, scimErrorf(http.StatusMethodNotAllowed, "", "method %s not allowed",
+			r.Method)
+	}
+
+	if patch.active != nil && !*patch.active {
+		return http.StatusNoContent, nil, deleteScimUser(ctx, uid, n)
+	}
+	if err := updateScimUser(ctx, uid, n, patch); err != nil {
+		return 0, nil, err
+	}
+	_, n, err = scimNode1(ctx, false, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, toScimUser(n), nil
+}
+
+func serveScimGroups(ctx context.Context, r *http.Request, id string) (int, interface{}, error) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		var name string
+		if filter := r.URL.Query().Get("filter"); filter != "" {
+			var err error
+			if name, err = parseScimFilter(filter, "displayName"); err != nil {
+				return 0, nil, err
+			}
+		}
+		var groups []*scimGroup
+		if name != "" || r.URL.Query().Get("filter") == "" {
+			nodes, err := scimNodes(ctx, true, 0, name)
+			if err != nil {
+				return 0, nil, err
+			}
+			for i := range nodes {
+				groups = append(groups, toScimGroup(&nodes[i]))
+			}
+		}
+		from, to := scimPage(r, len(groups))
+		return http.StatusOK, &scimList{
+			Schemas:      []string{scimListSchema},
+			TotalResults: len(groups),
+			StartIndex:   from + 1,
+			ItemsPerPage: to - from,
+			Resources:    append([]*scimGroup{}, groups[from:to]...),
+		}, nil
+
+	case id == "" && r.Method == http.MethodPost:
+		var group scimGroup
+		if err := readScimBody(r, &group); err != nil {
+			return 0, nil, err
+		}
+		members, err := scimMemberUids(group.Members)
+		if err != nil {
+			return 0, nil, err
+		}
+		uid, err := createScimGroup(ctx, group.DisplayName)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := updateScimMembers(ctx, uid, &scimGroupPatch{add: members}); err != nil {
+			return 0, nil, err
+		}
+		_, n, err := scimNode1(ctx, true, fmt.Sprintf("%#x", uid))
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, toScimGroup(n), nil
+
+	case id == "":
+		return 0, nil, scimErrorf(http.StatusMethodNotAllowed, "", "method %s not allowed",
+			r.Method)
+	}
+
+	uid, n, err := scimNode1(ctx, true, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	var patch *scimGroupPatch
+	switch r.Method {
+	case http.MethodGet:
+		return http.StatusOK, toScimGroup(n), nil
+	case http.MethodDelete:
+		return http.StatusNoContent, nil, deleteScimGroup(ctx, uid, n)
+	case http.MethodPut:
+		var group scimGroup
+		if err := readScimBody(r, &group); err != nil {
+			return 0, nil, err
+		}
+		members, err := scimMemberUids(group.Members)
+		if err != nil {
+			return 0, nil, err
+		}
+		patch = &scimGroupPatch{displayName: &group.DisplayName, add: members, replace: true}
+	case http.MethodPatch:
+		var p scimPatch
+		if err := readScimBody(r, &p); err != nil {
+			return 0, nil, err
+		}
+		if patch, err = parseScimGroupPatch(&p); err != nil {
+			return 0, nil, err
+		}
+	default:
+		return 0, nil, scimErrorf(http.StatusMethodNotAllowed, "", "method %s not allowed",
+			r.Method)
+	}
+
+	if err := updateScimGroup(ctx, uid, n, patch); err != nil {
+		return 0, nil, err
+	}
+	_, n, err = scimNode1(ctx, true, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, toScimGroup(n), nil
+}
@@ -0,0 +1,538 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/acl"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	scimUserSchema  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema = "urn:ietf:params:scim:schemas:core:2.0:Group"
+)
+
+type scimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+}
+
+type scimUser struct {
+	Schemas  []string `json:"schemas"`
+	ID       string   `json:"id"`
+	UserName string   `json:"userName"`
+	// Password is only read, it is never returned.
+	Password string       `json:"password,omitempty"`
+	Active   *bool        `json:"active,omitempty"`
+	Groups   []scimMember `json:"groups,omitempty"`
+	Meta     scimMeta     `json:"meta"`
+}
+
+type scimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id"`
+	DisplayName string       `json:"displayName"`
+	Members     []scimMember `json:"members"`
+	Meta        scimMeta     `json:"meta"`
+}
+
+type scimPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type scimPatch struct {
+	Operations []scimPatchOp `json:"Operations"`
+}
+
+// scimNode is a user or a group as queried from Dgraph.
+type scimNode struct {
+	Uid     string     `json:"uid"`
+	Xid     string     `json:"dgraph.xid"`
+	Groups  []scimNode `json:"dgraph.user.group"`
+	Members []scimNode `json:"~dgraph.user.group"`
+}
+
+// scimNodes returns the users or the groups, only the one with the given uid or name if set.
+func scimNodes(ctx context.Context, group bool, uid uint64, name string) ([]scimNode, error) {
+	typ, edge := "dgraph.type.User", "dgraph.user.group"
+	if group {
+		typ, edge = "dgraph.type.Group", "~dgraph.user.group"
+	}
+	fn, params, vars := "type("+typ+")", "", map[string]string(nil)
+	switch {
+	case uid != 0:
+		fn = fmt.Sprintf("uid(%#x)", uid)
+	case name != "":
+		fn, params, vars = "eq(dgraph.xid, $name)", "query q($name: string)",
+			map[string]string{"$name": name}
+	}
+	query := fmt.Sprintf(`%s {
+		nodes(func: %s) @filter(type(%s)) {
+			uid
+			dgraph.xid
+			%s { uid dgraph.xid }
+		}
+	}`, params, fn, typ, edge)
+	req := &Request{
+		req:    &api.Request{Query: query, Vars: vars, ReadOnly: true},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Nodes []scimNode `json:"nodes"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &res); err != nil {
+		return nil, err
+	}
+	return res.Nodes, nil
+}
+
+func scimNode1(ctx context.Context, group bool, id string) (uint64, *scimNode, error) {
+	uid, err := parseScimID(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	nodes, err := scimNodes(ctx, group, uid, "")
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(nodes) == 0 {
+		return 0, nil, scimErrorf(http.StatusNotFound, "", "resource %s not found", id)
+	}
+	return uid, &nodes[0], nil
+}
+
+func scimMembers(nodes []scimNode) []scimMember {
+	members := make([]scimMember, 0, len(nodes))
+	for _, n := range nodes {
+		members = append(members, scimMember{Value: n.Uid, Display: n.Xid})
+	}
+	return members
+}
+
+func toScimUser(n *scimNode) *scimUser {
+	active := true
+	return &scimUser{
+		Schemas:  []string{scimUserSchema},
+		ID:       n.Uid,
+		UserName: n.Xid,
+		Active:   &active,
+		Groups:   scimMembers(n.Groups),
+		Meta:     scimMeta{ResourceType: "User"},
+	}
+}
+
+func toScimGroup(n *scimNode) *scimGroup {
+	return &scimGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          n.Uid,
+		DisplayName: n.Xid,
+		Members:     scimMembers(n.Members),
+		Meta:        scimMeta{ResourceType: "Group"},
+	}
+}
+
+// scimUserPatch holds the changes to a user, nil for the attributes that don't change.
+type scimUserPatch struct {
+	userName *string
+	password *string
+	active   *bool
+}
+
+func parseScimUserPatch(p *scimPatch) (*scimUserPatch, error) {
+	patch := &scimUserPatch{}
+	set := func(path string, value json.RawMessage) error {
+		var err error
+		switch strings.ToLower(path) {
+		case "username":
+			patch.userName = new(string)
+			err = json.Unmarshal(value, patch.userName)
+		case "password":
+			patch.password = new(string)
+			err = json.Unmarshal(value, patch.password)
+		case "active":
+			// Some identity providers send the booleans as strings.
+			var s string
+			if json.Unmarshal(value, &s) == nil {
+				value = json.RawMessage(strings.ToLower(s))
+			}
+			patch.active = new(bool)
+			err = json.Unmarshal(value, patch.active)
+		default:
+			// The other attributes of the user aren't stored.
+		}
+		if err != nil {
+			return scimErrorf(http.StatusBadRequest, "invalidValue", "invalid %s: %v", path, err)
+		}
+		return nil
+	}
+
+	for _, op := range p.Operations {
+		if o := strings.ToLower(op.Op); o != "add" && o != "replace" {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue",
+				"unsupported operation %q on user", op.Op)
+		}
+		if op.Path != "" {
+			if err := set(op.Path, op.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue", "invalid value: %v", err)
+		}
+		for path, value := range values {
+			if err := set(path, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return patch, nil
+}
+
+func checkScimPassword(password string) error {
+	if len(password) < 6 {
+		return scimErrorf(http.StatusBadRequest, "invalidValue",
+			"password should have at least 6 characters")
+	}
+	return nil
+}
+
+func createScimUser(ctx context.Context, user *scimUser) (uint64, error) {
+	if user.UserName == "" {
+		return 0, scimErrorf(http.StatusBadRequest, "invalidValue", "userName is required")
+	}
+	password := user.Password
+	if password == "" {
+		// The user logs in through the identity provider, or LDAP.
+		password = randomPassword()
+	}
+	if err := checkScimPassword(password); err != nil {
+		return 0, err
+	}
+	resp, err := runUpsert(ctx, `query q($name: string) {
+		u as var(func: eq(dgraph.xid, $name)) @filter(type(dgraph.type.User))
+	}`, map[string]string{"$name": user.UserName}, &api.Mutation{
+		Set:  acl.CreateUserNQuads(user.UserName, password),
+		Cond: "@if(eq(len(u), 0))",
+	})
+	if err != nil {
+		return 0, err
+	}
+	uid, ok := resp.GetUids()["newuser"]
+	if !ok {
+		return 0, scimErrorf(http.StatusConflict, "uniqueness", "user %s already exists",
+			user.UserName)
+	}
+	return strconv.ParseUint(uid, 0, 64)
+}
+
+func updateScimUser(ctx context.Context, uid uint64, n *scimNode, patch *scimUserPatch) error {
+	var nqs []*api.NQuad
+	subject := fmt.Sprintf("%#x", uid)
+	if patch.userName != nil && *patch.userName != n.Xid {
+		if n.Xid == x.GrootId {
+			return scimErrorf(http.StatusBadRequest, "mutability", "groot can't be renamed")
+		}
+		if err := checkScimName(ctx, false, *patch.userName); err != nil {
+			return err
+		}
+		nqs = append(nqs, &api.NQuad{
+			Subject:     subject,
+			Predicate:   "dgraph.xid",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: *patch.userName}},
+		})
+	}
+	if patch.password != nil {
+		if err := checkScimPassword(*patch.password); err != nil {
+			return err
+		}
+		nqs = append(nqs, &api.NQuad{
+			Subject:     subject,
+			Predicate:   "dgraph.password",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: *patch.password}},
+		})
+	}
+	if len(nqs) == 0 {
+		return nil
+	}
+	_, err := runUpsert(ctx, "", nil, &api.Mutation{Set: nqs})
+	return err
+}
+
+// checkScimName checks that there is no user or group with the name yet.
+func checkScimName(ctx context.Context, group bool, name string) error {
+	if name == "" {
+		return scimErrorf(http.StatusBadRequest, "invalidValue", "the name is required")
+	}
+	nodes, err := scimNodes(ctx, group, 0, name)
+	if err != nil {
+		return err
+	}
+	if len(nodes) > 0 {
+		return scimErrorf(http.StatusConflict, "uniqueness", "%s already exists", name)
+	}
+	return nil
+}
+
+func deleteScimUser(ctx context.Context, uid uint64, n *scimNode) error {
+	if n.Xid == x.GrootId {
+		return scimErrorf(http.StatusBadRequest, "mutability", "groot can't be deleted")
+	}
+	_, err := runUpsert(ctx, "", nil, &api.Mutation{
+		DelNquads: []byte(fmt.Sprintf("<%#x> * * .", uid)),
+	})
+	return err
+}
+
+// scimGroupPatch holds the changes to a group. If replace is set, all the members are removed
+// before adding the ones in add.
+type scimGroupPatch struct {
+	displayName *string
+	add         []uint64
+	remove      []uint64
+	replace     bool
+}
+
+func (p *scimGroupPatch) addMembers(uids []uint64) {
+	for _, uid := range uids {
+		p.add = append(p.add, uid)
+		p.remove = removeUid(p.remove, uid)
+	}
+}
+
+func (p *scimGroupPatch) removeMembers(uids []uint64) {
+	for _, uid := range uids {
+		p.add = removeUid(p.add, uid)
+		if !p.replace {
+			p.remove = append(p.remove, uid)
+		}
+	}
+}
+
+func removeUid(uids []uint64, uid uint64) []uint64 {
+	res := uids[:0]
+	for _, u := range uids {
+		if u != uid {
+			res = append(res, u)
+		}
+	}
+	return res
+}
+
+func parseScimMembers(value json.RawMessage) ([]uint64, error) {
+	var members []scimMember
+	if err := json.Unmarshal(value, &members); err != nil {
+		// Some identity providers send a single member.
+		var member scimMember
+		if json.Unmarshal(value, &member) != nil {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue", "invalid members: %v",
+				err)
+		}
+		members = []scimMember{member}
+	}
+	return scimMemberUids(members)
+}
+
+func scimMemberUids(members []scimMember) ([]uint64, error) {
+	uids := make([]uint64, 0, len(members))
+	for _, m := range members {
+		uid, err := strconv.ParseUint(m.Value, 0, 64)
+		if err != nil || uid == 0 {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue", "invalid member %q",
+				m.Value)
+		}
+		uids = append(uids, uid)
+	}
+	return uids, nil
+}
+
+func parseScimGroupPatch(p *scimPatch) (*scimGroupPatch, error) {
+	patch := &scimGroupPatch{}
+	apply := func(op, path string, value json.RawMessage) error {
+		lpath := strings.ToLower(path)
+		switch {
+		case lpath == "displayname":
+			if op == "remove" {
+				return scimErrorf(http.StatusBadRequest, "mutability",
+					"displayName can't be removed")
+			}
+			patch.displayName = new(string)
+			if err := json.Unmarshal(value, patch.displayName); err != nil {
+				return scimErrorf(http.StatusBadRequest, "invalidValue", "invalid displayName")
+			}
+		case lpath == "members" && op == "remove" && len(value) == 0:
+			patch.replace, patch.add, patch.remove = true, nil, nil
+		case lpath == "members":
+			uids, err := parseScimMembers(value)
+			if err != nil {
+				return err
+			}
+			switch op {
+			case "add":
+				patch.addMembers(uids)
+			case "remove":
+				patch.removeMembers(uids)
+			case "replace":
+				patch.replace, patch.add, patch.remove = true, nil, nil
+				patch.addMembers(uids)
+			}
+		case strings.HasPrefix(lpath, "members[") && strings.HasSuffix(lpath, "]") &&
+			op == "remove":
+			member, err := parseScimFilter(path[len("members["):len(path)-1], "value")
+			if err != nil {
+				return err
+			}
+			uid, err := strconv.ParseUint(member, 0, 64)
+			if err != nil {
+				return scimErrorf(http.StatusBadRequest, "invalidValue", "invalid member %q",
+					member)
+			}
+			patch.removeMembers([]uint64{uid})
+		default:
+			return scimErrorf(http.StatusBadRequest, "invalidPath",
+				"unsupported %s of %q on group", op, path)
+		}
+		return nil
+	}
+
+	for _, op := range p.Operations {
+		o := strings.ToLower(op.Op)
+		if o != "add" && o != "remove" && o != "replace" {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue",
+				"unsupported operation %q", op.Op)
+		}
+		if op.Path != "" {
+			if err := apply(o, op.Path, op.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue", "invalid value: %v", err)
+		}
+		for path, value := range values {
+			if strings.EqualFold(path, "id") || strings.EqualFold(path, "schemas") {
+				continue
+			}
+			if err := apply(o, path, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return patch, nil
+}
+
+func createScimGroup(ctx context.Context, name string) (uint64, error) {
+	if name == "" {
+		return 0, scimErrorf(http.StatusBadRequest, "invalidValue", "displayName is required")
+	}
+	resp, err := runUpsert(ctx, `query q($name: string) {
+		g as var(func: eq(dgraph.xid, $name)) @filter(type(dgraph.type.Group))
+	}`, map[string]string{"$name": name}, &api.Mutation{
+		Set:  acl.CreateGroupNQuads(name),
+		Cond: "@if(eq(len(g), 0))",
+	})
+	if err != nil {
+		return 0, err
+	}
+	uid, ok := resp.GetUids()["newgroup"]
+	if !ok {
+		return 0, scimErrorf(http.StatusConflict, "uniqueness", "group %s already exists", name)
+	}
+	return strconv.ParseUint(uid, 0, 64)
+}
+
+// updateScimGroup renames the group and updates its members as per the patch.
+func updateScimGroup(ctx context.Context, uid uint64, n *scimNode, patch *scimGroupPatch) error {
+	if patch.displayName != nil && *patch.displayName != n.Xid {
+		if n.Xid == x.SuperAdminId {
+			return scimErrorf(http.StatusBadRequest, "mutability", "guardians can't be renamed")
+		}
+		if err := checkScimName(ctx, true, *patch.displayName); err != nil {
+			return err
+		}
+		_, err := runUpsert(ctx, "", nil, &api.Mutation{Set: []*api.NQuad{{
+			Subject:     fmt.Sprintf("%#x", uid),
+			Predicate:   "dgraph.xid",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: *patch.displayName}},
+		}}})
+		if err != nil {
+			return err
+		}
+	}
+	return updateScimMembers(ctx, uid, patch)
+}
+
+func uidList(uids []uint64) string {
+	strs := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		strs = append(strs, fmt.Sprintf("%#x", uid))
+	}
+	return strings.Join(strs, ", ")
+}
+
+// updateScimMembers updates the members of the group as per the patch. Only the users can
+// be added to the group.
+func updateScimMembers(ctx context.Context, group uint64, patch *scimGroupPatch) error {
+	var blocks []string
+	var del, set strings.Builder
+	if patch.replace {
+		blocks = append(blocks, fmt.Sprintf("var(func: uid(%#x)) { cur as ~dgraph.user.group }",
+			group))
+		fmt.Fprintf(&del, "uid(cur) <dgraph.user.group> <%#x> .\n", group)
+	}
+	if len(patch.remove) > 0 {
+		blocks = append(blocks, fmt.Sprintf("rm as var(func: uid(%s))", uidList(patch.remove)))
+		fmt.Fprintf(&del, "uid(rm) <dgraph.user.group> <%#x> .\n", group)
+	}
+	if len(patch.add) > 0 {
+		blocks = append(blocks, fmt.Sprintf("add as var(func: uid(%s)) "+
+			"@filter(type(dgraph.type.User))", uidList(patch.add)))
+		fmt.Fprintf(&set, "uid(add) <dgraph.user.group> <%#x> .\n", group)
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	// The members are deleted before the new ones are set.
+	_, err := runUpsert(ctx, "{\n"+strings.Join(blocks, "\n")+"\n}", nil, &api.Mutation{
+		DelNquads: []byte(del.String()),
+		SetNquads: []byte(set.String()),
+	})
+	return err
+}
+
+func deleteScimGroup(ctx context.Context, uid uint64, n *scimNode) error {
+	if n.Xid == x.SuperAdminId {
+		return scimErrorf(http.StatusBadRequest, "mutability", "guardians can't be deleted")
+	}
+	_, err := runUpsert(ctx, fmt.Sprintf(`{
+		var(func: uid(%#x)) { m as ~dgraph.user.group }
+	}`, uid), nil, &api.Mutation{
+		DelNquads: []byte(fmt.Sprintf("uid(m) <dgraph.user.group> <%#x> .\n<%#x> * * .", uid,
+			uid)),
+	})
+	return err
+}
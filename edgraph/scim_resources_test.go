@@ -0,0 +1,91 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parsePatch(t *testing.T, body string) *scimPatch {
+	var p scimPatch
+	require.NoError(t, json.Unmarshal([]byte(body), &p))
+	return &p
+}
+
+func TestParseScimUserPatch(t *testing.T) {
+	patch, err := parseScimUserPatch(parsePatch(t, `{"Operations": [
+		{"op": "Replace", "path": "active", "value": "False"},
+		{"op": "replace", "value": {"userName": "alice", "displayName": "Alice"}}
+	]}`))
+	require.NoError(t, err)
+	require.False(t, *patch.active)
+	require.Equal(t, "alice", *patch.userName)
+	require.Nil(t, patch.password)
+
+	_, err = parseScimUserPatch(parsePatch(t, `{"Operations": [{"op": "remove",
+		"path": "userName"}]}`))
+	require.Error(t, err)
+	_, err = parseScimUserPatch(parsePatch(t, `{"Operations": [{"op": "add",
+		"path": "active", "value": "maybe"}]}`))
+	require.Error(t, err)
+}
+
+func TestParseScimGroupPatch(t *testing.T) {
+	patch, err := parseScimGroupPatch(parsePatch(t, `{"Operations": [
+		{"op": "add", "path": "members", "value": [{"value": "0x1"}, {"value": "0x2"}]},
+		{"op": "remove", "path": "members[value eq \"0x2\"]"},
+		{"op": "remove", "path": "members", "value": [{"value": "0x3"}]},
+		{"op": "replace", "path": "displayName", "value": "devs"}
+	]}`))
+	require.NoError(t, err)
+	require.Equal(t, "devs", *patch.displayName)
+	require.Equal(t, []uint64{1}, patch.add)
+	require.Equal(t, []uint64{2, 3}, patch.remove)
+	require.False(t, patch.replace)
+
+	patch, err = parseScimGroupPatch(parsePatch(t, `{"Operations": [
+		{"op": "remove", "path": "members"},
+		{"op": "add", "path": "members", "value": {"value": "0x4"}},
+		{"op": "remove", "path": "members", "value": [{"value": "0x5"}]}
+	]}`))
+	require.NoError(t, err)
+	require.True(t, patch.replace)
+	require.Equal(t, []uint64{4}, patch.add)
+	require.Empty(t, patch.remove)
+
+	patch, err = parseScimGroupPatch(parsePatch(t, `{"Operations": [
+		{"op": "replace", "value": {"id": "0x9", "members": [{"value": "0x6"}]}}
+	]}`))
+	require.NoError(t, err)
+	require.True(t, patch.replace)
+	require.Equal(t, []uint64{6}, patch.add)
+
+	for _, body := range []string{
+		`{"Operations": [{"op": "add", "path": "members", "value": [{"value": "alice"}]}]}`,
+		`{"Operations": [{"op": "remove", "path": "displayName"}]}`,
+		`{"Operations": [{"op": "add", "path": "owner", "value": "x"}]}`,
+		`{"Operations": [{"op": "move", "path": "members"}]}`,
+	} {
+		_, err := parseScimGroupPatch(parsePatch(t, body))
+		require.Error(t, err, body)
+	}
+
+	// The filters of the members to remove are parsed like the ones of the lists.
+	for _, path := range []string{`members[value co \"0x1\"]`, `members[display eq \"0x1\"]`,
+		`members[value eq 0x1]`, `members[value eq \"alice\"]`, `members[]`} {
+		body := `{"Operations": [{"op": "remove", "path": "` + path + `"}]}`
+		_, err := parseScimGroupPatch(parsePatch(t, body))
+		require.Error(t, err, path)
+		require.Contains(t, []string{"invalidFilter", "invalidValue"}, err.(*scimError).scimType,
+			path)
+	}
+	_, err = parseScimGroupPatch(parsePatch(t,
+		`{"Operations": [{"op": "add", "path": "members[value eq \"0x1\"]"}]}`))
+	require.Equal(t, "invalidPath", err.(*scimError).scimType)
+}
@@ -0,0 +1,106 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestParseScimFilter(t *testing.T) {
+	name, err := parseScimFilter(`userName eq "alice \"a\""`, "userName")
+	require.NoError(t, err)
+	require.Equal(t, `alice "a"`, name)
+	name, err = parseScimFilter(`USERNAME Eq "bob"`, "userName")
+	require.NoError(t, err)
+	require.Equal(t, "bob", name)
+
+	for _, filter := range []string{`userName co "a"`, `displayName eq "a"`, `userName eq a`,
+		`userName eq "a" and active eq true`, ``, `userName`, `userName eq`, `userName eq "a`,
+		`userName  eq "a"`, `userName eq "a" or userName eq "b"`, `(userName eq "a")`,
+		`userName eq 1`, `userName eq null`, `userName pr`} {
+		_, err := parseScimFilter(filter, "userName")
+		require.Error(t, err, filter)
+		require.Equal(t, "invalidFilter", err.(*scimError).scimType, filter)
+	}
+}
+
+func TestScimFilterErrors(t *testing.T) {
+	defer func(scim *x.ScimConfig) { worker.Config.Scim = scim }(worker.Config.Scim)
+	worker.Config.Scim = &x.ScimConfig{Token: x.Sensitive("token")}
+
+	// The invalid filters are rejected before anything is queried.
+	for _, path := range []string{
+		`/scim/v2/Users?filter=userName+co+"a"`,
+		`/scim/v2/Users?filter=displayName+eq+"a"`,
+		`/scim/v2/Groups?filter=userName+eq+"a"`,
+		`/scim/v2/Groups?filter=displayName+eq+devs`,
+	} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		serveScim(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code, path)
+		require.Equal(t, scimContentType, w.Header().Get("Content-Type"))
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, "400", resp["status"], path)
+		require.Equal(t, "invalidFilter", resp["scimType"], path)
+	}
+}
+
+func TestScimPage(t *testing.T) {
+	page := func(query string) []int {
+		from, to := scimPage(httptest.NewRequest(http.MethodGet, "/scim/v2/Users?"+query, nil), 5)
+		return []int{from, to}
+	}
+	require.Equal(t, []int{0, 5}, page(""))
+	require.Equal(t, []int{1, 3}, page("startIndex=2&count=2"))
+	require.Equal(t, []int{5, 5}, page("startIndex=10"))
+	require.Equal(t, []int{0, 0}, page("count=0"))
+}
+
+func TestScimAuth(t *testing.T) {
+	defer func(scim *x.ScimConfig) { worker.Config.Scim = scim }(worker.Config.Scim)
+	worker.Config.Scim = nil
+	status := func(auth, path string) (int, map[string]interface{}) {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		serveScim(w, r)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	code, _ := status("Bearer token", "/scim/v2/Users")
+	require.Equal(t, http.StatusNotFound, code)
+
+	worker.Config.Scim = &x.ScimConfig{Token: x.Sensitive("token")}
+	code, resp := status("", "/scim/v2/Users")
+	require.Equal(t, http.StatusUnauthorized, code)
+	require.Equal(t, "401", resp["status"])
+	code, _ = status("Bearer other", "/scim/v2/Users")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = status("Basic token", "/scim/v2/Users")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	code, resp = status("bearer token", "/scim/v2/ServiceProviderConfig")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []interface{}{scimConfigSchema}, resp["schemas"])
+	code, _ = status("Bearer token", "/scim/v2/Schemas")
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = status("Bearer token", "/scim/v2/Users/alice")
+	require.Equal(t, http.StatusNotFound, code)
+}
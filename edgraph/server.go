@@ -32,9 +32,11 @@ import (
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/dgraph-io/dgo/v250"
 	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/audit"
 	"github.com/hypermodeinc/dgraph/v25/chunker"
 	"github.com/hypermodeinc/dgraph/v25/conn"
 	"github.com/hypermodeinc/dgraph/v25/dql"
@@ -69,6 +71,10 @@ const (
 	IsGraphql GraphqlContextKey = iota
 	// Authorize is used to set if the request requires validation.
 	Authorize
+	// IsTrigger is set for the requests run by the triggers, whose mutations don't run triggers.
+	IsTrigger
+	// idempotencyCtxKey carries the *Idempotency of a mutation sent with an idempotency key.
+	idempotencyCtxKey
 )
 
 type AuthMode int
@@ -101,15 +107,15 @@ type graphQLSchemaNode struct {
 	Uid    string `json:"uid"`
 	UidInt uint64
 	Schema string `json:"dgraph.graphql.schema"`
+	Xid    string `json:"dgraph.graphql.xid"`
 }
 
 type existingGQLSchemaQryResp struct {
 	ExistingGQLSchema []graphQLSchemaNode `json:"ExistingGQLSchema"`
 }
 
-// GetGQLSchema queries for the GraphQL schema node, and returns the uid and the GraphQL schema.
-// If multiple schema nodes were found, it returns an error.
-func GetGQLSchema(namespace uint64) (uid, graphQLSchema string, err error) {
+// getGQLSchemaNodes queries for the nodes of the default and the named GraphQL schemas.
+func getGQLSchemaNodes(namespace uint64) ([]graphQLSchemaNode, error) {
 	ctx := context.WithValue(context.Background(), Authorize, false)
 	ctx = x.AttachNamespace(ctx, namespace)
 	resp, err := (&Server{}).QueryNoGrpc(ctx,
@@ -119,17 +125,38 @@ func GetGQLSchema(namespace uint64) (uid, graphQLSchema string, err error) {
 				ExistingGQLSchema(func: has(dgraph.graphql.schema)) {
 					uid
 					dgraph.graphql.schema
+					dgraph.graphql.xid
 				  }
 				}`})
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	var result existingGQLSchemaQryResp
 	if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
-		return "", "", errors.Wrap(err, "Couldn't unmarshal response from Dgraph query")
+		return nil, errors.Wrap(err, "Couldn't unmarshal response from Dgraph query")
+	}
+	return result.ExistingGQLSchema, nil
+}
+
+// GetGQLSchema queries for the GraphQL schema node, and returns the uid and the GraphQL schema.
+// If multiple schema nodes were found, it returns an error.
+func GetGQLSchema(namespace uint64) (uid, graphQLSchema string, err error) {
+	return GetNamedGQLSchema(namespace, "")
+}
+
+// GetNamedGQLSchema is like GetGQLSchema, for the GraphQL schema with the given name.
+func GetNamedGQLSchema(namespace uint64, name string) (uid, graphQLSchema string, err error) {
+	nodes, err := getGQLSchemaNodes(namespace)
+	if err != nil {
+		return "", "", err
+	}
+	var res []graphQLSchemaNode
+	for _, node := range nodes {
+		if worker.GQLSchemaName(node.Xid) == name {
+			res = append(res, node)
+		}
 	}
-	res := result.ExistingGQLSchema
 	if len(res) == 0 {
 		// no schema has been stored yet in Dgraph
 		return "", "", nil
@@ -157,11 +184,47 @@ func GetGQLSchema(namespace uint64) (uid, graphQLSchema string, err error) {
 	return resLast.Uid, resLast.Schema, nil
 }
 
+// GetNamedGQLSchemas returns the named GraphQL schemas of the namespace, by name.
+func GetNamedGQLSchemas(namespace uint64) (map[string]string, error) {
+	nodes, err := getGQLSchemaNodes(namespace)
+	if err != nil {
+		return nil, err
+	}
+	schemas := make(map[string]string)
+	for _, node := range nodes {
+		if name := worker.GQLSchemaName(node.Xid); name != "" {
+			schemas[name] = node.Schema
+		}
+	}
+	return schemas, nil
+}
+
+// GetGQLSchemaName returns the name of the GraphQL schema stored in the node with the given uid,
+// empty for the default schema.
+func GetGQLSchemaName(namespace uint64, uid string) (string, error) {
+	nodes, err := getGQLSchemaNodes(namespace)
+	if err != nil {
+		return "", err
+	}
+	for _, node := range nodes {
+		if node.Uid == uid {
+			return worker.GQLSchemaName(node.Xid), nil
+		}
+	}
+	return "", nil
+}
+
 // UpdateGQLSchema updates the GraphQL and Dgraph schemas using the given inputs.
 // It first validates and parses the dgraphSchema given in input. If that fails,
 // it returns an error. All this is done on the alpha on which the update request is received.
 // Then it sends an update request to the worker, which is executed only on Group-1 leader.
 func UpdateGQLSchema(ctx context.Context, gqlSchema,
+	dgraphSchema string) (*pb.UpdateGraphQLSchemaResponse, error) {
+	return UpdateNamedGQLSchema(ctx, "", gqlSchema, dgraphSchema)
+}
+
+// UpdateNamedGQLSchema is like UpdateGQLSchema, for the GraphQL schema with the given name.
+func UpdateNamedGQLSchema(ctx context.Context, name, gqlSchema,
 	dgraphSchema string) (*pb.UpdateGraphQLSchemaResponse, error) {
 	var err error
 	parsedDgraphSchema := &schema.ParsedSchema{}
@@ -185,6 +248,7 @@ func UpdateGQLSchema(ctx context.Context, gqlSchema,
 		GraphqlSchema: gqlSchema,
 		DgraphPreds:   parsedDgraphSchema.Preds,
 		DgraphTypes:   parsedDgraphSchema.Types,
+		Name:          name,
 	})
 }
 
@@ -236,6 +300,9 @@ func parseSchemaFromAlterOperation(ctx context.Context, sch string) (
 		return nil, errors.Wrapf(err, "While parsing schema")
 	}
 
+	if err := checkSandboxed(ctx); err != nil {
+		return nil, err
+	}
 	if x.IsRootNsOperation(ctx) {
 		// Only the guardian of the galaxy can do a galaxy wide query/mutation. This operation is
 		// needed by live loader.
@@ -284,6 +351,9 @@ func parseSchemaFromAlterOperation(ctx context.Context, sch string) (
 				x.ParseAttr(update.Predicate))
 		}
 	}
+	if err := checkEmbeddingSources(ctx, namespace, result.Preds); err != nil {
+		return nil, err
+	}
 
 	types := make(map[string]struct{})
 	for _, typ := range result.Types {
@@ -348,6 +418,7 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 	if err := validateAlterOperation(ctx, op); err != nil {
 		return nil, err
 	}
+	audit.SetOp(ctx, "alter")
 
 	defer glog.Infof("ALTER op: %+v done", op)
 
@@ -379,6 +450,7 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		if err != nil {
 			return empty, err
 		}
+		invalidateNamespaceViews(namespace, true)
 
 		// insert a helper record for backup & restore, indicating that drop_all was done
 		err = InsertDropRecord(ctx, "DROP_ALL;")
@@ -399,11 +471,15 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 			return empty, errors.Errorf("If DropOp is set to DATA, DropValue must be empty")
 		}
 
-		// query the GraphQL schema and keep it in memory, so it can be inserted again
+		// query the GraphQL schemas and keep them in memory, so they can be inserted again
 		_, graphQLSchema, err := GetGQLSchema(namespace)
 		if err != nil {
 			return empty, err
 		}
+		namedSchemas, err := GetNamedGQLSchemas(namespace)
+		if err != nil {
+			return empty, err
+		}
 
 		m.DropOp = pb.Mutations_DATA
 		m.DropValue = fmt.Sprintf("%#x", namespace)
@@ -411,6 +487,7 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		if err != nil {
 			return empty, err
 		}
+		invalidateNamespaceViews(namespace, false)
 
 		// insert a helper record for backup & restore, indicating that drop_data was done
 		err = InsertDropRecord(ctx, fmt.Sprintf("DROP_DATA;%#x", namespace))
@@ -418,8 +495,14 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 			return empty, err
 		}
 
-		// just reinsert the GraphQL schema, no need to alter dgraph schema as this was drop_data
+		// just reinsert the GraphQL schemas, no need to alter dgraph schema as this was drop_data
 		_, err = UpdateGQLSchema(ctx, graphQLSchema, "")
+		for name, sch := range namedSchemas {
+			if err != nil {
+				break
+			}
+			_, err = UpdateNamedGQLSchema(ctx, name, sch, "")
+		}
 
 		// Since all data has been dropped, we need to recreate the admin account in the respective namespace.
 		upsertGuardianAndGroot(nil, namespace)
@@ -437,6 +520,7 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		} else {
 			attr = op.DropValue
 		}
+		audit.AddPredicates(ctx, attr)
 		attr = x.NamespaceAttr(namespace, attr)
 		// Pre-defined predicates cannot be dropped.
 		if x.IsPreDefinedPredicate(attr) {
@@ -460,6 +544,7 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		if err != nil {
 			return empty, err
 		}
+		invalidateViews([]string{attr})
 
 		// insert a helper record for backup & restore, indicating that drop_attr was done
 		err = InsertDropRecord(ctx, "DROP_ATTR;"+attr)
@@ -483,6 +568,11 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		_, err := query.ApplyMutations(ctx, m)
 		return empty, err
 	}
+	if ren := parseRename(op.Schema); ren != nil {
+		audit.AddPredicates(ctx, ren.preds()...)
+		return s.rename(ctx, namespace, ren, op.RunInBackground)
+	}
+
 	result, err := parseSchemaFromAlterOperation(ctx, op.Schema)
 	if err == errIndexingInProgress {
 		// Make the client wait a bit.
@@ -492,6 +582,9 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		return nil, err
 	}
 
+	for _, update := range result.Preds {
+		audit.AddPredicates(ctx, x.ParseAttr(update.Predicate))
+	}
 	glog.Infof("Got schema: %+v\n", result)
 	// TODO: Maybe add some checks about the schema.
 	m.Schema = result.Preds
@@ -534,6 +627,17 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 		return errors.Errorf("no mutations allowed")
 	}
 
+	// A mutation whose idempotency key was used already gets the result of the earlier mutation.
+	idem := requestIdempotency(ctx)
+	var idemUid uint64
+	if idem != nil {
+		replayed, uid, err := replayIdempotent(ctx, qc, idem, resp)
+		if err != nil || replayed {
+			return err
+		}
+		idemUid = uid
+	}
+
 	// update mutations from the query results before assigning UIDs
 	if err := updateMutations(qc); err != nil {
 		return err
@@ -567,6 +671,12 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 	if err != nil {
 		return errors.Wrapf(err, "While doing mutations:")
 	}
+	if edges, err = addEmbeddingEdges(ctx, ns, edges); err != nil {
+		return err
+	}
+	if err := addProvenanceFacets(ctx, ns, edges, qc.req.StartTs); err != nil {
+		return err
+	}
 	predHints := make(map[string]pb.Metadata_HintType)
 	for _, gmu := range qc.gmuList {
 		for pred, hint := range gmu.Metadata.GetPredHints() {
@@ -589,10 +699,21 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 	if err := validateMutation(ctx, edges); err != nil {
 		return err
 	}
+	if idem != nil {
+		res := &idempotencyResult{Uids: resp.Uids, StartTs: qc.req.StartTs}
+		recEdges, err := recordIdempotent(ctx, ns, idemUid, idem, res)
+		if err != nil {
+			return err
+		}
+		m.Edges = append(m.Edges, recEdges...)
+	}
 
 	qc.span.AddEvent("Applying mutations",
 		trace.WithAttributes(attribute.String("m", fmt.Sprintf("%+v", m))))
 	resp.Txn, err = query.ApplyMutations(ctx, m)
+	if err == nil {
+		err = s.runTriggers(ctx, edges, resp.Txn)
+	}
 	qc.span.AddEvent("Txn Context",
 		trace.WithAttributes(attribute.String("txn", fmt.Sprintf("%+v", resp.Txn))))
 	if err != nil {
@@ -626,6 +747,7 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 
 		resp.Txn.Aborted = true
 		_, _ = worker.CommitOverNetwork(ctx, resp.Txn)
+		finishTriggers(qc.req.StartTs, false)
 
 		if err == x.ErrConflict {
 			// We have already aborted the transaction, so the error message should reflect that.
@@ -638,6 +760,7 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 	ctxn := resp.Txn
 	// zero would assign the CommitTs
 	cts, err := worker.CommitOverNetwork(ctx, ctxn)
+	finishTriggers(ctxn.StartTs, err == nil)
 	if err != nil {
 		qc.span.AddEvent("Status of commit at ts",
 			trace.WithAttributes(attribute.String("err", err.Error())))
@@ -653,6 +776,7 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 	// CommitNow was true, no need to send keys.
 	resp.Txn.Keys = resp.Txn.Keys[:0]
 	resp.Txn.CommitTs = cts
+	invalidateViews(committedAttrs(resp.Txn.Preds))
 	calculateMutationMetrics()
 	return nil
 }
@@ -1064,7 +1188,7 @@ func (s *Server) Health(ctx context.Context, all bool) (*api.Response, error) {
 		return nil, ctx.Err()
 	}
 
-	var healthAll []pb.HealthInfo
+	var healthAll []interface{}
 	if all {
 		if err := AuthorizeGuardians(ctx); err != nil {
 			return nil, err
@@ -1079,7 +1203,7 @@ func (s *Server) Health(ctx context.Context, all bool) (*api.Response, error) {
 	}
 
 	// Append self.
-	healthAll = append(healthAll, pb.HealthInfo{
+	healthAll = append(healthAll, alphaHealth{HealthInfo: &pb.HealthInfo{
 		Instance:    "alpha",
 		Address:     x.WorkerConfig.MyAddr,
 		Status:      "healthy",
@@ -1091,7 +1215,7 @@ func (s *Server) Health(ctx context.Context, all bool) (*api.Response, error) {
 		Indexing:    schema.GetIndexingPredicates(),
 		EeFeatures:  worker.GetFeaturesList(),
 		MaxAssigned: posting.Oracle().MaxAssigned(),
-	})
+	}, Encryption: getEncryptionStatus()})
 
 	var err error
 	var jsonOut []byte
@@ -1101,6 +1225,54 @@ func (s *Server) Health(ctx context.Context, all bool) (*api.Response, error) {
 	return &api.Response{Json: jsonOut}, nil
 }
 
+// alphaHealth is the health of this alpha, along with the status of its encryption keys.
+type alphaHealth struct {
+	*pb.HealthInfo
+	Encryption *encryptionStatus `json:"encryption,omitempty"`
+}
+
+type encryptionStatus struct {
+	// Enabled is true if encryption at rest is enabled, for the whole store or for some
+	// namespaces.
+	Enabled       bool                 `json:"enabled"`
+	Kms           string               `json:"kms,omitempty"`
+	NamespaceKeys []namespaceKeyStatus `json:"namespaceKeys,omitempty"`
+	// LastReload is the Unix time at which the namespace keys were last reloaded.
+	LastReload int64  `json:"lastReload,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type namespaceKeyStatus struct {
+	Namespace uint64 `json:"namespace"`
+	Version   uint32 `json:"version"`
+}
+
+func getEncryptionStatus() *encryptionStatus {
+	status := &encryptionStatus{
+		Enabled: len(x.WorkerConfig.EncryptionKey) > 0,
+		Kms:     x.WorkerConfig.EncryptionKms,
+	}
+	keys, ok := posting.NamespaceKeyStatus()
+	if !ok {
+		return status
+	}
+	status.Enabled = true
+	for ns, version := range keys.Versions {
+		status.NamespaceKeys = append(status.NamespaceKeys,
+			namespaceKeyStatus{Namespace: ns, Version: version})
+	}
+	sort.Slice(status.NamespaceKeys, func(i, j int) bool {
+		return status.NamespaceKeys[i].Namespace < status.NamespaceKeys[j].Namespace
+	})
+	if !keys.LastReload.IsZero() {
+		status.LastReload = keys.LastReload.Unix()
+	}
+	if keys.LastError != nil {
+		status.Error = keys.LastError.Error()
+	}
+	return status
+}
+
 // Filter out the tablets that do not belong to the requestor's namespace.
 func filterTablets(ctx context.Context, ms *pb.MembershipState) error {
 	if !x.WorkerConfig.AclEnabled {
@@ -1155,6 +1327,21 @@ func (s *Server) State(ctx context.Context) (*api.Response, error) {
 	return &api.Response{Json: jsonState}, nil
 }
 
+// checkSandboxed returns an error if the request of a sandboxed namespace reaches out of it, as
+// a galaxy wide operation forcing the namespace does. The users of the sandboxed namespaces have
+// access to their own namespace only, even if their credentials would pass the checks of ACLs.
+func checkSandboxed(ctx context.Context) error {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil || !isSandboxed(ns) {
+		return nil
+	}
+	if x.IsRootNsOperation(ctx) {
+		return status.Errorf(codes.PermissionDenied,
+			"The requests of the sandboxed namespace %#x can't access the other namespaces", ns)
+	}
+	return nil
+}
+
 func getAuthMode(ctx context.Context) AuthMode {
 	if auth := ctx.Value(Authorize); auth == nil || auth.(bool) {
 		return NeedAuthorize
@@ -1168,10 +1355,10 @@ func (s *Server) QueryGraphQL(ctx context.Context, req *api.Request,
 	// Add a timeout for queries which don't have a deadline set. We don't want to
 	// apply a timeout if it's a mutation, that's currently handled by flag
 	// "txn-abort-after".
-	if req.GetMutations() == nil && x.Config.QueryTimeout != 0 {
+	if timeout := time.Duration(queryTimeout.Load()); req.GetMutations() == nil && timeout != 0 {
 		if d, _ := ctx.Deadline(); d.IsZero() {
 			var cancel context.CancelFunc
-			ctx, cancel = context.WithTimeout(ctx, x.Config.QueryTimeout)
+			ctx, cancel = context.WithTimeout(ctx, timeout)
 			defer cancel()
 		}
 	}
@@ -1180,11 +1367,50 @@ func (s *Server) QueryGraphQL(ctx context.Context, req *api.Request,
 }
 
 func (s *Server) Query(ctx context.Context, req *api.Request) (*api.Response, error) {
-	resp, err := s.QueryNoGrpc(ctx, req)
+	ctx, requestId := x.WithRequestId(ctx)
+	if requestId != "" {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(x.RequestIdHeader, requestId)); err != nil {
+			glog.Warningf("error in setting grpc headers: %v", err)
+		}
+	}
+	maxRetries, err := requestedRetries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	isPartial, err := query.PartialResultsRequested(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var partial *query.PartialResults
+	if isPartial {
+		ctx, partial = query.WithPartialResults(ctx)
+	}
+	if spec, ok := x.RequestedExperimentalPaths(ctx); ok {
+		if ctx, err = x.WithExperimentalPaths(ctx, spec); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	var idem *Idempotency
+	if key, ok := requestedIdempotencyKey(ctx); ok {
+		if ctx, idem, err = WithIdempotencyKey(ctx, key); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	resp, retries, err := s.QueryWithRetries(ctx, req, maxRetries)
 	if err != nil {
 		return resp, err
 	}
 	md := metadata.Pairs(x.DgraphCostHeader, fmt.Sprint(resp.Metrics.NumUids["_total"]))
+	if maxRetries > 0 {
+		md.Append(x.DgraphRetriesHeader, strconv.Itoa(retries))
+	}
+	if idem != nil && idem.Replayed() {
+		md.Append(x.IdempotentReplayHeader, "true")
+	}
+	if partial != nil {
+		// The paths of the subgraphs which weren't fetched, the results are partial if any.
+		md.Append(x.DgraphBoundaryHeader, partial.Boundary()...)
+	}
 	if err := grpc.SendHeader(ctx, md); err != nil {
 		glog.Warningf("error in sending grpc headers: %v", err)
 	}
@@ -1207,16 +1433,84 @@ func (s *Server) QueryNoGrpc(ctx context.Context, req *api.Request) (*api.Respon
 	// Add a timeout for queries which don't have a deadline set. We don't want to
 	// apply a timeout if it's a mutation, that's currently handled by flag
 	// "txn-abort-after".
-	if req.GetMutations() == nil && x.Config.QueryTimeout != 0 {
+	if timeout := time.Duration(queryTimeout.Load()); req.GetMutations() == nil && timeout != 0 {
 		if d, _ := ctx.Deadline(); d.IsZero() {
 			var cancel context.CancelFunc
-			ctx, cancel = context.WithTimeout(ctx, x.Config.QueryTimeout)
+			ctx, cancel = context.WithTimeout(ctx, timeout)
 			defer cancel()
 		}
 	}
 	return s.doQuery(ctx, &Request{req: req, doAuth: getAuthMode(ctx)})
 }
 
+// requestedRetries returns the number of retries the client allows for a mutation aborted due
+// to a conflict, from the Dgraph-Retries header of the gRPC request.
+func requestedRetries(ctx context.Context) (int, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+	vals := md.Get(x.DgraphRetriesHeader)
+	if len(vals) == 0 {
+		return 0, nil
+	}
+	retries, err := strconv.Atoi(vals[0])
+	if err != nil || retries < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "Invalid value %q for the %s header",
+			vals[0], x.DgraphRetriesHeader)
+	}
+	return retries, nil
+}
+
+// retriable returns true if the request can be run again as a whole when it's aborted due to
+// a conflict. That's the case for the mutations, and upsert blocks, which run in a transaction
+// of their own, i.e. commit immediately without a start ts. The query of an upsert block is
+// evaluated again at the fresh start ts of each retry.
+func retriable(req *api.Request) bool {
+	return len(req.GetMutations()) > 0 && req.GetCommitNow() && req.GetStartTs() == 0
+}
+
+// isAborted returns true if the error means the transaction was aborted due to a conflict.
+func isAborted(err error) bool {
+	return err == dgo.ErrAborted || status.Code(err) == codes.Aborted
+}
+
+// QueryWithRetries runs the request like QueryNoGrpc. If the request is retriable and it's
+// aborted due to a conflict, it's retried with a fresh start ts, up to maxRetries times, capped
+// by the mutation-retries limit. It returns the number of retries made.
+func (s *Server) QueryWithRetries(ctx context.Context, req *api.Request,
+	maxRetries int) (*api.Response, int, error) {
+	return retryAborted(ctx, req, maxRetries, s.QueryNoGrpc)
+}
+
+// retryAborted runs the request with run, retrying it like QueryWithRetries does.
+func retryAborted(ctx context.Context, req *api.Request, maxRetries int,
+	run func(context.Context, *api.Request) (*api.Response, error)) (*api.Response, int, error) {
+	maxRetries = min(maxRetries, x.Config.MutationRetries)
+	if maxRetries <= 0 || !retriable(req) {
+		resp, err := run(ctx, req)
+		return resp, 0, err
+	}
+
+	wait := 10 * time.Millisecond
+	for retries := 0; ; retries++ {
+		// Running the request sets its start ts, and trims its query. Each attempt starts from
+		// the request as it was sent.
+		resp, err := run(ctx, proto.Clone(req).(*api.Request))
+		if !isAborted(err) || retries == maxRetries {
+			return resp, retries, err
+		}
+		glog.V(2).Infof("Retrying the mutation aborted due to a conflict, retry %d of %d",
+			retries+1, maxRetries)
+		select {
+		case <-ctx.Done():
+			return resp, retries, err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
 func (s *Server) QueryNoAuth(ctx context.Context, req *api.Request) (*api.Response, error) {
 	return s.doQuery(ctx, &Request{req: req, doAuth: NoAuthorize})
 }
@@ -1225,8 +1519,33 @@ var pendingQueries int64
 var maxPendingQueries int64
 var serverOverloadErr = errors.New("429 Too Many Requests. Please throttle your requests")
 
+// queryTimeout is x.Config.QueryTimeout, which can be changed by the cluster config.
+var queryTimeout atomic.Int64
+
 func Init() {
-	maxPendingQueries = x.Config.Limit.GetInt64("max-pending-queries")
+	atomic.StoreInt64(&maxPendingQueries, x.Config.Limit.GetInt64("max-pending-queries"))
+	queryTimeout.Store(int64(x.Config.QueryTimeout))
+
+	x.HandleClusterSetting("max-pending-queries", func() string {
+		return strconv.FormatInt(atomic.LoadInt64(&maxPendingQueries), 10)
+	}, func(val string) error {
+		limit, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		atomic.StoreInt64(&maxPendingQueries, limit)
+		return nil
+	})
+	x.HandleClusterSetting("query-timeout", func() string {
+		return time.Duration(queryTimeout.Load()).String()
+	}, func(val string) error {
+		timeout, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		queryTimeout.Store(int64(timeout))
+		return nil
+	})
 }
 
 func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response, rerr error) {
@@ -1234,7 +1553,7 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 		return nil, ctx.Err()
 	}
 	defer atomic.AddInt64(&pendingQueries, -1)
-	if val := atomic.AddInt64(&pendingQueries, 1); val > maxPendingQueries {
+	if val := atomic.AddInt64(&pendingQueries, 1); val > atomic.LoadInt64(&maxPendingQueries) {
 		return nil, serverOverloadErr
 	}
 
@@ -1246,9 +1565,10 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 	}
 	l := &query.Latency{}
 	l.Start = time.Now()
+	ctx, _ = x.WithRequestId(ctx)
 
 	if bool(glog.V(3)) || worker.LogDQLRequestEnabled() {
-		glog.Infof("Got a query, DQL form: %+v %+v at %+v",
+		glog.Infof("%sGot a query, DQL form: %+v %+v at %+v", x.RequestLogPrefix(ctx),
 			req.req.Query, req.req.Mutations, l.Start.Format(time.RFC3339))
 	}
 
@@ -1260,8 +1580,10 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 
 	var measurements []ostats.Measurement
 	ctx, span := otel.Tracer("").Start(ctx, methodRequest)
+	x.AnnotateRequestId(ctx)
 	if ns, err := x.ExtractNamespace(ctx); err == nil {
 		annotateNamespace(span, ns)
+		nsRequests.record(ns, l.Start.Unix())
 	}
 
 	ctx = x.WithMethod(ctx, methodRequest)
@@ -1275,6 +1597,14 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 		timeSpentMs := x.SinceMs(l.Start)
 		measurements = append(measurements, x.LatencyMs.M(timeSpentMs))
 		ostats.Record(ctx, measurements...)
+		if ns, err := x.ExtractNamespace(ctx); err == nil {
+			x.ObserveNamespace(ctx, ns, methodRequest, v, l.Start)
+		}
+		if x.FlightRecorderEnabled() {
+			ns, _ := x.ExtractNamespace(ctx)
+			x.RecordFlightEvent("query", "%s ns=%#x latency=%s err=%v query=%.256q",
+				methodRequest, ns, time.Since(l.Start), rerr, req.req.Query)
+		}
 	}()
 
 	if rerr = x.HealthCheck(); rerr != nil {
@@ -1284,6 +1614,18 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 	req.req.Query = strings.TrimSpace(req.req.Query)
 	isQuery := len(req.req.Query) != 0
 	if !isQuery && !isMutation {
+		if req.req.StartTs != 0 {
+			// An empty request in a transaction keeps the transaction alive.
+			span.AddEvent("Keeping the transaction alive")
+			if rerr = worker.KeepAliveOverNetwork(ctx, req.req.StartTs); rerr != nil {
+				return nil, rerr
+			}
+			return &api.Response{
+				Txn:     &api.TxnContext{StartTs: req.req.StartTs},
+				Metrics: &api.Metrics{NumUids: map[string]uint64{"_total": 0}},
+				Latency: &api.Latency{TotalNs: uint64(time.Since(l.Start).Nanoseconds())},
+			}, nil
+		}
 		span.AddEvent("empty request")
 		return nil, errors.Errorf("empty request")
 	}
@@ -1300,6 +1642,11 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 		ostats.Record(ctx, x.NumMutations.M(1))
 	}
 
+	if req.doAuth == NeedAuthorize {
+		if rerr = checkSandboxed(ctx); rerr != nil {
+			return
+		}
+	}
 	if req.doAuth == NeedAuthorize && x.IsRootNsOperation(ctx) {
 		// Only the guardian of the galaxy can do a galaxy wide query/mutation. This operation is
 		// needed by live loader.
@@ -1310,6 +1657,34 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 		}
 	}
 
+	// The shape of the DQL queries of the users is limited. The GraphQL queries have limits of
+	// their own, and the internal queries aren't limited. The queries of the sandboxed
+	// namespaces are limited whatever their entry point, DQL, GraphQL or the custom DQL of
+	// GraphQL.
+	if req.doAuth != NoAuthorize {
+		if ns, err := x.ExtractNamespace(ctx); err == nil {
+			if sandboxed := isSandboxed(ns); sandboxed || (req.gqlField == nil && !isGraphQL) {
+				if limits := x.Config.NsQueryLimits(ns, sandboxed); !limits.IsZero() {
+					ctx = context.WithValue(ctx, query.QueryLimitsKey, limits)
+				}
+			}
+		}
+	}
+
+	if idem := requestIdempotency(ctx); idem != nil && isMutation && !retriable(req.req) {
+		return nil, errors.Errorf("A mutation with an idempotency key should commit immediately, " +
+			"without a start ts")
+	}
+
+	// Likewise, the size of the mutations of the users is limited.
+	if isMutation && req.doAuth != NoAuthorize {
+		if ns, err := x.ExtractNamespace(ctx); err == nil {
+			if rerr = checkMutationSize(ns, mutationsSize(req.req.Mutations)); rerr != nil {
+				return
+			}
+		}
+	}
+
 	qc := &queryContext{
 		req:      req.req,
 		latency:  l,
@@ -1320,6 +1695,9 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 	if rerr = parseRequest(ctx, qc); rerr != nil {
 		return
 	}
+	if audit.Enabled(ctx) {
+		auditRequest(ctx, qc)
+	}
 
 	if req.doAuth == NeedAuthorize {
 		if rerr = authorizeRequest(ctx, qc); rerr != nil {
@@ -1350,7 +1728,9 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 	}
 
 	var gqlErrs error
-	if resp, rerr = processQuery(ctx, qc); rerr != nil {
+	queryCtx, cancel := sandboxQueryContext(ctx, req)
+	defer cancel()
+	if resp, rerr = processQuery(queryCtx, qc); rerr != nil {
 		// if rerr is just some error from GraphQL encoding, then we need to continue the normal
 		// execution ignoring the error as we still need to assign latency info to resp. If we can
 		// change the api.Response proto to have a field to contain GraphQL errors, that would be
@@ -1381,9 +1761,39 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (resp *api.Response,
 		EncodingNs:        uint64(l.Json.Nanoseconds()),
 		TotalNs:           uint64((time.Since(l.Start)).Nanoseconds()),
 	}
+	// The DQL queries of the users are sampled, to be replayed by dgraph replay.
+	if !isMutation && !isGraphQL && qc.gqlField == nil && req.doAuth != NoAuthorize &&
+		x.SampleQueryCapture() {
+		captureQuery(ctx, qc, resp)
+	}
 	return resp, gqlErrs
 }
 
+// captureQuery captures the query with its result, see x.CaptureQuery.
+func captureQuery(ctx context.Context, qc *queryContext, resp *api.Response) {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return
+	}
+	q := &x.CapturedQuery{
+		Time:       qc.latency.Start.UTC(),
+		Namespace:  ns,
+		Query:      qc.req.Query,
+		Vars:       qc.req.Vars,
+		ReadOnly:   qc.req.ReadOnly,
+		BestEffort: qc.req.BestEffort,
+		StartTs:    qc.req.StartTs,
+		Latency:    time.Duration(resp.Latency.TotalNs),
+	}
+	if len(resp.Json) > 0 {
+		if q.ResultHash, err = x.ResultHash(resp.Json); err != nil {
+			glog.Warningf("%sWhile capturing the query: %v", x.RequestLogPrefix(ctx), err)
+			return
+		}
+	}
+	x.CaptureQuery(q)
+}
+
 func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error) {
 	resp := &api.Response{}
 	if qc.req.Query == "" {
@@ -1437,13 +1847,13 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 	er, err := qr.Process(ctx)
 
 	if bool(glog.V(3)) || worker.LogDQLRequestEnabled() {
-		glog.Infof("Finished a query that started at: %+v",
+		glog.Infof("%sFinished a query that started at: %+v", x.RequestLogPrefix(ctx),
 			qr.Latency.Start.Format(time.RFC3339))
 	}
 
 	if err != nil {
 		if bool(glog.V(3)) {
-			glog.Infof("Error processing query: %+v\n", err.Error())
+			glog.Infof("%sError processing query: %+v\n", x.RequestLogPrefix(ctx), err.Error())
 		}
 		return resp, errors.Wrap(err, "")
 	}
@@ -1469,6 +1879,8 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 		resp.Json, err = json.Marshal(respMap)
 	} else if qc.req.RespFormat == api.Request_RDF {
 		resp.Rdf, err = query.ToRDF(qc.latency, er.Subgraphs)
+	} else if qc.gqlField == nil && query.IsGraphFormat(ctx) {
+		resp.Json, err = query.ToGraph(qc.latency, er.Subgraphs)
 	} else {
 		resp.Json, err = query.ToJson(ctx, qc.latency, er.Subgraphs, qc.gqlField)
 	}
@@ -1539,6 +1951,23 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 	return resp, err
 }
 
+// auditRequest adds the operation and the predicates of the request to its audit event.
+func auditRequest(ctx context.Context, qc *queryContext) {
+	switch {
+	case len(qc.gmuList) == 0:
+		audit.SetOp(ctx, "query")
+	case len(qc.dqlRes.Query) == 0:
+		audit.SetOp(ctx, "mutation")
+	default:
+		audit.SetOp(ctx, "upsert")
+	}
+	audit.AddPredicates(ctx, parsePredsFromQuery(qc.dqlRes.Query).preds...)
+	for _, gmu := range qc.gmuList {
+		audit.AddPredicates(ctx, parsePredsFromMutation(gmu.Set)...)
+		audit.AddPredicates(ctx, parsePredsFromMutation(gmu.Del)...)
+	}
+}
+
 // parseRequest parses the incoming request
 func parseRequest(ctx context.Context, qc *queryContext) error {
 	start := time.Now()
@@ -1579,9 +2008,14 @@ func parseRequest(ctx context.Context, qc *queryContext) error {
 
 	// parsing the updated query
 	var err error
+	var funcs map[string]*dql.StoredFunction
+	if ns, err := x.ExtractNamespace(ctx); err == nil {
+		funcs = storedFunctions(ns)
+	}
 	qc.dqlRes, err = dql.ParseWithNeedVars(dql.Request{
 		Str:       upsertQuery,
 		Variables: qc.req.Vars,
+		Functions: funcs,
 	}, needVars)
 	if err != nil {
 		return err
@@ -1871,6 +2305,7 @@ func (s *Server) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (*api.Tx
 
 	span.AddEvent("Txn Context received", trace.WithAttributes(attribute.Stringer("txn", tc)))
 	commitTs, err := worker.CommitOverNetwork(ctx, tc)
+	finishTriggers(tc.StartTs, err == nil && !tc.Aborted)
 	if err == dgo.ErrAborted {
 		// If err returned is dgo.ErrAborted and tc.Aborted was set, that means the client has
 		// aborted the transaction by calling txn.Discard(). Hence return a nil error.
@@ -1883,9 +2318,35 @@ func (s *Server) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (*api.Tx
 	}
 	tctx.StartTs = tc.StartTs
 	tctx.CommitTs = commitTs
+	if err == nil && !tc.Aborted {
+		invalidateViews(committedAttrs(tc.Preds))
+	}
 	return tctx, err
 }
 
+// KeepAlive keeps the transaction alive, so that it isn't aborted for being idle, like an empty
+// request in the transaction does.
+func (s *Server) KeepAlive(ctx context.Context, tc *api.TxnContext) (*api.TxnContext, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "Server.KeepAlive")
+	defer span.End()
+
+	if err := x.HealthCheck(); err != nil {
+		return &api.TxnContext{}, err
+	}
+	if tc.StartTs == 0 {
+		return &api.TxnContext{}, errors.Errorf(
+			"StartTs cannot be zero while keeping a transaction alive")
+	}
+	annotateStartTs(span, tc.StartTs)
+	if err := validateNamespace(ctx, tc); err != nil {
+		return &api.TxnContext{}, err
+	}
+	if err := worker.KeepAliveOverNetwork(ctx, tc.StartTs); err != nil {
+		return &api.TxnContext{}, err
+	}
+	return &api.TxnContext{StartTs: tc.StartTs}, nil
+}
+
 // CheckVersion returns the version of this Dgraph instance.
 func (s *Server) CheckVersion(ctx context.Context, c *api.Check) (v *api.Version, err error) {
 	if err := x.HealthCheck(); err != nil {
@@ -10,9 +10,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/dgraph-io/dgo/v250/protos/api"
@@ -286,3 +289,83 @@ func TestVerifyUniqueWithinMutationBoundsChecks(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestRequestedRetries(t *testing.T) {
+	retries, err := requestedRetries(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, retries)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(x.DgraphRetriesHeader, "3"))
+	retries, err = requestedRetries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, retries)
+
+	for _, val := range []string{"-1", "many"} {
+		ctx := metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(x.DgraphRetriesHeader, val))
+		_, err := requestedRetries(ctx)
+		require.Error(t, err)
+	}
+}
+
+func TestRetriable(t *testing.T) {
+	mu := []*api.Mutation{{SetNquads: []byte(`_:a <name> "a" .`)}}
+	require.True(t, retriable(&api.Request{Mutations: mu, CommitNow: true}))
+	require.True(t, retriable(&api.Request{Query: `{ q(func: eq(name, "a")) { v as uid } }`,
+		Mutations: mu, CommitNow: true}))
+	// The transactions started by the client can't be run again by the server.
+	require.False(t, retriable(&api.Request{Mutations: mu}))
+	require.False(t, retriable(&api.Request{Mutations: mu, CommitNow: true, StartTs: 5}))
+	require.False(t, retriable(&api.Request{Query: `{ q(func: has(name)) { uid } }`}))
+}
+
+func TestCheckSandboxed(t *testing.T) {
+	defer func(namespaces map[uint64]struct{}) { sandboxes.namespaces = namespaces }(
+		sandboxes.namespaces)
+	sandboxes.namespaces = map[uint64]struct{}{2: {}}
+
+	ctx := func(ns string, galaxy bool) context.Context {
+		md := metadata.Pairs("namespace", ns)
+		if galaxy {
+			md.Set("galaxy-operation", "true")
+			md.Set("force-namespace", "3")
+		}
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+	require.NoError(t, checkSandboxed(ctx("2", false)))
+	require.NoError(t, checkSandboxed(ctx("1", true)))
+	require.NoError(t, checkSandboxed(context.Background()))
+	err := checkSandboxed(ctx("2", true))
+	require.Error(t, err)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestSandboxQueryContext(t *testing.T) {
+	defer func(namespaces map[uint64]struct{}, timeout time.Duration) {
+		sandboxes.namespaces, x.Config.SandboxTimeout = namespaces, timeout
+	}(sandboxes.namespaces, x.Config.SandboxTimeout)
+	sandboxes.namespaces = map[uint64]struct{}{2: {}}
+	x.Config.SandboxTimeout = time.Minute
+
+	upsert := &api.Request{
+		Query:     `{ q(func: eq(name, "a")) { v as uid } }`,
+		Mutations: []*api.Mutation{{SetNquads: []byte(`uid(v) <name> "b" .`)}},
+		CommitNow: true,
+	}
+	deadline := func(ns uint64, req *Request) bool {
+		ctx, cancel := sandboxQueryContext(x.AttachNamespace(context.Background(), ns), req)
+		defer cancel()
+		_, ok := ctx.Deadline()
+		return ok
+	}
+	// The query block of an upsert is capped like a query, but not a mutation without query.
+	require.True(t, deadline(2, &Request{req: upsert, doAuth: NeedAuthorize}))
+	require.True(t, deadline(2, &Request{req: &api.Request{Query: upsert.Query},
+		doAuth: NeedAuthorize}))
+	require.False(t, deadline(2, &Request{req: &api.Request{Mutations: upsert.Mutations,
+		CommitNow: true}, doAuth: NeedAuthorize}))
+	// The other namespaces and the internal requests aren't capped.
+	require.False(t, deadline(1, &Request{req: upsert, doAuth: NeedAuthorize}))
+	require.False(t, deadline(2, &Request{req: upsert, doAuth: NoAuthorize}))
+}
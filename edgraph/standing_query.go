@@ -0,0 +1,390 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// standingQueueSize is the number of deltas buffered for the webhook and for every
+	// subscriber of a standing query.
+	standingQueueSize = 1000
+	standingTimeout   = 10 * time.Second
+	standingRetries   = 3
+)
+
+// StandingQueryRequest registers a standing DQL query, whose changes are pushed as deltas.
+type StandingQueryRequest struct {
+	// Query has a single query block selecting the uid of its root nodes, as for the views.
+	Query string
+	// Webhook is the url the deltas are posted to. Without a webhook, the deltas are only sent to
+	// the subscribers of the standing query.
+	Webhook string
+	// RefreshInterval is how often the query is run again, on top of the runs after the mutations
+	// committed through this Alpha.
+	RefreshInterval time.Duration
+}
+
+// StandingQueryDelta is pushed when the root nodes matched by a standing query change. Its uids
+// are in hex. A gap in the sequence numbers means that deltas were dropped, in which case the
+// client should resync by subscribing again.
+type StandingQueryDelta struct {
+	Id  uint64 `json:"id"`
+	Seq uint64 `json:"seq"`
+	// Matched are the root nodes matched since the previous delta, and Unmatched the ones which
+	// aren't matched anymore.
+	Matched   []string `json:"matched"`
+	Unmatched []string `json:"unmatched"`
+}
+
+// StandingQueryStatus reports the state of a standing query.
+type StandingQueryStatus struct {
+	Id              uint64
+	Query           string
+	Webhook         string
+	Predicates      []string
+	RefreshInterval time.Duration
+	// Matched is the number of root nodes currently matched, and Seq the sequence number of the
+	// last delta.
+	Matched     int
+	Seq         uint64
+	Subscribers int
+	RefreshedAt time.Time
+	// Error is the error of the last run of the query, or of the last post to the webhook.
+	Error string
+}
+
+// standingQuery is maintained as a view, whose results are diffed with the previous ones to push
+// the deltas.
+type standingQuery struct {
+	*view
+	id      uint64
+	webhook string
+	client  *http.Client
+	// queue has the deltas to post to the webhook, it's nil without a webhook.
+	queue chan []byte
+
+	// The fields below are protected by the lock of the view.
+	matched     map[uint64]struct{}
+	seq         uint64
+	subscribers map[chan []byte]struct{}
+	webhookErr  string
+}
+
+// matchedDelta returns the delta between the uids matched before and the uids of the results.
+func matchedDelta(before map[uint64]struct{}, results map[uint64][]byte) ([]uint64, []uint64) {
+	var matched, unmatched []uint64
+	for uid := range results {
+		if _, ok := before[uid]; !ok {
+			matched = append(matched, uid)
+		}
+	}
+	for uid := range before {
+		if _, ok := results[uid]; !ok {
+			unmatched = append(unmatched, uid)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i] < matched[j] })
+	sort.Slice(unmatched, func(i, j int) bool { return unmatched[i] < unmatched[j] })
+	return matched, unmatched
+}
+
+func hexUids(uids []uint64) []string {
+	res := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		res = append(res, fmt.Sprintf("%#x", uid))
+	}
+	return res
+}
+
+func (sq *standingQuery) encode(seq uint64, matched, unmatched []uint64) []byte {
+	js, err := json.Marshal(StandingQueryDelta{Id: sq.id, Seq: seq, Matched: hexUids(matched),
+		Unmatched: hexUids(unmatched)})
+	x.Check(err)
+	return append(js, '\n')
+}
+
+// publish pushes the delta of the new results, if the matched root nodes changed. The first
+// results are pushed as matched. It's called holding the lock of the view.
+func (sq *standingQuery) publish(results map[uint64][]byte) {
+	matched, unmatched := matchedDelta(sq.matched, results)
+	sq.matched = make(map[uint64]struct{}, len(results))
+	for uid := range results {
+		sq.matched[uid] = struct{}{}
+	}
+	if len(matched) == 0 && len(unmatched) == 0 {
+		return
+	}
+	sq.seq++
+	delta := sq.encode(sq.seq, matched, unmatched)
+
+	if sq.queue != nil {
+		select {
+		case sq.queue <- delta:
+		default:
+			glog.Warningf("Webhook %s of standing query %d is not keeping up, dropping delta %d",
+				sq.webhook, sq.id, sq.seq)
+		}
+	}
+	// A subscriber which doesn't keep up is disconnected, it can subscribe again to resync.
+	for ch := range sq.subscribers {
+		select {
+		case ch <- delta:
+		default:
+			delete(sq.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns a channel receiving the deltas of the standing query, starting with the
+// matched root nodes. The returned function unsubscribes.
+func (sq *standingQuery) subscribe() (<-chan []byte, func(), error) {
+	sq.Lock()
+	defer sq.Unlock()
+	select {
+	case <-sq.done:
+		return nil, nil, errors.Errorf("The standing query %d was deleted", sq.id)
+	default:
+	}
+	ch := make(chan []byte, standingQueueSize)
+	matched := make([]uint64, 0, len(sq.matched))
+	for uid := range sq.matched {
+		matched = append(matched, uid)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i] < matched[j] })
+	ch <- sq.encode(sq.seq, matched, nil)
+	sq.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		sq.Lock()
+		defer sq.Unlock()
+		if _, ok := sq.subscribers[ch]; ok {
+			delete(sq.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+// stop stops maintaining the standing query, and disconnects its subscribers.
+func (sq *standingQuery) stop() {
+	sq.view.stop()
+	sq.Lock()
+	defer sq.Unlock()
+	for ch := range sq.subscribers {
+		delete(sq.subscribers, ch)
+		close(ch)
+	}
+	if sq.queue != nil {
+		close(sq.queue)
+	}
+}
+
+// postDeltas posts the queued deltas to the webhook in order, retrying failed posts.
+func (sq *standingQuery) postDeltas() {
+	for delta := range sq.queue {
+		var err error
+		for i := 0; i < standingRetries; i++ {
+			if i > 0 {
+				time.Sleep(time.Duration(i) * time.Second)
+			}
+			if err = sq.post(delta); err == nil {
+				break
+			}
+		}
+		sq.Lock()
+		sq.webhookErr = ""
+		if err != nil {
+			sq.webhookErr = err.Error()
+		}
+		sq.Unlock()
+		if err != nil {
+			glog.Errorf("Unable to post delta of standing query %d to webhook %s: %v", sq.id,
+				sq.webhook, err)
+		}
+	}
+}
+
+func (sq *standingQuery) post(body []byte) error {
+	resp, err := sq.client.Post(sq.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("got status %s", resp.Status)
+	}
+	return nil
+}
+
+func (sq *standingQuery) statusLocked() StandingQueryStatus {
+	st := StandingQueryStatus{
+		Id:              sq.id,
+		Query:           sq.req.Query,
+		Webhook:         sq.webhook,
+		Predicates:      sq.status.Predicates,
+		RefreshInterval: sq.req.RefreshInterval,
+		Matched:         len(sq.matched),
+		Seq:             sq.seq,
+		Subscribers:     len(sq.subscribers),
+		RefreshedAt:     sq.status.RefreshedAt,
+		Error:           sq.status.Error,
+	}
+	if st.Error == "" {
+		st.Error = sq.webhookErr
+	}
+	return st
+}
+
+// AddStandingQuery registers the standing query in the namespace of the context, and returns its
+// id. Its query is run once before it's registered, and then again after the mutations of its
+// predicates, pushing a delta whenever its matched root nodes change.
+func (s *Server) AddStandingQuery(ctx context.Context, req *StandingQueryRequest) (uint64, error) {
+	if err := x.HealthCheck(); err != nil {
+		return 0, err
+	}
+	if req.Webhook != "" {
+		if u, err := url.Parse(req.Webhook); err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") {
+			return 0, errors.Errorf("Invalid webhook url %q", req.Webhook)
+		}
+	}
+	if req.RefreshInterval < 0 {
+		return 0, errors.New("The refresh interval of a standing query can't be negative")
+	}
+	preds, err := parseViewQuery(req.Query)
+	if err != nil {
+		return 0, err
+	}
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	views.Lock()
+	views.lastId++
+	id := views.lastId
+	views.Unlock()
+
+	sq := &standingQuery{
+		view: newView(ns, ViewRequest{Name: "standing_" + strconv.FormatUint(id, 10),
+			Query: req.Query, RefreshInterval: req.RefreshInterval}, preds),
+		id:          id,
+		webhook:     req.Webhook,
+		subscribers: make(map[chan []byte]struct{}),
+	}
+	sq.view.publish = sq.publish
+	if req.Webhook != "" {
+		sq.client = &http.Client{Timeout: standingTimeout}
+		sq.queue = make(chan []byte, standingQueueSize)
+	}
+	commits, results, err := sq.computeFirst()
+	if err != nil {
+		return 0, err
+	}
+	if sq.queue != nil {
+		go sq.postDeltas()
+	}
+
+	views.Lock()
+	views.standing[id] = sq
+	sq.register(commits, results)
+	views.Unlock()
+
+	if req.RefreshInterval > 0 {
+		go sq.refreshPeriodically()
+	}
+	glog.Infof("Registered the standing query %d in namespace %#x, reading the predicates %v",
+		id, ns, preds)
+	return id, nil
+}
+
+// standingQueryOf returns the standing query of the namespace of the context with the id. The
+// registry must be locked.
+func standingQueryOf(ctx context.Context, id uint64) (*standingQuery, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	sq, ok := views.standing[id]
+	if !ok || sq.ns != ns {
+		return nil, nil
+	}
+	return sq, nil
+}
+
+// DeleteStandingQuery deletes the standing query of the namespace of the context, disconnecting
+// its subscribers. It returns false if there isn't a standing query with the id.
+func (s *Server) DeleteStandingQuery(ctx context.Context, id uint64) (bool, error) {
+	views.Lock()
+	defer views.Unlock()
+	sq, err := standingQueryOf(ctx, id)
+	if err != nil || sq == nil {
+		return false, err
+	}
+	sq.stop()
+	delete(views.standing, id)
+	return true, nil
+}
+
+// StandingQueries reports the standing queries of the namespace of the context, by id.
+func (s *Server) StandingQueries(ctx context.Context) ([]StandingQueryStatus, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	views.Lock()
+	defer views.Unlock()
+	var res []StandingQueryStatus
+	for _, sq := range views.standing {
+		if sq.ns == ns {
+			sq.Lock()
+			res = append(res, sq.statusLocked())
+			sq.Unlock()
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res, nil
+}
+
+// SubscribeStandingQuery returns a channel receiving the deltas of the standing query of the
+// namespace of the context, as newline terminated JSON. The first delta has all the matched root
+// nodes. The channel is closed when the standing query is deleted, or when the subscriber doesn't
+// keep up with the deltas. The returned function unsubscribes. Only guardians can subscribe.
+func (s *Server) SubscribeStandingQuery(ctx context.Context, id uint64) (
+	<-chan []byte, func(), error) {
+
+	if err := AuthorizeGuardians(ctx); err != nil {
+		return nil, nil, err
+	}
+	views.Lock()
+	sq, err := standingQueryOf(ctx, id)
+	views.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	if sq == nil {
+		return nil, nil, errors.Errorf("The standing query %d doesn't exist", id)
+	}
+	return sq.subscribe()
+}
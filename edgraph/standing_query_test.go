@@ -0,0 +1,64 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchedDelta(t *testing.T) {
+	before := map[uint64]struct{}{1: {}, 2: {}, 5: {}}
+	matched, unmatched := matchedDelta(before, map[uint64][]byte{5: nil, 4: nil, 3: nil})
+	require.Equal(t, []uint64{3, 4}, matched)
+	require.Equal(t, []uint64{1, 2}, unmatched)
+
+	matched, unmatched = matchedDelta(nil, map[uint64][]byte{2: nil})
+	require.Equal(t, []uint64{2}, matched)
+	require.Nil(t, unmatched)
+}
+
+func TestStandingQueryPublish(t *testing.T) {
+	sq := &standingQuery{
+		view:        newView(0, ViewRequest{}, nil),
+		id:          7,
+		subscribers: make(map[chan []byte]struct{}),
+	}
+	decode := func(js []byte) StandingQueryDelta {
+		var delta StandingQueryDelta
+		require.NoError(t, json.Unmarshal(js, &delta))
+		return delta
+	}
+
+	sq.publish(map[uint64][]byte{1: nil, 0x10: nil})
+	deltas, unsubscribe, err := sq.subscribe()
+	require.NoError(t, err)
+	require.Equal(t, StandingQueryDelta{Id: 7, Seq: 1, Matched: []string{"0x1", "0x10"},
+		Unmatched: []string{}}, decode(<-deltas))
+
+	// The same matched nodes don't push a delta.
+	sq.publish(map[uint64][]byte{1: nil, 0x10: []byte(`{"uid":"0x10"}`)})
+	sq.publish(map[uint64][]byte{0x10: nil, 0x20: nil})
+	require.Equal(t, StandingQueryDelta{Id: 7, Seq: 2, Matched: []string{"0x20"},
+		Unmatched: []string{"0x1"}}, decode(<-deltas))
+	require.Len(t, deltas, 0)
+
+	unsubscribe()
+	_, ok := <-deltas
+	require.False(t, ok)
+	unsubscribe()
+
+	deltas, _, err = sq.subscribe()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), decode(<-deltas).Seq)
+	sq.stop()
+	_, ok = <-deltas
+	require.False(t, ok)
+	_, _, err = sq.subscribe()
+	require.Error(t, err)
+}
@@ -0,0 +1,448 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/query"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// triggerRetries is the number of times an async trigger is run before giving up, waiting
+	// twice as long after every failure, starting with triggerBackoff.
+	triggerRetries = 5
+	triggerBackoff = 100 * time.Millisecond
+	// triggerUidsVar is the variable of the trigger queries set to the uids of the nodes touched
+	// by the mutation, like [0x1, 0x2].
+	triggerUidsVar = "$uids"
+)
+
+// Trigger runs an upsert when the committed mutations touch its predicate, or the nodes of its
+// type. The triggers are stored in the root namespace, along with the namespace they belong to.
+type Trigger struct {
+	Name string `json:"name"`
+	// Predicate is the predicate watched by the trigger. Type is the type watched instead, whose
+	// nodes are touched when their type or one of the predicates of the type is mutated.
+	Predicate string `json:"predicate,omitempty"`
+	Type      string `json:"type,omitempty"`
+	// Query is the query of the upsert, which declares the $uids variable set to the touched
+	// nodes, like query q($uids: string) { v as var(func: uid($uids)) }.
+	Query     string `json:"query"`
+	SetNquads string `json:"setNquads,omitempty"`
+	DelNquads string `json:"delNquads,omitempty"`
+	Cond      string `json:"cond,omitempty"`
+	// Async runs the upsert in its own transaction after the mutation is committed, retrying it
+	// on failures. Otherwise, it's run in the transaction of the mutation, and the mutation fails
+	// along with it.
+	Async bool `json:"async,omitempty"`
+}
+
+// parseTrigger checks the trigger, parsing its upsert as in the namespace of the context.
+func parseTrigger(ctx context.Context, t *Trigger) error {
+	if !viewNameRe.MatchString(t.Name) {
+		return errors.Errorf("Invalid trigger name %q, it should only have letters, digits "+
+			"and underscores, and not start with a digit", t.Name)
+	}
+	if (t.Predicate == "") == (t.Type == "") {
+		return errors.New("A trigger should watch either a predicate or a type")
+	}
+	if x.IsReservedPredicate(x.AttrInRootNamespace(t.Predicate)) ||
+		x.IsReservedPredicate(x.AttrInRootNamespace(t.Type)) {
+		return errors.New("A trigger can't watch the reserved predicates or types")
+	}
+	if t.SetNquads == "" && t.DelNquads == "" {
+		return errors.New("A trigger should have set or delete nquads")
+	}
+	qc := &queryContext{req: t.request([]uint64{1}), latency: &query.Latency{}}
+	if err := parseRequest(ctx, qc); err != nil {
+		return errors.Wrapf(err, "while parsing the upsert of the trigger, whose query should "+
+			"declare the %s variable", triggerUidsVar)
+	}
+	return nil
+}
+
+// touchedUids returns the sorted uids of the subjects of the edges touching the trigger. An edge
+// deleting all the predicates of a node touches every trigger.
+func (t *Trigger) touchedUids(ns uint64, edges []*pb.DirectedEdge) []uint64 {
+	var fields map[string]struct{}
+	if t.Type != "" {
+		typ, _ := schema.State().GetType(x.NamespaceAttr(ns, t.Type))
+		fields = make(map[string]struct{}, len(typ.Fields))
+		for _, field := range typ.Fields {
+			fields[x.ParseAttr(field.Predicate)] = struct{}{}
+		}
+	}
+	touches := func(e *pb.DirectedEdge) bool {
+		switch {
+		case e.Attr == x.Star:
+			return true
+		case t.Predicate != "":
+			return e.Attr == t.Predicate
+		case e.Attr == "dgraph.type":
+			return string(e.Value) == t.Type
+		}
+		_, ok := fields[e.Attr]
+		return ok
+	}
+
+	seen := make(map[uint64]struct{})
+	var uids []uint64
+	for _, e := range edges {
+		if _, ok := seen[e.Entity]; ok || !touches(e) {
+			continue
+		}
+		seen[e.Entity] = struct{}{}
+		uids = append(uids, e.Entity)
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	return uids
+}
+
+// request returns the upsert of the trigger for the touched uids.
+func (t *Trigger) request(uids []uint64) *api.Request {
+	return &api.Request{
+		Query: t.Query,
+		Vars:  map[string]string{triggerUidsVar: "[" + strings.Join(hexUids(uids), ", ") + "]"},
+		Mutations: []*api.Mutation{{
+			SetNquads: []byte(t.SetNquads),
+			DelNquads: []byte(t.DelNquads),
+			Cond:      t.Cond,
+		}},
+	}
+}
+
+// triggerCache caches the triggers by namespace, and is reset whenever the triggers change.
+type triggerCache struct {
+	sync.RWMutex
+	triggers map[uint64][]*Trigger
+	// generation is incremented by the resets, so that the triggers loaded meanwhile aren't
+	// cached.
+	generation uint64
+}
+
+var triggers = &triggerCache{triggers: make(map[uint64][]*Trigger)}
+
+var triggerPrefixes = [][]byte{
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.trigger.name")),
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.trigger.namespace")),
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.trigger.spec")),
+}
+
+func (c *triggerCache) reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.triggers = make(map[uint64][]*Trigger)
+	c.generation++
+}
+
+// namespaceTriggers returns the triggers of the namespace.
+func namespaceTriggers(ctx context.Context, ns uint64) ([]*Trigger, error) {
+	triggers.RLock()
+	ts, ok := triggers.triggers[ns]
+	generation := triggers.generation
+	triggers.RUnlock()
+	if ok {
+		return ts, nil
+	}
+
+	ts, err := loadTriggers(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+	triggers.Lock()
+	defer triggers.Unlock()
+	if triggers.generation == generation {
+		triggers.triggers[ns] = ts
+	}
+	return ts, nil
+}
+
+// loadTriggers reads the triggers of the namespace, by name.
+func loadTriggers(ctx context.Context, ns uint64) ([]*Trigger, error) {
+	req := &Request{
+		req: &api.Request{
+			Query: `query triggers($ns: int) {
+		triggers(func: eq(dgraph.trigger.namespace, $ns)) @filter(type(dgraph.trigger)) {
+			uid
+			dgraph.trigger.spec
+		}
+	}`,
+			Vars:     map[string]string{"$ns": strconv.FormatUint(ns, 10)},
+			ReadOnly: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(x.AttachNamespace(ctx, x.RootNamespace), req)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Triggers []struct {
+			Uid  string `json:"uid"`
+			Spec string `json:"dgraph.trigger.spec"`
+		} `json:"triggers"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &res); err != nil {
+		return nil, errors.Wrap(err, "while reading the triggers")
+	}
+	var ts []*Trigger
+	for _, node := range res.Triggers {
+		var t Trigger
+		if err := json.Unmarshal([]byte(node.Spec), &t); err != nil {
+			return nil, errors.Wrapf(err, "while reading the trigger %s", node.Uid)
+		}
+		ts = append(ts, &t)
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Name < ts[j].Name })
+	return ts, nil
+}
+
+// SubscribeForTriggerUpdates resets the cached triggers whenever they change, on any Alpha.
+func SubscribeForTriggerUpdates(closer *z.Closer) {
+	defer func() {
+		glog.Infoln("SubscribeForTriggerUpdates closed")
+		closer.Done()
+	}()
+
+	closer.AddRunning(1)
+	go worker.SubscribeForUpdates(triggerPrefixes, x.IgnoreBytes, func(kvs *bpb.KVList) {
+		if kvs == nil || len(kvs.Kv) == 0 {
+			return
+		}
+		glog.V(3).Infof("Got trigger update via subscription")
+		triggers.reset()
+	}, 1, closer)
+
+	<-closer.HasBeenClosed()
+}
+
+// triggerRun is a run of an async trigger for the uids touched by a transaction.
+type triggerRun struct {
+	ns      uint64
+	trigger *Trigger
+	uids    []uint64
+}
+
+// pendingTriggers holds the async trigger runs of the transactions which aren't committed yet,
+// by their start ts.
+type pendingTriggers struct {
+	sync.Mutex
+	runs  map[uint64][]triggerRun
+	added map[uint64]time.Time
+}
+
+var pending = &pendingTriggers{
+	runs:  make(map[uint64][]triggerRun),
+	added: make(map[uint64]time.Time),
+}
+
+// add queues the runs until the transaction is committed. The runs of the transactions which
+// were neither committed nor aborted through this Alpha are dropped once they're older than the
+// transactions can be.
+func (p *pendingTriggers) add(startTs uint64, runs []triggerRun) {
+	p.Lock()
+	defer p.Unlock()
+	now := time.Now()
+	for ts, added := range p.added {
+		if x.WorkerConfig.AbortOlderThan > 0 && now.Sub(added) > x.WorkerConfig.AbortOlderThan {
+			delete(p.runs, ts)
+			delete(p.added, ts)
+		}
+	}
+	p.runs[startTs] = append(p.runs[startTs], runs...)
+	if _, ok := p.added[startTs]; !ok {
+		p.added[startTs] = now
+	}
+}
+
+func (p *pendingTriggers) take(startTs uint64) []triggerRun {
+	p.Lock()
+	defer p.Unlock()
+	runs := p.runs[startTs]
+	delete(p.runs, startTs)
+	delete(p.added, startTs)
+	return runs
+}
+
+// finishTriggers runs the async triggers of the transaction once it's committed, or drops them
+// if it's aborted.
+func finishTriggers(startTs uint64, committed bool) {
+	runs := pending.take(startTs)
+	if !committed {
+		return
+	}
+	for _, run := range runs {
+		go run.run()
+	}
+}
+
+func (r triggerRun) run() {
+	ctx := context.WithValue(x.AttachNamespace(context.Background(), r.ns), IsTrigger, true)
+	var err error
+	for i := 0; i < triggerRetries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(triggerBackoff << (i - 1)):
+			case <-x.ServerCloser.HasBeenClosed():
+				return
+			}
+		}
+		req := r.trigger.request(r.uids)
+		req.CommitNow = true
+		if _, err = (&Server{}).doQuery(ctx, &Request{req: req, doAuth: NoAuthorize}); err == nil {
+			return
+		}
+	}
+	glog.Errorf("While running the trigger %s of namespace %#x for %d nodes: %v",
+		r.trigger.Name, r.ns, len(r.uids), err)
+}
+
+// runTriggers runs the triggers touched by the edges mutated in the transaction. The sync
+// triggers run in the transaction, whose keys and predicates are updated accordingly, and the
+// async ones wait for it to be committed. The mutations of the triggers don't run triggers.
+func (s *Server) runTriggers(ctx context.Context, edges []*pb.DirectedEdge,
+	txn *api.TxnContext) error {
+
+	if isTrigger, _ := ctx.Value(IsTrigger).(bool); isTrigger || txn == nil {
+		return nil
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	ts, err := namespaceTriggers(ctx, ns)
+	if err != nil || len(ts) == 0 {
+		return errors.Wrapf(err, "while reading the triggers")
+	}
+
+	ctx = context.WithValue(ctx, IsTrigger, true)
+	var async []triggerRun
+	for _, t := range ts {
+		uids := t.touchedUids(ns, edges)
+		switch {
+		case len(uids) == 0:
+			continue
+		case t.Async:
+			async = append(async, triggerRun{ns: ns, trigger: t, uids: uids})
+			continue
+		}
+
+		req := t.request(uids)
+		req.StartTs = txn.StartTs
+		resp, err := s.doQuery(ctx, &Request{req: req, doAuth: NoAuthorize})
+		if err != nil {
+			return errors.Wrapf(err, "while running the trigger %s", t.Name)
+		}
+		txn.Keys = append(txn.Keys, resp.GetTxn().GetKeys()...)
+		txn.Preds = x.Unique(append(txn.Preds, resp.GetTxn().GetPreds()...))
+	}
+	if len(async) > 0 {
+		pending.add(txn.StartTs, async)
+	}
+	return nil
+}
+
+// mutateTriggers runs the upsert of the triggers in the root namespace.
+func mutateTriggers(ctx context.Context, name string, ns uint64, mu *api.Mutation) error {
+	req := &api.Request{
+		Query: `query trigger($name: string, $ns: int) {
+		t as var(func: eq(dgraph.trigger.name, $name)) @filter(eq(dgraph.trigger.namespace, $ns))
+	}`,
+		Vars:      map[string]string{"$name": name, "$ns": strconv.FormatUint(ns, 10)},
+		Mutations: []*api.Mutation{mu},
+		CommitNow: true,
+	}
+	ctx = context.WithValue(context.WithValue(ctx, IsGraphql, true), IsTrigger, true)
+	_, err := (&Server{}).doQuery(x.AttachNamespace(ctx, x.RootNamespace),
+		&Request{req: req, doAuth: NoAuthorize})
+	triggers.reset()
+	return err
+}
+
+// AddTrigger adds the trigger to the namespace of the context, replacing the trigger with the
+// same name.
+func (s *Server) AddTrigger(ctx context.Context, t *Trigger) error {
+	if err := x.HealthCheck(); err != nil {
+		return err
+	}
+	ctx = x.AttachJWTNamespace(ctx)
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	if err := parseTrigger(ctx, t); err != nil {
+		return err
+	}
+	spec, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	str := func(s string) *api.Value { return &api.Value{Val: &api.Value_StrVal{StrVal: s}} }
+	mu := &api.Mutation{Set: []*api.NQuad{
+		{Subject: "uid(t)", Predicate: "dgraph.trigger.name", ObjectValue: str(t.Name)},
+		{Subject: "uid(t)", Predicate: "dgraph.trigger.namespace",
+			ObjectValue: &api.Value{Val: &api.Value_IntVal{IntVal: int64(ns)}}},
+		{Subject: "uid(t)", Predicate: "dgraph.trigger.spec", ObjectValue: str(string(spec))},
+		{Subject: "uid(t)", Predicate: "dgraph.type", ObjectValue: str("dgraph.trigger")},
+	}}
+	if err := mutateTriggers(ctx, t.Name, ns, mu); err != nil {
+		return err
+	}
+	glog.Infof("Added the trigger %s in namespace %#x", t.Name, ns)
+	return nil
+}
+
+// DeleteTrigger deletes the trigger of the namespace of the context. It returns false if there
+// isn't a trigger with the name.
+func (s *Server) DeleteTrigger(ctx context.Context, name string) (bool, error) {
+	if err := x.HealthCheck(); err != nil {
+		return false, err
+	}
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return false, err
+	}
+	ts, err := loadTriggers(ctx, ns)
+	if err != nil {
+		return false, err
+	}
+	exists := false
+	for _, t := range ts {
+		exists = exists || t.Name == name
+	}
+	if !exists {
+		return false, nil
+	}
+	mu := &api.Mutation{DelNquads: []byte(`uid(t) * * .`)}
+	return true, mutateTriggers(ctx, name, ns, mu)
+}
+
+// Triggers returns the triggers of the namespace of the context, by name.
+func (s *Server) Triggers(ctx context.Context) ([]*Trigger, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	ts, err := loadTriggers(ctx, ns)
+	return ts, err
+}
@@ -0,0 +1,103 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func initTriggerSchema(t *testing.T) {
+	ps, err := badger.OpenManaged(badger.DefaultOptions(t.TempDir()))
+	x.Check(err)
+	t.Cleanup(func() { _ = ps.Close() })
+	schema.Init(ps)
+}
+
+func TestParseTrigger(t *testing.T) {
+	initTriggerSchema(t)
+	ctx := x.AttachNamespace(context.Background(), x.RootNamespace)
+	valid := Trigger{
+		Name:      "post_count",
+		Predicate: "posts",
+		Query: `query q($uids: string) {
+			a as var(func: uid($uids)) {
+				n as count(posts)
+			}
+		}`,
+		SetNquads: `uid(a) <postCount> val(n) .`,
+	}
+	require.NoError(t, parseTrigger(ctx, &valid))
+
+	tests := []struct {
+		change func(t *Trigger)
+		err    string
+	}{
+		{func(t *Trigger) { t.Name = "post count" }, "Invalid trigger name"},
+		{func(t *Trigger) { t.Type = "Author" }, "either a predicate or a type"},
+		{func(t *Trigger) { t.Predicate = "" }, "either a predicate or a type"},
+		{func(t *Trigger) { t.Predicate = "dgraph.type" }, "reserved"},
+		{func(t *Trigger) { t.SetNquads = "" }, "set or delete nquads"},
+		{func(t *Trigger) { t.SetNquads = "uid(a) <postCount>" }, "upsert of the trigger"},
+		{func(t *Trigger) { t.Query = `{ a as var(func: has(posts)) }` }, "$uids"},
+	}
+	for _, tc := range tests {
+		trigger := valid
+		tc.change(&trigger)
+		err := parseTrigger(ctx, &trigger)
+		require.Error(t, err, trigger)
+		require.Contains(t, err.Error(), tc.err)
+	}
+}
+
+func TestTriggerTouchedUids(t *testing.T) {
+	initTriggerSchema(t)
+	schema.State().SetType(x.NamespaceAttr(1, "Author"), &pb.TypeUpdate{
+		TypeName: x.NamespaceAttr(1, "Author"),
+		Fields:   []*pb.SchemaUpdate{{Predicate: x.NamespaceAttr(1, "name")}},
+	})
+
+	edges := []*pb.DirectedEdge{
+		{Entity: 5, Attr: "posts", ValueId: 9},
+		{Entity: 3, Attr: "posts", ValueId: 8},
+		{Entity: 5, Attr: "posts", ValueId: 7},
+		{Entity: 4, Attr: "name", Value: []byte("Ann")},
+		{Entity: 6, Attr: "dgraph.type", Value: []byte("Author")},
+		{Entity: 7, Attr: "dgraph.type", Value: []byte("Post")},
+	}
+	byPred := &Trigger{Predicate: "posts"}
+	require.Equal(t, []uint64{3, 5}, byPred.touchedUids(1, edges))
+	byType := &Trigger{Type: "Author"}
+	require.Equal(t, []uint64{4, 6}, byType.touchedUids(1, edges))
+	// The type has no fields in the other namespaces.
+	require.Equal(t, []uint64{6}, byType.touchedUids(2, edges))
+
+	deleteAll := []*pb.DirectedEdge{{Entity: 2, Attr: x.Star, Op: pb.DirectedEdge_DEL}}
+	require.Equal(t, []uint64{2}, byPred.touchedUids(1, deleteAll))
+	require.Equal(t, []uint64{2}, byType.touchedUids(1, deleteAll))
+
+	req := byPred.request([]uint64{3, 5})
+	require.Equal(t, map[string]string{"$uids": "[0x3, 0x5]"}, req.Vars)
+}
+
+func TestPendingTriggers(t *testing.T) {
+	run := triggerRun{ns: 1, trigger: &Trigger{Name: "t"}, uids: []uint64{1}}
+	pending.add(10, []triggerRun{run})
+	pending.add(10, []triggerRun{run})
+	require.Len(t, pending.take(10), 2)
+	require.Empty(t, pending.take(10))
+
+	pending.add(11, []triggerRun{run})
+	finishTriggers(11, false)
+	require.Empty(t, pending.take(11))
+}
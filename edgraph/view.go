@@ -0,0 +1,478 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/query"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// viewNameRe matches the valid names of the materialized views.
+var viewNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ViewRequest registers a DQL query as a materialized view.
+type ViewRequest struct {
+	Name string
+	// Query has a single query block selecting the uid of its root nodes. The result of every
+	// root node is read with the synthetic predicate dgraph.view.<Name> of the node.
+	Query string
+	// RefreshInterval is how often the results are recomputed, on top of the recomputations after
+	// the mutations committed through this Alpha. Zero only recomputes them after the mutations.
+	RefreshInterval time.Duration
+}
+
+// ViewStatus reports the state of a materialized view.
+type ViewStatus struct {
+	Name  string
+	Query string
+	// Predicates are the predicates read by the query, whose mutations recompute the results.
+	// They are empty if the query expands the predicates, in which case any mutation does.
+	Predicates      []string
+	RefreshInterval time.Duration
+	// Nodes is the number of root nodes in the results.
+	Nodes int
+	// Stale is true if a mutation of the predicates was committed after the results were computed.
+	Stale       bool
+	RefreshedAt time.Time
+	// Error is the error of the last recomputation, whose results were kept.
+	Error string
+}
+
+// view is a materialized view maintained by this Alpha. Standing queries are maintained as
+// views too, with their own publish.
+type view struct {
+	ns  uint64
+	req ViewRequest
+	// preds has the namespaced predicates read by the query, nil if it reads any predicate.
+	preds map[string]struct{}
+	done  chan struct{}
+	// publish is called with every new results of the query, holding the lock of the view.
+	publish func(results map[uint64][]byte)
+
+	sync.Mutex
+	status ViewStatus
+	// version is incremented by every mutation of the predicates, the results are stale until
+	// they are computed at the latest version.
+	version    uint64
+	refreshing bool
+}
+
+// viewRegistry holds the materialized views of this Alpha, by namespace and name, and its
+// standing queries by id.
+type viewRegistry struct {
+	sync.Mutex
+	views    map[string]*view
+	standing map[uint64]*standingQuery
+	lastId   uint64
+	// commits counts the transactions which invalidated views.
+	commits uint64
+}
+
+var views = &viewRegistry{
+	views:    make(map[string]*view),
+	standing: make(map[uint64]*standingQuery),
+}
+
+// all returns the views and the views of the standing queries.
+func (r *viewRegistry) all() []*view {
+	all := make([]*view, 0, len(r.views)+len(r.standing))
+	for _, v := range r.views {
+		all = append(all, v)
+	}
+	for _, sq := range r.standing {
+		all = append(all, sq.view)
+	}
+	return all
+}
+
+// viewPredicates returns the predicates read by the query blocks, or nil if the blocks expand
+// the predicates of their nodes.
+func viewPredicates(gqs []*dql.GraphQuery) ([]string, error) {
+	var expands func(gqs []*dql.GraphQuery) bool
+	expands = func(gqs []*dql.GraphQuery) bool {
+		for _, gq := range gqs {
+			if gq.Expand != "" || expands(gq.Children) {
+				return true
+			}
+		}
+		return false
+	}
+	if expands(gqs) {
+		return nil, nil
+	}
+
+	preds := parsePredsFromQuery(gqs).preds
+	for i, pred := range preds {
+		if strings.HasPrefix(pred, query.ViewPredicatePrefix) {
+			return nil, errors.Errorf("The query can't read the view %s", pred)
+		}
+		preds[i] = strings.TrimPrefix(pred, "~")
+	}
+	preds = x.Unique(append(preds, "dgraph.type"))
+	sort.Strings(preds)
+	return preds, nil
+}
+
+// parseView checks the view, and returns the predicates read by its query.
+func parseView(req *ViewRequest) ([]string, error) {
+	if !viewNameRe.MatchString(req.Name) {
+		return nil, errors.Errorf("Invalid view name %q, it should only have letters, digits "+
+			"and underscores, and not start with a digit", req.Name)
+	}
+	if req.RefreshInterval < 0 {
+		return nil, errors.New("The refresh interval of a view can't be negative")
+	}
+	return parseViewQuery(req.Query)
+}
+
+// parseViewQuery checks the query of a view or of a standing query, and returns the predicates
+// it reads.
+func parseViewQuery(q string) ([]string, error) {
+	res, err := dql.Parse(dql.Request{Str: q})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing the query")
+	}
+	var blocks []*dql.GraphQuery
+	for _, gq := range res.Query {
+		if gq.Alias != "var" {
+			blocks = append(blocks, gq)
+		}
+	}
+	if len(blocks) != 1 {
+		return nil, errors.New("The query must have a single query block, besides var blocks")
+	}
+	selectsUid := false
+	for _, child := range blocks[0].Children {
+		selectsUid = selectsUid || (child.Attr == "uid" && child.Alias == "")
+	}
+	if !selectsUid || blocks[0].IsGroupby || blocks[0].Normalize {
+		return nil, errors.New("The query block must select the uid of its root nodes, " +
+			"without @groupby or @normalize")
+	}
+	return viewPredicates(res.Query)
+}
+
+// viewResults returns the JSON object of every root node of the query block in the response.
+func viewResults(resp []byte) (map[uint64][]byte, error) {
+	var blocks map[string][]json.RawMessage
+	if err := json.Unmarshal(resp, &blocks); err != nil {
+		return nil, errors.Wrap(err, "while reading the results of the view")
+	}
+	results := make(map[uint64][]byte)
+	for _, nodes := range blocks {
+		for _, node := range nodes {
+			var n struct {
+				Uid string `json:"uid"`
+			}
+			if err := json.Unmarshal(node, &n); err != nil || n.Uid == "" {
+				continue
+			}
+			uid, err := strconv.ParseUint(n.Uid, 0, 64)
+			if err != nil {
+				continue
+			}
+			results[uid] = node
+		}
+	}
+	return results, nil
+}
+
+// AddView registers the materialized view in the namespace of the context, replacing the view
+// with the same name. Its results are computed before it's registered, and are then recomputed in
+// the background when they're stale.
+func (s *Server) AddView(ctx context.Context, req *ViewRequest) error {
+	if err := x.HealthCheck(); err != nil {
+		return err
+	}
+	preds, err := parseView(req)
+	if err != nil {
+		return err
+	}
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return err
+	}
+
+	v := newView(ns, *req, preds)
+	v.publish = func(results map[uint64][]byte) {
+		query.SetViewResults(ns, req.Name, results)
+	}
+	commits, results, err := v.computeFirst()
+	if err != nil {
+		return err
+	}
+
+	views.Lock()
+	key := x.NamespaceAttr(ns, req.Name)
+	if old, ok := views.views[key]; ok {
+		old.stop()
+	}
+	views.views[key] = v
+	v.register(commits, results)
+	views.Unlock()
+
+	if req.RefreshInterval > 0 {
+		go v.refreshPeriodically()
+	}
+	glog.Infof("Registered the view %s in namespace %#x, reading the predicates %v",
+		req.Name, ns, preds)
+	return nil
+}
+
+// DeleteView deletes the materialized view of the namespace of the context. It returns false if
+// there isn't a view with the name.
+func (s *Server) DeleteView(ctx context.Context, name string) (bool, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return false, err
+	}
+	views.Lock()
+	defer views.Unlock()
+	key := x.NamespaceAttr(ns, name)
+	v, ok := views.views[key]
+	if !ok {
+		return false, nil
+	}
+	v.stop()
+	delete(views.views, key)
+	query.DeleteViewResults(ns, name)
+	return true, nil
+}
+
+// Views reports the materialized views of the namespace of the context, by name.
+func (s *Server) Views(ctx context.Context) ([]ViewStatus, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	views.Lock()
+	defer views.Unlock()
+	var res []ViewStatus
+	for _, v := range views.views {
+		if v.ns == ns {
+			v.Lock()
+			res = append(res, v.status)
+			v.Unlock()
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// committedAttrs returns the namespaced predicates of the predicates of a committed transaction,
+// which are the group id followed by the namespaced predicate, like 1-<namespace>name.
+func committedAttrs(txnPreds []string) []string {
+	attrs := make([]string, 0, len(txnPreds))
+	for _, pred := range txnPreds {
+		if _, attr, ok := strings.Cut(pred, "-"); ok {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// invalidateViews marks the views reading the namespaced predicates as stale, and recomputes
+// their results in the background.
+func invalidateViews(attrs []string) {
+	if len(attrs) == 0 {
+		return
+	}
+	preds := make(map[string]struct{}, len(attrs))
+	for _, attr := range attrs {
+		preds[attr] = struct{}{}
+	}
+
+	views.Lock()
+	defer views.Unlock()
+	views.commits++
+	for _, v := range views.all() {
+		if v.reads(preds) {
+			v.invalidate()
+		}
+	}
+}
+
+// invalidateNamespaceViews marks all the views of the namespace as stale, or the views of all
+// the namespaces with all, after the data is dropped.
+func invalidateNamespaceViews(ns uint64, all bool) {
+	views.Lock()
+	defer views.Unlock()
+	views.commits++
+	for _, v := range views.all() {
+		if all || v.ns == ns {
+			v.invalidate()
+		}
+	}
+}
+
+func (v *view) reads(preds map[string]struct{}) bool {
+	for pred := range preds {
+		if v.preds == nil {
+			if x.ParseNamespace(pred) == v.ns {
+				return true
+			}
+			continue
+		}
+		if _, ok := v.preds[pred]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *view) invalidate() {
+	v.Lock()
+	defer v.Unlock()
+	v.version++
+	v.status.Stale = true
+	if !v.refreshing {
+		v.refreshing = true
+		go v.refreshUntilFresh()
+	}
+}
+
+func newView(ns uint64, req ViewRequest, preds []string) *view {
+	v := &view{ns: ns, req: req, done: make(chan struct{})}
+	v.status = ViewStatus{Name: req.Name, Query: req.Query, Predicates: preds,
+		RefreshInterval: req.RefreshInterval}
+	if preds != nil {
+		v.preds = make(map[string]struct{}, len(preds))
+		for _, pred := range preds {
+			v.preds[x.NamespaceAttr(ns, pred)] = struct{}{}
+		}
+	}
+	return v
+}
+
+// computeFirst computes the results of the view before it's registered. It also returns the
+// number of commits which invalidated views before the computation, for register.
+func (v *view) computeFirst() (uint64, map[uint64][]byte, error) {
+	views.Lock()
+	commits := views.commits
+	views.Unlock()
+	results, err := v.compute()
+	return commits, results, err
+}
+
+// register publishes the first results of the view, once it's in the registry. A mutation
+// committed while the results were computed may not be in them, in which case they're computed
+// again. The registry must be locked.
+func (v *view) register(commits uint64, results map[uint64][]byte) {
+	v.Lock()
+	v.store(results)
+	v.Unlock()
+	if views.commits != commits {
+		v.invalidate()
+	}
+}
+
+// store publishes the results, holding the lock of the view.
+func (v *view) store(results map[uint64][]byte) {
+	v.publish(results)
+	v.status.Nodes = len(results)
+	v.status.RefreshedAt = time.Now()
+	v.status.Error = ""
+}
+
+// stop stops maintaining the view, after it's deleted or replaced.
+func (v *view) stop() {
+	v.Lock()
+	defer v.Unlock()
+	close(v.done)
+}
+
+// compute runs the query of the view, and returns its results.
+func (v *view) compute() (map[uint64][]byte, error) {
+	ctx := x.AttachNamespace(context.Background(), v.ns)
+	resp, err := (&Server{}).QueryNoAuth(ctx, &api.Request{Query: v.req.Query, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	return viewResults(resp.GetJson())
+}
+
+// refresh computes the results of the view once, and stores them unless the view was stopped.
+// The error of the computation is kept in the status, along with the previous results.
+func (v *view) refresh() error {
+	v.Lock()
+	version := v.version
+	v.Unlock()
+
+	results, err := v.compute()
+
+	v.Lock()
+	defer v.Unlock()
+	if err != nil {
+		v.status.Error = err.Error()
+		return err
+	}
+	select {
+	case <-v.done:
+		return nil
+	default:
+	}
+	v.store(results)
+	v.status.Stale = v.version != version
+	return nil
+}
+
+// refreshUntilFresh recomputes the results until no mutation of the predicates of the view was
+// committed while they were computed.
+func (v *view) refreshUntilFresh() {
+	for {
+		select {
+		case <-v.done:
+			return
+		case <-x.ServerCloser.HasBeenClosed():
+			return
+		default:
+		}
+		if err := v.refresh(); err != nil {
+			glog.Errorf("While recomputing the view %s of namespace %#x: %v", v.req.Name, v.ns,
+				err)
+			v.Lock()
+			v.refreshing = false
+			v.Unlock()
+			return
+		}
+		v.Lock()
+		if !v.status.Stale {
+			v.refreshing = false
+			v.Unlock()
+			return
+		}
+		v.Unlock()
+	}
+}
+
+func (v *view) refreshPeriodically() {
+	ticker := time.NewTicker(v.req.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-v.done:
+			return
+		case <-x.ServerCloser.HasBeenClosed():
+			return
+		case <-ticker.C:
+			v.invalidate()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestParseView(t *testing.T) {
+	preds, err := parseView(&ViewRequest{Name: "author_stats", Query: `{
+		var(func: type(Post)) {
+			a as ~posts
+		}
+		authors(func: uid(a)) @filter(has(name)) {
+			uid
+			name
+			posts: count(posts)
+		}
+	}`})
+	require.NoError(t, err)
+	require.Equal(t, []string{"dgraph.type", "name", "posts"}, preds)
+
+	preds, err = parseView(&ViewRequest{Name: "all", Query: `{
+		q(func: type(Post)) {
+			uid
+			expand(_all_)
+		}
+	}`})
+	require.NoError(t, err)
+	require.Nil(t, preds)
+
+	tests := []struct {
+		req ViewRequest
+		err string
+	}{
+		{ViewRequest{Name: "1st", Query: `{ q(func: has(name)) { uid } }`},
+			"Invalid view name"},
+		{ViewRequest{Name: "q", Query: `{ q(func: has(name)) { name } }`},
+			"must select the uid of its root nodes"},
+		{ViewRequest{Name: "q", Query: `{ q(func: has(name)) { uid } r(func: has(age)) { uid } }`},
+			"must have a single query block"},
+		{ViewRequest{Name: "q", Query: `{ q(func: has(name)) { uid dgraph.view.other } }`},
+			"can't read the view dgraph.view.other"},
+		{ViewRequest{Name: "q", Query: `{ q(func: has(name)) { uid } }`, RefreshInterval: -1},
+			"can't be negative"},
+	}
+	for _, tc := range tests {
+		_, err := parseView(&tc.req)
+		require.ErrorContains(t, err, tc.err, tc.req.Query)
+	}
+}
+
+func TestViewResults(t *testing.T) {
+	results, err := viewResults([]byte(`{"q": [
+		{"uid": "0x1", "name": "Alice", "posts": 2},
+		{"name": "no uid"},
+		{"uid": "0x2a", "name": "Bob", "posts": 0}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.JSONEq(t, `{"uid": "0x1", "name": "Alice", "posts": 2}`, string(results[1]))
+	require.JSONEq(t, `{"uid": "0x2a", "name": "Bob", "posts": 0}`, string(results[42]))
+}
+
+func TestViewReads(t *testing.T) {
+	ns := uint64(2)
+	v := &view{ns: ns, preds: map[string]struct{}{x.NamespaceAttr(ns, "name"): {}}}
+	attrs := committedAttrs([]string{"1-" + x.NamespaceAttr(ns, "age")})
+	require.Equal(t, []string{x.NamespaceAttr(ns, "age")}, attrs)
+
+	reads := func(attrs ...string) bool {
+		preds := make(map[string]struct{})
+		for _, attr := range attrs {
+			preds[attr] = struct{}{}
+		}
+		return v.reads(preds)
+	}
+	require.False(t, reads(attrs...))
+	require.True(t, reads(x.NamespaceAttr(ns, "age"), x.NamespaceAttr(ns, "name")))
+	require.False(t, reads(x.NamespaceAttr(x.RootNamespace, "name")))
+
+	// A view expanding the predicates reads all the predicates of its namespace.
+	v.preds = nil
+	require.True(t, reads(x.NamespaceAttr(ns, "age")))
+	require.False(t, reads(x.NamespaceAttr(x.RootNamespace, "age")))
+}
@@ -0,0 +1,276 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/dgraph/v25/query"
+	"github.com/hypermodeinc/dgraph/v25/wasm"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// maxWasmModuleSize is the maximum size of an uploaded wasm module.
+const maxWasmModuleSize = 1 << 20
+
+// WasmModule is a wasm module uploaded to a namespace, whose exported functions are called by
+// the queries as wasm.<module>.<function>, or wasm.<module> if it exports a single function.
+type WasmModule struct {
+	Name    string
+	Exports []string
+}
+
+var wasmPrefixes = [][]byte{
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.wasm.name")),
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.wasm.namespace")),
+	x.PredicatePrefix(x.AttrInRootNamespace("dgraph.wasm.code")),
+}
+
+// wasmReloads serializes the reloads of the wasm modules, so that an older reload doesn't
+// replace the modules loaded by a newer one.
+var wasmReloads sync.Mutex
+
+// compileWasmModule checks the module, which should export at least one function and be
+// instantiated within the limits of the queries.
+func compileWasmModule(name string, code []byte) (*wasm.Module, error) {
+	if !viewNameRe.MatchString(name) {
+		return nil, errors.Errorf("Invalid wasm module name %q, it should only have letters, "+
+			"digits and underscores, and not start with a digit", name)
+	}
+	if len(code) > maxWasmModuleSize {
+		return nil, errors.Errorf("The wasm module %s has %d bytes, above the limit of %d bytes",
+			name, len(code), maxWasmModuleSize)
+	}
+	m, err := wasm.Compile(code)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Exports()) == 0 {
+		return nil, errors.Errorf("The wasm module %s doesn't export any function", name)
+	}
+	in, err := m.Instantiate(wasm.Limits{
+		Timeout:     x.Config.LimitWasmTimeout,
+		MemoryPages: uint32(min(x.Config.LimitWasmMemory/wasm.PageSize, wasm.MaxPages)),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while instantiating the wasm module %s", name)
+	}
+	return m, in.Close()
+}
+
+type wasmNode struct {
+	Name      string `json:"dgraph.wasm.name"`
+	Namespace uint64 `json:"dgraph.wasm.namespace"`
+	Code      string `json:"dgraph.wasm.code"`
+}
+
+// loadWasmNodes reads the wasm modules of all the namespaces.
+func loadWasmNodes(ctx context.Context) ([]wasmNode, error) {
+	req := &Request{
+		req: &api.Request{
+			Query: `{
+		modules(func: type(dgraph.wasm)) {
+			dgraph.wasm.name
+			dgraph.wasm.namespace
+			dgraph.wasm.code
+		}
+	}`,
+			ReadOnly: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(x.AttachNamespace(ctx, x.RootNamespace), req)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Modules []wasmNode `json:"modules"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &res); err != nil {
+		return nil, errors.Wrap(err, "while reading the wasm modules")
+	}
+	return res.Modules, nil
+}
+
+// reloadWasmModules compiles the wasm modules of all the namespaces for the queries. The modules
+// which don't compile anymore are skipped.
+func reloadWasmModules(ctx context.Context) error {
+	wasmReloads.Lock()
+	defer wasmReloads.Unlock()
+	nodes, err := loadWasmNodes(ctx)
+	if err != nil {
+		return err
+	}
+	modules := make(map[uint64]map[string]*wasm.Module)
+	for _, node := range nodes {
+		code, err := base64.StdEncoding.DecodeString(node.Code)
+		if err != nil {
+			glog.Errorf("While decoding the wasm module %s of namespace %#x: %v", node.Name,
+				node.Namespace, err)
+			continue
+		}
+		m, err := compileWasmModule(node.Name, code)
+		if err != nil {
+			glog.Errorf("While compiling the wasm module %s of namespace %#x: %v", node.Name,
+				node.Namespace, err)
+			continue
+		}
+		if modules[node.Namespace] == nil {
+			modules[node.Namespace] = make(map[string]*wasm.Module)
+		}
+		modules[node.Namespace][node.Name] = m
+	}
+	query.SetWasmModules(modules)
+	return nil
+}
+
+// SubscribeForWasmUpdates loads the wasm modules, and reloads them whenever they change, on any
+// Alpha.
+func SubscribeForWasmUpdates(closer *z.Closer) {
+	defer func() {
+		glog.Infoln("SubscribeForWasmUpdates closed")
+		closer.Done()
+	}()
+
+	closer.AddRunning(1)
+	go worker.SubscribeForUpdates(wasmPrefixes, x.IgnoreBytes, func(kvs *bpb.KVList) {
+		if kvs == nil || len(kvs.Kv) == 0 {
+			return
+		}
+		glog.V(3).Infof("Got wasm update via subscription")
+		if err := reloadWasmModules(closer.Ctx()); err != nil {
+			glog.Errorf("While reloading the wasm modules: %v", err)
+		}
+	}, 1, closer)
+
+	for {
+		err := reloadWasmModules(closer.Ctx())
+		if err == nil {
+			break
+		}
+		glog.Warningf("While loading the wasm modules, retrying: %v", err)
+		select {
+		case <-time.After(time.Second):
+		case <-closer.HasBeenClosed():
+			return
+		}
+	}
+	<-closer.HasBeenClosed()
+}
+
+// mutateWasmModules runs the upsert of the wasm modules in the root namespace, and reloads them.
+func mutateWasmModules(ctx context.Context, name string, ns uint64, mu *api.Mutation) error {
+	req := &api.Request{
+		Query: `query wasm($name: string, $ns: int) {
+		w as var(func: eq(dgraph.wasm.name, $name)) @filter(eq(dgraph.wasm.namespace, $ns))
+	}`,
+		Vars:      map[string]string{"$name": name, "$ns": strconv.FormatUint(ns, 10)},
+		Mutations: []*api.Mutation{mu},
+		CommitNow: true,
+	}
+	ctx = context.WithValue(context.WithValue(ctx, IsGraphql, true), IsTrigger, true)
+	if _, err := (&Server{}).doQuery(x.AttachNamespace(ctx, x.RootNamespace),
+		&Request{req: req, doAuth: NoAuthorize}); err != nil {
+		return err
+	}
+	return reloadWasmModules(ctx)
+}
+
+// AddWasmModule compiles and adds the wasm module to the namespace of the context, replacing the
+// module with the same name.
+func (s *Server) AddWasmModule(ctx context.Context, name string, code []byte) error {
+	if err := x.HealthCheck(); err != nil {
+		return err
+	}
+	ctx = x.AttachJWTNamespace(ctx)
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := compileWasmModule(name, code); err != nil {
+		return err
+	}
+	str := func(s string) *api.Value { return &api.Value{Val: &api.Value_StrVal{StrVal: s}} }
+	mu := &api.Mutation{Set: []*api.NQuad{
+		{Subject: "uid(w)", Predicate: "dgraph.wasm.name", ObjectValue: str(name)},
+		{Subject: "uid(w)", Predicate: "dgraph.wasm.namespace",
+			ObjectValue: &api.Value{Val: &api.Value_IntVal{IntVal: int64(ns)}}},
+		{Subject: "uid(w)", Predicate: "dgraph.wasm.code",
+			ObjectValue: str(base64.StdEncoding.EncodeToString(code))},
+		{Subject: "uid(w)", Predicate: "dgraph.type", ObjectValue: str("dgraph.wasm")},
+	}}
+	if err := mutateWasmModules(ctx, name, ns, mu); err != nil {
+		return err
+	}
+	glog.Infof("Added the wasm module %s in namespace %#x", name, ns)
+	return nil
+}
+
+// DeleteWasmModule deletes the wasm module of the namespace of the context. It returns false if
+// there isn't a module with the name.
+func (s *Server) DeleteWasmModule(ctx context.Context, name string) (bool, error) {
+	if err := x.HealthCheck(); err != nil {
+		return false, err
+	}
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return false, err
+	}
+	nodes, err := loadWasmNodes(ctx)
+	if err != nil {
+		return false, err
+	}
+	exists := false
+	for _, node := range nodes {
+		exists = exists || (node.Namespace == ns && node.Name == name)
+	}
+	if !exists {
+		return false, nil
+	}
+	mu := &api.Mutation{DelNquads: []byte(`uid(w) * * .`)}
+	return true, mutateWasmModules(ctx, name, ns, mu)
+}
+
+// WasmModules returns the wasm modules of the namespace of the context, by name.
+func (s *Server) WasmModules(ctx context.Context) ([]*WasmModule, error) {
+	ns, err := x.ExtractNamespace(x.AttachJWTNamespace(ctx))
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := loadWasmNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var modules []*WasmModule
+	for _, node := range nodes {
+		if node.Namespace != ns {
+			continue
+		}
+		module := &WasmModule{Name: node.Name}
+		code, err := base64.StdEncoding.DecodeString(node.Code)
+		if err == nil {
+			if m, err := wasm.Compile(code); err == nil {
+				module.Exports = m.Exports()
+			}
+		}
+		modules = append(modules, module)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	return modules, nil
+}
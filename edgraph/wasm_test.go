@@ -0,0 +1,40 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileWasmModule(t *testing.T) {
+	header := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	// one exports one(): i32, returning 1.
+	one := append(append([]byte{}, header...),
+		0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f,
+		0x03, 0x02, 0x01, 0x00,
+		0x07, 0x07, 0x01, 0x03, 'o', 'n', 'e', 0x00, 0x00,
+		0x0a, 0x06, 0x01, 0x04, 0x00, 0x41, 0x01, 0x0b,
+	)
+	m, err := compileWasmModule("scoring", one)
+	require.NoError(t, err)
+	require.Equal(t, []string{"one"}, m.Exports())
+
+	for _, tc := range []struct {
+		name string
+		code []byte
+		err  string
+	}{
+		{"1scoring", one, "Invalid wasm module name"},
+		{"scoring", make([]byte, maxWasmModuleSize+1), "above the limit"},
+		{"scoring", []byte("wasm"), "invalid wasm module"},
+		{"scoring", header, "doesn't export any function"},
+	} {
+		_, err := compileWasmModule(tc.name, tc.code)
+		require.ErrorContains(t, err, tc.err)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/golang/glog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// AssignXids returns the uids of the nodes with the xids of the request as the values of its
+// predicate, keyed by the xids in the uids of the response. The xids without a node are assigned
+// new nodes with them as the values of the predicate, unless the request is lookup only, so the
+// loaders which share the predicate, and the applications, agree on the uids of the xids. The
+// predicate should be @upsert, so that the concurrent assignments of an xid conflict.
+func (l *Loader) AssignXids(ctx context.Context, req *pb.XidRequest) (*api.Response, error) {
+	ctx, err := loadContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkXidRequest(ctx, ns, req); err != nil {
+		return nil, err
+	}
+
+	xids := dedupXids(req.Xids)
+	xreq := xidRequest(req.Predicate, xids, req.LookupOnly)
+	var resp *api.Response
+	for wait := 10 * time.Millisecond; ; wait = min(2*wait, maxLoadRetryWait) {
+		resp, err = (&Server{}).QueryNoGrpc(ctx, xreq)
+		if !isAborted(err) {
+			break
+		}
+		glog.V(2).Infof("Retrying the aborted assignment of %d xids", len(xids))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		// Running the request trims its query.
+		xreq = xidRequest(req.Predicate, xids, req.LookupOnly)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uids, err := xidUids(xids, resp)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Response{Txn: resp.Txn, Uids: uids}, nil
+}
+
+// checkXidRequest checks that the request has xids, and that its predicate is a string predicate
+// with an index eq can use.
+func checkXidRequest(ctx context.Context, ns uint64, req *pb.XidRequest) error {
+	switch {
+	case req.Predicate == "":
+		return status.Error(codes.InvalidArgument, "The predicate of the xids must be set")
+	case len(req.Xids) == 0:
+		return status.Error(codes.InvalidArgument, "The request must have xids")
+	case len(req.Xids) > x.Config.LimitMutationsNquad:
+		return status.Errorf(codes.InvalidArgument,
+			"The request has %d xids, more than the limit of %d", len(req.Xids),
+			x.Config.LimitMutationsNquad)
+	}
+
+	attr := x.NamespaceAttr(ns, req.Predicate)
+	if typ, err := schema.State().TypeOf(attr); err != nil || typ != types.StringID {
+		return status.Errorf(codes.InvalidArgument,
+			"The predicate %s of the xids must be a string predicate", req.Predicate)
+	}
+	for _, name := range schema.State().TokenizerNames(ctx, attr) {
+		if name == "exact" || name == "hash" {
+			return nil
+		}
+	}
+	return status.Errorf(codes.InvalidArgument,
+		"The predicate %s of the xids must be indexed with exact or hash", req.Predicate)
+}
+
+// dedupXids returns the xids without their duplicates, in their order.
+func dedupXids(xids []string) []string {
+	seen := make(map[string]struct{}, len(xids))
+	out := xids[:0:0]
+	for _, xid := range xids {
+		if _, ok := seen[xid]; !ok {
+			seen[xid] = struct{}{}
+			out = append(out, xid)
+		}
+	}
+	return out
+}
+
+// xidVar returns the variable of the query block of the i-th xid.
+func xidVar(i int) string {
+	return "x_" + strconv.Itoa(i)
+}
+
+// xidRequest returns the upsert block querying the nodes of the xids, and creating the missing
+// ones unless lookupOnly is set.
+func xidRequest(pred string, xids []string, lookupOnly bool) *api.Request {
+	var query strings.Builder
+	query.WriteString("query {\n")
+	req := &api.Request{CommitNow: true}
+	for i, xid := range xids {
+		v := xidVar(i)
+		fmt.Fprintf(&query, "  %s as %s(func: eq(<%s>, %s), first: 1) { uid }\n", v, v, pred,
+			strconv.Quote(xid))
+		if lookupOnly {
+			continue
+		}
+		req.Mutations = append(req.Mutations, &api.Mutation{
+			Cond: fmt.Sprintf("@if(eq(len(%s), 0))", v),
+			Set: []*api.NQuad{{
+				Subject:     "uid(" + v + ")",
+				Predicate:   pred,
+				ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: xid}},
+			}},
+		})
+	}
+	query.WriteString("}")
+	req.Query = query.String()
+	if lookupOnly {
+		req.CommitNow = false
+		req.ReadOnly = true
+	}
+	return req
+}
+
+// xidUids returns the uids of the xids, from the nodes queried by the request of xidRequest and
+// the nodes it created. The xids without a node are left out.
+func xidUids(xids []string, resp *api.Response) (map[string]string, error) {
+	var result map[string][]struct {
+		Uid string `json:"uid"`
+	}
+	if len(resp.GetJson()) > 0 {
+		if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+			return nil, err
+		}
+	}
+
+	uids := make(map[string]string, len(xids))
+	for i, xid := range xids {
+		v := xidVar(i)
+		if nodes := result[v]; len(nodes) > 0 {
+			uids[xid] = nodes[0].Uid
+		} else if uid, ok := resp.GetUids()["uid("+v+")"]; ok {
+			uids[xid] = uid
+		}
+	}
+	return uids, nil
+}
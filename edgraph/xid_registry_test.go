@@ -0,0 +1,38 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXidRequest(t *testing.T) {
+	xids := dedupXids([]string{"a", "b", "a", `c"d`})
+	require.Equal(t, []string{"a", "b", `c"d`}, xids)
+
+	req := xidRequest("xid", xids, false)
+	require.Contains(t, req.Query, `x_2 as x_2(func: eq(<xid>, "c\"d"), first: 1) { uid }`)
+	require.True(t, req.CommitNow)
+	require.Len(t, req.Mutations, 3)
+	require.Equal(t, "@if(eq(len(x_1), 0))", req.Mutations[1].Cond)
+	require.Equal(t, "uid(x_1)", req.Mutations[1].Set[0].Subject)
+	require.Equal(t, "b", req.Mutations[1].Set[0].ObjectValue.GetStrVal())
+
+	lookup := xidRequest("xid", xids, true)
+	require.Empty(t, lookup.Mutations)
+	require.True(t, lookup.ReadOnly)
+
+	resp := &api.Response{
+		Json: []byte(`{"x_0": [{"uid": "0x5"}], "x_1": [], "x_2": []}`),
+		Uids: map[string]string{"uid(x_1)": "0x9"},
+	}
+	uids, err := xidUids(xids, resp)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a": "0x5", "b": "0x9"}, uids)
+}
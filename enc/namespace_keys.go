@@ -0,0 +1,299 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package enc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// sealedMagic is the first byte of a value sealed with a namespace key. A marshalled
+	// protobuf message can never start with it because its wire type (6) is invalid, so
+	// sealed and plain values can be told apart without any other metadata.
+	sealedMagic = byte(0xD6)
+	// sealedHeaderLen is the length of the magic byte followed by the key version.
+	sealedHeaderLen = 1 + 4
+
+	wrapAAD = "dgraph-namespace-key"
+)
+
+var (
+	// ErrNamespaceKeyNotFound is returned when a value was sealed with a namespace key that is
+	// no longer available, e.g. because it was destroyed while offboarding the namespace.
+	ErrNamespaceKeyNotFound = errors.New("namespace encryption key not found")
+)
+
+// NamespaceKeys holds the data encryption keys of the namespaces. The keys are read from a
+// directory with one file per key version, named <namespace>.<version>.key. If a master key
+// is given, the files hold the data keys wrapped with it by WrapNamespaceKey. Otherwise,
+// they hold the raw keys of length 16, 24 or 32 bytes. If a KMS is given, the files hold the
+// data keys encrypted by the KMS instead, and the master key isn't used.
+//
+// New values of a namespace are sealed with its highest key version. Older versions are
+// kept to open the values sealed before a rotation, until the data has been rewritten.
+type NamespaceKeys struct {
+	dir    string
+	master x.Sensitive
+	kms    x.KMS
+
+	sync.RWMutex
+	keys    map[uint64]map[uint32]cipher.AEAD
+	current map[uint64]uint32
+	// files caches the keys by the hash of their file, so that reloading the directory only
+	// asks the KMS to decrypt the files that changed.
+	files      map[[sha256.Size]byte]cipher.AEAD
+	lastReload time.Time
+	lastErr    error
+}
+
+// KeyStatus describes the state of the namespace keys.
+type KeyStatus struct {
+	// Versions is the current key version of each namespace that has a key.
+	Versions map[uint64]uint32
+	// LastReload is the time of the last successful reload of the key directory.
+	LastReload time.Time
+	// LastError is the error of the last reload, if it failed.
+	LastError error
+}
+
+// LoadNamespaceKeys reads the namespace keys from the given directory. The kms can be nil.
+func LoadNamespaceKeys(dir string, master x.Sensitive, kms x.KMS) (*NamespaceKeys, error) {
+	k := &NamespaceKeys{dir: dir, master: master, kms: kms}
+	if _, err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload reads the key directory again, picking up new key versions and dropping the keys
+// whose files were removed. It returns the namespaces which no longer have any key. If the
+// reload fails, the keys loaded before are kept.
+func (k *NamespaceKeys) Reload() ([]uint64, error) {
+	removed, err := k.reload()
+	k.Lock()
+	k.lastErr = err
+	if err == nil {
+		k.lastReload = time.Now()
+	}
+	k.Unlock()
+	return removed, err
+}
+
+func (k *NamespaceKeys) reload() ([]uint64, error) {
+	entries, err := os.ReadDir(k.dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading namespace key directory %s", k.dir)
+	}
+
+	k.RLock()
+	cached := k.files
+	k.RUnlock()
+	keys := make(map[uint64]map[uint32]cipher.AEAD)
+	current := make(map[uint64]uint32)
+	files := make(map[[sha256.Size]byte]cipher.AEAD)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".key") {
+			continue
+		}
+		ns, version, err := parseKeyFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filepath.Join(k.dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading namespace key file %s", entry.Name())
+		}
+		hash := sha256.Sum256(data)
+		aead, ok := cached[hash]
+		if !ok {
+			if aead, err = k.loadKey(data); err != nil {
+				return nil, errors.Wrapf(err, "invalid namespace key file %s", entry.Name())
+			}
+		}
+		files[hash] = aead
+		if keys[ns] == nil {
+			keys[ns] = make(map[uint32]cipher.AEAD)
+		}
+		keys[ns][version] = aead
+		if version >= current[ns] {
+			current[ns] = version
+		}
+	}
+
+	k.Lock()
+	defer k.Unlock()
+	var removed []uint64
+	for ns := range k.keys {
+		if _, ok := keys[ns]; !ok {
+			removed = append(removed, ns)
+		}
+	}
+	for ns, version := range current {
+		if k.current[ns] != version {
+			glog.Infof("Using encryption key version %d for namespace %#x", version, ns)
+		}
+	}
+	k.keys, k.current, k.files = keys, current, files
+	return removed, nil
+}
+
+// loadKey returns the cipher for the contents of a key file.
+func (k *NamespaceKeys) loadKey(data []byte) (cipher.AEAD, error) {
+	var err error
+	switch {
+	case k.kms != nil:
+		data, err = x.KmsDecrypt(k.kms, data)
+	case k.master != nil:
+		data, err = unwrapNamespaceKey(k.master, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(data)
+}
+
+// Status returns the current key versions and the result of the last reload.
+func (k *NamespaceKeys) Status() KeyStatus {
+	versions := k.Namespaces()
+	k.RLock()
+	defer k.RUnlock()
+	return KeyStatus{Versions: versions, LastReload: k.lastReload, LastError: k.lastErr}
+}
+
+// Namespaces returns the current key version of each namespace that has a key.
+func (k *NamespaceKeys) Namespaces() map[uint64]uint32 {
+	k.RLock()
+	defer k.RUnlock()
+	res := make(map[uint64]uint32, len(k.current))
+	for ns, version := range k.current {
+		res[ns] = version
+	}
+	return res
+}
+
+// Seal encrypts the value with the current key of the namespace. The key of the entry is used
+// as additional data, so that sealed values can't be moved to other keys. If the namespace
+// doesn't have a key, the value is returned as is.
+func (k *NamespaceKeys) Seal(ns uint64, key, val []byte) ([]byte, error) {
+	k.RLock()
+	version, ok := k.current[ns]
+	var aead cipher.AEAD
+	if ok {
+		aead = k.keys[ns][version]
+	}
+	k.RUnlock()
+	if !ok || len(val) == 0 {
+		return val, nil
+	}
+
+	nonceSize := aead.NonceSize()
+	out := make([]byte, sealedHeaderLen+nonceSize, sealedHeaderLen+nonceSize+len(val)+aead.Overhead())
+	out[0] = sealedMagic
+	binary.BigEndian.PutUint32(out[1:sealedHeaderLen], version)
+	nonce := out[sealedHeaderLen:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, val, key), nil
+}
+
+// Open decrypts a value sealed by Seal. Values which aren't sealed are returned as is.
+func (k *NamespaceKeys) Open(ns uint64, key, val []byte) ([]byte, error) {
+	if !IsSealed(val) {
+		return val, nil
+	}
+	version := binary.BigEndian.Uint32(val[1:sealedHeaderLen])
+	k.RLock()
+	aead := k.keys[ns][version]
+	k.RUnlock()
+	if aead == nil {
+		return nil, errors.Wrapf(ErrNamespaceKeyNotFound, "namespace %#x, version %d", ns, version)
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(val) < sealedHeaderLen+nonceSize {
+		return nil, errors.Errorf("sealed value of length %d is too short", len(val))
+	}
+	nonce := val[sealedHeaderLen : sealedHeaderLen+nonceSize]
+	out, err := aead.Open(nil, nonce, val[sealedHeaderLen+nonceSize:], key)
+	return out, errors.Wrapf(err, "while opening value sealed for namespace %#x", ns)
+}
+
+// IsSealed returns true if the value was sealed with a namespace key.
+func IsSealed(val []byte) bool {
+	return len(val) > sealedHeaderLen && val[0] == sealedMagic
+}
+
+// WrapNamespaceKey encrypts the data key with the master key, in the format expected in the
+// namespace key files.
+func WrapNamespaceKey(master x.Sensitive, key []byte) ([]byte, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(key)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, key, []byte(wrapAAD)), nil
+}
+
+func unwrapNamespaceKey(master x.Sensitive, data []byte) ([]byte, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	nonce, ct := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ct, []byte(wrapAAD))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if l := len(key); l != 16 && l != 24 && l != 32 {
+		return nil, errors.Errorf("key must have length of 16, 24 or 32 bytes, got %d bytes", l)
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
+
+// parseKeyFileName parses a key file name of the form <namespace>.<version>.key.
+func parseKeyFileName(name string) (uint64, uint32, error) {
+	parts := strings.Split(strings.TrimSuffix(name, ".key"), ".")
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("invalid namespace key file name %q, "+
+			"expected <namespace>.<version>.key", name)
+	}
+	ns, err := strconv.ParseUint(parts[0], 0, 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid namespace in key file name %q", name)
+	}
+	version, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid version in key file name %q", name)
+	}
+	return ns, uint32(version), nil
+}
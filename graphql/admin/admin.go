@@ -128,6 +128,43 @@ const (
 		List of Features that are enabled.
 		"""
 		ee_features: [String]
+
+		"""
+		Status of the encryption keys. Only set for the alpha serving the request.
+		"""
+		encryption: EncryptionStatus
+	}
+
+	type EncryptionStatus {
+		"""
+		Whether encryption at rest is enabled, for the whole store or for some namespaces.
+		"""
+		enabled: Boolean
+
+		"""
+		The KMS used to decrypt the encryption keys, if any.
+		"""
+		kms: String
+
+		"""
+		The current key version of the namespaces that have their own key.
+		"""
+		namespaceKeys: [NamespaceKeyStatus]
+
+		"""
+		Time in Unix epoch time that the namespace keys were last reloaded.
+		"""
+		lastReload: Int64
+
+		"""
+		The error of the last reload of the namespace keys, if it failed.
+		"""
+		error: String
+	}
+
+	type NamespaceKeyStatus {
+		namespace: UInt64
+		version: Int
 	}
 
 	type MembershipState {
@@ -164,6 +201,7 @@ const (
 		lastUpdate: UInt64
 		clusterInfoOnly: Boolean
 		forceGroupId: Boolean
+		witness: Boolean
 	}
 
 	type Tablet {
@@ -187,6 +225,13 @@ const (
 
 	input UpdateGQLSchemaInput {
 		set: GQLSchemaPatch!
+
+		"""
+		Name of the GraphQL schema to update. A namespace can have named schemas besides its
+		default schema, all served over the same data, each one at /graphql/<name> or at /graphql
+		with the header X-Dgraph-Schema: <name>. (default: the default schema)
+		"""
+		name: String
 	}
 
 	input GQLSchemaPatch {
@@ -375,7 +420,7 @@ const (
 	` + adminTypes + `
 
 	type Query {
-		getGQLSchema: GQLSchema
+		getGQLSchema(name: String): GQLSchema
 		health: [NodeState]
 		state: MembershipState
 		config: Config
@@ -483,11 +528,20 @@ var (
 		resolve.LoggingMWMutation,
 	}
 	adminQueryMWConfig = map[string]resolve.QueryMiddlewares{
-		"health":       minimalAdminQryMWs, // dgraph checks Guardian auth for health
-		"state":        minimalAdminQryMWs, // dgraph checks Guardian auth for state
-		"config":       gogQryMWs,
-		"listBackups":  gogQryMWs,
-		"getGQLSchema": stdAdminQryMWs,
+		"health":          minimalAdminQryMWs, // dgraph checks Guardian auth for health
+		"state":           minimalAdminQryMWs, // dgraph checks Guardian auth for state
+		"config":          gogQryMWs,
+		"listBackups":     gogQryMWs,
+		"namespaceUsage":  gogQryMWs,
+		"bulkDeletes":     stdAdminQryMWs,
+		"renames":         stdAdminQryMWs,
+		"views":           stdAdminQryMWs,
+		"standingQueries": stdAdminQryMWs,
+		"triggers":        stdAdminQryMWs,
+		"wasmModules":     stdAdminQryMWs,
+		"storedFunctions": stdAdminQryMWs,
+		"listApiKeys":     gogQryMWs,
+		"getGQLSchema":    stdAdminQryMWs,
 		// for queries and mutations related to User/Group, dgraph handles Guardian auth,
 		// so no need to apply GuardianAuth Middleware
 		"queryUser":      minimalAdminQryMWs,
@@ -497,20 +551,40 @@ var (
 		"getGroup":       minimalAdminQryMWs,
 	}
 	adminMutationMWConfig = map[string]resolve.MutationMiddlewares{
-		"backup":          gogMutMWs,
-		"config":          gogMutMWs,
-		"draining":        gogMutMWs,
-		"export":          stdAdminMutMWs, // dgraph handles the export for other namespaces by superadmin
-		"login":           minimalAdminMutMWs,
-		"restore":         gogMutMWs,
-		"shutdown":        gogMutMWs,
-		"removeNode":      gogMutMWs,
-		"moveTablet":      gogMutMWs,
-		"assign":          gogMutMWs,
-		"updateGQLSchema": stdAdminMutMWs,
-		"addNamespace":    gogAclMutMWs,
-		"deleteNamespace": gogAclMutMWs,
-		"resetPassword":   gogAclMutMWs,
+		"backup":                   gogMutMWs,
+		"config":                   gogMutMWs,
+		"draining":                 gogMutMWs,
+		"export":                   stdAdminMutMWs, // dgraph handles the export for other namespaces by superadmin
+		"login":                    minimalAdminMutMWs,
+		"restore":                  gogMutMWs,
+		"shutdown":                 gogMutMWs,
+		"removeNode":               gogMutMWs,
+		"moveTablet":               gogMutMWs,
+		"assign":                   gogMutMWs,
+		"updateGQLSchema":          stdAdminMutMWs,
+		"bulkDelete":               stdAdminMutMWs,
+		"dropTypeData":             stdAdminMutMWs,
+		"registerPersistedQueries": stdAdminMutMWs,
+		"deletePersistedQueries":   stdAdminMutMWs,
+		"addView":                  stdAdminMutMWs,
+		"deleteView":               stdAdminMutMWs,
+		"addStandingQuery":         stdAdminMutMWs,
+		"deleteStandingQuery":      stdAdminMutMWs,
+		"addTrigger":               stdAdminMutMWs,
+		"deleteTrigger":            stdAdminMutMWs,
+		"uploadWasmModule":         stdAdminMutMWs,
+		"deleteWasmModule":         stdAdminMutMWs,
+		"addStoredFunction":        stdAdminMutMWs,
+		"deleteStoredFunction":     stdAdminMutMWs,
+		"addNamespace":             gogAclMutMWs,
+		"deleteNamespace":          gogAclMutMWs,
+		"renameNamespace":          gogAclMutMWs,
+		"sandboxNamespace":         gogAclMutMWs,
+		"cloneNamespace":           gogAclMutMWs,
+		"resetPassword":            gogAclMutMWs,
+		"addApiKey":                gogAclMutMWs,
+		"rotateApiKey":             gogAclMutMWs,
+		"revokeApiKey":             gogAclMutMWs,
 		// for queries and mutations related to User/Group, dgraph handles Guardian auth,
 		// so no need to apply GuardianAuth Middleware
 		"addUser":     minimalAdminMutMWs,
@@ -524,6 +598,9 @@ var (
 	mainHealthStore = &GraphQLHealthStore{}
 	// adminServerVar stores a pointer to the adminServer. It is used for lazy loading schema.
 	adminServerVar *adminServer
+	// errNoNamedGQLSchema is returned when lazy loading a named GraphQL schema which hasn't been
+	// set.
+	errNoNamedGQLSchema = errors.New("GraphQL schema doesn't exist")
 )
 
 func SchemaValidate(sch string) error {
@@ -673,9 +750,18 @@ func newAdminResolver(
 			Version: kv.GetVersion(),
 			Schema:  string(pl.Postings[0].Value),
 		}
+		// the named schemas of the namespace are stored in nodes of their own, so unless this is
+		// the node of the default schema, look for the name of the schema
+		if cs, ok := server.gqlSchemas.GetCurrent(ns); !ok || cs.ID != newSchema.ID {
+			if newSchema.Name, err = edgraph.GetGQLSchemaName(ns, newSchema.ID); err != nil {
+				glog.Errorf("namespace: %d. Unable to find the name of updated schema %s", ns, err)
+				return
+			}
+		}
+		name := newSchema.Name
 
 		server.mux.RLock()
-		currentSchema, ok := server.gqlSchemas.GetCurrent(ns)
+		currentSchema, ok := server.gqlSchemas.GetNamed(ns, name)
 		if ok {
 			schemaChanged := newSchema.Schema == currentSchema.Schema
 			if newSchema.Version <= currentSchema.Version || schemaChanged {
@@ -702,11 +788,17 @@ func newAdminResolver(
 		defer server.mux.Unlock()
 
 		server.incrementSchemaUpdateCounter(ns)
+		if name == "" && newSchema.Schema == "" {
+			// the named schemas are gone as well on drop_all
+			for _, n := range server.gqlSchemas.DeleteNamed(ns) {
+				server.gqlServer.DeleteNamed(ns, n)
+			}
+		}
 		// if the schema hasn't been loaded yet, then we don't need to load it here
-		currentSchema, ok = server.gqlSchemas.GetCurrent(ns)
+		currentSchema, ok = server.gqlSchemas.GetNamed(ns, name)
 		if !(ok && currentSchema.Loaded) {
 			// this just set schema in admin server, so that next invalid badger subscription update gets rejected upfront
-			server.gqlSchemas.Set(ns, newSchema)
+			server.gqlSchemas.SetNamed(ns, name, newSchema)
 			glog.Infof("namespace: %d. Skipping in-memory GraphQL schema update, "+
 				"it will be lazy-loaded later.", ns)
 			return
@@ -714,8 +806,8 @@ func newAdminResolver(
 
 		// update this schema in both admin and graphql server
 		newSchema.Loaded = true
-		server.gqlSchemas.Set(ns, newSchema)
-		server.resetSchema(ns, gqlSchema)
+		server.gqlSchemas.SetNamed(ns, name, newSchema)
+		server.resetNamedSchema(ns, name, gqlSchema)
 
 		glog.Infof("namespace: %d. Successfully updated GraphQL schema. "+
 			"Serving New GraphQL API.", ns)
@@ -728,20 +820,40 @@ func newAdminResolver(
 
 func newAdminResolverFactory() resolve.ResolverFactory {
 	adminMutationResolvers := map[string]resolve.MutationResolverFunc{
-		"addNamespace":    resolveAddNamespace,
-		"backup":          resolveBackup,
-		"config":          resolveUpdateConfig,
-		"deleteNamespace": resolveDeleteNamespace,
-		"draining":        resolveDraining,
-		"export":          resolveExport,
-		"login":           resolveLogin,
-		"resetPassword":   resolveResetPassword,
-		"restore":         resolveRestore,
-		"shutdown":        resolveShutdown,
-		"removeNode":      resolveRemoveNode,
-		"moveTablet":      resolveMoveTablet,
-		"assign":          resolveAssign,
-		"restoreTenant":   resolveTenantRestore,
+		"addApiKey":                resolveAddApiKey,
+		"addNamespace":             resolveAddNamespace,
+		"backup":                   resolveBackup,
+		"bulkDelete":               resolveBulkDelete,
+		"dropTypeData":             resolveDropTypeData,
+		"registerPersistedQueries": resolveRegisterPersistedQueries,
+		"deletePersistedQueries":   resolveDeletePersistedQueries,
+		"addView":                  resolveAddView,
+		"deleteView":               resolveDeleteView,
+		"addStandingQuery":         resolveAddStandingQuery,
+		"deleteStandingQuery":      resolveDeleteStandingQuery,
+		"addTrigger":               resolveAddTrigger,
+		"deleteTrigger":            resolveDeleteTrigger,
+		"uploadWasmModule":         resolveUploadWasmModule,
+		"deleteWasmModule":         resolveDeleteWasmModule,
+		"addStoredFunction":        resolveAddStoredFunction,
+		"deleteStoredFunction":     resolveDeleteStoredFunction,
+		"cloneNamespace":           resolveCloneNamespace,
+		"config":                   resolveUpdateConfig,
+		"deleteNamespace":          resolveDeleteNamespace,
+		"draining":                 resolveDraining,
+		"export":                   resolveExport,
+		"login":                    resolveLogin,
+		"resetPassword":            resolveResetPassword,
+		"restore":                  resolveRestore,
+		"shutdown":                 resolveShutdown,
+		"removeNode":               resolveRemoveNode,
+		"renameNamespace":          resolveRenameNamespace,
+		"sandboxNamespace":         resolveSandboxNamespace,
+		"moveTablet":               resolveMoveTablet,
+		"assign":                   resolveAssign,
+		"restoreTenant":            resolveTenantRestore,
+		"revokeApiKey":             resolveRevokeApiKey,
+		"rotateApiKey":             resolveRotateApiKey,
 	}
 
 	rf := resolverFactoryWithErrorMsg(errResolverNotFound).
@@ -759,6 +871,33 @@ func newAdminResolverFactory() resolve.ResolverFactory {
 		WithQueryResolver("listBackups", func(q schema.Query) resolve.QueryResolver {
 			return resolve.QueryResolverFunc(resolveListBackups)
 		}).
+		WithQueryResolver("namespaceUsage", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveNamespaceUsage)
+		}).
+		WithQueryResolver("bulkDeletes", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveBulkDeletes)
+		}).
+		WithQueryResolver("renames", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveRenames)
+		}).
+		WithQueryResolver("views", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveViews)
+		}).
+		WithQueryResolver("standingQueries", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveStandingQueries)
+		}).
+		WithQueryResolver("triggers", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveTriggers)
+		}).
+		WithQueryResolver("wasmModules", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveWasmModules)
+		}).
+		WithQueryResolver("storedFunctions", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveStoredFunctions)
+		}).
+		WithQueryResolver("listApiKeys", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveListApiKeys)
+		}).
 		WithQueryResolver("task", func(q schema.Query) resolve.QueryResolver {
 			return resolve.QueryResolverFunc(resolveTask)
 		}).
@@ -788,13 +927,13 @@ func newAdminResolverFactory() resolve.ResolverFactory {
 	return rf.WithSchemaIntrospection()
 }
 
-func getCurrentGraphQLSchema(namespace uint64) (*worker.GqlSchema, error) {
-	uid, graphQLSchema, err := edgraph.GetGQLSchema(namespace)
+func getCurrentGraphQLSchema(namespace uint64, name string) (*worker.GqlSchema, error) {
+	uid, graphQLSchema, err := edgraph.GetNamedGQLSchema(namespace, name)
 	if err != nil {
 		return nil, err
 	}
 
-	return &worker.GqlSchema{ID: uid, Schema: graphQLSchema}, nil
+	return &worker.GqlSchema{ID: uid, Name: name, Schema: graphQLSchema}, nil
 }
 
 func generateGQLSchema(sch *worker.GqlSchema, ns uint64) (schema.Schema, error) {
@@ -830,7 +969,7 @@ func (as *adminServer) initServer() {
 	for {
 		<-time.After(waitFor)
 
-		sch, err := getCurrentGraphQLSchema(x.RootNamespace)
+		sch, err := getCurrentGraphQLSchema(x.RootNamespace, "")
 		if err != nil {
 			glog.Errorf("namespace: %d. Error reading GraphQL schema: %s.", x.RootNamespace, err)
 			continue
@@ -955,6 +1094,11 @@ func (as *adminServer) incrementSchemaUpdateCounter(ns uint64) {
 }
 
 func (as *adminServer) resetSchema(ns uint64, gqlSchema schema.Schema) {
+	as.resetNamedSchema(ns, "", gqlSchema)
+}
+
+// resetNamedSchema is like resetSchema, for the GraphQL schema with the given name.
+func (as *adminServer) resetNamedSchema(ns uint64, name string, gqlSchema schema.Schema) {
 	// set status as updating schema
 	mainHealthStore.updatingSchema()
 
@@ -976,7 +1120,7 @@ func (as *adminServer) resetSchema(ns uint64, gqlSchema schema.Schema) {
 				return resolve.QueryResolverFunc(func(ctx context.Context, query schema.Query) *resolve.Resolved {
 					as.mux.RLock()
 					defer as.mux.RUnlock()
-					sch, ok := as.gqlSchemas.GetCurrent(ns)
+					sch, ok := as.gqlSchemas.GetNamed(ns, name)
 					if !ok {
 						return resolve.EmptyResult(query,
 							fmt.Errorf("error while getting the schema for ns %d", ns))
@@ -999,27 +1143,35 @@ func (as *adminServer) resetSchema(ns uint64, gqlSchema schema.Schema) {
 	}
 
 	resolvers := resolve.New(gqlSchema, resolverFactory)
-	as.gqlServer.Set(ns, as.getGlobalEpoch(ns), resolvers)
+	as.gqlServer.SetNamed(ns, name, as.getGlobalEpoch(ns), resolvers)
 
 	// reset status to up, as now we are serving the new schema
 	mainHealthStore.up()
 }
 
-func (as *adminServer) lazyLoadSchema(namespace uint64) error {
+func (as *adminServer) lazyLoadSchema(namespace uint64, name string) error {
+	if name != "" {
+		if err := worker.ValidateGQLSchemaName(name); err != nil {
+			return err
+		}
+	}
 	// if the schema is already in memory, no need to fetch it from disk
-	if currentSchema, ok := as.gqlSchemas.GetCurrent(namespace); ok && currentSchema.Loaded {
+	if currentSchema, ok := as.gqlSchemas.GetNamed(namespace, name); ok && currentSchema.Loaded {
 		return nil
 	}
 
 	// otherwise, fetch the schema from disk
-	sch, err := getCurrentGraphQLSchema(namespace)
+	sch, err := getCurrentGraphQLSchema(namespace, name)
 	if err != nil {
 		glog.Errorf("namespace: %d. Error reading GraphQL schema: %s.", namespace, err)
 		return errors.Wrap(err, "failed to lazy-load GraphQL schema")
 	}
 
 	var generatedSchema schema.Schema
-	if sch.Schema == "" {
+	if sch.Schema == "" && name != "" {
+		// unlike the default schema, a named schema is only served once it has been set
+		return errors.Wrapf(errNoNamedGQLSchema, "namespace: %d. name: %s", namespace, name)
+	} else if sch.Schema == "" {
 		// if there was no schema stored in Dgraph, we still need to attach resolvers to the main
 		// graphql server which should just return errors for any incoming request.
 		// generatedSchema will be nil in this case
@@ -1036,15 +1188,21 @@ func (as *adminServer) lazyLoadSchema(namespace uint64) error {
 	as.mux.Lock()
 	defer as.mux.Unlock()
 	sch.Loaded = true
-	as.gqlSchemas.Set(namespace, sch)
-	as.resetSchema(namespace, generatedSchema)
+	as.gqlSchemas.SetNamed(namespace, name, sch)
+	as.resetNamedSchema(namespace, name, generatedSchema)
 
 	glog.Infof("namespace: %d. Successfully lazy-loaded GraphQL schema.", namespace)
 	return nil
 }
 
 func LazyLoadSchema(namespace uint64) error {
-	return adminServerVar.lazyLoadSchema(namespace)
+	return adminServerVar.lazyLoadSchema(namespace, "")
+}
+
+// LazyLoadNamedSchema is like LazyLoadSchema, for the GraphQL schema with the given name. It
+// returns an error if there is no such schema.
+func LazyLoadNamedSchema(namespace uint64, name string) error {
+	return adminServerVar.lazyLoadSchema(namespace, name)
 }
 
 func inputArgError(err error) error {
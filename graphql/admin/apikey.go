@@ -0,0 +1,149 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+type addApiKeyInput struct {
+	Name       string
+	Namespaces []uint64
+	Permission int32
+	Predicates []string
+	ExpiresAt  int64
+}
+
+type rotateApiKeyInput struct {
+	Id          string
+	GracePeriod int
+}
+
+type revokeApiKeyInput struct {
+	Id string
+}
+
+func resolveAddApiKey(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	input := &addApiKeyInput{}
+	if err := getApiKeyInput(m, input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	key := &edgraph.ApiKey{
+		Name:       input.Name,
+		Namespaces: input.Namespaces,
+		Permission: input.Permission,
+		Predicates: input.Predicates,
+	}
+	if input.ExpiresAt > 0 {
+		key.ExpiresAt = time.Unix(input.ExpiresAt, 0)
+	}
+	apiKey, err := (&edgraph.Server{}).CreateApiKey(ctx, key)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"key":     apiKey,
+			"apiKey":  apiKeyResult(key),
+			"message": "Added API key successfully",
+		}},
+		nil,
+	), true
+}
+
+func resolveRotateApiKey(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	input := &rotateApiKeyInput{}
+	if err := getApiKeyInput(m, input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	grace := time.Duration(input.GracePeriod) * time.Second
+	apiKey, err := (&edgraph.Server{}).RotateApiKey(ctx, input.Id, grace)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"key":     apiKey,
+			"apiKey":  map[string]interface{}{"id": input.Id},
+			"message": "Rotated API key successfully",
+		}},
+		nil,
+	), true
+}
+
+func resolveRevokeApiKey(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	input := &revokeApiKeyInput{}
+	if err := getApiKeyInput(m, input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	if err := (&edgraph.Server{}).RevokeApiKey(ctx, input.Id); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"apiKey":  map[string]interface{}{"id": input.Id},
+			"message": "Revoked API key successfully",
+		}},
+		nil,
+	), true
+}
+
+func resolveListApiKeys(ctx context.Context, q schema.Query) *resolve.Resolved {
+	keys, err := (&edgraph.Server{}).ListApiKeys(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+	results := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, apiKeyResult(key))
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
+
+func apiKeyResult(key *edgraph.ApiKey) map[string]interface{} {
+	namespaces := make([]interface{}, 0, len(key.Namespaces))
+	for _, ns := range key.Namespaces {
+		namespaces = append(namespaces, json.Number(strconv.FormatUint(ns, 10)))
+	}
+	predicates := make([]interface{}, 0, len(key.Predicates))
+	for _, pred := range key.Predicates {
+		predicates = append(predicates, pred)
+	}
+	result := map[string]interface{}{
+		"id":         key.Id,
+		"name":       key.Name,
+		"namespaces": namespaces,
+		"permission": json.Number(strconv.Itoa(int(key.Permission))),
+		"predicates": predicates,
+	}
+	if !key.ExpiresAt.IsZero() {
+		result["expiresAt"] = json.Number(strconv.FormatInt(key.ExpiresAt.Unix(), 10))
+	}
+	return result
+}
+
+func getApiKeyInput(m schema.Mutation, input interface{}) error {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return schema.GQLWrapf(err, "couldn't get input argument")
+	}
+	return schema.GQLWrapf(json.Unmarshal(inputByts, input), "couldn't get input argument")
+}
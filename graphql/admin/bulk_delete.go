@@ -0,0 +1,112 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+type bulkDeleteInput struct {
+	Query     string
+	BatchSize int
+	MaxRate   int
+}
+
+func resolveBulkDelete(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	input, err := getBulkDeleteInput(m)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	id, err := (&edgraph.Server{}).BulkDelete(ctx, &edgraph.BulkDeleteRequest{
+		Query:     input.Query,
+		BatchSize: input.BatchSize,
+		MaxRate:   input.MaxRate,
+	})
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"id":      json.Number(strconv.FormatUint(id, 10)),
+			"message": "Bulk deletion started. Query bulkDeletes for its progress.",
+		}},
+		nil,
+	), true
+}
+
+func resolveDropTypeData(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input struct {
+		Type string
+	}
+	inputByts, err := json.Marshal(m.ArgValue(schema.InputArgName))
+	if err == nil {
+		err = json.Unmarshal(inputByts, &input)
+	}
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+	id, err := (&edgraph.Server{}).DropTypeData(ctx, input.Type)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"id":      json.Number(strconv.FormatUint(id, 10)),
+			"message": "Bulk deletion started. Query bulkDeletes for its progress.",
+		}},
+		nil,
+	), true
+}
+
+func resolveBulkDeletes(ctx context.Context, q schema.Query) *resolve.Resolved {
+	deletes, err := (&edgraph.Server{}).BulkDeletes(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	results := make([]interface{}, 0, len(deletes))
+	for _, d := range deletes {
+		res := map[string]interface{}{
+			"id":        json.Number(strconv.FormatUint(d.Id, 10)),
+			"query":     d.Query,
+			"status":    d.Status,
+			"deleted":   json.Number(strconv.Itoa(d.Deleted)),
+			"batches":   json.Number(strconv.Itoa(d.Batches)),
+			"startedAt": d.StartedAt.Format(time.RFC3339),
+			"updatedAt": d.UpdatedAt.Format(time.RFC3339),
+		}
+		if d.Error != "" {
+			res["error"] = d.Error
+		}
+		results = append(results, res)
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
+
+func getBulkDeleteInput(m schema.Mutation) (*bulkDeleteInput, error) {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return nil, schema.GQLWrapf(err, "couldn't get input argument")
+	}
+
+	var input bulkDeleteInput
+	err = json.Unmarshal(inputByts, &input)
+	return &input, schema.GQLWrapf(err, "couldn't get input argument")
+}
@@ -435,17 +435,473 @@ const adminTypes = `
 		Enter a new password for groot in that namespace. If you leave it blank, the password will be the default.
 		"""
 		password: String
+
+		"""
+		Optional unique name for the namespace.
+		"""
+		name: String
 	}
 
 	input DeleteNamespaceInput {
 		namespaceId: Int!
 	}
 
+	input RenameNamespaceInput {
+		namespaceId: Int!
+
+		"""
+		New name for the namespace. Namespace names are unique across the cluster.
+		"""
+		name: String!
+	}
+
+	input SandboxNamespaceInput {
+		namespaceId: Int!
+
+		"""
+		Whether the queries of the namespace run in the sandbox, with the caps of --sandbox.
+		"""
+		sandboxed: Boolean!
+	}
+
+	input CloneNamespaceInput {
+		"""
+		ID of the namespace whose schema and data is copied into the new namespace.
+		"""
+		namespaceId: Int!
+
+		"""
+		Enter a new password for groot in the new namespace. If you leave it blank, the password will be the default.
+		"""
+		password: String
+
+		"""
+		Optional unique name for the new namespace.
+		"""
+		name: String
+	}
+
+	type NamespaceUsage {
+		namespaceId: UInt64
+		name: String
+
+		"""
+		Number of predicates in the schema of the namespace.
+		"""
+		predicates: Int
+
+		"""
+		Number of types in the schema of the namespace.
+		"""
+		types: Int
+
+		"""
+		Size of the predicates of the namespace on disk, as last reported to Zero.
+		"""
+		onDiskBytes: Int64
+		uncompressedBytes: Int64
+
+		"""
+		Queries and mutations per second served by this alpha for the namespace, averaged over the
+		last minute.
+		"""
+		qps: Float
+	}
+
 	type NamespacePayload {
 		namespaceId: UInt64
 		message: String
 	}
 
+	input BulkDeleteInput {
+		"""
+		The nodes to delete, as delete { matching(func: ...) @filter(...) }. The matching block
+		takes the same arguments and directives as a query block. Only the predicates of the
+		types of the matching nodes are deleted, as with a uid * * deletion.
+		"""
+		query: String!
+
+		"""
+		Number of nodes deleted per transaction. Defaults to 1000.
+		"""
+		batchSize: Int
+
+		"""
+		Maximum number of nodes deleted per second. The rate isn't limited if it's not given.
+		"""
+		maxRate: Int
+	}
+
+	input DropTypeDataInput {
+		"""
+		The type whose nodes are deleted. Only the fields of the type and the type itself are
+		deleted from the nodes, the definition of the type is kept.
+		"""
+		type: String!
+	}
+
+	type BulkDeletePayload {
+		id: UInt64
+		message: String
+	}
+
+	type BulkDelete {
+		id: UInt64
+		query: String
+
+		"""
+		Running, Success or Failed.
+		"""
+		status: String
+
+		"""
+		Number of nodes deleted so far, in the given number of batches.
+		"""
+		deleted: Int
+		batches: Int
+		error: String
+		startedAt: DateTime
+		updatedAt: DateTime
+	}
+
+	type Rename {
+		id: UInt64
+
+		"""
+		Either predicate or type.
+		"""
+		kind: String
+		from: String
+		to: String
+
+		"""
+		Running, Success or Failed.
+		"""
+		status: String
+
+		"""
+		Number of nodes moved to the new type so far, when renaming a type.
+		"""
+		nodes: Int
+		error: String
+		startedAt: DateTime
+		updatedAt: DateTime
+	}
+
+	input RegisterPersistedQueriesInput {
+		"""
+		The GraphQL queries to persist, each one identified by the sha256 hash of its text.
+		"""
+		queries: [String!]!
+
+		"""
+		Delete the other persisted queries of the namespace, to rotate them in one go.
+		"""
+		replace: Boolean
+	}
+
+	type RegisterPersistedQueriesPayload {
+		"""
+		The sha256 hashes of the queries, in the order they were given.
+		"""
+		hashes: [String]
+		message: String
+	}
+
+	input DeletePersistedQueriesInput {
+		hashes: [String!]!
+	}
+
+	type DeletePersistedQueriesPayload {
+		deleted: Int
+		message: String
+	}
+
+	input AddViewInput {
+		"""
+		Name of the view, whose results are read with the predicate dgraph.view.<name> of the
+		root nodes of its query. It has letters, digits and underscores.
+		"""
+		name: String!
+
+		"""
+		DQL query with a single query block, besides var blocks, which selects the uid of its
+		root nodes. It's run with access to all the predicates of the namespace.
+		"""
+		query: String!
+
+		"""
+		Number of seconds after which the results are recomputed, on top of the recomputations
+		after the mutations of the predicates of the query committed through this alpha. They're
+		only recomputed after the mutations if it's not given.
+		"""
+		refreshInterval: Int
+	}
+
+	input DeleteViewInput {
+		name: String!
+	}
+
+	type ViewPayload {
+		name: String
+		message: String
+	}
+
+	type View {
+		name: String
+		query: String
+
+		"""
+		Predicates read by the query, whose mutations recompute the results. They're empty if the
+		query expands the predicates of its nodes, in which case any mutation does.
+		"""
+		predicates: [String]
+		refreshInterval: Int
+
+		"""
+		Number of root nodes with results.
+		"""
+		nodes: Int
+
+		"""
+		True if a mutation committed after the results were computed is being taken into account.
+		"""
+		stale: Boolean
+		refreshedAt: DateTime
+
+		"""
+		Error of the last recomputation, in which case the previous results are still served.
+		"""
+		error: String
+	}
+
+	input AddTriggerInput {
+		"""
+		Name of the trigger. It has letters, digits and underscores.
+		"""
+		name: String!
+
+		"""
+		Predicate whose mutations run the trigger. Either a predicate or a type is given.
+		"""
+		predicate: String
+
+		"""
+		Type whose nodes run the trigger when their type or a predicate of the type is mutated.
+		"""
+		type: String
+
+		"""
+		Query of the upsert run by the trigger. It declares the $uids variable, which is set to
+		the uids of the nodes touched by the mutation, like query q($uids: string) {...}.
+		"""
+		query: String!
+		setNquads: String
+		delNquads: String
+		cond: String
+
+		"""
+		Runs the upsert in its own transaction after the mutation is committed, retrying it on
+		failures. Otherwise, it's run in the transaction of the mutation, which fails if the
+		upsert fails.
+		"""
+		async: Boolean
+	}
+
+	input DeleteTriggerInput {
+		name: String!
+	}
+
+	type TriggerPayload {
+		name: String
+		message: String
+	}
+
+	type Trigger {
+		name: String
+		predicate: String
+		type: String
+		query: String
+		setNquads: String
+		delNquads: String
+		cond: String
+		async: Boolean
+	}
+
+	input UploadWasmModuleInput {
+		"""
+		Name of the module. It has letters, digits and underscores.
+		"""
+		name: String!
+
+		"""
+		Base64 encoded WebAssembly binary of the module, which doesn't import anything. Its
+		exported functions take and return numbers.
+		"""
+		code: String!
+	}
+
+	input DeleteWasmModuleInput {
+		name: String!
+	}
+
+	type WasmModulePayload {
+		name: String
+		message: String
+	}
+
+	type WasmModule {
+		name: String
+		exports: [String]
+	}
+
+	input AddStoredFunctionInput {
+		"""
+		Name of the function, called by the queries as view(<name>, <arguments>...). It has
+		letters, digits and underscores.
+		"""
+		name: String!
+
+		"""
+		DQL query with a single block, whose root function and filter replace the calls to the
+		function, e.g. query activeUsers($since: string) { q(func: type(User)) @filter(ge(lastSeen,
+		$since)) }. The arguments of the calls are bound to its variables in order.
+		"""
+		query: String!
+	}
+
+	input DeleteStoredFunctionInput {
+		name: String!
+	}
+
+	type StoredFunctionPayload {
+		name: String
+		message: String
+	}
+
+	type StoredFunction {
+		name: String
+		params: [String]
+		query: String
+	}
+
+	input AddStandingQueryInput {
+		"""
+		DQL query with a single query block, besides var blocks, which selects the uid of its
+		root nodes. A delta is pushed whenever the root nodes it matches change.
+		"""
+		query: String!
+
+		"""
+		URL the deltas are posted to, as JSON. Without a webhook, the deltas are only streamed
+		to the subscribers of the /admin/standing?id=<id> endpoint of this alpha.
+		"""
+		webhook: String
+
+		"""
+		Number of seconds after which the query is run again, on top of the runs after the
+		mutations of the predicates of the query committed through this alpha.
+		"""
+		refreshInterval: Int
+	}
+
+	input DeleteStandingQueryInput {
+		id: Int!
+	}
+
+	type StandingQueryPayload {
+		id: UInt64
+		message: String
+	}
+
+	type StandingQuery {
+		id: UInt64
+		query: String
+		webhook: String
+		predicates: [String]
+		refreshInterval: Int
+
+		"""
+		Number of root nodes currently matched by the query.
+		"""
+		matched: Int
+
+		"""
+		Sequence number of the last delta.
+		"""
+		seq: UInt64
+		subscribers: Int
+		refreshedAt: DateTime
+
+		"""
+		Error of the last run of the query, or of the last post to the webhook.
+		"""
+		error: String
+	}
+
+	input AddApiKeyInput {
+		"""
+		Optional name to identify the API key.
+		"""
+		name: String
+
+		"""
+		Namespaces that can be accessed with the API key. If the key can access more than one
+		namespace, the namespace must be given with each request.
+		"""
+		namespaces: [Int!]!
+
+		"""
+		Permission of the API key, as in the ACL rules: read (4), write (2) and modify (1) can be
+		combined.
+		"""
+		permission: Int!
+
+		"""
+		Predicates that can be accessed with the API key. All the predicates apart from the
+		reserved ones can be accessed if none are given.
+		"""
+		predicates: [String!]
+
+		"""
+		Time in Unix epoch time after which the API key can't be used. The key doesn't expire if
+		it's not given.
+		"""
+		expiresAt: Int64
+	}
+
+	input RotateApiKeyInput {
+		id: String!
+
+		"""
+		Number of seconds during which the previous key can still be used. The previous key can't
+		be used as soon as the key is rotated if it's not given.
+		"""
+		gracePeriod: Int
+	}
+
+	input RevokeApiKeyInput {
+		id: String!
+	}
+
+	type ApiKey {
+		id: String
+		name: String
+		namespaces: [UInt64]
+		permission: Int
+		predicates: [String]
+		expiresAt: Int64
+	}
+
+	type ApiKeyPayload {
+		"""
+		The API key, only returned when the key is added or rotated. It can't be retrieved later.
+		"""
+		key: String
+		apiKey: ApiKey
+		message: String
+	}
+
 	input ResetPasswordInput {
 		userId: String!
 		password: String!
@@ -523,11 +979,120 @@ const adminMutations = `
 	"""
 	deleteNamespace(input: DeleteNamespaceInput!): NamespacePayload
 
+	"""
+	Rename a namespace.
+	"""
+	renameNamespace(input: RenameNamespaceInput!): NamespacePayload
+
+	"""
+	Sandbox a namespace, or release it from the sandbox.
+	"""
+	sandboxNamespace(input: SandboxNamespaceInput!): NamespacePayload
+
+	"""
+	Create a new namespace with a copy of the schema and data of an existing namespace. ACL users
+	and groups are not copied.
+	"""
+	cloneNamespace(input: CloneNamespaceInput!): NamespacePayload
+
+	"""
+	Start deleting the nodes of the namespace matching a query, in batches of transactions run
+	by this alpha until the query doesn't match any node.
+	"""
+	bulkDelete(input: BulkDeleteInput!): BulkDeletePayload
+
+	"""
+	Start deleting the nodes of a type in the namespace, as a bulk deletion.
+	"""
+	dropTypeData(input: DropTypeDataInput!): BulkDeletePayload
+
+	"""
+	Persist GraphQL queries in the namespace. With # Dgraph.PersistedQueries "enforce" in the
+	GraphQL schema, only the persisted queries are served at /graphql.
+	"""
+	registerPersistedQueries(input: RegisterPersistedQueriesInput!): RegisterPersistedQueriesPayload
+
+	"""
+	Delete persisted GraphQL queries of the namespace, given their sha256 hashes.
+	"""
+	deletePersistedQueries(input: DeletePersistedQueriesInput!): DeletePersistedQueriesPayload
+
+	"""
+	Register a DQL query as a materialized view of the namespace, replacing the view with the
+	same name. Its results are computed by this alpha, and kept up to date as mutations touch
+	the predicates of the query.
+	"""
+	addView(input: AddViewInput!): ViewPayload
+
+	"""
+	Delete a materialized view of the namespace.
+	"""
+	deleteView(input: DeleteViewInput!): ViewPayload
+
+	"""
+	Register a standing DQL query in the namespace. This alpha runs it again after the mutations
+	of its predicates, and pushes the uids matched and unmatched by its root nodes.
+	"""
+	addStandingQuery(input: AddStandingQueryInput!): StandingQueryPayload
+
+	"""
+	Delete a standing query of the namespace, disconnecting its subscribers.
+	"""
+	deleteStandingQuery(input: DeleteStandingQueryInput!): StandingQueryPayload
+
+	"""
+	Add a trigger to the namespace, replacing the trigger with the same name. It runs an upsert
+	whenever the mutations of the namespace touch its predicate or type.
+	"""
+	addTrigger(input: AddTriggerInput!): TriggerPayload
+
+	"""
+	Delete a trigger of the namespace.
+	"""
+	deleteTrigger(input: DeleteTriggerInput!): TriggerPayload
+
+	"""
+	Upload a wasm module to the namespace, replacing the module with the same name. The queries
+	call its functions as wasm.<module>.<function>, or wasm.<module> if it exports one function.
+	"""
+	uploadWasmModule(input: UploadWasmModuleInput!): WasmModulePayload
+
+	"""
+	Delete a wasm module of the namespace.
+	"""
+	deleteWasmModule(input: DeleteWasmModuleInput!): WasmModulePayload
+
+	"""
+	Add a stored DQL function to the namespace, replacing the function with the same name.
+	"""
+	addStoredFunction(input: AddStoredFunctionInput!): StoredFunctionPayload
+
+	"""
+	Delete a stored DQL function of the namespace.
+	"""
+	deleteStoredFunction(input: DeleteStoredFunctionInput!): StoredFunctionPayload
+
 	"""
 	Reset password can only be used by the Guardians of the galaxy to reset password of
 	any user in any namespace.
 	"""
 	resetPassword(input: ResetPasswordInput!): ResetPasswordPayload
+
+	"""
+	Add an API key, which can be used instead of logging in to access the given namespaces. The
+	key is passed in the X-Dgraph-ApiKey header over HTTP, and in the apikey metadata over gRPC.
+	"""
+	addApiKey(input: AddApiKeyInput!): ApiKeyPayload
+
+	"""
+	Replace an API key with a new one, keeping its scope.
+	"""
+	rotateApiKey(input: RotateApiKeyInput!): ApiKeyPayload
+
+	"""
+	Revoke an API key, after which it can't be used anymore.
+	"""
+	revokeApiKey(input: RevokeApiKeyInput!): ApiKeyPayload
 	`
 
 const adminQueries = `
@@ -546,4 +1111,52 @@ const adminQueries = `
 	Get the information about the backups at a given location.
 	"""
 	listBackups(input: ListBackupsInput!) : [Manifest]
+
+	"""
+	Get the usage of all the namespaces.
+	"""
+	namespaceUsage: [NamespaceUsage]
+
+	"""
+	Get the progress of the bulk deletions run by this alpha in the namespace, the latest first.
+	The nodes of a type dropped by dropTypeData are deleted as a bulk deletion too.
+	"""
+	bulkDeletes: [BulkDelete]
+
+	"""
+	Get the progress of the renames run by this alpha in the namespace, the latest first. A
+	predicate or a type is renamed with an alter operation whose schema is
+	rename predicate <from> to <to>, or rename type From to To.
+	"""
+	renames: [Rename]
+
+	"""
+	Get the materialized views maintained by this alpha in the namespace, by name.
+	"""
+	views: [View]
+
+	"""
+	Get the standing queries maintained by this alpha in the namespace, by id.
+	"""
+	standingQueries: [StandingQuery]
+
+	"""
+	Get the triggers of the namespace, by name.
+	"""
+	triggers: [Trigger]
+
+	"""
+	Get the wasm modules of the namespace, by name.
+	"""
+	wasmModules: [WasmModule]
+
+	"""
+	Get the stored DQL functions of the namespace, by name.
+	"""
+	storedFunctions: [StoredFunction]
+
+	"""
+	Get the API keys, without their secrets.
+	"""
+	listApiKeys: [ApiKey]
 	`
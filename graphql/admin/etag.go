@@ -0,0 +1,73 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// etagStart keeps the ETags given by the Alpha apart from those it gave before restarting, as
+// the epochs of the GraphQL schemas start again from zero.
+var etagStart = time.Now().UnixNano()
+
+// queryETag returns the ETag of the response to a persisted query sent with GET, or an empty
+// string if it can't have one. It's a hash of the request, of the credentials sent with it, of
+// the epoch of the GraphQL schema, and of the versions of the predicates read by the query, so
+// it changes whenever the response could.
+func queryETag(ctx context.Context, r *http.Request, ns, schemaEpoch uint64,
+	resolver *resolve.RequestResolver, gqlReq *schema.Request) string {
+
+	if r.Method != http.MethodGet || gqlReq.Extensions.PersistedQuery.Sha256Hash == "" {
+		return ""
+	}
+	preds, ok := resolver.QueryPredicates(ctx, gqlReq)
+	if !ok {
+		return ""
+	}
+	vars, err := json.Marshal(gqlReq.Variables)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%d\n%d\n%s\n%s\n%s\n", etagStart, ns, schemaEpoch, gqlReq.Query,
+		gqlReq.OperationName, vars)
+	credentials := []string{"X-Dgraph-AccessToken", "X-Dgraph-AuthToken"}
+	if authMeta := resolver.Schema().Meta().AuthMeta(); authMeta != nil && authMeta.Header != "" {
+		credentials = append(credentials, authMeta.Header)
+	}
+	for _, header := range credentials {
+		fmt.Fprintf(h, "%s\n", r.Header.Get(header))
+	}
+	for _, pred := range preds {
+		attr := x.NamespaceAttr(ns, pred)
+		fmt.Fprintf(h, "%s %s\n", attr, worker.PredicateVersion(ctx, attr))
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches tells whether the ETag is one of those of the If-None-Match header.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
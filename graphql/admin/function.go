@@ -0,0 +1,92 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+type addStoredFunctionInput struct {
+	Name  string
+	Query string
+}
+
+type deleteStoredFunctionInput struct {
+	Name string
+}
+
+func resolveAddStoredFunction(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input addStoredFunctionInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	if err := (&edgraph.Server{}).AddFunction(ctx, input.Name, input.Query); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"name":    input.Name,
+			"message": fmt.Sprintf("Added the stored function %s.", input.Name),
+		}},
+		nil,
+	), true
+}
+
+func resolveDeleteStoredFunction(ctx context.Context,
+	m schema.Mutation) (*resolve.Resolved, bool) {
+
+	var input deleteStoredFunctionInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	deleted, err := (&edgraph.Server{}).DeleteFunction(ctx, input.Name)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	msg := fmt.Sprintf("Deleted the stored function %s.", input.Name)
+	if !deleted {
+		msg = fmt.Sprintf("The stored function %s doesn't exist.", input.Name)
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"name":    input.Name,
+			"message": msg,
+		}},
+		nil,
+	), true
+}
+
+func resolveStoredFunctions(ctx context.Context, q schema.Query) *resolve.Resolved {
+	funcs, err := (&edgraph.Server{}).Functions(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	results := make([]interface{}, 0, len(funcs))
+	for _, fn := range funcs {
+		params := make([]interface{}, 0, len(fn.Params))
+		for _, param := range fn.Params {
+			params = append(params, param)
+		}
+		results = append(results, map[string]interface{}{
+			"name":   fn.Name,
+			"params": params,
+			"query":  fn.Query,
+		})
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
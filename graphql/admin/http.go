@@ -6,15 +6,18 @@
 package admin
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/json"
 	"io"
 	"mime"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
@@ -41,6 +44,12 @@ type IServeGraphQL interface {
 	// After Set is called, this IServeGraphQL serves the new resolvers for the given namespace ns.
 	Set(ns uint64, schemaEpoch *uint64, resolver *resolve.RequestResolver)
 
+	// SetNamed is like Set, for the named GraphQL schema of the namespace ns.
+	SetNamed(ns uint64, name string, schemaEpoch *uint64, resolver *resolve.RequestResolver)
+
+	// DeleteNamed stops serving the named GraphQL schema of the namespace ns.
+	DeleteNamed(ns uint64, name string)
+
 	// HTTPHandler returns a http.Handler that serves GraphQL.
 	HTTPHandler() http.Handler
 
@@ -48,31 +57,59 @@ type IServeGraphQL interface {
 	ResolveWithNs(ctx context.Context, ns uint64, gqlReq *schema.Request) *schema.Response
 }
 
+// schemaKey identifies a GraphQL schema being served, the default schema of the namespace if the
+// name is empty.
+type schemaKey struct {
+	ns   uint64
+	name string
+}
+
 type graphqlHandler struct {
-	resolver    map[uint64]*resolve.RequestResolver
+	resolver    map[schemaKey]*resolve.RequestResolver
+	epoch       map[schemaKey]*uint64
 	handler     http.Handler
-	poller      map[uint64]*subscription.Poller
-	resolverMux sync.RWMutex // protects resolver from RW races
+	poller      map[schemaKey]*subscription.Poller
+	resolverMux sync.RWMutex // protects resolver and epoch from RW races
 	pollerMux   sync.RWMutex // protects poller from RW races
 }
 
 // NewServer returns a new IServeGraphQL that can serve the given resolvers
 func NewServer() IServeGraphQL {
 	gh := &graphqlHandler{
-		resolver: make(map[uint64]*resolve.RequestResolver),
-		poller:   make(map[uint64]*subscription.Poller),
+		resolver: make(map[schemaKey]*resolve.RequestResolver),
+		epoch:    make(map[schemaKey]*uint64),
+		poller:   make(map[schemaKey]*subscription.Poller),
 	}
 	gh.handler = recoveryHandler(commonHeaders(gh.Handler()))
 	return gh
 }
 
 func (gh *graphqlHandler) Set(ns uint64, schemaEpoch *uint64, resolver *resolve.RequestResolver) {
+	gh.SetNamed(ns, "", schemaEpoch, resolver)
+}
+
+func (gh *graphqlHandler) SetNamed(ns uint64, name string, schemaEpoch *uint64,
+	resolver *resolve.RequestResolver) {
+	key := schemaKey{ns: ns, name: name}
+	gh.resolverMux.Lock()
+	gh.resolver[key] = resolver
+	gh.epoch[key] = schemaEpoch
+	gh.resolverMux.Unlock()
+
+	gh.pollerMux.Lock()
+	gh.poller[key] = subscription.NewPoller(schemaEpoch, resolver)
+	gh.pollerMux.Unlock()
+}
+
+func (gh *graphqlHandler) DeleteNamed(ns uint64, name string) {
+	key := schemaKey{ns: ns, name: name}
 	gh.resolverMux.Lock()
-	gh.resolver[ns] = resolver
+	delete(gh.resolver, key)
+	delete(gh.epoch, key)
 	gh.resolverMux.Unlock()
 
 	gh.pollerMux.Lock()
-	gh.poller[ns] = subscription.NewPoller(schemaEpoch, resolver)
+	delete(gh.poller, key)
 	gh.pollerMux.Unlock()
 }
 
@@ -83,16 +120,14 @@ func (gh *graphqlHandler) HTTPHandler() http.Handler {
 func (gh *graphqlHandler) ResolveWithNs(ctx context.Context, ns uint64,
 	gqlReq *schema.Request) *schema.Response {
 	gh.resolverMux.RLock()
-	resolver := gh.resolver[ns]
+	resolver := gh.resolver[schemaKey{ns: ns}]
 	gh.resolverMux.RUnlock()
 	return resolver.Resolve(ctx, gqlReq)
 }
 
-// write chooses between the http response writer and gzip writer
-// and sends the schema response using that.
-func write(w http.ResponseWriter, rr *schema.Response, acceptGzip bool) {
-	var out io.Writer = w
-
+// write writes the response, compressed with the encoding accepted by the client if it's large
+// enough.
+func write(w http.ResponseWriter, r *http.Request, rr *schema.Response) {
 	// set TouchedUids header
 	w.Header().Set(touchedUidsHeader, strconv.FormatUint(rr.GetExtensions().GetTouchedUids(), 10))
 
@@ -100,39 +135,34 @@ func write(w http.ResponseWriter, rr *schema.Response, acceptGzip bool) {
 		w.Header()[key] = val
 	}
 
-	// If the receiver accepts gzip, then we would update the writer
-	// and send gzipped content instead.
-	if acceptGzip {
-		w.Header().Set("Content-Encoding", "gzip")
-		gzw := gzip.NewWriter(w)
-		defer gzw.Close()
-		out = gzw
+	var buf bytes.Buffer
+	if _, err := rr.WriteTo(&buf); err != nil {
+		glog.Error(err)
 	}
-
-	if _, err := rr.WriteTo(out); err != nil {
+	if _, err := x.WriteResponse(w, r, buf.Bytes()); err != nil {
 		glog.Error(err)
 	}
 }
 
 // WriteErrorResponse writes the error to the HTTP response writer in GraphQL format.
 func WriteErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	write(w, schema.ErrorResponse(err), strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
+	write(w, r, schema.ErrorResponse(err))
 }
 
 type graphqlSubscription struct {
 	graphqlHandler *graphqlHandler
 }
 
-func (gs *graphqlSubscription) isValid(namespace uint64) error {
+func (gs *graphqlSubscription) isValid(key schemaKey) error {
 	gs.graphqlHandler.pollerMux.RLock()
 	defer gs.graphqlHandler.pollerMux.RUnlock()
-	if err := gs.graphqlHandler.isValid(namespace); err != nil {
+	if err := gs.graphqlHandler.isValid(key); err != nil {
 		return err
 	}
 	if gs.graphqlHandler.poller == nil {
 		return errors.New("poller is nil")
 	}
-	if gs.graphqlHandler.poller[namespace] == nil {
+	if gs.graphqlHandler.poller[key] == nil {
 		return errors.New("poller not found")
 	}
 	return nil
@@ -183,20 +213,34 @@ func (gs *graphqlSubscription) Subscribe(
 
 	audit.AuditWebSockets(ctx, req)
 	namespace := x.ExtractNamespaceHTTP(&http.Request{Header: reqHeader})
+	name := reqHeader.Get(x.GraphQLSchemaHeader)
 	glog.Infof("namespace: %d. Got GraphQL request over websocket.", namespace)
 	// first load the schema, then do anything else
-	if err := LazyLoadSchema(namespace); err != nil {
+	if err := LazyLoadNamedSchema(namespace, name); err != nil {
 		return nil, err
 	}
-	if err := gs.isValid(namespace); err != nil {
+	key := schemaKey{ns: namespace, name: name}
+	if err := gs.isValid(key); err != nil {
 		glog.Errorf("namespace: %d. graphqlSubscription not initialized: %s", namespace, err)
 		return nil, errors.New(resolve.ErrInternal)
 	}
 
 	gs.graphqlHandler.pollerMux.RLock()
-	poller := gs.graphqlHandler.poller[namespace]
+	poller := gs.graphqlHandler.poller[key]
 	gs.graphqlHandler.pollerMux.RUnlock()
 
+	// the subscriptions have no persisted query extension, so their query must be persisted
+	gs.graphqlHandler.resolverMux.RLock()
+	persistedOnly := gs.graphqlHandler.resolver[key].Schema().Meta().PersistedQueriesOnly()
+	gs.graphqlHandler.resolverMux.RUnlock()
+	if persistedOnly {
+		pctx := x.AttachAccessJwt(ctx, &http.Request{Header: reqHeader})
+		if err := edgraph.ProcessPersistedQuery(x.AttachNamespace(pctx, namespace), req,
+			true); err != nil {
+			return nil, err
+		}
+	}
+
 	res, err := poller.AddSubscriber(req)
 	if err != nil {
 		return nil, err
@@ -225,15 +269,17 @@ func (gh *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer span.End()
 
 	ns, _ := strconv.ParseUint(r.Header.Get("resolver"), 10, 64)
+	key := schemaKey{ns: ns, name: r.Header.Get(x.GraphQLSchemaHeader)}
 	glog.Infof("namespace: %d. Got GraphQL request over HTTP.", ns)
-	if err := gh.isValid(ns); err != nil {
+	if err := gh.isValid(key); err != nil {
 		glog.Errorf("namespace: %d. graphqlHandler not initialised: %s", ns, err)
 		WriteErrorResponse(w, r, errors.New(resolve.ErrInternal))
 		return
 	}
 
 	gh.resolverMux.RLock()
-	resolver := gh.resolver[ns]
+	resolver := gh.resolver[key]
+	schemaEpoch := gh.epoch[key]
 	gh.resolverMux.RUnlock()
 
 	addDynamicHeaders(resolver, r.Header.Get("Origin"), w)
@@ -256,26 +302,44 @@ func (gh *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = edgraph.ProcessPersistedQuery(ctx, gqlReq); err != nil {
+	persistedOnly := resolver.Schema().Meta().PersistedQueriesOnly()
+	if err = edgraph.ProcessPersistedQuery(ctx, gqlReq, persistedOnly); err != nil {
 		WriteErrorResponse(w, r, err)
 		return
 	}
 
+	// The ETag is computed before resolving the query, so that it can only be older than the
+	// response. The query is resolved even if the ETag matches, so that the ACLs and the auth
+	// rules are checked with the current credentials.
+	var etag string
+	if schemaEpoch != nil {
+		etag = queryETag(ctx, r, ns, atomic.LoadUint64(schemaEpoch), resolver, gqlReq)
+	}
 	res = resolver.Resolve(ctx, gqlReq)
-	write(w, res, strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
+	if etag != "" && len(res.Errors) == 0 {
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			for key, val := range res.Header {
+				w.Header()[key] = val
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	write(w, r, res)
 }
 
-func (gh *graphqlHandler) isValid(namespace uint64) error {
+func (gh *graphqlHandler) isValid(key schemaKey) error {
 	gh.resolverMux.RLock()
 	defer gh.resolverMux.RUnlock()
 	switch {
 	case gh.resolver == nil:
 		return errors.New("resolver is nil")
-	case gh.resolver[namespace] == nil:
+	case gh.resolver[key] == nil:
 		return errors.New("resolver not found")
-	case gh.resolver[namespace].Schema() == nil:
+	case gh.resolver[key].Schema() == nil:
 		return errors.New("schema is nil")
-	case gh.resolver[namespace].Schema().Meta() == nil:
+	case gh.resolver[key].Schema().Meta() == nil:
 		return errors.New("schema meta is nil")
 	}
 	return nil
@@ -347,12 +411,17 @@ func getRequest(r *http.Request) (*schema.Request, error) {
 				return nil, errors.Wrap(err, "Could not read GraphQL request body")
 			}
 			gqlReq.Query = string(bytes)
+		case "multipart/form-data":
+			if err = getMultipartRequest(r, gqlReq); err != nil {
+				return nil, err
+			}
 		default:
 			// https://graphql.org/learn/serving-over-http/#post-request says:
 			// "A standard GraphQL POST request should use the application/json
 			// content type ..."
 			return nil, errors.New(
-				"Unrecognised Content-Type.  Please use application/json or application/graphql for GraphQL requests")
+				"Unrecognised Content-Type.  Please use application/json, application/graphql or " +
+					"multipart/form-data for GraphQL requests")
 		}
 	default:
 		return nil,
@@ -363,6 +432,120 @@ func getRequest(r *http.Request) (*schema.Request, error) {
 	return gqlReq, nil
 }
 
+// getMultipartRequest reads a request following the GraphQL multipart request specification
+// (https://github.com/jaydenseric/graphql-multipart-request-spec) into gqlReq. Its operations
+// field is the GraphQL request, its map field tells which variables the files stand for, and the
+// files follow them. The files are kept in memory as schema.Uploads in the variables, up to the
+// upload-max-size-mb of --graphql for all of them.
+func getMultipartRequest(r *http.Request, gqlReq *schema.Request) error {
+	if x.Config.GraphQL.GetString("upload-uri") == "" {
+		return errors.New("File uploads aren't enabled, the upload-uri of --graphql isn't set")
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return errors.Wrap(err, "Not a valid multipart GraphQL request")
+	}
+	left := x.Config.GraphQL.GetInt64("upload-max-size-mb") << 20
+
+	var fileMap map[string][]string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "Not a valid multipart GraphQL request")
+		}
+
+		switch name := part.FormName(); name {
+		case "operations":
+			d := json.NewDecoder(part)
+			d.UseNumber()
+			if err := d.Decode(gqlReq); err != nil {
+				return errors.Wrap(err, "Not a valid GraphQL request in the operations field, "+
+					"batched operations aren't supported")
+			}
+		case "map":
+			if err := json.NewDecoder(part).Decode(&fileMap); err != nil {
+				return errors.Wrap(err, "Not a valid map field in the multipart GraphQL request")
+			}
+		default:
+			paths, ok := fileMap[name]
+			if !ok {
+				return errors.Errorf("The file %s of the multipart GraphQL request isn't in its "+
+					"map field, which must come before the files", name)
+			}
+			data, err := io.ReadAll(io.LimitReader(part, left+1))
+			if err != nil {
+				return errors.Wrapf(err, "while reading the file %s", name)
+			}
+			if left -= int64(len(data)); left < 0 {
+				return errors.Errorf("The files of the request are bigger than the limit of %dMB",
+					x.Config.GraphQL.GetInt64("upload-max-size-mb"))
+			}
+			upload := &schema.Upload{
+				Filename:    part.FileName(),
+				ContentType: part.Header.Get("Content-Type"),
+				Data:        data,
+			}
+			for _, path := range paths {
+				if err := setVariable(gqlReq.Variables, path, upload); err != nil {
+					return err
+				}
+			}
+			delete(fileMap, name)
+		}
+	}
+	if len(fileMap) != 0 {
+		missing := make([]string, 0, len(fileMap))
+		for name := range fileMap {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return errors.Errorf("The files %s in the map field of the multipart GraphQL request are "+
+			"missing", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// setVariable sets the value at a path of the map field of a multipart request, like
+// variables.files.1, in the variables.
+func setVariable(vars map[string]interface{}, path string, val interface{}) error {
+	invalid := errors.Errorf("The path %s in the map field of the multipart GraphQL request "+
+		"doesn't lead to a variable", path)
+	keys := strings.Split(path, ".")
+	if len(keys) < 2 || keys[0] != "variables" || vars == nil {
+		return invalid
+	}
+
+	var parent interface{} = vars
+	for i, key := range keys[1:] {
+		last := i == len(keys)-2
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[key]; !ok {
+				return invalid
+			}
+			if last {
+				p[key] = val
+			}
+			parent = p[key]
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(p) {
+				return invalid
+			}
+			if last {
+				p[idx] = val
+			}
+			parent = p[idx]
+		default:
+			return invalid
+		}
+	}
+	return nil
+}
+
 func commonHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		x.AddCorsHeaders(w)
@@ -377,7 +560,7 @@ func recoveryHandler(next http.Handler) http.Handler {
 		defer api.PanicHandler(
 			func(err error) {
 				rr := schema.ErrorResponse(err)
-				write(w, rr, strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
+				write(w, r, rr)
 			}, "")
 
 		next.ServeHTTP(w, r)
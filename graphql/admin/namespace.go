@@ -20,12 +20,29 @@ import (
 
 type addNamespaceInput struct {
 	Password string
+	Name     string
 }
 
 type deleteNamespaceInput struct {
 	NamespaceId int
 }
 
+type renameNamespaceInput struct {
+	NamespaceId int
+	Name        string
+}
+
+type sandboxNamespaceInput struct {
+	NamespaceId int
+	Sandboxed   bool
+}
+
+type cloneNamespaceInput struct {
+	NamespaceId int
+	Password    string
+	Name        string
+}
+
 func resolveAddNamespace(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
 	req, err := getAddNamespaceInput(m)
 	if err != nil {
@@ -39,6 +56,11 @@ func resolveAddNamespace(ctx context.Context, m schema.Mutation) (*resolve.Resol
 	if ns, err = (&edgraph.Server{}).CreateNamespaceInternal(ctx, req.Password); err != nil {
 		return resolve.EmptyResult(m, err), false
 	}
+	if req.Name != "" {
+		if err = (&edgraph.Server{}).RenameNamespace(ctx, ns, req.Name); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+	}
 	return resolve.DataResult(
 		m,
 		map[string]interface{}{m.Name(): map[string]interface{}{
@@ -75,6 +97,100 @@ func resolveDeleteNamespace(ctx context.Context, m schema.Mutation) (*resolve.Re
 	), true
 }
 
+func resolveRenameNamespace(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	req, err := getRenameNamespaceInput(m)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	if err = (&edgraph.Server{}).RenameNamespace(ctx, uint64(req.NamespaceId), req.Name); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"namespaceId": json.Number(strconv.Itoa(req.NamespaceId)),
+			"message":     "Renamed namespace successfully",
+		}},
+		nil,
+	), true
+}
+
+func resolveSandboxNamespace(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	req, err := getSandboxNamespaceInput(m)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	err = (&edgraph.Server{}).SandboxNamespace(ctx, uint64(req.NamespaceId), req.Sandboxed)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	msg := "Sandboxed namespace successfully"
+	if !req.Sandboxed {
+		msg = "Released namespace from the sandbox successfully"
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"namespaceId": json.Number(strconv.Itoa(req.NamespaceId)),
+			"message":     msg,
+		}},
+		nil,
+	), true
+}
+
+func resolveCloneNamespace(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	req, err := getCloneNamespaceInput(m)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	if req.Password == "" {
+		// Use the default password, if the user does not specify.
+		req.Password = "password"
+	}
+	ns, err := (&edgraph.Server{}).CloneNamespaceInternal(ctx, uint64(req.NamespaceId), req.Password)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	if req.Name != "" {
+		if err = (&edgraph.Server{}).RenameNamespace(ctx, ns, req.Name); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"namespaceId": json.Number(strconv.FormatUint(ns, 10)),
+			"message":     "Cloned namespace successfully",
+		}},
+		nil,
+	), true
+}
+
+func resolveNamespaceUsage(ctx context.Context, q schema.Query) *resolve.Resolved {
+	usage, err := (&edgraph.Server{}).GetNamespaceUsage(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	results := make([]interface{}, 0, len(usage))
+	for _, u := range usage {
+		results = append(results, map[string]interface{}{
+			"namespaceId":       json.Number(strconv.FormatUint(u.Id, 10)),
+			"name":              u.Name,
+			"predicates":        json.Number(strconv.Itoa(u.Predicates)),
+			"types":             json.Number(strconv.Itoa(u.Types)),
+			"onDiskBytes":       json.Number(strconv.FormatInt(u.OnDiskBytes, 10)),
+			"uncompressedBytes": json.Number(strconv.FormatInt(u.UncompressedBytes, 10)),
+			"qps":               json.Number(strconv.FormatFloat(u.QPS, 'f', -1, 64)),
+		})
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
+
 func getAddNamespaceInput(m schema.Mutation) (*addNamespaceInput, error) {
 	inputArg := m.ArgValue(schema.InputArgName)
 	inputByts, err := json.Marshal(inputArg)
@@ -98,3 +214,39 @@ func getDeleteNamespaceInput(m schema.Mutation) (*deleteNamespaceInput, error) {
 	err = json.Unmarshal(inputByts, &input)
 	return &input, schema.GQLWrapf(err, "couldn't get input argument")
 }
+
+func getRenameNamespaceInput(m schema.Mutation) (*renameNamespaceInput, error) {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return nil, schema.GQLWrapf(err, "couldn't get input argument")
+	}
+
+	var input renameNamespaceInput
+	err = json.Unmarshal(inputByts, &input)
+	return &input, schema.GQLWrapf(err, "couldn't get input argument")
+}
+
+func getSandboxNamespaceInput(m schema.Mutation) (*sandboxNamespaceInput, error) {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return nil, schema.GQLWrapf(err, "couldn't get input argument")
+	}
+
+	var input sandboxNamespaceInput
+	err = json.Unmarshal(inputByts, &input)
+	return &input, schema.GQLWrapf(err, "couldn't get input argument")
+}
+
+func getCloneNamespaceInput(m schema.Mutation) (*cloneNamespaceInput, error) {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return nil, schema.GQLWrapf(err, "couldn't get input argument")
+	}
+
+	var input cloneNamespaceInput
+	err = json.Unmarshal(inputByts, &input)
+	return &input, schema.GQLWrapf(err, "couldn't get input argument")
+}
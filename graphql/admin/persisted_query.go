@@ -0,0 +1,80 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+type registerPersistedQueriesInput struct {
+	Queries []string
+	Replace bool
+}
+
+type deletePersistedQueriesInput struct {
+	Hashes []string
+}
+
+func resolveRegisterPersistedQueries(ctx context.Context,
+	m schema.Mutation) (*resolve.Resolved, bool) {
+	var input registerPersistedQueriesInput
+	if err := getPersistedQueriesInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	hashes, err := (&edgraph.Server{}).RegisterPersistedQueries(ctx, input.Queries, input.Replace)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	res := make([]interface{}, 0, len(hashes))
+	for _, hash := range hashes {
+		res = append(res, hash)
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"hashes":  res,
+			"message": fmt.Sprintf("Registered %d persisted queries.", len(hashes)),
+		}},
+		nil,
+	), true
+}
+
+func resolveDeletePersistedQueries(ctx context.Context,
+	m schema.Mutation) (*resolve.Resolved, bool) {
+	var input deletePersistedQueriesInput
+	if err := getPersistedQueriesInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	deleted, err := (&edgraph.Server{}).DeletePersistedQueries(ctx, input.Hashes)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"deleted": json.Number(strconv.Itoa(deleted)),
+			"message": fmt.Sprintf("Deleted %d persisted queries.", deleted),
+		}},
+		nil,
+	), true
+}
+
+func getPersistedQueriesInput(m schema.Mutation, input interface{}) error {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return schema.GQLWrapf(err, "couldn't get input argument")
+	}
+	return schema.GQLWrapf(json.Unmarshal(inputByts, input), "couldn't get input argument")
+}
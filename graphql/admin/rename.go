@@ -0,0 +1,47 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+func resolveRenames(ctx context.Context, q schema.Query) *resolve.Resolved {
+	renames, err := (&edgraph.Server{}).Renames(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	results := make([]interface{}, 0, len(renames))
+	for _, r := range renames {
+		res := map[string]interface{}{
+			"id":        json.Number(strconv.FormatUint(r.Id, 10)),
+			"kind":      r.Kind,
+			"from":      r.From,
+			"to":        r.To,
+			"status":    r.Status,
+			"nodes":     json.Number(strconv.Itoa(r.Nodes)),
+			"startedAt": r.StartedAt.Format(time.RFC3339),
+			"updatedAt": r.UpdatedAt.Format(time.RFC3339),
+		}
+		if r.Error != "" {
+			res["error"] = r.Error
+		}
+		results = append(results, res)
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
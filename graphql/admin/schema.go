@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
 
 	"github.com/hypermodeinc/dgraph/v25/edgraph"
 	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
@@ -24,7 +25,8 @@ type getSchemaResolver struct {
 }
 
 type updateGQLSchemaInput struct {
-	Set worker.GqlSchema `json:"set,omitempty"`
+	Set  worker.GqlSchema `json:"set,omitempty"`
+	Name string           `json:"name,omitempty"`
 }
 
 type updateSchemaResolver struct {
@@ -38,6 +40,11 @@ func (usr *updateSchemaResolver) Resolve(ctx context.Context, m schema.Mutation)
 	if err != nil {
 		return resolve.EmptyResult(m, err), false
 	}
+	if input.Name != "" {
+		if err := worker.ValidateGQLSchemaName(input.Name); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+	}
 
 	// We just need to validate the schema. Schema is later set in `resetSchema()` when the schema
 	// is returned from badger.
@@ -51,7 +58,8 @@ func (usr *updateSchemaResolver) Resolve(ctx context.Context, m schema.Mutation)
 		return resolve.EmptyResult(m, err), false
 	}
 
-	resp, err := edgraph.UpdateGQLSchema(ctx, input.Set.Schema, schHandler.DGSchema())
+	resp, err := edgraph.UpdateNamedGQLSchema(ctx, input.Name, input.Set.Schema,
+		schHandler.DGSchema())
 	if err != nil {
 		return resolve.EmptyResult(m, err), false
 	}
@@ -71,15 +79,24 @@ func (usr *updateSchemaResolver) Resolve(ctx context.Context, m schema.Mutation)
 func (gsr *getSchemaResolver) Resolve(ctx context.Context, q schema.Query) *resolve.Resolved {
 	var data map[string]interface{}
 
-	gsr.admin.mux.RLock()
-	defer gsr.admin.mux.RUnlock()
-
 	ns, err := x.ExtractNamespace(ctx)
 	if err != nil {
 		return resolve.EmptyResult(q, err)
 	}
 
-	cs, _ := gsr.admin.gqlSchemas.GetCurrent(ns)
+	// unlike the default schema, a named schema isn't loaded for the /admin requests
+	name, _ := q.ArgValue("name").(string)
+	if name != "" {
+		err := gsr.admin.lazyLoadSchema(ns, name)
+		if err != nil && !errors.Is(err, errNoNamedGQLSchema) {
+			return resolve.EmptyResult(q, err)
+		}
+	}
+
+	gsr.admin.mux.RLock()
+	defer gsr.admin.mux.RUnlock()
+
+	cs, _ := gsr.admin.gqlSchemas.GetNamed(ns, name)
 	if cs == nil || cs.ID == "" {
 		data = map[string]interface{}{q.Name(): nil}
 	} else {
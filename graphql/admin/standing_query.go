@@ -0,0 +1,112 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+type addStandingQueryInput struct {
+	Query           string
+	Webhook         string
+	RefreshInterval int
+}
+
+type deleteStandingQueryInput struct {
+	Id int
+}
+
+func resolveAddStandingQuery(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input addStandingQueryInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	id, err := (&edgraph.Server{}).AddStandingQuery(ctx, &edgraph.StandingQueryRequest{
+		Query:           input.Query,
+		Webhook:         input.Webhook,
+		RefreshInterval: time.Duration(input.RefreshInterval) * time.Second,
+	})
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"id": json.Number(strconv.FormatUint(id, 10)),
+			"message": fmt.Sprintf("Added the standing query %d, streamed from "+
+				"/admin/standing?id=%d.", id, id),
+		}},
+		nil,
+	), true
+}
+
+func resolveDeleteStandingQuery(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input deleteStandingQueryInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	deleted, err := (&edgraph.Server{}).DeleteStandingQuery(ctx, uint64(input.Id))
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	msg := fmt.Sprintf("Deleted the standing query %d.", input.Id)
+	if !deleted {
+		msg = fmt.Sprintf("The standing query %d doesn't exist.", input.Id)
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"id":      json.Number(strconv.Itoa(input.Id)),
+			"message": msg,
+		}},
+		nil,
+	), true
+}
+
+func resolveStandingQueries(ctx context.Context, q schema.Query) *resolve.Resolved {
+	queries, err := (&edgraph.Server{}).StandingQueries(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	results := make([]interface{}, 0, len(queries))
+	for _, sq := range queries {
+		preds := make([]interface{}, 0, len(sq.Predicates))
+		for _, pred := range sq.Predicates {
+			preds = append(preds, pred)
+		}
+		res := map[string]interface{}{
+			"id":              json.Number(strconv.FormatUint(sq.Id, 10)),
+			"query":           sq.Query,
+			"predicates":      preds,
+			"refreshInterval": json.Number(strconv.Itoa(int(sq.RefreshInterval / time.Second))),
+			"matched":         json.Number(strconv.Itoa(sq.Matched)),
+			"seq":             json.Number(strconv.FormatUint(sq.Seq, 10)),
+			"subscribers":     json.Number(strconv.Itoa(sq.Subscribers)),
+			"refreshedAt":     sq.RefreshedAt.Format(time.RFC3339),
+		}
+		if sq.Webhook != "" {
+			res["webhook"] = sq.Webhook
+		}
+		if sq.Error != "" {
+			res["error"] = sq.Error
+		}
+		results = append(results, res)
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
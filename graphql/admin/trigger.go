@@ -0,0 +1,88 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+type deleteTriggerInput struct {
+	Name string
+}
+
+func resolveAddTrigger(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input edgraph.Trigger
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	if err := (&edgraph.Server{}).AddTrigger(ctx, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"name":    input.Name,
+			"message": fmt.Sprintf("Added the trigger %s.", input.Name),
+		}},
+		nil,
+	), true
+}
+
+func resolveDeleteTrigger(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input deleteTriggerInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	deleted, err := (&edgraph.Server{}).DeleteTrigger(ctx, input.Name)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	msg := fmt.Sprintf("Deleted the trigger %s.", input.Name)
+	if !deleted {
+		msg = fmt.Sprintf("The trigger %s doesn't exist.", input.Name)
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"name":    input.Name,
+			"message": msg,
+		}},
+		nil,
+	), true
+}
+
+func resolveTriggers(ctx context.Context, q schema.Query) *resolve.Resolved {
+	triggers, err := (&edgraph.Server{}).Triggers(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	results := make([]interface{}, 0, len(triggers))
+	for _, t := range triggers {
+		res := map[string]interface{}{
+			"name":  t.Name,
+			"query": t.Query,
+			"async": t.Async,
+		}
+		for field, value := range map[string]string{"predicate": t.Predicate, "type": t.Type,
+			"setNquads": t.SetNquads, "delNquads": t.DelNquads, "cond": t.Cond} {
+			if value != "" {
+				res[field] = value
+			}
+		}
+		results = append(results, res)
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
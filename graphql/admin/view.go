@@ -0,0 +1,117 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+type addViewInput struct {
+	Name            string
+	Query           string
+	RefreshInterval int
+}
+
+type deleteViewInput struct {
+	Name string
+}
+
+func resolveAddView(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input addViewInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	err := (&edgraph.Server{}).AddView(ctx, &edgraph.ViewRequest{
+		Name:            input.Name,
+		Query:           input.Query,
+		RefreshInterval: time.Duration(input.RefreshInterval) * time.Second,
+	})
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"name": input.Name,
+			"message": fmt.Sprintf("Added the view %s, read with dgraph.view.%s.", input.Name,
+				input.Name),
+		}},
+		nil,
+	), true
+}
+
+func resolveDeleteView(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input deleteViewInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	deleted, err := (&edgraph.Server{}).DeleteView(ctx, input.Name)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	msg := fmt.Sprintf("Deleted the view %s.", input.Name)
+	if !deleted {
+		msg = fmt.Sprintf("The view %s doesn't exist.", input.Name)
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"name":    input.Name,
+			"message": msg,
+		}},
+		nil,
+	), true
+}
+
+func resolveViews(ctx context.Context, q schema.Query) *resolve.Resolved {
+	views, err := (&edgraph.Server{}).Views(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	results := make([]interface{}, 0, len(views))
+	for _, v := range views {
+		preds := make([]interface{}, 0, len(v.Predicates))
+		for _, pred := range v.Predicates {
+			preds = append(preds, pred)
+		}
+		res := map[string]interface{}{
+			"name":            v.Name,
+			"query":           v.Query,
+			"predicates":      preds,
+			"refreshInterval": json.Number(strconv.Itoa(int(v.RefreshInterval / time.Second))),
+			"nodes":           json.Number(strconv.Itoa(v.Nodes)),
+			"stale":           v.Stale,
+			"refreshedAt":     v.RefreshedAt.Format(time.RFC3339),
+		}
+		if v.Error != "" {
+			res["error"] = v.Error
+		}
+		results = append(results, res)
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
+
+func getViewInput(m schema.Mutation, input interface{}) error {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return schema.GQLWrapf(err, "couldn't get input argument")
+	}
+	return schema.GQLWrapf(json.Unmarshal(inputByts, input), "couldn't get input argument")
+}
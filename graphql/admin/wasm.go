@@ -0,0 +1,96 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
+	"github.com/hypermodeinc/dgraph/v25/graphql/resolve"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+type uploadWasmModuleInput struct {
+	Name string
+	Code string
+}
+
+type deleteWasmModuleInput struct {
+	Name string
+}
+
+func resolveUploadWasmModule(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input uploadWasmModuleInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	code, err := base64.StdEncoding.DecodeString(input.Code)
+	if err != nil {
+		return resolve.EmptyResult(m, errors.Wrap(err, "the code should be base64 encoded")), false
+	}
+	if err := (&edgraph.Server{}).AddWasmModule(ctx, input.Name, code); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"name":    input.Name,
+			"message": fmt.Sprintf("Uploaded the wasm module %s.", input.Name),
+		}},
+		nil,
+	), true
+}
+
+func resolveDeleteWasmModule(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	var input deleteWasmModuleInput
+	if err := getViewInput(m, &input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	deleted, err := (&edgraph.Server{}).DeleteWasmModule(ctx, input.Name)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	msg := fmt.Sprintf("Deleted the wasm module %s.", input.Name)
+	if !deleted {
+		msg = fmt.Sprintf("The wasm module %s doesn't exist.", input.Name)
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"name":    input.Name,
+			"message": msg,
+		}},
+		nil,
+	), true
+}
+
+func resolveWasmModules(ctx context.Context, q schema.Query) *resolve.Resolved {
+	modules, err := (&edgraph.Server{}).WasmModules(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	results := make([]interface{}, 0, len(modules))
+	for _, module := range modules {
+		exports := make([]interface{}, 0, len(module.Exports))
+		for _, export := range module.Exports {
+			exports = append(exports, export)
+		}
+		results = append(results, map[string]interface{}{
+			"name":    module.Name,
+			"exports": exports,
+		})
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): results},
+		nil,
+	)
+}
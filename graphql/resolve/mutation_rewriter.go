@@ -205,8 +205,11 @@ func NewXidMetadata() *xidMetadata {
 //     b. newXidObj has some values other than xid and isn't equal to existingXidObject
 //
 // It is used in places where we don't want to allow duplicates.
+//
+// keyLen is the number of fields identifying the node: 1 for an xid, and the number of fields of
+// the key for a composite id.
 func (xidMetadata *xidMetadata) isDuplicateXid(atTopLevel bool, xidVar string,
-	newXidObj map[string]interface{}, srcField schema.FieldDefinition) bool {
+	newXidObj map[string]interface{}, srcField schema.FieldDefinition, keyLen int) bool {
 	if atTopLevel && xidMetadata.seenAtTopLevel[xidVar] {
 		return true
 	}
@@ -222,7 +225,7 @@ func (xidMetadata *xidMetadata) isDuplicateXid(atTopLevel bool, xidVar string,
 	// and are not equal.
 	// XID should be defined with all its values at one of the places and references with its
 	// XID from other places.
-	if len(newXidObj) > 1 && len(xidMetadata.variableObjMap[xidVar]) > 1 &&
+	if inputLen(newXidObj) > keyLen && inputLen(xidMetadata.variableObjMap[xidVar]) > keyLen &&
 		!reflect.DeepEqual(xidMetadata.variableObjMap[xidVar], newXidObj) {
 		return true
 	}
@@ -230,6 +233,14 @@ func (xidMetadata *xidMetadata) isDuplicateXid(atTopLevel bool, xidVar string,
 	return false
 }
 
+// inputLen returns the number of fields given by the input object, connectOrCreate excepted.
+func inputLen(obj map[string]interface{}) int {
+	if _, ok := obj[schema.ConnectOrCreate]; ok {
+		return len(obj) - 1
+	}
+	return len(obj)
+}
+
 // RewriteQueries takes a GraphQL schema.Mutation add and creates queries to find out if
 // referenced nodes by XID and UID exist or not.
 // m must have a single argument called 'input' that carries the mutation data.
@@ -257,9 +268,10 @@ func (xidMetadata *xidMetadata) isDuplicateXid(atTopLevel bool, xidVar string,
 //
 // This query will be executed and depending on the result it would be decided whether
 // to create a new country as part of this mutation or link it to an existing country.
-// If it is found out that there is an existing country, no modifications are made to
-// the country's attributes and its children. Mutations of the country's children are
-// simply ignored.
+// If it is found out that there is an existing country, it is linked to the author and no
+// modifications are made to the country's attributes and its children. Mutations of the
+// country's children are simply ignored. That is, unless the country is given with
+// connectOrCreate: true, in which case the existing country is updated with them.
 // If it is found out that the Person with id 0x123 does not exist, the corresponding
 // mutation will fail.
 func (arw *AddRewriter) RewriteQueries(
@@ -979,6 +991,7 @@ func RewriteUpsertQueryFromMutation(
 	// If it is set to empty, this is either a delete or update mutation.
 	// In that case, we extract the IDs on which to apply this mutation using
 	// extractMutationFilter.
+	var relationVars []*dql.GraphQuery
 	if nodeID == "" {
 		filter := extractMutationFilter(m)
 		if ids := idFilter(filter, m.MutatedType().IDField()); ids != nil {
@@ -987,6 +1000,7 @@ func RewriteUpsertQueryFromMutation(
 			addTypeFunc(dgQuery[0], m.MutatedType().DgraphName())
 		}
 
+		filter, relationVars = rewriteRelationFilters(m.MutatedType(), filter, authRw.varGen)
 		_ = addFilter(dgQuery[0], m.MutatedType(), filter)
 	} else {
 		// It means this is called from upsert with Add mutation.
@@ -1004,6 +1018,7 @@ func RewriteUpsertQueryFromMutation(
 		addTypeFilter(dgQuery[0], m.MutatedType())
 	}
 	dgQuery = authRw.addAuthQueries(m.MutatedType(), dgQuery, rbac)
+	dgQuery = append(dgQuery, relationVars...)
 
 	return dgQuery
 }
@@ -1255,6 +1270,79 @@ func checkXIDExistsQuery(xidVariable, xidString, xidPredicate string, typ schema
 	return qry
 }
 
+// checkCompositeIDExistsQuery returns the query of the node of typ whose composite id has the
+// values vals.
+func checkCompositeIDExistsQuery(variable string, typ schema.Type,
+	keyFields []schema.FieldDefinition, vals []string) *dql.GraphQuery {
+	eq := func(i int) *dql.Function {
+		return &dql.Function{
+			Name: "eq",
+			Args: []dql.Arg{
+				{Value: typ.DgraphPredicate(keyFields[i].Name())},
+				{Value: maybeQuoteArg("eq", vals[i])},
+			},
+		}
+	}
+	filter := &dql.FilterTree{Op: "and"}
+	for i := 1; i < len(keyFields); i++ {
+		filter.Child = append(filter.Child, &dql.FilterTree{Func: eq(i)})
+	}
+	return &dql.GraphQuery{
+		Attr:     variable,
+		Func:     eq(0),
+		Filter:   filter,
+		Children: []*dql.GraphQuery{{Attr: "uid"}, {Attr: "dgraph.type"}},
+	}
+}
+
+// compositeIDValues returns the fields of the composite id of typ and their values in obj. The
+// values are nil if obj has none of them, and it's an error if it only has some of them.
+func compositeIDValues(typ schema.Type,
+	obj map[string]interface{}) ([]schema.FieldDefinition, []string, error) {
+	keyFields := typ.CompositeIDFields()
+	var vals []string
+	for _, fld := range keyFields {
+		val, ok := obj[fld.Name()]
+		if !ok || val == nil {
+			continue
+		}
+		str, err := extractVal(val, fld.Name(), fld.Type().Name())
+		if err != nil {
+			return nil, nil, err
+		}
+		vals = append(vals, str)
+	}
+	if len(vals) != 0 && len(vals) != len(keyFields) {
+		return nil, nil, errors.Errorf("fields %s must be given together, as they are the "+
+			"composite id of type %s", compositeIDNames(keyFields), typ.Name())
+	}
+	return keyFields, vals, nil
+}
+
+// compositeIDVariable returns the variable of the node of typ whose composite id has the values
+// vals. The first field of the key is flagged like the XIDs inherited from an interface, so that
+// the variable isn't the one of an XID with the same value.
+func compositeIDVariable(typ schema.Type, varGen *VariableGenerator,
+	keyFields []schema.FieldDefinition, vals []string) string {
+	quoted := make([]string, len(vals))
+	for i, val := range vals {
+		quoted[i] = strconv.Quote(val)
+	}
+	return varGen.Next(typ, "Key."+keyFields[0].Name(), strings.Join(quoted, ","), false)
+}
+
+func compositeIDNames(keyFields []schema.FieldDefinition) string {
+	names := make([]string, len(keyFields))
+	for i, fld := range keyFields {
+		names[i] = fld.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+func formatCompositeID(vals []string) string {
+	return "(" + strings.Join(vals, ", ") + ")"
+}
+
 func checkUIDExistsQuery(val interface{}, variable string) (*dql.GraphQuery, error) {
 	uid, err := asUID(val)
 	if err != nil {
@@ -1350,6 +1438,9 @@ func rewriteObject(
 	// 2. We use an existing node and link it to the parent.
 	//    We may have to add an inverse edge in this case. But generally, no other amendments
 	//    to the node need to be done.
+	// 3. We use an existing node found by its XIDs or composite id below the top level, which is
+	//    given with connectOrCreate: true. It is linked to the parent and updated with the rest
+	//    of obj.
 	// Note that as similar traversal of input tree was carried with getExistenceQueries, we
 	// don't have to report the same errors.
 
@@ -1357,6 +1448,8 @@ func rewriteObject(
 	atTopLevel := srcField == nil
 	var retErrors []error
 	variable := ""
+	// nestedUID is the UID of the existing node updated as it's given with connectOrCreate.
+	nestedUID := ""
 
 	id := typ.IDField()
 	if id != nil {
@@ -1496,11 +1589,18 @@ func rewriteObject(
 							return nil, "", retErrors
 						}
 						// As we are not at top level, we return the XID reference. We don't update this node
-						// further.
-						if typUidExist {
+						// further, unless it's given with connectOrCreate and more than its ids.
+						if typUidExist && !connectOrCreate(typ, obj, mutationType) {
 							return asIDReference(ctx, typUid, srcField, srcUID, varGen,
 								mutationType == UpdateWithRemove), upsertVar, nil
 						}
+						if typUidExist {
+							if err := checkConnectOrCreate(typ); err != nil {
+								return nil, upsertVar, append(retErrors, err)
+							}
+							nestedUID = typUid
+							break
+						}
 						// returns error if xid is present in some other implementing type
 						retErrors = append(retErrors, xidErrorForInterfaceType(typ, xidString, xid,
 							interfaceTyp.Name()))
@@ -1512,7 +1612,7 @@ func rewriteObject(
 			}
 		}
 
-		if len(xidVariables) != 0 {
+		if len(xidVariables) != 0 && nestedUID == "" {
 			exclude := ""
 			if srcField != nil {
 				invField := srcField.Inverse()
@@ -1550,7 +1650,7 @@ func rewriteObject(
 			}
 		}
 
-		if upsertVar == "" {
+		if upsertVar == "" && nestedUID == "" {
 			for _, xid := range xids {
 				xidType := xid.Type().String()
 				if xidVal, ok := obj[xid.Name()]; ok && xidVal != nil {
@@ -1578,6 +1678,50 @@ func rewriteObject(
 		}
 	}
 
+	// The composite id is handled like an XID, unless the node is already upserted by an XID.
+	keyFields, keyVals, _ := compositeIDValues(typ, obj)
+	if len(keyVals) != 0 && upsertVar == "" && nestedUID == "" {
+		keyVar := compositeIDVariable(typ, varGen, keyFields, keyVals)
+		if uid, ok := idExistence[keyVar]; ok {
+			switch {
+			case !atTopLevel && !connectOrCreate(typ, obj, mutationType):
+				return asIDReference(ctx, uid, srcField, srcUID, varGen,
+					mutationType == UpdateWithRemove), upsertVar, nil
+			case !atTopLevel:
+				if err := checkConnectOrCreate(typ); err != nil {
+					return nil, upsertVar, append(retErrors, err)
+				}
+				nestedUID = uid
+			case mutationType == AddWithUpsert:
+				upsertVar = keyVar
+				srcUID = fmt.Sprintf("uid(%s)", keyVar)
+			default:
+				var err error
+				if queryAuthSelector(typ) == nil {
+					err = x.GqlErrorf("id %s already exists for fields %s inside type %s",
+						formatCompositeID(keyVals), compositeIDNames(keyFields), typ.Name())
+				} else {
+					// This error will only be reported in debug mode.
+					err = x.GqlErrorf("GraphQL debug: id %s already exists for fields %s"+
+						" inside type %s", formatCompositeID(keyVals), compositeIDNames(keyFields),
+						typ.Name())
+				}
+				return nil, upsertVar, append(retErrors, err)
+			}
+		} else {
+			if variable == "" {
+				variable = keyVar
+				// Like for the XIDs, the first definition of the node is used.
+				obj = xidMetadata.variableObjMap[keyVar]
+				if err := typ.EnsureNonNulls(obj, ""); err != nil &&
+					!(mutationType == UpdateWithSet && atTopLevel) {
+					return nil, upsertVar, append(retErrors, err)
+				}
+			}
+			idExistence[keyVar] = fmt.Sprintf("_:%s", variable)
+		}
+	}
+
 	action := defaultDirectiveUpdateAct
 
 	// This is not an XID reference. This is also not a UID reference.
@@ -1612,6 +1756,10 @@ func rewriteObject(
 		// equal to uid(variable) in this case. Eg. uid(State1)
 		newObj["uid"] = srcUID
 		myUID = srcUID
+	} else if nestedUID != "" {
+		// The existing node given with connectOrCreate is updated like a top level one.
+		newObj["uid"] = nestedUID
+		myUID = nestedUID
 	} else if mutationType == UpdateWithRemove {
 		// It's a remove. As remove can only be part of Update Mutation. It can
 		// be inferred that this is an Update Mutation.
@@ -1650,6 +1798,10 @@ func rewriteObject(
 	frag := newFragment(newObj)
 	// TODO(Rajas)L Check if newNodes only needs to be set in case new nodes have been added.
 	frag.newNodes[variable] = typ
+	if nestedUID != "" {
+		// Like for a reference, the old edges of the inverse nodes are deleted.
+		addAdditionalDeletes(ctx, frag, varGen, srcField, srcUID, nestedUID)
+	}
 
 	updateFromChildren := func(parentFragment, childFragment *mutationFragment) {
 		copyTypeMap(childFragment.newNodes, parentFragment.newNodes)
@@ -1665,6 +1817,9 @@ func rewriteObject(
 	// Iterate on fields and call the same function recursively.
 	var fields []string
 	for field := range obj {
+		if field == schema.ConnectOrCreate {
+			continue
+		}
 		fields = append(fields, field)
 	}
 	// Fields are sorted to ensure that they are traversed in specific order each time. Golang maps
@@ -1789,6 +1944,46 @@ func rewriteObject(
 	return frag, upsertVar, retErrors
 }
 
+// connectOrCreate tells whether the existing node of typ given by obj below the top level is
+// updated with obj rather than only linked: obj is given with connectOrCreate: true and some fields
+// other than the XIDs and the composite id of typ.
+func connectOrCreate(typ schema.Type, obj map[string]interface{},
+	mutationType MutationType) bool {
+
+	if mutationType == UpdateWithRemove || obj[schema.ConnectOrCreate] != true {
+		return false
+	}
+	ids := map[string]bool{schema.ConnectOrCreate: true}
+	for _, xid := range typ.XIDFields() {
+		ids[xid.Name()] = true
+	}
+	for _, fld := range typ.CompositeIDFields() {
+		ids[fld.Name()] = true
+	}
+	for name := range obj {
+		if !ids[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConnectOrCreate returns an error if the existing nodes of the type can't be updated by
+// connectOrCreate. Their update rules are only checked at the top level of update mutations and
+// upserts, so the types having some are not updated below the top level.
+func checkConnectOrCreate(typ schema.Type) error {
+	if updateAuthSelector(typ) == nil {
+		return nil
+	}
+	if queryAuthSelector(typ) == nil {
+		return x.GqlErrorf("connectOrCreate isn't allowed for type %s as it has update rules",
+			typ.Name())
+	}
+	// This error will only be reported in debug mode.
+	return x.GqlErrorf("GraphQL debug: connectOrCreate isn't allowed for type %s as it has"+
+		" update rules", typ.Name())
+}
+
 func xidErrorForInterfaceType(typ schema.Type, xidString string, xid schema.FieldDefinition,
 	interfaceName string) error {
 
@@ -1884,7 +2079,7 @@ func existenceQueries(
 					// if we already encountered an object with same xid earlier, and this object is
 					// considered a duplicate of the existing object, then return error.
 
-					if xidMetadata.isDuplicateXid(atTopLevel, variable, obj, srcField) {
+					if xidMetadata.isDuplicateXid(atTopLevel, variable, obj, srcField, 1) {
 						// TODO(Jatin): Add this error for inherited @id field with interface arg.
 						//  Currently we don't return this error for the nested case when
 						//  at both root and nested level we have same value of @id fields
@@ -1903,7 +2098,7 @@ func existenceQueries(
 					oldObj := xidMetadata.variableObjMap[variable]
 					// TODO(Jatin): This condition also needs to change in accordance with multiple xids.
 					//  Also consider the case when @id fields can be nullable.
-					if len(oldObj) == 1 && len(obj) > 1 {
+					if inputLen(oldObj) == 1 && inputLen(obj) > 1 {
 						// Continue execution to perform dfs in this case. There may be more nodes
 						// in the subtree of this node.
 						xidMetadata.variableObjMap[variable] = obj
@@ -1942,9 +2137,38 @@ func existenceQueries(
 		}
 	}
 
+	keyFields, keyVals, err := compositeIDValues(typ, obj)
+	if err != nil {
+		return nil, nil, append(retErrors, err)
+	}
+	if len(keyVals) != 0 {
+		variable := compositeIDVariable(typ, varGen, keyFields, keyVals)
+		// The same cases as the ones of the XIDs above.
+		if oldObj := xidMetadata.variableObjMap[variable]; oldObj != nil {
+			if xidMetadata.isDuplicateXid(atTopLevel, variable, obj, srcField, len(keyFields)) {
+				err := errors.Errorf("duplicate composite ID found: %s",
+					formatCompositeID(keyVals))
+				return nil, nil, append(retErrors, err)
+			}
+			if inputLen(oldObj) == len(keyFields) && inputLen(obj) > len(keyFields) {
+				xidMetadata.variableObjMap[variable] = obj
+			} else {
+				return ret, retTypes, retErrors
+			}
+		} else {
+			xidMetadata.variableObjMap[variable] = obj
+			xidMetadata.seenAtTopLevel[variable] = atTopLevel
+			ret = append(ret, checkCompositeIDExistsQuery(variable, typ, keyFields, keyVals))
+			retTypes = append(retTypes, typ.DgraphName())
+		}
+	}
+
 	// Iterate on fields and call the same function recursively.
 	var fields []string
 	for field := range obj {
+		if field == schema.ConnectOrCreate {
+			continue
+		}
 		fields = append(fields, field)
 	}
 	// Fields are sorted to ensure that they are traversed in specific order each time. Golang maps
@@ -0,0 +1,122 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package resolve
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// QueryPredicates returns the Dgraph predicates read by the query operation of the request, as
+// rewritten into DQL with the auth rules of the request. It returns false if the operation isn't
+// a query, or if some of its results come from elsewhere than those predicates, like the custom
+// DQL queries and the @custom and @lambda fields, as the response can't be told to be unchanged
+// then.
+func (r *RequestResolver) QueryPredicates(ctx context.Context,
+	gqlReq *schema.Request) ([]string, bool) {
+
+	if r == nil || r.schema == nil {
+		return nil, false
+	}
+	ctx, err := r.schema.Meta().AuthMeta().AttachAuthorizationJwt(ctx, gqlReq.Header)
+	if err != nil {
+		return nil, false
+	}
+	ctx = x.AttachJWTNamespace(ctx)
+	op, err := r.schema.Operation(gqlReq)
+	if err != nil || !op.IsQuery() {
+		return nil, false
+	}
+
+	preds := make(map[string]struct{})
+	for _, q := range op.Queries() {
+		switch q.QueryType() {
+		case schema.SchemaQuery:
+			// The introspection only depends on the GraphQL schema.
+			continue
+		case schema.GetQuery, schema.FilterQuery, schema.AggregateQuery,
+			schema.SimilarByIdQuery, schema.SimilarByEmbeddingQuery:
+		default:
+			return nil, false
+		}
+		if q.IsCustomHTTP() || q.HasLambdaDirective() || q.HasCustomHTTPChild() {
+			return nil, false
+		}
+		dqls, err := NewQueryRewriter().Rewrite(ctx, q)
+		if err != nil {
+			return nil, false
+		}
+		for _, dq := range dqls {
+			addQueryPredicates(dq, preds)
+		}
+	}
+
+	res := make([]string, 0, len(preds))
+	for pred := range preds {
+		res = append(res, pred)
+	}
+	sort.Strings(res)
+	return res, true
+}
+
+// addQueryPredicates adds the predicates read by the block q, whose own attribute is its name at
+// the root, and those of the blocks below it.
+func addQueryPredicates(q *dql.GraphQuery, preds map[string]struct{}) {
+	if q == nil {
+		return
+	}
+	addFuncPredicates(q.Func, preds)
+	for _, order := range q.Order {
+		addPredicate(order.Attr, preds)
+	}
+	addFilterPredicates(q.Filter, preds)
+	for _, child := range q.Children {
+		addPredicate(child.Attr, preds)
+		addQueryPredicates(child, preds)
+	}
+}
+
+func addFilterPredicates(f *dql.FilterTree, preds map[string]struct{}) {
+	if f == nil {
+		return
+	}
+	addFuncPredicates(f.Func, preds)
+	for _, child := range f.Child {
+		addFilterPredicates(child, preds)
+	}
+}
+
+func addFuncPredicates(fn *dql.Function, preds map[string]struct{}) {
+	if fn == nil {
+		return
+	}
+	if fn.Name == "type" {
+		preds["dgraph.type"] = struct{}{}
+	}
+	addPredicate(fn.Attr, preds)
+}
+
+// addPredicate adds the predicate of the attribute of a query or function. The aggregations like
+// count(Post.title) read the predicate they aggregate, while the values of the variables, the
+// uids and the math expressions don't read any.
+func addPredicate(attr string, preds map[string]struct{}) {
+	for _, agg := range []string{"count(", "min(", "max(", "sum(", "avg("} {
+		if strings.HasPrefix(attr, agg) && strings.HasSuffix(attr, ")") {
+			attr = attr[len(agg) : len(attr)-1]
+			break
+		}
+	}
+	attr = strings.TrimPrefix(attr, "~")
+	if attr == "" || attr == "uid" || strings.ContainsAny(attr, "()") {
+		return
+	}
+	preds[attr] = struct{}{}
+}
@@ -0,0 +1,71 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package resolve
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+	"github.com/hypermodeinc/dgraph/v25/graphql/test"
+)
+
+func TestQueryPredicates(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromFile(t, "schema.graphql")
+	resolver := New(gqlSchema, nil)
+
+	tcases := []struct {
+		name  string
+		query string
+		preds []string
+		ok    bool
+	}{
+		{
+			name: "query with filter, order and nested fields",
+			query: `query { queryAuthor(filter: {name: {eq: "A"}}, order: {asc: dob}) {
+				name posts { title } postsAggregate { count } } }`,
+			preds: []string{"Author.dob", "Author.name", "Author.posts", "Post.title",
+				"dgraph.type"},
+			ok: true,
+		},
+		{
+			name:  "get query",
+			query: `query { getCountry(id: "0x1") { name } }`,
+			preds: []string{"Country.name", "dgraph.type"},
+			ok:    true,
+		},
+		{
+			name:  "introspection",
+			query: `query { __type(name: "Author") { name } }`,
+			preds: []string{},
+			ok:    true,
+		},
+		{
+			name:  "custom field",
+			query: `query { queryComment { title content } }`,
+		},
+		{
+			name:  "custom query",
+			query: `query { myFavoriteMovies(id: "1", name: "A", num: 1) { name } }`,
+		},
+		{
+			name:  "mutation",
+			query: `mutation { deleteCountry(filter: {}) { msg } }`,
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			preds, ok := resolver.QueryPredicates(context.Background(),
+				&schema.Request{Query: tcase.query})
+			require.Equal(t, tcase.ok, ok)
+			if tcase.ok {
+				require.Equal(t, tcase.preds, preds)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	dgoapi "github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/edgraph"
 	"github.com/hypermodeinc/dgraph/v25/graphql/dgraph"
 	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
 	"github.com/hypermodeinc/dgraph/v25/x"
@@ -225,7 +226,12 @@ func (qr *customDQLQueryResolver) rewriteAndExecute(ctx context.Context,
 }
 
 func resolveIntrospection(ctx context.Context, q schema.Query) *Resolved {
-	data, err := schema.Introspect(q)
+	// With ACL, the guardians of the namespace see the types and fields hidden from the others.
+	full := true
+	if q.Operation().Schema().Meta().IntrospectionRestricted() {
+		full = x.WorkerConfig.AclEnabled && edgraph.AuthorizeGuardians(ctx) == nil
+	}
+	data, err := schema.Introspect(q, full)
 	return &Resolved{
 		Data:  data,
 		Field: q,
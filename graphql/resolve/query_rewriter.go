@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -246,9 +247,11 @@ func aggregateQuery(query schema.Query, authRw *authRewriter) []*dql.GraphQuery
 
 	// Add filter
 	filter, _ := query.ArgValue("filter").(map[string]interface{})
+	filter, relationVars := rewriteRelationFilters(mainType, filter, authRw.varGen)
 	_ = addFilter(dgQuery[0], mainType, filter)
 
 	dgQuery = authRw.addAuthQueries(mainType, dgQuery, rbac)
+	dgQuery = append(dgQuery, relationVars...)
 
 	// mainQuery is the query with Attr: query.Name()
 	// It is the first query in dgQuery list.
@@ -464,7 +467,7 @@ func rewriteAsQueryByIds(
 		addUIDFunc(dgQuery[0], intersection(ids, uids))
 	}
 
-	addArgumentsToField(dgQuery[0], field)
+	relationVars := addArgumentsToField(dgQuery[0], field, authRw.varGen)
 
 	// The function getQueryByIds is called for passwordQuery or fetching query result types
 	// after making a mutation. In both cases, we want the selectionSet to use the `query` auth
@@ -480,6 +483,7 @@ func rewriteAsQueryByIds(
 	addCascadeDirective(dgQuery[0], field)
 
 	dgQuery = authRw.addAuthQueries(field.Type(), dgQuery, rbac)
+	dgQuery = append(dgQuery, relationVars...)
 
 	if len(selectionAuth) > 0 {
 		dgQuery = append(dgQuery, selectionAuth...)
@@ -489,12 +493,16 @@ func rewriteAsQueryByIds(
 }
 
 // addArgumentsToField adds various different arguments to a field, such as
-// filter, order and pagination.
-func addArgumentsToField(dgQuery *dql.GraphQuery, field schema.Field) {
+// filter, order and pagination. It returns the var blocks needed by the relationship filters and
+// the ordering by related fields.
+func addArgumentsToField(dgQuery *dql.GraphQuery, field schema.Field,
+	varGen *VariableGenerator) []*dql.GraphQuery {
 	filter, _ := field.ArgValue("filter").(map[string]interface{})
+	filter, relationVars := rewriteRelationFilters(field.Type(), filter, varGen)
 	_ = addFilter(dgQuery, field.Type(), filter)
-	addOrder(dgQuery, field)
+	relationVars = append(relationVars, addOrder(dgQuery, field, varGen)...)
 	addPagination(dgQuery, field)
+	return relationVars
 }
 
 func addTopLevelTypeFilter(query *dql.GraphQuery, field schema.Field) {
@@ -763,9 +771,10 @@ func rewriteAsSimilarByIdQuery(
 		},
 		Order: []*pb.Order{{Attr: "val(distance)", Desc: false}},
 	}
-	addArgumentsToField(sortQuery, query)
+	relationVars := addArgumentsToField(sortQuery, query, auth.varGen)
 
 	dgQuery = append(dgQuery, aggQuery, similarQuery, sortQuery)
+	dgQuery = append(dgQuery, relationVars...)
 	return dgQuery
 }
 
@@ -946,7 +955,7 @@ func rewriteAsQuery(field schema.Field, authRw *authRewriter) []*dql.GraphQuery
 		return dgQuery
 	}
 
-	addArgumentsToField(dgQuery[0], field)
+	relationVars := addArgumentsToField(dgQuery[0], field, authRw.varGen)
 	selectionAuth := addSelectionSetFrom(dgQuery[0], field, authRw)
 	// we don't need to query uid for auth queries, as they always have at least one field in their
 	// selection set.
@@ -956,6 +965,7 @@ func rewriteAsQuery(field schema.Field, authRw *authRewriter) []*dql.GraphQuery
 	addCascadeDirective(dgQuery[0], field)
 
 	dgQuery = authRw.addAuthQueries(field.Type(), dgQuery, rbac)
+	dgQuery = append(dgQuery, relationVars...)
 
 	if len(selectionAuth) > 0 {
 		return append(dgQuery, selectionAuth...)
@@ -1317,7 +1327,8 @@ func (authRw *authRewriter) rewriteRuleNode(
 			r1[0].Cascade = append(r1[0].Cascade, "__all__")
 		}
 
-		return []*dql.GraphQuery{r1[0]}, &dql.FilterTree{
+		// The other queries of an auth query are the var blocks of its relationship filters.
+		return r1, &dql.FilterTree{
 			Func: &dql.Function{
 				Name: "uid",
 				Args: []dql.Arg{{Value: varName}},
@@ -1454,6 +1465,7 @@ func buildAggregateFields(
 	// Filter for aggregate Fields. This is added to all count aggregate fields
 	// and mainField
 	fieldFilter, _ := f.ArgValue("filter").(map[string]interface{})
+	fieldFilter, relationVars := rewriteRelationFilters(constructedForType, fieldFilter, auth.varGen)
 	_ = addFilter(mainField, constructedForType, fieldFilter)
 
 	// Add type filter in case the Dgraph predicate for which the aggregate
@@ -1586,6 +1598,7 @@ func buildAggregateFields(
 	// not added to them.
 	aggregateChildren = append(aggregateChildren, otherAggregateChildren...)
 	retAuthQueries = append(retAuthQueries, fieldAuth...)
+	retAuthQueries = append(retAuthQueries, relationVars...)
 	return aggregateChildren, retAuthQueries
 }
 
@@ -1669,17 +1682,19 @@ func addSelectionSetFrom(
 		}
 
 		filter, _ := f.ArgValue("filter").(map[string]interface{})
+		filter, relationVars := rewriteRelationFilters(f.Type(), filter, auth.varGen)
 		// if this field has been filtered out by the filter, then don't add it in DQL query
 		if includeField := addFilter(child, f.Type(), filter); !includeField {
 			continue
 		}
+		authQueries = append(authQueries, relationVars...)
 
 		// Add type filter in case the Dgraph predicate is a reverse edge
 		if strings.HasPrefix(f.DgraphPredicate(), "~") {
 			addTypeFilter(child, f.Type())
 		}
 
-		addOrder(child, f)
+		authQueries = append(authQueries, addOrder(child, f, auth.varGen)...)
 		addPagination(child, f)
 		addCascadeDirective(child, f)
 		rbac := auth.evaluateStaticRules(f.Type())
@@ -1795,24 +1810,103 @@ func addSelectionSetFrom(
 	return authQueries
 }
 
-func addOrder(q *dql.GraphQuery, field schema.Field) {
-	orderArg := field.ArgValue("order")
-	order, ok := orderArg.(map[string]interface{})
+// orderKey is a sort key of an order argument, the field of the type or, if relField is set, the
+// field relField of the node linked by the field.
+type orderKey struct {
+	field    string
+	relField string
+	desc     bool
+}
+
+// addOrder adds the sort keys of the order argument of the field to q. They are the predicates of
+// the type, unless some of them are fields of related nodes, like in
+//
+//	order: { asc: title, then: { author: { desc: name } } }
+//
+// in which case they all are value variables, computed by the var block it returns:
+//
+//	var(func: type(Post)) {
+//	  Post_1 as Post.title
+//	  Post.author {
+//	    Author_3 as Author.name
+//	  }
+//	  Post_2 as min(val(Author_3))
+//	}
+//
+// As with any ordering by value variables, the nodes without a value for any of the keys are
+// left out.
+func addOrder(q *dql.GraphQuery, field schema.Field, varGen *VariableGenerator) []*dql.GraphQuery {
+	var keys []orderKey
+	related := false
+	order, ok := field.ArgValue("order").(map[string]interface{})
 	for ok {
-		ascArg := order["asc"]
-		descArg := order["desc"]
-		thenArg := order["then"]
+		if asc, ok := order["asc"].(string); ok {
+			keys = append(keys, orderKey{field: asc})
+		} else if desc, ok := order["desc"].(string); ok {
+			keys = append(keys, orderKey{field: desc, desc: true})
+		}
 
-		if asc, ok := ascArg.(string); ok {
-			q.Order = append(q.Order,
-				&pb.Order{Attr: field.Type().DgraphPredicate(asc)})
-		} else if desc, ok := descArg.(string); ok {
-			q.Order = append(q.Order,
-				&pb.Order{Attr: field.Type().DgraphPredicate(desc), Desc: true})
+		// Get a stable ordering of the related fields, there should be only one of them anyway.
+		var relFields []string
+		for key := range order {
+			if key != "asc" && key != "desc" && key != "then" {
+				relFields = append(relFields, key)
+			}
+		}
+		sort.Strings(relFields)
+		for _, relField := range relFields {
+			relOrder, _ := order[relField].(map[string]interface{})
+			if asc, ok := relOrder["asc"].(string); ok {
+				keys = append(keys, orderKey{field: relField, relField: asc})
+				related = true
+			} else if desc, ok := relOrder["desc"].(string); ok {
+				keys = append(keys, orderKey{field: relField, relField: desc, desc: true})
+				related = true
+			}
 		}
 
-		order, ok = thenArg.(map[string]interface{})
+		order, ok = order["then"].(map[string]interface{})
 	}
+
+	typ := field.Type()
+	if !related {
+		for _, key := range keys {
+			q.Order = append(q.Order, &pb.Order{Attr: typ.DgraphPredicate(key.field), Desc: key.desc})
+		}
+		return nil
+	}
+
+	varBlock := &dql.GraphQuery{
+		Attr: "var",
+		Func: buildTypeFunc(typ.DgraphName()),
+	}
+	relChildren := make(map[string]*dql.GraphQuery)
+	var aggregates []*dql.GraphQuery
+	for _, key := range keys {
+		varName := varGen.Next(typ, "", "", false)
+		q.Order = append(q.Order, &pb.Order{Attr: "val(" + varName + ")", Desc: key.desc})
+		if key.relField == "" {
+			varBlock.Children = append(varBlock.Children,
+				&dql.GraphQuery{Var: varName, Attr: typ.DgraphPredicate(key.field)})
+			continue
+		}
+
+		// The value of a singleton field is brought up to the node with min().
+		relType := typ.Field(key.field).Type()
+		relVar := varGen.Next(relType, "", "", false)
+		child, ok := relChildren[key.field]
+		if !ok {
+			child = &dql.GraphQuery{Attr: typ.DgraphPredicate(key.field)}
+			relChildren[key.field] = child
+			varBlock.Children = append(varBlock.Children, child)
+		}
+		child.Children = append(child.Children,
+			&dql.GraphQuery{Var: relVar, Attr: relType.DgraphPredicate(key.relField)})
+		aggregates = append(aggregates,
+			&dql.GraphQuery{Var: varName, Attr: "min(val(" + relVar + "))"})
+	}
+	varBlock.Children = append(varBlock.Children, aggregates...)
+	return []*dql.GraphQuery{varBlock}
 }
 
 func addPagination(q *dql.GraphQuery, field schema.Field) {
@@ -2041,8 +2135,24 @@ func buildFilter(typ schema.Type, filter map[string]interface{}) *dql.FilterTree
 					}
 					continue
 				}
+				if fn == "isNull" || fn == "isNotNull" {
+					// title: { isNull: true } -> NOT has(Post.title)
+					// title: { isNotNull: true } -> has(Post.title)
+					has := buildHasFilterList(typ, []interface{}{field})[0]
+					if (fn == "isNull") == val.(bool) {
+						has = &dql.FilterTree{Op: "not", Child: []*dql.FilterTree{has}}
+					}
+					ands = append(ands, has)
+					continue
+				}
 				args := []dql.Arg{{Value: typ.DgraphPredicate(field)}}
 				switch fn {
+				case "eqIgnoreCase":
+					// The trigram index of the field serves the case-insensitive equality, as in
+					// title: { eqIgnoreCase: "GraphQL" } -> regexp(Post.title, /^GraphQL$/i)
+					fn = "regexp"
+					args = append(args, dql.Arg{Value: "/^" + strings.ReplaceAll(
+						regexp.QuoteMeta(val.(string)), "/", "\\/") + "$/i"})
 				// in takes List of Scalars as argument, for eg:
 				// code : { in: ["abc", "def", "ghi"] } -> eq(State.code,"abc","def","ghi")
 				case "in":
@@ -2127,6 +2237,17 @@ func buildFilter(typ schema.Type, filter map[string]interface{}) *dql.FilterTree
 						Args: args,
 					},
 				})
+			case []relationFilterVar:
+				// posts: { some: { ... } } -> uid(Author_1), see rewriteRelationFilters
+				for _, rv := range dgFunc {
+					ft := &dql.FilterTree{
+						Func: &dql.Function{Name: "uid", Args: []dql.Arg{{Value: rv.varName}}},
+					}
+					if rv.negate {
+						ft = &dql.FilterTree{Op: "not", Child: []*dql.FilterTree{ft}}
+					}
+					ands = append(ands, ft)
+				}
 			case []interface{}:
 				// has: [comments, text] -> has(comments) AND has(text)
 				// ids: [ 0x123, 0x124]
@@ -2201,6 +2322,114 @@ func buildFilter(typ schema.Type, filter map[string]interface{}) *dql.FilterTree
 	}
 }
 
+// relationFilterVar is a relationship filter rewritten by rewriteRelationFilters, it's satisfied
+// by the nodes in the variable varName, or the ones not in it if negate is set.
+type relationFilterVar struct {
+	varName string
+	negate  bool
+}
+
+// rewriteRelationFilters returns a copy of the filter in which the relationship filters, like
+//
+//	posts: { some: { title: { anyoftext: "graph" } } }
+//
+// are replaced by filters on the uids of the nodes with such related nodes, and the var blocks
+// computing these uids, like
+//
+//	Author_1 as var(func: type(Author)) @cascade {
+//	  Author.posts @filter(anyoftext(Post.title, "graph")) {
+//	    uid
+//	  }
+//	}
+//
+// The filter on a list field has some, every and none, the filter on a singleton field is the
+// filter of the related node.
+func rewriteRelationFilters(
+	typ schema.Type,
+	filter map[string]interface{},
+	varGen *VariableGenerator) (map[string]interface{}, []*dql.GraphQuery) {
+
+	if len(filter) == 0 || typ.IsUnion() {
+		return filter, nil
+	}
+
+	var varBlocks []*dql.GraphQuery
+	rewrite := func(f map[string]interface{}) map[string]interface{} {
+		f, blocks := rewriteRelationFilters(typ, f, varGen)
+		varBlocks = append(varBlocks, blocks...)
+		return f
+	}
+	res := make(map[string]interface{}, len(filter))
+	for key, val := range filter {
+		res[key] = val
+		switch v := val.(type) {
+		case map[string]interface{}:
+			switch key {
+			case "and", "or", "not":
+				res[key] = rewrite(v)
+				continue
+			}
+			fld := typ.Field(key)
+			if fld == nil || fld.Type().IsInbuiltOrEnumType() || fld.Type().IsGeo() {
+				continue
+			}
+			if fld.Type().ListType() == nil {
+				// author: { name: { eq: "Alice" } } is the same as some on a list field.
+				v = map[string]interface{}{"some": v}
+			}
+			var vars []relationFilterVar
+			for _, quantifier := range []string{"some", "every", "none"} {
+				relFilter, ok := v[quantifier].(map[string]interface{})
+				if !ok || (quantifier == "every" && len(relFilter) == 0) {
+					continue
+				}
+				if quantifier == "every" {
+					// Every related node matches when none of them doesn't.
+					relFilter = map[string]interface{}{"not": relFilter}
+				}
+				varName, blocks := relationFilterVarBlocks(typ, fld, relFilter, varGen)
+				varBlocks = append(varBlocks, blocks...)
+				vars = append(vars, relationFilterVar{varName: varName, negate: quantifier != "some"})
+			}
+			res[key] = vars
+		case []interface{}:
+			if key == "and" || key == "or" {
+				fs := make([]interface{}, 0, len(v))
+				for _, obj := range v {
+					f, _ := obj.(map[string]interface{})
+					fs = append(fs, rewrite(f))
+				}
+				res[key] = fs
+			}
+		}
+	}
+	return res, varBlocks
+}
+
+// relationFilterVarBlocks returns the variable of the nodes of typ with a node matching the filter
+// through fld, and the var blocks computing it.
+func relationFilterVarBlocks(
+	typ schema.Type,
+	fld schema.FieldDefinition,
+	filter map[string]interface{},
+	varGen *VariableGenerator) (string, []*dql.GraphQuery) {
+
+	relFilter, varBlocks := rewriteRelationFilters(fld.Type(), filter, varGen)
+	child := &dql.GraphQuery{
+		Attr:     typ.DgraphPredicate(fld.Name()),
+		Children: []*dql.GraphQuery{{Attr: "uid"}},
+	}
+	_ = addFilter(child, fld.Type(), relFilter)
+	varName := varGen.Next(typ, "", "", false)
+	return varName, append(varBlocks, &dql.GraphQuery{
+		Var:      varName,
+		Attr:     "var",
+		Func:     buildTypeFunc(typ.DgraphName()),
+		Cascade:  []string{"__all__"},
+		Children: []*dql.GraphQuery{child},
+	})
+}
+
 func buildHasFilterList(typ schema.Type, fieldsSlice []interface{}) []*dql.FilterTree {
 	var ands []*dql.FilterTree
 	fn := "has"
@@ -499,6 +499,23 @@ func (r *RequestResolver) Resolve(ctx context.Context, gqlReq *schema.Request) (
 		return
 	}
 
+	// The files uploaded with the request are stored once it's known to be a valid mutation, and
+	// the operation is built again from the variables holding their URLs.
+	if hasUploads(gqlReq.Variables) {
+		if !op.IsMutation() {
+			resp.Errors = schema.AsGQLErrors(x.GqlErrorf("Files can only be uploaded with mutations"))
+			return
+		}
+		if err = storeUploads(ctx, gqlReq.Variables); err != nil {
+			resp.Errors = schema.AsGQLErrors(err)
+			return
+		}
+		if op, err = r.schema.Operation(gqlReq); err != nil {
+			resp.Errors = schema.AsGQLErrors(err)
+			return
+		}
+	}
+
 	if glog.V(3) {
 		// don't log the introspection queries they are sent too frequently
 		// by GraphQL dev tools
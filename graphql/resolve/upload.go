@@ -0,0 +1,134 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	minio "github.com/minio/minio-go/v7"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// hasUploads tells whether some files were uploaded for the variables val of a request.
+func hasUploads(val interface{}) bool {
+	switch val := val.(type) {
+	case *schema.Upload:
+		return true
+	case map[string]interface{}:
+		for _, v := range val {
+			if hasUploads(v) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range val {
+			if hasUploads(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// storeUploads stores the files uploaded for the variables of a request in the object storage
+// given by the upload-uri of --graphql, and replaces them in the variables by their URLs. The
+// objects are put under <prefix>/<namespace>/<random id>/<file name>. They aren't deleted if the
+// mutation fails afterwards.
+func storeUploads(ctx context.Context, vars map[string]interface{}) error {
+	uri, err := url.Parse(x.Config.GraphQL.GetString("upload-uri"))
+	if err != nil || uri.Scheme == "" {
+		return errors.Errorf("File uploads aren't enabled, the upload-uri of --graphql isn't set")
+	}
+	mc, err := x.NewMinioClient(uri, nil)
+	if err != nil {
+		return errors.Wrap(err, "while connecting to the storage of the uploads")
+	}
+	bucket, prefix := mc.ParseBucketAndPrefix(uri.Path)
+	ns, _ := x.ExtractNamespace(ctx)
+	prefix = path.Join(prefix, strconv.FormatUint(ns, 10))
+
+	var store func(val interface{}) (interface{}, error)
+	store = func(val interface{}) (interface{}, error) {
+		switch val := val.(type) {
+		case *schema.Upload:
+			return putUpload(ctx, mc, bucket, prefix, val)
+		case map[string]interface{}:
+			for k, v := range val {
+				stored, err := store(v)
+				if err != nil {
+					return nil, err
+				}
+				val[k] = stored
+			}
+		case []interface{}:
+			for i, v := range val {
+				stored, err := store(v)
+				if err != nil {
+					return nil, err
+				}
+				val[i] = stored
+			}
+		}
+		return val, nil
+	}
+	_, err = store(vars)
+	return err
+}
+
+// putUpload puts the file in the bucket and returns its URL.
+func putUpload(ctx context.Context, mc *x.MinioClient, bucket, prefix string,
+	upload *schema.Upload) (string, error) {
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", errors.Wrap(err, "while generating the name of an upload")
+	}
+	object := path.Join(prefix, hex.EncodeToString(id[:]), uploadFileName(upload.Filename))
+	contentType := upload.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	_, err := mc.PutObject(ctx, bucket, object, bytes.NewReader(upload.Data),
+		int64(len(upload.Data)), minio.PutObjectOptions{
+			ContentType:  contentType,
+			UserMetadata: map[string]string{"filename": upload.Filename},
+		})
+	if err != nil {
+		return "", errors.Wrapf(err, "while storing the upload %s", upload.Filename)
+	}
+
+	objectURL := *mc.EndpointURL()
+	objectURL.Path = "/" + path.Join(bucket, object)
+	return objectURL.String(), nil
+}
+
+// uploadFileName returns the name of the uploaded file, restricted to the characters that are
+// safe in the object names and URLs.
+func uploadFileName(filename string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, path.Base(filename))
+	if name == "." || name == ".." || name == "/" || name == "" {
+		return "file"
+	}
+	return name
+}
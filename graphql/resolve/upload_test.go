@@ -0,0 +1,31 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package resolve
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/graphql/schema"
+)
+
+func TestHasUploads(t *testing.T) {
+	upload := &schema.Upload{Filename: "avatar.png"}
+	require.False(t, hasUploads(map[string]interface{}{"name": "Alice"}))
+	require.True(t, hasUploads(map[string]interface{}{"avatar": upload}))
+	require.True(t, hasUploads(map[string]interface{}{
+		"input": []interface{}{map[string]interface{}{"photos": []interface{}{upload}}},
+	}))
+}
+
+func TestUploadFileName(t *testing.T) {
+	require.Equal(t, "avatar.png", uploadFileName("avatar.png"))
+	require.Equal(t, "my_photo__1_.jpg", uploadFileName("my photo (1).jpg"))
+	require.Equal(t, "passwd", uploadFileName("../../etc/passwd"))
+	require.Equal(t, "file", uploadFileName(""))
+	require.Equal(t, "file", uploadFileName(".."))
+}
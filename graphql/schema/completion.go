@@ -181,7 +181,7 @@ func CompleteValue(
 	switch val := val.(type) {
 	case map[string]interface{}:
 		switch field.Type().Name() {
-		case "String", "ID", "Boolean", "Float", "Int", "Int64", "DateTime":
+		case "String", "ID", "Boolean", "Float", "Int", "Int64", "DateTime", "Upload":
 			return nil, x.GqlErrorList{field.GqlErrorf(path, ErrExpectedScalar)}
 		}
 		enumValues := field.EnumValues()
@@ -342,7 +342,7 @@ func coerceScalar(val interface{}, field Field, path []interface{}) (interface{}
 	}
 
 	switch field.Type().Name() {
-	case "String", "ID":
+	case "String", "ID", "Upload":
 		switch v := val.(type) {
 		case bool:
 			val = strconv.FormatBool(v)
@@ -0,0 +1,183 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package schema
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/gqlparser/v2/ast"
+	"github.com/dgraph-io/gqlparser/v2/gqlerror"
+)
+
+const (
+	// defaultFieldCost is the cost of a field without the @cost directive.
+	defaultFieldCost = 1
+	// defaultListSize is the number of items assumed for a list field queried without first,
+	// when the limits don't give one.
+	defaultListSize = 10
+)
+
+// queryLimits bound the worst-case work of the GraphQL operations. They are extracted from
+// `# Dgraph.QueryLimits {"maxDepth": 10, "maxCost": 5000}`, and a zero limit isn't checked.
+type queryLimits struct {
+	// MaxDepth is the maximum number of nested fields in the selection set of an operation.
+	MaxDepth int `json:"maxDepth"`
+	// MaxCost is the maximum cost of an operation. The cost of a field is its @cost weight, or 1,
+	// plus the cost of its selection set times the number of items the field may return.
+	MaxCost int `json:"maxCost"`
+	// DefaultListSize is the number of items assumed for a list field queried without first.
+	DefaultListSize int `json:"defaultListSize"`
+}
+
+func parseQueryLimits(text, header string) (*queryLimits, error) {
+	limits := &queryLimits{}
+	dec := json.NewDecoder(strings.NewReader(strings.TrimPrefix(header, "Dgraph.QueryLimits")))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(limits); err != nil || dec.More() || limits.MaxDepth < 0 ||
+		limits.MaxCost < 0 || limits.DefaultListSize < 0 {
+		return nil, errors.Errorf("incorrect format for specifying Dgraph.QueryLimits found for "+
+			"comment: `%s`, it should be `# Dgraph.QueryLimits {\"maxDepth\": 10, \"maxCost\": "+
+			"5000, \"defaultListSize\": 10}` with non-negative limits", text)
+	}
+	if limits.DefaultListSize == 0 {
+		limits.DefaultListSize = defaultListSize
+	}
+	return limits, nil
+}
+
+// check returns an error if the operation goes beyond the limits. It only needs the validated
+// operation, so that a query is rejected before being rewritten and run.
+func (l *queryLimits) check(op *ast.OperationDefinition, vars map[string]interface{}) error {
+	if l.MaxDepth > 0 {
+		if depth := selectionDepth(op.SelectionSet); depth > l.MaxDepth {
+			return gqlerror.ErrorPosf(op.Position, "The %s has a depth of %d, which is more than "+
+				"the maximum allowed depth of %d.", operationKind(op), depth, l.MaxDepth)
+		}
+	}
+	if l.MaxCost > 0 {
+		if cost := l.selectionCost(op.SelectionSet, vars); cost > l.MaxCost {
+			return gqlerror.ErrorPosf(op.Position, "The %s has a cost of at least %d, which is "+
+				"more than the maximum allowed cost of %d.", operationKind(op), cost, l.MaxCost)
+		}
+	}
+	return nil
+}
+
+func operationKind(op *ast.OperationDefinition) string {
+	if op.Operation == "" {
+		return string(ast.Query)
+	}
+	return string(op.Operation)
+}
+
+// selectionDepth returns the number of nested fields of the selection set, looking through the
+// fragments. The introspection fields aren't counted.
+func selectionDepth(set ast.SelectionSet) int {
+	depth := 0
+	for _, sel := range set {
+		d := 0
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				d = selectionDepth(s.Definition.SelectionSet)
+			}
+		}
+		depth = max(depth, d)
+	}
+	return depth
+}
+
+// selectionCost returns the cost of the selection set, which is capped so that it doesn't
+// overflow. The introspection fields don't cost anything.
+func (l *queryLimits) selectionCost(set ast.SelectionSet, vars map[string]interface{}) int {
+	cost := 0
+	for _, sel := range set {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			cost = addCost(cost, l.fieldCost(s, vars))
+		case *ast.InlineFragment:
+			cost = addCost(cost, l.selectionCost(s.SelectionSet, vars))
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				cost = addCost(cost, l.selectionCost(s.Definition.SelectionSet, vars))
+			}
+		}
+	}
+	return cost
+}
+
+func (l *queryLimits) fieldCost(f *ast.Field, vars map[string]interface{}) int {
+	weight := defaultFieldCost
+	if f.Definition == nil {
+		return weight
+	}
+	if dir := f.Definition.Directives.ForName(costDirective); dir != nil {
+		if arg := dir.Arguments.ForName(costWeightArg); arg != nil {
+			if w, err := strconv.Atoi(arg.Value.Raw); err == nil {
+				weight = w
+			}
+		}
+	}
+	if len(f.SelectionSet) == 0 {
+		return weight
+	}
+	return addCost(weight, mulCost(l.listSize(f, vars), l.selectionCost(f.SelectionSet, vars)))
+}
+
+// listSize returns the number of items the field may return: its first argument if given,
+// otherwise the default list size for a list field, and 1 for any other field.
+func (l *queryLimits) listSize(f *ast.Field, vars map[string]interface{}) int {
+	if f.Definition.Type.Elem == nil {
+		return 1
+	}
+	if arg := f.Arguments.ForName("first"); arg != nil {
+		val, err := arg.Value.Value(vars)
+		if err == nil {
+			switch v := val.(type) {
+			case int64:
+				return int(max(v, 0))
+			case int:
+				return max(v, 0)
+			case float64:
+				return int(max(min(v, math.MaxInt32), 0))
+			case json.Number:
+				if n, err := v.Int64(); err == nil {
+					return int(max(n, 0))
+				}
+			}
+		}
+	}
+	return l.DefaultListSize
+}
+
+func addCost(a, b int) int {
+	if a > math.MaxInt32-b {
+		return math.MaxInt32
+	}
+	return a + b
+}
+
+func mulCost(a, b int) int {
+	if a != 0 && b > math.MaxInt32/a {
+		return math.MaxInt32
+	}
+	return a * b
+}
@@ -30,6 +30,7 @@ const (
 
 	idDirective             = "id"
 	idDirectiveInterfaceArg = "interface"
+	idDirectiveFieldsArg    = "fields"
 	subscriptionDirective   = "withSubscription"
 	secretDirective         = "secret"
 	authDirective           = "auth"
@@ -55,6 +56,9 @@ const (
 	cascadeDirective = "cascade"
 	cascadeArg       = "fields"
 
+	costDirective = "cost"
+	costWeightArg = "weight"
+
 	cacheControlDirective = "cacheControl"
 	CacheControlHeader    = "Cache-Control"
 
@@ -93,6 +97,11 @@ const (
 
 	Typename = "__typename"
 
+	// ConnectOrCreate is the field of the references to the types with @id fields which, when it's
+	// true, updates the existing node found by its ids with the other fields of the reference, and
+	// creates it otherwise. Without it, an existing node is only linked.
+	ConnectOrCreate = "connectOrCreate"
+
 	// schemaExtras is everything that gets added to an input schema to make it
 	// GraphQL valid and for the completion algorithm to use to build in search
 	// capability into the schema.
@@ -111,6 +120,13 @@ For example: "1985-04-12T23:20:50.52Z" represents 20 mins 50.52 secs after the 2
 """
 scalar DateTime
 
+"""
+The Upload scalar type represents a file sent with a multipart request, following the GraphQL
+multipart request specification. The file is stored in the object storage configured for the
+uploads, and the field holds its URL.
+"""
+scalar Upload
+
 input IntRange{
 	min: Int!
 	max: Int!
@@ -209,6 +225,8 @@ input NearFilter {
 input PointGeoFilter {
 	near: NearFilter
 	within: WithinFilter
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 type PointList {
@@ -254,6 +272,8 @@ input PolygonGeoFilter {
 	within: WithinFilter
 	contains: ContainsFilter
 	intersects: IntersectsFilter
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input GenerateQueryParams {
@@ -274,7 +294,7 @@ directive @hasInverse(field: String!) on FIELD_DEFINITION
 directive @search(by: [String!]) on FIELD_DEFINITION
 directive @embedding on FIELD_DEFINITION
 directive @dgraph(type: String, pred: String) on OBJECT | INTERFACE | FIELD_DEFINITION
-directive @id(interface: Boolean) on FIELD_DEFINITION
+directive @id(interface: Boolean, fields: [String!]) on FIELD_DEFINITION | OBJECT
 directive @default(add: DgraphDefault, update: DgraphDefault) on FIELD_DEFINITION
 directive @withSubscription on OBJECT | INTERFACE | FIELD_DEFINITION
 directive @secret(field: String!, pred: String) on OBJECT | INTERFACE
@@ -290,6 +310,7 @@ directive @remoteResponse(name: String) on FIELD_DEFINITION
 directive @cascade(fields: [String]) on FIELD
 directive @lambda on FIELD_DEFINITION
 directive @lambdaOnMutate(add: Boolean, update: Boolean, delete: Boolean) on OBJECT | INTERFACE
+directive @cost(weight: Int!) on FIELD_DEFINITION
 directive @cacheControl(maxAge: Int!) on QUERY
 directive @generate(
 	query: GenerateQueryParams,
@@ -307,7 +328,7 @@ directive @hasInverse(field: String!) on FIELD_DEFINITION
 directive @search(by: [String!]) on FIELD_DEFINITION
 directive @embedding on FIELD_DEFINITION
 directive @dgraph(type: String, pred: String) on OBJECT | INTERFACE | FIELD_DEFINITION
-directive @id(interface: Boolean) on FIELD_DEFINITION
+directive @id(interface: Boolean, fields: [String!]) on FIELD_DEFINITION | OBJECT
 directive @default(add: DgraphDefault, update: DgraphDefault) on FIELD_DEFINITION
 directive @withSubscription on OBJECT | INTERFACE | FIELD_DEFINITION
 directive @secret(field: String!, pred: String) on OBJECT | INTERFACE
@@ -315,6 +336,7 @@ directive @remote on OBJECT | INTERFACE | UNION | INPUT_OBJECT | ENUM
 directive @remoteResponse(name: String) on FIELD_DEFINITION
 directive @lambda on FIELD_DEFINITION
 directive @lambdaOnMutate(add: Boolean, update: Boolean, delete: Boolean) on OBJECT | INTERFACE
+directive @cost(weight: Int!) on FIELD_DEFINITION
 `
 	filterInputs = `
 input IntFilter {
@@ -325,6 +347,8 @@ input IntFilter {
 	ge: Int
 	gt: Int
 	between: IntRange
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input Int64Filter {
@@ -335,6 +359,8 @@ input Int64Filter {
 	ge: Int64
 	gt: Int64
 	between: Int64Range
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input FloatFilter {
@@ -345,6 +371,8 @@ input FloatFilter {
 	ge: Float
 	gt: Float
 	between: FloatRange
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input DateTimeFilter {
@@ -355,24 +383,35 @@ input DateTimeFilter {
 	ge: DateTime
 	gt: DateTime
 	between: DateTimeRange
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input StringTermFilter {
 	allofterms: String
 	anyofterms: String
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input StringRegExpFilter {
 	regexp: String
+	eqIgnoreCase: String
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input StringNgramFilter {
 	ngram: String
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input StringFullTextFilter {
 	alloftext: String
 	anyoftext: String
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input StringExactFilter {
@@ -383,11 +422,15 @@ input StringExactFilter {
 	ge: String
 	gt: String
 	between: StringRange
+	isNull: Boolean
+	isNotNull: Boolean
 }
 
 input StringHashFilter {
 	eq: String
 	in: [String]
+	isNull: Boolean
+	isNotNull: Boolean
 }
 `
 
@@ -552,6 +595,7 @@ var inbuiltTypeToDgraph = map[string]string{
 	"Float":        "float",
 	"String":       "string",
 	"DateTime":     "dateTime",
+	"Upload":       "string",
 	"Password":     "password",
 	"Point":        "geo",
 	"Polygon":      "geo",
@@ -589,6 +633,7 @@ var directiveValidators = map[string]directiveValidator{
 	apolloRequiresDirective: apolloRequiresValidation,
 	apolloProvidesDirective: apolloProvidesValidation,
 	remoteResponseDirective: remoteResponseValidation,
+	costDirective:           costValidation,
 }
 
 // directiveLocationMap stores the directives and their locations for the ones which can be
@@ -597,7 +642,7 @@ var directiveLocationMap = map[string]map[ast.DefinitionKind]bool{
 	inverseDirective:      nil,
 	searchDirective:       nil,
 	dgraphDirective:       {ast.Object: true, ast.Interface: true},
-	idDirective:           nil,
+	idDirective:           {ast.Object: true},
 	subscriptionDirective: {ast.Object: true, ast.Interface: true},
 	secretDirective:       {ast.Object: true, ast.Interface: true},
 	authDirective:         {ast.Object: true, ast.Interface: true},
@@ -614,6 +659,7 @@ var directiveLocationMap = map[string]map[ast.DefinitionKind]bool{
 	apolloProvidesDirective: nil,
 	remoteResponseDirective: nil,
 	cascadeDirective:        nil,
+	costDirective:           nil,
 }
 
 // Struct to store parameters of @generate directive
@@ -1053,6 +1099,11 @@ func completeSchema(
 		// should not be part of HasFilter or UpdatePayloadType etc.
 		addAggregateFields(sch, defn, apolloServiceQuery)
 	}
+
+	// The orderings by related fields need the TOrderable of all the types.
+	for _, key := range definitions {
+		addRelatedOrders(sch, sch.Types[key])
+	}
 }
 
 func cleanupInput(sch *ast.Schema, def *ast.Definition, seen map[string]bool) {
@@ -1251,6 +1302,15 @@ func addReferenceType(schema *ast.Schema, defn *ast.Definition, providesTypeMap
 		}
 	}
 
+	if defn.Kind == ast.Object && len(flds) > 1 &&
+		(hasXID(defn) || len(compositeIDFields(defn)) != 0) &&
+		defn.Fields.ForName(ConnectOrCreate) == nil {
+		flds = append(flds, &ast.FieldDefinition{
+			Name: ConnectOrCreate,
+			Type: &ast.Type{NamedType: "Boolean"},
+		})
+	}
+
 	if len(flds) != 0 {
 		schema.Types[defn.Name+"Ref"] = &ast.Definition{
 			Kind:   ast.InputObject,
@@ -1494,7 +1554,10 @@ func getFilterTypes(schema *ast.Schema, fld *ast.FieldDefinition, filterName str
 				enumTypeName := fld.Type.Name()
 				var typ *ast.Type
 
-				if i.Type.Elem == nil {
+				if i.Type.Name() == "Boolean" {
+					// isNull and isNotNull stay Boolean.
+					typ = i.Type
+				} else if i.Type.Elem == nil {
 					typ = &ast.Type{
 						NamedType: enumTypeName,
 					}
@@ -1533,7 +1596,12 @@ func mergeAndAddFilters(filterTypes []string, schema *ast.Schema, filterName str
 
 	var fieldList ast.FieldList
 	for _, typeName := range filterTypes {
-		fieldList = append(fieldList, schema.Types[typeName].Fields...)
+		for _, fld := range schema.Types[typeName].Fields {
+			// isNull and isNotNull are in all the filters.
+			if fieldList.ForName(fld.Name) == nil {
+				fieldList = append(fieldList, fld)
+			}
+		}
 	}
 
 	schema.Types[filterName] = &ast.Definition{
@@ -1592,6 +1660,14 @@ func addFilterType(schema *ast.Schema, defn *ast.Definition, providesTypeMap map
 				})
 
 			mergeAndAddFilters(filterTypes, schema, filterName)
+		} else if relFilterName := addRelationFilter(schema, fld); relFilterName != "" {
+			filter.Fields = append(filter.Fields,
+				&ast.FieldDefinition{
+					Name: fld.Name,
+					Type: &ast.Type{
+						NamedType: relFilterName,
+					},
+				})
 		}
 	}
 
@@ -1622,6 +1698,42 @@ func addFilterType(schema *ast.Schema, defn *ast.Definition, providesTypeMap map
 	schema.Types[filterName] = filter
 }
 
+// addRelationFilter returns the filter on the related nodes of fld, if fld links to an object or
+// an interface. It's the filter of the related type for a singleton field, and
+//
+//	input TListRelationFilter {
+//	  some: TFilter
+//	  every: TFilter
+//	  none: TFilter
+//	}
+//
+// for a list field, which is added to the schema.
+func addRelationFilter(schema *ast.Schema, fld *ast.FieldDefinition) string {
+	relType := schema.Types[fld.Type.Name()]
+	if relType == nil || (relType.Kind != ast.Object && relType.Kind != ast.Interface) ||
+		hasCustomOrLambda(fld) || hasExtends(relType) || !hasFilterable(relType) {
+		return ""
+	}
+	relFilterName := relType.Name + "Filter"
+	if fld.Type.Elem == nil {
+		return relFilterName
+	}
+
+	listFilterName := relType.Name + "ListRelationFilter"
+	if schema.Types[listFilterName] == nil {
+		schema.Types[listFilterName] = &ast.Definition{
+			Kind: ast.InputObject,
+			Name: listFilterName,
+			Fields: ast.FieldList{
+				{Name: "some", Type: &ast.Type{NamedType: relFilterName}},
+				{Name: "every", Type: &ast.Type{NamedType: relFilterName}},
+				{Name: "none", Type: &ast.Type{NamedType: relFilterName}},
+			},
+		}
+	}
+	return listFilterName
+}
+
 // hasFilterable Returns whether TypeFilter for a defn will be generated or not.
 // It returns true if any field have search arguments or it is an `ID` field or
 // there is atleast one non-custom filter which would be the part of the has filter.
@@ -1683,6 +1795,30 @@ func hasXID(defn *ast.Definition) bool {
 	return fieldAny(nonExternalAndKeyFields(defn), hasIDDirective)
 }
 
+// compositeIDFields returns the fields of the composite key given by @id(fields: [...]) on the
+// type, in the order of the directive, or nil if it has none.
+func compositeIDFields(defn *ast.Definition) ast.FieldList {
+	dir := defn.Directives.ForName(idDirective)
+	if dir == nil {
+		return nil
+	}
+	arg := dir.Arguments.ForName(idDirectiveFieldsArg)
+	if arg == nil || arg.Value == nil {
+		return nil
+	}
+	var flds ast.FieldList
+	for _, child := range arg.Value.Children {
+		if fld := defn.Fields.ForName(child.Value.Raw); fld != nil {
+			flds = append(flds, fld)
+		}
+	}
+	return flds
+}
+
+func isCompositeIDField(defn *ast.Definition, fld *ast.FieldDefinition) bool {
+	return compositeIDFields(defn).ForName(fld.Name) != nil
+}
+
 func hasEmbedding(defn *ast.Definition) bool {
 	return fieldAny(nonExternalAndKeyFields(defn), hasEmbeddingDirective)
 }
@@ -1818,6 +1954,52 @@ func addTypeOrderable(schema *ast.Schema, defn *ast.Definition, providesTypeMap
 	schema.Types[orderableName] = order
 }
 
+// addRelatedOrders adds to TOrder the singleton fields of T linking to a type R with an
+// ROrderable, of type
+//
+//	input RRelatedOrder {
+//	  asc: ROrderable
+//	  desc: ROrderable
+//	}
+//
+// which is added to the schema. They allow things like
+// order: { asc: title, then: { author: { asc: name } } }
+func addRelatedOrders(schema *ast.Schema, defn *ast.Definition) {
+	if defn == nil || hasExtends(defn) {
+		return
+	}
+	order := schema.Types[defn.Name+"Order"]
+	if order == nil || order.Kind != ast.InputObject {
+		return
+	}
+
+	for _, fld := range defn.Fields {
+		relType := schema.Types[fld.Type.NamedType]
+		if relType == nil || (relType.Kind != ast.Object && relType.Kind != ast.Interface) ||
+			hasCustomOrLambda(fld) || hasExternal(fld) || order.Fields.ForName(fld.Name) != nil {
+			continue
+		}
+		relOrderableName := relType.Name + "Orderable"
+		if schema.Types[relOrderableName] == nil {
+			continue
+		}
+
+		relOrderName := relType.Name + "RelatedOrder"
+		if schema.Types[relOrderName] == nil {
+			schema.Types[relOrderName] = &ast.Definition{
+				Kind: ast.InputObject,
+				Name: relOrderName,
+				Fields: ast.FieldList{
+					{Name: "asc", Type: &ast.Type{NamedType: relOrderableName}},
+					{Name: "desc", Type: &ast.Type{NamedType: relOrderableName}},
+				},
+			}
+		}
+		order.Fields = append(order.Fields,
+			&ast.FieldDefinition{Name: fld.Name, Type: &ast.Type{NamedType: relOrderName}})
+	}
+}
+
 func addAddPayloadType(schema *ast.Schema, defn *ast.Definition, providesTypeMap map[string]bool) {
 	qry := &ast.FieldDefinition{
 		Name: CamelCase(defn.Name),
@@ -1966,7 +2148,8 @@ func addGetQuery(schema *ast.Schema, defn *ast.Definition,
 	hasIDField := hasID(defn)
 	hasXIDField := hasXID(defn)
 	xidCount := xidsCount(defn.Fields)
-	if !hasIDField && !hasXIDField {
+	keyFields := compositeIDFields(defn)
+	if !hasIDField && !hasXIDField && len(keyFields) == 0 {
 		return
 	}
 	qry := &ast.FieldDefinition{
@@ -1984,7 +2167,7 @@ func addGetQuery(schema *ast.Schema, defn *ast.Definition,
 			Name: fields[0].Name,
 			Type: &ast.Type{
 				NamedType: idTypeFor(defn),
-				NonNull:   !hasXIDField,
+				NonNull:   !hasXIDField && len(keyFields) == 0,
 			},
 		})
 	}
@@ -2000,7 +2183,7 @@ func addGetQuery(schema *ast.Schema, defn *ast.Definition,
 					Name: fld.Name,
 					Type: &ast.Type{
 						NamedType: fld.Type.Name(),
-						NonNull:   !hasIDField && xidCount <= 1,
+						NonNull:   !hasIDField && xidCount <= 1 && len(keyFields) == 0,
 					},
 				})
 			}
@@ -2016,6 +2199,18 @@ func addGetQuery(schema *ast.Schema, defn *ast.Definition,
 							Kind: ast.StringValue}}}})
 		}
 	}
+
+	// The fields of a composite key are given together, which is checked when the query is run
+	// if the type can also be fetched by its ID or @id fields.
+	for _, fld := range keyFields {
+		qry.Arguments = append(qry.Arguments, &ast.ArgumentDefinition{
+			Name: fld.Name,
+			Type: &ast.Type{
+				NamedType: fld.Type.Name(),
+				NonNull:   !hasIDField && !hasXIDField,
+			},
+		})
+	}
 	schema.Query.Fields = append(schema.Query.Fields, qry)
 	subs := defn.Directives.ForName(subscriptionDirective)
 	if subs != nil || generateSubscription {
@@ -2331,7 +2526,7 @@ func addAddMutation(schema *ast.Schema, defn *ast.Definition) {
 			},
 		},
 	}
-	if hasXID(defn) {
+	if hasXID(defn) || len(compositeIDFields(defn)) != 0 {
 		add.Arguments = append(add.Arguments,
 			&ast.ArgumentDefinition{
 				Name: "upsert",
@@ -28,8 +28,9 @@ import (
 // gqlgen instead if they make more sense.
 
 // Introspect performs an introspection query given a query that's expected to be either
-// __schema or __type.
-func Introspect(q Query) (json.RawMessage, error) {
+// __schema or __type. Unless full is true, it's restricted by the Dgraph.Introspection settings
+// of the schema.
+func Introspect(q Query, full bool) (json.RawMessage, error) {
 	if q.Name() != "__schema" && q.Name() != "__type" && q.Name() != Typename {
 		return nil, errors.New("call to introspect for field that isn't an introspection query " +
 			"this indicates bug. Please let us know by filing an issue")
@@ -53,12 +54,21 @@ func Introspect(q Query) (json.RawMessage, error) {
 			"this indicates bug. Please let us know by filing an issue")
 	}
 
+	astSchema := sch.schema
+	if !full && sch.meta.IntrospectionRestricted() {
+		settings := sch.meta.introspection
+		if settings.Disabled && q.Name() != Typename {
+			return nil, errors.New("Introspection is disabled for this GraphQL schema.")
+		}
+		astSchema = settings.visibleSchema(sch.schema)
+	}
+
 	reqCtx := &requestContext{
 		RawQuery:  op.query,
 		Variables: op.vars,
 		Doc:       op.doc,
 	}
-	ec := executionContext{reqCtx, sch.schema, new(bytes.Buffer)}
+	ec := executionContext{reqCtx, astSchema, new(bytes.Buffer)}
 	return ec.handleQuery(qu.sel), nil
 }
 
@@ -25,6 +25,14 @@ type Request struct {
 	Header        http.Header `json:"-"` // no need to marshal headers while generating poll hash
 }
 
+// Upload is a file sent with a multipart request. It stands for the value of an Upload in the
+// variables of the request, until it's stored and replaced by its URL.
+type Upload struct {
+	Filename    string
+	ContentType string
+	Data        []byte `json:"-"`
+}
+
 // RequestExtensions represents extensions recieved in requests
 type RequestExtensions struct {
 	PersistedQuery PersistedQuery
@@ -76,6 +84,12 @@ func (s *schema) Operation(req *Request) (Operation, error) {
 		return nil, gqlErr
 	}
 
+	if s.meta != nil && s.meta.queryLimits != nil {
+		if err := s.meta.queryLimits.check(op, vars); err != nil {
+			return nil, err
+		}
+	}
+
 	operation := &operation{op: op,
 		vars:                    vars,
 		query:                   req.Query,
@@ -35,7 +35,7 @@ func init() {
 	typeValidations = append(typeValidations, idCountCheck, dgraphDirectiveTypeValidation,
 		passwordDirectiveValidation, conflictingDirectiveValidation, nonIdFieldsCheck,
 		remoteTypeValidation, generateDirectiveValidation, apolloKeyValidation,
-		apolloExtendsValidation, lambdaOnMutateValidation)
+		apolloExtendsValidation, lambdaOnMutateValidation, compositeIDValidation)
 	fieldValidations = append(fieldValidations, listValidityCheck, fieldArgumentCheck,
 		fieldNameCheck, isValidFieldForList, hasAuthDirective, fieldDirectiveCheck)
 
@@ -261,6 +261,7 @@ func typeNameValidation(schema *ast.SchemaDocument) gqlerror.List {
 		// The static types that we define in schemaExtras
 		"Int64":                true,
 		"DateTime":             true,
+		"Upload":               true,
 		"DgraphIndex":          true,
 		"AuthRule":             true,
 		"HTTPMethod":           true,
@@ -1522,6 +1523,27 @@ func defaultDirectiveValidation(sch *ast.Schema,
 	return nil
 }
 
+// costValidation makes sure that the weight of the @cost directive is a non-negative Int.
+func costValidation(sch *ast.Schema,
+	typ *ast.Definition,
+	field *ast.FieldDefinition,
+	dir *ast.Directive,
+	secrets map[string]x.Sensitive) gqlerror.List {
+	arg := dir.Arguments.ForName(costWeightArg)
+	if arg == nil || arg.Value == nil {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(dir.Position,
+			"Type %s; Field %s: argument %s inside @cost directive must be defined.",
+			typ.Name, field.Name, costWeightArg)}
+	}
+	if weight, err := strconv.Atoi(arg.Value.Raw); arg.Value.Kind != ast.IntValue || err != nil ||
+		weight < 0 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(arg.Position,
+			"Type %s; Field %s: %s argument in @cost directive must be a non-negative Int, "+
+				"found: `%s`.", typ.Name, field.Name, costWeightArg, arg.Value.String())}
+	}
+	return nil
+}
+
 func lambdaOnMutateValidation(sch *ast.Schema, typ *ast.Definition) gqlerror.List {
 	dir := typ.Directives.ForName(lambdaOnMutateDirective)
 	if dir == nil {
@@ -2331,6 +2353,12 @@ func idValidation(sch *ast.Schema,
 	field *ast.FieldDefinition,
 	dir *ast.Directive,
 	secrets map[string]x.Sensitive) gqlerror.List {
+	if dir.Arguments.ForName(idDirectiveFieldsArg) != nil {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: fields argument of @id directive can only be given on a type,"+
+				" for a composite key", typ.Name, field.Name)}
+	}
 	if field.Type.NamedType == "String" ||
 		field.Type.NamedType == "Int" ||
 		field.Type.NamedType == "Int64" {
@@ -2356,6 +2384,58 @@ func idValidation(sch *ast.Schema,
 
 }
 
+// compositeIDValidation validates the composite key given by @id(fields: [...]) on a type. Its
+// fields are at least two distinct String!, Int! or Int64! fields of the type, stored in Dgraph
+// and not inherited from an interface.
+func compositeIDValidation(sch *ast.Schema, typ *ast.Definition) gqlerror.List {
+	dirs := typ.Directives.ForNames(idDirective)
+	if len(dirs) == 0 {
+		return nil
+	}
+	if len(dirs) > 1 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(dirs[1].Position,
+			"Type %s; @id directive should not be defined more than once.", typ.Name)}
+	}
+	dir := dirs[0]
+	arg := dir.Arguments.ForName(idDirectiveFieldsArg)
+	if arg == nil || arg.Value.Kind != ast.ListValue || len(arg.Value.Children) < 2 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(dir.Position,
+			"Type %s; @id directive on a type must have a fields argument listing at least two"+
+				" fields of the type.", typ.Name)}
+	}
+	if typ.Directives.ForName(remoteDirective) != nil {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(dir.Position,
+			"Type %s; @id directive not allowed along with @remote directive.", typ.Name)}
+	}
+
+	var errs []*gqlerror.Error
+	seen := make(map[string]bool)
+	for _, child := range arg.Value.Children {
+		name := child.Value.Raw
+		fld := typ.Fields.ForName(name)
+		switch {
+		case fld == nil:
+			errs = append(errs, gqlerror.ErrorPosf(child.Value.Position,
+				"Type %s; @id directive uses a field %s which is not defined inside the type.",
+				typ.Name, name))
+		case seen[name]:
+			errs = append(errs, gqlerror.ErrorPosf(child.Value.Position,
+				"Type %s; @id directive uses the field %s more than once.", typ.Name, name))
+		case !fld.Type.NonNull || fld.Type.Elem != nil || (fld.Type.NamedType != "String" &&
+			fld.Type.NamedType != "Int" && fld.Type.NamedType != "Int64"):
+			errs = append(errs, gqlerror.ErrorPosf(child.Value.Position,
+				"Type %s; Field %s: used inside @id directive must be of type String!, Int! or"+
+					" Int64!, not %s", typ.Name, name, fld.Type.String()))
+		case hasIDDirective(fld) || hasCustomOrLambda(fld) || parentInterface(sch, typ, name) != nil:
+			errs = append(errs, gqlerror.ErrorPosf(child.Value.Position,
+				"Type %s; Field %s: used inside @id directive can't have @id, @custom or @lambda"+
+					" directives, or be inherited from an interface.", typ.Name, name))
+		}
+		seen[name] = true
+	}
+	return errs
+}
+
 func apolloKeyValidation(sch *ast.Schema, typ *ast.Definition) gqlerror.List {
 	dirList := typ.Directives.ForNames(apolloKeyDirective)
 	if len(dirList) == 0 {
@@ -180,6 +180,15 @@ type metaInfo struct {
 	// authMeta stores the authorization meta info extracted from `# Dgraph.Authorization` if any,
 	// otherwise it is nil.
 	authMeta *authorization.AuthMeta
+	// persistedQueriesOnly is set by `# Dgraph.PersistedQueries "enforce"`. Then only the queries
+	// registered as persisted queries are served, and clients can't persist queries of their own.
+	persistedQueriesOnly bool
+	// queryLimits bound the depth and the cost of the operations, if set by
+	// `# Dgraph.QueryLimits`, otherwise it is nil.
+	queryLimits *queryLimits
+	// introspection restricts the introspection of the schema, if set by
+	// `# Dgraph.Introspection`, otherwise it is nil.
+	introspection *introspectionSettings
 }
 
 func (m *metaInfo) AllowedCorsHeaders() string {
@@ -194,6 +203,16 @@ func (m *metaInfo) AuthMeta() *authorization.AuthMeta {
 	return m.authMeta
 }
 
+func (m *metaInfo) PersistedQueriesOnly() bool {
+	return m.persistedQueriesOnly
+}
+
+// IntrospectionRestricted tells whether the introspection of the schema is disabled or hides
+// some types or fields from the callers other than the guardians.
+func (m *metaInfo) IntrospectionRestricted() bool {
+	return m != nil && m.introspection != nil
+}
+
 func parseMetaInfo(sch string) (*metaInfo, error) {
 	scanner := bufio.NewScanner(strings.NewReader(sch))
 	authSecret := ""
@@ -233,6 +252,42 @@ func parseMetaInfo(sch string) (*metaInfo, error) {
 				continue
 			}
 
+			if strings.HasPrefix(header, "Dgraph.PersistedQueries") {
+				parts := strings.Fields(text)
+				var mode string
+				if len(parts) != 3 || json.Unmarshal([]byte(parts[2]), &mode) != nil ||
+					mode != "enforce" {
+					return nil, errors.Errorf("incorrect format for specifying "+
+						"Dgraph.PersistedQueries found for comment: `%s`, it should be "+
+						"`# Dgraph.PersistedQueries \"enforce\"`", text)
+				}
+				schMetaInfo.persistedQueriesOnly = true
+				continue
+			}
+
+			if strings.HasPrefix(header, "Dgraph.QueryLimits") {
+				if schMetaInfo.queryLimits != nil {
+					return nil, errors.Errorf("Dgraph.QueryLimits should only be specified once "+
+						"in a schema, found second mention: %v", text)
+				}
+				if schMetaInfo.queryLimits, err = parseQueryLimits(text, header); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if strings.HasPrefix(header, "Dgraph.Introspection") {
+				if schMetaInfo.introspection != nil {
+					return nil, errors.Errorf("Dgraph.Introspection should only be specified once "+
+						"in a schema, found second mention: %v", text)
+				}
+				if schMetaInfo.introspection, err = parseIntrospectionSettings(text,
+					header); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
 			if !strings.HasPrefix(header, "Dgraph.Secret") {
 				continue
 			}
@@ -396,6 +451,12 @@ func NewHandler(input string, apolloServiceQuery bool) (Handler, error) {
 		return nil, gqlerror.Errorf("No query or mutation found in the generated schema")
 	}
 
+	if metaInfo.introspection != nil {
+		if gqlErrList = metaInfo.introspection.validate(sch); gqlErrList != nil {
+			return nil, gqlErrList
+		}
+	}
+
 	// If Dgraph.Authorization header is parsed successfully and JWKUrls is present
 	// then initialise the http client and Fetch the JWKs from the JWKUrls.
 	if metaInfo.authMeta != nil && len(metaInfo.authMeta.JWKUrls) != 0 {
@@ -650,7 +711,7 @@ func genDgSchema(gqlSch *ast.Schema, definitions []string,
 					}
 
 					id := f.Directives.ForName(idDirective)
-					if id != nil || f.Type.Name() == "ID" {
+					if id != nil || f.Type.Name() == "ID" || isCompositeIDField(def, f) {
 						upsertStr = "@upsert "
 						switch f.Type.Name() {
 						case "Int", "Int64":
@@ -0,0 +1,155 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/gqlparser/v2/ast"
+	"github.com/dgraph-io/gqlparser/v2/gqlerror"
+)
+
+// introspectionSettings restrict the introspection of the schema of a namespace. They are
+// extracted from `# Dgraph.Introspection {"disabled": true}` or
+// `# Dgraph.Introspection {"hidden": ["Type", "Type.field"]}`. The hidden types and fields are
+// left out of the introspection results, but they are still served to the queries asking for
+// them. The guardians of the namespace always see the whole schema.
+type introspectionSettings struct {
+	// Disabled turns off __schema and __type, __typename is still answered.
+	Disabled bool `json:"disabled"`
+	// Hidden are the types, and the fields or enum values given as Type.name, to hide.
+	Hidden []string `json:"hidden"`
+
+	hiddenTypes  map[string]bool
+	hiddenFields map[string]bool
+}
+
+func parseIntrospectionSettings(text, header string) (*introspectionSettings, error) {
+	settings := &introspectionSettings{}
+	dec := json.NewDecoder(strings.NewReader(strings.TrimPrefix(header, "Dgraph.Introspection")))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(settings); err != nil || dec.More() {
+		return nil, errors.Errorf("incorrect format for specifying Dgraph.Introspection found "+
+			"for comment: `%s`, it should be `# Dgraph.Introspection {\"disabled\": true, "+
+			"\"hidden\": [\"Type\", \"Type.field\"]}`", text)
+	}
+	settings.hiddenTypes = make(map[string]bool)
+	settings.hiddenFields = make(map[string]bool)
+	for _, name := range settings.Hidden {
+		if strings.Contains(name, ".") {
+			settings.hiddenFields[name] = true
+		} else {
+			settings.hiddenTypes[name] = true
+		}
+	}
+	return settings, nil
+}
+
+// validate returns an error if a hidden type or field isn't in the complete schema, or if it
+// would hide an operation type.
+func (s *introspectionSettings) validate(sch *ast.Schema) gqlerror.List {
+	var errs gqlerror.List
+	for _, name := range s.Hidden {
+		typName, fldName, isField := strings.Cut(name, ".")
+		typ := sch.Types[typName]
+		switch {
+		case !isField && (typName == "Query" || typName == "Mutation" ||
+			typName == "Subscription" || (typ != nil && typ.BuiltIn)):
+			errs = append(errs, gqlerror.Errorf("Dgraph.Introspection can't hide the type %s.",
+				typName))
+		case typ == nil:
+			errs = append(errs, gqlerror.Errorf("Dgraph.Introspection hides %s, but there is no "+
+				"type %s in the schema.", name, typName))
+		case isField && typ.Fields.ForName(fldName) == nil &&
+			typ.EnumValues.ForName(fldName) == nil:
+			errs = append(errs, gqlerror.Errorf("Dgraph.Introspection hides %s, but the type %s "+
+				"has no field or enum value %s.", name, typName, fldName))
+		}
+	}
+	return errs
+}
+
+// visibleSchema returns a copy of the schema without the hidden types and fields. The fields
+// whose type or arguments are of a hidden type are left out too, so that the schema returned by
+// the introspection stays consistent.
+func (s *introspectionSettings) visibleSchema(sch *ast.Schema) *ast.Schema {
+	visible := &ast.Schema{
+		Types:         make(map[string]*ast.Definition, len(sch.Types)),
+		Directives:    sch.Directives,
+		PossibleTypes: make(map[string][]*ast.Definition, len(sch.PossibleTypes)),
+		Implements:    make(map[string][]*ast.Definition, len(sch.Implements)),
+	}
+	hiddenType := func(t *ast.Type) bool {
+		return t != nil && s.hiddenTypes[t.Name()]
+	}
+	for name, typ := range sch.Types {
+		if s.hiddenTypes[name] {
+			continue
+		}
+		def := *typ
+		def.Fields = nil
+		for _, fld := range typ.Fields {
+			if s.hiddenFields[name+"."+fld.Name] || hiddenType(fld.Type) {
+				continue
+			}
+			hiddenArg := false
+			for _, arg := range fld.Arguments {
+				hiddenArg = hiddenArg || hiddenType(arg.Type)
+			}
+			if !hiddenArg {
+				def.Fields = append(def.Fields, fld)
+			}
+		}
+		def.EnumValues = nil
+		for _, val := range typ.EnumValues {
+			if !s.hiddenFields[name+"."+val.Name] {
+				def.EnumValues = append(def.EnumValues, val)
+			}
+		}
+		def.Interfaces = s.visibleNames(typ.Interfaces)
+		def.Types = s.visibleNames(typ.Types)
+		visible.Types[name] = &def
+	}
+
+	visibleDefs := func(defs []*ast.Definition) []*ast.Definition {
+		var res []*ast.Definition
+		for _, def := range defs {
+			if v, ok := visible.Types[def.Name]; ok {
+				res = append(res, v)
+			}
+		}
+		return res
+	}
+	for name, defs := range sch.PossibleTypes {
+		visible.PossibleTypes[name] = visibleDefs(defs)
+	}
+	for name, defs := range sch.Implements {
+		visible.Implements[name] = visibleDefs(defs)
+	}
+	if sch.Query != nil {
+		visible.Query = visible.Types[sch.Query.Name]
+	}
+	if sch.Mutation != nil {
+		visible.Mutation = visible.Types[sch.Mutation.Name]
+	}
+	if sch.Subscription != nil {
+		visible.Subscription = visible.Types[sch.Subscription.Name]
+	}
+	return visible
+}
+
+func (s *introspectionSettings) visibleNames(names []string) []string {
+	var res []string
+	for _, name := range names {
+		if !s.hiddenTypes[name] {
+			res = append(res, name)
+		}
+	}
+	return res
+}
@@ -239,6 +239,7 @@ type Type interface {
 	Fields() []FieldDefinition
 	IDField() FieldDefinition
 	XIDFields() []FieldDefinition
+	CompositeIDFields() []FieldDefinition
 	InterfaceImplHasAuthRules() bool
 	PasswordField() FieldDefinition
 	Name() string
@@ -580,7 +581,11 @@ func dgraphMapping(sch *ast.Schema) map[string]map[string]string {
 			} else if strings.HasPrefix(inputTypeName, del) {
 				inputTypeName = strings.TrimSuffix(strings.TrimPrefix(inputTypeName, del), payload)
 			}
-			inputTyp = sch.Types[inputTypeName]
+			// A payload whose name doesn't match a type, like DeletePersistedQueriesPayload of
+			// the admin schema, is mapped as it is.
+			if typ, ok := sch.Types[inputTypeName]; ok {
+				inputTyp = typ
+			}
 		}
 
 		// We add password field to the cached type information to be used while opening
@@ -1411,6 +1416,22 @@ func (f *field) IDArgValue() (xids map[string]string, uid uint64, err error) {
 			xids[xidArgName] = xidArgVal
 		}
 	}
+	if keyFields := f.Type().CompositeIDFields(); len(keyFields) != 0 {
+		var given, names []string
+		for _, fd := range keyFields {
+			names = append(names, fd.Name())
+			if _, ok := xids[fd.Name()]; ok {
+				given = append(given, fd.Name())
+			}
+		}
+		if len(given) != 0 && len(given) != len(keyFields) {
+			pos := f.field.GetPosition()
+			err = x.GqlErrorf("Arguments %s of %s must be given together, as they are the "+
+				"composite id of type %s", strings.Join(names, ", "), f.Name(), f.Type().Name()).
+				WithLocations(x.Location{Line: pos.Line, Column: pos.Column})
+			return
+		}
+	}
 	if idField == nil {
 		return
 	}
@@ -2696,6 +2717,24 @@ func (t *astType) XIDFields() []FieldDefinition {
 	return xids
 }
 
+// CompositeIDFields returns the fields of the composite key given by @id(fields: [...]) on the
+// type, in the order of the directive, or nil if it has none.
+func (t *astType) CompositeIDFields() []FieldDefinition {
+	def := t.inSchema.schema.Types[t.Name()]
+	if def == nil || def.Kind != ast.Object {
+		return nil
+	}
+	var flds []FieldDefinition
+	for _, fd := range compositeIDFields(def) {
+		flds = append(flds, &fieldDefinition{
+			fieldDef:   fd,
+			inSchema:   t.inSchema,
+			parentType: t,
+		})
+	}
+	return flds
+}
+
 // InterfaceImplHasAuthRules checks if an interface's implementation has auth rules.
 func (t *astType) InterfaceImplHasAuthRules() bool {
 	schema := t.inSchema.schema
@@ -391,6 +391,28 @@ func TestDgraphMapping_WithDirectives(t *testing.T) {
 	}
 }
 
+func TestDgraphMapping_PayloadWithoutType(t *testing.T) {
+	// Like DeletePersistedQueriesPayload of the admin schema, there is no type Foo.
+	sch, err := FromString(`
+type DeleteFooPayload {
+	deleted: Int
+}
+
+type Query {
+	foo: Int
+}
+
+type Mutation {
+	deleteFoo: DeleteFooPayload
+}`, x.RootNamespace)
+	require.NoError(t, err)
+
+	s, ok := sch.(*schema)
+	require.True(t, ok, "expected to be able to convert sch to internal schema type")
+	require.Equal(t, map[string]string{"deleted": "DeleteFooPayload.deleted"},
+		s.dgraphPredicate["DeleteFooPayload"])
+}
+
 func TestCheckNonNulls(t *testing.T) {
 
 	gqlSchema, err := FromString(`
@@ -1323,6 +1345,71 @@ func TestParseSecrets(t *testing.T) {
 				"comment: `# Dgraph.Allow-Origin 1\"https://dgraph.io\"`, it should " +
 				"be `# Dgraph.Allow-Origin \"http://example.com\"`"),
 		},
+		{
+			"should parse Dgraph.PersistedQueries correctly",
+			`
+			type User {
+				id: ID!
+				name: String!
+			}
+
+			# Dgraph.PersistedQueries "enforce"
+			`,
+			map[string]string{},
+			"",
+			nil,
+			nil,
+		},
+		{
+			"should throw error if Dgraph.PersistedQueries has an unknown mode",
+			`
+			type User {
+				id: ID!
+				name: String!
+			}
+
+			# Dgraph.PersistedQueries "allow"
+			`,
+			map[string]string{},
+			"",
+			nil,
+			errors.New("incorrect format for specifying Dgraph.PersistedQueries found for " +
+				"comment: `# Dgraph.PersistedQueries \"allow\"`, it should " +
+				"be `# Dgraph.PersistedQueries \"enforce\"`"),
+		},
+		{
+			"should parse Dgraph.QueryLimits correctly",
+			`
+			type User {
+				id: ID!
+				name: String!
+			}
+
+			# Dgraph.QueryLimits {"maxDepth": 5, "maxCost": 1000}
+			`,
+			map[string]string{},
+			"",
+			nil,
+			nil,
+		},
+		{
+			"should throw error if Dgraph.QueryLimits has an unknown limit",
+			`
+			type User {
+				id: ID!
+				name: String!
+			}
+
+			# Dgraph.QueryLimits {"maxNodes": 5}
+			`,
+			map[string]string{},
+			"",
+			nil,
+			errors.New("incorrect format for specifying Dgraph.QueryLimits found for comment: " +
+				"`# Dgraph.QueryLimits {\"maxNodes\": 5}`, it should be `# Dgraph.QueryLimits " +
+				"{\"maxDepth\": 10, \"maxCost\": 5000, \"defaultListSize\": 10}` with " +
+				"non-negative limits"),
+		},
 	}
 	for _, test := range tcases {
 		t.Run(test.name, func(t *testing.T) {
@@ -1344,6 +1431,176 @@ func TestParseSecrets(t *testing.T) {
 				require.NotNil(t, meta.authMeta)
 				require.Equal(t, test.expectedAuthHeader, meta.authMeta.Header)
 			}
+			require.Equal(t, strings.Contains(test.schemaStr, "Dgraph.PersistedQueries"),
+				meta.PersistedQueriesOnly())
 		})
 	}
 }
+
+func TestQueryLimits(t *testing.T) {
+	schemaStr := `
+	type Author {
+		id: ID!
+		name: String!
+		posts: [Post] @hasInverse(field: author)
+	}
+
+	type Post {
+		id: ID!
+		title: String!
+		text: String @cost(weight: 5)
+		author: Author
+	}
+
+	# Dgraph.QueryLimits {"maxDepth": 3, "maxCost": 100, "defaultListSize": 5}
+	`
+	schHandler, errs := NewHandler(schemaStr, false)
+	require.NoError(t, errs)
+	sch, err := FromString(schHandler.GQLSchema(), x.RootNamespace)
+	require.NoError(t, err)
+	sch.SetMeta(schHandler.MetaInfo())
+
+	tcases := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		err   string
+	}{
+		{
+			name:  "within the limits",
+			query: `query { queryAuthor(first: 2) { name posts { title text } } }`,
+		},
+		{
+			name:  "too deep",
+			query: `query { queryAuthor(first: 1) { posts(first: 1) { author { name } } } }`,
+			err: "input:1: The query has a depth of 4, which is more than the maximum allowed " +
+				"depth of 3.",
+		},
+		{
+			name:  "too costly with the default list size",
+			query: `query { queryAuthor { posts { text } } }`,
+			err: "input:1: The query has a cost of at least 131, which is more than the maximum " +
+				"allowed cost of 100.",
+		},
+		{
+			name:  "first given as a variable",
+			query: `query($n: Int) { queryAuthor(first: $n) { posts { text } } }`,
+			vars:  map[string]interface{}{"n": 1},
+		},
+		{
+			name:  "too costly with first given as a variable",
+			query: `query($n: Int) { queryAuthor(first: $n) { posts { text } } }`,
+			vars:  map[string]interface{}{"n": 10},
+			err: "input:1: The query has a cost of at least 261, which is more than the maximum " +
+				"allowed cost of 100.",
+		},
+		{
+			name: "too costly through a fragment",
+			query: `query { queryAuthor { ...authorPosts } }
+			fragment authorPosts on Author { posts { text } }`,
+			err: "input:1: The query has a cost of at least 131, which is more than the maximum " +
+				"allowed cost of 100.",
+		},
+		{
+			name:  "introspection isn't limited",
+			query: `query { __schema { types { fields { type { ofType { name } } } } } }`,
+		},
+	}
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			_, err := sch.Operation(&Request{Query: tcase.query, Variables: tcase.vars})
+			if tcase.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tcase.err)
+		})
+	}
+}
+
+func TestIntrospectionSettings(t *testing.T) {
+	schemaStr := `
+	type Author {
+		id: ID!
+		name: String!
+		secret: Secret
+		role: Role
+	}
+
+	type Secret {
+		id: ID!
+		text: String
+	}
+
+	enum Role {
+		READER
+		ADMIN
+	}
+
+	# Dgraph.Introspection {"hidden": ["Secret", "Author.name", "Role.ADMIN"]}
+	`
+	schHandler, errs := NewHandler(schemaStr, false)
+	require.NoError(t, errs)
+	sch, err := FromString(schHandler.GQLSchema(), x.RootNamespace)
+	require.NoError(t, err)
+	sch.SetMeta(schHandler.MetaInfo())
+	require.True(t, sch.Meta().IntrospectionRestricted())
+
+	introspect := func(query string, full bool) string {
+		op, err := sch.Operation(&Request{Query: query})
+		require.NoError(t, err)
+		data, err := Introspect(op.Queries()[0], full)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	query := `query { __type(name: "Author") { fields { name } } }`
+	require.JSONEq(t, `{"__type":{"fields":[{"name":"id"},{"name":"role"}]}}`,
+		introspect(query, false))
+	require.JSONEq(t, `{"__type":{"fields":[{"name":"id"},{"name":"name"},{"name":"secret"},`+
+		`{"name":"role"}]}}`, introspect(query, true))
+	require.JSONEq(t, `{"__type":{"enumValues":[{"name":"READER"}]}}`,
+		introspect(`query { __type(name: "Role") { enumValues { name } } }`, false))
+	require.JSONEq(t, `{"__type":null}`,
+		introspect(`query { __type(name: "Secret") { name } }`, false))
+
+	// The queries of the hidden type are hidden, as they return it, but they are still served.
+	types := introspect(`query { __schema { types { name } queryType { fields { name } } } }`,
+		false)
+	require.NotContains(t, types, `"Secret"`)
+	require.NotContains(t, types, `"getSecret"`)
+	require.Contains(t, types, `"aggregateSecret"`)
+	_, err = sch.Operation(&Request{Query: `query { querySecret { text } }`})
+	require.NoError(t, err)
+
+	// Introspection can be disabled, except for __typename.
+	schHandler, errs = NewHandler(`
+	type Author {
+		id: ID!
+		name: String!
+	}
+
+	# Dgraph.Introspection {"disabled": true}
+	`, false)
+	require.NoError(t, errs)
+	sch, err = FromString(schHandler.GQLSchema(), x.RootNamespace)
+	require.NoError(t, err)
+	sch.SetMeta(schHandler.MetaInfo())
+	op, err := sch.Operation(&Request{Query: `query { __schema { types { name } } }`})
+	require.NoError(t, err)
+	_, err = Introspect(op.Queries()[0], false)
+	require.EqualError(t, err, "Introspection is disabled for this GraphQL schema.")
+	require.JSONEq(t, `{"__typename":"Query"}`, introspect(`query { __typename }`, false))
+
+	_, errs = NewHandler(`
+	type Author {
+		id: ID!
+		name: String!
+	}
+
+	# Dgraph.Introspection {"hidden": ["Author.age", "Query"]}
+	`, false)
+	require.EqualError(t, errs, "input: Dgraph.Introspection hides Author.age, but the type "+
+		"Author has no field or enum value age.\n"+
+		"input: Dgraph.Introspection can't hide the type Query.\n")
+}
@@ -0,0 +1,260 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package plugin connects to the out-of-process plugins serving custom tokenizers and query
+// functions over the gRPC protocol of protos/plugin.proto.
+package plugin
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/tok"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// ProtocolVersion is the version of the plugin protocol spoken by Dgraph. It's increased when
+// the protocol changes in a way the plugins can't ignore.
+const ProtocolVersion = 1
+
+// Defaults are the defaults of the --plugin superflag.
+const Defaults = `addrs=; timeout=10s; startup-timeout=1m; health-interval=10s;`
+
+// Options are the options of the connections to the plugins.
+type Options struct {
+	// Timeout is the timeout of the calls to the plugins.
+	Timeout time.Duration
+	// StartupTimeout is how long Dgraph waits for a plugin to be up when it starts.
+	StartupTimeout time.Duration
+	// HealthInterval is the interval of the health checks of the plugins.
+	HealthInterval time.Duration
+}
+
+// Plugin is the connection to a plugin. Its tokenizers and functions fail while it's unhealthy.
+type Plugin struct {
+	addr    string
+	opts    Options
+	conn    *grpc.ClientConn
+	client  pb.PluginClient
+	info    *pb.PluginInfo
+	healthy atomic.Bool
+	closer  *z.Closer
+}
+
+var (
+	// The plugins and their functions are registered when Dgraph starts, before it serves
+	// requests, like the tokenizers.
+	plugins   []*Plugin
+	functions = make(map[string]*Function)
+)
+
+// LoadAll connects to the plugins of the --plugin superflag, and registers their tokenizers and
+// functions. It must be called when Dgraph starts, before the schema is loaded.
+func LoadAll(flag *z.SuperFlag) error {
+	opts := Options{
+		Timeout:        flag.GetDuration("timeout"),
+		StartupTimeout: flag.GetDuration("startup-timeout"),
+		HealthInterval: flag.GetDuration("health-interval"),
+	}
+	for _, addr := range strings.Split(flag.GetString("addrs"), ",") {
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+		if _, err := Load(addr, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load connects to the plugin at addr, waiting for it to be up for at most the startup timeout,
+// checks that it speaks the protocol version of Dgraph and registers its tokenizers and functions.
+func Load(addr string, opts Options) (*Plugin, error) {
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(x.GrpcMaxSize),
+			grpc.MaxCallSendMsgSize(x.GrpcMaxSize)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "while connecting to plugin at %s", addr)
+	}
+	p := &Plugin{
+		addr:   addr,
+		opts:   opts,
+		conn:   conn,
+		client: pb.NewPluginClient(conn),
+		closer: z.NewCloser(1),
+	}
+	if err := p.register(); err != nil {
+		if cerr := conn.Close(); cerr != nil {
+			glog.Warningf("Error while closing connection to plugin at %s: %v", addr, cerr)
+		}
+		return nil, err
+	}
+	p.healthy.Store(true)
+	plugins = append(plugins, p)
+	go p.monitorHealth()
+
+	glog.Infof("Loaded plugin %s version %s at %s, with %d tokenizers and %d functions",
+		p.info.Name, p.info.Version, addr, len(p.info.Tokenizers), len(p.info.Functions))
+	return p, nil
+}
+
+// CloseAll stops the health checks of the plugins and closes the connections to them.
+func CloseAll() {
+	for _, p := range plugins {
+		p.closer.SignalAndWait()
+		if err := p.conn.Close(); err != nil {
+			glog.Warningf("Error while closing connection to plugin at %s: %v", p.addr, err)
+		}
+	}
+}
+
+// GetFunction returns the function of a plugin with the given name.
+func GetFunction(name string) (*Function, bool) {
+	f, ok := functions[name]
+	return f, ok
+}
+
+func (p *Plugin) register() error {
+	var info *pb.PluginInfo
+	deadline := time.Now().Add(p.opts.StartupTimeout)
+	for {
+		var err error
+		if info, err = p.fetchInfo(context.Background()); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrapf(err, "while connecting to plugin at %s", p.addr)
+		}
+		glog.Warningf("Plugin at %s is not up yet: %v. Retrying...", p.addr, err)
+		time.Sleep(time.Second)
+	}
+	if err := checkInfo(info); err != nil {
+		return errors.Wrapf(err, "while loading plugin at %s", p.addr)
+	}
+	p.info = info
+
+	for _, t := range info.Tokenizers {
+		if err := tok.RegisterPluginTokenizer(&remoteTokenizer{p: p, info: t}); err != nil {
+			return errors.Wrapf(err, "while loading plugin %s", info.Name)
+		}
+	}
+	for _, f := range info.Functions {
+		functions[f.Name] = &Function{p: p, info: f}
+	}
+	return nil
+}
+
+// checkInfo checks that a plugin speaks the protocol version of Dgraph, and that its tokenizers
+// and functions can be registered.
+func checkInfo(info *pb.PluginInfo) error {
+	if info.ProtocolVersion != ProtocolVersion {
+		return errors.Errorf("plugin %s speaks version %d of the plugin protocol, but Dgraph "+
+			"speaks version %d", info.Name, info.ProtocolVersion, ProtocolVersion)
+	}
+	for _, t := range info.Tokenizers {
+		if t.Identifier > 0xff {
+			return errors.Errorf("custom tokenizer identifier byte must be <= 0xff, but was %#x",
+				t.Identifier)
+		}
+		if !isValueType(t.Type) {
+			return errors.Errorf("Invalid type %q for tokenizer %s", t.Type, t.Name)
+		}
+	}
+	for _, f := range info.Functions {
+		if f.Name == "" {
+			return errors.Errorf("plugin %s has a function without a name", info.Name)
+		}
+		if _, ok := functions[f.Name]; ok {
+			return errors.Errorf("Duplicate plugin function: %s", f.Name)
+		}
+		if !isValueType(f.Type) {
+			return errors.Errorf("Invalid type %q for plugin function %s", f.Type, f.Name)
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) fetchInfo(ctx context.Context) (*pb.PluginInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+	return p.client.Info(ctx, &pb.PluginInfoRequest{ProtocolVersion: ProtocolVersion})
+}
+
+// monitorHealth checks the health of the plugin until it's closed. The plugin is unhealthy while
+// it's down, or if it comes back with other tokenizers or functions than the ones registered,
+// which needs Dgraph to be restarted.
+func (p *Plugin) monitorHealth() {
+	defer p.closer.Done()
+
+	ticker := time.NewTicker(p.opts.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := p.fetchInfo(p.closer.Ctx())
+		switch {
+		case err != nil:
+			if p.healthy.Swap(false) {
+				glog.Errorf("Plugin %s at %s is unavailable: %v", p.info.Name, p.addr, err)
+			}
+		case !sameRegistrations(p.info, info):
+			if p.healthy.Swap(false) {
+				glog.Errorf("Plugin %s at %s came back as %s version %s, with other tokenizers or "+
+					"functions. Please restart Dgraph to load it.", p.info.Name, p.addr,
+					info.Name, info.Version)
+			}
+		default:
+			if !p.healthy.Swap(true) {
+				glog.Infof("Plugin %s at %s is available again", p.info.Name, p.addr)
+			}
+		}
+	}
+}
+
+// sameRegistrations returns whether the plugins speak the same protocol version, with the same
+// tokenizers and functions.
+func sameRegistrations(a, b *pb.PluginInfo) bool {
+	if a.ProtocolVersion != b.ProtocolVersion || len(a.Tokenizers) != len(b.Tokenizers) ||
+		len(a.Functions) != len(b.Functions) {
+		return false
+	}
+	for i := range a.Tokenizers {
+		if !proto.Equal(a.Tokenizers[i], b.Tokenizers[i]) {
+			return false
+		}
+	}
+	for i := range a.Functions {
+		if !proto.Equal(a.Functions[i], b.Functions[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// call calls the plugin with the timeout of the plugins, if it's healthy.
+func (p *Plugin) call(ctx context.Context, f func(context.Context) error) error {
+	if !p.healthy.Load() {
+		return errors.Errorf("plugin %s at %s is unavailable", p.info.Name, p.addr)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+	return f(ctx)
+}
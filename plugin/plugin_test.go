@@ -0,0 +1,155 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package plugin
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/tok"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+// testPlugin serves a tokenizer returning the lowercase words of strings, and a function matching
+// the strings with a prefix.
+type testPlugin struct {
+	pb.UnimplementedPluginServer
+	info *pb.PluginInfo
+}
+
+func (s *testPlugin) Info(context.Context, *pb.PluginInfoRequest) (*pb.PluginInfo, error) {
+	return s.info, nil
+}
+
+func (s *testPlugin) Tokenize(_ context.Context, req *pb.TokenizeRequest) (
+	*pb.TokenizeResponse, error) {
+	res := &pb.TokenizeResponse{}
+	for _, word := range strings.Fields(strings.ToLower(req.Value.GetStrVal())) {
+		res.Tokens = append(res.Tokens, []byte(word))
+	}
+	return res, nil
+}
+
+func (s *testPlugin) Evaluate(_ context.Context, req *pb.EvaluateRequest) (
+	*pb.EvaluateResponse, error) {
+	res := &pb.EvaluateResponse{}
+	for _, val := range req.Values {
+		res.Matches = append(res.Matches, strings.HasPrefix(val.GetStrVal(), req.Args[0]))
+	}
+	return res, nil
+}
+
+func serve(t *testing.T, info *pb.PluginInfo) (string, *grpc.Server) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	pb.RegisterPluginServer(s, &testPlugin{info: info})
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String(), s
+}
+
+var testOpts = Options{
+	Timeout:        time.Second,
+	StartupTimeout: time.Second,
+	HealthInterval: 50 * time.Millisecond,
+}
+
+func TestLoadPlugin(t *testing.T) {
+	addr, s := serve(t, &pb.PluginInfo{
+		Name:            "words",
+		Version:         "1.0.0",
+		ProtocolVersion: ProtocolVersion,
+		Tokenizers:      []*pb.PluginTokenizer{{Name: "words", Type: "string", Identifier: 0xf0}},
+		Functions:       []*pb.PluginFunction{{Name: "prefix", Type: "string"}},
+	})
+	p, err := Load(addr, testOpts)
+	require.NoError(t, err)
+
+	tokenizer, ok := tok.GetTokenizer("words")
+	require.True(t, ok)
+	_, ok = tokenizer.(tok.CustomTokenizer)
+	require.True(t, ok)
+	tokens, err := tokenizer.Tokens("Hello Plugin")
+	require.NoError(t, err)
+	require.Equal(t, []string{"hello", "plugin"}, tokens)
+	_, err = tokenizer.Tokens(42)
+	require.Error(t, err)
+
+	f, ok := GetFunction("prefix")
+	require.True(t, ok)
+	require.Equal(t, types.StringID, f.Type())
+	vals := []types.Val{
+		{Tid: types.StringID, Value: "dgraph"},
+		{Tid: types.StringID, Value: "badger"},
+	}
+	matches, err := f.Evaluate(context.Background(), []string{"dg"}, vals)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false}, matches)
+
+	// The calls fail fast while the plugin is down.
+	s.Stop()
+	require.Eventually(t, func() bool { return !p.healthy.Load() }, 5*time.Second, 10*time.Millisecond)
+	_, err = f.Evaluate(context.Background(), []string{"dg"}, vals)
+	require.ErrorContains(t, err, "unavailable")
+	_, err = tokenizer.Tokens("Hello Plugin")
+	require.ErrorContains(t, err, "unavailable")
+}
+
+func TestLoadPluginChecks(t *testing.T) {
+	for _, tc := range []struct {
+		info *pb.PluginInfo
+		err  string
+	}{
+		{
+			info: &pb.PluginInfo{Name: "old", ProtocolVersion: ProtocolVersion + 1},
+			err:  "speaks version 2 of the plugin protocol",
+		},
+		{
+			info: &pb.PluginInfo{
+				Name:            "system",
+				ProtocolVersion: ProtocolVersion,
+				Tokenizers:      []*pb.PluginTokenizer{{Name: "lower", Type: "string", Identifier: 0x7}},
+			},
+			err: "must be >= 0x80",
+		},
+		{
+			info: &pb.PluginInfo{
+				Name:            "taken",
+				ProtocolVersion: ProtocolVersion,
+				Tokenizers:      []*pb.PluginTokenizer{{Name: "term", Type: "string", Identifier: 0xf1}},
+			},
+			err: "Duplicate tokenizer",
+		},
+		{
+			info: &pb.PluginInfo{
+				Name:            "geo",
+				ProtocolVersion: ProtocolVersion,
+				Functions:       []*pb.PluginFunction{{Name: "near_by", Type: "geo"}},
+			},
+			err: `Invalid type "geo"`,
+		},
+	} {
+		addr, _ := serve(t, tc.info)
+		_, err := Load(addr, testOpts)
+		require.ErrorContains(t, err, tc.err, tc.info.Name)
+	}
+
+	// The loading fails once the startup timeout is over, if the plugin isn't up.
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+	_, err = Load(addr, testOpts)
+	require.ErrorContains(t, err, "while connecting to plugin")
+}
@@ -0,0 +1,112 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package plugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+// remoteTokenizer is a custom tokenizer served by a plugin.
+type remoteTokenizer struct {
+	p    *Plugin
+	info *pb.PluginTokenizer
+}
+
+func (t *remoteTokenizer) Name() string     { return t.info.Name }
+func (t *remoteTokenizer) Type() string     { return t.info.Type }
+func (t *remoteTokenizer) Identifier() byte { return byte(t.info.Identifier) }
+func (t *remoteTokenizer) Tokens(v interface{}) ([]string, error) {
+	val, err := toPluginValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var res *pb.TokenizeResponse
+	err = t.p.call(context.Background(), func(ctx context.Context) error {
+		var err error
+		res, err = t.p.client.Tokenize(ctx, &pb.TokenizeRequest{Tokenizer: t.info.Name, Value: val})
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while tokenizing with plugin tokenizer %s", t.info.Name)
+	}
+	tokens := make([]string, len(res.Tokens))
+	for i, token := range res.Tokens {
+		tokens[i] = string(token)
+	}
+	return tokens, nil
+}
+
+// Function is a query function served by a plugin, used as plugin(predicate, "name", args...) in
+// a filter.
+type Function struct {
+	p    *Plugin
+	info *pb.PluginFunction
+}
+
+// Type returns the type the values of the predicate are converted to for the function.
+func (f *Function) Type() types.TypeID {
+	typ, _ := types.TypeForName(f.info.Type)
+	return typ
+}
+
+// Evaluate returns whether each of the values, of the type of the function, matches it with the
+// arguments of the query.
+func (f *Function) Evaluate(ctx context.Context, args []string, vals []types.Val) ([]bool, error) {
+	req := &pb.EvaluateRequest{Function: f.info.Name, Args: args}
+	for _, val := range vals {
+		v, err := toPluginValue(val.Value)
+		if err != nil {
+			return nil, err
+		}
+		req.Values = append(req.Values, v)
+	}
+	var res *pb.EvaluateResponse
+	err := f.p.call(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = f.p.client.Evaluate(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while evaluating plugin function %s", f.info.Name)
+	}
+	if len(res.Matches) != len(vals) {
+		return nil, errors.Errorf("plugin function %s returned %d matches for %d values",
+			f.info.Name, len(res.Matches), len(vals))
+	}
+	return res.Matches, nil
+}
+
+// isValueType returns whether values of the type can be sent to the plugins.
+func isValueType(name string) bool {
+	switch name {
+	case "string", "int", "float", "bool", "datetime":
+		return true
+	}
+	return false
+}
+
+func toPluginValue(v interface{}) (*pb.PluginValue, error) {
+	switch v := v.(type) {
+	case string:
+		return &pb.PluginValue{Val: &pb.PluginValue_StrVal{StrVal: v}}, nil
+	case int64:
+		return &pb.PluginValue{Val: &pb.PluginValue_IntVal{IntVal: v}}, nil
+	case float64:
+		return &pb.PluginValue{Val: &pb.PluginValue_FloatVal{FloatVal: v}}, nil
+	case bool:
+		return &pb.PluginValue{Val: &pb.PluginValue_BoolVal{BoolVal: v}}, nil
+	case time.Time:
+		return &pb.PluginValue{
+			Val: &pb.PluginValue_DatetimeVal{DatetimeVal: v.Format(time.RFC3339Nano)}}, nil
+	}
+	return nil, errors.Errorf("Values of type %T can't be sent to plugins", v)
+}
@@ -0,0 +1,230 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// compressedMagic is the first byte of a compressed posting list. Like the sealed values, a
+	// marshalled protobuf message can never start with it because its wire type (7) is invalid.
+	compressedMagic = byte(0xD7)
+	// compressedHeaderLen is the length of the magic byte followed by the codec and the id of
+	// the zstd dictionary, 0 if there's none.
+	compressedHeaderLen = 1 + 1 + 4
+
+	codecSnappy = byte(1)
+	codecZstd   = byte(2)
+
+	// maxDictSize is the maximum size of the trained zstd dictionaries.
+	maxDictSize = 112 << 10
+)
+
+type zstdEncoderKey struct {
+	level int32
+	dict  uint32
+}
+
+var (
+	zstdEncoders sync.Map // zstdEncoderKey -> *zstd.Encoder
+	zstdDecoders sync.Map // dictionary id -> *zstd.Decoder
+)
+
+// CompressionDictID returns the id of the zstd dictionary, or 0 if it isn't one.
+func CompressionDictID(d []byte) uint32 {
+	if len(d) < 8 || binary.LittleEndian.Uint32(d) != 0xEC30A437 {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(d[4:])
+}
+
+func zstdEncoder(level int32, d []byte) (*zstd.Encoder, error) {
+	key := zstdEncoderKey{level: level, dict: CompressionDictID(d)}
+	if enc, ok := zstdEncoders.Load(key); ok {
+		return enc.(*zstd.Encoder), nil
+	}
+	opts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+	if level > 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(int(level))))
+	}
+	if key.dict != 0 {
+		opts = append(opts, zstd.WithEncoderDict(d))
+	}
+	enc, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := zstdEncoders.LoadOrStore(key, enc)
+	return actual.(*zstd.Encoder), nil
+}
+
+func zstdDecoder(id uint32, d []byte) (*zstd.Decoder, error) {
+	if dec, ok := zstdDecoders.Load(id); ok {
+		return dec.(*zstd.Decoder), nil
+	}
+	opts := []zstd.DOption{zstd.WithDecoderConcurrency(0)}
+	if id != 0 {
+		opts = append(opts, zstd.WithDecoderDicts(d))
+	}
+	dec, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := zstdDecoders.LoadOrStore(id, dec)
+	return actual.(*zstd.Decoder), nil
+}
+
+// compressValue compresses the posting list of a data key with the codec set with @compress for
+// its predicate, and its last zstd dictionary. The value is returned as is if it isn't smaller
+// once compressed.
+func compressValue(key, val []byte) ([]byte, error) {
+	if len(val) == 0 {
+		return val, nil
+	}
+	pk, err := x.Parse(key)
+	if err != nil || !pk.IsData() {
+		return val, nil
+	}
+	codec, level, dicts := schema.State().Compression(pk.Attr)
+
+	out := make([]byte, compressedHeaderLen, compressedHeaderLen+len(val))
+	out[0] = compressedMagic
+	switch codec {
+	case schema.CompressionSnappy:
+		out[1] = codecSnappy
+		out = append(out, s2.EncodeSnappy(nil, val)...)
+	case schema.CompressionZstd:
+		var d []byte
+		if len(dicts) > 0 {
+			d = dicts[len(dicts)-1]
+		}
+		enc, err := zstdEncoder(level, d)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while compressing the value of %s",
+				x.ParseAttr(pk.Attr))
+		}
+		out[1] = codecZstd
+		binary.LittleEndian.PutUint32(out[2:], CompressionDictID(d))
+		out = enc.EncodeAll(val, out)
+	default:
+		return val, nil
+	}
+	if len(out) >= len(val) {
+		return val, nil
+	}
+	return out, nil
+}
+
+// decompressValue decompresses a value compressed by compressValue. Values that aren't
+// compressed are returned as is.
+func decompressValue(key, val []byte) ([]byte, error) {
+	if len(val) < compressedHeaderLen || val[0] != compressedMagic {
+		return val, nil
+	}
+	body := val[compressedHeaderLen:]
+	switch val[1] {
+	case codecSnappy:
+		out, err := s2.Decode(nil, body)
+		return out, errors.Wrapf(err, "while decompressing the value of key %x", key)
+	case codecZstd:
+		id := binary.LittleEndian.Uint32(val[2:])
+		var d []byte
+		if id != 0 {
+			pk, err := x.Parse(key)
+			if err != nil {
+				return nil, err
+			}
+			_, _, dicts := schema.State().Compression(pk.Attr)
+			for _, cand := range dicts {
+				if CompressionDictID(cand) == id {
+					d = cand
+				}
+			}
+			if d == nil {
+				return nil, errors.Errorf("The zstd dictionary %#x of the value of key %x is "+
+					"missing from the schema of %s", id, key, x.ParseAttr(pk.Attr))
+			}
+		}
+		dec, err := zstdDecoder(id, d)
+		if err != nil {
+			return nil, err
+		}
+		out, err := dec.DecodeAll(body, nil)
+		return out, errors.Wrapf(err, "while decompressing the value of key %x", key)
+	default:
+		return nil, errors.Errorf("Unknown codec %d of the value of key %x", val[1], key)
+	}
+}
+
+// sealListValue compresses and then seals the rolled up posting list of the key.
+func sealListValue(key, val []byte) ([]byte, error) {
+	val, err := compressValue(key, val)
+	if err != nil {
+		return nil, err
+	}
+	return sealValue(key, val)
+}
+
+// openListValue opens and then decompresses the posting list of the key read from Badger.
+func openListValue(key, val []byte) ([]byte, error) {
+	val, err := openValue(key, val)
+	if err != nil {
+		return nil, err
+	}
+	return decompressValue(key, val)
+}
+
+// TrainCompressionDict trains a zstd dictionary on the posting lists of up to samples data keys of
+// the predicate, read at readTs.
+func TrainCompressionDict(ctx context.Context, attr string, readTs uint64,
+	samples int) ([]byte, error) {
+
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	iterOpts := badger.DefaultIteratorOptions
+	iterOpts.Prefix = x.ParsedKey{Attr: attr}.DataPrefix()
+	it := txn.NewIterator(iterOpts)
+	defer it.Close()
+
+	var input [][]byte
+	for it.Rewind(); it.Valid() && len(input) < samples; it.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		item := it.Item()
+		if meta := item.UserMeta(); meta != BitCompletePosting && meta != BitDeltaPosting {
+			continue
+		}
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return nil, err
+		}
+		if val, err = openListValue(item.Key(), val); err != nil {
+			return nil, err
+		}
+		if len(val) > 0 {
+			input = append(input, val)
+		}
+	}
+	if len(input) == 0 {
+		return nil, errors.Errorf("Predicate %s has no values to train a dictionary on",
+			x.ParseAttr(attr))
+	}
+	d, err := dict.BuildZstdDict(input, dict.Options{MaxDictSize: maxDictSize, HashBytes: 6})
+	return d, errors.Wrapf(err, "while training the dictionary of %s", x.ParseAttr(attr))
+}
@@ -0,0 +1,96 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestCompression(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`
+		zstd_bio: string @compress(zstd, level: 3) .
+		snappy_bio: string @compress(snappy) .
+	`), 1))
+	ctx := context.Background()
+	zstdBio := x.AttrInRootNamespace("zstd_bio")
+	snappyBio := x.AttrInRootNamespace("snappy_bio")
+
+	bio := func(uid uint64) string {
+		return fmt.Sprintf("Person %d was born in a small town by the sea, %s", uid,
+			strings.Repeat("and spent the long summers reading books by the lighthouse. ", 10))
+	}
+	txn := Oracle().RegisterStartTs(1)
+	for uid := uint64(1); uid <= 200; uid++ {
+		for _, attr := range []string{zstdBio, snappyBio} {
+			l, err := txn.Get(x.DataKey(attr, uid))
+			require.NoError(t, err)
+			require.NoError(t, l.AddMutationWithIndex(ctx, &pb.DirectedEdge{
+				Attr: attr, Entity: uid, Value: []byte(bio(uid)), Op: pb.DirectedEdge_SET,
+			}, txn))
+		}
+	}
+	txn.Update()
+	writer := NewTxnWriter(pstore)
+	require.NoError(t, txn.CommitToDisk(writer, 2))
+	require.NoError(t, writer.Flush())
+
+	// rollup rolls up the list of the key and returns the size of its value on disk.
+	rollup := func(key []byte) int {
+		l, err := GetNoStore(key, math.MaxUint64)
+		require.NoError(t, err)
+		kvs, err := l.Rollup(nil, math.MaxUint64)
+		require.NoError(t, err)
+		require.Len(t, kvs, 1)
+		require.NoError(t, writePostingListToDisk(kvs))
+		return len(kvs[0].Value)
+	}
+	value := func(key []byte) string {
+		l, err := GetNoStore(key, math.MaxUint64)
+		require.NoError(t, err)
+		val, err := l.Value(math.MaxUint64)
+		require.NoError(t, err)
+		return string(val.Value.([]byte))
+	}
+
+	for _, attr := range []string{zstdBio, snappyBio} {
+		key := x.DataKey(attr, 1)
+		rollup(key)
+		raw := rawValue(t, key, math.MaxUint64)
+		require.Equal(t, compressedMagic, raw[0], attr)
+		require.Equal(t, bio(1), value(key))
+	}
+
+	// A dictionary trained on the values compresses them better, and the values compressed
+	// without it stay readable.
+	plainSize := rollup(x.DataKey(zstdBio, 2))
+	d, err := TrainCompressionDict(ctx, zstdBio, math.MaxUint64, 100)
+	require.NoError(t, err)
+	require.NotZero(t, CompressionDictID(d))
+	su, ok := schema.State().Get(ctx, zstdBio)
+	require.True(t, ok)
+	su.CompressionDicts = [][]byte{d}
+	schema.State().Set(zstdBio, &su)
+
+	require.Less(t, rollup(x.DataKey(zstdBio, 2)), plainSize)
+	require.Equal(t, bio(2), value(x.DataKey(zstdBio, 2)))
+	require.Equal(t, bio(1), value(x.DataKey(zstdBio, 1)))
+
+	// The values can't be read without the dictionary they were compressed with.
+	su.CompressionDicts = nil
+	schema.State().Set(zstdBio, &su)
+	_, err = GetNoStore(x.DataKey(zstdBio, 2), math.MaxUint64)
+	require.ErrorContains(t, err, "dictionary")
+}
@@ -0,0 +1,319 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"sort"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/codec"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/tok"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// fsckMaxUids is the number of the missing and orphaned uids of a key listed by a report.
+const fsckMaxUids = 10
+
+// FsckIssue is an index, reverse or count key of a predicate which doesn't match its data.
+type FsckIssue struct {
+	// Index is the name of the tokenizer of an index key, or reverse, count or count-reverse.
+	Index string `json:"index"`
+	Key   string `json:"key"`
+	// Missing is the number of uids the key should have but doesn't, and Orphaned the number of
+	// those it has but shouldn't. An orphaned key only has orphaned uids. The first few of them are
+	// listed.
+	Missing      int      `json:"missing"`
+	Orphaned     int      `json:"orphaned"`
+	MissingUids  []uint64 `json:"missingUids,omitempty"`
+	OrphanedUids []uint64 `json:"orphanedUids,omitempty"`
+}
+
+// FsckReport is the result of the check of a predicate.
+type FsckReport struct {
+	Predicate string       `json:"predicate"`
+	DataKeys  int          `json:"dataKeys"`
+	IndexKeys int          `json:"indexKeys"`
+	Issues    []*FsckIssue `json:"issues"`
+	Repaired  bool         `json:"repaired"`
+}
+
+// Fsck verifies the index, reverse and count keys of the predicate against its data at readTs,
+// and reports the keys which miss uids or have orphaned ones. The keys the data expects are kept
+// in memory, one predicate at a time. The vector indexes aren't checked.
+//
+// If repairTs isn't zero, the keys with issues are rewritten at repairTs as complete posting
+// lists holding the uids the data expects. This writes directly to the p directory, so nothing
+// else may write to it meanwhile.
+func Fsck(ctx context.Context, attr string, readTs, repairTs uint64) (*FsckReport, error) {
+	su, ok := schema.State().Get(ctx, attr)
+	if !ok {
+		return nil, errors.Errorf("Schema not defined for predicate: %s", x.ParseAttr(attr))
+	}
+	f := &fsck{
+		attr:     attr,
+		su:       &su,
+		readTs:   readTs,
+		report:   &FsckReport{Predicate: x.ParseAttr(attr)},
+		expected: make(map[string][]uint64),
+	}
+	for _, name := range su.Tokenizer {
+		t, ok := tok.GetTokenizer(name)
+		if !ok {
+			return nil, errors.Errorf("Invalid tokenizer %s", name)
+		}
+		f.tokenizers = append(f.tokenizers, t)
+	}
+
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	pk := x.ParsedKey{Attr: attr}
+	if err := f.iterate(ctx, txn, pk.DataPrefix(), f.expect); err != nil {
+		return nil, err
+	}
+	f.expectCountReverse()
+
+	prefixes := [][]byte{pk.ReversePrefix(), pk.CountPrefix(false), pk.CountPrefix(true)}
+	if len(su.IndexSpecs) == 0 {
+		prefixes = append(prefixes, pk.IndexPrefix())
+	}
+	for _, prefix := range prefixes {
+		if err := f.iterate(ctx, txn, prefix, f.verify); err != nil {
+			return nil, err
+		}
+	}
+	// The keys left are missing altogether.
+	for key, uids := range f.expected {
+		if err := f.addIssue([]byte(key), uids, uids, nil); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(f.report.Issues, func(i, j int) bool {
+		return f.report.Issues[i].Key < f.report.Issues[j].Key
+	})
+
+	if repairTs > 0 && len(f.repairs) > 0 {
+		if err := f.repair(repairTs); err != nil {
+			return nil, err
+		}
+		f.report.Repaired = true
+	}
+	return f.report, nil
+}
+
+type fsck struct {
+	attr       string
+	su         *pb.SchemaUpdate
+	tokenizers []tok.Tokenizer
+	readTs     uint64
+	report     *FsckReport
+	// expected are the uids of the keys expected by the data, and repairs the keys with issues
+	// along with these uids.
+	expected map[string][]uint64
+	repairs  []fsckRepair
+}
+
+type fsckRepair struct {
+	key  []byte
+	uids []uint64
+}
+
+// iterate reads the posting lists of the keys with the prefix at the read ts.
+func (f *fsck) iterate(ctx context.Context, txn *badger.Txn, prefix []byte,
+	fn func(x.ParsedKey, []byte, *List) error) error {
+
+	iterOpts := badger.DefaultIteratorOptions
+	iterOpts.AllVersions = true
+	iterOpts.Prefix = prefix
+	it := txn.NewIterator(iterOpts)
+	defer it.Close()
+
+	var lastKey []byte
+	for it.Rewind(); it.Valid(); {
+		item := it.Item()
+		if bytes.Equal(lastKey, item.Key()) {
+			it.Next()
+			continue
+		}
+		lastKey = append(lastKey[:0], item.Key()...)
+		pk, err := x.Parse(lastKey)
+		if err != nil {
+			return err
+		}
+		if pk.HasStartUid {
+			// The parts of a multi-part list are read through its main key.
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key := item.KeyCopy(nil)
+		pl, err := ReadPostingList(key, it)
+		if err != nil {
+			return err
+		}
+		if err := fn(pk, key, pl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expect adds the uid of the data key to the keys its values, edges and count are expected in.
+// The data keys are read by increasing uid, so the uids of the expected keys stay sorted.
+func (f *fsck) expect(pk x.ParsedKey, _ []byte, pl *List) error {
+	f.report.DataKeys++
+	uid := pk.Uid
+	add := func(key []byte, uid uint64) {
+		uids := f.expected[string(key)]
+		if n := len(uids); n == 0 || uids[n-1] != uid {
+			f.expected[string(key)] = append(uids, uid)
+		}
+	}
+
+	tid := types.TypeID(f.su.ValueType)
+	err := pl.Iterate(f.readTs, 0, func(p *pb.Posting) error {
+		if tid == types.UidID {
+			if f.su.Directive == pb.SchemaUpdate_REVERSE {
+				add(x.ReverseKey(f.attr, p.Uid), uid)
+			}
+			return nil
+		}
+		if len(f.tokenizers) == 0 {
+			return nil
+		}
+		sv, err := types.Convert(types.Val{Tid: types.TypeID(p.ValType), Value: p.Value}, tid)
+		if err != nil {
+			// Such a value can't have been indexed.
+			return nil
+		}
+		for _, t := range f.tokenizers {
+			toks, err := tok.BuildTokens(sv.Value, tok.GetTokenizerForLang(t, string(p.LangTag)))
+			if err != nil {
+				return err
+			}
+			for _, token := range toks {
+				add(x.IndexKey(f.attr, token), uid)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "while reading the data of uid %#x", uid)
+	}
+
+	if f.su.Count {
+		if n := pl.Length(f.readTs, 0); n > 0 {
+			add(x.CountKey(f.attr, uint32(n), false), uid)
+		}
+	}
+	return nil
+}
+
+// expectCountReverse adds the expected reverse count keys, once the expected reverse keys are
+// known.
+func (f *fsck) expectCountReverse() {
+	if !f.su.Count || f.su.Directive != pb.SchemaUpdate_REVERSE {
+		return
+	}
+	counts := make(map[uint64]int)
+	for key, uids := range f.expected {
+		pk, err := x.Parse([]byte(key))
+		if err == nil && pk.IsReverse() {
+			counts[pk.Uid] = len(uids)
+		}
+	}
+	dsts := make([]uint64, 0, len(counts))
+	for uid := range counts {
+		dsts = append(dsts, uid)
+	}
+	sort.Slice(dsts, func(i, j int) bool { return dsts[i] < dsts[j] })
+	for _, uid := range dsts {
+		key := string(x.CountKey(f.attr, uint32(counts[uid]), true))
+		f.expected[key] = append(f.expected[key], uid)
+	}
+}
+
+// verify compares the uids of the index key with the ones the data expects.
+func (f *fsck) verify(_ x.ParsedKey, key []byte, pl *List) error {
+	f.report.IndexKeys++
+	list, err := pl.Uids(ListOptions{ReadTs: f.readTs})
+	if err != nil {
+		return err
+	}
+	want := f.expected[string(key)]
+	delete(f.expected, string(key))
+	missing := algo.Difference(&pb.List{Uids: want}, list).Uids
+	orphaned := algo.Difference(list, &pb.List{Uids: want}).Uids
+	if len(missing) == 0 && len(orphaned) == 0 {
+		return nil
+	}
+	return f.addIssue(key, want, missing, orphaned)
+}
+
+func (f *fsck) addIssue(key []byte, want, missing, orphaned []uint64) error {
+	pk, err := x.Parse(key)
+	if err != nil {
+		return err
+	}
+	issue := &FsckIssue{
+		Key:          hex.EncodeToString(key),
+		Missing:      len(missing),
+		Orphaned:     len(orphaned),
+		MissingUids:  missing[:min(len(missing), fsckMaxUids)],
+		OrphanedUids: orphaned[:min(len(orphaned), fsckMaxUids)],
+	}
+	switch {
+	case pk.IsIndex():
+		issue.Index = "unknown"
+		if len(pk.Term) > 0 {
+			if t, ok := tok.GetTokenizerByID(pk.Term[0]); ok {
+				issue.Index = t.Name()
+			}
+		}
+	case pk.IsReverse():
+		issue.Index = "reverse"
+	case pk.IsCount():
+		issue.Index = "count"
+	case pk.IsCountRev():
+		issue.Index = "count-reverse"
+	}
+	f.report.Issues = append(f.report.Issues, issue)
+	f.repairs = append(f.repairs, fsckRepair{key: key, uids: want})
+	return nil
+}
+
+// repair rewrites the keys with issues as complete posting lists at the ts.
+func (f *fsck) repair(ts uint64) error {
+	wb := pstore.NewManagedWriteBatch()
+	defer wb.Cancel()
+	for _, r := range f.repairs {
+		e := &badger.Entry{Key: r.key, UserMeta: BitEmptyPosting}
+		if len(r.uids) > 0 {
+			val, err := proto.Marshal(&pb.PostingList{Pack: codec.Encode(r.uids, blockSize)})
+			if err != nil {
+				return err
+			}
+			if val, err = sealValue(r.key, val); err != nil {
+				return err
+			}
+			e = &badger.Entry{Key: r.key, Value: val, UserMeta: BitCompletePosting}
+		}
+		if err := wb.SetEntryAt(e.WithDiscard(), ts); err != nil {
+			return errors.Wrapf(err, "while repairing key %x", r.key)
+		}
+	}
+	return wb.Flush()
+}
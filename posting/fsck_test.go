@@ -0,0 +1,104 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/tok"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestFsck(t *testing.T) {
+	require.NoError(t, pstore.DropAll())
+	MemLayerInstance.clear()
+	require.NoError(t, schema.ParseBytes([]byte(`
+		fsck_name: string @index(exact) .
+		fsck_friend: [uid] @reverse @count .
+	`), 1))
+	ctx := context.Background()
+	name := x.AttrInRootNamespace("fsck_name")
+	friend := x.AttrInRootNamespace("fsck_friend")
+
+	addIndexed := func(edge *pb.DirectedEdge, ts uint64) {
+		l, err := GetNoStore(x.DataKey(edge.Attr, edge.Entity), ts)
+		require.NoError(t, err)
+		addMutation(t, l, edge, Set, ts, ts+1, true)
+	}
+	addIndexed(&pb.DirectedEdge{Attr: name, Entity: 1, Value: []byte("alice")}, 1)
+	addIndexed(&pb.DirectedEdge{Attr: name, Entity: 2, Value: []byte("bob")}, 3)
+	addIndexed(&pb.DirectedEdge{Attr: friend, Entity: 1, ValueId: 2}, 5)
+	addIndexed(&pb.DirectedEdge{Attr: friend, Entity: 1, ValueId: 3}, 7)
+
+	for _, attr := range []string{name, friend} {
+		report, err := Fsck(ctx, attr, 20, 0)
+		require.NoError(t, err)
+		require.Empty(t, report.Issues, "%s", x.ParseAttr(attr))
+	}
+	report, err := Fsck(ctx, friend, 20, 0)
+	require.NoError(t, err)
+	// Two reverse keys, the count key of 1 emptied by the second edge, the one of 2, and the
+	// reverse count key of 1.
+	require.Equal(t, 1, report.DataKeys)
+	require.Equal(t, 5, report.IndexKeys)
+
+	// A value which isn't indexed, and an index entry of no value.
+	addEdgeToValue(t, name, 3, "carol", 21, 22)
+	exact, ok := tok.GetTokenizer("exact")
+	require.True(t, ok)
+	toks, err := tok.BuildTokens("dave", exact)
+	require.NoError(t, err)
+	f := &fsck{repairs: []fsckRepair{{key: x.IndexKey(name, toks[0]), uids: []uint64{4}}}}
+	require.NoError(t, f.repair(23))
+
+	report, err = Fsck(ctx, name, 30, 0)
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 2)
+	byKey := make(map[string]*FsckIssue)
+	for _, issue := range report.Issues {
+		require.Equal(t, "exact", issue.Index)
+		byKey[issue.Key] = issue
+	}
+	carol, err := tok.BuildTokens("carol", exact)
+	require.NoError(t, err)
+	missing := byKey[hex.EncodeToString(x.IndexKey(name, carol[0]))]
+	require.NotNil(t, missing)
+	require.Equal(t, []uint64{3}, missing.MissingUids)
+	orphaned := byKey[hex.EncodeToString(x.IndexKey(name, toks[0]))]
+	require.NotNil(t, orphaned)
+	require.Equal(t, []uint64{4}, orphaned.OrphanedUids)
+	require.False(t, report.Repaired)
+
+	report, err = Fsck(ctx, name, 30, 31)
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 2)
+	require.True(t, report.Repaired)
+	report, err = Fsck(ctx, name, 32, 0)
+	require.NoError(t, err)
+	require.Empty(t, report.Issues)
+
+	// A reverse edge which is missing makes the reverse count drift too.
+	l, err := GetNoStore(x.DataKey(friend, 2), 33)
+	require.NoError(t, err)
+	addMutation(t, l, &pb.DirectedEdge{Attr: friend, Entity: 2, ValueId: 3}, Set, 33, 34, false)
+	report, err = Fsck(ctx, friend, 35, 0)
+	require.NoError(t, err)
+	var indexes []string
+	for _, issue := range report.Issues {
+		indexes = append(indexes, issue.Index)
+	}
+	require.ElementsMatch(t, []string{"reverse", "count", "count-reverse", "count-reverse"},
+		indexes)
+
+	_, err = Fsck(ctx, x.AttrInRootNamespace("fsck_missing"), 35, 0)
+	require.Error(t, err)
+}
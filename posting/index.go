@@ -373,6 +373,7 @@ func (txn *Txn) addReverseAndCountMutation(ctx context.Context, t *pb.DirectedEd
 
 func (l *List) handleDeleteAll(ctx context.Context, edge *pb.DirectedEdge, txn *Txn) error {
 	isReversed := schema.State().IsReversed(ctx, edge.Attr)
+	isAsyncReversed := isReversed && schema.State().HasAsyncReverse(ctx, edge.Attr)
 	isIndexed := schema.State().IsIndexed(ctx, edge.Attr)
 	hasCount := schema.State().HasCount(ctx, edge.Attr)
 	delEdge := &pb.DirectedEdge{
@@ -385,6 +386,9 @@ func (l *List) handleDeleteAll(ctx context.Context, edge *pb.DirectedEdge, txn *
 	err := l.Iterate(txn.StartTs, 0, func(p *pb.Posting) error {
 		plen++
 		switch {
+		case isAsyncReversed:
+			delEdge.ValueId = p.Uid
+			return txn.addAsyncReverse(ctx, delEdge)
 		case isReversed:
 			// Delete reverse edge for each posting.
 			delEdge.ValueId = p.Uid
@@ -467,6 +471,10 @@ func (txn *Txn) updateCount(ctx context.Context, params countParams) error {
 			return err
 		}
 	}
+	txn.Lock()
+	defer txn.Unlock()
+	txn.countUpdates = append(txn.countUpdates, countUpdate{attr: params.attr,
+		reverse: params.reverse, before: params.countBefore, after: params.countAfter})
 	return nil
 }
 
@@ -600,7 +608,11 @@ func (l *List) AddMutationWithIndex(ctx context.Context, edge *pb.DirectedEdge,
 	// Add reverse mutation irrespective of hasMutated, server crash can happen after
 	// mutation is synced and before reverse edge is synced
 	if (pstore != nil) && (edge.ValueId != 0) && schema.State().IsReversed(ctx, edge.Attr) {
-		if err := txn.addReverseAndCountMutation(ctx, edge); err != nil {
+		if schema.State().HasAsyncReverse(ctx, edge.Attr) {
+			if err := txn.addAsyncReverse(ctx, edge); err != nil {
+				return err
+			}
+		} else if err := txn.addReverseAndCountMutation(ctx, edge); err != nil {
 			return err
 		}
 	}
@@ -733,6 +745,10 @@ func (r *rebuilder) RunWithoutTemp(ctx context.Context) error {
 				// more versions of the same key.
 			case BitDeltaPosting:
 				err := item.Value(func(val []byte) error {
+					val, err := openListValue(item.Key(), val)
+					if err != nil {
+						return err
+					}
 					pl := &pb.PostingList{}
 					if err := proto.Unmarshal(val, pl); err != nil {
 						return err
@@ -848,9 +848,21 @@ func GetConflictKey(pk x.ParsedKey, key []byte, t *pb.DirectedEdge) uint64 {
 	}
 
 	var conflictKey uint64
-	switch {
+	switch policy := schema.State().ConflictPolicy(t.Attr); {
 	case schema.State().HasNoConflict(t.Attr):
 		break
+	case policy == schema.ConflictUid:
+		// Only the data keys conflict, whatever the values, so that the mutations of the
+		// different subjects never conflict on the index or count keys.
+		if pk.IsData() {
+			conflictKey = getKey(key, 0)
+		}
+	case policy == schema.ConflictValue:
+		// Only the same values of the same subject conflict. For a scalar, t.ValueId is the same
+		// for all the values without a language, so they still conflict.
+		if pk.IsData() {
+			conflictKey = getKey(key, t.ValueId)
+		}
 	case schema.State().HasUpsert(t.Attr):
 		// Consider checking to see if a email id is unique. A user adds:
 		// <uid> <email> "email@email.org", and there's a string equal tokenizer
@@ -1453,6 +1465,11 @@ func (l *List) Rollup(alloc *z.Allocator, readTs uint64) ([]*bpb.KV, error) {
 
 	x.PrintRollup(out.plist, out.parts, l.key, kv.Version)
 	x.VerifyPostingSplits(kvs, out.plist, out.parts, l.key)
+	for _, kv := range kvs {
+		if kv.Value, err = sealListValue(kv.Key, kv.Value); err != nil {
+			return nil, errors.Wrapf(err, "while sealing rolled up list")
+		}
+	}
 	return kvs, nil
 }
 
@@ -2091,6 +2108,19 @@ func (l *List) postingForLangs(readTs uint64, langs []string) (*pb.Posting, erro
 			any = true
 			break
 		}
+		if lang == "" {
+			// The value without language, given at the end of the language fallback chains.
+			found, pos, err := l.findPosting(readTs, math.MaxUint64)
+			if err != nil {
+				return nil, errors.Wrapf(err,
+					"cannot find value without language tag from list with key %s",
+					hex.EncodeToString(l.key))
+			}
+			if found {
+				return pos, nil
+			}
+			continue
+		}
 		pos, err := l.postingForTag(readTs, lang)
 		if err == nil {
 			return pos, nil
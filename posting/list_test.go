@@ -251,6 +251,29 @@ func TestAddMutation_Value(t *testing.T) {
 	checkValue(t, ol, "119", txn.StartTs)
 }
 
+func TestValueForLangs(t *testing.T) {
+	key := x.DataKey(x.AttrInRootNamespace("name"), 11)
+	ol, err := readPostingListFromDisk(key, ps, math.MaxUint64)
+	require.NoError(t, err)
+	txn := &Txn{StartTs: 1}
+	addMutationHelper(t, ol, &pb.DirectedEdge{Value: []byte("Alice")}, Set, txn)
+	addMutationHelper(t, ol, &pb.DirectedEdge{Value: []byte("Alicia"), Lang: "es"}, Set, txn)
+
+	valueFor := func(langs ...string) string {
+		val, err := ol.ValueFor(txn.StartTs, langs)
+		if err == ErrNoValue {
+			return ""
+		}
+		require.NoError(t, err)
+		return string(val.Value.([]byte))
+	}
+	require.Equal(t, "Alicia", valueFor("fr", "es", ""))
+	// The value without language ends the fallback chains.
+	require.Equal(t, "Alice", valueFor("fr", "", "es"))
+	require.Equal(t, "Alice", valueFor("fr", ""))
+	require.Equal(t, "", valueFor("fr"))
+}
+
 func TestAddMutation_jchiu1(t *testing.T) {
 	key := x.DataKey(x.AttrInRootNamespace(x.AttrInRootNamespace("value")), 12)
 	ol, err := GetNoStore(key, math.MaxUint64)
@@ -1794,6 +1817,38 @@ func TestRecursiveSplits(t *testing.T) {
 
 var ps *badger.DB
 
+func TestConflictPolicy(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`
+		tags: [string] @index(exact) @conflict(value) .
+		counter: int @index(int) @conflict(uid) .
+		name: string @index(exact) .
+	`), 1))
+	conflictKey := func(attr string, indexKey bool, uid uint64, value string) uint64 {
+		attr = x.AttrInRootNamespace(attr)
+		key := x.DataKey(attr, uid)
+		if indexKey {
+			key = x.IndexKey(attr, value)
+		}
+		pk, err := x.Parse(key)
+		require.NoError(t, err)
+		edge := &pb.DirectedEdge{Attr: attr, Entity: uid, Value: []byte(value)}
+		edge.ValueId = fingerprintEdge(edge)
+		if indexKey {
+			edge.ValueId = uid
+		}
+		return GetConflictKey(pk, key, edge)
+	}
+
+	// The different values of the same subject don't conflict.
+	require.NotEqual(t, conflictKey("tags", false, 1, "a"), conflictKey("tags", false, 1, "b"))
+	require.Zero(t, conflictKey("tags", true, 1, "a"))
+	// All the values of the same subject conflict.
+	require.Equal(t, conflictKey("counter", false, 1, "1"), conflictKey("counter", false, 1, "2"))
+	require.Zero(t, conflictKey("counter", true, 1, "1"))
+	// The default policy conflicts on the index keys too.
+	require.NotZero(t, conflictKey("name", true, 1, "a"))
+}
+
 func TestMain(m *testing.M) {
 	Config.CommitFraction = 0.10
 
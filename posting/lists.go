@@ -324,6 +324,10 @@ func (lc *LocalCache) readPostingListAt(key []byte) (*pb.PostingList, error) {
 	}
 
 	err = item.Value(func(val []byte) error {
+		val, err := openListValue(key, val)
+		if err != nil {
+			return err
+		}
 		return proto.Unmarshal(val, pl)
 	})
 
@@ -90,6 +90,12 @@ func init() {
 
 // rollUpKey takes the given key's posting lists, rolls it up and writes back to badger
 func (ir *incrRollupi) rollUpKey(writer *TxnWriter, key []byte) error {
+	if pk, err := x.Parse(key); err == nil && pk.IsReverse() {
+		// The async reverse edges written at a lower ts than the rollup would be hidden by it.
+		asyncReverseLock.RLock()
+		defer asyncReverseLock.RUnlock()
+	}
+
 	// Get a new non read only ts. This makes sure that no other txn would write at this
 	// ts, overwriting some data. Wait to read the Posting list until ts-1 have been applied
 	// to badger. This helps us prevent issues with wal replay, as we now have a timestamp
@@ -306,7 +312,11 @@ func (txn *Txn) CommitToDisk(writer *TxnWriter, commitTs uint64) error {
 					// not output anything here.
 					continue
 				}
-				err := btxn.SetEntry(&badger.Entry{
+				data, err := sealValue([]byte(key), data)
+				if err != nil {
+					return err
+				}
+				err = btxn.SetEntry(&badger.Entry{
 					Key:      []byte(key),
 					Value:    data,
 					UserMeta: BitDeltaPosting,
@@ -396,10 +406,19 @@ type MemoryLayer struct {
 
 	// metrics
 	statsHolder *StatsHolder
+
+	// presence bitmaps used by has()
+	presence *presenceIndex
+
+	// commit timestamps of the predicates
+	versions *predVersions
 }
 
 func (ml *MemoryLayer) clear() {
 	ml.cache.clear()
+	ml.presence.clear()
+	ml.versions.clear()
+	ml.statsHolder.clearCountHistograms()
 }
 func (ml *MemoryLayer) del(key []byte) {
 	ml.cache.del(key)
@@ -507,6 +526,8 @@ func initMemoryLayer(cacheSize int64, removeOnUpdate bool) *MemoryLayer {
 	ml := &MemoryLayer{}
 	ml.removeOnUpdate = removeOnUpdate
 	ml.statsHolder = NewStatsHolder()
+	ml.presence = newPresenceIndex()
+	ml.versions = newPredVersions()
 	if cacheSize > 0 {
 		cache, err := ristretto.NewCache(&ristretto.Config[[]byte, *CachePL]{
 			// Use 5% of cache memory for storing counters.
@@ -613,6 +634,16 @@ func (txn *Txn) UpdateCachedKeys(commitTs uint64) {
 	MemLayerInstance.wait()
 	for key, delta := range txn.cache.deltas {
 		MemLayerInstance.updateItemInCache(key, delta, txn.StartTs, commitTs)
+		if EnablePresenceBitmap && commitTs > 0 {
+			MemLayerInstance.presence.applyCommit([]byte(key), delta, commitTs)
+		}
+	}
+	if commitTs > 0 {
+		MemLayerInstance.versions.applyCommit(txn.cache.deltas, commitTs)
+		txn.Lock()
+		updates := txn.countUpdates
+		txn.Unlock()
+		MemLayerInstance.statsHolder.applyCountUpdates(updates, commitTs)
 	}
 }
 
@@ -627,6 +658,10 @@ func unmarshalOrCopy(plist *pb.PostingList, item *badger.Item) error {
 			// empty pl
 			return nil
 		}
+		val, err := openListValue(item.Key(), val)
+		if err != nil {
+			return err
+		}
 		return proto.Unmarshal(val, plist)
 	})
 }
@@ -710,6 +745,10 @@ func ReadPostingList(key []byte, it *badger.Iterator) (*List, error) {
 			return l, nil
 		case BitDeltaPosting:
 			err := item.Value(func(val []byte) error {
+				val, err := openListValue(item.Key(), val)
+				if err != nil {
+					return err
+				}
 				pl := &pb.PostingList{}
 				if err := proto.Unmarshal(val, pl); err != nil {
 					return err
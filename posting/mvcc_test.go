@@ -222,3 +222,38 @@ func TestPostingListRead(t *testing.T) {
 	addEdgeToUID(t, attr, 1, 7, 15, 16)
 	assertLength(17, 3)
 }
+
+func TestTxnMaxDuration(t *testing.T) {
+	const startTs = 1001
+	txn := Oracle().RegisterStartTs(startTs)
+	defer Oracle().ProcessDelta(&pb.OracleDelta{Txns: []*pb.TxnStatus{{StartTs: startTs}}})
+	txn.AddNamespaces([]*pb.DirectedEdge{
+		{Attr: x.AttrInRootNamespace("name")},
+		{Attr: x.NamespaceAttr(2, "name")},
+	})
+
+	limits := map[uint64]time.Duration{2: time.Minute}
+	maxDuration := func(ns uint64) time.Duration { return limits[ns] }
+	require.False(t, txn.Exceeds(maxDuration, time.Now()))
+	require.True(t, txn.Exceeds(maxDuration, time.Now().Add(2*time.Minute)))
+	require.Empty(t, Oracle().TxnsExceeding(maxDuration))
+	limits[2] = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	require.Equal(t, []uint64{startTs}, Oracle().TxnsExceeding(maxDuration))
+
+	// A heartbeat keeps the txn from being idle, but not from exceeding its maximum duration.
+	time.Sleep(10 * time.Millisecond)
+	require.True(t, Oracle().KeepAlive(startTs))
+	require.NotContains(t, Oracle().TxnOlderThan(5*time.Millisecond), uint64(startTs))
+	require.True(t, txn.Exceeds(maxDuration, time.Now()))
+	require.False(t, Oracle().KeepAlive(startTs+1))
+
+	var info TxnInfo
+	for _, i := range Oracle().PendingTxns() {
+		if i.StartTs == startTs {
+			info = i
+		}
+	}
+	require.Equal(t, []uint64{0, 2}, info.Namespaces)
+	require.True(t, info.LastUpdate.After(info.Started))
+}
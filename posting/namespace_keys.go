@@ -0,0 +1,90 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/enc"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// nsKeys holds the per-namespace data keys. When set, the posting lists of the namespaces
+// that have a key are sealed before being written to Badger. Lists written before a key was
+// added (or by the bulk loader and restore) stay readable, and get sealed when they are
+// rolled up.
+var nsKeys atomic.Pointer[enc.NamespaceKeys]
+
+// SetNamespaceKeys sets the keys used to seal the posting lists of the namespaces.
+func SetNamespaceKeys(k *enc.NamespaceKeys) {
+	nsKeys.Store(k)
+}
+
+// NamespaceKeyStatus returns the status of the namespace keys, and false if they aren't set.
+func NamespaceKeyStatus() (enc.KeyStatus, bool) {
+	k := nsKeys.Load()
+	if k == nil {
+		return enc.KeyStatus{}, false
+	}
+	return k.Status(), true
+}
+
+// ReloadNamespaceKeys reloads the namespace keys from disk. If the key of a namespace was
+// destroyed, the cache is reset so that its data can no longer be served from memory.
+func ReloadNamespaceKeys() error {
+	k := nsKeys.Load()
+	if k == nil {
+		return nil
+	}
+	removed, err := k.Reload()
+	if err != nil {
+		return err
+	}
+	if len(removed) > 0 {
+		glog.Infof("Encryption keys were removed for namespaces %#x. Resetting cache.", removed)
+		ResetCache()
+	}
+	return nil
+}
+
+// keyNamespace returns the namespace of a posting list key, and false for the keys which
+// don't hold posting lists, like schema and type keys.
+func keyNamespace(key []byte) (uint64, bool) {
+	if len(key) < 9 || (key[0] != x.DefaultPrefix && key[0] != x.ByteSplit) {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(key[1:9]), true
+}
+
+// sealValue encrypts the value of a posting list key with the key of its namespace, if any.
+func sealValue(key, val []byte) ([]byte, error) {
+	k := nsKeys.Load()
+	if k == nil {
+		return val, nil
+	}
+	ns, ok := keyNamespace(key)
+	if !ok {
+		return val, nil
+	}
+	return k.Seal(ns, key, val)
+}
+
+// openValue decrypts a value sealed by sealValue. Values that aren't sealed are returned as is.
+func openValue(key, val []byte) ([]byte, error) {
+	if !enc.IsSealed(val) {
+		return val, nil
+	}
+	ns, ok := keyNamespace(key)
+	k := nsKeys.Load()
+	if !ok || k == nil {
+		return nil, errors.Wrapf(enc.ErrNamespaceKeyNotFound, "while reading key %x", key)
+	}
+	return k.Open(ns, key, val)
+}
@@ -0,0 +1,121 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/enc"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func rawValue(t *testing.T, key []byte, readTs uint64) []byte {
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	item, err := txn.Get(key)
+	require.NoError(t, err)
+	val, err := item.ValueCopy(nil)
+	require.NoError(t, err)
+	return val
+}
+
+func TestNamespaceKeys(t *testing.T) {
+	master := []byte("0123456789abcdef")
+	dir := t.TempDir()
+	wrapped, err := enc.WrapNamespaceKey(master, []byte("fedcba9876543210"))
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "2.1.key")
+	require.NoError(t, os.WriteFile(keyFile, wrapped, 0600))
+
+	keys, err := enc.LoadNamespaceKeys(dir, master, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]uint32{2: 1}, keys.Namespaces())
+	SetNamespaceKeys(keys)
+	defer SetNamespaceKeys(nil)
+
+	sealed := x.DataKey(x.NamespaceAttr(2, "secret"), 1)
+	plain := x.DataKey(x.NamespaceAttr(3, "secret"), 1)
+	commitPresenceDelta(t, x.NamespaceAttr(2, "secret"), 1, Set, 5, 10)
+	commitPresenceDelta(t, x.NamespaceAttr(3, "secret"), 1, Set, 5, 10)
+	require.True(t, enc.IsSealed(rawValue(t, sealed, 20)))
+	require.False(t, enc.IsSealed(rawValue(t, plain, 20)))
+
+	l, err := GetNoStore(sealed, 20)
+	require.NoError(t, err)
+	uids, err := l.Uids(ListOptions{ReadTs: 20})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{100}, uids.Uids)
+
+	// Rolled up lists are sealed as well.
+	kvs, err := l.Rollup(nil, math.MaxUint64)
+	require.NoError(t, err)
+	require.Len(t, kvs, 1)
+	require.True(t, enc.IsSealed(kvs[0].Value))
+	require.NoError(t, writePostingListToDisk(kvs))
+	l, err = GetNoStore(sealed, math.MaxUint64)
+	require.NoError(t, err)
+	uids, err = l.Uids(ListOptions{ReadTs: math.MaxUint64})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{100}, uids.Uids)
+
+	// Destroying the key makes the data of the namespace unreadable.
+	require.NoError(t, os.Remove(keyFile))
+	require.NoError(t, ReloadNamespaceKeys())
+	_, err = GetNoStore(sealed, math.MaxUint64)
+	require.ErrorIs(t, err, enc.ErrNamespaceKeyNotFound)
+	_, err = GetNoStore(plain, 20)
+	require.NoError(t, err)
+}
+
+type testKms struct {
+	calls int
+}
+
+func (k *testKms) Name() string {
+	return "test"
+}
+
+func (k *testKms) Decrypt(_ context.Context, ciphertext []byte) (x.Sensitive, error) {
+	k.calls++
+	return x.Sensitive(strings.TrimPrefix(string(ciphertext), "kms:")), nil
+}
+
+func TestNamespaceKeysKms(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2.1.key"),
+		[]byte("kms:0123456789abcdef\n"), 0600))
+
+	kms := &testKms{}
+	keys, err := enc.LoadNamespaceKeys(dir, nil, kms)
+	require.NoError(t, err)
+	require.Equal(t, 1, kms.calls)
+
+	// Only the new files are decrypted on reload.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2.2.key"),
+		[]byte("kms:fedcba9876543210"), 0600))
+	_, err = keys.Reload()
+	require.NoError(t, err)
+	require.Equal(t, 2, kms.calls)
+	status := keys.Status()
+	require.Equal(t, map[uint64]uint32{2: 2}, status.Versions)
+	require.NoError(t, status.LastError)
+	require.False(t, status.LastReload.IsZero())
+
+	// A failed reload keeps the keys loaded before.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2.3.key"), []byte("kms:short"), 0600))
+	_, err = keys.Reload()
+	require.Error(t, err)
+	status = keys.Status()
+	require.Equal(t, map[uint64]uint32{2: 2}, status.Versions)
+	require.Error(t, status.LastError)
+}
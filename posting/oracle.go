@@ -9,6 +9,7 @@ import (
 	"context"
 	"encoding/hex"
 	"math"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -52,6 +53,16 @@ type Txn struct {
 	// Keeps track of last update wall clock. We use this fact later to
 	// determine unhealthy, stale txns.
 	lastUpdate time.Time
+	// started is the wall clock when the first mutation of the txn was applied.
+	started time.Time
+	// namespaces are the namespaces the txn has mutated.
+	namespaces map[uint64]struct{}
+	// asyncReverse are the reverse edges of the predicates with @reverse(async), which are added
+	// once the transaction is committed.
+	asyncReverse []*pb.DirectedEdge
+	// countUpdates are the changes of the counts of the predicates with @count, which are applied
+	// to their histograms once the transaction is committed.
+	countUpdates []countUpdate
 
 	cache *LocalCache // This pointer does not get modified.
 }
@@ -138,13 +149,72 @@ func (vt *viTxn) UnlockKey(key []byte) {
 
 // NewTxn returns a new Txn instance.
 func NewTxn(startTs uint64) *Txn {
+	now := time.Now()
 	return &Txn{
 		StartTs:    startTs,
 		cache:      NewLocalCache(startTs),
-		lastUpdate: time.Now(),
+		lastUpdate: now,
+		started:    now,
 	}
 }
 
+// AddNamespaces records the namespaces mutated by the edges.
+func (txn *Txn) AddNamespaces(edges []*pb.DirectedEdge) {
+	txn.Lock()
+	defer txn.Unlock()
+	if txn.namespaces == nil {
+		txn.namespaces = make(map[uint64]struct{})
+	}
+	for _, edge := range edges {
+		txn.namespaces[x.ParseNamespace(edge.Attr)] = struct{}{}
+	}
+}
+
+// Exceeds returns true if the txn has been running for longer than the maximum duration of any
+// of the namespaces it has mutated. A zero maximum duration doesn't limit the txn.
+func (txn *Txn) Exceeds(maxDuration func(ns uint64) time.Duration, now time.Time) bool {
+	txn.Lock()
+	defer txn.Unlock()
+	for ns := range txn.namespaces {
+		if d := maxDuration(ns); d > 0 && now.Sub(txn.started) > d {
+			return true
+		}
+	}
+	return false
+}
+
+// TxnInfo describes a pending transaction.
+type TxnInfo struct {
+	StartTs    uint64
+	Namespaces []uint64
+	Started    time.Time
+	LastUpdate time.Time
+	// Keys is the number of posting lists the txn has mutated, and Size the size of its deltas.
+	Keys int
+	Size int
+}
+
+// Info returns the description of the txn.
+func (txn *Txn) Info() TxnInfo {
+	txn.Lock()
+	info := TxnInfo{StartTs: txn.StartTs, Started: txn.started}
+	for ns := range txn.namespaces {
+		info.Namespaces = append(info.Namespaces, ns)
+	}
+	txn.Unlock()
+	sort.Slice(info.Namespaces, func(i, j int) bool {
+		return info.Namespaces[i] < info.Namespaces[j]
+	})
+
+	txn.cache.RLock()
+	info.Keys = len(txn.cache.deltas)
+	for _, delta := range txn.cache.deltas {
+		info.Size += len(delta)
+	}
+	txn.cache.RUnlock()
+	return info
+}
+
 // Get retrieves the posting list for the given list from the local cache.
 func (txn *Txn) Get(key []byte) (*List, error) {
 	return txn.cache.Get(key)
@@ -272,6 +342,47 @@ func (o *oracle) TxnOlderThan(dur time.Duration) (res []uint64) {
 	return res
 }
 
+// TxnsExceeding returns the start ts of the pending txns which have been running for longer
+// than the maximum duration of their namespaces.
+func (o *oracle) TxnsExceeding(maxDuration func(ns uint64) time.Duration) (res []uint64) {
+	o.RLock()
+	defer o.RUnlock()
+
+	now := time.Now()
+	for startTs, txn := range o.pendingTxns {
+		if txn.Exceeds(maxDuration, now) {
+			res = append(res, startTs)
+		}
+	}
+	return res
+}
+
+// KeepAlive marks the pending txn as active now, so that it isn't aborted for being idle. It
+// returns false if the txn isn't pending.
+func (o *oracle) KeepAlive(startTs uint64) bool {
+	o.Lock()
+	defer o.Unlock()
+	txn, ok := o.pendingTxns[startTs]
+	if ok {
+		txn.lastUpdate = time.Now()
+	}
+	return ok
+}
+
+// PendingTxns describes the pending txns, sorted by start ts.
+func (o *oracle) PendingTxns() []TxnInfo {
+	o.RLock()
+	res := make([]TxnInfo, 0, len(o.pendingTxns))
+	for _, txn := range o.pendingTxns {
+		info := txn.Info()
+		info.LastUpdate = txn.lastUpdate
+		res = append(res, info)
+	}
+	o.RUnlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].StartTs < res[j].StartTs })
+	return res
+}
+
 func (o *oracle) addToWaiters(startTs uint64) (chan struct{}, bool) {
 	if startTs <= o.MaxAssigned() {
 		return nil, false
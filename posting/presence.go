@@ -0,0 +1,409 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"math/bits"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/golang/glog"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// Presence bitmaps are used to answer has(pred) at root without iterating over the whole tablet.
+// For each predicate we keep one container per range of presenceRangeSize uids, holding the uids
+// in that range which have a data posting list. The bitmaps of a predicate are built the first
+// time has() scans the whole tablet, and are then kept up to date as transactions commit.
+//
+// A range can only be served from memory if it reflects the state of the tablet as of the read
+// timestamp of the query. Ranges that saw a deletion are marked stale, because finding out
+// whether the posting list became empty requires reading it. Stale ranges, and ranges updated
+// by a transaction that committed after the read timestamp, are read from disk instead, and
+// stale ranges get rebuilt from that read.
+
+const (
+	presenceRangeBits = 16
+	presenceRangeSize = 1 << presenceRangeBits
+	// presenceArrayMax is the number of uids after which a container switches from a sorted
+	// array to a bitmap. At this point both take 8KB.
+	presenceArrayMax = presenceRangeSize / 16
+)
+
+var (
+	// EnablePresenceBitmap decides whether presence bitmaps are maintained for has().
+	EnablePresenceBitmap bool
+)
+
+// SetEnablePresenceBitmap enables or disables the use of presence bitmaps.
+func SetEnablePresenceBitmap(enable bool) {
+	EnablePresenceBitmap = enable
+}
+
+type presenceContainer struct {
+	// ts is the highest timestamp reflected in this container.
+	ts    uint64
+	stale bool
+
+	array  []uint16
+	bitmap []uint64
+	n      int
+}
+
+func (c *presenceContainer) add(low uint16) {
+	if c.bitmap != nil {
+		w, b := low>>6, uint64(1)<<(low&63)
+		if c.bitmap[w]&b == 0 {
+			c.bitmap[w] |= b
+			c.n++
+		}
+		return
+	}
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= low })
+	if i < len(c.array) && c.array[i] == low {
+		return
+	}
+	c.array = slices.Insert(c.array, i, low)
+	c.n++
+	if len(c.array) > presenceArrayMax {
+		c.bitmap = make([]uint64, presenceRangeSize/64)
+		for _, v := range c.array {
+			c.bitmap[v>>6] |= 1 << (v & 63)
+		}
+		c.array = nil
+	}
+}
+
+// uids returns the uids stored in the container in increasing order.
+func (c *presenceContainer) uids(base uint64) []uint64 {
+	out := make([]uint64, 0, c.n)
+	if c.bitmap == nil {
+		for _, v := range c.array {
+			out = append(out, base|uint64(v))
+		}
+		return out
+	}
+	for w, word := range c.bitmap {
+		for word != 0 {
+			b := bits.TrailingZeros64(word)
+			out = append(out, base|uint64(w)<<6|uint64(b))
+			word &^= 1 << b
+		}
+	}
+	return out
+}
+
+func (c *presenceContainer) size() int {
+	return 2*len(c.array) + 8*len(c.bitmap)
+}
+
+type predPresence struct {
+	sync.RWMutex
+
+	// buildTs is the read timestamp of the scan used to build the bitmaps. Ranges which have no
+	// container are known to be empty as of this timestamp.
+	buildTs uint64
+	ranges  map[uint64]*presenceContainer
+}
+
+// container returns the container for the given range, creating it if needed. Must be called
+// with the lock held.
+func (pp *predPresence) container(r uint64) *presenceContainer {
+	c, ok := pp.ranges[r]
+	if !ok {
+		c = &presenceContainer{}
+		pp.ranges[r] = c
+	}
+	return c
+}
+
+type presenceIndex struct {
+	sync.RWMutex
+
+	// epoch is bumped every time the index is cleared, so that scans which started before
+	// the clear don't install outdated bitmaps.
+	epoch uint64
+	preds map[string]*predPresence
+	// lastCommit records the commit timestamp of the latest transaction touching a predicate
+	// which doesn't have bitmaps yet.
+	lastCommit map[string]uint64
+}
+
+func newPresenceIndex() *presenceIndex {
+	return &presenceIndex{
+		preds:      make(map[string]*predPresence),
+		lastCommit: make(map[string]uint64),
+	}
+}
+
+func (pi *presenceIndex) clear() {
+	pi.Lock()
+	defer pi.Unlock()
+	pi.epoch++
+	pi.preds = make(map[string]*predPresence)
+	pi.lastCommit = make(map[string]uint64)
+}
+
+func (pi *presenceIndex) get(attr string) *predPresence {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.preds[attr]
+}
+
+// applyCommit updates the bitmaps with the delta committed for the given key.
+func (pi *presenceIndex) applyCommit(key []byte, delta []byte, commitTs uint64) {
+	pk, err := x.Parse(key)
+	if err != nil || !pk.IsData() {
+		return
+	}
+
+	pi.Lock()
+	pp, ok := pi.preds[pk.Attr]
+	if !ok {
+		if commitTs > pi.lastCommit[pk.Attr] {
+			pi.lastCommit[pk.Attr] = commitTs
+		}
+		pi.Unlock()
+		return
+	}
+	pi.Unlock()
+
+	present := false
+	p := new(pb.PostingList)
+	if err := proto.Unmarshal(delta, p); err == nil && p.Pack == nil && len(p.Postings) > 0 {
+		present = true
+		for _, mpost := range p.Postings {
+			if mpost.Op != Set && mpost.Op != Ovr {
+				present = false
+				break
+			}
+		}
+	}
+
+	pp.Lock()
+	defer pp.Unlock()
+	c := pp.container(pk.Uid >> presenceRangeBits)
+	if commitTs > c.ts {
+		c.ts = commitTs
+	}
+	if !present {
+		// We can't tell whether the posting list is empty now. Read this range from disk
+		// until it gets rebuilt.
+		c.stale, c.array, c.bitmap, c.n = true, nil, nil, 0
+		return
+	}
+	if !c.stale {
+		c.add(uint16(pk.Uid))
+	}
+}
+
+// PresenceEpoch returns the current epoch of the presence bitmaps. It must be read before
+// starting a scan whose result is later passed to BuildPresence.
+func (ml *MemoryLayer) PresenceEpoch() uint64 {
+	ml.presence.RLock()
+	defer ml.presence.RUnlock()
+	return ml.presence.epoch
+}
+
+// HasPresence returns whether presence bitmaps are available for the predicate.
+func (ml *MemoryLayer) HasPresence(attr string) bool {
+	return EnablePresenceBitmap && ml.presence.get(attr) != nil
+}
+
+// BuildPresence installs the bitmaps for a predicate, given all the uids that have a data
+// posting list for it as of readTs. The bitmaps are discarded if a transaction touching the
+// predicate committed after readTs or the index was cleared since epoch was read.
+func (ml *MemoryLayer) BuildPresence(attr string, epoch, readTs uint64, uids []uint64) {
+	if !EnablePresenceBitmap {
+		return
+	}
+	pp := &predPresence{buildTs: readTs, ranges: make(map[uint64]*presenceContainer)}
+	for _, uid := range uids {
+		c := pp.container(uid >> presenceRangeBits)
+		c.ts = readTs
+		c.add(uint16(uid))
+	}
+
+	pi := ml.presence
+	pi.Lock()
+	defer pi.Unlock()
+	if pi.epoch != epoch || pi.lastCommit[attr] > readTs {
+		return
+	}
+	if _, ok := pi.preds[attr]; ok {
+		return
+	}
+	pi.preds[attr] = pp
+	delete(pi.lastCommit, attr)
+	glog.V(2).Infof("Built presence bitmap for %s with %d uids at ts: %d",
+		x.ParseAttr(attr), len(uids), readTs)
+}
+
+// IteratePresence calls fn in increasing order for every uid greater than afterUid that has a
+// data posting list for attr as of readTs. Iteration stops without an error if fn returns
+// ErrStopIteration. It returns false, without calling fn, if the bitmaps can't be used for
+// this read.
+func (ml *MemoryLayer) IteratePresence(ctx context.Context, attr string, readTs, afterUid uint64,
+	fn func(uid uint64) error) (bool, error) {
+	if !EnablePresenceBitmap {
+		return false, nil
+	}
+	pp := ml.presence.get(attr)
+	if pp == nil {
+		return false, nil
+	}
+
+	pp.RLock()
+	if pp.buildTs > readTs {
+		pp.RUnlock()
+		return false, nil
+	}
+	rangeIds := make([]uint64, 0, len(pp.ranges))
+	for r := range pp.ranges {
+		if r >= afterUid>>presenceRangeBits {
+			rangeIds = append(rangeIds, r)
+		}
+	}
+	pp.RUnlock()
+	slices.Sort(rangeIds)
+
+	for _, r := range rangeIds {
+		base := r << presenceRangeBits
+		pp.RLock()
+		c := pp.ranges[r]
+		fromDisk := c.stale || c.ts > readTs
+		staleTs := c.ts
+		var uids []uint64
+		if !fromDisk {
+			uids = c.uids(base)
+		}
+		pp.RUnlock()
+
+		if fromDisk {
+			var err error
+			var complete bool
+			uids, complete, err = ml.readPresenceRange(ctx, attr, readTs, base)
+			if err != nil {
+				return true, err
+			}
+			if complete && c.stale && staleTs <= readTs {
+				pp.rebuildRange(r, staleTs, readTs, uids)
+			}
+		}
+
+		for _, uid := range uids {
+			if uid <= afterUid {
+				continue
+			}
+			if err := fn(uid); err == ErrStopIteration {
+				return true, nil
+			} else if err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// readPresenceRange reads the uids within the range starting at base from disk.
+func (ml *MemoryLayer) readPresenceRange(ctx context.Context, attr string, readTs, base uint64) (
+	[]uint64, bool, error) {
+	var uids []uint64
+	complete := true
+	pk := x.ParsedKey{Attr: attr}
+	err := ml.IterateDisk(ctx, IterateDiskArgs{
+		Prefix:      pk.DataPrefix(),
+		StartKey:    x.DataKey(attr, base),
+		ReadTs:      readTs,
+		AllVersions: true,
+		CheckInclusion: func(uint64) error {
+			return nil
+		},
+		Function: func(l *List, pk x.ParsedKey) error {
+			if pk.Uid >= base+presenceRangeSize {
+				return ErrStopIteration
+			}
+			uids = append(uids, pk.Uid)
+			return nil
+		},
+	})
+	if err != nil {
+		complete = false
+	}
+	return uids, complete, err
+}
+
+// rebuildRange replaces a stale range with the uids read from disk at readTs, as long as no
+// other transaction touched it since it was found stale.
+func (pp *predPresence) rebuildRange(r, staleTs, readTs uint64, uids []uint64) {
+	pp.Lock()
+	defer pp.Unlock()
+	c, ok := pp.ranges[r]
+	if !ok || !c.stale || c.ts != staleTs {
+		return
+	}
+	if len(uids) == 0 && readTs >= pp.buildTs {
+		// An empty range is the same as a missing one.
+		delete(pp.ranges, r)
+		return
+	}
+	nc := &presenceContainer{ts: readTs}
+	for _, uid := range uids {
+		nc.add(uint16(uid))
+	}
+	pp.ranges[r] = nc
+}
+
+// PresenceStat holds the stats of the presence bitmaps of a predicate.
+type PresenceStat struct {
+	Predicate   string `json:"predicate"`
+	Namespace   uint64 `json:"namespace"`
+	Uids        int    `json:"uids"`
+	Ranges      int    `json:"ranges"`
+	StaleRanges int    `json:"staleRanges"`
+	SizeBytes   int    `json:"sizeBytes"`
+	BuildTs     uint64 `json:"buildTs"`
+}
+
+// PresenceStats returns the stats of all the presence bitmaps held by this alpha.
+func (ml *MemoryLayer) PresenceStats() []PresenceStat {
+	ml.presence.RLock()
+	preds := make(map[string]*predPresence, len(ml.presence.preds))
+	for attr, pp := range ml.presence.preds {
+		preds[attr] = pp
+	}
+	ml.presence.RUnlock()
+
+	stats := make([]PresenceStat, 0, len(preds))
+	for attr, pp := range preds {
+		ns, pred := x.ParseNamespaceAttr(attr)
+		stat := PresenceStat{Predicate: pred, Namespace: ns}
+		pp.RLock()
+		stat.BuildTs = pp.buildTs
+		stat.Ranges = len(pp.ranges)
+		for _, c := range pp.ranges {
+			stat.Uids += c.n
+			stat.SizeBytes += c.size()
+			if c.stale {
+				stat.StaleRanges++
+			}
+		}
+		pp.RUnlock()
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Namespace != stats[j].Namespace {
+			return stats[i].Namespace < stats[j].Namespace
+		}
+		return stats[i].Predicate < stats[j].Predicate
+	})
+	return stats
+}
@@ -0,0 +1,106 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func commitPresenceDelta(t *testing.T, attr string, uid uint64, op uint32, startTs, commitTs uint64) {
+	p := new(pb.PostingList)
+	p.Postings = []*pb.Posting{{
+		Uid:      100,
+		StartTs:  startTs,
+		CommitTs: commitTs,
+		Op:       op,
+	}}
+	delta, err := proto.Marshal(p)
+	require.NoError(t, err)
+
+	txn := Oracle().RegisterStartTs(startTs)
+	txn.cache.deltas[string(x.DataKey(attr, uid))] = delta
+	writer := NewTxnWriter(pstore)
+	require.NoError(t, txn.CommitToDisk(writer, commitTs))
+	require.NoError(t, writer.Flush())
+	txn.UpdateCachedKeys(commitTs)
+}
+
+func presenceUids(t *testing.T, attr string, readTs, afterUid uint64) []uint64 {
+	var uids []uint64
+	used, err := MemLayerInstance.IteratePresence(context.Background(), attr, readTs, afterUid,
+		func(uid uint64) error {
+			uids = append(uids, uid)
+			return nil
+		})
+	require.NoError(t, err)
+	require.True(t, used)
+	return uids
+}
+
+func TestPresenceBitmap(t *testing.T) {
+	SetEnablePresenceBitmap(true)
+	defer SetEnablePresenceBitmap(false)
+
+	attr := x.AttrInRootNamespace("presence")
+	for _, uid := range []uint64{1, 2, presenceRangeSize + 5} {
+		commitPresenceDelta(t, attr, uid, Set, 5, 10)
+	}
+
+	// A build based on a read older than the last commit must be discarded.
+	MemLayerInstance.BuildPresence(attr, MemLayerInstance.PresenceEpoch(), 8, []uint64{1, 2})
+	require.False(t, MemLayerInstance.HasPresence(attr))
+
+	MemLayerInstance.BuildPresence(attr, MemLayerInstance.PresenceEpoch(), 20,
+		[]uint64{1, 2, presenceRangeSize + 5})
+	require.True(t, MemLayerInstance.HasPresence(attr))
+	require.Equal(t, []uint64{1, 2, presenceRangeSize + 5}, presenceUids(t, attr, 30, 0))
+	require.Equal(t, []uint64{presenceRangeSize + 5}, presenceUids(t, attr, 30, 2))
+
+	// Reads older than the bitmap can't use it.
+	used, err := MemLayerInstance.IteratePresence(context.Background(), attr, 15, 0,
+		func(uint64) error { return nil })
+	require.NoError(t, err)
+	require.False(t, used)
+
+	commitPresenceDelta(t, attr, 3, Set, 35, 40)
+	require.Equal(t, []uint64{1, 2, 3, presenceRangeSize + 5}, presenceUids(t, attr, 50, 0))
+	// The first range was updated after ts 35, so it's read from disk.
+	require.Equal(t, []uint64{1, 2, presenceRangeSize + 5}, presenceUids(t, attr, 35, 0))
+
+	commitPresenceDelta(t, attr, 2, Del, 55, 60)
+	stats := MemLayerInstance.PresenceStats()
+	require.Len(t, stats, 1)
+	require.Equal(t, "presence", stats[0].Predicate)
+	require.Equal(t, 1, stats[0].StaleRanges)
+
+	require.Equal(t, []uint64{1, 3, presenceRangeSize + 5}, presenceUids(t, attr, 70, 0))
+	stats = MemLayerInstance.PresenceStats()
+	require.Equal(t, 0, stats[0].StaleRanges)
+	require.Equal(t, 3, stats[0].Uids)
+
+	ResetCache()
+	require.False(t, MemLayerInstance.HasPresence(attr))
+}
+
+func TestPresenceContainer(t *testing.T) {
+	c := &presenceContainer{}
+	var want []uint64
+	for i := uint64(0); i < presenceArrayMax+10; i++ {
+		c.add(uint16(i * 7))
+		c.add(uint16(i * 7))
+		want = append(want, presenceRangeSize|i*7)
+	}
+	require.NotNil(t, c.bitmap)
+	require.Equal(t, len(want), c.n)
+	require.Equal(t, want, c.uids(presenceRangeSize))
+}
@@ -0,0 +1,135 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// asyncReverseShards is the number of shards of the reverse keys applied concurrently by
+// ApplyAsyncReverse.
+const asyncReverseShards = 8
+
+// asyncReverseLock keeps the rollups of the reverse keys from running while the async reverse
+// edges are written. The edges are written at a new ts, so a rollup reading a reverse key at a
+// higher ts must wait for them to be written, or it would hide them.
+var asyncReverseLock sync.RWMutex
+
+// addAsyncReverse records the reverse edges of the edge in the transaction, instead of adding
+// them to the reverse keys, so that they're added once the transaction is committed. The edges
+// replacing the value of a single uid predicate delete the reverse edge of the previous value,
+// like addReverseAndCountMutation does.
+func (txn *Txn) addAsyncReverse(ctx context.Context, t *pb.DirectedEdge) error {
+	var edges []*pb.DirectedEdge
+	pred, ok := schema.State().Get(ctx, t.Attr)
+	if ok && !pred.GetList() && t.Op != pb.DirectedEdge_DEL && t.ValueId != 0 {
+		dataKey := x.DataKey(t.Attr, t.Entity)
+		dataList, err := txn.Get(dataKey)
+		if err != nil {
+			return errors.Wrapf(err, "cannot find single uid list to update with key %s",
+				hex.Dump(dataKey))
+		}
+		err = dataList.Iterate(txn.StartTs, 0, func(p *pb.Posting) error {
+			if p.Uid != t.ValueId {
+				edges = append(edges, &pb.DirectedEdge{
+					Entity:  p.Uid,
+					ValueId: t.Entity,
+					Attr:    t.Attr,
+					Op:      pb.DirectedEdge_DEL,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	edges = append(edges, &pb.DirectedEdge{
+		Entity:  t.ValueId,
+		ValueId: t.Entity,
+		Attr:    t.Attr,
+		Op:      t.Op,
+		Facets:  t.Facets,
+	})
+
+	txn.Lock()
+	defer txn.Unlock()
+	txn.asyncReverse = append(txn.asyncReverse, edges...)
+	return nil
+}
+
+// AsyncReverseEdges returns the reverse edges of the predicates with @reverse(async) to add once
+// the transaction is committed. Their entity is the object of the edge they reverse.
+func (txn *Txn) AsyncReverseEdges() []*pb.DirectedEdge {
+	if txn == nil {
+		return nil
+	}
+	txn.Lock()
+	defer txn.Unlock()
+	return txn.asyncReverse
+}
+
+// ApplyAsyncReverse adds the reverse edges, in their order, to the reverse keys at a new ts given
+// by getNewTs, and returns that ts. The reverse keys are split in shards written concurrently. The
+// edges of the predicates which aren't reversed anymore are dropped.
+func ApplyAsyncReverse(ctx context.Context, edges []*pb.DirectedEdge,
+	getNewTs func(bool) uint64) (uint64, error) {
+
+	asyncReverseLock.Lock()
+	defer asyncReverseLock.Unlock()
+
+	var shards [asyncReverseShards][]*pb.DirectedEdge
+	for _, edge := range edges {
+		if !schema.State().IsReversed(ctx, edge.Attr) {
+			continue
+		}
+		shard := edge.Entity % asyncReverseShards
+		shards[shard] = append(shards[shard], edge)
+	}
+
+	ts := getNewTs(false)
+	g, gCtx := errgroup.WithContext(ctx)
+	for _, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		g.Go(func() error {
+			txn := NewTxn(ts)
+			for _, edge := range shard {
+				plist, err := txn.GetFromDelta(x.ReverseKey(edge.Attr, edge.Entity))
+				if err != nil {
+					return err
+				}
+				if err := plist.addMutation(gCtx, txn, edge); err != nil {
+					return err
+				}
+			}
+			txn.Update()
+			writer := NewTxnWriter(pstore)
+			if err := txn.CommitToDisk(writer, ts); err != nil {
+				return err
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			txn.UpdateCachedKeys(ts)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, errors.Wrapf(err, "while adding the async reverse edges at ts %d", ts)
+	}
+	return ts, nil
+}
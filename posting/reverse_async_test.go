@@ -0,0 +1,88 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestAsyncReverse(t *testing.T) {
+	require.NoError(t, pstore.DropAll())
+	MemLayerInstance.clear()
+	require.NoError(t, schema.ParseBytes([]byte(`
+		async_follows: [uid] @reverse(async) .
+		async_boss: uid @reverse(async) .
+	`), 1))
+	ctx := context.Background()
+	follows := x.AttrInRootNamespace("async_follows")
+	boss := x.AttrInRootNamespace("async_boss")
+
+	// mutate applies the edges in a transaction committed at commitTs, and returns its async
+	// reverse edges.
+	mutate := func(startTs, commitTs uint64, edges ...*pb.DirectedEdge) []*pb.DirectedEdge {
+		txn := Oracle().RegisterStartTs(startTs)
+		for _, edge := range edges {
+			l, err := txn.Get(x.DataKey(edge.Attr, edge.Entity))
+			require.NoError(t, err)
+			require.NoError(t, l.AddMutationWithIndex(ctx, edge, txn))
+		}
+		txn.Update()
+		writer := NewTxnWriter(pstore)
+		require.NoError(t, txn.CommitToDisk(writer, commitTs))
+		require.NoError(t, writer.Flush())
+		txn.UpdateCachedKeys(commitTs)
+		return txn.AsyncReverseEdges()
+	}
+	reverse := func(attr string, uid, readTs uint64) []uint64 {
+		l, err := GetNoStore(x.ReverseKey(attr, uid), readTs)
+		require.NoError(t, err)
+		uids, err := l.Uids(ListOptions{ReadTs: readTs})
+		require.NoError(t, err)
+		return uids.Uids
+	}
+	newTs := func(ts uint64) func(bool) uint64 {
+		return func(bool) uint64 { return ts }
+	}
+
+	edges := mutate(1, 2,
+		&pb.DirectedEdge{Attr: follows, Entity: 1, ValueId: 10, Op: pb.DirectedEdge_SET},
+		&pb.DirectedEdge{Attr: follows, Entity: 2, ValueId: 10, Op: pb.DirectedEdge_SET},
+		&pb.DirectedEdge{Attr: boss, Entity: 1, ValueId: 20, Op: pb.DirectedEdge_SET})
+	require.Len(t, edges, 3)
+	// The reverse edges aren't added by the transaction.
+	require.Empty(t, reverse(follows, 10, 3))
+	require.Empty(t, reverse(boss, 20, 3))
+
+	ts, err := ApplyAsyncReverse(ctx, edges, newTs(4))
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), ts)
+	require.Empty(t, reverse(follows, 10, 3))
+	require.Equal(t, []uint64{1, 2}, reverse(follows, 10, 4))
+	require.Equal(t, []uint64{1}, reverse(boss, 20, 4))
+
+	// Replacing the value of a single uid predicate deletes the reverse edge of the previous one,
+	// and the edges of the transactions are applied in order.
+	edges = mutate(5, 6,
+		&pb.DirectedEdge{Attr: boss, Entity: 1, ValueId: 21, Op: pb.DirectedEdge_SET})
+	edges = append(edges, mutate(7, 8,
+		&pb.DirectedEdge{Attr: follows, Entity: 2, ValueId: 10, Op: pb.DirectedEdge_DEL})...)
+	edges = append(edges, mutate(9, 10,
+		&pb.DirectedEdge{Attr: follows, Entity: 2, ValueId: 10, Op: pb.DirectedEdge_SET},
+		&pb.DirectedEdge{Attr: follows, Entity: 1, Value: []byte(x.Star),
+			Op: pb.DirectedEdge_DEL})...)
+	_, err = ApplyAsyncReverse(ctx, edges, newTs(11))
+	require.NoError(t, err)
+	require.Empty(t, reverse(boss, 20, 11))
+	require.Equal(t, []uint64{1}, reverse(boss, 21, 11))
+	require.Equal(t, []uint64{2}, reverse(follows, 10, 11))
+}
@@ -7,20 +7,29 @@ package posting
 
 import (
 	"math"
+	"math/bits"
 	"sync"
 
+	"github.com/dgraph-io/badger/v4"
+	"github.com/golang/glog"
+
 	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/x"
 )
 
 type StatsHolder struct {
 	sync.RWMutex
 
-	predStats map[string]StatContainer
+	predStats  map[string]StatContainer
+	countStats map[countStatKey]*CountHistogram
+	// countTs is the max commit ts of the count updates applied to the histograms.
+	countTs uint64
 }
 
 func NewStatsHolder() *StatsHolder {
 	return &StatsHolder{
-		predStats: make(map[string]StatContainer),
+		predStats:  make(map[string]StatContainer),
+		countStats: make(map[countStatKey]*CountHistogram),
 	}
 
 }
@@ -78,3 +87,219 @@ func (sh *StatsHolder) ProcessEqPredicate(pred string, key []byte) uint64 {
 	sh.Unlock()
 	return math.MaxUint64
 }
+
+// countBuckets is the number of power-of-two buckets kept by a CountHistogram. Bucket i holds
+// uids whose edge count c satisfies 2^i <= c < 2^(i+1), which is enough to cover any uint32
+// count stored in the count index.
+const countBuckets = 33
+
+// CountHistogram keeps the distribution of the edge counts of a predicate with a @count index.
+// It's seeded from the count index at a ts, and then updated with the counts changed by the
+// transactions committed after that ts, so that it only reflects committed data. It lives in
+// memory, so it's seeded again when it's first needed after a restart, or after the data of the
+// predicate is replaced, like by a drop, a snapshot or a tablet move.
+type CountHistogram struct {
+	sync.RWMutex
+
+	// seedTs is the ts at which the count index is read to seed the histogram.
+	seedTs uint64
+	// seeded is whether the counts read from the count index are in the buckets. Until then,
+	// the buckets only hold the changes committed after seedTs, and can be negative.
+	seeded  bool
+	buckets [countBuckets]int64
+}
+
+func countBucket(count int64) int {
+	if count <= 0 {
+		return -1
+	}
+	return bits.Len64(uint64(count)) - 1
+}
+
+// update moves one uid from the bucket of countBefore to the bucket of countAfter, if the change
+// is committed after the seed ts. A count of zero is not tracked, same as in the count index.
+func (ch *CountHistogram) update(countBefore, countAfter int, commitTs uint64) {
+	bb, ba := countBucket(int64(countBefore)), countBucket(int64(countAfter))
+	if bb == ba || commitTs <= ch.seedTs {
+		return
+	}
+	ch.Lock()
+	defer ch.Unlock()
+	if bb >= 0 {
+		ch.buckets[bb]--
+	}
+	if ba >= 0 {
+		ch.buckets[ba]++
+	}
+}
+
+// Seeded returns whether the histogram is seeded from the count index.
+func (ch *CountHistogram) Seeded() bool {
+	ch.RLock()
+	defer ch.RUnlock()
+	return ch.seeded
+}
+
+// Estimate returns an upper bound on the number of uids whose count lies within [lo, hi].
+// Buckets that only partially overlap the range are counted in full.
+func (ch *CountHistogram) Estimate(lo, hi int64) uint64 {
+	if lo < 1 {
+		lo = 1
+	}
+	if hi < lo {
+		return 0
+	}
+	ch.RLock()
+	defer ch.RUnlock()
+	var total uint64
+	for i := countBucket(lo); i <= countBucket(hi) && i < countBuckets; i++ {
+		if ch.buckets[i] > 0 {
+			total += uint64(ch.buckets[i])
+		}
+	}
+	return total
+}
+
+// Buckets returns a copy of the histogram, indexed by the bucket number.
+func (ch *CountHistogram) Buckets() []int64 {
+	ch.RLock()
+	defer ch.RUnlock()
+	out := make([]int64, countBuckets)
+	copy(out, ch.buckets[:])
+	return out
+}
+
+type countStatKey struct {
+	attr    string
+	reverse bool
+}
+
+// countUpdate is a change of the count of an entity for a predicate, made by a transaction.
+type countUpdate struct {
+	attr    string
+	reverse bool
+	before  int
+	after   int
+}
+
+// applyCountUpdates applies the count updates of a transaction committed at commitTs to the
+// histograms of their predicates. The predicates without a histogram are skipped, as their
+// counts are read from the count index once their histogram is seeded.
+func (sh *StatsHolder) applyCountUpdates(updates []countUpdate, commitTs uint64) {
+	if len(updates) == 0 {
+		return
+	}
+	sh.Lock()
+	defer sh.Unlock()
+	sh.countTs = max(sh.countTs, commitTs)
+	for _, u := range updates {
+		if ch, ok := sh.countStats[countStatKey{attr: u.attr, reverse: u.reverse}]; ok {
+			ch.update(u.before, u.after, commitTs)
+		}
+	}
+}
+
+// CountHistogram returns the count histogram of the predicate, or nil if it hasn't been needed
+// since the server started or the data of the predicate was replaced.
+func (sh *StatsHolder) CountHistogram(pred string, reverse bool) *CountHistogram {
+	sh.RLock()
+	defer sh.RUnlock()
+	return sh.countStats[countStatKey{attr: pred, reverse: reverse}]
+}
+
+// DeleteCountHistograms deletes the count histograms of the predicate, so that they're seeded
+// again from its count index.
+func (sh *StatsHolder) DeleteCountHistograms(pred string) {
+	sh.Lock()
+	defer sh.Unlock()
+	delete(sh.countStats, countStatKey{attr: pred})
+	delete(sh.countStats, countStatKey{attr: pred, reverse: true})
+}
+
+func (sh *StatsHolder) clearCountHistograms() {
+	sh.Lock()
+	defer sh.Unlock()
+	sh.countStats = make(map[countStatKey]*CountHistogram)
+}
+
+// EstimateCountRange returns the estimated number of uids whose count for the given predicate
+// lies within [lo, hi]. The second return value is false if the histogram isn't seeded yet, in
+// which case its seeding is started in the background.
+func (sh *StatsHolder) EstimateCountRange(pred string, reverse bool, lo, hi int64) (uint64, bool) {
+	ch := sh.CountHistogram(pred, reverse)
+	if ch == nil {
+		sh.seedCountHistogram(countStatKey{attr: pred, reverse: reverse}, o.MaxAssigned())
+		return 0, false
+	}
+	if !ch.Seeded() {
+		return 0, false
+	}
+	return ch.Estimate(lo, hi), true
+}
+
+// seedCountHistogram adds the histogram of the key, and seeds it in the background from the count
+// index. The count updates are applied once their transaction is written to disk, so the index
+// read at the max of readTs and of the commit ts of the updates applied so far has the counts of
+// all the updates the histogram missed, and the later ones are applied to the histogram.
+func (sh *StatsHolder) seedCountHistogram(key countStatKey, readTs uint64) {
+	sh.Lock()
+	if _, ok := sh.countStats[key]; ok {
+		sh.Unlock()
+		return
+	}
+	ch := &CountHistogram{seedTs: max(readTs, sh.countTs)}
+	sh.countStats[key] = ch
+	sh.Unlock()
+
+	go func() {
+		buckets, err := readCountBuckets(key, ch.seedTs)
+		if err != nil {
+			glog.Warningf("Error while seeding the count histogram of %s: %v",
+				x.ParseAttr(key.attr), err)
+			sh.Lock()
+			if sh.countStats[key] == ch {
+				delete(sh.countStats, key)
+			}
+			sh.Unlock()
+			return
+		}
+		ch.Lock()
+		defer ch.Unlock()
+		for i, n := range buckets {
+			ch.buckets[i] += n
+		}
+		ch.seeded = true
+	}()
+}
+
+// readCountBuckets returns the number of uids of the count index of the key at readTs, by bucket.
+func readCountBuckets(key countStatKey, readTs uint64) ([countBuckets]int64, error) {
+	var buckets [countBuckets]int64
+	if pstore.IsClosed() {
+		return buckets, badger.ErrDBClosed
+	}
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+
+	itOpt := badger.DefaultIteratorOptions
+	itOpt.PrefetchValues = false
+	itOpt.Prefix = x.ParsedKey{Attr: key.attr}.CountPrefix(key.reverse)
+	itr := txn.NewIterator(itOpt)
+	defer itr.Close()
+
+	for itr.Rewind(); itr.Valid(); itr.Next() {
+		countKey := itr.Item().KeyCopy(nil)
+		pk, err := x.Parse(countKey)
+		if err != nil {
+			return buckets, err
+		}
+		l, err := getNew(countKey, pstore, readTs, true)
+		if err != nil {
+			return buckets, err
+		}
+		if b := countBucket(int64(pk.Count)); b >= 0 {
+			buckets[b] += int64(max(l.Length(readTs, 0), 0))
+		}
+	}
+	return buckets, nil
+}
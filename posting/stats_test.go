@@ -0,0 +1,121 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestCountHistogram(t *testing.T) {
+	sh := NewStatsHolder()
+	sh.countStats[countStatKey{attr: "friend"}] = &CountHistogram{seedTs: 10, seeded: true}
+	update := func(before, after int, commitTs uint64) {
+		sh.applyCountUpdates([]countUpdate{{attr: "friend", before: before, after: after}},
+			commitTs)
+	}
+
+	// Three uids gain their first edge, one of them then grows to 5 edges.
+	for range 3 {
+		update(0, 1, 11)
+	}
+	update(1, 5, 12)
+	// The changes committed before the seed ts are already in the count index.
+	update(0, 1, 10)
+
+	est, ok := sh.EstimateCountRange("friend", false, 1, 1)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), est)
+
+	est, _ = sh.EstimateCountRange("friend", false, 4, 7)
+	require.Equal(t, uint64(1), est)
+
+	est, _ = sh.EstimateCountRange("friend", false, 2, math.MaxInt64)
+	require.Equal(t, uint64(1), est)
+
+	// Deleting all edges removes the uid from the histogram.
+	update(5, 0, 13)
+	est, _ = sh.EstimateCountRange("friend", false, 1, math.MaxInt64)
+	require.Equal(t, uint64(2), est)
+	require.Equal(t, int64(2), sh.CountHistogram("friend", false).Buckets()[0])
+}
+
+func TestCountHistogramSeed(t *testing.T) {
+	require.NoError(t, pstore.DropAll())
+	MemLayerInstance.clear()
+	require.NoError(t, schema.ParseBytes([]byte("cnt_friend: [uid] @count ."), 1))
+	attr := x.AttrInRootNamespace("cnt_friend")
+	sh := GetStatsHolder()
+
+	// The transactions are committed after the ones of the other tests, as they would be in a
+	// cluster.
+	base := max(Oracle().MaxAssigned(), sh.countTs)
+	addEdge := func(src, dst uint64, startTs, commitTs uint64) {
+		startTs, commitTs = base+startTs, base+commitTs
+		l, err := GetNoStore(x.DataKey(attr, src), startTs)
+		require.NoError(t, err)
+		edge := &pb.DirectedEdge{ValueId: dst, Attr: attr, Entity: src}
+		addMutation(t, l, edge, Set, startTs, commitTs, true)
+	}
+	waitSeeded := func() {
+		require.Eventually(t, func() bool {
+			_, ok := sh.EstimateCountRange(attr, false, 1, 1)
+			return ok
+		}, 10*time.Second, 10*time.Millisecond)
+	}
+
+	// Uid 1 has two edges and uid 2 one, committed before the histogram is needed.
+	addEdge(1, 10, 1, 2)
+	addEdge(1, 11, 3, 4)
+	addEdge(2, 10, 5, 6)
+
+	// An empty histogram isn't used, it's seeded from the count index instead.
+	require.Nil(t, sh.CountHistogram(attr, false))
+	_, ok := sh.EstimateCountRange(attr, false, 1, 1)
+	require.False(t, ok)
+	waitSeeded()
+	check := func(count, uids uint64) {
+		est, ok := sh.EstimateCountRange(attr, false, int64(count), int64(count))
+		require.True(t, ok)
+		require.Equal(t, uids, est, "count %d", count)
+	}
+	check(1, 1)
+	check(2, 1)
+
+	// The committed changes are applied, uid 2 now has two edges.
+	addEdge(2, 11, 7, 8)
+	check(1, 0)
+	check(2, 2)
+
+	// The aborted changes aren't.
+	l, err := GetNoStore(x.DataKey(attr, 3), base+9)
+	require.NoError(t, err)
+	txn := NewTxn(base + 9)
+	txn.cache.SetIfAbsent(string(l.key), l)
+	edge := &pb.DirectedEdge{ValueId: 10, Attr: attr, Entity: 3, Op: pb.DirectedEdge_SET}
+	require.NoError(t, l.AddMutationWithIndex(context.Background(), edge, txn))
+	txn.Update()
+	txn.UpdateCachedKeys(0)
+	check(1, 0)
+	check(2, 2)
+
+	// Once the memory layer is cleared, like after a drop or a snapshot, the histogram is
+	// seeded again.
+	MemLayerInstance.clear()
+	_, ok = sh.EstimateCountRange(attr, false, 1, 1)
+	require.False(t, ok)
+	waitSeeded()
+	check(1, 0)
+	check(2, 2)
+}
@@ -0,0 +1,69 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// predVersions records the commit timestamp of the latest transaction touching each predicate,
+// so that the results read from a predicate can be told to be unchanged. The records only cover
+// the transactions committed since the memory layer was last cleared, so the epoch is bumped at
+// every clear. It starts from the time the Alpha started, so that it changes across restarts.
+type predVersions struct {
+	sync.RWMutex
+
+	epoch    uint64
+	commitTs map[string]uint64
+}
+
+func newPredVersions() *predVersions {
+	return &predVersions{
+		epoch:    uint64(time.Now().UnixNano()),
+		commitTs: make(map[string]uint64),
+	}
+}
+
+func (pv *predVersions) clear() {
+	pv.Lock()
+	defer pv.Unlock()
+	pv.epoch++
+	pv.commitTs = make(map[string]uint64)
+}
+
+// applyCommit records the commit timestamp for the predicates of the given keys.
+func (pv *predVersions) applyCommit(keys map[string][]byte, commitTs uint64) {
+	attrs := make(map[string]struct{})
+	for key := range keys {
+		if pk, err := x.Parse([]byte(key)); err == nil {
+			attrs[pk.Attr] = struct{}{}
+		}
+	}
+
+	pv.Lock()
+	defer pv.Unlock()
+	for attr := range attrs {
+		if commitTs > pv.commitTs[attr] {
+			pv.commitTs[attr] = commitTs
+		}
+	}
+}
+
+func (pv *predVersions) get(attr string) (uint64, uint64) {
+	pv.RLock()
+	defer pv.RUnlock()
+	return pv.epoch, pv.commitTs[attr]
+}
+
+// PredicateVersion returns the version of the data of the given namespaced predicate served by
+// this Alpha, as the epoch of the memory layer and the commit timestamp of the latest transaction
+// touching the predicate during it. The version changes whenever the predicate is changed.
+func PredicateVersion(attr string) (epoch uint64, commitTs uint64) {
+	return MemLayerInstance.versions.get(attr)
+}
@@ -0,0 +1,42 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package posting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestPredVersions(t *testing.T) {
+	pv := newPredVersions()
+	name := x.AttrInRootNamespace("name")
+	age := x.AttrInRootNamespace("age")
+	keys := map[string][]byte{
+		string(x.DataKey(name, 1)):   nil,
+		string(x.IndexKey(name, "")): nil,
+	}
+
+	epoch, ts := pv.get(name)
+	require.Zero(t, ts)
+	pv.applyCommit(keys, 10)
+	_, ts = pv.get(name)
+	require.Equal(t, uint64(10), ts)
+	_, ts = pv.get(age)
+	require.Zero(t, ts)
+
+	// An older commit applied late doesn't take the version back.
+	pv.applyCommit(keys, 5)
+	_, ts = pv.get(name)
+	require.Equal(t, uint64(10), ts)
+
+	pv.clear()
+	newEpoch, ts := pv.get(name)
+	require.Zero(t, ts)
+	require.NotEqual(t, epoch, newEpoch)
+}
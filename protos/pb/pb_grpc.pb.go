@@ -306,6 +306,7 @@ const (
 	Zero_DeleteNamespace_FullMethodName  = "/pb.Zero/DeleteNamespace"
 	Zero_RemoveNode_FullMethodName       = "/pb.Zero/RemoveNode"
 	Zero_MoveTablet_FullMethodName       = "/pb.Zero/MoveTablet"
+	Zero_Leases_FullMethodName           = "/pb.Zero/Leases"
 )
 
 // ZeroClient is the client API for Zero service.
@@ -326,6 +327,9 @@ type ZeroClient interface {
 	DeleteNamespace(ctx context.Context, in *DeleteNsRequest, opts ...grpc.CallOption) (*Status, error)
 	RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*Status, error)
 	MoveTablet(ctx context.Context, in *MoveTabletRequest, opts ...grpc.CallOption) (*Status, error)
+	// Leases returns the state of the leases of the uids, timestamps and namespace ids, and
+	// the watermark of the timestamps, as known by the leader.
+	Leases(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (*LeaseState, error)
 }
 
 type zeroClient struct {
@@ -499,6 +503,15 @@ func (c *zeroClient) MoveTablet(ctx context.Context, in *MoveTabletRequest, opts
 	return out, nil
 }
 
+func (c *zeroClient) Leases(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (*LeaseState, error) {
+	out := new(LeaseState)
+	err := c.cc.Invoke(ctx, Zero_Leases_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ZeroServer is the server API for Zero service.
 // All implementations must embed UnimplementedZeroServer
 // for forward compatibility
@@ -517,6 +530,9 @@ type ZeroServer interface {
 	DeleteNamespace(context.Context, *DeleteNsRequest) (*Status, error)
 	RemoveNode(context.Context, *RemoveNodeRequest) (*Status, error)
 	MoveTablet(context.Context, *MoveTabletRequest) (*Status, error)
+	// Leases returns the state of the leases of the uids, timestamps and namespace ids, and
+	// the watermark of the timestamps, as known by the leader.
+	Leases(context.Context, *api.Payload) (*LeaseState, error)
 	mustEmbedUnimplementedZeroServer()
 }
 
@@ -563,6 +579,9 @@ func (UnimplementedZeroServer) RemoveNode(context.Context, *RemoveNodeRequest) (
 func (UnimplementedZeroServer) MoveTablet(context.Context, *MoveTabletRequest) (*Status, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MoveTablet not implemented")
 }
+func (UnimplementedZeroServer) Leases(context.Context, *api.Payload) (*LeaseState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leases not implemented")
+}
 func (UnimplementedZeroServer) mustEmbedUnimplementedZeroServer() {}
 
 // UnsafeZeroServer may be embedded to opt out of forward compatibility for this service.
@@ -816,6 +835,24 @@ func _Zero_MoveTablet_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Zero_Leases_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(api.Payload)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ZeroServer).Leases(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Zero_Leases_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).Leases(ctx, req.(*api.Payload))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Zero_ServiceDesc is the grpc.ServiceDesc for Zero service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -867,6 +904,10 @@ var Zero_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "MoveTablet",
 			Handler:    _Zero_MoveTablet_Handler,
 		},
+		{
+			MethodName: "Leases",
+			Handler:    _Zero_Leases_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -1655,3 +1696,420 @@ var Worker_ServiceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "pb.proto",
 }
+
+const (
+	Loader_Load_FullMethodName       = "/pb.Loader/Load"
+	Loader_AssignXids_FullMethodName = "/pb.Loader/AssignXids"
+	Loader_Mutate_FullMethodName     = "/pb.Loader/Mutate"
+)
+
+// LoaderClient is the client API for Loader service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LoaderClient interface {
+	// Load takes a stream of mutations setting chunks of N-Quads or JSON, which the alpha batches
+	// into transactions, assigning the uids of the blank nodes, and streams back the progress.
+	Load(ctx context.Context, opts ...grpc.CallOption) (Loader_LoadClient, error)
+	// AssignXids returns the uids of the nodes with the xids as the values of the predicate, in
+	// the uids of the response, and assigns the xids without a node to new nodes with them, so the
+	// loaders and the applications agree on the uids of the xids.
+	AssignXids(ctx context.Context, in *XidRequest, opts ...grpc.CallOption) (*api.Response, error)
+	// Mutate takes a stream of chunks of one mutation, which are run together in one transaction,
+	// split into bounded proposals. The chunks of N-Quads or JSON are concatenated, so they don't
+	// need to be split on the N-Quads, and the transaction commits if the first chunk is CommitNow.
+	Mutate(ctx context.Context, opts ...grpc.CallOption) (Loader_MutateClient, error)
+}
+
+type loaderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLoaderClient(cc grpc.ClientConnInterface) LoaderClient {
+	return &loaderClient{cc}
+}
+
+func (c *loaderClient) Load(ctx context.Context, opts ...grpc.CallOption) (Loader_LoadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Loader_ServiceDesc.Streams[0], Loader_Load_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &loaderLoadClient{stream}
+	return x, nil
+}
+
+type Loader_LoadClient interface {
+	Send(*api.Mutation) error
+	Recv() (*api.Response, error)
+	grpc.ClientStream
+}
+
+type loaderLoadClient struct {
+	grpc.ClientStream
+}
+
+func (x *loaderLoadClient) Send(m *api.Mutation) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *loaderLoadClient) Recv() (*api.Response, error) {
+	m := new(api.Response)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *loaderClient) AssignXids(ctx context.Context, in *XidRequest, opts ...grpc.CallOption) (*api.Response, error) {
+	out := new(api.Response)
+	err := c.cc.Invoke(ctx, Loader_AssignXids_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loaderClient) Mutate(ctx context.Context, opts ...grpc.CallOption) (Loader_MutateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Loader_ServiceDesc.Streams[1], Loader_Mutate_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &loaderMutateClient{stream}
+	return x, nil
+}
+
+type Loader_MutateClient interface {
+	Send(*api.Mutation) error
+	CloseAndRecv() (*api.Response, error)
+	grpc.ClientStream
+}
+
+type loaderMutateClient struct {
+	grpc.ClientStream
+}
+
+func (x *loaderMutateClient) Send(m *api.Mutation) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *loaderMutateClient) CloseAndRecv() (*api.Response, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(api.Response)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoaderServer is the server API for Loader service.
+// All implementations must embed UnimplementedLoaderServer
+// for forward compatibility
+type LoaderServer interface {
+	// Load takes a stream of mutations setting chunks of N-Quads or JSON, which the alpha batches
+	// into transactions, assigning the uids of the blank nodes, and streams back the progress.
+	Load(Loader_LoadServer) error
+	// AssignXids returns the uids of the nodes with the xids as the values of the predicate, in
+	// the uids of the response, and assigns the xids without a node to new nodes with them, so the
+	// loaders and the applications agree on the uids of the xids.
+	AssignXids(context.Context, *XidRequest) (*api.Response, error)
+	// Mutate takes a stream of chunks of one mutation, which are run together in one transaction,
+	// split into bounded proposals. The chunks of N-Quads or JSON are concatenated, so they don't
+	// need to be split on the N-Quads, and the transaction commits if the first chunk is CommitNow.
+	Mutate(Loader_MutateServer) error
+	mustEmbedUnimplementedLoaderServer()
+}
+
+// UnimplementedLoaderServer must be embedded to have forward compatible implementations.
+type UnimplementedLoaderServer struct {
+}
+
+func (UnimplementedLoaderServer) Load(Loader_LoadServer) error {
+	return status.Errorf(codes.Unimplemented, "method Load not implemented")
+}
+func (UnimplementedLoaderServer) AssignXids(context.Context, *XidRequest) (*api.Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AssignXids not implemented")
+}
+func (UnimplementedLoaderServer) Mutate(Loader_MutateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Mutate not implemented")
+}
+func (UnimplementedLoaderServer) mustEmbedUnimplementedLoaderServer() {}
+
+// UnsafeLoaderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LoaderServer will
+// result in compilation errors.
+type UnsafeLoaderServer interface {
+	mustEmbedUnimplementedLoaderServer()
+}
+
+func RegisterLoaderServer(s grpc.ServiceRegistrar, srv LoaderServer) {
+	s.RegisterService(&Loader_ServiceDesc, srv)
+}
+
+func _Loader_Load_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(LoaderServer).Load(&loaderLoadServer{stream})
+}
+
+type Loader_LoadServer interface {
+	Send(*api.Response) error
+	Recv() (*api.Mutation, error)
+	grpc.ServerStream
+}
+
+type loaderLoadServer struct {
+	grpc.ServerStream
+}
+
+func (x *loaderLoadServer) Send(m *api.Response) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *loaderLoadServer) Recv() (*api.Mutation, error) {
+	m := new(api.Mutation)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Loader_AssignXids_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(XidRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoaderServer).AssignXids(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Loader_AssignXids_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoaderServer).AssignXids(ctx, req.(*XidRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Loader_Mutate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(LoaderServer).Mutate(&loaderMutateServer{stream})
+}
+
+type Loader_MutateServer interface {
+	SendAndClose(*api.Response) error
+	Recv() (*api.Mutation, error)
+	grpc.ServerStream
+}
+
+type loaderMutateServer struct {
+	grpc.ServerStream
+}
+
+func (x *loaderMutateServer) SendAndClose(m *api.Response) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *loaderMutateServer) Recv() (*api.Mutation, error) {
+	m := new(api.Mutation)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Loader_ServiceDesc is the grpc.ServiceDesc for Loader service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Loader_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.Loader",
+	HandlerType: (*LoaderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AssignXids",
+			Handler:    _Loader_AssignXids_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Load",
+			Handler:       _Loader_Load_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Mutate",
+			Handler:       _Loader_Mutate_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pb.proto",
+}
+
+const (
+	BulkLoader_Reduce_FullMethodName      = "/pb.BulkLoader/Reduce"
+	BulkLoader_WriteSchema_FullMethodName = "/pb.BulkLoader/WriteSchema"
+)
+
+// BulkLoaderClient is the client API for BulkLoader service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BulkLoaderClient interface {
+	// Reduce takes the bulk meta of a reduce shard, then its map files, keyed by their paths in the
+	// shard. It reduces them into the p directory of the shard, and returns the schema of the
+	// predicates with data in the shard.
+	Reduce(ctx context.Context, opts ...grpc.CallOption) (BulkLoader_ReduceClient, error)
+	// WriteSchema writes the schema of the predicates of a reduced shard, and closes its p directory.
+	WriteSchema(ctx context.Context, in *BulkMeta, opts ...grpc.CallOption) (*Status, error)
+}
+
+type bulkLoaderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBulkLoaderClient(cc grpc.ClientConnInterface) BulkLoaderClient {
+	return &bulkLoaderClient{cc}
+}
+
+func (c *bulkLoaderClient) Reduce(ctx context.Context, opts ...grpc.CallOption) (BulkLoader_ReduceClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BulkLoader_ServiceDesc.Streams[0], BulkLoader_Reduce_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bulkLoaderReduceClient{stream}
+	return x, nil
+}
+
+type BulkLoader_ReduceClient interface {
+	Send(*pb.KV) error
+	CloseAndRecv() (*BulkMeta, error)
+	grpc.ClientStream
+}
+
+type bulkLoaderReduceClient struct {
+	grpc.ClientStream
+}
+
+func (x *bulkLoaderReduceClient) Send(m *pb.KV) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *bulkLoaderReduceClient) CloseAndRecv() (*BulkMeta, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BulkMeta)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *bulkLoaderClient) WriteSchema(ctx context.Context, in *BulkMeta, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, BulkLoader_WriteSchema_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BulkLoaderServer is the server API for BulkLoader service.
+// All implementations must embed UnimplementedBulkLoaderServer
+// for forward compatibility
+type BulkLoaderServer interface {
+	// Reduce takes the bulk meta of a reduce shard, then its map files, keyed by their paths in the
+	// shard. It reduces them into the p directory of the shard, and returns the schema of the
+	// predicates with data in the shard.
+	Reduce(BulkLoader_ReduceServer) error
+	// WriteSchema writes the schema of the predicates of a reduced shard, and closes its p directory.
+	WriteSchema(context.Context, *BulkMeta) (*Status, error)
+	mustEmbedUnimplementedBulkLoaderServer()
+}
+
+// UnimplementedBulkLoaderServer must be embedded to have forward compatible implementations.
+type UnimplementedBulkLoaderServer struct {
+}
+
+func (UnimplementedBulkLoaderServer) Reduce(BulkLoader_ReduceServer) error {
+	return status.Errorf(codes.Unimplemented, "method Reduce not implemented")
+}
+func (UnimplementedBulkLoaderServer) WriteSchema(context.Context, *BulkMeta) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteSchema not implemented")
+}
+func (UnimplementedBulkLoaderServer) mustEmbedUnimplementedBulkLoaderServer() {}
+
+// UnsafeBulkLoaderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BulkLoaderServer will
+// result in compilation errors.
+type UnsafeBulkLoaderServer interface {
+	mustEmbedUnimplementedBulkLoaderServer()
+}
+
+func RegisterBulkLoaderServer(s grpc.ServiceRegistrar, srv BulkLoaderServer) {
+	s.RegisterService(&BulkLoader_ServiceDesc, srv)
+}
+
+func _BulkLoader_Reduce_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BulkLoaderServer).Reduce(&bulkLoaderReduceServer{stream})
+}
+
+type BulkLoader_ReduceServer interface {
+	SendAndClose(*BulkMeta) error
+	Recv() (*pb.KV, error)
+	grpc.ServerStream
+}
+
+type bulkLoaderReduceServer struct {
+	grpc.ServerStream
+}
+
+func (x *bulkLoaderReduceServer) SendAndClose(m *BulkMeta) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *bulkLoaderReduceServer) Recv() (*pb.KV, error) {
+	m := new(pb.KV)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _BulkLoader_WriteSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BulkMeta)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BulkLoaderServer).WriteSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BulkLoader_WriteSchema_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BulkLoaderServer).WriteSchema(ctx, req.(*BulkMeta))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BulkLoader_ServiceDesc is the grpc.ServiceDesc for BulkLoader service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BulkLoader_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.BulkLoader",
+	HandlerType: (*BulkLoaderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "WriteSchema",
+			Handler:    _BulkLoader_WriteSchema_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Reduce",
+			Handler:       _BulkLoader_Reduce_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pb.proto",
+}
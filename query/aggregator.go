@@ -22,6 +22,8 @@ type aggregator struct {
 	name   string
 	result types.Val
 	count  int // used when we need avergae.
+	// sketch replaces the result of the approximate aggregators, percentile and distinct.
+	sketch *aggSketch
 }
 
 func isUnary(f string) bool {
@@ -792,6 +794,9 @@ func (ag *aggregator) ApplyVal(v types.Val) error {
 }
 
 func (ag *aggregator) Apply(val types.Val) error {
+	if ag.sketch != nil {
+		return ag.sketch.apply(val)
+	}
 	if ag.result.Value == nil {
 		if val.Tid == types.VFloatID {
 			// Copy array if it's VFloat, otherwise we overwrite value.
@@ -903,6 +908,9 @@ func (ag *aggregator) divideByCount() {
 }
 
 func (ag *aggregator) Value() (types.Val, error) {
+	if ag.sketch != nil {
+		return ag.sketch.value()
+	}
 	if ag.result.Value == nil {
 		return ag.result, ErrEmptyVal
 	}
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/hypermodeinc/dgraph/v25/dgraphapi"
@@ -79,6 +80,18 @@ func processQueryRDF(ctx context.Context, query string) (string, error) {
 	return string(res.Rdf), err
 }
 
+func processQueryGraph(ctx context.Context, query string) (string, error) {
+	txn := client.NewTxn()
+	defer func() { _ = txn.Discard(ctx) }()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "resp-format", "graph")
+	res, err := txn.Query(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	return string(res.Json), err
+}
+
 func processQueryNoErr(t *testing.T, query string) string {
 	res, err := processQuery(context.Background(), t, query)
 	require.NoError(t, err)
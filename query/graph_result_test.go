@@ -0,0 +1,54 @@
+//go:build integration || cloud || upgrade
+
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphResult(t *testing.T) {
+	query := `{
+		friends_15_and_19(func: uid(1)) {
+		  name
+		  friend @filter(ge(age, 15) AND lt(age, 19)) {
+			  name
+			  age
+		  }
+		}
+	  }`
+
+	graph, err := processQueryGraph(context.Background(), query)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"nodes": [
+			{"id": "0x1", "types": [], "attributes": {"name": "Michonne"}},
+			{"id": "0x17", "types": [], "attributes": {"name": "Rick Grimes", "age": 15}},
+			{"id": "0x18", "types": [], "attributes": {"name": "Glenn Rhee", "age": 15}},
+			{"id": "0x19", "types": [], "attributes": {"name": "Daryl Dixon", "age": 17}}
+		],
+		"edges": [
+			{"source": "0x1", "target": "0x17", "predicate": "friend"},
+			{"source": "0x1", "target": "0x18", "predicate": "friend"},
+			{"source": "0x1", "target": "0x19", "predicate": "friend"}
+		]
+	}`, graph)
+}
+
+func TestGraphResultGroupBy(t *testing.T) {
+	query := `{
+		me(func: uid(1, 23, 24, 25, 31)) @groupby(age) {
+			count(uid)
+		}
+	}`
+	_, err := processQueryGraph(context.Background(), query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "groupby is not supported in the graph output format")
+}
@@ -6,13 +6,15 @@
 package query
 
 import (
-	"fmt"
+	"math"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/dql"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/types"
 )
@@ -26,6 +28,8 @@ type groupResult struct {
 	keys       []groupPair
 	aggregates []groupPair
 	uids       []uint64
+	// groups are the groups by the next attribute of a nested groupby.
+	groups []*groupResult
 }
 
 func (grp *groupResult) aggregateChild(child *SubGraph) error {
@@ -48,7 +52,7 @@ func (grp *groupResult) aggregateChild(child *SubGraph) error {
 	}
 	if child.SrcFunc != nil && isAggregatorFn(child.SrcFunc.Name) {
 		if fieldName == "" {
-			fieldName = fmt.Sprintf("%s(%s)", child.SrcFunc.Name, child.Attr)
+			fieldName = aggregatorFieldName(child.SrcFunc, child.Attr)
 		}
 		finalVal, err := aggregateGroup(grp, child)
 		if err != nil {
@@ -101,19 +105,23 @@ func (d *dedup) getGroup(attr string) *uniq {
 	return res
 }
 
+// groupKey returns the string key of the group of the value.
+func groupKey(value types.Val) (string, error) {
+	if value.Tid == types.UidID {
+		return strconv.FormatUint(value.Value.(uint64), 10), nil
+	}
+	valC := types.Val{Tid: types.StringID, Value: ""}
+	if err := types.Marshal(value, &valC); err != nil {
+		return "", err
+	}
+	return valC.Value.(string), nil
+}
+
 func (d *dedup) addValue(attr string, value types.Val, uid uint64) {
 	cur := d.getGroup(attr)
-	// Create the string key.
-	var strKey string
-	if value.Tid == types.UidID {
-		strKey = strconv.FormatUint(value.Value.(uint64), 10)
-	} else {
-		valC := types.Val{Tid: types.StringID, Value: ""}
-		err := types.Marshal(value, &valC)
-		if err != nil {
-			return
-		}
-		strKey = valC.Value.(string)
+	strKey, err := groupKey(value)
+	if err != nil {
+		return
 	}
 
 	if _, ok := cur.elements[strKey]; !ok {
@@ -127,9 +135,63 @@ func (d *dedup) addValue(attr string, value types.Val, uid uint64) {
 	curEntity.Uids = append(curEntity.Uids, uid)
 }
 
+// bucketValue returns the bucket of the value, the start of its range of the width of bucket, or
+// its truncation to the unit of datetrunc. It returns the value if there's no bucketing function.
+func bucketValue(bucket *dql.GroupByBucket, value types.Val) (types.Val, error) {
+	if bucket == nil {
+		return value, nil
+	}
+	if bucket.Name == "datetrunc" {
+		t, ok := value.Value.(time.Time)
+		if !ok {
+			return value, errors.Errorf("datetrunc only supports datetime values, got %s",
+				value.Tid.Name())
+		}
+		year, month, day := t.Date()
+		switch bucket.Arg {
+		case "year":
+			t = time.Date(year, 1, 1, 0, 0, 0, 0, t.Location())
+		case "month":
+			t = time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+		case "day":
+			t = time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+		case "hour":
+			t = time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+		case "minute":
+			t = time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, t.Location())
+		}
+		return types.Val{Tid: types.DateTimeID, Value: t}, nil
+	}
+
+	width, err := strconv.ParseFloat(bucket.Arg, 64)
+	if err != nil {
+		return value, err
+	}
+	switch value.Tid {
+	case types.IntID:
+		// The ranges of an integer width keep the integers, and start at its multiples.
+		if w := int64(width); float64(w) == width {
+			v := value.Value.(int64)
+			start := v - v%w
+			if v < 0 && start != v {
+				start -= w
+			}
+			return types.Val{Tid: types.IntID, Value: start}, nil
+		}
+		v := float64(value.Value.(int64))
+		return types.Val{Tid: types.FloatID, Value: math.Floor(v/width) * width}, nil
+	case types.FloatID:
+		v := value.Value.(float64)
+		return types.Val{Tid: types.FloatID, Value: math.Floor(v/width) * width}, nil
+	}
+	return value, errors.Errorf("bucket only supports int and float values, got %s",
+		value.Tid.Name())
+}
+
 func aggregateGroup(grp *groupResult, child *SubGraph) (types.Val, error) {
-	ag := aggregator{
-		name: child.SrcFunc.Name,
+	ag, err := newAggregator(child.SrcFunc)
+	if err != nil {
+		return types.Val{}, err
 	}
 	for _, uid := range grp.uids {
 		idx := sort.Search(len(child.SrcUIDs.Uids), func(i int) bool {
@@ -154,6 +216,59 @@ func aggregateGroup(grp *groupResult, child *SubGraph) (types.Val, error) {
 	return ag.Value()
 }
 
+// nestGroups nests the groups, formed by all the attributes from depth on, by the attribute at
+// depth. Every group by the attribute at depth holds the groups by the next one, and is
+// aggregated over the uids of its groups.
+func (sg *SubGraph) nestGroups(groups []*groupResult, depth int) ([]*groupResult, error) {
+	if len(groups) == 0 || depth == len(groups[0].keys)-1 {
+		for _, grp := range groups {
+			grp.keys = grp.keys[depth:]
+		}
+		return groups, nil
+	}
+
+	var nested []*groupResult
+	byKey := make(map[string]*groupResult)
+	for _, grp := range groups {
+		strKey, err := groupKey(grp.keys[depth].key)
+		if err != nil {
+			return nil, err
+		}
+		parent, ok := byKey[strKey]
+		if !ok {
+			parent = &groupResult{keys: []groupPair{grp.keys[depth]}}
+			byKey[strKey] = parent
+			nested = append(nested, parent)
+		}
+		parent.groups = append(parent.groups, grp)
+	}
+
+	for _, parent := range nested {
+		lists := make([]*pb.List, 0, len(parent.groups))
+		for _, grp := range parent.groups {
+			lists = append(lists, &pb.List{Uids: grp.uids})
+		}
+		parent.uids = algo.MergeSorted(lists).Uids
+
+		var err error
+		if parent.groups, err = sg.nestGroups(parent.groups, depth+1); err != nil {
+			return nil, err
+		}
+		for _, child := range sg.Children {
+			if child.Params.IgnoreResult {
+				continue
+			}
+			if err := parent.aggregateChild(child); err != nil && err != ErrEmptyVal {
+				return nil, err
+			}
+		}
+	}
+	sort.Slice(nested, func(i, j int) bool {
+		return groupLess(nested[i], nested[j])
+	})
+	return nested, nil
+}
+
 // formGroup creates all possible groups with the list of uids that belong to that
 // group.
 func (res *groupResults) formGroups(dedupMap dedup, cur *pb.List, groupVal []groupPair) {
@@ -207,6 +322,9 @@ func (sg *SubGraph) formResult(ul *pb.List) (*groupResults, error) {
 		}
 		if len(child.DestUIDs.GetUids()) > 0 {
 			// It's a UID node.
+			if bucket := child.Params.GroupbyBucket; bucket != nil {
+				return res, errors.Errorf("%s can't group the uids of %s", bucket.Name, child.Attr)
+			}
 			for i := range child.uidMatrix {
 				srcUid := child.SrcUIDs.Uids[i]
 				// Ignore uids which are not part of srcUid.
@@ -230,6 +348,9 @@ func (sg *SubGraph) formResult(ul *pb.List) (*groupResults, error) {
 				if err != nil {
 					continue
 				}
+				if val, err = bucketValue(child.Params.GroupbyBucket, val); err != nil {
+					return res, err
+				}
 				dedupMap.addValue(attr, val, srcUid)
 			}
 		}
@@ -256,6 +377,12 @@ func (sg *SubGraph) formResult(ul *pb.List) (*groupResults, error) {
 		return groupLess(res.group[i], res.group[j])
 	})
 
+	if sg.Params.GroupbyNested {
+		var err error
+		if res.group, err = sg.nestGroups(res.group, 0); err != nil {
+			return res, err
+		}
+	}
 	return res, nil
 }
 
@@ -308,6 +435,9 @@ func (sg *SubGraph) fillGroupedVars(doneVars map[string]varValue, path []*SubGra
 				if err != nil {
 					continue
 				}
+				if val, err = bucketValue(child.Params.GroupbyBucket, val); err != nil {
+					return err
+				}
 				dedupMap.addValue(attr, val, srcUid)
 			}
 		}
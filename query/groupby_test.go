@@ -0,0 +1,79 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+func TestBucketValue(t *testing.T) {
+	bucket := &dql.GroupByBucket{Name: "bucket", Arg: "10"}
+	for v, start := range map[int64]int64{0: 0, 9: 0, 10: 10, 25: 20, -1: -10, -10: -10} {
+		b, err := bucketValue(bucket, types.Val{Tid: types.IntID, Value: v})
+		require.NoError(t, err)
+		require.Equal(t, types.Val{Tid: types.IntID, Value: start}, b)
+	}
+	b, err := bucketValue(bucket, types.Val{Tid: types.FloatID, Value: 12.5})
+	require.NoError(t, err)
+	require.Equal(t, types.Val{Tid: types.FloatID, Value: 10.0}, b)
+	b, err = bucketValue(&dql.GroupByBucket{Name: "bucket", Arg: "2.5"},
+		types.Val{Tid: types.IntID, Value: int64(6)})
+	require.NoError(t, err)
+	require.Equal(t, types.Val{Tid: types.FloatID, Value: 5.0}, b)
+	_, err = bucketValue(bucket, types.Val{Tid: types.StringID, Value: "a"})
+	require.Error(t, err)
+
+	dob := time.Date(2020, 5, 17, 13, 45, 30, 0, time.UTC)
+	for unit, want := range map[string]time.Time{
+		"year":   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"month":  time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+		"day":    time.Date(2020, 5, 17, 0, 0, 0, 0, time.UTC),
+		"hour":   time.Date(2020, 5, 17, 13, 0, 0, 0, time.UTC),
+		"minute": time.Date(2020, 5, 17, 13, 45, 0, 0, time.UTC),
+	} {
+		b, err := bucketValue(&dql.GroupByBucket{Name: "datetrunc", Arg: unit},
+			types.Val{Tid: types.DateTimeID, Value: dob})
+		require.NoError(t, err)
+		require.Equal(t, types.Val{Tid: types.DateTimeID, Value: want}, b)
+	}
+}
+
+func TestNestGroups(t *testing.T) {
+	country := func(s string) groupPair {
+		return groupPair{attr: "country", key: types.Val{Tid: types.StringID, Value: s}}
+	}
+	status := func(s string) groupPair {
+		return groupPair{attr: "status", key: types.Val{Tid: types.StringID, Value: s}}
+	}
+	sg := &SubGraph{Children: []*SubGraph{{Attr: "uid", Params: params{DoCount: true}}}}
+	groups, err := sg.nestGroups([]*groupResult{
+		{keys: []groupPair{country("in"), status("active")}, uids: []uint64{1, 3}},
+		{keys: []groupPair{country("us"), status("active")}, uids: []uint64{2}},
+		{keys: []groupPair{country("in"), status("idle")}, uids: []uint64{4}},
+	}, 0)
+	require.NoError(t, err)
+
+	count := func(n int64) []groupPair {
+		return []groupPair{{attr: "count", key: types.Val{Tid: types.IntID, Value: n}}}
+	}
+	require.Equal(t, []*groupResult{
+		{keys: []groupPair{country("us")}, aggregates: count(1), uids: []uint64{2},
+			groups: []*groupResult{
+				{keys: []groupPair{status("active")}, uids: []uint64{2}},
+			}},
+		{keys: []groupPair{country("in")}, aggregates: count(3), uids: []uint64{1, 3, 4},
+			groups: []*groupResult{
+				{keys: []groupPair{status("active")}, uids: []uint64{1, 3}},
+				{keys: []groupPair{status("idle")}, uids: []uint64{4}},
+			}},
+	}, groups)
+}
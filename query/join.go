@@ -0,0 +1,329 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+)
+
+// processJoin fills the uidMatrix of a virtual edge given by join(eq(predicate, val(x))). The
+// edge goes from every source uid to the other nodes whose predicate has the same value as the
+// value of x for the source uid. The predicate needs an index, as for eq at the root.
+func (sg *SubGraph) processJoin(ctx context.Context) error {
+	sg.List = true
+	sg.uidMatrix = make([]*pb.List, len(sg.SrcUIDs.GetUids()))
+	for i := range sg.uidMatrix {
+		sg.uidMatrix[i] = &pb.List{}
+	}
+	sg.DestUIDs = &pb.List{}
+	// The val() argument of eq has been replaced by the values of the variable in fillVars.
+	if len(sg.SrcUIDs.GetUids()) == 0 || len(sg.SrcFunc.Args) == 0 {
+		return nil
+	}
+
+	// Find the nodes having one of the values, and then fetch their values to know which source
+	// uids they should be joined with.
+	eq := &SubGraph{
+		Attr:    sg.Attr,
+		ReadTs:  sg.ReadTs,
+		Cache:   sg.Cache,
+		SrcFunc: sg.SrcFunc,
+		Params:  params{Langs: sg.Params.Langs},
+	}
+	result, err := sg.processJoinTask(ctx, eq)
+	if err != nil || result == nil {
+		return err
+	}
+	uids := algo.MergeSorted(result.UidMatrix)
+	if len(uids.Uids) == 0 {
+		return nil
+	}
+
+	values := &SubGraph{
+		Attr:    sg.Attr,
+		ReadTs:  sg.ReadTs,
+		Cache:   sg.Cache,
+		SrcUIDs: uids,
+		Params:  params{Langs: sg.Params.Langs},
+	}
+	if result, err = sg.processJoinTask(ctx, values); err != nil || result == nil {
+		return err
+	}
+	vals := make([]types.Val, len(uids.Uids))
+	for i, vl := range result.ValueMatrix {
+		if i >= len(vals) || len(vl.Values) == 0 {
+			continue
+		}
+		v, err := convertWithBestEffort(vl.Values[0], sg.Attr)
+		if err != nil {
+			return err
+		}
+		vals[i] = v
+	}
+
+	if sg.uidMatrix, err = joinMatrix(sg.SrcUIDs.Uids, sg.Params.UidToVal, uids.Uids,
+		vals); err != nil {
+		return err
+	}
+	sg.DestUIDs = algo.MergeSorted(sg.uidMatrix)
+	return nil
+}
+
+// processJoinTask runs the task of sg over the network. It returns a nil result if the
+// predicate doesn't exist.
+func (sg *SubGraph) processJoinTask(ctx context.Context, task *SubGraph) (*pb.Result, error) {
+	taskQuery, err := createTaskQuery(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return sg.runJoinTask(ctx, taskQuery)
+}
+
+// runJoinTask runs the task query over the network. It returns a nil result if the predicate
+// doesn't exist.
+func (sg *SubGraph) runJoinTask(ctx context.Context, taskQuery *pb.Query) (*pb.Result, error) {
+	result, err := worker.ProcessTaskOverNetwork(ctx, taskQuery)
+	switch {
+	case err != nil && strings.Contains(err.Error(), worker.ErrNonExistentTabletMessage):
+		sg.UnknownAttr = true
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return result, nil
+}
+
+// joinMatrix returns the uids joined with every source uid: the uids whose value is equal to the
+// value of the source uid in srcVals. A source uid isn't joined with itself.
+func joinMatrix(srcUids []uint64, srcVals *types.ShardedMap, uids []uint64,
+	vals []types.Val) ([]*pb.List, error) {
+
+	byValue := make(map[string][]uint64)
+	for i, uid := range uids {
+		if vals[i].Value == nil {
+			continue
+		}
+		key, err := joinKey(vals[i])
+		if err != nil {
+			return nil, err
+		}
+		byValue[key] = append(byValue[key], uid)
+	}
+
+	matrix := make([]*pb.List, len(srcUids))
+	for i, src := range srcUids {
+		matrix[i] = &pb.List{}
+		v, ok := srcVals.Get(src)
+		if !ok {
+			continue
+		}
+		key, err := joinKey(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, uid := range byValue[key] {
+			if uid != src {
+				matrix[i].Uids = append(matrix[i].Uids, uid)
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// joinKey returns the key of the value in the hash tables of the joins, so that the values of
+// different types are equal if they're the same once converted to strings.
+func joinKey(v types.Val) (string, error) {
+	s, err := toStringVal(v)
+	if err != nil {
+		return "", err
+	}
+	return s.Value.(string), nil
+}
+
+// processHashJoin fills the uidMatrix of a virtual edge given by join(eq(val(y), val(x))), which
+// joins the blocks defining x and y in memory. The edge goes from every source uid to the uids
+// whose value of y is equal to the value of x for the source uid, without reading an index.
+func (sg *SubGraph) processHashJoin() error {
+	sg.List = true
+	var err error
+	sg.uidMatrix, err = hashJoinMatrix(sg.SrcUIDs.GetUids(), sg.Params.UidToVal,
+		sg.Params.JoinVals)
+	if err != nil {
+		return err
+	}
+	sg.DestUIDs = algo.MergeSorted(sg.uidMatrix)
+	return nil
+}
+
+// hashJoinMatrix returns the uids joined with every source uid: the uids whose value in vals is
+// equal to the value of the source uid in srcVals. A source uid isn't joined with itself. The hash
+// table is built on the smaller side, and the other side probes it.
+func hashJoinMatrix(srcUids []uint64, srcVals, vals *types.ShardedMap) ([]*pb.List, error) {
+	matrix := make([]*pb.List, len(srcUids))
+	for i := range matrix {
+		matrix[i] = &pb.List{}
+	}
+	if len(srcUids) == 0 || srcVals == nil || vals == nil || vals.Len() == 0 {
+		return matrix, nil
+	}
+
+	if vals.Len() > len(srcUids) {
+		// Build on the source uids, and probe with the values of the other side.
+		bySrc := make(map[string][]int)
+		for i, src := range srcUids {
+			v, ok := srcVals.Get(src)
+			if !ok {
+				continue
+			}
+			key, err := joinKey(v)
+			if err != nil {
+				return nil, err
+			}
+			bySrc[key] = append(bySrc[key], i)
+		}
+		err := vals.Iterate(func(uid uint64, v types.Val) error {
+			key, err := joinKey(v)
+			if err != nil {
+				return err
+			}
+			for _, i := range bySrc[key] {
+				if uid != srcUids[i] {
+					matrix[i].Uids = append(matrix[i].Uids, uid)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The values aren't iterated in the order of their uids.
+		for _, list := range matrix {
+			sort.Slice(list.Uids, func(i, j int) bool { return list.Uids[i] < list.Uids[j] })
+		}
+		return matrix, nil
+	}
+
+	// Build on the values of the other side, and probe with the source uids.
+	byValue := make(map[string][]uint64)
+	err := vals.Iterate(func(uid uint64, v types.Val) error {
+		key, err := joinKey(v)
+		if err != nil {
+			return err
+		}
+		byValue[key] = append(byValue[key], uid)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, uids := range byValue {
+		sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	}
+	for i, src := range srcUids {
+		v, ok := srcVals.Get(src)
+		if !ok {
+			continue
+		}
+		key, err := joinKey(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, uid := range byValue[key] {
+			if uid != src {
+				matrix[i].Uids = append(matrix[i].Uids, uid)
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// processSimilarityJoin fills the uidMatrix of a virtual edge given by
+// join(similar_to(predicate, k, val(x))). The edge goes from every source uid to the k nearest
+// nodes to its vector in x, other than itself, found by the vector index of the predicate. With
+// similar_to(predicate, k, val(x), uid(y)), the nearest nodes are searched among the nodes of y.
+// The searches of all the source uids are sent in a single task, and the candidates are filtered
+// by the index while it's searched, so that k of them are found.
+func (sg *SubGraph) processSimilarityJoin(ctx context.Context) error {
+	sg.List = true
+	sg.uidMatrix = make([]*pb.List, len(sg.SrcUIDs.GetUids()))
+	for i := range sg.uidMatrix {
+		sg.uidMatrix[i] = &pb.List{}
+	}
+	sg.DestUIDs = &pb.List{}
+	hasCandidates := len(sg.SrcFunc.Args) == 3
+	if hasCandidates && len(sg.Params.JoinCandidates.GetUids()) == 0 {
+		return nil
+	}
+
+	var vectors [][]byte
+	var uids []uint64
+	var rows []int
+	for i, src := range sg.SrcUIDs.GetUids() {
+		v, ok := sg.Params.UidToVal.Get(src)
+		if !ok {
+			continue
+		}
+		vec, err := joinVector(v)
+		if err != nil {
+			return errors.Wrapf(err, "while reading the vector of %s for uid %#x",
+				sg.SrcFunc.Args[1].Value, src)
+		}
+		vectors = append(vectors, types.FloatArrayAsBytes(vec))
+		uids = append(uids, src)
+		rows = append(rows, i)
+	}
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	task := &SubGraph{
+		Attr:    sg.Attr,
+		ReadTs:  sg.ReadTs,
+		Cache:   sg.Cache,
+		SrcFunc: &Function{Name: sg.SrcFunc.Name, Args: sg.SrcFunc.Args[:1]},
+	}
+	taskQuery, err := createTaskQuery(ctx, task)
+	if err != nil {
+		return err
+	}
+	taskQuery.JoinVectors, taskQuery.JoinUids = vectors, uids
+	if hasCandidates {
+		taskQuery.UidList = sg.Params.JoinCandidates
+	}
+	result, err := sg.runJoinTask(ctx, taskQuery)
+	if err != nil || result == nil {
+		return err
+	}
+	if len(result.UidMatrix) != len(rows) {
+		return errors.Errorf("Got %d results for the %d vectors of the similarity join",
+			len(result.UidMatrix), len(rows))
+	}
+	for j, i := range rows {
+		sg.uidMatrix[i] = result.UidMatrix[j]
+	}
+	sg.DestUIDs = algo.MergeSorted(sg.uidMatrix)
+	return nil
+}
+
+// joinVector returns the vector of a value of the variable of a similarity join.
+func joinVector(v types.Val) ([]float32, error) {
+	switch val := v.Value.(type) {
+	case []float32:
+		return val, nil
+	case string:
+		return types.ParseVFloat(val)
+	}
+	return nil, errors.Errorf("%s is not a vector", v.Tid.Name())
+}
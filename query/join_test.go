@@ -0,0 +1,76 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+func TestJoinMatrix(t *testing.T) {
+	str := func(s string) types.Val {
+		return types.Val{Tid: types.StringID, Value: s}
+	}
+	srcVals := types.NewShardedMap()
+	srcVals.Set(1, str("a@dgraph.io"))
+	srcVals.Set(2, str("b@dgraph.io"))
+	srcVals.Set(3, types.Val{Tid: types.IntID, Value: int64(15)})
+
+	uids := []uint64{1, 5, 6, 7, 8}
+	vals := []types.Val{
+		str("a@dgraph.io"),
+		str("a@dgraph.io"),
+		str("c@dgraph.io"),
+		{Tid: types.IntID, Value: int64(15)},
+		{},
+	}
+	matrix, err := joinMatrix([]uint64{1, 2, 3, 4}, srcVals, uids, vals)
+	require.NoError(t, err)
+	require.Equal(t, []*pb.List{
+		{Uids: []uint64{5}},
+		{},
+		{Uids: []uint64{7}},
+		{},
+	}, matrix)
+}
+
+func TestHashJoinMatrix(t *testing.T) {
+	srcVals := types.NewShardedMap()
+	srcVals.Set(1, types.Val{Tid: types.StringID, Value: "a@dgraph.io"})
+	srcVals.Set(2, types.Val{Tid: types.StringID, Value: "b@dgraph.io"})
+	srcVals.Set(3, types.Val{Tid: types.IntID, Value: int64(15)})
+
+	vals := types.NewShardedMap()
+	vals.Set(1, types.Val{Tid: types.StringID, Value: "a@dgraph.io"})
+	vals.Set(6, types.Val{Tid: types.StringID, Value: "a@dgraph.io"})
+	vals.Set(5, types.Val{Tid: types.StringID, Value: "a@dgraph.io"})
+	vals.Set(7, types.Val{Tid: types.StringID, Value: "15"})
+	vals.Set(8, types.Val{Tid: types.StringID, Value: "c@dgraph.io"})
+
+	expected := []*pb.List{
+		{Uids: []uint64{5, 6}},
+		{},
+		{Uids: []uint64{7}},
+		{},
+	}
+	// The hash table is built on the source uids, which are fewer than the values.
+	matrix, err := hashJoinMatrix([]uint64{1, 2, 3, 4}, srcVals, vals)
+	require.NoError(t, err)
+	require.Equal(t, expected, matrix)
+
+	// The hash table is built on the values, which are fewer than the source uids.
+	matrix, err = hashJoinMatrix([]uint64{1, 2, 3, 4, 9, 10}, srcVals, vals)
+	require.NoError(t, err)
+	require.Equal(t, append(expected, &pb.List{}, &pb.List{}), matrix)
+
+	matrix, err = hashJoinMatrix([]uint64{1, 2}, srcVals, nil)
+	require.NoError(t, err)
+	require.Equal(t, []*pb.List{{}, {}}, matrix)
+}
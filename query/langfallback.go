@@ -0,0 +1,99 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// ParseLangFallback parses a language fallback chain given like an Accept-Language header, e.g.
+// "fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5". The languages are returned from the most preferred to the
+// least preferred one, "*" becoming "." which stands for the value without language or any other
+// language. The chain always ends with the value without language, given as "".
+func ParseLangFallback(s string) ([]string, error) {
+	type weightedLang struct {
+		lang string
+		q    float64
+	}
+	var langs []weightedLang
+	for _, part := range strings.Split(s, ",") {
+		lang, params, _ := strings.Cut(part, ";")
+		lang = strings.TrimSpace(lang)
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				return nil, errors.Errorf("invalid quality value %q for language %s", v, lang)
+			}
+		}
+		if lang != "*" && strings.TrimFunc(lang, isLangTagRune) != "" {
+			return nil, errors.Errorf("invalid language %q in the language fallback chain", lang)
+		}
+		if q > 0 {
+			langs = append(langs, weightedLang{lang: lang, q: q})
+		}
+	}
+	if len(langs) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	chain := make([]string, 0, len(langs)+1)
+	for _, l := range langs {
+		if l.lang == "*" {
+			// The value without language or any other language, nothing can come after it.
+			return append(chain, "."), nil
+		}
+		chain = append(chain, l.lang)
+	}
+	return append(chain, ""), nil
+}
+
+func isLangTagRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-'
+}
+
+// langFallback returns the language fallback chain of the request of the context, if any.
+func langFallback(ctx context.Context) ([]string, error) {
+	// gRPC client passes the chain as metadata.
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if l := md.Get("lang"); len(l) > 0 {
+			return ParseLangFallback(l[0])
+		}
+	}
+	// HTTP passes it as query parameter which is attached to context.
+	langs, _ := ctx.Value(LangFallbackKey).([]string)
+	return langs, nil
+}
+
+// applyLangFallback looks up the values of the predicates with @lang fetched without languages in
+// the language fallback chain of the request. The results are still returned under the name of the
+// predicate, without the languages.
+func (sg *SubGraph) applyLangFallback(ctx context.Context, namespace uint64) error {
+	if len(sg.Params.Langs) > 0 || sg.SrcFunc != nil || sg.Params.DoCount || sg.IsInternal() ||
+		!schema.State().HasLang(x.NamespaceAttr(namespace, sg.Attr)) {
+		return nil
+	}
+	langs, err := langFallback(ctx)
+	if err != nil || len(langs) == 0 {
+		return err
+	}
+	sg.Params.Langs = langs
+	sg.Params.LangFallback = true
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/task"
+)
+
+func TestParseLangFallback(t *testing.T) {
+	langs, err := ParseLangFallback("")
+	require.NoError(t, err)
+	require.Nil(t, langs)
+
+	langs, err = ParseLangFallback("en;q=0.8, fr-CH, fr;q=0.9")
+	require.NoError(t, err)
+	require.Equal(t, []string{"fr-CH", "fr", "en", ""}, langs)
+
+	// Nothing comes after any language, and the languages with q=0 are left out.
+	langs, err = ParseLangFallback("de, *;q=0.5, en;q=0.3, es;q=0")
+	require.NoError(t, err)
+	require.Equal(t, []string{"de", "."}, langs)
+
+	_, err = ParseLangFallback("en;q=high")
+	require.Error(t, err)
+	_, err = ParseLangFallback("en:fr")
+	require.Error(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("lang", "ja, en"))
+	langs, err = langFallback(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ja", "en", ""}, langs)
+}
+
+func TestLangMapOutput(t *testing.T) {
+	sg := &SubGraph{
+		Params:    params{Alias: "me"},
+		SrcUIDs:   &pb.List{Uids: []uint64{1}},
+		DestUIDs:  &pb.List{Uids: []uint64{1}},
+		uidMatrix: []*pb.List{{Uids: []uint64{1}}},
+		Children: []*SubGraph{
+			{
+				Attr:      "name",
+				Params:    params{Langs: []string{"*"}, ExpandAll: true},
+				SrcUIDs:   &pb.List{Uids: []uint64{1}},
+				uidMatrix: []*pb.List{{}},
+				valueMatrix: []*pb.ValueList{{Values: []*pb.TaskValue{
+					task.FromString("Alice"), task.FromString("Alicia"), task.FromString("Alix"),
+				}}},
+				LangTags: []*pb.LangList{{Lang: []string{"", "es", "fr"}}},
+			},
+		},
+	}
+
+	buf, err := ToJson(context.Background(), &Latency{}, []*SubGraph{sg}, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"me":[{"name":"Alice","name@es":"Alicia","name@fr":"Alix"}]}`, string(buf))
+
+	ctx := context.WithValue(context.Background(), OutputOptionsKey, OutputOptions{LangMap: true})
+	buf, err = ToJson(ctx, &Latency{}, []*SubGraph{sg}, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"me":[{"name":{"":"Alice","es":"Alicia","fr":"Alix"}}]}`, string(buf))
+}
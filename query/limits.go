@@ -0,0 +1,52 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// queryLimits returns the limits of the query of the context, which are all zero if the query
+// isn't limited.
+func queryLimits(ctx context.Context) x.QueryLimits {
+	limits, _ := ctx.Value(QueryLimitsKey).(x.QueryLimits)
+	return limits
+}
+
+// checkLimit returns a *x.QueryLimitError if actual goes beyond the limit max. A zero max doesn't
+// limit anything.
+func checkLimit(name string, max, actual uint64) error {
+	if max > 0 && actual > max {
+		return &x.QueryLimitError{Limit: name, Max: max, Actual: actual}
+	}
+	return nil
+}
+
+// blockDepth returns the number of levels of predicates below the root of the block.
+func blockDepth(gq *dql.GraphQuery) uint64 {
+	var depth uint64
+	for _, child := range gq.Children {
+		depth = max(depth, 1+blockDepth(child))
+	}
+	return depth
+}
+
+// checkVarsLimit checks the number of uids, or values, of the variables populated so far.
+func checkVarsLimit(vars map[string]varValue, limit uint64) error {
+	for _, v := range vars {
+		size := uint64(v.Vals.Len())
+		if v.Uids != nil {
+			size = max(size, uint64(len(v.Uids.Uids)))
+		}
+		if err := checkLimit("query-var-uids", limit, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
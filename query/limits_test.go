@@ -0,0 +1,73 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestBlockDepth(t *testing.T) {
+	res, err := dql.Parse(dql.Request{Str: `{
+		me(func: uid(1)) {
+			name
+			friend {
+				name
+				friend { name }
+			}
+		}
+		you(func: uid(2)) { uid }
+	}`})
+	require.NoError(t, err)
+	require.Len(t, res.Query, 2)
+	require.Equal(t, uint64(3), blockDepth(res.Query[0]))
+	require.Equal(t, uint64(1), blockDepth(res.Query[1]))
+}
+
+func TestQueryLimitsCheck(t *testing.T) {
+	require.Zero(t, queryLimits(context.Background()))
+	limits := x.QueryLimits{Depth: 2, VarUids: 2}
+	ctx := context.WithValue(context.Background(), QueryLimitsKey, limits)
+	require.Equal(t, limits, queryLimits(ctx))
+
+	require.NoError(t, checkLimit("query-depth", 0, 10))
+	require.NoError(t, checkLimit("query-depth", 2, 2))
+	err := checkLimit("query-depth", 2, 3)
+	var limitErr *x.QueryLimitError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, x.QueryLimitError{Limit: "query-depth", Max: 2, Actual: 3}, *limitErr)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	vars := map[string]varValue{"a": {Uids: &pb.List{Uids: []uint64{1, 2}}}}
+	require.NoError(t, checkVarsLimit(vars, 2))
+	vars["b"] = varValue{Uids: &pb.List{Uids: []uint64{1, 2, 3}}}
+	require.ErrorAs(t, checkVarsLimit(vars, 2), &limitErr)
+	require.Equal(t, "query-var-uids", limitErr.Limit)
+}
+
+func TestQueryMemoryLimit(t *testing.T) {
+	enc := newEncoder()
+	defer func() {
+		arenaPool.Put(enc.arena)
+		enc.alloc.Release()
+	}()
+	enc.maxSize = 1 << 20
+	fj := enc.newNode(enc.idForAttr("name"))
+	require.NoError(t, enc.setScalarVal(fj, make([]byte, 1<<19)))
+	err := enc.setScalarVal(enc.newNode(enc.idForAttr("name")), bytes.Repeat([]byte("a"), 1<<19))
+	var limitErr *x.QueryLimitError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, x.QueryLimitError{Limit: "query-memory-mb", Max: 1, Actual: 2}, *limitErr)
+}
@@ -20,6 +20,7 @@ type mathTree struct {
 	Const types.Val // If its a const value node.
 	Val   *types.ShardedMap
 	Child []*mathTree
+	Wasm  *wasmFunc // The user-defined function of the node, resolved by resolveWasmFuncs.
 }
 
 var (
@@ -268,6 +269,10 @@ func evalMathTree(mNode *mathTree) error {
 
 	aggName := mNode.Fn
 
+	if mNode.Wasm != nil {
+		return processWasm(mNode)
+	}
+
 	if isUnary(aggName) {
 		if len(mNode.Child) != 1 {
 			return errors.Errorf("Function %v expects 1 argument. But got: %v", aggName,
@@ -0,0 +1,86 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// processMotif matches the motif of @motif, binding its first variable to the uids of the root
+// function of the block. The children of the block are then processed for all the bound uids, so
+// that every variable of a binding is output as an object with them.
+func (sg *SubGraph) processMotif(ctx context.Context) error {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "while matching @motif")
+	}
+	m := sg.Params.Motif
+	q := &worker.MotifQuery{
+		ReadTs:   sg.ReadTs,
+		TypeAttr: x.NamespaceAttr(ns, "dgraph.type"),
+		Types:    m.Types,
+		Start:    sg.DestUIDs.GetUids(),
+		Offset:   sg.Params.Offset,
+		First:    sg.Params.Count,
+	}
+	for _, e := range m.Edges {
+		q.Edges = append(q.Edges, worker.MotifEdge{
+			From: e.From,
+			To:   e.To,
+			Attr: x.NamespaceAttr(ns, e.Attr),
+		})
+	}
+	if sg.motifBindings, err = worker.MatchMotif(ctx, q); err != nil {
+		return err
+	}
+
+	seen := make(map[uint64]struct{})
+	uids := make([]uint64, 0, len(sg.motifBindings))
+	for _, b := range sg.motifBindings {
+		for _, uid := range b {
+			if _, ok := seen[uid]; !ok {
+				seen[uid] = struct{}{}
+				uids = append(uids, uid)
+			}
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	sg.DestUIDs = &pb.List{Uids: uids}
+	sg.uidMatrix = []*pb.List{sg.DestUIDs}
+	return nil
+}
+
+// addMotifBindings adds an object for every binding of the motif to fj, having the variables as
+// keys. A variable is an object with the uid it's bound to, and the children of the block.
+func (sg *SubGraph) addMotifBindings(enc *encoder, fj fastJsonNode) error {
+	attrID := enc.idForAttr(sg.Params.Alias)
+	for _, b := range sg.motifBindings {
+		n := enc.newNode(attrID)
+		for i, name := range sg.Params.Motif.Vars {
+			uc := enc.newNode(enc.idForAttr(name))
+			if err := sg.preTraverse(enc, b[i], uc); err != nil {
+				return err
+			}
+			if err := enc.SetUID(uc, b[i], enc.uidAttr); err != nil {
+				return err
+			}
+			enc.AddMapChild(n, uc)
+		}
+		enc.AddListChild(fj, n)
+	}
+	if len(sg.motifBindings) == 0 {
+		// So that we return an empty key if the motif doesn't have any binding.
+		enc.AddListChild(fj, enc.newNode(attrID))
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+)
+
+// GraphFormat is the name of the graph output format, given in the respFormat parameter of the
+// HTTP requests and in the resp-format metadata of the gRPC requests.
+const GraphFormat = "graph"
+
+// IsGraphFormat returns true if the response of the query must be in the graph output format.
+func IsGraphFormat(ctx context.Context) bool {
+	// gRPC client passes the response format as metadata.
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if f := md.Get("resp-format"); len(f) > 0 && f[0] == GraphFormat {
+			return true
+		}
+	}
+	// HTTP passes it as query parameter which is attached to context.
+	g, _ := ctx.Value(GraphFormatKey).(bool)
+	return g
+}
+
+// graphNode is a node of the graph output, along with the values of its scalar predicates.
+type graphNode struct {
+	uid        uint64
+	Id         string                     `json:"id"`
+	Types      []string                   `json:"types"`
+	Attributes map[string]json.RawMessage `json:"attributes"`
+}
+
+// graphEdge is an edge of the graph output, from the node with the source uid to the node with
+// the target uid.
+type graphEdge struct {
+	source    uint64
+	target    uint64
+	predicate string
+}
+
+type graphEdgeJson struct {
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Predicate string `json:"predicate"`
+}
+
+// graphBuilder is used to generate the nodes and edges of the graph output from subgraph.
+type graphBuilder struct {
+	nodes map[uint64]*graphNode
+	edges map[graphEdge]struct{}
+}
+
+// ToGraph converts the given subgraph list into the graph output format, which has the nodes and
+// the edges of the result as flat arrays instead of nested objects:
+//
+//	{"nodes": [{"id": "0x1", "types": ["Person"], "attributes": {"name": "Alice"}}],
+//	 "edges": [{"source": "0x1", "target": "0x2", "predicate": "friend"}]}
+//
+// Every node is listed once, however many times it's reached in the query. The nodes are sorted
+// by uid, and the edges by source, predicate and target, so that the output is stable.
+func ToGraph(l *Latency, sgl []*SubGraph) ([]byte, error) {
+	b := &graphBuilder{
+		nodes: make(map[uint64]*graphNode),
+		edges: make(map[graphEdge]struct{}),
+	}
+	for _, sg := range sgl {
+		if sg.Params.Alias == "var" || sg.Params.Alias == "shortest" {
+			continue
+		}
+		if err := validateSubGraphForGraph(sg); err != nil {
+			return nil, err
+		}
+		// Skip parent graph. we don't want parent values.
+		for _, child := range sg.Children {
+			if err := b.castToGraph(child); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	out := struct {
+		Nodes []*graphNode     `json:"nodes"`
+		Edges []*graphEdgeJson `json:"edges"`
+	}{
+		Nodes: make([]*graphNode, 0, len(b.nodes)),
+		Edges: make([]*graphEdgeJson, 0, len(b.edges)),
+	}
+	for _, node := range b.nodes {
+		sort.Strings(node.Types)
+		out.Nodes = append(out.Nodes, node)
+	}
+	sort.Slice(out.Nodes, func(i, j int) bool { return out.Nodes[i].uid < out.Nodes[j].uid })
+
+	edges := make([]graphEdge, 0, len(b.edges))
+	for edge := range b.edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		switch {
+		case edges[i].source != edges[j].source:
+			return edges[i].source < edges[j].source
+		case edges[i].predicate != edges[j].predicate:
+			return edges[i].predicate < edges[j].predicate
+		default:
+			return edges[i].target < edges[j].target
+		}
+	})
+	for _, edge := range edges {
+		out.Edges = append(out.Edges, &graphEdgeJson{
+			Source:    fmt.Sprintf("%#x", edge.source),
+			Target:    fmt.Sprintf("%#x", edge.target),
+			Predicate: edge.predicate,
+		})
+	}
+	return json.Marshal(out)
+}
+
+// castToGraph adds the nodes and edges of the given subgraph and of its children.
+func (b *graphBuilder) castToGraph(sg *SubGraph) error {
+	if err := validateSubGraphForGraph(sg); err != nil {
+		return err
+	}
+	if sg.SrcUIDs != nil {
+		if err := b.graphForSubgraph(sg); err != nil {
+			return err
+		}
+	}
+	for _, child := range sg.Children {
+		if err := b.castToGraph(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *graphBuilder) node(uid uint64) *graphNode {
+	node, ok := b.nodes[uid]
+	if !ok {
+		node = &graphNode{
+			uid:        uid,
+			Id:         fmt.Sprintf("%#x", uid),
+			Types:      []string{},
+			Attributes: make(map[string]json.RawMessage),
+		}
+		b.nodes[uid] = node
+	}
+	return node
+}
+
+// graphForSubgraph adds the values and edges of the given subgraph to the nodes of its source
+// uids.
+func (b *graphBuilder) graphForSubgraph(sg *SubGraph) error {
+	// Do not generate the edges of recurse queries if all the children have null uidMatrix.
+	nonNullChild := false
+	for _, ch := range sg.Children {
+		if len(ch.uidMatrix) != 0 {
+			nonNullChild = true
+		}
+	}
+	if len(sg.Children) > 0 && !nonNullChild {
+		return nil
+	}
+
+	for i, uid := range sg.SrcUIDs.Uids {
+		if sg.Params.IgnoreResult {
+			continue
+		}
+		if sg.IsInternal() {
+			if sg.Params.Expand != "" {
+				continue
+			}
+			val, ok := sg.Params.UidToVal.Get(uid)
+			if !ok && val.Value == nil {
+				continue
+			}
+			outputval, err := valToBytes(val)
+			if err != nil {
+				continue
+			}
+			b.node(uid).Attributes[sg.aggWithVarFieldName()] = outputval
+			continue
+		}
+		switch {
+		case len(sg.counts) > 0:
+			fieldName := sg.Params.Alias
+			if fieldName == "" {
+				fieldName = fmt.Sprintf("count(%s)", sg.Attr)
+			}
+			b.node(uid).Attributes[fieldName] =
+				[]byte(strconv.FormatUint(uint64(sg.counts[i]), 10))
+		case i < len(sg.uidMatrix) && len(sg.uidMatrix[i].Uids) != 0 && len(sg.Children) > 0:
+			b.graphForUIDList(uid, sg.uidMatrix[i], sg)
+		case i < len(sg.valueMatrix):
+			if err := b.graphForValueList(uid, sg.valueMatrix[i], sg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// graphForUIDList adds the edges from the subject to the uids of the list.
+func (b *graphBuilder) graphForUIDList(subject uint64, list *pb.List, sg *SubGraph) {
+	for _, destUID := range list.Uids {
+		if algo.IndexOf(sg.DestUIDs, destUID) < 0 {
+			// This uid is filtered.
+			continue
+		}
+		b.node(subject)
+		b.node(destUID)
+		b.edges[graphEdge{source: subject, target: destUID, predicate: sg.fieldName()}] =
+			struct{}{}
+	}
+}
+
+// graphForValueList adds the values of the list to the attributes or the types of the subject.
+// The uid attribute is skipped as it's the id of the node.
+func (b *graphBuilder) graphForValueList(subject uint64, valueList *pb.ValueList,
+	sg *SubGraph) error {
+
+	if sg.Attr == "uid" || len(valueList.Values) == 0 {
+		return nil
+	}
+	fieldName := sg.fieldName()
+	if sg.Params.Alias == "" && len(sg.Params.Langs) > 0 && sg.Params.Langs[0] != "*" &&
+		!sg.Params.LangFallback {
+		fieldName += "@" + strings.Join(sg.Params.Langs, ":")
+	}
+
+	values := make([]json.RawMessage, 0, len(valueList.Values))
+	for _, destValue := range valueList.Values {
+		val, err := convertWithBestEffort(destValue, sg.Attr)
+		if err != nil {
+			continue
+		}
+		if sg.Attr == "dgraph.type" && sg.Params.Alias == "" {
+			if typ, ok := val.Value.(string); ok {
+				b.addType(subject, typ)
+			}
+			continue
+		}
+		outputval, err := valToBytes(val)
+		if err != nil {
+			return err
+		}
+		values = append(values, outputval)
+	}
+
+	switch {
+	case len(values) == 0:
+	case len(values) == 1 && !sg.List:
+		b.node(subject).Attributes[fieldName] = values[0]
+	default:
+		list, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		b.node(subject).Attributes[fieldName] = list
+	}
+	return nil
+}
+
+func (b *graphBuilder) addType(subject uint64, typ string) {
+	node := b.node(subject)
+	for _, t := range node.Types {
+		if t == typ {
+			return
+		}
+	}
+	node.Types = append(node.Types, typ)
+}
+
+func validateSubGraphForGraph(sg *SubGraph) error {
+	if sg.IsGroupBy() {
+		return errors.New("groupby is not supported in the graph output format")
+	}
+	if sg.Attr == "uid" && sg.Params.DoCount && sg.IsInternal() {
+		return errors.New("uid count is not supported in the graph output format")
+	}
+	if sg.Params.Normalize {
+		return errors.New("normalize directive is not supported in the graph output format")
+	}
+	if sg.Params.Facet != nil && !sg.Params.ExpandAll {
+		return errors.New("facets are not supported in the graph output format")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+)
+
+func stringValues(uids []uint64, vals map[uint64][]string) []*pb.ValueList {
+	matrix := make([]*pb.ValueList, 0, len(uids))
+	for _, uid := range uids {
+		list := &pb.ValueList{}
+		for _, val := range vals[uid] {
+			list.Values = append(list.Values, &pb.TaskValue{
+				Val:     []byte(val),
+				ValType: pb.Posting_STRING,
+			})
+		}
+		matrix = append(matrix, list)
+	}
+	return matrix
+}
+
+func TestToGraph(t *testing.T) {
+	src := []uint64{1, 2}
+	dest := []uint64{1, 2, 4}
+	names := map[uint64][]string{1: {"Alice"}, 2: {"Bob"}}
+	root := &SubGraph{
+		SrcUIDs:   &pb.List{Uids: src},
+		DestUIDs:  &pb.List{Uids: src},
+		uidMatrix: []*pb.List{{Uids: src}},
+		Children: []*SubGraph{
+			{Attr: "name", SrcUIDs: &pb.List{Uids: src}, valueMatrix: stringValues(src, names)},
+			{
+				Attr:    "dgraph.type",
+				SrcUIDs: &pb.List{Uids: src},
+				valueMatrix: stringValues(src,
+					map[uint64][]string{1: {"Person", "Admin"}, 2: {"Person"}}),
+			},
+			{
+				Attr:    "nick",
+				List:    true,
+				SrcUIDs: &pb.List{Uids: src},
+				valueMatrix: stringValues(src,
+					map[uint64][]string{1: {"Al", "Ali"}, 2: {"Bobby"}}),
+			},
+			{
+				Attr:     "friend",
+				SrcUIDs:  &pb.List{Uids: src},
+				DestUIDs: &pb.List{Uids: dest},
+				// 0x3 is filtered out.
+				uidMatrix: []*pb.List{{Uids: []uint64{2, 3, 4}}, {Uids: []uint64{1}}},
+				Children: []*SubGraph{{
+					Attr:    "name",
+					SrcUIDs: &pb.List{Uids: dest},
+					// The scalar predicates have an empty uid list for every source uid.
+					uidMatrix:   []*pb.List{{}, {}, {}},
+					valueMatrix: stringValues(dest, names),
+				}},
+			},
+		},
+	}
+	varBlock := &SubGraph{Params: params{Alias: "var"}, Children: []*SubGraph{{
+		Attr:        "name",
+		SrcUIDs:     &pb.List{Uids: []uint64{5}},
+		valueMatrix: stringValues([]uint64{5}, map[uint64][]string{5: {"Carol"}}),
+	}}}
+
+	out, err := ToGraph(nil, []*SubGraph{root, varBlock})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"nodes": [
+			{"id": "0x1", "types": ["Admin", "Person"],
+				"attributes": {"name": "Alice", "nick": ["Al", "Ali"]}},
+			{"id": "0x2", "types": ["Person"], "attributes": {"name": "Bob", "nick": ["Bobby"]}},
+			{"id": "0x4", "types": [], "attributes": {}}
+		],
+		"edges": [
+			{"source": "0x1", "target": "0x2", "predicate": "friend"},
+			{"source": "0x1", "target": "0x4", "predicate": "friend"},
+			{"source": "0x2", "target": "0x1", "predicate": "friend"}
+		]
+	}`, string(out))
+
+	out, err = ToGraph(nil, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"nodes": [], "edges": []}`, string(out))
+
+	root.Children[3].Params.Normalize = true
+	_, err = ToGraph(nil, []*SubGraph{root})
+	require.ErrorContains(t, err, "normalize directive is not supported in the graph output format")
+}
+
+func TestIsGraphFormat(t *testing.T) {
+	ctx := context.Background()
+	require.False(t, IsGraphFormat(ctx))
+	require.True(t, IsGraphFormat(context.WithValue(ctx, GraphFormatKey, true)))
+	require.True(t, IsGraphFormat(metadata.NewIncomingContext(ctx,
+		metadata.Pairs("resp-format", GraphFormat))))
+	require.False(t, IsGraphFormat(metadata.NewIncomingContext(ctx,
+		metadata.Pairs("resp-format", "json"))))
+}
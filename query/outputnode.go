@@ -28,6 +28,7 @@ import (
 	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/dgraph-io/ristretto/v2/z"
 	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/dql"
 	gqlSchema "github.com/hypermodeinc/dgraph/v25/graphql/schema"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/task"
@@ -93,6 +94,14 @@ type encoder struct {
 
 	// buf is the buffer which stores the JSON encoded response
 	buf *bytes.Buffer
+
+	// langMap is true if the values of all the languages are encoded as an object, see
+	// OutputOptions.LangMap.
+	langMap bool
+
+	// maxSize is the threshold of curSize, maxEncodedSize unless the query-memory-mb limit of the
+	// query is lower.
+	maxSize uint64
 }
 
 type node struct {
@@ -104,6 +113,7 @@ type node struct {
 	// Bit ThirdMSB(third bit in Byte-8) stores if the node contains uid value
 	// Bit FourthMSB(fourth bit in Byte-8) stores if the order of node's children has been fixed.
 	// Bit FifthMSB(fifth bit in Byte-8) stores if node contains value for a @custom GraphQL field.
+	// Bit SixthMSB(sixth bit in Byte-8) stores if @normalize should keep the node nested.
 	// Byte-5 is not getting used as of now.
 	// |-----------------------------------------------------------------------------|
 	// |             8              |    7   |    6   |    5   |  4  |  3  |  2 |  1 |
@@ -113,6 +123,7 @@ type node struct {
 	// | ThirdMSB - uid             |                 | Now    |                     |
 	// | FourthMSB - Order Info     |                 |        |                     |
 	// | FifthMSB - @custom GraphQL |                 |        |                     |
+	// | SixthMSB - nested          |                 |        |                     |
 	// |-----------------------------------------------------------------------------|
 	meta uint64
 
@@ -134,6 +145,7 @@ func newEncoder() *encoder {
 		arena:   a,
 		alloc:   z.NewAllocator(4<<10, "OutputNode.Encoder"),
 		buf:     &bytes.Buffer{},
+		maxSize: maxEncodedSize,
 	}
 	e.uidAttr = e.idForAttr("uid")
 	return e
@@ -264,6 +276,9 @@ const (
 	// customBit is a value with fifth most significant bit set to 1. If a node has customBit set
 	// in its meta, it means that node stores the value for a @custom GraphQL field.
 	customBit = 1 << 59
+	// nestedBit is a value with sixth most significant bit set to 1. If a node has nestedBit set in
+	// its meta, @normalize keeps it as an object instead of flattening it into its parent.
+	nestedBit = 1 << 58
 
 	// Value with all bits set to 1 for bytes 7 and 6.
 	setBytes76 = uint64(0x00FFFF0000000000)
@@ -323,7 +338,11 @@ func (enc *encoder) setScalarVal(fj fastJsonNode, sv []byte) error {
 
 	// Also increase curSize.
 	enc.curSize += uint64(len(sv))
-	if size := uint64(enc.alloc.Size()) + enc.curSize; size > maxEncodedSize {
+	if size := uint64(enc.alloc.Size()) + enc.curSize; size > enc.maxSize {
+		if enc.maxSize < maxEncodedSize {
+			return &x.QueryLimitError{Limit: "query-memory-mb", Max: enc.maxSize >> 20,
+				Actual: (size + 1<<20 - 1) >> 20}
+		}
 		return fmt.Errorf("estimated response size: %d is bigger than threshold: %d",
 			size, maxEncodedSize)
 	}
@@ -354,6 +373,10 @@ func (enc *encoder) setCustom(fj fastJsonNode) {
 	fj.meta |= customBit
 }
 
+func (enc *encoder) setNested(fj fastJsonNode) {
+	fj.meta |= nestedBit
+}
+
 //nolint:unused // appendAttrs is used in outputnode_test.go as a helper function
 func (enc *encoder) appendAttrs(fj, child fastJsonNode) {
 	enc.addChildren(fj, child)
@@ -461,6 +484,12 @@ func (enc *encoder) getCustom(fj fastJsonNode) bool {
 	return (fj.meta & customBit) > 0
 }
 
+// flattens returns true if @normalize flattens the attributes of fj into its parent, that is if
+// fj has children, and isn't a facetsParent nor a nested node.
+func (enc *encoder) flattens(fj fastJsonNode) bool {
+	return enc.children(fj) != nil && !enc.getFacetsParent(fj) && (fj.meta&nestedBit) == 0
+}
+
 func (enc *encoder) children(fj fastJsonNode) fastJsonNode {
 	// Return nil if no attrs are found.
 	return fj.child
@@ -690,6 +719,11 @@ func valToBytes(v types.Val) ([]byte, error) {
 		return []byte(fmt.Sprintf("%q", v.Value.(string))), nil
 	case types.VFloatID:
 		return json.Marshal(v.Value.([]float32))
+	case types.Uint64ID:
+		return []byte(strconv.FormatUint(v.Value.(uint64), 10)), nil
+	case types.BytesID:
+		// Raw bytes are returned as a base64 string.
+		return json.Marshal(v.Value.([]byte))
 	default:
 		return nil, errors.New("Unsupported types.Val.Tid")
 	}
@@ -883,6 +917,15 @@ func (enc *encoder) copySingleNode(fj fastJsonNode) fastJsonNode {
 	return nn
 }
 
+// prefixAttrs returns a copy of the fastJson list fj, with the attributes prefixed by prefix.
+func (enc *encoder) prefixAttrs(fj fastJsonNode, prefix string) fastJsonNode {
+	head, _ := enc.copyFastJsonList(fj)
+	for cur := head; cur != nil; cur = cur.next {
+		enc.setAttr(cur, enc.idForAttr(prefix+enc.attrForID(enc.getAttr(cur))))
+	}
+	return head
+}
+
 func (enc *encoder) merge(parent, child []fastJsonNode) ([]fastJsonNode, error) {
 	if len(parent) == 0 {
 		return child, nil
@@ -918,15 +961,15 @@ func (enc *encoder) merge(parent, child []fastJsonNode) ([]fastJsonNode, error)
 }
 
 // normalize returns all attributes of fj and its children (if any).
-func (enc *encoder) normalize(fj fastJsonNode) ([]fastJsonNode, error) {
+func (enc *encoder) normalize(fj fastJsonNode, args dql.NormalizeArgs) ([]fastJsonNode, error) {
 	cnt := 0
 	chead := enc.children(fj)
 	for chead != nil {
 		// Here we are counting all non-scalar children of fj. If there are any such
 		// children, we will flatten them, otherwise we will return all children.
 		// We should only consider those children(of fj) for flattening which have
-		// children and are not facetsParent.
-		if enc.children(chead) != nil && !enc.getFacetsParent(chead) {
+		// children and are not facetsParent or nested.
+		if enc.flattens(chead) {
 			cnt++
 		}
 		chead = chead.next
@@ -944,12 +987,12 @@ func (enc *encoder) normalize(fj fastJsonNode) ([]fastJsonNode, error) {
 	var shead, curScalar fastJsonNode
 	chead = enc.children(fj)
 	for chead != nil {
-		if enc.children(chead) != nil && !enc.getFacetsParent(chead) {
+		if enc.flattens(chead) {
 			chead = chead.next
 			continue
 		}
 
-		// Here, add all nodes which have either no children or they are facetsParent.
+		// Here, add all nodes which have either no children or they are facetsParent or nested.
 		copyNode := enc.copySingleNode(chead)
 		if curScalar == nil {
 			shead, curScalar = copyNode, copyNode
@@ -965,8 +1008,8 @@ func (enc *encoder) normalize(fj fastJsonNode) ([]fastJsonNode, error) {
 	chead = enc.children(fj)
 	for chead != nil {
 		childNode := chead
-		// Here, exclude all nodes which have either no children or they are facetsParent.
-		if enc.children(childNode) == nil || enc.getFacetsParent(childNode) {
+		// Here, exclude all nodes which have either no children or they are facetsParent or nested.
+		if !enc.flattens(childNode) {
 			chead = chead.next
 			continue
 		}
@@ -991,7 +1034,11 @@ func (enc *encoder) normalize(fj fastJsonNode) ([]fastJsonNode, error) {
 			enc.MergeSort(&parentSlice[i])
 		}
 
-		// From every list we need to remove node with attribute "uid".
+		// From every list we need to remove node with attribute "uid", unless the dotted keys keep
+		// the fields without an alias.
+		if args.Dotted {
+			continue
+		}
 		var prev, cur fastJsonNode
 		cur = slice
 		for cur != nil {
@@ -1019,7 +1066,16 @@ func (sg *SubGraph) addGroupby(enc *encoder, fj fastJsonNode,
 		return nil
 	}
 	g := enc.newNode(enc.idForAttr(fname))
-	for _, grp := range res.group {
+	if err := addGroups(enc, g, res.group); err != nil {
+		return err
+	}
+	enc.AddListChild(fj, g)
+	return nil
+}
+
+// addGroups adds the groups to fj under @groupby, with the groups nested within them.
+func addGroups(enc *encoder, fj fastJsonNode, groups []*groupResult) error {
+	for _, grp := range groups {
 		uc := enc.newNode(enc.idForAttr("@groupby"))
 		for _, it := range grp.keys {
 			if err := enc.AddValue(uc, enc.idForAttr(it.attr), it.key); err != nil {
@@ -1031,9 +1087,11 @@ func (sg *SubGraph) addGroupby(enc *encoder, fj fastJsonNode,
 				return err
 			}
 		}
-		enc.AddListChild(g, uc)
+		if err := addGroups(enc, uc, grp.groups); err != nil {
+			return err
+		}
+		enc.AddListChild(fj, uc)
 	}
-	enc.AddListChild(fj, g)
 	return nil
 }
 
@@ -1048,7 +1106,7 @@ func (sg *SubGraph) addAggregations(enc *encoder, fj fastJsonNode) error {
 			// See: query.go:fillVars
 			// In this case we do nothing. The aggregate value in response will be returned as NULL.
 		}
-		if child.Params.Normalize && child.Params.Alias == "" {
+		if child.normalizeDrops() {
 			continue
 		}
 		fieldName := child.aggWithVarFieldName()
@@ -1070,8 +1128,7 @@ func (sg *SubGraph) handleCountUIDNodes(enc *encoder, n fastJsonNode, count int)
 	sgFieldID := enc.idForAttr(fieldName)
 	for _, child := range sg.Children {
 		uidCount := child.Attr == "uid" && child.Params.DoCount && child.IsInternal()
-		normWithoutAlias := child.Params.Alias == "" && child.Params.Normalize
-		if uidCount && !normWithoutAlias {
+		if uidCount && !child.normalizeDrops() {
 			addedNewChild = true
 
 			c := types.ValueForType(types.IntID)
@@ -1116,6 +1173,9 @@ func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 		}
 		return sg.addGroupby(enc, fj, sg.GroupbyRes[0], sg.Params.Alias)
 	}
+	if sg.Params.Motif != nil {
+		return sg.addMotifBindings(enc, fj)
+	}
 
 	lenList := len(sg.uidMatrix[0].Uids)
 	for i := range lenList {
@@ -1151,7 +1211,7 @@ func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 		// fix its order again. Hence mark the newly created node visited immediately.
 		enc.fixOrder(n1)
 		// Lets normalize the response now.
-		normalized, err := enc.normalize(n1)
+		normalized, err := enc.normalize(n1, sg.Params.NormalizeArgs)
 		if err != nil {
 			return err
 		}
@@ -1175,6 +1235,14 @@ type Extensions struct {
 	Latency *api.Latency    `json:"server_latency,omitempty"`
 	Txn     *api.TxnContext `json:"txn,omitempty"`
 	Metrics *api.Metrics    `json:"metrics,omitempty"`
+	Retries int             `json:"retries,omitempty"`
+	// RequestId is the id of the request, to correlate it with the logs and traces of the
+	// cluster.
+	RequestId string `json:"request_id,omitempty"`
+	// Partial is true if the results are partial, and Boundary lists the paths of the subgraphs
+	// which weren't fetched, when the request asked for partial results.
+	Partial  bool     `json:"partial,omitempty"`
+	Boundary []string `json:"boundary,omitempty"`
 }
 
 func (sg *SubGraph) toFastJSON(ctx context.Context, l *Latency, field gqlSchema.Field) ([]byte,
@@ -1190,8 +1258,18 @@ func (sg *SubGraph) toFastJSON(ctx context.Context, l *Latency, field gqlSchema.
 		arenaPool.Put(enc.arena)
 		enc.alloc.Release()
 	}()
+	if limit := queryLimits(ctx).MemoryMb; limit > 0 {
+		enc.maxSize = min(enc.maxSize, limit<<20)
+	}
 
 	var err error
+	var opts OutputOptions
+	if field == nil {
+		if opts, err = outputOptions(ctx); err != nil {
+			return nil, err
+		}
+		enc.langMap = opts.LangMap
+	}
 	n := enc.newNode(enc.idForAttr("_root_"))
 	for _, sg := range sg.Children {
 		err = processNodeUids(n, enc, sg)
@@ -1211,8 +1289,13 @@ func (sg *SubGraph) toFastJSON(ctx context.Context, l *Latency, field gqlSchema.
 		// if there were any GraphQL errors, we need to propagate them back to GraphQL layer along
 		// with the data. So, don't return here if we get an error.
 		err = sg.toGraphqlJSON(newGraphQLEncoder(ctx, enc), n, field)
-	} else if err = sg.toDqlJSON(enc, n); err != nil {
-		return nil, err
+	} else {
+		if err := enc.applyOutputOptions(n, opts); err != nil {
+			return nil, err
+		}
+		if err := sg.toDqlJSON(enc, n); err != nil {
+			return nil, err
+		}
 	}
 
 	// Return error if encoded buffer size exceeds than a threshold size.
@@ -1275,8 +1358,14 @@ func (sg *SubGraph) fieldName() string {
 	return fieldName
 }
 
+// normalizeDrops returns true if the field is left out of the response of @normalize, which only
+// keeps the fields with an alias unless it gives dotted keys.
+func (sg *SubGraph) normalizeDrops() bool {
+	return sg.Params.Normalize && sg.Params.Alias == "" && !sg.Params.NormalizeArgs.Dotted
+}
+
 func (sg *SubGraph) addCount(enc *encoder, count uint64, dst fastJsonNode) error {
-	if sg.Params.Normalize && sg.Params.Alias == "" {
+	if sg.normalizeDrops() {
 		return nil
 	}
 	c := types.ValueForType(types.IntID)
@@ -1288,6 +1377,18 @@ func (sg *SubGraph) addCount(enc *encoder, count uint64, dst fastJsonNode) error
 	return enc.AddValue(dst, enc.idForAttr(fieldName), c)
 }
 
+// aggregatorFieldName returns the field of the aggregation of arg, like min(val(x)),
+// percentile(val(x), 0.95) or count(distinct val(x)).
+func aggregatorFieldName(fn *Function, arg string) string {
+	switch {
+	case fn.Name == "distinct":
+		return fmt.Sprintf("count(distinct %s)", arg)
+	case fn.Name == "percentile" && len(fn.Args) == 1:
+		return fmt.Sprintf("percentile(%s, %s)", arg, fn.Args[0].Value)
+	}
+	return fmt.Sprintf("%s(%s)", fn.Name, arg)
+}
+
 func (sg *SubGraph) aggWithVarFieldName() string {
 	if sg.Params.Alias != "" {
 		return sg.Params.Alias
@@ -1296,7 +1397,7 @@ func (sg *SubGraph) aggWithVarFieldName() string {
 	if len(sg.Params.NeedsVar) > 0 {
 		fieldName = fmt.Sprintf("val(%v)", sg.Params.NeedsVar[0].Name)
 		if sg.SrcFunc != nil {
-			fieldName = fmt.Sprintf("%s(%v)", sg.SrcFunc.Name, fieldName)
+			fieldName = aggregatorFieldName(sg.SrcFunc, fieldName)
 		}
 	}
 	return fieldName
@@ -1363,7 +1464,7 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 			if pc.Params.Expand != "" {
 				continue
 			}
-			if pc.Params.Normalize && pc.Params.Alias == "" {
+			if pc.normalizeDrops() {
 				continue
 			}
 			if err := pc.addInternalNode(enc, uid, dst); err != nil {
@@ -1403,6 +1504,17 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 				return err
 			}
 
+		case isViewAttr(pc.Attr):
+			if len(pc.viewMatrix[idx]) == 0 || pc.normalizeDrops() {
+				continue
+			}
+			enc.curSize += uint64(len(fieldName) + len(pc.viewMatrix[idx]))
+			fj, err := enc.makeScalarNode(enc.idForAttr(fieldName), pc.viewMatrix[idx], false)
+			if err != nil {
+				return err
+			}
+			enc.addChildren(dst, fj)
+
 		case pc.SrcFunc != nil && pc.SrcFunc.Name == "checkpwd":
 			if err := pc.addCheckPwd(enc, pc.valueMatrix[idx].Values, dst); err != nil {
 				return err
@@ -1468,10 +1580,14 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 						// TODO(ashish): Check reason for calling fixOrder() here in
 						// processNodeUids(), just before calling normalize().
 						enc.fixOrder(uc)
-						normAttrs, err := enc.normalize(uc)
+						normAttrs, err := enc.normalize(uc, pc.Params.NormalizeArgs)
 						if err != nil {
 							return err
 						}
+						// With lists, the items of a list predicate are kept as objects in its
+						// parent, under the field name. Otherwise, with dotted keys, their
+						// attributes are flattened under fieldName.attribute.
+						nested := pc.Params.NormalizeArgs.Lists && pc.List
 
 						for _, c := range normAttrs {
 							// Adding as list child irrespective of the type of pc
@@ -1500,8 +1616,14 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 							// }
 							// boss should be of list type because there can be multiple friends of
 							// boss.
+							if pc.Params.NormalizeArgs.Dotted && !nested {
+								c = enc.prefixAttrs(c, fieldName+".")
+							}
 							node := enc.newNode(fieldID)
 							enc.setVisited(node, true)
+							if nested {
+								enc.setNested(node)
+							}
 							enc.addChildren(node, c)
 							enc.AddListChild(dst, node)
 						}
@@ -1520,7 +1642,8 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 				return err
 			}
 		default:
-			if pc.Params.Alias == "" && len(pc.Params.Langs) > 0 && pc.Params.Langs[0] != "*" {
+			if pc.Params.Alias == "" && len(pc.Params.Langs) > 0 && pc.Params.Langs[0] != "*" &&
+				!pc.Params.LangFallback {
 				fieldName += "@"
 				fieldName += strings.Join(pc.Params.Langs, ":")
 			}
@@ -1550,6 +1673,14 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 				continue
 			}
 
+			// With the lang-map output option, the values of all the languages are put in one
+			// object under the field name.
+			var langMap fastJsonNode
+			if enc.langMap && pc.Params.ExpandAll && len(pc.LangTags) > idx &&
+				len(pc.LangTags[idx].Lang) != 0 {
+				langMap = enc.newNode(fieldID)
+			}
+
 			for i, tv := range pc.valueMatrix[idx].Values {
 				// if conversion not possible, we ignore it in the result.
 				sv, convErr := convertWithBestEffort(tv, pc.Attr)
@@ -1564,6 +1695,16 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 					}
 					fieldNameWithTag := fieldName
 					lang := pc.LangTags[idx].Lang[i]
+					if langMap != nil {
+						if lang == "*" {
+							lang = ""
+						}
+						if err := enc.AddListValue(langMap, enc.idForAttr(lang), sv,
+							pc.List && lang == ""); err != nil {
+							return err
+						}
+						continue
+					}
 					if lang != "" && lang != "*" {
 						fieldNameWithTag += "@" + lang
 					}
@@ -1576,22 +1717,18 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 				}
 
 				encodeAsList := pc.List && len(pc.Params.Langs) == 0
-				if !pc.Params.Normalize {
-					err := enc.AddListValue(dst, fieldID, sv, encodeAsList)
-					if err != nil {
-						return err
-					}
-					continue
-				}
 				// If the query had the normalize directive, then we only add nodes
-				// with an Alias.
-				if pc.Params.Alias != "" {
+				// with an Alias, unless it asked for dotted keys.
+				if !pc.normalizeDrops() {
 					err := enc.AddListValue(dst, fieldID, sv, encodeAsList)
 					if err != nil {
 						return err
 					}
 				}
 			}
+			if langMap != nil && !enc.IsEmpty(langMap) && !pc.normalizeDrops() {
+				enc.AddMapChild(dst, langMap)
+			}
 		}
 	}
 
@@ -18,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/hypermodeinc/dgraph/v25/dql"
 	"github.com/hypermodeinc/dgraph/v25/types"
 	"github.com/hypermodeinc/dgraph/v25/worker"
 	"github.com/hypermodeinc/dgraph/v25/x"
@@ -85,10 +86,54 @@ func TestNormalizeJSONLimit(t *testing.T) {
 				types.ValueForType(types.StringID)))
 		}
 	}
-	_, err := enc.normalize(n)
+	_, err := enc.normalize(n, dql.NormalizeArgs{})
 	require.Error(t, err, "Couldn't evaluate @normalize directive - too many results")
 }
 
+func TestNormalizeNested(t *testing.T) {
+	x.Config.LimitNormalizeNode = 10000
+	enc := newEncoder()
+	str := func(s string) types.Val {
+		return types.Val{Tid: types.StringID, Value: s}
+	}
+	normalized := func(nested bool) []string {
+		root := enc.newNode(enc.idForAttr("me"))
+		require.NoError(t, enc.AddValue(root, enc.idForAttr("name"), str("alice")))
+		for _, name := range []string{"bob", "carl"} {
+			friend := enc.newNode(enc.idForAttr("friend"))
+			require.NoError(t, enc.AddValue(friend, enc.idForAttr("friend.name"), str(name)))
+			if nested {
+				enc.setNested(friend)
+			}
+			enc.AddListChild(root, friend)
+		}
+		enc.fixOrder(root)
+
+		lists, err := enc.normalize(root, dql.NormalizeArgs{Lists: nested})
+		require.NoError(t, err)
+		var res []string
+		for _, l := range lists {
+			n := enc.newNode(0)
+			enc.setVisited(n, true)
+			enc.addChildren(n, l)
+			enc.buf.Reset()
+			require.NoError(t, enc.encode(n))
+			res = append(res, enc.buf.String())
+		}
+		return res
+	}
+
+	res := normalized(false)
+	require.Len(t, res, 2)
+	require.JSONEq(t, `{"friend.name":"bob","name":"alice"}`, res[0])
+	require.JSONEq(t, `{"friend.name":"carl","name":"alice"}`, res[1])
+
+	res = normalized(true)
+	require.Len(t, res, 1)
+	require.JSONEq(t, `{"friend":[{"friend.name":"bob"},{"friend.name":"carl"}],"name":"alice"}`,
+		res[0])
+}
+
 func BenchmarkJsonMarshal(b *testing.B) {
 	inputStrings := [][]string{
 		{"largestring", strings.Repeat("a", 1024)},
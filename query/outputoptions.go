@@ -0,0 +1,143 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// OutputOptions change the shape of the JSON results of DQL queries, so that they match what their
+// consumers expect. They are given as a comma separated list, like flatten-lists,sort-keys, in
+// the output parameter of the HTTP requests and in the output metadata of the gRPC requests.
+type OutputOptions struct {
+	// FlattenLists emits the lists holding a single scalar value as that value (flatten-lists).
+	FlattenLists bool
+	// OmitEmpty leaves out the blocks without results instead of emitting them as [], and the
+	// null values (omit-empty).
+	OmitEmpty bool
+	// SortKeys orders the keys of the objects alphabetically instead of in the order of the query
+	// (sort-keys).
+	SortKeys bool
+	// LangMap returns the values of all the languages asked with @* as an object in one field,
+	// keyed by their language tags and with the value without language under "" (lang-map).
+	LangMap bool
+}
+
+// ParseOutputOptions parses the comma separated list of output options.
+func ParseOutputOptions(s string) (OutputOptions, error) {
+	var opts OutputOptions
+	for _, opt := range strings.Split(s, ",") {
+		switch strings.TrimSpace(opt) {
+		case "":
+		case "flatten-lists":
+			opts.FlattenLists = true
+		case "omit-empty":
+			opts.OmitEmpty = true
+		case "sort-keys":
+			opts.SortKeys = true
+		case "lang-map":
+			opts.LangMap = true
+		default:
+			return opts, errors.Errorf("invalid output option %q, it should be one of "+
+				"flatten-lists, omit-empty, sort-keys and lang-map", opt)
+		}
+	}
+	return opts, nil
+}
+
+// outputOptions returns the output options of the request of the context.
+func outputOptions(ctx context.Context) (OutputOptions, error) {
+	// gRPC client passes the output options as metadata.
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if o := md.Get("output"); len(o) > 0 {
+			return ParseOutputOptions(o[0])
+		}
+	}
+	// HTTP passes them as query parameter which is attached to context.
+	opts, _ := ctx.Value(OutputOptionsKey).(OutputOptions)
+	return opts, nil
+}
+
+// applyOutputOptions reshapes the tree of fj, whose children must have been put in order
+// already, according to the output options.
+func (enc *encoder) applyOutputOptions(fj fastJsonNode, opts OutputOptions) error {
+	if enc.children(fj) == nil {
+		return nil
+	}
+	if opts.OmitEmpty {
+		if err := enc.omitEmpty(fj); err != nil {
+			return err
+		}
+	}
+	// The keys of the facets of a scalar list are the indexes of the values.
+	if opts.SortKeys && !enc.getFacetsParent(fj) {
+		// The sort is stable, so the elements of the lists stay next to each other and in order.
+		head := enc.children(fj)
+		enc.MergeSort(&head)
+		fj.child = head
+	}
+	if opts.FlattenLists {
+		if err := enc.flattenLists(fj); err != nil {
+			return err
+		}
+	}
+	for child := enc.children(fj); child != nil; child = child.next {
+		if err := enc.applyOutputOptions(child, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// omitEmpty removes the children of fj which are empty lists or null values.
+func (enc *encoder) omitEmpty(fj fastJsonNode) error {
+	var prev fastJsonNode
+	for child := enc.children(fj); child != nil; child = child.next {
+		empty := false
+		if enc.children(child) == nil && (child.meta&uidNodeBit) == 0 {
+			val, err := enc.getScalarVal(child)
+			if err != nil {
+				return err
+			}
+			empty = (len(val) == 0 && enc.getList(child)) || string(val) == "null"
+		}
+		switch {
+		case !empty:
+			prev = child
+		case prev == nil:
+			fj.child = child.next
+		default:
+			prev.next = child.next
+		}
+	}
+	return nil
+}
+
+// flattenLists turns the lists of fj with a single scalar value into that value.
+func (enc *encoder) flattenLists(fj fastJsonNode) error {
+	for child := enc.children(fj); child != nil; child = child.next {
+		if !enc.getList(child) || enc.children(child) != nil ||
+			(child.next != nil && enc.getAttr(child.next) == enc.getAttr(child)) {
+			// Skip the rest of the list.
+			for child.next != nil && enc.getAttr(child.next) == enc.getAttr(child) {
+				child = child.next
+			}
+			continue
+		}
+		val, err := enc.getScalarVal(child)
+		if err != nil {
+			return err
+		}
+		if len(val) > 0 {
+			enc.setList(child, false)
+		}
+	}
+	return nil
+}
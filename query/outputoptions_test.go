@@ -0,0 +1,70 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+func TestParseOutputOptions(t *testing.T) {
+	opts, err := ParseOutputOptions("")
+	require.NoError(t, err)
+	require.Equal(t, OutputOptions{}, opts)
+
+	opts, err = ParseOutputOptions("flatten-lists, sort-keys")
+	require.NoError(t, err)
+	require.Equal(t, OutputOptions{FlattenLists: true, SortKeys: true}, opts)
+
+	opts, err = ParseOutputOptions("lang-map")
+	require.NoError(t, err)
+	require.Equal(t, OutputOptions{LangMap: true}, opts)
+
+	_, err = ParseOutputOptions("omit-nulls")
+	require.Error(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("output", "omit-empty"))
+	opts, err = outputOptions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, OutputOptions{OmitEmpty: true}, opts)
+}
+
+func TestOutputOptions(t *testing.T) {
+	str := func(s string) types.Val {
+		return types.Val{Tid: types.StringID, Value: s}
+	}
+	encoded := func(opts OutputOptions) string {
+		enc := newEncoder()
+		root := enc.newNode(enc.idForAttr("_root_"))
+		me := enc.newNode(enc.idForAttr("me"))
+		require.NoError(t, enc.AddValue(me, enc.idForAttr("name"), str("alice")))
+		require.NoError(t, enc.AddListValue(me, enc.idForAttr("nick"), str("al"), true))
+		require.NoError(t, enc.AddListValue(me, enc.idForAttr("alias"), str("a"), true))
+		require.NoError(t, enc.AddListValue(me, enc.idForAttr("alias"), str("ali"), true))
+		enc.AddListChild(root, me)
+		enc.AddListChild(root, enc.newNode(enc.idForAttr("empty")))
+		enc.fixOrder(root)
+
+		require.NoError(t, enc.applyOutputOptions(root, opts))
+		require.NoError(t, enc.encode(root))
+		return enc.buf.String()
+	}
+
+	require.Equal(t, `{"me":[{"name":"alice","nick":["al"],"alias":["a","ali"]}],"empty":[]}`,
+		encoded(OutputOptions{}))
+	require.Equal(t, `{"me":[{"name":"alice","nick":"al","alias":["a","ali"]}],"empty":[]}`,
+		encoded(OutputOptions{FlattenLists: true}))
+	require.Equal(t, `{"me":[{"name":"alice","nick":["al"],"alias":["a","ali"]}]}`,
+		encoded(OutputOptions{OmitEmpty: true}))
+	require.Equal(t, `{"empty":[],"me":[{"alias":["a","ali"],"name":"alice","nick":["al"]}]}`,
+		encoded(OutputOptions{SortKeys: true}))
+}
@@ -180,7 +180,7 @@ func getObjectVal(v types.Val) ([]byte, error) {
 	switch v.Tid {
 	case types.UidID:
 		return buildTriple(outputval), nil
-	case types.IntID:
+	case types.IntID, types.Uint64ID:
 		return quotedNumber(outputval), nil
 	case types.FloatID:
 		return quotedNumber(outputval), nil
@@ -0,0 +1,115 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// partialDeadlineRatio is the share of the time left to the deadline of a query asking for partial
+// results after which it stops descending. The rest is left to the levels already started and to
+// the encoding of the results.
+const partialDeadlineRatio = 0.8
+
+// PartialResults tracks the subgraphs skipped by a query which asked for partial results rather
+// than a timeout error. Past its soft deadline, the query doesn't fetch the levels it hasn't
+// started yet, so the results hold what was fetched so far. The deadline is checked whenever the
+// query descends a level, which makes the boundary of the results follow the levels of the query.
+// The root of the blocks is always fetched.
+type PartialResults struct {
+	sync.Mutex
+	deadline time.Time
+	boundary []string
+}
+
+type partialPathKey struct{}
+
+// WithPartialResults attaches a new tracker of partial results to the context, asking the query
+// for partial results.
+func WithPartialResults(ctx context.Context) (context.Context, *PartialResults) {
+	p := &PartialResults{}
+	return context.WithValue(ctx, PartialResultsKey, p), p
+}
+
+// PartialResultsRequested returns true if the gRPC request asks for partial results, through the
+// partial metadata.
+func PartialResultsRequested(ctx context.Context) (bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get("partial")) == 0 {
+		return false, nil
+	}
+	partial, err := strconv.ParseBool(md.Get("partial")[0])
+	if err != nil {
+		return false, errors.Errorf("invalid value %q for the partial metadata",
+			md.Get("partial")[0])
+	}
+	return partial, nil
+}
+
+// Boundary returns the paths of the subgraphs which weren't fetched, like q.friend.name, sorted.
+// The results are partial if it isn't empty.
+func (p *PartialResults) Boundary() []string {
+	p.Lock()
+	defer p.Unlock()
+	boundary := append([]string(nil), p.boundary...)
+	sort.Strings(boundary)
+	return boundary
+}
+
+// start sets the soft deadline of the query from the deadline of the context, if any.
+func (p *PartialResults) start(ctx context.Context) {
+	d, ok := ctx.Deadline()
+	p.Lock()
+	defer p.Unlock()
+	if ok && p.deadline.IsZero() {
+		now := time.Now()
+		p.deadline = now.Add(time.Duration(float64(d.Sub(now)) * partialDeadlineRatio))
+	}
+}
+
+// startPartial starts the soft deadline of the query asking for partial results, if it isn't
+// started already.
+func startPartial(ctx context.Context) {
+	if p, ok := ctx.Value(PartialResultsKey).(*PartialResults); ok {
+		p.start(ctx)
+	}
+}
+
+// partialRoot returns the context of the root of a block, which is always fetched.
+func partialRoot(ctx context.Context, sg *SubGraph) context.Context {
+	if _, ok := ctx.Value(PartialResultsKey).(*PartialResults); !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, partialPathKey{}, sg.fieldName())
+}
+
+// enterPartial returns the context of the child of a subgraph, and true if the child shouldn't be
+// fetched because the soft deadline of the query passed.
+func enterPartial(ctx context.Context, child *SubGraph) (context.Context, bool) {
+	p, _ := ctx.Value(PartialResultsKey).(*PartialResults)
+	if p == nil {
+		return ctx, false
+	}
+	path := child.fieldName()
+	if parent, ok := ctx.Value(partialPathKey{}).(string); ok {
+		path = parent + "." + path
+	}
+	p.Lock()
+	defer p.Unlock()
+	// The uids come from the parent, there's nothing to fetch.
+	if child.Attr != "uid" && !p.deadline.IsZero() && time.Now().After(p.deadline) {
+		p.boundary = append(p.boundary, path)
+		return ctx, true
+	}
+	return context.WithValue(ctx, partialPathKey{}, path), false
+}
@@ -0,0 +1,62 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPartialResultsRequested(t *testing.T) {
+	partial, err := PartialResultsRequested(context.Background())
+	require.NoError(t, err)
+	require.False(t, partial)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("partial", "true"))
+	partial, err = PartialResultsRequested(ctx)
+	require.NoError(t, err)
+	require.True(t, partial)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("partial", "maybe"))
+	_, err = PartialResultsRequested(ctx)
+	require.Error(t, err)
+}
+
+func TestPartialResultsBoundary(t *testing.T) {
+	root := &SubGraph{Params: params{Alias: "q"}}
+	friend := &SubGraph{Attr: "friend"}
+	name := &SubGraph{Attr: "name"}
+	uid := &SubGraph{Attr: "uid"}
+
+	// Without partial results, everything is fetched.
+	_, skip := enterPartial(context.Background(), friend)
+	require.False(t, skip)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	ctx, p := WithPartialResults(ctx)
+	startPartial(ctx)
+	require.WithinDuration(t, time.Now().Add(48*time.Minute), p.deadline, time.Minute)
+
+	ctx = partialRoot(ctx, root)
+	ctx, skip = enterPartial(ctx, friend)
+	require.False(t, skip)
+	require.Empty(t, p.Boundary())
+
+	// Past the deadline, the children aren't fetched, except their uids.
+	p.deadline = time.Now().Add(-time.Second)
+	_, skip = enterPartial(ctx, uid)
+	require.False(t, skip)
+	_, skip = enterPartial(ctx, name)
+	require.True(t, skip)
+	_, skip = enterPartial(ctx, &SubGraph{Attr: "age", Params: params{Alias: "years"}})
+	require.True(t, skip)
+	require.Equal(t, []string{"q.friend.name", "q.friend.years"}, p.Boundary())
+}
@@ -0,0 +1,138 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+// concatSeparator separates the values joined by the concat propagator.
+const concatSeparator = ","
+
+// propagator combines the values of a value variable reaching the same uid, while the variable
+// is propagated to a lower level across the uid edges. Its name is the aggregator given by
+// @propagate: sum (the default), min, max, avg, first or concat.
+type propagator struct {
+	name string
+	vals *types.ShardedMap
+	// counts stores the number of values reaching every uid, for the avg propagator.
+	counts map[uint64]int
+}
+
+func newPropagator(name string) *propagator {
+	if name == "" {
+		name = "sum"
+	}
+	p := &propagator{name: name, vals: types.NewShardedMap()}
+	if name == "avg" {
+		p.counts = make(map[uint64]int)
+	}
+	return p
+}
+
+// accepts returns true if the propagator can combine the value v. The sum and avg propagators
+// only combine numbers, the values of the other ones just have to be comparable or printable.
+func (p *propagator) accepts(v types.Val) bool {
+	switch p.name {
+	case "sum", "avg":
+		return v.Tid == types.IntID || v.Tid == types.FloatID
+	}
+	return true
+}
+
+// add combines the value v reaching uid with the values which reached it already.
+func (p *propagator) add(uid uint64, v types.Val) error {
+	if p.counts != nil {
+		p.counts[uid]++
+	}
+	prev, ok := p.vals.Get(uid)
+	if !ok {
+		if p.name == "concat" {
+			var err error
+			if v, err = toStringVal(v); err != nil {
+				return err
+			}
+		}
+		p.vals.Set(uid, v)
+		return nil
+	}
+	res, err := p.combine(prev, v)
+	if err != nil {
+		return err
+	}
+	if res.Value != nil {
+		p.vals.Set(uid, res)
+	}
+	return nil
+}
+
+// combine returns the combination of the value a, which reached a uid first, with the value b.
+func (p *propagator) combine(a, b types.Val) (types.Val, error) {
+	switch p.name {
+	case "first":
+		return a, nil
+	case "concat":
+		s, err := toStringVal(b)
+		if err != nil {
+			return types.Val{}, err
+		}
+		return types.Val{Tid: types.StringID,
+			Value: a.Value.(string) + concatSeparator + s.Value.(string)}, nil
+	}
+
+	// The avg propagator sums the values, and divides them by their count at the end.
+	ag := aggregator{name: p.name}
+	if p.name == "avg" {
+		ag.name = "sum"
+	}
+	if err := ag.Apply(b); err != nil {
+		return types.Val{}, err
+	}
+	if err := ag.Apply(a); err != nil {
+		return types.Val{}, err
+	}
+	v, err := ag.Value()
+	if err != nil {
+		return types.Val{}, nil
+	}
+	return v, nil
+}
+
+func toStringVal(v types.Val) (types.Val, error) {
+	s := types.ValueForType(types.StringID)
+	err := types.Marshal(v, &s)
+	return s, err
+}
+
+// merge combines the values propagated by other, which come after the ones of p.
+func (p *propagator) merge(other *propagator) {
+	p.vals.Merge(other.vals, func(a, b types.Val) types.Val {
+		v, err := p.combine(a, b)
+		if err != nil || v.Value == nil {
+			return a
+		}
+		return v
+	})
+	for uid, count := range other.counts {
+		p.counts[uid] += count
+	}
+}
+
+// values returns the propagated values.
+func (p *propagator) values() *types.ShardedMap {
+	if p.counts == nil {
+		return p.vals
+	}
+	avg := types.NewShardedMap()
+	_ = p.vals.Iterate(func(uid uint64, v types.Val) error {
+		ag := aggregator{name: "avg", result: v, count: p.counts[uid]}
+		if res, err := ag.Value(); err == nil {
+			avg.Set(uid, res)
+		}
+		return nil
+	})
+	return avg
+}
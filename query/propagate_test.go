@@ -0,0 +1,79 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+func TestTransformToPropagate(t *testing.T) {
+	root := &SubGraph{}
+	friend := &SubGraph{
+		SrcUIDs:   &pb.List{Uids: []uint64{1, 2}},
+		uidMatrix: []*pb.List{{Uids: []uint64{10, 11}}, {Uids: []uint64{11}}},
+	}
+	intVal := func(v int64) types.Val {
+		return types.Val{Tid: types.IntID, Value: v}
+	}
+
+	tests := []struct {
+		propagate string
+		expected  map[uint64]types.Val
+	}{
+		{"", map[uint64]types.Val{10: intVal(5), 11: intVal(12)}},
+		{"sum", map[uint64]types.Val{10: intVal(5), 11: intVal(12)}},
+		{"min", map[uint64]types.Val{10: intVal(5), 11: intVal(5)}},
+		{"max", map[uint64]types.Val{10: intVal(5), 11: intVal(7)}},
+		{"avg", map[uint64]types.Val{
+			10: {Tid: types.FloatID, Value: 5.0},
+			11: {Tid: types.FloatID, Value: 6.0},
+		}},
+		{"first", map[uint64]types.Val{10: intVal(5), 11: intVal(5)}},
+		{"concat", map[uint64]types.Val{
+			10: {Tid: types.StringID, Value: "5"},
+			11: {Tid: types.StringID, Value: "5,7"},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.propagate, func(t *testing.T) {
+			v := varValue{
+				Vals:      types.NewShardedMap(),
+				path:      []*SubGraph{root},
+				propagate: tc.propagate,
+			}
+			v.Vals.Set(1, intVal(5))
+			v.Vals.Set(2, intVal(7))
+
+			res, err := v.transformTo([]*SubGraph{root, friend})
+			require.NoError(t, err)
+			require.Equal(t, len(tc.expected), res.Len())
+			for uid, expected := range tc.expected {
+				val, ok := res.Get(uid)
+				require.True(t, ok)
+				require.Equal(t, expected, val)
+			}
+		})
+	}
+}
+
+func TestPropagatorNonNumeric(t *testing.T) {
+	str := types.Val{Tid: types.StringID, Value: "alice"}
+	require.False(t, newPropagator("").accepts(str))
+	require.False(t, newPropagator("avg").accepts(str))
+
+	p := newPropagator("max")
+	require.True(t, p.accepts(str))
+	require.NoError(t, p.add(1, str))
+	require.NoError(t, p.add(1, types.Val{Tid: types.StringID, Value: "bob"}))
+	val, ok := p.values().Get(1)
+	require.True(t, ok)
+	require.Equal(t, "bob", val.Value)
+}
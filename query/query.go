@@ -114,6 +114,9 @@ type params struct {
 	Order []*pb.Order
 	// Langs is the list of languages and their preferred order for looking up a predicate value.
 	Langs []string
+	// LangFallback is true if Langs is the language fallback chain of the request, rather than
+	// the languages asked for the predicate in the query.
+	LangFallback bool
 
 	// Facet tells us about the requested facets and their aliases.
 	Facet *pb.FacetParams
@@ -124,9 +127,15 @@ type params struct {
 	// Var is the name of the variable defined in this SubGraph
 	// (e.g. in "x as name", this would be x).
 	Var string
+	// Propagate is the aggregator given by @propagate, which combines the values of the value
+	// variable Var reaching the same uid when it's used at a lower level. It's sum if empty.
+	Propagate string
 	// FacetVar is a map of predicate to the facet variable alias
 	// for e.g. @facets(L1 as weight) the map would be { "weight": "L1" }
 	FacetVar map[string]string
+	// FacetAggVars are the value variables of the aggregates of facets over the edges of every
+	// uid, for e.g. @facets(total as sum(weight)).
+	FacetAggVars []dql.FacetAggVar
 	// NeedsVar is the list of variables required by this SubGraph along with their type.
 	NeedsVar []dql.VarContext
 
@@ -141,9 +150,18 @@ type params struct {
 	// variable that is part of req.Vars. This value variable would have been defined
 	// in some other query.
 	UidToVal *types.ShardedMap
+	// WasmVals are the values of the value variables given to the wasm function of a filter,
+	// by variable name.
+	WasmVals map[string]*types.ShardedMap
+	// OrderVals are the values of the value variables of the sort keys, by variable name, when
+	// there are several of them.
+	OrderVals map[string]*types.ShardedMap
 
 	// Normalize is true if the @normalize directive is specified.
 	Normalize bool
+	// NormalizeArgs stores the arguments of the @normalize directive, inherited from the parent
+	// unless the predicate gives its own.
+	NormalizeArgs dql.NormalizeArgs
 	// Recurse is true if the @recurse directive is specified.
 	Recurse bool
 	// RecurseArgs stores the arguments passed to the @recurse directive.
@@ -172,6 +190,10 @@ type params struct {
 	// but may affect solution optimality if set too low.
 	MaxFrontierSize int64
 
+	// Sample is the number of uids sampled from the result of the function at root, by the n
+	// argument of a sample query.
+	Sample int
+
 	// ExploreDepth is used by recurse and shortest path queries to specify the maximum graph
 	// depth to explore.
 	ExploreDepth *uint64
@@ -187,6 +209,10 @@ type params struct {
 	IsGroupBy bool // True if @groupby is specified.
 	// GroupbyAttrs holds the list of attributes to group by.
 	GroupbyAttrs []dql.GroupByAttr
+	// GroupbyNested is true if the groups of each attribute are nested within the previous one.
+	GroupbyNested bool
+	// GroupbyBucket is the bucketing function of the attribute, if it's a groupby attribute.
+	GroupbyBucket *dql.GroupByBucket
 
 	// ParentIds is a stack that is maintained and passed down to children.
 	ParentIds []uint64
@@ -197,6 +223,19 @@ type params struct {
 	ExpandAll bool
 	// Shortest is true when the subgraph holds the results of a shortest paths query.
 	Shortest bool
+	// IsJoin is true if the subgraph is a virtual edge given by join().
+	IsJoin bool
+	// JoinVals holds the values of y for a hash join, join(eq(val(y), val(x))), whose values of x
+	// are in UidToVal.
+	JoinVals *types.ShardedMap
+	// JoinCandidates holds the uids of y for a similarity join,
+	// join(similar_to(predicate, k, val(x), uid(y))), among which the neighbours are searched.
+	JoinCandidates *pb.List
+	// Motif is the pattern of @motif, whose bindings are returned by the block.
+	Motif *dql.Motif
+	// ValidAt is the datetime of @valid_at, inherited by the children. The uid edges are only
+	// traversed if they're valid at that time.
+	ValidAt string
 	// AllowedPreds is a list of predicates accessible to query in context of ACL.
 	AllowedPreds []string
 }
@@ -220,6 +259,10 @@ type Function struct {
 	IsCount    bool      // gt(count(friends),0)
 	IsValueVar bool      // eq(val(s), 10)
 	IsLenVar   bool      // eq(len(s), 10)
+	Wasm       *wasmFunc // wasm.keep(val(s), 10), resolved by resolveWasmFuncs.
+	// SetOp is union, intersect or except for the set operations, which are run as a uid function
+	// taking the combination of the uids of their variables.
+	SetOp string
 }
 
 // SubGraph is the way to represent data. It contains both the request parameters and the response.
@@ -267,6 +310,9 @@ type SubGraph struct {
 	// uidMatrix is a slice of List. There would be one List corresponding to each uid in SrcUIDs.
 	// In graph terms, a list is a slice of outgoing edges from a node.
 	uidMatrix []*pb.List
+	// viewMatrix has the JSON result of a materialized view for every uid in SrcUIDs, if the
+	// SubGraph reads the synthetic predicate of a view.
+	viewMatrix [][]byte
 
 	// facetsMatrix contains the facet values. There would a list corresponding to each uid in
 	// uidMatrix.
@@ -275,6 +321,9 @@ type SubGraph struct {
 	GroupbyRes   []*groupResults // one result for each uid list.
 	LangTags     []*pb.LangList
 
+	// motifBindings are the uids of the variables of @motif, for every binding of the motif.
+	motifBindings [][]uint64
+
 	// SrcUIDs is a list of unique source UIDs. They are always copies of destUIDs
 	// of parent nodes in GraphQL structure.
 	SrcUIDs *pb.List
@@ -330,6 +379,12 @@ func (sg *SubGraph) createSrcFunction(gf *dql.Function) {
 		IsLenVar:   gf.IsLenVar,
 	}
 
+	if dql.IsSetOpFunc(gf.Name) {
+		sg.SrcFunc.Name = "uid"
+		sg.SrcFunc.SetOp = gf.Name
+		return
+	}
+
 	// type function is just an alias for eq(type, "dgraph.type").
 	if gf.Name == "type" {
 		sg.Attr = "dgraph.type"
@@ -568,6 +623,8 @@ func treeCopy(gq *dql.GraphQuery, sg *SubGraph) error {
 			IsInternal:   gchild.IsInternal,
 			Cascade:      &CascadeArgs{},
 		}
+		args.GroupbyNested = gchild.GroupbyNested
+		args.FacetAggVars = gchild.FacetAggVars
 
 		// Inherit from the parent.
 		if len(sg.Params.Cascade.Fields) > 0 {
@@ -577,6 +634,15 @@ func treeCopy(gq *dql.GraphQuery, sg *SubGraph) error {
 		if len(gchild.Cascade) > 0 {
 			args.Cascade.Fields = gchild.Cascade
 		}
+		args.NormalizeArgs = sg.Params.NormalizeArgs
+		args.Propagate = gchild.Propagate
+		args.ValidAt = sg.Params.ValidAt
+		if gchild.ValidAt != "" {
+			args.ValidAt = gchild.ValidAt
+		}
+		if gchild.NormalizeArgs != nil {
+			args.NormalizeArgs = *gchild.NormalizeArgs
+		}
 
 		// Remove pagination arguments from the query if @cascade is mentioned since
 		// pagination will be applied post processing the data.
@@ -590,6 +656,11 @@ func treeCopy(gq *dql.GraphQuery, sg *SubGraph) error {
 			}
 			args.DoCount = true
 		}
+		if isViewAttr(gchild.Attr) && (len(gchild.Children) != 0 || gchild.IsCount ||
+			gchild.Filter != nil) {
+			return errors.Errorf("The view %s can't have child attributes, filters or count",
+				gchild.Attr)
+		}
 
 		for argk := range gchild.Args {
 			if !isValidArg(argk) {
@@ -635,6 +706,10 @@ func treeCopy(gq *dql.GraphQuery, sg *SubGraph) error {
 			}
 			dst.createSrcFunction(gchild.Func)
 		}
+		if gchild.Join != nil {
+			dst.createSrcFunction(gchild.Join)
+			dst.Params.IsJoin = true
+		}
 
 		if gchild.Filter != nil {
 			dstf := &SubGraph{}
@@ -741,6 +816,20 @@ func (args *params) fill(gq *dql.GraphQuery) error {
 		}
 	}
 
+	if v, ok := gq.Args["n"]; ok && args.Alias == "sample" {
+		n, err := strconv.ParseInt(v, 0, 32)
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return errors.Errorf("n of sample must be positive, got %d", n)
+		}
+		if gq.Func == nil || gq.Func.Name == "uid" || dql.IsSetOpFunc(gq.Func.Name) {
+			return errors.Errorf("sample needs a root function other than uid")
+		}
+		args.Sample = int(n)
+	}
+
 	if v, ok := gq.Args["first"]; ok {
 		first, err := strconv.ParseInt(v, 0, 32)
 		if err != nil {
@@ -761,6 +850,9 @@ func ToSubGraph(ctx context.Context, gq *dql.GraphQuery) (*SubGraph, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := sg.resolveWasmFuncs(ctx); err != nil {
+		return nil, err
+	}
 	return sg, err
 }
 
@@ -770,6 +862,16 @@ type ContextKey int
 const (
 	// DebugKey is the key used to toggle debug mode.
 	DebugKey ContextKey = iota
+	// GraphFormatKey is the key used to request the graph output format.
+	GraphFormatKey
+	// QueryLimitsKey is the key used to set the x.QueryLimits of a query.
+	QueryLimitsKey
+	// OutputOptionsKey is the key used to set the OutputOptions of a query.
+	OutputOptionsKey
+	// LangFallbackKey is the key used to set the language fallback chain of a query.
+	LangFallbackKey
+	// PartialResultsKey is the key used to set the PartialResults of a query.
+	PartialResultsKey
 )
 
 func isDebug(ctx context.Context) bool {
@@ -823,8 +925,14 @@ func newGraph(ctx context.Context, gq *dql.GraphQuery) (*SubGraph, error) {
 		Var:              gq.Var,
 		GroupbyAttrs:     gq.GroupbyAttrs,
 		IsGroupBy:        gq.IsGroupby,
+		Motif:            gq.Motif,
+		ValidAt:          gq.ValidAt,
+		GroupbyNested:    gq.GroupbyNested,
 		AllowedPreds:     gq.AllowedPreds,
 	}
+	if gq.NormalizeArgs != nil {
+		args.NormalizeArgs = *gq.NormalizeArgs
+	}
 
 	// Remove pagination arguments from the query if @cascade is mentioned since
 	// pagination will be applied post processing the data.
@@ -937,6 +1045,9 @@ func createTaskQuery(ctx context.Context, sg *SubGraph) (*pb.Query, error) {
 		}
 	}
 
+	if err := sg.applyLangFallback(ctx, namespace); err != nil {
+		return nil, err
+	}
 	// If the lang is set to *, query all the languages.
 	if len(sg.Params.Langs) == 1 && sg.Params.Langs[0] == "*" {
 		sg.Params.ExpandAll = true
@@ -957,6 +1068,11 @@ func createTaskQuery(ctx context.Context, sg *SubGraph) (*pb.Query, error) {
 		ReadTs:    sg.ReadTs,
 	}
 
+	facetsFilter := sg.facetsFilter
+	if sg.Params.ValidAt != "" && sg.SrcFunc == nil && isUidPredicate(namespace, attr) {
+		facetsFilter = withValidAt(facetsFilter, sg.Params.ValidAt)
+	}
+
 	out := &pb.Query{
 		ReadTs:       sg.ReadTs,
 		Cache:        int32(sg.Cache),
@@ -967,11 +1083,12 @@ func createTaskQuery(ctx context.Context, sg *SubGraph) (*pb.Query, error) {
 		AfterUid:     sg.Params.AfterUID,
 		DoCount:      len(sg.Filters) == 0 && sg.Params.DoCount,
 		FacetParam:   sg.Params.Facet,
-		FacetsFilter: sg.facetsFilter,
+		FacetsFilter: facetsFilter,
 		ExpandAll:    sg.Params.ExpandAll,
 		First:        first,
 		Offset:       offset,
 		Order:        sortMsg,
+		Sample:       int32(sg.Params.Sample),
 	}
 
 	if sg.SrcUIDs != nil {
@@ -1002,19 +1119,23 @@ func calculatePaginationParams(sg *SubGraph) (int32, int32) {
 	// - should not be one of those function which fetches some results and then do further
 	// processing to narrow down the result. For example: allofterm will fetch the index postings
 	// for each term and then do an intersection.
+	// - No sample (The sample is taken from all the results of the function)
+	// - No @motif (The bindings of the motif are paginated, not the results of the function)
 	// TODO: Look into how we can optimize queries involving these functions.
 
 	shouldExclude := false
 	if sg.SrcFunc != nil {
 		switch sg.SrcFunc.Name {
-		case "regexp", "alloftext", "allofterms", "match", "ngram":
+		case "regexp", "alloftext", "allofterms", "match", "fuzzy", "levenshtein", "ngram",
+			"plugin":
 			shouldExclude = true
 		default:
 			shouldExclude = false
 		}
 	}
 
-	if len(sg.Filters) == 0 && len(sg.Params.Order) == 0 && !shouldExclude {
+	if len(sg.Filters) == 0 && len(sg.Params.Order) == 0 && !shouldExclude &&
+		sg.Params.Sample == 0 && sg.Params.Motif == nil {
 		if sg.Params.Count != 0 {
 			return int32(sg.Params.Count), int32(sg.Params.Offset)
 		}
@@ -1030,6 +1151,8 @@ type varValue struct {
 	Uids *pb.List // list of uids if this denotes a uid variable.
 	Vals *types.ShardedMap
 	path []*SubGraph // This stores the subgraph path from root to var definition.
+	// propagate is the aggregator used to propagate the values to a lower level. Sum if empty.
+	propagate string
 	// strList stores the valueMatrix corresponding to a predicate and is later used in
 	// expand(val(x)) query.
 	strList []*pb.ValueList
@@ -1050,16 +1173,21 @@ func evalLevelAgg(
 		// corresponding to uid 0 to avoid defining another field in SubGraph.
 		vals := doneVars[needsVar].Vals
 
-		ag := aggregator{
-			name: sg.SrcFunc.Name,
+		ag, err := newAggregator(sg.SrcFunc)
+		if err != nil {
+			return nil, err
+		}
+		if ag.sketch != nil {
+			err = ag.sketch.applyShards(vals)
+		} else {
+			err = vals.Iterate(func(k uint64, val types.Val) error {
+				err := ag.Apply(val)
+				if err != nil {
+					return err
+				}
+				return nil
+			})
 		}
-		err := vals.Iterate(func(k uint64, val types.Val) error {
-			err := ag.Apply(val)
-			if err != nil {
-				return err
-			}
-			return nil
-		})
 		if err != nil {
 			return nil, err
 		}
@@ -1099,8 +1227,9 @@ func evalLevelAgg(
 	mp = types.NewShardedMap()
 	// Go over the sibling node and aggregate.
 	for i, list := range relSG.uidMatrix {
-		ag := aggregator{
-			name: sg.SrcFunc.Name,
+		ag, err := newAggregator(sg.SrcFunc)
+		if err != nil {
+			return nil, err
 		}
 		for _, uid := range list.Uids {
 			if val, ok := vals.Get(uid); ok {
@@ -1159,11 +1288,11 @@ func (fromNode *varValue) transformTo(toPath []*SubGraph) (*types.ShardedMap, er
 			continue
 		}
 
-		var mutex sync.Mutex
-		var resultMap *types.ShardedMap
-
-		calculate := func(start, end int) {
-			tempMap := types.NewShardedMap()
+		// The values propagated by every thread are merged in order, so that the first and concat
+		// aggregators don't depend on the scheduling.
+		results := make([]*propagator, numThreadsVariablePropogation)
+		calculate := func(t, start, end int) {
+			p := newPropagator(fromNode.propagate)
 
 			for i := start; i < end; i++ {
 				ul := curNode.uidMatrix[i]
@@ -1172,42 +1301,16 @@ func (fromNode *varValue) transformTo(toPath []*SubGraph) (*types.ShardedMap, er
 				if !ok || curVal.Value == nil {
 					continue
 				}
-				if curVal.Tid != types.IntID && curVal.Tid != types.FloatID {
+				if !p.accepts(curVal) {
 					return
 				}
-				ag := aggregator{name: "sum"}
-				for j := range ul.Uids {
-					ag.result = types.Val{}
-					dstUid := ul.Uids[j]
-					if err := ag.Apply(curVal); err != nil {
+				for _, dstUid := range ul.Uids {
+					if err := p.add(dstUid, curVal); err != nil {
 						return
 					}
-					if tempVal, ok := tempMap.Get(dstUid); ok {
-						if err := ag.Apply(tempVal); err != nil {
-							return
-						}
-					}
-					val, err := ag.Value()
-					if err != nil {
-						continue
-					}
-					tempMap.Set(dstUid, val)
 				}
 			}
-
-			mutex.Lock()
-			defer mutex.Unlock()
-			if resultMap == nil {
-				resultMap = tempMap
-			} else {
-				resultMap.Merge(tempMap, func(a, b types.Val) types.Val {
-					ag := &aggregator{name: "sum"}
-					ag.Apply(a)
-					ag.Apply(b)
-					v, _ := ag.Value()
-					return v
-				})
-			}
+			results[t] = p
 		}
 
 		width := len(curNode.uidMatrix) / numThreadsVariablePropogation
@@ -1220,12 +1323,26 @@ func (fromNode *varValue) transformTo(toPath []*SubGraph) (*types.ShardedMap, er
 			}
 			wg.Add(1)
 			go func() {
-				calculate(start, end)
+				calculate(i, start, end)
 				wg.Done()
 			}()
 		}
 		wg.Wait()
-		newMap = resultMap
+
+		var resultProp *propagator
+		for _, p := range results {
+			switch {
+			case p == nil:
+			case resultProp == nil:
+				resultProp = p
+			default:
+				resultProp.merge(p)
+			}
+		}
+		newMap = nil
+		if resultProp != nil {
+			newMap = resultProp.values()
+		}
 	}
 
 	return newMap, nil
@@ -1280,6 +1397,7 @@ func (sg *SubGraph) valueVarAggregation(doneVars map[string]varValue, path []*Su
 		if sg.Params.Var != "" {
 			it := doneVars[sg.Params.Var]
 			it.Vals = mp
+			it.propagate = sg.Params.Propagate
 			doneVars[sg.Params.Var] = it
 		}
 		sg.Params.UidToVal = mp
@@ -1328,6 +1446,7 @@ func (sg *SubGraph) valueVarAggregation(doneVars map[string]varValue, path []*Su
 			it.Vals = sg.MathExp.Val
 			// The path of math node is the path of max var node used in it.
 			it.path = path
+			it.propagate = sg.Params.Propagate
 			doneVars[sg.Params.Var] = it
 			sg.Params.UidToVal = sg.MathExp.Val
 		case sg.MathExp.Const.Value != nil:
@@ -1531,7 +1650,8 @@ AssignStep:
 func (sg *SubGraph) updateVars(doneVars map[string]varValue, sgPath []*SubGraph) error {
 	// NOTE: although we initialize doneVars (req.Vars) in ProcessQuery, this nil check is for
 	// non-root lookups that happen to other nodes. Don't use len(doneVars) == 0 !
-	if doneVars == nil || (sg.Params.Var == "" && sg.Params.FacetVar == nil) {
+	if doneVars == nil || (sg.Params.Var == "" && sg.Params.FacetVar == nil &&
+		len(sg.Params.FacetAggVars) == 0) {
 		return nil
 	}
 
@@ -1539,7 +1659,10 @@ func (sg *SubGraph) updateVars(doneVars map[string]varValue, sgPath []*SubGraph)
 	if err := sg.populateUidValVar(doneVars, sgPathCopy); err != nil {
 		return err
 	}
-	return sg.populateFacetVars(doneVars, sgPathCopy)
+	if err := sg.populateFacetVars(doneVars, sgPathCopy); err != nil {
+		return err
+	}
+	return sg.populateFacetAggVars(doneVars, sgPathCopy)
 }
 
 // populateUidValVar populates the value of the variable into doneVars.
@@ -1558,9 +1681,10 @@ func (sg *SubGraph) populateUidValVar(doneVars map[string]varValue, sgPath []*Su
 
 		// This implies it is a value variable.
 		doneVars[sg.Params.Var] = varValue{
-			Vals:    types.NewShardedMap(),
-			path:    sgPath,
-			strList: sg.valueMatrix,
+			Vals:      types.NewShardedMap(),
+			path:      sgPath,
+			strList:   sg.valueMatrix,
+			propagate: sg.Params.Propagate,
 		}
 		for idx, uid := range sg.SrcUIDs.Uids {
 			val := types.Val{
@@ -1629,6 +1753,7 @@ func (sg *SubGraph) populateUidValVar(doneVars map[string]varValue, sgPath []*Su
 			v.Vals = types.NewShardedMap()
 			v.path = sgPath
 			v.strList = sg.valueMatrix
+			v.propagate = sg.Params.Propagate
 		}
 
 		for idx, uid := range sg.SrcUIDs.Uids {
@@ -1736,6 +1861,73 @@ func (sg *SubGraph) populateFacetVars(doneVars map[string]varValue, sgPath []*Su
 	return nil
 }
 
+// populateFacetAggVars aggregates the facets over the edges of every uid in SrcUIDs, for the facet
+// variables like total as sum(weight). Unlike a facet variable, which is a value of the uids at the
+// other end of the edges, it's a value of the uids the edges start from.
+func (sg *SubGraph) populateFacetAggVars(doneVars map[string]varValue, sgPath []*SubGraph) error {
+	for _, fa := range sg.Params.FacetAggVars {
+		vals := types.NewShardedMap()
+		doneVars[fa.Var] = varValue{Vals: vals, path: sgPath}
+
+		// Note: Like for the facet variables, we ignore the facets of a value edge.
+		for i, uids := range sg.uidMatrix {
+			if i >= len(sg.facetsMatrix) || i >= len(sg.SrcUIDs.GetUids()) {
+				break
+			}
+			ag := aggregator{name: fa.Func}
+			var count int64
+			for j := range uids.Uids {
+				for _, f := range sg.facetsMatrix[i].FacetsList[j].GetFacets() {
+					if f.Key != fa.Key {
+						continue
+					}
+					count++
+					if fa.Func == "count" {
+						continue
+					}
+					fVal, err := facets.ValFor(f)
+					if err != nil {
+						return err
+					}
+					numeric := fVal.Tid == types.IntID || fVal.Tid == types.FloatID
+					if !numeric && (fa.Func == "sum" || fa.Func == "avg") {
+						return errors.Errorf("%s of facet %s needs int or float values, got %s",
+							fa.Func, fa.Key, fVal.Tid.Name())
+					}
+					// The int and float values of the facet are aggregated as floats.
+					if numeric && ag.result.Value != nil && ag.result.Tid != fVal.Tid {
+						if ag.result.Tid == types.IntID {
+							ag.result = types.Val{Tid: types.FloatID,
+								Value: float64(ag.result.Value.(int64))}
+						}
+						if fVal.Tid == types.IntID {
+							fVal = types.Val{Tid: types.FloatID, Value: float64(fVal.Value.(int64))}
+						}
+					}
+					if err := ag.Apply(fVal); err != nil {
+						return err
+					}
+				}
+			}
+
+			srcUid := sg.SrcUIDs.Uids[i]
+			if fa.Func == "count" {
+				vals.Set(srcUid, types.Val{Tid: types.IntID, Value: count})
+				continue
+			}
+			fVal, err := ag.Value()
+			if err == ErrEmptyVal {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			vals.Set(srcUid, fVal)
+		}
+	}
+	return nil
+}
+
 // recursiveFillVars fills the value of variables before a query is to be processed using the result
 // of the values (doneVars) computed by other queries that were successfully run before this query.
 func (sg *SubGraph) recursiveFillVars(doneVars map[string]varValue) error {
@@ -1807,6 +1999,9 @@ func (sg *SubGraph) fillVars(mp map[string]varValue) error {
 	}
 
 	var lists []*pb.List
+	if sg.SrcFunc != nil && sg.SrcFunc.SetOp != "" {
+		lists = append(lists, sg.applySetOp(mp))
+	}
 	// Go through all the variables in NeedsVar and see if we have a value for them in the map. If
 	// we do, then we store that value in the appropriate variable inside SubGraph.
 	for _, v := range sg.Params.NeedsVar {
@@ -1814,7 +2009,21 @@ func (sg *SubGraph) fillVars(mp map[string]varValue) error {
 		if !ok {
 			continue
 		}
+		if v.Typ == dql.ValueVar && len(sg.Params.Order) > 1 {
+			for _, o := range sg.Params.Order {
+				if o.Attr != v.Name {
+					continue
+				}
+				if sg.Params.OrderVals == nil {
+					sg.Params.OrderVals = make(map[string]*types.ShardedMap)
+				}
+				sg.Params.OrderVals[v.Name] = l.Vals
+			}
+		}
 		switch {
+		case v.Typ == dql.UidVar && sg.SrcFunc != nil && sg.SrcFunc.SetOp != "":
+			// The variables of the set operations are combined by applySetOp.
+
 		case (v.Typ == dql.AnyVar || v.Typ == dql.ListVar) && l.strList != nil:
 			// This is for the case when we use expand(val(x)) with a value variable.
 			// We populate the list of values into ExpandPreds and use that for the expand query
@@ -1822,7 +2031,8 @@ func (sg *SubGraph) fillVars(mp map[string]varValue) error {
 			// TODO: If we support value vars for list type then this needn't be true
 			sg.ExpandPreds = l.strList
 
-		case (v.Typ == dql.UidVar && sg.SrcFunc != nil && sg.SrcFunc.Name == "uid_in"):
+		case v.Typ == dql.UidVar && sg.SrcFunc != nil &&
+			(sg.SrcFunc.Name == "uid_in" || sg.SrcFunc.Name == "not_exists"):
 			srcFuncArgs := sg.SrcFunc.Args[:0]
 
 			for _, uid := range l.Uids.GetUids() {
@@ -1832,9 +2042,29 @@ func (sg *SubGraph) fillVars(mp map[string]varValue) error {
 			}
 			sg.SrcFunc.Args = srcFuncArgs
 
+		case v.Typ == dql.UidVar && sg.Params.IsJoin:
+			// The candidates of a similarity join.
+			sg.Params.JoinCandidates = l.Uids
+
 		case (v.Typ == dql.AnyVar || v.Typ == dql.UidVar) && l.Uids != nil:
 			lists = append(lists, l.Uids)
 
+		case v.Typ == dql.ValueVar && sg.Params.IsJoin && sg.SrcFunc.IsValueVar:
+			// The hash joins take the values of both variables, x and y can be the same.
+			if v.Name == sg.Attr {
+				sg.Params.JoinVals = l.Vals
+			}
+			if v.Name == sg.SrcFunc.Args[0].Value {
+				sg.Params.UidToVal = l.Vals
+			}
+
+		case v.Typ == dql.ValueVar && sg.SrcFunc != nil && sg.SrcFunc.Wasm != nil:
+			// The wasm functions take several value variables.
+			if sg.Params.WasmVals == nil {
+				sg.Params.WasmVals = make(map[string]*types.ShardedMap)
+			}
+			sg.Params.WasmVals[v.Name] = l.Vals
+
 		case (v.Typ == dql.AnyVar || v.Typ == dql.ValueVar):
 			// This should happen only once.
 			// TODO: This allows only one value var per subgraph, change it later
@@ -1876,7 +2106,10 @@ func (sg *SubGraph) fillVars(mp map[string]varValue) error {
 // E.g. - func: eq(score, val(myscore))
 // NOTE - We disallow vars in facets filter so we don't need to worry about that as of now.
 func (sg *SubGraph) replaceVarInFunc() error {
-	if sg.SrcFunc == nil {
+	if sg.SrcFunc == nil || sg.SrcFunc.Wasm != nil || (sg.Params.IsJoin &&
+		(sg.SrcFunc.IsValueVar || sg.SrcFunc.Name == "similar_to")) {
+		// The wasm functions, the hash joins and the similarity joins read the values of the
+		// variables by uid.
 		return nil
 	}
 	var args []dql.Arg
@@ -2055,29 +2288,21 @@ func expandSubgraph(ctx context.Context, sg *SubGraph) ([]*SubGraph, error) {
 			return out, err
 		}
 
-		switch child.Params.Expand {
-		// It could be expand(_all_) or expand(val(x)).
-		case "_all_":
+		switch {
+		// It could be expand(_all_), expand(/regex/), expand(val(x)) or expand(Type).
+		case child.Params.Expand == "_all_":
 			span.AddEvent("expand(_all_)")
 			if len(typeNames) == 0 {
 				break
 			}
 
 			preds = getPredicatesFromTypes(namespace, typeNames)
-			// restrict preds to allowed preds if ACL is turned on.
-			if sg.Params.AllowedPreds != nil {
-				// Take intersection of both the predicate lists
-				intersectPreds := make([]string, 0)
-				hashMap := make(map[string]bool)
-				for _, allowedPred := range sg.Params.AllowedPreds {
-					hashMap[allowedPred] = true
-				}
-				for _, pred := range preds {
-					if _, found := hashMap[pred]; found {
-						intersectPreds = append(intersectPreds, pred)
-					}
-				}
-				preds = intersectPreds
+
+		case strings.HasPrefix(child.Params.Expand, "/"):
+			// It's expand(/regex/), expanding the predicates of the schema matching the regex.
+			span.AddEvent("expand regex")
+			if preds, err = predicatesMatching(ctx, namespace, child.Params.Expand); err != nil {
+				return out, err
 			}
 
 		default:
@@ -2093,7 +2318,12 @@ func expandSubgraph(ctx context.Context, sg *SubGraph) ([]*SubGraph, error) {
 				preds = getPredicatesFromTypes(namespace, typeNames)
 			}
 		}
-		preds = uniquePreds(preds)
+		// restrict preds to allowed preds if ACL is turned on, whichever way they are expanded.
+		preds = uniquePreds(allowedPredicates(sg, preds))
+		if err := checkLimit("query-expand", queryLimits(ctx).Expand,
+			uint64(len(preds))); err != nil {
+			return out, err
+		}
 
 		// There's a types filter at this level so filter out any non-uid predicates
 		// since only uid nodes can have a type.
@@ -2169,6 +2399,11 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 		rch <- nil
 		return
 	}
+	if isViewAttr(sg.Attr) {
+		// The results of the views are computed already, they're just looked up.
+		rch <- sg.processView(ctx)
+		return
+	}
 	var err error
 	switch {
 	case parent == nil && sg.SrcFunc != nil && sg.SrcFunc.Name == "uid":
@@ -2218,9 +2453,29 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 		// Each filter use it's own (shallow) copy of SrcUIDs, so there is no race conditions,
 		// when multiple filters replace their sg.DestUIDs
 		sg.DestUIDs = &pb.List{Uids: sg.SrcUIDs.Uids}
+	case sg.Params.IsJoin && sg.SrcFunc.Name == "similar_to":
+		if err = sg.processSimilarityJoin(ctx); err != nil {
+			rch <- err
+			return
+		}
+	case sg.Params.IsJoin && sg.SrcFunc.IsValueVar:
+		if err = sg.processHashJoin(); err != nil {
+			rch <- err
+			return
+		}
+	case sg.Params.IsJoin:
+		if err = sg.processJoin(ctx); err != nil {
+			rch <- err
+			return
+		}
 	default:
 		isInequalityFn := sg.SrcFunc != nil && isInequalityFn(sg.SrcFunc.Name)
 		switch {
+		case sg.SrcFunc != nil && sg.SrcFunc.Wasm != nil:
+			if err = sg.applyWasmFunc(); err != nil || parent != nil {
+				rch <- err
+				return
+			}
 		case isInequalityFn && sg.SrcFunc.IsValueVar:
 			// This is a ineq function which uses a value variable.
 			err = sg.applyIneqFunc()
@@ -2368,7 +2623,10 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 		}
 	}
 
-	if len(sg.Params.Order) == 0 && len(sg.Params.FacetsOrder) == 0 {
+	switch {
+	case sg.Params.Motif != nil:
+		// The bindings of the motif are paginated instead of the uids of its first variable.
+	case len(sg.Params.Order) == 0 && len(sg.Params.FacetsOrder) == 0:
 		// for `has` function when there is no filtering and ordering, we fetch
 		// correct paginated results so no need to apply pagination here.
 		if !(len(sg.Filters) == 0 && sg.SrcFunc != nil && sg.SrcFunc.Name == "has") {
@@ -2378,7 +2636,7 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 				return
 			}
 		}
-	} else {
+	default:
 		// If we are asked for count, we don't need to change the order of results.
 		if !sg.Params.DoCount {
 			// We need to sort first before pagination.
@@ -2389,6 +2647,21 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 		}
 	}
 
+	if parent == nil {
+		if err = checkLimit("query-root-uids", queryLimits(ctx).RootUids,
+			uint64(len(sg.DestUIDs.GetUids()))); err != nil {
+			rch <- err
+			return
+		}
+	}
+
+	if parent == nil && sg.Params.Motif != nil {
+		if err = sg.processMotif(ctx); err != nil {
+			rch <- err
+			return
+		}
+	}
+
 	// Here we consider handling count with filtering. We do this after
 	// pagination because otherwise, we need to do the count with pagination
 	// taken into account. For example, a PL might have only 50 entries but the
@@ -2421,9 +2694,10 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 				Attr:   it.Attr,
 				ReadTs: sg.ReadTs,
 				Params: params{
-					Alias:        it.Alias,
-					IgnoreResult: true,
-					Langs:        it.Langs,
+					Alias:         it.Alias,
+					IgnoreResult:  true,
+					Langs:         it.Langs,
+					GroupbyBucket: it.Bucket,
 				},
 			})
 		}
@@ -2452,7 +2726,12 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 			// We dont have to execute these nodes.
 			continue
 		}
-		go ProcessGraph(ctx, child, sg, childChan)
+		cctx, skip := enterPartial(ctx, child)
+		if skip {
+			childChan <- nil
+			continue
+		}
+		go ProcessGraph(cctx, child, sg, childChan)
 	}
 
 	var childErr error
@@ -2698,23 +2977,45 @@ func (sg *SubGraph) sortAndPaginateUsingVar(ctx context.Context) error {
 		return errors.Errorf("Variable: [%s] used before definition.", sg.Params.Order[0].Attr)
 	}
 
+	// With several sort keys, the UIDs are skipped only if they have no value for any of them,
+	// and the missing values are sorted last.
+	orderVals := []*types.ShardedMap{sg.Params.UidToVal}
+	desc := []bool{sg.Params.Order[0].Desc}
+	if len(sg.Params.Order) > 1 {
+		orderVals, desc = orderVals[:0], desc[:0]
+		for _, o := range sg.Params.Order {
+			vals, ok := sg.Params.OrderVals[o.Attr]
+			if !ok {
+				return errors.Errorf("Variable: [%s] used before definition.", o.Attr)
+			}
+			orderVals = append(orderVals, vals)
+			desc = append(desc, o.Desc)
+		}
+	}
+
 	for i := range sg.uidMatrix {
 		ul := sg.uidMatrix[i]
 		uids := make([]uint64, 0, len(ul.Uids))
 		values := make([][]types.Val, 0, len(ul.Uids))
 		for _, uid := range ul.Uids {
-			v, ok := sg.Params.UidToVal.Get(uid)
-			if !ok {
+			row := make([]types.Val, len(orderVals))
+			found := false
+			for j, vals := range orderVals {
+				if v, ok := vals.Get(uid); ok {
+					row[j], found = v, true
+				}
+			}
+			if !found {
 				// We skip the UIDs which don't have a value
 				continue
 			}
-			values = append(values, []types.Val{v})
+			values = append(values, row)
 			uids = append(uids, uid)
 		}
 		if len(values) == 0 {
 			continue
 		}
-		if err := types.Sort(values, &uids, []bool{sg.Params.Order[0].Desc}, ""); err != nil {
+		if err := types.Sort(values, &uids, desc, ""); err != nil {
 			return err
 		}
 		sg.uidMatrix[i].Uids = uids
@@ -2737,7 +3038,7 @@ func (sg *SubGraph) sortAndPaginateUsingVar(ctx context.Context) error {
 func isValidArg(a string) bool {
 	switch a {
 	case "numpaths", "from", "to", "orderasc", "orderdesc", "first", "offset", "after", "depth",
-		"minweight", "maxweight", "maxfrontiersize":
+		"minweight", "maxweight", "maxfrontiersize", "n":
 		return true
 	}
 	return false
@@ -2747,10 +3048,11 @@ func isValidArg(a string) bool {
 func isValidFuncName(f string) bool {
 	switch f {
 	case "anyofterms", "allofterms", "val", "regexp", "anyoftext", "alloftext", "ngram",
-		"has", "uid", "uid_in", "anyof", "allof", "type", "match", "similar_to":
+		"has", "uid", "uid_in", "not_exists", "anyof", "allof", "type", "match", "fuzzy",
+		"levenshtein", "similar_to", "plugin":
 		return true
 	}
-	return isInequalityFn(f) || types.IsGeoFunc(f)
+	return isInequalityFn(f) || types.IsGeoFunc(f) || dql.IsWasmFunc(f) || dql.IsSetOpFunc(f)
 }
 
 func isInequalityFn(f string) bool {
@@ -2763,7 +3065,7 @@ func isInequalityFn(f string) bool {
 
 func isAggregatorFn(f string) bool {
 	switch f {
-	case "min", "max", "sum", "avg":
+	case "min", "max", "sum", "avg", "percentile", "distinct":
 		return true
 	}
 	return false
@@ -2807,6 +3109,50 @@ func getPredicatesFromTypes(namespace uint64, typeNames []string) []string {
 	return preds
 }
 
+// allowedPredicates restricts preds to the predicates allowed by the ACLs, if they are turned on.
+func allowedPredicates(sg *SubGraph, preds []string) []string {
+	if sg.Params.AllowedPreds == nil {
+		return preds
+	}
+	// Take intersection of both the predicate lists
+	intersectPreds := make([]string, 0)
+	hashMap := make(map[string]bool)
+	for _, allowedPred := range sg.Params.AllowedPreds {
+		hashMap[allowedPred] = true
+	}
+	for _, pred := range preds {
+		if _, found := hashMap[pred]; found {
+			intersectPreds = append(intersectPreds, pred)
+		}
+	}
+	return intersectPreds
+}
+
+// predicatesMatching returns the predicates of the namespace whose names match the regex of
+// expand(/regex/). The reserved predicates aren't matched.
+func predicatesMatching(ctx context.Context, namespace uint64, expand string) ([]string, error) {
+	re, err := dql.ExpandRegexp(expand)
+	if err != nil {
+		return nil, err
+	}
+	schs, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{Fields: []string{"type"}})
+	if err != nil {
+		return nil, err
+	}
+	var preds []string
+	for _, sch := range schs {
+		pred := sch.GetPredicate()
+		if x.ParseNamespace(pred) != namespace || x.IsReservedPredicate(pred) {
+			continue
+		}
+		if re.MatchString(x.ParseAttr(pred)) {
+			preds = append(preds, pred)
+		}
+	}
+	sort.Strings(preds)
+	return preds, nil
+}
+
 // filterUidPredicates takes a list of predicates and returns a list of the predicates
 // that are of type uid or [uid].
 func filterUidPredicates(ctx context.Context, preds []string) ([]string, error) {
@@ -2862,6 +3208,8 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 
 	// Vars stores the processed variables.
 	req.Vars = make(map[string]varValue)
+	limits := queryLimits(ctx)
+	startPartial(ctx)
 	loopStart := time.Now()
 	queries := req.DqlQuery.Query
 	// first loop converts queries to SubGraph representation and populates ReadTs And Cache.
@@ -2873,6 +3221,18 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 			return errors.Errorf("Invalid query. No function used at root and no aggregation" +
 				" or math variables found in the body.")
 		}
+		if err := checkLimit("query-depth", limits.Depth, blockDepth(gq)); err != nil {
+			return err
+		}
+		if gq.Recurse {
+			if limits.Depth > 0 && gq.RecurseArgs.Depth == 0 {
+				return errors.Errorf("@recurse must be given a depth of at most %d, the "+
+					"query-depth limit", limits.Depth)
+			}
+			if err := checkLimit("query-depth", limits.Depth, gq.RecurseArgs.Depth); err != nil {
+				return err
+			}
+		}
 		sg, err := ToSubGraph(ctx, gq)
 		if err != nil {
 			return errors.Wrapf(err, "while converting to subgraph")
@@ -2887,6 +3247,10 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 	req.Latency.Parsing += time.Since(loopStart)
 
 	execStart := time.Now()
+	// The results of the executed blocks are spilled to disk once their variables are populated,
+	// as they aren't needed until the results are encoded.
+	spill := newQuerySpill(x.Config.QuerySpillUids, x.WorkerConfig.TmpDir)
+	defer spill.close()
 	hasExecuted := make([]bool, len(req.Subgraphs))
 	numQueriesDone := 0
 
@@ -2967,7 +3331,7 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 					errChan <- recurse(ctx, sg)
 				}()
 			default:
-				go ProcessGraph(ctx, sg, nil, errChan)
+				go ProcessGraph(partialRoot(ctx, sg), sg, nil, errChan)
 			}
 		}
 
@@ -3012,6 +3376,13 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 				return err
 			}
 			spanp.End()
+
+			if err := spill.add(ctx, sg); err != nil {
+				return err
+			}
+		}
+		if err := checkVarsLimit(req.Vars, limits.VarUids); err != nil {
+			return err
 		}
 	}
 
@@ -3021,6 +3392,9 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 			return errors.Errorf("Query couldn't be executed")
 		}
 	}
+	if err := spill.restore(); err != nil {
+		return err
+	}
 	req.Latency.Processing += time.Since(execStart)
 
 	// If we had a shortestPath SG, append it to the result.
@@ -953,6 +953,25 @@ func TestQueryVarValAggSince(t *testing.T) {
 		js)
 }
 
+func TestQueryVarValOrderByMultipleVars(t *testing.T) {
+	query := `
+		{
+			var(func: uid(1, 23, 24, 25, 31)) {
+				a as age
+				n as name
+			}
+
+			AgeOrder(func: uid(a), orderasc: val(a), orderdesc: val(n)) {
+				name
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"AgeOrder":[{"name":"Rick Grimes"},{"name":"Glenn Rhee"},{"name":"Daryl Dixon"},{"name":"Andrea"},{"name":"Michonne"}]}}`,
+		js)
+}
+
 func TestQueryVarValAggNestedFuncConst(t *testing.T) {
 	query := `
 		{
@@ -1776,6 +1795,20 @@ func TestGroupByMultiParents_2(t *testing.T) {
 
 }
 
+func TestGroupByNestedBuckets(t *testing.T) {
+	query := `
+		{
+			me(func: uid(1)) {
+				friend @groupby(nested: true, bucket(age, 3), name) {
+					count(uid)
+				}
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data":{"me":[{"friend":[{"@groupby":[{"age":18,"count":1,"@groupby":[{"name":"Andrea","count":1}]},{"age":15,"count":3,"@groupby":[{"name":"Daryl Dixon","count":1},{"name":"Glenn Rhee","count":1},{"name":"Rick Grimes","count":1}]}]}]}]}}`, js)
+}
+
 func TestGroupByAgeMultiParents(t *testing.T) {
 	// We dont have any data for uid 99999, 99998.
 	query := `
@@ -1502,6 +1502,24 @@ func TestAggregateRoot6(t *testing.T) {
 	require.JSONEq(t, `{"data": {"me":[]}}`, js)
 }
 
+func TestAggregateRootApprox(t *testing.T) {
+	query := `
+		{
+			var(func: anyofterms(name, "Rick Michonne Andrea")) {
+				a as age
+			}
+
+			me() {
+				percentile(val(a), 0.5)
+				count(distinct val(a))
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"percentile(val(a), 0.5)":19},`+
+		`{"count(distinct val(a))":3}]}}`, js)
+}
+
 func TestAggregateRootError(t *testing.T) {
 
 	query := `
@@ -2622,6 +2640,21 @@ func TestMatchFuncWithAfterWithLastUid(t *testing.T) {
 	require.JSONEq(t, `{"data": {"q":[] } }`, js)
 }
 
+func TestFuzzyAndLevenshteinFuncs(t *testing.T) {
+	// fuzzy is match, and levenshtein is match without the index in a filter.
+	for _, fn := range []string{"fuzzy", "levenshtein"} {
+		query := `{ q(func: has(name)) @filter(%s(name, "Alica", 1)) { uid name } }`
+		js := processQueryNoErr(t, fmt.Sprintf(query, fn))
+		require.JSONEq(t, processQueryNoErr(t, fmt.Sprintf(query, "match")), js)
+	}
+	js := processQueryNoErr(t, `{ q(func: fuzzy(name, "Alica", 1)) { name } }`)
+	require.JSONEq(t, processQueryNoErr(t, `{ q(func: match(name, "Alica", 1)) { name } }`), js)
+
+	_, err := processQuery(context.Background(), t, `{ q(func: levenshtein(name, "Alica", 1)) { name } }`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "levenshtein can only be used in a filter")
+}
+
 func TestCompareFuncWithAfter(t *testing.T) {
 	query := `
 		{
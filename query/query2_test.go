@@ -1579,6 +1579,22 @@ func TestGeneratorRootFilterOnCountWithAnd(t *testing.T) {
 	require.JSONEq(t, `{"data": {"me":[{"friend":[{"name":"Michonne"}],"name":"Rick Grimes"}]}}`, js)
 }
 
+func TestGeneratorRootFilterOnCountBetweenChildLevel(t *testing.T) {
+
+	query := `
+                {
+                        me(func: uid(23)) {
+                                name
+                                friend @filter(between(count(friend), 5, 99)) {
+                                        name
+                                }
+                        }
+                }
+        `
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"friend":[{"name":"Michonne"}],"name":"Rick Grimes"}]}}`, js)
+}
+
 func TestGeneratorRootFilterOnCountError1(t *testing.T) {
 
 	// only cmp(count(attr), int) is valid, 'max'/'min'/'sum' not supported
@@ -1897,6 +1913,94 @@ func TestNormalizeDirectiveWithRecurseDirective(t *testing.T) {
       }`, js)
 }
 
+func TestNormalizeDirectiveDottedKeys(t *testing.T) {
+	query := `
+		{
+			me(func: uid(0x01)) @normalize(dotted: true) {
+				name
+				friend {
+					name
+				}
+			}
+		}`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"me": [
+					{"name": "Michonne", "friend.name": "Rick Grimes"},
+					{"name": "Michonne", "friend.name": "Glenn Rhee"},
+					{"name": "Michonne", "friend.name": "Daryl Dixon"},
+					{"name": "Michonne", "friend.name": "Andrea"}
+				]
+			}
+		}`, js)
+
+	// The arguments of a nested @normalize apply to its subtree.
+	query = `
+		{
+			me(func: uid(0x01)) @normalize {
+				mn: name
+				gender
+				son @normalize(dotted: true) {
+					name
+				}
+			}
+		}`
+
+	js = processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"me": [
+					{"mn": "Michonne", "son.name": "Andre"},
+					{"mn": "Michonne", "son.name": "Helmut"}
+				]
+			}
+		}`, js)
+}
+
+func TestNormalizeDirectiveLists(t *testing.T) {
+	query := `
+		{
+			me(func: uid(0x01)) @normalize(lists: true) {
+				mn: name
+				friend {
+					n: name
+					friend {
+						fn : name
+					}
+				}
+				son {
+					sn: name
+				}
+			}
+		}`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"me": [
+					{
+						"mn": "Michonne",
+						"friend": [
+							{"n": "Rick Grimes", "friend": [{"fn": "Michonne"}]},
+							{"n": "Glenn Rhee"},
+							{"n": "Daryl Dixon"},
+							{"n": "Andrea", "friend": [{"fn": "Glenn Rhee"}]}
+						],
+						"son": [
+							{"sn": "Andre"},
+							{"sn": "Helmut"}
+						]
+					}
+				]
+			}
+		}`, js)
+}
+
 func TestNormalizeDirectiveSubQueryLevel1(t *testing.T) {
 	query := `
 		{
@@ -254,12 +254,11 @@ func TestDeleteAndReadReverse(t *testing.T) {
 	js := processQueryNoErr(t, q1)
 	require.JSONEq(t, `{"data": {"me": [{"~child_pred": [{"uid": "0x666"}]}]}}`, js)
 
-	// Remove the reverse edges and verify the previous query is no longer supported.
+	// Remove the reverse edges and verify the previous query scans the tablet instead.
 	s2 := testSchema + "\n child_pred: uid .\n"
 	setSchema(s2)
-	_, err := processQuery(context.Background(), t, q1)
-	require.Error(t, err)
-	require.Contains(t, err.Error(), "Predicate child_pred doesn't have reverse edge")
+	js = processQueryNoErr(t, q1)
+	require.JSONEq(t, `{"data": {"me": [{"~child_pred": [{"uid": "0x666"}]}]}}`, js)
 
 	// Re-add reverse edges and verify that the original query works again.
 	setSchema(s1)
@@ -593,6 +592,24 @@ func TestTypeExpandMultipleExplicitTypes(t *testing.T) {
 			"owner": [{"uid": "0xcb"}]}]}}`, js)
 }
 
+func TestExpandRegex(t *testing.T) {
+	query := `{
+		q(func: eq(make, "Toyota")) {
+			expand(/^(make|year)$/)
+		}
+	}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"q":[{"make":"Toyota", "year":2009}]}}`, js)
+
+	query = `{
+		q(func: eq(make, "Toyota")) {
+			expand(/^MAKE$/i)
+		}
+	}`
+	js = processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"q":[{"make":"Toyota"}]}}`, js)
+}
+
 func TestTypeFilterAtExpand(t *testing.T) {
 	query := `{
 		q(func: eq(make, "Toyota")) {
@@ -1900,3 +1917,302 @@ func TestMultiplesSortingOrderWithVarAndPredicate(t *testing.T) {
 	_, err := processQuery(context.Background(), t, query)
 	require.ErrorContains(t, err, "Val() is not allowed in multiple sorting. Got: [SECTIONS_COUNT]")
 }
+
+func TestJoinOnValueVar(t *testing.T) {
+	query := `
+		{
+			var(func: uid(23, 25)) {
+				a as age
+			}
+			me(func: uid(a)) {
+				name
+				same_age: join(eq(age, val(a))) {
+					name
+					age
+				}
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"me": [
+					{
+						"name": "Rick Grimes",
+						"same_age": [{"name": "Glenn Rhee", "age": 15}]
+					},
+					{
+						"name": "Daryl Dixon"
+					}
+				]
+			}
+		}`, js)
+}
+
+func TestSampleHas(t *testing.T) {
+	query := `
+		{
+			sample(func: has(name), n: 3) {
+				count(uid)
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"sample": [{"count": 3}]}}`, js)
+
+	query = `
+		{
+			sample(func: uid(1, 23, 24), n: 2) {
+				name
+			}
+		}`
+	_, err := processQuery(context.Background(), t, query)
+	require.ErrorContains(t, err, "sample needs a root function other than uid")
+}
+
+func TestHashJoinOnValueVars(t *testing.T) {
+	query := `
+		{
+			var(func: uid(23, 25, 10000)) {
+				a as age
+			}
+			var(func: uid(24, 31, 10005, 10006)) {
+				b as age
+			}
+			me(func: uid(a)) {
+				uid
+				same_age: join(eq(val(b), val(a))) {
+					uid
+					age
+				}
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"me": [
+					{
+						"uid": "0x17",
+						"same_age": [{"uid": "0x18", "age": 15}]
+					},
+					{
+						"uid": "0x19"
+					},
+					{
+						"uid": "0x2710",
+						"same_age": [{"uid": "0x2715", "age": 25}, {"uid": "0x2716", "age": 25}]
+					}
+				]
+			}
+		}`, js)
+}
+
+func TestNotExists(t *testing.T) {
+	query := `
+		{
+			b as var(func: uid(24))
+			filtered(func: uid(1, 23, 31)) @filter(not_exists(friend, uid(b))) {
+				uid
+			}
+			root(func: not_exists(friend, uid(b))) {
+				uid
+			}
+			none(func: uid(1, 23, 31)) @filter(not_exists(friend, [0x1, 0x18])) {
+				uid
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"filtered": [{"uid": "0x17"}],
+				"root": [{"uid": "0x17"}],
+				"none": []
+			}
+		}`, js)
+}
+
+func TestCorrelatedFilter(t *testing.T) {
+	query := `
+		{
+			q(func: uid(1, 23, 31)) @filter(gt(val(oldest), 18)) {
+				uid
+				friend (orderdesc: age, first: 1) {
+					a as age
+				}
+				oldest as max(val(a))
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"q": [
+					{"uid": "0x1", "friend": [{"age": 19}], "max(val(a))": 19},
+					{"uid": "0x17", "friend": [{"age": 38}], "max(val(a))": 38}
+				]
+			}
+		}`, js)
+}
+
+func TestSetOps(t *testing.T) {
+	query := `
+		{
+			a as var(func: uid(1, 23, 24))
+			b(func: uid(23, 24, 25)) {
+				uid
+			}
+			union(func: union(a, b)) {
+				uid
+			}
+			intersect(func: intersect(a, b)) {
+				uid
+			}
+			except(func: except(a, b)) {
+				uid
+			}
+			filtered(func: uid(1, 23, 25)) @filter(intersect(a, b)) {
+				uid
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"b": [{"uid": "0x17"}, {"uid": "0x18"}, {"uid": "0x19"}],
+				"union": [{"uid": "0x1"}, {"uid": "0x17"}, {"uid": "0x18"}, {"uid": "0x19"}],
+				"intersect": [{"uid": "0x17"}, {"uid": "0x18"}],
+				"except": [{"uid": "0x1"}],
+				"filtered": [{"uid": "0x17"}]
+			}
+		}`, js)
+}
+
+func TestMotif(t *testing.T) {
+	query := `
+		{
+			cycles(func: uid(1, 23, 24, 31)) @motif("(a)-[friend]->(b)-[friend]->(a)") {
+				name
+			}
+			triangles(func: uid(1)) @motif("(a)-[friend]->(b)-[friend]->(c), (a)-[friend]->(c)") {
+				name
+			}
+			none(func: uid(1)) @motif("(a)<-[friend]-(b)-[friend]->(c)-[friend]->(a)") {
+				name
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"cycles": [
+					{"a": {"uid": "0x1", "name": "Michonne"}, "b": {"uid": "0x17", "name": "Rick Grimes"}},
+					{"a": {"uid": "0x17", "name": "Rick Grimes"}, "b": {"uid": "0x1", "name": "Michonne"}}
+				],
+				"triangles": [
+					{
+						"a": {"uid": "0x1", "name": "Michonne"},
+						"b": {"uid": "0x1f", "name": "Andrea"},
+						"c": {"uid": "0x18", "name": "Glenn Rhee"}
+					}
+				],
+				"none": []
+			}
+		}`, js)
+}
+
+func TestValidAt(t *testing.T) {
+	s1 := testSchema + "\n manages: [uid] .\n"
+	setSchema(s1)
+	triples := `
+		<0x3001> <name> "Ada" .
+		<0x3002> <name> "Grace" .
+		<0x3003> <name> "Linus" .
+		<0x3004> <name> "Ken" .
+		<0x3001> <manages> <0x3002> (valid_from=2010-01-01T00:00:00Z, valid_to=2015-01-01T00:00:00Z) .
+		<0x3001> <manages> <0x3003> (valid_from=2014-01-01T00:00:00Z) .
+		<0x3001> <manages> <0x3004> .
+		<0x3003> <manages> <0x3002> (valid_to=2016-01-01T00:00:00Z) .
+	`
+	require.NoError(t, addTriplesToCluster(triples))
+	defer func() {
+		dropPredicate("manages")
+		setSchema(testSchema)
+	}()
+
+	query := `
+		{
+			y2012(func: uid(0x3001)) @valid_at("2012-06-01") {
+				manages {
+					name
+					manages {
+						name
+					}
+				}
+			}
+			y2020(func: uid(0x3001)) @valid_at("2020-06-01") {
+				manages {
+					name
+					manages {
+						name
+					}
+				}
+			}
+			nested(func: uid(0x3001)) {
+				manages @valid_at("2015-06-01") {
+					name
+					count(manages)
+				}
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"y2012": [{"manages": [
+					{"name": "Grace"},
+					{"name": "Ken"}
+				]}],
+				"y2020": [{"manages": [
+					{"name": "Linus"},
+					{"name": "Ken"}
+				]}],
+				"nested": [{"manages": [
+					{"name": "Linus", "count(manages)": 1},
+					{"name": "Ken", "count(manages)": 0}
+				]}]
+			}
+		}`, js)
+}
+
+func TestReverseScan(t *testing.T) {
+	// school doesn't have @reverse, so its tablet is scanned.
+	query := `
+	{
+		me(func: uid(5000, 5001)) {
+			name
+			count(~school)
+			~school {
+				uid
+			}
+			first: ~school (first: 1, offset: 1) {
+				uid
+			}
+		}
+	}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me": [
+		{
+			"name": "School A",
+			"count(~school)": 3,
+			"~school": [{"uid": "0x1"}, {"uid": "0x18"}, {"uid": "0x19"}],
+			"first": [{"uid": "0x18"}]
+		},
+		{
+			"name": "School B",
+			"count(~school)": 3,
+			"~school": [{"uid": "0x17"}, {"uid": "0x1f"}, {"uid": "0x65"}],
+			"first": [{"uid": "0x1f"}]
+		}
+	]}}`, js)
+}
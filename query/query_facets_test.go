@@ -582,6 +582,29 @@ func TestRetrieveFacetsAsVars(t *testing.T) {
 		js)
 }
 
+func TestRetrieveFacetAggregatesAsVars(t *testing.T) {
+	require.NoError(t, populateClusterWithFacets())
+	query := `
+		{
+			var(func: uid(31, 33)) {
+				friend @facets(total as sum(score), top as max(score), n as count(score))
+			}
+
+			me(func: uid(31, 33)) {
+				name
+				total: val(total)
+				top: val(top)
+				n: val(n)
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"name":"Andrea","n":0},{"name":"Michale","total":400,"top":200,"n":3}]}}`,
+		js)
+}
+
 func TestRetrieveFacetsUidValues(t *testing.T) {
 	require.NoError(t, populateClusterWithFacets())
 	// to see how friend @facets are positioned in output.
@@ -0,0 +1,81 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+// applySetOp returns the uids of the set operation of sg, combining the uids of its variables in
+// the order they're given. A variable which hasn't been populated has no uids.
+func (sg *SubGraph) applySetOp(mp map[string]varValue) *pb.List {
+	lists := make([]*pb.List, 0, len(sg.Params.NeedsVar))
+	for _, v := range sg.Params.NeedsVar {
+		lists = append(lists, varUids(mp[v.Name]))
+	}
+	minCount := 1
+	if len(sg.SrcFunc.Args) > 0 {
+		// The parser has checked the minimum count of union.
+		n, _ := strconv.Atoi(sg.SrcFunc.Args[0].Value)
+		minCount = n
+	}
+	return setOp(sg.SrcFunc.SetOp, lists, minCount)
+}
+
+// setOp combines the sorted lists of uids. union keeps the uids found in at least minCount of
+// them, intersect the ones found in all of them, and except the ones of the first list which
+// aren't in any of the others.
+func setOp(op string, lists []*pb.List, minCount int) *pb.List {
+	switch {
+	case len(lists) == 0:
+		return &pb.List{}
+	case op == "intersect":
+		return algo.IntersectSorted(lists)
+	case op == "except":
+		out := lists[0]
+		for _, l := range lists[1:] {
+			out = algo.Difference(out, l)
+		}
+		return out
+	case minCount <= 1:
+		return algo.MergeSorted(lists)
+	}
+
+	counts := make(map[uint64]int)
+	for _, l := range lists {
+		for _, uid := range l.Uids {
+			counts[uid]++
+		}
+	}
+	out := &pb.List{}
+	for uid, n := range counts {
+		if n >= minCount {
+			out.Uids = append(out.Uids, uid)
+		}
+	}
+	sort.Slice(out.Uids, func(i, j int) bool { return out.Uids[i] < out.Uids[j] })
+	return out
+}
+
+// varUids returns the sorted uids of a uid variable, or the ones having a value in a value
+// variable.
+func varUids(v varValue) *pb.List {
+	if v.Uids != nil {
+		return v.Uids
+	}
+	out := &pb.List{}
+	_ = v.Vals.Iterate(func(uid uint64, _ types.Val) error {
+		out.Uids = append(out.Uids, uid)
+		return nil
+	})
+	sort.Slice(out.Uids, func(i, j int) bool { return out.Uids[i] < out.Uids[j] })
+	return out
+}
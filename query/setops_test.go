@@ -0,0 +1,30 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+)
+
+func TestSetOp(t *testing.T) {
+	lists := func() []*pb.List {
+		return []*pb.List{
+			{Uids: []uint64{1, 2, 3, 5}},
+			{Uids: []uint64{2, 3, 4}},
+			{Uids: []uint64{3, 5, 6}},
+		}
+	}
+	require.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, setOp("union", lists(), 1).Uids)
+	require.Equal(t, []uint64{2, 3, 5}, setOp("union", lists(), 2).Uids)
+	require.Equal(t, []uint64{3}, setOp("union", lists(), 3).Uids)
+	require.Equal(t, []uint64{3}, setOp("intersect", lists(), 1).Uids)
+	require.Equal(t, []uint64{1}, setOp("except", lists(), 1).Uids)
+	require.Empty(t, setOp("intersect", append(lists(), &pb.List{}), 1).Uids)
+}
@@ -0,0 +1,119 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+// aggSketch summarizes the values of the approximate aggregators, percentile(val(x), q) with a
+// t-digest and count(distinct val(x)) with a HyperLogLog, instead of keeping or sorting them.
+type aggSketch struct {
+	quantile float64
+	digest   *algo.TDigest
+	distinct *algo.HyperLogLog
+}
+
+// newAggregator returns the aggregator of the function, with the sketch of the approximate
+// aggregators.
+func newAggregator(fn *Function) (aggregator, error) {
+	ag := aggregator{name: fn.Name}
+	switch fn.Name {
+	case "percentile":
+		if len(fn.Args) != 1 {
+			return ag, errors.Errorf("percentile expects a quantile, like percentile(val(x), 0.95)")
+		}
+		quantile, err := strconv.ParseFloat(fn.Args[0].Value, 64)
+		if err != nil || quantile < 0 || quantile > 1 {
+			return ag, errors.Errorf("The quantile of percentile must be between 0 and 1, got %s",
+				fn.Args[0].Value)
+		}
+		ag.sketch = &aggSketch{quantile: quantile, digest: algo.NewTDigest()}
+	case "distinct":
+		ag.sketch = &aggSketch{distinct: algo.NewHyperLogLog()}
+	}
+	return ag, nil
+}
+
+// empty returns an empty sketch of the same aggregator.
+func (s *aggSketch) empty() *aggSketch {
+	if s.distinct != nil {
+		return &aggSketch{distinct: algo.NewHyperLogLog()}
+	}
+	return &aggSketch{quantile: s.quantile, digest: algo.NewTDigest()}
+}
+
+func (s *aggSketch) apply(v types.Val) error {
+	if s.distinct != nil {
+		data := types.ValueForType(types.BinaryID)
+		if err := types.Marshal(v, &data); err != nil {
+			return err
+		}
+		// The values of different types are distinct, even if they marshal the same way.
+		s.distinct.Add(append([]byte{byte(v.Tid)}, data.Value.([]byte)...))
+		return nil
+	}
+	switch v.Tid {
+	case types.IntID:
+		s.digest.Add(float64(v.Value.(int64)))
+	case types.FloatID:
+		s.digest.Add(v.Value.(float64))
+	default:
+		return errors.Errorf("percentile only supports int and float values, got %s",
+			v.Tid.Name())
+	}
+	return nil
+}
+
+// applyShards applies the values of the map, summarized shard by shard in parallel, and merged.
+func (s *aggSketch) applyShards(vals *types.ShardedMap) error {
+	shards := make([]*aggSketch, types.NumShards)
+	var g errgroup.Group
+	for i := range shards {
+		shards[i] = s.empty()
+		g.Go(func() error {
+			for _, v := range vals.GetShardOrNil(i) {
+				if err := shards[i].apply(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		s.merge(shard)
+	}
+	return nil
+}
+
+func (s *aggSketch) merge(other *aggSketch) {
+	if s.distinct != nil {
+		s.distinct.Merge(other.distinct)
+		return
+	}
+	s.digest.Merge(other.digest)
+}
+
+// value returns the approximate number of distinct values, or the approximate value at the
+// quantile.
+func (s *aggSketch) value() (types.Val, error) {
+	if s.distinct != nil {
+		return types.Val{Tid: types.IntID, Value: int64(s.distinct.Count())}, nil
+	}
+	if s.digest.Count() == 0 {
+		return types.Val{}, ErrEmptyVal
+	}
+	return types.Val{Tid: types.FloatID, Value: s.digest.Quantile(s.quantile)}, nil
+}
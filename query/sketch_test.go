@@ -0,0 +1,48 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+func TestApproxAggregators(t *testing.T) {
+	vals := types.NewShardedMap()
+	for uid := uint64(1); uid <= 1000; uid++ {
+		vals.Set(uid, types.Val{Tid: types.IntID, Value: int64(uid % 100)})
+	}
+
+	ag, err := newAggregator(&Function{Name: "distinct"})
+	require.NoError(t, err)
+	require.NoError(t, ag.sketch.applyShards(vals))
+	v, err := ag.Value()
+	require.NoError(t, err)
+	require.Equal(t, int64(100), v.Value)
+
+	ag, err = newAggregator(&Function{Name: "percentile", Args: []dql.Arg{{Value: "0.5"}}})
+	require.NoError(t, err)
+	_, err = ag.Value()
+	require.Equal(t, ErrEmptyVal, err)
+	require.NoError(t, ag.sketch.applyShards(vals))
+	v, err = ag.Value()
+	require.NoError(t, err)
+	require.InDelta(t, 49.5, v.Value, 1)
+	require.Error(t, ag.Apply(types.Val{Tid: types.StringID, Value: "a"}))
+
+	_, err = newAggregator(&Function{Name: "percentile", Args: []dql.Arg{{Value: "1.5"}}})
+	require.ErrorContains(t, err, "must be between 0 and 1")
+
+	require.Equal(t, "count(distinct val(x))",
+		aggregatorFieldName(&Function{Name: "distinct"}, "val(x)"))
+	require.Equal(t, "percentile(age, 0.95)",
+		aggregatorFieldName(&Function{Name: "percentile", Args: []dql.Arg{{Value: "0.95"}}}, "age"))
+	require.Equal(t, "min(val(x))", aggregatorFieldName(&Function{Name: "min"}, "val(x)"))
+}
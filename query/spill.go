@@ -0,0 +1,128 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// querySpill holds the uid and value matrices of the executed blocks of a query, spilled to a
+// temporary file so that the blocks with large results don't keep them in memory while the other
+// blocks of the query run. The matrices are read back once all the blocks have run, before the
+// results are encoded.
+type querySpill struct {
+	// threshold is the number of uids and values of a block from which it's spilled. Zero
+	// disables the spilling.
+	threshold uint64
+	dir       string
+
+	f       *os.File
+	size    int64
+	spilled []spilledMatrices
+}
+
+// spilledMatrices is where the matrices of a SubGraph are in the spill file.
+type spilledMatrices struct {
+	sg     *SubGraph
+	offset int64
+	length int
+}
+
+func newQuerySpill(threshold uint64, dir string) *querySpill {
+	return &querySpill{threshold: threshold, dir: dir}
+}
+
+// matricesSize returns the number of uids and values of the matrices of the block.
+func matricesSize(sg *SubGraph) uint64 {
+	var size uint64
+	sg.recurse(func(sg *SubGraph) {
+		for _, l := range sg.uidMatrix {
+			size += uint64(len(l.GetUids()))
+		}
+		for _, vl := range sg.valueMatrix {
+			size += uint64(len(vl.GetValues()))
+		}
+	})
+	return size
+}
+
+// add spills the matrices of the executed block if they are beyond the threshold.
+func (s *querySpill) add(ctx context.Context, sg *SubGraph) error {
+	if s.threshold == 0 || matricesSize(sg) < s.threshold {
+		return nil
+	}
+	if s.f == nil {
+		var err error
+		if s.f, err = os.CreateTemp(s.dir, "query-spill-*"); err != nil {
+			return errors.Wrapf(err, "while creating the spill file of the query")
+		}
+	}
+
+	start := s.size
+	var err error
+	sg.recurse(func(sg *SubGraph) {
+		if err != nil || (len(sg.uidMatrix) == 0 && len(sg.valueMatrix) == 0) {
+			return
+		}
+		var data []byte
+		if data, err = proto.Marshal(&pb.Result{
+			UidMatrix:   sg.uidMatrix,
+			ValueMatrix: sg.valueMatrix,
+		}); err != nil {
+			return
+		}
+		if _, err = s.f.WriteAt(data, s.size); err != nil {
+			return
+		}
+		s.spilled = append(s.spilled, spilledMatrices{sg: sg, offset: s.size, length: len(data)})
+		s.size += int64(len(data))
+		sg.uidMatrix, sg.valueMatrix = nil, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "while spilling the results of the query")
+	}
+	ostats.Record(ctx, x.NumQuerySpills.M(1), x.QuerySpilledBytes.M(s.size-start))
+	return nil
+}
+
+// restore reads the spilled matrices back into their SubGraphs, and removes the spill file.
+func (s *querySpill) restore() error {
+	if s.f == nil {
+		return nil
+	}
+	defer s.close()
+	for _, sp := range s.spilled {
+		data := make([]byte, sp.length)
+		if _, err := s.f.ReadAt(data, sp.offset); err != nil {
+			return errors.Wrapf(err, "while reading the spilled results of the query")
+		}
+		var res pb.Result
+		if err := proto.Unmarshal(data, &res); err != nil {
+			return errors.Wrapf(err, "while reading the spilled results of the query")
+		}
+		sp.sg.uidMatrix, sp.sg.valueMatrix = res.UidMatrix, res.ValueMatrix
+	}
+	s.spilled = nil
+	return nil
+}
+
+// close removes the spill file.
+func (s *querySpill) close() {
+	if s.f == nil {
+		return
+	}
+	_ = s.f.Close()
+	_ = os.Remove(s.f.Name())
+	s.f = nil
+}
@@ -0,0 +1,65 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+)
+
+func TestQuerySpill(t *testing.T) {
+	ctx := context.Background()
+	child := &SubGraph{
+		Attr:      "name",
+		uidMatrix: []*pb.List{{Uids: []uint64{1}}, {Uids: []uint64{2}}},
+		valueMatrix: []*pb.ValueList{
+			{Values: []*pb.TaskValue{{Val: []byte("a")}}},
+			{Values: []*pb.TaskValue{{Val: []byte("b")}}},
+		},
+	}
+	filter := &SubGraph{Attr: "age", uidMatrix: []*pb.List{{Uids: []uint64{2}}}}
+	sg := &SubGraph{
+		Attr:      "me",
+		uidMatrix: []*pb.List{{Uids: []uint64{1, 2}}},
+		Children:  []*SubGraph{child},
+		Filters:   []*SubGraph{filter},
+	}
+	small := &SubGraph{Attr: "you", uidMatrix: []*pb.List{{Uids: []uint64{3}}}}
+	require.Equal(t, uint64(7), matricesSize(sg))
+
+	dir := t.TempDir()
+	spill := newQuerySpill(5, dir)
+	defer spill.close()
+	require.NoError(t, spill.add(ctx, small))
+	require.Nil(t, spill.f)
+	require.Len(t, small.uidMatrix, 1)
+
+	require.NoError(t, spill.add(ctx, sg))
+	require.Len(t, spill.spilled, 3)
+	require.Zero(t, matricesSize(sg))
+	require.Nil(t, child.valueMatrix)
+
+	require.NoError(t, spill.restore())
+	require.Equal(t, uint64(7), matricesSize(sg))
+	require.True(t, proto.Equal(&pb.List{Uids: []uint64{1, 2}}, sg.uidMatrix[0]))
+	require.True(t, proto.Equal(&pb.List{Uids: []uint64{2}}, filter.uidMatrix[0]))
+	require.Equal(t, []byte("b"), child.valueMatrix[1].Values[0].Val)
+
+	// The spill file is removed once the results are restored.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// Spilling is disabled with a zero threshold.
+	require.NoError(t, newQuerySpill(0, dir).add(ctx, sg))
+	require.Equal(t, uint64(7), matricesSize(sg))
+}
@@ -0,0 +1,53 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// validFromFacet and validToFacet are the facets giving the interval an edge is valid in, from
+	// valid_from included to valid_to excluded. An edge without one of them is valid since or
+	// until forever.
+	validFromFacet = "valid_from"
+	validToFacet   = "valid_to"
+)
+
+// withValidAt returns the facets filter keeping the edges valid at the datetime ts, along with
+// the ones kept by ft. A missing facet fails any comparison, hence the negations: an edge is valid
+// unless it becomes valid after ts, or stops being valid at or before ts.
+func withValidAt(ft *pb.FilterTree, ts string) *pb.FilterTree {
+	notFn := func(name, key string) *pb.FilterTree {
+		return &pb.FilterTree{
+			Op: "not",
+			Children: []*pb.FilterTree{{
+				Func: &pb.Function{Key: key, Name: name, Args: []string{ts}},
+			}},
+		}
+	}
+	validAt := &pb.FilterTree{
+		Op: "and",
+		Children: []*pb.FilterTree{
+			notFn("gt", validFromFacet),
+			notFn("le", validToFacet),
+		},
+	}
+	if ft == nil {
+		return validAt
+	}
+	return &pb.FilterTree{Op: "and", Children: []*pb.FilterTree{ft, validAt}}
+}
+
+// isUidPredicate returns true if the predicate is a uid one, whose edges can have a validity. The
+// reverse edges don't have facets, so they're always valid.
+func isUidPredicate(namespace uint64, attr string) bool {
+	typ, err := schema.State().TypeOf(x.NamespaceAttr(namespace, attr))
+	return err == nil && typ == types.UidID
+}
@@ -845,3 +845,57 @@ func TestDotProductWithConstantVector(t *testing.T) {
 		`{"data":{"q":[{"vec452":[1,1,2,2],"distance":10},{"vec452":[2,1,2,2],"distance":13}]} }`,
 		processQueryNoErr(t, query))
 }
+
+func TestSimilarityJoin(t *testing.T) {
+	setSchema(`
+		taste201 : float32vector .
+		item201 : float32vector @index(hnsw(metric: "euclidean")) .
+		name201 : string @index(exact) .`)
+
+	rdfs := `
+		<0x201> <taste201> "[0.0, 0.0]" .
+		<0x202> <taste201> "[10.0, 10.0]" .
+		<0x211> <item201> "[0.0, 1.0]" .
+		<0x211> <name201> "a" .
+		<0x212> <item201> "[1.0, 1.0]" .
+		<0x212> <name201> "b" .
+		<0x213> <item201> "[9.0, 10.0]" .
+		<0x213> <name201> "c" .
+		<0x214> <item201> "[10.0, 11.0]" .
+		<0x214> <name201> "d" .`
+	require.NoError(t, addTriplesToCluster(rdfs))
+
+	query := `{
+		var(func: has(taste201)) {
+			v as taste201
+		}
+		q(func: uid(v)) {
+			uid
+			nearest: join(similar_to(item201, 2, val(v))) {
+				name201
+			}
+		}
+	}`
+	require.JSONEq(t, `{"data": {"q": [
+		{"uid": "0x201", "nearest": [{"name201": "a"}, {"name201": "b"}]},
+		{"uid": "0x202", "nearest": [{"name201": "c"}, {"name201": "d"}]}
+	]}}`, processQueryNoErr(t, query))
+
+	// The nearest nodes are searched among the candidates only.
+	query = `{
+		var(func: has(taste201)) {
+			v as taste201
+		}
+		c as var(func: eq(name201, "b", "c"))
+		q(func: uid(v)) {
+			uid
+			nearest: join(similar_to(item201, 1, val(v), uid(c))) {
+				name201
+			}
+		}
+	}`
+	require.JSONEq(t, `{"data": {"q": [
+		{"uid": "0x201", "nearest": [{"name201": "b"}]},
+		{"uid": "0x202", "nearest": [{"name201": "c"}]}
+	]}}`, processQueryNoErr(t, query))
+}
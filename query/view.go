@@ -0,0 +1,74 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// ViewPredicatePrefix is the prefix of the synthetic predicates serving the results of the
+// materialized views. The results of the view stats are read with dgraph.view.stats.
+const ViewPredicatePrefix = "dgraph.view."
+
+// viewStore holds the results of the materialized views, by namespace and name. The results of
+// a view are the JSON objects of the root nodes of its query, by uid.
+type viewStore struct {
+	sync.RWMutex
+	results map[string]map[uint64][]byte
+}
+
+var views = &viewStore{results: make(map[string]map[uint64][]byte)}
+
+// SetViewResults replaces the results of the view of the namespace. The results must not be
+// modified afterwards.
+func SetViewResults(ns uint64, name string, results map[uint64][]byte) {
+	views.Lock()
+	defer views.Unlock()
+	views.results[x.NamespaceAttr(ns, name)] = results
+}
+
+// DeleteViewResults deletes the results of the view of the namespace.
+func DeleteViewResults(ns uint64, name string) {
+	views.Lock()
+	defer views.Unlock()
+	delete(views.results, x.NamespaceAttr(ns, name))
+}
+
+func viewResults(ns uint64, name string) map[uint64][]byte {
+	views.RLock()
+	defer views.RUnlock()
+	return views.results[x.NamespaceAttr(ns, name)]
+}
+
+// isViewAttr returns true if attr is the synthetic predicate of a materialized view.
+func isViewAttr(attr string) bool {
+	return strings.HasPrefix(attr, ViewPredicatePrefix) && len(attr) > len(ViewPredicatePrefix)
+}
+
+// processView fills the results of the view read by sg for its source uids. The uids which
+// aren't root nodes of the view query don't have any result.
+func (sg *SubGraph) processView(ctx context.Context) error {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	results := viewResults(ns, strings.TrimPrefix(sg.Attr, ViewPredicatePrefix))
+
+	sg.DestUIDs = &pb.List{}
+	uids := sg.SrcUIDs.GetUids()
+	sg.uidMatrix = make([]*pb.List, len(uids))
+	sg.viewMatrix = make([][]byte, len(uids))
+	for i, uid := range uids {
+		sg.uidMatrix[i] = &pb.List{}
+		sg.viewMatrix[i] = results[uid]
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestProcessView(t *testing.T) {
+	SetViewResults(x.RootNamespace, "stats", map[uint64][]byte{
+		1: []byte(`{"uid":"0x1","posts":2}`),
+	})
+	defer DeleteViewResults(x.RootNamespace, "stats")
+
+	require.False(t, isViewAttr(ViewPredicatePrefix))
+	sg := &SubGraph{
+		Attr:    ViewPredicatePrefix + "stats",
+		SrcUIDs: &pb.List{Uids: []uint64{1, 2}},
+	}
+	require.True(t, isViewAttr(sg.Attr))
+	ctx := x.AttachNamespace(context.Background(), x.RootNamespace)
+	require.NoError(t, sg.processView(ctx))
+	require.Equal(t, [][]byte{[]byte(`{"uid":"0x1","posts":2}`), nil}, sg.viewMatrix)
+	require.Len(t, sg.uidMatrix, 2)
+	require.Empty(t, sg.DestUIDs.Uids)
+
+	// Another namespace doesn't see the view.
+	ctx = x.AttachNamespace(context.Background(), 2)
+	require.NoError(t, sg.processView(ctx))
+	require.Equal(t, [][]byte{nil, nil}, sg.viewMatrix)
+}
@@ -0,0 +1,362 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/wasm"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// wasmStore holds the compiled wasm modules uploaded through the admin API, by namespace and
+// name.
+type wasmStore struct {
+	sync.RWMutex
+	modules map[uint64]map[string]*wasm.Module
+}
+
+var wasmModules = &wasmStore{modules: make(map[uint64]map[string]*wasm.Module)}
+
+// SetWasmModules replaces the wasm modules of all the namespaces, by namespace and name. The
+// modules must not be modified afterwards.
+func SetWasmModules(modules map[uint64]map[string]*wasm.Module) {
+	wasmModules.Lock()
+	defer wasmModules.Unlock()
+	wasmModules.modules = modules
+}
+
+// wasmFunc is a user-defined function compiled to wasm, called by a query.
+type wasmFunc struct {
+	name   string
+	module *wasm.Module
+	export string
+	typ    wasm.FuncType
+}
+
+// resolveWasmFunc returns the function called with the name and number of arguments in the
+// namespace: the function of the module wasm.<module>.<function>, or the single function of the
+// module wasm.<module>.
+func resolveWasmFunc(ns uint64, name string, args int) (*wasmFunc, error) {
+	moduleName, export, _ := strings.Cut(strings.TrimPrefix(name, dql.WasmFuncPrefix), ".")
+	wasmModules.RLock()
+	m := wasmModules.modules[ns][moduleName]
+	wasmModules.RUnlock()
+	if m == nil {
+		return nil, errors.Errorf("Unknown wasm module %q in %s", moduleName, name)
+	}
+	if export == "" {
+		exports := m.Exports()
+		if len(exports) != 1 {
+			return nil, errors.Errorf("The wasm module %s exports %d functions, call one of them "+
+				"with wasm.%s.<function>", moduleName, len(exports), moduleName)
+		}
+		export = exports[0]
+	}
+	typ, ok := m.Signature(export)
+	switch {
+	case !ok:
+		return nil, errors.Errorf("The wasm module %s doesn't export a function named %s",
+			moduleName, export)
+	case len(typ.Params) != args:
+		return nil, errors.Errorf("The wasm function %s takes %d arguments, but got %d", name,
+			len(typ.Params), args)
+	case len(typ.Results) != 1:
+		return nil, errors.Errorf("The wasm function %s should return a single value, but "+
+			"returns %d", name, len(typ.Results))
+	}
+	return &wasmFunc{name: name, module: m, export: export, typ: typ}, nil
+}
+
+// resolveWasmFuncs resolves the wasm functions of the math expressions and the functions of the
+// subgraph and of its children, in the namespace of the context.
+func (sg *SubGraph) resolveWasmFuncs(ctx context.Context) error {
+	resolve := func(name string, args int) (*wasmFunc, error) {
+		ns, err := x.ExtractNamespace(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return resolveWasmFunc(ns, name, args)
+	}
+	var resolveMath func(mt *mathTree) error
+	resolveMath = func(mt *mathTree) error {
+		if dql.IsWasmFunc(mt.Fn) {
+			var err error
+			if mt.Wasm, err = resolve(mt.Fn, len(mt.Child)); err != nil {
+				return err
+			}
+		}
+		for _, child := range mt.Child {
+			if err := resolveMath(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if sg.MathExp != nil {
+		if err := resolveMath(sg.MathExp); err != nil {
+			return err
+		}
+	}
+	if sg.SrcFunc != nil && dql.IsWasmFunc(sg.SrcFunc.Name) {
+		var err error
+		if sg.SrcFunc.Wasm, err = resolve(sg.SrcFunc.Name, len(sg.wasmArgs())); err != nil {
+			return err
+		}
+	}
+	for _, child := range sg.Children {
+		if err := child.resolveWasmFuncs(ctx); err != nil {
+			return err
+		}
+	}
+	for _, filter := range sg.Filters {
+		if err := filter.resolveWasmFuncs(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wasmArgs returns the arguments of the wasm function of the subgraph, which start with its
+// attribute, like val(a) in wasm.keep(val(a), 2).
+func (sg *SubGraph) wasmArgs() []dql.Arg {
+	args := []dql.Arg{{Value: sg.Attr, IsValueVar: sg.SrcFunc.IsValueVar}}
+	return append(args, sg.SrcFunc.Args...)
+}
+
+// wasmCall calls a wasm function in its own instance, limited by --limit wasm-timeout and
+// wasm-memory-mb. It must be closed.
+type wasmCall struct {
+	f    *wasmFunc
+	in   *wasm.Instance
+	args []uint64
+}
+
+func (f *wasmFunc) instantiate() (*wasmCall, error) {
+	in, err := f.module.Instantiate(wasm.Limits{
+		Timeout:     x.Config.LimitWasmTimeout,
+		MemoryPages: uint32(min(x.Config.LimitWasmMemory/wasm.PageSize, wasm.MaxPages)),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while instantiating the wasm function %s", f.name)
+	}
+	return &wasmCall{f: f, in: in, args: make([]uint64, len(f.typ.Params))}, nil
+}
+
+func (c *wasmCall) close() {
+	if err := c.in.Close(); err != nil {
+		glog.Warningf("Error while closing the instance of the wasm function %s: %v", c.f.name, err)
+	}
+}
+
+// call calls the function with the values, converted to the types of its parameters. Its result
+// is an int or a float.
+func (c *wasmCall) call(vals []types.Val) (types.Val, error) {
+	for i, v := range vals {
+		arg, err := toWasmValue(v, c.f.typ.Params[i])
+		if err != nil {
+			return types.Val{}, errors.Wrapf(err, "in argument %d of the wasm function %s", i+1,
+				c.f.name)
+		}
+		c.args[i] = arg
+	}
+	res, err := c.in.Call(c.f.export, c.args...)
+	if err != nil {
+		return types.Val{}, errors.Wrapf(err, "in %s", c.f.name)
+	}
+	switch c.f.typ.Results[0] {
+	case wasm.I32:
+		return types.Val{Tid: types.IntID, Value: int64(int32(res[0]))}, nil
+	case wasm.I64:
+		return types.Val{Tid: types.IntID, Value: int64(res[0])}, nil
+	case wasm.F32:
+		return types.Val{Tid: types.FloatID,
+			Value: float64(math.Float32frombits(uint32(res[0])))}, nil
+	default:
+		return types.Val{Tid: types.FloatID, Value: math.Float64frombits(res[0])}, nil
+	}
+}
+
+// toWasmValue converts an int, float, bool or datetime value to the type of a parameter. The
+// datetimes are passed as seconds since the Unix epoch.
+func toWasmValue(v types.Val, typ wasm.ValueType) (uint64, error) {
+	var i int64
+	var f float64
+	switch val := v.Value.(type) {
+	case int64:
+		i, f = val, float64(val)
+	case float64:
+		i, f = int64(val), val
+	case bool:
+		if val {
+			i, f = 1, 1
+		}
+	case time.Time:
+		i, f = val.Unix(), float64(val.Unix())
+	default:
+		return 0, errors.Errorf("Values of type %s can't be passed to wasm functions",
+			v.Tid.Name())
+	}
+	switch typ {
+	case wasm.I32:
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return 0, errors.Errorf("%d is out of the range of i32", i)
+		}
+		return uint64(uint32(int32(i))), nil
+	case wasm.I64:
+		return uint64(i), nil
+	case wasm.F32:
+		return uint64(math.Float32bits(float32(f))), nil
+	default:
+		return math.Float64bits(f), nil
+	}
+}
+
+// processWasm calls the wasm function of the math node for the uids having a value for all its
+// variable arguments, or once if all its arguments are constant.
+func processWasm(mNode *mathTree) error {
+	c, err := mNode.Wasm.instantiate()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	vals := make([]types.Val, len(mNode.Child))
+	var vars []*types.ShardedMap
+	for i, child := range mNode.Child {
+		if child.Const.Value != nil {
+			vals[i] = child.Const
+		} else {
+			vars = append(vars, child.Val)
+		}
+	}
+	if len(vars) == 0 {
+		mNode.Const, err = c.call(vals)
+		return err
+	}
+
+	destMap := types.NewShardedMap()
+	err = vars[0].Iterate(func(uid uint64, _ types.Val) error {
+		for i, child := range mNode.Child {
+			if child.Const.Value != nil {
+				continue
+			}
+			v, ok := child.Val.Get(uid)
+			if !ok {
+				return nil
+			}
+			vals[i] = v
+		}
+		res, err := c.call(vals)
+		if err != nil {
+			return err
+		}
+		destMap.Set(uid, res)
+		return nil
+	})
+	mNode.Val = destMap
+	return err
+}
+
+// applyWasmFunc keeps the uids for which the wasm function of the filter returns a non-zero
+// value, given the values of its value variables and its constants.
+func (sg *SubGraph) applyWasmFunc() error {
+	c, err := sg.SrcFunc.Wasm.instantiate()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	args := sg.wasmArgs()
+	vals := make([]types.Val, len(args))
+	var vars []*types.ShardedMap
+	for i, arg := range args {
+		if arg.IsValueVar {
+			vars = append(vars, sg.Params.WasmVals[arg.Value])
+			continue
+		}
+		if vals[i], err = parseWasmConst(arg.Value); err != nil {
+			return err
+		}
+	}
+	keep := func(uid uint64) (bool, error) {
+		for i, arg := range args {
+			if !arg.IsValueVar {
+				continue
+			}
+			v, ok := sg.Params.WasmVals[arg.Value].Get(uid)
+			if !ok {
+				return false, nil
+			}
+			vals[i] = v
+		}
+		res, err := c.call(vals)
+		if err != nil {
+			return false, err
+		}
+		switch v := res.Value.(type) {
+		case int64:
+			return v != 0, nil
+		default:
+			return v.(float64) != 0, nil
+		}
+	}
+
+	var uids []uint64
+	if sg.SrcUIDs != nil {
+		uids = sg.SrcUIDs.Uids
+	} else if len(vars) > 0 {
+		// This is a function at root, which reads the uids of the values.
+		uids = make([]uint64, 0, vars[0].Len())
+		err = vars[0].Iterate(func(uid uint64, _ types.Val) error {
+			uids = append(uids, uid)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	}
+	sg.DestUIDs = &pb.List{}
+	for _, uid := range uids {
+		ok, err := keep(uid)
+		if err != nil {
+			return err
+		}
+		if ok {
+			sg.DestUIDs.Uids = append(sg.DestUIDs.Uids, uid)
+		}
+	}
+	if sg.SrcUIDs == nil {
+		sg.uidMatrix = []*pb.List{sg.DestUIDs}
+	}
+	return nil
+}
+
+// parseWasmConst parses a constant argument of a wasm function in a filter, as an int or a
+// float.
+func parseWasmConst(arg string) (types.Val, error) {
+	if i, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		return types.Val{Tid: types.IntID, Value: i}, nil
+	}
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return types.Val{}, errors.Errorf("Invalid argument %q of a wasm function, which should "+
+			"be a number or a value variable", arg)
+	}
+	return types.Val{Tid: types.FloatID, Value: f}, nil
+}
@@ -0,0 +1,103 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/wasm"
+)
+
+// scoreModule exports score(a, b f64) f64, returning a + 2 * b.
+var scoreModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x07, 0x01, 0x60, 0x02, 0x7c, 0x7c, 0x01, 0x7c,
+	0x03, 0x02, 0x01, 0x00,
+	0x07, 0x09, 0x01, 0x05, 's', 'c', 'o', 'r', 'e', 0x00, 0x00,
+	0x0a, 0x0c, 0x01, 0x0a, 0x00, 0x20, 0x00, 0x20, 0x01, 0x20, 0x01, 0xa0, 0xa0, 0x0b,
+}
+
+func setScoreModule(t *testing.T) {
+	m, err := wasm.Compile(scoreModule)
+	require.NoError(t, err)
+	SetWasmModules(map[uint64]map[string]*wasm.Module{0: {"scoring": m}})
+	t.Cleanup(func() { SetWasmModules(nil) })
+}
+
+func TestResolveWasmFunc(t *testing.T) {
+	setScoreModule(t)
+	for _, name := range []string{"wasm.scoring", "wasm.scoring.score"} {
+		f, err := resolveWasmFunc(0, name, 2)
+		require.NoError(t, err)
+		require.Equal(t, "score", f.export)
+	}
+	_, err := resolveWasmFunc(1, "wasm.scoring", 2)
+	require.ErrorContains(t, err, `Unknown wasm module "scoring"`)
+	_, err = resolveWasmFunc(0, "wasm.scoring.rank", 2)
+	require.ErrorContains(t, err, "doesn't export a function named rank")
+	_, err = resolveWasmFunc(0, "wasm.scoring", 3)
+	require.ErrorContains(t, err, "takes 2 arguments, but got 3")
+}
+
+func TestWasmMath(t *testing.T) {
+	setScoreModule(t)
+	f, err := resolveWasmFunc(0, "wasm.scoring", 2)
+	require.NoError(t, err)
+
+	a := types.NewShardedMap()
+	a.Set(1, types.Val{Tid: types.FloatID, Value: 1.5})
+	a.Set(2, types.Val{Tid: types.IntID, Value: int64(2)})
+	tree := &mathTree{
+		Fn:   "wasm.scoring",
+		Wasm: f,
+		Child: []*mathTree{
+			{Var: "a", Val: a},
+			{Const: types.Val{Tid: types.IntID, Value: int64(10)}},
+		},
+	}
+	require.NoError(t, evalMathTree(tree))
+	require.Equal(t, 2, tree.Val.Len())
+	for uid, score := range map[uint64]float64{1: 21.5, 2: 22} {
+		v, ok := tree.Val.Get(uid)
+		require.True(t, ok)
+		require.Equal(t, types.Val{Tid: types.FloatID, Value: score}, v)
+	}
+
+	a.Set(3, types.Val{Tid: types.StringID, Value: "three"})
+	require.ErrorContains(t, evalMathTree(tree), "Values of type string can't be passed")
+}
+
+func TestWasmFilter(t *testing.T) {
+	setScoreModule(t)
+	f, err := resolveWasmFunc(0, "wasm.scoring", 2)
+	require.NoError(t, err)
+
+	a := types.NewShardedMap()
+	a.Set(1, types.Val{Tid: types.IntID, Value: int64(10)})
+	a.Set(2, types.Val{Tid: types.IntID, Value: int64(12)})
+	// Keeps the uids whose value of a isn't 10, as score(a, -5) is a - 10.
+	sg := &SubGraph{
+		Attr: "a",
+		SrcFunc: &Function{
+			Name:       "wasm.scoring",
+			Args:       []dql.Arg{{Value: "-5"}},
+			IsValueVar: true,
+			Wasm:       f,
+		},
+		SrcUIDs: &pb.List{Uids: []uint64{1, 2, 3}},
+		Params:  params{WasmVals: map[string]*types.ShardedMap{"a": a}},
+	}
+	require.NoError(t, sg.applyWasmFunc())
+	require.Equal(t, []uint64{2}, sg.DestUIDs.Uids)
+
+	sg.SrcFunc.Args[0].Value = "five"
+	require.ErrorContains(t, sg.applyWasmFunc(), `Invalid argument "five"`)
+}
@@ -48,6 +48,8 @@ const (
 	entrySize = 32
 	// logSuffix is the suffix for log files.
 	logSuffix = ".wal"
+	// entryCompressed is set in the type of the entries whose data is compressed with zstd.
+	entryCompressed = 1 << 32
 )
 
 var (
@@ -60,7 +62,8 @@ type entry []byte
 func (e entry) Term() uint64       { return binary.BigEndian.Uint64(e) }
 func (e entry) Index() uint64      { return binary.BigEndian.Uint64(e[8:]) }
 func (e entry) DataOffset() uint64 { return binary.BigEndian.Uint64(e[16:]) }
-func (e entry) Type() uint64       { return binary.BigEndian.Uint64(e[24:]) }
+func (e entry) Type() uint64       { return binary.BigEndian.Uint64(e[24:]) &^ entryCompressed }
+func (e entry) Compressed() bool   { return binary.BigEndian.Uint64(e[24:])&entryCompressed > 0 }
 
 func marshalEntry(b []byte, term, index, do, typ uint64) {
 	x.AssertTrue(len(b) == entrySize)
@@ -179,6 +182,11 @@ func (lf *logFile) GetRaftEntry(idx int) raftpb.Entry {
 		x.Check(err)
 		re.Data = decoded
 	}
+	if entry.Compressed() && len(re.Data) > 0 {
+		decoded, err := zstdDecoder().DecodeAll(re.Data, nil)
+		x.Check(err)
+		re.Data = decoded
+	}
 	return re
 }
 
@@ -38,7 +38,8 @@ import (
 // These files contain raftpb.Entry protos. Each entry is composed of term, index, type and data.
 //
 // Term takes 8 bytes. Index takes 8 bytes. Type takes 8 bytes. And for data, we store an offset to
-// the actual slice, which is 8 bytes. Size of entry = 32 bytes.
+// the actual slice, which is 8 bytes. Size of entry = 32 bytes. Bit 32 of the type is set if the
+// data is compressed with zstd.
 // First 30K entries would consume 960KB, hence fitting on the first MB of the file (logFileOffset).
 //
 // Pre-allocate 1MB in each file just for these entries, and zero them out explicitly. Zeroing them
@@ -115,6 +116,15 @@ func InitEncrypted(dir string, encKey x.Sensitive) (*DiskStorage, error) {
 	return w, nil
 }
 
+// SetCompressionThreshold makes the data of the entries bigger than threshold bytes to be
+// compressed with zstd, when they're written to the log. A threshold of zero disables the
+// compression. The compressed entries can be read back whatever the threshold.
+func (w *DiskStorage) SetCompressionThreshold(threshold int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.wal.compressAbove = threshold
+}
+
 func (w *DiskStorage) SetUint(info MetaInfo, id uint64) { w.meta.SetUint(info, id) }
 func (w *DiskStorage) Uint(info MetaInfo) uint64        { return w.meta.Uint(info) }
 
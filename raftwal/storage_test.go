@@ -27,6 +27,7 @@ import (
 	"math"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -559,3 +560,43 @@ func TestStorageBig(t *testing.T) {
 	t.Run("without encryption", func(t *testing.T) { test(t, nil) })
 	t.Run("with encryption", func(t *testing.T) { test(t, []byte("badger16byteskey")) })
 }
+
+func TestStorageCompression(t *testing.T) {
+	test := func(t *testing.T, key x.Sensitive) {
+		dir := t.TempDir()
+		ds, err := InitEncrypted(dir, key)
+		require.NoError(t, err)
+		ds.SetCompressionThreshold(1 << 10)
+
+		small := []byte("small entry")
+		big := []byte(strings.Repeat("a big entry which compresses well. ", 100))
+		random := make([]byte, 2<<10)
+		_, err = rand.Read(random)
+		require.NoError(t, err)
+		ents := []raftpb.Entry{
+			{Index: 1, Term: 1, Data: small},
+			{Index: 2, Term: 1, Data: big},
+			{Index: 3, Term: 1, Data: random, Type: raftpb.EntryConfChange},
+		}
+		require.NoError(t, ds.wal.AddEntries(ents))
+		require.False(t, ds.wal.current.getEntry(0).Compressed())
+		require.True(t, ds.wal.current.getEntry(1).Compressed())
+		require.Equal(t, uint64(raftpb.EntryNormal), ds.wal.current.getEntry(1).Type())
+		// The data which doesn't compress is kept as it is.
+		require.False(t, ds.wal.current.getEntry(2).Compressed())
+		require.NoError(t, ds.Sync())
+
+		// The compressed entries are read back after disabling the compression.
+		ks, err := InitEncrypted(dir, key)
+		require.NoError(t, err)
+		require.Equal(t, ents, ks.wal.allEntries(1, math.MaxInt64, math.MaxInt64))
+
+		// The data of the compressed entries is truncated as any other.
+		ks.TruncateEntriesUntil(3)
+		got := ks.wal.allEntries(1, math.MaxInt64, math.MaxInt64)
+		require.Empty(t, got[1].Data)
+		require.Equal(t, random, got[2].Data)
+	}
+	t.Run("without encryption", func(t *testing.T) { test(t, nil) })
+	t.Run("with encryption", func(t *testing.T) { test(t, []byte("badger16byteskey")) })
+}
@@ -8,8 +8,10 @@ package raftwal
 import (
 	"bytes"
 	"sort"
+	"sync"
 
 	"github.com/golang/glog"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/raft/v3"
 	"go.etcd.io/etcd/raft/v3/raftpb"
@@ -35,6 +37,9 @@ type wal struct {
 	nextEntryIdx int
 	// dir is the directory to use to store files.
 	dir string
+	// compressAbove is the size in bytes above which the data of the entries is compressed.
+	// Zero disables the compression.
+	compressAbove int
 }
 
 // allEntries returns all the entries in the range [lo, hi).
@@ -187,6 +192,15 @@ func (l *wal) AddEntries(entries []raftpb.Entry) error {
 	}
 
 	for _, re := range entries {
+		typ := uint64(re.Type)
+		if l.compressAbove > 0 && len(re.Data) > l.compressAbove {
+			// Keep the data as it is if it doesn't compress.
+			if data := zstdEncoder().EncodeAll(re.Data, nil); len(data) < len(re.Data) {
+				re.Data = data
+				typ |= entryCompressed
+			}
+		}
+
 		// Write upto maxNumEntries or 1GB, whatever happens first.
 		if l.nextEntryIdx >= maxNumEntries || offset+4+len(re.Data) > 1<<30 {
 			if err := l.rotate(re.Index, offset); err != nil {
@@ -214,7 +228,7 @@ func (l *wal) AddEntries(entries []raftpb.Entry) error {
 
 		// Write the entry at the given slot.
 		buf := l.current.getEntry(l.nextEntryIdx)
-		marshalEntry(buf, re.Term, re.Index, uint64(offset), uint64(re.Type))
+		marshalEntry(buf, re.Term, re.Index, uint64(offset), typ)
 
 		// Update values for the next entry.
 		offset = next
@@ -445,3 +459,29 @@ func openWal(dir string) (*wal, error) {
 	e.current = ef
 	return e, err
 }
+
+var (
+	zstdEnc          *zstd.Encoder
+	zstdDec          *zstd.Decoder
+	encOnce, decOnce sync.Once
+)
+
+// zstdEncoder returns the encoder of the data of the compressed entries. The encoder favors speed,
+// as the entries are compressed while Raft waits for them to be written.
+func zstdEncoder() *zstd.Encoder {
+	encOnce.Do(func() {
+		var err error
+		zstdEnc, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		x.Check(err)
+	})
+	return zstdEnc
+}
+
+func zstdDecoder() *zstd.Decoder {
+	decOnce.Do(func() {
+		var err error
+		zstdDec, err = zstd.NewReader(nil)
+		x.Check(err)
+	})
+	return zstdDec
+}
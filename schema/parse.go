@@ -51,6 +51,13 @@ func parseDirective(it *lex.ItemIterator, schema *pb.SchemaUpdate, t types.TypeI
 			return next.Errorf("Cannot reverse for non-UID type")
 		}
 		schema.Directive = pb.SchemaUpdate_REVERSE
+		if next, ok := it.PeekOne(); ok && next.Typ == itemLeftRound {
+			async, err := parseReverseDirective(it, schema.Predicate)
+			if err != nil {
+				return err
+			}
+			schema.AsyncReverse = async
+		}
 	case "index":
 		tokenizer, vectorSpecs, err := parseIndexDirective(it, schema.Predicate, t)
 		if err != nil {
@@ -67,6 +74,39 @@ func parseDirective(it *lex.ItemIterator, schema *pb.SchemaUpdate, t types.TypeI
 		schema.Unique = true
 	case "noconflict":
 		schema.NoConflict = true
+	case "conflict":
+		policy, err := parseConflictDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		if policy == ConflictNone {
+			schema.NoConflict = true
+		} else {
+			schema.Conflict = policy
+		}
+	case "provenance":
+		schema.Provenance = true
+	case "embedding":
+		if t != types.VFloatID || schema.List {
+			return next.Errorf("@embedding directive can only be specified for float32vector type."+
+				" Got: [%v] for attr: [%v]", t.Name(), schema.Predicate)
+		}
+		source, err := parseEmbeddingDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.EmbeddingSource = source
+	case "compress":
+		if t == types.UidID {
+			return next.Errorf("@compress directive can't be specified for uid type."+
+				" Got: [%v] for attr: [%v]", t.Name(), schema.Predicate)
+		}
+		codec, level, err := parseCompressDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.Compression = codec
+		schema.CompressionLevel = level
 	case "lang":
 		if t != types.StringID || schema.List {
 			return next.Errorf("@lang directive can only be specified for string type."+
@@ -150,6 +190,10 @@ func parseScalarPair(it *lex.ItemIterator, predicate string, ns uint64) (*pb.Sch
 		next = it.Item()
 	}
 
+	if schema.AsyncReverse && schema.Count {
+		return nil, next.Errorf("@reverse(async) can't be used with @count for pred: %s",
+			predicate)
+	}
 	if next.Typ != itemDot {
 		return nil, next.Errorf("Invalid ending")
 	}
@@ -165,6 +209,127 @@ func parseScalarPair(it *lex.ItemIterator, predicate string, ns uint64) (*pb.Sch
 	return schema, nil
 }
 
+// parseReverseDirective works on "@reverse(async)", once "@reverse" has been found. It returns
+// true if the reverse edges are to be added asynchronously.
+func parseReverseDirective(it *lex.ItemIterator, predicate string) (bool, error) {
+	// Move past the '(' found by the caller.
+	it.Next()
+	it.Next()
+	next := it.Item()
+	if next.Typ != itemText || strings.ToLower(next.Val) != "async" {
+		return false, next.Errorf("Invalid reverse option '%s' for pred: %s. It must be async.",
+			next.Val, x.ParseAttr(predicate))
+	}
+	it.Next()
+	if next := it.Item(); next.Typ != itemRightRound {
+		return false, next.Errorf("Expected ')' but found '%s' for pred: %s", next.Val,
+			x.ParseAttr(predicate))
+	}
+	return true, nil
+}
+
+// parseConflictDirective works on "@conflict(policy)", once "@conflict" has been found. The
+// policy sets which keys the mutations of the predicate conflict on.
+func parseConflictDirective(it *lex.ItemIterator, predicate string) (string, error) {
+	if !it.Next() {
+		return "", it.Item().Errorf("Invalid ending.")
+	}
+	if next := it.Item(); next.Typ != itemLeftRound {
+		return "", next.Errorf("Require a conflict policy for pred: %s.", x.ParseAttr(predicate))
+	}
+	it.Next()
+	next := it.Item()
+	policy := strings.ToLower(next.Val)
+	if next.Typ != itemText ||
+		(policy != ConflictValue && policy != ConflictUid && policy != ConflictNone) {
+		return "", next.Errorf("Invalid conflict policy '%s' for pred: %s. It must be one of"+
+			" value, uid or none.", next.Val, x.ParseAttr(predicate))
+	}
+	it.Next()
+	if next := it.Item(); next.Typ != itemRightRound {
+		return "", next.Errorf("Expected ')' but found '%s' for pred: %s", next.Val,
+			x.ParseAttr(predicate))
+	}
+	return policy, nil
+}
+
+// parseEmbeddingDirective works on "@embedding(source)", once "@embedding" has been found. It
+// returns the source predicate, without namespace.
+func parseEmbeddingDirective(it *lex.ItemIterator, predicate string) (string, error) {
+	if !it.Next() {
+		return "", it.Item().Errorf("Invalid ending.")
+	}
+	if next := it.Item(); next.Typ != itemLeftRound {
+		return "", next.Errorf("Require a source predicate for pred: %s.", x.ParseAttr(predicate))
+	}
+	it.Next()
+	next := it.Item()
+	if next.Typ != itemText {
+		return "", next.Errorf("Invalid source predicate '%s' for pred: %s.", next.Val,
+			x.ParseAttr(predicate))
+	}
+	source := next.Val
+	if source == x.ParseAttr(predicate) {
+		return "", next.Errorf("The source predicate of pred: %s can't be itself.", source)
+	}
+	it.Next()
+	if next := it.Item(); next.Typ != itemRightRound {
+		return "", next.Errorf("Expected ')' but found '%s' for pred: %s", next.Val,
+			x.ParseAttr(predicate))
+	}
+	return source, nil
+}
+
+// parseCompressDirective works on "@compress(codec)" or "@compress(zstd, level: n)", once
+// "@compress" has been found. It returns the codec and the zstd level, 0 for the default one.
+func parseCompressDirective(it *lex.ItemIterator, predicate string) (string, int32, error) {
+	if !it.Next() {
+		return "", 0, it.Item().Errorf("Invalid ending.")
+	}
+	if next := it.Item(); next.Typ != itemLeftRound {
+		return "", 0, next.Errorf("Require a codec for pred: %s.", x.ParseAttr(predicate))
+	}
+	it.Next()
+	next := it.Item()
+	codec := strings.ToLower(next.Val)
+	if next.Typ != itemText || (codec != CompressionSnappy && codec != CompressionZstd) {
+		return "", 0, next.Errorf("Invalid codec '%s' for pred: %s. It must be one of"+
+			" snappy or zstd.", next.Val, x.ParseAttr(predicate))
+	}
+	it.Next()
+	var level int32
+	if next := it.Item(); next.Typ == itemComma {
+		it.Next()
+		if next := it.Item(); next.Typ != itemText || next.Val != "level" {
+			return "", 0, next.Errorf("Invalid compression option '%s' for pred: %s. It must"+
+				" be level.", next.Val, x.ParseAttr(predicate))
+		}
+		it.Next()
+		if next := it.Item(); next.Typ != itemColon {
+			return "", 0, next.Errorf("Expected ':' but found '%s' for pred: %s", next.Val,
+				x.ParseAttr(predicate))
+		}
+		it.Next()
+		next := it.Item()
+		n, err := strconv.Atoi(next.Val)
+		if next.Typ != itemNumber || err != nil || n < 1 || n > MaxCompressionLevel {
+			return "", 0, next.Errorf("Invalid compression level '%s' for pred: %s. It must be"+
+				" between 1 and %d.", next.Val, x.ParseAttr(predicate), MaxCompressionLevel)
+		}
+		if codec != CompressionZstd {
+			return "", 0, next.Errorf("The compression level can only be set for zstd, for"+
+				" pred: %s", x.ParseAttr(predicate))
+		}
+		level = int32(n)
+		it.Next()
+	}
+	if next := it.Item(); next.Typ != itemRightRound {
+		return "", 0, next.Errorf("Expected ')' but found '%s' for pred: %s", next.Val,
+			x.ParseAttr(predicate))
+	}
+	return codec, level, nil
+}
+
 // parseIndexDirective works on "@index" or "@index(customtokenizer)"
 // or @index(tok1(opt1:"opt1val",opt2:"opt2val"), tok2, tok3)
 // We assume that the "@index" has already been found, so we just need
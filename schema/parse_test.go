@@ -389,6 +389,126 @@ func TestParseUpsert(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestParseConflict(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		tags : [string] @index(exact) @conflict(value) .
+		counter : int @conflict(UID) .
+		log : [string] @conflict(none) .
+	`)
+	require.NoError(t, err)
+	require.Len(t, result.Preds, 3)
+	require.Equal(t, ConflictValue, result.Preds[0].Conflict)
+	require.Equal(t, ConflictUid, result.Preds[1].Conflict)
+	require.Empty(t, result.Preds[2].Conflict)
+	require.True(t, result.Preds[2].NoConflict)
+
+	for _, s := range []string{
+		"tags : string @conflict .",
+		"tags : string @conflict() .",
+		"tags : string @conflict(index) .",
+		"tags : string @conflict(value .",
+	} {
+		_, err := Parse(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestParseProvenance(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		name : string @index(exact) @provenance .
+		friend : [uid] @reverse @provenance .
+		age : int .
+	`)
+	require.NoError(t, err)
+	require.Len(t, result.Preds, 3)
+	require.True(t, result.Preds[0].Provenance)
+	require.True(t, result.Preds[1].Provenance)
+	require.False(t, result.Preds[2].Provenance)
+}
+
+func TestParseEmbedding(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		description : string .
+		description_vec : float32vector @index(hnsw(metric:"cosine")) @embedding(description) .
+		summary_vec : float32vector @embedding(<summary.text>) .
+	`)
+	require.NoError(t, err)
+	require.Len(t, result.Preds, 3)
+	require.Empty(t, result.Preds[0].EmbeddingSource)
+	require.Equal(t, "description", result.Preds[1].EmbeddingSource)
+	require.Equal(t, "summary.text", result.Preds[2].EmbeddingSource)
+
+	for _, s := range []string{
+		"description : string @embedding(text) .",
+		"vec : float32vector @embedding .",
+		"vec : float32vector @embedding() .",
+		"vec : float32vector @embedding(vec) .",
+		"vec : float32vector @embedding(description .",
+	} {
+		_, err := Parse(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestParseAsyncReverse(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		follows : [uid] @reverse(async) .
+		friend : [uid] @reverse @count .
+	`)
+	require.NoError(t, err)
+	require.Len(t, result.Preds, 2)
+	require.Equal(t, pb.SchemaUpdate_REVERSE, result.Preds[0].Directive)
+	require.True(t, result.Preds[0].AsyncReverse)
+	require.Equal(t, pb.SchemaUpdate_REVERSE, result.Preds[1].Directive)
+	require.False(t, result.Preds[1].AsyncReverse)
+
+	for _, s := range []string{
+		"follows : [uid] @reverse() .",
+		"follows : [uid] @reverse(sync) .",
+		"follows : [uid] @reverse(async .",
+		"follows : [uid] @reverse(async) @count .",
+	} {
+		_, err := Parse(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestParseCompress(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		bio : string @compress(zstd) @index(term) .
+		notes : [string] @compress(zstd, level: 19) .
+		code : string @compress(snappy) .
+	`)
+	require.NoError(t, err)
+	require.Len(t, result.Preds, 3)
+	require.Equal(t, CompressionZstd, result.Preds[0].Compression)
+	require.Equal(t, int32(0), result.Preds[0].CompressionLevel)
+	require.Equal(t, []string{"term"}, result.Preds[0].Tokenizer)
+	require.Equal(t, CompressionZstd, result.Preds[1].Compression)
+	require.Equal(t, int32(19), result.Preds[1].CompressionLevel)
+	require.Equal(t, CompressionSnappy, result.Preds[2].Compression)
+
+	for _, s := range []string{
+		"bio : string @compress .",
+		"bio : string @compress() .",
+		"bio : string @compress(gzip) .",
+		"bio : string @compress(zstd, level: 23) .",
+		"bio : string @compress(zstd, level: 0) .",
+		"bio : string @compress(zstd, size: 3) .",
+		"bio : string @compress(snappy, level: 3) .",
+		"bio : string @compress(zstd .",
+		"friend : uid @compress(zstd) .",
+	} {
+		_, err := Parse(s)
+		require.Error(t, err, s)
+	}
+}
+
 func TestParseEmptyType(t *testing.T) {
 	reset()
 	result, err := Parse(`
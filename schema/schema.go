@@ -44,6 +44,27 @@ const (
 	IsUniqueDgraphXid            = true
 )
 
+// The conflict policies of @conflict. They set which keys the mutations of a predicate conflict
+// on, to avoid the aborts of transactions which conflict only on the index keys.
+const (
+	// ConflictValue conflicts on the value of a subject, so that the mutations of the different
+	// values of a list don't conflict.
+	ConflictValue = "value"
+	// ConflictUid conflicts on the subject, whatever the values.
+	ConflictUid = "uid"
+	// ConflictNone doesn't conflict at all, like @noconflict.
+	ConflictNone = "none"
+)
+
+// The codecs of @compress, compressing the rolled up posting lists of the data keys of a
+// predicate.
+const (
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+	// MaxCompressionLevel is the highest zstd level of @compress(zstd, level: n).
+	MaxCompressionLevel = 22
+)
+
 // GetWriteContext returns a context that sets the schema context for writing.
 func GetWriteContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, IsWrite, true)
@@ -448,6 +469,29 @@ func (s *state) IsReversed(ctx context.Context, pred string) bool {
 	return false
 }
 
+// HasAsyncReverse returns whether the reverse edges of the predicate are added once its
+// mutations are committed, with @reverse(async).
+func (s *state) HasAsyncReverse(ctx context.Context, pred string) bool {
+	isWrite, _ := ctx.Value(IsWrite).(bool)
+	s.RLock()
+	defer s.RUnlock()
+	if isWrite {
+		if schema, ok := s.mutSchema[pred]; ok {
+			return schema.AsyncReverse
+		}
+	}
+	return s.predicate[pred].GetAsyncReverse()
+}
+
+// Compression returns the codec, the zstd level and the zstd dictionaries set with @compress for
+// the given predicate. The codec is empty if its values aren't compressed.
+func (s *state) Compression(pred string) (string, int32, [][]byte) {
+	s.RLock()
+	defer s.RUnlock()
+	schema := s.predicate[pred]
+	return schema.GetCompression(), schema.GetCompressionLevel(), schema.GetCompressionDicts()
+}
+
 // HasCount returns whether we want to mantain a count index for the given predicate or not.
 func (s *state) HasCount(ctx context.Context, pred string) bool {
 	isWrite, _ := ctx.Value(IsWrite).(bool)
@@ -514,6 +558,42 @@ func (s *state) HasNoConflict(pred string) bool {
 	return s.predicate[pred].GetNoConflict()
 }
 
+// ConflictPolicy returns the conflict policy set for the predicate with @conflict, which is
+// empty for the default policy.
+func (s *state) ConflictPolicy(pred string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetConflict()
+}
+
+// HasProvenance returns whether the predicate has @provenance, which records who set its values
+// and when in their facets.
+func (s *state) HasProvenance(pred string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetProvenance()
+}
+
+// EmbeddingTargets returns the predicates of the namespace with @embedding by their source
+// predicates, both with the namespace.
+func (s *state) EmbeddingTargets(ns uint64) map[string][]string {
+	s.RLock()
+	defer s.RUnlock()
+	var targets map[string][]string
+	for pred, update := range s.predicate {
+		source := update.GetEmbeddingSource()
+		if source == "" || x.ParseNamespace(pred) != ns {
+			continue
+		}
+		if targets == nil {
+			targets = make(map[string][]string)
+		}
+		source = x.NamespaceAttr(ns, source)
+		targets[source] = append(targets[source], pred)
+	}
+	return targets
+}
+
 // IndexingInProgress checks whether indexing is going on for a given predicate.
 func (s *state) IndexingInProgress() bool {
 	s.RLock()
@@ -675,6 +755,23 @@ func initialTypesInternal(namespace uint64, all bool) []*pb.TypeUpdate {
 					ValueType: pb.Posting_STRING,
 				},
 			},
+		},
+		&pb.TypeUpdate{
+			TypeName: "dgraph.idempotency",
+			Fields: []*pb.SchemaUpdate{
+				{
+					Predicate: "dgraph.idempotency.key",
+					ValueType: pb.Posting_STRING,
+				},
+				{
+					Predicate: "dgraph.idempotency.result",
+					ValueType: pb.Posting_STRING,
+				},
+				{
+					Predicate: "dgraph.idempotency.expiry",
+					ValueType: pb.Posting_DATETIME,
+				},
+			},
 		})
 
 	if namespace == x.RootNamespace {
@@ -690,6 +787,106 @@ func initialTypesInternal(namespace uint64, all bool) []*pb.TypeUpdate {
 						Predicate: "dgraph.namespace.id",
 						ValueType: pb.Posting_INT,
 					},
+					{
+						Predicate: "dgraph.namespace.sandboxed",
+						ValueType: pb.Posting_BOOL,
+					},
+				},
+			},
+			&pb.TypeUpdate{
+				TypeName: "dgraph.trigger",
+				Fields: []*pb.SchemaUpdate{
+					{
+						Predicate: "dgraph.trigger.name",
+						ValueType: pb.Posting_STRING,
+					},
+					{
+						Predicate: "dgraph.trigger.namespace",
+						ValueType: pb.Posting_INT,
+					},
+					{
+						Predicate: "dgraph.trigger.spec",
+						ValueType: pb.Posting_STRING,
+					},
+				},
+			},
+			&pb.TypeUpdate{
+				TypeName: "dgraph.wasm",
+				Fields: []*pb.SchemaUpdate{
+					{
+						Predicate: "dgraph.wasm.name",
+						ValueType: pb.Posting_STRING,
+					},
+					{
+						Predicate: "dgraph.wasm.namespace",
+						ValueType: pb.Posting_INT,
+					},
+					{
+						Predicate: "dgraph.wasm.code",
+						ValueType: pb.Posting_STRING,
+					},
+				},
+			},
+			&pb.TypeUpdate{
+				TypeName: "dgraph.function",
+				Fields: []*pb.SchemaUpdate{
+					{
+						Predicate: "dgraph.function.name",
+						ValueType: pb.Posting_STRING,
+					},
+					{
+						Predicate: "dgraph.function.namespace",
+						ValueType: pb.Posting_INT,
+					},
+					{
+						Predicate: "dgraph.function.query",
+						ValueType: pb.Posting_STRING,
+					},
+				},
+			})
+	}
+
+	if namespace == x.RootNamespace && (all || x.WorkerConfig.AclEnabled) {
+		initialTypes = append(initialTypes,
+			&pb.TypeUpdate{
+				TypeName: "dgraph.type.ApiKey",
+				Fields: []*pb.SchemaUpdate{
+					{
+						Predicate: "dgraph.apikey.id",
+						ValueType: pb.Posting_STRING,
+					},
+					{
+						Predicate: "dgraph.apikey.name",
+						ValueType: pb.Posting_STRING,
+					},
+					{
+						Predicate: "dgraph.apikey.hash",
+						ValueType: pb.Posting_STRING,
+					},
+					{
+						Predicate: "dgraph.apikey.prev_hash",
+						ValueType: pb.Posting_STRING,
+					},
+					{
+						Predicate: "dgraph.apikey.prev_expiry",
+						ValueType: pb.Posting_INT,
+					},
+					{
+						Predicate: "dgraph.apikey.expiry",
+						ValueType: pb.Posting_INT,
+					},
+					{
+						Predicate: "dgraph.apikey.namespaces",
+						ValueType: pb.Posting_INT,
+					},
+					{
+						Predicate: "dgraph.apikey.permission",
+						ValueType: pb.Posting_INT,
+					},
+					{
+						Predicate: "dgraph.apikey.predicates",
+						ValueType: pb.Posting_STRING,
+					},
 				},
 			})
 	}
@@ -800,6 +997,25 @@ func initialSchemaInternal(namespace uint64, all bool) []*pb.SchemaUpdate {
 			Directive: pb.SchemaUpdate_INDEX,
 			Tokenizer: []string{"sha256"},
 		},
+		// The results of the mutations sent with an idempotency key, kept in the namespace of
+		// the mutations for the idempotency-window.
+		{
+			Predicate: "dgraph.idempotency.key",
+			ValueType: pb.Posting_STRING,
+			Directive: pb.SchemaUpdate_INDEX,
+			Tokenizer: []string{"exact"},
+			Upsert:    true,
+		},
+		{
+			Predicate: "dgraph.idempotency.result",
+			ValueType: pb.Posting_STRING,
+		},
+		{
+			Predicate: "dgraph.idempotency.expiry",
+			ValueType: pb.Posting_DATETIME,
+			Directive: pb.SchemaUpdate_INDEX,
+			Tokenizer: []string{"hour"},
+		},
 	}...)
 
 	if namespace == x.RootNamespace {
@@ -820,6 +1036,114 @@ func initialSchemaInternal(namespace uint64, all bool) []*pb.SchemaUpdate {
 				Unique:    true,
 				Upsert:    true,
 			},
+			// The queries of the sandboxed namespaces run with the caps of --sandbox.
+			{
+				Predicate: "dgraph.namespace.sandboxed",
+				ValueType: pb.Posting_BOOL,
+			},
+			// The triggers of all the namespaces are stored in the root namespace, so that every
+			// Alpha subscribes to their changes.
+			{
+				Predicate: "dgraph.trigger.name",
+				ValueType: pb.Posting_STRING,
+				Directive: pb.SchemaUpdate_INDEX,
+				Tokenizer: []string{"exact"},
+				Upsert:    true,
+			},
+			{
+				Predicate: "dgraph.trigger.namespace",
+				ValueType: pb.Posting_INT,
+				Directive: pb.SchemaUpdate_INDEX,
+				Tokenizer: []string{"int"},
+			},
+			{
+				Predicate: "dgraph.trigger.spec",
+				ValueType: pb.Posting_STRING,
+			},
+			// The wasm modules are stored in the root namespace too, base64 encoded.
+			{
+				Predicate: "dgraph.wasm.name",
+				ValueType: pb.Posting_STRING,
+				Directive: pb.SchemaUpdate_INDEX,
+				Tokenizer: []string{"exact"},
+				Upsert:    true,
+			},
+			{
+				Predicate: "dgraph.wasm.namespace",
+				ValueType: pb.Posting_INT,
+				Directive: pb.SchemaUpdate_INDEX,
+				Tokenizer: []string{"int"},
+			},
+			{
+				Predicate: "dgraph.wasm.code",
+				ValueType: pb.Posting_STRING,
+			},
+			// The stored DQL functions of the namespaces too.
+			{
+				Predicate: "dgraph.function.name",
+				ValueType: pb.Posting_STRING,
+				Directive: pb.SchemaUpdate_INDEX,
+				Tokenizer: []string{"exact"},
+				Upsert:    true,
+			},
+			{
+				Predicate: "dgraph.function.namespace",
+				ValueType: pb.Posting_INT,
+				Directive: pb.SchemaUpdate_INDEX,
+				Tokenizer: []string{"int"},
+			},
+			{
+				Predicate: "dgraph.function.query",
+				ValueType: pb.Posting_STRING,
+			},
+		}...)
+	}
+
+	if namespace == x.RootNamespace && (all || x.WorkerConfig.AclEnabled) {
+		// The API keys are stored in the root namespace, along with the namespaces they can
+		// access. Only the hashes of the secrets are stored.
+		initialSchema = append(initialSchema, []*pb.SchemaUpdate{
+			{
+				Predicate: "dgraph.apikey.id",
+				ValueType: pb.Posting_STRING,
+				Directive: pb.SchemaUpdate_INDEX,
+				Tokenizer: []string{"exact"},
+				Upsert:    true,
+			},
+			{
+				Predicate: "dgraph.apikey.name",
+				ValueType: pb.Posting_STRING,
+			},
+			{
+				Predicate: "dgraph.apikey.hash",
+				ValueType: pb.Posting_STRING,
+			},
+			{
+				Predicate: "dgraph.apikey.prev_hash",
+				ValueType: pb.Posting_STRING,
+			},
+			{
+				Predicate: "dgraph.apikey.prev_expiry",
+				ValueType: pb.Posting_INT,
+			},
+			{
+				Predicate: "dgraph.apikey.expiry",
+				ValueType: pb.Posting_INT,
+			},
+			{
+				Predicate: "dgraph.apikey.namespaces",
+				ValueType: pb.Posting_INT,
+				List:      true,
+			},
+			{
+				Predicate: "dgraph.apikey.permission",
+				ValueType: pb.Posting_INT,
+			},
+			{
+				Predicate: "dgraph.apikey.predicates",
+				ValueType: pb.Posting_STRING,
+				List:      true,
+			},
 		}...)
 	}
 
@@ -28,6 +28,7 @@ import (
 	"github.com/hypermodeinc/dgraph/v25/dgraphapi"
 	"github.com/hypermodeinc/dgraph/v25/dgraphtest"
 	"github.com/hypermodeinc/dgraph/v25/testutil"
+	"github.com/hypermodeinc/dgraph/v25/x"
 )
 
 func (ssuite *SystestTestSuite) TestFacetJsonInputSupportsAnyOfTerms() {
@@ -2020,6 +2021,100 @@ func (ssuite *SystestTestSuite) TestDropTypeNoValue() {
 	require.Contains(t, err.Error(), "DropValue must not be empty")
 }
 
+func (ssuite *SystestTestSuite) TestDropTypeData() {
+	t := ssuite.T()
+
+	// Upgrade
+	ssuite.Upgrade()
+
+	gcli, cleanup, err := doGrpcLogin(ssuite)
+	defer cleanup()
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, gcli.Alter(ctx, &api.Operation{
+		Schema: `
+			name: string @index(exact) .
+			pet: [uid] .
+			species: string .
+
+			type Person {
+				name
+				pet
+			}
+			type Animal {
+				species
+			}
+		`,
+	}))
+	_, err = gcli.NewTxn().Mutate(ctx, &api.Mutation{
+		CommitNow: true,
+		SetNquads: []byte(`
+			_:alice <name> "alice" .
+			_:alice <pet> _:rex .
+			_:alice <dgraph.type> "Person" .
+			_:bob <name> "bob" .
+			_:bob <dgraph.type> "Person" .
+			_:rex <species> "dog" .
+			_:rex <dgraph.type> "Animal" .
+			_:sphinx <name> "sphinx" .
+			_:sphinx <species> "lion" .
+			_:sphinx <dgraph.type> "Person" .
+			_:sphinx <dgraph.type> "Animal" .
+		`),
+	})
+	require.NoError(t, err)
+
+	// A drop of the data can't be scoped to a type.
+	err = gcli.Alter(ctx, &api.Operation{DropOp: api.Operation_DATA, DropValue: "Person"})
+	require.ErrorContains(t, err, "If DropOp is set to DATA, DropValue must be empty")
+
+	hcli, err := ssuite.dc.HTTPClient()
+	require.NoError(t, err)
+	require.NoError(t, hcli.LoginIntoNamespace(dgraphapi.DefaultUser,
+		dgraphapi.DefaultPassword, x.RootNamespace))
+	_, err = hcli.RunGraphqlQuery(dgraphapi.GraphQLParams{
+		Query: `mutation {
+			dropTypeData(input: {type: "Person"}) {
+				id
+			}
+		}`,
+	}, true)
+	require.NoError(t, err)
+
+	// The nodes of the type are deleted in the background.
+	require.Eventually(t, func() bool {
+		resp, err := gcli.NewReadOnlyTxn().Query(ctx, `{ q(func: type(Person)) { uid } }`)
+		require.NoError(t, err)
+		return dgraphapi.CompareJSON(`{"q": []}`, string(resp.Json)) == nil
+	}, time.Minute, time.Second)
+
+	// The fields of the type are deleted, the nodes keep the predicates of their other types.
+	resp, err := gcli.NewReadOnlyTxn().Query(ctx, `{
+		people(func: has(name)) {
+			uid
+		}
+		pets(func: has(pet)) {
+			uid
+		}
+		animals(func: type(Animal), orderasc: species) {
+			species
+			dgraph.type
+		}
+	}`)
+	require.NoError(t, err)
+	require.NoError(t, dgraphapi.CompareJSON(`{"people": [], "pets": [], "animals": [
+		{"species": "dog", "dgraph.type": ["Animal"]},
+		{"species": "lion", "dgraph.type": ["Animal"]}
+	]}`, string(resp.Json)))
+
+	// The type itself is kept.
+	resp, err = gcli.NewReadOnlyTxn().Query(ctx, `schema(type: Person) {}`)
+	require.NoError(t, err)
+	require.NoError(t, dgraphapi.CompareJSON(
+		`{"types":[{"name":"Person", "fields":[{"name":"name"}, {"name":"pet"}]}]}`,
+		string(resp.Json)))
+}
+
 func (ssuite *SystestTestSuite) TestCountIndexConcurrentSetDelUIDList() {
 	t := ssuite.T()
 	dgraphtest.ShouldSkipTest(t, "8631dab37c951b288f839789bbabac5e7088b58f", ssuite.dc.GetVersion())
@@ -0,0 +1,227 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package tok
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// SoundexTokenizer returns the Soundex codes of the words of string data, so that the names
+// sounding alike, like Smith and Smyth, have the same tokens. Like the tokenizers of the plugins,
+// it's queried with anyof and allof: anyof(name, "soundex", "Smith").
+type SoundexTokenizer struct{}
+
+func (t SoundexTokenizer) Name() string { return "soundex" }
+func (t SoundexTokenizer) Type() string { return "string" }
+func (t SoundexTokenizer) Tokens(v interface{}) ([]string, error) {
+	return phoneticTokens(v, soundex)
+}
+func (t SoundexTokenizer) Identifier() byte { return IdentSoundex }
+
+// MetaphoneTokenizer returns the Metaphone codes of the words of string data. Metaphone knows more
+// of the English pronunciation than Soundex, like Jon and John or Smith and Smyth sounding alike,
+// but not Smith and Snyth. It's queried with anyof and allof: anyof(name, "metaphone", "John").
+type MetaphoneTokenizer struct{}
+
+func (t MetaphoneTokenizer) Name() string { return "metaphone" }
+func (t MetaphoneTokenizer) Type() string { return "string" }
+func (t MetaphoneTokenizer) Tokens(v interface{}) ([]string, error) {
+	return phoneticTokens(v, metaphone)
+}
+func (t MetaphoneTokenizer) Identifier() byte { return IdentMetaphone }
+
+// phoneticTokens returns the codes of the words of the value, keeping their letters from A to Z.
+func phoneticTokens(v interface{}, code func(word string) string) ([]string, error) {
+	value, ok := v.(string)
+	if !ok {
+		return nil, errors.Errorf("Phonetic indices only supported for string types")
+	}
+	var tokens []string
+	words := strings.FieldsFunc(strings.ToUpper(value), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		word = strings.Map(func(r rune) rune {
+			if r < 'A' || r > 'Z' {
+				return -1
+			}
+			return r
+		}, word)
+		if word == "" {
+			continue
+		}
+		if c := code(word); c != "" {
+			tokens = append(tokens, c)
+		}
+	}
+	return x.RemoveDuplicates(tokens), nil
+}
+
+// soundexCodes are the Soundex digits of the letters from A to Z. The vowels, H, W and Y are 0.
+const soundexCodes = "01230120022455012623010202"
+
+// soundex returns the Soundex code of the uppercase word, its first letter followed by the digits
+// of the next consonants, skipping the consonants of the same digit as the previous one unless a
+// vowel is in between.
+func soundex(word string) string {
+	code := []byte{word[0]}
+	last := soundexCodes[word[0]-'A']
+	for i := 1; i < len(word) && len(code) < 4; i++ {
+		c := word[i]
+		if c == 'H' || c == 'W' {
+			// Unlike the vowels, they don't separate the consonants of the same digit.
+			continue
+		}
+		d := soundexCodes[c-'A']
+		if d != '0' && d != last {
+			code = append(code, d)
+		}
+		last = d
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
+
+// metaphone returns the Metaphone code of the uppercase word, as described by Lawrence Philips in
+// Hanging on the Metaphone, Computer Language, December 1990. The code has the consonants, and the
+// first vowel, of the word as they're pronounced, 0 being the sound of TH and X of SH.
+func metaphone(word string) string {
+	switch {
+	case hasAnyPrefix(word, "AE", "GN", "KN", "PN", "WR"):
+		word = word[1:]
+	case word[0] == 'X':
+		word = "S" + word[1:]
+	case strings.HasPrefix(word, "WH"):
+		word = "W" + word[2:]
+	}
+
+	n := len(word)
+	at := func(i int) byte {
+		if i < 0 || i >= n {
+			return 0
+		}
+		return word[i]
+	}
+	isVowel := func(c byte) bool { return c != 0 && strings.IndexByte("AEIOU", c) >= 0 }
+	isFrontVowel := func(c byte) bool { return c != 0 && strings.IndexByte("EIY", c) >= 0 }
+
+	var code strings.Builder
+	for i := range n {
+		c := word[i]
+		if c == at(i-1) && c != 'C' {
+			continue
+		}
+		next := at(i + 1)
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			if i == 0 {
+				code.WriteByte(c)
+			}
+		case 'B':
+			// B is silent after M at the end, like in dumb.
+			if at(i-1) != 'M' || i != n-1 {
+				code.WriteByte('B')
+			}
+		case 'C':
+			switch {
+			case next == 'I' && at(i+2) == 'A':
+				code.WriteByte('X')
+			case next == 'H' && at(i-1) == 'S':
+				code.WriteByte('K')
+			case next == 'H':
+				code.WriteByte('X')
+			case isFrontVowel(next):
+				// C is silent in SCI, SCE and SCY.
+				if at(i-1) != 'S' {
+					code.WriteByte('S')
+				}
+			default:
+				code.WriteByte('K')
+			}
+		case 'D':
+			if next == 'G' && isFrontVowel(at(i+2)) {
+				code.WriteByte('J')
+			} else {
+				code.WriteByte('T')
+			}
+		case 'G':
+			switch {
+			case next == 'H' && i+2 < n && !isVowel(at(i+2)):
+				// G is silent in GH, unless it's at the end or before a vowel, like in night.
+			case next == 'N' && (i+2 == n || word[i+2:] == "ED"):
+				// G is silent in GN and GNED at the end, like in sign.
+			case isFrontVowel(next):
+				code.WriteByte('J')
+			default:
+				code.WriteByte('K')
+			}
+		case 'H':
+			// H is silent after CSPTG, and when it isn't before a vowel.
+			if strings.IndexByte("CSPTG", at(i-1)) < 0 && isVowel(next) {
+				code.WriteByte('H')
+			}
+		case 'K':
+			if at(i-1) != 'C' {
+				code.WriteByte('K')
+			}
+		case 'P':
+			if next == 'H' {
+				code.WriteByte('F')
+			} else {
+				code.WriteByte('P')
+			}
+		case 'Q':
+			code.WriteByte('K')
+		case 'S':
+			if next == 'H' || (next == 'I' && (at(i+2) == 'O' || at(i+2) == 'A')) {
+				code.WriteByte('X')
+			} else {
+				code.WriteByte('S')
+			}
+		case 'T':
+			switch {
+			case next == 'I' && (at(i+2) == 'O' || at(i+2) == 'A'):
+				code.WriteByte('X')
+			case next == 'H':
+				code.WriteByte('0')
+			case next == 'C' && at(i+2) == 'H':
+				// T is silent in TCH, like in witch.
+			default:
+				code.WriteByte('T')
+			}
+		case 'V':
+			code.WriteByte('F')
+		case 'W', 'Y':
+			if isVowel(next) {
+				code.WriteByte(c)
+			}
+		case 'X':
+			code.WriteString("KS")
+		case 'Z':
+			code.WriteByte('S')
+		default:
+			// F, J, L, M, N and R.
+			code.WriteByte(c)
+		}
+	}
+	return code.String()
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
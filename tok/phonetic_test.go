@@ -0,0 +1,53 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package tok
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoundex(t *testing.T) {
+	for word, code := range map[string]string{
+		"ROBERT": "R163", "RUPERT": "R163", "ASHCRAFT": "A261", "TYMCZAK": "T522",
+		"PFISTER": "P236", "HONEYMAN": "H555", "LI": "L000",
+	} {
+		require.Equal(t, code, soundex(word), word)
+	}
+}
+
+func TestMetaphone(t *testing.T) {
+	for word, code := range map[string]string{
+		"JOHN": "JN", "JON": "JN", "SMITH": "SM0", "SMYTH": "SM0", "THOMAS": "0MS",
+		"KNIGHT": "NT", "PHILIP": "FLP", "CATHERINE": "K0RN", "KATHRYN": "K0RN",
+		"SCHOOL": "SKL", "WITCH": "WX", "XAVIER": "SFR", "ANDREW": "ANTR",
+	} {
+		require.Equal(t, code, metaphone(word), word)
+	}
+}
+
+func TestPhoneticTokenizers(t *testing.T) {
+	for _, name := range []string{"soundex", "metaphone"} {
+		tokenizer, has := GetTokenizer(name)
+		require.True(t, has)
+		_, ok := tokenizer.(CustomTokenizer)
+		require.True(t, ok)
+
+		tokens, err := BuildTokens("Jon Smyth, jon", tokenizer)
+		require.NoError(t, err)
+		require.Len(t, tokens, 2)
+		expected, err := BuildTokens("John Smith", tokenizer)
+		require.NoError(t, err)
+		require.Equal(t, expected, tokens)
+
+		tokens, err = BuildTokens("42 -", tokenizer)
+		require.NoError(t, err)
+		require.Empty(t, tokens)
+		_, err = BuildTokens(42, tokenizer)
+		require.Error(t, err)
+	}
+}
@@ -29,7 +29,7 @@ import (
 
 // Tokenizer identifiers are unique and can't be reused.
 // The range 0x00 - 0x7f is system reserved.
-// The range 0x80 - 0xff is for custom tokenizers.
+// The range 0x80 - 0xff is for the custom tokenizers of plugins.
 // TODO: use these everywhere where we must ensure a system tokenizer.
 const (
 	IdentNone      = 0x0
@@ -51,6 +51,10 @@ const (
 	IdentBigFloat  = 0xD
 	IdentVFloat    = 0xE
 	IdentNGram     = 0xF
+	IdentSoundex   = 0x10
+	IdentMetaphone = 0x11
+	IdentUint64    = 0x12
+	IdentBytes     = 0x13
 	IdentCustom    = 0x80
 	IdentDelimiter = 0x1f // ASCII 31 - Unit separator
 )
@@ -104,6 +108,11 @@ func init() {
 	registerTokenizer(FullTextTokenizer{})
 	registerTokenizer(NGramTokenizer{})
 	registerTokenizer(Sha256Tokenizer{})
+	registerTokenizer(Uint64Tokenizer{})
+	registerTokenizer(BytesTokenizer{})
+	// The phonetic tokenizers are queried like the custom tokenizers, with anyof and allof.
+	registerTokenizer(CustomTokenizer{PluginTokenizer: SoundexTokenizer{}})
+	registerTokenizer(CustomTokenizer{PluginTokenizer: MetaphoneTokenizer{}})
 	setupBleve()
 }
 
@@ -145,11 +154,29 @@ func LoadCustomTokenizer(soFile string) {
 	// telling the user what went wrong. Otherwise it's hard to capture this
 	// information to pass on to the user.
 	tokenizer := symb.(func() interface{})().(PluginTokenizer)
+	x.Check(RegisterPluginTokenizer(tokenizer))
+}
 
+// RegisterPluginTokenizer registers the custom tokenizer of a plugin, loaded from a Go plugin file
+// or served by an out-of-process plugin. Its name and identifier must not be used by another
+// tokenizer.
+func RegisterPluginTokenizer(tokenizer PluginTokenizer) error {
 	id := tokenizer.Identifier()
-	x.AssertTruef(id >= IdentCustom,
-		"custom tokenizer identifier byte must be >= 0x80, but was %#x", id)
-	registerTokenizer(CustomTokenizer{PluginTokenizer: tokenizer})
+	if id < IdentCustom {
+		return errors.Errorf("custom tokenizer identifier byte must be >= 0x80, but was %#x", id)
+	}
+	if _, ok := tokenizers[tokenizer.Name()]; ok {
+		return errors.Errorf("Duplicate tokenizer: %s", tokenizer.Name())
+	}
+	if t, ok := GetTokenizerByID(id); ok {
+		return errors.Errorf("custom tokenizer %s has the identifier byte %#x of tokenizer %s",
+			tokenizer.Name(), id, t.Name())
+	}
+	if _, ok := types.TypeForName(tokenizer.Type()); !ok {
+		return errors.Errorf("Invalid type %q for tokenizer %s", tokenizer.Type(), tokenizer.Name())
+	}
+	tokenizers[tokenizer.Name()] = CustomTokenizer{PluginTokenizer: tokenizer}
+	return nil
 }
 
 // GetTokenizerByID tries to find a tokenizer by id in the registered list.
@@ -285,6 +312,33 @@ func (t IntTokenizer) Identifier() byte { return IdentInt }
 func (t IntTokenizer) IsSortable() bool { return true }
 func (t IntTokenizer) IsLossy() bool    { return false }
 
+// Uint64Tokenizer generates tokens from unsigned 64-bit integer data.
+type Uint64Tokenizer struct{}
+
+func (t Uint64Tokenizer) Name() string { return "uint64" }
+func (t Uint64Tokenizer) Type() string { return "uint64" }
+func (t Uint64Tokenizer) Tokens(v interface{}) ([]string, error) {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v.(uint64))
+	return []string{string(buf)}, nil
+}
+func (t Uint64Tokenizer) Identifier() byte { return IdentUint64 }
+func (t Uint64Tokenizer) IsSortable() bool { return true }
+func (t Uint64Tokenizer) IsLossy() bool    { return false }
+
+// BytesTokenizer generates tokens from raw bytes. The tokens are the bytes themselves, so they
+// sort in the lexicographic order of the values.
+type BytesTokenizer struct{}
+
+func (t BytesTokenizer) Name() string { return "bytes" }
+func (t BytesTokenizer) Type() string { return "bytes" }
+func (t BytesTokenizer) Tokens(v interface{}) ([]string, error) {
+	return []string{string(v.([]byte))}, nil
+}
+func (t BytesTokenizer) Identifier() byte { return IdentBytes }
+func (t BytesTokenizer) IsSortable() bool { return true }
+func (t BytesTokenizer) IsLossy() bool    { return false }
+
 // FloatTokenizer generates tokens from floating-point data.
 type FloatTokenizer struct{}
 
@@ -676,8 +730,8 @@ type PluginTokenizer interface {
 	Identifier() byte
 }
 
-// CustomTokenizer generates tokens from custom logic.
-// It doesn't make sense for plugins to implement the IsSortable and IsLossy methods,
+// CustomTokenizer generates tokens from custom logic, of a plugin or of a built-in tokenizer like
+// soundex. It doesn't make sense for plugins to implement the IsSortable and IsLossy methods,
 // so they're hard-coded.
 type CustomTokenizer struct{ PluginTokenizer }
 
@@ -59,6 +59,35 @@ func TestIntEncoding(t *testing.T) {
 	}
 }
 
+func TestUint64Tokenizer(t *testing.T) {
+	tokenizer, has := GetTokenizer("uint64")
+	require.True(t, has)
+	require.False(t, tokenizer.IsLossy())
+
+	// The tokens sort in the order of the values, including above the int64 range.
+	vals := []uint64{0, 1, 255, 1 << 32, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64}
+	var prev string
+	for i, v := range vals {
+		tokens, err := BuildTokens(v, tokenizer)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(tokens))
+		require.Equal(t, 1+8, len(tokens[0]))
+		if i > 0 {
+			require.True(t, prev < tokens[0], "%d vs %d", vals[i-1], v)
+		}
+		prev = tokens[0]
+	}
+}
+
+func TestBytesTokenizer(t *testing.T) {
+	tokenizer, has := GetTokenizer("bytes")
+	require.True(t, has)
+
+	tokens, err := BuildTokens([]byte{0x00, 0xff}, tokenizer)
+	require.NoError(t, err)
+	require.Equal(t, []string{encodeToken("\x00\xff", IdentBytes)}, tokens)
+}
+
 func TestFullTextTokenizer(t *testing.T) {
 	tokenizer, has := GetTokenizer("fulltext")
 	require.True(t, has)
@@ -7,6 +7,7 @@ package types
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"math"
@@ -162,6 +163,13 @@ func Convert(from Val, toID TypeID) (Val, error) {
 					return to, errors.Errorf("invalid data for vector of floats: %v", data)
 				}
 				*res = BytesAsFloatArray(data)
+			case Uint64ID:
+				if len(data) < 8 {
+					return to, errors.Errorf("invalid data for uint64 %v", data)
+				}
+				*res = binary.LittleEndian.Uint64(data)
+			case BytesID:
+				*res = data
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -228,6 +236,18 @@ func Convert(from Val, toID TypeID) (Val, error) {
 					return to, err
 				}
 				*res = vf
+			case Uint64ID:
+				val, err := ParseUint64(vc)
+				if err != nil {
+					return to, err
+				}
+				*res = val
+			case BytesID:
+				val, err := base64.StdEncoding.DecodeString(vc)
+				if err != nil {
+					return to, errors.Wrapf(err, "while decoding %q as base64 bytes", vc)
+				}
+				*res = val
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -259,6 +279,11 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				*res = time.Unix(vc, 0).UTC()
 			case VFloatID:
 				*res = []float32{float32(vc)}
+			case Uint64ID:
+				if vc < 0 {
+					return to, errors.Errorf("Int %d out of uint64 range", vc)
+				}
+				*res = uint64(vc)
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -294,6 +319,11 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				fracSecs := floatSecs - float64(secs)
 				nsecs := int64(fracSecs * nanoSecondsInSec)
 				*res = time.Unix(secs, nsecs).UTC()
+			case Uint64ID:
+				if t.Sign() < 0 || t.Cmp(new(big.Float).SetUint64(math.MaxUint64)) > 0 {
+					return to, errors.Errorf("BigFloat %s out of uint64 range", t.String())
+				}
+				*res, _ = t.Uint64()
 
 			default:
 				return to, cantConvert(fromID, toID)
@@ -334,6 +364,11 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				*res = time.Unix(secs, nsecs).UTC()
 			case VFloatID:
 				*res = []float32{float32(vc)}
+			case Uint64ID:
+				if vc >= math.MaxUint64 || vc < 0 || math.IsNaN(vc) {
+					return to, errors.Errorf("Float out of uint64 range")
+				}
+				*res = uint64(vc)
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -378,6 +413,11 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				}
 			case StringID, DefaultID:
 				*res = strconv.FormatBool(vc)
+			case Uint64ID:
+				*res = uint64(0)
+				if vc {
+					*res = uint64(1)
+				}
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -472,12 +512,64 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				return to, cantConvert(fromID, toID)
 			}
 		}
+	case Uint64ID:
+		{
+			if len(data) < 8 {
+				return to, errors.Errorf("invalid data for uint64 %v", data)
+			}
+			vc := binary.LittleEndian.Uint64(data)
+			switch toID {
+			case Uint64ID:
+				*res = vc
+			case BinaryID:
+				var bs [8]byte
+				binary.LittleEndian.PutUint64(bs[:], vc)
+				*res = bs[:]
+			case IntID:
+				if vc > math.MaxInt64 {
+					return to, errors.Errorf("Uint64 %d out of int64 range", vc)
+				}
+				*res = int64(vc)
+			case FloatID:
+				*res = float64(vc)
+			case BigFloatID:
+				var b big.Float
+				b.SetPrec(BigFloatPrecision).SetUint64(vc)
+				*res = b
+			case BoolID:
+				*res = vc != 0
+			case StringID, DefaultID:
+				*res = strconv.FormatUint(vc, 10)
+			default:
+				return to, cantConvert(fromID, toID)
+			}
+		}
+	case BytesID:
+		{
+			switch toID {
+			case BytesID, BinaryID:
+				*res = data
+			case StringID, DefaultID:
+				*res = base64.StdEncoding.EncodeToString(data)
+			default:
+				return to, cantConvert(fromID, toID)
+			}
+		}
 	default:
 		return to, cantConvert(fromID, toID)
 	}
 	return to, nil
 }
 
+// ParseUint64 parses an unsigned 64-bit integer given in decimal, or in hexadecimal with the 0x
+// prefix.
+func ParseUint64(s string) (uint64, error) {
+	if len(s) > 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		return strconv.ParseUint(s[2:], 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
 func Marshal(from Val, to *Val) error {
 	if to == nil {
 		return errors.Errorf("invalid conversion %s to nil", from.Tid.Name())
@@ -627,6 +719,28 @@ func Marshal(from Val, to *Val) error {
 		default:
 			return cantConvert(fromID, toID)
 		}
+	case Uint64ID:
+		vc := val.(uint64)
+		switch toID {
+		case StringID, DefaultID:
+			*res = strconv.FormatUint(vc, 10)
+		case BinaryID:
+			var bs [8]byte
+			binary.LittleEndian.PutUint64(bs[:], vc)
+			*res = bs[:]
+		default:
+			return cantConvert(fromID, toID)
+		}
+	case BytesID:
+		vc := val.([]byte)
+		switch toID {
+		case StringID, DefaultID:
+			*res = base64.StdEncoding.EncodeToString(vc)
+		case BinaryID:
+			*res = vc
+		default:
+			return cantConvert(fromID, toID)
+		}
 	default:
 		return cantConvert(fromID, toID)
 	}
@@ -707,6 +821,20 @@ func ObjectValue(id TypeID, value interface{}) (*api.Value, error) {
 			return def, err
 		}
 		return &api.Value{Val: &api.Value_Vfloat32Val{Vfloat32Val: vf}}, nil
+	// There is no api.Value for the unsigned integers, the decimal string is converted to
+	// uint64 by the schema of the predicate.
+	case Uint64ID:
+		var v uint64
+		if v, ok = value.(uint64); !ok {
+			return def, errors.Errorf("Expected value of type uint64. Got : %v", value)
+		}
+		return &api.Value{Val: &api.Value_DefaultVal{DefaultVal: strconv.FormatUint(v, 10)}}, nil
+	case BytesID:
+		var v []byte
+		if v, ok = value.([]byte); !ok {
+			return def, errors.Errorf("Expected value of type []byte. Got : %v", value)
+		}
+		return &api.Value{Val: &api.Value_BytesVal{BytesVal: v}}, nil
 	default:
 		return def, errors.Errorf("ObjectValue not available for: %v", id)
 	}
@@ -744,6 +872,11 @@ func (v Val) MarshalJSON() ([]byte, error) {
 		return json.Marshal(v.Safe().(string))
 	case PasswordID:
 		return json.Marshal(v.Value.(string))
+	case Uint64ID:
+		return json.Marshal(v.Value.(uint64))
+	case BytesID:
+		// []byte is marshalled as a base64 string.
+		return json.Marshal(v.Value.([]byte))
 	}
 	return nil, errors.Errorf("invalid type for MarshalJSON: %v", v.Tid)
 }
@@ -708,6 +708,91 @@ func TestConvertStringToInt(t *testing.T) {
 	}
 }
 
+func TestConvertStringToUint64(t *testing.T) {
+	tests := []struct {
+		in      string
+		out     uint64
+		failure string
+	}{
+		{in: "0", out: 0},
+		{in: "18446744073709551615", out: math.MaxUint64},
+		{in: "0xff", out: 255},
+		{in: "0XDEADBEEF", out: 0xdeadbeef},
+		{
+			in:      "-1",
+			failure: `strconv.ParseUint: parsing "-1": invalid syntax`,
+		},
+		{
+			in:      "18446744073709551616",
+			failure: `strconv.ParseUint: parsing "18446744073709551616": value out of range`,
+		},
+	}
+
+	for _, tc := range tests {
+		out, err := Convert(Val{Tid: StringID, Value: []byte(tc.in)}, Uint64ID)
+		if tc.failure != "" {
+			require.EqualError(t, err, tc.failure)
+			continue
+		}
+		require.NoError(t, err)
+		require.EqualValues(t, Val{Tid: Uint64ID, Value: tc.out}, out)
+
+		// The stored binary value converts back to the same uint64.
+		b := ValueForType(BinaryID)
+		require.NoError(t, Marshal(out, &b))
+		back, err := Convert(Val{Tid: Uint64ID, Value: b.Value}, Uint64ID)
+		require.NoError(t, err)
+		require.EqualValues(t, out, back)
+	}
+}
+
+func TestConvertUint64(t *testing.T) {
+	var max [8]byte
+	binary.LittleEndian.PutUint64(max[:], math.MaxUint64)
+
+	out, err := Convert(Val{Tid: Uint64ID, Value: max[:]}, StringID)
+	require.NoError(t, err)
+	require.EqualValues(t, Val{Tid: StringID, Value: "18446744073709551615"}, out)
+	out, err = Convert(Val{Tid: Uint64ID, Value: max[:]}, FloatID)
+	require.NoError(t, err)
+	require.EqualValues(t, Val{Tid: FloatID, Value: float64(math.MaxUint64)}, out)
+	_, err = Convert(Val{Tid: Uint64ID, Value: max[:]}, IntID)
+	require.EqualError(t, err, "Uint64 18446744073709551615 out of int64 range")
+
+	out, err = Convert(Val{Tid: IntID, Value: bs(int64(42))}, Uint64ID)
+	require.NoError(t, err)
+	require.EqualValues(t, Val{Tid: Uint64ID, Value: uint64(42)}, out)
+	_, err = Convert(Val{Tid: IntID, Value: bs(int64(-42))}, Uint64ID)
+	require.EqualError(t, err, "Int -42 out of uint64 range")
+	_, err = Convert(Val{Tid: FloatID, Value: bs(float64(-1.5))}, Uint64ID)
+	require.EqualError(t, err, "Float out of uint64 range")
+}
+
+func TestConvertBytes(t *testing.T) {
+	raw := []byte{0x00, 0xff, 0x10, 'a'}
+
+	out, err := Convert(Val{Tid: StringID, Value: []byte("AP8QYQ==")}, BytesID)
+	require.NoError(t, err)
+	require.EqualValues(t, Val{Tid: BytesID, Value: raw}, out)
+	_, err = Convert(Val{Tid: DefaultID, Value: []byte("not base64!")}, BytesID)
+	require.Error(t, err)
+
+	// The bytes are stored as they are, and read back as base64.
+	b := ValueForType(BinaryID)
+	require.NoError(t, Marshal(out, &b))
+	require.Equal(t, raw, b.Value)
+	out, err = Convert(Val{Tid: BytesID, Value: raw}, StringID)
+	require.NoError(t, err)
+	require.EqualValues(t, Val{Tid: StringID, Value: "AP8QYQ=="}, out)
+
+	js, err := Val{Tid: BytesID, Value: raw}.MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, `"AP8QYQ=="`, string(js))
+	js, err = Val{Tid: Uint64ID, Value: uint64(math.MaxUint64)}.MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, "18446744073709551615", string(js))
+}
+
 func TestConvertStringToFloat(t *testing.T) {
 	tests := []struct {
 		in      string
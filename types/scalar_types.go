@@ -62,6 +62,10 @@ const (
 	UndefinedID = TypeID(100)
 	// BigFloatID represents the arbitrary precision type.
 	BigFloatID = TypeID(pb.Posting_BIGFLOAT)
+	// Uint64ID represents the unsigned 64-bit integer type.
+	Uint64ID = TypeID(pb.Posting_UINT64)
+	// BytesID represents raw bytes, given and returned in base64.
+	BytesID = TypeID(pb.Posting_BYTES)
 )
 
 var typeNameMap = map[string]TypeID{
@@ -77,6 +81,8 @@ var typeNameMap = map[string]TypeID{
 	"password":      PasswordID,
 	"bigfloat":      BigFloatID,
 	"float32vector": VFloatID,
+	"uint64":        Uint64ID,
+	"bytes":         BytesID,
 }
 
 // TypeID represents the type of the data.
@@ -114,6 +120,10 @@ func (t TypeID) Name() string {
 		return "bigfloat"
 	case VFloatID:
 		return "float32vector"
+	case Uint64ID:
+		return "uint64"
+	case BytesID:
+		return "bytes"
 	}
 	return ""
 }
@@ -205,6 +215,12 @@ func ValueForType(id TypeID) Val {
 	case VFloatID:
 		var v []float32
 		return Val{VFloatID, &v}
+	case Uint64ID:
+		var u uint64
+		return Val{Uint64ID, &u}
+	case BytesID:
+		var b []byte
+		return Val{BytesID, &b}
 	default:
 		return Val{}
 	}
@@ -6,6 +6,7 @@
 package types
 
 import (
+	"bytes"
 	"math/big"
 	"sort"
 	"time"
@@ -84,7 +85,7 @@ func (s byValue) Less(i, j int) bool {
 // IsSortable returns true, if tid is sortable. Otherwise it returns false.
 func IsSortable(tid TypeID) bool {
 	switch tid {
-	case DateTimeID, IntID, FloatID, StringID, DefaultID, BigFloatID:
+	case DateTimeID, IntID, FloatID, StringID, DefaultID, BigFloatID, Uint64ID, BytesID:
 		return true
 	default:
 		return false
@@ -177,7 +178,7 @@ func Less(a, b Val) (bool, error) {
 	}
 	typ := a.Tid
 	switch typ {
-	case DateTimeID, UidID, IntID, FloatID, StringID, DefaultID, BigFloatID:
+	case DateTimeID, UidID, IntID, FloatID, StringID, DefaultID, BigFloatID, Uint64ID, BytesID:
 		// Don't do anything, we can sort values of this type.
 	default:
 		return false, errors.Errorf("Compare not supported for type: %v", a.Tid)
@@ -196,8 +197,10 @@ func less(a, b Val, cl *collate.Collator) bool {
 		return (a.Value.(int64)) < (b.Value.(int64))
 	case FloatID:
 		return (a.Value.(float64)) < (b.Value.(float64))
-	case UidID:
+	case UidID, Uint64ID:
 		return (a.Value.(uint64) < b.Value.(uint64))
+	case BytesID:
+		return bytes.Compare(a.Value.([]byte), b.Value.([]byte)) < 0
 	case StringID, DefaultID:
 		// Use language comparator.
 		if cl != nil {
@@ -238,7 +241,8 @@ func Equal(a, b Val) (bool, error) {
 	}
 	typ := a.Tid
 	switch typ {
-	case DateTimeID, IntID, FloatID, StringID, DefaultID, BoolID, BigFloatID:
+	case DateTimeID, IntID, FloatID, StringID, DefaultID, BoolID, BigFloatID, Uint64ID,
+		BytesID:
 		// Don't do anything, we can sort values of this type.
 	default:
 		return false, errors.Errorf("Equal not supported for type: %v", a.Tid)
@@ -275,6 +279,14 @@ func equal(a, b Val) bool {
 		aVal := a.Value.(big.Float)
 		bVal := b.Value.(big.Float)
 		return aVal.Cmp(&bVal) == 0
+	case Uint64ID:
+		aVal, aOk := a.Value.(uint64)
+		bVal, bOk := b.Value.(uint64)
+		return aOk && bOk && aVal == bVal
+	case BytesID:
+		aVal, aOk := a.Value.([]byte)
+		bVal, bOk := b.Value.([]byte)
+		return aOk && bOk && bytes.Equal(aVal, bVal)
 	}
 	return false
 }
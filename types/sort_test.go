@@ -167,6 +167,25 @@ func TestSortInts(t *testing.T) {
 		toString(t, list, IntID))
 }
 
+func TestSortUint64s(t *testing.T) {
+	list := getInput(t, Uint64ID, []string{"18446744073709551615", "9223372036854775808", "0", "42"})
+	ul := getUIDList(4)
+	require.NoError(t, Sort(list, &ul.Uids, []bool{false}, ""))
+	require.EqualValues(t, []uint64{300, 400, 200, 100}, ul.Uids)
+	require.EqualValues(t, []string{"0", "42", "9223372036854775808", "18446744073709551615"},
+		toString(t, list, Uint64ID))
+}
+
+func TestSortBytes(t *testing.T) {
+	// 0x01, 0x00 0x02, 0xff and 0x00 0x01.
+	list := getInput(t, BytesID, []string{"AQ==", "AAI=", "/w==", "AAE="})
+	ul := getUIDList(4)
+	require.NoError(t, Sort(list, &ul.Uids, []bool{false}, ""))
+	require.EqualValues(t, []uint64{400, 200, 100, 300}, ul.Uids)
+	require.EqualValues(t, []string{"AAE=", "AAI=", "AQ==", "/w=="},
+		toString(t, list, BytesID))
+}
+
 func TestSortFloats(t *testing.T) {
 	list := getInput(t, FloatID, []string{"22.2", "11.2", "11.5", "2.12"})
 	ul := getUIDList(4)
@@ -0,0 +1,97 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package types
+
+import (
+	"container/heap"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// TopK keeps the first k values added to it in ascending, or descending, order with their uids.
+// Unlike SortTopN, the values are kept in a heap of k values as they are added, so that the first
+// values of a list can be found without having all of its values, or sorting them.
+type TopK struct {
+	k int
+	h topKHeap
+}
+
+// topKHeap has the last of the first k values at its root, the one replaced by a value before it.
+type topKHeap struct {
+	desc bool
+	cl   *collate.Collator
+	vals []Val
+	uids []uint64
+}
+
+// NewTopK returns an empty TopK of the k first values, compared in the language lang.
+func NewTopK(k int, desc bool, lang string) *TopK {
+	t := &TopK{k: k, h: topKHeap{desc: desc}}
+	if lang != "" {
+		// Collator is nil if we are unable to parse the language.
+		// We default to bytewise comparison in that case.
+		if langTag, err := language.Parse(lang); err == nil {
+			t.h.cl = collate.New(langTag)
+		}
+	}
+	return t
+}
+
+// before returns whether the value and the uid come before the ith ones. Equal values are in the
+// order of their uids.
+func (h *topKHeap) before(v Val, uid uint64, i int) bool {
+	if !equal(v, h.vals[i]) {
+		return less(v, h.vals[i], h.cl) != h.desc
+	}
+	return uid < h.uids[i]
+}
+
+func (h *topKHeap) Len() int           { return len(h.vals) }
+func (h *topKHeap) Less(i, j int) bool { return h.before(h.vals[j], h.uids[j], i) }
+func (h *topKHeap) Swap(i, j int) {
+	h.vals[i], h.vals[j] = h.vals[j], h.vals[i]
+	h.uids[i], h.uids[j] = h.uids[j], h.uids[i]
+}
+
+// Push is a no-op, the values are appended before heap.Push.
+func (h *topKHeap) Push(x interface{}) {}
+func (h *topKHeap) Pop() interface{} {
+	h.vals, h.uids = h.vals[:len(h.vals)-1], h.uids[:len(h.uids)-1]
+	return nil
+}
+
+// Add adds the value of the uid, if it's one of the first k values added so far.
+func (t *TopK) Add(v Val, uid uint64) error {
+	if !IsSortable(v.Tid) {
+		return errors.Errorf("Value of type: %s isn't sortable", v.Tid.Name())
+	}
+	h := &t.h
+	if len(h.vals) < t.k {
+		h.vals, h.uids = append(h.vals, v), append(h.uids, uid)
+		heap.Push(h, nil)
+		return nil
+	}
+	if t.k == 0 || !h.before(v, uid, 0) {
+		return nil
+	}
+	h.vals[0], h.uids[0] = v, uid
+	heap.Fix(h, 0)
+	return nil
+}
+
+// Sorted returns the first k values added, sorted, with their uids. The TopK is empty afterwards.
+func (t *TopK) Sorted() ([]Val, []uint64) {
+	h := &t.h
+	n := len(h.vals)
+	vals, uids := make([]Val, n), make([]uint64, n)
+	for i := n - 1; i >= 0; i-- {
+		vals[i], uids[i] = h.vals[0], h.uids[0]
+		heap.Pop(h)
+	}
+	return vals, uids
+}
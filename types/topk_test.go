@@ -0,0 +1,53 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package types
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopK(t *testing.T) {
+	n, k := 1000, 10
+	vals := make([]int64, n)
+	for i := range vals {
+		vals[i] = rand.Int63n(100)
+	}
+
+	for _, desc := range []bool{false, true} {
+		topK := NewTopK(k, desc, "")
+		for i, v := range vals {
+			require.NoError(t, topK.Add(Val{Tid: IntID, Value: v}, uint64(i+1)))
+		}
+		gotVals, gotUids := topK.Sorted()
+
+		uids := make([]uint64, n)
+		for i := range uids {
+			uids[i] = uint64(i + 1)
+		}
+		sort.SliceStable(uids, func(i, j int) bool {
+			a, b := vals[uids[i]-1], vals[uids[j]-1]
+			if desc {
+				return a > b
+			}
+			return a < b
+		})
+		require.Equal(t, uids[:k], gotUids)
+		for i, v := range gotVals {
+			require.Equal(t, vals[gotUids[i]-1], v.Value)
+		}
+	}
+
+	topK := NewTopK(5, false, "")
+	require.NoError(t, topK.Add(Val{Tid: StringID, Value: "b"}, 1))
+	require.NoError(t, topK.Add(Val{Tid: StringID, Value: "a"}, 2))
+	_, uids := topK.Sorted()
+	require.Equal(t, []uint64{2, 1}, uids)
+	require.Error(t, topK.Add(Val{Tid: GeoID}, 3))
+}
@@ -19,6 +19,11 @@
 //  3. Add that function to change_list.go inside the changes for the change set introduced in
 //     that version. Also add a short name and some meaningful description with it.
 //
+// Besides the changes applied through a running cluster, the tool migrates the postings of a
+// stopped Alpha to the current data format (see postings.go), and guides rolling upgrades one
+// instance at a time (see rolling.go). When the data format changes, bump x.DataFormatVersion
+// and add the migration from the previous format to migrateKey and migrateValue.
+//
 // Points to keep in mind:
 //  1. Upgrade is expected only for breaking changes which go in as part of the breaking releases.
 //  2. Look at the upgrade algorithm in upgrade.go to understand how & when a change is applied.
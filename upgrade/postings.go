@@ -0,0 +1,196 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package upgrade
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/dgraph-io/badger/v4"
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// upgradePostings detects the data format of the postings in dir, and migrates them in place to
+// the current data format if they're in an older one. The migrated postings are written to a new
+// directory which replaces dir once they're complete, and the old postings are kept next to it.
+// The Alpha using dir must be stopped.
+func upgradePostings(dir string, encKey x.Sensitive, dryRun bool) error {
+	db, err := openPostings(dir, encKey)
+	if err != nil {
+		return err
+	}
+	format, err := x.DetectDataFormat(db)
+	if err != nil {
+		_ = db.Close()
+		return errors.Wrapf(err, "while detecting the data format of %s", dir)
+	}
+	fmt.Printf("The postings in %s are in the data format of %s.\n", dir,
+		x.DataFormatName(format))
+	if format == x.DataFormatVersion || dryRun {
+		if format != x.DataFormatVersion {
+			fmt.Printf("They would be migrated to the data format of %s.\n",
+				x.DataFormatName(x.DataFormatVersion))
+		}
+		return db.Close()
+	}
+
+	tmpDir := dir + ".upgrading"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		_ = db.Close()
+		return err
+	}
+	out, err := openPostings(tmpDir, encKey)
+	if err != nil {
+		_ = db.Close()
+		return err
+	}
+	err = migratePostings(db, out, format)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "while migrating the postings of %s", dir)
+	}
+
+	backupDir := fmt.Sprintf("%s.format-%d", dir, format)
+	if err := os.Rename(dir, backupDir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return err
+	}
+	fmt.Printf("Migrated the postings to the data format of %s. The old postings are kept in %s,"+
+		" and can be removed once the upgraded Alpha is running fine.\n",
+		x.DataFormatName(x.DataFormatVersion), backupDir)
+	return nil
+}
+
+func openPostings(dir string, encKey x.Sensitive) (*badger.DB, error) {
+	opt := badger.DefaultOptions(dir).
+		WithNumVersionsToKeep(math.MaxInt32).
+		WithEncryptionKey(encKey).
+		WithLogger(nil)
+	db, err := badger.OpenManaged(opt)
+	return db, errors.Wrapf(err, "while opening the postings in %s", dir)
+}
+
+// migratePostings writes all the versions of the postings of in to out, migrated from format to
+// the current data format.
+func migratePostings(in, out *badger.DB, format int) error {
+	sw := out.NewStreamWriter()
+	if err := sw.Prepare(); err != nil {
+		return err
+	}
+
+	stream := in.NewStreamAt(math.MaxUint64)
+	stream.LogPrefix = "Migrating postings"
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		newKey, err := migrateKey(key, format)
+		if err != nil {
+			return nil, err
+		}
+		list := &bpb.KVList{}
+		for ; itr.Valid(); itr.Next() {
+			item := itr.Item()
+			if !bytes.Equal(item.Key(), key) || item.IsDeletedOrExpired() {
+				break
+			}
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return nil, err
+			}
+			if val, err = migrateValue(newKey, val, format); err != nil {
+				return nil, errors.Wrapf(err, "while migrating the value of key %x", key)
+			}
+			list.Kv = append(list.Kv, &bpb.KV{
+				Key:       newKey,
+				Value:     val,
+				UserMeta:  []byte{item.UserMeta()},
+				Version:   item.Version(),
+				ExpiresAt: item.ExpiresAt(),
+			})
+			if item.DiscardEarlierVersions() {
+				break
+			}
+		}
+		return list, nil
+	}
+	stream.Send = func(buf *z.Buffer) error {
+		return sw.Write(buf)
+	}
+	if err := stream.Orchestrate(context.Background()); err != nil {
+		return err
+	}
+	return sw.Flush()
+}
+
+// migrateKey adds the root namespace to the keys of the data format v0, which had no namespace.
+// Adding the namespace keeps the keys sorted, as they all get the same namespace.
+func migrateKey(key []byte, format int) ([]byte, error) {
+	if format != x.DataFormatV0 {
+		return key, nil
+	}
+	if len(key) < 3 || int(binary.BigEndian.Uint16(key[1:3]))+3 > len(key) {
+		return nil, errors.Errorf("invalid key %x", key)
+	}
+	newKey := make([]byte, 0, len(key)+8)
+	newKey = append(newKey, key[0])
+	newKey = append(newKey, x.NamespaceToBytes(x.RootNamespace)...)
+	return append(newKey, key[1:]...), nil
+}
+
+// migrateValue migrates the predicates stored in the values of the schema and the types. The
+// values of the other keys didn't change.
+func migrateValue(key, val []byte, format int) ([]byte, error) {
+	pk, err := x.Parse(key)
+	if err != nil {
+		return nil, err
+	}
+	migrateAttr := func(attr string) (string, error) {
+		if format == x.DataFormatV0 {
+			return x.AttrInRootNamespace(attr), nil
+		}
+		return x.AttrFrom2103(attr)
+	}
+
+	switch {
+	case pk.IsSchema():
+		var update pb.SchemaUpdate
+		if err := proto.Unmarshal(val, &update); err != nil {
+			return nil, err
+		}
+		// The attribute of the key is the one of the current format.
+		update.Predicate = pk.Attr
+		return proto.Marshal(&update)
+	case pk.IsType():
+		var update pb.TypeUpdate
+		if err := proto.Unmarshal(val, &update); err != nil {
+			return nil, err
+		}
+		update.TypeName = pk.Attr
+		for _, field := range update.Fields {
+			if field.Predicate, err = migrateAttr(field.Predicate); err != nil {
+				return nil, err
+			}
+		}
+		return proto.Marshal(&update)
+	default:
+		return val, nil
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package upgrade
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// v0Key returns a key in the data format v0, without a namespace.
+func v0Key(prefix byte, attr string, rest ...byte) []byte {
+	key := []byte{prefix, 0, 0}
+	binary.BigEndian.PutUint16(key[1:], uint16(len(attr)))
+	key = append(key, attr...)
+	return append(key, rest...)
+}
+
+func writePostings(t *testing.T, dir string, entries map[string][]byte, schemaKeys [][]byte) {
+	db, err := openPostings(dir, nil)
+	require.NoError(t, err)
+	txn := db.NewTransactionAt(10, true)
+	for key, val := range entries {
+		e := badger.NewEntry([]byte(key), val)
+		for _, k := range schemaKeys {
+			if string(k) == key {
+				e.UserMeta = posting.BitSchemaPosting
+			}
+		}
+		require.NoError(t, txn.SetEntry(e))
+	}
+	require.NoError(t, txn.CommitAt(10, nil))
+	require.NoError(t, db.Close())
+}
+
+func marshal(t *testing.T, m proto.Message) []byte {
+	b, err := proto.Marshal(m)
+	require.NoError(t, err)
+	return b
+}
+
+func checkMigratedPostings(t *testing.T, dir string) {
+	db, err := openPostings(dir, nil)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+
+	format, err := x.DetectDataFormat(db)
+	require.NoError(t, err)
+	require.Equal(t, x.DataFormatVersion, format)
+
+	txn := db.NewTransactionAt(10, false)
+	defer txn.Discard()
+	read := func(key []byte) []byte {
+		item, err := txn.Get(key)
+		require.NoError(t, err)
+		require.Equal(t, uint64(10), item.Version())
+		val, err := item.ValueCopy(nil)
+		require.NoError(t, err)
+		return val
+	}
+
+	require.Equal(t, []byte("alice"), read(x.DataKey(x.AttrInRootNamespace("name"), 1)))
+	var su pb.SchemaUpdate
+	require.NoError(t, proto.Unmarshal(read(x.SchemaKey(x.AttrInRootNamespace("name"))), &su))
+	require.Equal(t, x.AttrInRootNamespace("name"), su.Predicate)
+	require.Equal(t, pb.Posting_STRING, su.ValueType)
+	var tu pb.TypeUpdate
+	require.NoError(t, proto.Unmarshal(read(x.TypeKey(x.AttrInRootNamespace("Person"))), &tu))
+	require.Equal(t, x.AttrInRootNamespace("Person"), tu.TypeName)
+	require.Equal(t, x.AttrInRootNamespace("name"), tu.Fields[0].Predicate)
+}
+
+func TestUpgradePostingsV0(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "p")
+	schemaKey := v0Key(x.ByteSchema, "name")
+	typeKey := v0Key(x.ByteType, "Person")
+	writePostings(t, dir, map[string][]byte{
+		string(v0Key(x.DefaultPrefix, "name", x.ByteData, 0, 0, 0, 0, 0, 0, 0, 1)): []byte("alice"),
+		string(schemaKey): marshal(t, &pb.SchemaUpdate{Predicate: "name",
+			ValueType: pb.Posting_STRING}),
+		string(typeKey): marshal(t, &pb.TypeUpdate{TypeName: "Person",
+			Fields: []*pb.SchemaUpdate{{Predicate: "name"}}}),
+	}, [][]byte{schemaKey, typeKey})
+
+	// A dry run only detects the data format.
+	require.NoError(t, upgradePostings(dir, nil, true))
+	require.NoDirExists(t, dir+".format-0")
+
+	require.NoError(t, upgradePostings(dir, nil, false))
+	require.DirExists(t, dir+".format-0")
+	require.NoDirExists(t, dir+".upgrading")
+	checkMigratedPostings(t, dir)
+
+	// The postings in the current data format are left as they are.
+	require.NoError(t, upgradePostings(dir, nil, false))
+	require.NoDirExists(t, dir+".format-2105")
+}
+
+func TestUpgradePostingsV2103(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "p")
+	ns := strings.Repeat("\x00", 8)
+	schemaKey := x.SchemaKey(x.AttrInRootNamespace("name"))
+	typeKey := x.TypeKey(x.AttrInRootNamespace("Person"))
+	writePostings(t, dir, map[string][]byte{
+		string(x.DataKey(x.AttrInRootNamespace("name"), 1)): []byte("alice"),
+		string(schemaKey): marshal(t, &pb.SchemaUpdate{Predicate: ns + "name",
+			ValueType: pb.Posting_STRING}),
+		string(typeKey): marshal(t, &pb.TypeUpdate{TypeName: ns + "Person",
+			Fields: []*pb.SchemaUpdate{{Predicate: ns + "name"}}}),
+	}, [][]byte{schemaKey, typeKey})
+
+	require.NoError(t, upgradePostings(dir, nil, false))
+	require.DirExists(t, dir+".format-2103")
+	checkMigratedPostings(t, dir)
+}
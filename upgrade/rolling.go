@@ -0,0 +1,244 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package upgrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// clusterQuery reads the instances of the cluster, along with the version they run.
+const clusterQuery = `query {
+	health {
+		instance
+		address
+		status
+		version
+		group
+	}
+	state {
+		zeros {
+			addr
+			leader
+		}
+		groups {
+			id
+			members {
+				addr
+				leader
+			}
+		}
+	}
+}`
+
+// rollingPollInterval is how often the cluster is read while waiting for an instance to be
+// upgraded.
+const rollingPollInterval = 5 * time.Second
+
+type clusterMember struct {
+	Addr   string `json:"addr"`
+	Leader bool   `json:"leader"`
+}
+
+type clusterState struct {
+	Health []struct {
+		Instance string `json:"instance"`
+		Address  string `json:"address"`
+		Status   string `json:"status"`
+		Version  string `json:"version"`
+		Group    string `json:"group"`
+	} `json:"health"`
+	State struct {
+		Zeros  []clusterMember `json:"zeros"`
+		Groups []struct {
+			ID      uint64          `json:"id"`
+			Members []clusterMember `json:"members"`
+		} `json:"groups"`
+	} `json:"state"`
+}
+
+// instance is an instance of the cluster to upgrade.
+type instance struct {
+	kind   string // either alpha or zero
+	addr   string
+	group  uint64
+	leader bool
+}
+
+func (i *instance) String() string {
+	role := "follower"
+	if i.leader {
+		role = "leader"
+	}
+	if i.kind == "zero" {
+		return fmt.Sprintf("zero %s (%s)", i.addr, role)
+	}
+	return fmt.Sprintf("alpha %s (group %d, %s)", i.addr, i.group, role)
+}
+
+// upgradePlan returns the order in which the instances of the cluster are upgraded. The Zeros are
+// upgraded before the Alphas, and the followers of every group are upgraded before its leader, so
+// that the leadership only moves once per group, to an upgraded instance.
+func upgradePlan(cs *clusterState) []*instance {
+	var plan []*instance
+	addGroup := func(kind string, group uint64, members []clusterMember) {
+		sorted := append([]clusterMember{}, members...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if sorted[i].Leader != sorted[j].Leader {
+				return !sorted[i].Leader
+			}
+			return sorted[i].Addr < sorted[j].Addr
+		})
+		for _, m := range sorted {
+			plan = append(plan, &instance{kind: kind, addr: m.Addr, group: group, leader: m.Leader})
+		}
+	}
+
+	addGroup("zero", 0, cs.State.Zeros)
+	groups := cs.State.Groups
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	for _, g := range groups {
+		addGroup("alpha", g.ID, g.Members)
+	}
+	return plan
+}
+
+// instanceVersion returns the version reported by the instance, and whether it's healthy.
+func (cs *clusterState) instanceVersion(addr string) (string, bool) {
+	for _, h := range cs.Health {
+		if h.Address == addr {
+			return h.Version, h.Status == "healthy"
+		}
+	}
+	return "", false
+}
+
+// runsVersion returns true if the reported version is the version v.
+func runsVersion(reported string, v *version) bool {
+	parsed, err := parseVersionFromString(reported)
+	return err == nil && parsed.Compare(v) == equal
+}
+
+// checkUpgradable checks that none of the instances runs a version newer than the version to
+// upgrade to, as the instances can't be downgraded.
+func checkUpgradable(cs *clusterState, to *version) error {
+	for _, h := range cs.Health {
+		parsed, err := parseVersionFromString(h.Version)
+		if err != nil {
+			// It's a development build, which can be upgraded to any version.
+			continue
+		}
+		if parsed.Compare(to) == greater {
+			return errors.Errorf("%s %s runs %s, which is newer than %s", h.Instance, h.Address,
+				h.Version, to)
+		}
+	}
+	return nil
+}
+
+func fetchCluster() (*clusterState, error) {
+	jwt, err := getAccessJwt()
+	if err != nil {
+		return nil, errors.Wrapf(err, "while logging in")
+	}
+	header := http.Header{}
+	header.Set("X-Dgraph-AuthToken", Upgrade.Conf.GetString(authToken))
+	if jwt.AccessJwt != "" {
+		header.Set("X-Dgraph-AccessToken", jwt.AccessJwt)
+	}
+	resp, err := makeGqlRequest(&GraphQLParams{Query: clusterQuery, Headers: header},
+		Upgrade.Conf.GetString(alphaHttp)+"/admin")
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, errors.Errorf("while reading the cluster: %s", resp.Errors.Error())
+	}
+	var cs clusterState
+	if err := json.Unmarshal(resp.Data, &cs); err != nil {
+		return nil, err
+	}
+	return &cs, nil
+}
+
+// rollingUpgrade guides the upgrade of the cluster to the version to, one instance at a time, so
+// that the cluster keeps serving requests while the instances run different versions. The
+// instances of different versions check while connecting that they use the same data format.
+// As the tool can't restart the instances, it tells which instance to restart next, and waits
+// until the instance runs the new version and the cluster is healthy again before going on.
+func rollingUpgrade(to *version, wait time.Duration, dryRun bool) error {
+	cs, err := fetchCluster()
+	if err != nil {
+		return err
+	}
+	if err := checkUpgradable(cs, to); err != nil {
+		return err
+	}
+
+	plan := upgradePlan(cs)
+	fmt.Println("**********************************************")
+	fmt.Println("Rolling upgrade to version:", to)
+	fmt.Println("**********************************************")
+	for i, inst := range plan {
+		v, _ := cs.instanceVersion(inst.addr)
+		fmt.Printf("%d. %s, running %s\n", i+1, inst, v)
+	}
+	if dryRun {
+		return nil
+	}
+
+	for i, inst := range plan {
+		if v, _ := cs.instanceVersion(inst.addr); runsVersion(v, to) {
+			fmt.Printf("\n%d. %s already runs %s.\n", i+1, inst, to)
+			continue
+		}
+		fmt.Printf("\n%d. Upgrade %s now: stop it, ", i+1, inst)
+		if inst.kind == "alpha" {
+			fmt.Print("run `dgraph upgrade --postings <its p directory>`, ")
+		}
+		fmt.Printf("and start it again with the binary of %s and the same flags.\n", to)
+
+		deadline := time.Now().Add(wait)
+		for {
+			time.Sleep(rollingPollInterval)
+			cs, err = fetchCluster()
+			if err == nil && clusterUpgraded(cs, plan[:i+1], to) {
+				break
+			}
+			if time.Now().After(deadline) {
+				if err == nil {
+					err = errors.Errorf("%s doesn't run %s, or the cluster isn't healthy", inst, to)
+				}
+				return errors.Wrapf(err, "timed out after %s waiting for %s", wait, inst)
+			}
+		}
+		fmt.Printf("\t%s now runs %s.\n", inst, to)
+	}
+	fmt.Println("\nRolling upgrade finished!")
+	return nil
+}
+
+// clusterUpgraded returns true if the instances run the version to, and all the instances of the
+// cluster are healthy.
+func clusterUpgraded(cs *clusterState, upgraded []*instance, to *version) bool {
+	for _, h := range cs.Health {
+		if !strings.EqualFold(h.Status, "healthy") {
+			return false
+		}
+	}
+	for _, inst := range upgraded {
+		if v, healthy := cs.instanceVersion(inst.addr); !healthy || !runsVersion(v, to) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package upgrade
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testCluster = `{
+	"health": [
+		{"instance": "zero", "address": "zero1:5080", "status": "healthy", "version": "v24.1.0"},
+		{"instance": "alpha", "address": "alpha1:7080", "status": "healthy", "version": "v24.1.0"},
+		{"instance": "alpha", "address": "alpha2:7080", "status": "healthy", "version": "v25.0.0"},
+		{"instance": "alpha", "address": "alpha3:7080", "status": "healthy", "version": "v24.1.0"}
+	],
+	"state": {
+		"zeros": [{"addr": "zero1:5080", "leader": true}],
+		"groups": [
+			{"id": 2, "members": [{"addr": "alpha3:7080", "leader": true}]},
+			{"id": 1, "members": [
+				{"addr": "alpha1:7080", "leader": true},
+				{"addr": "alpha2:7080", "leader": false}
+			]}
+		]
+	}
+}`
+
+func TestUpgradePlan(t *testing.T) {
+	var cs clusterState
+	require.NoError(t, json.Unmarshal([]byte(testCluster), &cs))
+
+	var plan []string
+	for _, inst := range upgradePlan(&cs) {
+		plan = append(plan, inst.String())
+	}
+	require.Equal(t, []string{
+		"zero zero1:5080 (leader)",
+		"alpha alpha2:7080 (group 1, follower)",
+		"alpha alpha1:7080 (group 1, leader)",
+		"alpha alpha3:7080 (group 2, leader)",
+	}, plan)
+
+	to := &version{major: 25, minor: 0, patch: 0}
+	require.NoError(t, checkUpgradable(&cs, to))
+	require.Error(t, checkUpgradable(&cs, &version{major: 24, minor: 1, patch: 0}))
+
+	upgraded := upgradePlan(&cs)
+	require.False(t, clusterUpgraded(&cs, upgraded[:1], to))
+	require.True(t, clusterUpgraded(&cs, upgraded[1:2], to))
+	cs.Health[0].Status = "unhealthy"
+	require.False(t, clusterUpgraded(&cs, upgraded[1:2], to))
+
+	require.True(t, runsVersion("v25.0.0-rc1", to))
+	require.False(t, runsVersion("dev", to))
+	require.False(t, runsVersion("v25", to))
+}
@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -41,6 +42,9 @@ const (
 	deleteOld = "deleteOld"
 	from      = "from"
 	to        = "to"
+	postings  = "postings"
+	rolling   = "rolling"
+	wait      = "wait"
 
 	versionFmtBeforeCalVer = "v%d.%d.%d"
 	versionFmtAfterCalVer  = "v%d.%02d.%d"
@@ -134,7 +138,11 @@ func init() {
 		Use:   "upgrade",
 		Short: "Run the Dgraph upgrade tool",
 		Long: "This tool is supported only for the mainstream release versions of Dgraph, " +
-			"not for the beta releases.",
+			"not for the beta releases.\n\n" +
+			"With --postings, it migrates the postings of a stopped Alpha in place to the data " +
+			"format of this version.\n" +
+			"With --rolling, it upgrades the cluster to the --to version one instance at a " +
+			"time, waiting for every instance to be upgraded before telling which one is next.",
 		Run: func(cmd *cobra.Command, args []string) {
 			run()
 		},
@@ -159,11 +167,46 @@ func init() {
 	flag.BoolP(deleteOld, "d", true, "Delete the older ACL types/predicates")
 	flag.StringP(from, "f", "", "The version string from which to upgrade, e.g.: v1.2.2")
 	flag.StringP(to, "t", "", "The version string till which to upgrade, e.g.: v20.03.0")
+	flag.String(postings, "", "Directory of the postings of a stopped Alpha, to migrate in "+
+		"place to the data format of this version. The other flags are ignored, except "+
+		"--dry-run and --encryption")
+	flag.Bool(rolling, false, "Upgrade the cluster to the --to version one instance at a time")
+	flag.Duration(wait, 30*time.Minute, "How long --rolling waits for each instance to be "+
+		"upgraded")
+
+	x.RegisterEncFlag(flag)
 
 	x.RegisterClientTLSFlags(flag)
 }
 
 func run() {
+	switch {
+	case Upgrade.Conf.GetString(postings) != "":
+		keys, err := x.GetEncAclKeys(Upgrade.Conf)
+		if err == nil {
+			err = upgradePostings(Upgrade.Conf.GetString(postings), keys.EncKey,
+				Upgrade.Conf.GetBool(dryRun))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	case Upgrade.Conf.GetBool(rolling):
+		toVersion, err := parseVersionFromString(Upgrade.Conf.GetString(to))
+		if err == nil {
+			err = rollingUpgrade(toVersion, Upgrade.Conf.GetDuration(wait),
+				Upgrade.Conf.GetBool(dryRun))
+		} else {
+			err = formatAsFlagParsingError(to, err)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	cmdInput, err := validateAndParseInput()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -220,6 +263,9 @@ func parseVersionFromString(v string) (*version, error) {
 	}
 
 	versionSplit := strings.Split(v[1:], ".")
+	if len(versionSplit) < 3 {
+		return nil, fmt.Errorf("version must have a major, minor and patch version. E.g.: v1.2.2")
+	}
 	result := &version{}
 	var err error
 
@@ -0,0 +1,111 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package wasm
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// ErrTimeout is returned by the calls running for longer than their timeout.
+var ErrTimeout = errors.New("the wasm function exceeded its CPU limit")
+
+// Limits bound the resources used by an instance.
+type Limits struct {
+	// Timeout is the time each call can run for, or zero for no limit.
+	Timeout time.Duration
+	// MemoryPages is the maximum number of pages of the linear memory.
+	MemoryPages uint32
+}
+
+// Instance is an instance of a module, with its own memory and globals. It isn't safe for
+// concurrent use, and it must be closed.
+type Instance struct {
+	m      *Module
+	limits Limits
+	mod    api.Module
+}
+
+// Instantiate creates an instance of the module, initializing its memory, globals and table, and
+// running its start function.
+func (m *Module) Instantiate(limits Limits) (*Instance, error) {
+	rt := m.runtime(limits.MemoryPages)
+	if rt.err != nil {
+		return nil, errors.Wrapf(rt.err, "the wasm module doesn't fit in the limit of %d pages "+
+			"of memory", limits.MemoryPages)
+	}
+	ctx, cancel := limits.context()
+	defer cancel()
+	// The instances have no name, so that a module can be instantiated more than once.
+	mod, err := rt.r.InstantiateModule(ctx, rt.compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return nil, errors.Wrapf(callError(err), "while instantiating the wasm module")
+	}
+	return &Instance{m: m, limits: limits, mod: mod}, nil
+}
+
+func (l Limits) context() (context.Context, context.CancelFunc) {
+	if l.Timeout > 0 {
+		return context.WithTimeout(context.Background(), l.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Call calls the exported function with the arguments, returning its results. The values of type
+// i32 and i64 are their bits, and the ones of type f32 and f64 the bits of their IEEE 754
+// representation. An instance can still be called after a trap, but not after a timeout.
+func (in *Instance) Call(name string, args ...uint64) ([]uint64, error) {
+	typ, ok := in.m.exports[name]
+	if !ok {
+		return nil, errors.Errorf("the wasm module doesn't export a function named %q", name)
+	}
+	if len(args) != len(typ.Params) {
+		return nil, errors.Errorf("the wasm function %s takes %d arguments, but got %d", name,
+			len(typ.Params), len(args))
+	}
+	for i, t := range typ.Params {
+		if t == I32 || t == F32 {
+			args[i] = uint64(uint32(args[i]))
+		}
+	}
+	ctx, cancel := in.limits.context()
+	defer cancel()
+	res, err := in.mod.ExportedFunction(name).Call(ctx, args...)
+	if err != nil {
+		return nil, errors.Wrapf(callError(err), "while calling the wasm function %s", name)
+	}
+	for i, t := range typ.Results {
+		if t == I32 || t == F32 {
+			res[i] = uint64(uint32(res[i]))
+		}
+	}
+	return res, nil
+}
+
+// Close releases the instance.
+func (in *Instance) Close() error {
+	return in.mod.Close(context.Background())
+}
+
+// callError returns ErrTimeout for the calls closed at their timeout, and the message of the
+// traps without the stack trace of wazero.
+func callError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var exit *sys.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == sys.ExitCodeDeadlineExceeded {
+		return ErrTimeout
+	}
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	return errors.Errorf("wasm trap: %s", strings.TrimPrefix(msg, "wasm error: "))
+}
@@ -0,0 +1,154 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package wasm runs the user-defined functions of the DQL queries, compiled to WebAssembly.
+//
+// The modules are decoded, validated and run by wazero, with its interpreter, which bounds the
+// time and the memory used by the calls. The modules can't import anything, so their functions
+// can only compute on their arguments, and can't reach the host.
+package wasm
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ValueType is the type of a WebAssembly value.
+type ValueType byte
+
+const (
+	I32 ValueType = ValueType(api.ValueTypeI32)
+	I64 ValueType = ValueType(api.ValueTypeI64)
+	F32 ValueType = ValueType(api.ValueTypeF32)
+	F64 ValueType = ValueType(api.ValueTypeF64)
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case I32:
+		return "i32"
+	case I64:
+		return "i64"
+	case F32:
+		return "f32"
+	case F64:
+		return "f64"
+	}
+	return "unknown"
+}
+
+// FuncType is the signature of a function.
+type FuncType struct {
+	Params  []ValueType
+	Results []ValueType
+}
+
+const (
+	// PageSize is the size of a page of the linear memory.
+	PageSize = 64 << 10
+	// MaxPages is the maximum number of pages of the linear memory.
+	MaxPages = 1 << 16
+)
+
+// Module is a validated WebAssembly module. It's immutable, so that it can be instantiated
+// concurrently.
+type Module struct {
+	code    []byte
+	exports map[string]FuncType
+
+	// runtimes holds the runtimes of the module, by their limit of pages of memory, as wazero
+	// limits the memory of the runtimes. They run on the interpreter of wazero, whose compiled
+	// modules live in the heap, so that they're collected with the module.
+	sync.Mutex
+	runtimes map[uint32]*runtime
+}
+
+type runtime struct {
+	r        wazero.Runtime
+	compiled wazero.CompiledModule
+	// err is the error compiling the module within the memory of the runtime.
+	err error
+}
+
+func newRuntime(code []byte, pages uint32) *runtime {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().
+		WithCoreFeatures(api.CoreFeaturesV2).
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true))
+	compiled, err := r.CompileModule(ctx, code)
+	return &runtime{r: r, compiled: compiled, err: err}
+}
+
+// Compile decodes and validates a WebAssembly module in the binary format. The invalid modules,
+// like the ones whose functions don't type check, are rejected here, before they can run.
+func Compile(code []byte) (*Module, error) {
+	code = append([]byte(nil), code...)
+	rt := newRuntime(code, MaxPages)
+	if rt.err != nil {
+		return nil, errors.Wrapf(rt.err, "invalid wasm module")
+	}
+	c := rt.compiled
+	if len(c.ImportedFunctions()) > 0 || len(c.ImportedMemories()) > 0 {
+		return nil, errors.Errorf("invalid wasm module: the modules can't import anything")
+	}
+
+	m := &Module{
+		code:     code,
+		exports:  make(map[string]FuncType),
+		runtimes: map[uint32]*runtime{MaxPages: rt},
+	}
+	for name, def := range c.ExportedFunctions() {
+		var typ FuncType
+		for _, t := range def.ParamTypes() {
+			typ.Params = append(typ.Params, ValueType(t))
+		}
+		for _, t := range def.ResultTypes() {
+			typ.Results = append(typ.Results, ValueType(t))
+		}
+		for _, t := range append(append([]ValueType(nil), typ.Params...), typ.Results...) {
+			if t.String() == "unknown" {
+				return nil, errors.Errorf("invalid wasm module: the exported function %s has a "+
+					"value of type %s, only i32, i64, f32 and f64 are supported", name,
+					api.ValueTypeName(api.ValueType(t)))
+			}
+		}
+		m.exports[name] = typ
+	}
+	return m, nil
+}
+
+// runtime returns the runtime of the module limiting its memory to the pages.
+func (m *Module) runtime(pages uint32) *runtime {
+	m.Lock()
+	defer m.Unlock()
+	rt, ok := m.runtimes[pages]
+	if !ok {
+		rt = newRuntime(m.code, pages)
+		m.runtimes[pages] = rt
+	}
+	return rt
+}
+
+// Exports returns the names of the exported functions, in order.
+func (m *Module) Exports() []string {
+	names := make([]string, 0, len(m.exports))
+	for name := range m.exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Signature returns the signature of the exported function.
+func (m *Module) Signature(name string) (FuncType, bool) {
+	typ, ok := m.exports[name]
+	return typ, ok
+}
@@ -0,0 +1,378 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package wasm
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// The helpers below assemble modules in the binary format.
+
+func leb(v uint32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		if v >>= 7; v != 0 {
+			b = append(b, c|0x80)
+			continue
+		}
+		return append(b, c)
+	}
+}
+
+func cat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func vec(items ...[]byte) []byte { return cat(leb(uint32(len(items))), cat(items...)) }
+func str(s string) []byte        { return cat(leb(uint32(len(s))), []byte(s)) }
+
+func section(id byte, items ...[]byte) []byte {
+	content := vec(items...)
+	return cat([]byte{id}, leb(uint32(len(content))), content)
+}
+
+func funcType(params, results []byte) []byte {
+	return cat([]byte{0x60}, leb(uint32(len(params))), params, leb(uint32(len(results))), results)
+}
+
+func body(locals []byte, code ...byte) []byte {
+	content := cat(locals, code)
+	return cat(leb(uint32(len(content))), content)
+}
+
+func f64(f float64) []byte { return binary.LittleEndian.AppendUint64(nil, math.Float64bits(f)) }
+
+// sectionOrder is the order of the sections by id, the data count section being between the
+// element and code sections.
+var sectionOrder = [...]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 11, 12, 10}
+
+type fn struct {
+	typ    uint32
+	export string
+	body   []byte
+}
+
+// module assembles a module of the types and functions, with the other sections.
+func module(types [][]byte, funcs []fn, sections ...[]byte) []byte {
+	var funcIdxs, exports, bodies [][]byte
+	for i, f := range funcs {
+		funcIdxs = append(funcIdxs, leb(f.typ))
+		if f.export != "" {
+			exports = append(exports, cat(str(f.export), []byte{0}, leb(uint32(i))))
+		}
+		bodies = append(bodies, f.body)
+	}
+	sections = append(sections, section(1, types...), section(3, funcIdxs...),
+		section(7, exports...), section(10, bodies...))
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sectionOrder[sections[i][0]] < sectionOrder[sections[j][0]]
+	})
+	return cat([]byte("\x00asm\x01\x00\x00\x00"), cat(sections...))
+}
+
+var (
+	i32 = byte(I32)
+	i64 = byte(I64)
+	f   = byte(F64)
+
+	noLimits = Limits{}
+)
+
+func instantiate(t *testing.T, code []byte, limits Limits) *Instance {
+	m, err := Compile(code)
+	require.NoError(t, err)
+	in, err := m.Instantiate(limits)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, in.Close()) })
+	return in
+}
+
+func TestNumeric(t *testing.T) {
+	code := module(
+		[][]byte{
+			funcType([]byte{i32, i32}, []byte{i32}),
+			funcType([]byte{f, f}, []byte{f}),
+			funcType([]byte{i64}, []byte{i64}),
+		},
+		[]fn{
+			{typ: 0, export: "add", body: body(vec(), 0x20, 0, 0x20, 1, 0x6a, 0x0b)},
+			{typ: 0, export: "div", body: body(vec(), 0x20, 0, 0x20, 1, 0x6d, 0x0b)},
+			// a * 0.7 + b * 0.3
+			{typ: 1, export: "score", body: body(vec(), cat(
+				[]byte{0x20, 0, 0x44}, f64(0.7), []byte{0xa2, 0x20, 1, 0x44}, f64(0.3),
+				[]byte{0xa2, 0xa0, 0x0b})...)},
+			// The factorial, with a loop multiplying the accumulator in local 1.
+			{typ: 2, export: "fact", body: body(vec(cat(leb(1), []byte{i64})),
+				0x42, 1, 0x21, 1,
+				0x02, 0x40, 0x03, 0x40,
+				0x20, 0, 0x50, 0x0d, 1,
+				0x20, 1, 0x20, 0, 0x7e, 0x21, 1,
+				0x20, 0, 0x42, 1, 0x7d, 0x21, 0,
+				0x0c, 0, 0x0b, 0x0b,
+				0x20, 1, 0x0b)},
+		})
+	m, err := Compile(code)
+	require.NoError(t, err)
+	require.Equal(t, []string{"add", "div", "fact", "score"}, m.Exports())
+	sig, ok := m.Signature("score")
+	require.True(t, ok)
+	require.Equal(t, FuncType{Params: []ValueType{F64, F64}, Results: []ValueType{F64}}, sig)
+
+	in, err := m.Instantiate(noLimits)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, in.Close()) }()
+	res, err := in.Call("add", 40, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, res)
+	res, err = in.Call("add", math.MaxUint32, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, res)
+	res, err = in.Call("div", uint64(uint32(0xfffffff6)), 3) // -10 / 3
+	require.NoError(t, err)
+	require.Equal(t, int32(-3), int32(res[0]))
+
+	res, err = in.Call("score", math.Float64bits(10), math.Float64bits(20))
+	require.NoError(t, err)
+	require.InDelta(t, 13.0, math.Float64frombits(res[0]), 1e-9)
+
+	res, err = in.Call("fact", 20)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2432902008176640000}, res)
+
+	_, err = in.Call("div", 1, 0)
+	require.ErrorContains(t, err, "integer divide by zero")
+	_, err = in.Call("div", math.MaxInt32+1, math.MaxUint32)
+	require.ErrorContains(t, err, "integer overflow")
+	_, err = in.Call("add", 1)
+	require.ErrorContains(t, err, "takes 2 arguments, but got 1")
+	_, err = in.Call("sub", 1, 2)
+	require.ErrorContains(t, err, `doesn't export a function named "sub"`)
+
+	// The instance can still be called after the traps.
+	res, err = in.Call("add", 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3}, res)
+}
+
+func TestControl(t *testing.T) {
+	code := module(
+		[][]byte{funcType([]byte{i32}, []byte{i32})},
+		[]fn{
+			// The sign of the argument, with nested ifs.
+			{typ: 0, export: "sign", body: body(vec(),
+				0x20, 0, 0x41, 0, 0x48, 0x04, i32,
+				0x41, 0x7f,
+				0x05,
+				0x20, 0, 0x45, 0x04, i32, 0x41, 0, 0x05, 0x41, 1, 0x0b,
+				0x0b, 0x0b)},
+			// 10 for 0, 20 for 1 and 30 for the other arguments, with br_table.
+			{typ: 0, export: "switch", body: body(vec(),
+				0x02, 0x40, 0x02, 0x40, 0x02, 0x40,
+				0x20, 0, 0x0e, 2, 0, 1, 2,
+				0x0b, 0x41, 10, 0x0f,
+				0x0b, 0x41, 20, 0x0f,
+				0x0b, 0x41, 30, 0x0b)},
+		})
+	in := instantiate(t, code, noLimits)
+	for arg, sign := range map[int32]int32{-5: -1, 0: 0, 7: 1} {
+		res, err := in.Call("sign", uint64(uint32(arg)))
+		require.NoError(t, err)
+		require.Equal(t, sign, int32(res[0]), arg)
+	}
+	for arg, val := range map[uint64]uint64{0: 10, 1: 20, 2: 30, 100: 30} {
+		res, err := in.Call("switch", arg)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{val}, res, arg)
+	}
+}
+
+func TestCalls(t *testing.T) {
+	code := module(
+		[][]byte{funcType([]byte{i32}, []byte{i32}), funcType(nil, nil)},
+		[]fn{
+			{typ: 0, body: body(vec(), 0x20, 0, 0x20, 0, 0x6a, 0x0b)},
+			// Doubles the argument with the function of the table.
+			{typ: 0, export: "indirect", body: body(vec(), 0x20, 0, 0x41, 0, 0x11, 0, 0, 0x0b)},
+			// Calls the function of the table with the wrong type.
+			{typ: 1, export: "mismatch", body: body(vec(), 0x41, 0, 0x11, 1, 0, 0x0b)},
+			// Quadruples the argument with direct calls.
+			{typ: 0, export: "direct", body: body(vec(), 0x20, 0, 0x10, 0, 0x10, 0, 0x0b)},
+			{typ: 1, export: "recurse", body: body(vec(), 0x10, 4, 0x0b)},
+		},
+		section(4, []byte{0x70, 0, 2}),
+		section(9, cat(leb(0), []byte{0x41, 0, 0x0b}, vec(leb(0)))))
+	in := instantiate(t, code, noLimits)
+	res, err := in.Call("indirect", 21)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, res)
+	res, err = in.Call("direct", 3)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{12}, res)
+	_, err = in.Call("mismatch")
+	require.ErrorContains(t, err, "indirect call type mismatch")
+	_, err = in.Call("recurse")
+	require.ErrorContains(t, err, "stack overflow")
+}
+
+func TestLimits(t *testing.T) {
+	code := module(
+		[][]byte{funcType(nil, []byte{i32}), funcType([]byte{i32}, []byte{i32}), funcType(nil, nil)},
+		[]fn{
+			{typ: 0, export: "load", body: body(vec(), 0x41, 0, 0x28, 2, 0, 0x0b)},
+			{typ: 1, export: "grow", body: body(vec(), 0x20, 0, 0x40, 0, 0x0b)},
+			{typ: 2, export: "spin", body: body(vec(), 0x03, 0x40, 0x0c, 0, 0x0b, 0x0b)},
+			// Loads past the end of the memory.
+			{typ: 0, export: "outside", body: body(vec(), 0x41, 0, 0x28, 2, 0x80, 0x80, 0x04, 0x0b)},
+		},
+		section(5, []byte{0, 1}),
+		section(11, cat(leb(0), []byte{0x41, 0, 0x0b}, str("\x2a\x00\x00\x00"))))
+	m, err := Compile(code)
+	require.NoError(t, err)
+	_, err = m.Instantiate(Limits{Timeout: time.Second})
+	require.ErrorContains(t, err, "doesn't fit in the limit of 0 pages of memory")
+
+	in, err := m.Instantiate(Limits{Timeout: 100 * time.Millisecond, MemoryPages: 2})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, in.Close()) }()
+	res, err := in.Call("load")
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, res)
+	_, err = in.Call("outside")
+	require.ErrorContains(t, err, "out of bounds memory access")
+
+	res, err = in.Call("grow", 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, res)
+	res, err = in.Call("grow", 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{math.MaxUint32}, res)
+
+	_, err = in.Call("spin")
+	require.True(t, errors.Is(err, ErrTimeout), "%v", err)
+	// The instance is closed at the timeout.
+	_, err = in.Call("load")
+	require.Error(t, err)
+}
+
+func TestCompileErrors(t *testing.T) {
+	valid := module([][]byte{funcType([]byte{i32, i32}, []byte{i32})},
+		[]fn{{typ: 0, export: "add", body: body(vec(), 0x20, 0, 0x20, 1, 0x6a, 0x0b)}})
+	// The truncated modules are invalid unless they end after the header, at 8, or after the type
+	// section, at 17.
+	for i := range valid {
+		_, err := Compile(valid[:i])
+		if i != 8 && i != 17 {
+			require.Error(t, err, i)
+		}
+	}
+
+	noMemory := [][]byte{funcType(nil, []byte{i32})}
+	for _, tc := range []struct {
+		name string
+		code []byte
+		err  string
+	}{
+		{name: "magic", code: []byte("\x00wasm\x01\x00\x00"), err: "invalid magic number"},
+		{name: "version", code: []byte("\x00asm\x02\x00\x00\x00"), err: "invalid version"},
+		{
+			name: "import",
+			code: cat([]byte("\x00asm\x01\x00\x00\x00"),
+				section(1, funcType(nil, nil)),
+				section(2, cat(str("env"), str("log"), []byte{0}, leb(0)))),
+			err: "the modules can't import anything",
+		},
+		{
+			name: "unknown local",
+			code: module([][]byte{funcType(nil, nil)},
+				[]fn{{typ: 0, body: body(vec(), 0x20, 0, 0x1a, 0x0b)}}),
+			err: "invalid local index",
+		},
+		{
+			name: "unknown label",
+			code: module([][]byte{funcType(nil, nil)},
+				[]fn{{typ: 0, body: body(vec(), 0x0c, 1, 0x0b)}}),
+			err: "invalid br operation",
+		},
+		{
+			name: "stack underflow",
+			code: module([][]byte{funcType([]byte{i32, i32}, []byte{i32})},
+				[]fn{{typ: 0, body: body(vec(), 0x20, 0, 0x6a, 0x6a, 0x0b)}}),
+			err: "cannot pop the 2nd operand for i32.add",
+		},
+		{
+			name: "type mismatch",
+			code: module([][]byte{funcType([]byte{i64}, []byte{i32})},
+				[]fn{{typ: 0, body: body(vec(), 0x20, 0, 0x20, 0, 0x6a, 0x0b)}}),
+			err: "type mismatch",
+		},
+		{
+			name: "wrong result",
+			code: module([][]byte{funcType(nil, []byte{i32})},
+				[]fn{{typ: 0, body: body(vec(), 0x42, 1, 0x0b)}}),
+			err: "cannot use i64 as result[0] type i32",
+		},
+		{
+			name: "missing result",
+			code: module(noMemory, []fn{{typ: 0, body: body(vec(), 0x0b)}}),
+			err:  "not enough results",
+		},
+		{
+			name: "memory",
+			code: module(noMemory, []fn{{typ: 0, body: body(vec(), 0x41, 0, 0x28, 2, 0, 0x0b)}}),
+			err:  "memory must exist",
+		},
+		{
+			name: "table",
+			code: module([][]byte{funcType(nil, nil)},
+				[]fn{{typ: 0, body: body(vec(), 0x41, 0, 0x11, 0, 0, 0x0b)}}),
+			err: "table",
+		},
+		{
+			name: "unknown function",
+			code: module([][]byte{funcType(nil, nil)},
+				[]fn{{typ: 0, body: body(vec(), 0x10, 5, 0x0b)}}),
+			err: "invalid function index",
+		},
+		{
+			name: "unknown global",
+			code: module(noMemory, []fn{{typ: 0, body: body(vec(), 0x23, 0, 0x0b)}}),
+			err:  "invalid index for global.get",
+		},
+		{
+			name: "missing end",
+			code: module([][]byte{funcType(nil, nil)}, []fn{{typ: 0, body: body(vec(), 0x01)}}),
+			err:  "end",
+		},
+		{
+			name: "alignment",
+			code: module(noMemory,
+				[]fn{{typ: 0, body: body(vec(), 0x41, 0, 0x28, 3, 0, 0x0b)}},
+				section(5, []byte{1, 0, 1})),
+			err: "invalid memory alignment",
+		},
+		{
+			name: "unknown instruction",
+			code: module([][]byte{funcType(nil, nil)},
+				[]fn{{typ: 0, body: body(vec(), 0xd7, 0x0b)}}),
+			err: "invalid instruction 0xd7",
+		},
+	} {
+		_, err := Compile(tc.code)
+		require.ErrorContains(t, err, tc.err, tc.name)
+	}
+}
@@ -16,6 +16,7 @@ func couldApplyAggregatorOn(agrtr string, typ types.TypeID) bool {
 	switch agrtr {
 	case "min", "max":
 		return typ == types.IntID ||
+			typ == types.Uint64ID ||
 			typ == types.FloatID ||
 			typ == types.DateTimeID ||
 			typ == types.StringID ||
@@ -24,6 +25,11 @@ func couldApplyAggregatorOn(agrtr string, typ types.TypeID) bool {
 		return typ == types.IntID ||
 			typ == types.FloatID ||
 			typ == types.VFloatID
+	case "percentile":
+		return typ == types.IntID ||
+			typ == types.FloatID
+	case "distinct":
+		return true
 	default:
 		return false
 	}
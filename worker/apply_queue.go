@@ -0,0 +1,258 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// applyQueues applies the mutations of the committed proposals in per-predicate queues, so that a
+// predicate receiving heavy writes doesn't hold up the mutations of the other predicates. The
+// mutations of each predicate are applied in the order of the Raft log, and the proposals which
+// depend on the earlier mutations, like commits and schema changes, wait for them to be applied.
+type applyQueues struct {
+	sync.Mutex
+	cond *sync.Cond
+
+	// maxDepth is the number of queued mutations of a predicate from which the new mutations of
+	// the predicate wait before being proposed. Zero disables the queues.
+	maxDepth int
+	queues   map[string][]*queuedMutation
+	// txns counts the queued mutations of each transaction, by start ts.
+	txns   map[uint64]int
+	queued int
+
+	// sem bounds the number of predicates applying their mutations at a time. A channel serves
+	// the goroutines blocked on it in order, so the busy predicates take turns.
+	sem chan struct{}
+}
+
+// queuedMutation holds the edges of a proposal for a single predicate.
+type queuedMutation struct {
+	ctx   context.Context
+	txn   *posting.Txn
+	edges []*pb.DirectedEdge
+	done  func(error)
+}
+
+func newApplyQueues(maxDepth int) *applyQueues {
+	q := &applyQueues{
+		maxDepth: maxDepth,
+		queues:   make(map[string][]*queuedMutation),
+		txns:     make(map[uint64]int),
+		sem:      make(chan struct{}, runtime.NumCPU()),
+	}
+	q.cond = sync.NewCond(&q.Mutex)
+	return q
+}
+
+func (q *applyQueues) enabled() bool {
+	return q.maxDepth > 0
+}
+
+// push queues the mutation of the predicate attr, and starts applying the mutations of the
+// predicate if they weren't being applied already.
+func (q *applyQueues) push(attr string, m *queuedMutation) {
+	q.Lock()
+	defer q.Unlock()
+
+	pending := q.queues[attr]
+	q.queues[attr] = append(pending, m)
+	q.txns[m.txn.StartTs]++
+	q.queued++
+	if len(pending) == 0 {
+		go q.run(attr)
+	}
+}
+
+// run applies the queued mutations of the predicate attr, until its queue is empty.
+func (q *applyQueues) run(attr string) {
+	for {
+		q.Lock()
+		m := q.queues[attr][0]
+		q.Unlock()
+
+		q.sem <- struct{}{}
+		err := applyEdges(m.ctx, m.txn, m.edges)
+		<-q.sem
+		// The mutation must be done before the transaction stops being tracked, so that its
+		// commit finds all of its deltas.
+		m.done(err)
+
+		q.Lock()
+		pending := q.queues[attr][1:]
+		if len(pending) == 0 {
+			delete(q.queues, attr)
+		} else {
+			q.queues[attr] = pending
+		}
+		if q.txns[m.txn.StartTs]--; q.txns[m.txn.StartTs] == 0 {
+			delete(q.txns, m.txn.StartTs)
+		}
+		q.queued--
+		q.cond.Broadcast()
+		q.Unlock()
+
+		if len(pending) == 0 {
+			return
+		}
+	}
+}
+
+// waitTxns blocks until the queued mutations of the transactions with the given start ts are
+// applied.
+func (q *applyQueues) waitTxns(startTs ...uint64) {
+	q.Lock()
+	defer q.Unlock()
+	for _, ts := range startTs {
+		for q.txns[ts] > 0 {
+			q.cond.Wait()
+		}
+	}
+}
+
+// waitAll blocks until all the queued mutations are applied.
+func (q *applyQueues) waitAll() {
+	q.Lock()
+	defer q.Unlock()
+	for q.queued > 0 {
+		q.cond.Wait()
+	}
+}
+
+// admit blocks until the queues of the predicates of the edges are below the maximum depth, so
+// that the mutations of a predicate which can't keep up are held back before being proposed,
+// without holding up the mutations of the other predicates.
+func (q *applyQueues) admit(ctx context.Context, edges []*pb.DirectedEdge) error {
+	if !q.enabled() {
+		return nil
+	}
+	q.Lock()
+	defer q.Unlock()
+	for _, edge := range edges {
+		for len(q.queues[edge.Attr]) >= q.maxDepth {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			q.cond.Wait()
+		}
+	}
+	return nil
+}
+
+// depths returns the number of queued mutations, and the predicate with the deepest queue along
+// with its depth.
+func (q *applyQueues) depths() (queued int, attr string, depth int) {
+	q.Lock()
+	defer q.Unlock()
+	for a, pending := range q.queues {
+		if len(pending) > depth {
+			attr, depth = a, len(pending)
+		}
+	}
+	return q.queued, attr, depth
+}
+
+// queueable returns true if the proposal only has edges to apply, which can be queued to their
+// predicates.
+func queueable(proposal *pb.Proposal) bool {
+	m := proposal.Mutations
+	if m == nil || m.DropOp != pb.Mutations_NONE || len(m.Schema) > 0 || len(m.Types) > 0 ||
+		m.StartTs == 0 || len(m.Edges) == 0 {
+		return false
+	}
+	for _, edge := range m.Edges {
+		if isDropPredicateEdge(edge) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyQueued queues the mutations of the proposal to their predicates, and calls done once they
+// are all applied. It returns false if the proposal must be applied in the order of the Raft log
+// instead, once the queued mutations it depends on are applied.
+func (n *node) applyQueued(proposal *pb.Proposal, key uint64, done func(error)) bool {
+	q := n.applyQueues
+	switch {
+	case proposal.Delta != nil:
+		// A commit only depends on the mutations of its own transactions.
+		var startTs []uint64
+		for _, txn := range proposal.Delta.GetTxns() {
+			startTs = append(startTs, txn.StartTs)
+		}
+		q.waitTxns(startTs...)
+		return false
+	case proposal.Mutations.GetHeartbeat():
+		// A heartbeat doesn't depend on the queued mutations.
+		return false
+	case !q.enabled() || n.witness || !queueable(proposal):
+		q.waitAll()
+		return false
+	}
+
+	m := proposal.Mutations
+	// The mutations of a transaction share its posting lists, so they are applied one proposal
+	// after the other.
+	q.waitTxns(m.StartTs)
+
+	ctx := n.Ctx(key)
+	txn, err := prepareMutations(ctx, proposal)
+	if err != nil {
+		done(err)
+		return true
+	}
+
+	total := len(m.Edges)
+	ostats.Record(ctx, x.ActiveMutations.M(int64(total)))
+
+	// The edges are sorted by predicate, so each predicate gets a contiguous part of them.
+	var parts [][]*pb.DirectedEdge
+	for start := 0; start < len(m.Edges); {
+		end := start + 1
+		for end < len(m.Edges) && m.Edges[end].Attr == m.Edges[start].Attr {
+			end++
+		}
+		parts = append(parts, m.Edges[start:end])
+		start = end
+	}
+
+	var mu sync.Mutex
+	var errs error
+	left := len(parts)
+	partDone := func(err error) {
+		mu.Lock()
+		if err != nil {
+			if errs == nil {
+				errs = errors.New("Got error while running mutation")
+			}
+			errs = errors.Wrap(err, errs.Error())
+		}
+		left--
+		last := left == 0
+		mu.Unlock()
+		if !last {
+			return
+		}
+		// Discard the posting lists from cache to release memory at the end.
+		txn.Update()
+		ostats.Record(ctx, x.ActiveMutations.M(int64(-total)))
+		done(errs)
+	}
+	for _, edges := range parts {
+		q.push(edges[0].Attr, &queuedMutation{ctx: ctx, txn: txn, edges: edges, done: partDone})
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestApplyQueues(t *testing.T) {
+	q := newApplyQueues(2)
+	var mu sync.Mutex
+	var applied []string
+	// The first mutation of the hot predicate blocks until it's released.
+	release := make(chan struct{})
+	push := func(attr string, startTs uint64, block bool) {
+		q.push(attr, &queuedMutation{
+			ctx: context.Background(),
+			txn: &posting.Txn{StartTs: startTs},
+			done: func(err error) {
+				require.NoError(t, err)
+				if block {
+					<-release
+				}
+				mu.Lock()
+				applied = append(applied, attr)
+				mu.Unlock()
+			},
+		})
+	}
+
+	push("hot", 1, true)
+	push("hot", 2, false)
+	queued, attr, depth := q.depths()
+	require.Equal(t, 2, queued)
+	require.Equal(t, "hot", attr)
+	require.Equal(t, 2, depth)
+
+	// The mutations of the other predicates don't wait for the hot predicate.
+	push("cold", 3, false)
+	q.waitTxns(3)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.NoError(t, q.admit(ctx, []*pb.DirectedEdge{{Attr: "cold"}}))
+
+	// The new mutations of the hot predicate are held back.
+	admitted := make(chan error, 1)
+	go func() {
+		admitted <- q.admit(context.Background(), []*pb.DirectedEdge{{Attr: "hot"}})
+	}()
+	select {
+	case <-admitted:
+		t.Fatal("The mutation of the hot predicate shouldn't be admitted")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-admitted)
+	q.waitAll()
+	require.Equal(t, []string{"cold", "hot", "hot"}, applied)
+	queued, _, depth = q.depths()
+	require.Zero(t, queued)
+	require.Zero(t, depth)
+}
+
+func TestQueueable(t *testing.T) {
+	edges := []*pb.DirectedEdge{{Attr: "name", Entity: 1, Value: []byte("alice")}}
+	require.True(t, queueable(&pb.Proposal{Mutations: &pb.Mutations{StartTs: 1, Edges: edges}}))
+	require.False(t, queueable(&pb.Proposal{Delta: &pb.OracleDelta{}}))
+	require.False(t, queueable(&pb.Proposal{Mutations: &pb.Mutations{StartTs: 1, Edges: edges,
+		Schema: []*pb.SchemaUpdate{{Predicate: "name"}}}}))
+	require.False(t, queueable(&pb.Proposal{Mutations: &pb.Mutations{StartTs: 1,
+		Edges: []*pb.DirectedEdge{{Attr: "name", Value: []byte(x.Star)}}}}))
+	require.False(t, queueable(&pb.Proposal{Mutations: &pb.Mutations{StartTs: 1,
+		DropOp: pb.Mutations_DATA}}))
+}
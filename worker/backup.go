@@ -160,6 +160,12 @@ func backupCurrentGroup(ctx context.Context, req *pb.BackupRequest) (*pb.BackupR
 	}
 	defer closer.Done()
 
+	for _, pred := range req.Predicates {
+		if err := tiers().hot(ctx, pred, false); err != nil {
+			return nil, err
+		}
+	}
+
 	bp := NewBackupProcessor(pstore, req)
 	defer bp.Close()
 
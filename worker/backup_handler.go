@@ -108,7 +108,7 @@ type UriHandler interface {
 //
 // Target URI parts:
 //
-//	scheme - service handler, one of: "file", "s3", "minio"
+//	scheme - service handler, one of: "file", "s3", "minio", "gs"
 //	  host - remote address. ex: "dgraph.s3.amazonaws.com"
 //	  path - directory, bucket or container at target. ex: "/dgraph/backups/"
 //	  args - specific arguments that are ok to appear in logs.
@@ -124,13 +124,14 @@ type UriHandler interface {
 //
 //	s3://dgraph.s3.amazonaws.com/dgraph/backups?secure=true
 //	minio://localhost:9000/dgraph?secure=true
+//	gs:///dgraph/backups
 //	file:///tmp/dgraph/backups
 //	/tmp/dgraph/backups?compress=gzip
 func NewUriHandler(uri *url.URL, creds *x.MinioCredentials) (UriHandler, error) {
 	switch uri.Scheme {
 	case "file", "":
 		return NewFileHandler(uri), nil
-	case "minio", "s3":
+	case "minio", "s3", "gs":
 		return NewS3Handler(uri, creds)
 	}
 	return nil, errors.Errorf("Unable to handle url: %s", uri)
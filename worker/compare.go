@@ -20,3 +20,12 @@ func evalCompare(cmp string, lv, rv int64) bool {
 	}
 	panic("EvalCompare: unreachable")
 }
+
+// evalCompareCount compares the count of a posting list against the thresholds of a count
+// function. Unlike evalCompare it also supports between, whose bounds are inclusive.
+func evalCompareCount(cmp string, count int64, thresholds []int64) bool {
+	if cmp == between {
+		return count >= thresholds[0] && count <= thresholds[1]
+	}
+	return evalCompare(cmp, count, thresholds[0])
+}
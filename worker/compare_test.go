@@ -0,0 +1,84 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestEvalCompareCount(t *testing.T) {
+	require.True(t, evalCompareCount("gt", 3, []int64{2}))
+	require.False(t, evalCompareCount("le", 3, []int64{2}))
+	require.True(t, evalCompareCount(between, 2, []int64{2, 4}))
+	require.True(t, evalCompareCount(between, 4, []int64{2, 4}))
+	require.False(t, evalCompareCount(between, 5, []int64{2, 4}))
+}
+
+func TestCountRange(t *testing.T) {
+	tests := []struct {
+		fname      string
+		thresholds []int64
+		lo, hi     int64
+	}{
+		{"eq", []int64{3}, 3, 3},
+		{"gt", []int64{3}, 4, math.MaxInt64},
+		{"ge", []int64{3}, 3, math.MaxInt64},
+		{"lt", []int64{3}, 0, 2},
+		{"le", []int64{3}, 0, 3},
+		{between, []int64{2, 5}, 2, 5},
+	}
+	for _, tc := range tests {
+		lo, hi := countRange(tc.fname, tc.thresholds)
+		require.Equal(t, tc.lo, lo, tc.fname)
+		require.Equal(t, tc.hi, hi, tc.fname)
+	}
+}
+
+func TestPlanForCountFilter(t *testing.T) {
+	dir, err := os.MkdirTemp("", "storetest_")
+	x.Check(err)
+	defer os.RemoveAll(dir)
+
+	ps, err := badger.OpenManaged(badger.DefaultOptions(dir))
+	x.Check(err)
+	defer ps.Close()
+	pstore = ps
+	posting.Init(ps, 0, false)
+	Init(ps)
+
+	require.NoError(t, schema.ParseBytes([]byte("plan_friend: [uid] @count ."), 1))
+	attr := x.AttrInRootNamespace("plan_friend")
+	uids := make([]uint64, Config.TypeFilterUidLimit+10)
+	for i := range uids {
+		uids[i] = uint64(i + 1)
+	}
+	q := &pb.Query{Attr: attr, UidList: &pb.List{Uids: uids}}
+	fc := &functionContext{fname: "eq", threshold: []int64{3}}
+
+	// Without a histogram, like after a restart, the filter checks the uids itself.
+	require.False(t, planForCountFilter(context.Background(), q, fc))
+
+	// Once the histogram is seeded from the empty count index, the index is used.
+	require.Eventually(t, func() bool {
+		return planForCountFilter(context.Background(), q, fc)
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// The ranges including zero can't be answered by the count index.
+	fc = &functionContext{fname: "lt", threshold: []int64{3}}
+	require.False(t, planForCountFilter(context.Background(), q, fc))
+}
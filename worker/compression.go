@@ -0,0 +1,95 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// CompressionDict is a zstd dictionary trained on the values of a predicate.
+type CompressionDict struct {
+	Predicate string `json:"predicate"`
+	Id        string `json:"id"`
+	Size      int    `json:"size"`
+	// Dictionaries is the number of dictionaries of the predicate, including this one.
+	Dictionaries int `json:"dictionaries"`
+}
+
+// TrainCompressionDict trains a zstd dictionary on the values of up to samples nodes of the
+// predicate of the namespace, which must be served by the group of this Alpha and have
+// @compress(zstd), and adds it to its schema. Its values are compressed with the dictionary from
+// their next rollup, the ones compressed with its previous dictionaries stay readable.
+func TrainCompressionDict(ctx context.Context, ns uint64, pred string,
+	samples int) (*CompressionDict, error) {
+
+	attr := x.NamespaceAttr(ns, pred)
+	if gid, err := groups().BelongsToReadOnly(attr, 0); err != nil {
+		return nil, err
+	} else if gid != groups().groupId() {
+		return nil, errors.Errorf("Predicate %s isn't served by group %d", pred, groups().groupId())
+	}
+	current, ok := schema.State().Get(ctx, attr)
+	if !ok {
+		return nil, errors.Errorf("Predicate %s doesn't exist", pred)
+	}
+	if current.Compression != schema.CompressionZstd {
+		return nil, errors.Errorf("Predicate %s needs @compress(zstd) to train a dictionary", pred)
+	}
+	if err := tiers().hot(ctx, attr, false); err != nil {
+		return nil, err
+	}
+
+	d, err := posting.TrainCompressionDict(ctx, attr, posting.Oracle().MaxAssigned(), samples)
+	if err != nil {
+		return nil, err
+	}
+	su := proto.Clone(&current).(*pb.SchemaUpdate)
+	su.CompressionDicts = append(su.CompressionDicts, d)
+	m := &pb.Mutations{StartTs: State.GetTimestamp(false), Schema: []*pb.SchemaUpdate{su}}
+	if _, err := MutateOverNetwork(ctx, m); err != nil {
+		return nil, errors.Wrapf(err, "while adding the dictionary to the schema of %s", pred)
+	}
+
+	dict := &CompressionDict{
+		Predicate:    pred,
+		Id:           fmt.Sprintf("%#x", posting.CompressionDictID(d)),
+		Size:         len(d),
+		Dictionaries: len(su.CompressionDicts),
+	}
+	glog.Infof("Trained the zstd dictionary %s of %d bytes for predicate %s", dict.Id, dict.Size,
+		pred)
+	return dict, nil
+}
+
+// mergeCompressionDicts returns the dictionaries of the schema of a predicate followed by the
+// ones of its update that it doesn't have, as the values compressed with them must stay readable.
+func mergeCompressionDicts(old, update [][]byte) [][]byte {
+	merged := append([][]byte(nil), old...)
+	for _, d := range update {
+		found := false
+		for _, o := range old {
+			if bytes.Equal(o, d) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, d)
+		}
+	}
+	return merged
+}
@@ -33,6 +33,9 @@ type Options struct {
 	MutationsMode int
 	// AuthToken is the token to be passed for Alter HTTP requests.
 	AuthToken string
+	// RequestSigner verifies the signatures of the requests to the signed HTTP endpoints, nil if
+	// the requests aren't signed.
+	RequestSigner *x.RequestSigner
 
 	// AclJwtAlg stores the JWT signing algorithm.
 	AclJwtAlg jwt.SigningMethod
@@ -46,6 +49,10 @@ type Options struct {
 	AccessJwtTtl time.Duration
 	// RefreshJwtTtl is the TTL of the refresh JWT.
 	RefreshJwtTtl time.Duration
+	// Ldap is the LDAP directory used to authenticate the ACL users, if any.
+	Ldap *x.LdapConfig
+	// Scim holds the options of the SCIM endpoint, nil if it is disabled.
+	Scim *x.ScimConfig
 
 	// CachePercentage is the comma-separated list of cache percentages
 	// used to split the total cache size among the multiple caches.
@@ -63,6 +70,9 @@ type Options struct {
 	// Define different ChangeDataCapture configurations
 	ChangeDataConf string
 
+	// TieringConf holds the options of the tiering of the idle tablets to object storage.
+	TieringConf string
+
 	// TypeFilterUidLimit decides how many elements would be searched directly
 	// vs searched via type index. If the number of elements are too low, then querying the
 	// index might be slower. This would allow people to set their limit according to
@@ -108,4 +118,7 @@ func (opt *Options) validate() {
 		x.AssertTruef(ad != td,
 			"Tmp directory and Audit Output cannot be the same ('%s').", opt.Audit.Output)
 	}
+	raft := x.WorkerConfig.Raft
+	x.AssertTruef(!raft.GetBool("witness") || !raft.GetBool("learner"),
+		"A witness votes in Raft, so it can't be a learner.")
 }
@@ -0,0 +1,287 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/y"
+	"github.com/golang/glog"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// DefaultDiskUsageSamples is the number of keys of each predicate sampled in the tables holding
+// the keys of more than one predicate to split their size among them.
+const DefaultDiskUsageSamples = 1000
+
+// LevelUsage is the on-disk size of the tables of a level of the LSM tree.
+type LevelUsage struct {
+	Level  int   `json:"level"`
+	Tables int   `json:"tables"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// PredicateUsage is the on-disk size of the keys and values of a predicate in the LSM tree.
+type PredicateUsage struct {
+	Namespace uint64 `json:"namespace"`
+	Predicate string `json:"predicate"`
+	// Levels is the size of the predicate by level, indexed by the level.
+	Levels []int64 `json:"levels"`
+	Bytes  int64   `json:"bytes"`
+	// Estimated is true if some of its size comes from tables shared with other predicates, split
+	// among them by sampling their keys.
+	Estimated bool `json:"estimated"`
+}
+
+// NamespaceUsage is the on-disk size of the predicates of a namespace in the LSM tree.
+type NamespaceUsage struct {
+	Namespace uint64  `json:"namespace"`
+	Levels    []int64 `json:"levels"`
+	Bytes     int64   `json:"bytes"`
+}
+
+// DiskUsage is the breakdown of the on-disk size of the p directory of this Alpha.
+type DiskUsage struct {
+	GroupId uint32 `json:"groupId"`
+	// LsmBytes and VlogBytes are the sizes of the SST and value log files.
+	LsmBytes   int64             `json:"lsmBytes"`
+	VlogBytes  int64             `json:"vlogBytes"`
+	Levels     []*LevelUsage     `json:"levels"`
+	Namespaces []*NamespaceUsage `json:"namespaces"`
+	Predicates []*PredicateUsage `json:"predicates"`
+	// OtherBytes is the size of the keys that aren't of a predicate, like the internal keys of
+	// Badger.
+	OtherBytes int64 `json:"otherBytes"`
+	// SampledTables is the number of tables shared by predicates whose keys were sampled.
+	SampledTables int `json:"sampledTables"`
+}
+
+// diskUsage breaks the size of the tables of the LSM tree of pstore down by level, namespace and
+// predicate. The size of a table holding a single predicate is taken from its metadata, the one
+// of a table shared by predicates is split among them by sampling their keys and values.
+func diskUsage(ctx context.Context, samples int) (*DiskUsage, error) {
+	usage := &DiskUsage{GroupId: groups().groupId()}
+	usage.LsmBytes, usage.VlogBytes = pstore.Size()
+	for i := range pstore.Levels() {
+		usage.Levels = append(usage.Levels, &LevelUsage{Level: i})
+	}
+	numLevels := len(usage.Levels)
+
+	preds := make(map[string]*PredicateUsage)
+	add := func(attr string, level int, size int64, estimated bool) {
+		if attr == "" {
+			usage.OtherBytes += size
+			return
+		}
+		p, ok := preds[attr]
+		if !ok {
+			ns, pred := x.ParseNamespaceAttr(attr)
+			p = &PredicateUsage{Namespace: ns, Predicate: pred, Levels: make([]int64, numLevels)}
+			preds[attr] = p
+		}
+		p.Levels[level] += size
+		p.Bytes += size
+		p.Estimated = p.Estimated || estimated
+	}
+	attrOf := func(key []byte) string {
+		pk, err := x.Parse(key)
+		if err != nil {
+			return ""
+		}
+		return pk.Attr
+	}
+
+	txn := pstore.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	for _, tinfo := range pstore.Tables() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if tinfo.Level >= numLevels {
+			continue
+		}
+		size := int64(tinfo.OnDiskSize)
+		usage.Levels[tinfo.Level].Tables++
+		usage.Levels[tinfo.Level].Bytes += size
+
+		left, right := attrOf(tinfo.Left), attrOf(tinfo.Right)
+		if left == right {
+			add(left, tinfo.Level, size, false)
+			continue
+		}
+		shares := sampleTable(txn, y.ParseKey(tinfo.Left), y.ParseKey(tinfo.Right),
+			tinfo.KeyCount, samples)
+		usage.SampledTables++
+		var total int64
+		for _, s := range shares {
+			total += s
+		}
+		if total == 0 {
+			add(left, tinfo.Level, size, true)
+			continue
+		}
+		// Whatever is left after the integer division goes to the first predicate of the table.
+		rest := size
+		for attr, s := range shares {
+			share := size * s / total
+			rest -= share
+			add(attr, tinfo.Level, share, true)
+		}
+		add(left, tinfo.Level, rest, true)
+	}
+
+	nss := make(map[uint64]*NamespaceUsage)
+	for _, p := range preds {
+		usage.Predicates = append(usage.Predicates, p)
+		n, ok := nss[p.Namespace]
+		if !ok {
+			n = &NamespaceUsage{Namespace: p.Namespace, Levels: make([]int64, numLevels)}
+			nss[p.Namespace] = n
+			usage.Namespaces = append(usage.Namespaces, n)
+		}
+		for i, b := range p.Levels {
+			n.Levels[i] += b
+		}
+		n.Bytes += p.Bytes
+	}
+	sort.Slice(usage.Predicates, func(i, j int) bool {
+		return usage.Predicates[i].Bytes > usage.Predicates[j].Bytes
+	})
+	sort.Slice(usage.Namespaces, func(i, j int) bool {
+		return usage.Namespaces[i].Namespace < usage.Namespaces[j].Namespace
+	})
+	return usage, nil
+}
+
+// sampleTable splits the keys of the table between left and right by predicate and returns the
+// size of the keys and values of each one. Only the first samples versions of the keys of a
+// predicate are read, the size of the ones of the keyCount keys of the table that aren't is
+// extrapolated from them.
+func sampleTable(txn *badger.Txn, left, right []byte, keyCount uint32,
+	samples int) map[string]int64 {
+
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.PrefetchValues = false
+	it := txn.NewIterator(iopt)
+	defer it.Close()
+
+	type sample struct {
+		keys, bytes int64
+		truncated   bool
+	}
+	stats := make(map[string]*sample)
+	var read int64
+	for it.Seek(left); it.Valid(); {
+		item := it.Item()
+		key := item.Key()
+		if bytes.Compare(key, right) > 0 {
+			break
+		}
+		var attr string
+		if pk, err := x.Parse(key); err == nil {
+			attr = pk.Attr
+		}
+		s, ok := stats[attr]
+		if !ok {
+			s = &sample{}
+			stats[attr] = s
+		}
+		if attr != "" && s.keys >= int64(samples) {
+			// Skip the rest of the keys of the predicate with the prefix of this one: the type
+			// byte, namespace, length and name of the predicate.
+			s.truncated = true
+			prefix := key[:11+int(binary.BigEndian.Uint16(key[9:11]))]
+			it.Seek(append(append([]byte{}, prefix...), 0xFF))
+			continue
+		}
+		s.keys++
+		s.bytes += item.EstimatedSize() + int64(len(key))
+		read++
+		it.Next()
+	}
+
+	var truncated int64
+	for _, s := range stats {
+		if s.truncated {
+			truncated++
+		}
+	}
+	unread := int64(keyCount) - read
+	shares := make(map[string]int64)
+	for attr, s := range stats {
+		shares[attr] = s.bytes
+		if s.truncated && unread > 0 {
+			shares[attr] += unread / truncated * s.bytes / s.keys
+		}
+	}
+	return shares
+}
+
+// DiskUsageOf returns the breakdown of the on-disk size of the p directory of this Alpha. The
+// breakdown is limited to the predicates of the namespace, unless it is the root namespace.
+func DiskUsageOf(ctx context.Context, ns uint64, samples int) (*DiskUsage, error) {
+	if samples <= 0 {
+		samples = DefaultDiskUsageSamples
+	}
+	usage, err := diskUsage(ctx, samples)
+	if err != nil || ns == x.RootNamespace {
+		return usage, err
+	}
+	var preds []*PredicateUsage
+	for _, p := range usage.Predicates {
+		if p.Namespace == ns {
+			preds = append(preds, p)
+		}
+	}
+	var nss []*NamespaceUsage
+	for _, n := range usage.Namespaces {
+		if n.Namespace == ns {
+			nss = append(nss, n)
+		}
+	}
+	// The sizes of the files and levels would tell about the other namespaces.
+	return &DiskUsage{GroupId: usage.GroupId, Namespaces: nss, Predicates: preds}, nil
+}
+
+// recordDiskUsage records the on-disk size of the predicates and namespaces of this Alpha by
+// level.
+func (n *node) recordDiskUsage() {
+	usage, err := diskUsage(n.ctx, DefaultDiskUsageSamples)
+	if err != nil {
+		glog.Errorf("Error while computing the disk usage: %v", err)
+		return
+	}
+	record := func(m *ostats.Int64Measure, levels []int64, mutators ...tag.Mutator) {
+		for level, b := range levels {
+			ctx, err := tag.New(n.ctx, append(mutators,
+				tag.Upsert(x.KeyLevel, strconv.Itoa(level)))...)
+			if err != nil {
+				glog.Errorf("Error while tagging the disk usage: %v", err)
+				return
+			}
+			ostats.Record(ctx, m.M(b))
+		}
+	}
+	for _, p := range usage.Predicates {
+		record(x.PredicateDiskBytes, p.Levels,
+			tag.Upsert(x.KeyNamespace, strconv.FormatUint(p.Namespace, 10)),
+			tag.Upsert(x.KeyPredicate, p.Predicate))
+	}
+	for _, ns := range usage.Namespaces {
+		record(x.NamespaceDiskBytes, ns.Levels,
+			tag.Upsert(x.KeyNamespace, strconv.FormatUint(ns.Namespace, 10)))
+	}
+}
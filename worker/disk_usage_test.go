@@ -0,0 +1,83 @@
+//go:build integration
+
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+	// write writes the values of the attributes in a table of their own, as closing the DB
+	// flushes its memtable.
+	write := func(attrs ...string) {
+		db, err := badger.OpenManaged(badger.DefaultOptions(dir).WithLogger(nil))
+		require.NoError(t, err)
+		wb := db.NewManagedWriteBatch()
+		for i, attr := range attrs {
+			// The values of each attribute are bigger than the ones of the previous one.
+			val := bytes.Repeat([]byte{'v'}, 100*(i+1))
+			for uid := uint64(1); uid <= 100; uid++ {
+				require.NoError(t, wb.SetEntryAt(badger.NewEntry(x.DataKey(attr, uid), val), 1))
+			}
+		}
+		require.NoError(t, wb.Flush())
+		require.NoError(t, db.Close())
+	}
+	alone := x.AttrInRootNamespace("usage_alone")
+	small, big := x.NamespaceAttr(2, "usage_small"), x.NamespaceAttr(2, "usage_big")
+	write(alone)
+	write(small, big)
+
+	db, err := badger.OpenManaged(badger.DefaultOptions(dir).WithLogger(nil))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+	old := pstore
+	pstore = db
+	defer func() { pstore = old }()
+
+	usage, err := DiskUsageOf(context.Background(), x.RootNamespace, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, usage.SampledTables)
+	var tables int
+	var total int64
+	for _, l := range usage.Levels {
+		tables += l.Tables
+		total += l.Bytes
+	}
+	require.Equal(t, 2, tables)
+
+	preds := make(map[string]*PredicateUsage)
+	var sum int64
+	for _, p := range usage.Predicates {
+		preds[p.Predicate] = p
+		sum += p.Bytes
+	}
+	require.Len(t, preds, 3)
+	require.Equal(t, total, sum+usage.OtherBytes)
+	require.False(t, preds["usage_alone"].Estimated)
+	require.True(t, preds["usage_big"].Estimated)
+	require.Greater(t, preds["usage_big"].Bytes, preds["usage_small"].Bytes)
+	require.Len(t, usage.Namespaces, 2)
+	require.Equal(t, preds["usage_small"].Bytes+preds["usage_big"].Bytes, usage.Namespaces[1].Bytes)
+
+	// A namespace only sees its own predicates.
+	usage, err = DiskUsageOf(context.Background(), 2, 10)
+	require.NoError(t, err)
+	require.Len(t, usage.Predicates, 2)
+	require.Len(t, usage.Namespaces, 1)
+	require.Empty(t, usage.Levels)
+	require.Zero(t, usage.LsmBytes)
+}
@@ -12,6 +12,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
@@ -68,6 +69,18 @@ type node struct {
 	opsLock     sync.Mutex
 	cdcTracker  *CDC
 	canCampaign bool
+	// witness is true if this node only votes in the Raft group, and doesn't store any data.
+	witness bool
+	// applyQueues applies the mutations in per-predicate queues.
+	applyQueues *applyQueues
+	// asyncReverse holds the reverse edges of the predicates with @reverse(async) to add.
+	asyncReverse *asyncReverse
+	// tiering moves the idle tablets to object storage, if --tiering dest is set.
+	tiering *tiering
+	// expiredTxns are the txns this node aborted for exceeding their maximum duration.
+	expiredTxns *expiredTxns
+	// proposalBatches batches the proposals of this node, if --raft proposal-batch-window is set.
+	proposalBatches *proposalBatcher
 }
 
 type op int
@@ -274,10 +287,23 @@ func newNode(store *raftwal.DiskStorage, gid uint32, id uint64, myAddr string) *
 		// 10ms. If we restrict the size here, then Raft goes into a loop trying
 		// to maintain quorum health.
 		applyCh:    make(chan []raftpb.Entry, 1000),
-		closer:     z.NewCloser(4), // Matches CLOSER:1
+		closer:     z.NewCloser(6), // Matches CLOSER:1
 		ops:        make(map[op]operation),
 		cdcTracker: newCDC(),
-	}
+		witness:    x.WorkerConfig.Raft.GetBool("witness"),
+		applyQueues: newApplyQueues(
+			int(x.WorkerConfig.Raft.GetInt64("apply-queue-depth"))),
+		expiredTxns:  newExpiredTxns(),
+		asyncReverse: newAsyncReverse(x.WorkerConfig.Raft.GetDuration("reverse-async-lag")),
+		tiering:      newTiering(gid, id),
+	}
+	n.proposalBatches = newProposalBatcher(
+		x.WorkerConfig.Raft.GetDuration("proposal-batch-window"),
+		int(x.WorkerConfig.Raft.GetUint64("proposal-batch-kb"))<<10,
+		x.WorkerConfig.Raft.GetBool("proposal-batch-adaptive"),
+		func(ctx context.Context, entries []raftpb.Entry) error {
+			return n.Raft().Step(ctx, raftpb.Message{Type: raftpb.MsgProp, Entries: entries})
+		})
 	return n
 }
 
@@ -344,6 +370,9 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		if err := posting.DeleteAllForNs(ns); err != nil {
 			return err
 		}
+		if err := n.tiering.forgetNs(ns); err != nil {
+			return err
+		}
 
 		// TODO: What about multi shard cluster?
 		// It should be okay to set the schema at timestamp 1 after drop all operation.
@@ -380,6 +409,9 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		if err := posting.DeleteData(ns); err != nil {
 			return err
 		}
+		if err := n.tiering.forgetNs(ns); err != nil {
+			return err
+		}
 
 		// TODO: Revisit this when we work on posting cache. Don't clear entire cache.
 		// We don't want to drop entire cache, just due to one namespace.
@@ -395,6 +427,9 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		if err := posting.DeleteAll(); err != nil {
 			return err
 		}
+		if err := n.tiering.forgetAll(); err != nil {
+			return err
+		}
 
 		// Clear entire cache.
 		posting.ResetCache()
@@ -428,6 +463,12 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		return errors.New("StartTs must be provided")
 	}
 
+	if proposal.Mutations.Heartbeat {
+		// Every node keeps the txn alive, so that a new leader doesn't abort it for being idle.
+		posting.Oracle().KeepAlive(proposal.Mutations.StartTs)
+		return nil
+	}
+
 	if len(proposal.Mutations.Schema) > 0 || len(proposal.Mutations.Types) > 0 {
 		// MaxAssigned would ensure that everything that's committed up until this point
 		// would be picked up in building indexes. Any uncommitted txns would be cancelled
@@ -440,6 +481,19 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 			if err := detectPendingTxns(supdate.Predicate); err != nil {
 				return err
 			}
+			// The indexes are rebuilt from the data of the tablet.
+			if err := n.tiering.hot(ctx, supdate.Predicate, true); err != nil {
+				return err
+			}
+			if supdate.RenameFrom == "" {
+				continue
+			}
+			if err := detectPendingTxns(supdate.RenameFrom); err != nil {
+				return err
+			}
+			if err := n.tiering.hot(ctx, supdate.RenameFrom, true); err != nil {
+				return err
+			}
 		}
 
 		if err := runSchemaMutation(ctx, proposal.Mutations.Schema, startTs); err != nil {
@@ -466,11 +520,8 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	// We derive the schema here if it's not present
 	// Since raft committed logs are serialized, we can derive
 	// schema here without any locking
-
-	// Stores a map of predicate and type of first mutation for each predicate.
-	schemaMap := make(map[string]types.TypeID)
 	for _, edge := range proposal.Mutations.Edges {
-		if edge.Entity == 0 && bytes.Equal(edge.Value, []byte(x.Star)) {
+		if isDropPredicateEdge(edge) {
 			// We should only drop the predicate if there is no pending
 			// transaction.
 			if err := detectPendingTxns(edge.Attr); err != nil {
@@ -478,14 +529,10 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 				return err
 			}
 			span.AddEvent("Deleting predicate")
-			return posting.DeletePredicate(ctx, edge.Attr, proposal.StartTs)
-		}
-		// Don't derive schema when doing deletion.
-		if edge.Op == pb.DirectedEdge_DEL {
-			continue
-		}
-		if _, ok := schemaMap[edge.Attr]; !ok {
-			schemaMap[edge.Attr] = posting.TypeID(edge)
+			if err := posting.DeletePredicate(ctx, edge.Attr, proposal.StartTs); err != nil {
+				return err
+			}
+			return n.tiering.forgetAttr(edge.Attr)
 		}
 	}
 
@@ -499,6 +546,37 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		ostats.Record(ctx, x.ActiveMutations.M(int64(-total)))
 	}()
 
+	txn, err := prepareMutations(ctx, proposal)
+	if err != nil {
+		return err
+	}
+	// Discard the posting lists from cache to release memory at the end.
+	defer txn.Update()
+	return applyEdges(ctx, txn, proposal.Mutations.Edges)
+}
+
+// isDropPredicateEdge returns true if the edge drops its whole predicate.
+func isDropPredicateEdge(edge *pb.DirectedEdge) bool {
+	return edge.Entity == 0 && bytes.Equal(edge.Value, []byte(x.Star))
+}
+
+// prepareMutations creates the schema of the new predicates of the mutations, sorts their edges
+// and registers their transaction. It must be called in the order of the Raft log.
+func prepareMutations(ctx context.Context, proposal *pb.Proposal) (*posting.Txn, error) {
+	span := trace.SpanFromContext(ctx)
+
+	// Stores a map of predicate and type of first mutation for each predicate.
+	schemaMap := make(map[string]types.TypeID)
+	for _, edge := range proposal.Mutations.Edges {
+		// Don't derive schema when doing deletion.
+		if edge.Op == pb.DirectedEdge_DEL {
+			continue
+		}
+		if _, ok := schemaMap[edge.Attr]; !ok {
+			schemaMap[edge.Attr] = posting.TypeID(edge)
+		}
+	}
+
 	// Go through all the predicates and their first observed schema type. If we are unable to find
 	// these predicates in the current schema state, add them to the schema state. Note that the
 	// schema deduction is done by RDF/JSON chunker.
@@ -509,7 +587,7 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 				hint = mutHint
 			}
 			if err = createSchema(attr, storageType, hint, proposal.StartTs); err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
@@ -527,20 +605,37 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		}
 		return ei.GetEntity() < ej.GetEntity()
 	})
+	for i, edge := range m.Edges {
+		if i > 0 && m.Edges[i-1].Attr == edge.Attr {
+			continue
+		}
+		if err := tiers().hot(ctx, edge.Attr, true); err != nil {
+			return nil, err
+		}
+	}
 
 	txn := posting.Oracle().RegisterStartTs(m.StartTs)
 	if txn.ShouldAbort() {
 		span.AddEvent("Txn should abort.", trace.WithAttributes(
 			attribute.Int64("start_ts", int64(m.StartTs)),
 		))
-		return x.ErrConflict
+		return nil, x.ErrConflict
 	}
-	// Discard the posting lists from cache to release memory at the end.
-	defer txn.Update()
+	txn.AddNamespaces(m.Edges)
+	if txn.Exceeds(x.WorkerConfig.MaxTxnDuration, time.Now()) {
+		return nil, x.ErrTxnExpired
+	}
+	return txn, nil
+}
+
+// applyEdges applies the edges, sorted by predicate and entity, in the transaction txn.
+func applyEdges(ctx context.Context, txn *posting.Txn, edges []*pb.DirectedEdge) error {
+	span := trace.SpanFromContext(ctx)
 
 	process := func(edges []*pb.DirectedEdge) error {
 		var retries int
 		for _, edge := range edges {
+			start := time.Now()
 			for {
 				err := runMutation(ctx, edge, txn)
 				if err == nil {
@@ -551,6 +646,7 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 				}
 				retries++
 			}
+			x.ObservePredicate(ctx, edge.Attr, "mutation", start)
 		}
 		if retries > 0 {
 			span.AddEvent("retries=true num=%d", trace.WithAttributes(
@@ -558,14 +654,14 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		}
 		return nil
 	}
-	numGo, width := x.DivideAndRule(len(m.Edges))
+	numGo, width := x.DivideAndRule(len(edges))
 	span.AddEvent("To apply: %d edges. NumGo: %d. Width: %d", trace.WithAttributes(
-		attribute.Int("num_edges", len(m.Edges)),
+		attribute.Int("num_edges", len(edges)),
 		attribute.Int("num_go", numGo),
 		attribute.Int("width", width)))
 
 	if numGo == 1 {
-		return process(m.Edges)
+		return process(edges)
 	}
 
 	// We need to create batches such that no two batches contains the same entry (<Entity> + <Predicate>)
@@ -573,8 +669,8 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	// the next different entry. New number of chans would ne less than NumGo. So we can create the chan with
 	// numGo.
 	sameAttrAndUid := func(i, j int) bool {
-		ei := m.Edges[i]
-		ej := m.Edges[j]
+		ei := edges[i]
+		ej := edges[j]
 		if ei.GetAttr() != ej.GetAttr() {
 			return false
 		}
@@ -583,19 +679,19 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	numChanCreated := 0
 
 	errCh := make(chan error, numGo)
-	for i := 0; i < len(m.Edges); {
+	for i := 0; i < len(edges); {
 		end := i + width
-		if end > len(m.Edges) {
-			end = len(m.Edges)
+		if end > len(edges) {
+			end = len(edges)
 		}
 
-		for end < len(m.Edges) && sameAttrAndUid(end, end-1) {
+		for end < len(edges) && sameAttrAndUid(end, end-1) {
 			end++
 		}
 
 		numChanCreated += 1
 		go func(start, end int) {
-			errCh <- process(m.Edges[start:end])
+			errCh <- process(edges[start:end])
 		}(i, end)
 		i = end
 	}
@@ -614,6 +710,12 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	return errs
 }
 
+// witnessApplies returns true if a witness applies the proposal. A witness keeps track of the
+// membership and of the snapshots, which truncate its Raft log, but doesn't apply any data.
+func witnessApplies(proposal *pb.Proposal) bool {
+	return proposal.State != nil || proposal.Snapshot != nil || proposal.CdcState != nil
+}
+
 func (n *node) applyCommitted(proposal *pb.Proposal, key uint64) error {
 	start := time.Now()
 	defer func() {
@@ -630,6 +732,11 @@ func (n *node) applyCommitted(proposal *pb.Proposal, key uint64) error {
 		attribute.Int64("Group Id", int64(n.gid)),
 		attribute.Int64("proposal key", int64(key))))
 
+	if n.witness && !witnessApplies(proposal) {
+		span.AddEvent("Skipping proposal on witness")
+		return nil
+	}
+
 	if proposal.Mutations != nil {
 		// syncmarks for this shouldn't be marked done until it's committed.
 		span.AddEvent("Applying mutations")
@@ -675,6 +782,9 @@ func (n *node) applyCommitted(proposal *pb.Proposal, key uint64) error {
 			return nil
 		}
 		err := posting.DeletePredicate(ctx, proposal.CleanPredicate, proposal.StartTs)
+		if err == nil {
+			err = n.tiering.forgetAttr(proposal.CleanPredicate)
+		}
 		if err == badger.ErrBannedKey {
 			// Zero might send the delete predicate instruction to alpha when updating the
 			// membership state. This can happen for predicates from banned namespaces too.
@@ -782,8 +892,9 @@ func (n *node) applyCommitted(proposal *pb.Proposal, key uint64) error {
 			}
 			glog.Warningf("Error while calling CreateSnapshot: %v. Retrying...", err)
 		}
-		// We can now discard all invalid versions of keys below this ts.
-		pstore.SetDiscardTs(snap.ReadTs)
+		// We can now discard all invalid versions of keys below this ts, unless a snapshot
+		// handle pins an older ts.
+		pstore.SetDiscardTs(snapshotDiscardTs(snap.ReadTs))
 		return nil
 	case proposal.Restore != nil:
 		// Enable draining mode for the duration of the restore processing.
@@ -838,6 +949,7 @@ func (n *node) processTabletSizes() {
 		case <-n.closer.HasBeenClosed():
 			return
 		case <-tick.C:
+			n.recordDiskUsage()
 			n.calculateTabletSizes()
 		}
 	}
@@ -863,11 +975,15 @@ func updateStartTs(p *pb.Proposal) {
 
 func (n *node) processApplyCh() {
 	defer n.closer.Done() // CLOSER:1
+	defer n.applyQueues.waitAll()
 
 	type P struct {
 		err  error
 		size int
 		seen time.Time
+		// done is closed once the proposal is applied, which can happen after handle returns
+		// for the mutations applied in the queues of their predicates.
+		done chan struct{}
 	}
 	previous := make(map[uint64]*P)
 
@@ -876,14 +992,12 @@ func (n *node) processApplyCh() {
 		glog.V(3).Infof("handling element in applyCh with #entries %v", len(entries))
 		defer glog.V(3).Infof("done handling element in applyCh")
 
-		var totalSize int64
 		for _, entry := range entries {
 			x.AssertTrue(len(entry.Data) > 0)
 
 			// We use the size as a double check to ensure that we're
 			// working with the same proposal as before.
 			psz := entry.Size()
-			totalSize += int64(psz)
 
 			var proposal pb.Proposal
 			key := binary.BigEndian.Uint64(entry.Data[:8])
@@ -891,24 +1005,41 @@ func (n *node) processApplyCh() {
 			proposal.Index = entry.Index
 			updateStartTs(&proposal)
 
-			var perr error
+			finish := func(perr error) {
+				n.Proposals.Done(key, perr)
+				n.Applied.Done(proposal.Index)
+				ostats.Record(context.Background(),
+					x.RaftAppliedIndex.M(int64(n.Applied.DoneUntil())))
+				if sz := atomic.AddInt64(&n.pendingSize, -int64(psz)); sz < 0 {
+					glog.Warningf("Pending size should remain above zero: %d", sz)
+				}
+			}
+
 			p, ok := previous[key]
+			if ok {
+				// Wait for the previous proposal to be applied, to know if it succeeded.
+				<-p.done
+			}
 			if ok && p.err == nil && p.size == psz {
 				msg := fmt.Sprintf("Proposal with key: %d already applied. Skipping index: %d."+
 					" Delta: %+v Snapshot: %+v.\n",
 					key, proposal.Index, proposal.Delta, proposal.Snapshot)
 				glog.Infof(msg)
 				previous[key].seen = time.Now() // Update the ts.
-				// Don't break here. We still need to call the Done below.
+				// We still need to mark the proposal as done.
+				finish(nil)
+				continue
+			}
 
-			} else {
-				// if this applyCommitted fails, how do we ensure
-				start := time.Now()
-				perr = n.applyCommitted(&proposal, key)
-				if key != 0 {
-					p := &P{err: perr, size: psz, seen: time.Now()}
-					previous[key] = p
-				}
+			// if this applyCommitted fails, how do we ensure
+			start := time.Now()
+			p = &P{size: psz, seen: time.Now(), done: make(chan struct{})}
+			if key != 0 {
+				previous[key] = p
+			}
+			applied := func(perr error) {
+				p.err = perr
+				close(p.done)
 				span := trace.SpanFromContext(n.ctx)
 				if perr != nil {
 					glog.Errorf("Applying proposal. Error: %v. Proposal: %q.", perr, &proposal)
@@ -929,14 +1060,11 @@ func (n *node) processApplyCh() {
 				}
 				ms := x.SinceMs(start)
 				_ = ostats.RecordWithTags(context.Background(), tags, x.LatencyMs.M(ms))
+				finish(perr)
+			}
+			if !n.applyQueued(&proposal, key, applied) {
+				applied(n.applyCommitted(&proposal, key))
 			}
-
-			n.Proposals.Done(key, perr)
-			n.Applied.Done(proposal.Index)
-			ostats.Record(context.Background(), x.RaftAppliedIndex.M(int64(n.Applied.DoneUntil())))
-		}
-		if sz := atomic.AddInt64(&n.pendingSize, -totalSize); sz < 0 {
-			glog.Warningf("Pending size should remain above zero: %d", sz)
 		}
 	}
 
@@ -1019,6 +1147,9 @@ func (n *node) commitOrAbort(pkey uint64, delta *pb.OracleDelta) error {
 	for _, status := range delta.Txns {
 		txn := posting.Oracle().GetTxn(status.StartTs)
 		txn.UpdateCachedKeys(status.CommitTs)
+		if status.CommitTs > 0 && txn != nil {
+			n.asyncReverse.push(txn)
+		}
 	}
 
 	// Now advance Oracle(), so we can service waiting reads.
@@ -1135,6 +1266,7 @@ func (n *node) proposeSnapshot() error {
 	// snapshotIdx. In any case, we continue picking up txn updates, to generate
 	// a maxCommitTs, which would become the readTs for the snapshot.
 	minPendingStart := x.Min(posting.Oracle().MinPendingStartTs(), n.cdcTracker.getTs())
+	minPendingStart = x.Min(minPendingStart, n.asyncReverse.minStartTs())
 	snap, err := n.calculateSnapshot(0, lastIdx, minPendingStart)
 	if err != nil {
 		return err
@@ -1183,7 +1315,7 @@ func (n *node) updateRaftProgress() error {
 	applied := n.Store.Uint(raftwal.CheckpointIndex)
 
 	snap, err := n.calculateSnapshot(applied, n.Applied.DoneUntil(),
-		posting.Oracle().MinPendingStartTs())
+		x.Min(posting.Oracle().MinPendingStartTs(), n.asyncReverse.minStartTs()))
 	if err != nil || snap == nil || snap.Index <= applied {
 		return err
 	}
@@ -1215,7 +1347,7 @@ func (n *node) checkpointAndClose(done chan struct{}) {
 				glog.Errorf("While updating Raft progress: %v", err)
 			}
 
-			if n.AmLeader() {
+			if n.AmLeader() && !n.witness {
 				// If leader doesn't have a snapshot, we should create one immediately. This is very
 				// useful when you bring up the cluster from bulk loader. If you remove an alpha and
 				// add a new alpha, the new follower won't get a snapshot if the leader doesn't have
@@ -1385,6 +1517,9 @@ func (n *node) Run() {
 				} else {
 					ostats.Record(ctx, x.RaftIsLeader.M(0))
 				}
+				if leader && n.witness {
+					go n.transferWitnessLeadership()
+				}
 				timer.Record("updating soft state")
 			}
 			if leader {
@@ -1422,7 +1557,11 @@ func (n *node) Run() {
 				x.Check(proto.Unmarshal(rd.Snapshot.Data, snap))
 				rc := snap.GetContext()
 				x.AssertTrue(rc.GetGroup() == n.gid)
-				if rc.Id != n.Id {
+				switch {
+				case rc.Id != n.Id && n.witness:
+					glog.Infof("---> SNAPSHOT: %+v. Group %d from node id %#x. Witness doesn't"+
+						" retrieve the data.\n", snap, n.gid, rc.Id)
+				case rc.Id != n.Id:
 					// Set node to unhealthy state here while it applies the snapshot.
 					x.UpdateHealthStatus(false)
 
@@ -1466,7 +1605,7 @@ func (n *node) Run() {
 
 					// Set node to healthy state here.
 					x.UpdateHealthStatus(true)
-				} else {
+				default:
 					glog.Infof("---> SNAPSHOT: %+v. Group %d from node id %#x [SELF]. Ignoring.\n",
 						snap, n.gid, rc.Id)
 				}
@@ -1603,8 +1742,9 @@ func listWrap(kv *bpb.KV) *bpb.KVList {
 
 // calculateTabletSizes updates the tablet sizes for the keys.
 func (n *node) calculateTabletSizes() {
-	if !n.AmLeader() {
-		// Only leader sends the tablet size updates to Zero. No one else does.
+	if !n.AmLeader() || n.witness {
+		// Only leader sends the tablet size updates to Zero. No one else does. A witness has no
+		// data, so it doesn't send any while it transfers its leadership.
 		return
 	}
 	var total int64
@@ -1720,6 +1860,16 @@ func (n *node) blockingAbort(req *pb.TxnTimestamps) error {
 func (n *node) abortOldTransactions() {
 	// Aborts if not already committed.
 	starts := posting.Oracle().TxnOlderThan(x.WorkerConfig.AbortOlderThan)
+	expired := posting.Oracle().TxnsExceeding(x.WorkerConfig.MaxTxnDuration)
+	if len(expired) > 0 {
+		glog.Infof("Found %d transactions exceeding their maximum duration.", len(expired))
+		n.expiredTxns.add(expired, time.Now())
+		for _, ts := range expired {
+			if !slices.Contains(starts, ts) {
+				starts = append(starts, ts)
+			}
+		}
+	}
 	if len(starts) == 0 {
 		return
 	}
@@ -1964,6 +2114,9 @@ func (n *node) InitAndStartNode() {
 	if n.RaftContext.IsLearner && !hasPeer {
 		glog.Fatal("Cannot start a learner node without peer alpha nodes")
 	}
+	if n.witness && !hasPeer {
+		glog.Fatal("Cannot start a witness node without peer alpha nodes")
+	}
 
 	if restart {
 		glog.Infof("Restarting node for group: %d\n", n.gid)
@@ -2020,6 +2173,8 @@ func (n *node) InitAndStartNode() {
 	go n.processApplyCh()
 	go n.BatchAndSendMessages()
 	go n.monitorRaftMetrics()
+	go n.processAsyncReverse()
+	go n.processTiering()
 	go n.cdcTracker.processCDCEvents()
 	// Ignoring the error since InitAndStartNode does not return an error and using x.Check would
 	// not be the right thing to do.
@@ -2028,6 +2183,20 @@ func (n *node) InitAndStartNode() {
 	go n.Run()
 }
 
+// transferWitnessLeadership transfers the leadership of a witness to a data replica of its group,
+// as the witness has no data to lead the group with.
+func (n *node) transferWitnessLeadership() {
+	peerId, has := groups().dataPeer()
+	if !has {
+		glog.Warningf("Witness %#x is the leader of group %d, and has no data replica to transfer"+
+			" the leadership to.", n.Id, n.gid)
+		return
+	}
+	glog.Infof("Witness %#x became the leader of group %d. Transferring the leadership to %#x.",
+		n.Id, n.gid, peerId)
+	n.Raft().TransferLeadership(n.ctx, n.Id, peerId)
+}
+
 func (n *node) AmLeader() bool {
 	if n.Raft() == nil {
 		return false
@@ -2043,5 +2212,13 @@ func (n *node) monitorRaftMetrics() {
 		curPendingSize := atomic.LoadInt64(&n.pendingSize)
 		ostats.Record(n.ctx, x.RaftPendingSize.M(curPendingSize))
 		ostats.Record(n.ctx, x.RaftApplyCh.M(int64(len(n.applyCh))))
+
+		queued, attr, depth := n.applyQueues.depths()
+		ostats.Record(n.ctx, x.RaftApplyQueued.M(int64(queued)))
+		ostats.Record(n.ctx, x.RaftApplyQueueMaxDepth.M(int64(depth)))
+		if n.applyQueues.enabled() && depth >= n.applyQueues.maxDepth {
+			glog.Warningf("The mutations of predicate %s are held back, with %d of them queued"+
+				" to be applied.", x.ParseAttr(attr), depth)
+		}
 	}
 }
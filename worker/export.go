@@ -82,6 +82,8 @@ var rdfTypeMap = map[types.TypeID]string{
 	types.PasswordID: "xs:password",
 	types.BigFloatID: "xs:decimal",
 	types.VFloatID:   "xs:[]float32",
+	types.Uint64ID:   "xs:unsignedLong",
+	types.BytesID:    "xs:bytes",
 }
 
 // UIDs like 0x1 look weird but 64-bit ones like 0x0000000000000001 are too long.
@@ -301,6 +303,8 @@ func toSchema(attr string, update *pb.SchemaUpdate) *bpb.KV {
 		x.Check2(buf.WriteRune(']'))
 	}
 	switch {
+	case update.GetDirective() == pb.SchemaUpdate_REVERSE && update.GetAsyncReverse():
+		x.Check2(buf.WriteString(" @reverse(async)"))
 	case update.GetDirective() == pb.SchemaUpdate_REVERSE:
 		x.Check2(buf.WriteString(" @reverse"))
 	case update.GetDirective() == pb.SchemaUpdate_INDEX && len(update.GetTokenizer()) > 0:
@@ -325,6 +329,26 @@ func toSchema(attr string, update *pb.SchemaUpdate) *bpb.KV {
 	if update.GetUnique() {
 		x.Check2(buf.WriteString(" @unique"))
 	}
+	if update.GetNoConflict() {
+		x.Check2(buf.WriteString(" @noconflict"))
+	}
+	if update.GetConflict() != "" {
+		x.Check2(buf.WriteString(" @conflict(" + update.GetConflict() + ")"))
+	}
+	if update.GetProvenance() {
+		x.Check2(buf.WriteString(" @provenance"))
+	}
+	if update.GetEmbeddingSource() != "" {
+		x.Check2(buf.WriteString(" @embedding(<" + update.GetEmbeddingSource() + ">)"))
+	}
+	// The dictionaries aren't exported, the values are exported decompressed.
+	switch {
+	case update.GetCompression() != "" && update.GetCompressionLevel() > 0:
+		x.Check2(buf.WriteString(fmt.Sprintf(" @compress(%s, level: %d)",
+			update.GetCompression(), update.GetCompressionLevel())))
+	case update.GetCompression() != "":
+		x.Check2(buf.WriteString(" @compress(" + update.GetCompression() + ")"))
+	}
 	x.Check2(buf.WriteString(" . \n"))
 	//TODO(Naman): We don't need the version anymore.
 	return &bpb.KV{
@@ -599,6 +623,9 @@ func export(ctx context.Context, in *pb.ExportRequest) (ExportedFiles, error) {
 		return nil, err
 	}
 	glog.Infof("Running export for group %d at timestamp %d.", in.GroupId, in.ReadTs)
+	if err := tiers().rehydrateNs(ctx, in.Namespace); err != nil {
+		return nil, err
+	}
 
 	return exportInternal(ctx, in, pstore, false)
 }
@@ -618,6 +645,8 @@ func ToExportKvList(pk x.ParsedKey, pl *posting.List, in *pb.ExportRequest) (*bp
 	case e.attr == "dgraph.graphql.xid":
 	case e.attr == "dgraph.drop.op":
 	case e.attr == "dgraph.graphql.p_query":
+	// The idempotency keys only last for the idempotency-window.
+	case strings.HasPrefix(e.attr, "dgraph.idempotency."):
 
 	case pk.IsData() && e.attr == "dgraph.graphql.schema":
 		// Export the graphql schema.
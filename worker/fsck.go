@@ -0,0 +1,59 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// Fsck verifies the index, reverse and count keys of the tablets of the namespace served by the
+// group of this Alpha against their data, or only the ones of the predicate if it isn't empty.
+// It only reports the issues, they are repaired by dgraph debug --fsck --fsck_repair with the
+// Alpha stopped, or by rebuilding the indexes.
+func Fsck(ctx context.Context, ns uint64, pred string) ([]*posting.FsckReport, error) {
+	readTs := posting.Oracle().MaxAssigned()
+	var reports []*posting.FsckReport
+	for _, attr := range namespaceTablets(ns) {
+		if pred != "" && x.ParseAttr(attr) != pred {
+			continue
+		}
+		if _, err := schema.State().TypeOf(attr); err != nil {
+			// The tablet of a dropped predicate which isn't deleted yet.
+			continue
+		}
+		if err := tiers().hot(ctx, attr, false); err != nil {
+			return nil, err
+		}
+		report, err := posting.Fsck(ctx, attr, readTs, 0)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while checking predicate %s", x.ParseAttr(attr))
+		}
+		reports = append(reports, report)
+	}
+	if pred != "" && len(reports) == 0 {
+		return nil, errors.Errorf("Predicate %s isn't served by group %d", pred, GroupId())
+	}
+	return reports, nil
+}
+
+// namespaceTablets returns the sorted tablets of the namespace served by the group of this Alpha.
+func namespaceTablets(ns uint64) []string {
+	var attrs []string
+	for attr := range GetMembershipState().GetGroups()[GroupId()].GetTablets() {
+		if x.ParseNamespace(attr) == ns {
+			attrs = append(attrs, attr)
+		}
+	}
+	sort.Strings(attrs)
+	return attrs
+}
@@ -7,7 +7,9 @@ package worker
 
 import (
 	"context"
+	"regexp"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,10 +39,41 @@ var (
 		"while updating GraphQL schema: this server isn't group-1 leader, please retry")
 	ErrMultipleGraphQLSchemaNodes = errors.New("found multiple nodes for GraphQL schema")
 	gqlSchemaStore                *GQLSchemaStore
+
+	gqlSchemaNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 )
 
+// GQLSchemaXid returns the xid of the node of the GraphQL schema with the given name. Besides the
+// default GraphQL schema of a namespace, which has no name, a namespace can have named schemas
+// served over the same data.
+func GQLSchemaXid(name string) string {
+	if name == "" {
+		return gqlSchemaXidVal
+	}
+	return gqlSchemaXidVal + "." + name
+}
+
+// GQLSchemaName returns the name of the GraphQL schema whose node has the given xid. The nodes
+// stored without an xid are those of the default schema.
+func GQLSchemaName(xid string) string {
+	if xid == "" || xid == gqlSchemaXidVal {
+		return ""
+	}
+	return strings.TrimPrefix(xid, gqlSchemaXidVal+".")
+}
+
+// ValidateGQLSchemaName returns an error if the name can't be used for a named GraphQL schema.
+func ValidateGQLSchemaName(name string) error {
+	if !gqlSchemaNameRe.MatchString(name) {
+		return errors.Errorf("Invalid GraphQL schema name %q, it must only contain letters,"+
+			" digits, underscores and dashes", name)
+	}
+	return nil
+}
+
 type GqlSchema struct {
 	ID              string `json:"id,omitempty"`
+	Name            string `json:"name,omitempty"`
 	Schema          string `json:"schema,omitempty"`
 	Version         uint64
 	GeneratedSchema string
@@ -51,34 +84,72 @@ type GqlSchema struct {
 type GQLSchemaStore struct {
 	mux    sync.RWMutex
 	schema map[uint64]*GqlSchema
+	// named has the named GraphQL schemas of each namespace.
+	named map[uint64]map[string]*GqlSchema
 }
 
 func NewGQLSchemaStore() *GQLSchemaStore {
 	gqlSchemaStore = &GQLSchemaStore{
 		mux:    sync.RWMutex{},
 		schema: make(map[uint64]*GqlSchema),
+		named:  make(map[uint64]map[string]*GqlSchema),
 	}
 	return gqlSchemaStore
 }
 
 func (gs *GQLSchemaStore) Set(ns uint64, sch *GqlSchema) {
+	gs.SetNamed(ns, "", sch)
+}
+
+func (gs *GQLSchemaStore) GetCurrent(ns uint64) (*GqlSchema, bool) {
+	return gs.GetNamed(ns, "")
+}
+
+// SetNamed sets the GraphQL schema with the given name, the default schema if the name is empty.
+func (gs *GQLSchemaStore) SetNamed(ns uint64, name string, sch *GqlSchema) {
 	gs.mux.Lock()
 	defer gs.mux.Unlock()
-	gs.schema[ns] = sch
+	if name == "" {
+		gs.schema[ns] = sch
+		return
+	}
+	if gs.named[ns] == nil {
+		gs.named[ns] = make(map[string]*GqlSchema)
+	}
+	gs.named[ns][name] = sch
 }
 
-func (gs *GQLSchemaStore) GetCurrent(ns uint64) (*GqlSchema, bool) {
+// GetNamed returns the GraphQL schema with the given name, the default schema if the name is
+// empty.
+func (gs *GQLSchemaStore) GetNamed(ns uint64, name string) (*GqlSchema, bool) {
 	gs.mux.RLock()
 	defer gs.mux.RUnlock()
-	sch, ok := gs.schema[ns]
+	if name == "" {
+		sch, ok := gs.schema[ns]
+		return sch, ok
+	}
+	sch, ok := gs.named[ns][name]
 	return sch, ok
 }
 
+// DeleteNamed removes the named GraphQL schemas of the namespace, and returns their names.
+func (gs *GQLSchemaStore) DeleteNamed(ns uint64) []string {
+	gs.mux.Lock()
+	defer gs.mux.Unlock()
+	var names []string
+	for name := range gs.named[ns] {
+		names = append(names, name)
+	}
+	delete(gs.named, ns)
+	return names
+}
+
 func (gs *GQLSchemaStore) resetGQLSchema() {
 	gs.mux.Lock()
 	defer gs.mux.Unlock()
 
 	gs.schema = make(map[uint64]*GqlSchema)
+	gs.named = make(map[uint64]map[string]*GqlSchema)
 }
 
 func ResetGQLSchemaStore() {
@@ -134,14 +205,7 @@ func (w *grpcWorker) UpdateGraphQLSchema(ctx context.Context,
 	}
 
 	// query the GraphQL schema node uid
-	res, err := ProcessTaskOverNetwork(ctx, &pb.Query{
-		Attr:    x.NamespaceAttr(namespace, GqlSchemaPred),
-		SrcFunc: &pb.SrcFunction{Name: "has"},
-		ReadTs:  req.StartTs,
-		// there can only be one GraphQL schema node,
-		// so querying two just to detect if this condition is ever violated
-		First: 2,
-	})
+	uidList, err := gqlSchemaNodes(ctx, namespace, req.Name, req.StartTs)
 	if err != nil {
 		return nil, err
 	}
@@ -149,8 +213,7 @@ func (w *grpcWorker) UpdateGraphQLSchema(ctx context.Context,
 	// find if we need to create the node or can use the uid from existing node
 	creatingNode := false
 	var schemaNodeUid uint64
-	uidMtrxLen := len(res.GetUidMatrix())
-	if uidMtrxLen == 0 || (uidMtrxLen == 1 && len(res.GetUidMatrix()[0].GetUids()) == 0) {
+	if len(uidList) == 0 {
 		// if there was no schema node earlier, then need to assign a new uid for the node
 		res, err := AssignUidsOverNetwork(ctx, &pb.Num{Val: 1, Type: pb.Num_UID})
 		if err != nil {
@@ -158,13 +221,12 @@ func (w *grpcWorker) UpdateGraphQLSchema(ctx context.Context,
 		}
 		creatingNode = true
 		schemaNodeUid = res.StartId
-	} else if uidMtrxLen == 1 && len(res.GetUidMatrix()[0].GetUids()) == 1 {
+	} else if len(uidList) == 1 {
 		// if there was already a schema node, then just use the uid from that node
-		schemaNodeUid = res.GetUidMatrix()[0].GetUids()[0]
+		schemaNodeUid = uidList[0]
 	} else {
 		// there seems to be multiple nodes for GraphQL schema,Ideally we should never reach here
 		// But if by any bug we reach here then return the schema node which is added last
-		uidList := res.GetUidMatrix()[0].GetUids()
 		sort.Slice(uidList, func(i, j int) bool {
 			return uidList[i] < uidList[j]
 		})
@@ -192,7 +254,7 @@ func (w *grpcWorker) UpdateGraphQLSchema(ctx context.Context,
 				// only be one server which is able to successfully update the GraphQL schema.
 				Entity:    schemaNodeUid,
 				Attr:      x.NamespaceAttr(namespace, gqlSchemaXidPred),
-				Value:     []byte(gqlSchemaXidVal),
+				Value:     []byte(GQLSchemaXid(req.Name)),
 				ValueType: pb.Posting_STRING,
 				Op:        pb.DirectedEdge_SET,
 			},
@@ -244,6 +306,46 @@ func (w *grpcWorker) UpdateGraphQLSchema(ctx context.Context,
 	return &pb.UpdateGraphQLSchemaResponse{Uid: schemaNodeUid}, nil
 }
 
+// gqlSchemaNodes returns the uids of the nodes of the GraphQL schema of the namespace with the
+// given name, read at readTs. There should only be one, but more are returned to detect if this
+// is ever violated.
+func gqlSchemaNodes(ctx context.Context, ns uint64, name string, readTs uint64) ([]uint64, error) {
+	res, err := ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    x.NamespaceAttr(ns, GqlSchemaPred),
+		SrcFunc: &pb.SrcFunction{Name: "has"},
+		ReadTs:  readTs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.GetUidMatrix()) == 0 || len(res.GetUidMatrix()[0].GetUids()) == 0 {
+		return nil, nil
+	}
+
+	// the named schemas are also stored in nodes with a GraphQL schema, so tell them apart from
+	// their xid
+	all := res.GetUidMatrix()[0]
+	res, err = ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    x.NamespaceAttr(ns, gqlSchemaXidPred),
+		UidList: all,
+		ReadTs:  readTs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint64
+	for i, uid := range all.GetUids() {
+		var xid string
+		if i < len(res.GetValueMatrix()) && len(res.GetValueMatrix()[i].GetValues()) > 0 {
+			xid = string(res.GetValueMatrix()[i].GetValues()[0].GetVal())
+		}
+		if GQLSchemaName(xid) == name {
+			uids = append(uids, uid)
+		}
+	}
+	return uids, nil
+}
+
 // WaitForIndexing does a busy wait for indexing to finish or the context to error out,
 // if the input flag shouldWait is true. Otherwise, it just returns nil straight away.
 // If the context errors, it returns that error.
@@ -266,3 +368,9 @@ func WaitForIndexing(ctx context.Context, shouldWait bool) error {
 func isGroupOneLeader() bool {
 	return groups().ServesGroup(1) && groups().Node.AmLeader()
 }
+
+// IsGroupOneLeader is the exported version of isGroupOneLeader, used to run the cluster wide
+// background tasks on a single alpha.
+func IsGroupOneLeader() bool {
+	return isGroupOneLeader()
+}
@@ -0,0 +1,49 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGQLSchemaXid(t *testing.T) {
+	require.Equal(t, "dgraph.graphql.schema", GQLSchemaXid(""))
+	require.Equal(t, "", GQLSchemaName(GQLSchemaXid("")))
+	// the nodes stored before the named schemas, without an xid, are for the default schema
+	require.Equal(t, "", GQLSchemaName(""))
+	require.Equal(t, "internal", GQLSchemaName(GQLSchemaXid("internal")))
+
+	require.NoError(t, ValidateGQLSchemaName("internal-api_v2"))
+	for _, name := range []string{"", "a.b", "a/b", "a b"} {
+		require.Error(t, ValidateGQLSchemaName(name))
+	}
+}
+
+func TestGQLSchemaStoreNamed(t *testing.T) {
+	gs := NewGQLSchemaStore()
+	gs.Set(1, &GqlSchema{Schema: "default"})
+	gs.SetNamed(1, "internal", &GqlSchema{Name: "internal", Schema: "internal"})
+	gs.SetNamed(2, "internal", &GqlSchema{Name: "internal", Schema: "other"})
+
+	sch, ok := gs.GetCurrent(1)
+	require.True(t, ok)
+	require.Equal(t, "default", sch.Schema)
+	sch, ok = gs.GetNamed(1, "internal")
+	require.True(t, ok)
+	require.Equal(t, "internal", sch.Schema)
+	_, ok = gs.GetNamed(1, "public")
+	require.False(t, ok)
+
+	require.Equal(t, []string{"internal"}, gs.DeleteNamed(1))
+	_, ok = gs.GetNamed(1, "internal")
+	require.False(t, ok)
+	_, ok = gs.GetCurrent(1)
+	require.True(t, ok)
+	_, ok = gs.GetNamed(2, "internal")
+	require.True(t, ok)
+}
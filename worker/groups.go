@@ -6,6 +6,7 @@
 package worker
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -22,6 +23,7 @@ import (
 	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/dgraph-io/ristretto/v2/z"
 	"github.com/hypermodeinc/dgraph/v25/conn"
+	"github.com/hypermodeinc/dgraph/v25/posting"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/raftwal"
 	"github.com/hypermodeinc/dgraph/v25/schema"
@@ -98,6 +100,7 @@ func StartRaftNodes(walStore *raftwal.DiskStorage, bindall bool) {
 		GroupId: x.WorkerConfig.ProposedGroupId,
 		Addr:    x.WorkerConfig.MyAddr,
 		Learner: x.WorkerConfig.Raft.GetBool("learner"),
+		Witness: x.WorkerConfig.Raft.GetBool("witness"),
 	}
 	if m.GroupId > 0 {
 		m.ForceGroupId = true
@@ -112,7 +115,7 @@ func StartRaftNodes(walStore *raftwal.DiskStorage, bindall bool) {
 			continue
 		}
 		zc := pb.NewZeroClient(pl.Get())
-		connState, err = zc.Connect(gr.Ctx(), m)
+		connState, err = zc.Connect(x.WithHandshake(gr.Ctx()), m)
 		if err == nil || x.ShouldCrash(err) {
 			break
 		}
@@ -264,6 +267,26 @@ func MaxLeaseId() uint64 {
 	return g.state.MaxUID
 }
 
+// PredicateVersion returns a version of the data of the given namespaced predicate, which changes
+// whenever the predicate is changed. For the predicates served by the group of this Alpha, it's
+// given by the latest commit touching them. For the other predicates, it's the max assigned
+// timestamp, which changes with every commit in the cluster.
+func PredicateVersion(ctx context.Context, attr string) string {
+	if _, ok := schema.State().Get(ctx, attr); !ok {
+		return "none"
+	}
+	gid, err := groups().BelongsToReadOnly(attr, 0)
+	switch {
+	case err == nil && gid == 0:
+		// Nobody serves the predicate yet, so it has no data.
+		return "none"
+	case err != nil || gid != groups().groupId():
+		return fmt.Sprintf("max:%d", posting.Oracle().MaxAssigned())
+	}
+	epoch, commitTs := posting.PredicateVersion(attr)
+	return fmt.Sprintf("%d:%d", epoch, commitTs)
+}
+
 // GetMembershipState returns the current membership state.
 func GetMembershipState() *pb.MembershipState {
 	g := groups()
@@ -302,6 +325,9 @@ func (g *groupi) applyState(myId uint64, state *pb.MembershipState) {
 
 	oldState := g.state
 	g.state = state
+	if !bytes.Equal(oldState.GetConfig(), state.GetConfig()) {
+		x.ApplyClusterConfig(state.GetConfig())
+	}
 
 	// Sometimes this can cause us to lose latest tablet info, but that shouldn't cause any issues.
 	var foundSelf bool
@@ -367,8 +393,10 @@ func (g *groupi) applyState(myId uint64, state *pb.MembershipState) {
 	}
 }
 
+// ServesGroup returns true if this Alpha serves the data of the group gid. A witness is a member
+// of its group, but doesn't serve its data.
 func (g *groupi) ServesGroup(gid uint32) bool {
-	return g.groupId() == gid
+	return g.groupId() == gid && (g.Node == nil || !g.Node.witness)
 }
 
 func (g *groupi) ChecksumsMatch(ctx context.Context) error {
@@ -574,6 +602,10 @@ func (g *groupi) AnyTwoServers(gid uint32) []string {
 	}
 	var res []string
 	for _, m := range group.Members {
+		if m.Witness {
+			// Witnesses don't have any data to serve.
+			continue
+		}
 		// map iteration gives us members in no particular order.
 		res = append(res, m.Addr)
 		if len(res) >= 2 {
@@ -603,6 +635,9 @@ func (g *groupi) members(gid uint32) map[uint64]*pb.Member {
 func (g *groupi) AnyServer(gid uint32) *conn.Pool {
 	members := g.members(gid)
 	for _, m := range members {
+		if m.Witness {
+			continue
+		}
 		pl, err := conn.GetPools().Get(m.Addr)
 		if err == nil {
 			return pl
@@ -612,6 +647,9 @@ func (g *groupi) AnyServer(gid uint32) *conn.Pool {
 }
 
 func (g *groupi) MyPeer() (uint64, bool) {
+	if id, has := g.dataPeer(); has {
+		return id, true
+	}
 	members := g.members(g.groupId())
 	for _, m := range members {
 		if m.Id != g.Node.Id {
@@ -621,6 +659,17 @@ func (g *groupi) MyPeer() (uint64, bool) {
 	return 0, false
 }
 
+// dataPeer returns a peer of this Alpha which isn't a witness.
+func (g *groupi) dataPeer() (uint64, bool) {
+	members := g.members(g.groupId())
+	for _, m := range members {
+		if m.Id != g.Node.Id && !m.Witness {
+			return m.Id, true
+		}
+	}
+	return 0, false
+}
+
 // Leader will try to return the leader of a given group, based on membership information.
 // There is currently no guarantee that the returned server is the leader of the group.
 func (g *groupi) Leader(gid uint32) *conn.Pool {
@@ -742,12 +791,13 @@ func (g *groupi) doSendMembership(tablets map[string]*pb.Tablet) error {
 		Addr:       x.WorkerConfig.MyAddr,
 		Leader:     leader,
 		LastUpdate: uint64(time.Now().Unix()),
+		Witness:    g.Node.witness,
 	}
 	group := &pb.Group{
 		Members: make(map[uint64]*pb.Member),
 	}
 	group.Members[member.Id] = member
-	if leader {
+	if leader && !g.Node.witness {
 		// Do not send tablet information, if I'm not the leader.
 		group.Tablets = tablets
 		if snap, err := g.Node.Snapshot(); err == nil {
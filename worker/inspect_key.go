@@ -0,0 +1,229 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// DefaultInspectLimit is the maximum number of versions and postings of a key returned by
+// InspectKey, unless told otherwise.
+const DefaultInspectLimit = 1000
+
+// KeyVersion is a version of a key in Badger.
+type KeyVersion struct {
+	Ts uint64 `json:"ts"`
+	// Kind is complete, delta, empty or schema, from the user meta of the version.
+	Kind           string `json:"kind"`
+	Size           int64  `json:"size"`
+	Deleted        bool   `json:"deleted,omitempty"`
+	DiscardEarlier bool   `json:"discardEarlier,omitempty"`
+}
+
+// InspectedPosting is a posting of a posting list read at the timestamp of the inspection.
+type InspectedPosting struct {
+	Uid     uint64   `json:"uid"`
+	Type    string   `json:"type,omitempty"`
+	Value   string   `json:"value,omitempty"`
+	Lang    string   `json:"lang,omitempty"`
+	Facets  []string `json:"facets,omitempty"`
+	StartTs uint64   `json:"startTs,omitempty"`
+}
+
+// InspectedSplit is a part of a multi-part posting list.
+type InspectedSplit struct {
+	StartUid uint64 `json:"startUid"`
+	Key      string `json:"key"`
+	Size     int64  `json:"size"`
+}
+
+// KeyInspection is the raw state of a key of the p directory of this Alpha.
+type KeyInspection struct {
+	Key       string `json:"key"`
+	Type      string `json:"type"`
+	Namespace uint64 `json:"namespace"`
+	Predicate string `json:"predicate"`
+	Uid       uint64 `json:"uid,omitempty"`
+	Term      string `json:"term,omitempty"`
+	StartUid  uint64 `json:"startUid,omitempty"`
+	ReadTs    uint64 `json:"readTs"`
+
+	Versions []*KeyVersion `json:"versions"`
+	// Length is the number of postings of the list at ReadTs, -1 for the schema and type keys.
+	Length   int                 `json:"length"`
+	Splits   []*InspectedSplit   `json:"splits,omitempty"`
+	Postings []*InspectedPosting `json:"postings,omitempty"`
+	// Truncated is true if there are more versions or postings than the limit.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// InspectKey returns the versions of the key and its posting list read at readTs, or at the max
+// assigned timestamp if it's 0, with up to limit versions and postings. The key must belong to a
+// predicate served by the group of this Alpha. Nothing is written, not even a rollup.
+func InspectKey(ctx context.Context, key []byte, readTs uint64,
+	limit int) (*KeyInspection, error) {
+
+	pk, err := x.Parse(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing the key %x", key)
+	}
+	if !pk.IsSchema() && !pk.IsType() {
+		if gid, err := groups().BelongsToReadOnly(pk.Attr, 0); err != nil {
+			return nil, err
+		} else if gid != groups().groupId() {
+			return nil, errors.Errorf("Predicate %s isn't served by group %d",
+				x.ParseAttr(pk.Attr), groups().groupId())
+		}
+		if err := tiers().hot(ctx, pk.Attr, false); err != nil {
+			return nil, err
+		}
+	}
+	if readTs == 0 {
+		readTs = posting.Oracle().MaxAssigned()
+	}
+	if limit <= 0 {
+		limit = DefaultInspectLimit
+	}
+
+	ns, attr := x.ParseNamespaceAttr(pk.Attr)
+	res := &KeyInspection{
+		Key:       hex.EncodeToString(key),
+		Type:      keyType(pk),
+		Namespace: ns,
+		Predicate: attr,
+		Uid:       pk.Uid,
+		StartUid:  pk.StartUid,
+		ReadTs:    readTs,
+		Length:    -1,
+	}
+	if len(pk.Term) > 0 {
+		res.Term = hex.EncodeToString([]byte(pk.Term))
+	}
+
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.PrefetchValues = false
+	iopt.Prefix = key
+	it := txn.NewIterator(iopt)
+	defer it.Close()
+	for it.Seek(key); it.Valid(); it.Next() {
+		item := it.Item()
+		if !bytes.Equal(item.Key(), key) {
+			break
+		}
+		if len(res.Versions) == limit {
+			res.Truncated = true
+			break
+		}
+		res.Versions = append(res.Versions, &KeyVersion{
+			Ts:             item.Version(),
+			Kind:           versionKind(item.UserMeta()),
+			Size:           item.EstimatedSize(),
+			Deleted:        item.IsDeletedOrExpired(),
+			DiscardEarlier: item.DiscardEarlierVersions(),
+		})
+	}
+	if pk.IsSchema() || pk.IsType() {
+		return res, nil
+	}
+
+	pl, err := posting.GetNoStore(key, readTs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading the posting list of the key %x", key)
+	}
+	res.Length = pl.Length(readTs, 0)
+	for _, startUid := range pl.PartSplits() {
+		skey, err := x.SplitKey(key, startUid)
+		if err != nil {
+			return nil, err
+		}
+		split := &InspectedSplit{StartUid: startUid, Key: hex.EncodeToString(skey)}
+		if item, err := txn.Get(skey); err == nil {
+			split.Size = item.EstimatedSize()
+		} else if err != badger.ErrKeyNotFound {
+			return nil, err
+		}
+		res.Splits = append(res.Splits, split)
+	}
+	err = pl.Iterate(readTs, 0, func(p *pb.Posting) error {
+		if len(res.Postings) == limit {
+			res.Truncated = true
+			return posting.ErrStopIteration
+		}
+		res.Postings = append(res.Postings, inspectPosting(p))
+		return nil
+	})
+	if err != nil && err != posting.ErrStopIteration {
+		return nil, err
+	}
+	return res, nil
+}
+
+func keyType(pk x.ParsedKey) string {
+	switch {
+	case pk.IsSchema():
+		return "schema"
+	case pk.IsType():
+		return "type"
+	case pk.IsData():
+		return "data"
+	case pk.IsIndex():
+		return "index"
+	case pk.IsReverse():
+		return "reverse"
+	case pk.IsCountRev():
+		return "count-reverse"
+	case pk.IsCount():
+		return "count"
+	default:
+		return "unknown"
+	}
+}
+
+func versionKind(meta byte) string {
+	switch {
+	case meta&posting.BitCompletePosting > 0:
+		return "complete"
+	case meta&posting.BitDeltaPosting > 0:
+		return "delta"
+	case meta&posting.BitEmptyPosting > 0:
+		return "empty"
+	case meta&posting.BitSchemaPosting > 0:
+		return "schema"
+	default:
+		return "unknown"
+	}
+}
+
+func inspectPosting(p *pb.Posting) *InspectedPosting {
+	ip := &InspectedPosting{Uid: p.Uid, Lang: string(p.LangTag), StartTs: p.StartTs}
+	if len(p.Value) > 0 {
+		tid := types.TypeID(p.ValType)
+		ip.Type = tid.Name()
+		if out, err := types.Convert(types.Val{Tid: tid, Value: p.Value},
+			types.StringID); err == nil {
+			ip.Value = out.Value.(string)
+		} else {
+			ip.Value = hex.EncodeToString(p.Value)
+		}
+	}
+	for _, f := range p.Facets {
+		ip.Facets = append(ip.Facets, f.Key)
+	}
+	return ip
+}
@@ -0,0 +1,55 @@
+//go:build integration
+
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestInspectKey(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`friend: [uid] .`), 1))
+	attr := x.AttrInRootNamespace("friend")
+	key := x.DataKey(attr, 1010)
+	for _, uid := range []uint64{11, 12, 13} {
+		edge := &pb.DirectedEdge{ValueId: uid, Attr: attr, Entity: 1010}
+		addEdge(t, edge, getOrCreate(key))
+	}
+	ctx := context.Background()
+
+	res, err := InspectKey(ctx, key, math.MaxUint64, 0)
+	require.NoError(t, err)
+	require.Equal(t, "data", res.Type)
+	require.Equal(t, "friend", res.Predicate)
+	require.Equal(t, uint64(1010), res.Uid)
+	require.Equal(t, 3, res.Length)
+	require.Len(t, res.Postings, 3)
+	require.Equal(t, uint64(12), res.Postings[1].Uid)
+	require.NotEmpty(t, res.Versions)
+	for _, v := range res.Versions {
+		require.Contains(t, []string{"delta", "complete"}, v.Kind)
+	}
+	require.False(t, res.Truncated)
+
+	res, err = InspectKey(ctx, key, math.MaxUint64, 2)
+	require.NoError(t, err)
+	require.Len(t, res.Postings, 2)
+	require.True(t, res.Truncated)
+
+	_, err = InspectKey(ctx, x.DataKey(x.AttrInRootNamespace("friend_not_served"), 1), 0, 0)
+	require.ErrorContains(t, err, "isn't served by group 1")
+	_, err = InspectKey(ctx, []byte("not a key"), 0, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,191 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// snapshotManifestName is the name of the manifest of an uploaded p directory.
+	snapshotManifestName = "manifest.json"
+	// snapshotPDir is the directory the files of an uploaded p directory are uploaded to.
+	snapshotPDir = "p"
+)
+
+// snapshotManifest is the manifest of a p directory uploaded by the bulk loader. It's uploaded
+// after the files of the p directory, so a partial upload is never installed.
+type snapshotManifest struct {
+	// Files maps the paths of the files in the p directory to their sizes.
+	Files map[string]int64 `json:"files"`
+}
+
+// UploadSnapshot uploads the p directory dir to the location, like s3:///bucket/bulk/0, from
+// which an alpha installs it with --install-snapshot.
+func UploadSnapshot(dir string, uri *url.URL) error {
+	h, err := NewUriHandler(uri, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := h.CreateDir(""); err != nil {
+		return err
+	}
+	manifest := snapshotManifest{Files: make(map[string]int64)}
+	err = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		size, err := uploadSnapshotFile(h, file, path.Join(snapshotPDir, name))
+		if err != nil {
+			return errors.Wrapf(err, "while uploading %s", file)
+		}
+		manifest.Files[name] = size
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&manifest)
+	if err != nil {
+		return err
+	}
+	w, err := h.CreateFile(snapshotManifestName)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func uploadSnapshotFile(h UriHandler, file, name string) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if err := h.CreateDir(path.Dir(name)); err != nil {
+		return 0, err
+	}
+	w, err := h.CreateFile(name)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(w, f)
+	if err != nil {
+		_ = w.Close()
+		return 0, err
+	}
+	return size, w.Close()
+}
+
+// InstallSnapshot downloads the p directory uploaded to the location by the bulk loader into dir,
+// unless dir already has data, so that the restarts of the alpha keep its data. The p directory
+// is downloaded next to dir first, so a failed download isn't taken for an installed one.
+func InstallSnapshot(uri *url.URL, dir string) error {
+	if err := x.IsMissingOrEmptyDir(dir); err == nil {
+		glog.Infof("Not installing the snapshot at %s, as the p directory %s isn't empty",
+			uri.Redacted(), dir)
+		return nil
+	} else if err != x.ErrMissingDir {
+		return err
+	}
+
+	h, err := NewUriHandler(uri, nil)
+	if err != nil {
+		return err
+	}
+	if !h.FileExists(snapshotManifestName) {
+		return errors.Errorf("The snapshot at %s has no %s, its upload may not have finished",
+			uri.Redacted(), snapshotManifestName)
+	}
+	data, err := h.Read(snapshotManifestName)
+	if err != nil {
+		return err
+	}
+	var manifest snapshotManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return errors.Wrapf(err, "while reading the %s of the snapshot", snapshotManifestName)
+	}
+
+	tmpDir := filepath.Clean(dir) + ".install"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmpDir, 0700); err != nil {
+		return err
+	}
+	glog.Infof("Installing the snapshot at %s into %s: %d files", uri.Redacted(), dir,
+		len(manifest.Files))
+	for name, size := range manifest.Files {
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return errors.Errorf("Invalid path %q in the %s of the snapshot", name,
+				snapshotManifestName)
+		}
+		file := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := downloadSnapshotFile(h, path.Join(snapshotPDir, name), file, size); err != nil {
+			return errors.Wrapf(err, "while downloading %s", name)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return err
+	}
+	glog.Infof("Installed the snapshot at %s into %s", uri.Redacted(), dir)
+	return nil
+}
+
+func downloadSnapshotFile(h UriHandler, name, file string, size int64) error {
+	r, err := h.Stream(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if n != size {
+		return errors.Errorf("Downloaded %d bytes, expected %d", n, size)
+	}
+	return nil
+}
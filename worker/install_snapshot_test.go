@@ -0,0 +1,43 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallSnapshot(t *testing.T) {
+	pdir := filepath.Join(t.TempDir(), "p")
+	require.NoError(t, os.MkdirAll(pdir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(pdir, "000001.sst"), []byte("sst"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(pdir, "group_id"), []byte("2"), 0600))
+
+	uri := &url.URL{Scheme: "file", Path: filepath.Join(t.TempDir(), "bulk", "1")}
+	dir := filepath.Join(t.TempDir(), "p")
+	// The snapshot isn't installed before its manifest is uploaded.
+	require.Error(t, InstallSnapshot(uri, dir))
+
+	require.NoError(t, UploadSnapshot(pdir, uri))
+	require.NoError(t, InstallSnapshot(uri, dir))
+	data, err := os.ReadFile(filepath.Join(dir, "000001.sst"))
+	require.NoError(t, err)
+	require.Equal(t, "sst", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "group_id"))
+	require.NoError(t, err)
+	require.Equal(t, "2", string(data))
+
+	// A p directory with data is kept as it is.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000001.sst"), []byte("new"), 0600))
+	require.NoError(t, InstallSnapshot(uri, dir))
+	data, err = os.ReadFile(filepath.Join(dir, "000001.sst"))
+	require.NoError(t, err)
+	require.Equal(t, "new", string(data))
+}
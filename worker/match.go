@@ -6,6 +6,10 @@
 package worker
 
 import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/hypermodeinc/dgraph/v25/algo"
 	"github.com/hypermodeinc/dgraph/v25/posting"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
@@ -58,17 +62,69 @@ func min(a, b, c int) int {
 	return c
 }
 
+// levenshteinWithin returns whether the Levenshtein distance between s and t is at most max. Unlike
+// levenshteinDistance, it stops as soon as the distance is known to be above max.
+func levenshteinWithin(s, t string, max int) bool {
+	if len(s) > len(t) {
+		s, t = t, s
+	}
+	r1, r2 := []rune(s), []rune(t)
+	if len(r2)-len(r1) > max {
+		return false
+	}
+	column := make([]int, len(r1)+1)
+	for y := 1; y <= len(r1); y++ {
+		column[y] = y
+	}
+
+	for x := 1; x <= len(r2); x++ {
+		column[0] = x
+		// The distance is at least the minimum of the column, as it never decreases.
+		least := x
+		for y, lastDiag := 1, x-1; y <= len(r1); y++ {
+			oldDiag := column[y]
+			cost := 0
+			if r1[y-1] != r2[x-1] {
+				cost = 1
+			}
+			column[y] = min(column[y]+1, column[y-1]+1, lastDiag+cost)
+			lastDiag = oldDiag
+			if column[y] < least {
+				least = column[y]
+			}
+		}
+		if least > max {
+			return false
+		}
+	}
+	return column[len(r1)] <= max
+}
+
 // matchFuzzy takes in a value (from posting) and compares it to our list of ngram tokens.
 // Returns true if value matches fuzzy tokens, false otherwise.
 func matchFuzzy(query, val string, max int) bool {
 	if val == "" {
 		return false
 	}
-	return levenshteinDistance(val, query) <= max
+	return levenshteinWithin(val, query, max)
+}
+
+// minSharedTrigrams returns the number of trigrams of the term that a value within maxEdits edits
+// of it has at least. An edit removes the trigrams of the term overlapping the bytes of the rune it
+// changes, at most the byte length of the rune plus two.
+func minSharedTrigrams(term string, numTrigrams, maxEdits int) int {
+	runeLen := 1
+	for _, r := range term {
+		if l := utf8.RuneLen(r); l > runeLen {
+			runeLen = l
+		}
+	}
+	return numTrigrams - maxEdits*(runeLen+2)
 }
 
 // uidsForMatch collects a list of uids that "might" match a fuzzy term based on the ngram
-// index. matchFuzzy does the actual fuzzy match.
+// index. matchFuzzy does the actual fuzzy match. A value within the distance of the term has a
+// few of its trigrams at least, so the uids found in fewer trigram lists are skipped.
 // Returns the list of uids even if empty, or an error otherwise.
 func uidsForMatch(attr string, arg funcArgs) (*pb.List, error) {
 	opts := posting.ListOptions{
@@ -97,5 +153,24 @@ func uidsForMatch(attr string, arg funcArgs) (*pb.List, error) {
 			return nil, err
 		}
 	}
-	return algo.MergeSorted(uidMatrix), nil
+
+	shared := minSharedTrigrams(strings.Join(arg.srcFn.tokens, ""), len(tokens),
+		int(arg.srcFn.threshold[0]))
+	if shared <= 1 {
+		return algo.MergeSorted(uidMatrix), nil
+	}
+	counts := make(map[uint64]int)
+	for _, l := range uidMatrix {
+		for _, uid := range l.Uids {
+			counts[uid]++
+		}
+	}
+	out := &pb.List{}
+	for uid, count := range counts {
+		if count >= shared {
+			out.Uids = append(out.Uids, uid)
+		}
+	}
+	slices.Sort(out.Uids)
+	return out, nil
 }
@@ -23,3 +23,23 @@ func TestDistance(t *testing.T) {
 	require.Equal(t, 1, levenshteinDistance("detour", "detoar"))
 	require.Equal(t, 6, levenshteinDistance("detour", "DETOUR"))
 }
+
+func TestLevenshteinWithin(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"detour", "detour"}, {"detour", "det..our"}, {"detour", "..det..our"},
+		{"detour", "DETOUR"}, {"", "abc"}, {"héllo", "hello"},
+	} {
+		d := levenshteinDistance(pair[0], pair[1])
+		for max := 0; max <= 6; max++ {
+			require.Equal(t, d <= max, levenshteinWithin(pair[0], pair[1], max), "%v %d", pair, max)
+		}
+	}
+}
+
+func TestMinSharedTrigrams(t *testing.T) {
+	// detour has the trigrams det, eto, tou, our. An edit, like detaur, removes three of them.
+	require.Equal(t, 1, minSharedTrigrams("detour", 4, 1))
+	require.Equal(t, 4, minSharedTrigrams("detour", 4, 0))
+	// é takes two bytes, and an edit removes up to four of the five trigrams of détour.
+	require.Equal(t, 1, minSharedTrigrams("détour", 5, 1))
+}
@@ -0,0 +1,294 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// MotifEdge is an edge of a motif, over the namespaced predicate Attr, going from the variable at
+// index From to the one at index To.
+type MotifEdge struct {
+	From, To int
+	Attr     string
+}
+
+// MotifQuery is a motif to match, a small subgraph pattern over variables bound to distinct
+// nodes.
+type MotifQuery struct {
+	ReadTs uint64
+	// TypeAttr is the namespaced dgraph.type predicate, and Types the types of the variables, by
+	// index. The variables without a type have an empty one.
+	TypeAttr string
+	Types    []string
+	Edges    []MotifEdge
+	// Start are the sorted candidates of the first variable.
+	Start []uint64
+	// Offset and First paginate the bindings, First is unlimited if zero.
+	Offset, First int
+}
+
+// MatchMotif returns the bindings of the motif, the uids of its variables by index. It's a
+// generic join: the variables are bound one at a time, and the candidates of a variable for a
+// partial binding are the intersection of the adjacency lists of the variables already bound
+// which it is linked to, smallest first. This is worst-case optimal in the size of the output.
+//
+// An edge going to a bound variable is followed backwards if its predicate has @reverse. It's
+// otherwise checked once the variable it starts from has its candidates, as it is for an edge of
+// a variable to itself.
+func MatchMotif(ctx context.Context, q *MotifQuery) ([][]uint64, error) {
+	span := trace.SpanFromContext(ctx)
+	stop := x.SpanTimer(span, "MatchMotif")
+	defer stop()
+
+	m := &motifMatcher{q: q, adj: make(map[motifAdjKey]map[uint64]*pb.List)}
+	n := len(q.Types)
+	bound := make([]bool, n)
+	reversed := make(map[string]bool)
+	for _, e := range q.Edges {
+		reversed[e.Attr] = schema.State().IsReversed(ctx, e.Attr)
+	}
+
+	start := &pb.List{Uids: q.Start}
+	if q.Types[0] != "" {
+		typed, err := m.typeUids(ctx, q.Types[0])
+		if err != nil {
+			return nil, err
+		}
+		start = algo.IntersectSorted([]*pb.List{start, typed})
+	}
+	bindings := make([][]uint64, 0, len(start.Uids))
+	for _, uid := range start.Uids {
+		b := make([]uint64, n)
+		b[0] = uid
+		bindings = append(bindings, b)
+	}
+	bound[0] = true
+	var err error
+	if bindings, err = m.check(ctx, bindings, 0, bound, reversed); err != nil {
+		return nil, err
+	}
+
+	for step := 1; step < n && len(bindings) > 0; step++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		v, usable := m.next(bound, reversed)
+		if v < 0 {
+			return nil, errors.Errorf("A variable of the motif can only be reached through edges " +
+				"going to the other ones, whose predicates need @reverse")
+		}
+
+		var next [][]uint64
+		for _, e := range usable {
+			// The adjacency lists of all the partial bindings are fetched at once.
+			reverse, other := false, e.From
+			if e.From == v {
+				reverse, other = true, e.To
+			}
+			if err := m.fetch(ctx, e.Attr, reverse, bindings, other); err != nil {
+				return nil, err
+			}
+		}
+		var typed *pb.List
+		if q.Types[v] != "" {
+			if typed, err = m.typeUids(ctx, q.Types[v]); err != nil {
+				return nil, err
+			}
+		}
+		for _, b := range bindings {
+			lists := make([]*pb.List, 0, len(usable)+1)
+			for _, e := range usable {
+				if e.From == v {
+					lists = append(lists, m.list(e.Attr, true, b[e.To]))
+				} else {
+					lists = append(lists, m.list(e.Attr, false, b[e.From]))
+				}
+			}
+			if typed != nil {
+				lists = append(lists, typed)
+			}
+			for _, uid := range algo.IntersectSorted(lists).Uids {
+				if isBound(b, uid) {
+					// The variables are bound to distinct nodes.
+					continue
+				}
+				nb := append([]uint64(nil), b...)
+				nb[v] = uid
+				next = append(next, nb)
+			}
+		}
+		bound[v] = true
+		if bindings, err = m.check(ctx, next, v, bound, reversed); err != nil {
+			return nil, err
+		}
+	}
+
+	span.AddEvent("MatchMotif result", trace.WithAttributes(
+		attribute.Int("bindings", len(bindings))))
+	from, to := x.PageRange(q.First, q.Offset, len(bindings))
+	return bindings[from:to], nil
+}
+
+type motifAdjKey struct {
+	attr    string
+	reverse bool
+}
+
+type motifMatcher struct {
+	q     *MotifQuery
+	adj   map[motifAdjKey]map[uint64]*pb.List
+	types map[string]*pb.List
+}
+
+// next returns the unbound variable to bind next, the one with the most edges to the bound
+// variables that can give its candidates, along with these edges. It returns -1 if there is none.
+func (m *motifMatcher) next(bound []bool, reversed map[string]bool) (int, []MotifEdge) {
+	best := -1
+	var bestEdges []MotifEdge
+	for v := range bound {
+		if bound[v] {
+			continue
+		}
+		var usable []MotifEdge
+		for _, e := range m.q.Edges {
+			switch {
+			case e.To == v && e.From != v && bound[e.From]:
+				usable = append(usable, e)
+			case e.From == v && e.To != v && bound[e.To] && reversed[e.Attr]:
+				usable = append(usable, e)
+			}
+		}
+		if len(usable) > len(bestEdges) {
+			best, bestEdges = v, usable
+		}
+	}
+	return best, bestEdges
+}
+
+// check keeps the bindings having the edges of v to the bound variables which weren't followed
+// backwards to find its candidates, and to itself.
+func (m *motifMatcher) check(ctx context.Context, bindings [][]uint64, v int, bound []bool,
+	reversed map[string]bool) ([][]uint64, error) {
+
+	for _, e := range m.q.Edges {
+		if e.From != v || !bound[e.To] || (e.To != v && reversed[e.Attr]) {
+			continue
+		}
+		if err := m.fetch(ctx, e.Attr, false, bindings, v); err != nil {
+			return nil, err
+		}
+		out := bindings[:0]
+		for _, b := range bindings {
+			if algo.IndexOf(m.list(e.Attr, false, b[v]), b[e.To]) >= 0 {
+				out = append(out, b)
+			}
+		}
+		bindings = out
+	}
+	return bindings, nil
+}
+
+// fetch reads the adjacency lists of the predicate for the uids of the variable at index i of the
+// bindings, which haven't been read yet.
+func (m *motifMatcher) fetch(ctx context.Context, attr string, reverse bool,
+	bindings [][]uint64, i int) error {
+
+	key := motifAdjKey{attr: attr, reverse: reverse}
+	lists, ok := m.adj[key]
+	if !ok {
+		lists = make(map[uint64]*pb.List)
+		m.adj[key] = lists
+	}
+	var uids []uint64
+	seen := make(map[uint64]struct{})
+	for _, b := range bindings {
+		if _, ok := lists[b[i]]; ok {
+			continue
+		}
+		if _, ok := seen[b[i]]; !ok {
+			seen[b[i]] = struct{}{}
+			uids = append(uids, b[i])
+		}
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+
+	result, err := ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    attr,
+		Reverse: reverse,
+		UidList: &pb.List{Uids: uids},
+		ReadTs:  m.q.ReadTs,
+	})
+	switch {
+	case err != nil && strings.Contains(err.Error(), ErrNonExistentTabletMessage):
+		result = &pb.Result{}
+	case err != nil:
+		return err
+	}
+	for j, uid := range uids {
+		if j < len(result.UidMatrix) {
+			lists[uid] = result.UidMatrix[j]
+		} else {
+			lists[uid] = &pb.List{}
+		}
+	}
+	return nil
+}
+
+// list returns the adjacency list of the uid, read by fetch.
+func (m *motifMatcher) list(attr string, reverse bool, uid uint64) *pb.List {
+	if l := m.adj[motifAdjKey{attr: attr, reverse: reverse}][uid]; l != nil {
+		return l
+	}
+	return &pb.List{}
+}
+
+// typeUids returns the uids of the nodes of the type, read from the index of dgraph.type.
+func (m *motifMatcher) typeUids(ctx context.Context, typ string) (*pb.List, error) {
+	if l, ok := m.types[typ]; ok {
+		return l, nil
+	}
+	result, err := ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    m.q.TypeAttr,
+		SrcFunc: &pb.SrcFunction{Name: "eq", Args: []string{typ}},
+		ReadTs:  m.q.ReadTs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	l := &pb.List{}
+	if len(result.UidMatrix) > 0 {
+		l = result.UidMatrix[0]
+	}
+	if m.types == nil {
+		m.types = make(map[string]*pb.List)
+	}
+	m.types[typ] = l
+	return l, nil
+}
+
+func isBound(b []uint64, uid uint64) bool {
+	for _, u := range b {
+		if u == uid {
+			return true
+		}
+	}
+	return false
+}
@@ -217,6 +217,12 @@ func runSchemaMutation(ctx context.Context, updates []*pb.SchemaUpdate, startTs
 
 	var closer *z.Closer
 	for _, su := range updates {
+		if su.RenameFrom != "" {
+			if err := renamePredicate(ctx, su, startTs); err != nil {
+				return err
+			}
+			continue
+		}
 		if tablet, err := groups().Tablet(su.Predicate); err != nil {
 			return err
 		} else if tablet.GetGroupId() != groups().groupId() {
@@ -228,6 +234,10 @@ func runSchemaMutation(ctx context.Context, updates []*pb.SchemaUpdate, startTs
 		}
 
 		old, ok := schema.State().Get(ctx, su.Predicate)
+		if ok {
+			// The values compressed with the dictionaries of the predicate must stay readable.
+			su.CompressionDicts = mergeCompressionDicts(old.CompressionDicts, su.CompressionDicts)
+		}
 		rebuild := posting.IndexRebuild{
 			Attr:          su.Predicate,
 			StartTs:       startTs,
@@ -416,6 +426,11 @@ func checkSchema(s *pb.SchemaUpdate) error {
 			x.ParseAttr(s.Predicate))
 	}
 
+	if s.Upsert && s.Conflict != "" {
+		return errors.Errorf("@conflict(%s) can't be used with @upsert on predicate %s, which"+
+			" needs the conflicts on its index keys", s.Conflict, x.ParseAttr(s.Predicate))
+	}
+
 	if s.Unique {
 		ctx := context.WithValue(context.Background(), schema.IsWrite, false)
 		prevSchema, _ := schema.State().Get(ctx, s.Predicate)
@@ -685,6 +700,63 @@ func proposeOrSend(ctx context.Context, gid uint32, m *pb.Mutations, chr chan re
 	chr <- res
 }
 
+// proposeInParts proposes the mutation like proposeOrSend, in parts of at most proposal-edges
+// edges proposed one after the other, so that a large mutation doesn't make a large proposal.
+// The parts are applied in the transaction of the mutation, like the mutations of a transaction.
+func proposeInParts(ctx context.Context, gid uint32, m *pb.Mutations, chr chan res) {
+	parts := splitMutations(m, x.Config.LimitProposalEdges)
+	if len(parts) == 1 {
+		proposeOrSend(ctx, gid, m, chr)
+		return
+	}
+
+	partCh := make(chan res, 1)
+	merged := res{ctx: &api.TxnContext{}}
+	for _, part := range parts {
+		proposeOrSend(ctx, gid, part, partCh)
+		pres := <-partCh
+		if pres.ctx != nil {
+			merged.ctx.Keys = append(merged.ctx.Keys, pres.ctx.Keys...)
+			merged.ctx.Preds = append(merged.ctx.Preds, pres.ctx.Preds...)
+		}
+		if pres.err != nil {
+			merged.err = pres.err
+			break
+		}
+	}
+	chr <- merged
+}
+
+// splitMutations splits the edges of the mutation into mutations of at most maxEdges edges.
+// The mutations changing the schema, the types, or dropping data, are kept whole.
+func splitMutations(m *pb.Mutations, maxEdges int) []*pb.Mutations {
+	if maxEdges <= 0 || len(m.Edges) <= maxEdges || len(m.Schema) > 0 || len(m.Types) > 0 ||
+		m.DropOp != pb.Mutations_NONE {
+		return []*pb.Mutations{m}
+	}
+	for _, edge := range m.Edges {
+		if isDropPredicateEdge(edge) {
+			return []*pb.Mutations{m}
+		}
+	}
+
+	var parts []*pb.Mutations
+	for edges := m.Edges; len(edges) > 0; {
+		n := maxEdges
+		if len(edges) < n {
+			n = len(edges)
+		}
+		parts = append(parts, &pb.Mutations{
+			GroupId:  m.GroupId,
+			StartTs:  m.StartTs,
+			Edges:    edges[:n],
+			Metadata: m.Metadata,
+		})
+		edges = edges[n:]
+	}
+	return parts
+}
+
 // populateMutationMap populates a map from group id to the mutation that
 // should be sent to that group.
 func populateMutationMap(src *pb.Mutations) (map[uint32]*pb.Mutations, error) {
@@ -775,7 +847,7 @@ func MutateOverNetwork(ctx context.Context, m *pb.Mutations) (*api.TxnContext, e
 			return tctx, errNonExistentTablet
 		}
 		mu.StartTs = m.StartTs
-		go proposeOrSend(ctx, gid, mu, resCh)
+		go proposeInParts(ctx, gid, mu, resCh)
 	}
 
 	// Wait for all the goroutines to reply back.
@@ -947,7 +1019,16 @@ func (w *grpcWorker) proposeAndWait(ctx context.Context, txnCtx *api.TxnContext,
 	}
 
 	node := groups().Node
+	if posting.Oracle().GetTxn(m.StartTs) == nil && node.expiredTxns.has(m.StartTs) {
+		return x.ErrTxnExpired
+	}
 	err := node.proposeAndWait(ctx, &pb.Proposal{Mutations: m})
+	if m.Heartbeat {
+		if err != nil {
+			return err
+		}
+		return node.checkTxnAlive(m.StartTs)
+	}
 	// When we are filling txn context, we don't need to update latest delta if the transaction has failed.
 	fillTxnContext(txnCtx, m.StartTs, err != nil)
 	return err
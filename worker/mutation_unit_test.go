@@ -253,3 +253,24 @@ func TestTypeSanityCheck(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "Field in type definition cannot have tokenizers")
 }
+
+func TestSplitMutations(t *testing.T) {
+	edges := make([]*pb.DirectedEdge, 5)
+	for i := range edges {
+		edges[i] = &pb.DirectedEdge{Entity: uint64(i + 1), Attr: x.AttrInRootNamespace("name")}
+	}
+	m := &pb.Mutations{GroupId: 1, StartTs: 10, Edges: edges}
+	parts := splitMutations(m, 2)
+	require.Len(t, parts, 3)
+	require.Equal(t, edges[4:], parts[2].Edges)
+	for _, part := range parts {
+		require.Equal(t, uint64(10), part.StartTs)
+	}
+
+	require.Len(t, splitMutations(m, 0), 1)
+	require.Len(t, splitMutations(m, 5), 1)
+	withSchema := &pb.Mutations{Edges: edges, Schema: []*pb.SchemaUpdate{{}}}
+	require.Len(t, splitMutations(withSchema, 2), 1)
+	drop := &pb.Mutations{Edges: append([]*pb.DirectedEdge{{Value: []byte(x.Star)}}, edges...)}
+	require.Len(t, splitMutations(drop, 2), 1)
+}
@@ -54,6 +54,7 @@ func populateKeyValues(ctx context.Context, kvs []*bpb.KV) error {
 	if err != nil {
 		return errors.Errorf("while parsing KV: %+v, got error: %v", kvs[0], err)
 	}
+	posting.GetStatsHolder().DeleteCountHistograms(pk.Attr)
 	return schema.Load(pk.Attr)
 }
 
@@ -276,6 +277,10 @@ func movePredicateHelper(ctx context.Context, in *pb.MovePredicatePayload) error
 		return errors.Wrapf(err, "while calling ReceivePredicate")
 	}
 
+	if err := tiers().hot(ctx, in.Predicate, false); err != nil {
+		return err
+	}
+
 	txn := pstore.NewTransactionAt(in.TxnTs, false)
 	defer txn.Discard()
 
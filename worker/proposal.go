@@ -134,6 +134,11 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.Proposal) (perr
 		ctx, _ = tag.New(ctx, tag.Upsert(x.KeyStatus, v))
 		timeMs := x.SinceMs(startTime)
 		ostats.Record(ctx, x.LatencyMs.M(timeMs))
+		if x.FlightRecorderEnabled() {
+			x.RecordFlightEvent("proposal", "group=%d bytes=%d edges=%d latency=%s err=%v",
+				groups().groupId(), proto.Size(proposal), len(proposal.GetMutations().GetEdges()),
+				time.Since(startTime), perr)
+		}
 	}()
 
 	if n.Raft() == nil {
@@ -195,6 +200,12 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.Proposal) (perr
 			noTimeout = true
 		}
 	}
+	if queueable(proposal) {
+		// Hold back the mutations of the predicates whose queues can't keep up.
+		if err := n.applyQueues.admit(ctx, proposal.Mutations.Edges); err != nil {
+			return err
+		}
+	}
 
 	// Let's keep the same key, so multiple retries of the same proposal would
 	// have this shared key. Thus, each server in the group can identify
@@ -233,7 +244,12 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.Proposal) (perr
 			attribute.Int64("key", int64(key)),
 			attribute.String("timeout", timeout.String())))
 
-		if err = n.Raft().Propose(cctx, data); err != nil {
+		if n.proposalBatches != nil {
+			err = n.proposalBatches.propose(cctx, data)
+		} else {
+			err = n.Raft().Propose(cctx, data)
+		}
+		if err != nil {
 			return errors.Wrapf(err, "While proposing")
 		}
 
@@ -246,7 +262,8 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.Proposal) (perr
 				// We arrived here by a call to n.Proposals.Done().
 				return err
 			case <-ctx.Done():
-				glog.Warningf("Context expired while processing proposal %v", ctx.Err())
+				glog.Warningf("%sContext expired while processing proposal %v",
+					x.RequestLogPrefix(ctx), ctx.Err())
 				return ctx.Err()
 			case <-timer.C:
 				if atomic.LoadUint32(&pctx.Found) > 0 {
@@ -257,7 +274,8 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.Proposal) (perr
 					cancel()
 				}
 			case <-cctx.Done():
-				glog.Warningf("Internal context expired while processing proposal %v", cctx.Err())
+				glog.Warningf("%sInternal context expired while processing proposal %v",
+					x.RequestLogPrefix(ctx), cctx.Err())
 				return errInternalRetry
 			}
 		}
@@ -0,0 +1,156 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+// minAdaptiveWindow is the narrowest non-zero window of the adaptive mode. The window drops to
+// zero below it.
+const minAdaptiveWindow = 100 * time.Microsecond
+
+// proposalBatcher groups the proposals made within a window into a single Raft message, like a
+// group commit, so that many small mutations share the appends and the broadcasts of the leader
+// without the clients batching them. The proposals of a batch are still applied and acknowledged
+// one by one.
+type proposalBatcher struct {
+	// window is the time the first proposal of a batch waits for the others, in nanoseconds.
+	window atomic.Int64
+	// maxWindow is the window of the fixed mode, and the widest window of the adaptive mode.
+	maxWindow time.Duration
+	// adaptive widens the window while the batches fill up under load, up to maxWindow, and
+	// narrows it back down to zero while the proposals come one at a time.
+	adaptive bool
+	// maxBytes is the size of the proposals from which a batch is proposed without waiting for
+	// the end of its window.
+	maxBytes int
+
+	ch   chan *batchedProposal
+	step func(ctx context.Context, entries []raftpb.Entry) error
+}
+
+type batchedProposal struct {
+	data  []byte
+	errCh chan error
+}
+
+// newProposalBatcher returns the batcher of the proposals stepped into Raft with step, or nil if
+// the proposals aren't batched.
+func newProposalBatcher(window time.Duration, maxBytes int, adaptive bool,
+	step func(ctx context.Context, entries []raftpb.Entry) error) *proposalBatcher {
+
+	if window <= 0 {
+		return nil
+	}
+	b := &proposalBatcher{
+		maxWindow: window,
+		adaptive:  adaptive,
+		maxBytes:  maxBytes,
+		ch:        make(chan *batchedProposal, 1024),
+		step:      step,
+	}
+	if !adaptive {
+		b.window.Store(int64(window))
+	}
+	go b.run()
+	return b
+}
+
+// propose adds the proposal to the current batch, and returns once its batch has been stepped
+// into Raft. Like raft.Node.Propose, it doesn't wait for the proposal to be committed.
+func (b *proposalBatcher) propose(ctx context.Context, data []byte) error {
+	p := &batchedProposal{data: data, errCh: make(chan error, 1)}
+	select {
+	case b.ch <- p:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-p.errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *proposalBatcher) run() {
+	for first := range b.ch {
+		batch := b.collect(first)
+		entries := make([]raftpb.Entry, len(batch))
+		for i, p := range batch {
+			entries[i] = raftpb.Entry{Data: p.data}
+		}
+		// A dropped batch isn't reported by Step, its proposals are retried once they time out,
+		// like the proposals lost on the way to the leader.
+		err := b.step(context.Background(), entries)
+		for _, p := range batch {
+			p.errCh <- err
+		}
+		if b.adaptive {
+			b.adapt(len(batch))
+		}
+	}
+}
+
+// collect returns the batch of the first proposal, with the proposals received until the end of
+// its window, or until the batch is maxBytes big. With a zero window, the batch holds the
+// proposals already waiting.
+func (b *proposalBatcher) collect(first *batchedProposal) []*batchedProposal {
+	batch := []*batchedProposal{first}
+	size := len(first.data)
+	window := time.Duration(b.window.Load())
+	var timeout <-chan time.Time
+	if window > 0 {
+		timer := time.NewTimer(window)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for size < b.maxBytes {
+		var p *batchedProposal
+		if timeout == nil {
+			select {
+			case p = <-b.ch:
+			default:
+				return batch
+			}
+		} else {
+			select {
+			case p = <-b.ch:
+			case <-timeout:
+				return batch
+			}
+		}
+		batch = append(batch, p)
+		size += len(p.data)
+	}
+	return batch
+}
+
+// adapt doubles the window after a batch of several proposals, and halves it after a batch of a
+// single proposal.
+func (b *proposalBatcher) adapt(batched int) {
+	window := time.Duration(b.window.Load())
+	switch {
+	case batched > 1:
+		if window *= 2; window < minAdaptiveWindow {
+			window = minAdaptiveWindow
+		}
+		if window > b.maxWindow {
+			window = b.maxWindow
+		}
+	case window/2 < minAdaptiveWindow:
+		window = 0
+	default:
+		window /= 2
+	}
+	b.window.Store(int64(window))
+}
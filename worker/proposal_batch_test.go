@@ -0,0 +1,71 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+func TestProposalBatcher(t *testing.T) {
+	require.Nil(t, newProposalBatcher(0, 1<<10, false, nil))
+
+	var mu sync.Mutex
+	var steps [][]raftpb.Entry
+	b := newProposalBatcher(50*time.Millisecond, 1<<10, false,
+		func(ctx context.Context, entries []raftpb.Entry) error {
+			mu.Lock()
+			steps = append(steps, entries)
+			mu.Unlock()
+			return nil
+		})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, b.propose(context.Background(), []byte("proposal")))
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	var entries int
+	for _, step := range steps {
+		entries += len(step)
+	}
+	require.Equal(t, 10, entries)
+	require.Less(t, len(steps), 10)
+	mu.Unlock()
+
+	// A batch bigger than the max bytes is sent without waiting for the end of its window.
+	start := time.Now()
+	require.NoError(t, b.propose(context.Background(), make([]byte, 2<<10)))
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestProposalBatcherAdapt(t *testing.T) {
+	b := &proposalBatcher{maxWindow: time.Millisecond, adaptive: true}
+	b.adapt(1)
+	require.Zero(t, b.window.Load())
+	b.adapt(3)
+	require.Equal(t, int64(minAdaptiveWindow), b.window.Load())
+	for range 10 {
+		b.adapt(3)
+	}
+	require.Equal(t, int64(time.Millisecond), b.window.Load())
+	b.adapt(1)
+	require.Equal(t, int64(time.Millisecond/2), b.window.Load())
+	for range 3 {
+		b.adapt(1)
+	}
+	require.Zero(t, b.window.Load())
+}
@@ -0,0 +1,125 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dgraph-io/badger/v4"
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// ServeTabletAlongside has the predicate attr served by the group serving the predicate other,
+// so that a schema update can rename other to attr within the group.
+func ServeTabletAlongside(attr, other string) error {
+	gid, err := groups().BelongsToReadOnly(other, 0)
+	if err != nil {
+		return err
+	}
+	if gid == 0 {
+		return errors.Errorf("Predicate %s isn't served by any group", x.ParseAttr(other))
+	}
+	tablet, err := groups().sendTablet(&pb.Tablet{GroupId: gid, Predicate: attr})
+	if err != nil {
+		return err
+	}
+	if tablet.GetGroupId() != gid {
+		return errors.Errorf("Predicate %s is served by group %d, while %s is served by group %d",
+			x.ParseAttr(attr), tablet.GetGroupId(), x.ParseAttr(other), gid)
+	}
+	return nil
+}
+
+// renamePredicate creates the predicate of the schema update by renaming su.RenameFrom at ts.
+// The posting lists of the predicate, including its indexes, reverse edges and counts, are
+// written again under the new name, and the predicate is dropped. The new predicate takes the
+// schema of the renamed one.
+func renamePredicate(ctx context.Context, su *pb.SchemaUpdate, ts uint64) error {
+	from, to := su.RenameFrom, su.Predicate
+	for _, attr := range []string{from, to} {
+		if tablet, err := groups().Tablet(attr); err != nil {
+			return err
+		} else if tablet.GetGroupId() != groups().groupId() {
+			return errors.Errorf("Tablet isn't being served by this group. Tablet: %+v", tablet)
+		}
+	}
+	if _, ok := schema.State().Get(ctx, to); ok {
+		return errors.Errorf("Predicate %s already exists", x.ParseAttr(to))
+	}
+	old, ok := schema.State().Get(ctx, from)
+	if !ok {
+		return errors.Errorf("Predicate %s doesn't exist", x.ParseAttr(from))
+	}
+	if len(old.IndexSpecs) > 0 {
+		return errors.Errorf("Predicate %s has a vector index, which can't be renamed",
+			x.ParseAttr(from))
+	}
+
+	glog.Infof("Renaming predicate %s to %s at ts %d", from, to, ts)
+	posting.ResetCache()
+	writer := posting.NewTxnWriter(pstore)
+	var keys atomic.Uint64
+	stream := pstore.NewStreamAt(ts)
+	stream.LogPrefix = fmt.Sprintf("Renaming predicate %s to %s:", from, to)
+	stream.Prefix = x.PredicatePrefix(from)
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		// Like a predicate move, write a single version of each posting list at ts, so that
+		// the splits of the lists are written again as well.
+		l, err := posting.ReadPostingList(key, itr)
+		if err != nil {
+			return nil, err
+		}
+		kvs, err := l.Rollup(itr.Alloc, math.MaxUint64)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range kvs {
+			if kv.Key, err = x.ReplaceAttr(kv.Key, to); err != nil {
+				return nil, err
+			}
+			kv.Version = ts
+		}
+		keys.Add(1)
+		return &bpb.KVList{Kv: kvs}, nil
+	}
+	stream.Send = func(buf *z.Buffer) error {
+		kvs, err := badger.BufferToKVList(buf)
+		if err != nil {
+			return err
+		}
+		return writer.Write(kvs)
+	}
+	if err := stream.Orchestrate(ctx); err != nil {
+		return errors.Wrapf(err, "while renaming predicate %s", x.ParseAttr(from))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	renamed := proto.Clone(&old).(*pb.SchemaUpdate)
+	renamed.Predicate = to
+	if err := updateSchema(renamed, ts); err != nil {
+		return err
+	}
+	if err := posting.DeletePredicate(ctx, from, ts); err != nil {
+		return err
+	}
+	posting.ResetCache()
+	glog.Infof("Renamed predicate %s to %s, with %d posting lists", from, to, keys.Load())
+	return nil
+}
@@ -0,0 +1,162 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	ostats "go.opencensus.io/stats"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// asyncReverseBatch is the number of pending async reverse edges from which they're added without
+// waiting for the next tick.
+const asyncReverseBatch = 100000
+
+// asyncReverse holds the reverse edges of the committed transactions on the predicates with
+// @reverse(async), until they're added to the reverse keys in batches by processAsyncReverse. The
+// Raft snapshots and checkpoints don't go past the transactions whose edges are pending, so that
+// they're queued again by the replay of the Raft log after a restart.
+type asyncReverse struct {
+	sync.Mutex
+	// maxLag is the maximum time the edges of a transaction wait before being added.
+	maxLag  time.Duration
+	pending []*asyncReverseTxn
+	edges   int
+	// full is signaled when asyncReverseBatch edges are pending.
+	full chan struct{}
+}
+
+type asyncReverseTxn struct {
+	startTs   uint64
+	edges     []*pb.DirectedEdge
+	committed time.Time
+}
+
+func newAsyncReverse(maxLag time.Duration) *asyncReverse {
+	return &asyncReverse{
+		maxLag: maxLag,
+		full:   make(chan struct{}, 1),
+	}
+}
+
+// push queues the async reverse edges of the committed transaction.
+func (q *asyncReverse) push(txn *posting.Txn) {
+	edges := txn.AsyncReverseEdges()
+	if len(edges) == 0 {
+		return
+	}
+	q.Lock()
+	defer q.Unlock()
+	q.pending = append(q.pending, &asyncReverseTxn{
+		startTs:   txn.StartTs,
+		edges:     edges,
+		committed: time.Now(),
+	})
+	q.edges += len(edges)
+	if q.edges >= asyncReverseBatch {
+		select {
+		case q.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// minStartTs returns the lowest start ts of the transactions whose edges are pending, or
+// math.MaxUint64 if there are none.
+func (q *asyncReverse) minStartTs() uint64 {
+	q.Lock()
+	defer q.Unlock()
+	minTs := uint64(math.MaxUint64)
+	for _, txn := range q.pending {
+		if txn.startTs < minTs {
+			minTs = txn.startTs
+		}
+	}
+	return minTs
+}
+
+// lag returns how long the oldest pending edges have been waiting, and the number of pending
+// edges.
+func (q *asyncReverse) lag() (time.Duration, int) {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.pending) == 0 {
+		return 0, 0
+	}
+	return time.Since(q.pending[0].committed), q.edges
+}
+
+// apply adds the pending edges to the reverse keys. They stay pending until they're written, so
+// that the snapshots can't go past them meanwhile.
+func (q *asyncReverse) apply(ctx context.Context) error {
+	q.Lock()
+	txns := q.pending
+	q.Unlock()
+
+	var edges []*pb.DirectedEdge
+	for _, txn := range txns {
+		edges = append(edges, txn.edges...)
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	ts, err := posting.ApplyAsyncReverse(ctx, edges, State.GetTimestamp)
+	if err != nil {
+		return err
+	}
+	glog.V(2).Infof("Added %d async reverse edges of %d transactions at ts %d",
+		len(edges), len(txns), ts)
+
+	q.Lock()
+	defer q.Unlock()
+	q.pending = append([]*asyncReverseTxn(nil), q.pending[len(txns):]...)
+	q.edges -= len(edges)
+	return nil
+}
+
+// processAsyncReverse adds the pending async reverse edges every half of their maximum lag, or as
+// soon as a batch of them is pending.
+func (n *node) processAsyncReverse() {
+	defer n.closer.Done() // CLOSER:1
+	q := n.asyncReverse
+	interval := q.maxLag / 2
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-n.closer.HasBeenClosed():
+			return
+		case <-tick.C:
+		case <-q.full:
+		}
+		lag, edges := q.lag()
+		ostats.Record(n.ctx, x.ReverseAsyncLag.M(lag.Milliseconds()),
+			x.ReverseAsyncPending.M(int64(edges)))
+		if edges == 0 {
+			continue
+		}
+		if lag > q.maxLag {
+			glog.Warningf("The async reverse edges are added %v after their mutations are "+
+				"committed, above the maximum lag of %v. %d edges are pending.",
+				lag.Round(time.Millisecond), q.maxLag, edges)
+		}
+		if err := q.apply(n.ctx); err != nil {
+			glog.Errorf("Error while adding the async reverse edges: %v", err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hypermodeinc/dgraph/v25/codec"
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// reverseScanWarned holds the predicates which were already scanned to be traversed backwards,
+// so that the warning is logged once per predicate.
+var reverseScanWarned sync.Map
+
+// scanReverse finds the reverse edges of the uids of the query, when its predicate doesn't have
+// @reverse, by scanning the data of the predicate for the edges going to them. It returns a cache
+// holding their reverse lists, like the ones of the reverse index, from which the query is then
+// processed as usual. The scan fails above x.Config.LimitReverseScanKeys keys.
+func scanReverse(ctx context.Context, q *pb.Query) (*posting.LocalCache, error) {
+	attr := q.Attr
+	if typ, err := schema.State().TypeOf(attr); err != nil || typ != types.UidID {
+		return nil, errors.Errorf("Predicate %s doesn't have reverse edge", x.ParseAttr(attr))
+	}
+	if _, warned := reverseScanWarned.LoadOrStore(attr, struct{}{}); !warned {
+		glog.Warningf("Scanning the tablet of %s to traverse it backwards, add @reverse to its "+
+			"schema to use the reverse index instead", x.ParseAttr(attr))
+	}
+	span := trace.SpanFromContext(ctx)
+	stop := x.SpanTimer(span, "scanReverse")
+	defer stop()
+
+	uids := q.UidList.GetUids()
+	index := make(map[uint64]int, len(uids))
+	for i, uid := range uids {
+		index[uid] = i
+	}
+	lists := make([]*pb.PostingList, len(uids))
+	srcs := make([][]uint64, len(uids))
+	for i := range lists {
+		lists[i] = &pb.PostingList{}
+	}
+
+	txn := pstore.NewTransactionAt(q.ReadTs, false)
+	defer txn.Discard()
+	iterOpts := badger.DefaultIteratorOptions
+	iterOpts.AllVersions = true
+	iterOpts.Prefix = x.ParsedKey{Attr: attr}.DataPrefix()
+	it := txn.NewIterator(iterOpts)
+	defer it.Close()
+
+	var keys uint64
+	var lastKey []byte
+	for it.Rewind(); it.Valid(); {
+		item := it.Item()
+		if bytes.Equal(lastKey, item.Key()) {
+			it.Next()
+			continue
+		}
+		lastKey = append(lastKey[:0], item.Key()...)
+		pk, err := x.Parse(lastKey)
+		if err != nil {
+			return nil, err
+		}
+		if pk.HasStartUid {
+			// The parts of a multi-part list are read through its main key.
+			continue
+		}
+		if keys++; keys > x.Config.LimitReverseScanKeys {
+			return nil, errors.Errorf("Traversing ~%s backwards needs @reverse in its schema, its "+
+				"tablet has more than %d keys to scan", x.ParseAttr(attr),
+				x.Config.LimitReverseScanKeys)
+		}
+		if keys%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		pl, err := posting.ReadPostingList(item.KeyCopy(nil), it)
+		if err != nil {
+			return nil, err
+		}
+		err = pl.Iterate(q.ReadTs, 0, func(p *pb.Posting) error {
+			i, ok := index[p.Uid]
+			if !ok {
+				return nil
+			}
+			// The data keys are read by increasing uid, so the reverse lists stay sorted.
+			srcs[i] = append(srcs[i], pk.Uid)
+			if len(p.Facets) > 0 {
+				lists[i].Postings = append(lists[i].Postings, &pb.Posting{
+					Uid:         pk.Uid,
+					Facets:      p.Facets,
+					PostingType: pb.Posting_REF,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	span.AddEvent("scanReverse result", trace.WithAttributes(
+		attribute.Int64("keys_scanned", int64(keys))))
+
+	cache := posting.NewLocalCache(q.ReadTs)
+	for i, uid := range uids {
+		lists[i].Pack = codec.Encode(srcs[i], 256)
+		key := x.ReverseKey(attr, uid)
+		cache.SetIfAbsent(string(key), posting.NewList(key, lists[i], q.ReadTs))
+	}
+	return cache, nil
+}
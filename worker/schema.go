@@ -46,7 +46,8 @@ func getSchema(ctx context.Context, s *pb.SchemaRequest) (*pb.SchemaResult, erro
 		fields = s.Fields
 	} else {
 		fields = []string{"type", "index", "tokenizer", "reverse", "count", "list", "upsert", "unique",
-			"lang", "noconflict", "vector_specs"}
+			"lang", "noconflict", "conflict", "provenance", "embedding_source",
+			"vector_specs"}
 	}
 
 	myGid := groups().groupId()
@@ -108,6 +109,12 @@ func populateSchema(attr string, fields []string) *pb.SchemaNode {
 			schemaNode.Lang = pred.GetLang()
 		case "noconflict":
 			schemaNode.NoConflict = pred.GetNoConflict()
+		case "conflict":
+			schemaNode.Conflict = pred.GetConflict()
+		case "provenance":
+			schemaNode.Provenance = pred.GetProvenance()
+		case "embedding_source":
+			schemaNode.EmbeddingSource = pred.GetEmbeddingSource()
 		case "vector_specs":
 			schemaNode.IndexSpecs = pred.GetIndexSpecs()
 		default:
@@ -0,0 +1,290 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/algo"
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// driftBatchSize is the number of nodes whose types are read at once by the schema drift report.
+const driftBatchSize = 10000
+
+// DriftReport is a report of the drift of the data of a namespace from its schema, typically
+// after a large live load. It covers the tablets served by the group of this Alpha, so the report
+// of a cluster is made of the reports of an Alpha of every group.
+type DriftReport struct {
+	GroupId uint32 `json:"groupId"`
+	ReadTs  uint64 `json:"readTs"`
+	// Sample is the number of nodes checked per predicate and per type, or zero if the tablets
+	// were fully scanned.
+	Sample     int               `json:"sample"`
+	Predicates []*PredicateDrift `json:"predicates"`
+}
+
+// PredicateDrift is the drift of the data of a predicate from the schema.
+type PredicateDrift struct {
+	Predicate string `json:"predicate"`
+	ValueType string `json:"valueType"`
+	// Nodes is the number of nodes having the predicate which were checked.
+	Nodes int `json:"nodes"`
+	// InNoType is true if no type has the predicate as a field.
+	InNoType bool `json:"inNoType"`
+	// Untyped is the number of the nodes without a type, and Undeclared the number of the typed
+	// nodes none of whose types has the predicate as a field.
+	Untyped    int `json:"untyped"`
+	Undeclared int `json:"undeclared"`
+	// Mismatches counts the values stored with another type than the one of the schema, by their
+	// stored type. Unconvertible counts those which can't be converted to the type of the schema,
+	// failing the queries reading them.
+	Mismatches    map[string]int `json:"mismatches,omitempty"`
+	Unconvertible int            `json:"unconvertible"`
+	// Types are the types having the predicate as a field.
+	Types []*TypeFieldDrift `json:"types,omitempty"`
+}
+
+// TypeFieldDrift is the number of members of a type, which were checked, missing a field.
+type TypeFieldDrift struct {
+	Type    string `json:"type"`
+	Members int    `json:"members"`
+	Missing int    `json:"missing"`
+}
+
+// SchemaDriftReport scans the tablets of the namespace served by the group of this Alpha and
+// reports the drift of their data from the schema. If sample isn't zero, only a random sample of
+// that many nodes of each predicate and of each type is checked.
+func SchemaDriftReport(ctx context.Context, ns uint64, sample int) (*DriftReport, error) {
+	if sample < 0 {
+		return nil, errors.Errorf("Invalid sample size %d", sample)
+	}
+	gid := groups().groupId()
+	report := &DriftReport{
+		GroupId: gid,
+		ReadTs:  posting.Oracle().MaxAssigned(),
+		Sample:  sample,
+	}
+
+	// The types having each predicate as a field.
+	fieldOf := make(map[string][]string)
+	for _, name := range schema.State().Types() {
+		if x.ParseNamespace(name) != ns {
+			continue
+		}
+		typ, ok := schema.State().GetType(name)
+		if !ok {
+			continue
+		}
+		for _, field := range typ.Fields {
+			fieldOf[field.Predicate] = append(fieldOf[field.Predicate], name)
+		}
+	}
+
+	d := &driftScanner{
+		readTs:   report.ReadTs,
+		sample:   sample,
+		typeAttr: x.NamespaceAttr(ns, "dgraph.type"),
+		fieldOf:  fieldOf,
+	}
+	for _, attr := range namespaceTablets(ns) {
+		if x.IsReservedPredicate(x.ParseAttr(attr)) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		tid, err := schema.State().TypeOf(attr)
+		if err != nil {
+			// The tablet of a dropped predicate which isn't deleted yet.
+			continue
+		}
+		if err := tiers().hot(ctx, attr, false); err != nil {
+			return nil, err
+		}
+		pd, err := d.predicate(ctx, attr, tid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while checking predicate %s", x.ParseAttr(attr))
+		}
+		report.Predicates = append(report.Predicates, pd)
+	}
+	return report, nil
+}
+
+type driftScanner struct {
+	readTs   uint64
+	sample   int
+	typeAttr string
+	fieldOf  map[string][]string
+}
+
+func (d *driftScanner) predicate(ctx context.Context, attr string,
+	tid types.TypeID) (*PredicateDrift, error) {
+
+	pd := &PredicateDrift{
+		Predicate: x.ParseAttr(attr),
+		ValueType: tid.Name(),
+		InNoType:  len(d.fieldOf[attr]) == 0,
+	}
+	result, err := ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    attr,
+		SrcFunc: &pb.SrcFunction{Name: "has"},
+		First:   math.MaxInt32,
+		Sample:  int32(d.sample),
+		ReadTs:  d.readTs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint64
+	if len(result.UidMatrix) > 0 {
+		uids = result.UidMatrix[0].Uids
+	}
+	pd.Nodes = len(uids)
+
+	for start := 0; start < len(uids); start += driftBatchSize {
+		end := start + driftBatchSize
+		if end > len(uids) {
+			end = len(uids)
+		}
+		nodeTypes, err := d.nodeTypes(ctx, uids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, typs := range nodeTypes {
+			switch {
+			case len(typs) == 0:
+				pd.Untyped++
+			case !declares(d.fieldOf[attr], typs):
+				pd.Undeclared++
+			}
+		}
+	}
+
+	if tid != types.UidID {
+		for _, uid := range uids {
+			pl, err := posting.GetNoStore(x.DataKey(attr, uid), d.readTs)
+			if err != nil {
+				return nil, err
+			}
+			err = pl.Iterate(d.readTs, 0, func(p *pb.Posting) error {
+				mismatch, convertible := valueDrift(p, tid)
+				if mismatch {
+					if pd.Mismatches == nil {
+						pd.Mismatches = make(map[string]int)
+					}
+					pd.Mismatches[types.TypeID(p.ValType).Name()]++
+				}
+				if !convertible {
+					pd.Unconvertible++
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, typ := range d.fieldOf[attr] {
+		tfd, err := d.typeField(ctx, attr, typ)
+		if err != nil {
+			return nil, err
+		}
+		pd.Types = append(pd.Types, tfd)
+	}
+	return pd, nil
+}
+
+// nodeTypes returns the namespaced types of the nodes.
+func (d *driftScanner) nodeTypes(ctx context.Context, uids []uint64) ([][]string, error) {
+	result, err := ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    d.typeAttr,
+		UidList: &pb.List{Uids: uids},
+		ReadTs:  d.readTs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]string, len(uids))
+	ns := x.ParseNamespace(d.typeAttr)
+	for i := range uids {
+		if i >= len(result.ValueMatrix) {
+			break
+		}
+		for _, v := range result.ValueMatrix[i].Values {
+			out[i] = append(out[i], x.NamespaceAttr(ns, string(v.Val)))
+		}
+	}
+	return out, nil
+}
+
+// typeField counts the members of the type which don't have the predicate. The tablet of the
+// predicate is served by this group, so it's read locally.
+func (d *driftScanner) typeField(ctx context.Context, attr, typ string) (*TypeFieldDrift, error) {
+	tfd := &TypeFieldDrift{Type: x.ParseAttr(typ)}
+	result, err := ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    d.typeAttr,
+		SrcFunc: &pb.SrcFunction{Name: "eq", Args: []string{x.ParseAttr(typ)}},
+		ReadTs:  d.readTs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var members []uint64
+	if len(result.UidMatrix) > 0 {
+		members = result.UidMatrix[0].Uids
+	}
+	if d.sample > 0 {
+		members = algo.SampleUids(members, d.sample)
+	}
+	tfd.Members = len(members)
+
+	for _, uid := range members {
+		pl, err := posting.GetNoStore(x.DataKey(attr, uid), d.readTs)
+		if err != nil {
+			return nil, err
+		}
+		empty, err := pl.IsEmpty(d.readTs, 0)
+		if err != nil {
+			return nil, err
+		}
+		if empty {
+			tfd.Missing++
+		}
+	}
+	return tfd, nil
+}
+
+// valueDrift returns whether the value of the posting is stored with another type than the one
+// of the schema, and whether it can be converted to it.
+func valueDrift(p *pb.Posting, tid types.TypeID) (mismatch, convertible bool) {
+	stored := types.TypeID(p.ValType)
+	if stored == tid {
+		return false, true
+	}
+	_, err := types.Convert(types.Val{Tid: stored, Value: p.Value}, tid)
+	return true, err == nil
+}
+
+// declares returns true if one of the node types is among the types having the field.
+func declares(fieldOf, nodeTypes []string) bool {
+	for _, typ := range nodeTypes {
+		for _, f := range fieldOf {
+			if f == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
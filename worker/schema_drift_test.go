@@ -0,0 +1,42 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/types"
+)
+
+func TestValueDrift(t *testing.T) {
+	posting := func(tid types.TypeID, val string) *pb.Posting {
+		return &pb.Posting{ValType: tid.Enum(), Value: []byte(val)}
+	}
+	tests := []struct {
+		p           *pb.Posting
+		tid         types.TypeID
+		mismatch    bool
+		convertible bool
+	}{
+		{posting(types.StringID, "alice"), types.StringID, false, true},
+		{posting(types.DefaultID, "42"), types.IntID, true, true},
+		{posting(types.DefaultID, "forty two"), types.IntID, true, false},
+		{posting(types.StringID, "2020-01-01"), types.DateTimeID, true, true},
+		{posting(types.StringID, "yesterday"), types.DateTimeID, true, false},
+	}
+	for _, tc := range tests {
+		mismatch, convertible := valueDrift(tc.p, tc.tid)
+		require.Equal(t, tc.mismatch, mismatch, "%s as %s", tc.p.Value, tc.tid.Name())
+		require.Equal(t, tc.convertible, convertible, "%s as %s", tc.p.Value, tc.tid.Name())
+	}
+
+	require.True(t, declares([]string{"Person", "Employee"}, []string{"Company", "Employee"}))
+	require.False(t, declares([]string{"Person"}, []string{"Company"}))
+	require.False(t, declares(nil, []string{"Company"}))
+}
@@ -28,21 +28,41 @@ const (
 	//       For easy readability, keep the options without default values (if any) at the end of
 	//       the *Defaults string. Also, since these strings are printed in --help text, avoid line
 	//       breaks.
-	AuditDefaults  = `compress=false; days=10; size=100; dir=; output=; encrypt-file=;`
+	AuditDefaults  = `compress=false; days=10; size=100; dir=; output=; encrypt-file=; routes=;`
 	BadgerDefaults = `compression=snappy; numgoroutines=8;`
 	RaftDefaults   = `learner=false; snapshot-after-entries=10000; ` +
-		`snapshot-after-duration=30m; pending-proposals=256; idx=; group=;`
-	SecurityDefaults = `token=; whitelist=;`
-	CDCDefaults      = `file=; kafka=; sasl_user=; sasl_password=; ca_cert=; client_cert=; ` +
+		`snapshot-after-duration=30m; pending-proposals=256; wal-compress-above-kb=0; ` +
+		`snapshot-bandwidth-mb=0; witness=false; apply-queue-depth=64; idx=; group=; ` +
+		`proposal-batch-window=0ms; proposal-batch-kb=256; proposal-batch-adaptive=false; ` +
+		`reverse-async-lag=1s;`
+	SecurityDefaults = `signature-max-age=5m; signed-endpoints=/admin,/alter; token=; ` +
+		`whitelist=; signing-key-file=; signed-body-max-mb=256;`
+	CDCDefaults = `file=; kafka=; sasl_user=; sasl_password=; ca_cert=; client_cert=; ` +
 		`client_key=; sasl-mechanism=PLAIN; tls=false;`
-	LimitDefaults = `mutations=allow; query-edge=1000000; normalize-node=10000; ` +
+	TieringDefaults = `dest=; cold-after=720h; interval=10m;`
+	LimitDefaults   = `mutations=allow; query-edge=1000000; normalize-node=10000; ` +
 		`mutations-nquad=1000000; disallow-drop=false; query-timeout=0ms; txn-abort-after=5m; ` +
-		` max-retries=10;max-pending-queries=10000;shared-instance=false;type-filter-uid-limit=10`
+		` max-retries=10;max-pending-queries=10000;mutation-retries=10;shared-instance=false;` +
+		`type-filter-uid-limit=10; txn-max-duration=0s; txn-max-duration-ns=; query-depth=0; ` +
+		`query-expand=0; query-root-uids=0; query-var-uids=0; query-memory-mb=0; ` +
+		`query-limits-ns=; ` +
+		`mutation-size-mb=0; mutation-size-mb-ns=; proposal-edges=100000; query-spill-uids=0; ` +
+		`wasm-timeout=100ms; wasm-memory-mb=16; reverse-scan-keys=1000000; ` +
+		`idempotency-window=24h;`
 	ZeroLimitsDefaults = `uid-lease=0; refill-interval=30s; disable-admin-http=false;`
 	GraphQLDefaults    = `introspection=true; debug=false; extensions=true; poll-interval=1s; ` +
-		`lambda-url=;`
+		`lambda-url=; upload-uri=; upload-max-size-mb=10;`
+	CompressionDefaults = `min-size=0; level=default;`
+	MetricsDefaults     = `exemplars=true; predicates=; namespaces=;`
+	CaptureDefaults     = `dir=; sample=0.01; size-mb=100; days=10;`
+	SandboxDefaults     = `timeout=5s; query-depth=10; query-expand=50; query-root-uids=10000; ` +
+		`query-var-uids=100000; query-memory-mb=64;`
+	NL2DQLDefaults = `provider=; url=; model=; api-key-file=; max-tokens=1024; timeout=30s; ` +
+		`retries=1;`
+	EmbeddingDefaults    = `url=; model=; api-key-file=; batch-size=64; timeout=10s;`
 	CacheDefaults        = `size-mb=4096; percentage=40,40,20; remove-on-update=false`
-	FeatureFlagsDefaults = `normalize-compatibility-mode=; enable-detailed-metrics=false`
+	FeatureFlagsDefaults = `normalize-compatibility-mode=; enable-detailed-metrics=false; ` +
+		`presence-bitmap=false; experimental-paths=`
 )
 
 // ServerState holds the state of the Dgraph server.
@@ -104,6 +124,10 @@ func (s *ServerState) InitStorage() {
 		x.Checkf(os.MkdirAll(Config.WALDir, 0700), "Error while creating WAL dir.")
 		s.WALstore, err = raftwal.InitEncrypted(Config.WALDir, x.WorkerConfig.EncryptionKey)
 		x.Check(err)
+		if kb := x.WorkerConfig.Raft.GetUint64("wal-compress-above-kb"); kb > 0 {
+			glog.Infof("Compressing the Raft entries bigger than %d KB in the WAL.", kb)
+			s.WALstore.SetCompressionThreshold(int(kb) << 10)
+		}
 	}
 	{
 		// Postings directory
@@ -126,8 +150,17 @@ func (s *ServerState) InitStorage() {
 		s.Pstore, err = badger.OpenManaged(opt)
 		x.Checkf(err, "Error while creating badger KV posting store")
 
+		format, err := x.DetectDataFormat(s.Pstore)
+		x.Checkf(err, "Error while detecting the format of the postings")
+		if format != x.DataFormatVersion {
+			glog.Fatalf("The postings in %s are in the data format of %s, which this version "+
+				"can't read. Run `dgraph upgrade --postings %s` to migrate them.",
+				Config.PostingDir, x.DataFormatName(format), Config.PostingDir)
+		}
+
 		// zero out from memory
 		opt.EncryptionKey = nil
+		removeStaleSnapshotHandles()
 	}
 	// Temp directory
 	x.Check(os.MkdirAll(x.WorkerConfig.TmpDir, 0700))
@@ -7,6 +7,7 @@ package worker
 
 import (
 	"context"
+	"math"
 	"sync/atomic"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/raft/v3"
+	"golang.org/x/time/rate"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/dgraph-io/ristretto/v2/z"
@@ -54,6 +56,14 @@ func (n *node) populateSnapshot(snap *pb.Snapshot, pl *conn.Pool) error {
 		return err
 	}
 
+	// A full snapshot replaces the tiered tablets, the cold tablets are read back before the
+	// changes since the last one are written to them.
+	if snap.SinceTs > 0 {
+		if err := n.tiering.rehydrateNs(ctx, math.MaxUint64); err != nil {
+			return err
+		}
+	}
+
 	var writer badgerWriter
 	if snap.SinceTs == 0 {
 		sw := pstore.NewStreamWriter()
@@ -99,6 +109,11 @@ func (n *node) populateSnapshot(snap *pb.Snapshot, pl *conn.Pool) error {
 	if err := writer.Flush(); err != nil {
 		return err
 	}
+	if snap.SinceTs == 0 {
+		if err := n.tiering.forgetAll(); err != nil {
+			return err
+		}
+	}
 
 	if err := deleteStalePreds(ctx, done, snap.ReadTs); err != nil {
 		return err
@@ -196,13 +211,26 @@ func doStreamSnapshot(snap *pb.Snapshot, out pb.Worker_StreamSnapshotServer) err
 		return err
 	}
 
+	// The followers get the cold tablets too.
+	if err := tiers().rehydrateNs(out.Context(), math.MaxUint64); err != nil {
+		return err
+	}
+
 	stream := pstore.NewStreamAt(snap.ReadTs)
 	stream.LogPrefix = "Sending Snapshot"
 	// Use the default implementation. We no longer try to generate a rolled up posting list here.
 	// Instead, we just stream out all the versions as they are.
 	stream.KeyToList = nil
+	var limiter *rate.Limiter
+	if mb := x.WorkerConfig.Raft.GetUint64("snapshot-bandwidth-mb"); mb > 0 {
+		glog.Infof("Throttling the snapshot streamed to %#x at %d MB/s", snap.Context.GetId(), mb)
+		limiter = rate.NewLimiter(rate.Limit(mb*MB), int(mb*MB))
+	}
 	stream.Send = func(buf *z.Buffer) error {
 		kvs := &pb.KVS{Data: buf.Bytes()}
+		if err := throttle(out.Context(), limiter, len(kvs.Data)); err != nil {
+			return err
+		}
 		return out.Send(kvs)
 	}
 	stream.ChooseKey = func(item *badger.Item) bool {
@@ -251,6 +279,26 @@ func doStreamSnapshot(snap *pb.Snapshot, out pb.Worker_StreamSnapshotServer) err
 	return nil
 }
 
+// throttle waits until the limiter allows to send n bytes. The bytes are taken in chunks no bigger
+// than the burst of the limiter, so that the batches bigger than the burst can be sent too. A nil
+// limiter doesn't throttle.
+func throttle(ctx context.Context, limiter *rate.Limiter, n int) error {
+	if limiter == nil {
+		return nil
+	}
+	for n > 0 {
+		chunk := limiter.Burst()
+		if n < chunk {
+			chunk = n
+		}
+		if err := limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
 func (w *grpcWorker) StreamSnapshot(stream pb.Worker_StreamSnapshotServer) error {
 	// Pause rollups during snapshot streaming.
 	closer, err := groups().Node.startTask(opSnapshot)
@@ -0,0 +1,297 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/golang/glog"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+)
+
+const (
+	// snapshotHandleFile is the file of a snapshot handle describing it.
+	snapshotHandleFile = "snapshot.json"
+	// linkSnapshotRetries is the number of times the files of a snapshot are linked again, when
+	// a compaction changes them while they are linked.
+	linkSnapshotRetries = 10
+)
+
+// SnapshotHandle is a read-only snapshot of the p directory of this alpha, for the long-running
+// analytical scans of a namespace. Its directory holds hard links to the tables and the value
+// logs of the p directory, and copies of its manifest and memtables, which can be opened with
+// badger in read-only mode by an external process, and read at the read ts. The compactions of
+// the alpha go on, the files they replace stay in the snapshot. While the handle exists, the
+// versions of the read ts aren't discarded from the p directory either, so the read-only queries
+// of the namespace can run at the read ts too.
+type SnapshotHandle struct {
+	Id        string    `json:"id"`
+	Namespace uint64    `json:"namespace"`
+	ReadTs    uint64    `json:"readTs"`
+	Dir       string    `json:"dir"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// snapshotHandles holds the snapshot handles of this alpha, which don't survive its restarts.
+type snapshotHandles struct {
+	sync.Mutex
+	handles map[string]*SnapshotHandle
+}
+
+var snapHandles = snapshotHandles{handles: make(map[string]*SnapshotHandle)}
+
+// snapshotHandlesDir returns the directory of the snapshot handles, next to the p directory so
+// that its files can be hard linked.
+func snapshotHandlesDir() string {
+	return filepath.Clean(Config.PostingDir) + ".snapshots"
+}
+
+// CreateSnapshotHandle creates a snapshot handle of the namespace at the latest read ts of this
+// alpha, released after the ttl.
+func CreateSnapshotHandle(ns uint64, ttl time.Duration) (*SnapshotHandle, error) {
+	if ttl <= 0 {
+		return nil, errors.Errorf("The ttl of the snapshot handle must be positive, got %s", ttl)
+	}
+	now := time.Now()
+	h := &SnapshotHandle{
+		Id:        uuid.New().String(),
+		Namespace: ns,
+		ReadTs:    posting.Oracle().MaxAssigned(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	h.Dir = filepath.Join(snapshotHandlesDir(), h.Id)
+
+	// The read ts is pinned before the files are linked, so that none of its versions is
+	// discarded in the meantime.
+	snapHandles.Lock()
+	snapHandles.handles[h.Id] = h
+	snapHandles.Unlock()
+	if err := linkSnapshot(pstore, h.Dir); err != nil {
+		_ = ReleaseSnapshotHandle(h.Id)
+		return nil, errors.Wrapf(err, "while linking the files of the snapshot")
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		_ = ReleaseSnapshotHandle(h.Id)
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(h.Dir, snapshotHandleFile), data, 0600); err != nil {
+		_ = ReleaseSnapshotHandle(h.Id)
+		return nil, err
+	}
+	glog.Infof("Created the snapshot handle %s of namespace %#x at read ts %d in %s", h.Id, ns,
+		h.ReadTs, h.Dir)
+	return h, nil
+}
+
+// SnapshotHandles returns the snapshot handles of this alpha, after releasing the expired ones.
+func SnapshotHandles() []*SnapshotHandle {
+	releaseExpiredSnapshotHandles()
+	snapHandles.Lock()
+	defer snapHandles.Unlock()
+	handles := make([]*SnapshotHandle, 0, len(snapHandles.handles))
+	for _, h := range snapHandles.handles {
+		handles = append(handles, h)
+	}
+	sort.Slice(handles, func(i, j int) bool {
+		return handles[i].CreatedAt.Before(handles[j].CreatedAt)
+	})
+	return handles
+}
+
+// ReleaseSnapshotHandle unpins the read ts of the snapshot handle and removes its directory.
+func ReleaseSnapshotHandle(id string) error {
+	snapHandles.Lock()
+	h, ok := snapHandles.handles[id]
+	delete(snapHandles.handles, id)
+	snapHandles.Unlock()
+	if !ok {
+		return errors.Errorf("Unknown snapshot handle %q", id)
+	}
+	glog.Infof("Releasing the snapshot handle %s", id)
+	return os.RemoveAll(h.Dir)
+}
+
+func releaseExpiredSnapshotHandles() {
+	now := time.Now()
+	var expired []string
+	snapHandles.Lock()
+	for id, h := range snapHandles.handles {
+		if now.After(h.ExpiresAt) {
+			expired = append(expired, id)
+		}
+	}
+	snapHandles.Unlock()
+	for _, id := range expired {
+		if err := ReleaseSnapshotHandle(id); err != nil {
+			glog.Warningf("While releasing the expired snapshot handle %s: %v", id, err)
+		}
+	}
+}
+
+// snapshotDiscardTs returns the ts below which the invalid versions can be discarded, which is
+// ts unless a snapshot handle pins an older read ts.
+func snapshotDiscardTs(ts uint64) uint64 {
+	releaseExpiredSnapshotHandles()
+	snapHandles.Lock()
+	defer snapHandles.Unlock()
+	for _, h := range snapHandles.handles {
+		if h.ReadTs < ts {
+			ts = h.ReadTs
+		}
+	}
+	return ts
+}
+
+// removeStaleSnapshotHandles removes the directories of the snapshot handles of a previous run
+// of the alpha.
+func removeStaleSnapshotHandles() {
+	if err := os.RemoveAll(snapshotHandlesDir()); err != nil {
+		glog.Warningf("While removing the stale snapshot handles: %v", err)
+	}
+}
+
+// linkSnapshot hard links the tables and the value logs of the badger db into dir, and copies
+// its manifest, memtables and active value log. If a compaction removes a table of the copied
+// manifest before it's linked, the files are linked again. The snapshot is then opened and closed
+// once, which flushes its memtables into tables of its own, so that it can be opened read-only.
+func linkSnapshot(db *badger.DB, dir string) error {
+	if err := db.Sync(); err != nil {
+		return err
+	}
+	opt := db.Opts()
+	for i := 0; ; i++ {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		missing, err := linkSnapshotFiles(opt, opt.Dir, dir)
+		if err != nil {
+			return err
+		}
+		if missing == 0 {
+			break
+		}
+		if i == linkSnapshotRetries {
+			return errors.Errorf("%d tables of the manifest were compacted away while linking",
+				missing)
+		}
+	}
+
+	// The linked files are never written to: the tables are immutable, and the value logs but
+	// the active one, which is copied, are only read.
+	snap, err := badger.OpenManaged(opt.WithDir(dir).WithValueDir(dir).WithNumCompactors(0).
+		WithCompactL0OnClose(false))
+	if err != nil {
+		return errors.Wrapf(err, "while opening the snapshot")
+	}
+	return snap.Close()
+}
+
+// linkSnapshotFiles links the files of the badger db in src into dst, and returns the number of
+// tables of the copied manifest which are missing.
+func linkSnapshotFiles(opt badger.Options, src, dst string) (int, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return 0, err
+	}
+	var memtables, tables, vlogs, others []string
+	for _, e := range entries {
+		switch name := e.Name(); {
+		case e.IsDir(), name == badger.ManifestFilename, name == "LOCK":
+		case strings.HasSuffix(name, ".mem"):
+			memtables = append(memtables, name)
+		case strings.HasSuffix(name, ".sst"):
+			tables = append(tables, name)
+		case strings.HasSuffix(name, ".vlog"):
+			vlogs = append(vlogs, name)
+		default:
+			others = append(others, name)
+		}
+	}
+	sort.Strings(vlogs)
+
+	// The memtables are copied before the manifest, so that a memtable flushed in the meantime is
+	// in a table of the manifest. They are copied before the value logs, which have the values of
+	// their entries.
+	for _, name := range memtables {
+		if err := copySnapshotFile(src, dst, name); err != nil {
+			return 0, errors.Wrapf(err, "while copying %s", name)
+		}
+	}
+	if err := copySnapshotFile(src, dst, badger.ManifestFilename); err != nil {
+		return 0, err
+	}
+	for i, name := range append(tables, vlogs...) {
+		var err error
+		if i == len(tables)+len(vlogs)-1 && strings.HasSuffix(name, ".vlog") {
+			// The active value log is appended to, and truncated when the db is opened.
+			err = copySnapshotFile(src, dst, name)
+		} else if err = os.Link(filepath.Join(src, name), filepath.Join(dst, name)); os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			return 0, errors.Wrapf(err, "while linking %s", name)
+		}
+	}
+	for _, name := range others {
+		if err := copySnapshotFile(src, dst, name); err != nil {
+			return 0, errors.Wrapf(err, "while copying %s", name)
+		}
+	}
+
+	mf, err := os.Open(filepath.Join(dst, badger.ManifestFilename))
+	if err != nil {
+		return 0, err
+	}
+	defer mf.Close()
+	manifest, _, err := badger.ReplayManifestFile(mf, opt.ExternalMagicVersion, opt)
+	if err != nil {
+		return 0, err
+	}
+	var missing int
+	for id := range manifest.Tables {
+		if _, err := os.Stat(filepath.Join(dst, fmt.Sprintf("%06d.sst", id))); err != nil {
+			missing++
+		}
+	}
+	return missing, nil
+}
+
+func copySnapshotFile(src, dst, name string) error {
+	in, err := os.Open(filepath.Join(src, name))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(filepath.Join(dst, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,77 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkSnapshot(t *testing.T) {
+	db, err := badger.OpenManaged(badger.DefaultOptions(t.TempDir()).WithLogger(nil))
+	require.NoError(t, err)
+	defer db.Close()
+
+	write := func(ts uint64, n int) {
+		wb := db.NewManagedWriteBatch()
+		for i := range n {
+			require.NoError(t, wb.SetEntryAt(badger.NewEntry(
+				[]byte(fmt.Sprintf("key-%04d", i)), []byte(fmt.Sprintf("%d", ts))), ts))
+		}
+		require.NoError(t, wb.Flush())
+	}
+	write(10, 1000)
+	// The tables are flattened, so that the snapshot has tables as well as memtables.
+	require.NoError(t, db.Flatten(1))
+	write(20, 10)
+
+	dir := filepath.Join(t.TempDir(), "snapshot")
+	require.NoError(t, linkSnapshot(db, dir))
+	write(30, 1000)
+	// The db keeps working on the files linked into the snapshot.
+	require.NoError(t, db.Flatten(1))
+	item, err := db.NewTransactionAt(35, false).Get([]byte("key-0005"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(30), item.Version())
+
+	snap, err := badger.OpenManaged(badger.DefaultOptions(dir).WithReadOnly(true).
+		WithLogger(nil))
+	require.NoError(t, err)
+	defer snap.Close()
+	txn := snap.NewTransactionAt(25, false)
+	defer txn.Discard()
+	for key, val := range map[string]string{"key-0005": "20", "key-0500": "10"} {
+		item, err := txn.Get([]byte(key))
+		require.NoError(t, err)
+		v, err := item.ValueCopy(nil)
+		require.NoError(t, err)
+		require.Equal(t, val, string(v), key)
+	}
+	require.Equal(t, uint64(20), func() uint64 {
+		item, err := snap.NewTransactionAt(100, false).Get([]byte("key-0005"))
+		require.NoError(t, err)
+		return item.Version()
+	}())
+}
+
+func TestSnapshotDiscardTs(t *testing.T) {
+	snapHandles.Lock()
+	snapHandles.handles["a"] = &SnapshotHandle{Id: "a", ReadTs: 50,
+		ExpiresAt: time.Now().Add(time.Hour)}
+	snapHandles.Unlock()
+	require.Equal(t, uint64(40), snapshotDiscardTs(40))
+	require.Equal(t, uint64(50), snapshotDiscardTs(60))
+	require.Len(t, SnapshotHandles(), 1)
+
+	require.NoError(t, ReleaseSnapshotHandle("a"))
+	require.Equal(t, uint64(60), snapshotDiscardTs(60))
+	require.Error(t, ReleaseSnapshotHandle("a"))
+}
@@ -506,6 +506,12 @@ func processSort(ctx context.Context, ts *pb.SortMessage) (*pb.SortResult, error
 			x.ParseAttr(ts.Order[0].Attr))
 	}
 
+	for _, order := range ts.Order {
+		if err := tiers().hot(ctx, order.Attr, false); err != nil {
+			return nil, err
+		}
+	}
+
 	// We're not using any txn local cache here. So, no need to deal with that yet.
 	cctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -774,6 +780,14 @@ func sortByValue(ctx context.Context, ts *pb.SortMessage, ul *pb.List,
 		return nil, errors.Errorf("Sorting on multiple language is not supported.")
 	}
 
+	// With a single order and a count, only the first offset+count values are kept, in a heap,
+	// instead of sorting all of them.
+	var topK *types.TopK
+	if k := int(ts.Offset) + int(ts.Count); len(ts.Order) == 1 && ts.Count > 0 &&
+		ts.Offset >= 0 && k < lenList {
+		topK = types.NewTopK(k, order.Desc, lang)
+	}
+
 	// nullsList is the list of UIDs for which value doesn't exist.
 	var nullsList []uint64
 	var nullVals [][]types.Val
@@ -792,11 +806,22 @@ func sortByValue(ctx context.Context, ts *pb.SortMessage, ul *pb.List,
 				nullVals = append(nullVals, []types.Val{val})
 				continue
 			}
+			if topK != nil {
+				if err := topK.Add(val, uid); err != nil {
+					return multiSortVals, err
+				}
+				continue
+			}
 			uids = append(uids, uid)
 			values = append(values, []types.Val{val})
 		}
 	}
-	err := types.Sort(values, &uids, []bool{order.Desc}, lang)
+	var err error
+	if topK != nil {
+		_, uids = topK.Sorted()
+	} else {
+		err = types.Sort(values, &uids, []bool{order.Desc}, lang)
+	}
 	ul.Uids = append(uids, nullsList...)
 	values = append(values, nullVals...)
 	if len(ts.Order) > 1 {
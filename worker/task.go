@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -28,6 +29,7 @@ import (
 	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/hypermodeinc/dgraph/v25/algo"
 	"github.com/hypermodeinc/dgraph/v25/conn"
+	"github.com/hypermodeinc/dgraph/v25/plugin"
 	"github.com/hypermodeinc/dgraph/v25/posting"
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/schema"
@@ -217,6 +219,9 @@ const (
 	customIndexFn
 	matchFn
 	similarToFn
+	levenshteinFn
+	pluginFn
+	notExistsFn
 	standardFn = 100
 )
 
@@ -243,7 +248,7 @@ func parseFuncTypeHelper(name string) (FuncType, string) {
 	switch f {
 	case "le", "ge", "lt", "gt", "eq", "between":
 		return compareAttrFn, f
-	case "min", "max", "sum", "avg":
+	case "min", "max", "sum", "avg", "percentile", "distinct":
 		return aggregatorFn, f
 	case "checkpwd":
 		return passwordFn, f
@@ -257,12 +262,18 @@ func parseFuncTypeHelper(name string) (FuncType, string) {
 		return hasFn, f
 	case "uid_in":
 		return uidInFn, f
+	case "not_exists":
+		return notExistsFn, f
 	case "similar_to":
 		return similarToFn, f
 	case "anyof", "allof":
 		return customIndexFn, f
-	case "match":
+	case "match", "fuzzy":
 		return matchFn, f
+	case "levenshtein":
+		return levenshteinFn, f
+	case "plugin":
+		return pluginFn, f
 	default:
 		if types.IsGeoFunc(f) {
 			return geoFn, f
@@ -317,9 +328,15 @@ func (srcFn *functionContext) needsValuePostings(typ types.TypeID) (bool, error)
 	case geoFn, regexFn, fullTextSearchFn, standardFn, hasFn, customIndexFn, matchFn, ngramFn:
 		// All of these require an index, hence would require fetching uid postings.
 		return false, nil
-	case uidInFn, compareScalarFn:
+	case uidInFn, notExistsFn, compareScalarFn:
 		// Operate on uid postings
 		return false, nil
+	case levenshteinFn:
+		// The values of the uids are compared to the term by handleMatchFunction.
+		return false, nil
+	case pluginFn:
+		// The values of the uids are evaluated by the plugin in handlePluginFunction.
+		return false, nil
 	case notAFunction:
 		return typ.IsScalar(), nil
 	}
@@ -369,6 +386,10 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 			return err
 		}
 
+		if len(q.JoinVectors) > 0 {
+			return similarityJoin(ctx, indexer, qc, q, int(numNeighbors), args.out)
+		}
+
 		nnUids, err := indexer.Search(ctx, qc, srcFn.vectorInfo,
 			int(numNeighbors), index.AcceptAll[float32])
 		if err != nil {
@@ -776,7 +797,7 @@ func (qs *queryState) handleUidPostings(
 	// calculate(). panic is of the form "index out of range [4] with length 1". Hence return error
 	// from here when srcFn.n != len(q.UidList.Uids).
 	switch srcFn.fnType {
-	case notAFunction, compareScalarFn, hasFn, uidInFn:
+	case notAFunction, compareScalarFn, hasFn, uidInFn, notExistsFn:
 		if srcFn.n != len(q.UidList.GetUids()) {
 			return errors.Errorf("srcFn.n: %d is not equal to len(q.UidList.Uids): %d, srcFn: %+v in "+
 				"handleUidPostings", srcFn.n, len(q.UidList.GetUids()), srcFn)
@@ -812,7 +833,7 @@ func (qs *queryState) handleUidPostings(
 			}
 			var key []byte
 			switch srcFn.fnType {
-			case notAFunction, compareScalarFn, hasFn, uidInFn:
+			case notAFunction, compareScalarFn, hasFn, uidInFn, notExistsFn:
 				if q.Reverse {
 					key = x.ReverseKey(q.Attr, q.UidList.Uids[i])
 				} else {
@@ -857,7 +878,7 @@ func (qs *queryState) handleUidPostings(
 					return errors.Wrapf(posting.ErrTsTooOld, "While reading posting list length")
 				}
 				count := int64(len)
-				if evalCompare(srcFn.fname, count, srcFn.threshold[0]) {
+				if evalCompareCount(srcFn.fname, count, srcFn.threshold) {
 					tlist := &pb.List{Uids: []uint64{q.UidList.Uids[i]}}
 					out.UidMatrix = append(out.UidMatrix, tlist)
 				}
@@ -917,6 +938,18 @@ func (qs *queryState) handleUidPostings(
 					tlist := &pb.List{Uids: []uint64{q.UidList.Uids[i]}}
 					out.UidMatrix = append(out.UidMatrix, tlist)
 				}
+			case srcFn.fnType == notExistsFn:
+				if i == 0 {
+					span.AddEvent("NotExistsFn")
+				}
+				found, err := hasEdgeTo(pl, args.q.ReadTs, srcFn.uidsPresent)
+				if err != nil {
+					return err
+				}
+				if !found {
+					tlist := &pb.List{Uids: []uint64{q.UidList.Uids[i]}}
+					out.UidMatrix = append(out.UidMatrix, tlist)
+				}
 			case q.FacetParam != nil || facetsTree != nil:
 				if i == 0 {
 					span.AddEvent("default with facets")
@@ -985,6 +1018,7 @@ const (
 func processTask(ctx context.Context, q *pb.Query, gid uint32) (*pb.Result, error) {
 	ctx, span := otel.Tracer("").Start(ctx, "processTask."+q.Attr)
 	defer span.End()
+	defer x.ObservePredicate(ctx, q.Attr, "query", time.Now())
 
 	stop := x.SpanTimer(span, "processTask"+q.Attr)
 	defer stop()
@@ -1022,6 +1056,10 @@ func processTask(ctx context.Context, q *pb.Query, gid uint32) (*pb.Result, erro
 		return nil, errUnservedTablet
 	}
 
+	if err := tiers().hot(ctx, q.Attr, false); err != nil {
+		return nil, err
+	}
+
 	var qs queryState
 	if q.Cache == UseTxnCache {
 		qs.cache = posting.Oracle().CacheAt(q.ReadTs)
@@ -1055,7 +1093,13 @@ func (qs *queryState) helpProcessTask(ctx context.Context, q *pb.Query, gid uint
 	}
 
 	if q.Reverse && !schema.State().IsReversed(ctx, attr) {
-		return nil, errors.Errorf("Predicate %s doesn't have reverse edge", x.ParseAttr(attr))
+		// Without the reverse index, the edges are traversed backwards by scanning the tablet.
+		if srcFn.fnType != notAFunction || x.Config.LimitReverseScanKeys == 0 {
+			return nil, errors.Errorf("Predicate %s doesn't have reverse edge", x.ParseAttr(attr))
+		}
+		if qs.cache, err = scanReverse(ctx, q); err != nil {
+			return nil, err
+		}
 	}
 
 	if needsIndex(srcFn.fnType, q.UidList) && !schema.State().IsIndexed(ctx, q.Attr) {
@@ -1123,7 +1167,14 @@ func (qs *queryState) helpProcessTask(ctx context.Context, q *pb.Query, gid uint
 		}
 	}
 
-	if srcFn.fnType == compareScalarFn && srcFn.isFuncAtRoot {
+	if srcFn.fnType == notExistsFn && srcFn.isFuncAtRoot {
+		span.AddEvent("handleNotExistsFunction")
+		if err := qs.handleNotExistsFunction(ctx, q, out, srcFn); err != nil {
+			return nil, err
+		}
+	}
+
+	if srcFn.fnType == compareScalarFn && (srcFn.isFuncAtRoot || srcFn.useCountIndex) {
 		span.AddEvent("handleCompareScalarFunction")
 		if err := qs.handleCompareScalarFunction(ctx, args); err != nil {
 			return nil, err
@@ -1137,13 +1188,20 @@ func (qs *queryState) helpProcessTask(ctx context.Context, q *pb.Query, gid uint
 		}
 	}
 
-	if srcFn.fnType == matchFn {
+	if srcFn.fnType == matchFn || srcFn.fnType == levenshteinFn {
 		span.AddEvent("handleMatchFunction")
 		if err := qs.handleMatchFunction(ctx, args); err != nil {
 			return nil, err
 		}
 	}
 
+	if srcFn.fnType == pluginFn {
+		span.AddEvent("handlePluginFunction")
+		if err := qs.handlePluginFunction(ctx, args); err != nil {
+			return nil, err
+		}
+	}
+
 	// We fetch the actual value for the uids, compare them to the value in the
 	// request and filter the uids only if the tokenizer IsLossy.
 	if srcFn.fnType == compareAttrFn && len(srcFn.tokens) > 0 {
@@ -1170,6 +1228,13 @@ func (qs *queryState) helpProcessTask(ctx context.Context, q *pb.Query, gid uint
 		}
 	}
 
+	// The uids of the has function are sampled as the tablet is iterated over.
+	if q.Sample > 0 && srcFn.isFuncAtRoot && srcFn.fnType != hasFn {
+		for _, l := range out.UidMatrix {
+			l.Uids = algo.SampleUids(l.Uids, int(q.Sample))
+		}
+	}
+
 	out.IntersectDest = srcFn.intersectDest
 	return out, nil
 }
@@ -1206,7 +1271,16 @@ func (qs *queryState) handleCompareScalarFunction(ctx context.Context, arg funcA
 		readTs:  arg.q.ReadTs,
 		reverse: arg.q.Reverse,
 	}
-	return qs.evaluate(cp, arg.out)
+	if err := qs.evaluate(cp, arg.out); err != nil {
+		return err
+	}
+	if arg.srcFn.useCountIndex {
+		// The count index returns every uid in the range, keep only the ones being filtered.
+		for _, l := range arg.out.UidMatrix {
+			algo.IntersectWith(l, arg.q.UidList, l)
+		}
+	}
+	return nil
 }
 
 func (qs *queryState) handleRegexFunction(ctx context.Context, arg funcArgs) error {
@@ -1511,6 +1585,13 @@ func (qs *queryState) handleMatchFunction(ctx context.Context, arg funcArgs) err
 	case arg.q.UidList != nil && len(arg.q.UidList.Uids) != 0:
 		uids = arg.q.UidList
 
+	case arg.srcFn.fnType == levenshteinFn:
+		if arg.q.UidList == nil {
+			return errors.Errorf("levenshtein can only be used in a filter. " +
+				"Please use fuzzy with a trigram index at root.")
+		}
+		uids = arg.q.UidList
+
 	case schema.State().HasTokenizer(ctx, tok.IdentTrigram, attr):
 		var err error
 		uids, err = uidsForMatch(attr, arg)
@@ -1583,6 +1664,104 @@ func (qs *queryState) handleMatchFunction(ctx context.Context, arg funcArgs) err
 	return nil
 }
 
+// pluginBatchSize is the number of values sent to a plugin in one call to evaluate a function.
+const pluginBatchSize = 1000
+
+func (qs *queryState) handlePluginFunction(ctx context.Context, arg funcArgs) error {
+	span := trace.SpanFromContext(ctx)
+	stop := x.SpanTimer(span, "handlePluginFunction")
+	defer stop()
+
+	attr := arg.q.Attr
+	typ := arg.srcFn.atype
+	if !typ.IsScalar() {
+		return errors.Errorf("Attribute not scalar: %s %v", attr, typ)
+	}
+	if arg.q.UidList == nil {
+		return errors.Errorf("plugin functions can only be used in a filter.")
+	}
+	uids := arg.q.UidList
+	arg.out.UidMatrix = append(arg.out.UidMatrix, uids)
+
+	isList := schema.State().IsList(attr)
+	lang := langForFunc(arg.q.Langs)
+	fnType := arg.srcFn.plugin.Type()
+	filtered := &pb.List{}
+
+	// The values of the uids are evaluated in batches, and a uid matches if any of its values does.
+	var vals []types.Val
+	var owners []uint64
+	evaluate := func() error {
+		if len(vals) == 0 {
+			return nil
+		}
+		matches, err := arg.srcFn.plugin.Evaluate(ctx, arg.srcFn.pluginArgs, vals)
+		if err != nil {
+			return err
+		}
+		for i, match := range matches {
+			last := len(filtered.Uids) - 1
+			if match && (last < 0 || filtered.Uids[last] != owners[i]) {
+				filtered.Uids = append(filtered.Uids, owners[i])
+			}
+		}
+		vals, owners = vals[:0], owners[:0]
+		return nil
+	}
+	for _, uid := range uids.Uids {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		pl, err := qs.cache.Get(x.DataKey(attr, uid))
+		if err != nil {
+			return err
+		}
+
+		uidVals := make([]types.Val, 1)
+		switch {
+		case lang != "":
+			uidVals[0], err = pl.ValueForTag(arg.q.ReadTs, lang)
+
+		case isList:
+			uidVals, err = pl.AllUntaggedValues(arg.q.ReadTs)
+
+		default:
+			uidVals[0], err = pl.Value(arg.q.ReadTs)
+		}
+		if err != nil {
+			if err == posting.ErrNoValue {
+				continue
+			}
+			return err
+		}
+
+		for _, val := range uidVals {
+			// The values that can't be converted to the type of the function don't match it.
+			v, err := types.Convert(val, fnType)
+			if err != nil {
+				continue
+			}
+			vals = append(vals, v)
+			owners = append(owners, uid)
+		}
+		if len(vals) >= pluginBatchSize {
+			if err := evaluate(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := evaluate(); err != nil {
+		return err
+	}
+
+	for i := range arg.out.UidMatrix {
+		algo.IntersectWith(arg.out.UidMatrix[i], filtered, arg.out.UidMatrix[i])
+	}
+	return nil
+}
+
 func (qs *queryState) filterGeoFunction(ctx context.Context, arg funcArgs) error {
 	span := trace.SpanFromContext(ctx)
 	stop := x.SpanTimer(span, "filterGeoFunction")
@@ -1788,6 +1967,12 @@ type functionContext struct {
 	atype          types.TypeID
 	vectorInfo     []float32
 	vectorUid      uint64
+	// useCountIndex is set when a count comparison used as a filter is answered from the
+	// count index instead of reading the length of every posting list.
+	useCountIndex bool
+	// plugin and pluginArgs are the function of a plugin and its arguments, for pluginFn.
+	plugin     *plugin.Function
+	pluginArgs []string
 }
 
 const (
@@ -1872,6 +2057,45 @@ func planForEqFilter(fc *functionContext, pred string, uidlist []uint64) {
 	fc.n = len(fc.tokens)
 }
 
+// countRange returns the inclusive range of counts matched by a count comparison function.
+func countRange(fname string, thresholds []int64) (int64, int64) {
+	switch fname {
+	case "eq":
+		return thresholds[0], thresholds[0]
+	case "gt":
+		return thresholds[0] + 1, math.MaxInt64
+	case "ge":
+		return thresholds[0], math.MaxInt64
+	case "lt":
+		return 0, thresholds[0] - 1
+	case "le":
+		return 0, thresholds[0]
+	case between:
+		return thresholds[0], thresholds[1]
+	}
+	return 0, math.MaxInt64
+}
+
+// planForCountFilter decides whether a count comparison used inside a filter should be
+// answered using the count index. Walking the index is only worth it when the count histogram
+// estimates that it would return fewer uids than the filter has to check. Until the histogram is
+// seeded from the count index, like after a restart, the filter checks the uids itself. Ranges
+// which include zero can't be answered by the index as zero counts are not tracked.
+func planForCountFilter(ctx context.Context, q *pb.Query, fc *functionContext) bool {
+	if uint64(len(q.UidList.GetUids())) < Config.TypeFilterUidLimit || checkUidZero(q.UidList.Uids) {
+		return false
+	}
+	if !schema.State().HasCount(ctx, q.Attr) {
+		return false
+	}
+	lo, hi := countRange(fc.fname, fc.threshold)
+	if lo < 1 || hi < lo {
+		return false
+	}
+	estimate, ok := posting.GetStatsHolder().EstimateCountRange(q.Attr, q.Reverse, lo, hi)
+	return ok && estimate < uint64(len(q.UidList.Uids))
+}
+
 func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 	fnType, f := parseFuncType(q.SrcFunc)
 	attr := q.Attr
@@ -2015,6 +2239,10 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		}
 		fc.threshold = thresholds
 		checkRoot(q, fc)
+		if !fc.isFuncAtRoot && planForCountFilter(ctx, q, fc) {
+			fc.n = 0
+			fc.useCountIndex = true
+		}
 	case geoFn:
 		// For geo functions, we get extra information used for filtering.
 		fc.tokens, fc.geoQuery, err = types.GetGeoTokens(q.SrcFunc)
@@ -2044,14 +2272,17 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		}
 		fc.intersectDest = needsIntersect(f)
 		fc.n = len(fc.tokens)
-	case matchFn:
+	case matchFn, levenshteinFn:
 		if err = ensureArgsCount(q.SrcFunc, 2); err != nil {
 			return nil, err
 		}
-		required, found := verifyStringIndex(ctx, attr, fnType)
-		if !found {
-			return nil, errors.Errorf("Attribute %s is not indexed with type %s", x.ParseAttr(attr),
-				required)
+		// levenshtein compares the values of the uids it filters, without an index.
+		if fnType == matchFn {
+			required, found := verifyStringIndex(ctx, attr, fnType)
+			if !found {
+				return nil, errors.Errorf("Attribute %s is not indexed with type %s",
+					x.ParseAttr(attr), required)
+			}
 		}
 		fc.intersectDest = needsIntersect(f)
 		// Max Levenshtein distance
@@ -2066,7 +2297,19 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		}
 		fc.threshold = []int64{max}
 		fc.tokens = q.SrcFunc.Args
-		fc.n = len(fc.tokens)
+		if fnType == matchFn {
+			fc.n = len(fc.tokens)
+		}
+	case pluginFn:
+		if len(q.SrcFunc.Args) == 0 {
+			return nil, errors.Errorf("Function '%s' requires the name of a function of a plugin",
+				q.SrcFunc.Name)
+		}
+		var ok bool
+		if fc.plugin, ok = plugin.GetFunction(q.SrcFunc.Args[0]); !ok {
+			return nil, errors.Errorf("No plugin serves a function named %q", q.SrcFunc.Args[0])
+		}
+		fc.pluginArgs = q.SrcFunc.Args[1:]
 	case customIndexFn:
 		if err = ensureArgsCount(q.SrcFunc, 2); err != nil {
 			return nil, err
@@ -2115,6 +2358,13 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		}
 		checkRoot(q, fc)
 	case similarToFn:
+		if len(q.JoinVectors) > 0 {
+			// The vectors of a similarity join are given by the query, the only argument is k.
+			if err = ensureArgsCount(q.SrcFunc, 1); err != nil {
+				return nil, err
+			}
+			break
+		}
 		if err = ensureArgsCount(q.SrcFunc, 2); err != nil {
 			return nil, err
 		}
@@ -2122,7 +2372,7 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		if err != nil {
 			return nil, err
 		}
-	case uidInFn:
+	case uidInFn, notExistsFn:
 		for _, arg := range q.SrcFunc.Args {
 			uidParsed, err := strconv.ParseUint(arg, 0, 64)
 			if err != nil {
@@ -2138,7 +2388,7 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 			return fc.uidsPresent[i] < fc.uidsPresent[j]
 		})
 		checkRoot(q, fc)
-		if fc.isFuncAtRoot {
+		if fc.isFuncAtRoot && fnType == uidInFn {
 			return nil, errors.Errorf("uid_in function not allowed at root")
 		}
 	default:
@@ -2147,6 +2397,42 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 	return fc, nil
 }
 
+// similarityJoin searches the index for the k nearest neighbours of each vector of the join of q,
+// appending them to the uid matrix of out. The candidates of uid_list and the uid of the vector
+// are filtered in the search, so that k neighbours are found among the other candidates.
+func similarityJoin(ctx context.Context, indexer index.VectorIndex[float32],
+	qc index.CacheType, q *pb.Query, k int, out *pb.Result) error {
+
+	if len(q.JoinUids) != len(q.JoinVectors) {
+		return errors.Errorf("Got %d uids for the %d vectors of the similarity join",
+			len(q.JoinUids), len(q.JoinVectors))
+	}
+	for i, vec := range q.JoinVectors {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		src := q.JoinUids[i]
+		filter := func(_, _ []float32, uid uint64) bool {
+			if uid == src {
+				return false
+			}
+			if q.UidList == nil {
+				return true
+			}
+			uids := q.UidList.Uids
+			j := sort.Search(len(uids), func(j int) bool { return uids[j] >= uid })
+			return j < len(uids) && uids[j] == uid
+		}
+		nnUids, err := indexer.Search(ctx, qc, types.BytesAsFloatArray(vec), k, filter)
+		if err != nil {
+			return err
+		}
+		sort.Slice(nnUids, func(i, j int) bool { return nnUids[i] < nnUids[j] })
+		out.UidMatrix = append(out.UidMatrix, &pb.List{Uids: nnUids})
+	}
+	return nil
+}
+
 func interpretVFloatOrUid(val string) ([]float32, uint64, error) {
 	vf, err := types.ParseVFloat(val)
 	if err == nil {
@@ -2626,7 +2912,7 @@ func (qs *queryState) handleHasFunction(ctx context.Context, q *pb.Query, out *p
 	}
 
 	isIndexed := schema.State().IsIndexed(ctx, q.Attr)
-	if isIndexed && ok && isSortablePred && hasOrders {
+	if isIndexed && ok && isSortablePred && hasOrders && q.Sample == 0 {
 		var order *pb.Order
 		for _, i := range q.Order.Order {
 			if i.Attr == q.Attr {
@@ -2668,35 +2954,196 @@ func (qs *queryState) handleHasFunction(ctx context.Context, q *pb.Query, out *p
 		return err
 	}
 
-	cnt := int32(0)
+	// collectUids returns the uids passed by iterate to its callback, applying the offset, first
+	// and sample of the query. The sample is taken as the tablet is iterated over, without keeping
+	// all of its uids.
+	collectUids := func(iterate func(collect func(uint64) error) error) ([]uint64, error) {
+		var sample *algo.UidSample
+		if q.Sample > 0 {
+			sample = algo.NewUidSample(int(q.Sample))
+		}
+		var uids []uint64
+		cnt := int32(0)
+		collect := func(uid uint64) error {
+			if sample != nil {
+				sample.Add(uid)
+				return nil
+			}
+			if cnt < q.Offset {
+				cnt++
+				return nil
+			}
+			uids = append(uids, uid)
 
-	iteratorFunc := &posting.IterateDiskArgs{
+			// We'll stop fetching if we fetch the required count.
+			if len(uids) >= int(q.First) {
+				return posting.ErrStopIteration
+			}
+			return nil
+		}
+		if err := iterate(collect); err != nil {
+			return nil, err
+		}
+		if sample != nil {
+			uids = sample.Uids()
+		}
+		return uids, nil
+	}
+
+	// Presence bitmaps only track data keys, and can't tell apart values by language. Reading
+	// them is the presence-read experimental path.
+	presenceMode := x.PathOff
+	if posting.EnablePresenceBitmap && !q.Reverse && !needFiltering {
+		presenceMode = x.ExperimentalPathMode(ctx, x.ParseNamespace(q.Attr), "presence-read")
+		if presenceMode == x.PathShadow && q.Sample > 0 {
+			// The samples of both paths would differ.
+			presenceMode = x.PathOff
+		}
+	}
+	// fromPresence returns the uids from the presence bitmap of the predicate, or false if it
+	// doesn't have one yet.
+	fromPresence := func() ([]uint64, bool, error) {
+		var used bool
+		uids, err := collectUids(func(collect func(uint64) error) error {
+			var err error
+			used, err = posting.MemLayerInstance.IteratePresence(ctx, q.Attr, q.ReadTs,
+				q.AfterUid, collect)
+			return err
+		})
+		return uids, used, err
+	}
+	// fromDisk returns the uids by iterating over the tablet. If this iteration covers the whole
+	// tablet, its result is used to build the presence bitmap.
+	fromDisk := func() ([]uint64, error) {
+		buildPresence := presenceMode != x.PathOff && q.AfterUid == 0 && q.Offset == 0 &&
+			q.First == math.MaxInt32 && q.Sample == 0
+		presenceEpoch := posting.MemLayerInstance.PresenceEpoch()
+		uids, err := collectUids(func(collect func(uint64) error) error {
+			return posting.MemLayerInstance.IterateDisk(ctx, posting.IterateDiskArgs{
+				Prefix:         prefix,
+				ReadTs:         q.ReadTs,
+				Reverse:        false,
+				AllVersions:    true,
+				CheckInclusion: checkInclusion,
+				Function: func(l *posting.List, pk x.ParsedKey) error {
+					return collect(pk.Uid)
+				},
+				StartKey: startKey,
+			})
+		})
+		if err == nil && buildPresence {
+			posting.MemLayerInstance.BuildPresence(q.Attr, presenceEpoch, q.ReadTs, uids)
+		}
+		return uids, err
+	}
+
+	var err error
+	switch presenceMode {
+	case x.PathOn:
+		var used bool
+		if result.Uids, used, err = fromPresence(); err != nil {
+			return err
+		}
+		if used {
+			span.AddEvent("handleHasFunction result from presence bitmap", trace.WithAttributes(
+				attribute.Int("uid_count", len(result.Uids))))
+			out.UidMatrix = append(out.UidMatrix, result)
+			return nil
+		}
+		result.Uids, err = fromDisk()
+	case x.PathShadow:
+		result.Uids, err = x.ShadowPath(ctx, "presence-read", fromDisk,
+			func() ([]uint64, error) {
+				uids, used, err := fromPresence()
+				if err == nil && !used {
+					err = x.ErrShadowSkipped
+				}
+				return uids, err
+			}, slices.Equal[[]uint64])
+	default:
+		result.Uids, err = fromDisk()
+	}
+	if err != nil {
+		return err
+	}
+	span.AddEvent("handleHasFunction result", trace.WithAttributes(
+		attribute.Int("uid_count", len(result.Uids))))
+	out.UidMatrix = append(out.UidMatrix, result)
+	return nil
+}
+
+// handleNotExistsFunction handles not_exists(predicate, uid(x)) at root, returning the nodes
+// having the predicate, none of whose edges point to the uids of x. The tablet is iterated over
+// once, and the edges of every node are read until the first one pointing to x.
+func (qs *queryState) handleNotExistsFunction(ctx context.Context, q *pb.Query, out *pb.Result,
+	srcFn *functionContext) error {
+	span := trace.SpanFromContext(ctx)
+	stop := x.SpanTimer(span, "handleNotExistsFunction")
+	defer stop()
+
+	initKey := x.ParsedKey{Attr: q.Attr}
+	startKey := x.DataKey(q.Attr, q.AfterUid+1)
+	prefix := initKey.DataPrefix()
+	key := func(uid uint64) []byte { return x.DataKey(q.Attr, uid) }
+	if q.Reverse {
+		startKey = x.ReverseKey(q.Attr, q.AfterUid+1)
+		prefix = initKey.ReversePrefix()
+		key = func(uid uint64) []byte { return x.ReverseKey(q.Attr, uid) }
+	}
+
+	result := &pb.List{}
+	cnt := int32(0)
+	err := posting.MemLayerInstance.IterateDisk(ctx, posting.IterateDiskArgs{
 		Prefix:         prefix,
 		ReadTs:         q.ReadTs,
-		Reverse:        false,
 		AllVersions:    true,
-		CheckInclusion: checkInclusion,
-		Function: func(l *posting.List, pk x.ParsedKey) error {
-			if cnt < q.Offset {
+		CheckInclusion: func(uint64) error { return nil },
+		Function: func(_ *posting.List, pk x.ParsedKey) error {
+			// Read the posting list through the cache, which has the latest mutations.
+			pl, err := qs.cache.GetUids(key(pk.Uid))
+			if err != nil {
+				return err
+			}
+			found, err := hasEdgeTo(pl, q.ReadTs, srcFn.uidsPresent)
+			switch {
+			case err != nil:
+				return err
+			case found:
+				return nil
+			case cnt < q.Offset:
 				cnt++
 				return nil
 			}
 			result.Uids = append(result.Uids, pk.Uid)
-
-			// We'll stop fetching if we fetch the required count.
 			if len(result.Uids) >= int(q.First) {
 				return posting.ErrStopIteration
 			}
 			return nil
 		},
 		StartKey: startKey,
-	}
-
-	if err := posting.MemLayerInstance.IterateDisk(ctx, *iteratorFunc); err != nil {
+	})
+	if err != nil {
 		return err
 	}
-	span.AddEvent("handleHasFunction result", trace.WithAttributes(
+	span.AddEvent("handleNotExistsFunction result", trace.WithAttributes(
 		attribute.Int("uid_count", len(result.Uids))))
 	out.UidMatrix = append(out.UidMatrix, result)
 	return nil
 }
+
+// hasEdgeTo returns true if the posting list has an edge to one of the sorted uids, stopping at
+// the first one.
+func hasEdgeTo(pl *posting.List, readTs uint64, uids []uint64) (bool, error) {
+	if len(uids) == 0 {
+		return false, nil
+	}
+	plist, err := pl.Uids(posting.ListOptions{
+		ReadTs:    readTs,
+		Intersect: &pb.List{Uids: uids},
+		First:     1,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(plist.Uids) > 0, nil
+}
@@ -0,0 +1,511 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/klauspost/compress/s2"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dgraph-io/badger/v4"
+	bpb "github.com/dgraph-io/badger/v4/pb"
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/hypermodeinc/dgraph/v25/enc"
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// tieringStateFile is the file of the postings directory holding the tablets copied to the
+// object storage.
+const tieringStateFile = "tiering.json"
+
+// tiering moves the tablets of the group which haven't been accessed for a while to object
+// storage and drops them from Badger, so that the local disk only holds the hot data. A cold tablet
+// is read back into Badger on its next access, which then acts as its cache until it's idle again.
+// The tablets are copied rolled up at the ts they're offloaded at, without their older versions.
+type tiering struct {
+	sync.Mutex
+	handler   UriHandler
+	root      string
+	coldAfter time.Duration
+	interval  time.Duration
+	statePath string
+	started   time.Time
+	// tablets holds the copies of the tablets in the object storage. A cold tablet only has its
+	// copy, and the copy of a tablet is forgotten once the tablet is written.
+	tablets map[string]*TieredTablet
+	// accessed holds the time of the last access of the tablets. The tablets which weren't
+	// accessed since the start of the Alpha are considered accessed at its start.
+	accessed map[string]time.Time
+	// locks serialize the offload and the rehydration of each tablet.
+	locks map[string]*sync.Mutex
+}
+
+// TieredTablet is a tablet copied to the object storage.
+type TieredTablet struct {
+	Namespace uint64    `json:"namespace"`
+	Predicate string    `json:"predicate"`
+	Attr      string    `json:"-"`
+	Path      string    `json:"path"`
+	ReadTs    uint64    `json:"readTs"`
+	Keys      int       `json:"keys"`
+	Size      int64     `json:"size"`
+	Cold      bool      `json:"cold"`
+	Offloaded time.Time `json:"offloaded"`
+}
+
+// newTiering returns the tiering of the tablets of the group of the node, or nil if it's disabled.
+func newTiering(gid uint32, id uint64) *tiering {
+	if Config.TieringConf == "" || Config.TieringConf == TieringDefaults {
+		return nil
+	}
+	flag := z.NewSuperFlag(Config.TieringConf).MergeAndCheckDefault(TieringDefaults)
+	dest := flag.GetString("dest")
+	if dest == "" {
+		return nil
+	}
+	uri, err := url.Parse(dest)
+	x.Check(err)
+	handler, err := NewUriHandler(uri, nil)
+	x.Check(err)
+
+	t := &tiering{
+		handler:   handler,
+		root:      fmt.Sprintf("group-%d/node-%#x", gid, id),
+		coldAfter: flag.GetDuration("cold-after"),
+		interval:  flag.GetDuration("interval"),
+		statePath: filepath.Join(Config.PostingDir, tieringStateFile),
+		started:   time.Now(),
+		tablets:   make(map[string]*TieredTablet),
+		accessed:  make(map[string]time.Time),
+		locks:     make(map[string]*sync.Mutex),
+	}
+	x.Check(t.load())
+	glog.Infof("Tiering the tablets idle for %v to %s. %d tablets are tiered.",
+		t.coldAfter, uri.Redacted(), len(t.tablets))
+	return t
+}
+
+// tiers returns the tiering of the node, or nil if it's disabled or the node isn't started.
+func tiers() *tiering {
+	if gr == nil || gr.Node == nil {
+		return nil
+	}
+	return gr.Node.tiering
+}
+
+func (t *tiering) load() error {
+	data, err := os.ReadFile(t.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "while reading the tiering state")
+	}
+	var tablets []*TieredTablet
+	if err := json.Unmarshal(data, &tablets); err != nil {
+		return errors.Wrapf(err, "while reading the tiering state")
+	}
+	for _, tab := range tablets {
+		tab.Attr = x.NamespaceAttr(tab.Namespace, tab.Predicate)
+		t.tablets[tab.Attr] = tab
+	}
+	return nil
+}
+
+// save writes the state of the tiered tablets. It must be called with the lock held.
+func (t *tiering) save() error {
+	data, err := json.Marshal(t.list())
+	if err != nil {
+		return err
+	}
+	tmp := t.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrapf(err, "while writing the tiering state")
+	}
+	return errors.Wrapf(os.Rename(tmp, t.statePath), "while writing the tiering state")
+}
+
+// list returns the tiered tablets sorted by attr. It must be called with the lock held.
+func (t *tiering) list() []*TieredTablet {
+	tablets := make([]*TieredTablet, 0, len(t.tablets))
+	for _, tab := range t.tablets {
+		tablets = append(tablets, tab)
+	}
+	sort.Slice(tablets, func(i, j int) bool { return tablets[i].Attr < tablets[j].Attr })
+	return tablets
+}
+
+func (t *tiering) lock(attr string) *sync.Mutex {
+	t.Lock()
+	defer t.Unlock()
+	mu, ok := t.locks[attr]
+	if !ok {
+		mu = &sync.Mutex{}
+		t.locks[attr] = mu
+	}
+	return mu
+}
+
+// hot records an access to the tablet, and reads it back from the object storage if it's cold.
+// The copy of a tablet which is written is forgotten, as it's stale.
+func (t *tiering) hot(ctx context.Context, attr string, write bool) error {
+	if t == nil {
+		return nil
+	}
+	mu := t.lock(attr)
+	mu.Lock()
+	defer mu.Unlock()
+
+	t.Lock()
+	t.accessed[attr] = time.Now()
+	tab := t.tablets[attr]
+	t.Unlock()
+	if tab == nil {
+		return nil
+	}
+	if tab.Cold {
+		start := time.Now()
+		if err := t.rehydrate(ctx, tab); err != nil {
+			return errors.Wrapf(err, "while reading back the tablet of %s from %s",
+				x.ParseAttr(attr), tab.Path)
+		}
+		glog.Infof("Read back the tablet of %s with %d keys from %s in %v", x.ParseAttr(attr),
+			tab.Keys, tab.Path, time.Since(start).Round(time.Millisecond))
+	}
+
+	if !tab.Cold && !write {
+		return nil
+	}
+	t.Lock()
+	defer t.Unlock()
+	if write {
+		delete(t.tablets, attr)
+	} else {
+		tab.Cold = false
+	}
+	return t.save()
+}
+
+// rehydrate writes the keys of the copy of the tablet back to Badger at their versions.
+func (t *tiering) rehydrate(ctx context.Context, tab *TieredTablet) error {
+	r, err := t.handler.Stream(tab.Path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	dr, err := enc.GetReader(x.WorkerConfig.EncryptionKey, r)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReaderSize(s2.NewReader(dr), 16<<10)
+
+	wb := pstore.NewManagedWriteBatch()
+	defer wb.Cancel()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var sz uint64
+		err := binary.Read(br, binary.LittleEndian, &sz)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		buf := make([]byte, sz)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return err
+		}
+		var list bpb.KVList
+		if err := proto.Unmarshal(buf, &list); err != nil {
+			return err
+		}
+		for _, kv := range list.Kv {
+			e := &badger.Entry{Key: kv.Key, Value: kv.Value}
+			if len(kv.UserMeta) > 0 {
+				e.UserMeta = kv.UserMeta[0]
+			}
+			if err := wb.SetEntryAt(e.WithDiscard(), kv.Version); err != nil {
+				return err
+			}
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return err
+	}
+	posting.ResetCache()
+	return nil
+}
+
+// offload copies the tablet to the object storage, unless a copy of it is already there, and
+// drops it locally. Unless force is set, only a tablet which wasn't accessed for the cold-after
+// duration is offloaded. The offload is given up if the tablet is accessed meanwhile.
+func (t *tiering) offload(ctx context.Context, attr string, force bool) error {
+	t.Lock()
+	accessed := t.accessed[attr]
+	tab := t.tablets[attr]
+	t.Unlock()
+	if tab != nil && tab.Cold {
+		return nil
+	}
+	idleSince := accessed
+	if idleSince.IsZero() {
+		idleSince = t.started
+	}
+	if !force && time.Since(idleSince) < t.coldAfter {
+		return nil
+	}
+
+	if tab == nil {
+		var err error
+		if tab, err = t.upload(ctx, attr); err != nil {
+			return errors.Wrapf(err, "while copying the tablet of %s", x.ParseAttr(attr))
+		}
+	}
+
+	mu := t.lock(attr)
+	mu.Lock()
+	defer mu.Unlock()
+	if err := detectPendingTxns(attr); err != nil {
+		return err
+	}
+
+	t.Lock()
+	if !t.accessed[attr].Equal(accessed) {
+		t.Unlock()
+		return errors.Errorf("The tablet of %s was accessed while being offloaded",
+			x.ParseAttr(attr))
+	}
+	tab.Cold = true
+	tab.Offloaded = time.Now()
+	t.tablets[attr] = tab
+	err := t.save()
+	t.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := pstore.DropPrefix(x.PredicatePrefix(attr)); err != nil {
+		return errors.Wrapf(err, "while dropping the tablet of %s", x.ParseAttr(attr))
+	}
+	posting.ResetCache()
+	glog.Infof("Offloaded the tablet of %s with %d keys to %s", x.ParseAttr(attr), tab.Keys,
+		tab.Path)
+	return nil
+}
+
+// upload writes the keys of the tablet, rolled up at the max assigned ts, to a new file of the
+// object storage, like the backups are written.
+func (t *tiering) upload(ctx context.Context, attr string) (*TieredTablet, error) {
+	readTs := posting.Oracle().MaxAssigned()
+	dir := filepath.Join(t.root, hex.EncodeToString([]byte(attr)))
+	if err := t.handler.CreateDir(dir); err != nil {
+		return nil, err
+	}
+	tab := &TieredTablet{
+		Namespace: x.ParseNamespace(attr),
+		Predicate: x.ParseAttr(attr),
+		Attr:      attr,
+		Path:      filepath.Join(dir, fmt.Sprintf("%d.kv", readTs)),
+		ReadTs:    readTs,
+	}
+	w, err := t.handler.CreateFile(tab.Path)
+	if err != nil {
+		return nil, err
+	}
+	eWriter, err := enc.GetWriter(x.WorkerConfig.EncryptionKey, w)
+	if err != nil {
+		return nil, err
+	}
+	cWriter := s2.NewWriter(eWriter)
+
+	stream := pstore.NewStreamAt(readTs)
+	stream.LogPrefix = "Dgraph.Tiering"
+	stream.Prefix = x.PredicatePrefix(attr)
+	stream.ChooseKey = func(item *badger.Item) bool {
+		// The parts of a multi-part list are written with its main key.
+		pk, err := x.Parse(item.Key())
+		return err == nil && !pk.HasStartUid
+	}
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		l, err := posting.ReadPostingList(key, itr)
+		if err != nil {
+			return nil, err
+		}
+		kvs, err := l.Rollup(nil, readTs)
+		if err != nil {
+			return nil, err
+		}
+		return &bpb.KVList{Kv: kvs}, nil
+	}
+	stream.Send = func(buf *z.Buffer) error {
+		list, err := badger.BufferToKVList(buf)
+		if err != nil {
+			return err
+		}
+		for _, kv := range list.Kv {
+			tab.Keys++
+			tab.Size += int64(len(kv.Key) + len(kv.Value))
+		}
+		return writeKVList(list, cWriter)
+	}
+	if err := stream.Orchestrate(ctx); err != nil {
+		return nil, err
+	}
+	if err := cWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return tab, nil
+}
+
+// offloadIdle offloads the tablets of the group which weren't accessed for the cold-after
+// duration.
+func (t *tiering) offloadIdle(ctx context.Context) {
+	for attr := range GetMembershipState().GetGroups()[GroupId()].GetTablets() {
+		if x.IsReservedPredicate(attr) {
+			continue
+		}
+		if _, err := schema.State().TypeOf(attr); err != nil {
+			// The tablet of a dropped predicate which isn't deleted yet.
+			continue
+		}
+		if err := t.offload(ctx, attr, false); err != nil {
+			glog.Warningf("Error while offloading the tablet of %s: %v", x.ParseAttr(attr), err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// rehydrateNs reads back the cold tablets of the namespace, or of all of them for math.MaxUint64,
+// for the operations reading whole namespaces.
+func (t *tiering) rehydrateNs(ctx context.Context, ns uint64) error {
+	if t == nil {
+		return nil
+	}
+	t.Lock()
+	var attrs []string
+	for attr, tab := range t.tablets {
+		if tab.Cold && (ns == math.MaxUint64 || tab.Namespace == ns) {
+			attrs = append(attrs, attr)
+		}
+	}
+	t.Unlock()
+	for _, attr := range attrs {
+		if err := t.hot(ctx, attr, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forget drops the copies of the tablets for which the filter returns true, once they're deleted
+// locally. The files are left in the object storage.
+func (t *tiering) forget(filter func(tab *TieredTablet) bool) error {
+	if t == nil {
+		return nil
+	}
+	t.Lock()
+	defer t.Unlock()
+	for attr, tab := range t.tablets {
+		if filter(tab) {
+			delete(t.tablets, attr)
+		}
+	}
+	return t.save()
+}
+
+func (t *tiering) forgetAttr(attr string) error {
+	return t.forget(func(tab *TieredTablet) bool { return tab.Attr == attr })
+}
+
+func (t *tiering) forgetNs(ns uint64) error {
+	return t.forget(func(tab *TieredTablet) bool { return tab.Namespace == ns })
+}
+
+func (t *tiering) forgetAll() error {
+	return t.forget(func(*TieredTablet) bool { return true })
+}
+
+// processTiering looks for the idle tablets to offload at every interval.
+func (n *node) processTiering() {
+	defer n.closer.Done() // CLOSER:1
+	t := n.tiering
+	if t == nil {
+		return
+	}
+	tick := time.NewTicker(t.interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-n.closer.HasBeenClosed():
+			return
+		case <-tick.C:
+			t.offloadIdle(n.closer.Ctx())
+		}
+	}
+}
+
+// TieredTablets returns the tablets of the namespace copied to the object storage, or an error if
+// the tiering is disabled.
+func TieredTablets(ns uint64) ([]*TieredTablet, error) {
+	t := tiers()
+	if t == nil {
+		return nil, errors.New("Tiering is disabled, set --tiering dest to enable it")
+	}
+	t.Lock()
+	defer t.Unlock()
+	var tablets []*TieredTablet
+	for _, tab := range t.list() {
+		if tab.Namespace == ns {
+			tablets = append(tablets, tab)
+		}
+	}
+	return tablets, nil
+}
+
+// OffloadTablet offloads the tablet of the predicate of the namespace now, even if it's accessed
+// recently.
+func OffloadTablet(ctx context.Context, ns uint64, pred string) error {
+	t := tiers()
+	if t == nil {
+		return errors.New("Tiering is disabled, set --tiering dest to enable it")
+	}
+	attr := x.NamespaceAttr(ns, pred)
+	if gid, err := groups().BelongsToReadOnly(attr, 0); err != nil {
+		return err
+	} else if gid != groups().groupId() {
+		return errors.Errorf("Predicate %s isn't served by group %d", pred, groups().groupId())
+	}
+	if x.IsReservedPredicate(attr) {
+		return errors.Errorf("Predicate %s is reserved and can't be offloaded", pred)
+	}
+	return t.offload(ctx, attr, true)
+}
@@ -0,0 +1,106 @@
+//go:build integration
+
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/tok"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestTiering(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`tier_name: string @index(exact) .`), 1))
+	attr := x.AttrInRootNamespace("tier_name")
+	for uid, name := range map[uint64]string{1: "alice", 2: "bob"} {
+		edge := &pb.DirectedEdge{Attr: attr, Entity: uid, Value: []byte(name)}
+		addEdge(t, edge, getOrCreate(x.DataKey(attr, uid)))
+	}
+	value := func(uid uint64) string {
+		l, err := posting.GetNoStore(x.DataKey(attr, uid), math.MaxUint64)
+		require.NoError(t, err)
+		val, err := l.Value(math.MaxUint64)
+		if err == posting.ErrNoValue {
+			return ""
+		}
+		require.NoError(t, err)
+		return string(val.Value.([]byte))
+	}
+	indexed := func(name string) []uint64 {
+		l, err := posting.GetNoStore(x.IndexKey(attr, string([]byte{tok.IdentExact})+name), math.MaxUint64)
+		require.NoError(t, err)
+		uids, err := l.Uids(posting.ListOptions{ReadTs: math.MaxUint64})
+		require.NoError(t, err)
+		return uids.Uids
+	}
+
+	dir := t.TempDir()
+	tier := &tiering{
+		handler:   NewFileHandler(&url.URL{Path: filepath.Join(dir, "cold")}),
+		root:      "group-1/node-0x1",
+		coldAfter: time.Hour,
+		statePath: filepath.Join(dir, tieringStateFile),
+		started:   time.Now(),
+		tablets:   make(map[string]*TieredTablet),
+		accessed:  make(map[string]time.Time),
+		locks:     make(map[string]*sync.Mutex),
+	}
+	ctx := context.Background()
+
+	// A tablet accessed recently isn't offloaded, unless forced.
+	require.Equal(t, []uint64{2}, indexed("bob"))
+	require.NoError(t, tier.offload(ctx, attr, false))
+	require.Empty(t, tier.tablets)
+	require.NoError(t, tier.offload(ctx, attr, true))
+	require.Len(t, tier.tablets, 1)
+	tab := tier.tablets[attr]
+	require.True(t, tab.Cold)
+	require.Equal(t, "tier_name", tab.Predicate)
+	require.Equal(t, 4, tab.Keys)
+	require.Empty(t, value(1))
+	require.Empty(t, indexed("bob"))
+
+	// The state is kept across restarts.
+	loaded := &tiering{statePath: tier.statePath, tablets: make(map[string]*TieredTablet)}
+	require.NoError(t, loaded.load())
+	require.Equal(t, tab.Path, loaded.tablets[attr].Path)
+	require.True(t, loaded.tablets[attr].Cold)
+
+	// A read brings the tablet back, keeping its copy for the next offload.
+	require.NoError(t, tier.hot(ctx, attr, false))
+	require.Equal(t, "alice", value(1))
+	require.Equal(t, "bob", value(2))
+	require.Equal(t, []uint64{2}, indexed("bob"))
+	require.False(t, tier.tablets[attr].Cold)
+
+	require.NoError(t, tier.offload(ctx, attr, true))
+	require.Empty(t, value(2))
+	require.Equal(t, tab.Path, tier.tablets[attr].Path)
+
+	// A write brings the tablet back, and its copy is stale.
+	require.NoError(t, tier.hot(ctx, attr, true))
+	require.Equal(t, "bob", value(2))
+	require.Empty(t, tier.tablets)
+
+	require.NoError(t, tier.offload(ctx, attr, true))
+	require.NoError(t, tier.forgetAttr(attr))
+	require.Empty(t, tier.tablets)
+	require.NoError(t, tier.rehydrateNs(ctx, math.MaxUint64))
+	require.Empty(t, value(1))
+}
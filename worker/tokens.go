@@ -49,7 +49,7 @@ func verifyCustomIndex(ctx context.Context, attr string, tokenizerName string) b
 		return false
 	}
 	for _, t := range schema.State().Tokenizer(ctx, attr) {
-		if t.Identifier() >= tok.IdentCustom && t.Name() == tokenizerName {
+		if _, ok := t.(tok.CustomTokenizer); ok && t.Name() == tokenizerName {
 			return true
 		}
 	}
@@ -0,0 +1,162 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+// expiredTxnRetention is how long the txns aborted for exceeding their maximum duration are
+// remembered.
+const expiredTxnRetention = time.Hour
+
+var errTxnNotPending = status.Error(codes.NotFound,
+	"Transaction isn't pending. It has been committed or aborted")
+
+// expiredTxns remembers the txns which this node aborted for exceeding their maximum duration,
+// so that their later mutations and heartbeats fail with x.ErrTxnExpired, instead of the txns
+// looking like they were never started. Only the leader aborts the txns, so the other nodes of
+// the group, and a new leader, don't know about them.
+type expiredTxns struct {
+	sync.Mutex
+	ts map[uint64]time.Time
+}
+
+func newExpiredTxns() *expiredTxns {
+	return &expiredTxns{ts: make(map[uint64]time.Time)}
+}
+
+func (e *expiredTxns) add(startTs []uint64, now time.Time) {
+	e.Lock()
+	defer e.Unlock()
+	for ts, at := range e.ts {
+		if now.Sub(at) > expiredTxnRetention {
+			delete(e.ts, ts)
+		}
+	}
+	for _, ts := range startTs {
+		e.ts[ts] = now
+	}
+}
+
+func (e *expiredTxns) has(startTs uint64) bool {
+	e.Lock()
+	defer e.Unlock()
+	_, ok := e.ts[startTs]
+	return ok
+}
+
+// checkTxnAlive returns nil if the txn is pending in this group, and hasn't exceeded its
+// maximum duration.
+func (n *node) checkTxnAlive(startTs uint64) error {
+	txn := posting.Oracle().GetTxn(startTs)
+	switch {
+	case txn == nil && n.expiredTxns.has(startTs):
+		return x.ErrTxnExpired
+	case txn == nil:
+		return errTxnNotPending
+	case txn.Exceeds(x.WorkerConfig.MaxTxnDuration, time.Now()):
+		return x.ErrTxnExpired
+	}
+	return nil
+}
+
+// KeepAliveOverNetwork proposes a heartbeat of the txn to all the groups, so that the txn isn't
+// aborted for being idle. It fails if the txn isn't pending in any group, or if it has exceeded
+// its maximum duration.
+func KeepAliveOverNetwork(ctx context.Context, startTs uint64) error {
+	if startTs == 0 {
+		return errors.New("StartTs must be provided")
+	}
+	gids := groups().KnownGroups()
+	resCh := make(chan res, len(gids))
+	for _, gid := range gids {
+		m := &pb.Mutations{GroupId: gid, StartTs: startTs, Heartbeat: true}
+		go proposeOrSend(ctx, gid, m, resCh)
+	}
+
+	var pending bool
+	var rerr error
+	for range gids {
+		res := <-resCh
+		switch {
+		case res.err == nil:
+			pending = true
+		case status.Code(res.err) == codes.NotFound:
+			// The txn hasn't mutated the predicates of this group.
+		case errors.Is(res.err, x.ErrTxnExpired):
+			rerr = x.ErrTxnExpired
+		case rerr == nil:
+			rerr = res.err
+		}
+	}
+	switch {
+	case rerr != nil:
+		return rerr
+	case !pending:
+		return errTxnNotPending
+	}
+	return nil
+}
+
+// TxnStatus reports a pending transaction of this group.
+type TxnStatus struct {
+	StartTs    uint64   `json:"startTs"`
+	Namespaces []uint64 `json:"namespaces"`
+	Age        string   `json:"age"`
+	Idle       string   `json:"idle"`
+	// Keys is the number of posting lists mutated by the transaction, and SizeBytes the size of
+	// its mutations.
+	Keys      int `json:"keys"`
+	SizeBytes int `json:"sizeBytes"`
+	// MaxDuration is the maximum duration of the transaction, if any, and AbortIn the time left
+	// before the transaction is aborted for being idle or exceeding its maximum duration.
+	MaxDuration string `json:"maxDuration,omitempty"`
+	AbortIn     string `json:"abortIn"`
+}
+
+// PendingTxns reports the pending transactions of the group of this Alpha, sorted by start ts.
+func PendingTxns() []TxnStatus {
+	now := time.Now()
+	txns := posting.Oracle().PendingTxns()
+	res := make([]TxnStatus, 0, len(txns))
+	for _, txn := range txns {
+		abortAt := txn.LastUpdate.Add(x.WorkerConfig.AbortOlderThan)
+		var maxDuration time.Duration
+		for _, ns := range txn.Namespaces {
+			if d := x.WorkerConfig.MaxTxnDuration(ns); d > 0 && (maxDuration == 0 || d < maxDuration) {
+				maxDuration = d
+			}
+		}
+		st := TxnStatus{
+			StartTs:    txn.StartTs,
+			Namespaces: txn.Namespaces,
+			Age:        now.Sub(txn.Started).Round(time.Second).String(),
+			Idle:       now.Sub(txn.LastUpdate).Round(time.Second).String(),
+			Keys:       txn.Keys,
+			SizeBytes:  txn.Size,
+		}
+		if maxDuration > 0 {
+			st.MaxDuration = maxDuration.String()
+			if expireAt := txn.Started.Add(maxDuration); expireAt.Before(abortAt) {
+				abortAt = expireAt
+			}
+		}
+		st.AbortIn = max(abortAt.Sub(now), 0).Round(time.Second).String()
+		res = append(res, st)
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+func TestCheckTxnAlive(t *testing.T) {
+	n := &node{expiredTxns: newExpiredTxns()}
+	require.ErrorIs(t, n.checkTxnAlive(2001), errTxnNotPending)
+
+	// The txns aborted for exceeding their maximum duration are remembered for a while.
+	now := time.Now()
+	n.expiredTxns.add([]uint64{2001}, now.Add(-2*expiredTxnRetention))
+	n.expiredTxns.add([]uint64{2002}, now)
+	require.False(t, n.expiredTxns.has(2001))
+	require.ErrorIs(t, n.checkTxnAlive(2002), x.ErrTxnExpired)
+
+	const startTs = 2003
+	txn := posting.Oracle().RegisterStartTs(startTs)
+	defer posting.Oracle().ProcessDelta(&pb.OracleDelta{Txns: []*pb.TxnStatus{{StartTs: startTs}}})
+	txn.AddNamespaces([]*pb.DirectedEdge{{Attr: x.NamespaceAttr(3, "name")}})
+	require.NoError(t, n.checkTxnAlive(startTs))
+
+	defer func(durations map[uint64]time.Duration) {
+		x.WorkerConfig.TxnMaxDurationNs = durations
+	}(x.WorkerConfig.TxnMaxDurationNs)
+	x.WorkerConfig.TxnMaxDurationNs = map[uint64]time.Duration{3: time.Nanosecond}
+	time.Sleep(time.Millisecond)
+	require.ErrorIs(t, n.checkTxnAlive(startTs), x.ErrTxnExpired)
+	st := PendingTxns()
+	require.NotEmpty(t, st)
+	require.Equal(t, "1ns", st[len(st)-1].MaxDuration)
+	require.Equal(t, "0s", st[len(st)-1].AbortIn)
+}
@@ -12,6 +12,7 @@ import (
 	"log"
 	"math"
 	"net"
+	"strconv"
 	"sync"
 	"sync/atomic"
 
@@ -56,12 +57,33 @@ func Init(ps *badger.DB) {
 		grpc.MaxSendMsgSize(x.GrpcMaxSize),
 		grpc.MaxConcurrentStreams(math.MaxInt32),
 		grpc.StatsHandler(otelgrpc.NewClientHandler()),
+		grpc.UnaryInterceptor(x.RequestIdUnaryServerInterceptor),
 	}
 
 	if x.WorkerConfig.TLSServerConfig != nil {
 		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(x.WorkerConfig.TLSServerConfig)))
 	}
 	workerServer = grpc.NewServer(grpcOpts...)
+
+	x.HandleClusterSetting("cache-mb", func() string {
+		return strconv.FormatInt(Config.CacheMb, 10)
+	}, func(val string) error {
+		mb, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		return UpdateCacheMb(mb)
+	})
+	x.HandleClusterSetting("log-dql-request", func() string {
+		return strconv.FormatBool(LogDQLRequestEnabled())
+	}, func(val string) error {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		UpdateLogDQLRequest(enabled)
+		return nil
+	})
 }
 
 // grpcWorker struct implements the gRPC server interface.
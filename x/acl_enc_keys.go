@@ -6,6 +6,7 @@
 package x
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ed25519"
 	"fmt"
@@ -31,6 +32,23 @@ type Keys struct {
 	AclAccessTtl      time.Duration
 	AclRefreshTtl     time.Duration
 	EncKey            Sensitive
+	// EncNsKeyDir is the directory holding the per-namespace encryption keys.
+	EncNsKeyDir string
+	// EncKms is the KMS used to decrypt the encryption keys, if any.
+	EncKms KMS
+	// AclLdap is the LDAP directory used to authenticate the ACL users, if any.
+	AclLdap *LdapConfig
+	// AclScim holds the options of the SCIM endpoint, nil if it is disabled.
+	AclScim *ScimConfig
+}
+
+// ScimConfig holds the options of the SCIM endpoint, which identity providers use to provision
+// the ACL users and groups.
+type ScimConfig struct {
+	// Token is the bearer token the identity provider authenticates with.
+	Token Sensitive
+	// Namespace is the namespace in which the users and groups are provisioned.
+	Namespace uint64
 }
 
 // GetEncAclKeys returns the ACL and encryption keys as configured by the user
@@ -42,15 +60,24 @@ func GetEncAclKeys(config *viper.Viper) (*Keys, error) {
 	// Get SecretKey and EncKey from vault / acl / encryption SuperFlags
 	aclKey, encKey := vaultGetKeys(config)
 
+	kms, err := kmsFromFlag(encSuperFlag, config)
+	if err != nil {
+		return nil, err
+	}
 	encKeyFile := encSuperFlag.GetPath(flagEncKeyFile)
 	if encKeyFile != "" {
 		if encKey != nil {
 			return nil, fmt.Errorf("flags: Encryption key set in both vault and encryption flags")
 		}
-		var err error
 		if encKey, err = os.ReadFile(encKeyFile); err != nil {
 			return nil, fmt.Errorf("error reading encryption key from file: %s: %s", encKeyFile, err)
 		}
+		// With a KMS, the key file holds the encrypted key.
+		if kms != nil {
+			if encKey, err = KmsDecrypt(kms, encKey); err != nil {
+				return nil, err
+			}
+		}
 	}
 	if l := len(encKey); encKey != nil && l != 16 && l != 32 && l != 64 {
 		return nil, fmt.Errorf("encryption key must have length of 16, 32, or 64 bytes, got %d bytes instead", l)
@@ -72,6 +99,28 @@ func GetEncAclKeys(config *viper.Viper) (*Keys, error) {
 		AclAccessTtl:      aclSuperFlag.GetDuration(flagAclAccessTtl),
 		AclRefreshTtl:     aclSuperFlag.GetDuration(flagAclRefreshTtl),
 		EncKey:            encKey,
+		EncNsKeyDir:       encSuperFlag.GetPath(flagEncNsKeyDir),
+		EncKms:            kms,
+	}
+
+	if keys.AclLdap, err = ldapFromFlag(aclSuperFlag); err != nil {
+		return nil, err
+	}
+	if file := aclSuperFlag.GetPath(flagAclScimTokenFile); file != "" {
+		token, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading SCIM token from file: %s: %s", file, err)
+		}
+		if token = bytes.TrimSpace(token); len(token) == 0 {
+			return nil, fmt.Errorf("flags: SCIM token file %s is empty", file)
+		}
+		keys.AclScim = &ScimConfig{
+			Token:     token,
+			Namespace: aclSuperFlag.GetUint64(flagAclScimNamespace),
+		}
+	}
+	if aclKey == nil && (keys.AclLdap != nil || keys.AclScim != nil) {
+		return nil, fmt.Errorf("flags: LDAP and SCIM require ACL to be enabled")
 	}
 
 	if aclKey != nil {
@@ -165,6 +214,28 @@ func registerAclFlag(flag *pflag.FlagSet) {
 			"The TTL for the access JWT.").
 		Flag("refresh-ttl",
 			"The TTL for the refresh JWT.").
+		Flag(flagAclLdapURL, "The ldap:// or ldaps:// URL of the LDAP directory to authenticate "+
+			"the users with. If set, the users found in the directory log in with their LDAP "+
+			"password, and their groups are replaced with their LDAP groups. groot and the users "+
+			"not found in the directory log in with their Dgraph password.").
+		Flag(flagAclLdapCAFile, "The CA certificate to verify the ldaps:// server with, instead "+
+			"of the system roots.").
+		Flag(flagAclLdapBindDN, "The DN of the service account used to search the directory. "+
+			"The search is anonymous if not set.").
+		Flag(flagAclLdapBindPassFile, "The file that stores the password of the service account.").
+		Flag(flagAclLdapUserBase, "The DN under which the users are searched.").
+		Flag(flagAclLdapUserFilter, "The LDAP filter matching the users.").
+		Flag(flagAclLdapUserAttr, "The attribute holding the user ID.").
+		Flag(flagAclLdapGroupAttr, "The attribute of the users holding the DNs of their groups. "+
+			"The group name is the value of the first RDN of the group DN.").
+		Flag(flagAclLdapNamespace, "The namespace in which the LDAP users are synced.").
+		Flag(flagAclLdapSync, "How often all the LDAP users and their groups are synced, "+
+			"0 to only sync the users when they log in.").
+		Flag(flagAclScimTokenFile, "The file that stores the bearer token of the SCIM 2.0 "+
+			"endpoint at /scim/v2, used by identity providers to provision the users and groups. "+
+			"The endpoint is disabled if not set.").
+		Flag(flagAclScimNamespace, "The namespace in which the SCIM users and groups are "+
+			"provisioned.").
 		String()
 	flag.String(flagAcl, AclDefaults, helpText)
 }
@@ -174,6 +245,22 @@ func registerEncFlag(flag *pflag.FlagSet) {
 		Head("Encryption At Rest options").
 		Flag("key-file", "The file that stores the symmetric key of length 16, 24, or 32 bytes."+
 			"The key size determines the chosen AES cipher (AES-128, AES-192, and AES-256 respectively).").
+		Flag("ns-key-dir", "The directory that stores the per-namespace data keys, in files named "+
+			"<namespace>.<version>.key. If key-file is set, the data keys must be wrapped with it. "+
+			"The posting lists of a namespace are encrypted with its highest key version, and "+
+			"removing all the key files of a namespace makes its data unreadable.").
+		Flag("kms", "The KMS used for envelope encryption: aws, gcp or vault (using the "+
+			"transit secrets engine and the --vault flag to connect). If set, key-file and the "+
+			"files in ns-key-dir hold the keys encrypted by the KMS, base64 encoded for aws and "+
+			"gcp, and in the vault:v<version>:<ciphertext> format for vault.").
+		Flag("kms-key", "The KMS key: the key ID or ARN for aws (optional), the crypto key "+
+			"resource name for gcp, and the transit key name, optionally prefixed with its mount "+
+			"path, for vault.").
+		Flag("kms-region", "The AWS region of the KMS key. Defaults to $AWS_REGION.").
+		Flag("kms-endpoint", "Overrides the endpoint of the aws or gcp KMS.").
+		Flag("kms-credentials-file", "The service account credentials file for gcp. Defaults "+
+			"to the application default credentials, i.e. $GOOGLE_APPLICATION_CREDENTIALS or the "+
+			"metadata server.").
 		String()
 	flag.String(flagEnc, EncDefaults, helpText)
 }
@@ -0,0 +1,264 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"encoding/json"
+	"flag"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// maxClusterConfigVersions is the number of versions of the cluster config kept for rollbacks.
+const maxClusterConfigVersions = 32
+
+// ClusterSetting is a setting of the Alphas which can be changed at runtime for the whole
+// cluster, through the /config endpoint of Zero. The settings not set by the cluster config keep
+// the value given by the flags of each Alpha.
+type ClusterSetting struct {
+	Name string `json:"name"`
+	Help string `json:"help"`
+	// check returns an error if the value is invalid, it's called by Zero before storing it.
+	check func(val string) error
+	// get returns the current value of the setting, and set changes it. They're registered by
+	// the Alphas, with HandleClusterSetting.
+	get func() string
+	set func(val string) error
+}
+
+func checkUint(val string) error {
+	_, err := strconv.ParseUint(val, 10, 64)
+	return err
+}
+
+func checkInt(val string) error {
+	_, err := strconv.ParseInt(val, 10, 64)
+	return err
+}
+
+func checkBool(val string) error {
+	_, err := strconv.ParseBool(val)
+	return err
+}
+
+func checkDuration(val string) error {
+	d, err := time.ParseDuration(val)
+	if err == nil && d < 0 {
+		return errors.Errorf("it can't be negative")
+	}
+	return err
+}
+
+var clusterSettings = map[string]*ClusterSetting{
+	"cache-mb": {
+		Help:  "Total size of the caches in MB, like --cache size-mb.",
+		check: checkUint,
+	},
+	"experimental-paths": {
+		Help: "The modes of the experimental paths of the query engine, like --feature-flags " +
+			"experimental-paths.",
+		check: func(val string) error {
+			_, err := parsePathModes(val, true)
+			return err
+		},
+		get: func() string { return clusterPathModes.Load().spec },
+		set: SetExperimentalPaths,
+	},
+	"log-dql-request": {
+		Help:  "Log all the DQL requests, like --logging log-dql-request.",
+		check: checkBool,
+	},
+	"log-verbosity": {
+		Help:  "The verbosity of the logs, like -v.",
+		check: checkUint,
+		get:   func() string { return flag.Lookup("v").Value.String() },
+		set:   func(val string) error { return flag.Set("v", val) },
+	},
+	"max-pending-queries": {
+		Help:  "The number of requests processed at once, like --limit max-pending-queries.",
+		check: checkInt,
+	},
+	"query-timeout": {
+		Help:  "The timeout of the queries, like --limit query-timeout. 0s for no timeout.",
+		check: checkDuration,
+	},
+}
+
+func init() {
+	for name, s := range clusterSettings {
+		s.Name = name
+	}
+}
+
+// ClusterSettings returns the settings which can be changed by the cluster config, by name.
+func ClusterSettings() []*ClusterSetting {
+	settings := make([]*ClusterSetting, 0, len(clusterSettings))
+	for _, s := range clusterSettings {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+	return settings
+}
+
+// HandleClusterSetting registers the getter and setter of a cluster setting on an Alpha. It must
+// be called before the cluster config is applied.
+func HandleClusterSetting(name string, get func() string, set func(val string) error) {
+	s, ok := clusterSettings[name]
+	AssertTruef(ok, "Unknown cluster setting %s", name)
+	s.get, s.set = get, set
+}
+
+// ClusterConfigVersion is a version of the cluster config.
+type ClusterConfigVersion struct {
+	Version  uint64            `json:"version"`
+	Time     time.Time         `json:"time"`
+	Settings map[string]string `json:"settings"`
+	// RollbackOf is the version whose settings this version restored, if any.
+	RollbackOf uint64 `json:"rollback_of,omitempty"`
+}
+
+// ClusterConfig is the config of the cluster kept by Zero, with its latest versions. The last one
+// is the current config, applied by all the Alphas.
+type ClusterConfig struct {
+	Versions []ClusterConfigVersion `json:"versions"`
+}
+
+// ParseClusterConfig parses the cluster config stored by Zero. It's empty if b is.
+func ParseClusterConfig(b []byte) (*ClusterConfig, error) {
+	c := &ClusterConfig{}
+	if len(b) == 0 {
+		return c, nil
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, errors.Wrap(err, "while parsing the cluster config")
+	}
+	return c, nil
+}
+
+// Current returns the current version of the config, the zero version if it was never changed.
+func (c *ClusterConfig) Current() ClusterConfigVersion {
+	if len(c.Versions) == 0 {
+		return ClusterConfigVersion{}
+	}
+	return c.Versions[len(c.Versions)-1]
+}
+
+// next returns the config with a new version holding the settings.
+func (c *ClusterConfig) next(settings map[string]string, rollbackOf uint64) *ClusterConfig {
+	versions := append([]ClusterConfigVersion(nil), c.Versions...)
+	versions = append(versions, ClusterConfigVersion{
+		Version:    c.Current().Version + 1,
+		Time:       time.Now().UTC(),
+		Settings:   settings,
+		RollbackOf: rollbackOf,
+	})
+	if len(versions) > maxClusterConfigVersions {
+		versions = versions[len(versions)-maxClusterConfigVersions:]
+	}
+	return &ClusterConfig{Versions: versions}
+}
+
+// Update returns the config with a new version, changing the settings of the current version
+// given in updates. An empty value resets the setting to the value given by the flags.
+func (c *ClusterConfig) Update(updates map[string]string) (*ClusterConfig, error) {
+	if len(updates) == 0 {
+		return nil, errors.Errorf("No settings to update")
+	}
+	settings := make(map[string]string)
+	for name, val := range c.Current().Settings {
+		settings[name] = val
+	}
+	for name, val := range updates {
+		s, ok := clusterSettings[name]
+		switch {
+		case !ok:
+			return nil, errors.Errorf("Unknown cluster setting %q", name)
+		case val == "":
+			delete(settings, name)
+			continue
+		}
+		if err := s.check(val); err != nil {
+			return nil, errors.Wrapf(err, "Invalid value %q for the cluster setting %s", val, name)
+		}
+		settings[name] = val
+	}
+	return c.next(settings, 0), nil
+}
+
+// Rollback returns the config with a new version restoring the settings of the given version.
+func (c *ClusterConfig) Rollback(version uint64) (*ClusterConfig, error) {
+	if version == 0 {
+		// The config before the first version, without settings.
+		return c.next(map[string]string{}, 0), nil
+	}
+	for _, v := range c.Versions {
+		if v.Version == version {
+			return c.next(v.Settings, version), nil
+		}
+	}
+	return nil, errors.Errorf("Version %d of the cluster config isn't kept anymore, or doesn't "+
+		"exist", version)
+}
+
+// appliedConfig tracks the cluster config applied by an Alpha.
+var appliedConfig struct {
+	sync.Mutex
+	version uint64
+	// flags holds the values of the settings before the cluster config set them, to reset them.
+	flags map[string]string
+}
+
+// ApplyClusterConfig applies the current version of the cluster config to the settings of the
+// Alpha, if it changed. The settings which aren't set by it anymore get back the value given by
+// the flags.
+func ApplyClusterConfig(b []byte) {
+	c, err := ParseClusterConfig(b)
+	if err != nil {
+		glog.Errorf("While applying the cluster config: %v", err)
+		return
+	}
+	cur := c.Current()
+
+	appliedConfig.Lock()
+	defer appliedConfig.Unlock()
+	if cur.Version == appliedConfig.version {
+		return
+	}
+	if appliedConfig.flags == nil {
+		appliedConfig.flags = make(map[string]string)
+	}
+	for _, s := range ClusterSettings() {
+		if s.set == nil {
+			continue
+		}
+		val, ok := cur.Settings[s.Name]
+		flagVal, overridden := appliedConfig.flags[s.Name]
+		switch {
+		case ok && !overridden:
+			appliedConfig.flags[s.Name] = s.get()
+		case !ok && overridden:
+			val = flagVal
+			delete(appliedConfig.flags, s.Name)
+		case !ok:
+			continue
+		}
+		if s.get() == val {
+			continue
+		}
+		if err := s.set(val); err != nil {
+			glog.Errorf("While setting %s to %q from version %d of the cluster config: %v",
+				s.Name, val, cur.Version, err)
+			continue
+		}
+		glog.Infof("Set %s to %q from version %d of the cluster config", s.Name, val, cur.Version)
+	}
+	appliedConfig.version = cur.Version
+}
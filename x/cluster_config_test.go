@@ -0,0 +1,87 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterConfigVersions(t *testing.T) {
+	c, err := ParseClusterConfig(nil)
+	require.NoError(t, err)
+	require.Zero(t, c.Current().Version)
+
+	_, err = c.Update(map[string]string{"cache-size": "1"})
+	require.ErrorContains(t, err, "Unknown cluster setting")
+	_, err = c.Update(map[string]string{"query-timeout": "-1s"})
+	require.ErrorContains(t, err, "can't be negative")
+	_, err = c.Update(map[string]string{"log-dql-request": "maybe"})
+	require.Error(t, err)
+
+	c, err = c.Update(map[string]string{"query-timeout": "1m", "log-dql-request": "true"})
+	require.NoError(t, err)
+	c, err = c.Update(map[string]string{"query-timeout": "", "cache-mb": "1024"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), c.Current().Version)
+	require.Equal(t, map[string]string{"log-dql-request": "true", "cache-mb": "1024"},
+		c.Current().Settings)
+
+	c, err = c.Rollback(1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), c.Current().Version)
+	require.Equal(t, uint64(1), c.Current().RollbackOf)
+	require.Equal(t, map[string]string{"query-timeout": "1m", "log-dql-request": "true"},
+		c.Current().Settings)
+	_, err = c.Rollback(7)
+	require.Error(t, err)
+
+	// Only the latest versions are kept.
+	for i := 0; i < maxClusterConfigVersions; i++ {
+		c, err = c.Update(map[string]string{"cache-mb": "2048"})
+		require.NoError(t, err)
+	}
+	require.Len(t, c.Versions, maxClusterConfigVersions)
+	_, err = c.Rollback(1)
+	require.Error(t, err)
+
+	b, err := json.Marshal(c)
+	require.NoError(t, err)
+	parsed, err := ParseClusterConfig(b)
+	require.NoError(t, err)
+	require.Equal(t, c.Current().Version, parsed.Current().Version)
+}
+
+func TestApplyClusterConfig(t *testing.T) {
+	s := clusterSettings["max-pending-queries"]
+	defer func() { s.get, s.set = nil, nil }()
+	val := "100"
+	HandleClusterSetting("max-pending-queries", func() string { return val },
+		func(v string) error { val = v; return nil })
+
+	apply := func(c *ClusterConfig) {
+		b, err := json.Marshal(c)
+		require.NoError(t, err)
+		ApplyClusterConfig(b)
+	}
+	c, err := (&ClusterConfig{}).Update(map[string]string{"max-pending-queries": "10"})
+	require.NoError(t, err)
+	apply(c)
+	require.Equal(t, "10", val)
+
+	// The setting gets back the value of the flag once the config doesn't set it anymore.
+	c, err = c.Update(map[string]string{"max-pending-queries": ""})
+	require.NoError(t, err)
+	apply(c)
+	require.Equal(t, "100", val)
+
+	c, err = c.Rollback(1)
+	require.NoError(t, err)
+	apply(c)
+	require.Equal(t, "10", val)
+}
@@ -0,0 +1,119 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+)
+
+// The HTTP responses are compressed with zstd or gzip, as negotiated from the Accept-Encoding
+// header of the request, once they reach the minimum size set with --compression. The level of
+// --compression applies to both, and to the gRPC messages compressed with them.
+var (
+	responseMinSize int
+	gzipLevel       = gzip.DefaultCompression
+	zstdLevel       = zstd.SpeedDefault
+
+	// zstdEncoder compresses the HTTP responses, creating it can only fail with invalid options.
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel))
+)
+
+// SetCompression sets the minimum size in bytes of the HTTP responses which are compressed, and
+// the compression level, which is one of fastest, default, better and best.
+func SetCompression(minSize int, level string) error {
+	switch level {
+	case "fastest":
+		gzipLevel, zstdLevel = gzip.BestSpeed, zstd.SpeedFastest
+	case "default":
+		gzipLevel, zstdLevel = gzip.DefaultCompression, zstd.SpeedDefault
+	case "better":
+		gzipLevel, zstdLevel = 7, zstd.SpeedBetterCompression
+	case "best":
+		gzipLevel, zstdLevel = gzip.BestCompression, zstd.SpeedBestCompression
+	default:
+		return errors.Errorf("invalid compression level %q, it should be fastest, default, "+
+			"better or best", level)
+	}
+	if minSize < 0 {
+		return errors.Errorf("invalid compression min-size %d, it can't be negative", minSize)
+	}
+	responseMinSize = minSize
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdLevel))
+	return grpcgzip.SetLevel(gzipLevel)
+}
+
+// ZstdLevel returns the level at which the responses are compressed with zstd.
+func ZstdLevel() zstd.EncoderLevel {
+	return zstdLevel
+}
+
+// ResponseEncoding returns the encoding of the response negotiated from the Accept-Encoding
+// header of the request: zstd or gzip, or an empty string if it shouldn't be compressed. zstd
+// is preferred when both are accepted with the same quality.
+func ResponseEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if (name != "zstd" && name != "gzip") || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "zstd") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressResponse returns the body compressed with the encoding, or nil if it isn't
+// compressed.
+func compressResponse(b []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "zstd":
+		return zstdEncoder.EncodeAll(b, make([]byte, 0, len(b)/2)), nil
+	case "gzip":
+		var buf bytes.Buffer
+		gzw, err := gzip.NewWriterLevel(&buf, gzipLevel)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := gzw.Write(b); err != nil {
+			return nil, err
+		}
+		if err := gzw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, nil
+}
+
+// addVary adds the header to the Vary header of the response, unless it's already there.
+func addVary(h http.Header, header string) {
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(name), header) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", header)
+}
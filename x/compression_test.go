@@ -0,0 +1,65 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseEncoding(t *testing.T) {
+	require.Equal(t, "", ResponseEncoding(""))
+	require.Equal(t, "", ResponseEncoding("br, deflate"))
+	require.Equal(t, "gzip", ResponseEncoding("gzip, deflate, br"))
+	require.Equal(t, "zstd", ResponseEncoding("gzip, zstd"))
+	require.Equal(t, "gzip", ResponseEncoding("zstd;q=0.5, gzip"))
+	require.Equal(t, "", ResponseEncoding("gzip;q=0, zstd;q=0"))
+}
+
+func TestWriteResponseCompression(t *testing.T) {
+	defer func() { require.NoError(t, SetCompression(0, "default")) }()
+	require.NoError(t, SetCompression(64, "best"))
+	require.Error(t, SetCompression(64, "max"))
+
+	body := bytes.Repeat([]byte(`{"name":"Alice"},`), 16)
+	write := func(b []byte, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/query", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		_, err := WriteResponse(w, r, b)
+		require.NoError(t, err)
+		require.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		return w
+	}
+
+	w := write(body, "gzip, zstd")
+	require.Equal(t, "zstd", w.Header().Get("Content-Encoding"))
+	dec, err := zstd.NewReader(w.Body)
+	require.NoError(t, err)
+	out, err := io.ReadAll(dec)
+	require.NoError(t, err)
+	require.Equal(t, body, out)
+
+	w = write(body, "gzip")
+	require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	gz, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	out, err = io.ReadAll(gz)
+	require.NoError(t, err)
+	require.Equal(t, body, out)
+
+	// The responses smaller than min-size aren't compressed.
+	w = write(body[:32], "gzip, zstd")
+	require.Empty(t, w.Header().Get("Content-Encoding"))
+	require.Equal(t, body[:32], w.Body.Bytes())
+}
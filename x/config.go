@@ -7,11 +7,17 @@ package x
 
 import (
 	"crypto/tls"
+	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/dgraph-io/ristretto/v2/z"
@@ -30,7 +36,25 @@ type Options struct {
 	// BlockDropAll bool - if set to true, the drop all operation will be rejected by the server.
 	// query-timeout duration - Maximum time after which a query execution will fail.
 	// max-retries int64 - maximum number of retries made by dgraph to commit a transaction to disk.
+	// mutation-retries int - maximum number of times a mutation aborted due to a conflict is
+	//                        retried, when the request asks for retries.
 	// shared-instance bool - if set to true, ACLs will be disabled for non-galaxy users.
+	// query-depth, query-expand, query-root-uids, query-var-uids, query-memory-mb uint64 - limits
+	//                        of the shape of the DQL queries, see QueryLimits.
+	// query-limits-ns string - <namespace>:<limit>=<value> pairs overriding the query limits for
+	//                          some namespaces.
+	// mutation-size-mb uint64 - maximum size of the mutations of a request, 0 for no limit.
+	// mutation-size-mb-ns string - <namespace>:<size> pairs overriding mutation-size-mb.
+	// proposal-edges int - maximum number of edges of a mutation proposal, above which the
+	//                      mutations are split into several proposals of the same transaction.
+	// query-spill-uids uint64 - number of uids and values of a DQL query block from which its
+	//                           results are spilled to disk while the other blocks run.
+	// wasm-timeout duration - time each call to a wasm function of a query can run for.
+	// wasm-memory-mb uint64 - maximum memory of each instance of a wasm function of a query.
+	// reverse-scan-keys uint64 - maximum number of keys of a tablet scanned to traverse a
+	//                            predicate without @reverse backwards, 0 to never scan.
+	// idempotency-window duration - how long the results of the mutations sent with an
+	//                               idempotency key are kept, 0 to reject the keys.
 	Limit                *z.SuperFlag
 	LimitMutationsNquad  int
 	LimitQueryEdge       uint64
@@ -38,7 +62,27 @@ type Options struct {
 	LimitNormalizeNode   int
 	QueryTimeout         time.Duration
 	MaxRetries           int64
+	MutationRetries      int
 	SharedInstance       bool
+	QueryLimits          QueryLimits
+	QueryLimitsNs        map[uint64]QueryLimits
+	// LimitMutationSize and LimitMutationSizeNs are in bytes.
+	LimitMutationSize   uint64
+	LimitMutationSizeNs map[uint64]uint64
+	LimitProposalEdges  int
+	QuerySpillUids      uint64
+	LimitWasmTimeout    time.Duration
+	// LimitWasmMemory is in bytes.
+	LimitWasmMemory      uint64
+	LimitReverseScanKeys uint64
+	IdempotencyWindow    time.Duration
+
+	// Sandbox options:
+	//
+	// The queries of the sandboxed namespaces, a setting of each namespace, run with the
+	// SandboxLimits on top of their query limits and for at most SandboxTimeout.
+	SandboxTimeout time.Duration
+	SandboxLimits  QueryLimits
 
 	// GraphQL options:
 	//
@@ -112,6 +156,11 @@ type WorkerOptions struct {
 	AclPublicKey interface{}
 	// AbortOlderThan tells Dgraph to discard transactions that are older than this duration.
 	AbortOlderThan time.Duration
+	// TxnMaxDuration is the duration after which the transactions are aborted, even if they are
+	// kept alive. Zero means the transactions can run for as long as they are kept alive.
+	TxnMaxDuration time.Duration
+	// TxnMaxDurationNs overrides TxnMaxDuration for the transactions of some namespaces.
+	TxnMaxDurationNs map[uint64]time.Duration
 	// ProposedGroupId will be used if there's a file in the p directory called group_id with the
 	// proposed group ID for this server.
 	ProposedGroupId uint32
@@ -124,6 +173,8 @@ type WorkerOptions struct {
 	Security *z.SuperFlag
 	// EncryptionKey is the key used for encryption at rest, backups, exports.
 	EncryptionKey Sensitive
+	// EncryptionKms is the name of the KMS used to decrypt the encryption keys, if any.
+	EncryptionKms string
 	// LogDQLRequest indicates whether alpha should log all query/mutation requests coming to it.
 	// Ideally LogDQLRequest should be a bool value. But we are reading it using atomics across
 	// queries hence it has been kept as int32. LogDQLRequest value 1 enables logging of requests
@@ -147,3 +198,203 @@ func (w *WorkerOptions) Parse(conf *viper.Viper) {
 		"Invalid survival mode: %s", survive)
 	w.HardSync = survive == "filesystem"
 }
+
+// MaxTxnDuration returns the duration after which the transactions of the namespace are aborted,
+// or zero if they can run for as long as they are kept alive.
+func (w *WorkerOptions) MaxTxnDuration(ns uint64) time.Duration {
+	if d, ok := w.TxnMaxDurationNs[ns]; ok {
+		return d
+	}
+	return w.TxnMaxDuration
+}
+
+// ParseNsDurations parses a comma separated list of <namespace>:<duration>.
+func ParseNsDurations(s string) (map[uint64]time.Duration, error) {
+	res := make(map[uint64]time.Duration)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		nsStr, durStr, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, errors.Errorf("Invalid <namespace>:<duration> pair %q", pair)
+		}
+		ns, err := strconv.ParseUint(strings.TrimSpace(nsStr), 0, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing the namespace of %q", pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(durStr))
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing the duration of %q", pair)
+		}
+		if d < 0 {
+			return nil, errors.Errorf("The duration of %q can't be negative", pair)
+		}
+		res[ns] = d
+	}
+	return res, nil
+}
+
+// NsMutationSize returns the maximum size in bytes of the mutations of a request in the
+// namespace, or zero if it isn't limited.
+func (o *Options) NsMutationSize(ns uint64) uint64 {
+	if size, ok := o.LimitMutationSizeNs[ns]; ok {
+		return size
+	}
+	return o.LimitMutationSize
+}
+
+// ParseNsUints parses a comma separated list of <namespace>:<value> with unsigned values.
+func ParseNsUints(s string) (map[uint64]uint64, error) {
+	res := make(map[uint64]uint64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		nsStr, valStr, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, errors.Errorf("Invalid <namespace>:<value> pair %q", pair)
+		}
+		ns, err := strconv.ParseUint(strings.TrimSpace(nsStr), 0, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing the namespace of %q", pair)
+		}
+		val, err := strconv.ParseUint(strings.TrimSpace(valStr), 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing the value of %q", pair)
+		}
+		res[ns] = val
+	}
+	return res, nil
+}
+
+// QueryLimits bound the shape of the DQL queries, so that they can be run by semi-trusted users.
+// A zero limit isn't checked.
+type QueryLimits struct {
+	// Depth is the maximum nesting of the blocks of a query, and the maximum depth of @recurse.
+	Depth uint64
+	// Expand is the maximum number of predicates added to a block by expand().
+	Expand uint64
+	// RootUids is the maximum number of uids at the root of a query block.
+	RootUids uint64
+	// VarUids is the maximum number of uids, or values, of a query variable.
+	VarUids uint64
+	// MemoryMb is the maximum estimated size in MB of the results of a query.
+	MemoryMb uint64
+}
+
+// queryLimitNames are the names of the query limits, as given to --limit.
+var queryLimitNames = []string{"query-depth", "query-expand", "query-root-uids", "query-var-uids",
+	"query-memory-mb"}
+
+func (l *QueryLimits) limit(name string) *uint64 {
+	switch name {
+	case "query-depth":
+		return &l.Depth
+	case "query-expand":
+		return &l.Expand
+	case "query-root-uids":
+		return &l.RootUids
+	case "query-var-uids":
+		return &l.VarUids
+	case "query-memory-mb":
+		return &l.MemoryMb
+	}
+	return nil
+}
+
+// IsZero returns true if none of the limits is set.
+func (l QueryLimits) IsZero() bool {
+	return l == QueryLimits{}
+}
+
+// ParseQueryLimits returns the query limits given to the --limit superflag.
+func ParseQueryLimits(sf *z.SuperFlag) QueryLimits {
+	var limits QueryLimits
+	for _, name := range queryLimitNames {
+		*limits.limit(name) = sf.GetUint64(name)
+	}
+	return limits
+}
+
+// NsQueryLimits returns the limits of the DQL queries of the namespace. The limits of a sandboxed
+// namespace are the strictest of its limits and of the sandbox limits, one by one.
+func (o *Options) NsQueryLimits(ns uint64, sandboxed bool) QueryLimits {
+	limits, ok := o.QueryLimitsNs[ns]
+	if !ok {
+		limits = o.QueryLimits
+	}
+	if sandboxed {
+		limits = limits.strictest(o.SandboxLimits)
+	}
+	return limits
+}
+
+// strictest returns the lowest of the limits of l and other, one by one. A zero limit is unset,
+// so it loses to any set limit.
+func (l QueryLimits) strictest(other QueryLimits) QueryLimits {
+	for _, name := range queryLimitNames {
+		limit, o := l.limit(name), *other.limit(name)
+		if *limit == 0 || (o != 0 && o < *limit) {
+			*limit = o
+		}
+	}
+	return l
+}
+
+// ParseNsQueryLimits parses a comma separated list of <namespace>:<limit>=<value>, like
+// 1:query-depth=5,1:query-var-uids=10000. The limits not given for a namespace are the defaults.
+func ParseNsQueryLimits(s string, defaults QueryLimits) (map[uint64]QueryLimits, error) {
+	res := make(map[uint64]QueryLimits)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		nsStr, limitStr, ok := strings.Cut(pair, ":")
+		name, valStr, ok2 := strings.Cut(limitStr, "=")
+		if !ok || !ok2 {
+			return nil, errors.Errorf("Invalid <namespace>:<limit>=<value> pair %q", pair)
+		}
+		ns, err := strconv.ParseUint(strings.TrimSpace(nsStr), 0, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing the namespace of %q", pair)
+		}
+		val, err := strconv.ParseUint(strings.TrimSpace(valStr), 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing the value of %q", pair)
+		}
+		limits, ok := res[ns]
+		if !ok {
+			limits = defaults
+		}
+		limit := limits.limit(strings.TrimSpace(name))
+		if limit == nil {
+			return nil, errors.Errorf("Unknown query limit in %q, it should be one of %s", pair,
+				strings.Join(queryLimitNames, ", "))
+		}
+		*limit = val
+		res[ns] = limits
+	}
+	return res, nil
+}
+
+// QueryLimitError is returned for a DQL query going beyond one of its QueryLimits.
+type QueryLimitError struct {
+	// Limit is the name of the limit, like query-depth.
+	Limit string
+	// Max is the value of the limit, and Actual the value reached by the query.
+	Max, Actual uint64
+}
+
+func (e *QueryLimitError) Error() string {
+	return fmt.Sprintf("The query goes beyond the %s limit of %d, with %d", e.Limit, e.Max,
+		e.Actual)
+}
+
+// GRPCStatus returns the status of the error for the gRPC clients.
+func (e *QueryLimitError) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, e.Error())
+}
@@ -0,0 +1,151 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+)
+
+// The formats of the postings on disk are named after the release that introduced them, like the
+// versions of the backup manifests.
+const (
+	// DataFormatV0 is the format before v21.03, whose keys have no namespace.
+	DataFormatV0 = 0
+	// DataFormatV2103 is the format of v21.03, whose keys have a namespace, and whose schema and
+	// types store the namespace of their predicates as 8 bytes.
+	DataFormatV2103 = 2103
+	// DataFormatVersion is the current format, in which the schema and the types store the
+	// namespace of their predicates as a hex string.
+	DataFormatVersion = 2105
+
+	// versionHandshakeKey and dataFormatHandshakeKey are the gRPC metadata exchanged by the
+	// instances of a cluster to check that they're compatible.
+	versionHandshakeKey    = "dgraph-version"
+	dataFormatHandshakeKey = "dgraph-data-format"
+)
+
+// DetectDataFormat returns the format of the postings in db, as found in the keys and values of
+// its schema. An empty db is in the current format.
+func DetectDataFormat(db *badger.DB) (int, error) {
+	txn := db.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+
+	iopt := badger.DefaultIteratorOptions
+	iopt.Prefix = []byte{ByteSchema}
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	for itr.Rewind(); itr.Valid(); itr.Next() {
+		item := itr.Item()
+		if item.IsDeletedOrExpired() {
+			continue
+		}
+		key := item.Key()
+		switch {
+		case len(key) >= 11 && int(binary.BigEndian.Uint16(key[9:11]))+11 == len(key):
+			// The key has a namespace, so the format depends on the value.
+		case len(key) >= 3 && int(binary.BigEndian.Uint16(key[1:3]))+3 == len(key):
+			return DataFormatV0, nil
+		default:
+			return 0, errors.Errorf("unknown format of schema key %x", key)
+		}
+
+		var update pb.SchemaUpdate
+		if err := item.Value(func(val []byte) error {
+			return proto.Unmarshal(val, &update)
+		}); err != nil {
+			return 0, errors.Wrapf(err, "while reading schema key %x", key)
+		}
+		if update.Predicate == "" {
+			continue
+		}
+		if IsNamespaceAttr(update.Predicate) {
+			return DataFormatVersion, nil
+		}
+		return DataFormatV2103, nil
+	}
+	return DataFormatVersion, nil
+}
+
+// IsNamespaceAttr returns true if attr is in the format "hex(namespace)-predicate".
+func IsNamespaceAttr(attr string) bool {
+	idx := strings.Index(attr, NsSeparator)
+	if idx <= 0 {
+		return false
+	}
+	_, err := strconv.ParseUint(attr[:idx], 16, 64)
+	return err == nil
+}
+
+// DataFormatName returns the release that introduced the format.
+func DataFormatName(format int) string {
+	switch format {
+	case DataFormatV0:
+		return "v20.11 or older"
+	case DataFormatV2103:
+		return "v21.03"
+	case DataFormatVersion:
+		return "v21.12 or newer"
+	default:
+		return "unknown (" + strconv.Itoa(format) + ")"
+	}
+}
+
+// WithHandshake returns the context with the version and the data format of this instance in its
+// outgoing metadata, to be checked by the instance receiving the request with CheckHandshake.
+func WithHandshake(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, versionHandshakeKey, Version(),
+		dataFormatHandshakeKey, strconv.Itoa(DataFormatVersion))
+}
+
+// HandshakeMetadata returns the version and the data format of this instance, sent back in the
+// headers of the streams whose requests had a handshake.
+func HandshakeMetadata() metadata.MD {
+	return metadata.Pairs(versionHandshakeKey, Version(),
+		dataFormatHandshakeKey, strconv.Itoa(DataFormatVersion))
+}
+
+// CheckHandshake checks that the instance whose version and data format are in md is compatible
+// with this instance. The instances running different versions of Dgraph are compatible as long as
+// they use the same data format, so that a cluster can be upgraded one instance at a time. The
+// instances that don't send a handshake predate it, and use the current data format.
+func CheckHandshake(md metadata.MD, peer string) error {
+	formats := md.Get(dataFormatHandshakeKey)
+	if len(formats) == 0 {
+		return nil
+	}
+	format, err := strconv.Atoi(formats[0])
+	if err != nil {
+		return errors.Wrapf(err, "while parsing the data format of %s", peer)
+	}
+
+	var version string
+	if versions := md.Get(versionHandshakeKey); len(versions) > 0 {
+		version = versions[0]
+	}
+	if format != DataFormatVersion {
+		return errors.Errorf("INCOMPATIBLE_FORMAT: %s runs Dgraph %s with the data format of %s, "+
+			"which is incompatible with the data format of %s used by Dgraph %s", peer, version,
+			DataFormatName(format), DataFormatName(DataFormatVersion), Version())
+	}
+	if version != Version() {
+		glog.Warningf("%s runs Dgraph %s, while this instance runs Dgraph %s. Complete the "+
+			"upgrade of the cluster to run the same version everywhere.", peer, version, Version())
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+)
+
+func TestDetectDataFormat(t *testing.T) {
+	db, err := badger.OpenManaged(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, db.Close()) }()
+
+	format, err := DetectDataFormat(db)
+	require.NoError(t, err)
+	require.Equal(t, DataFormatVersion, format)
+
+	write := func(key []byte, predicate string, ts uint64) {
+		val, err := proto.Marshal(&pb.SchemaUpdate{Predicate: predicate})
+		require.NoError(t, err)
+		txn := db.NewTransactionAt(ts, true)
+		defer txn.Discard()
+		require.NoError(t, txn.Set(key, val))
+		require.NoError(t, txn.CommitAt(ts, nil))
+	}
+	key := SchemaKey(AttrInRootNamespace("name"))
+	write(key, strings.Repeat("\x00", 8)+"name", 1)
+	format, err = DetectDataFormat(db)
+	require.NoError(t, err)
+	require.Equal(t, DataFormatV2103, format)
+
+	write(key, AttrInRootNamespace("name"), 2)
+	format, err = DetectDataFormat(db)
+	require.NoError(t, err)
+	require.Equal(t, DataFormatVersion, format)
+
+	require.NoError(t, db.DropAll())
+	write([]byte{ByteSchema, 0, 4, 'n', 'a', 'm', 'e'}, "name", 3)
+	format, err = DetectDataFormat(db)
+	require.NoError(t, err)
+	require.Equal(t, DataFormatV0, format)
+}
+
+func TestCheckHandshake(t *testing.T) {
+	require.True(t, IsNamespaceAttr(AttrInRootNamespace("name")))
+	require.False(t, IsNamespaceAttr("name"))
+	require.False(t, IsNamespaceAttr("my-name"))
+
+	// The instances that predate the handshake are compatible.
+	require.NoError(t, CheckHandshake(metadata.MD{}, "alpha1"))
+
+	md, ok := metadata.FromOutgoingContext(WithHandshake(context.Background()))
+	require.True(t, ok)
+	require.NoError(t, CheckHandshake(md, "alpha1"))
+	require.Equal(t, HandshakeMetadata(), md)
+
+	md = metadata.Pairs(versionHandshakeKey, "v21.03.0", dataFormatHandshakeKey, "2103")
+	err := CheckHandshake(md, "alpha1")
+	require.ErrorContains(t, err, "INCOMPATIBLE_FORMAT: alpha1 runs Dgraph v21.03.0")
+	require.True(t, ShouldCrash(err))
+
+	md = metadata.Pairs(dataFormatHandshakeKey, "new")
+	require.Error(t, CheckHandshake(md, "alpha1"))
+}
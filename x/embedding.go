@@ -0,0 +1,88 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Embedder embeds texts into vectors, with an embedding provider.
+type Embedder interface {
+	// Embed returns the vectors of the texts, in the order of the texts.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderConfig configures the embedder of an endpoint speaking the embeddings API of OpenAI,
+// like the one of OpenAI or the self-hosted ones.
+type EmbedderConfig struct {
+	URL    string
+	Model  string
+	APIKey Sensitive
+	// BatchSize is the max number of texts embedded by a request to the endpoint.
+	BatchSize int
+	Timeout   time.Duration
+}
+
+// NewEmbedder returns the embedder of the endpoint of the config.
+func NewEmbedder(c EmbedderConfig) (Embedder, error) {
+	if c.URL == "" {
+		return nil, errors.Errorf("The URL of the embedding endpoint is required")
+	}
+	if c.Model == "" {
+		return nil, errors.Errorf("The model of the embedding endpoint is required")
+	}
+	if c.BatchSize <= 0 {
+		return nil, errors.Errorf("The batch size of the embedder must be positive, got %d",
+			c.BatchSize)
+	}
+	return &openaiEmbedder{client: &http.Client{Timeout: c.Timeout}, c: c}, nil
+}
+
+// openaiEmbedder embeds the texts with the embeddings API of OpenAI.
+type openaiEmbedder struct {
+	client *http.Client
+	c      EmbedderConfig
+}
+
+func (o *openaiEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	headers := make(map[string]string)
+	if len(o.c.APIKey) > 0 {
+		headers["Authorization"] = "Bearer " + string(o.c.APIKey)
+	}
+	vecs := make([][]float32, len(texts))
+	for start := 0; start < len(texts); start += o.c.BatchSize {
+		batch := texts[start:min(start+o.c.BatchSize, len(texts))]
+		in := map[string]interface{}{"model": o.c.Model, "input": batch}
+		var out struct {
+			Data []struct {
+				Embedding []float32 `json:"embedding"`
+				Index     int       `json:"index"`
+			} `json:"data"`
+		}
+		if err := llmPost(ctx, o.client, o.c.URL, headers, in, &out); err != nil {
+			return nil, err
+		}
+		if len(out.Data) != len(batch) {
+			return nil, errors.Errorf("The embedding endpoint returned %d vectors for %d texts",
+				len(out.Data), len(batch))
+		}
+		for _, d := range out.Data {
+			if d.Index < 0 || d.Index >= len(batch) || vecs[start+d.Index] != nil {
+				return nil, errors.Errorf("The embedding endpoint returned an invalid index %d",
+					d.Index)
+			}
+			if len(d.Embedding) == 0 {
+				return nil, errors.Errorf("The embedding endpoint returned an empty vector")
+			}
+			vecs[start+d.Index] = d.Embedding
+		}
+	}
+	return vecs, nil
+}
@@ -0,0 +1,57 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedder(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		var in struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		require.Equal(t, "m", in.Model)
+		// Answer in reverse order, the vectors are put back in the order of the texts.
+		data := make([]string, 0, len(in.Input))
+		for i := len(in.Input) - 1; i >= 0; i-- {
+			data = append(data, fmt.Sprintf(`{"index": %d, "embedding": [%d, 1]}`, i,
+				len(in.Input[i])))
+		}
+		_, _ = fmt.Fprintf(w, `{"data": [%s]}`, strings.Join(data, ","))
+	}))
+	defer srv.Close()
+
+	c := EmbedderConfig{URL: srv.URL, Model: "m", APIKey: Sensitive("key"), BatchSize: 2,
+		Timeout: time.Second}
+	e, err := NewEmbedder(c)
+	require.NoError(t, err)
+	vecs, err := e.Embed(context.Background(), []string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	require.Equal(t, [][]float32{{1, 1}, {2, 1}, {3, 1}}, vecs)
+	require.Equal(t, 2, requests)
+
+	c.Model = ""
+	_, err = NewEmbedder(c)
+	require.Error(t, err)
+	c.Model, c.BatchSize = "m", 0
+	_, err = NewEmbedder(c)
+	require.Error(t, err)
+}
@@ -0,0 +1,157 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorCode is the stable code of an error returned to the clients, so that they don't have to
+// match its message. It is carried by the ErrorInfo detail of the gRPC status of the error, as its
+// reason, and by the errorCode extension of the HTTP errors.
+type ErrorCode string
+
+const (
+	// ErrorCodeTxnConflict is returned for a transaction that conflicts with another one. It can
+	// be retried from the start.
+	ErrorCodeTxnConflict ErrorCode = "TXN_CONFLICT"
+	// ErrorCodeRetriable is returned for a request that failed because of the state of the
+	// cluster, like a missing leader, and can be retried as is.
+	ErrorCodeRetriable ErrorCode = "RETRIABLE"
+	// ErrorCodeAuth is returned for a request that isn't authenticated or allowed.
+	ErrorCodeAuth ErrorCode = "AUTH"
+	// ErrorCodeNotFound is returned for a request about something that doesn't exist.
+	ErrorCodeNotFound ErrorCode = "NOT_FOUND"
+	// ErrorCodeTooLarge is returned for a request or a result going beyond a limit.
+	ErrorCodeTooLarge ErrorCode = "TOO_LARGE"
+	// ErrorCodeTimeout is returned for a request that ran out of time.
+	ErrorCodeTimeout ErrorCode = "TIMEOUT"
+	// ErrorCodeInvalid is returned for a request that is malformed or invalid.
+	ErrorCodeInvalid ErrorCode = "INVALID_REQUEST"
+	// ErrorCodeUnknown is returned for all the other errors.
+	ErrorCodeUnknown ErrorCode = "UNKNOWN"
+
+	// ErrorDomain is the domain of the ErrorInfo details of the gRPC errors.
+	ErrorDomain = "dgraph.io"
+)
+
+// Retriable tells whether the request failing with the error code can be retried.
+func (c ErrorCode) Retriable() bool {
+	return c == ErrorCodeTxnConflict || c == ErrorCodeRetriable
+}
+
+// grpcCode returns the gRPC code of the errors with the error code, unless they have one.
+func (c ErrorCode) grpcCode() codes.Code {
+	switch c {
+	case ErrorCodeTxnConflict:
+		return codes.Aborted
+	case ErrorCodeRetriable:
+		return codes.Unavailable
+	case ErrorCodeAuth:
+		return codes.PermissionDenied
+	case ErrorCodeNotFound:
+		return codes.NotFound
+	case ErrorCodeTooLarge:
+		return codes.ResourceExhausted
+	case ErrorCodeTimeout:
+		return codes.DeadlineExceeded
+	case ErrorCodeInvalid:
+		return codes.InvalidArgument
+	default:
+		return codes.Unknown
+	}
+}
+
+// ErrorCodeOf returns the error code of the error, from the ErrorInfo detail of its gRPC status
+// if it has one, and from its gRPC code or its value otherwise.
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	if s, ok := status.FromError(err); ok {
+		for _, d := range s.Details() {
+			if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+				return ErrorCode(info.Reason)
+			}
+		}
+	}
+
+	var limitErr *QueryLimitError
+	switch {
+	case errors.Is(err, ErrConflict) || errors.Is(err, dgo.ErrAborted):
+		return ErrorCodeTxnConflict
+	case errors.As(err, &limitErr):
+		return ErrorCodeTooLarge
+	case errors.Is(err, ErrNoJwt) || errors.Is(err, ErrorInvalidLogin):
+		return ErrorCodeAuth
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	}
+
+	switch status.Code(errors.Cause(err)) {
+	case codes.Aborted:
+		return ErrorCodeTxnConflict
+	case codes.Unavailable:
+		return ErrorCodeRetriable
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return ErrorCodeAuth
+	case codes.NotFound:
+		return ErrorCodeNotFound
+	case codes.ResourceExhausted:
+		return ErrorCodeTooLarge
+	case codes.DeadlineExceeded:
+		return ErrorCodeTimeout
+	case codes.InvalidArgument:
+		return ErrorCodeInvalid
+	}
+	// The conflicts of the transactions that aren't committed right away are returned with the
+	// FailedPrecondition code, as they aren't aborted yet.
+	if strings.Contains(err.Error(), ErrConflict.Error()) {
+		return ErrorCodeTxnConflict
+	}
+	return ErrorCodeUnknown
+}
+
+// WithErrorCode returns the error as a gRPC status error carrying its error code in an ErrorInfo
+// detail. The message of the error is kept, and so is its gRPC code if it has one.
+func WithErrorCode(err error) error {
+	if err == nil {
+		return nil
+	}
+	code := ErrorCodeOf(err)
+	s, ok := status.FromError(err)
+	if !ok {
+		s = status.New(code.grpcCode(), err.Error())
+	}
+	for _, d := range s.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			return err
+		}
+	}
+	ds, derr := s.WithDetails(&errdetails.ErrorInfo{Reason: string(code), Domain: ErrorDomain})
+	if derr != nil {
+		return err
+	}
+	return ds.Err()
+}
+
+// ErrorExtensions returns the extensions of the HTTP error for the error, with the legacy code of
+// the response.
+func ErrorExtensions(code string, err error) map[string]interface{} {
+	ec := ErrorCodeOf(err)
+	return map[string]interface{}{
+		"code":      code,
+		"errorCode": ec,
+		"retriable": ec.Retriable(),
+	}
+}
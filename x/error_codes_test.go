@@ -0,0 +1,85 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		code ErrorCode
+	}{
+		{ErrConflict, ErrorCodeTxnConflict},
+		{dgo.ErrAborted, ErrorCodeTxnConflict},
+		{status.Error(codes.FailedPrecondition, ErrConflict.Error()), ErrorCodeTxnConflict},
+		{errors.Wrap(ErrConflict, "while committing"), ErrorCodeTxnConflict},
+		{&QueryLimitError{Limit: "query-depth", Max: 2, Actual: 3}, ErrorCodeTooLarge},
+		{ErrNoJwt, ErrorCodeAuth},
+		{status.Error(codes.PermissionDenied, "no"), ErrorCodeAuth},
+		{status.Error(codes.Unavailable, "no leader"), ErrorCodeRetriable},
+		{status.Error(codes.NotFound, "no txn"), ErrorCodeNotFound},
+		{ErrTxnExpired, ErrorCodeTimeout},
+		{context.DeadlineExceeded, ErrorCodeTimeout},
+		{errors.New("while parsing the query"), ErrorCodeUnknown},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.code, ErrorCodeOf(tc.err), tc.err.Error())
+	}
+	require.Empty(t, ErrorCodeOf(nil))
+	require.True(t, ErrorCodeTxnConflict.Retriable())
+	require.False(t, ErrorCodeAuth.Retriable())
+}
+
+func TestWithErrorCode(t *testing.T) {
+	require.NoError(t, WithErrorCode(nil))
+
+	// The plain errors get the gRPC code of their error code.
+	err := WithErrorCode(dgo.ErrAborted)
+	require.Equal(t, codes.Aborted, status.Code(err))
+	require.Equal(t, dgo.ErrAborted.Error(), status.Convert(err).Message())
+	require.Equal(t, ErrorCodeTxnConflict, ErrorCodeOf(err))
+
+	// The statuses keep their code and message.
+	err = WithErrorCode(status.Error(codes.FailedPrecondition, ErrConflict.Error()))
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, ErrorCodeTxnConflict, ErrorCodeOf(err))
+	require.Len(t, status.Convert(err).Details(), 1)
+	require.Equal(t, err, WithErrorCode(err))
+
+	err = WithErrorCode(errors.New("while parsing the query"))
+	require.Equal(t, codes.Unknown, status.Code(err))
+	require.Equal(t, ErrorCodeUnknown, ErrorCodeOf(err))
+}
+
+func TestSetErrorStatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetErrorStatusWithData(w, ErrorInvalidRequest, dgo.ErrAborted)
+	var res struct {
+		Errors []struct {
+			Message    string                 `json:"message"`
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, dgo.ErrAborted.Error(), res.Errors[0].Message)
+	require.Equal(t, map[string]interface{}{
+		"code":      ErrorInvalidRequest,
+		"errorCode": string(ErrorCodeTxnConflict),
+		"retriable": true,
+	}, res.Errors[0].Extensions)
+}
@@ -0,0 +1,299 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The experimental paths are the new execution paths of the query engine, rolled out next to the
+// old ones they replace. The mode of a path is given, from the most to the least specific, by the
+// request, by the namespace of the predicate it runs on, by the whole cluster, and by its default.
+// The modes of the namespaces and of the cluster come from --feature-flags experimental-paths, or
+// from the experimental-paths setting of the cluster config which overrides it.
+
+// PathMode is the mode of an experimental path.
+type PathMode int
+
+const (
+	// PathOff runs the old path.
+	PathOff PathMode = iota
+	// PathOn runs the new path.
+	PathOn
+	// PathShadow runs both paths and compares their results and latencies, returning the result
+	// of the old one.
+	PathShadow
+)
+
+var pathModeNames = []string{"off", "on", "shadow"}
+
+func (m PathMode) String() string {
+	return pathModeNames[m]
+}
+
+func parsePathMode(s string) (PathMode, error) {
+	for m, name := range pathModeNames {
+		if s == name {
+			return PathMode(m), nil
+		}
+	}
+	return PathOff, errors.Errorf("invalid mode %q, it should be off, on or shadow", s)
+}
+
+// ExperimentalPath is an execution path of the query engine which can be switched at runtime.
+type ExperimentalPath struct {
+	Name    string
+	Help    string
+	Default PathMode
+}
+
+var experimentalPaths = map[string]*ExperimentalPath{
+	"presence-read": {
+		Help: "Answer has() at the root from the presence bitmaps of the predicates, kept with " +
+			"--feature-flags presence-bitmap, rather than by iterating over their tablets.",
+		Default: PathOn,
+	},
+}
+
+func init() {
+	for name, p := range experimentalPaths {
+		p.Name = name
+	}
+}
+
+// ExperimentalPaths returns the experimental paths, by name.
+func ExperimentalPaths() []*ExperimentalPath {
+	paths := make([]*ExperimentalPath, 0, len(experimentalPaths))
+	for _, p := range experimentalPaths {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i].Name < paths[j].Name })
+	return paths
+}
+
+// pathModes holds the modes of the experimental paths set for all the namespaces, and for some of
+// them.
+type pathModes struct {
+	spec       string
+	all        map[string]PathMode
+	namespaces map[uint64]map[string]PathMode
+}
+
+// parsePathModes parses the modes of the experimental paths, given as a comma separated list of
+// <path>[@<namespace>]=<off|on|shadow>, like "presence-read=shadow,presence-read@2=off". The
+// namespaces are only allowed if withNs is true.
+func parsePathModes(spec string, withNs bool) (*pathModes, error) {
+	modes := &pathModes{
+		spec:       spec,
+		all:        make(map[string]PathMode),
+		namespaces: make(map[uint64]map[string]PathMode),
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, errors.Errorf("invalid experimental path %q, it should be "+
+				"<path>[@<namespace>]=<off|on|shadow>", item)
+		}
+		name, nsVal, hasNs := strings.Cut(strings.TrimSpace(name), "@")
+		if _, ok := experimentalPaths[name]; !ok {
+			return nil, errors.Errorf("unknown experimental path %q", name)
+		}
+		mode, err := parsePathMode(strings.TrimSpace(val))
+		if err != nil {
+			return nil, errors.Wrapf(err, "for the experimental path %s", name)
+		}
+		if !hasNs {
+			modes.all[name] = mode
+			continue
+		}
+		if !withNs {
+			return nil, errors.Errorf("the mode of the experimental path %s can't be set for "+
+				"namespace %s by a request", name, nsVal)
+		}
+		ns, err := strconv.ParseUint(nsVal, 0, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid namespace %q for the experimental path %s", nsVal,
+				name)
+		}
+		if modes.namespaces[ns] == nil {
+			modes.namespaces[ns] = make(map[string]PathMode)
+		}
+		modes.namespaces[ns][name] = mode
+	}
+	return modes, nil
+}
+
+var clusterPathModes atomic.Pointer[pathModes]
+
+func init() {
+	clusterPathModes.Store(&pathModes{})
+}
+
+// SetExperimentalPaths sets the modes of the experimental paths for the namespaces and the
+// cluster, as a comma separated list of <path>[@<namespace>]=<off|on|shadow>. The paths not
+// listed get their default mode.
+func SetExperimentalPaths(spec string) error {
+	modes, err := parsePathModes(spec, true)
+	if err != nil {
+		return err
+	}
+	clusterPathModes.Store(modes)
+	return nil
+}
+
+const (
+	// ExperimentalPathsHeader is the query parameter and the gRPC metadata overriding the modes of
+	// the experimental paths for a request, as a comma separated list of <path>=<off|on|shadow>.
+	// It's also sent with the tasks of the request to the other Alphas.
+	ExperimentalPathsHeader = "experimental-paths"
+)
+
+type experimentalPathsKey struct{}
+
+// WithExperimentalPaths returns the context of a request overriding the modes of the experimental
+// paths with spec, a comma separated list of <path>=<off|on|shadow>.
+func WithExperimentalPaths(ctx context.Context, spec string) (context.Context, error) {
+	modes, err := parsePathModes(spec, false)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, experimentalPathsKey{}, modes), nil
+}
+
+// RequestedExperimentalPaths returns the modes of the experimental paths asked by the gRPC
+// request, through its experimental-paths metadata, if any.
+func RequestedExperimentalPaths(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(ExperimentalPathsHeader)) == 0 {
+		return "", false
+	}
+	return md.Get(ExperimentalPathsHeader)[0], true
+}
+
+// requestPathModes returns the modes of the experimental paths overridden by the request of the
+// context, which were received from another Alpha if they aren't attached to it.
+func requestPathModes(ctx context.Context) *pathModes {
+	if modes, ok := ctx.Value(experimentalPathsKey{}).(*pathModes); ok {
+		return modes
+	}
+	spec, ok := RequestedExperimentalPaths(ctx)
+	if !ok {
+		return nil
+	}
+	modes, err := parsePathModes(spec, false)
+	if err != nil {
+		// It was checked by the Alpha which got the request.
+		return nil
+	}
+	return modes
+}
+
+// ExperimentalPathMode returns the mode of the experimental path for the request of the context,
+// on the predicates of the namespace.
+func ExperimentalPathMode(ctx context.Context, ns uint64, name string) PathMode {
+	p, ok := experimentalPaths[name]
+	AssertTruef(ok, "Unknown experimental path %s", name)
+	if modes := requestPathModes(ctx); modes != nil {
+		if m, ok := modes.all[name]; ok {
+			return m
+		}
+	}
+	modes := clusterPathModes.Load()
+	if m, ok := modes.namespaces[ns][name]; ok {
+		return m
+	}
+	if m, ok := modes.all[name]; ok {
+		return m
+	}
+	return p.Default
+}
+
+// ExperimentalPathsUnaryClientInterceptor sends the modes of the experimental paths overridden by
+// the request of the context with the calls to the other nodes.
+func ExperimentalPathsUnaryClientInterceptor(ctx context.Context, method string, req,
+	reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption) error {
+
+	if modes, ok := ctx.Value(experimentalPathsKey{}).(*pathModes); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, ExperimentalPathsHeader, modes.spec)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+var (
+	// ExperimentalPathLatencyMs is the latency of the old and the new execution paths of the
+	// experimental paths in shadow mode.
+	ExperimentalPathLatencyMs = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "dgraph",
+		Name:      "experimental_path_latency_ms",
+		Help:      "Latency of the old and new execution paths of the experimental paths in shadow mode",
+		Buckets:   latencyMsBuckets,
+	}, []string{"path", "variant"})
+	// ExperimentalPathShadowRuns is the number of runs of the experimental paths in shadow mode,
+	// by outcome of the comparison of their results with the ones of the old paths.
+	ExperimentalPathShadowRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "dgraph",
+		Name:      "experimental_path_shadow_runs_total",
+		Help:      "Runs of the experimental paths in shadow mode, by outcome of the comparison",
+	}, []string{"path", "outcome"})
+)
+
+// ErrShadowSkipped is returned by the new path of an experimental path in shadow mode when it
+// can't answer, so that there's nothing to compare.
+var ErrShadowSkipped = errors.New("The experimental path can't answer")
+
+// ShadowPath runs the old and the new execution paths of the experimental path in shadow mode,
+// one after the other, and returns the result of the old one. The results are compared with equal,
+// the mismatches and the errors of the new path being logged, and the latencies of both paths are
+// recorded.
+func ShadowPath[T any](ctx context.Context, name string, old, new func() (T, error),
+	equal func(a, b T) bool) (T, error) {
+
+	observe := func(variant string, start time.Time) {
+		ExperimentalPathLatencyMs.WithLabelValues(name, variant).Observe(
+			float64(time.Since(start)) / float64(time.Millisecond))
+	}
+	start := time.Now()
+	res, err := old()
+	observe("old", start)
+	if err != nil {
+		return res, err
+	}
+	start = time.Now()
+	shadow, err := new()
+	observe("new", start)
+
+	outcome := "match"
+	switch {
+	case errors.Is(err, ErrShadowSkipped):
+		outcome = "skipped"
+	case err != nil:
+		outcome = "error"
+		glog.Warningf("%sThe experimental path %s failed in shadow mode: %v",
+			RequestLogPrefix(ctx), name, err)
+	case !equal(res, shadow):
+		outcome = "mismatch"
+		glog.Warningf("%sThe experimental path %s returned a different result than the old "+
+			"path in shadow mode", RequestLogPrefix(ctx), name)
+	}
+	ExperimentalPathShadowRuns.WithLabelValues(name, outcome).Inc()
+	return res, nil
+}
@@ -0,0 +1,91 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestExperimentalPathMode(t *testing.T) {
+	defer func() { require.NoError(t, SetExperimentalPaths("")) }()
+	ctx := context.Background()
+	require.Equal(t, PathOn, ExperimentalPathMode(ctx, 0, "presence-read"))
+
+	require.Error(t, SetExperimentalPaths("unknown=on"))
+	require.Error(t, SetExperimentalPaths("presence-read=maybe"))
+	require.Error(t, SetExperimentalPaths("presence-read@ns=off"))
+
+	require.NoError(t, SetExperimentalPaths("presence-read=shadow, presence-read@0x2=off"))
+	require.Equal(t, PathShadow, ExperimentalPathMode(ctx, 0, "presence-read"))
+	require.Equal(t, PathOff, ExperimentalPathMode(ctx, 2, "presence-read"))
+
+	// The requests override the namespaces and the cluster, but not for a namespace.
+	_, err := WithExperimentalPaths(ctx, "presence-read@2=on")
+	require.Error(t, err)
+	reqCtx, err := WithExperimentalPaths(ctx, "presence-read=on")
+	require.NoError(t, err)
+	require.Equal(t, PathOn, ExperimentalPathMode(reqCtx, 2, "presence-read"))
+
+	// The overrides are sent to the other Alphas with the tasks of the request.
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	require.NoError(t, ExperimentalPathsUnaryClientInterceptor(reqCtx, "/pb.Worker/ServeTask",
+		nil, nil, nil, invoker))
+	remoteCtx := metadata.NewIncomingContext(ctx, outgoing)
+	require.Equal(t, PathOn, ExperimentalPathMode(remoteCtx, 2, "presence-read"))
+}
+
+func TestShadowPath(t *testing.T) {
+	ctx := context.Background()
+	runs := func(outcome string) float64 {
+		m := &dto.Metric{}
+		require.NoError(t, ExperimentalPathShadowRuns.WithLabelValues("presence-read",
+			outcome).Write(m))
+		return m.GetCounter().GetValue()
+	}
+	run := func(newRes []int, newErr error) ([]int, error) {
+		return ShadowPath(ctx, "presence-read",
+			func() ([]int, error) { return []int{1, 2}, nil },
+			func() ([]int, error) { return newRes, newErr },
+			func(a, b []int) bool { return len(a) == len(b) })
+	}
+
+	for _, tc := range []struct {
+		newRes  []int
+		newErr  error
+		outcome string
+	}{
+		{newRes: []int{1, 2}, outcome: "match"},
+		{newRes: []int{1}, outcome: "mismatch"},
+		{newErr: errors.New("boom"), outcome: "error"},
+		{newErr: ErrShadowSkipped, outcome: "skipped"},
+	} {
+		before := runs(tc.outcome)
+		res, err := run(tc.newRes, tc.newErr)
+		// The result of the old path is always returned.
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, res)
+		require.Equal(t, before+1, runs(tc.outcome), tc.outcome)
+	}
+
+	// The errors of the old path are returned, without running the new one.
+	_, err := ShadowPath(ctx, "presence-read",
+		func() ([]int, error) { return nil, errors.New("old") },
+		func() ([]int, error) { panic("the new path shouldn't run") },
+		func(a, b []int) bool { return true })
+	require.EqualError(t, err, "old")
+}
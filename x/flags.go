@@ -14,6 +14,10 @@ import (
 const (
 	TraceDefaults     = `ratio=0.01; jaeger=; datadog=;`
 	TelemetryDefaults = `reports=true;sentry=false;`
+	ProfilingDefaults = `interval=1m; cpu-duration=10s; types=cpu,heap,goroutine; url=; app=; ` +
+		`auth-token=;`
+	FlightRecorderDefaults = `events=10000; interval=10s; memory-threshold=0.9; ` +
+		`memory-limit-mb=0; dir=;`
 )
 
 // FillCommonFlags stores flags common to Alpha and Zero.
@@ -36,6 +40,45 @@ func FillCommonFlags(flag *pflag.FlagSet) {
 				"support annotation logs and discards them.").
 		String())
 
+	flag.String("profiling", ProfilingDefaults, z.NewSuperFlagHelp(ProfilingDefaults).
+		Head("Continuous profiling options. The profiles are captured periodically and pushed "+
+			"to the /ingest API of Pyroscope, labeled with the node, its group and the namespace "+
+			"with the most requests. Pull based profilers like Parca can scrape /debug/pprof "+
+			"instead.").
+		Flag("url",
+			"URL of the continuous profiling server. The profiles aren't pushed if it's empty.").
+		Flag("app",
+			"Name of the application of the profiles, dgraph.alpha or dgraph.zero by default.").
+		Flag("auth-token",
+			"Bearer token sent to the continuous profiling server.").
+		Flag("interval",
+			"Interval at which the profiles are captured and pushed.").
+		Flag("cpu-duration",
+			"How long the CPU is profiled at each interval.").
+		Flag("types",
+			"Comma separated list of the profiles captured: cpu, heap, goroutine, allocs, mutex "+
+				"or block.").
+		String())
+
+	flag.String("flight-recorder", FlightRecorderDefaults, z.NewSuperFlagHelp(
+		FlightRecorderDefaults).
+		Head("Flight recorder options. The recent queries, proposals and memory stats are kept "+
+			"in a ring buffer, dumped with a heap profile when the memory gets close to its limit "+
+			"or on panics. GET /debug/flight-recorder returns them, POST dumps them.").
+		Flag("dir",
+			"Directory where the flight recorder and the crashes are dumped. The flight recorder "+
+				"is disabled if it's empty.").
+		Flag("events",
+			"Number of the most recent events kept.").
+		Flag("interval",
+			"Interval at which the memory stats are recorded and checked.").
+		Flag("memory-threshold",
+			"Fraction of the memory limit past which the flight recorder is dumped.").
+		Flag("memory-limit-mb",
+			"Memory limit in MB. It's the limit of the cgroup of the process if 0, and the "+
+				"memory isn't checked without limit.").
+		String())
+
 	flag.String("survive", "process",
 		`Choose between "process" or "filesystem".`+"\n    "+
 			`If set to "process", there would be no data loss in case of process crash, but `+
@@ -0,0 +1,256 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// FlightEvent is an event kept by the flight recorder, like a query, a proposal or the memory
+// stats.
+type FlightEvent struct {
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail"`
+}
+
+// flightRecorder keeps the most recent events in a ring buffer, which is dumped with a heap
+// profile when the memory gets close to its limit or on panics, so that the postmortems of the
+// crashes have data.
+type flightRecorder struct {
+	sync.Mutex
+	events []FlightEvent
+	next   int
+	full   bool
+
+	dir string
+	// memoryLimit is the memory in bytes past which the memory is close to its limit, or 0.
+	memoryLimit int64
+	interval    time.Duration
+}
+
+var recorder atomic.Pointer[flightRecorder]
+
+// InitFlightRecorder sets up the flight recorder from the --flight-recorder superflag. It's
+// disabled if no dir is given. The crashes of the Go runtime, which can't be recovered, are
+// written to a file in dir too.
+func InitFlightRecorder(flag string) error {
+	sf := z.NewSuperFlag(flag).MergeAndCheckDefault(FlightRecorderDefaults)
+	dir := sf.GetString("dir")
+	if dir == "" {
+		return nil
+	}
+	r := &flightRecorder{
+		events:   make([]FlightEvent, sf.GetUint64("events")),
+		dir:      dir,
+		interval: sf.GetDuration("interval"),
+	}
+	if len(r.events) == 0 || r.interval <= 0 {
+		return errors.Errorf("--flight-recorder events and interval should be positive")
+	}
+	threshold := sf.GetFloat64("memory-threshold")
+	if threshold <= 0 || threshold > 1 {
+		return errors.Errorf("invalid --flight-recorder memory-threshold %v, it should be in "+
+			"(0, 1]", threshold)
+	}
+	limit := int64(sf.GetUint64("memory-limit-mb")) << 20
+	if limit == 0 {
+		limit = cgroupMemoryLimit()
+	}
+	r.memoryLimit = int64(float64(limit) * threshold)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrapf(err, "while creating the flight recorder dir %s", dir)
+	}
+	crash, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("crash-%d.log", os.Getpid())),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "while creating the crash output")
+	}
+	defer func() { _ = crash.Close() }()
+	if err := debug.SetCrashOutput(crash, debug.CrashOptions{}); err != nil {
+		return errors.Wrap(err, "while setting the crash output")
+	}
+
+	recorder.Store(r)
+	http.HandleFunc("/debug/flight-recorder", flightRecorderHandler)
+	glog.Infof("Flight recorder keeping the last %d events, dumped to %s", len(r.events), dir)
+	return nil
+}
+
+// cgroupMemoryLimit returns the memory limit of the cgroup of the process, or 0 if it has none.
+func cgroupMemoryLimit() int64 {
+	for _, file := range []string{"/sys/fs/cgroup/memory.max",
+		"/sys/fs/cgroup/memory/memory.limit_in_bytes"} {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		// cgroup v1 gives a huge number rather than max without limit.
+		limit, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+		if err == nil && limit < 1<<60 {
+			return limit
+		}
+		return 0
+	}
+	return 0
+}
+
+// FlightRecorderEnabled returns true if the flight recorder is set up, so that the callers can
+// skip computing the details of the events otherwise.
+func FlightRecorderEnabled() bool {
+	return recorder.Load() != nil
+}
+
+// RecordFlightEvent adds the event to the flight recorder, if it's set up.
+func RecordFlightEvent(kind, format string, args ...any) {
+	r := recorder.Load()
+	if r == nil {
+		return
+	}
+	event := FlightEvent{Time: time.Now(), Kind: kind, Detail: fmt.Sprintf(format, args...)}
+	r.Lock()
+	defer r.Unlock()
+	r.events[r.next] = event
+	r.next++
+	if r.next == len(r.events) {
+		r.next, r.full = 0, true
+	}
+}
+
+// recent returns the events of the ring buffer, from the oldest to the newest.
+func (r *flightRecorder) recent() []FlightEvent {
+	r.Lock()
+	defer r.Unlock()
+	if !r.full {
+		return append([]FlightEvent(nil), r.events[:r.next]...)
+	}
+	return append(append([]FlightEvent(nil), r.events[r.next:]...), r.events[:r.next]...)
+}
+
+// DumpFlightRecorder writes the events of the flight recorder and a heap profile to its dir, named
+// after the time of the dump. It returns the path of the events.
+func DumpFlightRecorder(reason string) (string, error) {
+	r := recorder.Load()
+	if r == nil {
+		return "", errors.Errorf("The flight recorder isn't enabled")
+	}
+	prefix := filepath.Join(r.dir, "flight-"+time.Now().UTC().Format("20060102T150405.000"))
+	dump := struct {
+		Reason     string        `json:"reason"`
+		Time       time.Time     `json:"time"`
+		Goroutines int           `json:"goroutines"`
+		Events     []FlightEvent `json:"events"`
+	}{reason, time.Now(), runtime.NumGoroutine(), r.recent()}
+	b, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(prefix+".json", b, 0600); err != nil {
+		return "", errors.Wrap(err, "while dumping the flight recorder")
+	}
+	heap, err := os.Create(prefix + ".heap.pb.gz")
+	if err != nil {
+		return "", errors.Wrap(err, "while dumping the heap profile")
+	}
+	defer func() { _ = heap.Close() }()
+	if err := pprof.Lookup("heap").WriteTo(heap, 0); err != nil {
+		return "", errors.Wrap(err, "while dumping the heap profile")
+	}
+	glog.Warningf("Dumped the flight recorder to %s.json because of %s", prefix, reason)
+	return prefix + ".json", nil
+}
+
+// DumpFlightRecorderOnPanic dumps the flight recorder on panic, before panicking again. It's
+// deferred at the top of the goroutines.
+func DumpFlightRecorderOnPanic() {
+	if r := recover(); r != nil {
+		if FlightRecorderEnabled() {
+			if _, err := DumpFlightRecorder(fmt.Sprintf("panic: %v", r)); err != nil {
+				glog.Errorf("While dumping the flight recorder: %v", err)
+			}
+		}
+		panic(r)
+	}
+}
+
+// MonitorFlightRecorder records the memory stats in the flight recorder at each interval, and
+// dumps it when the memory gets past its limit. It's dumped again once the memory went back
+// under the limit and past it again.
+func MonitorFlightRecorder(closer *z.Closer) {
+	defer closer.Done()
+	r := recorder.Load()
+	if r == nil {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	var dumped bool
+	for {
+		select {
+		case <-ticker.C:
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			rss := int64(getMemUsage())
+			RecordFlightEvent("memory", "rss=%d heap_inuse=%d heap_idle=%d jemalloc=%d "+
+				"goroutines=%d gc=%d", rss, ms.HeapInuse, ms.HeapIdle, z.NumAllocBytes(),
+				runtime.NumGoroutine(), ms.NumGC)
+			if r.memoryLimit == 0 {
+				continue
+			}
+			switch {
+			case rss >= r.memoryLimit && !dumped:
+				reason := fmt.Sprintf("memory usage %d bytes past %d bytes", rss, r.memoryLimit)
+				if _, err := DumpFlightRecorder(reason); err != nil {
+					glog.Errorf("While dumping the flight recorder: %v", err)
+				}
+				dumped = true
+			case rss < r.memoryLimit:
+				dumped = false
+			}
+		case <-closer.HasBeenClosed():
+			return
+		}
+	}
+}
+
+// flightRecorderHandler returns the events of the flight recorder on GET, and dumps them on POST.
+func flightRecorderHandler(w http.ResponseWriter, r *http.Request) {
+	rec := recorder.Load()
+	if rec == nil {
+		http.Error(w, "The flight recorder isn't enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodPost {
+		path, err := DumpFlightRecorder("requested through /debug/flight-recorder")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"path": path})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rec.recent())
+}
@@ -0,0 +1,74 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	defer func() {
+		recorder.Store(nil)
+		require.NoError(t, debug.SetCrashOutput(nil, debug.CrashOptions{}))
+	}()
+	require.NoError(t, InitFlightRecorder(""))
+	require.False(t, FlightRecorderEnabled())
+	RecordFlightEvent("query", "ignored")
+
+	dir := t.TempDir()
+	require.Error(t, InitFlightRecorder("dir="+dir+"; memory-threshold=2"))
+	require.NoError(t, InitFlightRecorder("dir="+dir+"; events=3"))
+	require.True(t, FlightRecorderEnabled())
+	for _, q := range []string{"q1", "q2", "q3", "q4"} {
+		RecordFlightEvent("query", "query=%s", q)
+	}
+
+	// The oldest event was overwritten.
+	w := httptest.NewRecorder()
+	flightRecorderHandler(w, httptest.NewRequest(http.MethodGet, "/debug/flight-recorder", nil))
+	var events []FlightEvent
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+	require.Len(t, events, 3)
+	for i, q := range []string{"q2", "q3", "q4"} {
+		require.Equal(t, "query", events[i].Kind)
+		require.Equal(t, "query="+q, events[i].Detail)
+	}
+
+	require.Panics(t, func() {
+		defer DumpFlightRecorderOnPanic()
+		panic("boom")
+	})
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	require.Len(t, names, 3, "%v", names)
+	var dump struct {
+		Reason string        `json:"reason"`
+		Events []FlightEvent `json:"events"`
+	}
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(b, &dump))
+	}
+	require.Equal(t, "panic: boom", dump.Reason)
+	require.Len(t, dump.Events, 3)
+}
@@ -524,6 +524,25 @@ func SplitKey(baseKey []byte, startUid uint64) ([]byte, error) {
 	return keyCopy, nil
 }
 
+// ReplaceAttr returns a copy of the key with its namespace and attribute replaced by the ones of
+// attr. The rest of the key, like the uid, the index term or the start uid of a split, is kept.
+func ReplaceAttr(key []byte, attr string) ([]byte, error) {
+	if len(key) < 11 {
+		return nil, errors.Errorf("Invalid format for key %v", key)
+	}
+	if key[0] == ByteUnused {
+		return nil, errors.Errorf("Key %v has no attribute", key)
+	}
+	sz := int(binary.BigEndian.Uint16(key[9:11]))
+	if len(key) < 11+sz {
+		return nil, errors.Errorf("Invalid size %v for key %v", sz, key)
+	}
+	rest := key[11+sz:]
+	buf, prefixLen := generateKey(key[0], attr, len(rest))
+	copy(buf[prefixLen:], rest)
+	return buf, nil
+}
+
 // Parse would parse the key. ParsedKey does not reuse the key slice, so the key slice can change
 // without affecting the contents of ParsedKey.
 func Parse(key []byte) (ParsedKey, error) {
@@ -648,12 +667,34 @@ var aclPredicateMap = map[string]struct{}{
 // predicates, but for all those which are PreDefined and whose value is not allowed to be mutated
 // by users. When renaming this also rename the IsGraphql context key in edgraph/server.go.
 var otherReservedPredicate = map[string]struct{}{
-	"dgraph.graphql.xid":     {},
-	"dgraph.graphql.schema":  {},
-	"dgraph.drop.op":         {},
-	"dgraph.graphql.p_query": {},
-	"dgraph.namespace.id":    {},
-	"dgraph.namespace.name":  {},
+	"dgraph.graphql.xid":         {},
+	"dgraph.graphql.schema":      {},
+	"dgraph.drop.op":             {},
+	"dgraph.graphql.p_query":     {},
+	"dgraph.namespace.id":        {},
+	"dgraph.namespace.name":      {},
+	"dgraph.namespace.sandboxed": {},
+	"dgraph.trigger.name":        {},
+	"dgraph.trigger.namespace":   {},
+	"dgraph.trigger.spec":        {},
+	"dgraph.wasm.name":           {},
+	"dgraph.wasm.namespace":      {},
+	"dgraph.wasm.code":           {},
+	"dgraph.function.name":       {},
+	"dgraph.function.namespace":  {},
+	"dgraph.function.query":      {},
+	"dgraph.idempotency.key":     {},
+	"dgraph.idempotency.result":  {},
+	"dgraph.idempotency.expiry":  {},
+	"dgraph.apikey.id":           {},
+	"dgraph.apikey.name":         {},
+	"dgraph.apikey.hash":         {},
+	"dgraph.apikey.prev_hash":    {},
+	"dgraph.apikey.prev_expiry":  {},
+	"dgraph.apikey.expiry":       {},
+	"dgraph.apikey.namespaces":   {},
+	"dgraph.apikey.permission":   {},
+	"dgraph.apikey.predicates":   {},
 }
 
 // internalPredicateMap stores a set of Dgraph's internal predicate. An internal
@@ -671,6 +712,11 @@ var preDefinedTypeMap = map[string]struct{}{
 	"dgraph.type.Rule":               {},
 	"dgraph.graphql.persisted_query": {},
 	"dgraph.namespace":               {},
+	"dgraph.type.ApiKey":             {},
+	"dgraph.trigger":                 {},
+	"dgraph.wasm":                    {},
+	"dgraph.function":                {},
+	"dgraph.idempotency":             {},
 }
 
 // IsOtherReservedPredicate returns true if it is the predicate is reserved by graphql.
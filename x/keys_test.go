@@ -184,6 +184,27 @@ func TestCountKeyWithStartUid(t *testing.T) {
 	}
 }
 
+func TestReplaceAttr(t *testing.T) {
+	from, to := NamespaceAttr(2, "name"), NamespaceAttr(2, "fullName")
+	dataKey, err := SplitKey(DataKey(from, 5), 10)
+	require.NoError(t, err)
+	for _, key := range [][]byte{DataKey(from, 5), dataKey, IndexKey(from, "term"),
+		ReverseKey(from, 7), CountKey(from, 3, true)} {
+		before, err := Parse(key)
+		require.NoError(t, err)
+		renamed, err := ReplaceAttr(key, to)
+		require.NoError(t, err)
+		after, err := Parse(renamed)
+		require.NoError(t, err)
+
+		require.Equal(t, to, after.Attr)
+		before.Attr = to
+		require.Equal(t, before, after)
+	}
+	_, err = ReplaceAttr([]byte{DefaultPrefix, 0}, to)
+	require.Error(t, err)
+}
+
 func TestSchemaKey(t *testing.T) {
 	for uid := range 1001 {
 		sattr := fmt.Sprintf("attr:%d", uid)
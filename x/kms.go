@@ -0,0 +1,148 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+)
+
+const (
+	flagEncKms                = "kms"
+	flagEncKmsKey             = "kms-key"
+	flagEncKmsRegion          = "kms-region"
+	flagEncKmsEndpoint        = "kms-endpoint"
+	flagEncKmsCredentialsFile = "kms-credentials-file"
+
+	kmsRequestTimeout = 10 * time.Second
+)
+
+// KMS decrypts the keys that were encrypted by an external key management service. It is used
+// for envelope encryption: Dgraph only stores the encrypted data keys, and asks the KMS to
+// decrypt them when they are loaded.
+type KMS interface {
+	// Name returns the name of the KMS provider.
+	Name() string
+	// Decrypt decrypts the ciphertext, as stored in a key file, and returns the plain key.
+	Decrypt(ctx context.Context, ciphertext []byte) (Sensitive, error)
+}
+
+// kmsFromFlag returns the KMS configured in the encryption superflag, or nil if none is
+// configured.
+func kmsFromFlag(flag *z.SuperFlag, config *viper.Viper) (KMS, error) {
+	provider := strings.ToLower(flag.GetString(flagEncKms))
+	key := flag.GetString(flagEncKmsKey)
+	if provider == "" {
+		return nil, nil
+	}
+	if key == "" && provider != "aws" {
+		return nil, errors.Errorf("encryption: %s is required for %s KMS", flagEncKmsKey, provider)
+	}
+
+	client := &http.Client{Timeout: kmsRequestTimeout}
+	switch provider {
+	case "aws":
+		return newAwsKms(client, key, flag.GetString(flagEncKmsRegion),
+			flag.GetString(flagEncKmsEndpoint)), nil
+	case "gcp":
+		return newGcpKms(client, key, flag.GetString(flagEncKmsEndpoint),
+			flag.GetPath(flagEncKmsCredentialsFile))
+	case "vault":
+		return newVaultTransit(config, key)
+	default:
+		return nil, errors.Errorf("encryption: unsupported KMS %q, must be one of aws, gcp or vault",
+			provider)
+	}
+}
+
+// KmsDecrypt decrypts the contents of a key file with the KMS.
+func KmsDecrypt(kms KMS, data []byte) (Sensitive, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kmsRequestTimeout)
+	defer cancel()
+	key, err := kms.Decrypt(ctx, bytes.TrimSpace(data))
+	return key, errors.Wrapf(err, "while decrypting key with %s KMS", kms.Name())
+}
+
+// kmsPost sends a JSON request, and decodes the JSON response into out.
+func kmsPost(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("request to %s failed with status %s: %s",
+			req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// vaultTransit decrypts keys using the transit secrets engine of Vault. The key files hold
+// the ciphertext returned by Vault, in the vault:v<version>:<ciphertext> format.
+type vaultTransit struct {
+	client *api.Client
+	key    string
+}
+
+func newVaultTransit(config *viper.Viper, key string) (*vaultTransit, error) {
+	flag := z.NewSuperFlag(config.GetString(flagVault)).MergeAndCheckDefault(
+		vaultDefaults(true, true))
+	addr := flag.GetString(flagVaultAddr)
+	roleIdFile := flag.GetPath(flagVaultRoleIdFile)
+	if addr == "" || roleIdFile == "" {
+		return nil, errors.Errorf("vault: %s and %s are required for the vault KMS",
+			flagVaultAddr, flagVaultRoleIdFile)
+	}
+	client, err := vaultNewClient(addr, roleIdFile, flag.GetPath(flagVaultSecretIdFile))
+	if err != nil {
+		return nil, err
+	}
+	// The key can be given as <mount>/<key>, the mount defaults to transit.
+	if !strings.Contains(key, "/") {
+		key = "transit/" + key
+	}
+	return &vaultTransit{client: client, key: key}, nil
+}
+
+func (v *vaultTransit) Name() string {
+	return "vault"
+}
+
+func (v *vaultTransit) Decrypt(ctx context.Context, ciphertext []byte) (Sensitive, error) {
+	idx := strings.LastIndex(v.key, "/")
+	path := fmt.Sprintf("%s/decrypt/%s", v.key[:idx], v.key[idx+1:])
+	secret, err := v.client.Logical().WriteWithContext(ctx, path, map[string]interface{}{
+		"ciphertext": string(ciphertext),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.Errorf("vault: empty response from %s", path)
+	}
+	plaintext, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, errors.Errorf("vault: plaintext not found in response from %s", path)
+	}
+	return base64.StdEncoding.DecodeString(plaintext)
+}
@@ -0,0 +1,100 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/errors"
+)
+
+// awsKms decrypts keys using AWS KMS. The key files hold the base64 encoded ciphertext blob,
+// as returned by `aws kms encrypt --output text --query CiphertextBlob`. The credentials are
+// picked up from the environment or the instance metadata, like for S3 backups.
+type awsKms struct {
+	client   *http.Client
+	creds    *credentials.Credentials
+	keyId    string
+	region   string
+	endpoint string
+}
+
+func newAwsKms(client *http.Client, keyId, region, endpoint string) *awsKms {
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", region)
+	}
+	return &awsKms{
+		client:   client,
+		creds:    credentials.New(MinioCredentialsProvider("s3", credentials.Value{})),
+		keyId:    keyId,
+		region:   region,
+		endpoint: endpoint,
+	}
+}
+
+func (a *awsKms) Name() string {
+	return "aws"
+}
+
+func (a *awsKms) Decrypt(ctx context.Context, ciphertext []byte) (Sensitive, error) {
+	in := map[string]string{"CiphertextBlob": string(ciphertext)}
+	if a.keyId != "" {
+		in["KeyId"] = a.keyId
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+
+	creds, err := a.creds.Get()
+	if err != nil {
+		return nil, errors.Wrapf(err, "while getting AWS credentials")
+	}
+	if err := signAwsV4(req, body, creds, a.region, "kms", time.Now()); err != nil {
+		return nil, errors.Wrapf(err, "while signing AWS KMS request")
+	}
+
+	var out struct {
+		Plaintext string
+	}
+	if err := kmsPost(a.client, req, &out); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(out.Plaintext)
+}
+
+// signAwsV4 signs the request with the AWS Signature Version 4 signing process.
+func signAwsV4(req *http.Request, body []byte, creds credentials.Value, region, service string,
+	now time.Time) error {
+
+	hash := sha256.Sum256(body)
+	return v4.NewSigner().SignHTTP(req.Context(), aws.Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+	}, req, hex.EncodeToString(hash[:]), service, region, now)
+}
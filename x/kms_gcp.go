@@ -0,0 +1,96 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcpKmsEndpoint = "https://cloudkms.googleapis.com"
+	gcpKmsScope    = "https://www.googleapis.com/auth/cloudkms"
+)
+
+// gcpKms decrypts keys using Google Cloud KMS. The key is the resource name of the crypto
+// key, i.e. projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>, and the key files hold the
+// base64 encoded ciphertext. The access tokens are obtained with the credentials file if one is
+// given, and with the application default credentials otherwise, i.e.
+// $GOOGLE_APPLICATION_CREDENTIALS or the metadata server, and are cached until they expire.
+type gcpKms struct {
+	client   *http.Client
+	key      string
+	endpoint string
+	tokens   oauth2.TokenSource
+}
+
+func newGcpKms(client *http.Client, key, endpoint, credentialsFile string) (*gcpKms, error) {
+	if endpoint == "" {
+		endpoint = gcpKmsEndpoint
+	}
+	// The tokens are fetched with the client of the KMS, whatever the context of the calls.
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	var creds *google.Credentials
+	if credentialsFile != "" {
+		data, err := os.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading GCP credentials file")
+		}
+		if creds, err = google.CredentialsFromJSON(ctx, data, gcpKmsScope); err != nil {
+			return nil, errors.Wrapf(err, "while parsing GCP credentials file")
+		}
+	} else {
+		var err error
+		if creds, err = google.FindDefaultCredentials(ctx, gcpKmsScope); err != nil {
+			return nil, errors.Wrapf(err, "while finding GCP default credentials")
+		}
+	}
+	return &gcpKms{
+		client:   client,
+		key:      key,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		tokens:   creds.TokenSource,
+	}, nil
+}
+
+func (g *gcpKms) Name() string {
+	return "gcp"
+}
+
+func (g *gcpKms) Decrypt(ctx context.Context, ciphertext []byte) (Sensitive, error) {
+	token, err := g.tokens.Token()
+	if err != nil {
+		return nil, errors.Wrapf(err, "while getting GCP access token")
+	}
+	body, err := json.Marshal(map[string]string{"ciphertext": string(ciphertext)})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		g.endpoint+"/v1/"+g.key+":decrypt", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	token.SetAuthHeader(req)
+
+	var out struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := kmsPost(g.client, req, &out); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(out.Plaintext)
+}
@@ -0,0 +1,123 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAwsV4(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite.
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	creds := credentials.Value{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	require.NoError(t, signAwsV4(req, nil, creds, "us-east-1", "service", now))
+	require.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/"+
+		"aws4_request, SignedHeaders=host;x-amz-date, Signature="+
+		"5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestAwsKms(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "TrentService.Decrypt", r.Header.Get("X-Amz-Target"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		var in map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		require.Equal(t, "alias/dgraph", in["KeyId"])
+		if in["CiphertextBlob"] != "Y2lwaGVy" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{
+			"Plaintext": base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")),
+		}))
+	}))
+	defer srv.Close()
+
+	kms := newAwsKms(srv.Client(), "alias/dgraph", "us-east-1", srv.URL)
+	key, err := KmsDecrypt(kms, []byte("Y2lwaGVy\n"))
+	require.NoError(t, err)
+	require.Equal(t, Sensitive("0123456789abcdef"), key)
+
+	_, err = KmsDecrypt(kms, []byte("other"))
+	require.ErrorContains(t, err, "400 Bad Request")
+}
+
+func TestGcpKms(t *testing.T) {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(pk),
+	})
+
+	tokens := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.NotEmpty(t, r.Form.Get("assertion"))
+		tokens++
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token", "expires_in": 3600,
+		}))
+	})
+	mux.HandleFunc("/v1/projects/p/locations/l/keyRings/r/cryptoKeys/k:decrypt",
+		func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			var in map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+			require.Equal(t, "Y2lwaGVy", in["ciphertext"])
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]string{
+				"plaintext": base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")),
+			}))
+		})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	account, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "dgraph@p.iam.gserviceaccount.com",
+		"private_key":  string(pemKey),
+		"token_uri":    srv.URL + "/token",
+	})
+	require.NoError(t, err)
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(credentialsFile, account, 0600))
+
+	kms, err := newGcpKms(srv.Client(), "projects/p/locations/l/keyRings/r/cryptoKeys/k",
+		srv.URL, credentialsFile)
+	require.NoError(t, err)
+	for range 2 {
+		key, err := KmsDecrypt(kms, []byte("Y2lwaGVy"))
+		require.NoError(t, err)
+		require.Equal(t, Sensitive("0123456789abcdef"), key)
+	}
+	// The access token is cached.
+	require.Equal(t, 1, tokens)
+}
@@ -0,0 +1,223 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+)
+
+const (
+	flagAclLdapURL          = "ldap-url"
+	flagAclLdapCAFile       = "ldap-ca-file"
+	flagAclLdapBindDN       = "ldap-bind-dn"
+	flagAclLdapBindPassFile = "ldap-bind-password-file"
+	flagAclLdapUserBase     = "ldap-user-base"
+	flagAclLdapUserFilter   = "ldap-user-filter"
+	flagAclLdapUserAttr     = "ldap-user-attr"
+	flagAclLdapGroupAttr    = "ldap-group-attr"
+	flagAclLdapNamespace    = "ldap-namespace"
+	flagAclLdapSync         = "ldap-sync-interval"
+
+	ldapTimeout = 30 * time.Second
+)
+
+// ErrLdapUserNotFound is returned when the user isn't in the LDAP directory.
+var ErrLdapUserNotFound = errors.New("ldap: user not found")
+
+// LdapConfig holds the options to authenticate the ACL users against an LDAP directory, and to
+// sync them and their group memberships into Dgraph.
+type LdapConfig struct {
+	// URL is the ldap:// or ldaps:// URL of the directory.
+	URL     string
+	RootCAs *x509.CertPool
+	// BindDN and BindPassword are the credentials of the service account used to search the
+	// directory. The search is anonymous if BindDN is empty.
+	BindDN       string
+	BindPassword Sensitive
+	// UserBase is the DN under which the users are searched, with UserFilter. The users are
+	// identified by the UserAttr attribute, and their groups are the GroupAttr values.
+	UserBase   string
+	UserFilter string
+	UserAttr   string
+	GroupAttr  string
+	// Namespace is the namespace in which the LDAP users are synced.
+	Namespace uint64
+	// SyncInterval is how often all the users are synced, 0 to only sync them when they log in.
+	SyncInterval time.Duration
+}
+
+func ldapFromFlag(flag *z.SuperFlag) (*LdapConfig, error) {
+	addr := flag.GetString(flagAclLdapURL)
+	if addr == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(addr)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return nil, errors.Errorf("acl: invalid %s %q, must be ldap://<host> or ldaps://<host>",
+			flagAclLdapURL, addr)
+	}
+	conf := &LdapConfig{
+		URL:          addr,
+		BindDN:       flag.GetString(flagAclLdapBindDN),
+		UserBase:     flag.GetString(flagAclLdapUserBase),
+		UserFilter:   flag.GetString(flagAclLdapUserFilter),
+		UserAttr:     flag.GetString(flagAclLdapUserAttr),
+		GroupAttr:    flag.GetString(flagAclLdapGroupAttr),
+		Namespace:    flag.GetUint64(flagAclLdapNamespace),
+		SyncInterval: flag.GetDuration(flagAclLdapSync),
+	}
+	if conf.UserBase == "" || conf.UserAttr == "" {
+		return nil, errors.Errorf("acl: %s and %s are required with %s", flagAclLdapUserBase,
+			flagAclLdapUserAttr, flagAclLdapURL)
+	}
+	if _, err := ldap.CompileFilter(conf.UserFilter); err != nil {
+		return nil, errors.Wrapf(err, "acl: invalid %s", flagAclLdapUserFilter)
+	}
+	if file := flag.GetPath(flagAclLdapBindPassFile); file != "" {
+		pass, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "acl: while reading LDAP bind password")
+		}
+		conf.BindPassword = Sensitive(strings.TrimSpace(string(pass)))
+	}
+	if file := flag.GetPath(flagAclLdapCAFile); file != "" {
+		ca, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "acl: while reading LDAP CA certificate")
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("acl: no certificate found in %s", file)
+		}
+	}
+	return conf, nil
+}
+
+// Authenticate checks the password of the user by binding as the user to the directory, and
+// returns the names of the groups of the user. ErrLdapUserNotFound is returned if the user isn't
+// in the directory.
+func (c *LdapConfig) Authenticate(userid, password string) ([]string, error) {
+	if password == "" {
+		return nil, errors.New("ldap: the password should not be empty")
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filter := fmt.Sprintf("(&%s(%s=%s))", c.UserFilter, c.UserAttr, ldap.EscapeFilter(userid))
+	entries, err := c.search(conn, filter, []string{c.GroupAttr})
+	if err != nil {
+		return nil, err
+	}
+	switch len(entries) {
+	case 0:
+		return nil, ErrLdapUserNotFound
+	case 1:
+	default:
+		return nil, errors.Errorf("ldap: found %d entries for user %s", len(entries), userid)
+	}
+	if err := conn.Bind(entries[0].DN, password); err != nil {
+		return nil, err
+	}
+	return ldapGroupNames(entries[0].GetEqualFoldAttributeValues(c.GroupAttr)), nil
+}
+
+// Users returns all the users of the directory, with the names of their groups.
+func (c *LdapConfig) Users() (map[string][]string, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entries, err := c.search(conn, c.UserFilter, []string{c.UserAttr, c.GroupAttr})
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[string][]string, len(entries))
+	for _, e := range entries {
+		ids := e.GetEqualFoldAttributeValues(c.UserAttr)
+		if len(ids) != 1 {
+			continue
+		}
+		users[ids[0]] = ldapGroupNames(e.GetEqualFoldAttributeValues(c.GroupAttr))
+	}
+	return users, nil
+}
+
+// dial connects to the directory, and binds with the service account.
+func (c *LdapConfig) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := ldap.DialURL(c.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}),
+		ldap.DialWithTLSConfig(&tls.Config{
+			ServerName: u.Hostname(),
+			RootCAs:    c.RootCAs,
+			MinVersion: tls.VersionTLS12,
+		}))
+	if err != nil {
+		return nil, errors.Wrapf(err, "ldap: while connecting to %s", u.Host)
+	}
+	conn.SetTimeout(ldapTimeout)
+	// Anonymous bind is allowed for the service account.
+	if c.BindDN != "" {
+		if err := conn.Bind(c.BindDN, string(c.BindPassword)); err != nil {
+			_ = conn.Close()
+			return nil, errors.Wrapf(err, "while binding as %s", c.BindDN)
+		}
+	}
+	return conn, nil
+}
+
+// search returns the entries under the user base matching the filter, with the attributes.
+// Referrals to other servers aren't followed.
+func (c *LdapConfig) search(conn *ldap.Conn, filter string, attrs []string) (
+	[]*ldap.Entry, error) {
+
+	res, err := conn.Search(ldap.NewSearchRequest(c.UserBase, ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases, 0, int(ldapTimeout/time.Second), false, filter, attrs, nil))
+	if err != nil {
+		return nil, err
+	}
+	return res.Entries, nil
+}
+
+// ldapGroupNames returns the names of the groups, which are the values of the first RDN of the
+// group DNs, e.g. cn=devs,ou=groups,dc=example,dc=org is the devs group.
+func ldapGroupNames(dns []string) []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, dn := range dns {
+		parsed, err := ldap.ParseDN(dn)
+		if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+			continue
+		}
+		name := strings.TrimSpace(parsed.RDNs[0].Attributes[0].Value)
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,195 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+)
+
+type testLdapEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// serveTestLdap serves the binds and the searches of the entries. The searches only match the
+// filters given, with all the entries if the filter is for no user.
+func serveTestLdap(t *testing.T, entries map[string]*testLdapEntry, userFilter string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	result := func(tag ber.Tag, code int, msg string) *ber.Packet {
+		op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+		op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated,
+			code, ""))
+		op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString,
+			"", ""))
+		op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString,
+			msg, ""))
+		return op
+	}
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		for {
+			req, err := ber.ReadPacket(conn)
+			if err != nil || len(req.Children) < 2 {
+				return
+			}
+			id, op := req.Children[0].Value, req.Children[1]
+			respond := func(op *ber.Packet) {
+				msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+				msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive,
+					ber.TagInteger, id, ""))
+				msg.AppendChild(op)
+				_, err := conn.Write(msg.Bytes())
+				require.NoError(t, err)
+			}
+
+			switch op.Tag {
+			case ldap.ApplicationBindRequest:
+				dn, password := op.Children[1].Data.String(), op.Children[2].Data.String()
+				if e, ok := entries[dn]; (ok && e.password == password) ||
+					dn == "cn=admin" && password == "secret" {
+					respond(result(ldap.ApplicationBindResponse, 0, ""))
+				} else {
+					respond(result(ldap.ApplicationBindResponse,
+						ldap.LDAPResultInvalidCredentials, "invalid credentials"))
+				}
+			case ldap.ApplicationSearchRequest:
+				filter, err := ldap.DecompileFilter(op.Children[6])
+				require.NoError(t, err)
+				for _, e := range entries {
+					entryFilter := fmt.Sprintf("(&%s(uid=%s))", userFilter,
+						ldap.EscapeFilter(e.attrs["uid"][0]))
+					if filter != entryFilter && filter != userFilter {
+						continue
+					}
+					entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed,
+						ldap.ApplicationSearchResultEntry, nil, "")
+					entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive,
+						ber.TagOctetString, e.dn, ""))
+					attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence,
+						nil, "")
+					for name, vals := range e.attrs {
+						attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed,
+							ber.TagSequence, nil, "")
+						attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive,
+							ber.TagOctetString, name, ""))
+						set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet,
+							nil, "")
+						for _, v := range vals {
+							set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive,
+								ber.TagOctetString, v, ""))
+						}
+						attr.AppendChild(set)
+						attrs.AppendChild(attr)
+					}
+					entry.AppendChild(attrs)
+					respond(entry)
+				}
+				respond(result(ldap.ApplicationSearchResultDone, 0, ""))
+			case ldap.ApplicationUnbindRequest:
+				return
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return "ldap://" + l.Addr().String()
+}
+
+func TestLdapGroupNames(t *testing.T) {
+	require.Equal(t, []string{"devs", "ops", "a,b"},
+		ldapGroupNames([]string{"cn=devs,ou=groups", "CN=devs,ou=other", "cn=ops ,dc=x", "",
+			"not a dn", `cn=a\,b,ou=groups`}))
+}
+
+func TestLdapAuthenticate(t *testing.T) {
+	userFilter := "(objectClass=person)"
+	url := serveTestLdap(t, map[string]*testLdapEntry{
+		"uid=alice,ou=users": {
+			dn:       "uid=alice,ou=users",
+			password: "alicepass",
+			attrs: map[string][]string{
+				"uid":      {"alice"},
+				"memberOf": {"cn=devs,ou=groups", "cn=ops,ou=groups"},
+			},
+		},
+		"uid=bob,ou=users": {
+			dn:       "uid=bob,ou=users",
+			password: "bobpass",
+			attrs:    map[string][]string{"uid": {"bob"}},
+		},
+	}, userFilter)
+	conf := &LdapConfig{
+		URL:          url,
+		BindDN:       "cn=admin",
+		BindPassword: Sensitive("secret"),
+		UserBase:     "ou=users",
+		UserFilter:   userFilter,
+		UserAttr:     "uid",
+		GroupAttr:    "memberOf",
+	}
+
+	groups, err := conf.Authenticate("alice", "alicepass")
+	require.NoError(t, err)
+	require.Equal(t, []string{"devs", "ops"}, groups)
+	_, err = conf.Authenticate("alice", "wrong")
+	require.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials))
+	_, err = conf.Authenticate("alice", "")
+	require.Error(t, err)
+	_, err = conf.Authenticate("carol", "carolpass")
+	require.ErrorIs(t, err, ErrLdapUserNotFound)
+	// The user ID is escaped, so it can't change the filter.
+	_, err = conf.Authenticate("*", "alicepass")
+	require.ErrorIs(t, err, ErrLdapUserNotFound)
+
+	users, err := conf.Users()
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"alice": {"devs", "ops"}, "bob": nil}, users)
+
+	conf.BindPassword = Sensitive("wrong")
+	_, err = conf.Users()
+	require.ErrorContains(t, err, "while binding as cn=admin")
+}
+
+func TestLdapFromFlag(t *testing.T) {
+	parse := func(flag string) (*LdapConfig, error) {
+		return ldapFromFlag(z.NewSuperFlag(flag).MergeAndCheckDefault(AclDefaults))
+	}
+	conf, err := parse("ldap-url=ldaps://ldap.example.org; ldap-user-base=ou=users; " +
+		"ldap-user-attr=uid; ldap-user-filter=(objectClass=person)")
+	require.NoError(t, err)
+	require.Equal(t, "ldaps://ldap.example.org", conf.URL)
+
+	for _, bad := range []string{
+		"ldap-url=http://ldap.example.org; ldap-user-base=ou=users; ldap-user-attr=uid",
+		"ldap-url=ldap://ldap.example.org; ldap-user-attr=uid",
+		"ldap-url=ldap://ldap.example.org; ldap-user-base=ou=users; ldap-user-attr=uid; " +
+			"ldap-user-filter=(objectClass=person",
+		"ldap-url=ldap://ldap.example.org; ldap-user-base=ou=users; ldap-user-attr=uid; " +
+			"ldap-user-filter=(!(a=b)(c=d))",
+	} {
+		_, err := parse(bad)
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	openaiURL        = "https://api.openai.com/v1/chat/completions"
+	anthropicURL     = "https://api.anthropic.com/v1/messages"
+	anthropicVersion = "2023-06-01"
+)
+
+// LLM is a large language model completing prompts, served by a provider like OpenAI, Anthropic
+// or a self-hosted endpoint.
+type LLM interface {
+	// Name returns the name of the provider.
+	Name() string
+	// Complete returns the answer of the model to the prompt, following the system instructions.
+	Complete(ctx context.Context, system, prompt string) (string, error)
+}
+
+// LLMConfig configures the LLM of a provider.
+type LLMConfig struct {
+	// Provider is openai or anthropic. The self-hosted endpoints speaking the chat completions
+	// API of OpenAI use the openai provider with their URL.
+	Provider string
+	// URL overrides the endpoint of the provider.
+	URL       string
+	Model     string
+	APIKey    Sensitive
+	MaxTokens int
+	Timeout   time.Duration
+}
+
+// NewLLM returns the LLM of the provider of the config.
+func NewLLM(c LLMConfig) (LLM, error) {
+	if c.Model == "" {
+		return nil, errors.Errorf("The model of the %s LLM is required", c.Provider)
+	}
+	client := &http.Client{Timeout: c.Timeout}
+	switch strings.ToLower(c.Provider) {
+	case "openai":
+		if c.URL == "" {
+			c.URL = openaiURL
+		}
+		return &openaiLLM{client: client, c: c}, nil
+	case "anthropic":
+		if c.URL == "" {
+			c.URL = anthropicURL
+		}
+		return &anthropicLLM{client: client, c: c}, nil
+	default:
+		return nil, errors.Errorf("Unsupported LLM provider %q, must be one of openai or anthropic",
+			c.Provider)
+	}
+}
+
+// llmPost sends the JSON request to the URL with the headers, and decodes the JSON response
+// into out.
+func llmPost(ctx context.Context, client *http.Client, url string, headers map[string]string,
+	in, out interface{}) error {
+
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, val := range headers {
+		req.Header.Set(name, val)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("request to %s failed with status %s: %s", req.URL.Host,
+			resp.Status, strings.TrimSpace(string(b)))
+	}
+	return json.Unmarshal(b, out)
+}
+
+// openaiLLM completes the prompts with the chat completions API of OpenAI.
+type openaiLLM struct {
+	client *http.Client
+	c      LLMConfig
+}
+
+func (o *openaiLLM) Name() string {
+	return "openai"
+}
+
+func (o *openaiLLM) Complete(ctx context.Context, system, prompt string) (string, error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	in := map[string]interface{}{
+		"model":       o.c.Model,
+		"temperature": 0,
+		"messages": []message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+	}
+	if o.c.MaxTokens > 0 {
+		in["max_tokens"] = o.c.MaxTokens
+	}
+	headers := make(map[string]string)
+	if len(o.c.APIKey) > 0 {
+		headers["Authorization"] = "Bearer " + string(o.c.APIKey)
+	}
+	var out struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+	}
+	if err := llmPost(ctx, o.client, o.c.URL, headers, in, &out); err != nil {
+		return "", err
+	}
+	if len(out.Choices) == 0 {
+		return "", errors.Errorf("The %s LLM returned no answer", o.Name())
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// anthropicLLM completes the prompts with the messages API of Anthropic.
+type anthropicLLM struct {
+	client *http.Client
+	c      LLMConfig
+}
+
+func (a *anthropicLLM) Name() string {
+	return "anthropic"
+}
+
+func (a *anthropicLLM) Complete(ctx context.Context, system, prompt string) (string, error) {
+	maxTokens := a.c.MaxTokens
+	if maxTokens <= 0 {
+		// The max tokens are required by the messages API.
+		maxTokens = 1024
+	}
+	in := map[string]interface{}{
+		"model":       a.c.Model,
+		"system":      system,
+		"max_tokens":  maxTokens,
+		"temperature": 0,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	}
+	headers := map[string]string{"anthropic-version": anthropicVersion}
+	if len(a.c.APIKey) > 0 {
+		headers["x-api-key"] = string(a.c.APIKey)
+	}
+	var out struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := llmPost(ctx, a.client, a.c.URL, headers, in, &out); err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, c := range out.Content {
+		if c.Type == "text" {
+			text.WriteString(c.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", errors.Errorf("The %s LLM returned no answer", a.Name())
+	}
+	return text.String(), nil
+}
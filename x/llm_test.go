@@ -0,0 +1,62 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLLM(t *testing.T) {
+	var got map[string]interface{}
+	var header http.Header
+	answer := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_, _ = w.Write([]byte(answer))
+	}))
+	defer srv.Close()
+
+	c := LLMConfig{Provider: "openai", URL: srv.URL, Model: "m", APIKey: Sensitive("key"),
+		Timeout: time.Second}
+	llm, err := NewLLM(c)
+	require.NoError(t, err)
+	answer = `{"choices": [{"message": {"role": "assistant", "content": "{ q() }"}}]}`
+	text, err := llm.Complete(context.Background(), "system", "prompt")
+	require.NoError(t, err)
+	require.Equal(t, "{ q() }", text)
+	require.Equal(t, "Bearer key", header.Get("Authorization"))
+	require.Equal(t, "m", got["model"])
+	require.Len(t, got["messages"], 2)
+
+	c.Provider = "anthropic"
+	llm, err = NewLLM(c)
+	require.NoError(t, err)
+	answer = `{"content": [{"type": "text", "text": "{ q() }"}]}`
+	text, err = llm.Complete(context.Background(), "system", "prompt")
+	require.NoError(t, err)
+	require.Equal(t, "{ q() }", text)
+	require.Equal(t, "key", header.Get("x-api-key"))
+	require.Equal(t, "system", got["system"])
+
+	answer = `{"content": []}`
+	_, err = llm.Complete(context.Background(), "system", "prompt")
+	require.Error(t, err)
+
+	c.Provider = "other"
+	_, err = NewLLM(c)
+	require.Error(t, err)
+	c.Provider, c.Model = "openai", ""
+	_, err = NewLLM(c)
+	require.Error(t, err)
+}
@@ -20,6 +20,9 @@ type LoggerConf struct {
 	Size          int64
 	Days          int64
 	MessageKey    string
+	// Routes maps the namespaces whose audit events are sent to their own sink, instead of
+	// Output, to the sink.
+	Routes map[uint64]string
 }
 
 func InitLogger(conf *LoggerConf, filename string) (*Logger, error) {
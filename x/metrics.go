@@ -24,6 +24,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/viper"
 	ostats "go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
@@ -60,6 +61,12 @@ var (
 	// NumBackupsFailed is the number of backups failed
 	NumBackupsFailed = ostats.Int64("num_backups_failed_total",
 		"Total number of backups failed", ostats.UnitDimensionless)
+	// NumQuerySpills is the number of query blocks whose results were spilled to disk.
+	NumQuerySpills = ostats.Int64("num_query_spills_total",
+		"Total number of query blocks spilled to disk", ostats.UnitDimensionless)
+	// QuerySpilledBytes is the number of bytes of query results spilled to disk.
+	QuerySpilledBytes = ostats.Int64("query_spilled_bytes_total",
+		"Total number of bytes of query results spilled to disk", ostats.UnitBytes)
 	// LatencyMs is the latency of the various Dgraph operations.
 	LatencyMs = ostats.Float64("latency",
 		"Latency of the various methods", ostats.UnitMilliseconds)
@@ -112,6 +119,28 @@ var (
 		"Number of proposals in Raft apply channel", ostats.UnitDimensionless)
 	RaftPendingSize = ostats.Int64("pending_proposal_bytes",
 		"Size of Raft pending proposal", ostats.UnitBytes)
+	// RaftApplyQueued records the number of mutations queued to be applied, across predicates.
+	RaftApplyQueued = ostats.Int64("raft_apply_queued_mutations",
+		"Number of mutations queued to be applied", ostats.UnitDimensionless)
+	// RaftApplyQueueMaxDepth records the depth of the deepest queue of mutations of a predicate.
+	RaftApplyQueueMaxDepth = ostats.Int64("raft_apply_queue_max_depth",
+		"Number of mutations queued to be applied for the busiest predicate",
+		ostats.UnitDimensionless)
+	// ReverseAsyncLag records how long the oldest committed mutation of the predicates with
+	// @reverse(async) has been waiting for its reverse edges to be added.
+	ReverseAsyncLag = ostats.Int64("reverse_async_lag_ms",
+		"Time the oldest pending async reverse edges have been waiting to be added",
+		ostats.UnitMilliseconds)
+	// ReverseAsyncPending records the number of async reverse edges waiting to be added.
+	ReverseAsyncPending = ostats.Int64("reverse_async_pending_edges",
+		"Number of async reverse edges waiting to be added", ostats.UnitDimensionless)
+	// PredicateDiskBytes records the on-disk size of a predicate in a level of the LSM tree.
+	PredicateDiskBytes = ostats.Int64("predicate_disk_bytes",
+		"On-disk size of the keys and values of a predicate in a level", ostats.UnitBytes)
+	// NamespaceDiskBytes records the on-disk size of the predicates of a namespace in a level of
+	// the LSM tree.
+	NamespaceDiskBytes = ostats.Int64("namespace_disk_bytes",
+		"On-disk size of the keys and values of a namespace in a level", ostats.UnitBytes)
 	// MaxAssignedTs records the latest max assigned timestamp.
 	MaxAssignedTs = ostats.Int64("max_assigned_ts",
 		"Latest max assigned timestamp", ostats.UnitDimensionless)
@@ -166,6 +195,12 @@ var (
 	// KeyDirType is the tag key used to record the group for FileSystem metrics
 	KeyDirType, _ = tag.NewKey("dir")
 
+	// KeyNamespace, KeyPredicate and KeyLevel are the tag keys used to record the namespace,
+	// predicate and LSM level of the disk usage metrics.
+	KeyNamespace, _ = tag.NewKey("namespace")
+	KeyPredicate, _ = tag.NewKey("predicate")
+	KeyLevel, _     = tag.NewKey("level")
+
 	// Tag values.
 
 	// TagValueStatusOK is the tag value used to signal a successful operation.
@@ -173,10 +208,11 @@ var (
 	// TagValueStatusError is the tag value used to signal an unsuccessful operation.
 	TagValueStatusError = "error"
 
-	defaultLatencyMsDistribution = view.Distribution(
+	latencyMsBuckets = []float64{
 		0, 0.01, 0.05, 0.1, 0.3, 0.6, 0.8, 1, 2, 3, 4, 5, 6, 8, 10, 13, 16,
 		20, 25, 30, 40, 50, 65, 80, 100, 130, 160, 200, 250, 300, 400, 500,
-		650, 800, 1000, 2000, 5000, 10000, 20000, 50000, 100000)
+		650, 800, 1000, 2000, 5000, 10000, 20000, 50000, 100000}
+	defaultLatencyMsDistribution = view.Distribution(latencyMsBuckets...)
 
 	// Use this tag for the metric view if it needs status or method granularity.
 	// Metrics would be viewed separately for different tag values.
@@ -188,6 +224,8 @@ var (
 
 	allFSKeys = []tag.Key{KeyDirType}
 
+	allDiskUsageKeys = []tag.Key{KeyNamespace, KeyPredicate, KeyLevel}
+
 	allViews = []*view.View{
 		{
 			Name:        LatencyMs.Name(),
@@ -259,6 +297,20 @@ var (
 			Aggregation: view.Count(),
 			TagKeys:     nil,
 		},
+		{
+			Name:        NumQuerySpills.Name(),
+			Measure:     NumQuerySpills,
+			Description: NumQuerySpills.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        QuerySpilledBytes.Name(),
+			Measure:     QuerySpilledBytes,
+			Description: QuerySpilledBytes.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     nil,
+		},
 		{
 			Name:        TxnCommits.Name(),
 			Measure:     TxnCommits,
@@ -416,6 +468,48 @@ var (
 			Aggregation: view.LastValue(),
 			TagKeys:     allRaftKeys,
 		},
+		{
+			Name:        RaftApplyQueued.Name(),
+			Measure:     RaftApplyQueued,
+			Description: RaftApplyQueued.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     allRaftKeys,
+		},
+		{
+			Name:        RaftApplyQueueMaxDepth.Name(),
+			Measure:     RaftApplyQueueMaxDepth,
+			Description: RaftApplyQueueMaxDepth.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     allRaftKeys,
+		},
+		{
+			Name:        ReverseAsyncLag.Name(),
+			Measure:     ReverseAsyncLag,
+			Description: ReverseAsyncLag.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     allRaftKeys,
+		},
+		{
+			Name:        ReverseAsyncPending.Name(),
+			Measure:     ReverseAsyncPending,
+			Description: ReverseAsyncPending.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     allRaftKeys,
+		},
+		{
+			Name:        PredicateDiskBytes.Name(),
+			Measure:     PredicateDiskBytes,
+			Description: PredicateDiskBytes.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     allDiskUsageKeys,
+		},
+		{
+			Name:        NamespaceDiskBytes.Name(),
+			Measure:     NamespaceDiskBytes,
+			Description: NamespaceDiskBytes.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{KeyNamespace, KeyLevel},
+		},
 		{
 			Name:        RaftHasLeader.Name(),
 			Measure:     RaftHasLeader,
@@ -467,6 +561,8 @@ func init() {
 	promRegistry.MustRegister(collectors.NewGoCollector(collectors.WithGoCollectorRuntimeMetrics(
 		collectors.GoRuntimeMetricsRule{Matcher: regexp.MustCompile("/.*")})))
 	promRegistry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	promRegistry.MustRegister(PredicateLatencyMs, NamespaceLatencyMs)
+	promRegistry.MustRegister(ExperimentalPathLatencyMs, ExperimentalPathShadowRuns)
 
 	pe, err := oc_prom.NewExporter(oc_prom.Options{
 		// includes a process_* metrics, a GoCollector for go_* metrics, and the badger_* metrics.
@@ -477,9 +573,12 @@ func init() {
 	Checkf(err, "Failed to create OpenCensus Prometheus exporter: %v", err)
 	view.RegisterExporter(pe)
 
-	// Exposing metrics at /metrics, which is the usual standard, as well as at the old endpoint
-	http.Handle("/metrics", pe)
-	http.Handle("/debug/prometheus_metrics", pe)
+	// Exposing metrics at /metrics, which is the usual standard, as well as at the old endpoint.
+	// The registry gathers the views of the exporter too. The OpenMetrics format, negotiated by
+	// the scrapers, is the one carrying the exemplars.
+	handler := promhttp.HandlerFor(promRegistry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+	http.Handle("/metrics", handler)
+	http.Handle("/debug/prometheus_metrics", handler)
 }
 
 // NewBadgerCollector returns a prometheus Collector for Badger metrics from expvar.
@@ -28,6 +28,10 @@ const (
 	// defaultEndpointS3 is used with s3 scheme when no host is provided
 	defaultEndpointS3 = "s3.amazonaws.com"
 
+	// defaultEndpointGCS is used with gs scheme when no host is provided. GCS is accessed through
+	// its S3 compatible XML API, with HMAC keys as the credentials.
+	defaultEndpointGCS = "storage.googleapis.com"
+
 	// s3AccelerateSubstr S3 acceleration is enabled if the S3 host is contains this substring.
 	// See http://docs.aws.amazon.com/AmazonS3/latest/dev/transfer-acceleration.html
 	s3AccelerateSubstr = "s3-accelerate"
@@ -64,6 +68,8 @@ func MinioCredentialsProvider(scheme string, requestCreds credentials.Value) cre
 	switch scheme {
 	case "s3":
 		providers = append(providers, &credentials.EnvAWS{}, &credentials.IAM{Client: &http.Client{}})
+	case "gs":
+		providers = append(providers, &credentials.EnvAWS{})
 	default:
 		providers = append(providers, &credentials.EnvMinio{})
 	}
@@ -97,6 +103,11 @@ func NewMinioClient(uri *url.URL, creds *MinioCredentials) (*MinioClient, error)
 		if !strings.Contains(uri.Host, ".") {
 			uri.Host = defaultEndpointS3
 		}
+	case "gs":
+		// gs:///bucket/folder
+		if !strings.Contains(uri.Host, ".") {
+			uri.Host = defaultEndpointGCS
+		}
 	default: // minio
 		if uri.Host == "" {
 			return nil, errors.Errorf("Minio handler requires a host")
@@ -0,0 +1,187 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// ProfilingOptions are the options of the profiles captured periodically and pushed to a
+// continuous profiling server, like Pyroscope, through its /ingest HTTP API.
+type ProfilingOptions struct {
+	URL       string
+	App       string
+	AuthToken string
+	Interval  time.Duration
+	// CPUDuration is how long the CPU is profiled at each interval.
+	CPUDuration time.Duration
+	// Types are the profiles captured: cpu, or any of the profiles of runtime/pprof.
+	Types []string
+	// Labels returns the labels attached to the profiles, like the node and its group.
+	Labels func() map[string]string
+}
+
+// ParseProfilingOptions parses the --profiling superflag. It returns nil if no url is given. The
+// name of the application defaults to service.
+func ParseProfilingOptions(flag, service string) (*ProfilingOptions, error) {
+	sf := z.NewSuperFlag(flag).MergeAndCheckDefault(ProfilingDefaults)
+	opts := &ProfilingOptions{
+		URL:         strings.TrimSuffix(sf.GetString("url"), "/"),
+		App:         sf.GetString("app"),
+		AuthToken:   sf.GetString("auth-token"),
+		Interval:    sf.GetDuration("interval"),
+		CPUDuration: sf.GetDuration("cpu-duration"),
+	}
+	if opts.URL == "" {
+		return nil, nil
+	}
+	if opts.App == "" {
+		opts.App = service
+	}
+	for _, typ := range strings.Split(sf.GetString("types"), ",") {
+		if typ = strings.TrimSpace(typ); typ == "" {
+			continue
+		}
+		if typ != "cpu" && pprof.Lookup(typ) == nil {
+			return nil, errors.Errorf("invalid profile type %q, it should be cpu or one of the "+
+				"runtime/pprof profiles, like heap or goroutine", typ)
+		}
+		opts.Types = append(opts.Types, typ)
+	}
+	if len(opts.Types) == 0 {
+		return nil, errors.Errorf("no profile types given to --profiling")
+	}
+	if opts.CPUDuration <= 0 || opts.Interval <= opts.CPUDuration {
+		return nil, errors.Errorf("--profiling cpu-duration %s should be positive and shorter "+
+			"than the interval %s", opts.CPUDuration, opts.Interval)
+	}
+	return opts, nil
+}
+
+// PushProfiles captures the profiles at each interval, and pushes them to the continuous profiling
+// server, until the closer is signalled.
+func PushProfiles(opts *ProfilingOptions, closer *z.Closer) {
+	defer closer.Done()
+
+	glog.Infof("Pushing the %s profiles to %s every %s", strings.Join(opts.Types, ","),
+		opts.URL, opts.Interval)
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	client := &http.Client{Timeout: time.Minute}
+	for {
+		select {
+		case <-ticker.C:
+			for _, err := range pushProfiles(closer.Ctx(), client, opts) {
+				glog.Warningf("While pushing the profiles: %v", err)
+			}
+		case <-closer.HasBeenClosed():
+			return
+		}
+	}
+}
+
+// pushProfiles captures and pushes each of the profiles, returning their errors.
+func pushProfiles(ctx context.Context, client *http.Client, opts *ProfilingOptions) []error {
+	var labels map[string]string
+	if opts.Labels != nil {
+		labels = opts.Labels()
+	}
+	var errs []error
+	for _, typ := range opts.Types {
+		var buf bytes.Buffer
+		until := time.Now()
+		from := until
+		if typ == "cpu" {
+			if err := pprof.StartCPUProfile(&buf); err != nil {
+				// The CPU is already profiled, probably through /debug/pprof/profile.
+				errs = append(errs, errors.Wrap(err, "while profiling the cpu"))
+				continue
+			}
+			select {
+			case <-time.After(opts.CPUDuration):
+			case <-ctx.Done():
+			}
+			pprof.StopCPUProfile()
+			until = time.Now()
+		} else if err := pprof.Lookup(typ).WriteTo(&buf, 0); err != nil {
+			errs = append(errs, errors.Wrapf(err, "while capturing the %s profile", typ))
+			continue
+		}
+		if err := pushProfile(ctx, client, opts, labels, buf.Bytes(), from, until); err != nil {
+			errs = append(errs, errors.Wrapf(err, "while pushing the %s profile", typ))
+		}
+	}
+	return errs
+}
+
+// pushProfile pushes the pprof profile to the /ingest API, under the name of the application with
+// the labels, e.g. dgraph.alpha{group=1,node=2}.
+func pushProfile(ctx context.Context, client *http.Client, opts *ProfilingOptions,
+	labels map[string]string, profile []byte, from, until time.Time) error {
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(profile); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("name", opts.App+"{"+strings.Join(pairs, ",")+"}")
+	q.Set("from", strconv.FormatInt(from.Unix(), 10))
+	q.Set("until", strconv.FormatInt(until.Unix(), 10))
+	q.Set("spyName", "gospy")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		opts.URL+"/ingest?"+q.Encode(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if opts.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.AuthToken)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return errors.Errorf("got status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProfilingOptions(t *testing.T) {
+	opts, err := ParseProfilingOptions("", "dgraph.alpha")
+	require.NoError(t, err)
+	require.Nil(t, opts)
+
+	opts, err = ParseProfilingOptions("url=http://pyroscope:4040/; types=heap, goroutine",
+		"dgraph.alpha")
+	require.NoError(t, err)
+	require.Equal(t, "http://pyroscope:4040", opts.URL)
+	require.Equal(t, "dgraph.alpha", opts.App)
+	require.Equal(t, []string{"heap", "goroutine"}, opts.Types)
+	require.Equal(t, time.Minute, opts.Interval)
+
+	_, err = ParseProfilingOptions("url=http://pyroscope:4040; types=cpu,disk", "dgraph.alpha")
+	require.ErrorContains(t, err, `invalid profile type "disk"`)
+	_, err = ParseProfilingOptions("url=http://pyroscope:4040; interval=5s", "dgraph.alpha")
+	require.ErrorContains(t, err, "shorter than the interval")
+}
+
+func TestPushProfiles(t *testing.T) {
+	var mu sync.Mutex
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ingest" {
+			http.NotFound(w, r)
+			return
+		}
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		f, _, err := r.FormFile("profile")
+		require.NoError(t, err)
+		profile, err := io.ReadAll(f)
+		require.NoError(t, err)
+		// The profiles are gzipped protobufs.
+		require.Equal(t, []byte{0x1f, 0x8b}, profile[:2])
+
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, r.URL.Query().Get("name"))
+	}))
+	defer server.Close()
+
+	opts := &ProfilingOptions{
+		URL:         server.URL,
+		App:         "dgraph.alpha",
+		AuthToken:   "secret",
+		CPUDuration: 10 * time.Millisecond,
+		Types:       []string{"cpu", "heap"},
+		Labels: func() map[string]string {
+			return map[string]string{"node": "2", "group": "1"}
+		},
+	}
+	require.Empty(t, pushProfiles(context.Background(), server.Client(), opts))
+	require.Equal(t, []string{"dgraph.alpha{group=1,node=2}", "dgraph.alpha{group=1,node=2}"},
+		names)
+
+	opts.URL = server.URL + "/missing"
+	opts.Types = []string{"goroutine"}
+	errs := pushProfiles(context.Background(), server.Client(), opts)
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "404")
+}
@@ -0,0 +1,114 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// QueryCaptureFile is the name of the file the queries are captured to, in the capture directory.
+// The rotated files are named after it, with the time of their rotation.
+const QueryCaptureFile = "query_capture.jsonl"
+
+// CapturedQuery is a DQL query captured by an Alpha with --capture, to be replayed against another
+// cluster by dgraph replay. The files hold one captured query per line, as JSON.
+type CapturedQuery struct {
+	Time       time.Time         `json:"time"`
+	Namespace  uint64            `json:"namespace"`
+	Query      string            `json:"query"`
+	Vars       map[string]string `json:"vars,omitempty"`
+	ReadOnly   bool              `json:"read_only,omitempty"`
+	BestEffort bool              `json:"best_effort,omitempty"`
+	StartTs    uint64            `json:"start_ts"`
+	Latency    time.Duration     `json:"latency_ns"`
+	// ResultHash is the hash of the JSON result of the query, see ResultHash.
+	ResultHash string `json:"result_hash"`
+}
+
+type queryCapture struct {
+	w      *LogWriter
+	sample float64
+}
+
+var capture atomic.Pointer[queryCapture]
+
+// InitQueryCapture starts capturing the ratio sample of the DQL queries to the files of dir,
+// rotated once they reach sizeMb and deleted after days.
+func InitQueryCapture(dir string, sample float64, sizeMb, days int64) error {
+	if sample <= 0 || sample > 1 {
+		return errors.Errorf("invalid --capture sample %v, it should be in (0, 1]", sample)
+	}
+	w, err := (&LogWriter{
+		FilePath: filepath.Join(dir, QueryCaptureFile),
+		MaxSize:  sizeMb,
+		MaxAge:   days,
+	}).Init()
+	if err != nil {
+		return errors.Wrap(err, "while opening the query capture file")
+	}
+	capture.Store(&queryCapture{w: w, sample: sample})
+	glog.Infof("Capturing %v of the queries to %s", sample, dir)
+	return nil
+}
+
+// SampleQueryCapture returns true if the query should be captured, which it picks at random
+// according to the sample of the capture.
+func SampleQueryCapture() bool {
+	c := capture.Load()
+	return c != nil && rand.Float64() < c.sample
+}
+
+// CaptureQuery writes the query to the capture files.
+func CaptureQuery(q *CapturedQuery) {
+	c := capture.Load()
+	if c == nil {
+		return
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		glog.Errorf("While capturing a query: %v", err)
+		return
+	}
+	if _, err := c.w.Write(append(b, '\n')); err != nil {
+		glog.Errorf("While capturing a query: %v", err)
+	}
+}
+
+// CloseQueryCapture stops capturing the queries, and flushes the captured ones.
+func CloseQueryCapture() {
+	if c := capture.Swap(nil); c != nil {
+		if err := c.w.Close(); err != nil {
+			glog.Errorf("While closing the query capture file: %v", err)
+		}
+	}
+}
+
+// ResultHash returns the hash of the JSON result of a query, with the keys of its objects sorted
+// so that it doesn't depend on their order.
+func ResultHash(result []byte) (string, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(result))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", errors.Wrap(err, "while hashing the result")
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.Wrap(err, "while hashing the result")
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,69 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultHash(t *testing.T) {
+	h1, err := ResultHash([]byte(`{"q":[{"name":"Alice","age":30.0}]}`))
+	require.NoError(t, err)
+	h2, err := ResultHash([]byte(`{"q": [{"age": 30.0, "name": "Alice"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, h1, h2)
+
+	h3, err := ResultHash([]byte(`{"q":[{"name":"Alice","age":30}]}`))
+	require.NoError(t, err)
+	require.NotEqual(t, h1, h3)
+
+	_, err = ResultHash([]byte(`{"q":`))
+	require.Error(t, err)
+}
+
+func TestQueryCapture(t *testing.T) {
+	require.False(t, SampleQueryCapture())
+	dir := t.TempDir()
+	require.Error(t, InitQueryCapture(dir, 0, 1, 1))
+	require.NoError(t, InitQueryCapture(dir, 1, 1, 1))
+	require.True(t, SampleQueryCapture())
+
+	q := &CapturedQuery{
+		Time:       time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC),
+		Namespace:  2,
+		Query:      `query q($name: string) { q(func: eq(name, $name)) { uid } }`,
+		Vars:       map[string]string{"$name": "Alice"},
+		ReadOnly:   true,
+		StartTs:    10,
+		Latency:    3 * time.Millisecond,
+		ResultHash: "abc",
+	}
+	CaptureQuery(q)
+	CaptureQuery(q)
+	CloseQueryCapture()
+	require.False(t, SampleQueryCapture())
+
+	f, err := os.Open(filepath.Join(dir, QueryCaptureFile))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+	scanner := bufio.NewScanner(f)
+	var lines int
+	for scanner.Scan() {
+		var got CapturedQuery
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &got))
+		require.Equal(t, *q, got)
+		lines++
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, 2, lines)
+}
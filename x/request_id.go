@@ -0,0 +1,118 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/golang/glog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// RequestIdHeader is the HTTP header carrying the id of a request, sent by the client or
+	// generated by the Alpha, and returned in the response.
+	RequestIdHeader = "X-Request-Id"
+	// requestIdMetadata is the gRPC metadata carrying the id of a request, from the client to the
+	// Alpha, between the Alphas and Zeros, and back to the client in the response header.
+	requestIdMetadata = "x-request-id"
+)
+
+type requestIdKey struct{}
+
+// WithRequestId returns the context with the id of its request, which is the one sent by the
+// client or received from another node if any, and a new one otherwise.
+func WithRequestId(ctx context.Context) (context.Context, string) {
+	if id := RequestId(ctx); id != "" {
+		return context.WithValue(ctx, requestIdKey{}, id), id
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// The id is only used to correlate the logs and traces of the request.
+		glog.Warningf("Unable to generate a request id: %v", err)
+		return ctx, ""
+	}
+	id := hex.EncodeToString(b[:])
+	return context.WithValue(ctx, requestIdKey{}, id), id
+}
+
+// RequestId returns the id of the request of the context, or an empty string if it has none.
+func RequestId(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIdKey{}).(string); ok {
+		return id
+	}
+	return ExtractRequestId(ctx)
+}
+
+// RequestLogPrefix returns the prefix of the log lines about the request of the context, so that
+// they can be correlated across the Alphas and Zeros.
+func RequestLogPrefix(ctx context.Context) string {
+	if id := RequestId(ctx); id != "" {
+		return "[req " + id + "] "
+	}
+	return ""
+}
+
+// AnnotateRequestId adds the id of the request of the context to its current span.
+func AnnotateRequestId(ctx context.Context) {
+	if id := RequestId(ctx); id != "" {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request_id", id))
+	}
+}
+
+// withOutgoingRequestId adds the id of the request of the context to its outgoing metadata.
+func withOutgoingRequestId(ctx context.Context) context.Context {
+	id := RequestId(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(requestIdMetadata)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, requestIdMetadata, id)
+}
+
+// RequestIdUnaryClientInterceptor sends the id of the request of the context of the calls to the
+// other nodes.
+func RequestIdUnaryClientInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+	return invoker(withOutgoingRequestId(ctx), method, req, reply, cc, opts...)
+}
+
+// RequestIdStreamClientInterceptor is the same as RequestIdUnaryClientInterceptor for the
+// streaming calls.
+func RequestIdStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc,
+	cc *grpc.ClientConn, method string, streamer grpc.Streamer,
+	opts ...grpc.CallOption) (grpc.ClientStream, error) {
+
+	return streamer(withOutgoingRequestId(ctx), desc, cc, method, opts...)
+}
+
+// RequestIdUnaryServerInterceptor annotates the span of the calls from the other nodes with the
+// id of their request, and logs them with it at verbosity 2.
+func RequestIdUnaryServerInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	if RequestId(ctx) == "" {
+		return handler(ctx, req)
+	}
+	ctx, _ = WithRequestId(ctx)
+	AnnotateRequestId(ctx)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	if glog.V(2) {
+		glog.Infof("%s%s took %v, error: %v", RequestLogPrefix(ctx), info.FullMethod,
+			time.Since(start).Round(time.Microsecond), err)
+	}
+	return resp, err
+}
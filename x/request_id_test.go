@@ -0,0 +1,60 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestId(t *testing.T) {
+	// A request without an id gets a new one, kept for the rest of the request.
+	ctx, id := WithRequestId(context.Background())
+	require.Len(t, id, 16)
+	require.Equal(t, id, RequestId(ctx))
+	_, again := WithRequestId(ctx)
+	require.Equal(t, id, again)
+	require.Equal(t, "[req "+id+"] ", RequestLogPrefix(ctx))
+	require.Empty(t, RequestLogPrefix(context.Background()))
+
+	// The id sent by the client is kept.
+	r := httptest.NewRequest("POST", "/query", nil)
+	r.Header.Set(RequestIdHeader, "client-id")
+	ctx, id = WithRequestId(AttachRequestId(context.Background(), r))
+	require.Equal(t, "client-id", id)
+
+	// It is sent to the other nodes, which see it in their incoming metadata.
+	var sent metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	require.NoError(t, RequestIdUnaryClientInterceptor(ctx, "/pb.Worker/ServeTask", nil, nil,
+		nil, invoker))
+	require.Equal(t, []string{"client-id"}, sent.Get(requestIdMetadata))
+
+	var received string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		received = RequestId(ctx)
+		return nil, nil
+	}
+	incoming := metadata.NewIncomingContext(context.Background(), sent)
+	_, err := RequestIdUnaryServerInterceptor(incoming, nil,
+		&grpc.UnaryServerInfo{FullMethod: "/pb.Worker/ServeTask"}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "client-id", received)
+
+	// The calls without a request aren't changed.
+	require.NoError(t, RequestIdUnaryClientInterceptor(context.Background(), "/pb.Raft/Echo",
+		nil, nil, nil, invoker))
+	require.Empty(t, sent.Get(requestIdMetadata))
+}
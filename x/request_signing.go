@@ -0,0 +1,220 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+)
+
+const (
+	flagSigningKeyFile  = "signing-key-file"
+	flagSignedEndpoints = "signed-endpoints"
+	flagSignatureMaxAge = "signature-max-age"
+	flagSignedBodyMaxMB = "signed-body-max-mb"
+
+	// SignatureHeader, TimestampHeader and NonceHeader carry the signature of the signed HTTP
+	// requests, the Unix time at which they were signed and their nonce.
+	SignatureHeader = "X-Dgraph-Signature"
+	TimestampHeader = "X-Dgraph-Timestamp"
+	NonceHeader     = "X-Dgraph-Nonce"
+
+	minSigningKeyLen = 32
+	maxNonceLen      = 128
+	// maxSignedNonces is the number of nonces tracked, after which the signed requests are
+	// rejected until the oldest nonces expire.
+	maxSignedNonces = 1 << 20
+)
+
+// RequestSigner verifies the HMAC-SHA256 signatures of the HTTP requests to the signed endpoints.
+// The signature covers the method, host, URI, timestamp, nonce and body of the request. The
+// requests are rejected if they were signed more than the max age ago, or if their nonce has
+// already been used, so that captured requests can't be replayed.
+type RequestSigner struct {
+	Key Sensitive
+	// Endpoints are the paths of the signed endpoints, along with the paths below them.
+	Endpoints []string
+	MaxAge    time.Duration
+	// MaxBody is the maximum size of the body of the signed requests, which is read before the
+	// handler to verify its signature.
+	MaxBody int64
+
+	sync.Mutex
+	// nonces are the nonces used, along with the time until which they are tracked.
+	nonces map[string]time.Time
+	pruned time.Time
+}
+
+// RequestSignerFromFlag returns the request signer configured in the security superflag, or nil
+// if there is no signing key.
+func RequestSignerFromFlag(flag *z.SuperFlag) (*RequestSigner, error) {
+	file := flag.GetPath(flagSigningKeyFile)
+	if file == "" {
+		return nil, nil
+	}
+	key, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "security: while reading the signing key")
+	}
+	key = bytes.TrimSpace(key)
+	if len(key) < minSigningKeyLen {
+		return nil, errors.Errorf("security: the signing key must be at least %d bytes long",
+			minSigningKeyLen)
+	}
+	s := &RequestSigner{
+		Key:    Sensitive(key),
+		MaxAge: flag.GetDuration(flagSignatureMaxAge),
+		nonces: make(map[string]time.Time),
+	}
+	if s.MaxAge <= 0 {
+		return nil, errors.Errorf("security: %s must be positive", flagSignatureMaxAge)
+	}
+	if s.MaxBody = flag.GetInt64(flagSignedBodyMaxMB) << 20; s.MaxBody <= 0 {
+		return nil, errors.Errorf("security: %s must be positive", flagSignedBodyMaxMB)
+	}
+	for _, endpoint := range strings.Split(flag.GetString(flagSignedEndpoints), ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint == "" {
+			continue
+		}
+		if !strings.HasPrefix(endpoint, "/") {
+			return nil, errors.Errorf("security: invalid signed endpoint %q, it must be a path",
+				endpoint)
+		}
+		s.Endpoints = append(s.Endpoints, strings.TrimSuffix(endpoint, "/"))
+	}
+	return s, nil
+}
+
+// IsSigned returns true if the requests to the path must be signed.
+func (s *RequestSigner) IsSigned(path string) bool {
+	for _, endpoint := range s.Endpoints {
+		if endpoint == "" || path == endpoint || strings.HasPrefix(path, endpoint+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify verifies the signature of the request, and records its nonce. The headers are checked
+// first, so that the body is only read for the requests signed recently with a new nonce. The
+// body is then read up to the max body size, and replaced so that it can be read again.
+func (s *RequestSigner) Verify(w http.ResponseWriter, r *http.Request) error {
+	signature, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return errors.Errorf("the request must be signed in the %s header", SignatureHeader)
+	}
+	nonce := r.Header.Get(NonceHeader)
+	if nonce == "" || len(nonce) > maxNonceLen {
+		return errors.Errorf("the %s header must be set, with at most %d characters",
+			NonceHeader, maxNonceLen)
+	}
+	ts, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return errors.Errorf("the %s header must be set to the Unix time of the request",
+			TimestampHeader)
+	}
+	now := time.Now()
+	signedAt := time.Unix(ts, 0)
+	if signedAt.Before(now.Add(-s.MaxAge)) || signedAt.After(now.Add(s.MaxAge)) {
+		return errors.Errorf("the request was signed at %s, more than %s from now",
+			signedAt.UTC().Format(time.RFC3339), s.MaxAge)
+	}
+	if s.nonceUsed(nonce, now) {
+		return errors.Errorf("the nonce %q has already been used", nonce)
+	}
+
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, s.MaxBody)); err != nil {
+			return errors.Wrapf(err, "while reading the request body")
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	expected := requestSignature(s.Key, r, strconv.FormatInt(ts, 10), nonce, body)
+	if !hmac.Equal(signature, expected) {
+		return errors.New("invalid request signature")
+	}
+	// The nonce is tracked for as long as the timestamp is valid.
+	return s.useNonce(nonce, signedAt.Add(s.MaxAge), now)
+}
+
+func (s *RequestSigner) nonceUsed(nonce string, now time.Time) bool {
+	s.Lock()
+	defer s.Unlock()
+	exp, ok := s.nonces[nonce]
+	return ok && exp.After(now)
+}
+
+func (s *RequestSigner) useNonce(nonce string, until, now time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.nonces) >= maxSignedNonces || now.Sub(s.pruned) > s.MaxAge {
+		for n, exp := range s.nonces {
+			if !exp.After(now) {
+				delete(s.nonces, n)
+			}
+		}
+		s.pruned = now
+	}
+	if exp, ok := s.nonces[nonce]; ok && exp.After(now) {
+		return errors.Errorf("the nonce %q has already been used", nonce)
+	}
+	if len(s.nonces) >= maxSignedNonces {
+		return errors.New("too many signed requests, try again later")
+	}
+	s.nonces[nonce] = until
+	return nil
+}
+
+// SignRequest signs the request with the key, setting its signature, timestamp and nonce
+// headers. The body of the request is read, and replaced so that it can be sent.
+func SignRequest(r *http.Request, key []byte) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return errors.Wrapf(err, "while reading the request body")
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(b)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(TimestampHeader, ts)
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set(SignatureHeader, hex.EncodeToString(requestSignature(key, r, ts, nonce, body)))
+	return nil
+}
+
+// requestSignature returns the HMAC-SHA256 of the method, host, URI, timestamp, nonce and hash
+// of the body of the request, separated by new lines.
+func requestSignature(key []byte, r *http.Request, ts, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, key)
+	for _, part := range []string{r.Method, r.Host, r.URL.RequestURI(), ts, nonce} {
+		_, _ = mac.Write([]byte(part))
+		_, _ = mac.Write([]byte{'\n'})
+	}
+	_, _ = mac.Write([]byte(hex.EncodeToString(bodyHash[:])))
+	return mac.Sum(nil)
+}
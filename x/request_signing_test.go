@@ -0,0 +1,132 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/ristretto/v2/z"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func testRequestSigner(t *testing.T, endpoints string) *RequestSigner {
+	file := filepath.Join(t.TempDir(), "signing.key")
+	require.NoError(t, os.WriteFile(file, []byte(testSigningKey+"\n"), 0600))
+	flag := z.NewSuperFlag("signing-key-file=" + file + "; signed-endpoints=" + endpoints).
+		MergeAndCheckDefault("signature-max-age=5m; signed-endpoints=; signing-key-file=; " +
+			"signed-body-max-mb=1;")
+	s, err := RequestSignerFromFlag(flag)
+	require.NoError(t, err)
+	return s
+}
+
+// signedRequest returns a request to the server signed with the key, as the server receives it.
+func signedRequest(t *testing.T, key, method, target, body string) *http.Request {
+	client, err := http.NewRequest(method, "http://alpha:8080"+target, strings.NewReader(body))
+	require.NoError(t, err)
+	require.NoError(t, SignRequest(client, []byte(key)))
+	r := httptest.NewRequest(method, "http://alpha:8080"+target, strings.NewReader(body))
+	r.Header = client.Header
+	return r
+}
+
+func TestRequestSignerFromFlag(t *testing.T) {
+	s, err := RequestSignerFromFlag(z.NewSuperFlag("signing-key-file=;"))
+	require.NoError(t, err)
+	require.Nil(t, s)
+
+	s = testRequestSigner(t, "/admin/, /alter")
+	require.Equal(t, []string{"/admin", "/alter"}, s.Endpoints)
+	require.Equal(t, Sensitive(testSigningKey), s.Key)
+	require.Equal(t, int64(1<<20), s.MaxBody)
+	require.True(t, s.IsSigned("/admin"))
+	require.True(t, s.IsSigned("/admin/shutdown"))
+	require.True(t, s.IsSigned("/alter"))
+	require.False(t, s.IsSigned("/administrator"))
+	require.False(t, s.IsSigned("/query"))
+	require.True(t, testRequestSigner(t, "/").IsSigned("/query"))
+
+	file := filepath.Join(t.TempDir(), "short.key")
+	require.NoError(t, os.WriteFile(file, []byte("short"), 0600))
+	_, err = RequestSignerFromFlag(z.NewSuperFlag("signing-key-file=" + file))
+	require.ErrorContains(t, err, "at least 32 bytes")
+}
+
+func TestVerifyRequest(t *testing.T) {
+	s := testRequestSigner(t, "/admin")
+
+	r := signedRequest(t, testSigningKey, http.MethodPost, "/admin?x=1", `{"query": "{ health }"}`)
+	require.NoError(t, s.Verify(httptest.NewRecorder(), r))
+	// The body can still be read by the handler.
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"query": "{ health }"}`, string(body))
+
+	// The same request can't be replayed.
+	replayed := signedRequest(t, testSigningKey, http.MethodPost, "/admin", `{}`)
+	require.NoError(t, s.Verify(httptest.NewRecorder(), replayed))
+	replayed.Body = io.NopCloser(strings.NewReader(`{}`))
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), replayed), "has already been used")
+	require.NoError(t, s.Verify(httptest.NewRecorder(),
+		signedRequest(t, testSigningKey, http.MethodPost, "/admin", `{}`)))
+
+	tampered := signedRequest(t, testSigningKey, http.MethodPost, "/admin", `{}`)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"query": "mutation { shutdown }"}`))
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), tampered), "invalid request signature")
+	tampered = signedRequest(t, testSigningKey, http.MethodGet, "/admin/shutdown", "")
+	tampered.URL.Path = "/admin/draining"
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), tampered), "invalid request signature")
+	tampered = signedRequest(t, testSigningKey, http.MethodGet, "/admin/shutdown", "")
+	tampered.Host = "other:8080"
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), tampered), "invalid request signature")
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), signedRequest(t, strings.Repeat("k", 32),
+		http.MethodGet, "/admin/shutdown", "")), "invalid request signature")
+
+	stale := signedRequest(t, testSigningKey, http.MethodGet, "/admin/shutdown", "")
+	stale.Header.Set(TimestampHeader, strconv.FormatInt(time.Now().Add(-6*time.Minute).Unix(), 10))
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), stale), "more than 5m0s from now")
+
+	// The body of a replayed request isn't read.
+	replayed = signedRequest(t, testSigningKey, http.MethodPost, "/admin", `{}`)
+	require.NoError(t, s.Verify(httptest.NewRecorder(), replayed))
+	replayed.Body = nil
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), replayed), "has already been used")
+
+	// The body is only read up to the max body size.
+	large := signedRequest(t, testSigningKey, http.MethodPost, "/admin", strings.Repeat("a", 1<<20+1))
+	var tooLarge *http.MaxBytesError
+	require.ErrorAs(t, s.Verify(httptest.NewRecorder(), large), &tooLarge)
+
+	unsigned := httptest.NewRequest(http.MethodGet, "/admin/shutdown", nil)
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), unsigned), "must be signed")
+	unsigned.Header.Set(SignatureHeader, "00")
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), unsigned), NonceHeader)
+	unsigned.Header.Set(NonceHeader, "nonce")
+	require.ErrorContains(t, s.Verify(httptest.NewRecorder(), unsigned), TimestampHeader)
+}
+
+func TestRequestNonces(t *testing.T) {
+	s := testRequestSigner(t, "/admin")
+	now := time.Now()
+	require.NoError(t, s.useNonce("a", now.Add(time.Minute), now))
+	require.Error(t, s.useNonce("a", now.Add(time.Minute), now))
+	// The nonces are forgotten once they expire, as their requests are too old by then.
+	later := now.Add(6 * time.Minute)
+	require.NoError(t, s.useNonce("b", later.Add(time.Minute), later))
+	require.NotContains(t, s.nonces, "a")
+	require.NoError(t, s.useNonce("a", later.Add(time.Minute), later))
+}
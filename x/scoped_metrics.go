@@ -0,0 +1,136 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The per-predicate and per-namespace metrics are opt-in: only the predicates and namespaces
+// allow-listed with --metrics get their own series, so that the cardinality of the labels stays
+// under the control of the operator. They're recorded with the Prometheus client rather than
+// OpenCensus, which can't attach the trace ids of the requests to the buckets as exemplars.
+var (
+	// PredicateLatencyMs is the latency of the queries and mutations of the allow-listed
+	// predicates. Its count is their throughput.
+	PredicateLatencyMs = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "dgraph",
+		Name:      "predicate_latency_ms",
+		Help:      "Latency of the queries and mutations of a predicate",
+		Buckets:   latencyMsBuckets,
+	}, []string{"namespace", "predicate", "method"})
+	// NamespaceLatencyMs is the latency of the requests of the allow-listed namespaces. Its count
+	// is their throughput.
+	NamespaceLatencyMs = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "dgraph",
+		Name:      "namespace_latency_ms",
+		Help:      "Latency of the requests of a namespace",
+		Buckets:   latencyMsBuckets,
+	}, []string{"namespace", "method", "status"})
+
+	scopedMetrics atomic.Pointer[metricsAllowList]
+)
+
+// otherNamespace is the namespace label of the allow-listed predicates of the namespaces which
+// aren't allow-listed.
+const otherNamespace = "other"
+
+type metricsAllowList struct {
+	predicates    map[string]struct{}
+	namespaces    map[uint64]struct{}
+	allNamespaces bool
+	exemplars     bool
+}
+
+// SetScopedMetrics sets the allow-lists of the per-predicate and per-namespace metrics. predicates
+// is a comma separated list of predicate names, recorded in all the namespaces. namespaces is a
+// comma separated list of namespace ids, or * for all of them. The predicates of the namespaces
+// which aren't allow-listed are recorded with the namespace "other". The trace ids of the sampled
+// requests are attached as exemplars if exemplars is true.
+func SetScopedMetrics(predicates, namespaces string, exemplars bool) error {
+	list := &metricsAllowList{
+		predicates: make(map[string]struct{}),
+		namespaces: make(map[uint64]struct{}),
+		exemplars:  exemplars,
+	}
+	for _, pred := range strings.Split(predicates, ",") {
+		if pred = strings.TrimSpace(pred); pred != "" {
+			list.predicates[pred] = struct{}{}
+		}
+	}
+	for _, ns := range strings.Split(namespaces, ",") {
+		switch ns = strings.TrimSpace(ns); ns {
+		case "":
+		case "*":
+			list.allNamespaces = true
+		default:
+			id, err := strconv.ParseUint(ns, 0, 64)
+			if err != nil {
+				return errors.Errorf("invalid namespace %q in the metrics namespaces, it should "+
+					"be a namespace id or *", ns)
+			}
+			list.namespaces[id] = struct{}{}
+		}
+	}
+	if len(list.predicates) == 0 && len(list.namespaces) == 0 && !list.allNamespaces {
+		list = nil
+	}
+	scopedMetrics.Store(list)
+	return nil
+}
+
+func (l *metricsAllowList) hasNamespace(ns uint64) bool {
+	_, ok := l.namespaces[ns]
+	return ok || l.allNamespaces
+}
+
+func (l *metricsAllowList) observe(ctx context.Context, h prometheus.Observer, start time.Time) {
+	ms := SinceMs(start)
+	if sc := trace.SpanContextFromContext(ctx); l.exemplars && sc.IsSampled() {
+		if eo, ok := h.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(ms, prometheus.Labels{"trace_id": sc.TraceID().String()})
+			return
+		}
+	}
+	h.Observe(ms)
+}
+
+// ObservePredicate records the latency since start of the query or mutation, given by method, of
+// the namespaced attribute attr if its predicate is allow-listed.
+func ObservePredicate(ctx context.Context, attr, method string, start time.Time) {
+	list := scopedMetrics.Load()
+	if list == nil || len(list.predicates) == 0 {
+		return
+	}
+	ns, pred := ParseNamespaceAttr(attr)
+	if _, ok := list.predicates[pred]; !ok {
+		return
+	}
+	nsLabel := otherNamespace
+	if list.hasNamespace(ns) {
+		nsLabel = strconv.FormatUint(ns, 10)
+	}
+	list.observe(ctx, PredicateLatencyMs.WithLabelValues(nsLabel, pred, method), start)
+}
+
+// ObserveNamespace records the latency since start of the request, given by method, of the
+// namespace ns if it's allow-listed.
+func ObserveNamespace(ctx context.Context, ns uint64, method, status string, start time.Time) {
+	list := scopedMetrics.Load()
+	if list == nil || !list.hasNamespace(ns) {
+		return
+	}
+	list.observe(ctx, NamespaceLatencyMs.WithLabelValues(strconv.FormatUint(ns, 10), method,
+		status), start)
+}
@@ -0,0 +1,86 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestScopedMetrics(t *testing.T) {
+	defer func() {
+		require.NoError(t, SetScopedMetrics("", "", true))
+		PredicateLatencyMs.Reset()
+		NamespaceLatencyMs.Reset()
+	}()
+	require.Error(t, SetScopedMetrics("name", "galaxy", true))
+	require.NoError(t, SetScopedMetrics("name, age", "0x2", true))
+
+	traceId := trace.TraceID{1, 2, 3}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(
+		trace.SpanContextConfig{TraceID: traceId, SpanID: trace.SpanID{1},
+			TraceFlags: trace.FlagsSampled}))
+	start := time.Now()
+	ObservePredicate(ctx, NamespaceAttr(2, "name"), "query", start)
+	ObservePredicate(ctx, NamespaceAttr(3, "name"), "mutation", start)
+	ObservePredicate(ctx, NamespaceAttr(2, "friend"), "query", start)
+	ObserveNamespace(context.Background(), 2, "query", TagValueStatusOK, start)
+	ObserveNamespace(context.Background(), 3, "query", TagValueStatusOK, start)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(PredicateLatencyMs, NamespaceLatencyMs)
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	series := make(map[string][]*dto.Metric)
+	for _, f := range families {
+		series[f.GetName()] = f.GetMetric()
+	}
+	labels := func(m *dto.Metric) map[string]string {
+		res := make(map[string]string)
+		for _, l := range m.GetLabel() {
+			res[l.GetName()] = l.GetValue()
+		}
+		return res
+	}
+
+	preds := series["dgraph_predicate_latency_ms"]
+	require.Len(t, preds, 2)
+	require.Equal(t, map[string]string{"namespace": "other", "predicate": "name",
+		"method": "mutation"}, labels(preds[0]))
+	require.Equal(t, map[string]string{"namespace": "2", "predicate": "name",
+		"method": "query"}, labels(preds[1]))
+	var exemplar *dto.Exemplar
+	for _, b := range preds[1].GetHistogram().GetBucket() {
+		if b.GetExemplar() != nil {
+			exemplar = b.GetExemplar()
+		}
+	}
+	require.NotNil(t, exemplar)
+	require.Equal(t, traceId.String(), exemplar.GetLabel()[0].GetValue())
+
+	nss := series["dgraph_namespace_latency_ms"]
+	require.Len(t, nss, 1)
+	require.Equal(t, map[string]string{"namespace": "2", "method": "query", "status": "ok"},
+		labels(nss[0]))
+	require.Equal(t, uint64(1), nss[0].GetHistogram().GetSampleCount())
+
+	// Nothing is recorded without allow-list.
+	require.NoError(t, SetScopedMetrics("", "", true))
+	ObserveNamespace(context.Background(), 3, "query", TagValueStatusOK, start)
+	families, err = reg.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() == "dgraph_namespace_latency_ms" {
+			require.Len(t, f.GetMetric(), 1)
+		}
+	}
+}
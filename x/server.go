@@ -27,17 +27,20 @@ var (
 	ServerCloser = z.NewCloser(0)
 )
 
-func StartListenHttpAndHttps(l net.Listener, tlsCfg *tls.Config, closer *z.Closer) {
+// StartListenHttpAndHttps serves the default mux over HTTP and HTTPS. If wrap is not nil, it
+// wraps the handler of the HTTPS server.
+func StartListenHttpAndHttps(l net.Listener, tlsCfg *tls.Config,
+	wrap func(http.Handler) http.Handler, closer *z.Closer) {
 	defer closer.Done()
 	m := cmux.New(l)
-	startServers(m, tlsCfg)
+	startServers(m, tlsCfg, wrap)
 	err := m.Serve()
 	if err != nil {
 		glog.Errorf("error from cmux serve: %v", err)
 	}
 }
 
-func startServers(m cmux.CMux, tlsConf *tls.Config) {
+func startServers(m cmux.CMux, tlsConf *tls.Config, wrap func(http.Handler) http.Handler) {
 	httpRule := m.Match(func(r io.Reader) bool {
 		// no tls config is provided. http is being used.
 		if tlsConf == nil {
@@ -53,19 +56,24 @@ func startServers(m cmux.CMux, tlsConf *tls.Config) {
 		// monitoring tools which operate without authentication.
 		return strings.HasPrefix(path, "/health")
 	})
-	go startListen(httpRule)
+	go startListen(httpRule, nil)
 
 	// if tls is enabled, make tls encryption based connections as default
 	if tlsConf != nil {
 		httpsRule := m.Match(cmux.Any())
 		// this is chained listener. tls listener will decrypt
 		// the message and send it in plain text to HTTP server
-		go startListen(tls.NewListener(httpsRule, tlsConf))
+		var handler http.Handler
+		if wrap != nil {
+			handler = wrap(http.DefaultServeMux)
+		}
+		go startListen(tls.NewListener(httpsRule, tlsConf), handler)
 	}
 }
 
-func startListen(l net.Listener) {
+func startListen(l net.Listener, handler http.Handler) {
 	srv := &http.Server{
+		Handler:           handler,
 		ReadTimeout:       10 * time.Second,
 		WriteTimeout:      600 * time.Second,
 		IdleTimeout:       2 * time.Minute,
@@ -8,6 +8,7 @@ package x
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"net/http"
 	"os"
 	"strings"
 
@@ -27,14 +28,19 @@ type TLSHelperConfig struct {
 	RootCACert       string
 	ClientAuth       string
 	UseSystemCACerts bool
+	// SpiffeIDs are the SPIFFE IDs allowed for the peer. If set, the peer must present an
+	// X.509 SVID with one of them.
+	SpiffeIDs []string
 }
 
 const (
 	TLSDefaults = `use-system-ca=true; client-auth-type=VERIFYIFGIVEN; internal-port=false; ` +
-		`ca-cert=; server-name=; server-cert=; server-key=; client-cert=; client-key=;`
+		`ca-cert=; server-name=; server-cert=; server-key=; client-cert=; client-key=; ` +
+		`grpc-client-auth-type=; http-client-auth-type=; http-client-auth-exempt=; spiffe-ids=;`
 
 	TLSServerDefaults = `use-system-ca=true; client-auth-type=VERIFYIFGIVEN; internal-port=false; ` +
-		`server-cert=; server-key=; ca-cert=; client-cert=; client-key=;`
+		`server-cert=; server-key=; ca-cert=; client-cert=; client-key=; ` +
+		`grpc-client-auth-type=; http-client-auth-type=; http-client-auth-exempt=; spiffe-ids=;`
 
 	TLSClientDefaults = `use-system-ca=true; internal-port=false; server-name=; ca-cert=; ` +
 		`client-cert=; client-key=;`
@@ -44,7 +50,8 @@ const (
 func RegisterServerTLSFlags(flag *pflag.FlagSet) {
 	flag.String("tls", "use-system-ca=true; client-auth-type=VERIFYIFGIVEN; internal-port=false;",
 		z.NewSuperFlagHelp(TLSServerDefaults).
-			Head("TLS Server options").
+			Head("TLS Server options. The server and client certificates are reloaded when "+
+				"their files change.").
 			Flag("internal-port",
 				"(Optional) Enable inter-node TLS encryption between cluster nodes.").
 			Flag("server-cert",
@@ -57,12 +64,25 @@ func RegisterServerTLSFlags(flag *pflag.FlagSet) {
 				"Includes System CA into CA Certs.").
 			Flag("client-auth-type",
 				"The TLS client authentication method.").
+			Flag("grpc-client-auth-type",
+				"(Optional) The TLS client authentication method of the gRPC endpoint. Defaults to "+
+					"client-auth-type.").
+			Flag("http-client-auth-type",
+				"(Optional) The TLS client authentication method of the HTTP endpoint. Defaults to "+
+					"client-auth-type.").
+			Flag("http-client-auth-exempt",
+				"(Optional) Comma separated list of HTTP paths which can be accessed without a client "+
+					"certificate, when the HTTP endpoint requires one, e.g. /health,/probe/graphql.").
 			Flag("client-cert",
 				"(Optional) The client Cert file which is needed to connect as a client with the other "+
 					"nodes in the cluster.").
 			Flag("client-key",
 				"(Optional) The private client Key file which is needed to connect as a client with the "+
 					"other nodes in the cluster.").
+			Flag("spiffe-ids",
+				"(Optional) Comma separated list of the SPIFFE IDs allowed for inter-node TLS, e.g. "+
+					"spiffe://example.org/dgraph/*. If set, the nodes must present X.509 SVIDs with one "+
+					"of these IDs.").
 			String())
 }
 
@@ -99,12 +119,17 @@ func LoadClientTLSConfigForInternalPort(v *viper.Viper) (*tls.Config, error) {
 			`"client-cert=...; client-key=...;"`)
 	}
 
+	spiffeIDs, err := parseSpiffeIDs(tlsFlag.GetString("spiffe-ids"))
+	if err != nil {
+		return nil, err
+	}
 	conf := &TLSHelperConfig{}
 	conf.UseSystemCACerts = tlsFlag.GetBool("use-system-ca")
 	conf.RootCACert = tlsFlag.GetPath("ca-cert")
 	conf.CertRequired = true
 	conf.Cert = tlsFlag.GetPath("client-cert")
 	conf.Key = tlsFlag.GetPath("client-key")
+	conf.SpiffeIDs = spiffeIDs
 	return GenerateClientTLSConfig(conf)
 }
 
@@ -119,6 +144,10 @@ func LoadServerTLSConfigForInternalPort(v *viper.Viper) (*tls.Config, error) {
 		return nil, errors.Errorf(`Inter-node TLS is enabled but server node certs are not provided. ` +
 			`Please provide --tls "server-cert=...; server-key=...;"`)
 	}
+	spiffeIDs, err := parseSpiffeIDs(tlsFlag.GetString("spiffe-ids"))
+	if err != nil {
+		return nil, err
+	}
 	conf := TLSHelperConfig{}
 	conf.UseSystemCACerts = tlsFlag.GetBool("use-system-ca")
 	conf.RootCACert = tlsFlag.GetPath("ca-cert")
@@ -126,11 +155,59 @@ func LoadServerTLSConfigForInternalPort(v *viper.Viper) (*tls.Config, error) {
 	conf.Cert = tlsFlag.GetPath("server-cert")
 	conf.Key = tlsFlag.GetPath("server-key")
 	conf.ClientAuth = "REQUIREANDVERIFY"
+	conf.SpiffeIDs = spiffeIDs
 	return GenerateServerTLSConfig(&conf)
 }
 
 // LoadServerTLSConfig loads the TLS config into the server with the given parameters.
 func LoadServerTLSConfig(v *viper.Viper) (*tls.Config, error) {
+	return loadServerTLSConfig(v, "client-auth-type")
+}
+
+// LoadGrpcServerTLSConfig loads the TLS config of the gRPC endpoint, which uses
+// grpc-client-auth-type instead of client-auth-type if set.
+func LoadGrpcServerTLSConfig(v *viper.Viper) (*tls.Config, error) {
+	return loadServerTLSConfig(v, "grpc-client-auth-type")
+}
+
+// LoadHttpServerTLSConfig loads the TLS config of the HTTP endpoint, which uses
+// http-client-auth-type instead of client-auth-type if set. It also returns the handler that
+// wraps the HTTPS handlers to require client certificates outside of the exempt paths.
+func LoadHttpServerTLSConfig(v *viper.Viper) (*tls.Config, func(http.Handler) http.Handler,
+	error) {
+
+	tlsCfg, err := loadServerTLSConfig(v, "http-client-auth-type")
+	if tlsCfg == nil || err != nil {
+		return tlsCfg, nil, err
+	}
+	tlsFlag := z.NewSuperFlag(v.GetString("tls")).MergeAndCheckDefault(TLSDefaults)
+	var exempt []string
+	for _, path := range strings.Split(tlsFlag.GetString("http-client-auth-exempt"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			exempt = append(exempt, path)
+		}
+	}
+	if len(exempt) == 0 {
+		return tlsCfg, nil, nil
+	}
+	// The exempt paths are only known after the handshake, so the handshake must accept
+	// connections without certificates, and the handler rejects them on the other paths.
+	switch tlsCfg.ClientAuth {
+	case tls.RequireAndVerifyClientCert:
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	case tls.RequireAnyClientCert:
+		tlsCfg.ClientAuth = tls.RequestClientCert
+	default:
+		return tlsCfg, nil, nil
+	}
+	return tlsCfg, func(next http.Handler) http.Handler {
+		return requireClientCert(exempt, next)
+	}, nil
+}
+
+// loadServerTLSConfig loads the server TLS config, using the client auth type in the given
+// option, or in client-auth-type if it isn't set.
+func loadServerTLSConfig(v *viper.Viper, authOption string) (*tls.Config, error) {
 	tlsFlag := z.NewSuperFlag(v.GetString("tls")).MergeAndCheckDefault(TLSDefaults)
 
 	if tlsFlag.GetPath("server-cert") == "" && tlsFlag.GetPath("server-key") == "" {
@@ -142,7 +219,10 @@ func LoadServerTLSConfig(v *viper.Viper) (*tls.Config, error) {
 	conf.CertRequired = true
 	conf.Cert = tlsFlag.GetPath("server-cert")
 	conf.Key = tlsFlag.GetPath("server-key")
-	conf.ClientAuth = tlsFlag.GetString("client-auth-type")
+	conf.ClientAuth = tlsFlag.GetString(authOption)
+	if conf.ClientAuth == "" {
+		conf.ClientAuth = tlsFlag.GetString("client-auth-type")
+	}
 	conf.UseSystemCACerts = tlsFlag.GetBool("use-system-ca")
 	return GenerateServerTLSConfig(&conf)
 }
@@ -284,11 +364,14 @@ func TLSBaseConfig() *tls.Config {
 func GenerateServerTLSConfig(config *TLSHelperConfig) (tlsCfg *tls.Config, err error) {
 	if config.CertRequired {
 		tlsCfg = TLSBaseConfig()
-		cert, err := tls.LoadX509KeyPair(config.Cert, config.Key)
+		reloader, err := newCertReloader(config.Cert, config.Key)
 		if err != nil {
 			return nil, err
 		}
-		tlsCfg.Certificates = []tls.Certificate{cert}
+		tlsCfg.GetCertificate = reloader.getCertificate
+		if len(config.SpiffeIDs) > 0 {
+			tlsCfg.VerifyConnection = verifySpiffeID(config.SpiffeIDs)
+		}
 
 		pool, err := generateCertPool(config.RootCACert, config.UseSystemCACerts)
 		if err != nil {
@@ -328,11 +411,16 @@ func GenerateClientTLSConfig(config *TLSHelperConfig) (tlsCfg *tls.Config, err e
 		certFile := config.Cert
 		keyFile := config.Key
 		if certFile != "" && keyFile != "" {
-			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			reloader, err := newCertReloader(certFile, keyFile)
 			if err != nil {
 				return nil, err
 			}
-			tlsCfg.Certificates = []tls.Certificate{cert}
+			tlsCfg.GetClientCertificate = reloader.getClientCertificate
+		}
+
+		// 4. optionally verify the SPIFFE ID of the server
+		if len(config.SpiffeIDs) > 0 {
+			tlsCfg.VerifyConnection = verifySpiffeID(config.SpiffeIDs)
 		}
 
 		return &tlsCfg, nil
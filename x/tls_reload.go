@@ -0,0 +1,165 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// certCheckInterval is how often the certificate files are checked for changes.
+const certCheckInterval = time.Second
+
+// certReloader serves a certificate, reloading it when its files change so that certificates
+// can be rotated without restarting. The files are checked while handshaking, at most once
+// per certCheckInterval. If the new files can't be loaded, the old certificate is kept.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	r.cert, r.modTime, r.checked = &cert, modTime, time.Now()
+	return r, nil
+}
+
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var modTime time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+func (r *certReloader) certificate() *tls.Certificate {
+	r.Lock()
+	defer r.Unlock()
+	if time.Since(r.checked) < certCheckInterval {
+		return r.cert
+	}
+	r.checked = time.Now()
+	modTime, err := r.filesModTime()
+	if err != nil || modTime.Equal(r.modTime) {
+		return r.cert
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// The files might be in the middle of being replaced, retry on the next check.
+		glog.Warningf("Unable to reload TLS certificate %s, keeping the old one: %v",
+			r.certFile, err)
+		return r.cert
+	}
+	glog.Infof("Reloaded TLS certificate %s", r.certFile)
+	r.cert, r.modTime = &cert, modTime
+	return r.cert
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate(), nil
+}
+
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate(), nil
+}
+
+// parseSpiffeIDs parses the allowed SPIFFE IDs. An ID ending with /* allows all the IDs under
+// it, e.g. spiffe://example.org/* allows all the workloads of the trust domain.
+func parseSpiffeIDs(ids string) ([]string, error) {
+	var res []string
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if !strings.HasPrefix(id, "spiffe://") || len(id) == len("spiffe://") {
+			return nil, errors.Errorf("invalid SPIFFE ID %q, must start with spiffe://", id)
+		}
+		res = append(res, id)
+	}
+	return res, nil
+}
+
+// verifySpiffeID returns a function for tls.Config.VerifyConnection, which checks that the
+// peer presented an X.509 SVID with one of the allowed SPIFFE IDs. It is called after the
+// certificate chain has been verified.
+func verifySpiffeID(allowed []string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("spiffe: peer did not present a certificate")
+		}
+		id, err := spiffeID(cs.PeerCertificates[0])
+		if err != nil {
+			return err
+		}
+		for _, a := range allowed {
+			if id == a || (strings.HasSuffix(a, "/*") && strings.HasPrefix(id, a[:len(a)-1])) {
+				return nil
+			}
+		}
+		return errors.Errorf("spiffe: ID %q of peer is not allowed", id)
+	}
+}
+
+// spiffeID returns the SPIFFE ID of an X.509 SVID, which must have exactly one URI SAN.
+func spiffeID(cert *x509.Certificate) (string, error) {
+	if len(cert.URIs) != 1 || cert.URIs[0].Scheme != "spiffe" || cert.URIs[0].Host == "" {
+		return "", errors.New("spiffe: peer certificate is not an SVID, it must have exactly " +
+			"one spiffe:// URI SAN")
+	}
+	return cert.URIs[0].String(), nil
+}
+
+// requireClientCert rejects the HTTPS requests without a client certificate, except for the
+// exempt paths. It is used when an endpoint requires client certificates only for some of its
+// paths, because the handshake happens before the path is known. The handshake still verifies
+// the certificates that are given, as required by the client auth type.
+func requireClientCert(exempt []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) == 0 {
+			exempted := false
+			for _, path := range exempt {
+				prefix := strings.TrimSuffix(path, "/") + "/"
+				if r.URL.Path == path || strings.HasPrefix(r.URL.Path, prefix) {
+					exempted = true
+					break
+				}
+			}
+			if !exempted {
+				http.Error(w, "a client certificate is required", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,204 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package x
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	file := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(file,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue writes a certificate for localhost signed by the CA, with the given SPIFFE ID if not
+// empty, and returns the paths of the certificate and key files.
+func (ca *testCA) issue(t *testing.T, dir, name, spiffeID string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+	}
+	if spiffeID != "" {
+		u, err := url.Parse(spiffeID)
+		require.NoError(t, err)
+		tmpl.URIs = []*url.URL{u}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "node", "", 2)
+	r, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	serial := func() int64 {
+		leaf, err := x509.ParseCertificate(r.certificate().Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	require.Equal(t, int64(2), serial())
+
+	ca.issue(t, dir, "node", "", 3)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	// The files are only checked once per interval.
+	require.Equal(t, int64(2), serial())
+	r.checked = time.Time{}
+	require.Equal(t, int64(3), serial())
+
+	// A broken key file keeps the old certificate.
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0600))
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+	r.checked = time.Time{}
+	require.Equal(t, int64(3), serial())
+}
+
+func TestSpiffeIDs(t *testing.T) {
+	_, err := parseSpiffeIDs("https://example.org/dgraph")
+	require.Error(t, err)
+	allowed, err := parseSpiffeIDs("spiffe://example.org/dgraph/alpha, spiffe://example.org/zero/*")
+	require.NoError(t, err)
+
+	verify := verifySpiffeID(allowed)
+	state := func(id string) tls.ConnectionState {
+		cert := &x509.Certificate{}
+		if id != "" {
+			u, err := url.Parse(id)
+			require.NoError(t, err)
+			cert.URIs = []*url.URL{u}
+		}
+		return tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	}
+	require.NoError(t, verify(state("spiffe://example.org/dgraph/alpha")))
+	require.NoError(t, verify(state("spiffe://example.org/zero/1")))
+	require.ErrorContains(t, verify(state("spiffe://example.org/dgraph/other")), "not allowed")
+	require.ErrorContains(t, verify(state("spiffe://example.org/zero")), "not allowed")
+	require.ErrorContains(t, verify(state("")), "not an SVID")
+	require.ErrorContains(t, verify(tls.ConnectionState{}), "did not present")
+}
+
+func TestInternalTLSSpiffe(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "server", "spiffe://example.org/dgraph/alpha", 2)
+	clientCert, clientKey := ca.issue(t, dir, "client", "spiffe://example.org/other", 3)
+
+	serverCfg, err := GenerateServerTLSConfig(&TLSHelperConfig{
+		CertRequired: true, Cert: serverCert, Key: serverKey, RootCACert: ca.file,
+		ClientAuth: "REQUIREANDVERIFY", SpiffeIDs: []string{"spiffe://example.org/dgraph/*"},
+	})
+	require.NoError(t, err)
+	clientCfg, err := GenerateClientTLSConfig(&TLSHelperConfig{
+		CertRequired: true, Cert: clientCert, Key: clientKey, RootCACert: ca.file,
+		ServerName: "localhost", SpiffeIDs: []string{"spiffe://example.org/dgraph/*"},
+	})
+	require.NoError(t, err)
+
+	handshake := func() (error, error) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- tls.Server(c1, serverCfg).Handshake()
+			c1.Close()
+		}()
+		clientErr := tls.Client(c2, clientCfg).Handshake()
+		c2.Close()
+		return <-errCh, clientErr
+	}
+	// The client doesn't have an allowed SPIFFE ID.
+	serverErr, _ := handshake()
+	require.ErrorContains(t, serverErr,
+		`spiffe: ID "spiffe://example.org/other" of peer is not allowed`)
+
+	ca.issue(t, dir, "client", "spiffe://example.org/dgraph/zero", 4)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(clientCert, future, future))
+	time.Sleep(certCheckInterval)
+	serverErr, clientErr := handshake()
+	require.NoError(t, serverErr)
+	require.NoError(t, clientErr)
+}
+
+func TestRequireClientCert(t *testing.T) {
+	h := requireClientCert([]string{"/health", "/probe/"},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	status := func(path string, withCert bool) int {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.TLS = &tls.ConnectionState{}
+		if withCert {
+			r.TLS.PeerCertificates = []*x509.Certificate{{}}
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, status("/health", false))
+	require.Equal(t, http.StatusOK, status("/probe/graphql", false))
+	require.Equal(t, http.StatusUnauthorized, status("/query", false))
+	require.Equal(t, http.StatusUnauthorized, status("/healthz", false))
+	require.Equal(t, http.StatusOK, status("/query", true))
+}
@@ -27,8 +27,12 @@ const (
 	flagAclJwtAlg     = "jwt-alg"
 	flagAclKeyFile    = "secret-file"
 
-	flagEnc        = "encryption"
-	flagEncKeyFile = "key-file"
+	flagAclScimTokenFile = "scim-token-file"
+	flagAclScimNamespace = "scim-namespace"
+
+	flagEnc         = "encryption"
+	flagEncKeyFile  = "key-file"
+	flagEncNsKeyDir = "ns-key-dir"
 
 	flagVault             = "vault"
 	flagVaultAddr         = "addr"
@@ -42,12 +46,32 @@ const (
 )
 
 var (
-	AclDefaults = fmt.Sprintf("%s=%s; %s=%s; %s=%s; %s=%s",
+	AclDefaults = fmt.Sprintf("%s=%s; %s=%s; %s=%s; %s=%s; %s=%s; %s=%s; %s=%s; %s=%s; "+
+		"%s=%s; %s=%s; %s=%s; %s=%s; %s=%s; %s=%s; %s=%s; %s=%s",
 		flagAclAccessTtl, "6h",
 		flagAclRefreshTtl, "30d",
 		flagAclJwtAlg, "HS256",
-		flagAclKeyFile, "")
-	EncDefaults = fmt.Sprintf("%s=%s", flagEncKeyFile, "")
+		flagAclKeyFile, "",
+		flagAclLdapURL, "",
+		flagAclLdapCAFile, "",
+		flagAclLdapBindDN, "",
+		flagAclLdapBindPassFile, "",
+		flagAclLdapUserBase, "",
+		flagAclLdapUserFilter, "(objectClass=*)",
+		flagAclLdapUserAttr, "uid",
+		flagAclLdapGroupAttr, "memberOf",
+		flagAclLdapNamespace, "0",
+		flagAclLdapSync, "0s",
+		flagAclScimTokenFile, "",
+		flagAclScimNamespace, "0")
+	EncDefaults = fmt.Sprintf("%s=%s; %s=%s; %s=%s; %s=%s; %s=%s; %s=%s; %s=%s",
+		flagEncKeyFile, "",
+		flagEncNsKeyDir, "",
+		flagEncKms, "",
+		flagEncKmsKey, "",
+		flagEncKmsRegion, "",
+		flagEncKmsEndpoint, "",
+		flagEncKmsCredentialsFile, "")
 )
 
 func vaultGetKeys(config *viper.Viper) (aclKey, encKey Sensitive) {
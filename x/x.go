@@ -8,14 +8,12 @@ package x
 import (
 	"bufio"
 	"bytes"
-	builtinGzip "compress/gzip"
 	"context"
 	cryptorand "crypto/rand"
 	"crypto/tls"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math"
 	"math/rand"
 	"net"
@@ -65,6 +63,10 @@ var (
 	ErrConflict = errors.New("Transaction conflict")
 	// ErrHashMismatch is returned when the hash does not matches the startTs
 	ErrHashMismatch = errors.New("hash mismatch the claimed startTs|namespace")
+	// ErrTxnExpired is returned for the transactions which ran for longer than the maximum
+	// duration of their namespace. They are aborted, and must be started over.
+	ErrTxnExpired = status.Error(codes.DeadlineExceeded,
+		"Transaction has exceeded its maximum duration and has been aborted")
 )
 
 const (
@@ -80,6 +82,8 @@ const (
 	Error = "Error"
 	// ErrorNoData is an error returned when the requested data cannot be returned.
 	ErrorNoData = "ErrorNoData"
+	// ErrorQueryLimit is returned for a query going beyond one of its QueryLimits.
+	ErrorQueryLimit = "ErrorQueryLimit"
 	// ValidHostnameRegex is a regex that accepts our expected hostname format.
 	ValidHostnameRegex = `^([a-zA-Z0-9_]{1}[a-zA-Z0-9_-]{0,62}){1}(\.[a-zA-Z0-9_]{1}` +
 		`[a-zA-Z0-9_-]{0,62})*[._]?$`
@@ -125,9 +129,26 @@ const (
 	DefaultCreds = "user=; password=; namespace=0;"
 
 	AccessControlAllowedHeaders = "X-Dgraph-AccessToken, X-Dgraph-AuthToken, " +
+		"X-Dgraph-ApiKey, X-Dgraph-ApiKey-Namespace, X-Dgraph-Schema, " +
+		"X-Dgraph-Signature, X-Dgraph-Timestamp, X-Dgraph-Nonce, Idempotency-Key, " +
 		"Content-Type, Content-Length, Accept-Encoding, Cache-Control, " +
 		"X-CSRF-Token, X-Auth-Token, X-Requested-With"
 	DgraphCostHeader = "Dgraph-TouchedUids"
+	// DgraphRetriesHeader carries the number of retries a client allows for a mutation aborted
+	// due to a conflict, and the number of retries made in the response.
+	DgraphRetriesHeader = "Dgraph-Retries"
+	// DgraphBoundaryHeader carries the paths of the subgraphs which weren't fetched by a query
+	// asking for partial results, which are partial if it's set.
+	DgraphBoundaryHeader = "Dgraph-Partial-Boundary"
+	// IdempotencyKeyHeader carries the key of a mutation which commits immediately, so that its
+	// retries with the same key get its result back instead of applying it again.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayHeader is set in the response of a mutation whose result was replayed,
+	// because a mutation with the same idempotency key was applied already.
+	IdempotentReplayHeader = "Idempotent-Replayed"
+	// GraphQLSchemaHeader selects the named GraphQL schema a /graphql request is served with,
+	// instead of the default schema of the namespace.
+	GraphQLSchemaHeader = "X-Dgraph-Schema"
 
 	ManifestVersion = 2105
 )
@@ -156,7 +177,9 @@ func ShouldCrash(err error) bool {
 	errStr := status.Convert(err).Message()
 	return strings.Contains(errStr, "REUSE_RAFTID") ||
 		strings.Contains(errStr, "REUSE_ADDR") ||
-		strings.Contains(errStr, "NO_ADDR")
+		strings.Contains(errStr, "NO_ADDR") ||
+		strings.Contains(errStr, "INCOMPATIBLE_FORMAT") ||
+		strings.Contains(errStr, "INVALID_WITNESS")
 }
 
 // WhiteSpace Replacer removes spaces and tabs from a string.
@@ -399,6 +422,58 @@ func SetStatusWithData(w http.ResponseWriter, code, msg string) {
 	}
 }
 
+// SetErrorStatus is like SetStatus for an error, whose error code is added to the extensions of
+// the response along with whether it can be retried.
+func SetErrorStatus(w http.ResponseWriter, code string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	var qr queryRes
+	qr.Errors = append(qr.Errors, &GqlError{Message: err.Error(),
+		Extensions: ErrorExtensions(code, err)})
+	if js, jerr := json.Marshal(qr); jerr == nil {
+		if _, werr := w.Write(js); werr != nil {
+			glog.Errorf("Error while writing: %+v", werr)
+		}
+	} else {
+		Panic(errors.Errorf("Unable to marshal: %+v", qr))
+	}
+}
+
+// SetErrorStatusWithData is like SetStatusWithData for an error, whose error code is added to the
+// extensions of the response along with whether it can be retried.
+func SetErrorStatusWithData(w http.ResponseWriter, code string, err error) {
+	var qr QueryResWithData
+	qr.Errors = append(qr.Errors, &GqlError{Message: err.Error(),
+		Extensions: ErrorExtensions(code, err)})
+	if js, jerr := json.Marshal(qr); jerr == nil {
+		if _, werr := w.Write(js); werr != nil {
+			glog.Errorf("Error while writing: %+v", werr)
+		}
+	} else {
+		Panic(errors.Errorf("Unable to marshal: %+v", qr))
+	}
+}
+
+// SetQueryLimitStatus is like SetStatusWithData for a query going beyond one of its limits. The
+// limit, its value and the value reached by the query are added to the extensions of the error.
+func SetQueryLimitStatus(w http.ResponseWriter, err *QueryLimitError) {
+	var qr QueryResWithData
+	qr.Errors = append(qr.Errors, &GqlError{Message: err.Error(), Extensions: map[string]interface{}{
+		"code":      ErrorQueryLimit,
+		"errorCode": ErrorCodeTooLarge,
+		"retriable": false,
+		"limit":     err.Limit,
+		"max":       err.Max,
+		"actual":    err.Actual,
+	}})
+	if js, err := json.Marshal(qr); err == nil {
+		if _, err := w.Write(js); err != nil {
+			glog.Errorf("Error while writing: %+v", err)
+		}
+	} else {
+		Panic(errors.Errorf("Unable to marshal: %+v", qr))
+	}
+}
+
 // Reply sets the body of an HTTP response to the JSON representation of the given reply.
 func Reply(w http.ResponseWriter, rep interface{}) {
 	if js, err := json.Marshal(rep); err == nil {
@@ -517,6 +592,33 @@ func AttachAccessJwt(ctx context.Context, r *http.Request) context.Context {
 	return ctx
 }
 
+// AttachRequestId adds any incoming X-Request-Id header data into the grpc context metadata
+func AttachRequestId(ctx context.Context, r *http.Request) context.Context {
+	if requestId := r.Header.Get("X-Request-Id"); requestId != "" {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.New(nil)
+		}
+
+		md.Append("x-request-id", requestId)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx
+}
+
+// ExtractRequestId returns the request id sent by the client in the x-request-id metadata, if any.
+func ExtractRequestId(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	requestId := md.Get("x-request-id")
+	if len(requestId) == 0 {
+		return ""
+	}
+	return requestId[0]
+}
+
 // AttachRemoteIP adds any incoming IP data into the grpc context metadata
 func AttachRemoteIP(ctx context.Context, r *http.Request) context.Context {
 	if ip, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
@@ -569,23 +671,23 @@ func HasWhitelistedIP(ctx context.Context) (net.Addr, error) {
 	return peerInfo.Addr, nil
 }
 
-// WriteResponse writes response body, transparently compressing if necessary.
+// WriteResponse writes response body, transparently compressing it with the encoding accepted
+// by the client if it's large enough.
 func WriteResponse(w http.ResponseWriter, r *http.Request, b []byte) (int, error) {
-	var out io.Writer = w
-
-	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-		w.Header().Set("Content-Encoding", "gzip")
-		gzw := builtinGzip.NewWriter(w)
-		defer gzw.Close()
-		out = gzw
+	addVary(w.Header(), "Accept-Encoding")
+	if len(b) >= responseMinSize {
+		if encoding := ResponseEncoding(r.Header.Get("Accept-Encoding")); encoding != "" {
+			compressed, err := compressResponse(b, encoding)
+			if err != nil {
+				return 0, err
+			}
+			w.Header().Set("Content-Encoding", encoding)
+			b = compressed
+		}
 	}
 
-	bytesWritten, err := out.Write(b)
-	if err != nil {
-		return 0, err
-	}
-	w.Header().Set("Content-Length", strconv.FormatInt(int64(bytesWritten), 10))
-	return bytesWritten, nil
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(b)), 10))
+	return w.Write(b)
 }
 
 // Min returns the minimum of the two given numbers.
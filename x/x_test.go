@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -186,3 +187,77 @@ func TestToHex(t *testing.T) {
 	require.Equal(t, []byte(`"0xffffffffffffffff"`), ToHex(math.MaxUint64, false))
 	require.Equal(t, []byte(`<0xffffffffffffffff>`), ToHex(math.MaxUint64, true))
 }
+
+func TestParseNsDurations(t *testing.T) {
+	durations, err := ParseNsDurations(" 1:10m, 0x2:1h30m,")
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]time.Duration{1: 10 * time.Minute, 2: 90 * time.Minute}, durations)
+
+	durations, err = ParseNsDurations("")
+	require.NoError(t, err)
+	require.Empty(t, durations)
+
+	for _, s := range []string{"1", "a:1m", "1:soon", "1:-1m"} {
+		_, err := ParseNsDurations(s)
+		require.Error(t, err, s)
+	}
+
+	opts := WorkerOptions{TxnMaxDuration: time.Hour, TxnMaxDurationNs: map[uint64]time.Duration{
+		1: time.Minute, 2: 0}}
+	require.Equal(t, time.Hour, opts.MaxTxnDuration(0))
+	require.Equal(t, time.Minute, opts.MaxTxnDuration(1))
+	require.Zero(t, opts.MaxTxnDuration(2))
+}
+
+func TestParseNsUints(t *testing.T) {
+	vals, err := ParseNsUints(" 1:1024, 0x2:0,")
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]uint64{1: 1024, 2: 0}, vals)
+
+	for _, s := range []string{"1", "a:1", "1:-1", "1:1MB"} {
+		_, err := ParseNsUints(s)
+		require.Error(t, err, s)
+	}
+
+	opts := Options{LimitMutationSize: 100, LimitMutationSizeNs: vals}
+	require.Equal(t, uint64(100), opts.NsMutationSize(0))
+	require.Equal(t, uint64(1024), opts.NsMutationSize(1))
+	require.Zero(t, opts.NsMutationSize(2))
+}
+
+func TestParseNsQueryLimits(t *testing.T) {
+	defaults := QueryLimits{Depth: 10, VarUids: 1000}
+	limits, err := ParseNsQueryLimits(" 1:query-depth=5, 1:query-expand=20,0x2:query-var-uids=0,",
+		defaults)
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]QueryLimits{
+		1: {Depth: 5, Expand: 20, VarUids: 1000},
+		2: {Depth: 10},
+	}, limits)
+
+	for _, s := range []string{"1", "1:query-depth", "a:query-depth=1", "1:query-depth=-1",
+		"1:query-width=1"} {
+		_, err := ParseNsQueryLimits(s, defaults)
+		require.Error(t, err, s)
+	}
+
+	opts := Options{QueryLimits: defaults, QueryLimitsNs: limits}
+	require.Equal(t, defaults, opts.NsQueryLimits(0, false))
+	require.Equal(t, uint64(5), opts.NsQueryLimits(1, false).Depth)
+	require.True(t, QueryLimits{}.IsZero())
+	require.False(t, opts.NsQueryLimits(2, false).IsZero())
+}
+
+func TestSandbox(t *testing.T) {
+	opts := Options{
+		QueryLimits:   QueryLimits{Depth: 10, VarUids: 100},
+		QueryLimitsNs: map[uint64]QueryLimits{1: {Depth: 2, RootUids: 50}},
+		SandboxLimits: QueryLimits{Depth: 3, RootUids: 1000, MemoryMb: 16},
+	}
+	// The strictest limit wins, and the limits unset in the sandbox are kept.
+	require.Equal(t, QueryLimits{Depth: 2, RootUids: 50, MemoryMb: 16}, opts.NsQueryLimits(1, true))
+	require.Equal(t, QueryLimits{Depth: 2, RootUids: 50}, opts.NsQueryLimits(1, false))
+	require.Equal(t, QueryLimits{Depth: 3, RootUids: 1000, VarUids: 100, MemoryMb: 16},
+		opts.NsQueryLimits(2, true))
+	require.Equal(t, QueryLimits{Depth: 10, VarUids: 100}, opts.NsQueryLimits(3, false))
+}